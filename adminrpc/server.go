@@ -0,0 +1,381 @@
+package adminrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/getAlby/hub/adminrpc/adminrpcpb"
+	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// the number of events buffered per stream before they are dropped
+const eventStreamBufferSize = 100
+
+// methodScopes maps every RPC to the api key scope it requires. An empty scope only requires a valid key.
+var methodScopes = map[string]string{
+	adminrpcpb.AdminService_GetInfo_FullMethodName:          "",
+	adminrpcpb.AdminService_GetBalances_FullMethodName:      api.API_KEY_SCOPE_TRANSACTIONS,
+	adminrpcpb.AdminService_ListTransactions_FullMethodName: api.API_KEY_SCOPE_TRANSACTIONS,
+	adminrpcpb.AdminService_SubscribeEvents_FullMethodName:  api.API_KEY_SCOPE_TRANSACTIONS,
+	adminrpcpb.AdminService_MakeInvoice_FullMethodName:      api.API_KEY_SCOPE_INVOICES,
+	adminrpcpb.AdminService_SendPayment_FullMethodName:      api.API_KEY_SCOPE_PAYMENTS,
+	adminrpcpb.AdminService_ListApps_FullMethodName:         api.API_KEY_SCOPE_APPS,
+	adminrpcpb.AdminService_GetApp_FullMethodName:           api.API_KEY_SCOPE_APPS,
+	adminrpcpb.AdminService_CreateApp_FullMethodName:        api.API_KEY_SCOPE_APPS,
+	adminrpcpb.AdminService_DeleteApp_FullMethodName:        api.API_KEY_SCOPE_APPS,
+}
+
+// RequestGuard applies the network policy, IP bans and rate limits of the HTTP admin API
+type RequestGuard interface {
+	// IsAllowed reports whether admin requests from the IP address are allowed
+	IsAllowed(ip string) bool
+	// AllowRequest applies the rate limits of the IP address and the api key
+	AllowRequest(ip string, apiKey string) bool
+	// RecordAuthFailure counts a failed authentication towards banning the IP address
+	RecordAuthFailure(ip string)
+}
+
+type server struct {
+	adminrpcpb.UnimplementedAdminServiceServer
+	ctx            context.Context
+	api            api.API
+	appsSvc        apps.AppsService
+	eventPublisher events.EventPublisher
+	guard          RequestGuard
+}
+
+// NewServer creates a gRPC server for the admin API. Event streams are closed when ctx is done,
+// so that the server can be stopped gracefully. The transport credentials are passed in opts.
+func NewServer(ctx context.Context, theAPI api.API, appsSvc apps.AppsService, eventPublisher events.EventPublisher, guard RequestGuard, opts ...grpc.ServerOption) *grpc.Server {
+	s := &server{
+		ctx:            ctx,
+		api:            theAPI,
+		appsSvc:        appsSvc,
+		eventPublisher: eventPublisher,
+		guard:          guard,
+	}
+	opts = append(opts,
+		grpc.UnaryInterceptor(s.unaryAuthInterceptor),
+		grpc.StreamInterceptor(s.streamAuthInterceptor),
+	)
+	grpcServer := grpc.NewServer(opts...)
+	adminrpcpb.RegisterAdminServiceServer(grpcServer, s)
+	return grpcServer
+}
+
+// CheckListenAddress refuses to serve the admin API without TLS on anything but a loopback address,
+// api keys would otherwise be sent in plain text over the network
+func CheckListenAddress(address string, tlsEnabled bool) error {
+	if tlsEnabled {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid gRPC address: %w", err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("the gRPC server requires TLS to listen on %s, configure GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE or listen on a loopback address", address)
+}
+
+func (s *server) unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *server) streamAuthInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+func (s *server) authorize(ctx context.Context, fullMethod string) error {
+	scope, ok := methodScopes[fullMethod]
+	if !ok {
+		return status.Error(codes.PermissionDenied, "unknown method")
+	}
+
+	// gRPC clients connect directly, so the peer address is the client address
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	if !s.guard.IsAllowed(ip) {
+		return status.Error(codes.PermissionDenied, "The admin API is not available from your network")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := ""
+	if authorization := md.Get("authorization"); len(authorization) > 0 {
+		key, _ = strings.CutPrefix(authorization[0], "Bearer ")
+	}
+	if !s.guard.AllowRequest(ip, key) {
+		return status.Error(codes.ResourceExhausted, "Too many requests")
+	}
+	if key == "" {
+		s.guard.RecordAuthFailure(ip)
+		return status.Error(codes.Unauthenticated, "This operation requires an api key")
+	}
+
+	apiKey, err := s.api.AuthenticateApiKey(key)
+	if err != nil {
+		s.guard.RecordAuthFailure(ip)
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if scope != "" && !slices.Contains(apiKey.Scopes, scope) {
+		return status.Errorf(codes.PermissionDenied, "This operation requires an api key with the %s scope", scope)
+	}
+	return nil
+}
+
+func (s *server) GetInfo(ctx context.Context, req *adminrpcpb.GetInfoRequest) (*adminrpcpb.GetInfoResponse, error) {
+	info, err := s.api.GetInfo(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get info: %s", err.Error())
+	}
+	return &adminrpcpb.GetInfoResponse{
+		Version:     info.Version,
+		Network:     info.Network,
+		BackendType: info.BackendType,
+		Running:     info.Running,
+		NodeAlias:   info.NodeAlias,
+	}, nil
+}
+
+func (s *server) GetBalances(ctx context.Context, req *adminrpcpb.GetBalancesRequest) (*adminrpcpb.GetBalancesResponse, error) {
+	balances, err := s.api.GetBalances(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get balances: %s", err.Error())
+	}
+	return &adminrpcpb.GetBalancesResponse{
+		LightningSpendableMsat:  balances.Lightning.TotalSpendable,
+		LightningReceivableMsat: balances.Lightning.TotalReceivable,
+		OnchainSpendableSat:     balances.Onchain.Spendable,
+		OnchainTotalSat:         balances.Onchain.Total,
+	}, nil
+}
+
+func (s *server) ListTransactions(ctx context.Context, req *adminrpcpb.ListTransactionsRequest) (*adminrpcpb.ListTransactionsResponse, error) {
+	var appId *uint
+	if req.AppId != nil {
+		id := uint(*req.AppId)
+		appId = &id
+	}
+	listTransactionsResponse, err := s.api.ListTransactions(ctx, appId, req.Limit, req.Offset, nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list transactions: %s", err.Error())
+	}
+
+	transactions := []*adminrpcpb.Transaction{}
+	for i := range listTransactionsResponse.Transactions {
+		transactions = append(transactions, toRpcTransaction(&listTransactionsResponse.Transactions[i]))
+	}
+	return &adminrpcpb.ListTransactionsResponse{
+		TotalCount:   listTransactionsResponse.TotalCount,
+		Transactions: transactions,
+	}, nil
+}
+
+func (s *server) MakeInvoice(ctx context.Context, req *adminrpcpb.MakeInvoiceRequest) (*adminrpcpb.Transaction, error) {
+	invoice, err := s.api.CreateInvoice(ctx, req.AmountMsat, req.Description)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to make invoice: %s", err.Error())
+	}
+	return toRpcTransaction(invoice), nil
+}
+
+func (s *server) SendPayment(ctx context.Context, req *adminrpcpb.SendPaymentRequest) (*adminrpcpb.Transaction, error) {
+	payment, err := s.api.SendPayment(ctx, req.Invoice, req.AmountMsat, nil, req.IdempotencyKey)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to send payment: %s", err.Error())
+	}
+	return toRpcTransaction(payment), nil
+}
+
+func (s *server) ListApps(ctx context.Context, req *adminrpcpb.ListAppsRequest) (*adminrpcpb.ListAppsResponse, error) {
+	listAppsResponse, err := s.api.ListApps(req.Limit, req.Offset, api.ListAppsFilters{}, "")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list apps: %s", err.Error())
+	}
+
+	apps := []*adminrpcpb.App{}
+	for i := range listAppsResponse.Apps {
+		apps = append(apps, toRpcApp(&listAppsResponse.Apps[i]))
+	}
+	return &adminrpcpb.ListAppsResponse{
+		TotalCount: listAppsResponse.TotalCount,
+		Apps:       apps,
+	}, nil
+}
+
+func (s *server) GetApp(ctx context.Context, req *adminrpcpb.GetAppRequest) (*adminrpcpb.App, error) {
+	dbApp, err := s.getApp(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toRpcApp(s.api.GetApp(dbApp)), nil
+}
+
+func (s *server) CreateApp(ctx context.Context, req *adminrpcpb.CreateAppRequest) (*adminrpcpb.CreateAppResponse, error) {
+	createAppResponse, err := s.api.CreateApp(&api.CreateAppRequest{
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		MaxAmountSat:  req.MaxAmountSat,
+		BudgetRenewal: req.BudgetRenewal,
+		Isolated:      req.Isolated,
+		ExpiresAt:     req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to create app: %s", err.Error())
+	}
+	return &adminrpcpb.CreateAppResponse{
+		Id:               uint32(createAppResponse.Id),
+		Name:             createAppResponse.Name,
+		PairingUri:       createAppResponse.PairingUri,
+		PairingPublicKey: createAppResponse.Pubkey,
+		WalletPubkey:     createAppResponse.WalletPubkey,
+		RelayUrls:        createAppResponse.RelayUrls,
+	}, nil
+}
+
+func (s *server) DeleteApp(ctx context.Context, req *adminrpcpb.DeleteAppRequest) (*adminrpcpb.DeleteAppResponse, error) {
+	dbApp, err := s.getApp(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.api.DeleteApp(dbApp); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete app: %s", err.Error())
+	}
+	return &adminrpcpb.DeleteAppResponse{}, nil
+}
+
+func (s *server) SubscribeEvents(req *adminrpcpb.SubscribeEventsRequest, stream adminrpcpb.AdminService_SubscribeEventsServer) error {
+	subscriber := events.NewStreamSubscriber(eventStreamBufferSize, req.Events)
+	s.eventPublisher.RegisterSubscriber(subscriber)
+	defer s.eventPublisher.RemoveSubscriber(subscriber)
+
+	// lets clients know that no events are missed from now on
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.ctx.Done():
+			return nil
+		case event := <-subscriber.Events():
+			rpcEvent, err := toRpcEvent(event)
+			if err != nil {
+				logger.Logger.WithError(err).WithField("event", event.Event).Error("Failed to convert event for stream")
+				continue
+			}
+			if err := stream.Send(rpcEvent); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *server) getApp(id uint32) (*db.App, error) {
+	dbApp := s.appsSvc.GetAppById(uint(id))
+	if dbApp == nil {
+		return nil, status.Error(codes.NotFound, "App not found")
+	}
+	return dbApp, nil
+}
+
+func toRpcEvent(event *events.Event) (*adminrpcpb.Event, error) {
+	rpcEvent := &adminrpcpb.Event{
+		Event: event.Event,
+	}
+	if transaction, ok := event.Properties.(*db.Transaction); ok {
+		rpcEvent.Transaction = toRpcTransaction(api.ToApiTransaction(transaction))
+		return rpcEvent, nil
+	}
+	if event.Properties != nil {
+		properties, err := json.Marshal(event.Properties)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event properties: %w", err)
+		}
+		rpcEvent.PropertiesJson = string(properties)
+	}
+	return rpcEvent, nil
+}
+
+func toRpcTransaction(transaction *api.Transaction) *adminrpcpb.Transaction {
+	rpcTransaction := &adminrpcpb.Transaction{
+		Type:            transaction.Type,
+		State:           transaction.State,
+		Invoice:         transaction.Invoice,
+		Description:     transaction.Description,
+		DescriptionHash: transaction.DescriptionHash,
+		PaymentHash:     transaction.PaymentHash,
+		AmountMsat:      transaction.Amount,
+		FeesPaidMsat:    transaction.FeesPaid,
+		CreatedAt:       transaction.CreatedAt,
+		UpdatedAt:       transaction.UpdatedAt,
+		FailureReason:   transaction.FailureReason,
+	}
+	if transaction.Preimage != nil {
+		rpcTransaction.Preimage = *transaction.Preimage
+	}
+	if transaction.SettledAt != nil {
+		rpcTransaction.SettledAt = *transaction.SettledAt
+	}
+	if transaction.AppId != nil {
+		appId := uint32(*transaction.AppId)
+		rpcTransaction.AppId = &appId
+	}
+	return rpcTransaction
+}
+
+func toRpcApp(app *api.App) *adminrpcpb.App {
+	rpcApp := &adminrpcpb.App{
+		Id:             uint32(app.ID),
+		Name:           app.Name,
+		Description:    app.Description,
+		AppPubkey:      app.AppPubkey,
+		Scopes:         app.Scopes,
+		MaxAmountSat:   app.MaxAmountSat,
+		BudgetUsageSat: app.BudgetUsage,
+		BudgetRenewal:  app.BudgetRenewal,
+		Isolated:       app.Isolated,
+		Paused:         app.Paused,
+		BalanceMsat:    app.Balance,
+		CreatedAt:      app.CreatedAt.Format(time.RFC3339),
+	}
+	if app.ExpiresAt != nil {
+		rpcApp.ExpiresAt = app.ExpiresAt.Format(time.RFC3339)
+	}
+	if app.LastUsedAt != nil {
+		rpcApp.LastUsedAt = app.LastUsedAt.Format(time.RFC3339)
+	}
+	return rpcApp
+}
@@ -0,0 +1,155 @@
+package adminrpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/getAlby/hub/adminrpc/adminrpcpb"
+	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/tests/mocks"
+)
+
+// fakeGuard allows every request unless configured otherwise
+type fakeGuard struct {
+	mtx          sync.Mutex
+	blocked      bool
+	rateLimited  bool
+	authFailures []string
+}
+
+func (guard *fakeGuard) IsAllowed(ip string) bool {
+	guard.mtx.Lock()
+	defer guard.mtx.Unlock()
+	return !guard.blocked
+}
+
+func (guard *fakeGuard) AllowRequest(ip string, apiKey string) bool {
+	guard.mtx.Lock()
+	defer guard.mtx.Unlock()
+	return !guard.rateLimited
+}
+
+func (guard *fakeGuard) RecordAuthFailure(ip string) {
+	guard.mtx.Lock()
+	defer guard.mtx.Unlock()
+	guard.authFailures = append(guard.authFailures, ip)
+}
+
+func (guard *fakeGuard) set(blocked bool, rateLimited bool) {
+	guard.mtx.Lock()
+	defer guard.mtx.Unlock()
+	guard.blocked = blocked
+	guard.rateLimited = rateLimited
+}
+
+func TestAdminService(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := api.NewAPI(mocks.NewMockService(t), svc.DB, svc.Cfg, svc.Keys, nil, nil, svc.EventPublisher)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener := bufconn.Listen(1024 * 1024)
+	guard := &fakeGuard{}
+	grpcServer := NewServer(ctx, theAPI, svc.AppsService, svc.EventPublisher, guard)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := adminrpcpb.NewAdminServiceClient(conn)
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	_, err = client.ListApps(ctx, &adminrpcpb.ListAppsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	// failed authentications count towards an IP ban like on the HTTP API
+	assert.Len(t, guard.authFailures, 1)
+
+	createApiKeyResponse, err := theAPI.CreateApiKey(&api.CreateApiKeyRequest{
+		Name:   "integration",
+		Scopes: []string{api.API_KEY_SCOPE_APPS, api.API_KEY_SCOPE_TRANSACTIONS},
+	})
+	require.NoError(t, err)
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+createApiKeyResponse.Key)
+
+	listAppsResponse, err := client.ListApps(authCtx, &adminrpcpb.ListAppsRequest{})
+	require.NoError(t, err)
+	require.Len(t, listAppsResponse.Apps, 1)
+	assert.Equal(t, uint32(app.ID), listAppsResponse.Apps[0].Id)
+
+	_, err = client.GetApp(authCtx, &adminrpcpb.GetAppRequest{Id: 999})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	guard.set(true, false)
+	_, err = client.ListApps(authCtx, &adminrpcpb.ListAppsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	guard.set(false, true)
+	_, err = client.ListApps(authCtx, &adminrpcpb.ListAppsRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	guard.set(false, false)
+
+	_, err = client.SendPayment(authCtx, &adminrpcpb.SendPaymentRequest{Invoice: tests.MockInvoice})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream, err := client.SubscribeEvents(authCtx, &adminrpcpb.SubscribeEventsRequest{
+		Events: []string{"nwc_payment_received"},
+	})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	svc.EventPublisher.Publish(&events.Event{
+		Event: "nwc_backup_channels",
+	})
+	svc.EventPublisher.Publish(&events.Event{
+		Event: "nwc_payment_received",
+		Properties: &db.Transaction{
+			Type:        constants.TRANSACTION_TYPE_INCOMING,
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			PaymentHash: "payment-hash",
+			AmountMsat:  1000,
+		},
+	})
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "nwc_payment_received", event.Event)
+	assert.Equal(t, "payment-hash", event.Transaction.PaymentHash)
+	assert.Equal(t, uint64(1000), event.Transaction.AmountMsat)
+}
+
+func TestCheckListenAddress(t *testing.T) {
+	assert.NoError(t, CheckListenAddress("127.0.0.1:8090", false))
+	assert.NoError(t, CheckListenAddress("[::1]:8090", false))
+	assert.NoError(t, CheckListenAddress("localhost:8090", false))
+	assert.Error(t, CheckListenAddress(":8090", false))
+	assert.Error(t, CheckListenAddress("0.0.0.0:8090", false))
+	assert.Error(t, CheckListenAddress("192.0.2.1:8090", false))
+	assert.NoError(t, CheckListenAddress(":8090", true))
+	assert.Error(t, CheckListenAddress("8090", false))
+}
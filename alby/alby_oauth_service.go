@@ -8,10 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
 	"slices"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -22,15 +20,16 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/backups"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/lsp"
 	"github.com/getAlby/hub/nip47/permissions"
 	"github.com/getAlby/hub/service/keys"
-	"github.com/getAlby/hub/utils"
 	"github.com/getAlby/hub/version"
 )
 
@@ -818,6 +817,7 @@ func (svc *albyOAuthService) backupChannels(ctx context.Context, event *events.E
 		return fmt.Errorf("request to /internal/backups returned non-success status: %d", resp.StatusCode)
 	}
 
+	backups.SetLastChannelBackupAt(svc.cfg, time.Now())
 	return nil
 }
 
@@ -1031,7 +1031,8 @@ func (svc *albyOAuthService) GetLSPChannelOffer(ctx context.Context) (*LSPChanne
 	return lspChannelOffer, nil
 }
 
-func (svc *albyOAuthService) GetLSPInfo(ctx context.Context, lspIdentifier, network string) (*LSPInfo, error) {
+// NewLSPS1Client returns a client for the LSPS1 API of an LSP, requested through the Alby API
+func (svc *albyOAuthService) NewLSPS1Client(ctx context.Context, lspIdentifier, network string) (*lsp.LSPS1Client, error) {
 	token, err := svc.fetchUserToken(ctx)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to fetch user token")
@@ -1041,178 +1042,52 @@ func (svc *albyOAuthService) GetLSPInfo(ctx context.Context, lspIdentifier, netw
 	var client *http.Client
 	if token != nil {
 		client = svc.oauthConf.Client(ctx, token)
-	} else {
-		client = &http.Client{}
-	}
-	client.Timeout = 30 * time.Second
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/internal/lsp/%s/%s/v1/get_info", albyOAuthAPIURL, lspIdentifier, network), nil)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to create lsp info request")
-		return nil, err
-	}
-
-	setDefaultRequestHeaders(req)
-
-	res, err := client.Do(req)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to request lsp info")
-		return nil, err
-	}
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to read response body")
-		return nil, errors.New("failed to read response body")
-	}
-
-	if res.StatusCode >= 300 {
-		logger.Logger.WithFields(logrus.Fields{
-			"body":        string(body),
-			"status_code": res.StatusCode,
-		}).Error("lsp info endpoint returned non-success code")
-		return nil, fmt.Errorf("lsp info endpoint returned non-success code: %s", string(body))
-	}
-
-	type lsps1LSPInfo struct {
-		MinRequiredChannelConfirmations uint64   `json:"min_required_channel_confirmations"`
-		MinFundingConfirmsWithinBlocks  uint64   `json:"min_funding_confirms_within_blocks"`
-		MaxChannelExpiryBlocks          uint64   `json:"max_channel_expiry_blocks"`
-		URIs                            []string `json:"uris"`
-	}
-
-	lsps1LspInfo := &lsps1LSPInfo{}
-	err = json.Unmarshal(body, lsps1LspInfo)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to decode API response")
-		return nil, err
-	}
-
-	httpUris := utils.Filter(lsps1LspInfo.URIs, func(uri string) bool {
-		return !strings.Contains(uri, ".onion")
-	})
-	if len(httpUris) == 0 {
-		logger.Logger.WithField("uris", lsps1LspInfo.URIs).WithError(err).Error("Couldn't find HTTP URI")
-		return nil, err
 	}
-	uri := httpUris[0]
 
-	// make sure it's a valid IPv4 URI
-	regex := regexp.MustCompile(`^([0-9a-f]+)@([0-9]+\.[0-9]+\.[0-9]+\.[0-9]+):([0-9]+)$`)
-	parts := regex.FindStringSubmatch(uri)
-	logger.Logger.WithField("parts", parts).Info("Split URI")
-	if parts == nil || len(parts) != 4 {
-		logger.Logger.WithField("parts", parts).Error("Unsupported URI")
-		return nil, errors.New("could not decode LSP URI")
-	}
-
-	port, err := strconv.Atoi(parts[3])
-	if err != nil {
-		logger.Logger.WithField("port", parts[3]).WithError(err).Error("Failed to decode port number")
-
-		return nil, err
-	}
-
-	return &LSPInfo{
-		Pubkey:                          parts[1],
-		Address:                         parts[2],
-		Port:                            uint16(port),
-		MaxChannelExpiryBlocks:          lsps1LspInfo.MaxChannelExpiryBlocks,
-		MinRequiredChannelConfirmations: lsps1LspInfo.MinRequiredChannelConfirmations,
-		MinFundingConfirmsWithinBlocks:  lsps1LspInfo.MinFundingConfirmsWithinBlocks,
-	}, nil
+	return lsp.NewLSPS1Client(fmt.Sprintf("%s/internal/lsp/%s/%s", albyOAuthAPIURL, lspIdentifier, network), client), nil
 }
 
-func (svc *albyOAuthService) CreateLSPOrder(ctx context.Context, lsp, network string, lspChannelRequest *LSPChannelRequest) (*LSPChannelResponse, error) {
-	token, err := svc.fetchUserToken(ctx)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to fetch user token")
-		return nil, err
-	}
-
-	var client *http.Client
-	if token != nil {
-		client = svc.oauthConf.Client(ctx, token)
-	} else {
-		client = &http.Client{}
-	}
-	client.Timeout = 30 * time.Second
-
-	payloadBytes, err := json.Marshal(lspChannelRequest)
-	if err != nil {
-		return nil, err
-	}
-	bodyReader := bytes.NewReader(payloadBytes)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/internal/lsp/%s/%s/v1/create_order", albyOAuthAPIURL, lsp, network), bodyReader)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to create lsp order request")
-		return nil, err
-	}
-
-	setDefaultRequestHeaders(req)
-
-	res, err := client.Do(req)
+func (svc *albyOAuthService) RequestAutoChannel(ctx context.Context, lnClient lnclient.LNClient, isPublic bool) (*AutoChannelResponse, error) {
+	nodeInfo, err := lnClient.GetInfo(ctx)
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to request lsp order")
+		logger.Logger.WithError(err).Error("Failed to request own node info", err)
 		return nil, err
 	}
 
-	body, err := io.ReadAll(res.Body)
+	lsps1Client, err := svc.NewLSPS1Client(ctx, "alby", nodeInfo.Network)
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to read response body")
-		return nil, errors.New("failed to read response body")
-	}
-
-	if res.StatusCode >= 300 {
-		logger.Logger.WithFields(logrus.Fields{
-			"body":        string(body),
-			"status_code": res.StatusCode,
-		}).Error("lsp create order endpoint returned non-success code")
-		return nil, fmt.Errorf("lsp create order endpoint returned non-success code: %s", string(body))
-	}
-
-	channelResponse := &LSPChannelResponse{}
-	err = json.Unmarshal(body, channelResponse)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to decode API response")
 		return nil, err
 	}
 
-	return channelResponse, nil
-}
-
-func (svc *albyOAuthService) RequestAutoChannel(ctx context.Context, lnClient lnclient.LNClient, isPublic bool) (*AutoChannelResponse, error) {
-	nodeInfo, err := lnClient.GetInfo(ctx)
+	lspInfo, err := lsps1Client.GetInfo(ctx)
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to request own node info", err)
+		logger.Logger.WithError(err).Error("Failed to request LSP info")
 		return nil, err
 	}
 
-	lspInfo, err := svc.GetLSPInfo(ctx, "alby", nodeInfo.Network)
-
+	pubkey, address, port, err := lsp.ParseURI(lspInfo.URIs)
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to request LSP info")
+		logger.Logger.WithField("uris", lspInfo.URIs).WithError(err).Error("Unsupported URI")
 		return nil, err
 	}
 
 	err = lnClient.ConnectPeer(ctx, &lnclient.ConnectPeerRequest{
-		Pubkey:  lspInfo.Pubkey,
-		Address: lspInfo.Address,
-		Port:    lspInfo.Port,
+		Pubkey:  pubkey,
+		Address: address,
+		Port:    port,
 	})
 
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
-			"pubkey":  lspInfo.Pubkey,
-			"address": lspInfo.Address,
-			"port":    lspInfo.Port,
+			"pubkey":  pubkey,
+			"address": address,
+			"port":    port,
 		}).WithError(err).Error("Failed to connect to peer")
 		return nil, err
 	}
 
 	logger.Logger.WithFields(logrus.Fields{
-		"pubkey": lspInfo.Pubkey,
+		"pubkey": pubkey,
 		"public": isPublic,
 	}).Info("Requesting auto channel")
 
@@ -1384,10 +1259,12 @@ func getEventWhitelist() []string {
 		"nwc_app_updated",
 		"nwc_app_deleted",
 		"nwc_unlocked",
+		"nwc_duress_unlock",
 		"nwc_node_sync_failed",
 		"nwc_outgoing_liquidity_required",
 		"nwc_incoming_liquidity_required",
 		"nwc_budget_warning",
+		"nwc_budget_threshold_reached",
 		"nwc_channel_ready",
 		"nwc_channel_closed",
 		"nwc_permission_denied",
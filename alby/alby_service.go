@@ -6,10 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/fiatrates"
 	"github.com/getAlby/hub/logger"
 	"github.com/sirupsen/logrus"
 )
@@ -17,72 +21,35 @@ import (
 const albyInternalAPIURL = "https://getalby.com/api"
 
 type albyService struct {
-	cfg config.Config
+	cfg       config.Config
+	fiatRates *fiatrates.FiatRatesService
 }
 
-func NewAlbyService(cfg config.Config) *albyService {
+func NewAlbyService(cfg config.Config, fiatRates *fiatrates.FiatRatesService) *albyService {
 	albySvc := &albyService{
-		cfg: cfg,
+		cfg:       cfg,
+		fiatRates: fiatRates,
 	}
 	return albySvc
 }
 
 func (svc *albyService) GetBitcoinRate(ctx context.Context) (*BitcoinRate, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	currency := svc.cfg.GetCurrency()
-
-	url := fmt.Sprintf("%s/rates/%s", albyInternalAPIURL, currency)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"currency": currency,
-			"error":    err,
-		}).Error("Error creating request to Bitcoin rate endpoint")
-		return nil, err
-	}
-	setDefaultRequestHeaders(req)
-
-	res, err := client.Do(req)
-	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"currency": currency,
-			"error":    err,
-		}).Error("Failed to fetch Bitcoin rate from API")
-		return nil, err
-	}
-
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		logger.Logger.WithError(err).WithFields(logrus.Fields{
-			"url": url,
-		}).Error("Failed to read response body")
-		return nil, errors.New("failed to read response body")
-	}
-
-	if res.StatusCode >= 300 {
-		logger.Logger.WithFields(logrus.Fields{
-			"currency":    currency,
-			"body":        string(body),
-			"status_code": res.StatusCode,
-		}).Error("Bitcoin rate endpoint returned non-success code")
-		return nil, fmt.Errorf("bitcoin rate endpoint returned non-success code: %s", string(body))
-	}
+	return svc.GetBitcoinRateForCurrency(ctx, svc.cfg.GetCurrency())
+}
 
-	var rate = &BitcoinRate{}
-	err = json.Unmarshal(body, rate)
+func (svc *albyService) GetBitcoinRateForCurrency(ctx context.Context, currency string) (*BitcoinRate, error) {
+	rate, err := svc.fiatRates.GetFiatRate(ctx, currency)
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"currency": currency,
-			"body":     string(body),
-			"error":    err,
-		}).Error("Failed to decode Bitcoin rate API response")
+		logger.Logger.WithError(err).WithField("currency", currency).Error("Failed to fetch Bitcoin rate")
 		return nil, err
 	}
 
-	return rate, nil
+	return &BitcoinRate{
+		Code:      strings.ToUpper(currency),
+		Rate:      strconv.FormatFloat(rate, 'f', 2, 64),
+		RateFloat: rate,
+		RateCents: int64(math.Round(rate * 100)),
+	}, nil
 }
 
 func (svc *albyService) GetChannelPeerSuggestions(ctx context.Context) ([]ChannelPeerSuggestion, error) {
@@ -5,19 +5,20 @@ import (
 
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/lsp"
 )
 
 type AlbyService interface {
 	GetInfo(ctx context.Context) (*AlbyInfo, error)
 	GetBitcoinRate(ctx context.Context) (*BitcoinRate, error)
+	GetBitcoinRateForCurrency(ctx context.Context, currency string) (*BitcoinRate, error)
 	GetChannelPeerSuggestions(ctx context.Context) ([]ChannelPeerSuggestion, error)
 }
 
 type AlbyOAuthService interface {
 	events.EventSubscriber
 	GetLSPChannelOffer(ctx context.Context) (*LSPChannelOffer, error)
-	GetLSPInfo(ctx context.Context, lsp, network string) (*LSPInfo, error)
-	CreateLSPOrder(ctx context.Context, lsp, network string, lspChannelRequest *LSPChannelRequest) (*LSPChannelResponse, error)
+	NewLSPS1Client(ctx context.Context, lspIdentifier, network string) (*lsp.LSPS1Client, error)
 	GetAuthUrl() string
 	GetUserIdentifier() (string, error)
 	GetLightningAddress() (string, error)
@@ -139,38 +140,3 @@ type BitcoinRate struct {
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
-
-type LSPChannelPaymentBolt11 struct {
-	Invoice     string `json:"invoice"`
-	FeeTotalSat string `json:"fee_total_sat"`
-}
-
-type LSPChannelPayment struct {
-	Bolt11 LSPChannelPaymentBolt11 `json:"bolt11"`
-	// TODO: add onchain
-}
-
-type LSPChannelResponse struct {
-	Payment *LSPChannelPayment `json:"payment"`
-}
-
-type LSPChannelRequest struct {
-	PublicKey                    string `json:"public_key"`
-	LSPBalanceSat                string `json:"lsp_balance_sat"`
-	ClientBalanceSat             string `json:"client_balance_sat"`
-	RequiredChannelConfirmations uint64 `json:"required_channel_confirmations"`
-	FundingConfirmsWithinBlocks  uint64 `json:"funding_confirms_within_blocks"`
-	ChannelExpiryBlocks          uint64 `json:"channel_expiry_blocks"`
-	Token                        string `json:"token"`
-	RefundOnchainAddress         string `json:"refund_onchain_address"`
-	AnnounceChannel              bool   `json:"announce_channel"`
-}
-
-type LSPInfo struct {
-	Pubkey                          string
-	Address                         string
-	Port                            uint16
-	MaxChannelExpiryBlocks          uint64
-	MinRequiredChannelConfirmations uint64
-	MinFundingConfirmsWithinBlocks  uint64
-}
@@ -0,0 +1,15 @@
+package api
+
+import (
+	"io"
+
+	"github.com/getAlby/hub/auditlog"
+)
+
+func (api *api) ExportAdminAuditLog(w io.Writer) error {
+	return auditlog.Export(api.db, w)
+}
+
+func (api *api) VerifyAdminAuditLog() (*AdminAuditLogVerification, error) {
+	return auditlog.Verify(api.db)
+}
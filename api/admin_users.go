@@ -0,0 +1,309 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/totp"
+)
+
+const minAdminUserPasswordLength = 8
+
+func (api *api) ListAdminUsers() ([]AdminUser, error) {
+	var dbAdminUsers []db.AdminUser
+	if err := api.db.Order("name").Find(&dbAdminUsers).Error; err != nil {
+		return nil, err
+	}
+
+	adminUsers := []AdminUser{}
+	for _, dbAdminUser := range dbAdminUsers {
+		adminUser, err := api.toApiAdminUser(&dbAdminUser)
+		if err != nil {
+			return nil, err
+		}
+		adminUsers = append(adminUsers, *adminUser)
+	}
+	return adminUsers, nil
+}
+
+func (api *api) GetAdminUser(id uint) (*AdminUser, error) {
+	var adminUser db.AdminUser
+	if api.db.Limit(1).Find(&adminUser, id).RowsAffected == 0 {
+		return nil, errors.New("admin user not found")
+	}
+	return api.toApiAdminUser(&adminUser)
+}
+
+func (api *api) CreateAdminUser(createAdminUserRequest *CreateAdminUserRequest) (*AdminUser, error) {
+	if createAdminUserRequest.Name == "" {
+		return nil, errors.New("no admin user name provided")
+	}
+	if api.db.Limit(1).Find(&db.AdminUser{}, &db.AdminUser{Name: createAdminUserRequest.Name}).RowsAffected > 0 {
+		return nil, errors.New("an admin user with this name already exists")
+	}
+	role := createAdminUserRequest.Role
+	if role == "" {
+		role = ADMIN_USER_ROLE_APP_MANAGER
+	}
+	if !slices.Contains(AdminUserRoles, role) {
+		return nil, fmt.Errorf("invalid admin user role: %s", role)
+	}
+	passwordHash, err := hashAdminUserPassword(createAdminUserRequest.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	adminUser := db.AdminUser{
+		Name:         createAdminUserRequest.Name,
+		PasswordHash: passwordHash,
+		Role:         role,
+	}
+	totpUri := ""
+	if requiresAdminUserTotp(role) {
+		totpUri, err = resetAdminUserTotp(&adminUser)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&adminUser).Error; err != nil {
+			return err
+		}
+		return setAdminUserApps(tx, adminUser.ID, createAdminUserRequest.AppIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"admin_user_id": adminUser.ID,
+		"name":          adminUser.Name,
+		"role":          adminUser.Role,
+	}).Info("Created admin user")
+	apiAdminUser, err := api.toApiAdminUser(&adminUser)
+	if err != nil {
+		return nil, err
+	}
+	apiAdminUser.TotpUri = totpUri
+	return apiAdminUser, nil
+}
+
+func (api *api) UpdateAdminUser(id uint, updateAdminUserRequest *UpdateAdminUserRequest) (*AdminUser, error) {
+	var adminUser db.AdminUser
+	if api.db.Limit(1).Find(&adminUser, id).RowsAffected == 0 {
+		return nil, errors.New("admin user not found")
+	}
+
+	if updateAdminUserRequest.Role != nil && !slices.Contains(AdminUserRoles, *updateAdminUserRequest.Role) {
+		return nil, fmt.Errorf("invalid admin user role: %s", *updateAdminUserRequest.Role)
+	}
+
+	totpUri := ""
+	err := api.db.Transaction(func(tx *gorm.DB) error {
+		if updateAdminUserRequest.Password != nil {
+			passwordHash, err := hashAdminUserPassword(*updateAdminUserRequest.Password)
+			if err != nil {
+				return err
+			}
+			adminUser.PasswordHash = passwordHash
+		}
+		if updateAdminUserRequest.Role != nil {
+			adminUser.Role = *updateAdminUserRequest.Role
+		}
+		if updateAdminUserRequest.ResetTotp || (requiresAdminUserTotp(adminUser.Role) && adminUser.TotpSecret == "") {
+			var err error
+			totpUri, err = resetAdminUserTotp(&adminUser)
+			if err != nil {
+				return err
+			}
+		}
+		if updateAdminUserRequest.Password != nil || updateAdminUserRequest.Role != nil || totpUri != "" {
+			if err := tx.Save(&adminUser).Error; err != nil {
+				return err
+			}
+		}
+		if updateAdminUserRequest.AppIds != nil {
+			if err := tx.Where("admin_user_id = ?", adminUser.ID).Delete(&db.AdminUserApp{}).Error; err != nil {
+				return err
+			}
+			return setAdminUserApps(tx, adminUser.ID, *updateAdminUserRequest.AppIds)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	apiAdminUser, err := api.toApiAdminUser(&adminUser)
+	if err != nil {
+		return nil, err
+	}
+	apiAdminUser.TotpUri = totpUri
+	return apiAdminUser, nil
+}
+
+// DeleteAdminUser removes the login, the apps it managed are kept
+func (api *api) DeleteAdminUser(id uint) error {
+	return api.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("admin_user_id = ?", id).Delete(&db.AdminUserApp{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&db.AdminUser{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("admin user not found")
+		}
+		return nil
+	})
+}
+
+// CheckAdminUserPassword returns the id of the admin user if the password matches
+func (api *api) CheckAdminUserPassword(name string, password string) (uint, bool) {
+	var adminUser db.AdminUser
+	if name == "" || password == "" || api.db.Limit(1).Find(&adminUser, &db.AdminUser{Name: name}).RowsAffected == 0 {
+		return 0, false
+	}
+	return adminUser.ID, checkAdminUserPassword(adminUser.PasswordHash, password)
+}
+
+// VerifyAdminUserTotp checks the two-factor code of an owner or operator.
+// Every code can only be used once.
+func (api *api) VerifyAdminUserTotp(adminUserId uint, code string) error {
+	totpMutex.Lock()
+	defer totpMutex.Unlock()
+
+	var adminUser db.AdminUser
+	if api.db.Limit(1).Find(&adminUser, adminUserId).RowsAffected == 0 {
+		return errors.New("admin user not found")
+	}
+	if !requiresAdminUserTotp(adminUser.Role) {
+		return nil
+	}
+	if adminUser.TotpSecret == "" {
+		return errors.New("two-factor authentication is not set up for this admin user, ask the owner of the hub to reset it")
+	}
+	if code == "" {
+		return errors.New("two-factor code required")
+	}
+	step, ok := totp.Validate(adminUser.TotpSecret, code, time.Now())
+	if !ok {
+		return errors.New("invalid two-factor code")
+	}
+	if step <= adminUser.TotpLastStep {
+		return errors.New("two-factor code was already used")
+	}
+	return api.db.Model(&adminUser).Update("totp_last_step", step).Error
+}
+
+func (api *api) CanAdminUserManageApp(adminUserId uint, appId uint) bool {
+	return api.db.Limit(1).Find(&db.AdminUserApp{}, &db.AdminUserApp{AdminUserId: adminUserId, AppId: appId}).RowsAffected > 0
+}
+
+// CreateAdminUserApp creates a connection on behalf of an admin user, who can then manage it
+func (api *api) CreateAdminUserApp(adminUserId uint, createAppRequest *CreateAppRequest) (*CreateAppResponse, error) {
+	if slices.Contains(createAppRequest.Scopes, constants.SUPERUSER_SCOPE) {
+		return nil, errors.New("admin users cannot create apps with the superuser scope")
+	}
+
+	createAppResponse, err := api.CreateApp(createAppRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.db.Create(&db.AdminUserApp{
+		AdminUserId: adminUserId,
+		AppId:       createAppResponse.Id,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return createAppResponse, nil
+}
+
+func setAdminUserApps(tx *gorm.DB, adminUserId uint, appIds []uint) error {
+	for _, appId := range appIds {
+		if tx.Limit(1).Find(&db.App{}, appId).RowsAffected == 0 {
+			return fmt.Errorf("app %d not found", appId)
+		}
+		if err := tx.Create(&db.AdminUserApp{AdminUserId: adminUserId, AppId: appId}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (api *api) toApiAdminUser(adminUser *db.AdminUser) (*AdminUser, error) {
+	appIds := []uint{}
+	if err := api.db.Model(&db.AdminUserApp{}).Where("admin_user_id = ?", adminUser.ID).Order("app_id").Pluck("app_id", &appIds).Error; err != nil {
+		return nil, err
+	}
+
+	return &AdminUser{
+		ID:        adminUser.ID,
+		Name:      adminUser.Name,
+		Role:      adminUser.Role,
+		AppIds:    appIds,
+		CreatedAt: adminUser.CreatedAt,
+	}, nil
+}
+
+// owners and operators can spend and change the hub, so their sessions need a second factor
+func requiresAdminUserTotp(role string) bool {
+	return role == ADMIN_USER_ROLE_OWNER || role == ADMIN_USER_ROLE_OPERATOR
+}
+
+// resetAdminUserTotp generates a new two-factor secret and returns its otpauth URI,
+// which is only shown once
+func resetAdminUserTotp(adminUser *db.AdminUser) (string, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	adminUser.TotpSecret = secret
+	adminUser.TotpLastStep = 0
+	return totp.ProvisioningUri(secret, totpIssuer, adminUser.Name), nil
+}
+
+func hashAdminUserPassword(password string) (string, error) {
+	if len(password) < minAdminUserPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minAdminUserPasswordLength)
+	}
+	key, salt, err := config.DeriveKey(password, nil)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt) + "-" + hex.EncodeToString(key), nil
+}
+
+func checkAdminUserPassword(passwordHash string, password string) bool {
+	parts := strings.Split(passwordHash, "-")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	expectedKey, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	key, _, err := config.DeriveKey(password, salt)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, expectedKey) == 1
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/totp"
+)
+
+func TestAdminUsers(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := createExportTestAPI(t, svc)
+
+	otherApp, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	managedApp, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	_, err = theAPI.CreateAdminUser(&CreateAdminUserRequest{Name: "alice", Password: "short"})
+	assert.EqualError(t, err, "password must be at least 8 characters")
+
+	adminUser, err := theAPI.CreateAdminUser(&CreateAdminUserRequest{
+		Name:     "alice",
+		Password: "correct horse",
+		AppIds:   []uint{managedApp.ID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{managedApp.ID}, adminUser.AppIds)
+
+	assert.Equal(t, ADMIN_USER_ROLE_APP_MANAGER, adminUser.Role)
+
+	_, err = theAPI.CreateAdminUser(&CreateAdminUserRequest{Name: "alice", Password: "correct horse"})
+	assert.EqualError(t, err, "an admin user with this name already exists")
+
+	_, err = theAPI.CreateAdminUser(&CreateAdminUserRequest{Name: "bob", Password: "correct horse", Role: "root"})
+	assert.EqualError(t, err, "invalid admin user role: root")
+
+	operatorRole := ADMIN_USER_ROLE_OPERATOR
+	updatedAdminUser, err := theAPI.UpdateAdminUser(adminUser.ID, &UpdateAdminUserRequest{Role: &operatorRole})
+	require.NoError(t, err)
+	assert.Equal(t, ADMIN_USER_ROLE_OPERATOR, updatedAdminUser.Role)
+	appManagerRole := ADMIN_USER_ROLE_APP_MANAGER
+	_, err = theAPI.UpdateAdminUser(adminUser.ID, &UpdateAdminUserRequest{Role: &appManagerRole})
+	require.NoError(t, err)
+
+	adminUserId, ok := theAPI.CheckAdminUserPassword("alice", "correct horse")
+	assert.True(t, ok)
+	assert.Equal(t, adminUser.ID, adminUserId)
+	_, ok = theAPI.CheckAdminUserPassword("alice", "wrong password")
+	assert.False(t, ok)
+
+	assert.True(t, theAPI.CanAdminUserManageApp(adminUser.ID, managedApp.ID))
+	assert.False(t, theAPI.CanAdminUserManageApp(adminUser.ID, otherApp.ID))
+
+	_, err = theAPI.CreateAdminUserApp(adminUser.ID, &CreateAppRequest{
+		Name:   "Everything",
+		Scopes: []string{constants.SUPERUSER_SCOPE},
+	})
+	assert.EqualError(t, err, "admin users cannot create apps with the superuser scope")
+
+	createAppResponse, err := theAPI.CreateAdminUserApp(adminUser.ID, &CreateAppRequest{
+		Name:   "Kids wallet",
+		Scopes: []string{constants.PAY_INVOICE_SCOPE},
+	})
+	require.NoError(t, err)
+	assert.True(t, theAPI.CanAdminUserManageApp(adminUser.ID, createAppResponse.Id))
+
+	listAppsResponse, err := theAPI.ListApps(0, 0, ListAppsFilters{AdminUserId: &adminUser.ID}, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), listAppsResponse.TotalCount)
+
+	emptyAppIds := []uint{}
+	adminUser, err = theAPI.UpdateAdminUser(adminUser.ID, &UpdateAdminUserRequest{AppIds: &emptyAppIds})
+	require.NoError(t, err)
+	assert.Empty(t, adminUser.AppIds)
+	assert.False(t, theAPI.CanAdminUserManageApp(adminUser.ID, managedApp.ID))
+
+	// the apps of a deleted admin user are kept
+	require.NoError(t, theAPI.DeleteAdminUser(adminUser.ID))
+	_, err = theAPI.GetAdminUser(adminUser.ID)
+	assert.EqualError(t, err, "admin user not found")
+	assert.NotNil(t, svc.AppsService.GetAppById(createAppResponse.Id))
+}
+
+func TestAdminUsers_Totp(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := createExportTestAPI(t, svc)
+
+	viewer, err := theAPI.CreateAdminUser(&CreateAdminUserRequest{Name: "viewer", Password: "correct horse", Role: ADMIN_USER_ROLE_VIEWER})
+	require.NoError(t, err)
+	assert.Empty(t, viewer.TotpUri)
+	assert.NoError(t, theAPI.VerifyAdminUserTotp(viewer.ID, ""))
+
+	// promoting an admin user sets up two-factor authentication
+	operatorRole := ADMIN_USER_ROLE_OPERATOR
+	operator, err := theAPI.UpdateAdminUser(viewer.ID, &UpdateAdminUserRequest{Role: &operatorRole})
+	require.NoError(t, err)
+	require.NotEmpty(t, operator.TotpUri)
+	assert.EqualError(t, theAPI.VerifyAdminUserTotp(operator.ID, ""), "two-factor code required")
+	assert.EqualError(t, theAPI.VerifyAdminUserTotp(operator.ID, "000000x"), "invalid two-factor code")
+
+	code := func(totpUri string) string {
+		parsedUri, err := url.Parse(totpUri)
+		require.NoError(t, err)
+		code, err := totp.Code(parsedUri.Query().Get("secret"), totp.Step(time.Now()))
+		require.NoError(t, err)
+		return code
+	}
+	operatorCode := code(operator.TotpUri)
+	require.NoError(t, theAPI.VerifyAdminUserTotp(operator.ID, operatorCode))
+	assert.EqualError(t, theAPI.VerifyAdminUserTotp(operator.ID, operatorCode), "two-factor code was already used")
+
+	// further updates keep the secret unless it is reset
+	operator, err = theAPI.UpdateAdminUser(operator.ID, &UpdateAdminUserRequest{Role: &operatorRole})
+	require.NoError(t, err)
+	assert.Empty(t, operator.TotpUri)
+	operator, err = theAPI.UpdateAdminUser(operator.ID, &UpdateAdminUserRequest{ResetTotp: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, operator.TotpUri)
+	require.NoError(t, theAPI.VerifyAdminUserTotp(operator.ID, code(operator.TotpUri)))
+}
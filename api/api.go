@@ -15,52 +15,101 @@ import (
 	"sync"
 	"time"
 
+	"github.com/nbd-wtf/go-nostr/nip19"
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/autolock"
+	"github.com/getAlby/hub/backups"
+	"github.com/getAlby/hub/channelacceptance"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/db/queries"
 	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/feeadvisor"
+	"github.com/getAlby/hub/feeestimator"
+	"github.com/getAlby/hub/feepolicies"
+	"github.com/getAlby/hub/liquidityhistory"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/lsporders"
+	"github.com/getAlby/hub/maintenance"
 	permissions "github.com/getAlby/hub/nip47/permissions"
+	"github.com/getAlby/hub/notifications"
+	"github.com/getAlby/hub/recovery"
+	"github.com/getAlby/hub/scheduledpayments"
 	"github.com/getAlby/hub/service"
 	"github.com/getAlby/hub/service/keys"
+	"github.com/getAlby/hub/spendablebalance"
+	"github.com/getAlby/hub/subwallets"
+	"github.com/getAlby/hub/swaprules"
 	"github.com/getAlby/hub/swaps"
+	"github.com/getAlby/hub/taprootassets"
+	"github.com/getAlby/hub/tor"
+	"github.com/getAlby/hub/transactions"
 	"github.com/getAlby/hub/utils"
 	"github.com/getAlby/hub/version"
+	"github.com/getAlby/hub/webhooks"
 )
 
 type api struct {
-	db               *gorm.DB
-	appsSvc          apps.AppsService
-	cfg              config.Config
-	svc              service.Service
-	permissionsSvc   permissions.PermissionsService
-	keys             keys.Keys
-	albyOAuthSvc     alby.AlbyOAuthService
-	albySvc          alby.AlbyService
-	startupError     error
-	startupErrorTime time.Time
-	eventPublisher   events.EventPublisher
+	db                   *gorm.DB
+	appsSvc              apps.AppsService
+	cfg                  config.Config
+	svc                  service.Service
+	permissionsSvc       permissions.PermissionsService
+	keys                 keys.Keys
+	albyOAuthSvc         alby.AlbyOAuthService
+	albySvc              alby.AlbyService
+	startupError         error
+	startupErrorTime     time.Time
+	eventPublisher       events.EventPublisher
+	webhooksSvc          webhooks.WebhooksService
+	scheduledPaymentsSvc scheduledpayments.ScheduledPaymentsService
+	swapRulesSvc         swaprules.SwapRulesService
+	feePoliciesSvc       feepolicies.FeePoliciesService
+	liquidityHistorySvc  liquidityhistory.LiquidityHistoryService
+	tapdClient           taprootassets.TapdClient
+	lspOrdersSvc         lsporders.LSPOrdersService
+	subwalletsSvc        subwallets.SubwalletsService
+	backupsSvc           backups.BackupsService
+	maintenanceSvc       maintenance.MaintenanceService
+	recoverySvc          recovery.RecoveryService
 }
 
 func NewAPI(svc service.Service, gormDB *gorm.DB, config config.Config, keys keys.Keys, albySvc alby.AlbyService, albyOAuthSvc alby.AlbyOAuthService, eventPublisher events.EventPublisher) *api {
+	var tapdClient taprootassets.TapdClient
+	if client, err := taprootassets.NewTapdClient(config); err != nil {
+		logger.Logger.WithError(err).Error("Failed to configure tapd client")
+	} else if client != nil {
+		tapdClient = client
+	}
+
 	return &api{
-		db:             gormDB,
-		appsSvc:        apps.NewAppsService(gormDB, eventPublisher, keys, config),
-		cfg:            config,
-		svc:            svc,
-		permissionsSvc: permissions.NewPermissionsService(gormDB, eventPublisher),
-		keys:           keys,
-		albySvc:        albySvc,
-		albyOAuthSvc:   albyOAuthSvc,
-		eventPublisher: eventPublisher,
+		db:                   gormDB,
+		appsSvc:              apps.NewAppsService(gormDB, eventPublisher, keys, config),
+		cfg:                  config,
+		svc:                  svc,
+		permissionsSvc:       permissions.NewPermissionsService(gormDB, eventPublisher),
+		keys:                 keys,
+		albySvc:              albySvc,
+		albyOAuthSvc:         albyOAuthSvc,
+		eventPublisher:       eventPublisher,
+		webhooksSvc:          webhooks.NewWebhooksService(gormDB),
+		scheduledPaymentsSvc: scheduledpayments.NewScheduledPaymentsService(gormDB, eventPublisher),
+		swapRulesSvc:         swaprules.NewSwapRulesService(gormDB, config, eventPublisher),
+		feePoliciesSvc:       feepolicies.NewFeePoliciesService(gormDB, eventPublisher),
+		liquidityHistorySvc:  liquidityhistory.NewLiquidityHistoryService(gormDB),
+		tapdClient:           tapdClient,
+		lspOrdersSvc:         lsporders.NewLSPOrdersService(gormDB, config, eventPublisher, albyOAuthSvc),
+		subwalletsSvc:        subwallets.NewSubwalletsService(gormDB, config, eventPublisher),
+		backupsSvc:           backups.NewBackupsService(gormDB, config, eventPublisher),
+		maintenanceSvc:       maintenance.NewMaintenanceService(gormDB, config, eventPublisher),
+		recoverySvc:          recovery.NewRecoveryService(config, eventPublisher),
 	}
 }
 
@@ -76,17 +125,76 @@ func (api *api) CreateApp(createAppRequest *CreateAppRequest) (*CreateAppRespons
 		return nil, fmt.Errorf("Reserved app name: %s", alby.ALBY_ACCOUNT_APP_NAME)
 	}
 
+	if createAppRequest.Template != "" {
+		if err := applyAppTemplate(createAppRequest); err != nil {
+			return nil, err
+		}
+	}
+
 	expiresAt, err := api.parseExpiresAt(createAppRequest.ExpiresAt)
 	if err != nil {
 		return nil, fmt.Errorf("invalid expiresAt: %v", err)
 	}
 
+	if err := validateMaxFeePercent(createAppRequest.MaxFeePercent); err != nil {
+		return nil, err
+	}
+
+	budgetCurrency, err := validateBudgetCurrency(createAppRequest.BudgetCurrency, createAppRequest.MaxAmountFiat)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAppBudgets(createAppRequest.Budgets); err != nil {
+		return nil, err
+	}
+
+	if createAppRequest.WebhookUrl != "" {
+		if err := webhooks.ValidateWebhookUrl(createAppRequest.WebhookUrl); err != nil {
+			return nil, err
+		}
+	}
+
+	var nostrPubkey string
+	if createAppRequest.NostrPubkey != "" {
+		nostrPubkey, err = apps.ParseNostrPubkey(createAppRequest.NostrPubkey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	for _, scope := range createAppRequest.Scopes {
 		if !slices.Contains(permissions.AllScopes(), scope) {
 			return nil, fmt.Errorf("did not recognize requested scope: %s", scope)
 		}
 	}
 
+	if createAppRequest.ReadOnly {
+		if createAppRequest.Isolated {
+			return nil, errors.New("read-only app cannot be isolated")
+		}
+		if len(createAppRequest.Scopes) == 0 {
+			createAppRequest.Scopes = permissions.ReadOnlyScopes()
+		}
+		if err := validateReadOnlyScopes(createAppRequest.Scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	if createAppRequest.ReceiveOnly {
+		if createAppRequest.ReadOnly {
+			return nil, errors.New("receive-only app cannot be read-only")
+		}
+		// only the hub owner can move the balance of a receive-only app
+		createAppRequest.Isolated = true
+		if len(createAppRequest.Scopes) == 0 {
+			createAppRequest.Scopes = permissions.ReceiveOnlyScopes()
+		}
+		if err := validateReceiveOnlyScopes(createAppRequest.Scopes); err != nil {
+			return nil, err
+		}
+	}
+
 	app, pairingSecretKey, err := api.appsSvc.CreateApp(
 		createAppRequest.Name,
 		createAppRequest.Pubkey,
@@ -102,43 +210,171 @@ func (api *api) CreateApp(createAppRequest *CreateAppRequest) (*CreateAppRespons
 		return nil, err
 	}
 
-	relayUrls := api.cfg.GetRelayUrls()
+	if createAppRequest.ReadOnly {
+		app.ReadOnly = true
+		err = api.db.Model(app).Update("read_only", true).Error
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	lightningAddress, err := api.albyOAuthSvc.GetLightningAddress()
+	if createAppRequest.ReceiveOnly {
+		app.ReceiveOnly = true
+		err = api.db.Model(app).Update("receive_only", true).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if nostrPubkey != "" {
+		app.NostrPubkey = &nostrPubkey
+		err = api.db.Model(app).Update("nostr_pubkey", nostrPubkey).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if createAppRequest.SingleUse {
+		app.SingleUse = true
+		err = api.db.Model(app).Update("single_use", true).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if createAppRequest.MaxPaymentAmountSat != nil {
+		app.MaxPaymentAmountSat = createAppRequest.MaxPaymentAmountSat
+		err = api.db.Model(app).Update("max_payment_amount_sat", *createAppRequest.MaxPaymentAmountSat).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if budgetCurrency != "" {
+		err = api.db.Model(&db.AppPermission{}).Where("app_id", app.ID).Updates(map[string]interface{}{
+			"budget_currency": budgetCurrency,
+			"max_amount_fiat": createAppRequest.MaxAmountFiat,
+			"max_amount_sat":  0,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if createAppRequest.ApprovalThreshold != nil {
+		app.ApprovalThresholdSat = createAppRequest.ApprovalThreshold
+		err = api.db.Model(app).Update("approval_threshold_sat", *createAppRequest.ApprovalThreshold).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if createAppRequest.MaxPaymentsPerMinute != nil || createAppRequest.MaxPaymentsPerDay != nil {
+		app.MaxPaymentsPerMinute = createAppRequest.MaxPaymentsPerMinute
+		app.MaxPaymentsPerDay = createAppRequest.MaxPaymentsPerDay
+		err = api.db.Model(app).Updates(map[string]interface{}{
+			"max_payments_per_minute": createAppRequest.MaxPaymentsPerMinute,
+			"max_payments_per_day":    createAppRequest.MaxPaymentsPerDay,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if createAppRequest.AppGroupId != nil {
+		if err := setAppGroup(api.db, app.ID, createAppRequest.AppGroupId); err != nil {
+			return nil, err
+		}
+		app.AppGroupId = createAppRequest.AppGroupId
+	}
+
+	if len(createAppRequest.Budgets) > 0 {
+		err = setAppBudgets(api.db, app.ID, createAppRequest.Budgets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if createAppRequest.MaxFeePercent != nil || createAppRequest.MaxFeeFloorSat != nil {
+		app.MaxFeePercent = createAppRequest.MaxFeePercent
+		app.MaxFeeFloorSat = createAppRequest.MaxFeeFloorSat
+		err = api.db.Model(app).Updates(map[string]interface{}{
+			"max_fee_percent":   createAppRequest.MaxFeePercent,
+			"max_fee_floor_sat": createAppRequest.MaxFeeFloorSat,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	responseBody, err := api.newPairingResponse(app, pairingSecretKey)
 	if err != nil {
 		return nil, err
 	}
 
-	responseBody := &CreateAppResponse{}
-	responseBody.Id = app.ID
-	responseBody.Name = app.Name
-	responseBody.Pubkey = app.AppPubkey
-	responseBody.PairingSecret = pairingSecretKey
-	responseBody.WalletPubkey = *app.WalletPubkey
-	responseBody.RelayUrls = relayUrls
-	responseBody.Lud16 = lightningAddress
+	if createAppRequest.WebhookUrl != "" {
+		webhook, err := api.webhooksSvc.CreateAppWebhook(app.ID, createAppRequest.WebhookUrl, webhooks.GetAppWebhookEventTypes(), nil)
+		if err != nil {
+			return nil, err
+		}
+		responseBody.WebhookSecret = webhook.Secret
+	}
 
 	if createAppRequest.ReturnTo != "" {
 		returnToUrl, err := url.Parse(createAppRequest.ReturnTo)
 		if err == nil {
 			query := returnToUrl.Query()
-			for _, relayUrl := range relayUrls {
+			for _, relayUrl := range responseBody.RelayUrls {
 				query.Add("relay", relayUrl)
 			}
-			query.Add("pubkey", *app.WalletPubkey)
-			if lightningAddress != "" && !app.Isolated {
-				query.Add("lud16", lightningAddress)
+			query.Add("pubkey", responseBody.WalletPubkey)
+			if responseBody.Lud16 != "" && !app.Isolated && !app.SingleUse {
+				query.Add("lud16", responseBody.Lud16)
 			}
 			returnToUrl.RawQuery = query.Encode()
 			responseBody.ReturnTo = returnToUrl.String()
 		}
 	}
 
+	return responseBody, nil
+}
+
+// newPairingResponse returns the connection details for a new pairing secret of the app
+func (api *api) newPairingResponse(app *db.App, pairingSecretKey string) (*CreateAppResponse, error) {
+	relayUrls := api.cfg.GetRelayUrls()
+
+	lightningAddress, err := api.albyOAuthSvc.GetLightningAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	// apps created before wallet keys were derived per app share the main wallet key
+	var walletPubkey string
+	if app.WalletPubkey != nil {
+		walletPubkey = *app.WalletPubkey
+	} else {
+		walletPubkey = api.keys.GetNostrPublicKey()
+	}
+
+	if app.SingleUse && len(relayUrls) > 1 {
+		// keep the pairing URI compact enough for a small QR code
+		relayUrls = relayUrls[:1]
+	}
+
+	responseBody := &CreateAppResponse{}
+	responseBody.Id = app.ID
+	responseBody.Name = app.Name
+	responseBody.Pubkey = app.AppPubkey
+	responseBody.PairingSecret = pairingSecretKey
+	responseBody.WalletPubkey = walletPubkey
+	responseBody.RelayUrls = relayUrls
+	responseBody.Lud16 = lightningAddress
+
 	var lud16 string
-	if lightningAddress != "" && !app.Isolated {
+	if lightningAddress != "" && !app.Isolated && !app.SingleUse {
 		lud16 = fmt.Sprintf("&lud16=%s", lightningAddress)
 	}
-	responseBody.PairingUri = fmt.Sprintf("nostr+walletconnect://%s?relay=%s&secret=%s%s", *app.WalletPubkey, strings.Join(relayUrls, "&relay="), pairingSecretKey, lud16)
+	responseBody.PairingUri = fmt.Sprintf("nostr+walletconnect://%s?relay=%s&secret=%s%s", walletPubkey, strings.Join(relayUrls, "&relay="), pairingSecretKey, lud16)
 
 	return responseBody, nil
 }
@@ -166,6 +402,12 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 		// Update app isolation if provided and different
 		if updateAppRequest.Isolated != nil {
 			isolated := *updateAppRequest.Isolated
+			if isolated && userApp.ReadOnly {
+				return errors.New("read-only app cannot be isolated")
+			}
+			if !isolated && userApp.ReceiveOnly {
+				return errors.New("receive-only app must be isolated")
+			}
 			if isolated != userApp.Isolated {
 				if !isolated {
 					var existingMetadata Metadata
@@ -190,6 +432,73 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 			}
 		}
 
+		if updateAppRequest.MaxPaymentAmountSat != nil || updateAppRequest.UpdateMaxPaymentAmount {
+			err := tx.Model(&db.App{}).Where("id", userApp.ID).Update("max_payment_amount_sat", updateAppRequest.MaxPaymentAmountSat).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if updateAppRequest.MaxFeePercent != nil || updateAppRequest.MaxFeeFloorSat != nil || updateAppRequest.UpdateMaxFee {
+			if err := validateMaxFeePercent(updateAppRequest.MaxFeePercent); err != nil {
+				return err
+			}
+			err := tx.Model(&db.App{}).Where("id", userApp.ID).Updates(map[string]interface{}{
+				"max_fee_percent":   updateAppRequest.MaxFeePercent,
+				"max_fee_floor_sat": updateAppRequest.MaxFeeFloorSat,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if updateAppRequest.ApprovalThreshold != nil || updateAppRequest.UpdateApprovalThreshold {
+			err := tx.Model(&db.App{}).Where("id", userApp.ID).Update("approval_threshold_sat", updateAppRequest.ApprovalThreshold).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if updateAppRequest.MaxPaymentsPerMinute != nil || updateAppRequest.MaxPaymentsPerDay != nil || updateAppRequest.UpdatePaymentRateLimits {
+			err := tx.Model(&db.App{}).Where("id", userApp.ID).Updates(map[string]interface{}{
+				"max_payments_per_minute": updateAppRequest.MaxPaymentsPerMinute,
+				"max_payments_per_day":    updateAppRequest.MaxPaymentsPerDay,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if updateAppRequest.NostrPubkey != nil {
+			var nostrPubkey *string
+			if *updateAppRequest.NostrPubkey != "" {
+				parsedNostrPubkey, err := apps.ParseNostrPubkey(*updateAppRequest.NostrPubkey)
+				if err != nil {
+					return err
+				}
+				nostrPubkey = &parsedNostrPubkey
+			}
+			err := tx.Model(&db.App{}).Where("id", userApp.ID).Update("nostr_pubkey", nostrPubkey).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if updateAppRequest.AppGroupId != nil || updateAppRequest.UpdateAppGroup {
+			if err := setAppGroup(tx, userApp.ID, updateAppRequest.AppGroupId); err != nil {
+				return err
+			}
+		}
+
+		if updateAppRequest.Budgets != nil {
+			if err := validateAppBudgets(*updateAppRequest.Budgets); err != nil {
+				return err
+			}
+			if err := setAppBudgets(tx, userApp.ID, *updateAppRequest.Budgets); err != nil {
+				return err
+			}
+		}
+
 		// Update the app metadata if provided
 		if updateAppRequest.Metadata != nil {
 			var metadataBytes []byte
@@ -207,12 +516,15 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 
 		// Handle permissions updates only if any permission-related field is provided
 		if updateAppRequest.Scopes != nil || updateAppRequest.MaxAmountSat != nil ||
-			updateAppRequest.BudgetRenewal != nil || updateAppRequest.ExpiresAt != nil || updateAppRequest.UpdateExpiresAt {
+			updateAppRequest.BudgetRenewal != nil || updateAppRequest.ExpiresAt != nil || updateAppRequest.UpdateExpiresAt ||
+			updateAppRequest.BudgetCurrency != nil || updateAppRequest.MaxAmountFiat != nil {
 
 			// Get current values or use provided ones
 			var maxAmount uint64
 			var budgetRenewal string
 			var expiresAt *time.Time
+			var budgetCurrency string
+			var maxAmountFiat float64
 
 			// Get existing permissions to use as defaults
 			var existingPermissions []db.AppPermission
@@ -228,6 +540,8 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 						maxAmount = uint64(perm.MaxAmountSat)
 						budgetRenewal = perm.BudgetRenewal
 						expiresAt = perm.ExpiresAt
+						budgetCurrency = perm.BudgetCurrency
+						maxAmountFiat = perm.MaxAmountFiat
 						break
 					}
 				}
@@ -250,12 +564,29 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 			if updateAppRequest.ExpiresAt == nil && updateAppRequest.UpdateExpiresAt {
 				expiresAt = nil
 			}
+			if updateAppRequest.BudgetCurrency != nil {
+				budgetCurrency = *updateAppRequest.BudgetCurrency
+			}
+			if updateAppRequest.MaxAmountFiat != nil {
+				maxAmountFiat = *updateAppRequest.MaxAmountFiat
+			}
+			budgetCurrency, err := validateBudgetCurrency(budgetCurrency, maxAmountFiat)
+			if err != nil {
+				return err
+			}
+			if budgetCurrency != "" {
+				maxAmount = 0
+			} else {
+				maxAmountFiat = 0
+			}
 
 			// Update existing permissions with new budget and expiry
-			err := tx.Model(&db.AppPermission{}).Where("app_id", userApp.ID).Updates(map[string]interface{}{
-				"ExpiresAt":     expiresAt,
-				"MaxAmountSat":  maxAmount,
-				"BudgetRenewal": budgetRenewal,
+			err = tx.Model(&db.AppPermission{}).Where("app_id", userApp.ID).Updates(map[string]interface{}{
+				"ExpiresAt":      expiresAt,
+				"MaxAmountSat":   maxAmount,
+				"BudgetRenewal":  budgetRenewal,
+				"BudgetCurrency": budgetCurrency,
+				"MaxAmountFiat":  maxAmountFiat,
 			}).Error
 			if err != nil {
 				return err
@@ -268,6 +599,17 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 					return fmt.Errorf("won't update an app to have no request methods")
 				}
 
+				if userApp.ReadOnly {
+					if err := validateReadOnlyScopes(updateAppRequest.Scopes); err != nil {
+						return err
+					}
+				}
+				if userApp.ReceiveOnly {
+					if err := validateReceiveOnlyScopes(updateAppRequest.Scopes); err != nil {
+						return err
+					}
+				}
+
 				existingScopeMap := make(map[string]bool)
 				for _, perm := range existingPermissions {
 					existingScopeMap[perm.Scope] = true
@@ -281,15 +623,20 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 				for _, scope := range updateAppRequest.Scopes {
 					if !existingScopeMap[scope] {
 						perm := db.AppPermission{
-							App:           *userApp,
-							Scope:         scope,
-							ExpiresAt:     expiresAt,
-							MaxAmountSat:  int(maxAmount),
-							BudgetRenewal: budgetRenewal,
+							App:            *userApp,
+							Scope:          scope,
+							ExpiresAt:      expiresAt,
+							MaxAmountSat:   int(maxAmount),
+							BudgetRenewal:  budgetRenewal,
+							BudgetCurrency: budgetCurrency,
+							MaxAmountFiat:  maxAmountFiat,
 						}
 						if err := tx.Create(&perm).Error; err != nil {
 							return err
 						}
+						if err := apps.RecordAuditLog(tx, userApp.ID, apps.AUDIT_ACTION_SCOPE_ADDED, map[string]interface{}{"scope": scope}); err != nil {
+							return err
+						}
 					}
 					delete(existingScopeMap, scope)
 				}
@@ -299,6 +646,9 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 					if err := tx.Where("app_id = ? AND scope = ?", userApp.ID, scope).Delete(&db.AppPermission{}).Error; err != nil {
 						return err
 					}
+					if err := apps.RecordAuditLog(tx, userApp.ID, apps.AUDIT_ACTION_SCOPE_REMOVED, map[string]interface{}{"scope": scope}); err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -319,6 +669,40 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 	return err
 }
 
+func (api *api) RenewApp(userApp *db.App, renewAppRequest *RenewAppRequest) (*App, error) {
+	var expiresAt *time.Time
+	if renewAppRequest.ExpiresAt != "" {
+		var err error
+		expiresAt, err = api.parseExpiresAt(renewAppRequest.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiresAt: %v", err)
+		}
+	}
+
+	_, err := api.appsSvc.RenewApp(userApp, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	dbApp := api.appsSvc.GetAppById(userApp.ID)
+	if dbApp == nil {
+		return nil, errors.New("app not found")
+	}
+	return api.GetApp(dbApp), nil
+}
+
+func (api *api) RotateAppSecret(userApp *db.App) (*CreateAppResponse, error) {
+	if userApp.Name == alby.ALBY_ACCOUNT_APP_NAME {
+		return nil, errors.New("cannot rotate the secret of the Alby Account app")
+	}
+
+	pairingSecretKey, err := api.appsSvc.RotateAppSecret(userApp)
+	if err != nil {
+		return nil, err
+	}
+	return api.newPairingResponse(userApp, pairingSecretKey)
+}
+
 func (api *api) DeleteApp(userApp *db.App) error {
 	// Delete lightning address if one exists
 	if api.appsSvc.HasLightningAddress(userApp) {
@@ -446,28 +830,59 @@ func (api *api) GetApp(dbApp *db.App) *App {
 	}
 
 	response := App{
-		ID:                 dbApp.ID,
-		Name:               dbApp.Name,
-		Description:        dbApp.Description,
-		CreatedAt:          dbApp.CreatedAt,
-		UpdatedAt:          dbApp.UpdatedAt,
-		AppPubkey:          dbApp.AppPubkey,
-		ExpiresAt:          expiresAt,
-		MaxAmountSat:       maxAmount,
-		Scopes:             requestMethods,
-		BudgetUsage:        budgetUsage,
-		BudgetRenewal:      paySpecificPermission.BudgetRenewal,
-		Isolated:           dbApp.Isolated,
-		Metadata:           metadata,
-		WalletPubkey:       walletPubkey,
-		UniqueWalletPubkey: uniqueWalletPubkey,
-		LastUsedAt:         dbApp.LastUsedAt,
+		ID:                   dbApp.ID,
+		Name:                 dbApp.Name,
+		Description:          dbApp.Description,
+		CreatedAt:            dbApp.CreatedAt,
+		UpdatedAt:            dbApp.UpdatedAt,
+		AppPubkey:            dbApp.AppPubkey,
+		ExpiresAt:            expiresAt,
+		RenewedAt:            dbApp.RenewedAt,
+		MaxAmountSat:         maxAmount,
+		Scopes:               requestMethods,
+		BudgetUsage:          budgetUsage,
+		BudgetRenewal:        paySpecificPermission.BudgetRenewal,
+		Isolated:             dbApp.Isolated,
+		ReadOnly:             dbApp.ReadOnly,
+		ReceiveOnly:          dbApp.ReceiveOnly,
+		Paused:               dbApp.PausedAt != nil,
+		LightningAddress:     api.lightningAddress(dbApp),
+		SingleUse:            dbApp.SingleUse,
+		NostrPubkey:          dbApp.NostrPubkey,
+		AppGroupId:           dbApp.AppGroupId,
+		ApprovalThreshold:    dbApp.ApprovalThresholdSat,
+		MaxPaymentsPerMinute: dbApp.MaxPaymentsPerMinute,
+		MaxPaymentsPerDay:    dbApp.MaxPaymentsPerDay,
+		Metadata:             metadata,
+		WalletPubkey:         walletPubkey,
+		UniqueWalletPubkey:   uniqueWalletPubkey,
+		LastUsedAt:           dbApp.LastUsedAt,
+		FeeReserve:           queries.GetFeeReserveMsat(api.db, dbApp.ID),
+		MaxPaymentAmountSat:  dbApp.MaxPaymentAmountSat,
+		OwnerLogin:           dbApp.OwnerPasswordHash != "",
+		MaxFeePercent:        dbApp.MaxFeePercent,
+		MaxFeeFloorSat:       dbApp.MaxFeeFloorSat,
+		Budgets:              api.getAppBudgets(dbApp.ID),
 	}
 
 	if dbApp.Isolated {
 		response.Balance = queries.GetIsolatedBalance(api.db, dbApp.ID)
 	}
 
+	if dbApp.NostrPubkey != nil {
+		nostrProfiles, err := apps.GetNostrProfiles(api.db, []string{*dbApp.NostrPubkey})
+		if err != nil {
+			logger.Logger.WithError(err).WithField("app_id", dbApp.ID).Error("Failed to get nostr profile of app")
+		}
+		response.NostrProfile = toAppNostrProfile(nostrProfiles, *dbApp.NostrPubkey)
+	}
+
+	if paySpecificPermission.BudgetCurrency != "" {
+		response.BudgetCurrency = paySpecificPermission.BudgetCurrency
+		response.MaxAmountFiat = paySpecificPermission.MaxAmountFiat
+		response.BudgetUsageFiat = api.getBudgetUsageFiat(&paySpecificPermission)
+	}
+
 	return &response
 }
 
@@ -504,6 +919,10 @@ func (api *api) ListApps(limit uint64, offset uint64, filters ListAppsFilters, o
 		}
 	}
 
+	if filters.AdminUserId != nil {
+		query = query.Where("id IN (SELECT app_id FROM admin_user_apps WHERE admin_user_id = ?)", *filters.AdminUserId)
+	}
+
 	if orderBy == "" {
 		orderBy = "last_used_at"
 	}
@@ -549,6 +968,18 @@ func (api *api) ListApps(limit uint64, offset uint64, filters ListAppsFilters, o
 		permissionsMap[perm.AppId] = append(permissionsMap[perm.AppId], perm)
 	}
 
+	nostrPubkeys := []string{}
+	for _, dbApp := range dbApps {
+		if dbApp.NostrPubkey != nil {
+			nostrPubkeys = append(nostrPubkeys, *dbApp.NostrPubkey)
+		}
+	}
+	nostrProfiles, err := apps.GetNostrProfiles(api.db, nostrPubkeys)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list nostr profiles of apps")
+		return nil, err
+	}
+
 	apiApps := []App{}
 	for _, dbApp := range dbApps {
 		walletPubkey := api.keys.GetNostrPublicKey()
@@ -558,22 +989,43 @@ func (api *api) ListApps(limit uint64, offset uint64, filters ListAppsFilters, o
 			uniqueWalletPubkey = true
 		}
 		apiApp := App{
-			ID:                 dbApp.ID,
-			Name:               dbApp.Name,
-			Description:        dbApp.Description,
-			CreatedAt:          dbApp.CreatedAt,
-			UpdatedAt:          dbApp.UpdatedAt,
-			AppPubkey:          dbApp.AppPubkey,
-			Isolated:           dbApp.Isolated,
-			WalletPubkey:       walletPubkey,
-			UniqueWalletPubkey: uniqueWalletPubkey,
-			LastUsedAt:         dbApp.LastUsedAt,
+			ID:                   dbApp.ID,
+			Name:                 dbApp.Name,
+			Description:          dbApp.Description,
+			CreatedAt:            dbApp.CreatedAt,
+			UpdatedAt:            dbApp.UpdatedAt,
+			AppPubkey:            dbApp.AppPubkey,
+			Isolated:             dbApp.Isolated,
+			ReadOnly:             dbApp.ReadOnly,
+			ReceiveOnly:          dbApp.ReceiveOnly,
+			Paused:               dbApp.PausedAt != nil,
+			LightningAddress:     api.lightningAddress(&dbApp),
+			SingleUse:            dbApp.SingleUse,
+			NostrPubkey:          dbApp.NostrPubkey,
+			AppGroupId:           dbApp.AppGroupId,
+			ApprovalThreshold:    dbApp.ApprovalThresholdSat,
+			MaxPaymentsPerMinute: dbApp.MaxPaymentsPerMinute,
+			MaxPaymentsPerDay:    dbApp.MaxPaymentsPerDay,
+			WalletPubkey:         walletPubkey,
+			UniqueWalletPubkey:   uniqueWalletPubkey,
+			LastUsedAt:           dbApp.LastUsedAt,
+			RenewedAt:            dbApp.RenewedAt,
+			FeeReserve:           queries.GetFeeReserveMsat(api.db, dbApp.ID),
+			MaxPaymentAmountSat:  dbApp.MaxPaymentAmountSat,
+			OwnerLogin:           dbApp.OwnerPasswordHash != "",
+			MaxFeePercent:        dbApp.MaxFeePercent,
+			MaxFeeFloorSat:       dbApp.MaxFeeFloorSat,
+			Budgets:              api.getAppBudgets(dbApp.ID),
 		}
 
 		if dbApp.Isolated {
 			apiApp.Balance = queries.GetIsolatedBalance(api.db, dbApp.ID)
 		}
 
+		if dbApp.NostrPubkey != nil {
+			apiApp.NostrProfile = toAppNostrProfile(nostrProfiles, *dbApp.NostrPubkey)
+		}
+
 		for _, appPermission := range permissionsMap[dbApp.ID] {
 			apiApp.Scopes = append(apiApp.Scopes, appPermission.Scope)
 			apiApp.ExpiresAt = appPermission.ExpiresAt
@@ -581,6 +1033,11 @@ func (api *api) ListApps(limit uint64, offset uint64, filters ListAppsFilters, o
 				apiApp.BudgetRenewal = appPermission.BudgetRenewal
 				apiApp.MaxAmountSat = uint64(appPermission.MaxAmountSat)
 				apiApp.BudgetUsage = queries.GetBudgetUsageSat(api.db, &appPermission)
+				if appPermission.BudgetCurrency != "" {
+					apiApp.BudgetCurrency = appPermission.BudgetCurrency
+					apiApp.MaxAmountFiat = appPermission.MaxAmountFiat
+					apiApp.BudgetUsageFiat = api.getBudgetUsageFiat(&appPermission)
+				}
 			}
 		}
 
@@ -894,6 +1351,10 @@ func (api *api) InitiateSwapOut(ctx context.Context, initiateSwapOutRequest *Ini
 		return nil, errors.New("invalid swap amount")
 	}
 
+	if err := feeadvisor.CheckDelay(ctx, api.cfg); err != nil {
+		return nil, err
+	}
+
 	swapOutResponse, err := api.svc.GetSwapsService().SwapOut(amount, destination, false, false)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
@@ -922,6 +1383,10 @@ func (api *api) InitiateSwapIn(ctx context.Context, initiateSwapInRequest *Initi
 		return nil, errors.New("invalid swap amount")
 	}
 
+	if err := feeadvisor.CheckDelay(ctx, api.cfg); err != nil {
+		return nil, err
+	}
+
 	swapInResponse, err := api.svc.GetSwapsService().SwapIn(amount, false)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
@@ -982,7 +1447,12 @@ func (api *api) GetNodeStatus(ctx context.Context) (*lnclient.NodeStatus, error)
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
 	}
-	return api.svc.GetLNClient().GetNodeStatus(ctx)
+	nodeStatus, err := api.svc.GetLNClient().GetNodeStatus(ctx)
+	if err != nil || nodeStatus == nil {
+		return nodeStatus, err
+	}
+	nodeStatus.LastChannelBackupAt = backups.GetLastChannelBackupAt(api.cfg)
+	return nodeStatus, nil
 }
 
 func (api *api) ListPeers(ctx context.Context) ([]lnclient.PeerDetails, error) {
@@ -1003,6 +1473,15 @@ func (api *api) OpenChannel(ctx context.Context, openChannelRequest *OpenChannel
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
 	}
+	if err := transactions.CheckSpendingAllowed(api.db); err != nil {
+		return nil, err
+	}
+	if err := feeadvisor.CheckDelay(ctx, api.cfg); err != nil {
+		return nil, err
+	}
+	if openChannelRequest.FeeRate == nil {
+		openChannelRequest.FeeRate = api.estimateFeeRate(ctx)
+	}
 	return api.svc.GetLNClient().OpenChannel(ctx, openChannelRequest)
 }
 
@@ -1020,6 +1499,9 @@ func (api *api) CloseChannel(ctx context.Context, peerId, channelId string, forc
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
 	}
+	if err := transactions.CheckSpendingAllowed(api.db); err != nil {
+		return nil, err
+	}
 	logger.Logger.WithFields(logrus.Fields{
 		"peer_id":    peerId,
 		"channel_id": channelId,
@@ -1128,6 +1610,15 @@ func (api *api) RedeemOnchainFunds(ctx context.Context, toAddress string, amount
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
 	}
+	if err := transactions.CheckSpendingAllowed(api.db); err != nil {
+		return nil, err
+	}
+	if err := feeadvisor.CheckDelay(ctx, api.cfg); err != nil {
+		return nil, err
+	}
+	if feeRate == nil {
+		feeRate = api.estimateFeeRate(ctx)
+	}
 	txId, err := api.svc.GetLNClient().RedeemOnchainFunds(ctx, toAddress, amount, feeRate, sendAll)
 	if err != nil {
 		return nil, err
@@ -1137,6 +1628,17 @@ func (api *api) RedeemOnchainFunds(ctx context.Context, toAddress string, amount
 	}, nil
 }
 
+// estimateFeeRate returns the fee rate for on-chain transactions without a fee rate set by the
+// user, or nil to use the default of the backend if it cannot be estimated
+func (api *api) estimateFeeRate(ctx context.Context) *uint64 {
+	feeRate, err := feeestimator.GetFeeRate(ctx, api.cfg, feeestimator.TARGET_HALF_HOUR)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to estimate fee rate, using the default of the backend")
+		return nil
+	}
+	return &feeRate
+}
+
 func (api *api) GetBalances(ctx context.Context) (*BalancesResponse, error) {
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
@@ -1145,7 +1647,30 @@ func (api *api) GetBalances(ctx context.Context) (*BalancesResponse, error) {
 	if err != nil {
 		return nil, err
 	}
-	return balances, nil
+
+	balancesResponse := &BalancesResponse{
+		BalancesResponse: *balances,
+		Spendable:        spendablebalance.GetSpendableBalance(api.cfg, balances),
+	}
+	if tapdClient := api.getTapdClient(); tapdClient != nil {
+		// the LN balances are still returned if tapd is unavailable
+		assets, err := tapdClient.GetBalances(ctx)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to get Taproot Asset balances")
+		} else {
+			balancesResponse.Assets = assets
+		}
+	}
+	return balancesResponse, nil
+}
+
+// getTapdClient returns the tapd client if tapd is configured for an LND backend
+func (api *api) getTapdClient() taprootassets.TapdClient {
+	backendType, _ := api.cfg.Get("LNBackendType", "")
+	if api.tapdClient == nil || backendType != config.LNDBackendType {
+		return nil
+	}
+	return api.tapdClient
 }
 
 // TODO: remove dependency on this endpoint
@@ -1200,7 +1725,44 @@ func (api *api) GetInfo(ctx context.Context) (*InfoResponse, error) {
 	autoUnlockPassword, _ := api.cfg.Get("AutoUnlockPassword", "")
 	info.SetupCompleted = api.cfg.SetupCompleted()
 	info.Currency = api.cfg.GetCurrency()
+	info.FiatCurrencies = config.GetFiatCurrencies(api.cfg)
 	info.BitcoinDisplayFormat = api.cfg.GetBitcoinDisplayFormat()
+	maxPaymentAmountSat, _ := api.cfg.Get(config.MaxPaymentAmountSatKey, "")
+	info.MaxPaymentAmountSat, _ = strconv.ParseUint(maxPaymentAmountSat, 10, 64)
+	info.MetadataMaxLength = constants.INVOICE_METADATA_MAX_LENGTH
+	if metadataMaxLength, _ := api.cfg.Get(config.MetadataMaxLengthKey, ""); metadataMaxLength != "" && metadataMaxLength != "0" {
+		info.MetadataMaxLength, _ = strconv.Atoi(metadataMaxLength)
+	}
+	if transactionRetentionMonths, _ := api.cfg.Get(config.TransactionRetentionMonthsKey, ""); transactionRetentionMonths != "" {
+		retentionMonths, _ := strconv.ParseUint(transactionRetentionMonths, 10, 32)
+		info.TransactionRetentionMonths = uint(retentionMonths)
+	}
+	info.AppActivityRetentionDays = apps.GetAppActivityRetentionDays(api.cfg)
+	info.RequestEventRetentionDays = maintenance.GetRequestEventRetentionDays(api.cfg)
+	info.AuditLogRetentionDays = maintenance.GetAuditLogRetentionDays(api.cfg)
+	info.WebhookDeliveryRetentionDays = maintenance.GetWebhookDeliveryRetentionDays(api.cfg)
+	info.BudgetAlertThresholds = transactions.GetBudgetAlertThresholds(api.db)
+	info.MetadataPolicy = constants.METADATA_POLICY_REJECT
+	if metadataPolicy, _ := api.cfg.Get(config.MetadataPolicyKey, ""); metadataPolicy != "" {
+		info.MetadataPolicy = metadataPolicy
+	}
+	info.AdminAllowedNetworks, _ = api.cfg.Get(config.AdminAllowedNetworksKey, "")
+	readOnlyMode, _ := api.cfg.Get(config.ReadOnlyModeKey, "")
+	info.ReadOnlyMode = readOnlyMode == "true"
+	nostrBackup, _ := api.cfg.Get(config.NostrBackupEnabledKey, "")
+	info.NostrBackup = nostrBackup == "true"
+	nostrNotificationsPubkey, _ := api.cfg.Get(config.NostrNotificationsPubkeyKey, "")
+	if nostrNotificationsPubkey != "" {
+		info.NostrNotificationsNpub, _ = nip19.EncodePublicKey(nostrNotificationsPubkey)
+	}
+	info.LowBalanceAlertSat = config.GetUintSetting(api.cfg, config.LowBalanceAlertThresholdKey, 0)
+	info.LowInboundLiquidityAlertSat = config.GetUintSetting(api.cfg, config.LowInboundLiquidityAlertThresholdKey, 0)
+	info.OnchainFeeCeiling = config.GetUintSetting(api.cfg, config.OnchainFeeCeilingKey, 0)
+	onchainFeeCeilingDelay, _ := api.cfg.Get(config.OnchainFeeCeilingDelayKey, "")
+	info.OnchainFeeCeilingDelay = onchainFeeCeilingDelay == "true"
+	info.ReadOnlyWindows = transactions.GetReadOnlyWindows(api.db)
+	info.ChannelAcceptancePolicy = channelacceptance.GetPolicy(api.cfg)
+	info.SpendableBalanceBuckets = spendablebalance.GetBuckets(api.cfg)
 	info.StartupState = api.svc.GetStartupState()
 	if api.startupError != nil {
 		info.StartupError = api.startupError.Error()
@@ -1216,6 +1778,18 @@ func (api *api) GetInfo(ctx context.Context) (*InfoResponse, error) {
 	info.VssSupported = backendType == config.LDKBackendType && api.cfg.GetEnv().LDKVssUrl != ""
 	info.AutoUnlockPasswordEnabled = autoUnlockPassword != ""
 	info.AutoUnlockPasswordSupported = api.cfg.GetEnv().IsDefaultClientId()
+	info.TotpEnabled = api.IsTotpEnabled()
+	info.SeedPassphraseEnabled = api.IsSeedPassphraseEnabled()
+	info.PasskeysRegistered = api.hasPasskeys()
+	info.SpendingLocked = autolock.IsLocked()
+	info.ReadOnly = transactions.IsReadOnly(api.db, time.Now())
+	info.WatchOnly = transactions.IsWatchOnly()
+	info.OnionUrl = tor.GetOnionUrl(api.cfg)
+	info.LogLevel = uint(logger.Logger.GetLevel())
+	info.RateLimitIpPerMinute = config.GetUintSetting(api.cfg, config.RateLimitIpPerMinuteKey, api.cfg.GetEnv().RateLimitIpPerMinute)
+	info.RateLimitApiKeyPerMinute = config.GetUintSetting(api.cfg, config.RateLimitApiKeyPerMinuteKey, api.cfg.GetEnv().RateLimitApiKeyPerMinute)
+	info.RateLimitSessionPerMinute = config.GetUintSetting(api.cfg, config.RateLimitSessionPerMinuteKey, api.cfg.GetEnv().RateLimitSessionPerMinute)
+	info.BannedIps = config.GetStringSetting(api.cfg, config.BannedIpsKey, api.cfg.GetEnv().BannedIps)
 	info.Relays = []InfoResponseRelay{}
 	for _, relayStatus := range api.svc.GetRelayStatuses() {
 		info.Relays = append(info.Relays, InfoResponseRelay{
@@ -1287,6 +1861,21 @@ func (api *api) UpdateSettings(updateSettingsRequest *UpdateSettingsRequest) err
 		}
 	}
 
+	if updateSettingsRequest.FiatCurrencies != nil {
+		currencies := []string{}
+		for _, currency := range *updateSettingsRequest.FiatCurrencies {
+			currency = strings.ToUpper(strings.TrimSpace(currency))
+			if len(currency) != 3 {
+				return fmt.Errorf("invalid currency code: %q", currency)
+			}
+			currencies = append(currencies, currency)
+		}
+		err := api.cfg.SetUpdate(config.FiatCurrenciesKey, strings.Join(currencies, ","), "")
+		if err != nil {
+			return fmt.Errorf("failed to set fiat currencies: %w", err)
+		}
+	}
+
 	if updateSettingsRequest.BitcoinDisplayFormat != "" {
 		err := api.SetBitcoinDisplayFormat(updateSettingsRequest.BitcoinDisplayFormat)
 		if err != nil {
@@ -1294,6 +1883,277 @@ func (api *api) UpdateSettings(updateSettingsRequest *UpdateSettingsRequest) err
 		}
 	}
 
+	if updateSettingsRequest.MaxPaymentAmountSat != nil {
+		err := api.cfg.SetUpdate(config.MaxPaymentAmountSatKey, strconv.FormatUint(*updateSettingsRequest.MaxPaymentAmountSat, 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set maximum payment amount: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.MetadataMaxLength != nil {
+		err := api.cfg.SetUpdate(config.MetadataMaxLengthKey, strconv.FormatUint(*updateSettingsRequest.MetadataMaxLength, 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set metadata max length: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.MetadataPolicy != "" {
+		if updateSettingsRequest.MetadataPolicy != constants.METADATA_POLICY_REJECT && updateSettingsRequest.MetadataPolicy != constants.METADATA_POLICY_TRUNCATE {
+			return fmt.Errorf("invalid metadata policy. Must be one of %s,%s", constants.METADATA_POLICY_REJECT, constants.METADATA_POLICY_TRUNCATE)
+		}
+		err := api.cfg.SetUpdate(config.MetadataPolicyKey, updateSettingsRequest.MetadataPolicy, "")
+		if err != nil {
+			return fmt.Errorf("failed to set metadata policy: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.TransactionRetentionMonths != nil {
+		retentionMonths := *updateSettingsRequest.TransactionRetentionMonths
+		if retentionMonths != 0 && retentionMonths < transactions.MinArchiveRetentionMonths {
+			return fmt.Errorf("transaction retention period must be at least %d months", transactions.MinArchiveRetentionMonths)
+		}
+		err := api.cfg.SetUpdate(config.TransactionRetentionMonthsKey, strconv.FormatUint(uint64(retentionMonths), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set transaction retention period: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.AppActivityRetentionDays != nil {
+		err := api.cfg.SetUpdate(config.AppActivityRetentionDaysKey, strconv.FormatUint(uint64(*updateSettingsRequest.AppActivityRetentionDays), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set app activity retention period: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.RequestEventRetentionDays != nil {
+		retentionDays := *updateSettingsRequest.RequestEventRetentionDays
+		if retentionDays != 0 && retentionDays < maintenance.MinRequestEventRetentionDays {
+			return fmt.Errorf("request event retention period must be at least %d days", maintenance.MinRequestEventRetentionDays)
+		}
+		err := api.cfg.SetUpdate(config.RequestEventRetentionDaysKey, strconv.FormatUint(uint64(retentionDays), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set request event retention period: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.AuditLogRetentionDays != nil {
+		err := api.cfg.SetUpdate(config.AuditLogRetentionDaysKey, strconv.FormatUint(uint64(*updateSettingsRequest.AuditLogRetentionDays), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set audit log retention period: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.WebhookDeliveryRetentionDays != nil {
+		err := api.cfg.SetUpdate(config.WebhookDeliveryRetentionDaysKey, strconv.FormatUint(uint64(*updateSettingsRequest.WebhookDeliveryRetentionDays), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set webhook delivery retention period: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.BudgetAlertThresholds != nil {
+		thresholds, err := transactions.NormalizeBudgetAlertThresholds(*updateSettingsRequest.BudgetAlertThresholds)
+		if err != nil {
+			return err
+		}
+		err = api.cfg.SetUpdate(config.BudgetAlertThresholdsKey, transactions.FormatBudgetAlertThresholds(thresholds), "")
+		if err != nil {
+			return fmt.Errorf("failed to set budget alert thresholds: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.AdminAllowedNetworks != nil {
+		allowedNetworks := []string{}
+		for _, network := range strings.Split(*updateSettingsRequest.AdminAllowedNetworks, ",") {
+			network = strings.ToLower(strings.TrimSpace(network))
+			if network == "" {
+				continue
+			}
+			if _, err := utils.ParseNetwork(network); err != nil {
+				return err
+			}
+			allowedNetworks = append(allowedNetworks, network)
+		}
+		err := api.cfg.SetUpdate(config.AdminAllowedNetworksKey, strings.Join(allowedNetworks, ","), "")
+		if err != nil {
+			return fmt.Errorf("failed to set admin allowed networks: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.ReadOnlyMode != nil {
+		err := api.cfg.SetUpdate(config.ReadOnlyModeKey, strconv.FormatBool(*updateSettingsRequest.ReadOnlyMode), "")
+		if err != nil {
+			return fmt.Errorf("failed to set read-only mode: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.ReadOnlyWindows != nil {
+		if err := transactions.ValidateReadOnlyWindows(*updateSettingsRequest.ReadOnlyWindows); err != nil {
+			return err
+		}
+		windows, err := json.Marshal(*updateSettingsRequest.ReadOnlyWindows)
+		if err != nil {
+			return err
+		}
+		err = api.cfg.SetUpdate(config.ReadOnlyWindowsKey, string(windows), "")
+		if err != nil {
+			return fmt.Errorf("failed to set read-only windows: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.NostrBackup != nil {
+		err := api.cfg.SetUpdate(config.NostrBackupEnabledKey, strconv.FormatBool(*updateSettingsRequest.NostrBackup), "")
+		if err != nil {
+			return fmt.Errorf("failed to set nostr backup: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.NostrNotificationsNpub != nil {
+		var pubkey string
+		if npub := strings.TrimSpace(*updateSettingsRequest.NostrNotificationsNpub); npub != "" {
+			var err error
+			pubkey, err = notifications.ParseNostrPubkey(npub)
+			if err != nil {
+				return err
+			}
+		}
+		err := api.cfg.SetUpdate(config.NostrNotificationsPubkeyKey, pubkey, "")
+		if err != nil {
+			return fmt.Errorf("failed to set nostr notifications npub: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.Relays != nil {
+		relayUrls := []string{}
+		for _, relayUrl := range *updateSettingsRequest.Relays {
+			relayUrl = strings.TrimSpace(relayUrl)
+			if relayUrl == "" {
+				continue
+			}
+			parsedUrl, err := url.Parse(relayUrl)
+			if err != nil || (parsedUrl.Scheme != "wss" && parsedUrl.Scheme != "ws") || parsedUrl.Host == "" {
+				return fmt.Errorf("invalid relay url: %q", relayUrl)
+			}
+			relayUrls = append(relayUrls, relayUrl)
+		}
+		err := api.cfg.SetUpdate(config.RelaysKey, strings.Join(relayUrls, ","), "")
+		if err != nil {
+			return fmt.Errorf("failed to set relays: %w", err)
+		}
+		// resubscribes to requests of all apps on the new relays
+		api.eventPublisher.Publish(&events.Event{
+			Event: "nwc_relays_updated",
+			Properties: map[string]interface{}{
+				"relays": api.cfg.GetRelayUrls(),
+			},
+		})
+	}
+
+	if updateSettingsRequest.LogLevel != nil {
+		if *updateSettingsRequest.LogLevel > uint(logrus.TraceLevel) {
+			return fmt.Errorf("log level must be between 0 and %d", logrus.TraceLevel)
+		}
+		err := api.cfg.SetUpdate(config.LogLevelKey, strconv.FormatUint(uint64(*updateSettingsRequest.LogLevel), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set log level: %w", err)
+		}
+		logger.SetLevel(logrus.Level(*updateSettingsRequest.LogLevel))
+	}
+
+	rateLimits := []struct {
+		key   string
+		value *uint
+	}{
+		{config.RateLimitIpPerMinuteKey, updateSettingsRequest.RateLimitIpPerMinute},
+		{config.RateLimitApiKeyPerMinuteKey, updateSettingsRequest.RateLimitApiKeyPerMinute},
+		{config.RateLimitSessionPerMinuteKey, updateSettingsRequest.RateLimitSessionPerMinute},
+	}
+	for _, rateLimit := range rateLimits {
+		if rateLimit.value == nil {
+			continue
+		}
+		err := api.cfg.SetUpdate(rateLimit.key, strconv.FormatUint(uint64(*rateLimit.value), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set rate limit: %w", err)
+		}
+	}
+
+	alertThresholds := []struct {
+		key   string
+		value *uint
+	}{
+		{config.LowBalanceAlertThresholdKey, updateSettingsRequest.LowBalanceAlertSat},
+		{config.LowInboundLiquidityAlertThresholdKey, updateSettingsRequest.LowInboundLiquidityAlertSat},
+	}
+	for _, alertThreshold := range alertThresholds {
+		if alertThreshold.value == nil {
+			continue
+		}
+		err := api.cfg.SetUpdate(alertThreshold.key, strconv.FormatUint(uint64(*alertThreshold.value), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set alert threshold: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.OnchainFeeCeiling != nil {
+		err := api.cfg.SetUpdate(config.OnchainFeeCeilingKey, strconv.FormatUint(uint64(*updateSettingsRequest.OnchainFeeCeiling), 10), "")
+		if err != nil {
+			return fmt.Errorf("failed to set on-chain fee ceiling: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.OnchainFeeCeilingDelay != nil {
+		err := api.cfg.SetUpdate(config.OnchainFeeCeilingDelayKey, strconv.FormatBool(*updateSettingsRequest.OnchainFeeCeilingDelay), "")
+		if err != nil {
+			return fmt.Errorf("failed to set on-chain fee ceiling delay: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.ChannelAcceptancePolicy != nil {
+		if err := channelacceptance.ValidatePolicy(updateSettingsRequest.ChannelAcceptancePolicy); err != nil {
+			return err
+		}
+		policy, err := json.Marshal(updateSettingsRequest.ChannelAcceptancePolicy)
+		if err != nil {
+			return err
+		}
+		err = api.cfg.SetUpdate(config.ChannelAcceptancePolicyKey, string(policy), "")
+		if err != nil {
+			return fmt.Errorf("failed to set channel acceptance policy: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.SpendableBalanceBuckets != nil {
+		buckets, err := spendablebalance.ParseBuckets(strings.Join(*updateSettingsRequest.SpendableBalanceBuckets, ","))
+		if err != nil {
+			return err
+		}
+		if len(buckets) == 0 {
+			return errors.New("at least one spendable balance bucket is required")
+		}
+		err = api.cfg.SetUpdate(config.SpendableBalanceBucketsKey, strings.Join(buckets, ","), "")
+		if err != nil {
+			return fmt.Errorf("failed to set spendable balance buckets: %w", err)
+		}
+	}
+
+	if updateSettingsRequest.BannedIps != nil {
+		bannedIps := []string{}
+		for _, bannedIp := range strings.Split(*updateSettingsRequest.BannedIps, ",") {
+			bannedIp = strings.TrimSpace(bannedIp)
+			if bannedIp == "" {
+				continue
+			}
+			if _, err := utils.ParseNetwork(bannedIp); err != nil {
+				return err
+			}
+			bannedIps = append(bannedIps, bannedIp)
+		}
+		err := api.cfg.SetUpdate(config.BannedIpsKey, strings.Join(bannedIps, ","), "")
+		if err != nil {
+			return fmt.Errorf("failed to set banned IPs: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -1307,11 +2167,22 @@ func (api *api) SetNodeAlias(nodeAlias string) error {
 	return nil
 }
 
-func (api *api) GetMnemonic(unlockPassword string) (*MnemonicResponse, error) {
+func (api *api) GetMnemonic(unlockPassword string, seedPassphrase string) (*MnemonicResponse, error) {
 	if !api.cfg.CheckUnlockPassword(unlockPassword) {
 		return nil, fmt.Errorf("wrong password")
 	}
 
+	// once a seed passphrase is set the unlock password alone does not reveal the recovery phrase
+	if api.IsSeedPassphraseEnabled() {
+		mnemonic, err := api.getMnemonicWithSeedPassphrase(seedPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		return &MnemonicResponse{
+			Mnemonic: mnemonic,
+		}, nil
+	}
+
 	mnemonic, err := api.cfg.Get("Mnemonic", unlockPassword)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch encryption key: %w", err)
@@ -1751,6 +2622,57 @@ func (api *api) parseExpiresAt(expiresAtString string) (*time.Time, error) {
 	return expiresAt, nil
 }
 
+// returns the normalized currency, or an empty string for a budget in sats
+func validateBudgetCurrency(budgetCurrency string, maxAmountFiat float64) (string, error) {
+	budgetCurrency = strings.ToUpper(strings.TrimSpace(budgetCurrency))
+	if budgetCurrency == "" {
+		return "", nil
+	}
+	if len(budgetCurrency) != 3 {
+		return "", fmt.Errorf("invalid budgetCurrency: %s", budgetCurrency)
+	}
+	if maxAmountFiat < 0 {
+		return "", fmt.Errorf("invalid maxAmountFiat: %v", maxAmountFiat)
+	}
+	return budgetCurrency, nil
+}
+
+// returns nil if the usage cannot be converted at the current rate
+func (api *api) getBudgetUsageFiat(appPermission *db.AppPermission) *float64 {
+	rate, err := transactions.GetCurrentFiatRate(context.Background(), appPermission.BudgetCurrency)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("currency", appPermission.BudgetCurrency).Warn("Failed to get rate for fiat budget")
+		return nil
+	}
+	budgetUsageFiat := queries.GetBudgetUsageFiat(api.db, appPermission, rate)
+	return &budgetUsageFiat
+}
+
+func validateReadOnlyScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(permissions.ReadOnlyScopes(), scope) {
+			return fmt.Errorf("read-only app cannot have the %s scope", scope)
+		}
+	}
+	return nil
+}
+
+func validateReceiveOnlyScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(permissions.ReceiveOnlyScopes(), scope) {
+			return fmt.Errorf("receive-only app cannot have the %s scope", scope)
+		}
+	}
+	return nil
+}
+
+func validateMaxFeePercent(maxFeePercent *float64) error {
+	if maxFeePercent != nil && (*maxFeePercent < 0 || *maxFeePercent > 100) {
+		return fmt.Errorf("invalid maxFeePercent: %v", *maxFeePercent)
+	}
+	return nil
+}
+
 func (api *api) GetForwards() (*GetForwardsResponse, error) {
 	var forwards []db.Forward
 	err := api.db.Find(&forwards).Error
@@ -1774,3 +2696,17 @@ func (api *api) GetForwards() (*GetForwardsResponse, error) {
 		NumForwards:                 uint64(numForwards),
 	}, nil
 }
+
+// toAppNostrProfile returns nil until a profile was fetched for the pubkey
+func toAppNostrProfile(nostrProfiles map[string]db.NostrProfile, pubkey string) *AppNostrProfile {
+	nostrProfile, ok := nostrProfiles[pubkey]
+	if !ok {
+		return nil
+	}
+	return &AppNostrProfile{
+		Name:      nostrProfile.Name,
+		Picture:   nostrProfile.Picture,
+		About:     nostrProfile.About,
+		FetchedAt: nostrProfile.FetchedAt,
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/tor"
+)
+
+const (
+	apiKeyPrefix       = "hub_"
+	apiKeyPrefixLength = 12
+)
+
+var apiKeyScopes = []string{
+	API_KEY_SCOPE_INVOICES,
+	API_KEY_SCOPE_PAYMENTS,
+	API_KEY_SCOPE_TRANSACTIONS,
+	API_KEY_SCOPE_APPS,
+}
+
+func (api *api) ListApiKeys() ([]ApiKey, error) {
+	var dbApiKeys []db.ApiKey
+	if err := api.db.Order("id").Find(&dbApiKeys).Error; err != nil {
+		return nil, err
+	}
+
+	apiKeys := []ApiKey{}
+	for _, dbApiKey := range dbApiKeys {
+		apiKeys = append(apiKeys, *toApiApiKey(&dbApiKey))
+	}
+	return apiKeys, nil
+}
+
+func (api *api) CreateApiKey(createApiKeyRequest *CreateApiKeyRequest) (*CreateApiKeyResponse, error) {
+	if createApiKeyRequest.Name == "" {
+		return nil, errors.New("no api key name provided")
+	}
+	if len(createApiKeyRequest.Scopes) == 0 {
+		return nil, errors.New("no api key scopes provided")
+	}
+	for _, scope := range createApiKeyRequest.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return nil, fmt.Errorf("unknown api key scope: %s", scope)
+		}
+	}
+
+	keyBytes := make([]byte, 32)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(keyBytes)
+
+	apiKey := db.ApiKey{
+		Name:      createApiKeyRequest.Name,
+		KeyHash:   hashApiKey(key),
+		KeyPrefix: key[:apiKeyPrefixLength],
+		Scopes:    strings.Join(createApiKeyRequest.Scopes, " "),
+	}
+	if createApiKeyRequest.ExpiryDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, int(*createApiKeyRequest.ExpiryDays))
+		apiKey.ExpiresAt = &expiresAt
+	}
+	if err := api.db.Create(&apiKey).Error; err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"api_key_id": apiKey.ID,
+		"name":       apiKey.Name,
+		"scopes":     apiKey.Scopes,
+	}).Info("Created api key")
+
+	return &CreateApiKeyResponse{
+		ApiKey:   *toApiApiKey(&apiKey),
+		Key:      key,
+		OnionUrl: tor.GetOnionUrl(api.cfg),
+	}, nil
+}
+
+// RevokeApiKey stops the key from working immediately. It is kept so that it still shows up in the list.
+func (api *api) RevokeApiKey(id uint) error {
+	result := api.db.Model(&db.ApiKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+// AuthenticateApiKey returns the api key if it exists and is neither revoked nor expired
+func (api *api) AuthenticateApiKey(key string) (*ApiKey, error) {
+	var apiKey db.ApiKey
+	if !strings.HasPrefix(key, apiKeyPrefix) || api.db.Limit(1).Find(&apiKey, &db.ApiKey{KeyHash: hashApiKey(key)}).RowsAffected == 0 {
+		return nil, errors.New("invalid api key")
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, errors.New("api key was revoked")
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now) {
+		return nil, errors.New("api key expired")
+	}
+
+	// only used for display, so a failure does not reject the request
+	if err := api.db.Model(&apiKey).Update("last_used_at", now).Error; err != nil {
+		logger.Logger.WithError(err).WithField("api_key_id", apiKey.ID).Error("Failed to update api key last used at")
+	}
+	return toApiApiKey(&apiKey), nil
+}
+
+// the keys are random, so a fast hash is enough to protect them if the database leaks
+func hashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func toApiApiKey(apiKey *db.ApiKey) *ApiKey {
+	return &ApiKey{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		KeyPrefix:  apiKey.KeyPrefix,
+		Scopes:     strings.Fields(apiKey.Scopes),
+		ExpiresAt:  apiKey.ExpiresAt,
+		RevokedAt:  apiKey.RevokedAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestApiKeys(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg}
+
+	_, err = theAPI.CreateApiKey(&CreateApiKeyRequest{Name: "script", Scopes: []string{"node"}})
+	assert.EqualError(t, err, "unknown api key scope: node")
+
+	createApiKeyResponse, err := theAPI.CreateApiKey(&CreateApiKeyRequest{
+		Name:   "script",
+		Scopes: []string{API_KEY_SCOPE_INVOICES, API_KEY_SCOPE_TRANSACTIONS},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, createApiKeyResponse.Key[:12], createApiKeyResponse.KeyPrefix)
+
+	var dbApiKey db.ApiKey
+	require.NoError(t, svc.DB.First(&dbApiKey, createApiKeyResponse.ID).Error)
+	assert.NotContains(t, dbApiKey.KeyHash, createApiKeyResponse.Key)
+
+	apiKey, err := theAPI.AuthenticateApiKey(createApiKeyResponse.Key)
+	require.NoError(t, err)
+	assert.Equal(t, []string{API_KEY_SCOPE_INVOICES, API_KEY_SCOPE_TRANSACTIONS}, apiKey.Scopes)
+
+	_, err = theAPI.AuthenticateApiKey(createApiKeyResponse.Key + "0")
+	assert.EqualError(t, err, "invalid api key")
+
+	require.NoError(t, theAPI.RevokeApiKey(createApiKeyResponse.ID))
+	_, err = theAPI.AuthenticateApiKey(createApiKeyResponse.Key)
+	assert.EqualError(t, err, "api key was revoked")
+	assert.EqualError(t, theAPI.RevokeApiKey(createApiKeyResponse.ID), "api key not found")
+
+	expiryDays := uint64(1)
+	createApiKeyResponse, err = theAPI.CreateApiKey(&CreateApiKeyRequest{
+		Name:       "expiring",
+		Scopes:     []string{API_KEY_SCOPE_APPS},
+		ExpiryDays: &expiryDays,
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Model(&db.ApiKey{}).Where("id = ?", createApiKeyResponse.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = theAPI.AuthenticateApiKey(createApiKeyResponse.Key)
+	assert.EqualError(t, err, "api key expired")
+
+	apiKeys, err := theAPI.ListApiKeys()
+	require.NoError(t, err)
+	assert.Len(t, apiKeys, 2)
+	assert.NotNil(t, apiKeys[0].RevokedAt)
+	assert.NotNil(t, apiKeys[0].LastUsedAt)
+}
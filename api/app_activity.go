@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) ListAppActivity(appId uint, filters ListAppActivityFilters, limit uint64, offset uint64) (*ListAppActivityResponse, error) {
+	query := api.db.Model(&db.AppActivityLog{}).Where("app_id = ?", appId)
+	if filters.Method != "" {
+		query = query.Where("method = ?", filters.Method)
+	}
+	if filters.Result != "" {
+		query = query.Where("result = ?", filters.Result)
+	}
+	if filters.From != 0 {
+		query = query.Where("created_at >= ?", time.Unix(int64(filters.From), 0))
+	}
+	if filters.Until != 0 {
+		query = query.Where("created_at <= ?", time.Unix(int64(filters.Until), 0))
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	var dbActivityLogs []db.AppActivityLog
+	query = query.Order("id DESC").Offset(int(offset))
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	if err := query.Find(&dbActivityLogs).Error; err != nil {
+		return nil, err
+	}
+
+	activity := []AppActivity{}
+	for _, dbActivityLog := range dbActivityLogs {
+		activity = append(activity, AppActivity{
+			ID:             dbActivityLog.ID,
+			RequestEventId: dbActivityLog.RequestNostrId,
+			Method:         dbActivityLog.Method,
+			Params:         json.RawMessage(dbActivityLog.Params),
+			Result:         dbActivityLog.Result,
+			ErrorCode:      dbActivityLog.ErrorCode,
+			ErrorMessage:   dbActivityLog.ErrorMessage,
+			RequestedAt:    dbActivityLog.RequestedAt,
+			CreatedAt:      dbActivityLog.CreatedAt,
+		})
+	}
+	return &ListAppActivityResponse{
+		TotalCount: uint64(totalCount),
+		Activity:   activity,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/logger"
+	"gorm.io/gorm"
+)
+
+func validateAppBudgets(budgets []AppBudgetRequest) error {
+	for _, budget := range budgets {
+		if !slices.Contains(constants.GetBudgetRenewals(), budget.BudgetRenewal) {
+			return fmt.Errorf("invalid budgetRenewal: %s", budget.BudgetRenewal)
+		}
+		if budget.MaxAmountSat == 0 {
+			return fmt.Errorf("invalid maxAmount for %s budget", budget.BudgetRenewal)
+		}
+	}
+	return nil
+}
+
+// setAppBudgets replaces the additional budgets of an app
+func setAppBudgets(tx *gorm.DB, appId uint, budgets []AppBudgetRequest) error {
+	if err := tx.Where("app_id = ?", appId).Delete(&db.AppBudget{}).Error; err != nil {
+		return err
+	}
+	for _, budget := range budgets {
+		err := tx.Create(&db.AppBudget{
+			AppId:         appId,
+			MaxAmountSat:  budget.MaxAmountSat,
+			BudgetRenewal: budget.BudgetRenewal,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (api *api) getAppBudgets(appId uint) []AppBudget {
+	var dbAppBudgets []db.AppBudget
+	if err := api.db.Where("app_id = ?", appId).Order("id").Find(&dbAppBudgets).Error; err != nil {
+		logger.Logger.WithError(err).WithField("app_id", appId).Error("Failed to list app budgets")
+	}
+
+	budgets := []AppBudget{}
+	for _, dbAppBudget := range dbAppBudgets {
+		budgets = append(budgets, AppBudget{
+			MaxAmountSat:  dbAppBudget.MaxAmountSat,
+			BudgetRenewal: dbAppBudget.BudgetRenewal,
+			BudgetUsage:   queries.GetBudgetUsageSatForPeriod(api.db, appId, dbAppBudget.BudgetRenewal),
+			RenewsAt:      queries.GetBudgetRenewsAt(dbAppBudget.BudgetRenewal),
+		})
+	}
+	return budgets
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// BulkUpdateApps applies the same action to many apps at once, e.g. to pause all apps during an incident.
+// Apps which fail are reported in the response and do not stop the others from being updated.
+func (api *api) BulkUpdateApps(bulkUpdateAppsRequest *BulkUpdateAppsRequest) (*BulkUpdateAppsResponse, error) {
+	var action func(app *db.App) error
+	switch bulkUpdateAppsRequest.Action {
+	case BULK_APP_ACTION_REVOKE:
+		action = api.appsSvc.RevokeApp
+	case BULK_APP_ACTION_PAUSE:
+		action = func(app *db.App) error {
+			return api.appsSvc.SetAppPaused(app, true)
+		}
+	case BULK_APP_ACTION_RESUME:
+		action = func(app *db.App) error {
+			return api.appsSvc.SetAppPaused(app, false)
+		}
+	case BULK_APP_ACTION_SCALE_BUDGETS:
+		if bulkUpdateAppsRequest.BudgetFactor == nil {
+			return nil, errors.New("no budget factor provided")
+		}
+		factor := *bulkUpdateAppsRequest.BudgetFactor
+		action = func(app *db.App) error {
+			return api.appsSvc.ScaleAppBudgets(app, factor)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action: %s", bulkUpdateAppsRequest.Action)
+	}
+
+	var dbApps []db.App
+	if bulkUpdateAppsRequest.All {
+		if err := api.db.Order("id").Find(&dbApps).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		if len(bulkUpdateAppsRequest.AppIds) == 0 {
+			return nil, errors.New("no apps provided")
+		}
+		if err := api.db.Where("id IN ?", bulkUpdateAppsRequest.AppIds).Order("id").Find(&dbApps).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	response := &BulkUpdateAppsResponse{
+		Updated: []uint{},
+		Failed:  []BulkUpdateAppsFailure{},
+	}
+	found := map[uint]bool{}
+	for i := range dbApps {
+		app := &dbApps[i]
+		found[app.ID] = true
+		if err := action(app); err != nil {
+			response.Failed = append(response.Failed, BulkUpdateAppsFailure{AppId: app.ID, Reason: err.Error()})
+			continue
+		}
+		response.Updated = append(response.Updated, app.ID)
+	}
+	for _, appId := range bulkUpdateAppsRequest.AppIds {
+		if !bulkUpdateAppsRequest.All && !found[appId] {
+			response.Failed = append(response.Failed, BulkUpdateAppsFailure{AppId: appId, Reason: "app not found"})
+		}
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"action":  bulkUpdateAppsRequest.Action,
+		"updated": len(response.Updated),
+		"failed":  len(response.Failed),
+	}).Info("Bulk updated apps")
+
+	return response, nil
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestBulkUpdateApps(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg, keys: svc.Keys, appsSvc: svc.AppsService}
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	otherApp, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId:        app.ID,
+		Scope:        constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat: 1000,
+	}).Error)
+
+	_, err = theAPI.BulkUpdateApps(&BulkUpdateAppsRequest{All: true, Action: "delete"})
+	assert.EqualError(t, err, "unknown action: delete")
+
+	response, err := theAPI.BulkUpdateApps(&BulkUpdateAppsRequest{All: true, Action: BULK_APP_ACTION_PAUSE})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{app.ID, otherApp.ID}, response.Updated)
+	assert.True(t, theAPI.GetApp(svc.AppsService.GetAppById(otherApp.ID)).Paused)
+
+	response, err = theAPI.BulkUpdateApps(&BulkUpdateAppsRequest{AppIds: []uint{otherApp.ID, 999}, Action: BULK_APP_ACTION_RESUME})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{otherApp.ID}, response.Updated)
+	assert.Equal(t, []BulkUpdateAppsFailure{{AppId: 999, Reason: "app not found"}}, response.Failed)
+	assert.False(t, theAPI.GetApp(svc.AppsService.GetAppById(otherApp.ID)).Paused)
+	assert.True(t, theAPI.GetApp(svc.AppsService.GetAppById(app.ID)).Paused)
+
+	factor := 0.5
+	response, err = theAPI.BulkUpdateApps(&BulkUpdateAppsRequest{AppIds: []uint{app.ID}, Action: BULK_APP_ACTION_SCALE_BUDGETS, BudgetFactor: &factor})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{app.ID}, response.Updated)
+	var appPermission db.AppPermission
+	require.NoError(t, svc.DB.First(&appPermission, &db.AppPermission{AppId: app.ID, Scope: constants.PAY_INVOICE_SCOPE}).Error)
+	assert.Equal(t, 500, appPermission.MaxAmountSat)
+
+	response, err = theAPI.BulkUpdateApps(&BulkUpdateAppsRequest{AppIds: []uint{app.ID}, Action: BULK_APP_ACTION_REVOKE})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{app.ID}, response.Updated)
+	require.NoError(t, svc.DB.First(&appPermission, appPermission.ID).Error)
+	require.NotNil(t, appPermission.ExpiresAt)
+	assert.False(t, appPermission.ExpiresAt.After(time.Now()))
+
+	var auditLogs []db.AppAuditLog
+	require.NoError(t, svc.DB.Where("app_id = ?", app.ID).Order("id").Find(&auditLogs).Error)
+	actions := []string{}
+	for _, auditLog := range auditLogs {
+		actions = append(actions, auditLog.Action)
+	}
+	assert.Subset(t, actions, []string{"paused", "budgets_scaled", "revoked"})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnurl"
+)
+
+// replaced in tests to avoid requesting invoices from real services
+var resolveLnurlPayNode = lnurl.ResolvePayNode
+
+func (api *api) ListAppDestinations(appId uint) ([]AppDestination, error) {
+	var dbAppDestinations []db.AppDestination
+	err := api.db.Where("app_id = ?", appId).Order("id").Find(&dbAppDestinations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	appDestinations := []AppDestination{}
+	for _, dbAppDestination := range dbAppDestinations {
+		appDestinations = append(appDestinations, toApiAppDestination(&dbAppDestination))
+	}
+	return appDestinations, nil
+}
+
+// AddAppDestination restricts the app to paying the given destination and any other destinations it already has.
+// Lightning addresses and LNURL domains are resolved to the node which issues their invoices, since invoices
+// do not carry where they were requested from. Any destination hosted on the same node can therefore be paid.
+func (api *api) AddAppDestination(ctx context.Context, app *db.App, addAppDestinationRequest *AddAppDestinationRequest) (*AppDestination, error) {
+	appDestination := db.AppDestination{
+		AppId: app.ID,
+		Type:  addAppDestinationRequest.Type,
+	}
+
+	value := strings.TrimSpace(addAppDestinationRequest.Value)
+	switch addAppDestinationRequest.Type {
+	case constants.APP_DESTINATION_TYPE_NODE:
+		value = strings.ToLower(value)
+		pubkey, err := hex.DecodeString(value)
+		if err != nil || len(pubkey) != 33 {
+			return nil, errors.New("invalid node pubkey")
+		}
+		appDestination.Value = value
+		appDestination.NodePubkey = value
+	case constants.APP_DESTINATION_TYPE_LIGHTNING_ADDRESS:
+		value = strings.ToLower(value)
+		lnurlpUrl, err := lnurl.LightningAddressPayUrl(value)
+		if err != nil {
+			return nil, err
+		}
+		nodePubkey, err := resolveLnurlPayNode(ctx, lnurlpUrl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve lightning address: %w", err)
+		}
+		appDestination.Value = value
+		appDestination.NodePubkey = nodePubkey
+	case constants.APP_DESTINATION_TYPE_LNURL_DOMAIN:
+		// an LNURL-pay endpoint of the domain is needed to find out which node it is paid to
+		lnurlpUrl, err := url.Parse(value)
+		if err != nil || lnurlpUrl.Scheme != "https" || lnurlpUrl.Hostname() == "" {
+			return nil, errors.New("an https LNURL-pay url of the domain is required")
+		}
+		nodePubkey, err := resolveLnurlPayNode(ctx, lnurlpUrl.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve LNURL domain: %w", err)
+		}
+		appDestination.Value = strings.ToLower(lnurlpUrl.Hostname())
+		appDestination.NodePubkey = nodePubkey
+	default:
+		return nil, fmt.Errorf("invalid destination type: %s", addAppDestinationRequest.Type)
+	}
+
+	var existingCount int64
+	err := api.db.Model(&db.AppDestination{}).Where(&db.AppDestination{AppId: app.ID, Type: appDestination.Type, Value: appDestination.Value}).Count(&existingCount).Error
+	if err != nil {
+		return nil, err
+	}
+	if existingCount > 0 {
+		return nil, fmt.Errorf("app already has the destination %s", appDestination.Value)
+	}
+
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&appDestination).Error; err != nil {
+			return err
+		}
+		return apps.RecordAuditLog(tx, app.ID, apps.AUDIT_ACTION_DESTINATION_ADDED, map[string]interface{}{
+			"type":        appDestination.Type,
+			"value":       appDestination.Value,
+			"node_pubkey": appDestination.NodePubkey,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := toApiAppDestination(&appDestination)
+	return &result, nil
+}
+
+// RemoveAppDestination removes a destination of the app. Once the last destination is removed,
+// the app can pay any destination again.
+func (api *api) RemoveAppDestination(app *db.App, destinationId uint) error {
+	var appDestination db.AppDestination
+	if api.db.Limit(1).Find(&appDestination, &db.AppDestination{ID: destinationId, AppId: app.ID}).RowsAffected == 0 {
+		return errors.New("destination not found")
+	}
+
+	return api.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&appDestination).Error; err != nil {
+			return err
+		}
+		return apps.RecordAuditLog(tx, app.ID, apps.AUDIT_ACTION_DESTINATION_REMOVED, map[string]interface{}{
+			"type":  appDestination.Type,
+			"value": appDestination.Value,
+		})
+	})
+}
+
+func toApiAppDestination(appDestination *db.AppDestination) AppDestination {
+	return AppDestination{
+		ID:         appDestination.ID,
+		Type:       appDestination.Type,
+		Value:      appDestination.Value,
+		NodePubkey: appDestination.NodePubkey,
+		CreatedAt:  appDestination.CreatedAt,
+	}
+}
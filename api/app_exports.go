@@ -0,0 +1,299 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/backups"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/webhooks"
+)
+
+const appsExportVersion = 1
+
+type appsExport struct {
+	Version        int           `json:"version"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	IncludeSecrets bool          `json:"includeSecrets"`
+	Apps           []exportedApp `json:"apps"`
+}
+
+// exportedApp is the request the app is recreated with, and the configuration which is set after creating it
+type exportedApp struct {
+	CreateAppRequest
+	Description  string                   `json:"description,omitempty"`
+	Destinations []exportedAppDestination `json:"destinations,omitempty"`
+	Webhooks     []exportedAppWebhook     `json:"webhooks,omitempty"`
+	// secrets, only exported on request
+	OwnerPasswordHash string `json:"ownerPasswordHash,omitempty"`
+}
+
+type exportedAppDestination struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	NodePubkey string `json:"nodePubkey"`
+}
+
+type exportedAppWebhook struct {
+	Url        string `json:"url"`
+	EventTypes string `json:"eventTypes"`
+	Filter     string `json:"filter,omitempty"`
+	Secret     string `json:"secret,omitempty"`
+}
+
+// ExportApps writes the configuration of all connections, encrypted with the unlock password.
+// Without secrets, the connections get new keys when they are imported and have to be connected again.
+// Balances of sub-wallets and lightning addresses are not part of the export.
+func (api *api) ExportApps(exportAppsRequest *ExportAppsRequest, w io.Writer) error {
+	if !api.cfg.CheckUnlockPassword(exportAppsRequest.UnlockPassword) {
+		return errors.New("invalid unlock password")
+	}
+
+	var dbApps []db.App
+	if err := api.db.Where("name != ?", alby.ALBY_ACCOUNT_APP_NAME).Order("id").Find(&dbApps).Error; err != nil {
+		return err
+	}
+
+	export := appsExport{
+		Version:        appsExportVersion,
+		CreatedAt:      time.Now(),
+		IncludeSecrets: exportAppsRequest.IncludeSecrets,
+		Apps:           []exportedApp{},
+	}
+	for _, dbApp := range dbApps {
+		exportedApp, err := api.exportApp(&dbApp, exportAppsRequest.IncludeSecrets)
+		if err != nil {
+			return err
+		}
+		export.Apps = append(export.Apps, *exportedApp)
+	}
+
+	encryptedWriter, err := backups.EncryptingWriter(w, exportAppsRequest.UnlockPassword)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(encryptedWriter).Encode(&export)
+}
+
+func (api *api) exportApp(dbApp *db.App, includeSecrets bool) (*exportedApp, error) {
+	var appPermissions []db.AppPermission
+	if err := api.db.Where("app_id = ?", dbApp.ID).Order("id").Find(&appPermissions).Error; err != nil {
+		return nil, err
+	}
+
+	exportedApp := exportedApp{
+		CreateAppRequest: CreateAppRequest{
+			Name:                 dbApp.Name,
+			Scopes:               []string{},
+			Isolated:             dbApp.Isolated,
+			ReadOnly:             dbApp.ReadOnly,
+			ReceiveOnly:          dbApp.ReceiveOnly,
+			SingleUse:            dbApp.SingleUse,
+			MaxPaymentAmountSat:  dbApp.MaxPaymentAmountSat,
+			MaxFeePercent:        dbApp.MaxFeePercent,
+			MaxFeeFloorSat:       dbApp.MaxFeeFloorSat,
+			ApprovalThreshold:    dbApp.ApprovalThresholdSat,
+			MaxPaymentsPerMinute: dbApp.MaxPaymentsPerMinute,
+			MaxPaymentsPerDay:    dbApp.MaxPaymentsPerDay,
+		},
+		Description: dbApp.Description,
+	}
+	if dbApp.NostrPubkey != nil {
+		exportedApp.NostrPubkey = *dbApp.NostrPubkey
+	}
+	for _, appPermission := range appPermissions {
+		exportedApp.Scopes = append(exportedApp.Scopes, appPermission.Scope)
+		if appPermission.ExpiresAt != nil {
+			exportedApp.ExpiresAt = appPermission.ExpiresAt.Format(time.RFC3339)
+		}
+		if appPermission.Scope == constants.PAY_INVOICE_SCOPE {
+			exportedApp.MaxAmountSat = uint64(appPermission.MaxAmountSat)
+			exportedApp.BudgetRenewal = appPermission.BudgetRenewal
+			exportedApp.BudgetCurrency = appPermission.BudgetCurrency
+			exportedApp.MaxAmountFiat = appPermission.MaxAmountFiat
+		}
+	}
+
+	if dbApp.Metadata != nil {
+		if err := json.Unmarshal(dbApp.Metadata, &exportedApp.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to deserialize metadata of app %d: %w", dbApp.ID, err)
+		}
+		// lightning addresses belong to the account of this hub
+		delete(exportedApp.Metadata, "lud16")
+	}
+
+	var dbAppBudgets []db.AppBudget
+	if err := api.db.Where("app_id = ?", dbApp.ID).Order("id").Find(&dbAppBudgets).Error; err != nil {
+		return nil, err
+	}
+	for _, dbAppBudget := range dbAppBudgets {
+		exportedApp.Budgets = append(exportedApp.Budgets, AppBudgetRequest{
+			MaxAmountSat:  dbAppBudget.MaxAmountSat,
+			BudgetRenewal: dbAppBudget.BudgetRenewal,
+		})
+	}
+
+	var dbAppDestinations []db.AppDestination
+	if err := api.db.Where("app_id = ?", dbApp.ID).Order("id").Find(&dbAppDestinations).Error; err != nil {
+		return nil, err
+	}
+	for _, dbAppDestination := range dbAppDestinations {
+		exportedApp.Destinations = append(exportedApp.Destinations, exportedAppDestination{
+			Type:       dbAppDestination.Type,
+			Value:      dbAppDestination.Value,
+			NodePubkey: dbAppDestination.NodePubkey,
+		})
+	}
+
+	var dbWebhooks []db.Webhook
+	if err := api.db.Where("app_id = ?", dbApp.ID).Order("id").Find(&dbWebhooks).Error; err != nil {
+		return nil, err
+	}
+	for _, dbWebhook := range dbWebhooks {
+		exportedWebhook := exportedAppWebhook{
+			Url:        dbWebhook.Url,
+			EventTypes: dbWebhook.EventTypes,
+			Filter:     dbWebhook.Filter,
+		}
+		if includeSecrets {
+			exportedWebhook.Secret = dbWebhook.Secret
+		}
+		exportedApp.Webhooks = append(exportedApp.Webhooks, exportedWebhook)
+	}
+
+	if includeSecrets {
+		exportedApp.Pubkey = dbApp.AppPubkey
+		exportedApp.OwnerPasswordHash = dbApp.OwnerPasswordHash
+	}
+
+	return &exportedApp, nil
+}
+
+// ImportApps recreates the connections of an export. Connections which cannot be created on this hub,
+// e.g. because they are already connected, are skipped and reported in the response.
+func (api *api) ImportApps(importAppsRequest *ImportAppsRequest, r io.Reader) (*ImportAppsResponse, error) {
+	if !api.cfg.CheckUnlockPassword(importAppsRequest.UnlockPassword) {
+		return nil, errors.New("invalid unlock password")
+	}
+	exportPassword := importAppsRequest.ExportPassword
+	if exportPassword == "" {
+		exportPassword = importAppsRequest.UnlockPassword
+	}
+
+	decryptedReader, err := backups.DecryptingReader(r, exportPassword)
+	if err != nil {
+		return nil, err
+	}
+	var export appsExport
+	if err := json.NewDecoder(decryptedReader).Decode(&export); err != nil {
+		return nil, errors.New("failed to read export, please check the password")
+	}
+	if export.Version != appsExportVersion {
+		return nil, fmt.Errorf("unsupported export version: %d", export.Version)
+	}
+
+	importAppsResponse := &ImportAppsResponse{
+		Apps:    []CreateAppResponse{},
+		Skipped: []ImportAppsSkippedApp{},
+	}
+	for _, exportedApp := range export.Apps {
+		createAppResponse, err := api.importApp(&exportedApp, importAppsRequest.UnlockPassword)
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"name": exportedApp.Name,
+			}).Warn("Skipped importing app")
+			importAppsResponse.Skipped = append(importAppsResponse.Skipped, ImportAppsSkippedApp{
+				Name:   exportedApp.Name,
+				Reason: err.Error(),
+			})
+			continue
+		}
+		importAppsResponse.Apps = append(importAppsResponse.Apps, *createAppResponse)
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"imported": len(importAppsResponse.Apps),
+		"skipped":  len(importAppsResponse.Skipped),
+	}).Info("Imported apps")
+	return importAppsResponse, nil
+}
+
+func (api *api) importApp(exportedApp *exportedApp, unlockPassword string) (*CreateAppResponse, error) {
+	if exportedApp.Pubkey != "" && api.appsSvc.GetAppByPubkey(exportedApp.Pubkey) != nil {
+		return nil, errors.New("app is already connected to this hub")
+	}
+
+	createAppRequest := exportedApp.CreateAppRequest
+	createAppRequest.UnlockPassword = unlockPassword
+	createAppResponse, err := api.CreateApp(&createAppRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.importAppConfiguration(createAppResponse.Id, exportedApp)
+	if err != nil {
+		// do not leave a partially imported app behind
+		if dbApp := api.appsSvc.GetAppById(createAppResponse.Id); dbApp != nil {
+			if deleteErr := api.appsSvc.DeleteApp(dbApp); deleteErr != nil {
+				logger.Logger.WithError(deleteErr).WithField("app_id", dbApp.ID).Error("Failed to delete partially imported app")
+			}
+		}
+		return nil, err
+	}
+
+	return createAppResponse, nil
+}
+
+func (api *api) importAppConfiguration(appId uint, exportedApp *exportedApp) error {
+	appUpdates := map[string]interface{}{}
+	if exportedApp.Description != "" {
+		appUpdates["description"] = exportedApp.Description
+	}
+	if exportedApp.OwnerPasswordHash != "" {
+		appUpdates["owner_password_hash"] = exportedApp.OwnerPasswordHash
+	}
+	if len(appUpdates) > 0 {
+		if err := api.db.Model(&db.App{}).Where("id = ?", appId).Updates(appUpdates).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, exportedDestination := range exportedApp.Destinations {
+		err := api.db.Create(&db.AppDestination{
+			AppId:      appId,
+			Type:       exportedDestination.Type,
+			Value:      exportedDestination.Value,
+			NodePubkey: exportedDestination.NodePubkey,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, exportedWebhook := range exportedApp.Webhooks {
+		filter, err := webhooks.ParseFilter(exportedWebhook.Filter)
+		if err != nil {
+			return err
+		}
+		webhook, err := api.webhooksSvc.CreateAppWebhook(appId, exportedWebhook.Url, strings.Split(exportedWebhook.EventTypes, ","), filter)
+		if err != nil {
+			return err
+		}
+		if exportedWebhook.Secret != "" {
+			if err := api.db.Model(webhook).Update("secret", exportedWebhook.Secret).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/tests/mocks"
+	"github.com/getAlby/hub/webhooks"
+)
+
+func createExportTestAPI(t *testing.T, svc *tests.TestService) *api {
+	require.NoError(t, svc.Cfg.SaveUnlockPasswordCheck("123"))
+	return &api{
+		db:           svc.DB,
+		cfg:          svc.Cfg,
+		svc:          mocks.NewMockService(t),
+		keys:         svc.Keys,
+		appsSvc:      svc.AppsService,
+		webhooksSvc:  webhooks.NewWebhooksService(svc.DB),
+		albyOAuthSvc: alby.NewAlbyOAuthService(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher),
+	}
+}
+
+func TestExportImportApps(t *testing.T) {
+	sourceSvc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	sourceAPI := createExportTestAPI(t, sourceSvc)
+
+	maxPaymentsPerDay := uint(10)
+	createAppResponse, err := sourceAPI.CreateApp(&CreateAppRequest{
+		Name:              "Shop",
+		Scopes:            []string{constants.PAY_INVOICE_SCOPE, constants.GET_BALANCE_SCOPE},
+		MaxAmountSat:      5_000,
+		BudgetRenewal:     constants.BUDGET_RENEWAL_MONTHLY,
+		Budgets:           []AppBudgetRequest{{MaxAmountSat: 1_000, BudgetRenewal: constants.BUDGET_RENEWAL_DAILY}},
+		MaxPaymentsPerDay: &maxPaymentsPerDay,
+		Metadata:          Metadata{"app_store_app_id": "shop", "lud16": "shop@getalby.com"},
+		WebhookUrl:        "https://example.com/webhook",
+	})
+	require.NoError(t, err)
+	require.NoError(t, sourceSvc.DB.Create(&db.AppDestination{
+		AppId:      createAppResponse.Id,
+		Type:       constants.APP_DESTINATION_TYPE_NODE,
+		Value:      "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c",
+		NodePubkey: "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c",
+	}).Error)
+
+	var export bytes.Buffer
+	assert.EqualError(t, sourceAPI.ExportApps(&ExportAppsRequest{UnlockPassword: "wrong"}, &export), "invalid unlock password")
+	require.NoError(t, sourceAPI.ExportApps(&ExportAppsRequest{UnlockPassword: "123", IncludeSecrets: true}, &export))
+	// the export is encrypted
+	assert.NotContains(t, export.String(), "Shop")
+
+	var sourceWebhook db.Webhook
+	require.NoError(t, sourceSvc.DB.First(&sourceWebhook).Error)
+	sourceSvc.Remove()
+
+	targetSvc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer targetSvc.Remove()
+	targetAPI := createExportTestAPI(t, targetSvc)
+
+	_, err = targetAPI.ImportApps(&ImportAppsRequest{UnlockPassword: "123", ExportPassword: "wrong"}, bytes.NewReader(export.Bytes()))
+	assert.EqualError(t, err, "failed to read export, please check the password")
+
+	importAppsResponse, err := targetAPI.ImportApps(&ImportAppsRequest{UnlockPassword: "123"}, bytes.NewReader(export.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, importAppsResponse.Apps, 1)
+	assert.Empty(t, importAppsResponse.Skipped)
+	// the connection keeps its key, so it does not need to be paired again
+	assert.Equal(t, createAppResponse.Pubkey, importAppsResponse.Apps[0].Pubkey)
+	assert.Empty(t, importAppsResponse.Apps[0].PairingSecret)
+
+	importedApp := targetAPI.GetApp(targetSvc.AppsService.GetAppById(importAppsResponse.Apps[0].Id))
+	assert.Equal(t, "Shop", importedApp.Name)
+	assert.ElementsMatch(t, []string{constants.PAY_INVOICE_SCOPE, constants.GET_BALANCE_SCOPE}, importedApp.Scopes)
+	assert.Equal(t, uint64(5_000), importedApp.MaxAmountSat)
+	assert.Equal(t, constants.BUDGET_RENEWAL_MONTHLY, importedApp.BudgetRenewal)
+	require.Len(t, importedApp.Budgets, 1)
+	assert.Equal(t, uint64(1_000), importedApp.Budgets[0].MaxAmountSat)
+	assert.Equal(t, &maxPaymentsPerDay, importedApp.MaxPaymentsPerDay)
+	assert.Equal(t, Metadata{"app_store_app_id": "shop"}, importedApp.Metadata)
+
+	var destinationCount int64
+	require.NoError(t, targetSvc.DB.Model(&db.AppDestination{}).Where("app_id = ?", importedApp.ID).Count(&destinationCount).Error)
+	assert.Equal(t, int64(1), destinationCount)
+
+	var importedWebhook db.Webhook
+	require.NoError(t, targetSvc.DB.First(&importedWebhook).Error)
+	assert.Equal(t, &importedApp.ID, importedWebhook.AppId)
+	assert.Equal(t, sourceWebhook.Secret, importedWebhook.Secret)
+
+	// the same connection cannot be imported twice
+	importAppsResponse, err = targetAPI.ImportApps(&ImportAppsRequest{UnlockPassword: "123"}, bytes.NewReader(export.Bytes()))
+	require.NoError(t, err)
+	assert.Empty(t, importAppsResponse.Apps)
+	require.Len(t, importAppsResponse.Skipped, 1)
+	assert.Equal(t, "app is already connected to this hub", importAppsResponse.Skipped[0].Reason)
+}
+
+func TestExportImportApps_WithoutSecrets(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+	theAPI := createExportTestAPI(t, svc)
+
+	createAppResponse, err := theAPI.CreateApp(&CreateAppRequest{
+		Name:       "Shop",
+		Scopes:     []string{constants.GET_INFO_SCOPE},
+		WebhookUrl: "https://example.com/webhook",
+	})
+	require.NoError(t, err)
+
+	var export bytes.Buffer
+	require.NoError(t, theAPI.ExportApps(&ExportAppsRequest{UnlockPassword: "123"}, &export))
+
+	// importing into the same hub creates a second connection with new keys
+	importAppsResponse, err := theAPI.ImportApps(&ImportAppsRequest{UnlockPassword: "123"}, &export)
+	require.NoError(t, err)
+	require.Len(t, importAppsResponse.Apps, 1)
+	assert.Equal(t, "Shop (1)", importAppsResponse.Apps[0].Name)
+	assert.NotEqual(t, createAppResponse.Pubkey, importAppsResponse.Apps[0].Pubkey)
+	assert.NotEmpty(t, importAppsResponse.Apps[0].PairingSecret)
+
+	var webhooks []db.Webhook
+	require.NoError(t, svc.DB.Order("id").Find(&webhooks).Error)
+	require.Len(t, webhooks, 2)
+	assert.NotEqual(t, webhooks[0].Secret, webhooks[1].Secret)
+}
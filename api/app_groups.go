@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+)
+
+func (api *api) ListAppGroups() ([]AppGroup, error) {
+	var dbAppGroups []db.AppGroup
+	if err := api.db.Order("name").Find(&dbAppGroups).Error; err != nil {
+		return nil, err
+	}
+
+	appGroups := []AppGroup{}
+	for _, dbAppGroup := range dbAppGroups {
+		appGroup, err := api.toApiAppGroup(&dbAppGroup)
+		if err != nil {
+			return nil, err
+		}
+		appGroups = append(appGroups, *appGroup)
+	}
+	return appGroups, nil
+}
+
+func (api *api) CreateAppGroup(createAppGroupRequest *CreateAppGroupRequest) (*AppGroup, error) {
+	budgetRenewal := createAppGroupRequest.BudgetRenewal
+	if budgetRenewal == "" {
+		budgetRenewal = constants.BUDGET_RENEWAL_NEVER
+	}
+	if err := validateAppGroup(createAppGroupRequest.Name, budgetRenewal); err != nil {
+		return nil, err
+	}
+
+	appGroup := db.AppGroup{
+		Name:          createAppGroupRequest.Name,
+		MaxAmountSat:  createAppGroupRequest.MaxAmountSat,
+		BudgetRenewal: budgetRenewal,
+	}
+	if err := api.db.Create(&appGroup).Error; err != nil {
+		return nil, err
+	}
+	return api.toApiAppGroup(&appGroup)
+}
+
+func (api *api) UpdateAppGroup(id uint, updateAppGroupRequest *UpdateAppGroupRequest) (*AppGroup, error) {
+	var appGroup db.AppGroup
+	if api.db.Limit(1).Find(&appGroup, id).RowsAffected == 0 {
+		return nil, errors.New("app group not found")
+	}
+
+	if updateAppGroupRequest.Name != nil {
+		appGroup.Name = *updateAppGroupRequest.Name
+	}
+	if updateAppGroupRequest.MaxAmountSat != nil {
+		appGroup.MaxAmountSat = *updateAppGroupRequest.MaxAmountSat
+	}
+	if updateAppGroupRequest.BudgetRenewal != nil {
+		appGroup.BudgetRenewal = *updateAppGroupRequest.BudgetRenewal
+	}
+	if err := validateAppGroup(appGroup.Name, appGroup.BudgetRenewal); err != nil {
+		return nil, err
+	}
+
+	if err := api.db.Save(&appGroup).Error; err != nil {
+		return nil, err
+	}
+	return api.toApiAppGroup(&appGroup)
+}
+
+// DeleteAppGroup deletes a group, its members keep their own budgets
+func (api *api) DeleteAppGroup(id uint) error {
+	return api.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&db.App{}).Where("app_group_id = ?", id).Update("app_group_id", nil).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Delete(&db.AppGroup{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("app group not found")
+		}
+		return nil
+	})
+}
+
+func setAppGroup(tx *gorm.DB, appId uint, appGroupId *uint) error {
+	if appGroupId != nil && tx.Limit(1).Find(&db.AppGroup{}, *appGroupId).RowsAffected == 0 {
+		return errors.New("app group not found")
+	}
+	return tx.Model(&db.App{}).Where("id = ?", appId).Update("app_group_id", appGroupId).Error
+}
+
+func (api *api) toApiAppGroup(appGroup *db.AppGroup) (*AppGroup, error) {
+	appIds := []uint{}
+	if err := api.db.Model(&db.App{}).Where("app_group_id = ?", appGroup.ID).Order("id").Pluck("id", &appIds).Error; err != nil {
+		return nil, err
+	}
+
+	return &AppGroup{
+		ID:            appGroup.ID,
+		Name:          appGroup.Name,
+		MaxAmountSat:  appGroup.MaxAmountSat,
+		BudgetRenewal: appGroup.BudgetRenewal,
+		BudgetUsage:   queries.GetAppGroupBudgetUsageSat(api.db, appGroup),
+		RenewsAt:      queries.GetBudgetRenewsAt(appGroup.BudgetRenewal),
+		AppIds:        appIds,
+		CreatedAt:     appGroup.CreatedAt,
+	}, nil
+}
+
+func validateAppGroup(name string, budgetRenewal string) error {
+	if name == "" {
+		return errors.New("no app group name provided")
+	}
+	if !slices.Contains(constants.GetBudgetRenewals(), budgetRenewal) {
+		return fmt.Errorf("invalid budget renewal. Must be one of %s", strings.Join(constants.GetBudgetRenewals(), ","))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	lnurlPayMinSendableMsat = 1_000
+	lnurlPayMaxSendableMsat = 100_000_000_000
+	lnurlPayCommentAllowed  = 255
+)
+
+var lightningAddressUsernameRegex = regexp.MustCompile(`^[a-z0-9._-]{1,64}$`)
+
+// SetAppLightningAddress assigns a lightning address served by the hub to an isolated app.
+// An empty username removes it.
+func (api *api) SetAppLightningAddress(appId uint, username string) error {
+	app := api.appsSvc.GetAppById(appId)
+	if app == nil {
+		return errors.New("app not found")
+	}
+
+	if username == "" {
+		return api.db.Model(app).Update("lightning_address_username", nil).Error
+	}
+
+	if !app.Isolated {
+		return errors.New("lightning addresses can only be assigned to isolated apps")
+	}
+	if api.cfg.GetEnv().GetLightningAddressDomain() == "" {
+		return errors.New("no lightning address domain configured")
+	}
+	username = strings.ToLower(username)
+	if !lightningAddressUsernameRegex.MatchString(username) {
+		return errors.New("username can only contain a-z, 0-9, '.', '_' and '-'")
+	}
+	var existingApp db.App
+	if api.db.Where("lightning_address_username = ? AND id != ?", username, app.ID).Limit(1).Find(&existingApp).RowsAffected > 0 {
+		return errors.New("lightning address is already taken")
+	}
+
+	return api.db.Model(app).Update("lightning_address_username", username).Error
+}
+
+// GetLnurlPay returns the LNURL-pay parameters of a lightning address served by the hub (LUD-06, LUD-16)
+func (api *api) GetLnurlPay(username string) (*LnurlPayResponse, error) {
+	app, err := api.getLightningAddressApp(username)
+	if err != nil {
+		return nil, err
+	}
+
+	callbackBaseUrl := api.cfg.GetEnv().BaseUrl
+	if callbackBaseUrl == "" {
+		callbackBaseUrl = "https://" + api.cfg.GetEnv().GetLightningAddressDomain()
+	}
+
+	return &LnurlPayResponse{
+		Tag:            "payRequest",
+		Callback:       fmt.Sprintf("%s/api/lnurlp/%s/callback", callbackBaseUrl, *app.LightningAddressUsername),
+		MinSendable:    lnurlPayMinSendableMsat,
+		MaxSendable:    lnurlPayMaxSendableMsat,
+		Metadata:       api.lnurlPayMetadata(app),
+		CommentAllowed: lnurlPayCommentAllowed,
+	}, nil
+}
+
+// GetLnurlPayInvoice creates an invoice for a lightning address served by the hub,
+// which is credited to the balance of its app when paid
+func (api *api) GetLnurlPayInvoice(ctx context.Context, username string, amountMsat uint64, comment string) (*LnurlPayInvoiceResponse, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	app, err := api.getLightningAddressApp(username)
+	if err != nil {
+		return nil, err
+	}
+	if amountMsat < lnurlPayMinSendableMsat || amountMsat > lnurlPayMaxSendableMsat {
+		return nil, fmt.Errorf("amount must be between %d and %d msat", lnurlPayMinSendableMsat, lnurlPayMaxSendableMsat)
+	}
+	if len(comment) > lnurlPayCommentAllowed {
+		return nil, fmt.Errorf("comment can be at most %d characters", lnurlPayCommentAllowed)
+	}
+
+	metadata := map[string]interface{}{
+		"lightning_address": api.lightningAddress(app),
+	}
+	if comment != "" {
+		metadata["comment"] = comment
+	}
+
+	// the invoice commits to the LNURL metadata, the comment is only stored with the transaction
+	descriptionHash := sha256.Sum256([]byte(api.lnurlPayMetadata(app)))
+	transaction, err := api.svc.GetTransactionsService().MakeInvoice(ctx, amountMsat, comment, hex.EncodeToString(descriptionHash[:]), 0, metadata, api.svc.GetLNClient(), &app.ID, nil, nil)
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"app_id":   app.ID,
+			"username": username,
+		}).Error("Failed to create lightning address invoice")
+		return nil, err
+	}
+
+	return &LnurlPayInvoiceResponse{
+		Pr:     transaction.PaymentRequest,
+		Routes: []interface{}{},
+	}, nil
+}
+
+func (api *api) getLightningAddressApp(username string) (*db.App, error) {
+	var app db.App
+	if api.db.Where("lightning_address_username = ?", strings.ToLower(username)).Limit(1).Find(&app).RowsAffected == 0 {
+		return nil, errors.New("lightning address not found")
+	}
+	if !app.Isolated {
+		return nil, errors.New("lightning address not found")
+	}
+	return &app, nil
+}
+
+// lightningAddress returns the full lightning address of an app, if it has one
+func (api *api) lightningAddress(app *db.App) string {
+	if app.LightningAddressUsername == nil {
+		return ""
+	}
+	domain := api.cfg.GetEnv().GetLightningAddressDomain()
+	if domain == "" {
+		return ""
+	}
+	return *app.LightningAddressUsername + "@" + domain
+}
+
+func (api *api) lnurlPayMetadata(app *db.App) string {
+	lightningAddress := api.lightningAddress(app)
+	metadata, _ := json.Marshal([][]string{
+		{"text/plain", "Payment to " + lightningAddress},
+		{"text/identifier", lightningAddress},
+	})
+	return string(metadata)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/tests/mocks"
+	"github.com/getAlby/hub/transactions"
+)
+
+func TestAppLightningAddress(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	svc.Cfg.GetEnv().BaseUrl = "https://hub.example.com"
+
+	mockSvc := mocks.NewMockService(t)
+	mockSvc.On("GetLNClient").Return(svc.LNClient)
+	mockSvc.On("GetTransactionsService").Return(transactions.NewTransactionsService(svc.DB, svc.EventPublisher))
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg, svc: mockSvc, keys: svc.Keys, appsSvc: svc.AppsService}
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	err = theAPI.SetAppLightningAddress(app.ID, "alice")
+	assert.EqualError(t, err, "lightning addresses can only be assigned to isolated apps")
+
+	app.Isolated = true
+	require.NoError(t, svc.DB.Save(app).Error)
+
+	err = theAPI.SetAppLightningAddress(app.ID, "alice@example.com")
+	assert.EqualError(t, err, "username can only contain a-z, 0-9, '.', '_' and '-'")
+
+	require.NoError(t, theAPI.SetAppLightningAddress(app.ID, "Alice"))
+	app = svc.AppsService.GetAppById(app.ID)
+	assert.Equal(t, "alice@hub.example.com", theAPI.GetApp(app).LightningAddress)
+
+	otherApp, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	otherApp.Isolated = true
+	require.NoError(t, svc.DB.Save(otherApp).Error)
+	err = theAPI.SetAppLightningAddress(otherApp.ID, "alice")
+	assert.EqualError(t, err, "lightning address is already taken")
+
+	lnurlPayResponse, err := theAPI.GetLnurlPay("alice")
+	require.NoError(t, err)
+	assert.Equal(t, "payRequest", lnurlPayResponse.Tag)
+	assert.Equal(t, "https://hub.example.com/api/lnurlp/alice/callback", lnurlPayResponse.Callback)
+	assert.Contains(t, lnurlPayResponse.Metadata, "alice@hub.example.com")
+
+	_, err = theAPI.GetLnurlPay("bob")
+	assert.EqualError(t, err, "lightning address not found")
+
+	_, err = theAPI.GetLnurlPayInvoice(context.TODO(), "alice", 1, "")
+	assert.Error(t, err)
+
+	lnurlPayInvoiceResponse, err := theAPI.GetLnurlPayInvoice(context.TODO(), "alice", 123_000, "thanks!")
+	require.NoError(t, err)
+	assert.NotEmpty(t, lnurlPayInvoiceResponse.Pr)
+
+	// the invoice is credited to the app
+	var transaction db.Transaction
+	require.NoError(t, svc.DB.First(&transaction, &db.Transaction{PaymentRequest: lnurlPayInvoiceResponse.Pr}).Error)
+	assert.Equal(t, app.ID, *transaction.AppId)
+	assert.Equal(t, constants.TRANSACTION_TYPE_INCOMING, transaction.Type)
+	descriptionHash := sha256.Sum256([]byte(lnurlPayResponse.Metadata))
+	assert.Equal(t, hex.EncodeToString(descriptionHash[:]), transaction.DescriptionHash)
+
+	require.NoError(t, theAPI.SetAppLightningAddress(app.ID, ""))
+	_, err = theAPI.GetLnurlPay("alice")
+	assert.EqualError(t, err, "lightning address not found")
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/permissions"
+)
+
+// AddAppScope grants a single additional scope to an existing app.
+// The new permission inherits the budget and expiry of the app.
+func (api *api) AddAppScope(userApp *db.App, scope string) error {
+	if !slices.Contains(permissions.AllScopes(), scope) {
+		return fmt.Errorf("did not recognize requested scope: %s", scope)
+	}
+
+	scopes, err := api.getAppScopes(userApp.ID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(scopes, scope) {
+		return fmt.Errorf("app already has the %s scope", scope)
+	}
+
+	return api.UpdateApp(userApp, &UpdateAppRequest{
+		Scopes: append(scopes, scope),
+	})
+}
+
+// RemoveAppScope revokes a single scope from an existing app
+func (api *api) RemoveAppScope(userApp *db.App, scope string) error {
+	scopes, err := api.getAppScopes(userApp.ID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(scopes, scope) {
+		return fmt.Errorf("app does not have the %s scope", scope)
+	}
+
+	return api.UpdateApp(userApp, &UpdateAppRequest{
+		Scopes: slices.DeleteFunc(scopes, func(existingScope string) bool {
+			return existingScope == scope
+		}),
+	})
+}
+
+func (api *api) ListAppAuditLogs(appId uint) ([]AppAuditLog, error) {
+	var dbAuditLogs []db.AppAuditLog
+	err := api.db.Where("app_id = ?", appId).Order("id DESC").Find(&dbAuditLogs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogs := []AppAuditLog{}
+	for _, dbAuditLog := range dbAuditLogs {
+		auditLogs = append(auditLogs, AppAuditLog{
+			ID:        dbAuditLog.ID,
+			Action:    dbAuditLog.Action,
+			Details:   json.RawMessage(dbAuditLog.Details),
+			CreatedAt: dbAuditLog.CreatedAt,
+		})
+	}
+	return auditLogs, nil
+}
+
+func (api *api) getAppScopes(appId uint) ([]string, error) {
+	var scopes []string
+	err := api.db.Model(&db.AppPermission{}).Where("app_id = ?", appId).Pluck("scope", &scopes).Error
+	return scopes, err
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/getAlby/hub/apps"
+)
+
+func (api *api) ListAppTemplates() []AppTemplate {
+	return apps.GetAppTemplates()
+}
+
+// applyAppTemplate fills in the scopes and budget which are not explicitly set in the request
+func applyAppTemplate(createAppRequest *CreateAppRequest) error {
+	template := apps.GetAppTemplate(createAppRequest.Template)
+	if template == nil {
+		return fmt.Errorf("unknown app template: %s", createAppRequest.Template)
+	}
+
+	if len(createAppRequest.Scopes) == 0 {
+		createAppRequest.Scopes = template.Scopes
+	}
+	if createAppRequest.MaxAmountSat == 0 && createAppRequest.MaxAmountFiat == 0 && createAppRequest.BudgetRenewal == "" {
+		createAppRequest.MaxAmountSat = template.MaxAmountSat
+		createAppRequest.BudgetRenewal = template.BudgetRenewal
+	}
+	return nil
+}
@@ -1,10 +1,20 @@
 package api
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/nip47/permissions"
+	"github.com/getAlby/hub/tests"
 	"github.com/getAlby/hub/tests/mocks"
+	"github.com/getAlby/hub/transactions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,3 +31,263 @@ func TestCreateApp_SuperuserScopeIncorrectPassword(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, "incorrect unlock password to create app with superuser permission", err.Error())
 }
+
+func TestAddRemoveAppScope(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockSvc := mocks.NewMockService(t)
+	mockSvc.On("GetEventPublisher").Return(svc.EventPublisher)
+	theAPI := &api{db: svc.DB, svc: mockSvc}
+
+	// created with the get_info scope
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	require.NoError(t, theAPI.AddAppScope(app, constants.GET_BALANCE_SCOPE))
+	assert.EqualError(t, theAPI.AddAppScope(app, constants.GET_BALANCE_SCOPE), "app already has the get_balance scope")
+	assert.EqualError(t, theAPI.AddAppScope(app, "unknown"), "did not recognize requested scope: unknown")
+
+	scopes, err := theAPI.getAppScopes(app.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{constants.GET_INFO_SCOPE, constants.GET_BALANCE_SCOPE}, scopes)
+
+	require.NoError(t, theAPI.RemoveAppScope(app, constants.GET_INFO_SCOPE))
+	assert.EqualError(t, theAPI.RemoveAppScope(app, constants.GET_BALANCE_SCOPE), "won't update an app to have no request methods")
+
+	scopes, err = theAPI.getAppScopes(app.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{constants.GET_BALANCE_SCOPE}, scopes)
+
+	auditLogs, err := theAPI.ListAppAuditLogs(app.ID)
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 2)
+	assert.Equal(t, apps.AUDIT_ACTION_SCOPE_REMOVED, auditLogs[0].Action)
+	assert.JSONEq(t, `{"scope":"get_info"}`, string(auditLogs[0].Details))
+	assert.Equal(t, apps.AUDIT_ACTION_SCOPE_ADDED, auditLogs[1].Action)
+	assert.JSONEq(t, `{"scope":"get_balance"}`, string(auditLogs[1].Details))
+}
+
+func TestApplyAppTemplate(t *testing.T) {
+	createAppRequest := &CreateAppRequest{Template: "point-of-sale"}
+	require.NoError(t, applyAppTemplate(createAppRequest))
+	assert.Equal(t, []string{constants.MAKE_INVOICE_SCOPE, constants.LOOKUP_INVOICE_SCOPE, constants.GET_INFO_SCOPE, constants.NOTIFICATIONS_SCOPE}, createAppRequest.Scopes)
+	assert.Equal(t, constants.BUDGET_RENEWAL_NEVER, createAppRequest.BudgetRenewal)
+
+	// explicit settings take precedence over the template
+	createAppRequest = &CreateAppRequest{Template: "nostr-zaps", Scopes: []string{constants.PAY_INVOICE_SCOPE}, MaxAmountSat: 500, BudgetRenewal: constants.BUDGET_RENEWAL_DAILY}
+	require.NoError(t, applyAppTemplate(createAppRequest))
+	assert.Equal(t, []string{constants.PAY_INVOICE_SCOPE}, createAppRequest.Scopes)
+	assert.Equal(t, uint64(500), createAppRequest.MaxAmountSat)
+	assert.Equal(t, constants.BUDGET_RENEWAL_DAILY, createAppRequest.BudgetRenewal)
+
+	createAppRequest = &CreateAppRequest{Template: "nostr-zaps", Scopes: []string{constants.PAY_INVOICE_SCOPE}}
+	require.NoError(t, applyAppTemplate(createAppRequest))
+	assert.Equal(t, uint64(10_000), createAppRequest.MaxAmountSat)
+	assert.Equal(t, constants.BUDGET_RENEWAL_MONTHLY, createAppRequest.BudgetRenewal)
+
+	assert.EqualError(t, applyAppTemplate(&CreateAppRequest{Template: "unknown"}), "unknown app template: unknown")
+}
+
+func TestTransfer(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// pubkey matches mock invoice = self payment
+	svc.LNClient.(*tests.MockLn).Pubkey = "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c"
+
+	mockSvc := mocks.NewMockService(t)
+	mockSvc.On("GetLNClient").Return(svc.LNClient)
+	mockSvc.On("GetTransactionsService").Return(transactions.NewTransactionsService(svc.DB, svc.EventPublisher))
+	theAPI := &api{db: svc.DB, svc: mockSvc, appsSvc: svc.AppsService}
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	_, err = theAPI.Transfer(context.TODO(), nil, &app.ID, 123_000)
+	assert.EqualError(t, err, "app is not isolated")
+	_, err = theAPI.Transfer(context.TODO(), nil, nil, 123_000)
+	assert.EqualError(t, err, "cannot transfer to the same balance")
+	_, err = theAPI.Transfer(context.TODO(), &app.ID, &app.ID, 123_000)
+	assert.EqualError(t, err, "cannot transfer to the same balance")
+	_, err = theAPI.Transfer(context.TODO(), nil, &app.ID, 0)
+	assert.EqualError(t, err, "amount must be greater than zero")
+
+	app.Isolated = true
+	require.NoError(t, svc.DB.Save(app).Error)
+
+	// the mock LNClient creates invoices of 1 sat
+	transferResponse, err := theAPI.Transfer(context.TODO(), nil, &app.ID, 1_000)
+	require.NoError(t, err)
+	assert.Equal(t, "settled", transferResponse.Sent.State)
+	assert.Nil(t, transferResponse.Sent.AppId)
+	assert.Equal(t, uint64(0), transferResponse.Sent.FeesPaid)
+	assert.Equal(t, "settled", transferResponse.Received.State)
+	assert.Equal(t, &app.ID, transferResponse.Received.AppId)
+	assert.Equal(t, int64(1_000), queries.GetIsolatedBalance(svc.DB, app.ID))
+
+	// both sides are recorded as a transfer
+	for _, transaction := range []*Transaction{transferResponse.Sent, transferResponse.Received} {
+		transfer, ok := transaction.Metadata["transfer"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Nil(t, transfer["from_app_id"])
+		assert.Equal(t, float64(app.ID), transfer["to_app_id"])
+	}
+}
+
+func TestAddRemoveAppDestination(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := &api{db: svc.DB}
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	nodePubkey := "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c"
+	var resolvedUrls []string
+	defer func(original func(ctx context.Context, lnurlpUrl string) (string, error)) {
+		resolveLnurlPayNode = original
+	}(resolveLnurlPayNode)
+	resolveLnurlPayNode = func(ctx context.Context, lnurlpUrl string) (string, error) {
+		resolvedUrls = append(resolvedUrls, lnurlpUrl)
+		return nodePubkey, nil
+	}
+
+	_, err = theAPI.AddAppDestination(context.TODO(), app, &AddAppDestinationRequest{Type: constants.APP_DESTINATION_TYPE_NODE, Value: "02abc"})
+	assert.EqualError(t, err, "invalid node pubkey")
+	_, err = theAPI.AddAppDestination(context.TODO(), app, &AddAppDestinationRequest{Type: constants.APP_DESTINATION_TYPE_LNURL_DOMAIN, Value: "http://example.com/lnurlp/pos"})
+	assert.EqualError(t, err, "an https LNURL-pay url of the domain is required")
+	_, err = theAPI.AddAppDestination(context.TODO(), app, &AddAppDestinationRequest{Type: "unknown", Value: "x"})
+	assert.EqualError(t, err, "invalid destination type: unknown")
+
+	nodeDestination, err := theAPI.AddAppDestination(context.TODO(), app, &AddAppDestinationRequest{Type: constants.APP_DESTINATION_TYPE_NODE, Value: strings.ToUpper(nodePubkey)})
+	require.NoError(t, err)
+	assert.Equal(t, nodePubkey, nodeDestination.Value)
+	assert.Equal(t, nodePubkey, nodeDestination.NodePubkey)
+	_, err = theAPI.AddAppDestination(context.TODO(), app, &AddAppDestinationRequest{Type: constants.APP_DESTINATION_TYPE_NODE, Value: nodePubkey})
+	assert.EqualError(t, err, "app already has the destination "+nodePubkey)
+
+	addressDestination, err := theAPI.AddAppDestination(context.TODO(), app, &AddAppDestinationRequest{Type: constants.APP_DESTINATION_TYPE_LIGHTNING_ADDRESS, Value: "Hello@Example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello@example.com", addressDestination.Value)
+	assert.Equal(t, nodePubkey, addressDestination.NodePubkey)
+
+	domainDestination, err := theAPI.AddAppDestination(context.TODO(), app, &AddAppDestinationRequest{Type: constants.APP_DESTINATION_TYPE_LNURL_DOMAIN, Value: "https://pay.example.com/lnurlp/pos"})
+	require.NoError(t, err)
+	assert.Equal(t, "pay.example.com", domainDestination.Value)
+	assert.Equal(t, []string{"https://example.com/.well-known/lnurlp/hello", "https://pay.example.com/lnurlp/pos"}, resolvedUrls)
+
+	appDestinations, err := theAPI.ListAppDestinations(app.ID)
+	require.NoError(t, err)
+	assert.Len(t, appDestinations, 3)
+
+	require.NoError(t, theAPI.RemoveAppDestination(app, addressDestination.ID))
+	assert.EqualError(t, theAPI.RemoveAppDestination(app, addressDestination.ID), "destination not found")
+
+	appDestinations, err = theAPI.ListAppDestinations(app.ID)
+	require.NoError(t, err)
+	assert.Len(t, appDestinations, 2)
+
+	auditLogs, err := theAPI.ListAppAuditLogs(app.ID)
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 4)
+	assert.Equal(t, apps.AUDIT_ACTION_DESTINATION_REMOVED, auditLogs[0].Action)
+	assert.Equal(t, apps.AUDIT_ACTION_DESTINATION_ADDED, auditLogs[1].Action)
+}
+
+func TestCreateApp_SingleUse(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := createExportTestAPI(t, svc)
+	require.NoError(t, svc.Cfg.SetUpdate("Relay", "wss://relay1.example.com,wss://relay2.example.com", ""))
+
+	createAppResponse, err := theAPI.CreateApp(&CreateAppRequest{
+		Name:      "Kiosk",
+		Scopes:    []string{constants.PAY_INVOICE_SCOPE},
+		SingleUse: true,
+	})
+	require.NoError(t, err)
+	// only the first relay is included in the pairing URI
+	assert.Equal(t, []string{"wss://relay1.example.com"}, createAppResponse.RelayUrls)
+	assert.NotContains(t, createAppResponse.PairingUri, "relay2")
+
+	app := svc.AppsService.GetAppById(createAppResponse.Id)
+	require.NotNil(t, app)
+	assert.True(t, app.SingleUse)
+}
+
+func TestCreateApp_ReceiveOnly(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := createExportTestAPI(t, svc)
+	// sub-wallets are only supported on some backends
+	require.NoError(t, svc.Cfg.SetUpdate("LNBackendType", config.LDKBackendType, ""))
+
+	_, err = theAPI.CreateApp(&CreateAppRequest{
+		Name:        "Donations",
+		Scopes:      []string{constants.MAKE_INVOICE_SCOPE, constants.PAY_INVOICE_SCOPE},
+		ReceiveOnly: true,
+	})
+	assert.EqualError(t, err, "receive-only app cannot have the pay_invoice scope")
+
+	createAppResponse, err := theAPI.CreateApp(&CreateAppRequest{
+		Name:        "Donations",
+		ReceiveOnly: true,
+	})
+	require.NoError(t, err)
+
+	app := svc.AppsService.GetAppById(createAppResponse.Id)
+	require.NotNil(t, app)
+	assert.True(t, app.ReceiveOnly)
+	assert.True(t, app.Isolated)
+
+	apiApp := theAPI.GetApp(app)
+	assert.ElementsMatch(t, permissions.ReceiveOnlyScopes(), apiApp.Scopes)
+}
+
+func TestCreateApp_NostrProfile(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := createExportTestAPI(t, svc)
+	mockSvc := mocks.NewMockService(t)
+	mockSvc.On("GetEventPublisher").Return(svc.EventPublisher)
+	theAPI.svc = mockSvc
+
+	_, err = theAPI.CreateApp(&CreateAppRequest{Name: "Damus", Scopes: []string{constants.GET_INFO_SCOPE}, NostrPubkey: "abc"})
+	assert.EqualError(t, err, "invalid nostr pubkey")
+
+	pubkey := "3bf0c63fcb93463407af97a5e5ee64fa883d107ef9e558472c4eb9aaaefa459d"
+	createAppResponse, err := theAPI.CreateApp(&CreateAppRequest{
+		Name:        "Damus",
+		Scopes:      []string{constants.GET_INFO_SCOPE},
+		NostrPubkey: "npub180cvv07tjdrrgpa0j7j7tmnyl2yr6yr7l8j4s3evf6u64th6gkwsyjh6w6",
+	})
+	require.NoError(t, err)
+
+	// the profile is only shown once it was fetched
+	app := theAPI.GetApp(svc.AppsService.GetAppById(createAppResponse.Id))
+	require.NotNil(t, app.NostrPubkey)
+	assert.Equal(t, pubkey, *app.NostrPubkey)
+	assert.Nil(t, app.NostrProfile)
+
+	require.NoError(t, svc.DB.Create(&db.NostrProfile{Pubkey: pubkey, Name: "fiatjaf", FetchedAt: time.Now()}).Error)
+	listAppsResponse, err := theAPI.ListApps(0, 0, ListAppsFilters{}, "")
+	require.NoError(t, err)
+	require.Len(t, listAppsResponse.Apps, 1)
+	require.NotNil(t, listAppsResponse.Apps[0].NostrProfile)
+	assert.Equal(t, "fiatjaf", listAppsResponse.Apps[0].NostrProfile.Name)
+
+	emptyPubkey := ""
+	require.NoError(t, theAPI.UpdateApp(svc.AppsService.GetAppById(createAppResponse.Id), &UpdateAppRequest{NostrPubkey: &emptyPubkey}))
+	assert.Nil(t, svc.AppsService.GetAppById(createAppResponse.Id).NostrPubkey)
+}
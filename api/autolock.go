@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/getAlby/hub/autolock"
+	"github.com/getAlby/hub/service/keys"
+)
+
+// LockSpending locks the hub immediately, as if it was inactive for too long
+func (api *api) LockSpending() {
+	autolock.Lock()
+}
+
+// UnlockSpending derives the keys which were dropped when the hub was locked and resumes spending
+func (api *api) UnlockSpending(unlockPassword string) error {
+	if !autolock.IsLocked() {
+		return nil
+	}
+	if !api.cfg.CheckUnlockPassword(unlockPassword) {
+		return errors.New("wrong password")
+	}
+	if lockableKeys, ok := api.keys.(keys.Lockable); ok {
+		if err := lockableKeys.Unlock(api.cfg, unlockPassword); err != nil {
+			return err
+		}
+	}
+	autolock.Unlock()
+	return nil
+}
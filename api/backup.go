@@ -1,6 +1,8 @@
 package api
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -11,24 +13,26 @@ import (
 	"os"
 	"path/filepath"
 
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/sha256"
+	"github.com/sirupsen/logrus"
 
+	"github.com/getAlby/hub/backups"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/utils"
-	"golang.org/x/crypto/pbkdf2"
+	"github.com/getAlby/hub/version"
 )
 
-func (api *api) CreateBackup(unlockPassword string, w io.Writer) error {
+func (api *api) CreateBackup(unlockPassword string, seedPassphrase string, w io.Writer) error {
 	logger.Logger.Info("Creating backup to migrate Alby Hub to another device")
 	var err error
 
 	if !api.cfg.CheckUnlockPassword(unlockPassword) {
 		return errors.New("invalid unlock password")
 	}
+	// the backup contains the recovery phrase encrypted with the unlock password
+	if api.IsSeedPassphraseEnabled() && !api.checkSeedPassphrase(seedPassphrase) {
+		return errors.New("wrong seed passphrase")
+	}
 
 	autoUnlockPassword, err := api.cfg.Get("AutoUnlockPassword", "")
 	if err != nil {
@@ -101,35 +105,28 @@ func (api *api) CreateBackup(unlockPassword string, w io.Writer) error {
 		filesToArchive = append(filesToArchive, lnFiles...)
 	}
 
-	cw, err := encryptingWriter(w, unlockPassword)
+	cw, err := backups.EncryptingWriter(w, unlockPassword)
 	if err != nil {
 		return fmt.Errorf("failed to create encrypted writer: %w", err)
 	}
 
-	zw := zip.NewWriter(cw)
-	defer zw.Close()
-
-	addFileToZip := func(fsPath, zipPath string) error {
-		inF, err := os.Open(fsPath)
-		if err != nil {
-			return fmt.Errorf("failed to open source file for reading: %w", err)
-		}
-		defer inF.Close()
-
-		outW, err := zw.Create(zipPath)
-		if err != nil {
-			return fmt.Errorf("failed to create zip entry: %w", err)
-		}
-
-		_, err = io.Copy(outW, inF)
+	migrationId := make([]byte, 16)
+	if _, err := rand.Read(migrationId); err != nil {
 		return err
 	}
+	manifest := &backups.MigrationManifest{
+		MigrationId: hex.EncodeToString(migrationId),
+		CreatedAt:   time.Now().UTC(),
+		HubVersion:  version.Tag,
+		BackendType: api.cfg.GetEnv().LNBackendType,
+	}
+	mw := backups.NewMigrationWriter(cw, manifest)
 
 	// Locate the main database file.
 	dbFilePath := api.cfg.GetEnv().DatabaseUri
 	// Add the database file to the archive.
 	logger.Logger.WithField("nwc.db", dbFilePath).Info("adding nwc db to zip")
-	err = addFileToZip(dbFilePath, "nwc.db")
+	err = mw.AddFile(dbFilePath, "nwc.db")
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to zip nwc db")
 		return fmt.Errorf("failed to write nwc db file to zip: %w", err)
@@ -144,66 +141,86 @@ func (api *api) CreateBackup(unlockPassword string, w io.Writer) error {
 		}
 
 		// Ensure forward slashes for zip format compatibility.
-		err = addFileToZip(fileToArchive, filepath.ToSlash(relPath))
+		err = mw.AddFile(fileToArchive, filepath.ToSlash(relPath))
 		if err != nil {
 			logger.Logger.WithError(err).Error("Failed to write file to zip")
 			return fmt.Errorf("failed to write input file to zip: %w", err)
 		}
 	}
 
-	logger.Logger.Info("Successfully created backup to migrate Alby Hub to another device")
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("failed to finish backup: %w", err)
+	}
+
+	// The node state now lives in the backup, so this hub must not be started again
+	// once the backup was restored on the new device.
+	if err := backups.WriteMigrationMarker(workDir, manifest.MigrationId); err != nil {
+		logger.Logger.WithError(err).Error("Failed to mark hub as migrated")
+		return fmt.Errorf("failed to mark hub as migrated: %w", err)
+	}
+
+	logger.Logger.WithField("migration_id", manifest.MigrationId).Info("Successfully created backup to migrate Alby Hub to another device")
 
 	return nil
 }
 
-func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
-	logger.Logger.Info("Restoring migration backup file")
-
-	workDir, err := filepath.Abs(api.cfg.GetEnv().Workdir)
+// ValidateBackup checks a migration backup without restoring it
+func (api *api) ValidateBackup(unlockPassword string, r io.Reader) (*BackupInfo, error) {
+	zr, closeBackup, err := api.openBackup(unlockPassword, r)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute workdir: %w", err)
-	}
-
-	if strings.HasPrefix(api.cfg.GetEnv().DatabaseUri, "file:") {
-		return errors.New("cannot restore backup when database path is a file URI")
-	}
-
-	if api.db.Dialector.Name() != "sqlite" {
-		return errors.New("migration to non-sqlite backend is currently not supported")
+		return nil, err
 	}
+	defer closeBackup()
 
-	cr, err := decryptingReader(r, unlockPassword)
+	manifest, err := backups.VerifyMigrationArchive(zr, unlockPassword, api.cfg.GetEnv().Workdir)
 	if err != nil {
-		return fmt.Errorf("failed to create decrypted reader: %w", err)
+		return nil, err
 	}
+	return toBackupInfo(manifest, len(zr.File)), nil
+}
 
-	tmpF, err := os.CreateTemp(api.cfg.GetEnv().Workdir, "albyhub-*.bkp")
-	if err != nil {
-		return fmt.Errorf("failed to create temporary output file: %w", err)
+func (api *api) RestoreBackup(unlockPassword string, seedPassphrase string, r io.Reader) error {
+	logger.Logger.Info("Restoring migration backup file")
+
+	// restoring replaces all data of the hub
+	if api.cfg.SetupCompleted() {
+		return errors.New("setup already completed")
 	}
-	tmpName := tmpF.Name()
-	defer os.Remove(tmpName)
-	defer tmpF.Close()
 
-	zipSize, err := io.Copy(tmpF, cr)
+	workDir, err := filepath.Abs(api.cfg.GetEnv().Workdir)
 	if err != nil {
-		return fmt.Errorf("failed to decrypt backup data into temporary file: %w", err)
+		return fmt.Errorf("failed to get absolute workdir: %w", err)
 	}
 
-	if err = tmpF.Sync(); err != nil {
-		return fmt.Errorf("failed to flush temporary file: %w", err)
+	if strings.HasPrefix(api.cfg.GetEnv().DatabaseUri, "file:") {
+		return errors.New("cannot restore backup when database path is a file URI")
 	}
 
-	if _, err = tmpF.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to seek to beginning of temporary file: %w", err)
+	zr, closeBackup, err := api.openBackup(unlockPassword, r)
+	if err != nil {
+		return err
 	}
+	defer closeBackup()
 
-	zr, err := zip.NewReader(tmpF, zipSize)
+	manifest, err := backups.VerifyMigrationArchive(zr, unlockPassword, api.cfg.GetEnv().Workdir)
 	if err != nil {
-		return fmt.Errorf("failed to create zip reader: %w", err)
+		return fmt.Errorf("backup verification failed: %w", err)
+	}
+	if manifest != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"migration_id": manifest.MigrationId,
+			"created_at":   manifest.CreatedAt,
+			"hub_version":  manifest.HubVersion,
+		}).Info("Verified migration backup")
 	}
 
 	extractZipEntry := func(zipFile *zip.File) error {
+		if zipFile.Name == backups.MigrationManifestName {
+			return nil
+		}
+		if !filepath.IsLocal(filepath.FromSlash(zipFile.Name)) {
+			return fmt.Errorf("invalid path in backup: %q", zipFile.Name)
+		}
 		fsFilePath := filepath.Join(workDir, "restore", filepath.FromSlash(zipFile.Name))
 
 		if err = os.MkdirAll(filepath.Dir(fsFilePath), 0700); err != nil {
@@ -238,6 +255,20 @@ func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
 	}
 	logger.Logger.WithField("count", len(zr.File)).Info("Extracted files")
 
+	// snapshots of hubs with a seed passphrase only contain the recovery phrase protected by it
+	err = backups.RestoreProtectedMnemonic(filepath.Join(workDir, "restore", "nwc.db"), unlockPassword, seedPassphrase)
+	if err != nil {
+		// the hub must not be started from a backup without its recovery phrase
+		if removeErr := os.RemoveAll(filepath.Join(workDir, "restore")); removeErr != nil {
+			logger.Logger.WithError(removeErr).Error("Failed to remove extracted backup")
+		}
+		return fmt.Errorf("failed to restore recovery phrase: %w", err)
+	}
+
+	if _, err := api.recoverySvc.StartArchiveRecovery(filepath.Join(workDir, "restore")); err != nil {
+		logger.Logger.WithError(err).Error("Failed to record recovery")
+	}
+
 	go func() {
 		logger.Logger.Info("Backup restored. Shutting down Alby Hub...")
 		api.svc.Shutdown()
@@ -257,64 +288,51 @@ func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
 	return nil
 }
 
-func encryptingWriter(w io.Writer, password string) (io.Writer, error) {
-	salt := make([]byte, 8)
-	if _, err := rand.Read(salt); err != nil {
-		return nil, fmt.Errorf("failed to generate salt: %w", err)
+// openBackup decrypts a migration backup into a temporary file
+func (api *api) openBackup(unlockPassword string, r io.Reader) (*zip.Reader, func(), error) {
+	if api.db.Dialector.Name() != "sqlite" {
+		return nil, nil, errors.New("migration to non-sqlite backend is currently not supported")
 	}
 
-	encKey := pbkdf2.Key([]byte(password), salt, 4096, 32, sha256.New)
-	block, err := aes.NewCipher(encKey)
+	cr, err := backups.DecryptingReader(r, unlockPassword)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
-	}
-
-	iv := make([]byte, aes.BlockSize)
-	if _, err = rand.Read(iv); err != nil {
-		return nil, fmt.Errorf("failed to generate IV: %w", err)
+		return nil, nil, fmt.Errorf("failed to create decrypted reader: %w", err)
 	}
 
-	_, err = w.Write(salt)
+	tmpF, err := os.CreateTemp(api.cfg.GetEnv().Workdir, "albyhub-*.bkp")
 	if err != nil {
-		return nil, fmt.Errorf("failed to write salt: %w", err)
+		return nil, nil, fmt.Errorf("failed to create temporary output file: %w", err)
+	}
+	closeBackup := func() {
+		tmpF.Close()
+		os.Remove(tmpF.Name())
 	}
 
-	_, err = w.Write(iv)
+	zipSize, err := io.Copy(tmpF, cr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write IV: %w", err)
+		closeBackup()
+		return nil, nil, fmt.Errorf("failed to decrypt backup data into temporary file: %w", err)
 	}
 
-	stream := cipher.NewOFB(block, iv)
-	cw := &cipher.StreamWriter{
-		S: stream,
-		W: w,
+	zr, err := zip.NewReader(tmpF, zipSize)
+	if err != nil {
+		closeBackup()
+		return nil, nil, fmt.Errorf("failed to read backup, is the unlock password correct? %w", err)
 	}
-
-	return cw, nil
+	return zr, closeBackup, nil
 }
 
-func decryptingReader(r io.Reader, password string) (io.Reader, error) {
-	salt := make([]byte, 8)
-	if _, err := io.ReadFull(r, salt); err != nil {
-		return nil, fmt.Errorf("failed to read salt: %w", err)
-	}
-
-	iv := make([]byte, aes.BlockSize)
-	if _, err := io.ReadFull(r, iv); err != nil {
-		return nil, fmt.Errorf("failed to read IV: %w", err)
-	}
-
-	encKey := pbkdf2.Key([]byte(password), salt, 4096, 32, sha256.New)
-	block, err := aes.NewCipher(encKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+func toBackupInfo(manifest *backups.MigrationManifest, fileCount int) *BackupInfo {
+	backupInfo := &BackupInfo{
+		Files: fileCount,
 	}
-
-	stream := cipher.NewOFB(block, iv)
-	cr := &cipher.StreamReader{
-		S: stream,
-		R: r,
+	if manifest != nil {
+		backupInfo.Files = len(manifest.Files)
+		backupInfo.HasManifest = true
+		backupInfo.MigrationId = manifest.MigrationId
+		backupInfo.CreatedAt = &manifest.CreatedAt
+		backupInfo.HubVersion = manifest.HubVersion
+		backupInfo.BackendType = manifest.BackendType
 	}
-
-	return cr, nil
+	return backupInfo
 }
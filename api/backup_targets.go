@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+
+	"github.com/getAlby/hub/backups"
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) ListBackupTargets() ([]BackupTarget, error) {
+	dbBackupTargets, err := api.backupsSvc.ListBackupTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	backupTargets := []BackupTarget{}
+	for _, dbBackupTarget := range dbBackupTargets {
+		backupTargets = append(backupTargets, *toApiBackupTarget(&dbBackupTarget))
+	}
+	return backupTargets, nil
+}
+
+func (api *api) CreateBackupTarget(createBackupTargetRequest *CreateBackupTargetRequest) (*BackupTarget, error) {
+	backupTarget, err := api.backupsSvc.CreateBackupTarget(&backups.CreateBackupTargetParams{
+		Name:            createBackupTargetRequest.Name,
+		Endpoint:        createBackupTargetRequest.Endpoint,
+		Region:          createBackupTargetRequest.Region,
+		Bucket:          createBackupTargetRequest.Bucket,
+		Prefix:          createBackupTargetRequest.Prefix,
+		AccessKeyId:     createBackupTargetRequest.AccessKeyId,
+		SecretAccessKey: createBackupTargetRequest.SecretAccessKey,
+		IntervalHours:   createBackupTargetRequest.IntervalHours,
+		KeepLast:        createBackupTargetRequest.KeepLast,
+		KeepDays:        createBackupTargetRequest.KeepDays,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toApiBackupTarget(backupTarget), nil
+}
+
+func (api *api) UpdateBackupTarget(id uint, updateBackupTargetRequest *UpdateBackupTargetRequest) (*BackupTarget, error) {
+	backupTarget, err := api.backupsSvc.UpdateBackupTarget(id, &backups.UpdateBackupTargetParams{
+		Enabled:       updateBackupTargetRequest.Enabled,
+		IntervalHours: updateBackupTargetRequest.IntervalHours,
+		KeepLast:      updateBackupTargetRequest.KeepLast,
+		KeepDays:      updateBackupTargetRequest.KeepDays,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toApiBackupTarget(backupTarget), nil
+}
+
+func (api *api) DeleteBackupTarget(id uint) error {
+	return api.backupsSvc.DeleteBackupTarget(id)
+}
+
+func (api *api) ListBackupSnapshots(ctx context.Context, id uint) ([]BackupSnapshot, error) {
+	snapshots, err := api.backupsSvc.ListSnapshots(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	backupSnapshots := []BackupSnapshot{}
+	for _, snapshot := range snapshots {
+		backupSnapshots = append(backupSnapshots, toApiBackupSnapshot(&snapshot))
+	}
+	return backupSnapshots, nil
+}
+
+func (api *api) RunBackup(ctx context.Context, id uint, runBackupRequest *RunBackupRequest) (*BackupSnapshot, error) {
+	snapshot, err := api.backupsSvc.RunBackup(ctx, id, runBackupRequest.UnlockPassword)
+	if err != nil {
+		return nil, err
+	}
+	backupSnapshot := toApiBackupSnapshot(snapshot)
+	return &backupSnapshot, nil
+}
+
+func toApiBackupTarget(backupTarget *db.BackupTarget) *BackupTarget {
+	return &BackupTarget{
+		ID:             backupTarget.ID,
+		Name:           backupTarget.Name,
+		Endpoint:       backupTarget.Endpoint,
+		Region:         backupTarget.Region,
+		Bucket:         backupTarget.Bucket,
+		Prefix:         backupTarget.Prefix,
+		AccessKeyId:    backupTarget.AccessKeyId,
+		IntervalHours:  backupTarget.IntervalHours,
+		KeepLast:       backupTarget.KeepLast,
+		KeepDays:       backupTarget.KeepDays,
+		Enabled:        backupTarget.Enabled,
+		LastBackupAt:   backupTarget.LastBackupAt,
+		LastVerifiedAt: backupTarget.LastVerifiedAt,
+		LastError:      backupTarget.LastError,
+		CreatedAt:      backupTarget.CreatedAt,
+	}
+}
+
+func toApiBackupSnapshot(snapshot *backups.Snapshot) BackupSnapshot {
+	return BackupSnapshot{
+		Key:       snapshot.Key,
+		Size:      snapshot.Size,
+		CreatedAt: snapshot.CreatedAt,
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/health"
+)
+
+type ComponentStatus string
+
+const (
+	ComponentStatusOk       ComponentStatus = "ok"
+	ComponentStatusDegraded ComponentStatus = "degraded"
+	ComponentStatusDown     ComponentStatus = "down"
+)
+
+type ComponentHealth struct {
+	Status  ComponentStatus `json:"status"`
+	Message string          `json:"message,omitempty"`
+	Details any             `json:"details,omitempty"`
+}
+
+type ComponentHealthResponse struct {
+	Status     ComponentStatus            `json:"status"`
+	CheckedAt  time.Time                  `json:"checkedAt"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type ReplicationHealthDetails struct {
+	*db.ReplicationStatus
+	// set if the database was restored from the replica when the hub started
+	RestoredAt string `json:"restoredAt,omitempty"`
+}
+
+type RelaysHealthDetails struct {
+	Online int `json:"online"`
+	Total  int `json:"total"`
+}
+
+// GetComponentHealth checks the database, lightning backend, relay connections, background jobs
+// and, if it is enabled, the replication of the database.
+// Unlike Health it only uses local state, so it can be polled frequently by probes.
+func (api *api) GetComponentHealth(ctx context.Context) *ComponentHealthResponse {
+	components := map[string]ComponentHealth{
+		"database": api.getDatabaseHealth(ctx),
+		"lnclient": api.getLNClientHealth(ctx),
+		"relays":   api.getRelaysHealth(),
+		"jobs":     getJobsHealth(),
+	}
+	if api.cfg.GetEnv().DatabaseReplication != "" {
+		components["replication"] = api.getReplicationHealth()
+	}
+
+	status := ComponentStatusOk
+	for _, component := range components {
+		if component.Status == ComponentStatusDown {
+			status = ComponentStatusDown
+			break
+		}
+		if component.Status == ComponentStatusDegraded {
+			status = ComponentStatusDegraded
+		}
+	}
+
+	return &ComponentHealthResponse{
+		Status:     status,
+		CheckedAt:  time.Now(),
+		Components: components,
+	}
+}
+
+func (api *api) getDatabaseHealth(ctx context.Context) ComponentHealth {
+	if err := api.db.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+		return ComponentHealth{
+			Status:  ComponentStatusDown,
+			Message: "Database is not reachable",
+		}
+	}
+	return ComponentHealth{Status: ComponentStatusOk}
+}
+
+func (api *api) getLNClientHealth(ctx context.Context) ComponentHealth {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		message := "Node is not running"
+		if startupState := api.svc.GetStartupState(); startupState != "" {
+			message = fmt.Sprintf("Node is not running: %s", startupState)
+		}
+		return ComponentHealth{
+			Status:  ComponentStatusDown,
+			Message: message,
+		}
+	}
+
+	nodeStatus, err := lnClient.GetNodeStatus(ctx)
+	if err != nil {
+		return ComponentHealth{
+			Status:  ComponentStatusDown,
+			Message: "Failed to get node status",
+		}
+	}
+	if nodeStatus == nil || !nodeStatus.IsReady {
+		return ComponentHealth{
+			Status:  ComponentStatusDown,
+			Message: "Node is not ready",
+		}
+	}
+	return ComponentHealth{Status: ComponentStatusOk}
+}
+
+func (api *api) getRelaysHealth() ComponentHealth {
+	relayStatuses := api.svc.GetRelayStatuses()
+	details := RelaysHealthDetails{
+		Total: len(relayStatuses),
+	}
+	for _, relayStatus := range relayStatuses {
+		if relayStatus.Online {
+			details.Online++
+		}
+	}
+
+	switch {
+	case details.Online == 0:
+		return ComponentHealth{
+			Status:  ComponentStatusDown,
+			Message: "Not connected to any relay",
+			Details: details,
+		}
+	case details.Online < details.Total:
+		return ComponentHealth{
+			Status:  ComponentStatusDegraded,
+			Message: "Some relays are offline",
+			Details: details,
+		}
+	}
+	return ComponentHealth{
+		Status:  ComponentStatusOk,
+		Details: details,
+	}
+}
+
+// replication only degrades the health, as the database itself is still available
+func (api *api) getReplicationHealth() ComponentHealth {
+	dbPath, _ := db.SqlitePath(api.cfg.GetEnv().DatabaseUri)
+	replicationStatus, err := db.GetLitestreamStatus(dbPath)
+	if err != nil {
+		return ComponentHealth{
+			Status:  ComponentStatusDegraded,
+			Message: "Failed to get replication status",
+		}
+	}
+	details := ReplicationHealthDetails{ReplicationStatus: replicationStatus}
+	details.RestoredAt, _ = api.cfg.Get(config.DatabaseRestoredAtKey, "")
+
+	maxLag := time.Duration(api.cfg.GetEnv().DatabaseReplicaMaxLagSeconds) * time.Second
+	switch {
+	case !replicationStatus.Replicating:
+		return ComponentHealth{
+			Status:  ComponentStatusDegraded,
+			Message: "Database is not replicated, is litestream running?",
+			Details: details,
+		}
+	case replicationStatus.Lag > maxLag:
+		return ComponentHealth{
+			Status:  ComponentStatusDegraded,
+			Message: fmt.Sprintf("Replication lags behind by %s", replicationStatus.Lag.Round(time.Second)),
+			Details: details,
+		}
+	}
+	return ComponentHealth{
+		Status:  ComponentStatusOk,
+		Details: details,
+	}
+}
+
+// background jobs only degrade the health, as the hub can still make and receive payments
+func getJobsHealth() ComponentHealth {
+	jobStatuses := health.GetJobStatuses()
+	for _, jobStatus := range jobStatuses {
+		if jobStatus.Failing || jobStatus.Stale {
+			return ComponentHealth{
+				Status:  ComponentStatusDegraded,
+				Message: fmt.Sprintf("Background job %s is not healthy", jobStatus.Name),
+				Details: jobStatuses,
+			}
+		}
+	}
+	return ComponentHealth{
+		Status:  ComponentStatusOk,
+		Details: jobStatuses,
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/migrations"
+)
+
+func (api *api) GetDatabaseMaintenanceStatus() *DatabaseMaintenanceStatus {
+	return api.maintenanceSvc.GetStatus()
+}
+
+// RunDatabaseMaintenance runs the maintenance right away instead of waiting for the next daily run
+func (api *api) RunDatabaseMaintenance(ctx context.Context, runDatabaseMaintenanceRequest *RunDatabaseMaintenanceRequest) (*DatabaseMaintenanceReport, error) {
+	return api.maintenanceSvc.RunMaintenance(ctx, runDatabaseMaintenanceRequest.Vacuum)
+}
+
+func (api *api) GetDatabaseKeyRotation() (*DatabaseKeyRotationStatus, error) {
+	return api.maintenanceSvc.GetKeyRotationStatus()
+}
+
+// RotateDatabaseEncryptionKey starts to re-encrypt the encrypted columns with a new key.
+// Like ChangeUnlockPassword, the node is stopped if the unlock password is changed, and the
+// rotation continues when the hub is unlocked with the new password.
+func (api *api) RotateDatabaseEncryptionKey(rotateDatabaseEncryptionKeyRequest *RotateDatabaseEncryptionKeyRequest) error {
+	if api.svc.GetLNClient() == nil {
+		return errors.New("LNClient not started")
+	}
+
+	changePassword := rotateDatabaseEncryptionKeyRequest.NewUnlockPassword != "" &&
+		rotateDatabaseEncryptionKeyRequest.NewUnlockPassword != rotateDatabaseEncryptionKeyRequest.CurrentUnlockPassword
+	if changePassword {
+		autoUnlockPassword, err := api.cfg.Get("AutoUnlockPassword", "")
+		if err != nil {
+			return err
+		}
+		if autoUnlockPassword != "" {
+			return errors.New("please disable auto-unlock before using this feature")
+		}
+	}
+
+	err := api.maintenanceSvc.RotateEncryptionKey(rotateDatabaseEncryptionKeyRequest.CurrentUnlockPassword, rotateDatabaseEncryptionKeyRequest.NewUnlockPassword)
+	if err != nil {
+		return err
+	}
+
+	if changePassword {
+		return api.Stop()
+	}
+	return nil
+}
+
+// GetDatabaseMigrations lists the applied migrations and the snapshots which allow to roll back an upgrade
+func (api *api) GetDatabaseMigrations() (*DatabaseMigrationsResponse, error) {
+	lastMigration, err := migrations.LastAppliedMigration(api.db)
+	if err != nil {
+		return nil, err
+	}
+	pendingMigrations, err := migrations.PendingMigrations(api.db)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DatabaseMigrationsResponse{
+		LastMigration:     lastMigration,
+		PendingMigrations: pendingMigrations,
+		Snapshots:         []DatabaseMigrationSnapshot{},
+	}
+	dbPath, isSqlite := db.SqlitePath(api.cfg.GetEnv().DatabaseUri)
+	if !isSqlite {
+		return response, nil
+	}
+	response.SnapshotsEnabled = api.cfg.GetEnv().DatabaseMigrationSnapshots
+	response.Snapshots, err = db.ListMigrationSnapshots(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) ListDeadLetters(consumer string, state string) ([]DeadLetter, error) {
+	dbDeadLetters, err := api.svc.GetDeadLetterService().List(consumer, state)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters := []DeadLetter{}
+	for _, dbDeadLetter := range dbDeadLetters {
+		deadLetters = append(deadLetters, *toApiDeadLetter(&dbDeadLetter))
+	}
+	return deadLetters, nil
+}
+
+func (api *api) ReplayDeadLetter(ctx context.Context, id uint) (*DeadLetter, error) {
+	deadLetter, err := api.svc.GetDeadLetterService().Replay(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toApiDeadLetter(deadLetter), nil
+}
+
+// ReplayDeadLetters replays the pending dead letters of a consumer, or of all consumers if it is empty
+func (api *api) ReplayDeadLetters(ctx context.Context, consumer string) (*ReplayDeadLettersResponse, error) {
+	return api.svc.GetDeadLetterService().ReplayAll(ctx, consumer)
+}
+
+func (api *api) DiscardDeadLetter(id uint) error {
+	return api.svc.GetDeadLetterService().Discard(id)
+}
+
+func toApiDeadLetter(deadLetter *db.DeadLetter) *DeadLetter {
+	return &DeadLetter{
+		ID:             deadLetter.ID,
+		Consumer:       deadLetter.Consumer,
+		Event:          deadLetter.Event,
+		Payload:        deadLetter.Payload,
+		Error:          deadLetter.Error,
+		State:          deadLetter.State,
+		ReplayAttempts: deadLetter.ReplayAttempts,
+		ReplayedAt:     deadLetter.ReplayedAt,
+		CreatedAt:      deadLetter.CreatedAt,
+	}
+}
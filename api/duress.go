@@ -0,0 +1,161 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/spendablebalance"
+)
+
+// the duress password is hashed like the passwords of admin users, it must not be
+// derivable from the unlock password and cannot decrypt anything
+const (
+	duressPasswordHashKey = "DuressPasswordHash"
+	duressAppIdKey        = "DuressAppId"
+)
+
+const duressAppName = "Wallet"
+
+func (api *api) GetDuressSettings() (*DuressSettings, error) {
+	appId, err := api.getDuressAppId()
+	if err != nil {
+		return nil, err
+	}
+	return &DuressSettings{
+		Enabled: appId != 0,
+		AppId:   appId,
+	}, nil
+}
+
+// SetDuressPassword sets up a secondary unlock password which only opens the decoy wallet,
+// an isolated app with its own small balance. A new app is created if none is given.
+func (api *api) SetDuressPassword(setDuressPasswordRequest *SetDuressPasswordRequest) (*DuressSettings, error) {
+	if !api.cfg.CheckUnlockPassword(setDuressPasswordRequest.UnlockPassword) {
+		return nil, errors.New("wrong password")
+	}
+	if setDuressPasswordRequest.DuressPassword == setDuressPasswordRequest.UnlockPassword {
+		return nil, errors.New("duress password must be different from the unlock password")
+	}
+	passwordHash, err := hashAdminUserPassword(setDuressPasswordRequest.DuressPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	appId := setDuressPasswordRequest.AppId
+	if appId != 0 {
+		var app db.App
+		if api.db.Limit(1).Find(&app, appId).RowsAffected == 0 {
+			return nil, errors.New("app not found")
+		}
+		if !app.Isolated {
+			return nil, errors.New("the decoy wallet must be an isolated app")
+		}
+	} else {
+		createAppResponse, err := api.CreateApp(&CreateAppRequest{
+			Name:     duressAppName,
+			Isolated: true,
+			Scopes: []string{
+				constants.PAY_INVOICE_SCOPE,
+				constants.GET_BALANCE_SCOPE,
+				constants.GET_INFO_SCOPE,
+				constants.MAKE_INVOICE_SCOPE,
+				constants.LOOKUP_INVOICE_SCOPE,
+				constants.LIST_TRANSACTIONS_SCOPE,
+				constants.NOTIFICATIONS_SCOPE,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		appId = createAppResponse.Id
+	}
+
+	if err := api.cfg.SetUpdate(duressPasswordHashKey, passwordHash, ""); err != nil {
+		return nil, err
+	}
+	if err := api.cfg.SetUpdate(duressAppIdKey, strconv.FormatUint(uint64(appId), 10), ""); err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithField("app_id", appId).Info("Enabled duress password")
+	return &DuressSettings{
+		Enabled: true,
+		AppId:   appId,
+	}, nil
+}
+
+// DisableDuressPassword keeps the decoy wallet, its balance stays with the isolated app
+func (api *api) DisableDuressPassword(unlockPassword string) error {
+	if !api.cfg.CheckUnlockPassword(unlockPassword) {
+		return errors.New("wrong password")
+	}
+	for _, key := range []string{duressPasswordHashKey, duressAppIdKey} {
+		if err := api.cfg.SetUpdate(key, "", ""); err != nil {
+			return err
+		}
+	}
+
+	logger.Logger.Info("Disabled duress password")
+	return nil
+}
+
+// CheckDuressPassword returns the id of the decoy wallet if the password is the duress password
+func (api *api) CheckDuressPassword(password string) (uint, bool) {
+	appId, err := api.getDuressAppId()
+	if err != nil || appId == 0 || password == "" {
+		return 0, false
+	}
+	passwordHash, err := api.cfg.Get(duressPasswordHashKey, "")
+	if err != nil || passwordHash == "" {
+		return 0, false
+	}
+	if !checkAdminUserPassword(passwordHash, password) {
+		return 0, false
+	}
+	// the decoy wallet could have been deleted in the meantime
+	if api.db.Limit(1).Find(&db.App{}, appId).RowsAffected == 0 {
+		return 0, false
+	}
+	return appId, true
+}
+
+func (api *api) getDuressAppId() (uint, error) {
+	value, err := api.cfg.Get(duressAppIdKey, "")
+	if err != nil || value == "" {
+		return 0, err
+	}
+	appId, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint(appId), nil
+}
+
+// GetDecoyBalances returns the balance of the decoy wallet in the shape of the node balances,
+// so that a duress session cannot tell it apart from the real wallet
+func (api *api) GetDecoyBalances(appId uint) (*BalancesResponse, error) {
+	if api.db.Limit(1).Find(&db.App{}, appId).RowsAffected == 0 {
+		return nil, errors.New("app not found")
+	}
+	balance := max(queries.GetIsolatedBalance(api.db, appId), 0)
+	balances := lnclient.BalancesResponse{
+		Onchain: lnclient.OnchainBalanceResponse{
+			PendingBalancesDetails:      []lnclient.PendingBalanceDetails{},
+			PendingSweepBalancesDetails: []lnclient.PendingBalanceDetails{},
+		},
+		Lightning: lnclient.LightningBalanceResponse{
+			TotalSpendable:      balance,
+			NextMaxSpendable:    balance,
+			NextMaxSpendableMPP: balance,
+		},
+	}
+	return &BalancesResponse{
+		BalancesResponse: balances,
+		Spendable:        spendablebalance.Compute(&balances, spendablebalance.GetBuckets(api.cfg)),
+	}, nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/tests"
+)
+
+func TestDuressPassword(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+	require.NoError(t, svc.Cfg.SaveUnlockPasswordCheck("unlock-password"))
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg}
+
+	duressSettings, err := theAPI.GetDuressSettings()
+	require.NoError(t, err)
+	assert.False(t, duressSettings.Enabled)
+	_, ok := theAPI.CheckDuressPassword("")
+	assert.False(t, ok)
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	_, err = theAPI.SetDuressPassword(&SetDuressPasswordRequest{UnlockPassword: "wrong", DuressPassword: "duress-password", AppId: app.ID})
+	assert.EqualError(t, err, "wrong password")
+	_, err = theAPI.SetDuressPassword(&SetDuressPasswordRequest{UnlockPassword: "unlock-password", DuressPassword: "unlock-password", AppId: app.ID})
+	assert.EqualError(t, err, "duress password must be different from the unlock password")
+	_, err = theAPI.SetDuressPassword(&SetDuressPasswordRequest{UnlockPassword: "unlock-password", DuressPassword: "duress-password", AppId: app.ID})
+	assert.EqualError(t, err, "the decoy wallet must be an isolated app")
+
+	app.Isolated = true
+	require.NoError(t, svc.DB.Save(app).Error)
+
+	duressSettings, err = theAPI.SetDuressPassword(&SetDuressPasswordRequest{UnlockPassword: "unlock-password", DuressPassword: "duress-password", AppId: app.ID})
+	require.NoError(t, err)
+	assert.True(t, duressSettings.Enabled)
+	assert.Equal(t, app.ID, duressSettings.AppId)
+
+	// the password itself is not stored
+	passwordHash, err := svc.Cfg.Get(duressPasswordHashKey, "")
+	require.NoError(t, err)
+	assert.NotContains(t, passwordHash, "duress-password")
+
+	appId, ok := theAPI.CheckDuressPassword("duress-password")
+	assert.True(t, ok)
+	assert.Equal(t, app.ID, appId)
+	_, ok = theAPI.CheckDuressPassword("unlock-password")
+	assert.False(t, ok)
+
+	assert.EqualError(t, theAPI.DisableDuressPassword("duress-password"), "wrong password")
+	require.NoError(t, theAPI.DisableDuressPassword("unlock-password"))
+	_, ok = theAPI.CheckDuressPassword("duress-password")
+	assert.False(t, ok)
+	duressSettings, err = theAPI.GetDuressSettings()
+	require.NoError(t, err)
+	assert.False(t, duressSettings.Enabled)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/notifications"
+)
+
+func (api *api) GetEmailNotificationSettings() (*EmailNotificationSettings, error) {
+	settings, err := notifications.GetEmailSettings(api.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailNotificationSettings{
+		EmailSettings:  *settings,
+		SmtpConfigured: notifications.IsConfigured(api.cfg.GetEnv()),
+	}, nil
+}
+
+func (api *api) UpdateEmailNotificationSettings(updateEmailNotificationsRequest *UpdateEmailNotificationsRequest) (*EmailNotificationSettings, error) {
+	if updateEmailNotificationsRequest.Mode == "" {
+		updateEmailNotificationsRequest.Mode = notifications.EMAIL_MODE_IMMEDIATE
+	}
+	if err := notifications.SetEmailSettings(api.cfg, updateEmailNotificationsRequest); err != nil {
+		return nil, err
+	}
+	logger.Logger.WithField("events", updateEmailNotificationsRequest.Events).Info("Updated email notification settings")
+	return api.GetEmailNotificationSettings()
+}
+
+// SendTestEmail checks the SMTP settings by sending an email to the configured recipients
+func (api *api) SendTestEmail() error {
+	settings, err := notifications.GetEmailSettings(api.cfg)
+	if err != nil {
+		return err
+	}
+	if len(settings.Recipients) == 0 {
+		return errors.New("no recipients configured")
+	}
+	return notifications.SendEmail(api.cfg.GetEnv(), settings.Recipients, "Test email", "Email notifications of your hub are set up correctly.")
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+)
+
+type StreamEvent struct {
+	Event      string      `json:"event"`
+	Properties interface{} `json:"properties,omitempty"`
+}
+
+// ToStreamEvent converts an event for API clients, so that e.g. transactions
+// have the same format as in the transactions API
+func ToStreamEvent(event *events.Event) *StreamEvent {
+	streamEvent := &StreamEvent{
+		Event:      event.Event,
+		Properties: event.Properties,
+	}
+	if transaction, ok := event.Properties.(*db.Transaction); ok {
+		streamEvent.Properties = ToApiTransaction(transaction)
+	}
+	return streamEvent
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/feepolicies"
+)
+
+func (api *api) ListFeePolicies() ([]FeePolicy, error) {
+	dbFeePolicies, err := api.feePoliciesSvc.ListFeePolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	feePolicies := []FeePolicy{}
+	for _, dbFeePolicy := range dbFeePolicies {
+		feePolicies = append(feePolicies, *toApiFeePolicy(&dbFeePolicy))
+	}
+	return feePolicies, nil
+}
+
+func (api *api) CreateFeePolicy(createFeePolicyRequest *CreateFeePolicyRequest) (*FeePolicy, error) {
+	feePolicy, err := api.feePoliciesSvc.CreateFeePolicy(&feepolicies.CreateFeePolicyParams{
+		Name:          createFeePolicyRequest.Name,
+		PeerPubkeys:   createFeePolicyRequest.PeerPubkeys,
+		Strategy:      createFeePolicyRequest.Strategy,
+		MinLocalRatio: createFeePolicyRequest.MinLocalRatio,
+		MaxLocalRatio: createFeePolicyRequest.MaxLocalRatio,
+		BaseFeeMsat:   createFeePolicyRequest.BaseFeeMsat,
+		MinFeePpm:     createFeePolicyRequest.MinFeePpm,
+		MaxFeePpm:     createFeePolicyRequest.MaxFeePpm,
+		Priority:      createFeePolicyRequest.Priority,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toApiFeePolicy(feePolicy), nil
+}
+
+func (api *api) UpdateFeePolicy(id uint, updateFeePolicyRequest *UpdateFeePolicyRequest) (*FeePolicy, error) {
+	feePolicy, err := api.feePoliciesSvc.UpdateFeePolicy(id, updateFeePolicyRequest.Enabled, updateFeePolicyRequest.Priority)
+	if err != nil {
+		return nil, err
+	}
+	return toApiFeePolicy(feePolicy), nil
+}
+
+func (api *api) DeleteFeePolicy(id uint) error {
+	return api.feePoliciesSvc.DeleteFeePolicy(id)
+}
+
+func toApiFeePolicy(feePolicy *db.FeePolicy) *FeePolicy {
+	peerPubkeys := []string{}
+	if feePolicy.PeerPubkeys != "" {
+		peerPubkeys = strings.Split(feePolicy.PeerPubkeys, ",")
+	}
+	return &FeePolicy{
+		ID:            feePolicy.ID,
+		Name:          feePolicy.Name,
+		PeerPubkeys:   peerPubkeys,
+		Strategy:      feePolicy.Strategy,
+		MinLocalRatio: feePolicy.MinLocalRatio,
+		MaxLocalRatio: feePolicy.MaxLocalRatio,
+		BaseFeeMsat:   feePolicy.BaseFeeMsat,
+		MinFeePpm:     feePolicy.MinFeePpm,
+		MaxFeePpm:     feePolicy.MaxFeePpm,
+		Priority:      feePolicy.Priority,
+		Enabled:       feePolicy.Enabled,
+		LastRunAt:     feePolicy.LastRunAt,
+		CreatedAt:     feePolicy.CreatedAt,
+	}
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"time"
+)
+
+// the default period of liquidity reports
+const defaultLiquidityReportPeriod = 30 * 24 * time.Hour
+
+func (api *api) GetLiquidityReport(from uint64, until uint64) (*LiquidityReport, error) {
+	untilTime := time.Now()
+	if until != 0 {
+		untilTime = time.Unix(int64(until), 0)
+	}
+	fromTime := untilTime.Add(-defaultLiquidityReportPeriod)
+	if from != 0 {
+		fromTime = time.Unix(int64(from), 0)
+	}
+
+	return api.liquidityHistorySvc.GetLiquidityReport(fromTime, untilTime)
+}
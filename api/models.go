@@ -2,24 +2,60 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"time"
 
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/auditlog"
+	"github.com/getAlby/hub/backups"
+	"github.com/getAlby/hub/channelacceptance"
 	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/liquidityhistory"
 	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/maintenance"
+	"github.com/getAlby/hub/notifications"
+	"github.com/getAlby/hub/recovery"
+	"github.com/getAlby/hub/spendablebalance"
 	"github.com/getAlby/hub/swaps"
+	"github.com/getAlby/hub/taprootassets"
+	"github.com/getAlby/hub/transactions"
+	"github.com/getAlby/hub/webhooks"
 )
 
 type API interface {
 	CreateApp(createAppRequest *CreateAppRequest) (*CreateAppResponse, error)
 	UpdateApp(app *db.App, updateAppRequest *UpdateAppRequest) error
-	Transfer(ctx context.Context, fromAppId *uint, toAppId *uint, amountMsat uint64) error
+	Transfer(ctx context.Context, fromAppId *uint, toAppId *uint, amountMsat uint64) (*TransferResponse, error)
 	DeleteApp(app *db.App) error
+	RenewApp(app *db.App, renewAppRequest *RenewAppRequest) (*App, error)
+	RotateAppSecret(app *db.App) (*CreateAppResponse, error)
+	AddAppScope(app *db.App, scope string) error
+	RemoveAppScope(app *db.App, scope string) error
+	ListAppAuditLogs(appId uint) ([]AppAuditLog, error)
+	ListAppDestinations(appId uint) ([]AppDestination, error)
+	AddAppDestination(ctx context.Context, app *db.App, addAppDestinationRequest *AddAppDestinationRequest) (*AppDestination, error)
+	RemoveAppDestination(app *db.App, destinationId uint) error
+	ListAppActivity(appId uint, filters ListAppActivityFilters, limit uint64, offset uint64) (*ListAppActivityResponse, error)
+	ListAppGroups() ([]AppGroup, error)
+	CreateAppGroup(createAppGroupRequest *CreateAppGroupRequest) (*AppGroup, error)
+	UpdateAppGroup(id uint, updateAppGroupRequest *UpdateAppGroupRequest) (*AppGroup, error)
+	DeleteAppGroup(id uint) error
+	ListAppTemplates() []AppTemplate
+	ExportApps(exportAppsRequest *ExportAppsRequest, w io.Writer) error
+	ImportApps(importAppsRequest *ImportAppsRequest, r io.Reader) (*ImportAppsResponse, error)
+	ListPaymentApprovals(state string) ([]PaymentApproval, error)
+	DecidePaymentApproval(id uint, approved bool) error
 	GetApp(app *db.App) *App
 	ListApps(limit uint64, offset uint64, filters ListAppsFilters, orderBy string) (*ListAppsResponse, error)
 	CreateLightningAddress(ctx context.Context, createLightningAddressRequest *CreateLightningAddressRequest) error
 	DeleteLightningAddress(ctx context.Context, appId uint) error
+	SetAppLightningAddress(appId uint, username string) error
+	BulkUpdateApps(bulkUpdateAppsRequest *BulkUpdateAppsRequest) (*BulkUpdateAppsResponse, error)
+	GetLnurlPay(username string) (*LnurlPayResponse, error)
+	GetLnurlPayInvoice(ctx context.Context, username string, amountMsat uint64, comment string) (*LnurlPayInvoiceResponse, error)
 	ListChannels(ctx context.Context) ([]Channel, error)
 	GetChannelPeerSuggestions(ctx context.Context) ([]alby.ChannelPeerSuggestion, error)
 	GetLSPChannelOffer(ctx context.Context) (*alby.LSPChannelOffer, error)
@@ -34,6 +70,7 @@ type API interface {
 	DisconnectPeer(ctx context.Context, peerId string) error
 	OpenChannel(ctx context.Context, openChannelRequest *OpenChannelRequest) (*OpenChannelResponse, error)
 	RebalanceChannel(ctx context.Context, rebalanceChannelRequest *RebalanceChannelRequest) (*RebalanceChannelResponse, error)
+	RebalanceCircular(ctx context.Context, circularRebalanceRequest *CircularRebalanceRequest) (*CircularRebalanceResponse, error)
 	CloseChannel(ctx context.Context, peerId, channelId string, force bool) (*CloseChannelResponse, error)
 	UpdateChannel(ctx context.Context, updateChannelRequest *UpdateChannelRequest) error
 	MakeOffer(ctx context.Context, description string) (string, error)
@@ -42,14 +79,22 @@ type API interface {
 	SignMessage(ctx context.Context, message string) (*SignMessageResponse, error)
 	RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, feeRate *uint64, sendAll bool) (*RedeemOnchainFundsResponse, error)
 	GetBalances(ctx context.Context) (*BalancesResponse, error)
-	ListTransactions(ctx context.Context, appId *uint, limit uint64, offset uint64) (*ListTransactionsResponse, error)
+	ListTransactions(ctx context.Context, appId *uint, limit uint64, offset uint64, currencies []string) (*ListTransactionsResponse, error)
+	ExportTransactions(ctx context.Context, format string, w io.Writer) error
+	GetTransactionReceipt(ctx context.Context, paymentHash string, transactionType string) (*TransactionReceipt, error)
+	ArchiveTransactions(ctx context.Context, archiveRequest *ArchiveTransactionsRequest, w io.Writer) error
+	RefundTransaction(ctx context.Context, paymentHash string, refundRequest *RefundTransactionRequest) (*Transaction, error)
+	GetTransactionsSummary(ctx context.Context, from uint64, until uint64, appId *uint, currency string) (*TransactionsSummary, error)
+	GetAppReport(ctx context.Context, appId uint, from uint64, until uint64, currency string) (*AppReport, error)
 	ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error)
-	SendPayment(ctx context.Context, invoice string, amountMsat *uint64, metadata map[string]interface{}) (*SendPaymentResponse, error)
+	SendPayment(ctx context.Context, invoice string, amountMsat *uint64, metadata map[string]interface{}, idempotencyKey string) (*SendPaymentResponse, error)
 	CreateInvoice(ctx context.Context, amount uint64, description string) (*MakeInvoiceResponse, error)
 	LookupInvoice(ctx context.Context, paymentHash string) (*LookupInvoiceResponse, error)
 	RequestMempoolApi(ctx context.Context, endpoint string) (interface{}, error)
 	GetInfo(ctx context.Context) (*InfoResponse, error)
-	GetMnemonic(unlockPassword string) (*MnemonicResponse, error)
+	GetMnemonic(unlockPassword string, seedPassphrase string) (*MnemonicResponse, error)
+	SetSeedPassphrase(setSeedPassphraseRequest *SetSeedPassphraseRequest) error
+	IsSeedPassphraseEnabled() bool
 	SetNextBackupReminder(backupReminderRequest *BackupReminderRequest) error
 	Start(startRequest *StartRequest)
 	Setup(ctx context.Context, setupRequest *SetupRequest) error
@@ -59,11 +104,14 @@ type API interface {
 	SyncWallet() error
 	GetLogOutput(ctx context.Context, logType string, getLogRequest *GetLogOutputRequest) (*GetLogOutputResponse, error)
 	RequestLSPOrder(ctx context.Context, request *LSPOrderRequest) (*LSPOrderResponse, error)
-	CreateBackup(unlockPassword string, w io.Writer) error
-	RestoreBackup(unlockPassword string, r io.Reader) error
+	ListLSPOrders() ([]LSPOrder, error)
+	CreateBackup(unlockPassword string, seedPassphrase string, w io.Writer) error
+	RestoreBackup(unlockPassword string, seedPassphrase string, r io.Reader) error
+	ValidateBackup(unlockPassword string, r io.Reader) (*BackupInfo, error)
 	MigrateNodeStorage(ctx context.Context, to string) error
 	GetWalletCapabilities(ctx context.Context) (*WalletCapabilitiesResponse, error)
 	Health(ctx context.Context) (*HealthResponse, error)
+	GetComponentHealth(ctx context.Context) *ComponentHealthResponse
 	SetCurrency(currency string) error
 	SetBitcoinDisplayFormat(format string) error
 	UpdateSettings(updateSettingsRequest *UpdateSettingsRequest) error
@@ -83,26 +131,150 @@ type API interface {
 	ExecuteCustomNodeCommand(ctx context.Context, command string) (interface{}, error)
 	SendEvent(event string, properties interface{})
 	GetForwards() (*GetForwardsResponse, error)
+	ListWebhooks() ([]Webhook, error)
+	ListWebhookEventTypes() []string
+	CreateWebhook(createWebhookRequest *CreateWebhookRequest) (*CreateWebhookResponse, error)
+	UpdateWebhook(id uint, updateWebhookRequest *UpdateWebhookRequest) (*Webhook, error)
+	DeleteWebhook(id uint) error
+	ListWebhookDeliveries(webhookId uint, state string, limit uint64) ([]WebhookDelivery, error)
+	RetryWebhookDelivery(ctx context.Context, webhookId uint, deliveryId uint) (*WebhookDelivery, error)
+	ListScheduledPayments() ([]ScheduledPayment, error)
+	CreateScheduledPayment(createScheduledPaymentRequest *CreateScheduledPaymentRequest) (*ScheduledPayment, error)
+	DeleteScheduledPayment(id uint) error
+	ListSwapRules() ([]SwapRule, error)
+	CreateSwapRule(createSwapRuleRequest *CreateSwapRuleRequest) (*SwapRule, error)
+	UpdateSwapRule(id uint, updateSwapRuleRequest *UpdateSwapRuleRequest) (*SwapRule, error)
+	DeleteSwapRule(id uint) error
+	ListSwapRuleRuns(id uint, limit uint64) ([]SwapRuleRun, error)
+	ListFeePolicies() ([]FeePolicy, error)
+	CreateFeePolicy(createFeePolicyRequest *CreateFeePolicyRequest) (*FeePolicy, error)
+	UpdateFeePolicy(id uint, updateFeePolicyRequest *UpdateFeePolicyRequest) (*FeePolicy, error)
+	DeleteFeePolicy(id uint) error
+	GetLiquidityReport(from uint64, until uint64) (*LiquidityReport, error)
+	ListBackupTargets() ([]BackupTarget, error)
+	CreateBackupTarget(createBackupTargetRequest *CreateBackupTargetRequest) (*BackupTarget, error)
+	UpdateBackupTarget(id uint, updateBackupTargetRequest *UpdateBackupTargetRequest) (*BackupTarget, error)
+	DeleteBackupTarget(id uint) error
+	ListBackupSnapshots(ctx context.Context, id uint) ([]BackupSnapshot, error)
+	RunBackup(ctx context.Context, id uint, runBackupRequest *RunBackupRequest) (*BackupSnapshot, error)
+	GetNostrBackup(ctx context.Context) (*NostrBackup, error)
+	PublishNostrBackup(ctx context.Context) (*NostrBackup, error)
+	GetDatabaseMaintenanceStatus() *DatabaseMaintenanceStatus
+	RunDatabaseMaintenance(ctx context.Context, runDatabaseMaintenanceRequest *RunDatabaseMaintenanceRequest) (*DatabaseMaintenanceReport, error)
+	GetDatabaseMigrations() (*DatabaseMigrationsResponse, error)
+	GetDatabaseKeyRotation() (*DatabaseKeyRotationStatus, error)
+	ExportAdminAuditLog(w io.Writer) error
+	VerifyAdminAuditLog() (*AdminAuditLogVerification, error)
+	RotateDatabaseEncryptionKey(rotateDatabaseEncryptionKeyRequest *RotateDatabaseEncryptionKeyRequest) error
+	StartRecovery(ctx context.Context, startRecoveryRequest *StartRecoveryRequest) (*Recovery, error)
+	GetRecovery() (*Recovery, error)
+	DeleteRecovery() error
+	ListSubwalletAddresses(appId uint) ([]SubwalletAddress, error)
+	CreateSubwalletAddress(ctx context.Context, appId uint) (*SubwalletAddress, error)
+	SetSubwalletOwnerPassword(appId uint, password string) error
+	CheckSubwalletOwnerPassword(appId uint, password string) bool
+	CreateSubwalletInvoice(ctx context.Context, appId uint, amount uint64, description string) (*MakeInvoiceResponse, error)
+	SendSubwalletPayment(ctx context.Context, appId uint, invoice string, amountMsat *uint64, idempotencyKey string) (*SendPaymentResponse, error)
+	ListAdminUsers() ([]AdminUser, error)
+	GetAdminUser(id uint) (*AdminUser, error)
+	CreateAdminUser(createAdminUserRequest *CreateAdminUserRequest) (*AdminUser, error)
+	UpdateAdminUser(id uint, updateAdminUserRequest *UpdateAdminUserRequest) (*AdminUser, error)
+	DeleteAdminUser(id uint) error
+	CheckAdminUserPassword(name string, password string) (uint, bool)
+	VerifyAdminUserTotp(adminUserId uint, code string) error
+	CanAdminUserManageApp(adminUserId uint, appId uint) bool
+	CreateAdminUserApp(adminUserId uint, createAppRequest *CreateAppRequest) (*CreateAppResponse, error)
+	ListApiKeys() ([]ApiKey, error)
+	CreateApiKey(createApiKeyRequest *CreateApiKeyRequest) (*CreateApiKeyResponse, error)
+	RevokeApiKey(id uint) error
+	AuthenticateApiKey(key string) (*ApiKey, error)
+	CreateSession(createSessionRequest *CreateSessionRequest) error
+	AuthenticateSession(tokenId string) (*Session, error)
+	ListSessions(currentTokenId string) ([]Session, error)
+	RevokeSession(id uint) error
+	RevokeAllSessions(exceptTokenId string) (int64, error)
+	SetupTotp(unlockPassword string) (*SetupTotpResponse, error)
+	EnableTotp(enableTotpRequest *EnableTotpRequest) (*EnableTotpResponse, error)
+	DisableTotp(disableTotpRequest *DisableTotpRequest) error
+	VerifyTotp(unlockPassword string, code string) error
+	IsTotpEnabled() bool
+	GetDuressSettings() (*DuressSettings, error)
+	SetDuressPassword(setDuressPasswordRequest *SetDuressPasswordRequest) (*DuressSettings, error)
+	DisableDuressPassword(unlockPassword string) error
+	CheckDuressPassword(password string) (uint, bool)
+	GetDecoyBalances(appId uint) (*BalancesResponse, error)
+	GetEmailNotificationSettings() (*EmailNotificationSettings, error)
+	UpdateEmailNotificationSettings(updateEmailNotificationsRequest *UpdateEmailNotificationsRequest) (*EmailNotificationSettings, error)
+	SendTestEmail() error
+	ListPasskeys() ([]Passkey, error)
+	BeginPasskeyRegistration() (*PasskeyRegistrationOptions, error)
+	FinishPasskeyRegistration(finishPasskeyRegistrationRequest *FinishPasskeyRegistrationRequest) (*Passkey, error)
+	DeletePasskey(id uint) error
+	ListPushDevices() ([]PushDevice, error)
+	RegisterPushDevice(registerPushDeviceRequest *RegisterPushDeviceRequest) (*PushDevice, error)
+	RevokePushDevice(id uint) error
+	SendTestPushNotification(ctx context.Context, id uint) error
+	ListDeadLetters(consumer string, state string) ([]DeadLetter, error)
+	ReplayDeadLetter(ctx context.Context, id uint) (*DeadLetter, error)
+	ReplayDeadLetters(ctx context.Context, consumer string) (*ReplayDeadLettersResponse, error)
+	DiscardDeadLetter(id uint) error
+	BeginPasskeyLogin() (*PasskeyLoginOptions, error)
+	FinishPasskeyLogin(credential *PasskeyLoginCredential) (*Passkey, error)
+	LockSpending()
+	UnlockSpending(unlockPassword string) error
 }
 
 type App struct {
-	ID                 uint       `json:"id"`
-	Name               string     `json:"name"`
-	Description        string     `json:"description"`
-	AppPubkey          string     `json:"appPubkey"`
-	CreatedAt          time.Time  `json:"createdAt"`
-	UpdatedAt          time.Time  `json:"updatedAt"`
-	LastUsedAt         *time.Time `json:"lastUsedAt"`
-	ExpiresAt          *time.Time `json:"expiresAt"`
-	Scopes             []string   `json:"scopes"`
-	MaxAmountSat       uint64     `json:"maxAmount"`
-	BudgetUsage        uint64     `json:"budgetUsage"`
-	BudgetRenewal      string     `json:"budgetRenewal"`
-	Isolated           bool       `json:"isolated"`
-	WalletPubkey       string     `json:"walletPubkey"`
-	UniqueWalletPubkey bool       `json:"uniqueWalletPubkey"`
-	Balance            int64      `json:"balance"`
-	Metadata           Metadata   `json:"metadata,omitempty"`
+	ID                   uint             `json:"id"`
+	Name                 string           `json:"name"`
+	Description          string           `json:"description"`
+	AppPubkey            string           `json:"appPubkey"`
+	CreatedAt            time.Time        `json:"createdAt"`
+	UpdatedAt            time.Time        `json:"updatedAt"`
+	LastUsedAt           *time.Time       `json:"lastUsedAt"`
+	ExpiresAt            *time.Time       `json:"expiresAt"`
+	RenewedAt            *time.Time       `json:"renewedAt"`
+	Scopes               []string         `json:"scopes"`
+	MaxAmountSat         uint64           `json:"maxAmount"`
+	BudgetUsage          uint64           `json:"budgetUsage"`
+	BudgetRenewal        string           `json:"budgetRenewal"`
+	Isolated             bool             `json:"isolated"`
+	ReadOnly             bool             `json:"readOnly"`
+	ReceiveOnly          bool             `json:"receiveOnly"`
+	Paused               bool             `json:"paused"`
+	LightningAddress     string           `json:"lightningAddress,omitempty"`
+	SingleUse            bool             `json:"singleUse"`
+	NostrPubkey          *string          `json:"nostrPubkey"`
+	NostrProfile         *AppNostrProfile `json:"nostrProfile"`
+	AppGroupId           *uint            `json:"appGroupId"`
+	ApprovalThreshold    *uint64          `json:"approvalThreshold"` // sats
+	MaxPaymentsPerMinute *uint            `json:"maxPaymentsPerMinute"`
+	MaxPaymentsPerDay    *uint            `json:"maxPaymentsPerDay"`
+	WalletPubkey         string           `json:"walletPubkey"`
+	UniqueWalletPubkey   bool             `json:"uniqueWalletPubkey"`
+	Balance              int64            `json:"balance"`
+	FeeReserve           uint64           `json:"feeReserve"` // msat reserved for routing fees of in-flight payments
+	MaxPaymentAmountSat  *uint64          `json:"maxPaymentAmount"`
+	OwnerLogin           bool             `json:"ownerLogin"` // sub-wallet owner can log in with their own password
+	MaxFeePercent        *float64         `json:"maxFeePercent"`
+	MaxFeeFloorSat       *uint64          `json:"maxFeeFloor"`
+	BudgetCurrency       string           `json:"budgetCurrency,omitempty"` // set for budgets in fiat instead of sats
+	MaxAmountFiat        float64          `json:"maxAmountFiat,omitempty"`
+	BudgetUsageFiat      *float64         `json:"budgetUsageFiat,omitempty"`
+	Budgets              []AppBudget      `json:"budgets"` // additional budgets, the most restrictive one applies
+	Metadata             Metadata         `json:"metadata,omitempty"`
+}
+
+type AppBudget struct {
+	MaxAmountSat  uint64  `json:"maxAmount"`
+	BudgetRenewal string  `json:"budgetRenewal"`
+	BudgetUsage   uint64  `json:"budgetUsage"`
+	RenewsAt      *uint64 `json:"renewsAt"`
+}
+
+type AppBudgetRequest struct {
+	MaxAmountSat  uint64 `json:"maxAmount"`
+	BudgetRenewal string `json:"budgetRenewal"`
 }
 
 type ListAppsFilters struct {
@@ -110,6 +282,8 @@ type ListAppsFilters struct {
 	AppStoreAppId string `json:"appStoreAppId"`
 	Unused        bool   `json:"unused"`
 	SubWallets    *bool  `json:"subWallets"`
+	// only apps managed by this admin user
+	AdminUserId *uint `json:"adminUserId"`
 }
 
 type ListAppsResponse struct {
@@ -126,6 +300,128 @@ type UpdateAppRequest struct {
 	Scopes          []string  `json:"scopes"`
 	Metadata        *Metadata `json:"metadata"`
 	Isolated        *bool     `json:"isolated"`
+	// nil keeps the current limit, unless UpdateMaxPaymentAmount is set to fall back to the hub-wide limit
+	MaxPaymentAmountSat    *uint64 `json:"maxPaymentAmount"`
+	UpdateMaxPaymentAmount bool    `json:"updateMaxPaymentAmount"`
+	// the max routing fee is the larger of the percentage and the floor. Both are replaced if either is set or UpdateMaxFee is set
+	MaxFeePercent  *float64 `json:"maxFeePercent"`
+	MaxFeeFloorSat *uint64  `json:"maxFeeFloor"`
+	UpdateMaxFee   bool     `json:"updateMaxFee"`
+	// an empty currency switches the budget back to sats
+	BudgetCurrency *string  `json:"budgetCurrency"`
+	MaxAmountFiat  *float64 `json:"maxAmountFiat"`
+	// replaces all additional budgets if set
+	Budgets *[]AppBudgetRequest `json:"budgets"`
+	// nil keeps the current group, unless UpdateAppGroup is set to remove the app from its group
+	AppGroupId     *uint `json:"appGroupId"`
+	UpdateAppGroup bool  `json:"updateAppGroup"`
+	// nil keeps the current threshold, unless UpdateApprovalThreshold is set to stop requiring approvals
+	ApprovalThreshold       *uint64 `json:"approvalThreshold"`
+	UpdateApprovalThreshold bool    `json:"updateApprovalThreshold"`
+	// both limits are replaced if either is set or UpdatePaymentRateLimits is set, nil removes a limit
+	MaxPaymentsPerMinute    *uint `json:"maxPaymentsPerMinute"`
+	MaxPaymentsPerDay       *uint `json:"maxPaymentsPerDay"`
+	UpdatePaymentRateLimits bool  `json:"updatePaymentRateLimits"`
+	// an empty pubkey removes the nostr user from the connection
+	NostrPubkey *string `json:"nostrPubkey"`
+}
+
+// AppNostrProfile is the cached kind-0 profile of the nostr user of a connection
+type AppNostrProfile struct {
+	Name      string    `json:"name"`
+	Picture   string    `json:"picture"`
+	About     string    `json:"about"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+type RenewAppRequest struct {
+	// defaults to extending the connection by the length of its current validity period
+	ExpiresAt string `json:"expiresAt"`
+}
+
+type AppGroup struct {
+	ID            uint      `json:"id"`
+	Name          string    `json:"name"`
+	MaxAmountSat  uint64    `json:"maxAmount"` // 0 = no group budget
+	BudgetRenewal string    `json:"budgetRenewal"`
+	BudgetUsage   uint64    `json:"budgetUsage"`
+	RenewsAt      *uint64   `json:"renewsAt"`
+	AppIds        []uint    `json:"appIds"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type CreateAppGroupRequest struct {
+	Name          string `json:"name"`
+	MaxAmountSat  uint64 `json:"maxAmount"`
+	BudgetRenewal string `json:"budgetRenewal"`
+}
+
+type UpdateAppGroupRequest struct {
+	Name          *string `json:"name"`
+	MaxAmountSat  *uint64 `json:"maxAmount"`
+	BudgetRenewal *string `json:"budgetRenewal"`
+}
+
+type PaymentApproval struct {
+	ID          uint      `json:"id"`
+	AppId       uint      `json:"appId"`
+	AppName     string    `json:"appName"`
+	PaymentHash string    `json:"paymentHash"`
+	Destination string    `json:"destination,omitempty"`
+	Amount      uint64    `json:"amount"` // in millisats
+	Description string    `json:"description"`
+	State       string    `json:"state"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type AppScopeRequest struct {
+	Scope string `json:"scope"`
+}
+
+type AppAuditLog struct {
+	ID        uint            `json:"id"`
+	Action    string          `json:"action"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type AppDestination struct {
+	ID         uint      `json:"id"`
+	Type       string    `json:"type"`
+	Value      string    `json:"value"`
+	NodePubkey string    `json:"nodePubkey"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type AddAppDestinationRequest struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type AppActivity struct {
+	ID             uint            `json:"id"`
+	RequestEventId string          `json:"requestEventId"`
+	Method         string          `json:"method"`
+	Params         json.RawMessage `json:"params,omitempty"`
+	Result         string          `json:"result"`
+	ErrorCode      string          `json:"errorCode,omitempty"`
+	ErrorMessage   string          `json:"errorMessage,omitempty"`
+	RequestedAt    time.Time       `json:"requestedAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+type ListAppActivityFilters struct {
+	Method string `json:"method"`
+	Result string `json:"result"`
+	// unix timestamps, 0 leaves the period open
+	From  uint64 `json:"from"`
+	Until uint64 `json:"until"`
+}
+
+type ListAppActivityResponse struct {
+	TotalCount uint64        `json:"totalCount"`
+	Activity   []AppActivity `json:"activity"`
 }
 
 type TransferRequest struct {
@@ -134,6 +430,12 @@ type TransferRequest struct {
 	ToAppId   *uint  `json:"toAppId"`
 }
 
+// TransferResponse contains the transactions recorded on both sides of the transfer
+type TransferResponse struct {
+	Sent     *Transaction `json:"sent"`
+	Received *Transaction `json:"received"`
+}
+
 type CreateAppRequest struct {
 	Name           string   `json:"name"`
 	Pubkey         string   `json:"pubkey"`
@@ -145,6 +447,83 @@ type CreateAppRequest struct {
 	Isolated       bool     `json:"isolated"`
 	Metadata       Metadata `json:"metadata,omitempty"`
 	UnlockPassword string   `json:"unlockPassword"`
+	// overrides the hub-wide maximum single payment amount (0 = no limit)
+	MaxPaymentAmountSat *uint64 `json:"maxPaymentAmount"`
+	// limits the routing fee of each payment to the larger of a percentage of the amount and an absolute floor
+	MaxFeePercent  *float64 `json:"maxFeePercent"`
+	MaxFeeFloorSat *uint64  `json:"maxFeeFloor"`
+	// defines the budget in fiat instead of sats, converted at the time of each payment
+	BudgetCurrency string  `json:"budgetCurrency"`
+	MaxAmountFiat  float64 `json:"maxAmountFiat"`
+	// additional budgets with other renewal periods, e.g. a daily limit on top of a monthly budget
+	Budgets []AppBudgetRequest `json:"budgets"`
+	// limits the app to read-only scopes, which default to all of them
+	ReadOnly bool `json:"readOnly"`
+	// isolates the app and limits it to scopes which do not spend funds, for e.g. donation widgets
+	ReceiveOnly bool `json:"receiveOnly"`
+	// the app also draws from the budget of this group
+	AppGroupId *uint `json:"appGroupId"`
+	// payments above this amount in sats wait for manual approval
+	ApprovalThreshold *uint64 `json:"approvalThreshold"`
+	// limits how many payments the app can make, in addition to its budget
+	MaxPaymentsPerMinute *uint `json:"maxPaymentsPerMinute"`
+	MaxPaymentsPerDay    *uint `json:"maxPaymentsPerDay"`
+	// receives the signed transaction events of the app
+	WebhookUrl string `json:"webhookUrl"`
+	// revokes the app after its first payment or paid invoice
+	SingleUse bool `json:"singleUse"`
+	// hex or npub of the nostr user the connection belongs to
+	NostrPubkey string `json:"nostrPubkey"`
+	// preset scopes and budget, used for the ones not set in the request
+	Template string `json:"template"`
+}
+
+type AppTemplate = apps.AppTemplate
+
+type SetAppLightningAddressRequest struct {
+	// an empty username removes the lightning address
+	Username string `json:"username"`
+}
+
+// LnurlPayResponse is the first step of paying a lightning address served by the hub (LUD-06)
+type LnurlPayResponse struct {
+	Tag            string `json:"tag"`
+	Callback       string `json:"callback"`
+	MinSendable    uint64 `json:"minSendable"`
+	MaxSendable    uint64 `json:"maxSendable"`
+	Metadata       string `json:"metadata"`
+	CommentAllowed int    `json:"commentAllowed"`
+}
+
+type LnurlPayInvoiceResponse struct {
+	Pr     string        `json:"pr"`
+	Routes []interface{} `json:"routes"`
+}
+
+const (
+	BULK_APP_ACTION_REVOKE        = "revoke"
+	BULK_APP_ACTION_PAUSE         = "pause"
+	BULK_APP_ACTION_RESUME        = "resume"
+	BULK_APP_ACTION_SCALE_BUDGETS = "scale_budgets"
+)
+
+type BulkUpdateAppsRequest struct {
+	AppIds []uint `json:"appIds"`
+	// applies the action to all apps instead of AppIds
+	All    bool   `json:"all"`
+	Action string `json:"action"`
+	// multiplies the budgets for scale_budgets, e.g. 0.5 cuts them by half
+	BudgetFactor *float64 `json:"budgetFactor"`
+}
+
+type BulkUpdateAppsFailure struct {
+	AppId  uint   `json:"appId"`
+	Reason string `json:"reason"`
+}
+
+type BulkUpdateAppsResponse struct {
+	Updated []uint                  `json:"updated"`
+	Failed  []BulkUpdateAppsFailure `json:"failed"`
 }
 
 type CreateLightningAddressRequest struct {
@@ -212,12 +591,16 @@ type Swap struct {
 
 type StartRequest struct {
 	UnlockPassword string `json:"unlockPassword"`
+	// required if two-factor authentication is enabled
+	TotpCode string `json:"totpCode"`
 }
 
 type UnlockRequest struct {
 	UnlockPassword  string  `json:"unlockPassword"`
 	TokenExpiryDays *uint64 `json:"tokenExpiryDays"`
 	Permission      string  `json:"permission,omitempty"` // "full" or "readonly"
+	// required if two-factor authentication is enabled
+	TotpCode string `json:"totpCode"`
 }
 
 type BackupReminderRequest struct {
@@ -261,6 +644,8 @@ type CreateAppResponse struct {
 	Id            uint     `json:"id"`
 	Name          string   `json:"name"`
 	ReturnTo      string   `json:"returnTo"`
+	// only returned on creation, if a webhook url was provided
+	WebhookSecret string `json:"webhookSecret,omitempty"`
 }
 
 type User struct {
@@ -273,35 +658,167 @@ type InfoResponseRelay struct {
 }
 
 type InfoResponse struct {
-	BackendType                 string              `json:"backendType"`
-	SetupCompleted              bool                `json:"setupCompleted"`
-	OAuthRedirect               bool                `json:"oauthRedirect"`
-	Running                     bool                `json:"running"`
-	Unlocked                    bool                `json:"unlocked"`
-	AlbyAuthUrl                 string              `json:"albyAuthUrl"`
-	NextBackupReminder          string              `json:"nextBackupReminder"`
-	AlbyUserIdentifier          string              `json:"albyUserIdentifier"`
-	AlbyAccountConnected        bool                `json:"albyAccountConnected"`
-	Version                     string              `json:"version"`
-	Network                     string              `json:"network"`
-	EnableAdvancedSetup         bool                `json:"enableAdvancedSetup"`
-	LdkVssEnabled               bool                `json:"ldkVssEnabled"`
-	VssSupported                bool                `json:"vssSupported"`
-	StartupState                string              `json:"startupState"`
-	StartupError                string              `json:"startupError"`
-	StartupErrorTime            time.Time           `json:"startupErrorTime"`
-	AutoUnlockPasswordSupported bool                `json:"autoUnlockPasswordSupported"`
-	AutoUnlockPasswordEnabled   bool                `json:"autoUnlockPasswordEnabled"`
-	Currency                    string              `json:"currency"`
-	BitcoinDisplayFormat        string              `json:"bitcoinDisplayFormat"`
-	Relays                      []InfoResponseRelay `json:"relays"`
-	NodeAlias                   string              `json:"nodeAlias"`
-	MempoolUrl                  string              `json:"mempoolUrl"`
+	BackendType                  string              `json:"backendType"`
+	SetupCompleted               bool                `json:"setupCompleted"`
+	OAuthRedirect                bool                `json:"oauthRedirect"`
+	Running                      bool                `json:"running"`
+	Unlocked                     bool                `json:"unlocked"`
+	AlbyAuthUrl                  string              `json:"albyAuthUrl"`
+	NextBackupReminder           string              `json:"nextBackupReminder"`
+	AlbyUserIdentifier           string              `json:"albyUserIdentifier"`
+	AlbyAccountConnected         bool                `json:"albyAccountConnected"`
+	Version                      string              `json:"version"`
+	Network                      string              `json:"network"`
+	EnableAdvancedSetup          bool                `json:"enableAdvancedSetup"`
+	LdkVssEnabled                bool                `json:"ldkVssEnabled"`
+	VssSupported                 bool                `json:"vssSupported"`
+	StartupState                 string              `json:"startupState"`
+	StartupError                 string              `json:"startupError"`
+	StartupErrorTime             time.Time           `json:"startupErrorTime"`
+	AutoUnlockPasswordSupported  bool                `json:"autoUnlockPasswordSupported"`
+	AutoUnlockPasswordEnabled    bool                `json:"autoUnlockPasswordEnabled"`
+	TotpEnabled                  bool                `json:"totpEnabled"`
+	SeedPassphraseEnabled        bool                `json:"seedPassphraseEnabled"`
+	PasskeysRegistered           bool                `json:"passkeysRegistered"`
+	SpendingLocked               bool                `json:"spendingLocked"`
+	ReadOnly                     bool                `json:"readOnly"`
+	WatchOnly                    bool                `json:"watchOnly"`
+	OnionUrl                     string              `json:"onionUrl"`
+	Currency                     string              `json:"currency"`
+	FiatCurrencies               []string            `json:"fiatCurrencies"`
+	BitcoinDisplayFormat         string              `json:"bitcoinDisplayFormat"`
+	Relays                       []InfoResponseRelay `json:"relays"`
+	NodeAlias                    string              `json:"nodeAlias"`
+	MempoolUrl                   string              `json:"mempoolUrl"`
+	MaxPaymentAmountSat          uint64              `json:"maxPaymentAmount"`
+	MetadataMaxLength            int                 `json:"metadataMaxLength"`
+	MetadataPolicy               string              `json:"metadataPolicy"`
+	TransactionRetentionMonths   uint                `json:"transactionRetentionMonths"`
+	AppActivityRetentionDays     uint                `json:"appActivityRetentionDays"`
+	RequestEventRetentionDays    uint                `json:"requestEventRetentionDays"`
+	AuditLogRetentionDays        uint                `json:"auditLogRetentionDays"`
+	WebhookDeliveryRetentionDays uint                `json:"webhookDeliveryRetentionDays"`
+	BudgetAlertThresholds        []uint              `json:"budgetAlertThresholds"`
+	AdminAllowedNetworks         string              `json:"adminAllowedNetworks"`
+	ReadOnlyMode                 bool                `json:"readOnlyMode"`
+	ReadOnlyWindows              []ReadOnlyWindow    `json:"readOnlyWindows"`
+	LogLevel                     uint                `json:"logLevel"`
+	RateLimitIpPerMinute         uint                `json:"rateLimitIpPerMinute"`
+	RateLimitApiKeyPerMinute     uint                `json:"rateLimitApiKeyPerMinute"`
+	RateLimitSessionPerMinute    uint                `json:"rateLimitSessionPerMinute"`
+	BannedIps                    string              `json:"bannedIps"`
+	NostrBackup                  bool                `json:"nostrBackup"`
+	NostrNotificationsNpub       string              `json:"nostrNotificationsNpub"`
+	LowBalanceAlertSat           uint                `json:"lowBalanceAlertSat"`
+	LowInboundLiquidityAlertSat  uint                `json:"lowInboundLiquidityAlertSat"`
+	OnchainFeeCeiling            uint                `json:"onchainFeeCeiling"`
+	OnchainFeeCeilingDelay       bool                `json:"onchainFeeCeilingDelay"`
+
+	ChannelAcceptancePolicy *ChannelAcceptancePolicy `json:"channelAcceptancePolicy"`
+	SpendableBalanceBuckets []string                 `json:"spendableBalanceBuckets"`
+}
+
+type ReadOnlyWindow = transactions.ReadOnlyWindow
+
+type ChannelAcceptancePolicy = channelacceptance.Policy
+
+type NostrBackup = backups.NostrBackup
+
+type DatabaseMaintenanceStatus = maintenance.MaintenanceStatus
+
+type Recovery = recovery.Recovery
+
+type StartRecoveryRequest struct {
+	SetupRequest
+	// channel backup of the nostr backup
+	ChannelBackup json.RawMessage `json:"channelBackup"`
+	// base64 encoded channel backup of a backup target, encrypted with the unlock password
+	EncryptedChannelBackup string `json:"encryptedChannelBackup"`
+}
+
+type DatabaseMaintenanceReport = maintenance.MaintenanceReport
+
+type RunDatabaseMaintenanceRequest struct {
+	// also check the integrity of the database and rebuild it with VACUUM
+	Vacuum bool `json:"vacuum"`
+}
+
+type DatabaseKeyRotationStatus = maintenance.KeyRotationStatus
+
+type AdminAuditLogVerification = auditlog.Verification
+
+type RotateDatabaseEncryptionKeyRequest struct {
+	CurrentUnlockPassword string `json:"currentUnlockPassword"`
+	// optional, e.g. after the unlock secret was rotated in the KMS
+	NewUnlockPassword string `json:"newUnlockPassword"`
+}
+
+type DatabaseMigrationSnapshot = db.MigrationSnapshot
+
+type DatabaseMigrationsResponse struct {
+	LastMigration string `json:"lastMigration"`
+	// always empty while the hub runs, migrations are applied when it starts
+	PendingMigrations []string `json:"pendingMigrations"`
+	// only sqlite databases are copied before migrations are applied
+	SnapshotsEnabled bool                        `json:"snapshotsEnabled"`
+	Snapshots        []DatabaseMigrationSnapshot `json:"snapshots"`
 }
 
 type UpdateSettingsRequest struct {
-	Currency             string `json:"currency"`
-	BitcoinDisplayFormat string `json:"bitcoinDisplayFormat"`
+	Currency string `json:"currency"`
+	// additional currencies to record rates for, the display currency is always included
+	FiatCurrencies       *[]string `json:"fiatCurrencies"`
+	BitcoinDisplayFormat string    `json:"bitcoinDisplayFormat"`
+	// hub-wide maximum single payment amount, 0 removes the limit
+	MaxPaymentAmountSat *uint64 `json:"maxPaymentAmount"`
+	// maximum size of encoded transaction metadata in bytes, 0 restores the default
+	MetadataMaxLength *uint64 `json:"metadataMaxLength"`
+	// reject or truncate metadata which exceeds the limit
+	MetadataPolicy string `json:"metadataPolicy"`
+	// transactions older than this are archived, 0 disables archiving
+	TransactionRetentionMonths *uint `json:"transactionRetentionMonths"`
+	// app activity older than this is deleted, 0 keeps it forever
+	AppActivityRetentionDays *uint `json:"appActivityRetentionDays"`
+	// NIP-47 requests and their responses older than this are deleted, 0 keeps them forever
+	RequestEventRetentionDays *uint `json:"requestEventRetentionDays"`
+	// app audit logs older than this are deleted, 0 keeps them forever
+	AuditLogRetentionDays *uint `json:"auditLogRetentionDays"`
+	// finished webhook deliveries older than this are deleted, 0 keeps them forever
+	WebhookDeliveryRetentionDays *uint `json:"webhookDeliveryRetentionDays"`
+	// percentages of app budgets at which an alert is sent, an empty list disables the alerts
+	BudgetAlertThresholds *[]uint `json:"budgetAlertThresholds"`
+	// comma-separated IP addresses, CIDR ranges, "tailscale" or "tor" which may use the admin API, empty allows all
+	AdminAllowedNetworks *string `json:"adminAllowedNetworks"`
+	// disables all spending until switched off again
+	ReadOnlyMode *bool `json:"readOnlyMode"`
+	// recurring periods in which spending is disabled, an empty list removes them
+	ReadOnlyWindows *[]ReadOnlyWindow `json:"readOnlyWindows"`
+	// relays to use instead of RELAY, an empty list restores RELAY
+	Relays *[]string `json:"relays"`
+	// logrus log level from 0 (panic) to 6 (trace)
+	LogLevel *uint `json:"logLevel"`
+	// requests per minute, 0 removes the limit
+	RateLimitIpPerMinute      *uint `json:"rateLimitIpPerMinute"`
+	RateLimitApiKeyPerMinute  *uint `json:"rateLimitApiKeyPerMinute"`
+	RateLimitSessionPerMinute *uint `json:"rateLimitSessionPerMinute"`
+	// comma-separated IP addresses and CIDR ranges, empty restores BANNED_IPS
+	BannedIps *string `json:"bannedIps"`
+	// publishes an encrypted backup to the relays of the hub
+	NostrBackup *bool `json:"nostrBackup"`
+	// npub which receives direct messages about critical events, empty disables them
+	NostrNotificationsNpub *string `json:"nostrNotificationsNpub"`
+	// publish an alert when the spendable balance or the receivable capacity drops below
+	// these amounts in sats, 0 disables the alert
+	LowBalanceAlertSat          *uint `json:"lowBalanceAlertSat"`
+	LowInboundLiquidityAlertSat *uint `json:"lowInboundLiquidityAlertSat"`
+	// fee rate in sat/vB above which an alert is published, 0 disables it
+	OnchainFeeCeiling *uint `json:"onchainFeeCeiling"`
+	// delays channel opens, swaps and on-chain payments while the fee rate is above the ceiling
+	OnchainFeeCeilingDelay *bool `json:"onchainFeeCeilingDelay"`
+	// rules for inbound channel requests, the zero-conf peers apply to LDK after a restart
+	ChannelAcceptancePolicy *ChannelAcceptancePolicy `json:"channelAcceptancePolicy"`
+	// buckets which count towards the total spendable balance: onchain, lightning, pending_ark and ecash
+	SpendableBalanceBuckets *[]string `json:"spendableBalanceBuckets"`
 }
 
 type SetNodeAliasRequest struct {
@@ -310,6 +827,17 @@ type SetNodeAliasRequest struct {
 
 type MnemonicRequest struct {
 	UnlockPassword string `json:"unlockPassword"`
+	TotpCode       string `json:"totpCode"`
+	// also required once a seed passphrase is set
+	SeedPassphrase string `json:"seedPassphrase"`
+}
+
+type SetSeedPassphraseRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	// required to change or remove an existing seed passphrase
+	CurrentSeedPassphrase string `json:"currentSeedPassphrase"`
+	// an empty passphrase removes the seed passphrase
+	NewSeedPassphrase string `json:"newSeedPassphrase"`
 }
 
 type MnemonicResponse struct {
@@ -338,11 +866,25 @@ type RebalanceChannelResponse struct {
 	TotalFeeSat uint64 `json:"totalFeeSat"`
 }
 
+type CircularRebalanceRequest struct {
+	OutgoingChannelId string `json:"outgoingChannelId"`
+	IncomingChannelId string `json:"incomingChannelId"`
+	AmountSat         uint64 `json:"amountSat"`
+	MaxFeeSat         uint64 `json:"maxFeeSat"`
+}
+type CircularRebalanceResponse struct {
+	PaymentHash string `json:"paymentHash"`
+	TotalFeeSat uint64 `json:"totalFeeSat"`
+}
+
 type RedeemOnchainFundsRequest struct {
 	ToAddress string  `json:"toAddress"`
 	Amount    uint64  `json:"amount"`
 	FeeRate   *uint64 `json:"feeRate"`
 	SendAll   bool    `json:"sendAll"`
+	// only required for large amounts if two-factor authentication is enabled
+	UnlockPassword string `json:"unlockPassword"`
+	TotpCode       string `json:"totpCode"`
 }
 
 type RedeemOnchainFundsResponse struct {
@@ -350,7 +892,19 @@ type RedeemOnchainFundsResponse struct {
 }
 
 type OnchainBalanceResponse = lnclient.OnchainBalanceResponse
-type BalancesResponse = lnclient.BalancesResponse
+type BalancesResponse struct {
+	lnclient.BalancesResponse
+	// Taproot Assets held by the tapd node of an LND backend
+	Assets []AssetBalance `json:"assets,omitempty"`
+	// total of the buckets counted as spendable, the same amount NIP-47 get_balance returns
+	Spendable *SpendableBalance `json:"spendable"`
+}
+
+type SpendableBalance = spendablebalance.SpendableBalance
+
+type AssetBalance = taprootassets.AssetBalance
+
+type AssetTransfer = taprootassets.AssetTransfer
 
 type SendPaymentResponse = Transaction
 type MakeInvoiceResponse = Transaction
@@ -359,6 +913,8 @@ type LookupInvoiceResponse = Transaction
 type ListTransactionsResponse struct {
 	TotalCount   uint64        `json:"totalCount"`
 	Transactions []Transaction `json:"transactions"`
+	// the most recent Taproot Asset transfers, on the first page of the node's transactions
+	AssetTransfers []AssetTransfer `json:"assetTransfers,omitempty"`
 }
 
 // TODO: camelCase
@@ -379,6 +935,82 @@ type Transaction struct {
 	Metadata        Metadata    `json:"metadata,omitempty"`
 	Boostagram      *Boostagram `json:"boostagram,omitempty"`
 	FailureReason   string      `json:"failureReason"`
+	RefundedAmount  uint64      `json:"refundedAmount,omitempty"`
+	// values based on the rates at the time the transaction settled
+	FiatValues []FiatValue `json:"fiatValues,omitempty"`
+	// only set for hold invoices
+	HoldState  string  `json:"holdState,omitempty"`
+	AcceptedAt *string `json:"acceptedAt,omitempty"`
+	CanceledAt *string `json:"canceledAt,omitempty"`
+}
+
+type FiatValue struct {
+	Currency string  `json:"currency"`
+	Rate     float64 `json:"rate"`
+	Value    float64 `json:"value"`
+}
+
+type RefundTransactionRequest struct {
+	// optional. If not provided an invoice is requested from the payer's lightning address
+	Invoice string `json:"invoice"`
+	// optional amount in millisats, defaults to the remaining refundable amount
+	Amount *uint64 `json:"amount"`
+}
+
+// amounts are in millisats
+type TransactionsSummaryTotals struct {
+	Count      uint64   `json:"count"`
+	Amount     uint64   `json:"amount"`
+	FeesPaid   uint64   `json:"feesPaid"`
+	FiatAmount *float64 `json:"fiatAmount,omitempty"`
+}
+
+type AppTransactionsSummary struct {
+	AppId    *uint                     `json:"appId"`
+	Sent     TransactionsSummaryTotals `json:"sent"`
+	Received TransactionsSummaryTotals `json:"received"`
+}
+
+type DailyTransactionsSummary struct {
+	Date     string                    `json:"date"`
+	Sent     TransactionsSummaryTotals `json:"sent"`
+	Received TransactionsSummaryTotals `json:"received"`
+}
+
+type TransactionsSummary struct {
+	From     time.Time                  `json:"from"`
+	Until    time.Time                  `json:"until"`
+	Currency string                     `json:"currency,omitempty"`
+	Sent     TransactionsSummaryTotals  `json:"sent"`
+	Received TransactionsSummaryTotals  `json:"received"`
+	Apps     []AppTransactionsSummary   `json:"apps"`
+	Days     []DailyTransactionsSummary `json:"days"`
+}
+
+type AppReportDestination struct {
+	Destination string `json:"destination"` // node pubkey
+	Count       uint64 `json:"count"`
+	Amount      uint64 `json:"amount"`
+	FeesPaid    uint64 `json:"feesPaid"`
+}
+
+// AppReportBudget is the usage of a budget in its current period
+type AppReportBudget struct {
+	BudgetRenewal string  `json:"budgetRenewal"`
+	MaxAmountSat  uint64  `json:"maxAmount"`
+	BudgetUsage   uint64  `json:"budgetUsage"`
+	RenewsAt      *uint64 `json:"renewsAt"`
+}
+
+type AppReport struct {
+	AppId           uint                      `json:"appId"`
+	From            time.Time                 `json:"from"`
+	Until           time.Time                 `json:"until"`
+	Currency        string                    `json:"currency,omitempty"`
+	Sent            TransactionsSummaryTotals `json:"sent"`
+	Received        TransactionsSummaryTotals `json:"received"`
+	TopDestinations []AppReportDestination    `json:"topDestinations"`
+	Budgets         []AppReportBudget         `json:"budgets"`
 }
 
 type Metadata = map[string]interface{}
@@ -398,6 +1030,12 @@ type Boostagram struct {
 	Time           string `json:"time"`
 	Action         string `json:"action"`
 	ValueMsatTotal int64  `json:"valueMsatTotal"`
+	Guid           string `json:"guid,omitempty"`
+	EpisodeGuid    string `json:"episodeGuid,omitempty"`
+	RemoteFeedGuid string `json:"remoteFeedGuid,omitempty"`
+	RemoteItemGuid string `json:"remoteItemGuid,omitempty"`
+	ReplyAddress   string `json:"replyAddress,omitempty"`
+	BoostLink      string `json:"boostLink,omitempty"`
 }
 
 // debug api
@@ -443,6 +1081,8 @@ type SignMessageResponse struct {
 type PayInvoiceRequest struct {
 	Amount   *uint64  `json:"amount"`
 	Metadata Metadata `json:"metadata"`
+	// optional. Retrying with the same key returns the original result instead of paying again
+	IdempotencyKey string `json:"idempotencyKey"`
 }
 
 type MakeOfferRequest struct {
@@ -460,10 +1100,67 @@ type ResetRouterRequest struct {
 
 type BasicBackupRequest struct {
 	UnlockPassword string `json:"unlockPassword"`
+	TotpCode       string `json:"totpCode"`
+	// required if a seed passphrase is set, the backup contains the recovery phrase
+	SeedPassphrase string `json:"seedPassphrase"`
+}
+
+// BackupInfo describes a migration backup which passed verification
+type BackupInfo struct {
+	// backups of older versions have no manifest and only their database is verified
+	HasManifest bool       `json:"hasManifest"`
+	MigrationId string     `json:"migrationId,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	HubVersion  string     `json:"hubVersion,omitempty"`
+	BackendType string     `json:"backendType,omitempty"`
+	Files       int        `json:"files"`
+}
+
+type TransactionReceipt struct {
+	Version     int       `json:"version"`
+	Type        string    `json:"type"`
+	PaymentHash string    `json:"paymentHash"`
+	Preimage    string    `json:"preimage"`
+	Amount      uint64    `json:"amount"`
+	Description string    `json:"description"`
+	SettledAt   time.Time `json:"settledAt"`
+	NodePubkey  string    `json:"nodePubkey"`
+	Message     string    `json:"message"`
+	Signature   string    `json:"signature"`
+}
+
+type ArchiveTransactionsRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	// overrides the configured retention period
+	RetentionMonths uint `json:"retentionMonths"`
+}
+
+type ExportAppsRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	// includes the keys of the connections, so they keep working after importing them
+	IncludeSecrets bool `json:"includeSecrets"`
+}
+
+type ImportAppsRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	// the unlock password of the hub the export was created on, if it is different
+	ExportPassword string `json:"exportPassword"`
+}
+
+type ImportAppsResponse struct {
+	Apps    []CreateAppResponse    `json:"apps"`
+	Skipped []ImportAppsSkippedApp `json:"skipped"`
+}
+
+type ImportAppsSkippedApp struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
 }
 
 type BasicRestoreWailsRequest struct {
 	UnlockPassword string `json:"unlockPassword"`
+	// required for backups of hubs with a seed passphrase which do not contain the recovery phrase
+	SeedPassphrase string `json:"seedPassphrase"`
 }
 
 type NetworkGraphResponse = lnclient.NetworkGraphResponse
@@ -476,6 +1173,7 @@ type LSPOrderRequest struct {
 }
 
 type LSPOrderResponse struct {
+	OrderId           string `json:"orderId"`
 	Invoice           string `json:"invoice"`
 	Fee               uint64 `json:"fee"`
 	InvoiceAmount     uint64 `json:"invoiceAmount"`
@@ -483,6 +1181,19 @@ type LSPOrderResponse struct {
 	OutgoingLiquidity uint64 `json:"outgoingLiquidity"`
 }
 
+type LSPOrder struct {
+	ID              uint      `json:"id"`
+	LSPIdentifier   string    `json:"lspIdentifier"`
+	OrderId         string    `json:"orderId"`
+	State           string    `json:"state"` // CREATED, COMPLETED or FAILED
+	PaymentState    string    `json:"paymentState"`
+	IncomingAmount  uint64    `json:"incomingAmount"` // in sats
+	Fee             uint64    `json:"fee"`            // in sats
+	FundingOutpoint string    `json:"fundingOutpoint"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
 type WalletCapabilitiesResponse struct {
 	Scopes            []string `json:"scopes"`
 	Methods           []string `json:"methods"`
@@ -513,6 +1224,9 @@ type Channel struct {
 
 type MigrateNodeStorageRequest struct {
 	To string `json:"to"`
+	// only required if two-factor authentication is enabled
+	UnlockPassword string `json:"unlockPassword"`
+	TotpCode       string `json:"totpCode"`
 }
 
 type HealthAlarmKind string
@@ -565,3 +1279,505 @@ type GetForwardsResponse struct {
 	TotalFeeEarnedMsat          uint64 `json:"totalFeeEarnedMsat"`
 	NumForwards                 uint64 `json:"numForwards"`
 }
+
+type WebhookFilter = webhooks.Filter
+type WebhookDeliveryStats = webhooks.DeliveryStats
+
+type Webhook struct {
+	ID         uint                  `json:"id"`
+	Url        string                `json:"url"`
+	EventTypes []string              `json:"eventTypes"`
+	Filter     *WebhookFilter        `json:"filter"`
+	Enabled    bool                  `json:"enabled"`
+	AppId      *uint                 `json:"appId"`
+	Deliveries *WebhookDeliveryStats `json:"deliveries,omitempty"`
+	CreatedAt  time.Time             `json:"createdAt"`
+}
+
+type CreateWebhookRequest struct {
+	Url string `json:"url"`
+	// "*" subscribes to all event types
+	EventTypes []string       `json:"eventTypes"`
+	Filter     *WebhookFilter `json:"filter"`
+	// only sends the events of this app if set
+	AppId *uint `json:"appId"`
+}
+
+// UpdateWebhookRequest only changes the fields which are set
+type UpdateWebhookRequest struct {
+	Enabled    *bool          `json:"enabled"`
+	EventTypes []string       `json:"eventTypes"`
+	Filter     *WebhookFilter `json:"filter"`
+}
+
+type CreateWebhookResponse struct {
+	Webhook
+	// the secret is only returned once, on creation
+	Secret string `json:"secret"`
+}
+
+type WebhookDelivery struct {
+	ID             uint      `json:"id"`
+	EventType      string    `json:"eventType"`
+	Payload        string    `json:"payload"`
+	State          string    `json:"state"`
+	Attempts       int       `json:"attempts"`
+	ResponseStatus int       `json:"responseStatus"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type ScheduledPayment struct {
+	ID          uint       `json:"id"`
+	AppId       *uint      `json:"appId"`
+	Destination string     `json:"destination"`
+	Amount      uint64     `json:"amount"` // in millisats
+	Comment     string     `json:"comment"`
+	Schedule    string     `json:"schedule"`
+	NextRunAt   time.Time  `json:"nextRunAt"`
+	EndsAt      *time.Time `json:"endsAt"`
+	Enabled     bool       `json:"enabled"`
+	LastRunAt   *time.Time `json:"lastRunAt"`
+	LastError   string     `json:"lastError,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type CreateScheduledPaymentRequest struct {
+	AppId       *uint      `json:"appId"`
+	Destination string     `json:"destination"`
+	Amount      uint64     `json:"amount"` // in millisats
+	Comment     string     `json:"comment"`
+	Schedule    string     `json:"schedule"`
+	EndsAt      *time.Time `json:"endsAt"`
+}
+
+type SwapRule struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`      // swap_out_excess or keep_inbound
+	Threshold   uint64     `json:"threshold"` // in sats
+	Destination string     `json:"destination"`
+	MinAmount   uint64     `json:"minAmount"` // in sats
+	DryRun      bool       `json:"dryRun"`
+	Enabled     bool       `json:"enabled"`
+	LastRunAt   *time.Time `json:"lastRunAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type CreateSwapRuleRequest struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Threshold   uint64 `json:"threshold"` // in sats
+	Destination string `json:"destination"`
+	MinAmount   uint64 `json:"minAmount"` // in sats
+	DryRun      bool   `json:"dryRun"`
+}
+
+type UpdateSwapRuleRequest struct {
+	Enabled *bool `json:"enabled"`
+	DryRun  *bool `json:"dryRun"`
+}
+
+type SwapRuleRun struct {
+	ID        uint      `json:"id"`
+	Amount    uint64    `json:"amount"` // in sats
+	DryRun    bool      `json:"dryRun"`
+	SwapId    string    `json:"swapId,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type FeePolicy struct {
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`
+	PeerPubkeys   []string   `json:"peerPubkeys"` // empty for all channels
+	Strategy      string     `json:"strategy"`    // static, proportional or flow
+	MinLocalRatio float64    `json:"minLocalRatio"`
+	MaxLocalRatio float64    `json:"maxLocalRatio"`
+	BaseFeeMsat   uint32     `json:"baseFeeMsat"`
+	MinFeePpm     uint32     `json:"minFeePpm"`
+	MaxFeePpm     uint32     `json:"maxFeePpm"`
+	Priority      int        `json:"priority"`
+	Enabled       bool       `json:"enabled"`
+	LastRunAt     *time.Time `json:"lastRunAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type CreateFeePolicyRequest struct {
+	Name          string   `json:"name"`
+	PeerPubkeys   []string `json:"peerPubkeys"`
+	Strategy      string   `json:"strategy"`
+	MinLocalRatio float64  `json:"minLocalRatio"`
+	MaxLocalRatio float64  `json:"maxLocalRatio"` // defaults to 1
+	BaseFeeMsat   uint32   `json:"baseFeeMsat"`
+	MinFeePpm     uint32   `json:"minFeePpm"`
+	MaxFeePpm     uint32   `json:"maxFeePpm"`
+	Priority      int      `json:"priority"`
+}
+
+type UpdateFeePolicyRequest struct {
+	Enabled  *bool `json:"enabled"`
+	Priority *int  `json:"priority"`
+}
+
+type LiquidityReport = liquidityhistory.LiquidityReport
+
+type BackupTarget struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	Endpoint       string     `json:"endpoint"`
+	Region         string     `json:"region"`
+	Bucket         string     `json:"bucket"`
+	Prefix         string     `json:"prefix"`
+	AccessKeyId    string     `json:"accessKeyId"`
+	IntervalHours  uint       `json:"intervalHours"`
+	KeepLast       uint       `json:"keepLast"`
+	KeepDays       uint       `json:"keepDays"`
+	Enabled        bool       `json:"enabled"`
+	LastBackupAt   *time.Time `json:"lastBackupAt"`
+	LastVerifiedAt *time.Time `json:"lastVerifiedAt"`
+	LastError      string     `json:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type CreateBackupTargetRequest struct {
+	Name            string `json:"name"`
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix"`
+	AccessKeyId     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	IntervalHours   uint   `json:"intervalHours"`
+	KeepLast        uint   `json:"keepLast"`
+	KeepDays        uint   `json:"keepDays"`
+}
+
+type UpdateBackupTargetRequest struct {
+	Enabled       *bool `json:"enabled"`
+	IntervalHours *uint `json:"intervalHours"`
+	KeepLast      *uint `json:"keepLast"`
+	KeepDays      *uint `json:"keepDays"`
+}
+
+type RunBackupRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+}
+
+type BackupSnapshot struct {
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type SubwalletAddress struct {
+	ID          uint      `json:"id"`
+	AppId       uint      `json:"appId"`
+	Address     string    `json:"address"`
+	ReceivedSat uint64    `json:"receivedSat"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type SetSubwalletOwnerPasswordRequest struct {
+	// an empty password disables the owner login
+	Password string `json:"password"`
+}
+
+type SubwalletLoginRequest struct {
+	AppId           uint    `json:"appId"`
+	Password        string  `json:"password"`
+	TokenExpiryDays *uint64 `json:"tokenExpiryDays"`
+}
+
+const (
+	// full access, including managing admin users, api keys and the unlock password
+	ADMIN_USER_ROLE_OWNER = "owner"
+	// full access to the node, payments and apps
+	ADMIN_USER_ROLE_OPERATOR = "operator"
+	// read-only access
+	ADMIN_USER_ROLE_VIEWER = "viewer"
+	// can only manage the apps linked to the admin user
+	ADMIN_USER_ROLE_APP_MANAGER = "app_manager"
+)
+
+var AdminUserRoles = []string{
+	ADMIN_USER_ROLE_OWNER,
+	ADMIN_USER_ROLE_OPERATOR,
+	ADMIN_USER_ROLE_VIEWER,
+	ADMIN_USER_ROLE_APP_MANAGER,
+}
+
+// AdminUser is a secondary login with its own credentials and sessions
+type AdminUser struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	AppIds    []uint    `json:"appIds"`
+	CreatedAt time.Time `json:"createdAt"`
+	// otpauth URI of a new two-factor secret, only returned when it was generated
+	TotpUri string `json:"totpUri,omitempty"`
+}
+
+type CreateAdminUserRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	// defaults to app_manager
+	Role string `json:"role"`
+	// the apps an app manager can manage, in addition to the ones they create
+	AppIds []uint `json:"appIds"`
+}
+
+type UpdateAdminUserRequest struct {
+	Password *string `json:"password"`
+	Role     *string `json:"role"`
+	AppIds   *[]uint `json:"appIds"`
+	// generates a new two-factor secret, e.g. if the admin user lost their authenticator
+	ResetTotp bool `json:"resetTotp"`
+}
+
+type AdminUserLoginRequest struct {
+	Name            string  `json:"name"`
+	Password        string  `json:"password"`
+	TokenExpiryDays *uint64 `json:"tokenExpiryDays"`
+	// required for owners and operators
+	TotpCode string `json:"totpCode"`
+}
+
+const (
+	API_KEY_SCOPE_INVOICES     = "invoices"
+	API_KEY_SCOPE_PAYMENTS     = "payments"
+	API_KEY_SCOPE_TRANSACTIONS = "transactions"
+	API_KEY_SCOPE_APPS         = "apps"
+)
+
+// ApiKey authenticates scripts and backends against the automation API
+type ApiKey struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"keyPrefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateApiKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// the key never expires if not set
+	ExpiryDays *uint64 `json:"expiryDays"`
+}
+
+type CreateApiKeyResponse struct {
+	ApiKey
+	// only returned once, the hub only stores a hash of it
+	Key string `json:"key"`
+	// the API can also be reached at this URL if the hub is published as an onion service
+	OnionUrl string `json:"onionUrl,omitempty"`
+}
+
+// Session is a login of the web UI or a sub-wallet, identified by the id of its token
+type Session struct {
+	ID          uint       `json:"id"`
+	Permission  string     `json:"permission"`
+	AdminUserId *uint      `json:"adminUserId"`
+	AppId       *uint      `json:"appId"`
+	IpAddress   string     `json:"ipAddress"`
+	UserAgent   string     `json:"userAgent"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	// true for the session the request was made with
+	Current bool `json:"current"`
+}
+
+type CreateSessionRequest struct {
+	TokenId     string
+	Permission  string
+	AdminUserId *uint
+	AppId       *uint
+	IpAddress   string
+	UserAgent   string
+	ExpiresAt   time.Time
+}
+
+type SetupTotpRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+}
+
+type SetupTotpResponse struct {
+	Secret string `json:"secret"`
+	// otpauth URI to show as QR code
+	Uri string `json:"uri"`
+}
+
+type EnableTotpRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	// a code of the secret returned by the setup, to confirm the authenticator app was set up
+	Code string `json:"code"`
+}
+
+type EnableTotpResponse struct {
+	// single-use codes to log in without the authenticator app, only returned once
+	BackupCodes []string `json:"backupCodes"`
+}
+
+type DisableTotpRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	Code           string `json:"code"`
+}
+
+type DuressSettings struct {
+	Enabled bool `json:"enabled"`
+	// the isolated app which is opened as decoy wallet
+	AppId uint `json:"appId"`
+}
+
+type SetDuressPasswordRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	DuressPassword string `json:"duressPassword"`
+	// an existing isolated app to use as decoy wallet, a new one is created if not set
+	AppId uint `json:"appId"`
+}
+
+type DisableDuressPasswordRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+}
+
+type UpdateEmailNotificationsRequest = notifications.EmailSettings
+
+type EmailNotificationSettings struct {
+	notifications.EmailSettings
+	// whether the SMTP server is set up with the SMTP_* environment variables
+	SmtpConfigured bool `json:"smtpConfigured"`
+}
+
+// PushDevice is a mobile device which receives payment and security alerts.
+// The token is not returned, it is only needed to deliver notifications.
+type PushDevice struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	Transport      string     `json:"transport"`
+	LastNotifiedAt *time.Time `json:"lastNotifiedAt"`
+	LastError      string     `json:"lastError"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type RegisterPushDeviceRequest struct {
+	Name string `json:"name"`
+	// unifiedpush, fcm or apns
+	Transport string `json:"transport"`
+	// the UnifiedPush endpoint or the FCM/APNs device token
+	Token string `json:"token"`
+}
+
+// DeadLetter is an event which a consumer (webhooks, email, push, nostr_dm or
+// nip47_notifications) failed to handle
+type DeadLetter struct {
+	ID       uint   `json:"id"`
+	Consumer string `json:"consumer"`
+	Event    string `json:"event"`
+	// JSON of what the consumer needs to replay the event
+	Payload        string     `json:"payload"`
+	Error          string     `json:"error"`
+	State          string     `json:"state"`
+	ReplayAttempts int        `json:"replayAttempts"`
+	ReplayedAt     *time.Time `json:"replayedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type ReplayDeadLettersResponse = deadletters.ReplayResult
+
+// Passkey is a WebAuthn credential which can be used instead of the unlock password to log in
+type Passkey struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// The passkey options and credentials use the WebAuthn JSON serialization, so that they can be
+// passed to PublicKeyCredential.parseCreationOptionsFromJSON and returned from PublicKeyCredential.toJSON
+type PasskeyRegistrationOptions struct {
+	Challenge              string                        `json:"challenge"`
+	Rp                     PasskeyRelyingParty           `json:"rp"`
+	User                   PasskeyUser                   `json:"user"`
+	PubKeyCredParams       []PasskeyCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                uint                          `json:"timeout"`
+	ExcludeCredentials     []PasskeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeyAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                        `json:"attestation"`
+}
+
+type PasskeyRelyingParty struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type PasskeyUser struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type PasskeyCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type PasskeyCredentialDescriptor struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type PasskeyAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type FinishPasskeyRegistrationRequest struct {
+	Name       string                        `json:"name"`
+	Credential PasskeyRegistrationCredential `json:"credential"`
+}
+
+type PasskeyRegistrationCredential struct {
+	Id       string `json:"id"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+type PasskeyLoginOptions struct {
+	Challenge        string                        `json:"challenge"`
+	RpId             string                        `json:"rpId"`
+	Timeout          uint                          `json:"timeout"`
+	AllowCredentials []PasskeyCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                        `json:"userVerification"`
+}
+
+type PasskeyLoginCredential struct {
+	Id       string `json:"id"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+	} `json:"response"`
+}
+
+type FinishPasskeyLoginRequest struct {
+	Credential      PasskeyLoginCredential `json:"credential"`
+	TokenExpiryDays *uint64                `json:"tokenExpiryDays"`
+	Permission      string                 `json:"permission,omitempty"` // "full" or "readonly"
+}
+
+type RevokeAllSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+type SubwalletTransferRequest struct {
+	AmountSat uint64 `json:"amountSat"`
+	ToAppId   uint   `json:"toAppId"`
+}
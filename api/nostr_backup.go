@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/getAlby/hub/backups"
+)
+
+const nostrBackupTimeout = 15 * time.Second
+
+// GetNostrBackup fetches the latest backup from the relays to check that it can be restored
+func (api *api) GetNostrBackup(ctx context.Context) (*NostrBackup, error) {
+	if api.keys.GetNostrSecretKey() == "" {
+		return nil, errors.New("hub is locked")
+	}
+	ctx, cancel := context.WithTimeout(ctx, nostrBackupTimeout)
+	defer cancel()
+	pool := nostr.NewSimplePool(ctx)
+	defer pool.Close("done")
+
+	return backups.FetchNostrBackup(ctx, pool, api.cfg.GetRelayUrls(), api.keys.GetNostrSecretKey())
+}
+
+// PublishNostrBackup publishes the backup right away instead of waiting for the next check
+func (api *api) PublishNostrBackup(ctx context.Context) (*NostrBackup, error) {
+	if api.keys.GetNostrSecretKey() == "" {
+		return nil, errors.New("hub is locked")
+	}
+	backup, err := backups.BuildNostrBackup(api.db, api.cfg.GetEnv().Workdir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nostrBackupTimeout)
+	defer cancel()
+	pool := nostr.NewSimplePool(ctx)
+	defer pool.Close("done")
+
+	if err := backups.PublishNostrBackup(ctx, pool, api.cfg.GetRelayUrls(), api.keys.GetNostrSecretKey(), backup); err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
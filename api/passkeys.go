@@ -0,0 +1,311 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/webauthn"
+)
+
+const (
+	passkeyTimeout          = 5 * time.Minute
+	passkeyRelyingPartyName = "Alby Hub"
+	// the hub has a single user, so the user handle does not need to identify anyone
+	passkeyUserHandle = "alby-hub"
+)
+
+const (
+	passkeyChallengeRegistration = "registration"
+	passkeyChallengeLogin        = "login"
+)
+
+type passkeyChallenge struct {
+	kind      string
+	expiresAt time.Time
+}
+
+// challenges are only valid for a single ceremony, so they are not persisted
+var (
+	passkeyChallenges      = map[string]passkeyChallenge{}
+	passkeyChallengesMutex sync.Mutex
+)
+
+func (api *api) ListPasskeys() ([]Passkey, error) {
+	var dbPasskeys []db.Passkey
+	if err := api.db.Order("id").Find(&dbPasskeys).Error; err != nil {
+		return nil, err
+	}
+
+	passkeys := []Passkey{}
+	for _, dbPasskey := range dbPasskeys {
+		passkeys = append(passkeys, *toApiPasskey(&dbPasskey))
+	}
+	return passkeys, nil
+}
+
+func (api *api) BeginPasskeyRegistration() (*PasskeyRegistrationOptions, error) {
+	rp, err := api.passkeyRelyingParty()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := newPasskeyChallenge(passkeyChallengeRegistration)
+	if err != nil {
+		return nil, err
+	}
+	excludeCredentials, err := api.passkeyCredentialDescriptors()
+	if err != nil {
+		return nil, err
+	}
+
+	pubKeyCredParams := []PasskeyCredentialParameters{}
+	for _, algorithm := range webauthn.SupportedAlgorithms {
+		pubKeyCredParams = append(pubKeyCredParams, PasskeyCredentialParameters{Type: "public-key", Alg: algorithm})
+	}
+
+	return &PasskeyRegistrationOptions{
+		Challenge: challenge,
+		Rp: PasskeyRelyingParty{
+			Id:   rp.Id,
+			Name: rp.Name,
+		},
+		User: PasskeyUser{
+			Id:          webauthn.EncodeBase64([]byte(passkeyUserHandle)),
+			Name:        "hub",
+			DisplayName: passkeyRelyingPartyName,
+		},
+		PubKeyCredParams:   pubKeyCredParams,
+		Timeout:            uint(passkeyTimeout.Milliseconds()),
+		ExcludeCredentials: excludeCredentials,
+		AuthenticatorSelection: PasskeyAuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+func (api *api) FinishPasskeyRegistration(finishPasskeyRegistrationRequest *FinishPasskeyRegistrationRequest) (*Passkey, error) {
+	if finishPasskeyRegistrationRequest.Name == "" {
+		return nil, errors.New("no passkey name provided")
+	}
+	rp, err := api.passkeyRelyingParty()
+	if err != nil {
+		return nil, err
+	}
+
+	response := finishPasskeyRegistrationRequest.Credential.Response
+	clientDataJSON, err := webauthn.DecodeBase64(response.ClientDataJSON)
+	if err != nil {
+		return nil, errors.New("invalid client data")
+	}
+	attestationObject, err := webauthn.DecodeBase64(response.AttestationObject)
+	if err != nil {
+		return nil, errors.New("invalid attestation object")
+	}
+	challenge, err := consumePasskeyChallenge(clientDataJSON, passkeyChallengeRegistration)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := rp.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	passkey := db.Passkey{
+		Name:         finishPasskeyRegistrationRequest.Name,
+		CredentialId: webauthn.EncodeBase64(credential.Id),
+		PublicKey:    webauthn.EncodeBase64(credential.PublicKey),
+		SignCount:    credential.SignCount,
+	}
+	if err := api.db.Create(&passkey).Error; err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"passkey_id": passkey.ID,
+		"name":       passkey.Name,
+	}).Info("Registered passkey")
+	return toApiPasskey(&passkey), nil
+}
+
+func (api *api) DeletePasskey(id uint) error {
+	result := api.db.Delete(&db.Passkey{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("passkey not found")
+	}
+	logger.Logger.WithField("passkey_id", id).Info("Deleted passkey")
+	return nil
+}
+
+func (api *api) BeginPasskeyLogin() (*PasskeyLoginOptions, error) {
+	rp, err := api.passkeyRelyingParty()
+	if err != nil {
+		return nil, err
+	}
+	allowCredentials, err := api.passkeyCredentialDescriptors()
+	if err != nil {
+		return nil, err
+	}
+	if len(allowCredentials) == 0 {
+		return nil, errors.New("no passkeys registered")
+	}
+	challenge, err := newPasskeyChallenge(passkeyChallengeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyLoginOptions{
+		Challenge:        challenge,
+		RpId:             rp.Id,
+		Timeout:          uint(passkeyTimeout.Milliseconds()),
+		AllowCredentials: allowCredentials,
+		UserVerification: "required",
+	}, nil
+}
+
+// FinishPasskeyLogin verifies the assertion of a registered passkey. The caller issues the session.
+func (api *api) FinishPasskeyLogin(credential *PasskeyLoginCredential) (*Passkey, error) {
+	rp, err := api.passkeyRelyingParty()
+	if err != nil {
+		return nil, err
+	}
+
+	var passkey db.Passkey
+	if api.db.Limit(1).Find(&passkey, &db.Passkey{CredentialId: credential.Id}).RowsAffected == 0 {
+		return nil, errors.New("unknown passkey")
+	}
+	clientDataJSON, err := webauthn.DecodeBase64(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, errors.New("invalid client data")
+	}
+	authenticatorData, err := webauthn.DecodeBase64(credential.Response.AuthenticatorData)
+	if err != nil {
+		return nil, errors.New("invalid authenticator data")
+	}
+	signature, err := webauthn.DecodeBase64(credential.Response.Signature)
+	if err != nil {
+		return nil, errors.New("invalid signature")
+	}
+	credentialId, err := webauthn.DecodeBase64(passkey.CredentialId)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := webauthn.DecodeBase64(passkey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := consumePasskeyChallenge(clientDataJSON, passkeyChallengeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	signCount, err := rp.VerifyAssertion(&webauthn.Credential{
+		Id:        credentialId,
+		PublicKey: publicKey,
+		SignCount: passkey.SignCount,
+	}, challenge, clientDataJSON, authenticatorData, signature)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("passkey_id", passkey.ID).Warn("Rejected passkey login")
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := api.db.Model(&passkey).Updates(map[string]any{
+		"sign_count":   signCount,
+		"last_used_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	passkey.SignCount = signCount
+	passkey.LastUsedAt = &now
+	return toApiPasskey(&passkey), nil
+}
+
+func (api *api) hasPasskeys() bool {
+	var count int64
+	if err := api.db.Model(&db.Passkey{}).Count(&count).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to count passkeys")
+		return false
+	}
+	return count > 0
+}
+
+// passkeys are bound to the domain of the frontend, which therefore has to be configured
+func (api *api) passkeyRelyingParty() (*webauthn.RelyingParty, error) {
+	frontendUrl := api.cfg.GetEnv().GetBaseFrontendUrl()
+	if frontendUrl == "" {
+		return nil, errors.New("passkeys require BASE_URL to be set")
+	}
+	return webauthn.NewRelyingParty(frontendUrl, passkeyRelyingPartyName)
+}
+
+func (api *api) passkeyCredentialDescriptors() ([]PasskeyCredentialDescriptor, error) {
+	var dbPasskeys []db.Passkey
+	if err := api.db.Order("id").Find(&dbPasskeys).Error; err != nil {
+		return nil, err
+	}
+	descriptors := []PasskeyCredentialDescriptor{}
+	for _, dbPasskey := range dbPasskeys {
+		descriptors = append(descriptors, PasskeyCredentialDescriptor{Type: "public-key", Id: dbPasskey.CredentialId})
+	}
+	return descriptors, nil
+}
+
+func newPasskeyChallenge(kind string) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+	encodedChallenge := webauthn.EncodeBase64(challenge)
+
+	passkeyChallengesMutex.Lock()
+	defer passkeyChallengesMutex.Unlock()
+	now := time.Now()
+	for existingChallenge, pending := range passkeyChallenges {
+		if now.After(pending.expiresAt) {
+			delete(passkeyChallenges, existingChallenge)
+		}
+	}
+	passkeyChallenges[encodedChallenge] = passkeyChallenge{
+		kind:      kind,
+		expiresAt: now.Add(passkeyTimeout),
+	}
+	return encodedChallenge, nil
+}
+
+// consumePasskeyChallenge returns the challenge the client data was created for, if it was issued by the hub
+func consumePasskeyChallenge(clientDataJSON []byte, kind string) ([]byte, error) {
+	var clientData struct {
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return nil, errors.New("invalid client data")
+	}
+
+	passkeyChallengesMutex.Lock()
+	defer passkeyChallengesMutex.Unlock()
+	pending, ok := passkeyChallenges[clientData.Challenge]
+	if !ok || pending.kind != kind || time.Now().After(pending.expiresAt) {
+		return nil, errors.New("unknown or expired challenge")
+	}
+	delete(passkeyChallenges, clientData.Challenge)
+	return webauthn.DecodeBase64(clientData.Challenge)
+}
+
+func toApiPasskey(passkey *db.Passkey) *Passkey {
+	return &Passkey{
+		ID:         passkey.ID,
+		Name:       passkey.Name,
+		LastUsedAt: passkey.LastUsedAt,
+		CreatedAt:  passkey.CreatedAt,
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/webauthn"
+)
+
+func TestPasskeys(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg}
+
+	_, err = theAPI.BeginPasskeyRegistration()
+	assert.EqualError(t, err, "passkeys require BASE_URL to be set")
+	svc.Cfg.GetEnv().BaseUrl = "https://hub.example.com"
+
+	_, err = theAPI.BeginPasskeyLogin()
+	assert.EqualError(t, err, "no passkeys registered")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rpIdHash := sha256.Sum256([]byte("hub.example.com"))
+	clientDataJSON := func(clientDataType string, challenge string) string {
+		clientDataJSON, err := json.Marshal(map[string]string{"type": clientDataType, "challenge": challenge, "origin": "https://hub.example.com"})
+		require.NoError(t, err)
+		return webauthn.EncodeBase64(clientDataJSON)
+	}
+
+	registrationOptions, err := theAPI.BeginPasskeyRegistration()
+	require.NoError(t, err)
+	assert.Equal(t, "hub.example.com", registrationOptions.Rp.Id)
+
+	publicKey, err := cbor.Marshal(map[int]any{1: 2, 3: -7, -1: 1, -2: key.X.FillBytes(make([]byte, 32)), -3: key.Y.FillBytes(make([]byte, 32))})
+	require.NoError(t, err)
+	authData := append(rpIdHash[:], 0x45, 0, 0, 0, 0)
+	authData = append(authData, make([]byte, 16)...)
+	authData = append(authData, 0, 2, 0xca, 0xfe)
+	authData = append(authData, publicKey...)
+	attestationObject, err := cbor.Marshal(map[string]any{"fmt": "none", "attStmt": map[string]any{}, "authData": authData})
+	require.NoError(t, err)
+
+	registrationRequest := &FinishPasskeyRegistrationRequest{Name: "laptop"}
+	registrationRequest.Credential.Id = webauthn.EncodeBase64([]byte{0xca, 0xfe})
+	registrationRequest.Credential.Response.ClientDataJSON = clientDataJSON("webauthn.create", registrationOptions.Challenge)
+	registrationRequest.Credential.Response.AttestationObject = webauthn.EncodeBase64(attestationObject)
+	passkey, err := theAPI.FinishPasskeyRegistration(registrationRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "laptop", passkey.Name)
+
+	// challenges can only be used once
+	_, err = theAPI.FinishPasskeyRegistration(registrationRequest)
+	assert.EqualError(t, err, "unknown or expired challenge")
+
+	loginOptions, err := theAPI.BeginPasskeyLogin()
+	require.NoError(t, err)
+	require.Len(t, loginOptions.AllowCredentials, 1)
+	assert.Equal(t, registrationRequest.Credential.Id, loginOptions.AllowCredentials[0].Id)
+
+	login := func(challenge string, signCount uint32) (*Passkey, error) {
+		loginCredential := &PasskeyLoginCredential{Id: loginOptions.AllowCredentials[0].Id}
+		loginCredential.Response.ClientDataJSON = clientDataJSON("webauthn.get", challenge)
+		authData := binary.BigEndian.AppendUint32(append(rpIdHash[:], 0x05), signCount)
+		decodedClientDataJSON, err := webauthn.DecodeBase64(loginCredential.Response.ClientDataJSON)
+		require.NoError(t, err)
+		clientDataHash := sha256.Sum256(decodedClientDataJSON)
+		hash := sha256.Sum256(append(authData, clientDataHash[:]...))
+		signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		require.NoError(t, err)
+		loginCredential.Response.AuthenticatorData = webauthn.EncodeBase64(authData)
+		loginCredential.Response.Signature = webauthn.EncodeBase64(signature)
+		return theAPI.FinishPasskeyLogin(loginCredential)
+	}
+
+	_, err = login(registrationOptions.Challenge, 1)
+	assert.EqualError(t, err, "unknown or expired challenge")
+
+	passkey, err = login(loginOptions.Challenge, 1)
+	require.NoError(t, err)
+	assert.NotNil(t, passkey.LastUsedAt)
+
+	loginOptions, err = theAPI.BeginPasskeyLogin()
+	require.NoError(t, err)
+	_, err = login(loginOptions.Challenge, 1)
+	assert.EqualError(t, err, "signature counter did not increase, the authenticator might be cloned")
+
+	passkeys, err := theAPI.ListPasskeys()
+	require.NoError(t, err)
+	assert.Len(t, passkeys, 1)
+	require.NoError(t, theAPI.DeletePasskey(passkey.ID))
+	assert.EqualError(t, theAPI.DeletePasskey(passkey.ID), "passkey not found")
+}
@@ -0,0 +1,32 @@
+package api
+
+func (api *api) ListPaymentApprovals(state string) ([]PaymentApproval, error) {
+	dbPaymentApprovals, err := api.svc.GetTransactionsService().ListPaymentApprovals(state)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentApprovals := []PaymentApproval{}
+	for _, dbPaymentApproval := range dbPaymentApprovals {
+		paymentApproval := PaymentApproval{
+			ID:          dbPaymentApproval.ID,
+			AppId:       dbPaymentApproval.AppId,
+			PaymentHash: dbPaymentApproval.PaymentHash,
+			Destination: dbPaymentApproval.Destination,
+			Amount:      dbPaymentApproval.AmountMsat,
+			Description: dbPaymentApproval.Description,
+			State:       dbPaymentApproval.State,
+			ExpiresAt:   dbPaymentApproval.ExpiresAt,
+			CreatedAt:   dbPaymentApproval.CreatedAt,
+		}
+		if dbPaymentApproval.App != nil {
+			paymentApproval.AppName = dbPaymentApproval.App.Name
+		}
+		paymentApprovals = append(paymentApprovals, paymentApproval)
+	}
+	return paymentApprovals, nil
+}
+
+func (api *api) DecidePaymentApproval(id uint, approved bool) error {
+	return api.svc.GetTransactionsService().DecidePaymentApproval(id, approved)
+}
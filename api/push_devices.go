@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/notifications"
+)
+
+func (api *api) ListPushDevices() ([]PushDevice, error) {
+	var dbPushDevices []db.PushDevice
+	if err := api.db.Order("id").Find(&dbPushDevices).Error; err != nil {
+		return nil, err
+	}
+
+	pushDevices := []PushDevice{}
+	for _, dbPushDevice := range dbPushDevices {
+		pushDevices = append(pushDevices, *toApiPushDevice(&dbPushDevice))
+	}
+	return pushDevices, nil
+}
+
+// RegisterPushDevice adds a device, or renames it if the token is already registered
+func (api *api) RegisterPushDevice(registerPushDeviceRequest *RegisterPushDeviceRequest) (*PushDevice, error) {
+	name := strings.TrimSpace(registerPushDeviceRequest.Name)
+	if name == "" {
+		return nil, errors.New("a device name is required")
+	}
+	transport := registerPushDeviceRequest.Transport
+	token := strings.TrimSpace(registerPushDeviceRequest.Token)
+	if err := notifications.ValidatePushDevice(api.cfg.GetEnv(), transport, token); err != nil {
+		return nil, err
+	}
+
+	pushDevice := db.PushDevice{}
+	err := api.db.
+		Where(db.PushDevice{Transport: transport, Token: token}).
+		Assign(db.PushDevice{Name: name}).
+		FirstOrCreate(&pushDevice).Error
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"push_device_id": pushDevice.ID,
+		"transport":      transport,
+	}).Info("Registered push device")
+	return toApiPushDevice(&pushDevice), nil
+}
+
+func (api *api) RevokePushDevice(id uint) error {
+	result := api.db.Delete(&db.PushDevice{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("push device not found")
+	}
+	logger.Logger.WithField("push_device_id", id).Info("Revoked push device")
+	return nil
+}
+
+// SendTestPushNotification checks a device is reachable
+func (api *api) SendTestPushNotification(ctx context.Context, id uint) error {
+	pushDevice := db.PushDevice{}
+	if err := api.db.First(&pushDevice, id).Error; err != nil {
+		return err
+	}
+	return notifications.SendPushNotification(ctx, api.cfg.GetEnv(), &pushDevice, &notifications.PushMessage{
+		Event:    "nwc_test",
+		Category: notifications.PUSH_CATEGORY_SECURITY,
+		Title:    "Test notification",
+		Body:     "Push notifications of your hub are set up correctly.",
+	})
+}
+
+func toApiPushDevice(pushDevice *db.PushDevice) *PushDevice {
+	return &PushDevice{
+		ID:             pushDevice.ID,
+		Name:           pushDevice.Name,
+		Transport:      pushDevice.Transport,
+		LastNotifiedAt: pushDevice.LastNotifiedAt,
+		LastError:      pushDevice.LastError,
+		CreatedAt:      pushDevice.CreatedAt,
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/transactions"
 	"github.com/getAlby/hub/version"
 	decodepay "github.com/nbd-wtf/ln-decodepay"
 	"github.com/sirupsen/logrus"
@@ -125,7 +126,7 @@ func (api *api) RebalanceChannel(ctx context.Context, rebalanceChannelRequest *R
 		"order_id":        rebalanceCreateOrderResponse.OrderId,
 	}
 
-	payRebalanceInvoiceResponse, err := api.svc.GetTransactionsService().SendPaymentSync(rebalanceCreateOrderResponse.PayRequest, nil, payMetadata, api.svc.GetLNClient(), nil, nil)
+	payRebalanceInvoiceResponse, err := api.svc.GetTransactionsService().SendPaymentSync(ctx, rebalanceCreateOrderResponse.PayRequest, nil, payMetadata, api.svc.GetLNClient(), nil, nil)
 
 	if err != nil {
 		logger.Logger.WithError(err).Error("failed to pay rebalance invoice")
@@ -141,3 +142,34 @@ func (api *api) RebalanceChannel(ctx context.Context, rebalanceChannelRequest *R
 		TotalFeeSat: uint64(paymentRequest.MSatoshi)/1000 + payRebalanceInvoiceResponse.FeeMsat/1000 - rebalanceChannelRequest.AmountSat,
 	}, nil
 }
+
+func (api *api) RebalanceCircular(ctx context.Context, circularRebalanceRequest *CircularRebalanceRequest) (*CircularRebalanceResponse, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+
+	transaction, err := api.svc.GetTransactionsService().RebalanceCircular(ctx, &transactions.CircularRebalanceRequest{
+		OutgoingChannelId: circularRebalanceRequest.OutgoingChannelId,
+		IncomingChannelId: circularRebalanceRequest.IncomingChannelId,
+		AmountSat:         circularRebalanceRequest.AmountSat,
+		MaxFeeSat:         circularRebalanceRequest.MaxFeeSat,
+	}, api.svc.GetLNClient())
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"outgoing_channel_id": circularRebalanceRequest.OutgoingChannelId,
+			"incoming_channel_id": circularRebalanceRequest.IncomingChannelId,
+			"amount_sat":          circularRebalanceRequest.AmountSat,
+		}).Error("Failed to rebalance channels")
+		return nil, err
+	}
+
+	api.eventPublisher.Publish(&events.Event{
+		Event:      "nwc_rebalance_succeeded",
+		Properties: map[string]interface{}{},
+	})
+
+	return &CircularRebalanceResponse{
+		PaymentHash: transaction.PaymentHash,
+		TotalFeeSat: transaction.FeeMsat / 1000,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/getAlby/hub/backups"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// StartRecovery sets up the hub with the seed of a lost hub and then closes the channels of its
+// channel backup, so that their funds return to the on-chain wallet of the seed.
+// The node is started right away, GetRecovery shows the progress.
+func (api *api) StartRecovery(ctx context.Context, startRecoveryRequest *StartRecoveryRequest) (*Recovery, error) {
+	if api.cfg.SetupCompleted() {
+		return nil, errors.New("setup already completed")
+	}
+	if startRecoveryRequest.Mnemonic == "" {
+		return nil, errors.New("the mnemonic of the hub is required")
+	}
+	if startRecoveryRequest.UnlockPassword == "" {
+		return nil, errors.New("no unlock password provided")
+	}
+
+	channelBackup, err := parseRecoveryChannelBackup(startRecoveryRequest)
+	if err != nil {
+		return nil, err
+	}
+	recovery, err := api.recoverySvc.StartChannelRecovery(channelBackup)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := api.Setup(ctx, &startRecoveryRequest.SetupRequest); err != nil {
+		if deleteErr := api.recoverySvc.DeleteRecovery(); deleteErr != nil {
+			logger.Logger.WithError(deleteErr).Error("Failed to remove recovery after failed setup")
+		}
+		return nil, err
+	}
+
+	go api.Start(&StartRequest{UnlockPassword: startRecoveryRequest.UnlockPassword})
+
+	return toRecoveryResponse(recovery), nil
+}
+
+// parseRecoveryChannelBackup accepts the channel backup of the nostr backup or the encrypted
+// channel backup which is uploaded to the backup targets
+func parseRecoveryChannelBackup(startRecoveryRequest *StartRecoveryRequest) (*events.StaticChannelsBackupEvent, error) {
+	payload := []byte(startRecoveryRequest.ChannelBackup)
+	if startRecoveryRequest.EncryptedChannelBackup != "" {
+		encrypted, err := base64.StdEncoding.DecodeString(startRecoveryRequest.EncryptedChannelBackup)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted channel backup: %w", err)
+		}
+		decryptedReader, err := backups.DecryptingReader(bytes.NewReader(encrypted), startRecoveryRequest.UnlockPassword)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = io.ReadAll(decryptedReader); err != nil {
+			return nil, err
+		}
+	}
+	if len(payload) == 0 {
+		return nil, errors.New("a channel backup is required")
+	}
+
+	var channelBackup events.StaticChannelsBackupEvent
+	if err := json.Unmarshal(payload, &channelBackup); err != nil {
+		return nil, fmt.Errorf("invalid channel backup, was it encrypted with another password? %w", err)
+	}
+	return &channelBackup, nil
+}
+
+func (api *api) GetRecovery() (*Recovery, error) {
+	recovery, err := api.recoverySvc.GetRecovery()
+	if err != nil || recovery == nil {
+		return nil, err
+	}
+	return toRecoveryResponse(recovery), nil
+}
+
+func (api *api) DeleteRecovery() error {
+	return api.recoverySvc.DeleteRecovery()
+}
+
+func toRecoveryResponse(recovery *Recovery) *Recovery {
+	response := *recovery
+	response.LNDMultiChanBackup = nil
+	return &response
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) ListScheduledPayments() ([]ScheduledPayment, error) {
+	dbScheduledPayments, err := api.scheduledPaymentsSvc.ListScheduledPayments()
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledPayments := []ScheduledPayment{}
+	for _, dbScheduledPayment := range dbScheduledPayments {
+		scheduledPayments = append(scheduledPayments, *toApiScheduledPayment(&dbScheduledPayment))
+	}
+	return scheduledPayments, nil
+}
+
+func (api *api) CreateScheduledPayment(createScheduledPaymentRequest *CreateScheduledPaymentRequest) (*ScheduledPayment, error) {
+	scheduledPayment, err := api.scheduledPaymentsSvc.CreateScheduledPayment(
+		createScheduledPaymentRequest.AppId,
+		createScheduledPaymentRequest.Destination,
+		createScheduledPaymentRequest.Amount,
+		createScheduledPaymentRequest.Comment,
+		createScheduledPaymentRequest.Schedule,
+		createScheduledPaymentRequest.EndsAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return toApiScheduledPayment(scheduledPayment), nil
+}
+
+func (api *api) DeleteScheduledPayment(id uint) error {
+	return api.scheduledPaymentsSvc.DeleteScheduledPayment(id)
+}
+
+func toApiScheduledPayment(scheduledPayment *db.ScheduledPayment) *ScheduledPayment {
+	return &ScheduledPayment{
+		ID:          scheduledPayment.ID,
+		AppId:       scheduledPayment.AppId,
+		Destination: scheduledPayment.Destination,
+		Amount:      scheduledPayment.AmountMsat,
+		Comment:     scheduledPayment.Comment,
+		Schedule:    scheduledPayment.Schedule,
+		NextRunAt:   scheduledPayment.NextRunAt,
+		EndsAt:      scheduledPayment.EndsAt,
+		Enabled:     scheduledPayment.Enabled,
+		LastRunAt:   scheduledPayment.LastRunAt,
+		LastError:   scheduledPayment.LastError,
+		CreatedAt:   scheduledPayment.CreatedAt,
+	}
+}
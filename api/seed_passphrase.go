@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	seedPassphraseCheck     = "THIS STRING SHOULD MATCH IF SEED PASSPHRASE IS CORRECT"
+	minSeedPassphraseLength = 12
+)
+
+func (api *api) IsSeedPassphraseEnabled() bool {
+	value, err := api.cfg.Get(config.SeedPassphraseCheckKey, "")
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get seed passphrase config")
+		return false
+	}
+	return value != ""
+}
+
+func (api *api) checkSeedPassphrase(seedPassphrase string) bool {
+	value, err := api.cfg.Get(config.SeedPassphraseCheckKey, "")
+	if err != nil || value == "" || seedPassphrase == "" {
+		return false
+	}
+	decrypted, err := config.AesGcmDecryptWithPassword(value, seedPassphrase)
+	return err == nil && decrypted == seedPassphraseCheck
+}
+
+// SetSeedPassphrase protects revealing the recovery phrase with a passphrase which is separate
+// from the unlock password. An empty new passphrase removes it again.
+func (api *api) SetSeedPassphrase(setSeedPassphraseRequest *SetSeedPassphraseRequest) error {
+	if !api.cfg.CheckUnlockPassword(setSeedPassphraseRequest.UnlockPassword) {
+		return errors.New("wrong password")
+	}
+	if api.IsSeedPassphraseEnabled() && !api.checkSeedPassphrase(setSeedPassphraseRequest.CurrentSeedPassphrase) {
+		return errors.New("wrong seed passphrase")
+	}
+
+	newSeedPassphrase := setSeedPassphraseRequest.NewSeedPassphrase
+	if newSeedPassphrase == "" {
+		for _, key := range []string{config.SeedPassphraseCheckKey, config.MnemonicBackupKey} {
+			if err := api.cfg.SetUpdate(key, "", ""); err != nil {
+				return err
+			}
+		}
+		logger.Logger.Info("Removed seed passphrase")
+		return nil
+	}
+
+	if len(newSeedPassphrase) < minSeedPassphraseLength {
+		return fmt.Errorf("seed passphrase must be at least %d characters", minSeedPassphraseLength)
+	}
+	if newSeedPassphrase == setSeedPassphraseRequest.UnlockPassword {
+		return errors.New("seed passphrase must be different from the unlock password")
+	}
+
+	mnemonic, err := api.cfg.Get("Mnemonic", setSeedPassphraseRequest.UnlockPassword)
+	if err != nil {
+		return err
+	}
+	if mnemonic == "" {
+		return errors.New("this hub has no recovery phrase")
+	}
+
+	encryptedCheck, err := config.AesGcmEncryptWithPassword(seedPassphraseCheck, newSeedPassphrase)
+	if err != nil {
+		return err
+	}
+	encryptedMnemonic, err := config.AesGcmEncryptWithPassword(mnemonic, newSeedPassphrase)
+	if err != nil {
+		return err
+	}
+	if err := api.cfg.SetUpdate(config.MnemonicBackupKey, encryptedMnemonic, ""); err != nil {
+		return err
+	}
+	if err := api.cfg.SetUpdate(config.SeedPassphraseCheckKey, encryptedCheck, ""); err != nil {
+		return err
+	}
+
+	logger.Logger.Info("Set seed passphrase")
+	return nil
+}
+
+// getMnemonicWithSeedPassphrase decrypts the copy of the recovery phrase which is protected
+// by the seed passphrase, it does not need the unlock password
+func (api *api) getMnemonicWithSeedPassphrase(seedPassphrase string) (string, error) {
+	if !api.checkSeedPassphrase(seedPassphrase) {
+		return "", errors.New("wrong seed passphrase")
+	}
+	encryptedMnemonic, err := api.cfg.Get(config.MnemonicBackupKey, "")
+	if err != nil {
+		return "", err
+	}
+	return config.AesGcmDecryptWithPassword(encryptedMnemonic, seedPassphrase)
+}
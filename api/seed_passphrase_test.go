@@ -0,0 +1,54 @@
+package api
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSeedPassphrase(t *testing.T) {
+	mnemonic := "limit reward expect search tissue call visa fit thank cream brave jump"
+	svc, err := tests.CreateTestServiceWithMnemonic(t, mnemonic, "123")
+	require.NoError(t, err)
+	defer svc.Remove()
+	require.NoError(t, svc.Cfg.SaveUnlockPasswordCheck("123"))
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg}
+
+	assert.False(t, theAPI.IsSeedPassphraseEnabled())
+	mnemonicResponse, err := theAPI.GetMnemonic("123", "")
+	require.NoError(t, err)
+	assert.Equal(t, mnemonic, mnemonicResponse.Mnemonic)
+
+	assert.EqualError(t, theAPI.SetSeedPassphrase(&SetSeedPassphraseRequest{UnlockPassword: "wrong", NewSeedPassphrase: "correct horse battery"}), "wrong password")
+	assert.EqualError(t, theAPI.SetSeedPassphrase(&SetSeedPassphraseRequest{UnlockPassword: "123", NewSeedPassphrase: "short"}), "seed passphrase must be at least 12 characters")
+	require.NoError(t, theAPI.SetSeedPassphrase(&SetSeedPassphraseRequest{UnlockPassword: "123", NewSeedPassphrase: "correct horse battery"}))
+	assert.True(t, theAPI.IsSeedPassphraseEnabled())
+
+	// the unlock password alone no longer reveals the recovery phrase
+	_, err = theAPI.GetMnemonic("123", "")
+	assert.EqualError(t, err, "wrong seed passphrase")
+	mnemonicResponse, err = theAPI.GetMnemonic("123", "correct horse battery")
+	require.NoError(t, err)
+	assert.Equal(t, mnemonic, mnemonicResponse.Mnemonic)
+
+	// the migration backup contains the recovery phrase as well
+	assert.EqualError(t, theAPI.CreateBackup("123", "", io.Discard), "wrong seed passphrase")
+
+	// changing the unlock password keeps the seed passphrase
+	require.NoError(t, svc.Cfg.ChangeUnlockPassword("123", "456"))
+	mnemonicResponse, err = theAPI.GetMnemonic("456", "correct horse battery")
+	require.NoError(t, err)
+	assert.Equal(t, mnemonic, mnemonicResponse.Mnemonic)
+
+	assert.EqualError(t, theAPI.SetSeedPassphrase(&SetSeedPassphraseRequest{UnlockPassword: "456", CurrentSeedPassphrase: "wrong", NewSeedPassphrase: ""}), "wrong seed passphrase")
+	require.NoError(t, theAPI.SetSeedPassphrase(&SetSeedPassphraseRequest{UnlockPassword: "456", CurrentSeedPassphrase: "correct horse battery", NewSeedPassphrase: ""}))
+	assert.False(t, theAPI.IsSeedPassphraseEnabled())
+	mnemonicResponse, err = theAPI.GetMnemonic("456", "")
+	require.NoError(t, err)
+	assert.Equal(t, mnemonic, mnemonicResponse.Mnemonic)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// last used at is only used for display, so it is not updated on every request
+const sessionLastUsedInterval = time.Minute
+
+// CreateSession records a newly issued token so that it can be listed and revoked
+func (api *api) CreateSession(createSessionRequest *CreateSessionRequest) error {
+	if createSessionRequest.TokenId == "" {
+		return errors.New("no token id provided")
+	}
+
+	// expired sessions can no longer be used, so there is no reason to keep them
+	if err := api.db.Where("expires_at < ?", time.Now()).Delete(&db.Session{}).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to delete expired sessions")
+	}
+
+	now := time.Now()
+	session := db.Session{
+		TokenId:     createSessionRequest.TokenId,
+		Permission:  createSessionRequest.Permission,
+		AdminUserId: createSessionRequest.AdminUserId,
+		AppId:       createSessionRequest.AppId,
+		IpAddress:   createSessionRequest.IpAddress,
+		UserAgent:   createSessionRequest.UserAgent,
+		ExpiresAt:   createSessionRequest.ExpiresAt,
+		LastUsedAt:  &now,
+	}
+	return api.db.Create(&session).Error
+}
+
+// AuthenticateSession returns the session if it exists and is neither revoked nor expired
+func (api *api) AuthenticateSession(tokenId string) (*Session, error) {
+	var session db.Session
+	if tokenId == "" || api.db.Limit(1).Find(&session, &db.Session{TokenId: tokenId}).RowsAffected == 0 {
+		return nil, errors.New("session not found")
+	}
+	if session.RevokedAt != nil {
+		return nil, errors.New("session was revoked")
+	}
+	now := time.Now()
+	if session.ExpiresAt.Before(now) {
+		return nil, errors.New("session expired")
+	}
+
+	if session.LastUsedAt == nil || now.Sub(*session.LastUsedAt) > sessionLastUsedInterval {
+		if err := api.db.Model(&session).Update("last_used_at", now).Error; err != nil {
+			logger.Logger.WithError(err).WithField("session_id", session.ID).Error("Failed to update session last used at")
+		}
+	}
+	return toApiSession(&session, tokenId), nil
+}
+
+// ListSessions returns the sessions which can still be used, the most recent first
+func (api *api) ListSessions(currentTokenId string) ([]Session, error) {
+	var dbSessions []db.Session
+	if err := api.db.Where("revoked_at IS NULL AND expires_at > ?", time.Now()).Order("id DESC").Find(&dbSessions).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, *toApiSession(&dbSession, currentTokenId))
+	}
+	return sessions, nil
+}
+
+func (api *api) RevokeSession(id uint) error {
+	result := api.db.Model(&db.Session{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+	logger.Logger.WithField("session_id", id).Info("Revoked session")
+	return nil
+}
+
+// RevokeAllSessions revokes every session except the given one, so that the user stays logged in
+func (api *api) RevokeAllSessions(exceptTokenId string) (int64, error) {
+	result := api.db.Model(&db.Session{}).Where("revoked_at IS NULL AND token_id != ?", exceptTokenId).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"revoked": result.RowsAffected,
+	}).Info("Revoked all other sessions")
+	return result.RowsAffected, nil
+}
+
+func toApiSession(session *db.Session, currentTokenId string) *Session {
+	return &Session{
+		ID:          session.ID,
+		Permission:  session.Permission,
+		AdminUserId: session.AdminUserId,
+		AppId:       session.AppId,
+		IpAddress:   session.IpAddress,
+		UserAgent:   session.UserAgent,
+		ExpiresAt:   session.ExpiresAt,
+		LastUsedAt:  session.LastUsedAt,
+		CreatedAt:   session.CreatedAt,
+		Current:     currentTokenId != "" && session.TokenId == currentTokenId,
+	}
+}
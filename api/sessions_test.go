@@ -0,0 +1,73 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSessions(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg}
+
+	for _, tokenId := range []string{"laptop", "phone", "tablet"} {
+		require.NoError(t, theAPI.CreateSession(&CreateSessionRequest{
+			TokenId:    tokenId,
+			Permission: "full",
+			IpAddress:  "192.0.2.1",
+			UserAgent:  "Mozilla/5.0",
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}))
+	}
+
+	session, err := theAPI.AuthenticateSession("laptop")
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", session.IpAddress)
+	assert.True(t, session.Current)
+
+	_, err = theAPI.AuthenticateSession("desktop")
+	assert.EqualError(t, err, "session not found")
+
+	sessions, err := theAPI.ListSessions("laptop")
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[2].Current)
+
+	phone, err := theAPI.AuthenticateSession("phone")
+	require.NoError(t, err)
+	require.NoError(t, theAPI.RevokeSession(phone.ID))
+	_, err = theAPI.AuthenticateSession("phone")
+	assert.EqualError(t, err, "session was revoked")
+	assert.EqualError(t, theAPI.RevokeSession(phone.ID), "session not found")
+
+	require.NoError(t, svc.DB.Model(&db.Session{}).Where("token_id = ?", "tablet").Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = theAPI.AuthenticateSession("tablet")
+	assert.EqualError(t, err, "session expired")
+
+	sessions, err = theAPI.ListSessions("laptop")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	// expired sessions are removed once a new one is created
+	require.NoError(t, theAPI.CreateSession(&CreateSessionRequest{TokenId: "desktop", Permission: "readonly", ExpiresAt: time.Now().Add(time.Hour)}))
+	var count int64
+	require.NoError(t, svc.DB.Model(&db.Session{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+
+	revoked, err := theAPI.RevokeAllSessions("laptop")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), revoked)
+	_, err = theAPI.AuthenticateSession("laptop")
+	assert.NoError(t, err)
+	_, err = theAPI.AuthenticateSession("desktop")
+	assert.EqualError(t, err, "session was revoked")
+}
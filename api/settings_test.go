@@ -0,0 +1,90 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestUpdateSettings_Relays(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg, eventPublisher: svc.EventPublisher}
+
+	envRelays := svc.Cfg.GetRelayUrls()
+
+	err = theAPI.UpdateSettings(&UpdateSettingsRequest{Relays: &[]string{"wss://relay.example.com", " wss://nos.example.org/ "}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wss://relay.example.com", "wss://nos.example.org/"}, svc.Cfg.GetRelayUrls())
+
+	consumedEvents := mockEventConsumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 1)
+	assert.Equal(t, "nwc_relays_updated", consumedEvents[0].Event)
+
+	err = theAPI.UpdateSettings(&UpdateSettingsRequest{Relays: &[]string{"https://relay.example.com"}})
+	assert.EqualError(t, err, `invalid relay url: "https://relay.example.com"`)
+
+	// an empty list restores the relays from the environment
+	err = theAPI.UpdateSettings(&UpdateSettingsRequest{Relays: &[]string{}})
+	require.NoError(t, err)
+	assert.Equal(t, envRelays, svc.Cfg.GetRelayUrls())
+}
+
+func TestUpdateSettings_LogLevel(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	previousLevel := logger.Logger.GetLevel()
+	defer logger.SetLevel(previousLevel)
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg}
+
+	level := uint(logrus.WarnLevel)
+	err = theAPI.UpdateSettings(&UpdateSettingsRequest{LogLevel: &level})
+	require.NoError(t, err)
+	assert.Equal(t, logrus.WarnLevel, logger.Logger.GetLevel())
+	storedLevel, err := svc.Cfg.Get(config.LogLevelKey, "")
+	require.NoError(t, err)
+	assert.Equal(t, "3", storedLevel)
+
+	level = 7
+	err = theAPI.UpdateSettings(&UpdateSettingsRequest{LogLevel: &level})
+	assert.EqualError(t, err, "log level must be between 0 and 6")
+}
+
+func TestUpdateSettings_RateLimits(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg}
+	svc.Cfg.GetEnv().RateLimitIpPerMinute = 60
+
+	assert.Equal(t, uint(60), config.GetUintSetting(svc.Cfg, config.RateLimitIpPerMinuteKey, svc.Cfg.GetEnv().RateLimitIpPerMinute))
+
+	// 0 removes the limit of the environment
+	noLimit := uint(0)
+	err = theAPI.UpdateSettings(&UpdateSettingsRequest{RateLimitIpPerMinute: &noLimit})
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), config.GetUintSetting(svc.Cfg, config.RateLimitIpPerMinuteKey, svc.Cfg.GetEnv().RateLimitIpPerMinute))
+
+	bannedIps := "192.0.2.1, 10.0.0.0/8"
+	err = theAPI.UpdateSettings(&UpdateSettingsRequest{BannedIps: &bannedIps})
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1,10.0.0.0/8", config.GetStringSetting(svc.Cfg, config.BannedIpsKey, ""))
+
+	bannedIps = "not-an-ip"
+	err = theAPI.UpdateSettings(&UpdateSettingsRequest{BannedIps: &bannedIps})
+	assert.Error(t, err)
+}
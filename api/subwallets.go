@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) ListSubwalletAddresses(appId uint) ([]SubwalletAddress, error) {
+	dbAddresses, err := api.subwalletsSvc.ListDepositAddresses(appId)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []SubwalletAddress{}
+	for _, dbAddress := range dbAddresses {
+		addresses = append(addresses, *toApiSubwalletAddress(&dbAddress))
+	}
+	return addresses, nil
+}
+
+func (api *api) CreateSubwalletAddress(ctx context.Context, appId uint) (*SubwalletAddress, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	address, err := api.subwalletsSvc.CreateDepositAddress(ctx, appId, api.svc.GetLNClient())
+	if err != nil {
+		return nil, err
+	}
+	return toApiSubwalletAddress(address), nil
+}
+
+func (api *api) SetSubwalletOwnerPassword(appId uint, password string) error {
+	return api.subwalletsSvc.SetOwnerPassword(appId, password)
+}
+
+func (api *api) CheckSubwalletOwnerPassword(appId uint, password string) bool {
+	return api.subwalletsSvc.CheckOwnerPassword(appId, password)
+}
+
+func (api *api) CreateSubwalletInvoice(ctx context.Context, appId uint, amount uint64, description string) (*MakeInvoiceResponse, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	transaction, err := api.svc.GetTransactionsService().MakeInvoice(ctx, amount, description, "", 0, nil, api.svc.GetLNClient(), &appId, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ToApiTransaction(transaction), nil
+}
+
+func (api *api) SendSubwalletPayment(ctx context.Context, appId uint, invoice string, amountMsat *uint64, idempotencyKey string) (*SendPaymentResponse, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	transaction, err := api.svc.GetTransactionsService().SendPaymentSyncWithIdempotencyKey(ctx, invoice, amountMsat, nil, api.svc.GetLNClient(), &appId, nil, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	return ToApiTransaction(transaction), nil
+}
+
+func toApiSubwalletAddress(address *db.SubwalletAddress) *SubwalletAddress {
+	return &SubwalletAddress{
+		ID:          address.ID,
+		AppId:       address.AppId,
+		Address:     address.Address,
+		ReceivedSat: address.ReceivedSat,
+		CreatedAt:   address.CreatedAt,
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) ListSwapRules() ([]SwapRule, error) {
+	dbSwapRules, err := api.swapRulesSvc.ListSwapRules()
+	if err != nil {
+		return nil, err
+	}
+
+	swapRules := []SwapRule{}
+	for _, dbSwapRule := range dbSwapRules {
+		swapRules = append(swapRules, *toApiSwapRule(&dbSwapRule))
+	}
+	return swapRules, nil
+}
+
+func (api *api) CreateSwapRule(createSwapRuleRequest *CreateSwapRuleRequest) (*SwapRule, error) {
+	swapRule, err := api.swapRulesSvc.CreateSwapRule(
+		createSwapRuleRequest.Name,
+		createSwapRuleRequest.Type,
+		createSwapRuleRequest.Threshold,
+		createSwapRuleRequest.Destination,
+		createSwapRuleRequest.MinAmount,
+		createSwapRuleRequest.DryRun,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return toApiSwapRule(swapRule), nil
+}
+
+func (api *api) UpdateSwapRule(id uint, updateSwapRuleRequest *UpdateSwapRuleRequest) (*SwapRule, error) {
+	swapRule, err := api.swapRulesSvc.UpdateSwapRule(id, updateSwapRuleRequest.Enabled, updateSwapRuleRequest.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	return toApiSwapRule(swapRule), nil
+}
+
+func (api *api) DeleteSwapRule(id uint) error {
+	return api.swapRulesSvc.DeleteSwapRule(id)
+}
+
+func (api *api) ListSwapRuleRuns(id uint, limit uint64) ([]SwapRuleRun, error) {
+	dbSwapRuleRuns, err := api.swapRulesSvc.ListSwapRuleRuns(id, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	swapRuleRuns := []SwapRuleRun{}
+	for _, dbSwapRuleRun := range dbSwapRuleRuns {
+		swapRuleRuns = append(swapRuleRuns, SwapRuleRun{
+			ID:        dbSwapRuleRun.ID,
+			Amount:    dbSwapRuleRun.AmountSat,
+			DryRun:    dbSwapRuleRun.DryRun,
+			SwapId:    dbSwapRuleRun.SwapId,
+			Error:     dbSwapRuleRun.Error,
+			CreatedAt: dbSwapRuleRun.CreatedAt,
+		})
+	}
+	return swapRuleRuns, nil
+}
+
+func toApiSwapRule(swapRule *db.SwapRule) *SwapRule {
+	return &SwapRule{
+		ID:          swapRule.ID,
+		Name:        swapRule.Name,
+		Type:        swapRule.Type,
+		Threshold:   swapRule.ThresholdSat,
+		Destination: swapRule.Destination,
+		MinAmount:   swapRule.MinAmountSat,
+		DryRun:      swapRule.DryRun,
+		Enabled:     swapRule.Enabled,
+		LastRunAt:   swapRule.LastRunAt,
+		CreatedAt:   swapRule.CreatedAt,
+	}
+}
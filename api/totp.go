@@ -0,0 +1,221 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/totp"
+)
+
+// the secret and backup codes are encrypted with the unlock password, so that they are
+// re-encrypted when the password is changed
+const (
+	totpEnabledKey       = "TotpEnabled"
+	totpSecretKey        = "TotpSecret"
+	totpPendingSecretKey = "TotpPendingSecret"
+	totpBackupCodesKey   = "TotpBackupCodes"
+	totpLastStepKey      = "TotpLastStep"
+)
+
+const (
+	totpIssuer          = "Alby Hub"
+	totpBackupCodeCount = 10
+)
+
+// verifying a code and recording that it was used must not interleave
+var totpMutex sync.Mutex
+
+func (api *api) IsTotpEnabled() bool {
+	enabled, err := api.cfg.Get(totpEnabledKey, "")
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get two-factor authentication config")
+		return false
+	}
+	return enabled == "true"
+}
+
+// SetupTotp generates a new secret which only takes effect once a code of it is confirmed with EnableTotp
+func (api *api) SetupTotp(unlockPassword string) (*SetupTotpResponse, error) {
+	if !api.cfg.CheckUnlockPassword(unlockPassword) {
+		return nil, errors.New("wrong password")
+	}
+	if api.IsTotpEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := api.cfg.SetUpdate(totpPendingSecretKey, secret, unlockPassword); err != nil {
+		return nil, err
+	}
+
+	return &SetupTotpResponse{
+		Secret: secret,
+		Uri:    totp.ProvisioningUri(secret, totpIssuer, "hub"),
+	}, nil
+}
+
+func (api *api) EnableTotp(enableTotpRequest *EnableTotpRequest) (*EnableTotpResponse, error) {
+	if !api.cfg.CheckUnlockPassword(enableTotpRequest.UnlockPassword) {
+		return nil, errors.New("wrong password")
+	}
+
+	totpMutex.Lock()
+	defer totpMutex.Unlock()
+
+	if api.IsTotpEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	secret, err := api.cfg.Get(totpPendingSecretKey, enableTotpRequest.UnlockPassword)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, errors.New("two-factor authentication was not set up")
+	}
+	step, ok := totp.Validate(secret, enableTotpRequest.Code, time.Now())
+	if !ok {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	backupCodes := make([]string, 0, totpBackupCodeCount)
+	for range totpBackupCodeCount {
+		backupCode, err := generateBackupCode()
+		if err != nil {
+			return nil, err
+		}
+		backupCodes = append(backupCodes, backupCode)
+	}
+	if err := api.saveTotpBackupCodes(backupCodes, enableTotpRequest.UnlockPassword); err != nil {
+		return nil, err
+	}
+	if err := api.cfg.SetUpdate(totpSecretKey, secret, enableTotpRequest.UnlockPassword); err != nil {
+		return nil, err
+	}
+	if err := api.cfg.SetUpdate(totpPendingSecretKey, "", ""); err != nil {
+		return nil, err
+	}
+	if err := api.cfg.SetUpdate(totpLastStepKey, strconv.FormatInt(step, 10), ""); err != nil {
+		return nil, err
+	}
+	if err := api.cfg.SetUpdate(totpEnabledKey, "true", ""); err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Info("Enabled two-factor authentication")
+	return &EnableTotpResponse{
+		BackupCodes: backupCodes,
+	}, nil
+}
+
+func (api *api) DisableTotp(disableTotpRequest *DisableTotpRequest) error {
+	if err := api.VerifyTotp(disableTotpRequest.UnlockPassword, disableTotpRequest.Code); err != nil {
+		return err
+	}
+	if !api.IsTotpEnabled() {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	for _, key := range []string{totpEnabledKey, totpSecretKey, totpBackupCodesKey, totpLastStepKey} {
+		if err := api.cfg.SetUpdate(key, "", ""); err != nil {
+			return err
+		}
+	}
+
+	logger.Logger.Info("Disabled two-factor authentication")
+	return nil
+}
+
+// VerifyTotp checks the code of the authenticator app, or one of the backup codes which
+// is used up. It always succeeds if two-factor authentication is not enabled.
+func (api *api) VerifyTotp(unlockPassword string, code string) error {
+	if !api.IsTotpEnabled() {
+		return nil
+	}
+	if code == "" {
+		return errors.New("two-factor code required")
+	}
+	if !api.cfg.CheckUnlockPassword(unlockPassword) {
+		return errors.New("wrong password")
+	}
+
+	totpMutex.Lock()
+	defer totpMutex.Unlock()
+
+	secret, err := api.cfg.Get(totpSecretKey, unlockPassword)
+	if err != nil {
+		return err
+	}
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		// every code can only be used once, otherwise an observed code could be replayed
+		lastStep, _ := api.cfg.Get(totpLastStepKey, "")
+		if lastStep != "" {
+			if parsedLastStep, err := strconv.ParseInt(lastStep, 10, 64); err == nil && step <= parsedLastStep {
+				return errors.New("two-factor code was already used")
+			}
+		}
+		return api.cfg.SetUpdate(totpLastStepKey, strconv.FormatInt(step, 10), "")
+	}
+
+	backupCodes, err := api.getTotpBackupCodes(unlockPassword)
+	if err != nil {
+		return err
+	}
+	normalizedCode := strings.ToLower(strings.TrimSpace(code))
+	index := slices.IndexFunc(backupCodes, func(backupCode string) bool {
+		return subtle.ConstantTimeCompare([]byte(backupCode), []byte(normalizedCode)) == 1
+	})
+	if index < 0 {
+		return errors.New("invalid two-factor code")
+	}
+	backupCodes = slices.Delete(backupCodes, index, index+1)
+	if err := api.saveTotpBackupCodes(backupCodes, unlockPassword); err != nil {
+		return err
+	}
+	logger.Logger.WithField("remaining", len(backupCodes)).Warn("Used two-factor backup code")
+	return nil
+}
+
+func (api *api) getTotpBackupCodes(unlockPassword string) ([]string, error) {
+	value, err := api.cfg.Get(totpBackupCodesKey, unlockPassword)
+	if err != nil {
+		return nil, err
+	}
+	backupCodes := []string{}
+	if value == "" {
+		return backupCodes, nil
+	}
+	if err := json.Unmarshal([]byte(value), &backupCodes); err != nil {
+		return nil, err
+	}
+	return backupCodes, nil
+}
+
+func (api *api) saveTotpBackupCodes(backupCodes []string, unlockPassword string) error {
+	value, err := json.Marshal(backupCodes)
+	if err != nil {
+		return err
+	}
+	return api.cfg.SetUpdate(totpBackupCodesKey, string(value), unlockPassword)
+}
+
+// generateBackupCode returns a code like 3f9a1-c07b2
+func generateBackupCode() (string, error) {
+	codeBytes := make([]byte, 5)
+	if _, err := rand.Read(codeBytes); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(codeBytes)
+	return code[:5] + "-" + code[5:], nil
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/totp"
+)
+
+func TestTotp(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+	require.NoError(t, svc.Cfg.SaveUnlockPasswordCheck("123"))
+
+	theAPI := &api{db: svc.DB, cfg: svc.Cfg}
+
+	assert.False(t, theAPI.IsTotpEnabled())
+	assert.NoError(t, theAPI.VerifyTotp("123", ""))
+
+	_, err = theAPI.SetupTotp("wrong")
+	assert.EqualError(t, err, "wrong password")
+	setupTotpResponse, err := theAPI.SetupTotp("123")
+	require.NoError(t, err)
+	assert.Contains(t, setupTotpResponse.Uri, setupTotpResponse.Secret)
+
+	_, err = theAPI.EnableTotp(&EnableTotpRequest{UnlockPassword: "123", Code: "000000"})
+	assert.EqualError(t, err, "invalid two-factor code")
+
+	now := time.Now()
+	code, err := totp.Code(setupTotpResponse.Secret, totp.Step(now))
+	require.NoError(t, err)
+	enableTotpResponse, err := theAPI.EnableTotp(&EnableTotpRequest{UnlockPassword: "123", Code: code})
+	require.NoError(t, err)
+	assert.Len(t, enableTotpResponse.BackupCodes, 10)
+	assert.True(t, theAPI.IsTotpEnabled())
+
+	// the secret and backup codes are not stored in plain text
+	secret, err := svc.Cfg.Get(totpSecretKey, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, setupTotpResponse.Secret, secret)
+	backupCodes, err := svc.Cfg.Get(totpBackupCodesKey, "")
+	require.NoError(t, err)
+	assert.NotContains(t, backupCodes, enableTotpResponse.BackupCodes[0])
+
+	assert.EqualError(t, theAPI.VerifyTotp("123", ""), "two-factor code required")
+	assert.EqualError(t, theAPI.VerifyTotp("wrong", code), "wrong password")
+	assert.EqualError(t, theAPI.VerifyTotp("123", code), "two-factor code was already used")
+
+	nextCode, err := totp.Code(setupTotpResponse.Secret, totp.Step(now)+1)
+	require.NoError(t, err)
+	assert.NoError(t, theAPI.VerifyTotp("123", nextCode))
+
+	assert.NoError(t, theAPI.VerifyTotp("123", enableTotpResponse.BackupCodes[0]))
+	assert.EqualError(t, theAPI.VerifyTotp("123", enableTotpResponse.BackupCodes[0]), "invalid two-factor code")
+
+	// the secret keeps working after the unlock password was changed
+	require.NoError(t, svc.Cfg.ChangeUnlockPassword("123", "456"))
+	assert.NoError(t, theAPI.VerifyTotp("456", enableTotpResponse.BackupCodes[1]))
+
+	require.NoError(t, theAPI.DisableTotp(&DisableTotpRequest{UnlockPassword: "456", Code: enableTotpResponse.BackupCodes[2]}))
+	assert.False(t, theAPI.IsTotpEnabled())
+	assert.NoError(t, theAPI.VerifyTotp("456", ""))
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/transactions"
 	"github.com/sirupsen/logrus"
@@ -20,7 +26,7 @@ func (api *api) CreateInvoice(ctx context.Context, amount uint64, description st
 	if err != nil {
 		return nil, err
 	}
-	return toApiTransaction(transaction), nil
+	return ToApiTransaction(transaction), nil
 }
 
 func (api *api) LookupInvoice(ctx context.Context, paymentHash string) (*LookupInvoiceResponse, error) {
@@ -31,10 +37,16 @@ func (api *api) LookupInvoice(ctx context.Context, paymentHash string) (*LookupI
 	if err != nil {
 		return nil, err
 	}
-	return toApiTransaction(transaction), nil
+	apiTransaction := ToApiTransaction(transaction)
+	refundedAmounts, err := api.svc.GetTransactionsService().GetRefundedAmounts([]uint{transaction.ID})
+	if err != nil {
+		return nil, err
+	}
+	apiTransaction.RefundedAmount = refundedAmounts[transaction.ID]
+	return apiTransaction, nil
 }
 
-func (api *api) ListTransactions(ctx context.Context, appId *uint, limit uint64, offset uint64) (*ListTransactionsResponse, error) {
+func (api *api) ListTransactions(ctx context.Context, appId *uint, limit uint64, offset uint64, currencies []string) (*ListTransactionsResponse, error) {
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
 	}
@@ -49,29 +61,168 @@ func (api *api) ListTransactions(ctx context.Context, appId *uint, limit uint64,
 		return nil, err
 	}
 
+	transactionIds := []uint{}
+	incomingTransactionIds := []uint{}
+	for _, transaction := range transactions {
+		transactionIds = append(transactionIds, transaction.ID)
+		if transaction.Type == constants.TRANSACTION_TYPE_INCOMING {
+			incomingTransactionIds = append(incomingTransactionIds, transaction.ID)
+		}
+	}
+	refundedAmounts, err := api.svc.GetTransactionsService().GetRefundedAmounts(incomingTransactionIds)
+	if err != nil {
+		return nil, err
+	}
+	fiatRates, err := api.svc.GetTransactionsService().GetFiatRates(transactionIds, currencies)
+	if err != nil {
+		return nil, err
+	}
+
 	apiTransactions := []Transaction{}
 	for _, transaction := range transactions {
-		apiTransactions = append(apiTransactions, *toApiTransaction(&transaction))
+		apiTransaction := ToApiTransaction(&transaction)
+		apiTransaction.RefundedAmount = refundedAmounts[transaction.ID]
+		apiTransaction.FiatValues = toApiFiatValues(transaction.AmountMsat, fiatRates[transaction.ID])
+		apiTransactions = append(apiTransactions, *apiTransaction)
 	}
 
-	return &ListTransactionsResponse{
+	response := &ListTransactionsResponse{
 		Transactions: apiTransactions,
 		TotalCount:   totalCount,
+	}
+	if tapdClient := api.getTapdClient(); tapdClient != nil && appId == nil && offset == 0 {
+		assetTransfers, err := tapdClient.ListTransfers(ctx, int(limit))
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to list Taproot Asset transfers")
+		} else {
+			response.AssetTransfers = assetTransfers
+		}
+	}
+	return response, nil
+}
+
+func (api *api) ExportTransactions(ctx context.Context, format string, w io.Writer) error {
+	if api.svc.GetLNClient() == nil {
+		return errors.New("LNClient not started")
+	}
+
+	transactionList, _, err := api.svc.GetTransactionsService().ListTransactions(ctx, 0, 0, 0, 0, false, false, nil, api.svc.GetLNClient(), nil, false)
+	if err != nil {
+		return err
+	}
+
+	// fiat values are based on the rates recorded when each transaction settled
+	currency := strings.ToUpper(api.cfg.GetCurrency())
+	transactionIds := make([]uint, 0, len(transactionList))
+	for _, transaction := range transactionList {
+		transactionIds = append(transactionIds, transaction.ID)
+	}
+	fiatRates, err := api.svc.GetTransactionsService().GetFiatRates(transactionIds, []string{currency})
+	if err != nil {
+		return err
+	}
+
+	exportOptions := &transactions.ExportOptions{
+		Format:       format,
+		FiatCurrency: currency,
+		FiatRates:    map[uint]float64{},
+	}
+	for transactionId, transactionFiatRates := range fiatRates {
+		for _, fiatRate := range transactionFiatRates {
+			exportOptions.FiatRates[transactionId] = fiatRate.Rate
+		}
+	}
+
+	return transactions.ExportTransactions(w, transactionList, exportOptions)
+}
+
+func (api *api) GetTransactionReceipt(ctx context.Context, paymentHash string, transactionType string) (*TransactionReceipt, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+
+	var transactionTypeFilter *string
+	if transactionType != "" {
+		if transactionType != constants.TRANSACTION_TYPE_INCOMING && transactionType != constants.TRANSACTION_TYPE_OUTGOING {
+			return nil, fmt.Errorf("invalid transaction type. Must be one of %s,%s", constants.TRANSACTION_TYPE_INCOMING, constants.TRANSACTION_TYPE_OUTGOING)
+		}
+		transactionTypeFilter = &transactionType
+	}
+
+	receipt, err := api.svc.GetTransactionsService().CreateReceipt(ctx, paymentHash, transactionTypeFilter, api.svc.GetLNClient(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionReceipt{
+		Version:     receipt.Version,
+		Type:        receipt.Type,
+		PaymentHash: receipt.PaymentHash,
+		Preimage:    receipt.Preimage,
+		Amount:      receipt.AmountMsat,
+		Description: receipt.Description,
+		SettledAt:   receipt.SettledAt,
+		NodePubkey:  receipt.NodePubkey,
+		Message:     receipt.Message,
+		Signature:   receipt.Signature,
 	}, nil
 }
 
-func (api *api) SendPayment(ctx context.Context, invoice string, amountMsat *uint64, metadata map[string]interface{}) (*SendPaymentResponse, error) {
+func (api *api) ArchiveTransactions(ctx context.Context, archiveRequest *ArchiveTransactionsRequest, w io.Writer) error {
+	if !api.cfg.CheckUnlockPassword(archiveRequest.UnlockPassword) {
+		return errors.New("invalid password")
+	}
+
+	retentionMonths := archiveRequest.RetentionMonths
+	if retentionMonths == 0 {
+		configuredRetentionMonths, _ := api.cfg.Get(config.TransactionRetentionMonthsKey, "")
+		parsedRetentionMonths, _ := strconv.ParseUint(configuredRetentionMonths, 10, 32)
+		retentionMonths = uint(parsedRetentionMonths)
+	}
+	if retentionMonths == 0 {
+		return errors.New("no transaction retention period configured")
+	}
+
+	result, err := api.svc.GetTransactionsService().ArchiveTransactions(retentionMonths, archiveRequest.UnlockPassword, w)
+	if err != nil {
+		return err
+	}
+	if result.ArchivedCount == 0 {
+		return fmt.Errorf("no transactions older than %d months to archive", retentionMonths)
+	}
+	return nil
+}
+
+func (api *api) RefundTransaction(ctx context.Context, paymentHash string, refundRequest *RefundTransactionRequest) (*Transaction, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+
+	transactionType := constants.TRANSACTION_TYPE_INCOMING
+	originalTransaction, err := api.svc.GetTransactionsService().LookupTransaction(ctx, paymentHash, &transactionType, api.svc.GetLNClient(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := api.svc.GetTransactionsService().RefundTransaction(ctx, originalTransaction.ID, refundRequest.Invoice, refundRequest.Amount, api.svc.GetLNClient())
+	if err != nil {
+		return nil, err
+	}
+	return ToApiTransaction(transaction), nil
+}
+
+func (api *api) SendPayment(ctx context.Context, invoice string, amountMsat *uint64, metadata map[string]interface{}, idempotencyKey string) (*SendPaymentResponse, error) {
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
 	}
-	transaction, err := api.svc.GetTransactionsService().SendPaymentSync(invoice, amountMsat, metadata, api.svc.GetLNClient(), nil, nil)
+	transaction, err := api.svc.GetTransactionsService().SendPaymentSyncWithIdempotencyKey(ctx, invoice, amountMsat, metadata, api.svc.GetLNClient(), nil, nil, idempotencyKey)
 	if err != nil {
 		return nil, err
 	}
-	return toApiTransaction(transaction), nil
+	return ToApiTransaction(transaction), nil
 }
 
-func toApiTransaction(transaction *transactions.Transaction) *Transaction {
+func ToApiTransaction(transaction *transactions.Transaction) *Transaction {
 
 	updatedAt := transaction.UpdatedAt.Format(time.RFC3339)
 	createdAt := transaction.CreatedAt.Format(time.RFC3339)
@@ -83,6 +234,17 @@ func toApiTransaction(transaction *transactions.Transaction) *Transaction {
 		preimage = transaction.Preimage
 	}
 
+	var acceptedAt *string
+	if transaction.AcceptedAt != nil {
+		acceptedAtValue := transaction.AcceptedAt.Format(time.RFC3339)
+		acceptedAt = &acceptedAtValue
+	}
+	var canceledAt *string
+	if transaction.CanceledAt != nil {
+		canceledAtValue := transaction.CanceledAt.Format(time.RFC3339)
+		canceledAt = &canceledAtValue
+	}
+
 	var metadata Metadata
 	if transaction.Metadata != nil {
 		jsonErr := json.Unmarshal(transaction.Metadata, &metadata)
@@ -124,34 +286,67 @@ func toApiTransaction(transaction *transactions.Transaction) *Transaction {
 		Metadata:        metadata,
 		Boostagram:      boostagram,
 		FailureReason:   transaction.FailureReason,
+		HoldState:       transactions.GetHoldInvoiceState(transaction),
+		AcceptedAt:      acceptedAt,
+		CanceledAt:      canceledAt,
 	}
 }
 
-func (api *api) Transfer(ctx context.Context, fromAppId *uint, toAppId *uint, amountMsat uint64) error {
+// Transfer moves funds between isolated apps and the main balance as a self payment, which is instant and fee-free
+func (api *api) Transfer(ctx context.Context, fromAppId *uint, toAppId *uint, amountMsat uint64) (*TransferResponse, error) {
 	if api.svc.GetLNClient() == nil {
-		return errors.New("LNClient not started")
+		return nil, errors.New("LNClient not started")
+	}
+
+	if amountMsat == 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+
+	if (fromAppId == nil && toAppId == nil) || (fromAppId != nil && toAppId != nil && *fromAppId == *toAppId) {
+		return nil, errors.New("cannot transfer to the same balance")
 	}
 
 	for _, appId := range []*uint{fromAppId, toAppId} {
 		if appId != nil {
 			dbApp := api.appsSvc.GetAppById(*appId)
 			if dbApp == nil {
-				return errors.New("app does not exist")
+				return nil, errors.New("app does not exist")
 			}
 			if !dbApp.Isolated {
-				return errors.New("app is not isolated")
+				return nil, errors.New("app is not isolated")
 			}
 		}
 	}
 
-	transaction, err := api.svc.GetTransactionsService().MakeInvoice(ctx, amountMsat, "transfer", "", 0, nil, api.svc.GetLNClient(), toAppId, nil, nil)
+	// the main balance is recorded as a null app id
+	metadata := map[string]interface{}{
+		"transfer": map[string]interface{}{
+			"from_app_id": fromAppId,
+			"to_app_id":   toAppId,
+		},
+	}
 
+	transactionsSvc := api.svc.GetTransactionsService()
+	invoice, err := transactionsSvc.MakeInvoice(ctx, amountMsat, "transfer", "", 0, metadata, api.svc.GetLNClient(), toAppId, nil, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	sent, err := transactionsSvc.SendPaymentSync(ctx, invoice.PaymentRequest, nil, metadata, api.svc.GetLNClient(), fromAppId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	incomingType := constants.TRANSACTION_TYPE_INCOMING
+	received, err := transactionsSvc.LookupTransaction(ctx, invoice.PaymentHash, &incomingType, api.svc.GetLNClient(), toAppId)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = api.svc.GetTransactionsService().SendPaymentSync(transaction.PaymentRequest, nil, nil, api.svc.GetLNClient(), fromAppId, nil)
-	return err
+	return &TransferResponse{
+		Sent:     ToApiTransaction(sent),
+		Received: ToApiTransaction(received),
+	}, nil
 }
 
 func toApiBoostagram(boostagram *transactions.Boostagram) *Boostagram {
@@ -170,5 +365,127 @@ func toApiBoostagram(boostagram *transactions.Boostagram) *Boostagram {
 		Time:           boostagram.Time,
 		Action:         boostagram.Action,
 		ValueMsatTotal: boostagram.ValueMsatTotal,
+		Guid:           boostagram.Guid,
+		EpisodeGuid:    boostagram.EpisodeGuid,
+		RemoteFeedGuid: boostagram.RemoteFeedGuid,
+		RemoteItemGuid: boostagram.RemoteItemGuid,
+		ReplyAddress:   boostagram.ReplyAddress,
+		BoostLink:      boostagram.BoostLink,
+	}
+}
+
+// defaults to the last 30 days if no period is provided
+const defaultTransactionsSummaryPeriod = 30 * 24 * time.Hour
+
+func (api *api) GetTransactionsSummary(ctx context.Context, from uint64, until uint64, appId *uint, currency string) (*TransactionsSummary, error) {
+	untilTime := time.Now()
+	if until != 0 {
+		untilTime = time.Unix(int64(until), 0)
+	}
+	fromTime := untilTime.Add(-defaultTransactionsSummaryPeriod)
+	if from != 0 {
+		fromTime = time.Unix(int64(from), 0)
+	}
+
+	summary, err := api.svc.GetTransactionsService().GetSummary(fromTime, untilTime, appId, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	apiSummary := &TransactionsSummary{
+		From:     summary.From,
+		Until:    summary.Until,
+		Currency: summary.Currency,
+		Sent:     toApiTransactionsSummaryTotals(&summary.Sent, summary.Currency),
+		Received: toApiTransactionsSummaryTotals(&summary.Received, summary.Currency),
+		Apps:     []AppTransactionsSummary{},
+		Days:     []DailyTransactionsSummary{},
+	}
+	for _, appSummary := range summary.Apps {
+		apiSummary.Apps = append(apiSummary.Apps, AppTransactionsSummary{
+			AppId:    appSummary.AppId,
+			Sent:     toApiTransactionsSummaryTotals(&appSummary.Sent, summary.Currency),
+			Received: toApiTransactionsSummaryTotals(&appSummary.Received, summary.Currency),
+		})
+	}
+	for _, dailySummary := range summary.Days {
+		apiSummary.Days = append(apiSummary.Days, DailyTransactionsSummary{
+			Date:     dailySummary.Date,
+			Sent:     toApiTransactionsSummaryTotals(&dailySummary.Sent, summary.Currency),
+			Received: toApiTransactionsSummaryTotals(&dailySummary.Received, summary.Currency),
+		})
+	}
+	return apiSummary, nil
+}
+
+func (api *api) GetAppReport(ctx context.Context, appId uint, from uint64, until uint64, currency string) (*AppReport, error) {
+	untilTime := time.Now()
+	if until != 0 {
+		untilTime = time.Unix(int64(until), 0)
+	}
+	fromTime := untilTime.Add(-defaultTransactionsSummaryPeriod)
+	if from != 0 {
+		fromTime = time.Unix(int64(from), 0)
+	}
+
+	report, err := api.svc.GetTransactionsService().GetAppReport(appId, fromTime, untilTime, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	apiReport := &AppReport{
+		AppId:           report.AppId,
+		From:            report.From,
+		Until:           report.Until,
+		Currency:        report.Currency,
+		Sent:            toApiTransactionsSummaryTotals(&report.Sent, report.Currency),
+		Received:        toApiTransactionsSummaryTotals(&report.Received, report.Currency),
+		TopDestinations: []AppReportDestination{},
+		Budgets:         []AppReportBudget{},
+	}
+	for _, destination := range report.TopDestinations {
+		apiReport.TopDestinations = append(apiReport.TopDestinations, AppReportDestination{
+			Destination: destination.Destination,
+			Count:       destination.Count,
+			Amount:      destination.AmountMsat,
+			FeesPaid:    destination.FeeMsat,
+		})
+	}
+	for _, budget := range report.Budgets {
+		apiReport.Budgets = append(apiReport.Budgets, AppReportBudget{
+			BudgetRenewal: budget.BudgetRenewal,
+			MaxAmountSat:  budget.MaxAmountSat,
+			BudgetUsage:   budget.UsedSat,
+			RenewsAt:      budget.RenewsAt,
+		})
+	}
+	return apiReport, nil
+}
+
+func toApiTransactionsSummaryTotals(totals *transactions.SummaryTotals, currency string) TransactionsSummaryTotals {
+	apiTotals := TransactionsSummaryTotals{
+		Count:    totals.Count,
+		Amount:   totals.AmountMsat,
+		FeesPaid: totals.FeeMsat,
+	}
+	if currency != "" {
+		fiatAmount := totals.FiatAmount
+		apiTotals.FiatAmount = &fiatAmount
+	}
+	return apiTotals
+}
+
+func toApiFiatValues(amountMsat uint64, fiatRates []db.TransactionFiatRate) []FiatValue {
+	if len(fiatRates) == 0 {
+		return nil
+	}
+	fiatValues := []FiatValue{}
+	for _, fiatRate := range fiatRates {
+		fiatValues = append(fiatValues, FiatValue{
+			Currency: fiatRate.Currency,
+			Rate:     fiatRate.Rate,
+			Value:    transactions.GetFiatValue(amountMsat, fiatRate.Rate),
+		})
 	}
+	return fiatValues
 }
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/webhooks"
+)
+
+func (api *api) ListWebhooks() ([]Webhook, error) {
+	dbWebhooks, err := api.webhooksSvc.ListWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	apiWebhooks := []Webhook{}
+	for _, dbWebhook := range dbWebhooks {
+		apiWebhook := toApiWebhook(&dbWebhook)
+		apiWebhook.Deliveries, err = api.webhooksSvc.GetDeliveryStats(dbWebhook.ID)
+		if err != nil {
+			return nil, err
+		}
+		apiWebhooks = append(apiWebhooks, apiWebhook)
+	}
+	return apiWebhooks, nil
+}
+
+func (api *api) ListWebhookEventTypes() []string {
+	return webhooks.GetWebhookEventTypes()
+}
+
+func (api *api) CreateWebhook(createWebhookRequest *CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	var webhook *db.Webhook
+	var err error
+	if createWebhookRequest.AppId != nil {
+		webhook, err = api.webhooksSvc.CreateAppWebhook(*createWebhookRequest.AppId, createWebhookRequest.Url, createWebhookRequest.EventTypes, createWebhookRequest.Filter)
+	} else {
+		webhook, err = api.webhooksSvc.CreateWebhook(createWebhookRequest.Url, createWebhookRequest.EventTypes, createWebhookRequest.Filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateWebhookResponse{
+		Webhook: toApiWebhook(webhook),
+		Secret:  webhook.Secret,
+	}, nil
+}
+
+func (api *api) UpdateWebhook(id uint, updateWebhookRequest *UpdateWebhookRequest) (*Webhook, error) {
+	webhook, err := api.webhooksSvc.UpdateWebhook(id, updateWebhookRequest.Enabled, updateWebhookRequest.EventTypes, updateWebhookRequest.Filter)
+	if err != nil {
+		return nil, err
+	}
+	apiWebhook := toApiWebhook(webhook)
+	return &apiWebhook, nil
+}
+
+func (api *api) DeleteWebhook(id uint) error {
+	return api.webhooksSvc.DeleteWebhook(id)
+}
+
+func (api *api) ListWebhookDeliveries(webhookId uint, state string, limit uint64) ([]WebhookDelivery, error) {
+	dbDeliveries, err := api.webhooksSvc.ListDeliveries(webhookId, state, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, dbDelivery := range dbDeliveries {
+		deliveries = append(deliveries, toApiWebhookDelivery(&dbDelivery))
+	}
+	return deliveries, nil
+}
+
+func (api *api) RetryWebhookDelivery(ctx context.Context, webhookId uint, deliveryId uint) (*WebhookDelivery, error) {
+	dbDelivery, err := api.webhooksSvc.RetryDelivery(ctx, webhookId, deliveryId)
+	if err != nil {
+		return nil, err
+	}
+	delivery := toApiWebhookDelivery(dbDelivery)
+	return &delivery, nil
+}
+
+func toApiWebhookDelivery(delivery *db.WebhookDelivery) WebhookDelivery {
+	return WebhookDelivery{
+		ID:             delivery.ID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		State:          delivery.State,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+}
+
+func toApiWebhook(webhook *db.Webhook) Webhook {
+	eventTypes := []string{}
+	if webhook.EventTypes != "" {
+		eventTypes = strings.Split(webhook.EventTypes, ",")
+	}
+	filter, err := webhooks.ParseFilter(webhook.Filter)
+	if err != nil {
+		logger.Logger.WithField("webhook_id", webhook.ID).WithError(err).Error("Invalid webhook filter")
+	}
+	return Webhook{
+		ID:         webhook.ID,
+		Url:        webhook.Url,
+		EventTypes: eventTypes,
+		Filter:     filter,
+		Enabled:    webhook.Enabled,
+		AppId:      webhook.AppId,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
@@ -0,0 +1,65 @@
+package apps
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/logger"
+)
+
+const appActivityPruneInterval = time.Hour
+
+// used until a retention period is configured, 0 keeps the activity forever
+const DefaultAppActivityRetentionDays = 90
+
+func GetAppActivityRetentionDays(cfg config.Config) uint {
+	retentionDays, _ := cfg.Get(config.AppActivityRetentionDaysKey, "")
+	if retentionDays == "" {
+		return DefaultAppActivityRetentionDays
+	}
+	parsedRetentionDays, _ := strconv.ParseUint(retentionDays, 10, 32)
+	return uint(parsedRetentionDays)
+}
+
+// StartActivityLogPruning periodically removes activity older than the retention period until the context is cancelled
+func (svc *appsService) StartActivityLogPruning(ctx context.Context) {
+	health.RegisterJob("app_activity_pruning", appActivityPruneInterval)
+	go func() {
+		defer health.RemoveJob("app_activity_pruning")
+		ticker := time.NewTicker(appActivityPruneInterval)
+		defer ticker.Stop()
+		for {
+			_, err := svc.PruneActivityLogs()
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to prune app activity")
+			}
+			health.ReportJobRun("app_activity_pruning", err)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// PruneActivityLogs deletes the activity of all apps which is older than the retention period
+func (svc *appsService) PruneActivityLogs() (int64, error) {
+	retentionDays := GetAppActivityRetentionDays(svc.cfg)
+	if retentionDays == 0 {
+		return 0, nil
+	}
+
+	result := svc.db.Where("created_at < ?", time.Now().AddDate(0, 0, -int(retentionDays))).Delete(&db.AppActivityLog{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		logger.Logger.WithField("count", result.RowsAffected).Info("Pruned app activity")
+	}
+	return result.RowsAffected, nil
+}
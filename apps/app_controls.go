@@ -0,0 +1,116 @@
+package apps
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// RevokeApp expires all permissions of an app. Unlike deleting it, its history is kept
+// and it can be renewed later.
+func (svc *appsService) RevokeApp(app *db.App) error {
+	now := time.Now()
+	err := svc.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&db.AppPermission{}).
+			Where("app_id = ? AND (expires_at IS NULL OR expires_at > ?)", app.ID, now).
+			Update("expires_at", now).Error
+		if err != nil {
+			return err
+		}
+		return RecordAuditLog(tx, app.ID, AUDIT_ACTION_REVOKED, nil)
+	})
+	if err != nil {
+		logger.Logger.WithError(err).WithField("app_id", app.ID).Error("Failed to revoke app")
+		return err
+	}
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_app_revoked",
+		Properties: map[string]interface{}{
+			"name": app.Name,
+			"id":   app.ID,
+		},
+	})
+	return nil
+}
+
+// SetAppPaused temporarily rejects all requests of an app, without changing its permissions
+func (svc *appsService) SetAppPaused(app *db.App, paused bool) error {
+	if paused == (app.PausedAt != nil) {
+		return nil
+	}
+
+	var pausedAt *time.Time
+	action := AUDIT_ACTION_RESUMED
+	event := "nwc_app_resumed"
+	if paused {
+		now := time.Now()
+		pausedAt = &now
+		action = AUDIT_ACTION_PAUSED
+		event = "nwc_app_paused"
+	}
+
+	err := svc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&db.App{}).Where("id = ?", app.ID).Update("paused_at", pausedAt).Error; err != nil {
+			return err
+		}
+		return RecordAuditLog(tx, app.ID, action, nil)
+	})
+	if err != nil {
+		logger.Logger.WithError(err).WithField("app_id", app.ID).Error("Failed to pause app")
+		return err
+	}
+	app.PausedAt = pausedAt
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: event,
+		Properties: map[string]interface{}{
+			"name": app.Name,
+			"id":   app.ID,
+		},
+	})
+	return nil
+}
+
+// ScaleAppBudgets multiplies all budgets of an app by the factor, e.g. 0.5 to cut them by half.
+// Apps without a budget are not changed.
+func (svc *appsService) ScaleAppBudgets(app *db.App, factor float64) error {
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return errors.New("budget factor must be greater than zero")
+	}
+
+	return svc.db.Transaction(func(tx *gorm.DB) error {
+		var appPermission db.AppPermission
+		if tx.Limit(1).Find(&appPermission, &db.AppPermission{AppId: app.ID, Scope: constants.PAY_INVOICE_SCOPE}).RowsAffected > 0 {
+			err := tx.Model(&appPermission).Updates(map[string]interface{}{
+				"max_amount_sat":  int(math.Floor(float64(appPermission.MaxAmountSat) * factor)),
+				"max_amount_fiat": appPermission.MaxAmountFiat * factor,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var appBudgets []db.AppBudget
+		if err := tx.Where("app_id = ?", app.ID).Find(&appBudgets).Error; err != nil {
+			return err
+		}
+		for _, appBudget := range appBudgets {
+			err := tx.Model(&appBudget).Update("max_amount_sat", uint64(math.Floor(float64(appBudget.MaxAmountSat)*factor))).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return RecordAuditLog(tx, app.ID, AUDIT_ACTION_BUDGETS_SCALED, map[string]interface{}{
+			"factor": factor,
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/logger"
+)
+
+const appExpiryNotificationInterval = time.Hour
+
+// connections are disabled once they expire, the user is notified this long before
+const appExpiryNotificationWindow = 3 * 24 * time.Hour
+
+// StartExpiryNotifications periodically notifies about connections which expire soon until the context is cancelled
+func (svc *appsService) StartExpiryNotifications(ctx context.Context) {
+	health.RegisterJob("app_expiry_notifications", appExpiryNotificationInterval)
+	go func() {
+		defer health.RemoveJob("app_expiry_notifications")
+		ticker := time.NewTicker(appExpiryNotificationInterval)
+		defer ticker.Stop()
+		for {
+			_, err := svc.NotifyExpiringApps()
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to notify expiring apps")
+			}
+			health.ReportJobRun("app_expiry_notifications", err)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// NotifyExpiringApps publishes an event once for each connection which expires within the notification window
+func (svc *appsService) NotifyExpiringApps() (int, error) {
+	var appPermissions []db.AppPermission
+	now := time.Now()
+	err := svc.db.
+		Preload("App").
+		Joins("JOIN apps ON apps.id = app_permissions.app_id").
+		Where("apps.expiry_notified_at IS NULL AND app_permissions.expires_at > ? AND app_permissions.expires_at <= ?", now, now.Add(appExpiryNotificationWindow)).
+		Find(&appPermissions).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// all permissions of an app share the same expiry
+	notifiedAppIds := map[uint]bool{}
+	for _, appPermission := range appPermissions {
+		if notifiedAppIds[appPermission.AppId] {
+			continue
+		}
+		notifiedAppIds[appPermission.AppId] = true
+
+		err := svc.db.Model(&db.App{}).Where("id = ?", appPermission.AppId).Update("expiry_notified_at", now).Error
+		if err != nil {
+			return 0, err
+		}
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_app_expiring",
+			Properties: map[string]interface{}{
+				"name":       appPermission.App.Name,
+				"id":         appPermission.AppId,
+				"expires_at": appPermission.ExpiresAt.Unix(),
+			},
+		})
+	}
+	return len(notifiedAppIds), nil
+}
+
+// RenewApp extends the expiry of a connection, keeping its permissions and history.
+// Without an explicit expiry the connection is renewed for the same length as its current validity period.
+func (svc *appsService) RenewApp(app *db.App, expiresAt *time.Time) (*time.Time, error) {
+	var appPermission db.AppPermission
+	if svc.db.Where("app_id = ? AND expires_at IS NOT NULL", app.ID).Limit(1).Find(&appPermission).RowsAffected == 0 {
+		return nil, errors.New("app does not expire")
+	}
+
+	now := time.Now()
+	if expiresAt == nil {
+		periodStart := app.CreatedAt
+		if app.RenewedAt != nil {
+			periodStart = *app.RenewedAt
+		}
+		period := appPermission.ExpiresAt.Sub(periodStart)
+		if period <= 0 {
+			return nil, errors.New("cannot determine the validity period of the app")
+		}
+		// an app which has not expired yet is extended from its current expiry
+		renewFrom := now
+		if appPermission.ExpiresAt.After(now) {
+			renewFrom = *appPermission.ExpiresAt
+		}
+		newExpiresAt := renewFrom.Add(period)
+		expiresAt = &newExpiresAt
+	}
+	if !expiresAt.After(now) {
+		return nil, errors.New("expiry must be in the future")
+	}
+
+	err := svc.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&db.AppPermission{}).Where("app_id = ?", app.ID).Update("expires_at", expiresAt).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&db.App{}).Where("id = ?", app.ID).Updates(map[string]interface{}{
+			"renewed_at":         now,
+			"expiry_notified_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return RecordAuditLog(tx, app.ID, AUDIT_ACTION_RENEWED, map[string]interface{}{
+			"expires_at": expiresAt.Unix(),
+		})
+	})
+	if err != nil {
+		logger.Logger.WithError(err).WithField("app_id", app.ID).Error("Failed to renew app")
+		return nil, err
+	}
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_app_renewed",
+		Properties: map[string]interface{}{
+			"name":       app.Name,
+			"id":         app.ID,
+			"expires_at": expiresAt.Unix(),
+		},
+	})
+	return expiresAt, nil
+}
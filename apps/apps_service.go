@@ -1,6 +1,7 @@
 package apps
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -27,6 +28,18 @@ type AppsService interface {
 	GetAppById(id uint) *db.App
 	SetAppMetadata(appId uint, metadata map[string]interface{}) error
 	HasLightningAddress(app *db.App) bool
+	RenewApp(app *db.App, expiresAt *time.Time) (*time.Time, error)
+	RotateAppSecret(app *db.App) (string, error)
+	RevokeApp(app *db.App) error
+	SetAppPaused(app *db.App, paused bool) error
+	ScaleAppBudgets(app *db.App, factor float64) error
+	NotifyExpiringApps() (int, error)
+	StartExpiryNotifications(ctx context.Context)
+	StartAppTemplatesRefresh(ctx context.Context)
+	PruneActivityLogs() (int64, error)
+	StartActivityLogPruning(ctx context.Context)
+	RefreshNostrProfiles(ctx context.Context, pool *nostr.SimplePool) (int, error)
+	StartNostrProfilesRefresh(ctx context.Context, pool *nostr.SimplePool)
 }
 
 type appsService struct {
@@ -0,0 +1,41 @@
+package apps
+
+import (
+	"encoding/json"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+)
+
+const (
+	AUDIT_ACTION_SCOPE_ADDED         = "scope_added"
+	AUDIT_ACTION_SCOPE_REMOVED       = "scope_removed"
+	AUDIT_ACTION_RENEWED             = "renewed"
+	AUDIT_ACTION_SECRET_ROTATED      = "secret_rotated"
+	AUDIT_ACTION_DESTINATION_ADDED   = "destination_added"
+	AUDIT_ACTION_DESTINATION_REMOVED = "destination_removed"
+	AUDIT_ACTION_REVOKED             = "revoked"
+	AUDIT_ACTION_PAUSED              = "paused"
+	AUDIT_ACTION_RESUMED             = "resumed"
+	AUDIT_ACTION_BUDGETS_SCALED      = "budgets_scaled"
+)
+
+// RecordAuditLog stores a change to the configuration of an app as part of the given transaction
+func RecordAuditLog(tx *gorm.DB, appId uint, action string, details map[string]interface{}) error {
+	var detailsBytes []byte
+	if details != nil {
+		var err error
+		detailsBytes, err = json.Marshal(details)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&db.AppAuditLog{
+		AppId:   appId,
+		Action:  action,
+		Details: datatypes.JSON(detailsBytes),
+	}).Error
+}
@@ -0,0 +1,167 @@
+package apps
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	nostrProfilesRefreshInterval = 5 * time.Minute
+	// profiles are fetched again once they are older than this
+	nostrProfileMaxAge = 24 * time.Hour
+	// fields of fetched profiles are shortened to this length
+	nostrProfileFieldMaxLength = 512
+)
+
+type nostrProfileContent struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Picture     string `json:"picture"`
+	About       string `json:"about"`
+}
+
+// fetchNostrProfileEvents returns the latest kind-0 event of each of the pubkeys which could be found on the relays
+func fetchNostrProfileEvents(ctx context.Context, pool *nostr.SimplePool, relayUrls []string, pubkeys []string) (map[string]*nostr.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	profileEvents := map[string]*nostr.Event{}
+	for relayEvent := range pool.FetchMany(ctx, relayUrls, nostr.Filter{
+		Kinds:   []int{nostr.KindProfileMetadata},
+		Authors: pubkeys,
+	}) {
+		event := relayEvent.Event
+		if existing, ok := profileEvents[event.PubKey]; !ok || event.CreatedAt > existing.CreatedAt {
+			profileEvents[event.PubKey] = event
+		}
+	}
+	return profileEvents, nil
+}
+
+// ParseNostrPubkey returns the hex pubkey of a hex or npub encoded nostr pubkey
+func ParseNostrPubkey(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "npub") {
+		prefix, decoded, err := nip19.Decode(value)
+		if err != nil || prefix != "npub" {
+			return "", errors.New("invalid npub")
+		}
+		return decoded.(string), nil
+	}
+	value = strings.ToLower(value)
+	if decoded, err := hex.DecodeString(value); err != nil || len(decoded) != 32 {
+		return "", errors.New("invalid nostr pubkey")
+	}
+	return value, nil
+}
+
+// GetNostrProfiles returns the cached profiles of the pubkeys, by pubkey
+func GetNostrProfiles(tx *gorm.DB, pubkeys []string) (map[string]db.NostrProfile, error) {
+	profiles := map[string]db.NostrProfile{}
+	if len(pubkeys) == 0 {
+		return profiles, nil
+	}
+	var nostrProfiles []db.NostrProfile
+	if err := tx.Where("pubkey IN ?", pubkeys).Find(&nostrProfiles).Error; err != nil {
+		return nil, err
+	}
+	for _, nostrProfile := range nostrProfiles {
+		profiles[nostrProfile.Pubkey] = nostrProfile
+	}
+	return profiles, nil
+}
+
+// RefreshNostrProfiles fetches the profiles of the nostr users of connections which were not fetched recently.
+// Pubkeys without a profile are cached as empty profiles, so that they are not fetched again until they are stale.
+func (svc *appsService) RefreshNostrProfiles(ctx context.Context, pool *nostr.SimplePool) (int, error) {
+	var pubkeys []string
+	err := svc.db.Model(&db.App{}).
+		Distinct("nostr_pubkey").
+		Where("nostr_pubkey IS NOT NULL AND nostr_pubkey NOT IN (?)",
+			svc.db.Model(&db.NostrProfile{}).Select("pubkey").Where("fetched_at > ?", time.Now().Add(-nostrProfileMaxAge))).
+		Pluck("nostr_pubkey", &pubkeys).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(pubkeys) == 0 {
+		return 0, nil
+	}
+
+	profileEvents, err := fetchNostrProfileEvents(ctx, pool, svc.cfg.GetRelayUrls(), pubkeys)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	for _, pubkey := range pubkeys {
+		nostrProfile := db.NostrProfile{
+			Pubkey:    pubkey,
+			FetchedAt: now,
+		}
+		if event, ok := profileEvents[pubkey]; ok {
+			var content nostrProfileContent
+			if err := json.Unmarshal([]byte(event.Content), &content); err != nil {
+				logger.Logger.WithError(err).WithField("pubkey", pubkey).Warn("Failed to parse nostr profile")
+			}
+			nostrProfile.Name = content.DisplayName
+			if nostrProfile.Name == "" {
+				nostrProfile.Name = content.Name
+			}
+			nostrProfile.Name = truncateNostrProfileField(nostrProfile.Name)
+			nostrProfile.Picture = truncateNostrProfileField(content.Picture)
+			nostrProfile.About = truncateNostrProfileField(content.About)
+		}
+
+		err := svc.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "pubkey"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "picture", "about", "fetched_at", "updated_at"}),
+		}).Create(&nostrProfile).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"pubkeys": len(pubkeys),
+		"found":   len(profileEvents),
+	}).Debug("Refreshed nostr profiles")
+	return len(pubkeys), nil
+}
+
+func truncateNostrProfileField(value string) string {
+	if len(value) > nostrProfileFieldMaxLength {
+		return value[:nostrProfileFieldMaxLength]
+	}
+	return value
+}
+
+// StartNostrProfilesRefresh periodically refreshes stale nostr profiles of connections until the context is cancelled
+func (svc *appsService) StartNostrProfilesRefresh(ctx context.Context, pool *nostr.SimplePool) {
+	go func() {
+		ticker := time.NewTicker(nostrProfilesRefreshInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := svc.RefreshNostrProfiles(ctx, pool); err != nil {
+				logger.Logger.WithError(err).Error("Failed to refresh nostr profiles")
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
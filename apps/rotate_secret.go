@@ -0,0 +1,46 @@
+package apps
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// RotateAppSecret replaces the keypair an app uses to connect. Requests signed with the old key are rejected,
+// while the permissions, budget and history of the app are kept. It returns the new pairing secret key.
+func (svc *appsService) RotateAppSecret(app *db.App) (string, error) {
+	pairingSecretKey := nostr.GeneratePrivateKey()
+	pairingPublicKey, err := nostr.GetPublicKey(pairingSecretKey)
+	if err != nil {
+		return "", err
+	}
+
+	previousPubkey := app.AppPubkey
+	err = svc.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&db.App{}).Where("id = ?", app.ID).Update("app_pubkey", pairingPublicKey).Error
+		if err != nil {
+			return err
+		}
+		return RecordAuditLog(tx, app.ID, AUDIT_ACTION_SECRET_ROTATED, map[string]interface{}{
+			"previous_pubkey": previousPubkey,
+			"pubkey":          pairingPublicKey,
+		})
+	})
+	if err != nil {
+		logger.Logger.WithError(err).WithField("app_id", app.ID).Error("Failed to rotate app secret")
+		return "", err
+	}
+	app.AppPubkey = pairingPublicKey
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_app_secret_rotated",
+		Properties: map[string]interface{}{
+			"name": app.Name,
+			"id":   app.ID,
+		},
+	})
+	return pairingSecretKey, nil
+}
@@ -0,0 +1,219 @@
+package apps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/logger"
+)
+
+const appTemplatesRefreshInterval = 24 * time.Hour
+
+// AppTemplate is a preset for connecting a popular kind of app with only the permissions it needs
+type AppTemplate struct {
+	Id            string   `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Scopes        []string `json:"scopes"`
+	MaxAmountSat  uint64   `json:"maxAmount"`
+	BudgetRenewal string   `json:"budgetRenewal"`
+}
+
+// the scopes a template can grant, which never include full control over the wallet
+var appTemplateScopes = []string{
+	constants.PAY_INVOICE_SCOPE,
+	constants.GET_BALANCE_SCOPE,
+	constants.GET_INFO_SCOPE,
+	constants.MAKE_INVOICE_SCOPE,
+	constants.LOOKUP_INVOICE_SCOPE,
+	constants.LIST_TRANSACTIONS_SCOPE,
+	constants.SIGN_MESSAGE_SCOPE,
+	constants.NOTIFICATIONS_SCOPE,
+}
+
+type appTemplatesManifest struct {
+	Templates []AppTemplate `json:"templates"`
+}
+
+var builtinAppTemplates = []AppTemplate{
+	{
+		Id:            "nostr-zaps",
+		Name:          "Nostr client",
+		Description:   "Send zaps from a nostr client",
+		Scopes:        []string{constants.PAY_INVOICE_SCOPE, constants.GET_BALANCE_SCOPE, constants.GET_INFO_SCOPE},
+		MaxAmountSat:  10_000,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	},
+	{
+		Id:            "podcasting",
+		Name:          "Podcasting 2.0",
+		Description:   "Stream sats to podcasts while listening",
+		Scopes:        []string{constants.PAY_INVOICE_SCOPE, constants.GET_BALANCE_SCOPE, constants.GET_INFO_SCOPE},
+		MaxAmountSat:  5_000,
+		BudgetRenewal: constants.BUDGET_RENEWAL_WEEKLY,
+	},
+	{
+		Id:            "point-of-sale",
+		Name:          "Point of sale",
+		Description:   "Receive payments and get notified about them, without being able to spend",
+		Scopes:        []string{constants.MAKE_INVOICE_SCOPE, constants.LOOKUP_INVOICE_SCOPE, constants.GET_INFO_SCOPE, constants.NOTIFICATIONS_SCOPE},
+		BudgetRenewal: constants.BUDGET_RENEWAL_NEVER,
+	},
+	{
+		Id:            "bookkeeping",
+		Name:          "Bookkeeping",
+		Description:   "Read the balance and transaction history for accounting",
+		Scopes:        []string{constants.GET_INFO_SCOPE, constants.GET_BALANCE_SCOPE, constants.LOOKUP_INVOICE_SCOPE, constants.LIST_TRANSACTIONS_SCOPE},
+		BudgetRenewal: constants.BUDGET_RENEWAL_NEVER,
+	},
+	{
+		Id:          "wallet",
+		Name:        "Mobile wallet",
+		Description: "Send and receive payments from a wallet app",
+		Scopes: []string{
+			constants.PAY_INVOICE_SCOPE,
+			constants.GET_BALANCE_SCOPE,
+			constants.GET_INFO_SCOPE,
+			constants.MAKE_INVOICE_SCOPE,
+			constants.LOOKUP_INVOICE_SCOPE,
+			constants.LIST_TRANSACTIONS_SCOPE,
+			constants.NOTIFICATIONS_SCOPE,
+		},
+		MaxAmountSat:  100_000,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	},
+}
+
+// templates from the remote manifest are shared by all apps service instances
+var remoteAppTemplates []AppTemplate
+var remoteAppTemplatesMutex sync.RWMutex
+
+// GetAppTemplates returns the built-in templates, with the ones from the remote manifest taking precedence
+func GetAppTemplates() []AppTemplate {
+	remoteAppTemplatesMutex.RLock()
+	defer remoteAppTemplatesMutex.RUnlock()
+
+	templates := []AppTemplate{}
+	for _, template := range builtinAppTemplates {
+		if !slices.ContainsFunc(remoteAppTemplates, func(remoteTemplate AppTemplate) bool { return remoteTemplate.Id == template.Id }) {
+			templates = append(templates, template)
+		}
+	}
+	return append(templates, remoteAppTemplates...)
+}
+
+func GetAppTemplate(id string) *AppTemplate {
+	for _, template := range GetAppTemplates() {
+		if template.Id == id {
+			return &template
+		}
+	}
+	return nil
+}
+
+func validateAppTemplate(template *AppTemplate) error {
+	if template.Id == "" || template.Name == "" {
+		return errors.New("template id and name are required")
+	}
+	if len(template.Scopes) == 0 {
+		return errors.New("no scopes provided")
+	}
+	for _, scope := range template.Scopes {
+		if !slices.Contains(appTemplateScopes, scope) {
+			return fmt.Errorf("invalid scope: %s", scope)
+		}
+	}
+	if template.BudgetRenewal == "" {
+		template.BudgetRenewal = constants.BUDGET_RENEWAL_NEVER
+	}
+	if !slices.Contains(constants.GetBudgetRenewals(), template.BudgetRenewal) {
+		return fmt.Errorf("invalid budget renewal: %s", template.BudgetRenewal)
+	}
+	return nil
+}
+
+// RefreshAppTemplates replaces the remote templates with the ones from the manifest, skipping invalid templates
+func RefreshAppTemplates(ctx context.Context, manifestUrl string) error {
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code fetching app templates: %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	var manifest appTemplatesManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("failed to deserialize app templates manifest: %w", err)
+	}
+
+	templates := []AppTemplate{}
+	for _, template := range manifest.Templates {
+		if err := validateAppTemplate(&template); err != nil {
+			logger.Logger.WithError(err).WithField("template_id", template.Id).Warn("Skipping invalid app template")
+			continue
+		}
+		if slices.ContainsFunc(templates, func(existing AppTemplate) bool { return existing.Id == template.Id }) {
+			continue
+		}
+		templates = append(templates, template)
+	}
+
+	remoteAppTemplatesMutex.Lock()
+	remoteAppTemplates = templates
+	remoteAppTemplatesMutex.Unlock()
+
+	logger.Logger.WithFields(logrus.Fields{
+		"url":       manifestUrl,
+		"templates": len(templates),
+	}).Info("Refreshed app templates")
+	return nil
+}
+
+// StartAppTemplatesRefresh periodically fetches the remote templates manifest, if one is configured, until the context is cancelled
+func (svc *appsService) StartAppTemplatesRefresh(ctx context.Context) {
+	manifestUrl := svc.cfg.GetEnv().AppTemplatesManifestUrl
+	if manifestUrl == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(appTemplatesRefreshInterval)
+		defer ticker.Stop()
+		for {
+			if err := RefreshAppTemplates(ctx, manifestUrl); err != nil {
+				logger.Logger.WithError(err).WithField("url", manifestUrl).Error("Failed to refresh app templates")
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestPruneActivityLogs(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	for _, age := range []time.Duration{time.Hour, 10 * 24 * time.Hour, 100 * 24 * time.Hour} {
+		require.NoError(t, svc.DB.Create(&db.AppActivityLog{
+			AppId:       app.ID,
+			Method:      "get_info",
+			Result:      constants.APP_ACTIVITY_RESULT_SUCCESS,
+			RequestedAt: time.Now().Add(-age),
+			CreatedAt:   time.Now().Add(-age),
+		}).Error)
+	}
+
+	appsSvc := apps.NewAppsService(svc.DB, svc.EventPublisher, svc.Keys, svc.Cfg)
+
+	// the default retention period applies until one is configured
+	assert.Equal(t, uint(apps.DefaultAppActivityRetentionDays), apps.GetAppActivityRetentionDays(svc.Cfg))
+	pruned, err := appsSvc.PruneActivityLogs()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.AppActivityRetentionDaysKey, "0", ""))
+	pruned, err = appsSvc.PruneActivityLogs()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pruned)
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.AppActivityRetentionDaysKey, "7", ""))
+	pruned, err = appsSvc.PruneActivityLogs()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	var count int64
+	svc.DB.Model(&db.AppActivityLog{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestNotifyExpiringApps(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	appsService := apps.NewAppsService(svc.DB, svc.EventPublisher, svc.Keys, svc.Cfg)
+	expiresSoon := time.Now().Add(24 * time.Hour)
+	expiresLater := time.Now().Add(30 * 24 * time.Hour)
+	app, _, err := appsService.CreateApp("Expires soon", "", 0, "monthly", &expiresSoon, []string{constants.GET_INFO_SCOPE, constants.GET_BALANCE_SCOPE}, false, nil)
+	require.NoError(t, err)
+	_, _, err = appsService.CreateApp("Expires later", "", 0, "monthly", &expiresLater, []string{constants.GET_INFO_SCOPE}, false, nil)
+	require.NoError(t, err)
+
+	count, err := appsService.NotifyExpiringApps()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// only notified once
+	count, err = appsService.NotifyExpiringApps()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	expiringEvents := 0
+	for _, event := range mockEventConsumer.GetConsumedEvents() {
+		if event.Event == "nwc_app_expiring" {
+			expiringEvents++
+			assert.Equal(t, app.ID, event.Properties.(map[string]interface{})["id"])
+		}
+	}
+	assert.Equal(t, 1, expiringEvents)
+}
+
+func TestRenewApp(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	appsService := apps.NewAppsService(svc.DB, svc.EventPublisher, svc.Keys, svc.Cfg)
+	expiresAt := time.Now().Add(-time.Hour)
+	app, _, err := appsService.CreateApp("Trial", "", 0, "monthly", &expiresAt, []string{constants.GET_INFO_SCOPE}, false, nil)
+	require.NoError(t, err)
+
+	// the app expired an hour after it was created
+	app.CreatedAt = expiresAt.Add(-7 * 24 * time.Hour)
+	require.NoError(t, svc.DB.Model(app).Update("created_at", app.CreatedAt).Error)
+	require.NoError(t, svc.DB.Model(app).Update("expiry_notified_at", time.Now()).Error)
+
+	renewedExpiresAt, err := appsService.RenewApp(app, nil)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), *renewedExpiresAt, time.Minute)
+
+	var appPermission db.AppPermission
+	require.NoError(t, svc.DB.First(&appPermission, &db.AppPermission{AppId: app.ID}).Error)
+	assert.WithinDuration(t, *renewedExpiresAt, *appPermission.ExpiresAt, time.Second)
+
+	renewedApp := appsService.GetAppById(app.ID)
+	require.NotNil(t, renewedApp)
+	assert.NotNil(t, renewedApp.RenewedAt)
+	assert.Nil(t, renewedApp.ExpiryNotifiedAt)
+
+	// an explicit expiry must be in the future
+	_, err = appsService.RenewApp(renewedApp, &expiresAt)
+	assert.EqualError(t, err, "expiry must be in the future")
+}
+
+func TestRenewApp_NoExpiry(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	appsService := apps.NewAppsService(svc.DB, svc.EventPublisher, svc.Keys, svc.Cfg)
+	app, _, err := appsService.CreateApp("Test", "", 0, "monthly", nil, []string{constants.GET_INFO_SCOPE}, false, nil)
+	require.NoError(t, err)
+
+	_, err = appsService.RenewApp(app, nil)
+	assert.EqualError(t, err, "app does not expire")
+}
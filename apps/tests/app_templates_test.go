@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/constants"
+)
+
+func TestRefreshAppTemplates(t *testing.T) {
+	manifest := `{"templates": [
+		{"id": "nostr-zaps", "name": "Nostr client", "scopes": ["pay_invoice"], "maxAmount": 2100, "budgetRenewal": "weekly"},
+		{"id": "tipping", "name": "Tipping", "scopes": ["pay_invoice"], "maxAmount": 1000},
+		{"id": "takeover", "name": "Takeover", "scopes": ["superuser"]},
+		{"id": "unknown-scope", "name": "Unknown", "scopes": ["steal_funds"]},
+		{"id": "no-scopes", "name": "No scopes"}
+	]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(manifest))
+	}))
+	defer server.Close()
+
+	builtinTemplateCount := len(apps.GetAppTemplates())
+
+	require.NoError(t, apps.RefreshAppTemplates(context.TODO(), server.URL))
+	// reset to the built-in templates
+	defer func() {
+		manifest = `{"templates": []}`
+		require.NoError(t, apps.RefreshAppTemplates(context.TODO(), server.URL))
+		assert.Len(t, apps.GetAppTemplates(), builtinTemplateCount)
+	}()
+
+	// the remote template replaces the built-in one and invalid templates are skipped
+	assert.Len(t, apps.GetAppTemplates(), builtinTemplateCount+1)
+	template := apps.GetAppTemplate("nostr-zaps")
+	require.NotNil(t, template)
+	assert.Equal(t, []string{constants.PAY_INVOICE_SCOPE}, template.Scopes)
+	assert.Equal(t, uint64(2100), template.MaxAmountSat)
+	assert.Equal(t, constants.BUDGET_RENEWAL_WEEKLY, template.BudgetRenewal)
+
+	template = apps.GetAppTemplate("tipping")
+	require.NotNil(t, template)
+	assert.Equal(t, constants.BUDGET_RENEWAL_NEVER, template.BudgetRenewal)
+
+	assert.Nil(t, apps.GetAppTemplate("takeover"))
+	assert.Nil(t, apps.GetAppTemplate("unknown-scope"))
+	assert.Nil(t, apps.GetAppTemplate("no-scopes"))
+}
+
+func TestRefreshAppTemplates_InvalidManifest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	builtinTemplates := apps.GetAppTemplates()
+	assert.EqualError(t, apps.RefreshAppTemplates(context.TODO(), server.URL), "unexpected status code fetching app templates: 404")
+	assert.Equal(t, builtinTemplates, apps.GetAppTemplates())
+}
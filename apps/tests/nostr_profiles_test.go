@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+// newProfileRelay returns a relay which answers every subscription with the given events
+func newProfileRelay(profileEvents ...nostr.Event) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		for {
+			_, message, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+			var envelope []json.RawMessage
+			if json.Unmarshal(message, &envelope) != nil || len(envelope) < 2 || string(envelope[0]) != `"REQ"` {
+				continue
+			}
+			for _, profileEvent := range profileEvents {
+				response, _ := json.Marshal([]interface{}{"EVENT", envelope[1], profileEvent})
+				_ = conn.Write(r.Context(), websocket.MessageText, response)
+			}
+			response, _ := json.Marshal([]interface{}{"EOSE", envelope[1]})
+			_ = conn.Write(r.Context(), websocket.MessageText, response)
+		}
+	}))
+}
+
+func newProfileEvent(t *testing.T, secretKey string, content string, createdAt time.Time) nostr.Event {
+	pubkey, err := nostr.GetPublicKey(secretKey)
+	require.NoError(t, err)
+	event := nostr.Event{
+		PubKey:    pubkey,
+		CreatedAt: nostr.Timestamp(createdAt.Unix()),
+		Kind:      nostr.KindProfileMetadata,
+		Tags:      nostr.Tags{},
+		Content:   content,
+	}
+	require.NoError(t, event.Sign(secretKey))
+	return event
+}
+
+func TestRefreshNostrProfiles(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	secretKey := nostr.GeneratePrivateKey()
+	pubkey, err := nostr.GetPublicKey(secretKey)
+	require.NoError(t, err)
+	unknownPubkey, err := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	require.NoError(t, err)
+
+	relay := newProfileRelay(
+		newProfileEvent(t, secretKey, `{"name":"old"}`, time.Now().Add(-time.Hour)),
+		newProfileEvent(t, secretKey, `{"name":"alice","display_name":"Alice","picture":"https://example.com/alice.png","about":"Zapping"}`, time.Now()),
+	)
+	defer relay.Close()
+	require.NoError(t, svc.Cfg.SetUpdate("Relay", "ws"+strings.TrimPrefix(relay.URL, "http"), ""))
+
+	for _, nostrPubkey := range []string{pubkey, unknownPubkey} {
+		app, _, err := tests.CreateApp(svc)
+		require.NoError(t, err)
+		require.NoError(t, svc.DB.Model(app).Update("nostr_pubkey", nostrPubkey).Error)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := nostr.NewSimplePool(ctx)
+
+	refreshed, err := svc.AppsService.RefreshNostrProfiles(ctx, pool)
+	require.NoError(t, err)
+	assert.Equal(t, 2, refreshed)
+
+	nostrProfiles, err := apps.GetNostrProfiles(svc.DB, []string{pubkey, unknownPubkey})
+	require.NoError(t, err)
+	require.Len(t, nostrProfiles, 2)
+	// the latest profile is used, preferring the display name
+	assert.Equal(t, "Alice", nostrProfiles[pubkey].Name)
+	assert.Equal(t, "https://example.com/alice.png", nostrProfiles[pubkey].Picture)
+	assert.Equal(t, "Zapping", nostrProfiles[pubkey].About)
+	assert.Empty(t, nostrProfiles[unknownPubkey].Name)
+
+	// recently fetched profiles are not fetched again
+	refreshed, err = svc.AppsService.RefreshNostrProfiles(ctx, pool)
+	require.NoError(t, err)
+	assert.Zero(t, refreshed)
+
+	require.NoError(t, svc.DB.Model(&db.NostrProfile{}).Where("pubkey = ?", unknownPubkey).Update("fetched_at", time.Now().Add(-48*time.Hour)).Error)
+	refreshed, err = svc.AppsService.RefreshNostrProfiles(ctx, pool)
+	require.NoError(t, err)
+	assert.Equal(t, 1, refreshed)
+}
+
+func TestParseNostrPubkey(t *testing.T) {
+	pubkey, err := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	require.NoError(t, err)
+	npub, err := nip19.EncodePublicKey(pubkey)
+	require.NoError(t, err)
+
+	parsedPubkey, err := apps.ParseNostrPubkey(npub)
+	require.NoError(t, err)
+	assert.Equal(t, pubkey, parsedPubkey)
+
+	parsedPubkey, err = apps.ParseNostrPubkey(strings.ToUpper(pubkey))
+	require.NoError(t, err)
+	assert.Equal(t, pubkey, parsedPubkey)
+
+	_, err = apps.ParseNostrPubkey("npub1invalid")
+	assert.EqualError(t, err, "invalid npub")
+	_, err = apps.ParseNostrPubkey("abc")
+	assert.EqualError(t, err, "invalid nostr pubkey")
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestRotateAppSecret(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	appsService := apps.NewAppsService(svc.DB, svc.EventPublisher, svc.Keys, svc.Cfg)
+	app, _, err := appsService.CreateApp("Test", "", 1000, constants.BUDGET_RENEWAL_MONTHLY, nil, []string{constants.PAY_INVOICE_SCOPE}, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Model(app).Update("isolated", true).Error)
+	require.NoError(t, svc.DB.Create(&db.Transaction{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 1000}).Error)
+
+	previousPubkey := app.AppPubkey
+	secretKey, err := appsService.RotateAppSecret(app)
+	require.NoError(t, err)
+
+	pubkey, err := nostr.GetPublicKey(secretKey)
+	require.NoError(t, err)
+	assert.Equal(t, pubkey, app.AppPubkey)
+	assert.NotEqual(t, previousPubkey, pubkey)
+
+	// the old key no longer finds the app
+	assert.Nil(t, appsService.GetAppByPubkey(previousPubkey))
+	rotatedApp := appsService.GetAppByPubkey(pubkey)
+	require.NotNil(t, rotatedApp)
+	assert.Equal(t, app.ID, rotatedApp.ID)
+	assert.True(t, rotatedApp.Isolated)
+	assert.Equal(t, app.WalletPubkey, rotatedApp.WalletPubkey)
+
+	var appPermission db.AppPermission
+	require.NoError(t, svc.DB.First(&appPermission, &db.AppPermission{AppId: app.ID}).Error)
+	assert.Equal(t, constants.PAY_INVOICE_SCOPE, appPermission.Scope)
+	assert.Equal(t, 1000, appPermission.MaxAmountSat)
+
+	var transactionCount int64
+	svc.DB.Model(&db.Transaction{}).Where("app_id = ?", app.ID).Count(&transactionCount)
+	assert.Equal(t, int64(1), transactionCount)
+
+	var auditLog db.AppAuditLog
+	require.NoError(t, svc.DB.First(&auditLog, &db.AppAuditLog{AppId: app.ID, Action: apps.AUDIT_ACTION_SECRET_ROTATED}).Error)
+	assert.Contains(t, string(auditLog.Details), previousPubkey)
+
+	rotatedEvents := 0
+	for _, event := range mockEventConsumer.GetConsumedEvents() {
+		if event.Event == "nwc_app_secret_rotated" {
+			rotatedEvents++
+		}
+	}
+	assert.Equal(t, 1, rotatedEvents)
+}
@@ -0,0 +1,172 @@
+package auditlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+)
+
+// the previous hash of the first entry
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// entries are chained, so they have to be appended one at a time
+var recordMutex sync.Mutex
+
+type Entry struct {
+	ID        uint            `json:"id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	RemoteIp  string          `json:"remoteIp,omitempty"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	PrevHash  string          `json:"prevHash"`
+	Hash      string          `json:"hash"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type Verification struct {
+	Valid   bool  `json:"valid"`
+	Entries int64 `json:"entries"`
+	// hash of the newest entry, keep it elsewhere to also detect removed entries at the end
+	HeadHash string `json:"headHash"`
+	// the first entry which does not match the chain
+	BrokenAtId uint `json:"brokenAtId,omitempty"`
+}
+
+// the fields which are hashed, in a fixed order
+type hashedEntry struct {
+	PrevHash  string `json:"prevHash"`
+	CreatedAt string `json:"createdAt"`
+	Action    string `json:"action"`
+	Actor     string `json:"actor"`
+	RemoteIp  string `json:"remoteIp"`
+	Details   string `json:"details"`
+}
+
+func computeHash(auditLog *db.AdminAuditLog) (string, error) {
+	hashed, err := json.Marshal(hashedEntry{
+		PrevHash:  auditLog.PrevHash,
+		CreatedAt: auditLog.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Action:    auditLog.Action,
+		Actor:     auditLog.Actor,
+		RemoteIp:  auditLog.RemoteIp,
+		Details:   auditLog.Details,
+	})
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(hashed)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Record appends an administrative action to the audit log
+func Record(gormDB *gorm.DB, action string, actor string, remoteIp string, details map[string]interface{}) error {
+	var detailsJson string
+	if details != nil {
+		detailsBytes, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		detailsJson = string(detailsBytes)
+	}
+
+	recordMutex.Lock()
+	defer recordMutex.Unlock()
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		prevHash := genesisHash
+		var lastAuditLog db.AdminAuditLog
+		result := tx.Order("id DESC").Limit(1).Find(&lastAuditLog)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			prevHash = lastAuditLog.Hash
+		}
+
+		auditLog := &db.AdminAuditLog{
+			Action:   action,
+			Actor:    actor,
+			RemoteIp: remoteIp,
+			Details:  detailsJson,
+			PrevHash: prevHash,
+			// postgres keeps microseconds
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		}
+		hash, err := computeHash(auditLog)
+		if err != nil {
+			return err
+		}
+		auditLog.Hash = hash
+		return tx.Create(auditLog).Error
+	})
+}
+
+// Verify recomputes the chain of hashes
+func Verify(gormDB *gorm.DB) (*Verification, error) {
+	verification := &Verification{Valid: true, HeadHash: genesisHash}
+	err := forEach(gormDB, func(auditLog *db.AdminAuditLog) error {
+		verification.Entries++
+		hash, err := computeHash(auditLog)
+		if err != nil {
+			return err
+		}
+		if verification.Valid && (auditLog.PrevHash != verification.HeadHash || auditLog.Hash != hash) {
+			verification.Valid = false
+			verification.BrokenAtId = auditLog.ID
+		}
+		verification.HeadHash = auditLog.Hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+// Export writes all entries as JSON lines, oldest first, so that the chain can be verified elsewhere
+func Export(gormDB *gorm.DB, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return forEach(gormDB, func(auditLog *db.AdminAuditLog) error {
+		entry := Entry{
+			ID:        auditLog.ID,
+			Action:    auditLog.Action,
+			Actor:     auditLog.Actor,
+			RemoteIp:  auditLog.RemoteIp,
+			PrevHash:  auditLog.PrevHash,
+			Hash:      auditLog.Hash,
+			CreatedAt: auditLog.CreatedAt.UTC(),
+		}
+		if auditLog.Details != "" {
+			entry.Details = json.RawMessage(auditLog.Details)
+		}
+		return encoder.Encode(entry)
+	})
+}
+
+func forEach(gormDB *gorm.DB, fn func(auditLog *db.AdminAuditLog) error) error {
+	var lastId uint
+	for {
+		var auditLogs []db.AdminAuditLog
+		err := gormDB.Where("id > ?", lastId).Order("id").Limit(1000).Find(&auditLogs).Error
+		if err != nil {
+			return fmt.Errorf("failed to read admin audit log: %w", err)
+		}
+		if len(auditLogs) == 0 {
+			return nil
+		}
+		for i := range auditLogs {
+			if err := fn(&auditLogs[i]); err != nil {
+				return err
+			}
+		}
+		lastId = auditLogs[len(auditLogs)-1].ID
+	}
+}
@@ -0,0 +1,69 @@
+package auditlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	testdb "github.com/getAlby/hub/tests/db"
+)
+
+func TestRecordAndVerify(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	gormDB, err := testdb.NewDB(t)
+	require.NoError(t, err)
+	defer testdb.CloseDB(gormDB)
+
+	verification, err := Verify(gormDB)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, genesisHash, verification.HeadHash)
+
+	require.NoError(t, Record(gormDB, "POST /api/unlock", "anonymous", "127.0.0.1", map[string]interface{}{"status": 204}))
+	require.NoError(t, Record(gormDB, "PATCH /api/settings", "owner", "127.0.0.1", nil))
+	require.NoError(t, Record(gormDB, "DELETE /api/apps/:pubkey", "admin_user:2", "10.0.0.1", map[string]interface{}{"status": 204}))
+
+	verification, err = Verify(gormDB)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, int64(3), verification.Entries)
+
+	var buffer bytes.Buffer
+	require.NoError(t, Export(gormDB, &buffer))
+	entries := []Entry{}
+	scanner := bufio.NewScanner(&buffer)
+	for scanner.Scan() {
+		var entry Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 3)
+	assert.Equal(t, genesisHash, entries[0].PrevHash)
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+	assert.Equal(t, verification.HeadHash, entries[2].Hash)
+	assert.JSONEq(t, `{"status":204}`, string(entries[2].Details))
+
+	// entries cannot be changed or removed
+	assert.Error(t, gormDB.Model(&db.AdminAuditLog{}).Where("id = ?", entries[1].ID).Update("actor", "someone").Error)
+	assert.Error(t, gormDB.Delete(&db.AdminAuditLog{}, entries[1].ID).Error)
+
+	// unless the triggers are dropped, which breaks the chain
+	if gormDB.Dialector.Name() == "postgres" {
+		require.NoError(t, gormDB.Exec("DROP TRIGGER admin_audit_logs_append_only ON admin_audit_logs").Error)
+	} else {
+		require.NoError(t, gormDB.Exec("DROP TRIGGER admin_audit_logs_no_update").Error)
+	}
+	require.NoError(t, gormDB.Model(&db.AdminAuditLog{}).Where("id = ?", entries[1].ID).Update("actor", "someone").Error)
+	verification, err = Verify(gormDB)
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+	assert.Equal(t, entries[1].ID, verification.BrokenAtId)
+}
@@ -0,0 +1,100 @@
+// Package autolock locks spending after a period without user activity. Receiving payments
+// keeps working while the hub is locked, spending requires the unlock password again.
+package autolock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/getAlby/hub/logger"
+)
+
+var checkInterval = 10 * time.Second
+
+var (
+	mutex        sync.Mutex
+	timeout      time.Duration
+	lastActivity time.Time
+	locked       bool
+	onLock       func()
+)
+
+// Start locks the hub after the given time without user activity, or when Lock is called.
+// A timeout of 0 disables the automatic lock. onLock is called whenever the hub gets locked,
+// to drop key material which is only needed to spend. The state is reset once ctx is done.
+func Start(ctx context.Context, lockTimeout time.Duration, lockCallback func()) {
+	mutex.Lock()
+	timeout = lockTimeout
+	lastActivity = time.Now()
+	locked = false
+	onLock = lockCallback
+	mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				mutex.Lock()
+				timeout = 0
+				locked = false
+				onLock = nil
+				mutex.Unlock()
+				return
+			case <-ticker.C:
+				lockIfInactive(time.Now())
+			}
+		}
+	}()
+}
+
+// RecordActivity postpones the automatic lock. It does not unlock a locked hub.
+func RecordActivity() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	lastActivity = time.Now()
+}
+
+func IsLocked() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return locked
+}
+
+func Lock() {
+	mutex.Lock()
+	if locked || onLock == nil {
+		mutex.Unlock()
+		return
+	}
+	locked = true
+	callback := onLock
+	mutex.Unlock()
+
+	logger.Logger.Info("Locked spending")
+	callback()
+}
+
+// Unlock resumes spending. The caller has to verify the unlock password first.
+func Unlock() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if locked {
+		logger.Logger.Info("Unlocked spending")
+	}
+	locked = false
+	lastActivity = time.Now()
+}
+
+func lockIfInactive(now time.Time) {
+	mutex.Lock()
+	inactive := timeout > 0 && !locked && now.Sub(lastActivity) >= timeout
+	mutex.Unlock()
+
+	if inactive {
+		logger.Logger.WithField("timeout", timeout).Info("Locking spending after inactivity")
+		Lock()
+	}
+}
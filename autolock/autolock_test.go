@@ -0,0 +1,55 @@
+package autolock
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/logger"
+)
+
+func TestAutoLock(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	lockCount := 0
+	Start(ctx, time.Minute, func() { lockCount++ })
+	assert.False(t, IsLocked())
+
+	lockIfInactive(time.Now().Add(30 * time.Second))
+	assert.False(t, IsLocked())
+
+	RecordActivity()
+	lockIfInactive(time.Now().Add(time.Minute))
+	assert.True(t, IsLocked())
+	assert.Equal(t, 1, lockCount)
+
+	// activity does not unlock
+	RecordActivity()
+	Lock()
+	assert.True(t, IsLocked())
+	assert.Equal(t, 1, lockCount)
+
+	Unlock()
+	assert.False(t, IsLocked())
+	Lock()
+	assert.True(t, IsLocked())
+	assert.Equal(t, 2, lockCount)
+
+	cancel()
+	assert.Eventually(t, func() bool { return !IsLocked() }, time.Second, 10*time.Millisecond)
+}
+
+func TestAutoLock_Disabled(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	Start(ctx, 0, func() {})
+	lockIfInactive(time.Now().Add(24 * time.Hour))
+	assert.False(t, IsLocked())
+}
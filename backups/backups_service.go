@@ -0,0 +1,412 @@
+package backups
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/logger"
+)
+
+const snapshotTimeFormat = "2006-01-02T15-04-05Z"
+
+type Snapshot struct {
+	Key       string
+	Size      int64
+	CreatedAt time.Time
+}
+
+type CreateBackupTargetParams struct {
+	Name            string
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyId     string
+	SecretAccessKey string
+	IntervalHours   uint
+	KeepLast        uint
+	KeepDays        uint
+}
+
+type UpdateBackupTargetParams struct {
+	Enabled       *bool
+	IntervalHours *uint
+	KeepLast      *uint
+	KeepDays      *uint
+}
+
+type BackupsService interface {
+	CreateBackupTarget(params *CreateBackupTargetParams) (*db.BackupTarget, error)
+	UpdateBackupTarget(id uint, params *UpdateBackupTargetParams) (*db.BackupTarget, error)
+	ListBackupTargets() ([]db.BackupTarget, error)
+	DeleteBackupTarget(id uint) error
+	ListSnapshots(ctx context.Context, id uint) ([]Snapshot, error)
+	// RunBackup uploads, verifies and prunes a snapshot encrypted with the unlock password
+	RunBackup(ctx context.Context, id uint, unlockPassword string) (*Snapshot, error)
+	Start(ctx context.Context, backupKey *BackupKey)
+}
+
+type backupsService struct {
+	db             *gorm.DB
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+}
+
+func NewBackupsService(db *gorm.DB, cfg config.Config, eventPublisher events.EventPublisher) *backupsService {
+	return &backupsService{
+		db:             db,
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (svc *backupsService) CreateBackupTarget(params *CreateBackupTargetParams) (*db.BackupTarget, error) {
+	endpoint, err := url.Parse(params.Endpoint)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint: %q", params.Endpoint)
+	}
+	if params.Bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	if params.AccessKeyId == "" || params.SecretAccessKey == "" {
+		return nil, errors.New("access key id and secret access key are required")
+	}
+	intervalHours := params.IntervalHours
+	if intervalHours == 0 {
+		intervalHours = 24
+	}
+	name := params.Name
+	if name == "" {
+		name = endpoint.Host + "/" + params.Bucket
+	}
+
+	backupTarget := db.BackupTarget{
+		Name:            name,
+		Endpoint:        params.Endpoint,
+		Region:          params.Region,
+		Bucket:          params.Bucket,
+		Prefix:          strings.Trim(params.Prefix, "/"),
+		AccessKeyId:     params.AccessKeyId,
+		SecretAccessKey: params.SecretAccessKey,
+		IntervalHours:   intervalHours,
+		KeepLast:        params.KeepLast,
+		KeepDays:        params.KeepDays,
+		Enabled:         true,
+	}
+	if err := svc.db.Create(&backupTarget).Error; err != nil {
+		return nil, err
+	}
+	return &backupTarget, nil
+}
+
+func (svc *backupsService) UpdateBackupTarget(id uint, params *UpdateBackupTargetParams) (*db.BackupTarget, error) {
+	backupTarget, err := svc.getBackupTarget(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if params.Enabled != nil {
+		updates["enabled"] = *params.Enabled
+	}
+	if params.IntervalHours != nil {
+		if *params.IntervalHours == 0 {
+			return nil, errors.New("interval must be at least one hour")
+		}
+		updates["interval_hours"] = *params.IntervalHours
+	}
+	if params.KeepLast != nil {
+		updates["keep_last"] = *params.KeepLast
+	}
+	if params.KeepDays != nil {
+		updates["keep_days"] = *params.KeepDays
+	}
+	if len(updates) > 0 {
+		if err := svc.db.Model(backupTarget).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return backupTarget, nil
+}
+
+func (svc *backupsService) ListBackupTargets() ([]db.BackupTarget, error) {
+	backupTargets := []db.BackupTarget{}
+	if err := svc.db.Order("id").Find(&backupTargets).Error; err != nil {
+		return nil, err
+	}
+	return backupTargets, nil
+}
+
+func (svc *backupsService) DeleteBackupTarget(id uint) error {
+	result := svc.db.Delete(&db.BackupTarget{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("backup target not found")
+	}
+	return nil
+}
+
+func (svc *backupsService) getBackupTarget(id uint) (*db.BackupTarget, error) {
+	var backupTarget db.BackupTarget
+	if svc.db.Limit(1).Find(&backupTarget, &db.BackupTarget{ID: id}).RowsAffected == 0 {
+		return nil, errors.New("backup target not found")
+	}
+	return &backupTarget, nil
+}
+
+func newClientForTarget(backupTarget *db.BackupTarget) *s3Client {
+	return newS3Client(backupTarget.Endpoint, backupTarget.Region, backupTarget.Bucket, backupTarget.AccessKeyId, backupTarget.SecretAccessKey)
+}
+
+func snapshotPrefix(backupTarget *db.BackupTarget) string {
+	if backupTarget.Prefix == "" {
+		return "albyhub-"
+	}
+	return backupTarget.Prefix + "/albyhub-"
+}
+
+// ListSnapshots returns the snapshots of a target, newest first
+func (svc *backupsService) ListSnapshots(ctx context.Context, id uint) ([]Snapshot, error) {
+	backupTarget, err := svc.getBackupTarget(id)
+	if err != nil {
+		return nil, err
+	}
+	return listSnapshots(ctx, newClientForTarget(backupTarget), backupTarget)
+}
+
+func listSnapshots(ctx context.Context, client *s3Client, backupTarget *db.BackupTarget) ([]Snapshot, error) {
+	prefix := snapshotPrefix(backupTarget)
+	objects, err := client.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, prefix)
+		if !strings.HasSuffix(name, snapshotExtension) || strings.Contains(name, "/") {
+			continue
+		}
+		createdAt, err := time.Parse(snapshotTimeFormat, strings.TrimSuffix(name, snapshotExtension))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			Key:       object.Key,
+			Size:      object.Size,
+			CreatedAt: createdAt,
+		})
+	}
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return snapshots, nil
+}
+
+func (svc *backupsService) RunBackup(ctx context.Context, id uint, unlockPassword string) (*Snapshot, error) {
+	if !svc.cfg.CheckUnlockPassword(unlockPassword) {
+		return nil, errors.New("invalid unlock password")
+	}
+	backupTarget, err := svc.getBackupTarget(id)
+	if err != nil {
+		return nil, err
+	}
+	backupKey, err := GetBackupKey(svc.cfg, unlockPassword)
+	if err != nil {
+		return nil, err
+	}
+	return svc.runBackup(ctx, backupTarget, backupKey)
+}
+
+func (svc *backupsService) runBackup(ctx context.Context, backupTarget *db.BackupTarget, backupKey *BackupKey) (*Snapshot, error) {
+	logger.Logger.WithFields(logrus.Fields{
+		"backup_target_id": backupTarget.ID,
+		"name":             backupTarget.Name,
+	}).Info("Creating backup snapshot")
+
+	now := time.Now().UTC()
+	snapshot, verifiedAt, err := svc.uploadSnapshot(ctx, backupTarget, backupKey, now)
+
+	updates := map[string]interface{}{
+		"last_backup_at": &now,
+		"last_error":     "",
+	}
+	if err != nil {
+		logger.Logger.WithField("backup_target_id", backupTarget.ID).WithError(err).Error("Backup failed")
+		updates["last_error"] = err.Error()
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_backup_failed",
+			Properties: map[string]interface{}{
+				"backup_target_id": backupTarget.ID,
+				"name":             backupTarget.Name,
+				"error":            err.Error(),
+			},
+		})
+	} else {
+		updates["last_verified_at"] = verifiedAt
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_backup_succeeded",
+			Properties: map[string]interface{}{
+				"backup_target_id": backupTarget.ID,
+				"name":             backupTarget.Name,
+				"key":              snapshot.Key,
+				"size":             snapshot.Size,
+			},
+		})
+	}
+	if dbErr := svc.db.Model(backupTarget).Updates(updates).Error; dbErr != nil {
+		logger.Logger.WithField("backup_target_id", backupTarget.ID).WithError(dbErr).Error("Failed to save backup result")
+	}
+	return snapshot, err
+}
+
+func (svc *backupsService) uploadSnapshot(ctx context.Context, backupTarget *db.BackupTarget, backupKey *BackupKey, now time.Time) (*Snapshot, *time.Time, error) {
+	workDir := svc.cfg.GetEnv().Workdir
+	unlockPasswordCheck, err := svc.cfg.Get("UnlockPasswordCheck", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshotFile, err := os.CreateTemp(workDir, "snapshot-*"+snapshotExtension)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(snapshotFile.Name())
+	defer snapshotFile.Close()
+
+	hash := sha256.New()
+	if err := writeSnapshot(svc.db, workDir, backupKey, io.MultiWriter(snapshotFile, hash)); err != nil {
+		return nil, nil, err
+	}
+	size, err := snapshotFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := snapshotFile.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	client := newClientForTarget(backupTarget)
+	snapshot := &Snapshot{
+		Key:       snapshotPrefix(backupTarget) + now.Format(snapshotTimeFormat) + snapshotExtension,
+		Size:      size,
+		CreatedAt: now,
+	}
+	if err := client.PutObject(ctx, snapshot.Key, snapshotFile, size, hex.EncodeToString(hash.Sum(nil))); err != nil {
+		return nil, nil, fmt.Errorf("failed to upload snapshot: %w", err)
+	}
+
+	// download the snapshot again to make sure it can be restored
+	uploadedSnapshot, err := client.GetObject(ctx, snapshot.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download snapshot for verification: %w", err)
+	}
+	defer uploadedSnapshot.Close()
+	if err := verifySnapshot(uploadedSnapshot, backupKey, unlockPasswordCheck, workDir); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify snapshot: %w", err)
+	}
+	verifiedAt := time.Now()
+
+	logger.Logger.WithFields(logrus.Fields{
+		"backup_target_id": backupTarget.ID,
+		"key":              snapshot.Key,
+		"size":             size,
+	}).Info("Uploaded and verified backup snapshot")
+
+	if err := pruneSnapshots(ctx, client, backupTarget, now); err != nil {
+		// the new snapshot is fine, old ones are removed on the next run
+		logger.Logger.WithField("backup_target_id", backupTarget.ID).WithError(err).Error("Failed to remove old snapshots")
+	}
+	return snapshot, &verifiedAt, nil
+}
+
+// pruneSnapshots removes the snapshots outside of the retention rules of the target.
+// The newest snapshot is always kept.
+func pruneSnapshots(ctx context.Context, client *s3Client, backupTarget *db.BackupTarget, now time.Time) error {
+	snapshots, err := listSnapshots(ctx, client, backupTarget)
+	if err != nil {
+		return err
+	}
+	for i, snapshot := range snapshots {
+		if i == 0 {
+			continue
+		}
+		tooMany := backupTarget.KeepLast > 0 && uint(i) >= backupTarget.KeepLast
+		tooOld := backupTarget.KeepDays > 0 && now.Sub(snapshot.CreatedAt) > time.Duration(backupTarget.KeepDays)*24*time.Hour
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := client.DeleteObject(ctx, snapshot.Key); err != nil {
+			return err
+		}
+		logger.Logger.WithField("key", snapshot.Key).Info("Removed old backup snapshot")
+	}
+	return nil
+}
+
+func isDue(backupTarget *db.BackupTarget, now time.Time) bool {
+	return backupTarget.Enabled && (backupTarget.LastBackupAt == nil ||
+		!now.Before(backupTarget.LastBackupAt.Add(time.Duration(backupTarget.IntervalHours)*time.Hour)))
+}
+
+// Start checks for due backups every minute until the context is cancelled. Snapshots are
+// encrypted with the backup key, which is derived from the unlock password when the hub is unlocked.
+func (svc *backupsService) Start(ctx context.Context, backupKey *BackupKey) {
+	// static channel backups are uploaded as soon as the node publishes them
+	channelBackupUploader := NewChannelBackupUploader(svc.db, svc.cfg, backupKey)
+	svc.eventPublisher.RegisterSubscriber(channelBackupUploader)
+
+	health.RegisterJob("backups", time.Minute)
+	go func() {
+		defer health.RemoveJob("backups")
+		defer svc.eventPublisher.RemoveSubscriber(channelBackupUploader)
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				health.ReportJobRun("backups", svc.processDueBackups(ctx, backupKey))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// processDueBackups only returns an error if the targets could not be loaded,
+// failed backups are recorded on the target itself
+func (svc *backupsService) processDueBackups(ctx context.Context, backupKey *BackupKey) error {
+	backupTargets, err := svc.ListBackupTargets()
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list backup targets")
+		return err
+	}
+
+	now := time.Now()
+	for _, backupTarget := range backupTargets {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if isDue(&backupTarget, now) {
+			svc.runBackup(ctx, &backupTarget, backupKey)
+		}
+	}
+	return nil
+}
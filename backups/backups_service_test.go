@@ -0,0 +1,225 @@
+package backups
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+const unlockPassword = "123"
+
+// fakeS3 keeps the objects of a single bucket in memory
+type fakeS3 struct {
+	mtx     sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (fake *fakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	fake.mtx.Lock()
+	defer fake.mtx.Unlock()
+
+	if fake.fail || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<Error><Code>AccessDenied</Code></Error>"))
+		return
+	}
+
+	key, _ := strings.CutPrefix(r.URL.Path, "/bucket")
+	key = strings.TrimPrefix(key, "/")
+	switch {
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		hash := sha256.Sum256(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fake.objects[key] = body
+	case r.Method == http.MethodGet && key == "":
+		response := listObjectsResponse{}
+		for objectKey, body := range fake.objects {
+			if strings.HasPrefix(objectKey, r.URL.Query().Get("prefix")) {
+				response.Contents = append(response.Contents, s3Object{Key: objectKey, Size: int64(len(body))})
+			}
+		}
+		xml.NewEncoder(w).Encode(response)
+	case r.Method == http.MethodGet:
+		body, ok := fake.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case r.Method == http.MethodDelete:
+		delete(fake.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (fake *fakeS3) keys() []string {
+	fake.mtx.Lock()
+	defer fake.mtx.Unlock()
+	keys := []string{}
+	for key := range fake.objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func createTestBackupsService(t *testing.T) (*tests.TestService, *backupsService) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	t.Cleanup(svc.Remove)
+	if svc.DB.Dialector.Name() != "sqlite" {
+		t.Skip("snapshots are only supported with sqlite")
+	}
+	require.NoError(t, svc.Cfg.SaveUnlockPasswordCheck(unlockPassword))
+	svc.Cfg.GetEnv().Workdir = t.TempDir()
+	return svc, NewBackupsService(svc.DB, svc.Cfg, svc.EventPublisher)
+}
+
+func TestCreateBackupTarget_Invalid(t *testing.T) {
+	_, backupsSvc := createTestBackupsService(t)
+
+	_, err := backupsSvc.CreateBackupTarget(&CreateBackupTargetParams{Endpoint: "s3.example.com", Bucket: "bucket", AccessKeyId: "access", SecretAccessKey: "secret"})
+	assert.Error(t, err)
+	_, err = backupsSvc.CreateBackupTarget(&CreateBackupTargetParams{Endpoint: "https://s3.example.com", AccessKeyId: "access", SecretAccessKey: "secret"})
+	assert.EqualError(t, err, "bucket is required")
+	_, err = backupsSvc.CreateBackupTarget(&CreateBackupTargetParams{Endpoint: "https://s3.example.com", Bucket: "bucket"})
+	assert.Error(t, err)
+}
+
+func TestRunBackup(t *testing.T) {
+	svc, backupsSvc := createTestBackupsService(t)
+	fake, server := newFakeS3(t)
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	backupTarget, err := backupsSvc.CreateBackupTarget(&CreateBackupTargetParams{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		Prefix:          "/hub/",
+		AccessKeyId:     "access",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(24), backupTarget.IntervalHours)
+	assert.Equal(t, "hub", backupTarget.Prefix)
+
+	_, err = backupsSvc.RunBackup(context.TODO(), backupTarget.ID, "wrong")
+	assert.EqualError(t, err, "invalid unlock password")
+
+	snapshot, err := backupsSvc.RunBackup(context.TODO(), backupTarget.ID, unlockPassword)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(snapshot.Key, "hub/albyhub-"))
+	assert.Equal(t, []string{snapshot.Key}, fake.keys())
+
+	snapshots, err := backupsSvc.ListSnapshots(context.TODO(), backupTarget.ID)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, snapshot.Key, snapshots[0].Key)
+	assert.Equal(t, snapshot.Size, snapshots[0].Size)
+
+	backupTarget, err = backupsSvc.getBackupTarget(backupTarget.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, backupTarget.LastBackupAt)
+	assert.NotNil(t, backupTarget.LastVerifiedAt)
+	assert.Empty(t, backupTarget.LastError)
+	assert.False(t, isDue(backupTarget, time.Now()))
+	assert.True(t, isDue(backupTarget, time.Now().Add(25*time.Hour)))
+
+	time.Sleep(10 * time.Millisecond)
+	consumedEvents := mockEventConsumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 1)
+	assert.Equal(t, "nwc_backup_succeeded", consumedEvents[0].Event)
+
+	// the snapshot cannot be verified with another password
+	fake.mtx.Lock()
+	uploadedSnapshot := fake.objects[snapshot.Key]
+	fake.mtx.Unlock()
+	unlockPasswordCheck, err := svc.Cfg.Get("UnlockPasswordCheck", "")
+	require.NoError(t, err)
+	backupKey, err := GetBackupKey(svc.Cfg, unlockPassword)
+	require.NoError(t, err)
+	assert.NoError(t, verifySnapshot(strings.NewReader(string(uploadedSnapshot)), backupKey, unlockPasswordCheck, t.TempDir()))
+	wrongBackupKey, err := GetBackupKey(svc.Cfg, "wrong")
+	require.NoError(t, err)
+	assert.Error(t, verifySnapshot(strings.NewReader(string(uploadedSnapshot)), wrongBackupKey, unlockPasswordCheck, t.TempDir()))
+}
+
+func TestRunBackup_UploadFails(t *testing.T) {
+	_, backupsSvc := createTestBackupsService(t)
+	fake, server := newFakeS3(t)
+	fake.fail = true
+
+	backupTarget, err := backupsSvc.CreateBackupTarget(&CreateBackupTargetParams{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		AccessKeyId:     "access",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+
+	_, err = backupsSvc.RunBackup(context.TODO(), backupTarget.ID, unlockPassword)
+	require.Error(t, err)
+
+	backupTarget, err = backupsSvc.getBackupTarget(backupTarget.ID)
+	require.NoError(t, err)
+	assert.Nil(t, backupTarget.LastVerifiedAt)
+	assert.Contains(t, backupTarget.LastError, "AccessDenied")
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	fake, server := newFakeS3(t)
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	for _, age := range []time.Duration{0, time.Hour, 3 * 24 * time.Hour, 10 * 24 * time.Hour} {
+		fake.objects["albyhub-"+now.Add(-age).Format(snapshotTimeFormat)+snapshotExtension] = []byte("snapshot")
+	}
+	// unrelated objects are never removed
+	fake.objects["notes.txt"] = []byte("notes")
+
+	client := newS3Client(server.URL, "", "bucket", "access", "secret")
+
+	backupTarget := &db.BackupTarget{KeepDays: 7}
+	require.NoError(t, pruneSnapshots(context.TODO(), client, backupTarget, now))
+	assert.Equal(t, []string{
+		"albyhub-2024-03-07T12-00-00Z.bkp",
+		"albyhub-2024-03-10T11-00-00Z.bkp",
+		"albyhub-2024-03-10T12-00-00Z.bkp",
+		"notes.txt",
+	}, fake.keys())
+
+	backupTarget = &db.BackupTarget{KeepLast: 1}
+	require.NoError(t, pruneSnapshots(context.TODO(), client, backupTarget, now))
+	assert.Equal(t, []string{"albyhub-2024-03-10T12-00-00Z.bkp", "notes.txt"}, fake.keys())
+
+	// the newest snapshot is kept even if it is too old
+	backupTarget = &db.BackupTarget{KeepDays: 1}
+	require.NoError(t, pruneSnapshots(context.TODO(), client, backupTarget, now.Add(30*24*time.Hour)))
+	assert.Equal(t, []string{"albyhub-2024-03-10T12-00-00Z.bkp", "notes.txt"}, fake.keys())
+}
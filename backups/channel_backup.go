@@ -0,0 +1,165 @@
+package backups
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	// LDK writes its static channel backups itself, the ones of LND are written by the hub
+	ldkStaticChannelBackupDir = "ldk/static_channel_backups"
+	lndStaticChannelBackupDir = "lnd/static_channel_backups"
+	// the latest static channel backup is kept next to the snapshots of each target
+	channelBackupObjectName = "albyhub-channels.scb"
+	channelBackupTimeFormat = "2006-01-02T15-04-05"
+)
+
+// latestStaticChannelBackup returns the path of the newest static channel backup in the
+// work dir and its path relative to the work dir, or empty strings if there is none
+func latestStaticChannelBackup(workDir string) (string, string, error) {
+	latestPath, latestRelPath := "", ""
+	for _, dir := range []string{ldkStaticChannelBackupDir, lndStaticChannelBackupDir} {
+		channelBackups, err := filepath.Glob(filepath.Join(workDir, filepath.FromSlash(dir), "*.json"))
+		if err != nil {
+			return "", "", err
+		}
+		for _, channelBackup := range channelBackups {
+			// the file names are timestamps
+			if latestPath == "" || filepath.Base(channelBackup) > filepath.Base(latestPath) {
+				latestPath = channelBackup
+				latestRelPath = dir + "/" + filepath.Base(channelBackup)
+			}
+		}
+	}
+	return latestPath, latestRelPath, nil
+}
+
+// SetLastChannelBackupAt records when the static channel backup was last stored off-site
+func SetLastChannelBackupAt(cfg config.Config, at time.Time) {
+	err := cfg.SetUpdate(config.LastChannelBackupAtKey, at.UTC().Format(time.RFC3339), "")
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to save time of last channel backup")
+	}
+}
+
+// GetLastChannelBackupAt returns when the static channel backup was last stored off-site
+func GetLastChannelBackupAt(cfg config.Config) *time.Time {
+	value, _ := cfg.Get(config.LastChannelBackupAtKey, "")
+	if value == "" {
+		return nil
+	}
+	lastChannelBackupAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &lastChannelBackupAt
+}
+
+type channelBackupUploader struct {
+	db        *gorm.DB
+	cfg       config.Config
+	backupKey *BackupKey
+}
+
+// NewChannelBackupUploader returns a subscriber which uploads every static channel backup published
+// by the node to the backup targets. The backups are encrypted with the backup key.
+func NewChannelBackupUploader(db *gorm.DB, cfg config.Config, backupKey *BackupKey) *channelBackupUploader {
+	return &channelBackupUploader{
+		db:        db,
+		cfg:       cfg,
+		backupKey: backupKey,
+	}
+}
+
+func (uploader *channelBackupUploader) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	if event.Event != "nwc_backup_channels" {
+		return
+	}
+	channelBackup, ok := event.Properties.(*events.StaticChannelsBackupEvent)
+	if !ok {
+		logger.Logger.WithField("event", event).Error("Invalid nwc_backup_channels event properties")
+		return
+	}
+	payload, err := json.Marshal(channelBackup)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to serialize static channel backup")
+		return
+	}
+
+	if channelBackup.LNDMultiChanBackup != nil {
+		// keep a copy in the work dir, so that it is part of snapshots and the nostr backup
+		if err := saveChannelBackup(uploader.cfg.GetEnv().Workdir, lndStaticChannelBackupDir, payload); err != nil {
+			logger.Logger.WithError(err).Error("Failed to save static channel backup to disk")
+		}
+	}
+
+	if err := uploader.upload(ctx, payload); err != nil {
+		logger.Logger.WithError(err).Error("Failed to upload static channel backup")
+	}
+}
+
+func saveChannelBackup(workDir string, dir string, payload []byte) error {
+	backupDirectory := filepath.Join(workDir, filepath.FromSlash(dir))
+	if err := os.MkdirAll(backupDirectory, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(backupDirectory, time.Now().Format(channelBackupTimeFormat)+".json"), payload, 0600)
+}
+
+// upload stores the backup on all enabled targets, it only records the time of the
+// backup if every target received it
+func (uploader *channelBackupUploader) upload(ctx context.Context, payload []byte) error {
+	var backupTargets []db.BackupTarget
+	if err := uploader.db.Where("enabled = ?", true).Find(&backupTargets).Error; err != nil {
+		return err
+	}
+	if len(backupTargets) == 0 {
+		return nil
+	}
+
+	var encrypted bytes.Buffer
+	encryptingWriter, err := EncryptingWriterWithKey(&encrypted, uploader.backupKey)
+	if err != nil {
+		return err
+	}
+	if _, err := encryptingWriter.Write(payload); err != nil {
+		return err
+	}
+	hash := sha256.Sum256(encrypted.Bytes())
+
+	var uploadErrors []error
+	for _, backupTarget := range backupTargets {
+		key := channelBackupObjectName
+		if backupTarget.Prefix != "" {
+			key = path.Join(backupTarget.Prefix, channelBackupObjectName)
+		}
+		err := newClientForTarget(&backupTarget).PutObject(ctx, key, bytes.NewReader(encrypted.Bytes()), int64(encrypted.Len()), hex.EncodeToString(hash[:]))
+		if err != nil {
+			logger.Logger.WithField("backup_target_id", backupTarget.ID).WithError(err).Error("Failed to upload static channel backup")
+			uploadErrors = append(uploadErrors, err)
+			continue
+		}
+		logger.Logger.WithField("backup_target_id", backupTarget.ID).WithField("key", key).Info("Uploaded static channel backup")
+	}
+	if len(uploadErrors) > 0 {
+		return errors.Join(uploadErrors...)
+	}
+
+	SetLastChannelBackupAt(uploader.cfg, time.Now())
+	return nil
+}
@@ -0,0 +1,92 @@
+package backups
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/events"
+)
+
+func TestChannelBackupUploader(t *testing.T) {
+	svc, backupsSvc := createTestBackupsService(t)
+	fake, server := newFakeS3(t)
+
+	_, err := backupsSvc.CreateBackupTarget(&CreateBackupTargetParams{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		Prefix:          "hub",
+		AccessKeyId:     "access",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, GetLastChannelBackupAt(svc.Cfg))
+
+	backupKey, err := GetBackupKey(svc.Cfg, unlockPassword)
+	require.NoError(t, err)
+	uploader := NewChannelBackupUploader(svc.DB, svc.Cfg, backupKey)
+	uploader.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_backup_channels",
+		Properties: &events.StaticChannelsBackupEvent{
+			NodeID:             "node",
+			Channels:           []events.ChannelBackup{{ChannelID: "channel"}},
+			LNDMultiChanBackup: []byte("multi"),
+		},
+	}, nil)
+
+	assert.Equal(t, []string{"hub/" + channelBackupObjectName}, fake.keys())
+	assert.NotNil(t, GetLastChannelBackupAt(svc.Cfg))
+
+	fake.mtx.Lock()
+	uploaded := fake.objects["hub/"+channelBackupObjectName]
+	fake.mtx.Unlock()
+	decryptedReader, err := DecryptingReader(bytes.NewReader(uploaded), unlockPassword)
+	require.NoError(t, err)
+	payload, err := io.ReadAll(decryptedReader)
+	require.NoError(t, err)
+	var channelBackup events.StaticChannelsBackupEvent
+	require.NoError(t, json.Unmarshal(payload, &channelBackup))
+	assert.Equal(t, "node", channelBackup.NodeID)
+	assert.Equal(t, []byte("multi"), channelBackup.LNDMultiChanBackup)
+
+	// LND backups are also kept in the work dir for snapshots
+	channelBackupPath, zipPath, err := latestStaticChannelBackup(svc.Cfg.GetEnv().Workdir)
+	require.NoError(t, err)
+	assert.Contains(t, zipPath, lndStaticChannelBackupDir+"/")
+	saved, err := os.ReadFile(channelBackupPath)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(payload), string(saved))
+}
+
+func TestChannelBackupUploader_UploadFails(t *testing.T) {
+	svc, backupsSvc := createTestBackupsService(t)
+	fake, server := newFakeS3(t)
+	fake.fail = true
+
+	_, err := backupsSvc.CreateBackupTarget(&CreateBackupTargetParams{
+		Endpoint:        server.URL,
+		Bucket:          "bucket",
+		AccessKeyId:     "access",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+
+	backupKey, err := GetBackupKey(svc.Cfg, unlockPassword)
+	require.NoError(t, err)
+	uploader := NewChannelBackupUploader(svc.DB, svc.Cfg, backupKey)
+	uploader.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_backup_channels",
+		Properties: &events.StaticChannelsBackupEvent{NodeID: "node"},
+	}, nil)
+
+	assert.Nil(t, GetLastChannelBackupAt(svc.Cfg))
+	_, err = os.Stat(filepath.Join(svc.Cfg.GetEnv().Workdir, filepath.FromSlash(lndStaticChannelBackupDir)))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,141 @@
+package backups
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/getAlby/hub/config"
+)
+
+// backupKeySaltKey is the salt of the backup key. It is not secret, but fixed so that the same
+// key is derived from the unlock password every time the hub is unlocked.
+const backupKeySaltKey = "BackupKeySalt"
+
+// BackupKey is the key which scheduled backups are encrypted with. It is derived from the unlock
+// password like the key of EncryptingWriter, so the backups can be decrypted with the unlock
+// password, and is kept in memory instead of the unlock password.
+type BackupKey struct {
+	salt []byte
+	key  []byte
+}
+
+func deriveBackupKey(password string, salt []byte) *BackupKey {
+	return &BackupKey{
+		salt: salt,
+		key:  pbkdf2.Key([]byte(password), salt, 4096, 32, sha256.New),
+	}
+}
+
+// GetBackupKey derives the backup key from the unlock password. The salt is created on first use.
+func GetBackupKey(cfg config.Config, unlockPassword string) (*BackupKey, error) {
+	saltHex, err := cfg.Get(backupKeySaltKey, "")
+	if err != nil {
+		return nil, err
+	}
+	if saltHex == "" {
+		salt := make([]byte, 8)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		saltHex = hex.EncodeToString(salt)
+		if err := cfg.SetUpdate(backupKeySaltKey, saltHex, ""); err != nil {
+			return nil, err
+		}
+	}
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return nil, err
+	}
+	return deriveBackupKey(unlockPassword, salt), nil
+}
+
+// EncryptingWriter encrypts everything written to w with a key derived from the password
+func EncryptingWriter(w io.Writer, password string) (io.Writer, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return EncryptingWriterWithKey(w, deriveBackupKey(password, salt))
+}
+
+// EncryptingWriterWithKey encrypts everything written to w with the key, the output can be
+// decrypted with DecryptingReader and the password the key was derived from
+func EncryptingWriterWithKey(w io.Writer, backupKey *BackupKey) (io.Writer, error) {
+	block, err := aes.NewCipher(backupKey.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err = rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("failed to generate IV: %w", err)
+	}
+
+	_, err = w.Write(backupKey.salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write salt: %w", err)
+	}
+
+	_, err = w.Write(iv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write IV: %w", err)
+	}
+
+	stream := cipher.NewOFB(block, iv)
+	cw := &cipher.StreamWriter{
+		S: stream,
+		W: w,
+	}
+
+	return cw, nil
+}
+
+// DecryptingReader decrypts the output of EncryptingWriter
+func DecryptingReader(r io.Reader, password string) (io.Reader, error) {
+	salt := make([]byte, 8)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, fmt.Errorf("failed to read salt: %w", err)
+	}
+	return decryptingReader(r, deriveBackupKey(password, salt))
+}
+
+// DecryptingReaderWithKey decrypts the output of EncryptingWriterWithKey
+func DecryptingReaderWithKey(r io.Reader, backupKey *BackupKey) (io.Reader, error) {
+	salt := make([]byte, 8)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, fmt.Errorf("failed to read salt: %w", err)
+	}
+	if !bytes.Equal(salt, backupKey.salt) {
+		return nil, errors.New("backup was encrypted with another key")
+	}
+	return decryptingReader(r, backupKey)
+}
+
+func decryptingReader(r io.Reader, backupKey *BackupKey) (io.Reader, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(r, iv); err != nil {
+		return nil, fmt.Errorf("failed to read IV: %w", err)
+	}
+
+	block, err := aes.NewCipher(backupKey.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	stream := cipher.NewOFB(block, iv)
+	cr := &cipher.StreamReader{
+		S: stream,
+		R: r,
+	}
+
+	return cr, nil
+}
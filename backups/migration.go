@@ -0,0 +1,196 @@
+package backups
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/getAlby/hub/logger"
+)
+
+// A migration backup contains a manifest with the hashes of all other files, which is
+// checked before anything is restored. Backups of older versions have no manifest.
+const (
+	MigrationManifestName = "manifest.json"
+	migrationVersion      = 1
+	// written to the work dir of a hub after its migration backup was created,
+	// the hub refuses to start its node while the file exists
+	MigrationMarkerName = "MIGRATED"
+)
+
+type MigrationManifest struct {
+	Version     int               `json:"version"`
+	MigrationId string            `json:"migrationId"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	HubVersion  string            `json:"hubVersion"`
+	BackendType string            `json:"backendType"`
+	Files       map[string]string `json:"files"` // zip path => hex encoded sha256
+}
+
+type MigrationMarker struct {
+	MigrationId string    `json:"migrationId"`
+	MigratedAt  time.Time `json:"migratedAt"`
+}
+
+// MigrationWriter writes the files of a migration backup and records their hashes
+type MigrationWriter struct {
+	zipWriter *zip.Writer
+	manifest  *MigrationManifest
+}
+
+func NewMigrationWriter(w io.Writer, manifest *MigrationManifest) *MigrationWriter {
+	manifest.Version = migrationVersion
+	manifest.Files = map[string]string{}
+	return &MigrationWriter{
+		zipWriter: zip.NewWriter(w),
+		manifest:  manifest,
+	}
+}
+
+func (migrationWriter *MigrationWriter) AddFile(fsPath, zipPath string) error {
+	if zipPath == MigrationManifestName {
+		return fmt.Errorf("%s is reserved for the manifest", MigrationManifestName)
+	}
+	file, err := os.Open(fsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open source file for reading: %w", err)
+	}
+	defer file.Close()
+
+	zipEntry, err := migrationWriter.zipWriter.Create(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to create zip entry: %w", err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(zipEntry, hash), file); err != nil {
+		return err
+	}
+	migrationWriter.manifest.Files[zipPath] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// Close adds the manifest and finishes the archive
+func (migrationWriter *MigrationWriter) Close() error {
+	zipEntry, err := migrationWriter.zipWriter.Create(MigrationManifestName)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	if err := json.NewEncoder(zipEntry).Encode(migrationWriter.manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return migrationWriter.zipWriter.Close()
+}
+
+// VerifyMigrationArchive checks the files against the manifest and the database against the
+// unlock password. It returns nil without an error for backups of older versions, which have
+// no manifest but whose database could still be verified.
+func VerifyMigrationArchive(zipReader *zip.Reader, password string, tmpDir string) (*MigrationManifest, error) {
+	return verifyMigrationArchive(zipReader, checkPassword(password), tmpDir)
+}
+
+func verifyMigrationArchive(zipReader *zip.Reader, checkPasswordCheck passwordCheck, tmpDir string) (*MigrationManifest, error) {
+	manifestFile, err := zipReader.Open(MigrationManifestName)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var manifest *MigrationManifest
+	if manifestFile != nil {
+		defer manifestFile.Close()
+		manifest = &MigrationManifest{}
+		if err := json.NewDecoder(manifestFile).Decode(manifest); err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		if manifest.Version > migrationVersion {
+			return nil, fmt.Errorf("backup was created by a newer version of Alby Hub (%s), please update first", manifest.HubVersion)
+		}
+		if err := verifyManifestFiles(zipReader, manifest); err != nil {
+			return nil, err
+		}
+	} else {
+		logger.Logger.Warn("Migration backup has no manifest, only the database is verified")
+	}
+
+	if err := verifyZipDatabase(zipReader, checkPasswordCheck, tmpDir); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func verifyManifestFiles(zipReader *zip.Reader, manifest *MigrationManifest) error {
+	seen := map[string]bool{}
+	for _, zipFile := range zipReader.File {
+		if zipFile.Name == MigrationManifestName {
+			continue
+		}
+		// reject entries which would be extracted outside of the restore directory
+		if !filepath.IsLocal(filepath.FromSlash(zipFile.Name)) || path.Clean(zipFile.Name) != zipFile.Name {
+			return fmt.Errorf("backup contains an invalid path: %q", zipFile.Name)
+		}
+		expectedHash, ok := manifest.Files[zipFile.Name]
+		if !ok {
+			return fmt.Errorf("backup contains a file which is not in the manifest: %s", zipFile.Name)
+		}
+
+		file, err := zipFile.Open()
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", zipFile.Name, err)
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != expectedHash {
+			return fmt.Errorf("backup is corrupt: %s does not match the manifest", zipFile.Name)
+		}
+		seen[zipFile.Name] = true
+	}
+
+	for name := range manifest.Files {
+		if !seen[name] {
+			return fmt.Errorf("backup is incomplete: %s is missing", name)
+		}
+	}
+	return nil
+}
+
+// WriteMigrationMarker marks the hub in workDir as migrated to another device
+func WriteMigrationMarker(workDir string, migrationId string) error {
+	marker, err := json.Marshal(&MigrationMarker{
+		MigrationId: migrationId,
+		MigratedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(workDir, MigrationMarkerName), marker, 0600)
+}
+
+// CheckMigrationMarker returns an error if the hub in workDir was migrated to another device.
+// Running both hubs with the same node state would lead to channel force closures.
+func CheckMigrationMarker(workDir string) error {
+	content, err := os.ReadFile(filepath.Join(workDir, MigrationMarkerName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var marker MigrationMarker
+	if err := json.Unmarshal(content, &marker); err != nil {
+		return fmt.Errorf("this hub was migrated to another device, remove %s from the work directory to start it anyway", MigrationMarkerName)
+	}
+	return fmt.Errorf("this hub was migrated to another device at %s (migration %s). Starting it again would risk the funds in its channels, remove %s from the work directory to start it anyway",
+		marker.MigratedAt.Format(time.RFC3339), marker.MigrationId, MigrationMarkerName)
+}
@@ -0,0 +1,97 @@
+package backups
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createMigrationArchive writes a migration backup with a copy of the test database and a node file.
+// modify is called before the manifest is written.
+func createMigrationArchive(t *testing.T, modify func(migrationWriter *MigrationWriter)) *zip.Reader {
+	svc, _ := createTestBackupsService(t)
+	workDir := svc.Cfg.GetEnv().Workdir
+
+	dbPath := filepath.Join(workDir, "copy.db")
+	require.NoError(t, svc.DB.Exec("VACUUM INTO ?", dbPath).Error)
+	nodeFilePath := filepath.Join(workDir, "node.sqlite")
+	require.NoError(t, os.WriteFile(nodeFilePath, []byte("node state"), 0600))
+
+	var buffer bytes.Buffer
+	migrationWriter := NewMigrationWriter(&buffer, &MigrationManifest{MigrationId: "abc"})
+	require.NoError(t, migrationWriter.AddFile(dbPath, snapshotDatabaseName))
+	require.NoError(t, migrationWriter.AddFile(nodeFilePath, "ldk/node.sqlite"))
+	if modify != nil {
+		modify(migrationWriter)
+	}
+	require.NoError(t, migrationWriter.Close())
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(t, err)
+	return zipReader
+}
+
+func TestVerifyMigrationArchive(t *testing.T) {
+	zipReader := createMigrationArchive(t, nil)
+
+	manifest, err := VerifyMigrationArchive(zipReader, unlockPassword, t.TempDir())
+	require.NoError(t, err)
+	require.NotNil(t, manifest)
+	assert.Equal(t, "abc", manifest.MigrationId)
+	assert.Len(t, manifest.Files, 2)
+
+	_, err = VerifyMigrationArchive(zipReader, "wrong", t.TempDir())
+	assert.ErrorContains(t, err, "cannot be unlocked with the password")
+}
+
+func TestVerifyMigrationArchive_Corrupt(t *testing.T) {
+	zipReader := createMigrationArchive(t, func(migrationWriter *MigrationWriter) {
+		migrationWriter.manifest.Files["ldk/node.sqlite"] = "0000"
+	})
+	_, err := VerifyMigrationArchive(zipReader, unlockPassword, t.TempDir())
+	assert.EqualError(t, err, "backup is corrupt: ldk/node.sqlite does not match the manifest")
+}
+
+func TestVerifyMigrationArchive_Incomplete(t *testing.T) {
+	zipReader := createMigrationArchive(t, func(migrationWriter *MigrationWriter) {
+		migrationWriter.manifest.Files["ldk/channel_manager"] = "0000"
+	})
+	_, err := VerifyMigrationArchive(zipReader, unlockPassword, t.TempDir())
+	assert.EqualError(t, err, "backup is incomplete: ldk/channel_manager is missing")
+}
+
+func TestVerifyMigrationArchive_InvalidPath(t *testing.T) {
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	_, err := zipWriter.Create("../outside")
+	require.NoError(t, err)
+	manifestEntry, err := zipWriter.Create(MigrationManifestName)
+	require.NoError(t, err)
+	_, err = manifestEntry.Write([]byte(`{"version":1,"files":{"../outside":""}}`))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(t, err)
+	_, err = VerifyMigrationArchive(zipReader, unlockPassword, t.TempDir())
+	assert.EqualError(t, err, `backup contains an invalid path: "../outside"`)
+}
+
+func TestMigrationMarker(t *testing.T) {
+	workDir := t.TempDir()
+	require.NoError(t, CheckMigrationMarker(workDir))
+
+	require.NoError(t, WriteMigrationMarker(workDir, "abc"))
+	err := CheckMigrationMarker(workDir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migrated to another device")
+	assert.Contains(t, err.Error(), "abc")
+
+	require.NoError(t, os.Remove(filepath.Join(workDir, MigrationMarkerName)))
+	require.NoError(t, CheckMigrationMarker(workDir))
+}
@@ -0,0 +1,104 @@
+package backups
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+)
+
+// The recovery phrase is stored encrypted with the unlock password so that the node can be started.
+// Once a seed passphrase is set, backups must not contain this copy, otherwise the unlock password
+// alone would be enough to reveal the recovery phrase from a backup.
+// Only the copy protected by the seed passphrase is kept, and restoring needs the seed passphrase.
+const mnemonicKey = "Mnemonic"
+
+func openDatabaseFile(dbPath string) (*gorm.DB, func(), error) {
+	gormDB, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	return gormDB, func() { sqlDB.Close() }, nil
+}
+
+func getConfigValue(gormDB *gorm.DB, key string) (string, error) {
+	var userConfig db.UserConfig
+	if err := gormDB.Limit(1).Find(&userConfig, &db.UserConfig{Key: key}).Error; err != nil {
+		return "", err
+	}
+	return userConfig.Value, nil
+}
+
+// removeUnprotectedMnemonic removes the recovery phrase encrypted with the unlock password
+// from a copy of the database if a seed passphrase is set
+func removeUnprotectedMnemonic(dbPath string) error {
+	gormDB, closeDB, err := openDatabaseFile(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	seedPassphraseCheck, err := getConfigValue(gormDB, config.SeedPassphraseCheckKey)
+	if err != nil {
+		return err
+	}
+	if seedPassphraseCheck == "" {
+		return nil
+	}
+	if err := gormDB.Where("key = ?", mnemonicKey).Delete(&db.UserConfig{}).Error; err != nil {
+		return fmt.Errorf("failed to remove recovery phrase: %w", err)
+	}
+	// make sure the removed value does not remain in free pages of the file
+	return gormDB.Exec("VACUUM").Error
+}
+
+// RestoreProtectedMnemonic restores the recovery phrase of a database which was backed up
+// with a seed passphrase, it is encrypted with the unlock password again.
+// Databases which still contain the recovery phrase are left unchanged.
+func RestoreProtectedMnemonic(dbPath string, unlockPassword string, seedPassphrase string) error {
+	gormDB, closeDB, err := openDatabaseFile(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	mnemonic, err := getConfigValue(gormDB, mnemonicKey)
+	if err != nil {
+		return err
+	}
+	if mnemonic != "" {
+		return nil
+	}
+	encryptedMnemonic, err := getConfigValue(gormDB, config.MnemonicBackupKey)
+	if err != nil {
+		return err
+	}
+	if encryptedMnemonic == "" {
+		// hubs without a recovery phrase, e.g. with an external LND node
+		return nil
+	}
+	if seedPassphrase == "" {
+		return errors.New("the backup is protected by a seed passphrase, please enter it to restore the backup")
+	}
+	mnemonic, err = config.AesGcmDecryptWithPassword(encryptedMnemonic, seedPassphrase)
+	if err != nil {
+		return errors.New("wrong seed passphrase")
+	}
+	encryptedMnemonic, err = config.AesGcmEncryptWithPassword(mnemonic, unlockPassword)
+	if err != nil {
+		return err
+	}
+	return gormDB.Create(&db.UserConfig{
+		Key:       mnemonicKey,
+		Value:     encryptedMnemonic,
+		Encrypted: true,
+	}).Error
+}
@@ -0,0 +1,98 @@
+package backups
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+)
+
+const (
+	seedPassphrase = "correct horse battery staple"
+	testMnemonic   = "connect maximum march lava ignore resist visa kind kiwi kidney develop animal"
+)
+
+// extractSnapshotDatabase decrypts a snapshot and returns the path of its database
+func extractSnapshotDatabase(t *testing.T, snapshot []byte) string {
+	decryptedReader, err := DecryptingReader(bytes.NewReader(snapshot), unlockPassword)
+	require.NoError(t, err)
+	zipBytes, err := io.ReadAll(decryptedReader)
+	require.NoError(t, err)
+	zipReader, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	require.NoError(t, err)
+	dbFile, err := zipReader.Open(snapshotDatabaseName)
+	require.NoError(t, err)
+	defer dbFile.Close()
+	dbBytes, err := io.ReadAll(dbFile)
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(t.TempDir(), snapshotDatabaseName)
+	require.NoError(t, os.WriteFile(dbPath, dbBytes, 0600))
+	return dbPath
+}
+
+func TestSnapshot_SeedPassphrase(t *testing.T) {
+	svc, _ := createTestBackupsService(t)
+
+	require.NoError(t, svc.Cfg.SetUpdate("Mnemonic", testMnemonic, unlockPassword))
+	encryptedCheck, err := config.AesGcmEncryptWithPassword("check", seedPassphrase)
+	require.NoError(t, err)
+	require.NoError(t, svc.Cfg.SetUpdate(config.SeedPassphraseCheckKey, encryptedCheck, ""))
+	encryptedMnemonic, err := config.AesGcmEncryptWithPassword(testMnemonic, seedPassphrase)
+	require.NoError(t, err)
+	require.NoError(t, svc.Cfg.SetUpdate(config.MnemonicBackupKey, encryptedMnemonic, ""))
+
+	backupKey, err := GetBackupKey(svc.Cfg, unlockPassword)
+	require.NoError(t, err)
+	var snapshot bytes.Buffer
+	require.NoError(t, writeSnapshot(svc.DB, t.TempDir(), backupKey, &snapshot))
+	dbPath := extractSnapshotDatabase(t, snapshot.Bytes())
+
+	// the unlock password alone does not reveal the recovery phrase
+	gormDB, closeDB, err := openDatabaseFile(dbPath)
+	require.NoError(t, err)
+	mnemonic, err := getConfigValue(gormDB, mnemonicKey)
+	require.NoError(t, err)
+	assert.Empty(t, mnemonic)
+	closeDB()
+
+	assert.EqualError(t, RestoreProtectedMnemonic(dbPath, unlockPassword, ""), "the backup is protected by a seed passphrase, please enter it to restore the backup")
+	assert.EqualError(t, RestoreProtectedMnemonic(dbPath, unlockPassword, "wrong passphrase"), "wrong seed passphrase")
+	require.NoError(t, RestoreProtectedMnemonic(dbPath, unlockPassword, seedPassphrase))
+
+	gormDB, closeDB, err = openDatabaseFile(dbPath)
+	require.NoError(t, err)
+	defer closeDB()
+	mnemonic, err = getConfigValue(gormDB, mnemonicKey)
+	require.NoError(t, err)
+	mnemonic, err = config.AesGcmDecryptWithPassword(mnemonic, unlockPassword)
+	require.NoError(t, err)
+	assert.Equal(t, testMnemonic, mnemonic)
+}
+
+func TestSnapshot_WithoutSeedPassphrase(t *testing.T) {
+	svc, _ := createTestBackupsService(t)
+	require.NoError(t, svc.Cfg.SetUpdate("Mnemonic", testMnemonic, unlockPassword))
+
+	backupKey, err := GetBackupKey(svc.Cfg, unlockPassword)
+	require.NoError(t, err)
+	var snapshot bytes.Buffer
+	require.NoError(t, writeSnapshot(svc.DB, t.TempDir(), backupKey, &snapshot))
+	dbPath := extractSnapshotDatabase(t, snapshot.Bytes())
+
+	// nothing needs to be restored
+	require.NoError(t, RestoreProtectedMnemonic(dbPath, unlockPassword, ""))
+	gormDB, closeDB, err := openDatabaseFile(dbPath)
+	require.NoError(t, err)
+	defer closeDB()
+	mnemonic, err := getConfigValue(gormDB, mnemonicKey)
+	require.NoError(t, err)
+	assert.NotEmpty(t, mnemonic)
+}
@@ -0,0 +1,363 @@
+package backups
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip44"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/logger"
+	nostrmodels "github.com/getAlby/hub/nostr/models"
+	"github.com/getAlby/hub/service/keys"
+)
+
+// The nostr backup is a NIP-78 application data event, which relays only keep in its latest version.
+// It is encrypted to the hub's own nostr key with NIP-44, so it can be fetched and decrypted
+// again after the hub was recovered from its seed.
+const (
+	NostrBackupKind          = 30078
+	nostrBackupIdentifier    = "albyhub-backup"
+	nostrBackupVersion       = 1
+	nostrBackupCheckInterval = 10 * time.Minute
+	// relays may drop old events, so the backup is republished even if nothing changed
+	nostrBackupRepublishInterval = 24 * time.Hour
+	// NIP-44 can encrypt at most 65535 bytes
+	nostrBackupMaxSize = 65535
+)
+
+type NostrBackup struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// latest static channel backup of the node
+	StaticChannelBackup json.RawMessage         `json:"staticChannelBackup,omitempty"`
+	Connections         []NostrBackupConnection `json:"connections"`
+	// unencrypted user config values
+	Settings map[string]string `json:"settings"`
+}
+
+type NostrBackupConnection struct {
+	Name         string                  `json:"name"`
+	AppPubkey    string                  `json:"appPubkey"`
+	WalletPubkey *string                 `json:"walletPubkey,omitempty"`
+	Isolated     bool                    `json:"isolated"`
+	CreatedAt    time.Time               `json:"createdAt"`
+	Permissions  []NostrBackupPermission `json:"permissions"`
+}
+
+type NostrBackupPermission struct {
+	Scope         string     `json:"scope"`
+	MaxAmountSat  int        `json:"maxAmountSat"`
+	BudgetRenewal string     `json:"budgetRenewal"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+}
+
+type nostrBackupPublisher struct {
+	db             *gorm.DB
+	cfg            config.Config
+	keys           keys.Keys
+	eventPublisher events.EventPublisher
+	pool           nostrmodels.SimplePool
+
+	mu              sync.Mutex
+	lastHash        [32]byte
+	lastPublishedAt time.Time
+}
+
+func NewNostrBackupPublisher(db *gorm.DB, cfg config.Config, keys keys.Keys, eventPublisher events.EventPublisher) *nostrBackupPublisher {
+	return &nostrBackupPublisher{
+		db:             db,
+		cfg:            cfg,
+		keys:           keys,
+		eventPublisher: eventPublisher,
+	}
+}
+
+// Start publishes the backup whenever it changed while the option is switched on,
+// and immediately after the node published a new static channel backup
+func (publisher *nostrBackupPublisher) Start(ctx context.Context, pool nostrmodels.SimplePool) {
+	publisher.pool = pool
+	publisher.eventPublisher.RegisterSubscriber(publisher)
+	health.RegisterJob("nostr_backup", nostrBackupCheckInterval)
+	go func() {
+		defer health.RemoveJob("nostr_backup")
+		defer publisher.eventPublisher.RemoveSubscriber(publisher)
+		ticker := time.NewTicker(nostrBackupCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				health.ReportJobRun("nostr_backup", publisher.publishIfChanged(ctx, pool, nil))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (publisher *nostrBackupPublisher) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	if event.Event != "nwc_backup_channels" {
+		return
+	}
+	// the channel backup of the event is used directly, LND backups may not be on disk yet
+	channelBackup, err := json.Marshal(event.Properties)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to serialize static channel backup")
+		return
+	}
+	if err := publisher.publishIfChanged(ctx, publisher.pool, channelBackup); err != nil {
+		return
+	}
+	enabled, _ := publisher.cfg.Get(config.NostrBackupEnabledKey, "")
+	if enabled == "true" {
+		SetLastChannelBackupAt(publisher.cfg, time.Now())
+	}
+}
+
+// publishIfChanged publishes the backup if it differs from the last published one,
+// channelBackup replaces the static channel backup from the work dir if set
+func (publisher *nostrBackupPublisher) publishIfChanged(ctx context.Context, pool nostrmodels.SimplePool, channelBackup json.RawMessage) error {
+	enabled, _ := publisher.cfg.Get(config.NostrBackupEnabledKey, "")
+	if enabled != "true" {
+		return nil
+	}
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	backup, err := BuildNostrBackup(publisher.db, publisher.cfg.GetEnv().Workdir)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to create nostr backup")
+		return err
+	}
+	if channelBackup != nil {
+		backup.StaticChannelBackup = channelBackup
+	}
+	// the creation time changes on every run
+	backup.CreatedAt = time.Time{}
+	payload, err := json.Marshal(backup)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(payload)
+	if hash == publisher.lastHash && time.Since(publisher.lastPublishedAt) < nostrBackupRepublishInterval {
+		return nil
+	}
+
+	backup.CreatedAt = time.Now().UTC()
+	if err := PublishNostrBackup(ctx, pool, publisher.cfg.GetRelayUrls(), publisher.keys.GetNostrSecretKey(), backup); err != nil {
+		logger.Logger.WithError(err).Error("Failed to publish nostr backup")
+		return err
+	}
+	publisher.lastHash = hash
+	publisher.lastPublishedAt = time.Now()
+	return nil
+}
+
+// BuildNostrBackup collects the state which is stored in the nostr backup
+func BuildNostrBackup(gormDB *gorm.DB, workDir string) (*NostrBackup, error) {
+	backup := &NostrBackup{
+		Version:     nostrBackupVersion,
+		CreatedAt:   time.Now().UTC(),
+		Connections: []NostrBackupConnection{},
+		Settings:    map[string]string{},
+	}
+
+	channelBackupPath, _, err := latestStaticChannelBackup(workDir)
+	if err != nil {
+		return nil, err
+	}
+	if channelBackupPath != "" {
+		channelBackup, err := os.ReadFile(channelBackupPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read static channel backup: %w", err)
+		}
+		if !json.Valid(channelBackup) {
+			return nil, errors.New("static channel backup is not valid JSON")
+		}
+		backup.StaticChannelBackup = channelBackup
+	}
+
+	var apps []db.App
+	if err := gormDB.Order("id").Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	var appPermissions []db.AppPermission
+	if err := gormDB.Order("id").Find(&appPermissions).Error; err != nil {
+		return nil, err
+	}
+	for _, app := range apps {
+		connection := NostrBackupConnection{
+			Name:         app.Name,
+			AppPubkey:    app.AppPubkey,
+			WalletPubkey: app.WalletPubkey,
+			Isolated:     app.Isolated,
+			CreatedAt:    app.CreatedAt,
+			Permissions:  []NostrBackupPermission{},
+		}
+		for _, appPermission := range appPermissions {
+			if appPermission.AppId != app.ID {
+				continue
+			}
+			connection.Permissions = append(connection.Permissions, NostrBackupPermission{
+				Scope:         appPermission.Scope,
+				MaxAmountSat:  appPermission.MaxAmountSat,
+				BudgetRenewal: appPermission.BudgetRenewal,
+				ExpiresAt:     appPermission.ExpiresAt,
+			})
+		}
+		backup.Connections = append(backup.Connections, connection)
+	}
+
+	var userConfigs []db.UserConfig
+	if err := gormDB.Where("encrypted = ?", false).Find(&userConfigs).Error; err != nil {
+		return nil, err
+	}
+	for _, userConfig := range userConfigs {
+		backup.Settings[userConfig.Key] = userConfig.Value
+	}
+
+	return backup, nil
+}
+
+// encryptNostrBackup compresses and encrypts the backup to the nostr key itself
+func encryptNostrBackup(backup *NostrBackup, secretKey string) (string, error) {
+	payload, err := json.Marshal(backup)
+	if err != nil {
+		return "", err
+	}
+
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	if _, err := gzipWriter.Write(payload); err != nil {
+		return "", err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", err
+	}
+	plaintext := base64.StdEncoding.EncodeToString(compressed.Bytes())
+	if len(plaintext) > nostrBackupMaxSize {
+		return "", fmt.Errorf("nostr backup is too large: %d bytes", len(plaintext))
+	}
+
+	conversationKey, err := selfConversationKey(secretKey)
+	if err != nil {
+		return "", err
+	}
+	return nip44.Encrypt(plaintext, conversationKey)
+}
+
+func decryptNostrBackup(content string, secretKey string) (*NostrBackup, error) {
+	conversationKey, err := selfConversationKey(secretKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := nip44.Decrypt(content, conversationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt nostr backup: %w", err)
+	}
+	compressed, err := base64.StdEncoding.DecodeString(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	gzipReader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	payload, err := io.ReadAll(io.LimitReader(gzipReader, 10*1024*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	var backup NostrBackup
+	if err := json.Unmarshal(payload, &backup); err != nil {
+		return nil, err
+	}
+	if backup.Version != nostrBackupVersion {
+		return nil, fmt.Errorf("unsupported nostr backup version: %d", backup.Version)
+	}
+	return &backup, nil
+}
+
+func selfConversationKey(secretKey string) ([32]byte, error) {
+	publicKey, err := nostr.GetPublicKey(secretKey)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return nip44.GenerateConversationKey(publicKey, secretKey)
+}
+
+// PublishNostrBackup publishes the backup to the relays, it fails if no relay accepted it
+func PublishNostrBackup(ctx context.Context, pool nostrmodels.SimplePool, relayUrls []string, secretKey string, backup *NostrBackup) error {
+	content, err := encryptNostrBackup(backup, secretKey)
+	if err != nil {
+		return err
+	}
+
+	ev := &nostr.Event{
+		Kind:      NostrBackupKind,
+		Content:   content,
+		Tags:      nostr.Tags{[]string{"d", nostrBackupIdentifier}},
+		CreatedAt: nostr.Timestamp(backup.CreatedAt.Unix()),
+	}
+	if err := ev.Sign(secretKey); err != nil {
+		return err
+	}
+
+	publishSuccessful := false
+	for result := range pool.PublishMany(ctx, relayUrls, *ev) {
+		if result.Error == nil {
+			publishSuccessful = true
+		} else {
+			logger.Logger.WithField("relay", result.RelayURL).WithError(result.Error).Error("failed to publish nostr backup to relay")
+		}
+	}
+	if !publishSuccessful {
+		return errors.New("failed to publish nostr backup to all relays")
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"event_id":    ev.ID,
+		"connections": len(backup.Connections),
+		"size":        len(content),
+	}).Info("Published nostr backup")
+	return nil
+}
+
+// FetchNostrBackup fetches and decrypts the latest backup of the nostr key from the relays
+func FetchNostrBackup(ctx context.Context, pool nostrmodels.SimplePool, relayUrls []string, secretKey string) (*NostrBackup, error) {
+	publicKey, err := nostr.GetPublicKey(secretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	relayEvent := pool.QuerySingle(ctx, relayUrls, nostr.Filter{
+		Kinds:   []int{NostrBackupKind},
+		Authors: []string{publicKey},
+		Tags:    nostr.TagMap{"d": []string{nostrBackupIdentifier}},
+		Limit:   1,
+	})
+	if relayEvent == nil || relayEvent.Event == nil {
+		return nil, errors.New("no nostr backup found on the relays")
+	}
+	if ok, err := relayEvent.CheckSignature(); !ok || err != nil {
+		return nil, errors.New("nostr backup has an invalid signature")
+	}
+	return decryptNostrBackup(relayEvent.Content, secretKey)
+}
@@ -0,0 +1,93 @@
+package backups
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestBuildNostrBackup(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	workDir := t.TempDir()
+	channelBackupDir := filepath.Join(workDir, filepath.FromSlash(ldkStaticChannelBackupDir))
+	require.NoError(t, os.MkdirAll(channelBackupDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(channelBackupDir, "1700000000.json"), []byte(`{"channels":[]}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(channelBackupDir, "1700000100.json"), []byte(`{"channels":[{"id":"1"}]}`), 0600))
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{AppId: app.ID, Scope: constants.PAY_INVOICE_SCOPE, MaxAmountSat: 1000, BudgetRenewal: "monthly"}).Error)
+	require.NoError(t, svc.Cfg.SetUpdate(config.RelaysKey, "wss://relay.example.com", ""))
+	require.NoError(t, svc.Cfg.SetUpdate("Secret", "do not back up", "123"))
+
+	backup, err := BuildNostrBackup(svc.DB, workDir)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"channels":[{"id":"1"}]}`, string(backup.StaticChannelBackup))
+	require.Len(t, backup.Connections, 1)
+	assert.Equal(t, app.AppPubkey, backup.Connections[0].AppPubkey)
+	permissions := backup.Connections[0].Permissions
+	require.NotEmpty(t, permissions)
+	assert.Equal(t, constants.PAY_INVOICE_SCOPE, permissions[len(permissions)-1].Scope)
+	assert.Equal(t, 1000, permissions[len(permissions)-1].MaxAmountSat)
+	assert.Equal(t, "wss://relay.example.com", backup.Settings[config.RelaysKey])
+	assert.NotContains(t, backup.Settings, "Secret")
+}
+
+func TestPublishNostrBackup(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	_, _, err = tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	pool := tests.NewMockSimplePool()
+	publisher := NewNostrBackupPublisher(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	// nothing is published until the option is switched on
+	require.NoError(t, publisher.publishIfChanged(context.TODO(), pool, nil))
+	assert.Empty(t, pool.PublishedEvents)
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.NostrBackupEnabledKey, "true", ""))
+	require.NoError(t, publisher.publishIfChanged(context.TODO(), pool, nil))
+	require.Len(t, pool.PublishedEvents, 1)
+
+	event := pool.PublishedEvents[0]
+	assert.Equal(t, NostrBackupKind, event.Kind)
+	assert.Equal(t, svc.Keys.GetNostrPublicKey(), event.PubKey)
+	assert.Equal(t, nostrBackupIdentifier, event.Tags.GetD())
+	ok, err := event.CheckSignature()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	backup, err := decryptNostrBackup(event.Content, svc.Keys.GetNostrSecretKey())
+	require.NoError(t, err)
+	assert.Len(t, backup.Connections, 1)
+	assert.Nil(t, backup.StaticChannelBackup)
+
+	// other keys cannot decrypt the backup
+	_, err = decryptNostrBackup(event.Content, nostr.GeneratePrivateKey())
+	assert.Error(t, err)
+
+	// unchanged backups are not published again
+	require.NoError(t, publisher.publishIfChanged(context.TODO(), pool, nil))
+	assert.Len(t, pool.PublishedEvents, 1)
+
+	_, _, err = tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, publisher.publishIfChanged(context.TODO(), pool, nil))
+	assert.Len(t, pool.PublishedEvents, 2)
+}
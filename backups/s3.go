@@ -0,0 +1,205 @@
+package backups
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// s3Client implements the few S3 requests needed for backups. It uses path-style
+// URLs, which are supported by AWS, MinIO, Backblaze B2 and most other providers.
+type s3Client struct {
+	endpoint        string
+	region          string
+	bucket          string
+	accessKeyId     string
+	secretAccessKey string
+	httpClient      *http.Client
+}
+
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+type listObjectsResponse struct {
+	Contents              []s3Object `xml:"Contents"`
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+}
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func newS3Client(endpoint, region, bucket, accessKeyId, secretAccessKey string) *s3Client {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &s3Client{
+		endpoint:        strings.TrimRight(endpoint, "/"),
+		region:          region,
+		bucket:          bucket,
+		accessKeyId:     accessKeyId,
+		secretAccessKey: secretAccessKey,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Minute,
+		},
+	}
+}
+
+func (client *s3Client) objectUrl(key string, query url.Values) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	objectUrl := client.endpoint + "/" + url.PathEscape(client.bucket)
+	if key != "" {
+		objectUrl += "/" + strings.Join(segments, "/")
+	}
+	if len(query) > 0 {
+		// S3 expects spaces to be encoded as %20
+		objectUrl += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+	return objectUrl
+}
+
+func (client *s3Client) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, client.objectUrl(key, query), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	client.sign(req, payloadHash, time.Now())
+
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		defer res.Body.Close()
+		responseBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return nil, fmt.Errorf("%s %s returned status %d: %s", method, key, res.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	return res, nil
+}
+
+// PutObject uploads size bytes of body, whose hex encoded sha256 hash has to be known upfront
+func (client *s3Client) PutObject(ctx context.Context, key string, body io.Reader, size int64, sha256Hex string) error {
+	res, err := client.do(ctx, http.MethodPut, key, nil, body, size, sha256Hex)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// GetObject returns the content of an object, which has to be closed by the caller
+func (client *s3Client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := client.do(ctx, http.MethodGet, key, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (client *s3Client) DeleteObject(ctx context.Context, key string) error {
+	res, err := client.do(ctx, http.MethodDelete, key, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// ListObjects returns all objects whose key starts with prefix
+func (client *s3Client) ListObjects(ctx context.Context, prefix string) ([]s3Object, error) {
+	objects := []s3Object{}
+	continuationToken := ""
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {prefix},
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		res, err := client.do(ctx, http.MethodGet, "", query, nil, 0, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var listResponse listObjectsResponse
+		err = xml.NewDecoder(res.Body).Decode(&listResponse)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object list: %w", err)
+		}
+		objects = append(objects, listResponse.Contents...)
+		if !listResponse.IsTruncated || listResponse.NextContinuationToken == "" {
+			return objects, nil
+		}
+		continuationToken = listResponse.NextContinuationToken
+	}
+}
+
+// sign adds an AWS Signature Version 4 to the request
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (client *s3Client) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// headers have to be sorted by name
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonicalHeaders := ""
+	for _, header := range signedHeaders {
+		canonicalHeaders += header + ":" + strings.TrimSpace(req.Header.Get(header)) + "\n"
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := strings.Join([]string{date, client.region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+client.secretAccessKey), date)
+	signingKey = hmacSha256(signingKey, client.region)
+	signingKey = hmacSha256(signingKey, "s3")
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		client.accessKeyId, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,170 @@
+package backups
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/version"
+)
+
+// Snapshots use the format of the migration backup including its manifest,
+// so they can be restored in the same way.
+// Instead of stopping the node they contain a copy of the database taken with VACUUM INTO
+// and the latest static channel backup of the node.
+const (
+	snapshotExtension    = ".bkp"
+	snapshotDatabaseName = "nwc.db"
+)
+
+// writeSnapshot writes an encrypted snapshot of the hub to w
+func writeSnapshot(gormDB *gorm.DB, workDir string, backupKey *BackupKey, w io.Writer) error {
+	if gormDB.Dialector.Name() != "sqlite" {
+		return errors.New("snapshots are only supported with sqlite, use the tools of your database server instead")
+	}
+
+	tmpDir, err := os.MkdirTemp(workDir, "snapshot-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// VACUUM INTO creates a consistent copy while the hub keeps running
+	dbCopyPath := filepath.Join(tmpDir, snapshotDatabaseName)
+	if err := gormDB.Exec("VACUUM INTO ?", dbCopyPath).Error; err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+	if err := removeUnprotectedMnemonic(dbCopyPath); err != nil {
+		return err
+	}
+
+	encryptedWriter, err := EncryptingWriterWithKey(w, backupKey)
+	if err != nil {
+		return fmt.Errorf("failed to create encrypted writer: %w", err)
+	}
+	migrationWriter := NewMigrationWriter(encryptedWriter, &MigrationManifest{
+		CreatedAt:  time.Now().UTC(),
+		HubVersion: version.Tag,
+	})
+
+	if err := migrationWriter.AddFile(dbCopyPath, snapshotDatabaseName); err != nil {
+		return err
+	}
+
+	channelBackupPath, channelBackupZipPath, err := latestStaticChannelBackup(workDir)
+	if err != nil {
+		return err
+	}
+	if channelBackupPath != "" {
+		if err := migrationWriter.AddFile(channelBackupPath, channelBackupZipPath); err != nil {
+			return err
+		}
+	}
+
+	return migrationWriter.Close()
+}
+
+// verifySnapshot checks that a snapshot can be decrypted with the backup key and contains an
+// intact database of a hub with the same unlock password, which is recognized by the encrypted
+// UnlockPasswordCheck value of the hub
+func verifySnapshot(r io.Reader, backupKey *BackupKey, unlockPasswordCheck string, tmpDir string) error {
+	decryptedReader, err := DecryptingReaderWithKey(r, backupKey)
+	if err != nil {
+		return err
+	}
+
+	zipFile, err := os.CreateTemp(tmpDir, "verify-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(zipFile.Name())
+	defer zipFile.Close()
+
+	zipSize, err := io.Copy(zipFile, decryptedReader)
+	if err != nil {
+		return fmt.Errorf("failed to download snapshot: %w", err)
+	}
+	zipReader, err := zip.NewReader(zipFile, zipSize)
+	if err != nil {
+		return fmt.Errorf("snapshot is not a valid archive, was it encrypted with another password? %w", err)
+	}
+
+	_, err = verifyMigrationArchive(zipReader, func(passwordCheck string) error {
+		if passwordCheck != unlockPasswordCheck {
+			return errors.New("database cannot be unlocked with the unlock password of the hub")
+		}
+		return nil
+	}, tmpDir)
+	return err
+}
+
+// passwordCheck verifies the encrypted UnlockPasswordCheck value of a database
+type passwordCheck func(encryptedPasswordCheck string) error
+
+func checkPassword(password string) passwordCheck {
+	return func(encryptedPasswordCheck string) error {
+		if _, err := config.AesGcmDecryptWithPassword(encryptedPasswordCheck, password); err != nil {
+			return fmt.Errorf("database cannot be unlocked with the password: %w", err)
+		}
+		return nil
+	}
+}
+
+// verifyZipDatabase extracts the database of a snapshot or migration backup and verifies it
+func verifyZipDatabase(zipReader *zip.Reader, checkPasswordCheck passwordCheck, tmpDir string) error {
+	dbFile, err := zipReader.Open(snapshotDatabaseName)
+	if err != nil {
+		return fmt.Errorf("backup does not contain a database: %w", err)
+	}
+	defer dbFile.Close()
+
+	dbPath := filepath.Join(tmpDir, "verify-"+snapshotDatabaseName)
+	defer os.Remove(dbPath)
+	dbCopy, err := os.Create(dbPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dbCopy, dbFile)
+	dbCopy.Close()
+	if err != nil {
+		return err
+	}
+
+	return verifyDatabase(dbPath, checkPasswordCheck)
+}
+
+// verifyDatabase checks that the database file is intact and can be unlocked
+func verifyDatabase(dbPath string, checkPasswordCheck passwordCheck) error {
+	gormDB, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var integrityCheck string
+	if err := gormDB.Raw("PRAGMA integrity_check").Scan(&integrityCheck).Error; err != nil {
+		return err
+	}
+	if integrityCheck != "ok" {
+		return fmt.Errorf("database is corrupt: %s", integrityCheck)
+	}
+
+	var passwordCheck db.UserConfig
+	if gormDB.Limit(1).Find(&passwordCheck, &db.UserConfig{Key: "UnlockPasswordCheck"}).RowsAffected == 0 {
+		return errors.New("database is not set up")
+	}
+	return checkPasswordCheck(passwordCheck.Value)
+}
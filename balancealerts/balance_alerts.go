@@ -0,0 +1,124 @@
+// Package balancealerts publishes events when the spendable balance or the receivable capacity
+// of the node drops below the thresholds set by the user, so they learn that payments cannot be
+// sent or received before a payment fails.
+package balancealerts
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+var checkInterval = time.Minute
+
+type balanceAlertsService struct {
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+	lnClient       lnclient.LNClient
+
+	// an alert is published once when the threshold is crossed, and again only after the
+	// balance was above the threshold in between
+	mu                  sync.Mutex
+	lowBalance          bool
+	lowInboundLiquidity bool
+}
+
+func NewBalanceAlertsService(cfg config.Config, eventPublisher events.EventPublisher) *balanceAlertsService {
+	return &balanceAlertsService{
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+// Start checks the balances periodically and after payments and channel changes until ctx is done
+func (svc *balanceAlertsService) Start(ctx context.Context, lnClient lnclient.LNClient) {
+	svc.lnClient = lnClient
+	svc.eventPublisher.RegisterSubscriber(svc)
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				svc.eventPublisher.RemoveSubscriber(svc)
+				return
+			case <-ticker.C:
+				svc.check(ctx)
+			}
+		}
+	}()
+}
+
+func (svc *balanceAlertsService) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	switch event.Event {
+	case "nwc_payment_received", "nwc_payment_sent", "nwc_channel_ready", "nwc_channel_closed":
+		svc.check(ctx)
+	}
+}
+
+func (svc *balanceAlertsService) check(ctx context.Context) {
+	lowBalanceThresholdSat := config.GetUintSetting(svc.cfg, config.LowBalanceAlertThresholdKey, 0)
+	lowInboundLiquidityThresholdSat := config.GetUintSetting(svc.cfg, config.LowInboundLiquidityAlertThresholdKey, 0)
+	if lowBalanceThresholdSat == 0 && lowInboundLiquidityThresholdSat == 0 {
+		return
+	}
+
+	balances, err := svc.lnClient.GetBalances(ctx, false)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get balances for balance alerts")
+		return
+	}
+
+	if lowBalanceThresholdSat > 0 {
+		spendableSat := balances.Lightning.TotalSpendable / 1000
+		if svc.crossed(&svc.lowBalance, spendableSat < int64(lowBalanceThresholdSat)) {
+			svc.publish("nwc_low_balance", map[string]interface{}{
+				"spendable_sat": spendableSat,
+				"threshold_sat": lowBalanceThresholdSat,
+			})
+		}
+	}
+
+	if lowInboundLiquidityThresholdSat > 0 {
+		// backends without channels do not report their receivable capacity
+		channels, err := svc.lnClient.ListChannels(ctx)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to list channels for balance alerts")
+			return
+		}
+		if len(channels) == 0 {
+			return
+		}
+		receivableSat := balances.Lightning.TotalReceivable / 1000
+		if svc.crossed(&svc.lowInboundLiquidity, receivableSat < int64(lowInboundLiquidityThresholdSat)) {
+			svc.publish("nwc_low_inbound_liquidity", map[string]interface{}{
+				"receivable_sat": receivableSat,
+				"threshold_sat":  lowInboundLiquidityThresholdSat,
+			})
+		}
+	}
+}
+
+// crossed records whether the balance is below the threshold and returns true if it just dropped below
+func (svc *balanceAlertsService) crossed(isLow *bool, belowThreshold bool) bool {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	crossed := belowThreshold && !*isLow
+	*isLow = belowThreshold
+	return crossed
+}
+
+func (svc *balanceAlertsService) publish(event string, properties map[string]interface{}) {
+	logger.Logger.WithFields(logrus.Fields(properties)).WithField("event", event).Info("Balance below alert threshold")
+	svc.eventPublisher.Publish(&events.Event{
+		Event:      event,
+		Properties: properties,
+	})
+}
@@ -0,0 +1,86 @@
+package balancealerts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+type mockBalancesLNClient struct {
+	lnclient.LNClient
+	balances lnclient.BalancesResponse
+	channels []lnclient.Channel
+}
+
+func (mln *mockBalancesLNClient) GetBalances(ctx context.Context, includeInactiveChannels bool) (*lnclient.BalancesResponse, error) {
+	return &mln.balances, nil
+}
+
+func (mln *mockBalancesLNClient) ListChannels(ctx context.Context) ([]lnclient.Channel, error) {
+	return mln.channels, nil
+}
+
+func TestBalanceAlerts_LowBalance(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.LowBalanceAlertThresholdKey, "1000", ""))
+	lnClient := &mockBalancesLNClient{LNClient: svc.LNClient}
+	lnClient.balances.Lightning.TotalSpendable = 500_000
+
+	consumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(consumer)
+	alertsSvc := NewBalanceAlertsService(svc.Cfg, svc.EventPublisher)
+	alertsSvc.lnClient = lnClient
+
+	// the alert is only published once while the balance stays low
+	alertsSvc.check(context.TODO())
+	alertsSvc.check(context.TODO())
+	consumedEvents := consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 1)
+	assert.Equal(t, "nwc_low_balance", consumedEvents[0].Event)
+	properties := consumedEvents[0].Properties.(map[string]interface{})
+	assert.Equal(t, int64(500), properties["spendable_sat"])
+	assert.Equal(t, uint(1000), properties["threshold_sat"])
+
+	// and again after the balance recovered
+	lnClient.balances.Lightning.TotalSpendable = 2_000_000
+	alertsSvc.check(context.TODO())
+	lnClient.balances.Lightning.TotalSpendable = 100_000
+	alertsSvc.check(context.TODO())
+	assert.Len(t, consumer.GetConsumedEvents(), 2)
+}
+
+func TestBalanceAlerts_LowInboundLiquidity(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.LowInboundLiquidityAlertThresholdKey, "10000", ""))
+	lnClient := &mockBalancesLNClient{LNClient: svc.LNClient}
+	lnClient.balances.Lightning.TotalReceivable = 5_000_000
+
+	consumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(consumer)
+	alertsSvc := NewBalanceAlertsService(svc.Cfg, svc.EventPublisher)
+	alertsSvc.lnClient = lnClient
+
+	// without channels the receivable capacity is not known
+	alertsSvc.check(context.TODO())
+	assert.Empty(t, consumer.GetConsumedEvents())
+
+	lnClient.channels = []lnclient.Channel{{Id: "channel1", Active: true}}
+	alertsSvc.check(context.TODO())
+	consumedEvents := consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 1)
+	assert.Equal(t, "nwc_low_inbound_liquidity", consumedEvents[0].Event)
+	properties := consumedEvents[0].Properties.(map[string]interface{})
+	assert.Equal(t, int64(5000), properties["receivable_sat"])
+}
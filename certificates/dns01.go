@@ -0,0 +1,290 @@
+package certificates
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	renewCheckInterval = 12 * time.Hour
+	issueRetryInterval = 10 * time.Minute
+	accountKeyName     = "acme_account+key"
+)
+
+// dnsProvider publishes the TXT records of DNS-01 challenges
+type dnsProvider interface {
+	SetTXT(ctx context.Context, fqdn string, value string) error
+	RemoveTXT(ctx context.Context, fqdn string, value string) error
+}
+
+type dns01Issuer struct {
+	domain           string
+	email            string
+	client           *acme.Client
+	cache            autocert.Cache
+	provider         dnsProvider
+	propagationDelay time.Duration
+	certificate      atomic.Pointer[tls.Certificate]
+}
+
+func (i *dns01Issuer) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certificate := i.certificate.Load()
+	if certificate == nil {
+		return nil, errors.New("certificate has not been issued yet")
+	}
+	return certificate, nil
+}
+
+// run loads the cached certificate and renews it whenever it is about to expire
+func (i *dns01Issuer) run(ctx context.Context) {
+	certificate, err := i.loadCertificate(ctx)
+	if err != nil && !errors.Is(err, autocert.ErrCacheMiss) {
+		logger.Logger.WithError(err).Error("Failed to load cached certificate")
+	}
+	if certificate != nil {
+		i.certificate.Store(certificate)
+	}
+
+	for {
+		interval := renewCheckInterval
+		if needsRenewal(i.certificate.Load(), time.Now()) {
+			logger.Logger.WithField("domain", i.domain).Info("Requesting certificate with DNS-01 challenge")
+			certificate, err := i.issue(ctx)
+			if err != nil {
+				logger.Logger.WithError(err).WithField("domain", i.domain).Error("Failed to issue certificate")
+				interval = issueRetryInterval
+			} else {
+				i.certificate.Store(certificate)
+				logger.Logger.WithFields(logrus.Fields{
+					"domain":     i.domain,
+					"expires_at": certificate.Leaf.NotAfter,
+				}).Info("Issued certificate")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func needsRenewal(certificate *tls.Certificate, now time.Time) bool {
+	return certificate == nil || certificate.Leaf == nil || now.Add(renewBefore).After(certificate.Leaf.NotAfter)
+}
+
+func (i *dns01Issuer) issue(ctx context.Context) (*tls.Certificate, error) {
+	accountKey, err := i.loadOrCreateAccountKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i.client.Key = accountKey
+
+	account := &acme.Account{}
+	if i.email != "" {
+		account.Contact = []string{"mailto:" + i.email}
+	}
+	_, err = i.client.Register(ctx, account, acme.AcceptTOS)
+	if err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("failed to register ACME account: %w", err)
+	}
+
+	order, err := i.client.AuthorizeOrder(ctx, acme.DomainIDs(i.domain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+	for _, authzUrl := range order.AuthzURLs {
+		err = i.authorize(ctx, authzUrl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	order, err = i.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, fmt.Errorf("order was not ready: %w", err)
+	}
+
+	certificateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: i.domain},
+		DNSNames: []string{i.domain},
+	}, certificateKey)
+	if err != nil {
+		return nil, err
+	}
+	chain, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize order: %w", err)
+	}
+
+	certificate, err := newCertificate(chain, certificateKey)
+	if err != nil {
+		return nil, err
+	}
+	err = i.saveCertificate(ctx, chain, certificateKey)
+	if err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
+
+func (i *dns01Issuer) authorize(ctx context.Context, authzUrl string) error {
+	authz, err := i.client.GetAuthorization(ctx, authzUrl)
+	if err != nil {
+		return fmt.Errorf("failed to get authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == ChallengeDns01 {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("no %s challenge offered for %s", ChallengeDns01, authz.Identifier.Value)
+	}
+
+	record, err := i.client.DNS01ChallengeRecord(challenge.Token)
+	if err != nil {
+		return err
+	}
+	fqdn := "_acme-challenge." + authz.Identifier.Value + "."
+	err = i.provider.SetTXT(ctx, fqdn, record)
+	if err != nil {
+		return fmt.Errorf("failed to publish challenge record: %w", err)
+	}
+	defer func() {
+		if err := i.provider.RemoveTXT(context.Background(), fqdn, record); err != nil {
+			logger.Logger.WithError(err).WithField("fqdn", fqdn).Warn("Failed to remove challenge record")
+		}
+	}()
+
+	// give secondary name servers time to pick up the record
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(i.propagationDelay):
+	}
+
+	_, err = i.client.Accept(ctx, challenge)
+	if err != nil {
+		return fmt.Errorf("failed to accept challenge: %w", err)
+	}
+	_, err = i.client.WaitAuthorization(ctx, authz.URI)
+	if err != nil {
+		return fmt.Errorf("authorization failed: %w", err)
+	}
+	return nil
+}
+
+func (i *dns01Issuer) loadOrCreateAccountKey(ctx context.Context) (crypto.Signer, error) {
+	data, err := i.cache.Get(ctx, accountKeyName)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("invalid cached ACME account key")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !errors.Is(err, autocert.ErrCacheMiss) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	err = i.cache.Put(ctx, accountKeyName, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// the certificate is cached as the PEM encoded private key followed by the certificate chain
+func (i *dns01Issuer) certificateCacheKey() string {
+	return i.domain + "+dns01"
+}
+
+func (i *dns01Issuer) saveCertificate(ctx context.Context, chain [][]byte, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	for _, certificate := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})...)
+	}
+	return i.cache.Put(ctx, i.certificateCacheKey(), data)
+}
+
+func (i *dns01Issuer) loadCertificate(ctx context.Context) (*tls.Certificate, error) {
+	data, err := i.cache.Get(ctx, i.certificateCacheKey())
+	if err != nil {
+		return nil, err
+	}
+
+	var key *ecdsa.PrivateKey
+	chain := [][]byte{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+		case "CERTIFICATE":
+			chain = append(chain, block.Bytes)
+		}
+	}
+	if key == nil || len(chain) == 0 {
+		return nil, errors.New("invalid cached certificate")
+	}
+	return newCertificate(chain, key)
+}
+
+func newCertificate(chain [][]byte, key *ecdsa.PrivateKey) (*tls.Certificate, error) {
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: chain,
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
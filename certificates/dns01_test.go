@@ -0,0 +1,87 @@
+package certificates
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func selfSignedCertificate(t *testing.T, notAfter time.Time) ([][]byte, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "hub.example.com"},
+		DNSNames:     []string{"hub.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return [][]byte{der}, key
+}
+
+func TestDns01Issuer_CertificateCache(t *testing.T) {
+	issuer := &dns01Issuer{
+		domain: "hub.example.com",
+		cache:  autocert.DirCache(t.TempDir()),
+	}
+
+	_, err := issuer.loadCertificate(context.Background())
+	assert.ErrorIs(t, err, autocert.ErrCacheMiss)
+
+	chain, key := selfSignedCertificate(t, time.Now().Add(90*24*time.Hour))
+	require.NoError(t, issuer.saveCertificate(context.Background(), chain, key))
+
+	certificate, err := issuer.loadCertificate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, chain, certificate.Certificate)
+	assert.True(t, key.Equal(certificate.PrivateKey))
+	assert.Equal(t, "hub.example.com", certificate.Leaf.Subject.CommonName)
+}
+
+func TestDns01Issuer_AccountKeyIsReused(t *testing.T) {
+	issuer := &dns01Issuer{cache: autocert.DirCache(t.TempDir())}
+
+	key, err := issuer.loadOrCreateAccountKey(context.Background())
+	require.NoError(t, err)
+	loadedKey, err := issuer.loadOrCreateAccountKey(context.Background())
+	require.NoError(t, err)
+	assert.True(t, key.(*ecdsa.PrivateKey).Equal(loadedKey))
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	assert.True(t, needsRenewal(nil, now))
+
+	chain, key := selfSignedCertificate(t, now.Add(60*24*time.Hour))
+	certificate, err := newCertificate(chain, key)
+	require.NoError(t, err)
+	assert.False(t, needsRenewal(certificate, now))
+	assert.True(t, needsRenewal(certificate, now.Add(31*24*time.Hour)))
+}
+
+func TestDns01Issuer_GetCertificate(t *testing.T) {
+	issuer := &dns01Issuer{}
+	_, err := issuer.getCertificate(&tls.ClientHelloInfo{})
+	assert.EqualError(t, err, "certificate has not been issued yet")
+
+	chain, key := selfSignedCertificate(t, time.Now().Add(time.Hour))
+	certificate, err := newCertificate(chain, key)
+	require.NoError(t, err)
+	issuer.certificate.Store(certificate)
+	served, err := issuer.getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, certificate, served)
+}
@@ -0,0 +1,98 @@
+// Package certificates provisions and renews TLS certificates for the hub's domain with ACME,
+// e.g. from Let's Encrypt, so the hub can be exposed without a separate reverse proxy.
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/getAlby/hub/config"
+)
+
+const (
+	ChallengeHttp01 = "http-01"
+	ChallengeDns01  = "dns-01"
+
+	// certificates are renewed once they expire within this period
+	renewBefore = 30 * 24 * time.Hour
+)
+
+type Manager struct {
+	autocertManager *autocert.Manager
+	dns01Issuer     *dns01Issuer
+}
+
+func NewManager(appConfig *config.AppConfig) (*Manager, error) {
+	if appConfig.AcmeDomain == "" {
+		return nil, errors.New("no ACME domain configured")
+	}
+	cache := autocert.DirCache(filepath.Join(appConfig.Workdir, "acme"))
+	client := &acme.Client{DirectoryURL: appConfig.AcmeDirectoryUrl}
+
+	switch appConfig.AcmeChallenge {
+	case ChallengeHttp01:
+		// autocert answers HTTP-01 and TLS-ALPN-01 challenges and renews on demand
+		return &Manager{
+			autocertManager: &autocert.Manager{
+				Prompt:      autocert.AcceptTOS,
+				Cache:       cache,
+				HostPolicy:  autocert.HostWhitelist(appConfig.AcmeDomain),
+				Email:       appConfig.AcmeEmail,
+				Client:      client,
+				RenewBefore: renewBefore,
+			},
+		}, nil
+	case ChallengeDns01:
+		provider, err := newRfc2136Provider(appConfig)
+		if err != nil {
+			return nil, err
+		}
+		return &Manager{
+			dns01Issuer: &dns01Issuer{
+				domain:           appConfig.AcmeDomain,
+				email:            appConfig.AcmeEmail,
+				client:           client,
+				cache:            cache,
+				provider:         provider,
+				propagationDelay: time.Duration(appConfig.AcmeDnsPropagationSeconds) * time.Second,
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported ACME challenge %q. Must be one of %s,%s", appConfig.AcmeChallenge, ChallengeHttp01, ChallengeDns01)
+	}
+}
+
+// Start issues the certificate in the background if it has to be obtained with DNS-01.
+// HTTP-01 certificates are obtained on the first TLS handshake.
+func (m *Manager) Start(ctx context.Context) {
+	if m.dns01Issuer != nil {
+		go m.dns01Issuer.run(ctx)
+	}
+}
+
+func (m *Manager) TLSConfig() *tls.Config {
+	if m.autocertManager != nil {
+		return m.autocertManager.TLSConfig()
+	}
+	return &tls.Config{
+		GetCertificate: m.dns01Issuer.getCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+}
+
+// HTTPChallengeHandler answers HTTP-01 challenges at /.well-known/acme-challenge/, or returns nil
+// if they are not used
+func (m *Manager) HTTPChallengeHandler() http.Handler {
+	if m.autocertManager == nil {
+		return nil
+	}
+	return m.autocertManager.HTTPHandler(nil)
+}
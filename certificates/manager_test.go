@@ -0,0 +1,38 @@
+package certificates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+)
+
+func TestNewManager(t *testing.T) {
+	manager, err := NewManager(&config.AppConfig{
+		AcmeDomain:    "hub.example.com",
+		AcmeChallenge: ChallengeHttp01,
+		Workdir:       t.TempDir(),
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, manager.HTTPChallengeHandler())
+	assert.Contains(t, manager.TLSConfig().NextProtos, "acme-tls/1")
+
+	manager, err = NewManager(&config.AppConfig{
+		AcmeDomain:    "hub.example.com",
+		AcmeChallenge: ChallengeDns01,
+		AcmeDnsServer: "127.0.0.1:53",
+		AcmeDnsZone:   "example.com",
+		Workdir:       t.TempDir(),
+	})
+	require.NoError(t, err)
+	assert.Nil(t, manager.HTTPChallengeHandler())
+	assert.NotNil(t, manager.TLSConfig().GetCertificate)
+
+	_, err = NewManager(&config.AppConfig{
+		AcmeDomain:    "hub.example.com",
+		AcmeChallenge: "tls-alpn-01",
+	})
+	assert.EqualError(t, err, `unsupported ACME challenge "tls-alpn-01". Must be one of http-01,dns-01`)
+}
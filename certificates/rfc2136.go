@@ -0,0 +1,82 @@
+package certificates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/getAlby/hub/config"
+)
+
+// challenge records are only needed while the authorization is validated
+const challengeRecordTtl = 60
+
+// rfc2136Provider publishes TXT records with dynamic DNS updates, which are supported by
+// BIND, Knot, PowerDNS and many DNS hosting providers
+type rfc2136Provider struct {
+	server        string
+	zone          string
+	tsigKey       string
+	tsigSecret    string
+	tsigAlgorithm string
+}
+
+func newRfc2136Provider(appConfig *config.AppConfig) (*rfc2136Provider, error) {
+	if appConfig.AcmeDnsServer == "" || appConfig.AcmeDnsZone == "" {
+		return nil, errors.New("ACME_DNS_SERVER and ACME_DNS_ZONE are required for DNS-01 challenges")
+	}
+	return &rfc2136Provider{
+		server:        appConfig.AcmeDnsServer,
+		zone:          dns.Fqdn(appConfig.AcmeDnsZone),
+		tsigKey:       appConfig.AcmeDnsTsigKey,
+		tsigSecret:    appConfig.AcmeDnsTsigSecret,
+		tsigAlgorithm: dns.Fqdn(appConfig.AcmeDnsTsigAlgorithm),
+	}, nil
+}
+
+func (p *rfc2136Provider) SetTXT(ctx context.Context, fqdn string, value string) error {
+	return p.update(ctx, fqdn, value, true)
+}
+
+func (p *rfc2136Provider) RemoveTXT(ctx context.Context, fqdn string, value string) error {
+	return p.update(ctx, fqdn, value, false)
+}
+
+func (p *rfc2136Provider) update(ctx context.Context, fqdn string, value string, insert bool) error {
+	record := &dns.TXT{
+		Hdr: dns.RR_Header{
+			Name:   dns.Fqdn(fqdn),
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassINET,
+			Ttl:    challengeRecordTtl,
+		},
+		Txt: []string{value},
+	}
+
+	message := new(dns.Msg)
+	message.SetUpdate(p.zone)
+	if insert {
+		message.Insert([]dns.RR{record})
+	} else {
+		message.Remove([]dns.RR{record})
+	}
+
+	client := &dns.Client{Timeout: 10 * time.Second}
+	if p.tsigKey != "" {
+		keyName := dns.Fqdn(p.tsigKey)
+		message.SetTsig(keyName, p.tsigAlgorithm, 300, time.Now().Unix())
+		client.TsigSecret = map[string]string{keyName: p.tsigSecret}
+	}
+
+	reply, _, err := client.ExchangeContext(ctx, message, p.server)
+	if err != nil {
+		return fmt.Errorf("dns update failed: %w", err)
+	}
+	if reply.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("dns update was rejected: %s", dns.RcodeToString[reply.Rcode])
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package certificates
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+)
+
+const testTsigSecret = "c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0LXNlY3JldA=="
+
+func TestRfc2136Provider(t *testing.T) {
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var mutex sync.Mutex
+	updates := []*dns.Msg{}
+	server := &dns.Server{
+		PacketConn: packetConn,
+		TsigSecret: map[string]string{"hub.": testTsigSecret},
+		// the default accepts queries only
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction { return dns.MsgAccept },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+			reply := new(dns.Msg)
+			reply.SetReply(request)
+			if request.IsTsig() == nil || w.TsigStatus() != nil {
+				reply.Rcode = dns.RcodeNotAuth
+			} else {
+				mutex.Lock()
+				updates = append(updates, request)
+				mutex.Unlock()
+			}
+			if request.IsTsig() != nil {
+				reply.SetTsig("hub.", dns.HmacSHA256, 300, int64(request.IsTsig().TimeSigned))
+			}
+			w.WriteMsg(reply)
+		}),
+	}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	provider, err := newRfc2136Provider(&config.AppConfig{
+		AcmeDnsServer:        packetConn.LocalAddr().String(),
+		AcmeDnsZone:          "example.com",
+		AcmeDnsTsigKey:       "hub",
+		AcmeDnsTsigSecret:    testTsigSecret,
+		AcmeDnsTsigAlgorithm: "hmac-sha256",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, provider.SetTXT(context.Background(), "_acme-challenge.hub.example.com.", "token"))
+	require.NoError(t, provider.RemoveTXT(context.Background(), "_acme-challenge.hub.example.com.", "token"))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Len(t, updates, 2)
+	assert.Equal(t, "example.com.", updates[0].Question[0].Name)
+	inserted := updates[0].Ns[0].(*dns.TXT)
+	assert.Equal(t, "_acme-challenge.hub.example.com.", inserted.Hdr.Name)
+	assert.Equal(t, uint16(dns.ClassINET), inserted.Hdr.Class)
+	assert.Equal(t, []string{"token"}, inserted.Txt)
+	removed := updates[1].Ns[0].(*dns.TXT)
+	assert.Equal(t, uint16(dns.ClassNONE), removed.Hdr.Class)
+
+	// updates with a wrong key are rejected
+	provider.tsigSecret = "d3Jvbmctc2VjcmV0"
+	assert.Error(t, provider.SetTXT(context.Background(), "_acme-challenge.hub.example.com.", "token"))
+}
+
+func TestNewRfc2136Provider_MissingConfig(t *testing.T) {
+	_, err := newRfc2136Provider(&config.AppConfig{AcmeDnsServer: "127.0.0.1:53"})
+	assert.EqualError(t, err, "ACME_DNS_SERVER and ACME_DNS_ZONE are required for DNS-01 challenges")
+}
@@ -0,0 +1,144 @@
+// Package channelacceptance decides about inbound channel requests with the policy set by the
+// user, for backends which let the hub accept or reject each request. Every decision is published
+// as an event.
+package channelacceptance
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+// Policy is the set of rules an inbound channel request has to pass
+type Policy struct {
+	// smallest channel in sats which is accepted, 0 accepts any size
+	MinChannelSizeSat uint64 `json:"minChannelSizeSat"`
+	// peers which may open channels, empty allows all peers
+	AllowedPeers []string `json:"allowedPeers"`
+	// peers whose channels may be used before they are confirmed
+	ZeroConfPeers []string `json:"zeroConfPeers"`
+	// largest total capacity in sats of the channels with a single peer, 0 is unlimited
+	MaxPeerExposureSat uint64 `json:"maxPeerExposureSat"`
+}
+
+// GetPolicy returns the configured policy, which accepts all channels without zero-conf if none is set
+func GetPolicy(cfg config.Config) *Policy {
+	policy := &Policy{}
+	value, _ := cfg.Get(config.ChannelAcceptancePolicyKey, "")
+	if value == "" {
+		return policy
+	}
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		logger.Logger.WithError(err).Error("Failed to parse channel acceptance policy")
+		return &Policy{}
+	}
+	return policy
+}
+
+func ValidatePolicy(policy *Policy) error {
+	for _, pubkey := range slices.Concat(policy.AllowedPeers, policy.ZeroConfPeers) {
+		if decoded, err := hex.DecodeString(pubkey); err != nil || len(decoded) != 33 {
+			return fmt.Errorf("invalid peer pubkey %q", pubkey)
+		}
+	}
+	if policy.MaxPeerExposureSat > 0 && policy.MaxPeerExposureSat < policy.MinChannelSizeSat {
+		return errors.New("the maximum exposure per peer is below the minimum channel size")
+	}
+	return nil
+}
+
+type channelAcceptanceService struct {
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+}
+
+func NewChannelAcceptanceService(cfg config.Config, eventPublisher events.EventPublisher) *channelAcceptanceService {
+	return &channelAcceptanceService{
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+// Start decides about inbound channel requests if the backend supports it. LDK only applies the
+// zero-conf peers of the policy, when the node is started.
+func (svc *channelAcceptanceService) Start(lnClient lnclient.LNClient) {
+	acceptorLNClient, ok := lnClient.(lnclient.ChannelAcceptorLNClient)
+	if !ok {
+		return
+	}
+	acceptorLNClient.AcceptChannels(func(ctx context.Context, request *lnclient.ChannelRequest) error {
+		return svc.checkRequest(ctx, lnClient, request)
+	})
+}
+
+func (svc *channelAcceptanceService) checkRequest(ctx context.Context, lnClient lnclient.LNClient, request *lnclient.ChannelRequest) error {
+	err := svc.evaluate(ctx, lnClient, request)
+
+	properties := map[string]interface{}{
+		"peer_pubkey": request.PeerPubkey,
+		"capacity":    request.CapacitySat,
+		"push_amount": request.PushAmountSat,
+		"zero_conf":   request.ZeroConf,
+	}
+	event := "nwc_channel_request_accepted"
+	if err != nil {
+		event = "nwc_channel_request_rejected"
+		properties["reason"] = err.Error()
+	}
+	logger.Logger.WithFields(logrus.Fields(properties)).Info("Decided about inbound channel request")
+	svc.eventPublisher.Publish(&events.Event{
+		Event:      event,
+		Properties: properties,
+	})
+	return err
+}
+
+func (svc *channelAcceptanceService) evaluate(ctx context.Context, lnClient lnclient.LNClient, request *lnclient.ChannelRequest) error {
+	policy := GetPolicy(svc.cfg)
+	peerPubkey := strings.ToLower(request.PeerPubkey)
+
+	if len(policy.AllowedPeers) > 0 && !containsPubkey(policy.AllowedPeers, peerPubkey) {
+		return errors.New("channels from this peer are not accepted")
+	}
+	if request.CapacitySat < policy.MinChannelSizeSat {
+		return fmt.Errorf("channel size below the minimum of %d sats", policy.MinChannelSizeSat)
+	}
+	if request.ZeroConf && !containsPubkey(policy.ZeroConfPeers, peerPubkey) {
+		return errors.New("zero-conf channels from this peer are not accepted")
+	}
+
+	if policy.MaxPeerExposureSat > 0 {
+		channels, err := lnClient.ListChannels(ctx)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to list channels to check peer exposure")
+			return errors.New("failed to check the exposure to this peer")
+		}
+		exposureSat := request.CapacitySat
+		for _, channel := range channels {
+			if strings.EqualFold(channel.RemotePubkey, peerPubkey) {
+				exposureSat += uint64(channel.LocalBalance+channel.RemoteBalance) / 1000
+			}
+		}
+		if exposureSat > policy.MaxPeerExposureSat {
+			return fmt.Errorf("channels with this peer would exceed the maximum exposure of %d sats", policy.MaxPeerExposureSat)
+		}
+	}
+	return nil
+}
+
+func containsPubkey(pubkeys []string, pubkey string) bool {
+	return slices.ContainsFunc(pubkeys, func(candidate string) bool {
+		return strings.EqualFold(candidate, pubkey)
+	})
+}
@@ -0,0 +1,89 @@
+package channelacceptance
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+const (
+	peerPubkey      = "02aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	otherPeerPubkey = "03bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+type mockAcceptorLNClient struct {
+	lnclient.LNClient
+	acceptor lnclient.ChannelAcceptor
+}
+
+func (mln *mockAcceptorLNClient) ListChannels(ctx context.Context) ([]lnclient.Channel, error) {
+	return []lnclient.Channel{
+		{RemotePubkey: peerPubkey, LocalBalance: 100_000_000, RemoteBalance: 400_000_000},
+	}, nil
+}
+
+func (mln *mockAcceptorLNClient) AcceptChannels(acceptor lnclient.ChannelAcceptor) {
+	mln.acceptor = acceptor
+}
+
+func TestChannelAcceptance(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	policy, err := json.Marshal(&Policy{
+		MinChannelSizeSat:  100_000,
+		AllowedPeers:       []string{peerPubkey},
+		ZeroConfPeers:      []string{peerPubkey},
+		MaxPeerExposureSat: 1_000_000,
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.Cfg.SetUpdate(config.ChannelAcceptancePolicyKey, string(policy), ""))
+
+	consumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(consumer)
+	lnClient := &mockAcceptorLNClient{LNClient: svc.LNClient}
+	NewChannelAcceptanceService(svc.Cfg, svc.EventPublisher).Start(lnClient)
+	require.NotNil(t, lnClient.acceptor)
+
+	assert.NoError(t, lnClient.acceptor(context.TODO(), &lnclient.ChannelRequest{PeerPubkey: peerPubkey, CapacitySat: 500_000, ZeroConf: true}))
+	assert.EqualError(t, lnClient.acceptor(context.TODO(), &lnclient.ChannelRequest{PeerPubkey: otherPeerPubkey, CapacitySat: 500_000}), "channels from this peer are not accepted")
+	assert.EqualError(t, lnClient.acceptor(context.TODO(), &lnclient.ChannelRequest{PeerPubkey: peerPubkey, CapacitySat: 50_000}), "channel size below the minimum of 100000 sats")
+	// the existing channel of 500k sats counts towards the exposure
+	assert.EqualError(t, lnClient.acceptor(context.TODO(), &lnclient.ChannelRequest{PeerPubkey: peerPubkey, CapacitySat: 600_000}), "channels with this peer would exceed the maximum exposure of 1000000 sats")
+
+	consumedEvents := consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 4)
+	reasons := []interface{}{}
+	for _, event := range consumedEvents {
+		if event.Event == "nwc_channel_request_rejected" {
+			reasons = append(reasons, event.Properties.(map[string]interface{})["reason"])
+		}
+	}
+	assert.Len(t, reasons, 3)
+	assert.Contains(t, reasons, "channels from this peer are not accepted")
+}
+
+func TestChannelAcceptance_ZeroConf(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// without a policy all channels are accepted, but only without zero-conf
+	channelAcceptanceSvc := NewChannelAcceptanceService(svc.Cfg, svc.EventPublisher)
+	assert.NoError(t, channelAcceptanceSvc.checkRequest(context.TODO(), svc.LNClient, &lnclient.ChannelRequest{PeerPubkey: otherPeerPubkey, CapacitySat: 1}))
+	assert.EqualError(t, channelAcceptanceSvc.checkRequest(context.TODO(), svc.LNClient, &lnclient.ChannelRequest{PeerPubkey: otherPeerPubkey, CapacitySat: 1, ZeroConf: true}), "zero-conf channels from this peer are not accepted")
+}
+
+func TestValidatePolicy(t *testing.T) {
+	assert.NoError(t, ValidatePolicy(&Policy{AllowedPeers: []string{peerPubkey}}))
+	assert.EqualError(t, ValidatePolicy(&Policy{ZeroConfPeers: []string{"02aa"}}), `invalid peer pubkey "02aa"`)
+	assert.Error(t, ValidatePolicy(&Policy{MinChannelSizeSat: 200_000, MaxPeerExposureSat: 100_000}))
+}
@@ -16,6 +16,7 @@ import (
 
 var expectedTables = []string{
 	"apps",
+	"app_groups",
 	"app_permissions",
 	"request_events",
 	"response_events",
@@ -24,6 +25,34 @@ var expectedTables = []string{
 	"user_configs",
 	"migrations",
 	"forwards",
+	"webhooks",
+	"webhook_deliveries",
+	"scheduled_payments",
+	"archived_transaction_totals",
+	"transaction_fiat_rates",
+	"subwallet_addresses",
+	"app_budgets",
+	"app_audit_logs",
+	"payment_approvals",
+	"app_activity_logs",
+	"app_destinations",
+	"nostr_profiles",
+	"admin_users",
+	"admin_user_apps",
+	"api_keys",
+	"sessions",
+	"passkeys",
+	"backup_targets",
+	"admin_audit_logs",
+	"push_devices",
+	"dead_letters",
+	"force_closes",
+	"swap_rules",
+	"swap_rule_runs",
+	"lsp_orders",
+	"fee_policies",
+	"channel_fee_states",
+	"channel_liquidity_snapshots",
 }
 
 func main() {
@@ -156,6 +185,136 @@ func migrateDB(from, to *gorm.DB) error {
 		return fmt.Errorf("failed to migrate transactions: %w", err)
 	}
 
+	logger.Logger.Info("migrating transaction_fiat_rates...")
+	if err := migrateTable[db.TransactionFiatRate](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate transaction_fiat_rates: %w", err)
+	}
+
+	logger.Logger.Info("migrating archived_transaction_totals...")
+	if err := migrateTable[db.ArchivedTransactionTotal](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate archived_transaction_totals: %w", err)
+	}
+
+	logger.Logger.Info("migrating subwallet_addresses...")
+	if err := migrateTable[db.SubwalletAddress](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate subwallet_addresses: %w", err)
+	}
+
+	logger.Logger.Info("migrating app_groups...")
+	if err := migrateTable[db.AppGroup](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate app_groups: %w", err)
+	}
+
+	logger.Logger.Info("migrating app_budgets...")
+	if err := migrateTable[db.AppBudget](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate app_budgets: %w", err)
+	}
+
+	logger.Logger.Info("migrating app_audit_logs...")
+	if err := migrateTable[db.AppAuditLog](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate app_audit_logs: %w", err)
+	}
+
+	logger.Logger.Info("migrating app_activity_logs...")
+	if err := migrateTable[db.AppActivityLog](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate app_activity_logs: %w", err)
+	}
+
+	logger.Logger.Info("migrating app_destinations...")
+	if err := migrateTable[db.AppDestination](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate app_destinations: %w", err)
+	}
+
+	logger.Logger.Info("migrating nostr_profiles...")
+	if err := migrateTable[db.NostrProfile](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate nostr_profiles: %w", err)
+	}
+
+	logger.Logger.Info("migrating admin_users...")
+	if err := migrateTable[db.AdminUser](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate admin_users: %w", err)
+	}
+
+	logger.Logger.Info("migrating admin_user_apps...")
+	if err := migrateTable[db.AdminUserApp](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate admin_user_apps: %w", err)
+	}
+
+	logger.Logger.Info("migrating api_keys...")
+	if err := migrateTable[db.ApiKey](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate api_keys: %w", err)
+	}
+
+	logger.Logger.Info("migrating sessions...")
+	if err := migrateTable[db.Session](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate sessions: %w", err)
+	}
+
+	logger.Logger.Info("migrating passkeys...")
+	if err := migrateTable[db.Passkey](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate passkeys: %w", err)
+	}
+
+	logger.Logger.Info("migrating backup_targets...")
+	if err := migrateTable[db.BackupTarget](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate backup_targets: %w", err)
+	}
+
+	logger.Logger.Info("migrating admin_audit_logs...")
+	if err := migrateTable[db.AdminAuditLog](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate admin_audit_logs: %w", err)
+	}
+
+	logger.Logger.Info("migrating push_devices...")
+	if err := migrateTable[db.PushDevice](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate push_devices: %w", err)
+	}
+
+	logger.Logger.Info("migrating dead_letters...")
+	if err := migrateTable[db.DeadLetter](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate dead_letters: %w", err)
+	}
+
+	logger.Logger.Info("migrating force_closes...")
+	if err := migrateTable[db.ForceClose](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate force_closes: %w", err)
+	}
+
+	logger.Logger.Info("migrating swap_rules...")
+	if err := migrateTable[db.SwapRule](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate swap_rules: %w", err)
+	}
+
+	logger.Logger.Info("migrating swap_rule_runs...")
+	if err := migrateTable[db.SwapRuleRun](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate swap_rule_runs: %w", err)
+	}
+
+	logger.Logger.Info("migrating lsp_orders...")
+	if err := migrateTable[db.LSPOrder](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate lsp_orders: %w", err)
+	}
+
+	logger.Logger.Info("migrating fee_policies...")
+	if err := migrateTable[db.FeePolicy](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate fee_policies: %w", err)
+	}
+
+	logger.Logger.Info("migrating channel_fee_states...")
+	if err := migrateTable[db.ChannelFeeState](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate channel_fee_states: %w", err)
+	}
+
+	logger.Logger.Info("migrating channel_liquidity_snapshots...")
+	if err := migrateTable[db.ChannelLiquiditySnapshot](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate channel_liquidity_snapshots: %w", err)
+	}
+
+	logger.Logger.Info("migrating payment_approvals...")
+	if err := migrateTable[db.PaymentApproval](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate payment_approvals: %w", err)
+	}
+
 	logger.Logger.Info("migrating user_configs...")
 	if err := migrateTable[db.UserConfig](from, tx); err != nil {
 		return fmt.Errorf("failed to migrate user_configs: %w", err)
@@ -272,6 +431,32 @@ func resetSequences(db *gorm.DB) error {
 		{"response_events", "response_events_id_seq"},
 		{"transactions", "transactions_id_seq"},
 		{"user_configs", "user_configs_id_seq"},
+		{"transaction_fiat_rates", "transaction_fiat_rates_id_seq"},
+		{"archived_transaction_totals", "archived_transaction_totals_id_seq"},
+		{"subwallet_addresses", "subwallet_addresses_id_seq"},
+		{"app_budgets", "app_budgets_id_seq"},
+		{"app_groups", "app_groups_id_seq"},
+		{"payment_approvals", "payment_approvals_id_seq"},
+		{"app_audit_logs", "app_audit_logs_id_seq"},
+		{"app_activity_logs", "app_activity_logs_id_seq"},
+		{"app_destinations", "app_destinations_id_seq"},
+		{"nostr_profiles", "nostr_profiles_id_seq"},
+		{"admin_users", "admin_users_id_seq"},
+		{"admin_user_apps", "admin_user_apps_id_seq"},
+		{"api_keys", "api_keys_id_seq"},
+		{"sessions", "sessions_id_seq"},
+		{"passkeys", "passkeys_id_seq"},
+		{"backup_targets", "backup_targets_id_seq"},
+		{"admin_audit_logs", "admin_audit_logs_id_seq"},
+		{"push_devices", "push_devices_id_seq"},
+		{"dead_letters", "dead_letters_id_seq"},
+		{"force_closes", "force_closes_id_seq"},
+		{"swap_rules", "swap_rules_id_seq"},
+		{"swap_rule_runs", "swap_rule_runs_id_seq"},
+		{"lsp_orders", "lsp_orders_id_seq"},
+		{"fee_policies", "fee_policies_id_seq"},
+		{"channel_fee_states", "channel_fee_states_id_seq"},
+		{"channel_liquidity_snapshots", "channel_liquidity_snapshots_id_seq"},
 	}
 
 	for _, req := range resetReqs {
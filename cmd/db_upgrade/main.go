@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// db_upgrade validates pending migrations before the hub is upgraded and rolls back an
+// upgrade by restoring the snapshot which the hub wrote before migrating. The hub has to be
+// stopped while the database is rolled back.
+func main() {
+	logger.Init(strconv.Itoa(int(logrus.InfoLevel)))
+
+	flags := flag.NewFlagSet("db_upgrade", flag.ExitOnError)
+	dsn := flags.String("db", os.Getenv("DATABASE_URI"), "database file or postgres DSN (DATABASE_URI)")
+	snapshot := flags.String("snapshot", "", "snapshot to restore with the rollback command")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: db_upgrade -db <database> status | dry-run | rollback -snapshot <file>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command := flags.Arg(0)
+	// flags can also follow the command, e.g. db_upgrade rollback -snapshot <file>
+	flags.Parse(flags.Args()[1:])
+	if *dsn == "" || flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg := &db.Config{URI: *dsn}
+	switch command {
+	case "status":
+		status, err := db.GetMigrationStatus(cfg)
+		exitOnError(err)
+		printJSON(status)
+	case "dry-run":
+		dryRun, err := db.DryRunMigrations(cfg)
+		if dryRun != nil {
+			printJSON(dryRun)
+		}
+		exitOnError(err)
+		logger.Logger.WithField("pending_migrations", len(dryRun.Pending)).WithField("duration", dryRun.Duration.String()).Info("dry run succeeded")
+	case "rollback":
+		dbPath, ok := db.SqlitePath(*dsn)
+		if !ok {
+			logger.Logger.Error("only sqlite databases can be rolled back, restore a postgres backup instead")
+			os.Exit(1)
+		}
+		if *snapshot == "" {
+			flags.Usage()
+			os.Exit(2)
+		}
+		replacedPath, err := db.RestoreMigrationSnapshot(dbPath, *snapshot)
+		exitOnError(err)
+		logger.Logger.WithField("replaced_database", replacedPath).Info("rollback complete, start the previous version of the hub now")
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+func printJSON(value interface{}) {
+	output, err := json.MarshalIndent(value, "", "  ")
+	exitOnError(err)
+	fmt.Println(string(output))
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		logger.Logger.WithError(err).Error("command failed")
+		os.Exit(1)
+	}
+}
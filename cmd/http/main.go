@@ -3,17 +3,23 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	nethttp "net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/getAlby/hub/adminrpc"
+	"github.com/getAlby/hub/certificates"
 	"github.com/getAlby/hub/http"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/service"
+	"github.com/getAlby/hub/tor"
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
@@ -54,6 +60,30 @@ func main() {
 	//register shared routes
 	httpSvc := http.NewHttpService(svc, svc.GetEventPublisher())
 	httpSvc.RegisterSharedRoutes(e)
+
+	var certificateManager *certificates.Manager
+	if svc.GetConfig().GetEnv().AcmeDomain != "" {
+		certificateManager, err = certificates.NewManager(svc.GetConfig().GetEnv())
+		if err != nil {
+			log.WithError(err).Fatal("Failed to configure certificates")
+			return
+		}
+		certificateManager.Start(ctx)
+		// HTTP-01 challenges are sent to port 80, which has to be forwarded to PORT
+		if challengeHandler := certificateManager.HTTPChallengeHandler(); challengeHandler != nil {
+			e.GET("/.well-known/acme-challenge/:token", echo.WrapHandler(challengeHandler))
+		}
+		e.TLSServer.Addr = svc.GetConfig().GetEnv().HttpsAddress
+		e.TLSServer.TLSConfig = certificateManager.TLSConfig()
+		go func() {
+			logger.Logger.WithField("address", e.TLSServer.Addr).Info("Starting HTTPS server")
+			if err := e.StartServer(e.TLSServer); err != nil && err != nethttp.ErrServerClosed {
+				logger.Logger.WithError(err).Error("HTTPS server failed to start")
+				cancel()
+			}
+		}()
+	}
+
 	//start Echo server
 	go func() {
 		if err := e.Start(fmt.Sprintf(":%v", svc.GetConfig().GetEnv().Port)); err != nil && err != nethttp.ErrServerClosed {
@@ -62,17 +92,61 @@ func main() {
 		}
 	}()
 
+	if svc.GetConfig().GetEnv().TorEnabled {
+		tor.PublishOnionService(ctx, svc.GetConfig(), svc.GetConfig().GetEnv().Port)
+	}
+
+	var grpcServer *grpc.Server
+	if grpcAddress := svc.GetConfig().GetEnv().GrpcAddress; grpcAddress != "" {
+		grpcOptions := []grpc.ServerOption{}
+		appConfig := svc.GetConfig().GetEnv()
+		if appConfig.GrpcTLSCertFile != "" || appConfig.GrpcTLSKeyFile != "" {
+			tlsCredentials, err := credentials.NewServerTLSFromFile(appConfig.GrpcTLSCertFile, appConfig.GrpcTLSKeyFile)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to load gRPC TLS certificate")
+				return
+			}
+			grpcOptions = append(grpcOptions, grpc.Creds(tlsCredentials))
+		} else if certificateManager != nil {
+			grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(certificateManager.TLSConfig())))
+		}
+		if err := adminrpc.CheckListenAddress(grpcAddress, len(grpcOptions) > 0); err != nil {
+			log.WithError(err).Fatal("Refusing to start gRPC server")
+			return
+		}
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to listen on gRPC address")
+			return
+		}
+		// the gRPC API shares the API instance and the abuse protection of the HTTP API
+		grpcServer = adminrpc.NewServer(ctx, httpSvc.GetAPI(), httpSvc.GetAppsService(), svc.GetEventPublisher(), httpSvc, grpcOptions...)
+		go func() {
+			logger.Logger.WithField("address", grpcAddress).Info("Starting gRPC server")
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Logger.WithError(err).Error("gRPC server failed to start")
+				cancel()
+			}
+		}()
+	}
+
 	//handle graceful shutdown
 	<-ctx.Done()
 	logger.Logger.WithField("signal", signal).Info("Context Done")
 	logger.Logger.Info("Shutting down echo server...")
-	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	// API requests which send payments are waited for as well
+	ctx, cancel = context.WithTimeout(context.Background(), time.Duration(svc.GetConfig().GetEnv().ShutdownTimeoutSeconds)*time.Second)
 	defer cancel()
 	err = e.Shutdown(ctx)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to shutdown echo server")
 	}
 	logger.Logger.Info("Echo server exited")
+	if grpcServer != nil {
+		// event streams end with the main context, so this does not block on them
+		grpcServer.GracefulStop()
+		logger.Logger.Info("gRPC server exited")
+	}
 	svc.Shutdown()
 	logger.Logger.Info("Service exited")
 	logger.Logger.Info("Alby Hub needs to stay online to send and receive transactions. Channels may be closed if your hub stays offline for an extended period of time.")
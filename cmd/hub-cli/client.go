@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// client talks to the admin API of a running hub
+type client struct {
+	baseUrl    string
+	token      string
+	httpClient *http.Client
+}
+
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+func newClient(baseUrl, token string) *client {
+	return &client{
+		baseUrl: strings.TrimRight(baseUrl, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+// do sends a JSON request and returns the raw response body
+func (c *client) do(method, path string, body interface{}, headers map[string]string) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.baseUrl+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	return c.send(req)
+}
+
+// upload sends a multipart form with a single file
+func (c *client) upload(path string, fields map[string]string, fileField, filename string, file io.Reader) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return nil, err
+		}
+	}
+	part, err := writer.CreateFormFile(fileField, filepath.Base(filename))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseUrl+path, &buffer)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return c.send(req)
+}
+
+func (c *client) send(req *http.Request) ([]byte, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 300 {
+		var errResponse errorResponse
+		if json.Unmarshal(responseBody, &errResponse) == nil && errResponse.Message != "" {
+			return nil, fmt.Errorf("%s (%d)", errResponse.Message, res.StatusCode)
+		}
+		message := strings.TrimSpace(string(responseBody))
+		if message == "" {
+			message = http.StatusText(res.StatusCode)
+		}
+		return nil, fmt.Errorf("%s (%d)", message, res.StatusCode)
+	}
+
+	return responseBody, nil
+}
+
+// defaultTokenFile returns where the session token is kept between invocations
+func defaultTokenFile() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ".hub-cli-token"
+	}
+	return filepath.Join(configDir, "albyhub", "cli-token")
+}
+
+func loadToken(tokenFile string) (string, error) {
+	token, err := os.ReadFile(tokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+func saveToken(tokenFile, token string) error {
+	if err := os.MkdirAll(filepath.Dir(tokenFile), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(tokenFile, []byte(token), 0600)
+}
+
+func removeToken(tokenFile string) error {
+	err := os.Remove(tokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
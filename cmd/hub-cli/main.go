@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/getAlby/hub/constants"
+)
+
+const defaultHubUrl = "http://localhost:8080"
+
+// cli holds the state shared by all commands
+type cli struct {
+	client    *client
+	tokenFile string
+	stdin     *bufio.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+	// whether secrets can be read from a terminal without echoing them
+	interactive bool
+}
+
+type command struct {
+	usage string
+	run   func(cli *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"info":         {"show the status of the hub", runInfo},
+	"setup":        {"set up a new hub and start it", runSetup},
+	"start":        {"start the node after a restart", runStart},
+	"unlock":       {"log in and keep the session token for later commands", runUnlock},
+	"lock":         {"lock spending until the hub is unlocked again", runLock},
+	"logout":       {"end the session and forget the token", runLogout},
+	"balance":      {"show lightning and onchain balances", runBalance},
+	"apps":         {"list, show, create or delete connections", runApps},
+	"pay":          {"pay a lightning invoice", runPay},
+	"invoice":      {"create a lightning invoice", runInvoice},
+	"transactions": {"list recent transactions", runTransactions},
+	"backup":       {"create, verify or restore a backup of the hub", runBackup},
+}
+
+func main() {
+	flags := flag.NewFlagSet("hub-cli", flag.ExitOnError)
+	hubUrl := flags.String("url", envOrDefault("HUB_URL", defaultHubUrl), "URL of the hub (HUB_URL)")
+	tokenFile := flags.String("token-file", envOrDefault("HUB_TOKEN_FILE", defaultTokenFile()), "file which keeps the session token (HUB_TOKEN_FILE)")
+	flags.Usage = func() {
+		printUsage(flags)
+	}
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	token := os.Getenv("HUB_TOKEN")
+	if token == "" {
+		var err error
+		token, err = loadToken(*tokenFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read token: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	c := &cli{
+		client:      newClient(*hubUrl, token),
+		tokenFile:   *tokenFile,
+		stdin:       bufio.NewReader(os.Stdin),
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		interactive: term.IsTerminal(int(os.Stdin.Fd())),
+	}
+
+	if err := cmd.run(c, flags.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage(flags *flag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "Usage: hub-cli [flags] <command> [arguments]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-13s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flags.PrintDefaults()
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// readSecret prompts for a secret without echoing it. When stdin is not a terminal
+// the secret is read from the next line, so it can be piped in from a secret store.
+func (cli *cli) readSecret(prompt string) (string, error) {
+	if cli.interactive {
+		fmt.Fprint(cli.stderr, prompt)
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(cli.stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(secret), nil
+	}
+	return cli.readLine(prompt)
+}
+
+func (cli *cli) readLine(prompt string) (string, error) {
+	if cli.interactive {
+		fmt.Fprint(cli.stderr, prompt)
+	}
+	line, err := cli.stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// printJSON pretty-prints a response from the hub
+func (cli *cli) printJSON(body []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(cli.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func (cli *cli) getAndPrint(path string) error {
+	body, err := cli.client.do(http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	return cli.printJSON(body)
+}
+
+func runInfo(cli *cli, args []string) error {
+	return cli.getAndPrint("/api/info")
+}
+
+func runBalance(cli *cli, args []string) error {
+	return cli.getAndPrint("/api/balances")
+}
+
+func runSetup(cli *cli, args []string) error {
+	flags := flag.NewFlagSet("setup", flag.ContinueOnError)
+	backend := flags.String("backend", "LDK", "lightning backend, e.g. LDK")
+	importMnemonic := flags.Bool("import-mnemonic", false, "prompt for an existing recovery phrase instead of generating one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	password, err := cli.readSecret("New unlock password: ")
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		return errors.New("the unlock password must not be empty")
+	}
+	confirmation, err := cli.readSecret("Repeat unlock password: ")
+	if err != nil {
+		return err
+	}
+	if password != confirmation {
+		return errors.New("passwords do not match")
+	}
+
+	setupRequest := map[string]interface{}{
+		"backendType":    *backend,
+		"unlockPassword": password,
+	}
+	if *importMnemonic {
+		mnemonic, err := cli.readSecret("Recovery phrase: ")
+		if err != nil {
+			return err
+		}
+		setupRequest["mnemonic"] = strings.Join(strings.Fields(mnemonic), " ")
+	}
+
+	if _, err := cli.client.do(http.MethodPost, "/api/setup", setupRequest, nil); err != nil {
+		return err
+	}
+	if _, err := cli.client.do(http.MethodPost, "/api/start", map[string]string{"unlockPassword": password}, nil); err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.stdout, "Hub set up and started. Run `hub-cli unlock` to log in.")
+	return nil
+}
+
+func runStart(cli *cli, args []string) error {
+	flags := flag.NewFlagSet("start", flag.ContinueOnError)
+	totpCode := flags.String("totp", "", "two-factor code, if enabled")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	password, err := cli.readSecret("Unlock password: ")
+	if err != nil {
+		return err
+	}
+	_, err = cli.client.do(http.MethodPost, "/api/start", map[string]string{
+		"unlockPassword": password,
+		"totpCode":       *totpCode,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.stdout, "Hub started.")
+	return nil
+}
+
+func runUnlock(cli *cli, args []string) error {
+	flags := flag.NewFlagSet("unlock", flag.ContinueOnError)
+	totpCode := flags.String("totp", "", "two-factor code, if enabled")
+	readOnly := flags.Bool("readonly", false, "request a token which cannot spend or change settings")
+	expiryDays := flags.Uint64("expiry-days", 0, "days until the token expires (defaults to the hub setting)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	password, err := cli.readSecret("Unlock password: ")
+	if err != nil {
+		return err
+	}
+
+	unlockRequest := map[string]interface{}{
+		"unlockPassword": password,
+		"totpCode":       *totpCode,
+		"permission":     "full",
+	}
+	if *readOnly {
+		unlockRequest["permission"] = "readonly"
+	}
+	if *expiryDays > 0 {
+		unlockRequest["tokenExpiryDays"] = *expiryDays
+	}
+
+	body, err := cli.client.do(http.MethodPost, "/api/unlock", unlockRequest, nil)
+	if err != nil {
+		return err
+	}
+	var tokenResponse struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return err
+	}
+	if err := saveToken(cli.tokenFile, tokenResponse.Token); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	fmt.Fprintf(cli.stdout, "Unlocked. Session token saved to %s\n", cli.tokenFile)
+	return nil
+}
+
+func runLock(cli *cli, args []string) error {
+	if _, err := cli.client.do(http.MethodPost, "/api/lock", nil, nil); err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.stdout, "Spending locked.")
+	return nil
+}
+
+func runLogout(cli *cli, args []string) error {
+	if _, err := cli.client.do(http.MethodDelete, "/api/session", nil, nil); err != nil {
+		return err
+	}
+	return removeToken(cli.tokenFile)
+}
+
+func runApps(cli *cli, args []string) error {
+	usage := errors.New("usage: hub-cli apps list | show <pubkey> | create -name <name> [flags] | delete <pubkey>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "list":
+		return cli.getAndPrint("/api/apps")
+	case "show":
+		if len(args) != 2 {
+			return usage
+		}
+		return cli.getAndPrint("/api/apps/" + url.PathEscape(args[1]))
+	case "delete":
+		if len(args) != 2 {
+			return usage
+		}
+		if _, err := cli.client.do(http.MethodDelete, "/api/apps/"+url.PathEscape(args[1]), nil, nil); err != nil {
+			return err
+		}
+		fmt.Fprintln(cli.stdout, "Connection deleted.")
+		return nil
+	case "create":
+		return runAppsCreate(cli, args[1:])
+	}
+	return usage
+}
+
+func runAppsCreate(cli *cli, args []string) error {
+	defaultScopes := []string{
+		constants.GET_INFO_SCOPE,
+		constants.GET_BALANCE_SCOPE,
+		constants.MAKE_INVOICE_SCOPE,
+		constants.LOOKUP_INVOICE_SCOPE,
+		constants.LIST_TRANSACTIONS_SCOPE,
+		constants.PAY_INVOICE_SCOPE,
+		constants.NOTIFICATIONS_SCOPE,
+	}
+
+	flags := flag.NewFlagSet("apps create", flag.ContinueOnError)
+	name := flags.String("name", "", "name of the connection")
+	scopes := flags.String("scopes", strings.Join(defaultScopes, ","), "comma-separated permissions")
+	maxAmount := flags.Uint64("max-amount", 0, "budget in sats (0 = no budget)")
+	budgetRenewal := flags.String("budget-renewal", "monthly", "daily, weekly, monthly, yearly or never")
+	expiresAt := flags.String("expires-at", "", "RFC 3339 expiry time of the connection")
+	isolated := flags.Bool("isolated", false, "create a sub-wallet with its own balance")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+
+	createAppRequest := map[string]interface{}{
+		"name":          *name,
+		"scopes":        strings.Split(*scopes, ","),
+		"maxAmount":     *maxAmount,
+		"budgetRenewal": *budgetRenewal,
+		"expiresAt":     *expiresAt,
+		"isolated":      *isolated,
+	}
+	// superuser connections have to be confirmed with the unlock password
+	if strings.Contains(*scopes, constants.SUPERUSER_SCOPE) {
+		password, err := cli.readSecret("Unlock password: ")
+		if err != nil {
+			return err
+		}
+		createAppRequest["unlockPassword"] = password
+	}
+
+	body, err := cli.client.do(http.MethodPost, "/api/apps", createAppRequest, nil)
+	if err != nil {
+		return err
+	}
+	return cli.printJSON(body)
+}
+
+func runPay(cli *cli, args []string) error {
+	flags := flag.NewFlagSet("pay", flag.ContinueOnError)
+	amount := flags.Uint64("amount", 0, "amount in millisats, for invoices without an amount")
+	idempotencyKey := flags.String("idempotency-key", "", "key which prevents paying twice when the command is retried")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: hub-cli pay [flags] <invoice>")
+	}
+
+	payRequest := map[string]interface{}{}
+	if *amount > 0 {
+		payRequest["amount"] = *amount
+	}
+	var headers map[string]string
+	if *idempotencyKey != "" {
+		headers = map[string]string{"Idempotency-Key": *idempotencyKey}
+	}
+
+	body, err := cli.client.do(http.MethodPost, "/api/payments/"+url.PathEscape(flags.Arg(0)), payRequest, headers)
+	if err != nil {
+		return err
+	}
+	return cli.printJSON(body)
+}
+
+func runInvoice(cli *cli, args []string) error {
+	flags := flag.NewFlagSet("invoice", flag.ContinueOnError)
+	amount := flags.Uint64("amount", 0, "amount in millisats")
+	description := flags.String("description", "", "description of the invoice")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	body, err := cli.client.do(http.MethodPost, "/api/invoices", map[string]interface{}{
+		"amount":      *amount,
+		"description": *description,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	return cli.printJSON(body)
+}
+
+func runTransactions(cli *cli, args []string) error {
+	flags := flag.NewFlagSet("transactions", flag.ContinueOnError)
+	limit := flags.Uint64("limit", 20, "maximum number of transactions")
+	offset := flags.Uint64("offset", 0, "number of transactions to skip")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.FormatUint(*limit, 10))
+	query.Set("offset", strconv.FormatUint(*offset, 10))
+	return cli.getAndPrint("/api/transactions?" + query.Encode())
+}
+
+func runBackup(cli *cli, args []string) error {
+	usage := errors.New("usage: hub-cli backup create -o <file> | verify <file> | restore <file>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("backup create", flag.ContinueOnError)
+		output := flags.String("o", "albyhub.bkp", "file to write the backup to")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		password, err := cli.readSecret("Unlock password: ")
+		if err != nil {
+			return err
+		}
+		body, err := cli.client.do(http.MethodPost, "/api/backup", map[string]string{"unlockPassword": password}, nil)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*output, body, 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.stdout, "Backup written to %s\n", *output)
+		return nil
+	case "verify":
+		if len(args) != 2 {
+			return usage
+		}
+		file, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		password, err := cli.readSecret("Unlock password of the backup: ")
+		if err != nil {
+			return err
+		}
+		body, err := cli.client.upload("/api/restore/validate", map[string]string{"unlockPassword": password}, "backup", args[1], file)
+		if err != nil {
+			return err
+		}
+		return cli.printJSON(body)
+	case "restore":
+		if len(args) != 2 {
+			return usage
+		}
+		file, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		password, err := cli.readSecret("Unlock password of the backup: ")
+		if err != nil {
+			return err
+		}
+		_, err = cli.client.upload("/api/restore", map[string]string{"unlockPassword": password}, "backup", args[1], file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cli.stdout, "Backup restored. The hub shuts down to apply it, start it again and run `hub-cli start`.")
+		return nil
+	}
+	return usage
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCli(t *testing.T, serverUrl, token, input string) (*cli, *bytes.Buffer) {
+	var stdout bytes.Buffer
+	return &cli{
+		client:    newClient(serverUrl, token),
+		tokenFile: filepath.Join(t.TempDir(), "albyhub", "cli-token"),
+		stdin:     bufio.NewReader(strings.NewReader(input)),
+		stdout:    &stdout,
+		stderr:    io.Discard,
+	}, &stdout
+}
+
+func TestUnlock_SavesToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/unlock", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "secret", body["unlockPassword"])
+		assert.Equal(t, "readonly", body["permission"])
+		assert.Equal(t, "123456", body["totpCode"])
+		w.Write([]byte(`{"token":"abc"}`))
+	}))
+	defer server.Close()
+
+	cli, _ := newTestCli(t, server.URL, "", "secret\n")
+	err := runUnlock(cli, []string{"-readonly", "-totp", "123456"})
+	require.NoError(t, err)
+
+	token, err := loadToken(cli.tokenFile)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", token)
+
+	fileInfo, err := os.Stat(cli.tokenFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+}
+
+func TestRequests_SendToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+		assert.Equal(t, "/api/transactions", r.URL.Path)
+		assert.Equal(t, "5", r.URL.Query().Get("limit"))
+		w.Write([]byte(`{"totalCount":0,"transactions":[]}`))
+	}))
+	defer server.Close()
+
+	cli, stdout := newTestCli(t, server.URL, "abc", "")
+	err := runTransactions(cli, []string{"-limit", "5"})
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), `"totalCount": 0`)
+}
+
+func TestRequests_ReturnErrorMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"Invalid password"}`))
+	}))
+	defer server.Close()
+
+	cli, _ := newTestCli(t, server.URL, "", "wrong\n")
+	err := runStart(cli, nil)
+	assert.EqualError(t, err, "Invalid password (401)")
+}
+
+func TestPay_SendsIdempotencyKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/payments/lnbc1", r.URL.Path)
+		assert.Equal(t, "retry-1", r.Header.Get("Idempotency-Key"))
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, float64(21000), body["amount"])
+		w.Write([]byte(`{"preimage":"00"}`))
+	}))
+	defer server.Close()
+
+	cli, _ := newTestCli(t, server.URL, "abc", "")
+	err := runPay(cli, []string{"-amount", "21000", "-idempotency-key", "retry-1", "lnbc1"})
+	require.NoError(t, err)
+}
+
+func TestAppsCreate_SuperuserRequiresPassword(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "agent", body["name"])
+		assert.Equal(t, []interface{}{"get_info", "superuser"}, body["scopes"])
+		assert.Equal(t, "secret", body["unlockPassword"])
+		w.Write([]byte(`{"pairingUri":"nostr+walletconnect://x"}`))
+	}))
+	defer server.Close()
+
+	cli, stdout := newTestCli(t, server.URL, "abc", "secret\n")
+	err := runApps(cli, []string{"create", "-name", "agent", "-scopes", "get_info,superuser"})
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "nostr+walletconnect://x")
+}
+
+func TestBackupRestore_UploadsFile(t *testing.T) {
+	backupFile := filepath.Join(t.TempDir(), "albyhub.bkp")
+	require.NoError(t, os.WriteFile(backupFile, []byte("backup"), 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/restore", r.URL.Path)
+		assert.Equal(t, "secret", r.FormValue("unlockPassword"))
+		file, _, err := r.FormFile("backup")
+		require.NoError(t, err)
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+		assert.Equal(t, "backup", string(content))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cli, _ := newTestCli(t, server.URL, "", "secret\n")
+	err := runBackup(cli, []string{"restore", backupFile})
+	require.NoError(t, err)
+}
+
+func TestBackupVerify_PrintsBackupInfo(t *testing.T) {
+	backupFile := filepath.Join(t.TempDir(), "albyhub.bkp")
+	require.NoError(t, os.WriteFile(backupFile, []byte("backup"), 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/restore/validate", r.URL.Path)
+		assert.Equal(t, "secret", r.FormValue("unlockPassword"))
+		w.Write([]byte(`{"hasManifest":true,"migrationId":"abc","files":3}`))
+	}))
+	defer server.Close()
+
+	cli, stdout := newTestCli(t, server.URL, "", "secret\n")
+	err := runBackup(cli, []string{"verify", backupFile})
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), `"migrationId": "abc"`)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"slices"
 	"strings"
 
 	"github.com/getAlby/hub/constants"
@@ -159,7 +160,11 @@ func (cfg *config) GetJWTSecret() string {
 }
 
 func (cfg *config) GetRelayUrls() []string {
-	relayUrls, _ := cfg.Get("Relay", "")
+	// relays changed in the settings take precedence over RELAY
+	relayUrls, _ := cfg.Get(RelaysKey, "")
+	if relayUrls == "" {
+		relayUrls, _ = cfg.Get("Relay", "")
+	}
 	return strings.Split(relayUrls, ",")
 }
 
@@ -377,6 +382,20 @@ func (cfg *config) SetCurrency(value string) error {
 	return nil
 }
 
+// GetFiatCurrencies returns the display currency followed by any additional
+// currencies which rates are recorded for when transactions settle
+func GetFiatCurrencies(cfg Config) []string {
+	currencies := []string{strings.ToUpper(cfg.GetCurrency())}
+	additionalCurrencies, _ := cfg.Get(FiatCurrenciesKey, "")
+	for _, currency := range strings.Split(additionalCurrencies, ",") {
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if currency != "" && !slices.Contains(currencies, currency) {
+			currencies = append(currencies, currency)
+		}
+	}
+	return currencies
+}
+
 func (cfg *config) GetBitcoinDisplayFormat() string {
 	format, err := cfg.Get("BitcoinDisplayFormat", "")
 	if err != nil {
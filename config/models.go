@@ -1,5 +1,7 @@
 package config
 
+import "net/url"
+
 const (
 	LNDBackendType     = "LND"
 	LDKBackendType     = "LDK"
@@ -9,11 +11,59 @@ const (
 )
 
 const (
-	OnchainAddressKey           = "OnchainAddress"
-	AutoSwapBalanceThresholdKey = "AutoSwapBalanceThreshold"
-	AutoSwapAmountKey           = "AutoSwapAmount"
-	AutoSwapDestinationKey      = "AutoSwapDestination"
-	AutoSwapXpubIndexStart      = "AutoSwapXpubIndexStart"
+	OnchainAddressKey               = "OnchainAddress"
+	AutoSwapBalanceThresholdKey     = "AutoSwapBalanceThreshold"
+	AutoSwapAmountKey               = "AutoSwapAmount"
+	AutoSwapDestinationKey          = "AutoSwapDestination"
+	AutoSwapXpubIndexStart          = "AutoSwapXpubIndexStart"
+	MaxPaymentAmountSatKey          = "MaxPaymentAmountSat"
+	MetadataMaxLengthKey            = "MetadataMaxLength"
+	MetadataPolicyKey               = "MetadataPolicy"
+	TransactionRetentionMonthsKey   = "TransactionRetentionMonths"
+	FiatCurrenciesKey               = "FiatCurrencies"
+	AppActivityRetentionDaysKey     = "AppActivityRetentionDays"
+	BudgetAlertThresholdsKey        = "BudgetAlertThresholds"
+	AdminAllowedNetworksKey         = "AdminAllowedNetworks"
+	ReadOnlyModeKey                 = "ReadOnlyMode"
+	ReadOnlyWindowsKey              = "ReadOnlyWindows"
+	TorOnionPrivateKeyKey           = "TorOnionPrivateKey"
+	TorOnionAddressKey              = "TorOnionAddress"
+	RelaysKey                       = "Relays"
+	LogLevelKey                     = "LogLevel"
+	RateLimitIpPerMinuteKey         = "RateLimitIpPerMinute"
+	RateLimitApiKeyPerMinuteKey     = "RateLimitApiKeyPerMinute"
+	RateLimitSessionPerMinuteKey    = "RateLimitSessionPerMinute"
+	BannedIpsKey                    = "BannedIps"
+	DatabaseEncryptionKeyKey        = "DatabaseEncryptionKey"
+	NostrBackupEnabledKey           = "NostrBackupEnabled"
+	LastChannelBackupAtKey          = "LastChannelBackupAt"
+	RequestEventRetentionDaysKey    = "RequestEventRetentionDays"
+	AuditLogRetentionDaysKey        = "AuditLogRetentionDays"
+	WebhookDeliveryRetentionDaysKey = "WebhookDeliveryRetentionDays"
+	LastDatabaseMaintenanceAtKey    = "LastDatabaseMaintenanceAt"
+	LastDatabaseVacuumAtKey         = "LastDatabaseVacuumAt"
+	DatabaseIntegrityErrorKey       = "DatabaseIntegrityError"
+	DatabaseReplicaIdKey            = "DatabaseReplicaId"
+	DatabaseRestoredAtKey           = "DatabaseRestoredAt"
+	DatabaseNextEncryptionKeyKey    = "DatabaseNextEncryptionKey"
+	DatabaseKeyRotationProgressKey  = "DatabaseKeyRotationProgress"
+	EmailNotificationsKey           = "EmailNotifications"
+	NostrNotificationsPubkeyKey     = "NostrNotificationsPubkey"
+	// spendable balance and receivable capacity in sats below which an alert is published, 0 disables it
+	LowBalanceAlertThresholdKey          = "LowBalanceAlertThresholdSat"
+	LowInboundLiquidityAlertThresholdKey = "LowInboundLiquidityAlertThresholdSat"
+	// fee rate in sat/vB above which on-chain operations are warned about, 0 disables it
+	OnchainFeeCeilingKey = "OnchainFeeCeiling"
+	// whether on-chain operations wait until the fee rate is below the ceiling again
+	OnchainFeeCeilingDelayKey = "OnchainFeeCeilingDelay"
+	// JSON-encoded rules for inbound channel requests
+	ChannelAcceptancePolicyKey = "ChannelAcceptancePolicy"
+	// comma-separated buckets which count towards the total spendable balance
+	SpendableBalanceBucketsKey = "SpendableBalanceBuckets"
+	// The entries protected by the seed passphrase are not marked as encrypted in the config,
+	// so that they are left alone when the unlock password is changed.
+	SeedPassphraseCheckKey = "SeedPassphraseCheck"
+	MnemonicBackupKey      = "MnemonicBackup"
 )
 
 type AppConfig struct {
@@ -22,9 +72,19 @@ type AppConfig struct {
 	LNDAddress                         string `envconfig:"LND_ADDRESS"`
 	LNDCertFile                        string `envconfig:"LND_CERT_FILE"`
 	LNDMacaroonFile                    string `envconfig:"LND_MACAROON_FILE"`
+	TapdAddress                        string `envconfig:"TAPD_ADDRESS"`
+	TapdCertFile                       string `envconfig:"TAPD_CERT_FILE"`
+	TapdMacaroonFile                   string `envconfig:"TAPD_MACAROON_FILE"`
 	Workdir                            string `envconfig:"WORK_DIR"`
 	Port                               string `envconfig:"PORT" default:"8080"`
 	DatabaseUri                        string `envconfig:"DATABASE_URI" default:"nwc.db"`
+	DatabaseMaxOpenConns               uint   `envconfig:"DATABASE_MAX_OPEN_CONNS" default:"25"`
+	DatabaseMaxIdleConns               uint   `envconfig:"DATABASE_MAX_IDLE_CONNS" default:"5"`
+	DatabaseConnMaxLifetimeMinutes     uint   `envconfig:"DATABASE_CONN_MAX_LIFETIME_MINUTES" default:"30"`
+	DatabaseMigrationSnapshots         bool   `envconfig:"DATABASE_MIGRATION_SNAPSHOTS" default:"true"`
+	DatabaseReplication                string `envconfig:"DATABASE_REPLICATION"`
+	DatabaseReplicaMaxLagSeconds       uint   `envconfig:"DATABASE_REPLICA_MAX_LAG_SECONDS" default:"60"`
+	EncryptDatabase                    bool   `envconfig:"ENCRYPT_DATABASE" default:"false"`
 	JWTSecret                          string `envconfig:"JWT_SECRET"`
 	LogLevel                           string `envconfig:"LOG_LEVEL" default:"4"`
 	LogToFile                          bool   `envconfig:"LOG_TO_FILE" default:"true"`
@@ -60,6 +120,73 @@ type AppConfig struct {
 	AutoUnlockPassword                 string `envconfig:"AUTO_UNLOCK_PASSWORD"`
 	LogDBQueries                       bool   `envconfig:"LOG_DB_QUERIES" default:"false"`
 	BoltzApi                           string `envconfig:"BOLTZ_API" default:"https://api.boltz.exchange"`
+	AppTemplatesManifestUrl            string `envconfig:"APP_TEMPLATES_MANIFEST_URL"`
+	LightningAddressDomain             string `envconfig:"LIGHTNING_ADDRESS_DOMAIN"`
+	MetricsEnabled                     bool   `envconfig:"METRICS_ENABLED" default:"false"`
+	MetricsToken                       string `envconfig:"METRICS_TOKEN"`
+	TracingEnabled                     bool   `envconfig:"TRACING_ENABLED" default:"false"`
+	GrpcAddress                        string `envconfig:"GRPC_ADDRESS"`
+	GrpcTLSCertFile                    string `envconfig:"GRPC_TLS_CERT_FILE"`
+	GrpcTLSKeyFile                     string `envconfig:"GRPC_TLS_KEY_FILE"`
+	TrustProxyHeaders                  bool   `envconfig:"TRUST_PROXY_HEADERS" default:"false"`
+	TrustedProxies                     string `envconfig:"TRUSTED_PROXIES"`
+	RateLimitIpPerMinute               uint   `envconfig:"RATE_LIMIT_IP_PER_MINUTE" default:"0"`
+	RateLimitApiKeyPerMinute           uint   `envconfig:"RATE_LIMIT_API_KEY_PER_MINUTE" default:"0"`
+	RateLimitSessionPerMinute          uint   `envconfig:"RATE_LIMIT_SESSION_PER_MINUTE" default:"0"`
+	BannedIps                          string `envconfig:"BANNED_IPS"`
+	AuthFailureBanThreshold            uint   `envconfig:"AUTH_FAILURE_BAN_THRESHOLD" default:"0"`
+	AuthFailureBanMinutes              uint   `envconfig:"AUTH_FAILURE_BAN_MINUTES" default:"60"`
+	TotpOnchainThresholdSat            uint   `envconfig:"TOTP_ONCHAIN_THRESHOLD_SAT" default:"1000000"`
+	AutoLockMinutes                    uint   `envconfig:"AUTO_LOCK_MINUTES" default:"0"`
+	WatchOnly                          bool   `envconfig:"WATCH_ONLY" default:"false"`
+	TorEnabled                         bool   `envconfig:"TOR_ENABLED" default:"false"`
+	TorControlAddress                  string `envconfig:"TOR_CONTROL_ADDRESS" default:"127.0.0.1:9051"`
+	TorControlPassword                 string `envconfig:"TOR_CONTROL_PASSWORD"`
+	AcmeDomain                         string `envconfig:"ACME_DOMAIN"`
+	AcmeEmail                          string `envconfig:"ACME_EMAIL"`
+	AcmeChallenge                      string `envconfig:"ACME_CHALLENGE" default:"http-01"`
+	AcmeDirectoryUrl                   string `envconfig:"ACME_DIRECTORY_URL" default:"https://acme-v02.api.letsencrypt.org/directory"`
+	HttpsAddress                       string `envconfig:"HTTPS_ADDRESS" default:":443"`
+	AcmeDnsServer                      string `envconfig:"ACME_DNS_SERVER"`
+	AcmeDnsZone                        string `envconfig:"ACME_DNS_ZONE"`
+	AcmeDnsTsigKey                     string `envconfig:"ACME_DNS_TSIG_KEY"`
+	AcmeDnsTsigSecret                  string `envconfig:"ACME_DNS_TSIG_SECRET"`
+	AcmeDnsTsigAlgorithm               string `envconfig:"ACME_DNS_TSIG_ALGORITHM" default:"hmac-sha256"`
+	AcmeDnsPropagationSeconds          uint   `envconfig:"ACME_DNS_PROPAGATION_SECONDS" default:"60"`
+	UnlockSecretSource                 string `envconfig:"UNLOCK_SECRET_SOURCE"`
+	UnlockSecretFile                   string `envconfig:"UNLOCK_SECRET_FILE"`
+	UnlockSecretAgeIdentityFile        string `envconfig:"UNLOCK_SECRET_AGE_IDENTITY_FILE"`
+	UnlockSecretVaultPath              string `envconfig:"UNLOCK_SECRET_VAULT_PATH"`
+	UnlockSecretVaultField             string `envconfig:"UNLOCK_SECRET_VAULT_FIELD" default:"unlock_password"`
+	UnlockSecretAwsEndpoint            string `envconfig:"UNLOCK_SECRET_AWS_ENDPOINT"`
+	VaultAddress                       string `envconfig:"VAULT_ADDR"`
+	VaultToken                         string `envconfig:"VAULT_TOKEN"`
+	AwsRegion                          string `envconfig:"AWS_REGION"`
+	AwsAccessKeyId                     string `envconfig:"AWS_ACCESS_KEY_ID"`
+	AwsSecretAccessKey                 string `envconfig:"AWS_SECRET_ACCESS_KEY"`
+	AwsSessionToken                    string `envconfig:"AWS_SESSION_TOKEN"`
+	SmtpHost                           string `envconfig:"SMTP_HOST"`
+	SmtpPort                           uint   `envconfig:"SMTP_PORT" default:"587"`
+	SmtpUsername                       string `envconfig:"SMTP_USERNAME"`
+	SmtpPassword                       string `envconfig:"SMTP_PASSWORD"`
+	SmtpFrom                           string `envconfig:"SMTP_FROM"`
+	TelegramBotToken                   string `envconfig:"TELEGRAM_BOT_TOKEN"`
+	TelegramChatIds                    string `envconfig:"TELEGRAM_CHAT_IDS"`
+	TelegramApiUrl                     string `envconfig:"TELEGRAM_API_URL" default:"https://api.telegram.org"`
+	PushProxyUrl                       string `envconfig:"PUSH_PROXY_URL"`
+	EventHooks                         string `envconfig:"EVENT_HOOKS"`
+	LSPS1LSPs                          string `envconfig:"LSPS1_LSPS"`
+	LSPS2LSP                           string `envconfig:"LSPS2_LSP"`
+	LSPS2Token                         string `envconfig:"LSPS2_TOKEN"`
+	LSPS2MaxFeeSat                     uint64 `envconfig:"LSPS2_MAX_FEE_SAT"`
+	FiatRateProviders                  string `envconfig:"FIAT_RATE_PROVIDERS" default:"alby,coingecko,kraken,mempool"`
+	FiatRateCustomURL                  string `envconfig:"FIAT_RATE_CUSTOM_URL"`
+	OnchainFeeEstimator                string `envconfig:"ONCHAIN_FEE_ESTIMATOR" default:"mempool"`
+	OnchainFeeMinRate                  uint64 `envconfig:"ONCHAIN_FEE_MIN_RATE" default:"1"`
+	OnchainFeeMaxRate                  uint64 `envconfig:"ONCHAIN_FEE_MAX_RATE" default:"500"`
+	SpendableBalanceBuckets            string `envconfig:"SPENDABLE_BALANCE_BUCKETS" default:"lightning,ecash"`
+	Plugins                            string `envconfig:"PLUGINS"`
+	ShutdownTimeoutSeconds             uint   `envconfig:"SHUTDOWN_TIMEOUT_SECONDS" default:"30"`
 }
 
 func (c *AppConfig) IsDefaultClientId() bool {
@@ -74,6 +201,19 @@ func (c *AppConfig) GetBaseFrontendUrl() string {
 	return url
 }
 
+// GetLightningAddressDomain returns the domain of the lightning addresses served by the hub,
+// which defaults to the domain of the base URL
+func (c *AppConfig) GetLightningAddressDomain() string {
+	if c.LightningAddressDomain != "" {
+		return c.LightningAddressDomain
+	}
+	baseUrl, err := url.Parse(c.BaseUrl)
+	if err != nil {
+		return ""
+	}
+	return baseUrl.Host
+}
+
 type Config interface {
 	Get(key string, encryptionKey string) (string, error)
 	SetIgnore(key string, value string, encryptionKey string) error
@@ -0,0 +1,25 @@
+package config
+
+import "strconv"
+
+// GetUintSetting returns a setting changed at runtime, or the value from the environment if it was never set
+func GetUintSetting(cfg Config, key string, envValue uint) uint {
+	value, _ := cfg.Get(key, "")
+	if value == "" {
+		return envValue
+	}
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return envValue
+	}
+	return uint(parsed)
+}
+
+// GetStringSetting returns a setting changed at runtime, or the value from the environment if it was never set
+func GetStringSetting(cfg Config, key string, envValue string) string {
+	value, _ := cfg.Get(key, "")
+	if value == "" {
+		return envValue
+	}
+	return value
+}
@@ -10,6 +10,13 @@ const (
 	TRANSACTION_STATE_SETTLED  = "SETTLED"
 	TRANSACTION_STATE_FAILED   = "FAILED"
 	TRANSACTION_STATE_ACCEPTED = "ACCEPTED"
+	TRANSACTION_STATE_EXPIRED  = "EXPIRED" // unpaid incoming invoices past their expiry
+
+	// lifecycle of hold invoices, derived from the transaction state
+	HOLD_INVOICE_STATE_OPEN     = "open"
+	HOLD_INVOICE_STATE_ACCEPTED = "accepted"
+	HOLD_INVOICE_STATE_SETTLED  = "settled"
+	HOLD_INVOICE_STATE_CANCELED = "canceled"
 
 	SWAP_TYPE_IN  = "in"
 	SWAP_TYPE_OUT = "out"
@@ -38,6 +45,24 @@ func GetBudgetRenewals() []string {
 	}
 }
 
+const (
+	PAYMENT_APPROVAL_STATE_PENDING  = "PENDING"
+	PAYMENT_APPROVAL_STATE_APPROVED = "APPROVED"
+	PAYMENT_APPROVAL_STATE_REJECTED = "REJECTED"
+	PAYMENT_APPROVAL_STATE_EXPIRED  = "EXPIRED"
+)
+
+const (
+	APP_DESTINATION_TYPE_NODE              = "node"
+	APP_DESTINATION_TYPE_LIGHTNING_ADDRESS = "lightning_address"
+	APP_DESTINATION_TYPE_LNURL_DOMAIN      = "lnurl_domain"
+)
+
+const (
+	APP_ACTIVITY_RESULT_SUCCESS = "success"
+	APP_ACTIVITY_RESULT_ERROR   = "error"
+)
+
 const (
 	PAY_INVOICE_SCOPE       = "pay_invoice" // also covers pay_keysend and multi_* payment methods
 	GET_BALANCE_SCOPE       = "get_balance"
@@ -54,8 +79,15 @@ const (
 // given a relay limit of 512000 bytes and ideally being able to list 25 transactions,
 // each transaction would have to have a maximum size of 20480
 // accounting for encryption and other metadata in the response, this is set to 4096 characters
+// default limit, can be changed in the settings
 const INVOICE_METADATA_MAX_LENGTH = 4096
 
+// what happens to metadata exceeding the limit
+const (
+	METADATA_POLICY_REJECT   = "reject"
+	METADATA_POLICY_TRUNCATE = "truncate"
+)
+
 // errors used by NIP-47 and the transaction service
 const (
 	ERROR_INTERNAL               = "INTERNAL"
@@ -65,6 +97,7 @@ const (
 	ERROR_UNAUTHORIZED           = "UNAUTHORIZED"
 	ERROR_EXPIRED                = "EXPIRED"
 	ERROR_RESTRICTED             = "RESTRICTED"
+	ERROR_RATE_LIMITED           = "RATE_LIMITED"
 	ERROR_BAD_REQUEST            = "BAD_REQUEST"
 	ERROR_NOT_FOUND              = "NOT_FOUND"
 	ERROR_UNSUPPORTED_ENCRYPTION = "UNSUPPORTED_ENCRYPTION"
@@ -3,6 +3,7 @@ package db
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -12,12 +13,21 @@ import (
 	"github.com/getAlby/hub/db/migrations"
 	sqlite_wrapper "github.com/getAlby/hub/db/sqlite-wrapper"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
 )
 
 type Config struct {
 	URI        string
 	LogQueries bool
 	DriverName string
+	// connection pool settings, only applied to postgres. 0 keeps the defaults of database/sql
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// copy a sqlite database next to it before pending migrations are applied
+	MigrationSnapshots bool
+	// leave checkpoints of a sqlite database to a replication tool like litestream
+	Replicated bool
 }
 
 func NewDB(uri string, logDBQueries bool) (*gorm.DB, error) {
@@ -29,6 +39,31 @@ func NewDB(uri string, logDBQueries bool) (*gorm.DB, error) {
 }
 
 func NewDBWithConfig(cfg *Config) (*gorm.DB, error) {
+	ret, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MigrationSnapshots {
+		if dbPath, ok := SqlitePath(cfg.URI); ok {
+			if _, err := createMigrationSnapshot(ret, dbPath); err != nil {
+				logger.Logger.WithError(err).Error("Failed to create pre-migration snapshot")
+				return nil, err
+			}
+		}
+	}
+
+	err = migrations.Migrate(ret)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to migrate")
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// openDB connects to the database without applying migrations
+func openDB(cfg *Config) (*gorm.DB, error) {
 	gormConfig := &gorm.Config{
 		TranslateError: true,
 	}
@@ -48,6 +83,10 @@ func NewDBWithConfig(cfg *Config) (*gorm.DB, error) {
 		if err != nil {
 			return nil, err
 		}
+		err = configurePool(ret, cfg)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		sqliteURI := cfg.URI
 
@@ -64,6 +103,9 @@ func NewDBWithConfig(cfg *Config) (*gorm.DB, error) {
 		}
 
 		driverName := sqlite_wrapper.Sqlite3WrapperDriverName
+		if cfg.Replicated {
+			driverName = sqlite_wrapper.Sqlite3ReplicatedWrapperDriverName
+		}
 		if cfg.DriverName != "" {
 			driverName = cfg.DriverName
 		}
@@ -82,9 +124,8 @@ func NewDBWithConfig(cfg *Config) (*gorm.DB, error) {
 
 	logger.Logger.WithField("db_backend", ret.Dialector.Name()).Debug("loaded database")
 
-	err := migrations.Migrate(ret)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to migrate")
+	if err := metrics.RegisterDBCallbacks(ret); err != nil {
+		logger.Logger.WithError(err).Error("Failed to register database metrics")
 		return nil, err
 	}
 
@@ -109,6 +150,25 @@ func newPostgresDB(pgConfig postgres.Config, gormConfig *gorm.Config) (*gorm.DB,
 	return gormDB, nil
 }
 
+// configurePool limits the connections to the database server, which are shared
+// with other hubs or containers in multi-container deployments
+func configurePool(db *gorm.DB, cfg *Config) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	return nil
+}
+
 func Stop(db *gorm.DB) error {
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -117,7 +177,7 @@ func Stop(db *gorm.DB) error {
 
 	dbBackend := db.Dialector.Name()
 	logger.Logger.WithField("db_backend", dbBackend).Debug("shutting down database")
-	if dbBackend == "sqlite" {
+	if dbBackend == "sqlite" && !isReplicated(db) {
 		err = db.Exec("PRAGMA wal_checkpoint(FULL)", nil).Error
 		if err != nil {
 			logger.Logger.WithError(err).Error("Failed to execute wal endpoint")
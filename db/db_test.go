@@ -0,0 +1,28 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestConfigurePool(t *testing.T) {
+	gormDB, err := newSqliteDB(sqlite.Config{DSN: ":memory:"}, &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := gormDB.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	err = configurePool(gormDB, &Config{MaxOpenConns: 10, MaxIdleConns: 2, ConnMaxLifetime: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, 10, sqlDB.Stats().MaxOpenConnections)
+
+	// 0 keeps the current settings
+	err = configurePool(gormDB, &Config{})
+	require.NoError(t, err)
+	assert.Equal(t, 10, sqlDB.Stats().MaxOpenConnections)
+}
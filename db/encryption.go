@@ -0,0 +1,367 @@
+package db
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/getAlby/hub/logger"
+)
+
+// Columns tagged with `gorm:"serializer:encrypted"` are encrypted with AES-GCM using a data
+// key which is kept in the user config, encrypted with the unlock password. The key is only
+// known after the hub was unlocked. Until then, and for values written before encryption was
+// switched on, the stored value is passed through unchanged.
+const encryptedValuePrefix = "enc:v1:"
+
+// EncryptedColumns lists the encrypted columns of each table
+var EncryptedColumns = map[string][]string{
+	"transactions":   {"preimage", "description"},
+	"swaps":          {"preimage"},
+	"request_events": {"content_data"},
+	"backup_targets": {"secret_access_key"},
+	"admin_users":    {"totp_secret"},
+	// webhook and email payloads contain invoices, descriptions and preimages
+	"webhook_deliveries": {"payload"},
+	"dead_letters":       {"payload"},
+}
+
+// AlwaysEncryptedColumns are credentials which are encrypted even if ENCRYPT_DATABASE is off.
+// They are tagged with `gorm:"serializer:always_encrypted"` and are also listed in EncryptedColumns.
+var AlwaysEncryptedColumns = map[string][]string{
+	"backup_targets": {"secret_access_key"},
+}
+
+var (
+	encryptionMtx sync.RWMutex
+	encryptionGCM cipher.AEAD
+	// the key which is replaced while the data key is rotated
+	previousEncryptionGCM cipher.AEAD
+	// whether new values are encrypted, existing values can be decrypted either way
+	encryptWrites bool
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+	schema.RegisterSerializer("always_encrypted", encryptedSerializer{always: true})
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SetEncryptionKey sets the 32 byte data key of the encrypted columns. The key is kept in memory
+// until the hub stops, also while spending is locked.
+func SetEncryptionKey(key []byte, encrypt bool) error {
+	return SetRotatingEncryptionKey(key, nil, encrypt)
+}
+
+// SetRotatingEncryptionKey sets a new data key while the values encrypted with the previous key
+// are re-encrypted. Values of both keys can be decrypted until the rotation is finished.
+func SetRotatingEncryptionKey(key []byte, previousKey []byte, encrypt bool) error {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	var previousGCM cipher.AEAD
+	if previousKey != nil {
+		previousGCM, err = newGCM(previousKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	encryptionMtx.Lock()
+	defer encryptionMtx.Unlock()
+	encryptionGCM = gcm
+	previousEncryptionGCM = previousGCM
+	encryptWrites = encrypt
+	return nil
+}
+
+// Encrypt returns the value to store in an encrypted column. It is only needed for
+// updates with a map or column name, which do not apply the serializer of the model.
+func Encrypt(value string) string {
+	return encrypt(value, false)
+}
+
+// encrypt encrypts the value if encryption is switched on, or always if always is set
+func encrypt(value string, always bool) string {
+	encryptionMtx.RLock()
+	defer encryptionMtx.RUnlock()
+	if (!encryptWrites && !always) || encryptionGCM == nil || value == "" || strings.HasPrefix(value, encryptedValuePrefix) {
+		return value
+	}
+
+	encryptedValue, err := seal(encryptionGCM, value)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to generate nonce, storing value unencrypted")
+		return value
+	}
+	return encryptedValue
+}
+
+func seal(gcm cipher.AEAD, value string) (string, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedValuePrefix + base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+func decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+
+	encryptionMtx.RLock()
+	defer encryptionMtx.RUnlock()
+	if encryptionGCM == nil {
+		// the hub is not unlocked yet
+		return value, nil
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil {
+		return "", err
+	}
+	nonceSize := encryptionGCM.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return "", errors.New("encrypted value is too short")
+	}
+	plaintext, err := encryptionGCM.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil && previousEncryptionGCM != nil {
+		// not re-encrypted yet
+		plaintext, err = previousEncryptionGCM.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+type encryptedSerializer struct {
+	always bool
+}
+
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+		return nil
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported value of encrypted column %s: %T", field.DBName, dbValue)
+	}
+
+	plaintext, err := decrypt(value)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt column %s: %w", field.DBName, err)
+	}
+
+	if field.FieldType.Kind() == reflect.Ptr {
+		return field.Set(ctx, dst, &plaintext)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+func (serializer encryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	switch v := fieldValue.(type) {
+	case string:
+		return encrypt(v, serializer.always), nil
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		return encrypt(*v, serializer.always), nil
+	default:
+		return nil, fmt.Errorf("unsupported type of encrypted column %s: %T", field.DBName, fieldValue)
+	}
+}
+
+// EncryptExistingValues encrypts the values which were stored before encryption was switched on.
+// Without ENCRYPT_DATABASE only the AlwaysEncryptedColumns are encrypted.
+func EncryptExistingValues(gormDB *gorm.DB) error {
+	encryptionMtx.RLock()
+	hasKey := encryptionGCM != nil
+	encryptAll := encryptWrites
+	encryptionMtx.RUnlock()
+	if !hasKey {
+		return nil
+	}
+
+	for table, columns := range EncryptedColumns {
+		for _, column := range columns {
+			always := slices.Contains(AlwaysEncryptedColumns[table], column)
+			if !encryptAll && !always {
+				continue
+			}
+			count := 0
+			for {
+				var rows []struct {
+					ID    uint
+					Value string
+				}
+				err := gormDB.Table(table).
+					Select("id, "+column+" AS value").
+					Where(column+" IS NOT NULL AND "+column+" != '' AND "+column+" NOT LIKE ?", encryptedValuePrefix+"%").
+					Limit(1000).
+					Scan(&rows).Error
+				if err != nil {
+					return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+				}
+				if len(rows) == 0 {
+					break
+				}
+				for _, row := range rows {
+					encryptedValue := encrypt(row.Value, always)
+					if !strings.HasPrefix(encryptedValue, encryptedValuePrefix) {
+						return fmt.Errorf("failed to encrypt %s.%s", table, column)
+					}
+					err := gormDB.Table(table).Where("id = ?", row.ID).Update(column, encryptedValue).Error
+					if err != nil {
+						return fmt.Errorf("failed to encrypt %s.%s: %w", table, column, err)
+					}
+				}
+				count += len(rows)
+			}
+			if count > 0 {
+				logger.Logger.WithField("table", table).WithField("column", column).WithField("count", count).Info("Encrypted existing values")
+			}
+		}
+	}
+	return nil
+}
+
+// KeyRotationProgress is saved after every batch of re-encrypted values, so that an
+// interrupted rotation continues after the last re-encrypted row
+type KeyRotationProgress struct {
+	StartedAt time.Time `json:"startedAt"`
+	// the column which is currently re-encrypted, columns are processed in alphabetical order
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	LastId uint   `json:"lastId"`
+	// the values which had to be re-encrypted when the rotation started
+	TotalValues      int64  `json:"totalValues"`
+	ReencryptedCount int64  `json:"reencryptedCount"`
+	Error            string `json:"error,omitempty"`
+}
+
+func sortedEncryptedColumns() [][2]string {
+	columns := [][2]string{}
+	for table, tableColumns := range EncryptedColumns {
+		for _, column := range tableColumns {
+			columns = append(columns, [2]string{table, column})
+		}
+	}
+	slices.SortFunc(columns, func(a, b [2]string) int {
+		return strings.Compare(a[0]+"."+a[1], b[0]+"."+b[1])
+	})
+	return columns
+}
+
+// CountEncryptedValues returns the number of values which a key rotation re-encrypts
+func CountEncryptedValues(gormDB *gorm.DB) (int64, error) {
+	var total int64
+	for _, column := range sortedEncryptedColumns() {
+		var count int64
+		err := gormDB.Table(column[0]).Where(column[1]+" LIKE ?", encryptedValuePrefix+"%").Count(&count).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s.%s: %w", column[0], column[1], err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// ReencryptValues encrypts the values of all encrypted columns with the current data key,
+// starting after the position of the progress. Values which were stored unencrypted are
+// left for EncryptExistingValues.
+func ReencryptValues(ctx context.Context, gormDB *gorm.DB, progress *KeyRotationProgress, saveProgress func(*KeyRotationProgress) error) error {
+	encryptionMtx.RLock()
+	gcm := encryptionGCM
+	encryptionMtx.RUnlock()
+	if gcm == nil {
+		return errors.New("encryption key is not set")
+	}
+
+	for _, column := range sortedEncryptedColumns() {
+		table, columnName := column[0], column[1]
+		if progress.Table != "" && strings.Compare(table+"."+columnName, progress.Table+"."+progress.Column) < 0 {
+			continue
+		}
+		if progress.Table != table || progress.Column != columnName {
+			progress.Table = table
+			progress.Column = columnName
+			progress.LastId = 0
+		}
+
+		for {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var rows []struct {
+				ID    uint
+				Value string
+			}
+			err := gormDB.Table(table).
+				Select("id, "+columnName+" AS value").
+				Where("id > ? AND "+columnName+" LIKE ?", progress.LastId, encryptedValuePrefix+"%").
+				Order("id").
+				Limit(1000).
+				Scan(&rows).Error
+			if err != nil {
+				return fmt.Errorf("failed to read %s.%s: %w", table, columnName, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+			err = gormDB.Transaction(func(tx *gorm.DB) error {
+				for _, row := range rows {
+					plaintext, err := decrypt(row.Value)
+					if err != nil {
+						return fmt.Errorf("failed to decrypt %s.%s of row %d: %w", table, columnName, row.ID, err)
+					}
+					encryptedValue, err := seal(gcm, plaintext)
+					if err != nil {
+						return err
+					}
+					err = tx.Table(table).Where("id = ?", row.ID).Update(columnName, encryptedValue).Error
+					if err != nil {
+						return fmt.Errorf("failed to re-encrypt %s.%s: %w", table, columnName, err)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			progress.LastId = rows[len(rows)-1].ID
+			progress.ReencryptedCount += int64(len(rows))
+			if err := saveProgress(progress); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/logger"
+)
+
+func TestEncryptedColumns(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	gormDB, err := NewDBWithConfig(&Config{URI: "file:encrypted_columns?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	defer Stop(gormDB)
+	t.Cleanup(func() {
+		encryptionGCM = nil
+		encryptWrites = false
+	})
+
+	rawValue := func(id uint, column string) string {
+		var value string
+		require.NoError(t, gormDB.Table("transactions").Select(column).Where("id = ?", id).Scan(&value).Error)
+		return value
+	}
+
+	preimage := "0101"
+	unencrypted := Transaction{Type: "incoming", Description: "coffee", Preimage: &preimage}
+	require.NoError(t, gormDB.Create(&unencrypted).Error)
+	assert.Equal(t, "coffee", rawValue(unencrypted.ID, "description"))
+
+	key := make([]byte, 32)
+	require.NoError(t, SetEncryptionKey(key, true))
+
+	encrypted := Transaction{Type: "incoming", Description: "pizza", Preimage: &preimage}
+	require.NoError(t, gormDB.Create(&encrypted).Error)
+	assert.True(t, strings.HasPrefix(rawValue(encrypted.ID, "description"), encryptedValuePrefix))
+	assert.True(t, strings.HasPrefix(rawValue(encrypted.ID, "preimage"), encryptedValuePrefix))
+
+	// values stored before encryption was switched on are encrypted afterwards
+	require.NoError(t, EncryptExistingValues(gormDB))
+	assert.True(t, strings.HasPrefix(rawValue(unencrypted.ID, "description"), encryptedValuePrefix))
+
+	var transactions []Transaction
+	require.NoError(t, gormDB.Order("id").Find(&transactions).Error)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "coffee", transactions[0].Description)
+	assert.Equal(t, "pizza", transactions[1].Description)
+	assert.Equal(t, preimage, *transactions[1].Preimage)
+
+	// without the key the stored value is passed through
+	encryptionGCM = nil
+	var locked Transaction
+	require.NoError(t, gormDB.First(&locked, encrypted.ID).Error)
+	assert.True(t, strings.HasPrefix(locked.Description, encryptedValuePrefix))
+
+	// a wrong key fails instead of returning garbage
+	wrongKey := make([]byte, 32)
+	wrongKey[0] = 1
+	require.NoError(t, SetEncryptionKey(wrongKey, true))
+	assert.Error(t, gormDB.First(&locked, encrypted.ID).Error)
+}
+
+func TestReencryptValues(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	gormDB, err := NewDBWithConfig(&Config{URI: "file:reencrypt_values?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	defer Stop(gormDB)
+	t.Cleanup(func() {
+		encryptionGCM = nil
+		previousEncryptionGCM = nil
+		encryptWrites = false
+	})
+
+	previousKey := make([]byte, 32)
+	require.NoError(t, SetEncryptionKey(previousKey, true))
+	for _, description := range []string{"coffee", "pizza", "beer"} {
+		require.NoError(t, gormDB.Create(&Transaction{Type: "incoming", Description: description}).Error)
+	}
+	count, err := CountEncryptedValues(gormDB)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	key := make([]byte, 32)
+	key[0] = 1
+	require.NoError(t, SetRotatingEncryptionKey(key, previousKey, true))
+
+	// interrupted after the first row
+	progress := &KeyRotationProgress{Table: "transactions", Column: "description", LastId: 1}
+	savedProgress := []KeyRotationProgress{}
+	err = ReencryptValues(context.TODO(), gormDB, progress, func(progress *KeyRotationProgress) error {
+		savedProgress = append(savedProgress, *progress)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), progress.ReencryptedCount)
+	require.NotEmpty(t, savedProgress)
+	assert.Equal(t, uint(3), savedProgress[0].LastId)
+
+	// values of both keys can be read during the rotation
+	var transactions []Transaction
+	require.NoError(t, gormDB.Order("id").Find(&transactions).Error)
+	require.Len(t, transactions, 3)
+	assert.Equal(t, "coffee", transactions[0].Description)
+	assert.Equal(t, "beer", transactions[2].Description)
+
+	require.NoError(t, SetEncryptionKey(key, true))
+	assert.Error(t, gormDB.First(&Transaction{}, transactions[0].ID).Error)
+	require.NoError(t, gormDB.First(&Transaction{}, transactions[2].ID).Error)
+}
+
+func TestReencryptValues_WebhookPayloads(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	gormDB, err := NewDBWithConfig(&Config{URI: "file:reencrypt_webhook_payloads?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	defer Stop(gormDB)
+	t.Cleanup(func() {
+		encryptionGCM = nil
+		previousEncryptionGCM = nil
+		encryptWrites = false
+	})
+
+	previousKey := make([]byte, 32)
+	require.NoError(t, SetEncryptionKey(previousKey, true))
+	webhook := Webhook{Url: "https://example.com/webhook", Enabled: true}
+	require.NoError(t, gormDB.Create(&webhook).Error)
+	delivery := WebhookDelivery{WebhookId: webhook.ID, EventType: "payment_received", Payload: `{"preimage":"0101"}`}
+	require.NoError(t, gormDB.Create(&delivery).Error)
+	deadLetter := DeadLetter{Consumer: "webhooks", Event: "payment_received", Payload: `{"deliveryId":1}`}
+	require.NoError(t, gormDB.Create(&deadLetter).Error)
+
+	for _, table := range []string{"webhook_deliveries", "dead_letters"} {
+		var value string
+		require.NoError(t, gormDB.Table(table).Select("payload").Scan(&value).Error)
+		assert.True(t, strings.HasPrefix(value, encryptedValuePrefix), table)
+	}
+
+	key := make([]byte, 32)
+	key[0] = 1
+	require.NoError(t, SetRotatingEncryptionKey(key, previousKey, true))
+	progress := &KeyRotationProgress{}
+	require.NoError(t, ReencryptValues(context.TODO(), gormDB, progress, func(progress *KeyRotationProgress) error {
+		return nil
+	}))
+	assert.Equal(t, int64(2), progress.ReencryptedCount)
+
+	// the payloads can be read with the new key only
+	require.NoError(t, SetEncryptionKey(key, true))
+	var storedDelivery WebhookDelivery
+	require.NoError(t, gormDB.First(&storedDelivery, delivery.ID).Error)
+	assert.Equal(t, delivery.Payload, storedDelivery.Payload)
+	var storedDeadLetter DeadLetter
+	require.NoError(t, gormDB.First(&storedDeadLetter, deadLetter.ID).Error)
+	assert.Equal(t, deadLetter.Payload, storedDeadLetter.Payload)
+}
+
+func TestAlwaysEncryptedColumns(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	gormDB, err := NewDBWithConfig(&Config{URI: "file:always_encrypted_columns?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	defer Stop(gormDB)
+	t.Cleanup(func() {
+		encryptionGCM = nil
+		encryptWrites = false
+	})
+
+	rawValue := func(table string, column string, id uint) string {
+		var value string
+		require.NoError(t, gormDB.Table(table).Select(column).Where("id = ?", id).Scan(&value).Error)
+		return value
+	}
+
+	// stored before the hub was unlocked
+	existingTarget := BackupTarget{Name: "existing", Endpoint: "https://s3.example.com", Bucket: "bucket", SecretAccessKey: "secret"}
+	require.NoError(t, gormDB.Create(&existingTarget).Error)
+	transaction := Transaction{Type: "incoming", Description: "coffee"}
+	require.NoError(t, gormDB.Create(&transaction).Error)
+
+	// ENCRYPT_DATABASE is off
+	key := make([]byte, 32)
+	require.NoError(t, SetEncryptionKey(key, false))
+
+	backupTarget := BackupTarget{Name: "new", Endpoint: "https://s3.example.com", Bucket: "bucket", SecretAccessKey: "secret"}
+	require.NoError(t, gormDB.Create(&backupTarget).Error)
+	assert.True(t, strings.HasPrefix(rawValue("backup_targets", "secret_access_key", backupTarget.ID), encryptedValuePrefix))
+
+	require.NoError(t, EncryptExistingValues(gormDB))
+	assert.True(t, strings.HasPrefix(rawValue("backup_targets", "secret_access_key", existingTarget.ID), encryptedValuePrefix))
+	assert.Equal(t, "coffee", rawValue("transactions", "description", transaction.ID))
+
+	var backupTargets []BackupTarget
+	require.NoError(t, gormDB.Order("id").Find(&backupTargets).Error)
+	require.Len(t, backupTargets, 2)
+	assert.Equal(t, "secret", backupTargets[0].SecretAccessKey)
+	assert.Equal(t, "secret", backupTargets[1].SecretAccessKey)
+}
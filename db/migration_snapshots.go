@@ -0,0 +1,291 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db/migrations"
+	"github.com/getAlby/hub/logger"
+)
+
+// Before pending migrations are applied to a sqlite database a copy of it is written to
+// <database>.pre-migration-<last applied migration>. Restoring the copy and starting the
+// previous version of the hub undoes the upgrade, see RestoreMigrationSnapshot.
+const (
+	migrationSnapshotInfix = ".pre-migration-"
+	maxMigrationSnapshots  = 3
+)
+
+type MigrationSnapshot struct {
+	Path string `json:"path"`
+	// the newest migration contained in the snapshot
+	MigrationID string    `json:"migrationId"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type MigrationStatus struct {
+	LastMigration string              `json:"lastMigration"`
+	Pending       []string            `json:"pending"`
+	Snapshots     []MigrationSnapshot `json:"snapshots"`
+}
+
+type MigrationDryRun struct {
+	Pending  []string      `json:"pending"`
+	Duration time.Duration `json:"duration"`
+}
+
+// SqlitePath returns the path of the database file if the URI points to a sqlite file
+func SqlitePath(uri string) (string, bool) {
+	if uri == "" || IsPostgresURI(uri) || strings.HasPrefix(uri, "file:") || strings.Contains(uri, ":memory:") || strings.Contains(uri, "?") {
+		return "", false
+	}
+	return uri, true
+}
+
+// createMigrationSnapshot copies the database if migrations are pending. New databases are not copied.
+func createMigrationSnapshot(gormDB *gorm.DB, dbPath string) (string, error) {
+	pending, err := migrations.PendingMigrations(gormDB)
+	if err != nil {
+		return "", err
+	}
+	lastApplied, err := migrations.LastAppliedMigration(gormDB)
+	if err != nil {
+		return "", err
+	}
+	if len(pending) == 0 || lastApplied == "" {
+		return "", nil
+	}
+
+	snapshotPath := dbPath + migrationSnapshotInfix + lastApplied
+	if _, err := os.Stat(snapshotPath); err == nil {
+		// a previous upgrade failed, the existing snapshot still has the state before it
+		logger.Logger.WithField("path", snapshotPath).Info("Pre-migration snapshot already exists")
+		return snapshotPath, nil
+	}
+
+	startedAt := time.Now()
+	// write to a temporary file first so that an interrupted copy is not mistaken for a snapshot
+	tmpPath := snapshotPath + ".tmp"
+	os.Remove(tmpPath)
+	if err := gormDB.Exec("VACUUM INTO ?", tmpPath).Error; err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to copy database: %w", err)
+	}
+	if err := os.Rename(tmpPath, snapshotPath); err != nil {
+		return "", err
+	}
+	logger.Logger.WithField("path", snapshotPath).
+		WithField("pending_migrations", len(pending)).
+		WithField("duration", time.Since(startedAt).String()).
+		Info("Created pre-migration snapshot")
+
+	if err := pruneMigrationSnapshots(dbPath); err != nil {
+		logger.Logger.WithError(err).Error("Failed to remove old pre-migration snapshots")
+	}
+	return snapshotPath, nil
+}
+
+// ListMigrationSnapshots returns the snapshots of the database, newest first
+func ListMigrationSnapshots(dbPath string) ([]MigrationSnapshot, error) {
+	paths, err := filepath.Glob(dbPath + migrationSnapshotInfix + "*")
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []MigrationSnapshot{}
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".tmp") {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, MigrationSnapshot{
+			Path:        path,
+			MigrationID: strings.TrimPrefix(path, dbPath+migrationSnapshotInfix),
+			SizeBytes:   info.Size(),
+			CreatedAt:   info.ModTime().UTC(),
+		})
+	}
+	// migration IDs start with their date
+	slices.SortFunc(snapshots, func(a, b MigrationSnapshot) int {
+		return strings.Compare(b.MigrationID, a.MigrationID)
+	})
+	return snapshots, nil
+}
+
+func pruneMigrationSnapshots(dbPath string) error {
+	snapshots, err := ListMigrationSnapshots(dbPath)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) <= maxMigrationSnapshots {
+		return nil
+	}
+	for _, snapshot := range snapshots[maxMigrationSnapshots:] {
+		if err := os.Remove(snapshot.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreMigrationSnapshot replaces the database with a pre-migration snapshot. The hub must be
+// stopped, and afterwards the version of the hub which wrote the snapshot has to be started, as
+// a newer version migrates the database again. The replaced database is kept and its path returned.
+func RestoreMigrationSnapshot(dbPath string, snapshotPath string) (string, error) {
+	if !strings.HasPrefix(snapshotPath, dbPath+migrationSnapshotInfix) {
+		return "", errors.New("not a snapshot of this database")
+	}
+	if err := checkIntegrity(snapshotPath); err != nil {
+		return "", err
+	}
+
+	replacedPath := fmt.Sprintf("%s.rolled-back-%d", dbPath, time.Now().Unix())
+	// the write-ahead log belongs to the replaced database
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(dbPath+suffix, replacedPath+suffix)
+		if err != nil && !(suffix != "" && errors.Is(err, os.ErrNotExist)) {
+			return "", fmt.Errorf("failed to move database: %w", err)
+		}
+	}
+	if err := copyFile(snapshotPath, dbPath); err != nil {
+		return "", fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	logger.Logger.WithField("snapshot", snapshotPath).WithField("replaced_database", replacedPath).Info("Restored pre-migration snapshot")
+	return replacedPath, nil
+}
+
+// GetMigrationStatus reads the applied migrations without migrating the database
+func GetMigrationStatus(cfg *Config) (*MigrationStatus, error) {
+	gormDB, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer Stop(gormDB)
+
+	status := &MigrationStatus{Snapshots: []MigrationSnapshot{}}
+	status.LastMigration, err = migrations.LastAppliedMigration(gormDB)
+	if err != nil {
+		return nil, err
+	}
+	status.Pending, err = migrations.PendingMigrations(gormDB)
+	if err != nil {
+		return nil, err
+	}
+	if dbPath, ok := SqlitePath(cfg.URI); ok {
+		status.Snapshots, err = ListMigrationSnapshots(dbPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// DryRunMigrations applies the pending migrations to a copy of the database, or within a
+// transaction which is rolled back for postgres, and reports how long they took.
+// The database itself is not changed.
+func DryRunMigrations(cfg *Config) (*MigrationDryRun, error) {
+	gormDB, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer Stop(gormDB)
+
+	pending, err := migrations.PendingMigrations(gormDB)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := &MigrationDryRun{Pending: pending}
+	if len(pending) == 0 {
+		return dryRun, nil
+	}
+
+	dbPath, isSqlite := SqlitePath(cfg.URI)
+	if !isSqlite {
+		if gormDB.Dialector.Name() != "postgres" {
+			return nil, errors.New("dry runs are only supported for sqlite files and postgres")
+		}
+		startedAt := time.Now()
+		tx := gormDB.Begin()
+		if tx.Error != nil {
+			return nil, tx.Error
+		}
+		defer tx.Rollback()
+		if err := migrations.Migrate(tx); err != nil {
+			return dryRun, fmt.Errorf("migration failed: %w", err)
+		}
+		dryRun.Duration = time.Since(startedAt)
+		return dryRun, nil
+	}
+
+	copyPath := fmt.Sprintf("%s.dry-run-%d", dbPath, time.Now().Unix())
+	defer func() {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(copyPath + suffix)
+		}
+	}()
+	if err := gormDB.Exec("VACUUM INTO ?", copyPath).Error; err != nil {
+		return nil, fmt.Errorf("failed to copy database: %w", err)
+	}
+	copyDB, err := openDB(&Config{URI: copyPath, DriverName: cfg.DriverName})
+	if err != nil {
+		return nil, err
+	}
+	defer Stop(copyDB)
+
+	startedAt := time.Now()
+	if err := migrations.Migrate(copyDB); err != nil {
+		return dryRun, fmt.Errorf("migration failed: %w", err)
+	}
+	dryRun.Duration = time.Since(startedAt)
+	return dryRun, nil
+}
+
+func checkIntegrity(dbPath string) error {
+	gormDB, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var integrityCheck string
+	if err := gormDB.Raw("PRAGMA integrity_check").Scan(&integrityCheck).Error; err != nil {
+		return err
+	}
+	if integrityCheck != "ok" {
+		return fmt.Errorf("snapshot is corrupt: %s", integrityCheck)
+	}
+	return nil
+}
+
+func copyFile(from string, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	destination, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		return err
+	}
+	return destination.Close()
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db/migrations"
+	"github.com/getAlby/hub/logger"
+)
+
+func TestSqlitePath(t *testing.T) {
+	path, ok := SqlitePath("/data/nwc.db")
+	assert.True(t, ok)
+	assert.Equal(t, "/data/nwc.db", path)
+
+	for _, uri := range []string{"", ":memory:", "file:test?mode=memory", "postgresql://user@localhost/hub", "nwc.db?_txlock=immediate"} {
+		_, ok := SqlitePath(uri)
+		assert.False(t, ok, uri)
+	}
+}
+
+func TestMigrationSnapshot(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	dbPath := filepath.Join(t.TempDir(), "nwc.db")
+	gormDB, err := NewDBWithConfig(&Config{URI: dbPath, MigrationSnapshots: true})
+	require.NoError(t, err)
+
+	// a new database is not copied
+	snapshots, err := ListMigrationSnapshots(dbPath)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+
+	lastMigration, err := migrations.LastAppliedMigration(gormDB)
+	require.NoError(t, err)
+	err = gormDB.Table(gormigrate.DefaultOptions.TableName).
+		Where(gormigrate.DefaultOptions.IDColumnName+" = ?", lastMigration).
+		Delete(nil).Error
+	require.NoError(t, err)
+	previousMigration, err := migrations.LastAppliedMigration(gormDB)
+	require.NoError(t, err)
+
+	snapshotPath, err := createMigrationSnapshot(gormDB, dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, dbPath+".pre-migration-"+previousMigration, snapshotPath)
+	Stop(gormDB)
+
+	snapshots, err = ListMigrationSnapshots(dbPath)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, previousMigration, snapshots[0].MigrationID)
+
+	_, err = RestoreMigrationSnapshot(dbPath, filepath.Join(t.TempDir(), "other.db"))
+	assert.Error(t, err)
+
+	replacedPath, err := RestoreMigrationSnapshot(dbPath, snapshotPath)
+	require.NoError(t, err)
+	_, err = os.Stat(replacedPath)
+	assert.NoError(t, err)
+
+	status, err := GetMigrationStatus(&Config{URI: dbPath})
+	require.NoError(t, err)
+	assert.Equal(t, previousMigration, status.LastMigration)
+	assert.Equal(t, []string{lastMigration}, status.Pending)
+}
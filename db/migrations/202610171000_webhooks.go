@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const webhooksMigration = `
+CREATE TABLE webhooks(
+	id {{ .AutoincrementPrimaryKey }},
+	url text NOT NULL,
+	secret text NOT NULL,
+	event_types text,
+	enabled boolean,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE TABLE webhook_deliveries(
+	id {{ .AutoincrementPrimaryKey }},
+	webhook_id integer,
+	event_type text,
+	payload text,
+	state text,
+	attempts integer,
+	response_status integer,
+	error text,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+`
+
+var webhooksMigrationTmpl = template.Must(template.New("webhooksMigration").Parse(webhooksMigration))
+
+var _202610171000_webhooks = &gormigrate.Migration{
+	ID: "202610171000_webhooks",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, webhooksMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var _202610171001_transaction_refunds = &gormigrate.Migration{
+	ID: "202610171001_transaction_refunds",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+	ALTER TABLE transactions ADD COLUMN refund_of_transaction_id integer;
+	CREATE INDEX idx_transactions_refund_of_transaction_id ON transactions(refund_of_transaction_id);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
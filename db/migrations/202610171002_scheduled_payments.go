@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const scheduledPaymentsMigration = `
+CREATE TABLE scheduled_payments(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer,
+	destination text NOT NULL,
+	amount_msat integer NOT NULL,
+	comment text,
+	schedule text NOT NULL,
+	next_run_at {{ .Timestamp }},
+	ends_at {{ .Timestamp }},
+	enabled boolean,
+	attempts integer,
+	last_run_at {{ .Timestamp }},
+	last_error text,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_scheduled_payments_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_scheduled_payments_next_run_at ON scheduled_payments(next_run_at);
+`
+
+var scheduledPaymentsMigrationTmpl = template.Must(template.New("scheduledPaymentsMigration").Parse(scheduledPaymentsMigration))
+
+var _202610171002_scheduled_payments = &gormigrate.Migration{
+	ID: "202610171002_scheduled_payments",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, scheduledPaymentsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
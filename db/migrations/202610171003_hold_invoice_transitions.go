@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const holdInvoiceTransitionsMigration = `
+ALTER TABLE transactions ADD COLUMN accepted_at {{ .Timestamp }};
+ALTER TABLE transactions ADD COLUMN canceled_at {{ .Timestamp }};
+`
+
+var holdInvoiceTransitionsMigrationTmpl = template.Must(template.New("holdInvoiceTransitionsMigration").Parse(holdInvoiceTransitionsMigration))
+
+var _202610171003_hold_invoice_transitions = &gormigrate.Migration{
+	ID: "202610171003_hold_invoice_transitions",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, holdInvoiceTransitionsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
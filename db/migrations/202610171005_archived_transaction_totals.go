@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const archivedTransactionTotalsMigration = `
+CREATE TABLE archived_transaction_totals(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer,
+	type text NOT NULL,
+	count integer NOT NULL,
+	amount_msat bigint NOT NULL,
+	fee_msat bigint NOT NULL,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_archived_transaction_totals_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_archived_transaction_totals_app_id ON archived_transaction_totals(app_id);
+`
+
+var archivedTransactionTotalsMigrationTmpl = template.Must(template.New("archivedTransactionTotalsMigration").Parse(archivedTransactionTotalsMigration))
+
+var _202610171005_archived_transaction_totals = &gormigrate.Migration{
+	ID: "202610171005_archived_transaction_totals",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, archivedTransactionTotalsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
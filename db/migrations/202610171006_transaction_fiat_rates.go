@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const transactionFiatRatesMigration = `
+CREATE TABLE transaction_fiat_rates(
+	id {{ .AutoincrementPrimaryKey }},
+	transaction_id integer NOT NULL,
+	currency text NOT NULL,
+	rate double precision NOT NULL,
+	created_at {{ .Timestamp }},
+	CONSTRAINT fk_transaction_fiat_rates_transaction FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_transaction_fiat_rates_transaction_id_currency ON transaction_fiat_rates(transaction_id, currency);
+`
+
+var transactionFiatRatesMigrationTmpl = template.Must(template.New("transactionFiatRatesMigration").Parse(transactionFiatRatesMigration))
+
+var _202610171006_transaction_fiat_rates = &gormigrate.Migration{
+	ID: "202610171006_transaction_fiat_rates",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, transactionFiatRatesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
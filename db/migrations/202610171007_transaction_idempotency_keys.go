@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// idempotency keys are scoped to an app, payments made by the hub itself have no app
+var _202610171007_transaction_idempotency_keys = &gormigrate.Migration{
+	ID: "202610171007_transaction_idempotency_keys",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+	ALTER TABLE transactions ADD COLUMN idempotency_key text;
+	CREATE UNIQUE INDEX idx_transactions_idempotency_key ON transactions(COALESCE(app_id, 0), idempotency_key) WHERE idempotency_key IS NOT NULL;
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
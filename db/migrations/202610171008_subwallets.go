@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const subwalletsMigration = `
+ALTER TABLE apps ADD COLUMN owner_password_hash text;
+
+CREATE TABLE subwallet_addresses(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer NOT NULL,
+	address text NOT NULL,
+	received_sat bigint NOT NULL DEFAULT 0,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_subwallet_addresses_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_subwallet_addresses_address ON subwallet_addresses(address);
+CREATE INDEX idx_subwallet_addresses_app_id ON subwallet_addresses(app_id);
+`
+
+var subwalletsMigrationTmpl = template.Must(template.New("subwalletsMigration").Parse(subwalletsMigration))
+
+var _202610171008_subwallets = &gormigrate.Migration{
+	ID: "202610171008_subwallets",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, subwalletsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var _202610171009_app_max_routing_fee = &gormigrate.Migration{
+	ID: "202610171009_app_max_routing_fee",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec("ALTER TABLE apps ADD COLUMN max_fee_percent double precision").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE apps ADD COLUMN max_fee_floor_sat bigint").Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
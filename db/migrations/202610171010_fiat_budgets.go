@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var _202610171010_fiat_budgets = &gormigrate.Migration{
+	ID: "202610171010_fiat_budgets",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec("ALTER TABLE app_permissions ADD COLUMN budget_currency text").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE app_permissions ADD COLUMN max_amount_fiat double precision").Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
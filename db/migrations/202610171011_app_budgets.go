@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const appBudgetsMigration = `
+CREATE TABLE app_budgets(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer NOT NULL,
+	max_amount_sat bigint NOT NULL,
+	budget_renewal text NOT NULL,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_app_budgets_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_app_budgets_app_id ON app_budgets(app_id);
+`
+
+var appBudgetsMigrationTmpl = template.Must(template.New("appBudgetsMigration").Parse(appBudgetsMigration))
+
+var _202610171011_app_budgets = &gormigrate.Migration{
+	ID: "202610171011_app_budgets",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appBudgetsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
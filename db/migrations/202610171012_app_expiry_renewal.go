@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const appExpiryRenewalMigration = `
+ALTER TABLE apps ADD COLUMN renewed_at {{ .Timestamp }};
+ALTER TABLE apps ADD COLUMN expiry_notified_at {{ .Timestamp }};
+`
+
+var appExpiryRenewalMigrationTmpl = template.Must(template.New("appExpiryRenewalMigration").Parse(appExpiryRenewalMigration))
+
+var _202610171012_app_expiry_renewal = &gormigrate.Migration{
+	ID: "202610171012_app_expiry_renewal",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appExpiryRenewalMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
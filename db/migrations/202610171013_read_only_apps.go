@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var _202610171013_read_only_apps = &gormigrate.Migration{
+	ID: "202610171013_read_only_apps",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec("ALTER TABLE apps ADD COLUMN read_only boolean NOT NULL DEFAULT false").Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
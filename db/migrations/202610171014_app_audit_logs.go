@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const appAuditLogsMigration = `
+CREATE TABLE app_audit_logs(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer NOT NULL,
+	action text NOT NULL,
+	details text,
+	created_at {{ .Timestamp }},
+	CONSTRAINT fk_app_audit_logs_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_app_audit_logs_app_id ON app_audit_logs(app_id);
+`
+
+var appAuditLogsMigrationTmpl = template.Must(template.New("appAuditLogsMigration").Parse(appAuditLogsMigration))
+
+var _202610171014_app_audit_logs = &gormigrate.Migration{
+	ID: "202610171014_app_audit_logs",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appAuditLogsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
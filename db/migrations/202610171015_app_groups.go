@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const appGroupsMigration = `
+CREATE TABLE app_groups(
+	id {{ .AutoincrementPrimaryKey }},
+	name text NOT NULL,
+	max_amount_sat bigint NOT NULL DEFAULT 0,
+	budget_renewal text NOT NULL,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+ALTER TABLE apps ADD COLUMN app_group_id integer;
+CREATE INDEX idx_apps_app_group_id ON apps(app_group_id);
+`
+
+var appGroupsMigrationTmpl = template.Must(template.New("appGroupsMigration").Parse(appGroupsMigration))
+
+var _202610171015_app_groups = &gormigrate.Migration{
+	ID: "202610171015_app_groups",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appGroupsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const paymentApprovalsMigration = `
+ALTER TABLE apps ADD COLUMN approval_threshold_sat bigint;
+
+CREATE TABLE payment_approvals(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer NOT NULL,
+	request_event_id integer,
+	payment_hash text,
+	destination text,
+	amount_msat bigint NOT NULL,
+	description text,
+	state text NOT NULL,
+	expires_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_payment_approvals_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_payment_approvals_state ON payment_approvals(state);
+`
+
+var paymentApprovalsMigrationTmpl = template.Must(template.New("paymentApprovalsMigration").Parse(paymentApprovalsMigration))
+
+var _202610171016_payment_approvals = &gormigrate.Migration{
+	ID: "202610171016_payment_approvals",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, paymentApprovalsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// request events are regularly pruned, so the activity log keeps its own copy of the request summary
+const appActivityLogsMigration = `
+CREATE TABLE app_activity_logs(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer NOT NULL,
+	request_nostr_id text,
+	method text NOT NULL,
+	params text,
+	result text NOT NULL,
+	error_code text,
+	error_message text,
+	requested_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	CONSTRAINT fk_app_activity_logs_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_app_activity_logs_app_id_created_at ON app_activity_logs(app_id, created_at);
+CREATE INDEX idx_app_activity_logs_created_at ON app_activity_logs(created_at);
+`
+
+var appActivityLogsMigrationTmpl = template.Must(template.New("appActivityLogsMigration").Parse(appActivityLogsMigration))
+
+var _202610171017_app_activity_logs = &gormigrate.Migration{
+	ID: "202610171017_app_activity_logs",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appActivityLogsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// lightning addresses and LNURL domains are stored with the node they were resolved to,
+// since invoices only identify the node that will be paid
+const appDestinationsMigration = `
+CREATE TABLE app_destinations(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer NOT NULL,
+	type text NOT NULL,
+	value text NOT NULL,
+	node_pubkey text NOT NULL,
+	created_at {{ .Timestamp }},
+	CONSTRAINT fk_app_destinations_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_app_destinations_app_id_type_value ON app_destinations(app_id, type, value);
+`
+
+var appDestinationsMigrationTmpl = template.Must(template.New("appDestinationsMigration").Parse(appDestinationsMigration))
+
+var _202610171018_app_destinations = &gormigrate.Migration{
+	ID: "202610171018_app_destinations",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appDestinationsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
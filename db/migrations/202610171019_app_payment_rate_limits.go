@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const appPaymentRateLimitsMigration = `
+ALTER TABLE apps ADD COLUMN max_payments_per_minute integer;
+ALTER TABLE apps ADD COLUMN max_payments_per_day integer;
+
+CREATE INDEX idx_transactions_app_id_type_created_at ON transactions(app_id, type, created_at);
+`
+
+var appPaymentRateLimitsMigrationTmpl = template.Must(template.New("appPaymentRateLimitsMigration").Parse(appPaymentRateLimitsMigration))
+
+var _202610171019_app_payment_rate_limits = &gormigrate.Migration{
+	ID: "202610171019_app_payment_rate_limits",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appPaymentRateLimitsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// webhooks without an app receive the events of all apps
+const appWebhooksMigration = `
+ALTER TABLE webhooks ADD COLUMN app_id integer REFERENCES apps(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_webhooks_app_id ON webhooks(app_id);
+`
+
+var appWebhooksMigrationTmpl = template.Must(template.New("appWebhooksMigration").Parse(appWebhooksMigration))
+
+var _202610171020_app_webhooks = &gormigrate.Migration{
+	ID: "202610171020_app_webhooks",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appWebhooksMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
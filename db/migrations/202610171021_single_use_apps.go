@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const singleUseAppsMigration = `
+ALTER TABLE apps ADD COLUMN single_use boolean;
+`
+
+var singleUseAppsMigrationTmpl = template.Must(template.New("singleUseAppsMigration").Parse(singleUseAppsMigration))
+
+var _202610171021_single_use_apps = &gormigrate.Migration{
+	ID: "202610171021_single_use_apps",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, singleUseAppsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// profiles are cached by pubkey, since several connections can belong to the same nostr user
+const nostrProfilesMigration = `
+ALTER TABLE apps ADD COLUMN nostr_pubkey text;
+
+CREATE TABLE nostr_profiles(
+	id {{ .AutoincrementPrimaryKey }},
+	pubkey text NOT NULL,
+	name text,
+	picture text,
+	about text,
+	fetched_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE UNIQUE INDEX idx_nostr_profiles_pubkey ON nostr_profiles(pubkey);
+`
+
+var nostrProfilesMigrationTmpl = template.Must(template.New("nostrProfilesMigration").Parse(nostrProfilesMigration))
+
+var _202610171022_nostr_profiles = &gormigrate.Migration{
+	ID: "202610171022_nostr_profiles",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, nostrProfilesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const receiveOnlyAppsMigration = `
+ALTER TABLE apps ADD COLUMN receive_only boolean;
+`
+
+var receiveOnlyAppsMigrationTmpl = template.Must(template.New("receiveOnlyAppsMigration").Parse(receiveOnlyAppsMigration))
+
+var _202610171023_receive_only_apps = &gormigrate.Migration{
+	ID: "202610171023_receive_only_apps",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, receiveOnlyAppsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// admin users log in with their own password and can only manage the apps linked to them
+const adminUsersMigration = `
+CREATE TABLE admin_users(
+	id {{ .AutoincrementPrimaryKey }},
+	name text NOT NULL,
+	password_hash text NOT NULL,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE UNIQUE INDEX idx_admin_users_name ON admin_users(name);
+
+CREATE TABLE admin_user_apps(
+	id {{ .AutoincrementPrimaryKey }},
+	admin_user_id integer NOT NULL,
+	app_id integer NOT NULL,
+	created_at {{ .Timestamp }},
+	CONSTRAINT fk_admin_user_apps_admin_user FOREIGN KEY (admin_user_id) REFERENCES admin_users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_admin_user_apps_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_admin_user_apps_admin_user_id_app_id ON admin_user_apps(admin_user_id, app_id);
+`
+
+var adminUsersMigrationTmpl = template.Must(template.New("adminUsersMigration").Parse(adminUsersMigration))
+
+var _202610171024_admin_users = &gormigrate.Migration{
+	ID: "202610171024_admin_users",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, adminUsersMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
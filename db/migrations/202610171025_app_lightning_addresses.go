@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// the username of a lightning address served by the hub itself, e.g. username@hub-domain
+const appLightningAddressesMigration = `
+ALTER TABLE apps ADD COLUMN lightning_address_username text;
+
+CREATE UNIQUE INDEX idx_apps_lightning_address_username ON apps(lightning_address_username);
+`
+
+var appLightningAddressesMigrationTmpl = template.Must(template.New("appLightningAddressesMigration").Parse(appLightningAddressesMigration))
+
+var _202610171025_app_lightning_addresses = &gormigrate.Migration{
+	ID: "202610171025_app_lightning_addresses",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appLightningAddressesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
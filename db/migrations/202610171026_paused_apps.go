@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const pausedAppsMigration = `
+ALTER TABLE apps ADD COLUMN paused_at {{ .Timestamp }};
+`
+
+var pausedAppsMigrationTmpl = template.Must(template.New("pausedAppsMigration").Parse(pausedAppsMigration))
+
+var _202610171026_paused_apps = &gormigrate.Migration{
+	ID: "202610171026_paused_apps",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, pausedAppsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// api keys authenticate scripts against the automation API. Only a hash of the key is stored.
+const apiKeysMigration = `
+CREATE TABLE api_keys(
+	id {{ .AutoincrementPrimaryKey }},
+	name text NOT NULL,
+	key_hash text NOT NULL,
+	key_prefix text NOT NULL,
+	scopes text NOT NULL,
+	expires_at {{ .Timestamp }},
+	revoked_at {{ .Timestamp }},
+	last_used_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys(key_hash);
+`
+
+var apiKeysMigrationTmpl = template.Must(template.New("apiKeysMigration").Parse(apiKeysMigration))
+
+var _202610171027_api_keys = &gormigrate.Migration{
+	ID: "202610171027_api_keys",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, apiKeysMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
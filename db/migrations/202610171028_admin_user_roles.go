@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// admin users created before roles existed could only manage their apps
+const adminUserRolesMigration = `
+ALTER TABLE admin_users ADD COLUMN role text NOT NULL DEFAULT 'app_manager';
+`
+
+var adminUserRolesMigrationTmpl = template.Must(template.New("adminUserRolesMigration").Parse(adminUserRolesMigration))
+
+var _202610171028_admin_user_roles = &gormigrate.Migration{
+	ID: "202610171028_admin_user_roles",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, adminUserRolesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
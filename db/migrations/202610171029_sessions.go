@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// sessions track the issued login tokens, so that they can be listed and revoked
+const sessionsMigration = `
+CREATE TABLE sessions(
+	id {{ .AutoincrementPrimaryKey }},
+	token_id text NOT NULL,
+	permission text NOT NULL,
+	admin_user_id integer,
+	app_id integer,
+	ip_address text,
+	user_agent text,
+	expires_at {{ .Timestamp }} NOT NULL,
+	last_used_at {{ .Timestamp }},
+	revoked_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE UNIQUE INDEX idx_sessions_token_id ON sessions(token_id);
+`
+
+var sessionsMigrationTmpl = template.Must(template.New("sessionsMigration").Parse(sessionsMigration))
+
+var _202610171029_sessions = &gormigrate.Migration{
+	ID: "202610171029_sessions",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, sessionsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
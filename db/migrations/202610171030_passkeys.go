@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// passkeys can be used instead of the unlock password to log in
+const passkeysMigration = `
+CREATE TABLE passkeys(
+	id {{ .AutoincrementPrimaryKey }},
+	name text NOT NULL,
+	credential_id text NOT NULL,
+	public_key text NOT NULL,
+	sign_count integer NOT NULL DEFAULT 0,
+	last_used_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE UNIQUE INDEX idx_passkeys_credential_id ON passkeys(credential_id);
+`
+
+var passkeysMigrationTmpl = template.Must(template.New("passkeysMigration").Parse(passkeysMigration))
+
+var _202610171030_passkeys = &gormigrate.Migration{
+	ID: "202610171030_passkeys",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, passkeysMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// backup targets receive encrypted snapshots of the hub on a schedule
+const backupTargetsMigration = `
+CREATE TABLE backup_targets(
+	id {{ .AutoincrementPrimaryKey }},
+	name text NOT NULL,
+	endpoint text NOT NULL,
+	region text,
+	bucket text NOT NULL,
+	prefix text,
+	access_key_id text,
+	secret_access_key text,
+	interval_hours integer NOT NULL DEFAULT 24,
+	keep_last integer NOT NULL DEFAULT 0,
+	keep_days integer NOT NULL DEFAULT 0,
+	enabled boolean NOT NULL DEFAULT true,
+	last_backup_at {{ .Timestamp }},
+	last_verified_at {{ .Timestamp }},
+	last_error text,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+`
+
+var backupTargetsMigrationTmpl = template.Must(template.New("backupTargetsMigration").Parse(backupTargetsMigration))
+
+var _202610171031_backup_targets = &gormigrate.Migration{
+	ID: "202610171031_backup_targets",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, backupTargetsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
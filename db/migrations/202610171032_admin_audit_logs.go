@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// administrative actions, each entry contains the hash of the previous one
+const adminAuditLogsMigration = `
+CREATE TABLE admin_audit_logs(
+	id {{ .AutoincrementPrimaryKey }},
+	action text NOT NULL,
+	actor text NOT NULL,
+	remote_ip text,
+	details text,
+	prev_hash text NOT NULL,
+	hash text NOT NULL,
+	created_at {{ .Timestamp }}
+);
+`
+
+var adminAuditLogsMigrationTmpl = template.Must(template.New("adminAuditLogsMigration").Parse(adminAuditLogsMigration))
+
+// entries can only be appended, changing or removing one requires dropping the triggers
+const adminAuditLogsSqliteTriggers = `
+CREATE TRIGGER admin_audit_logs_no_update BEFORE UPDATE ON admin_audit_logs
+BEGIN
+	SELECT RAISE(ABORT, 'admin audit log is append-only');
+END;
+CREATE TRIGGER admin_audit_logs_no_delete BEFORE DELETE ON admin_audit_logs
+BEGIN
+	SELECT RAISE(ABORT, 'admin audit log is append-only');
+END;
+`
+
+const adminAuditLogsPostgresTriggers = `
+CREATE FUNCTION admin_audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'admin audit log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER admin_audit_logs_append_only BEFORE UPDATE OR DELETE ON admin_audit_logs
+	FOR EACH ROW EXECUTE FUNCTION admin_audit_logs_append_only();
+`
+
+var _202610171032_admin_audit_logs = &gormigrate.Migration{
+	ID: "202610171032_admin_audit_logs",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, adminAuditLogsMigrationTmpl); err != nil {
+			return err
+		}
+
+		triggers := adminAuditLogsSqliteTriggers
+		if tx.Dialector.Name() == "postgres" {
+			triggers = adminAuditLogsPostgresTriggers
+		}
+		if err := tx.Exec(triggers).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const webhookFiltersMigration = `
+ALTER TABLE webhooks ADD COLUMN filter text;
+`
+
+var webhookFiltersMigrationTmpl = template.Must(template.New("webhookFiltersMigration").Parse(webhookFiltersMigration))
+
+var _202610171033_webhook_filters = &gormigrate.Migration{
+	ID: "202610171033_webhook_filters",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, webhookFiltersMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
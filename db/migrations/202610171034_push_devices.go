@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// push devices receive payment and security alerts through UnifiedPush or a push proxy
+const pushDevicesMigration = `
+CREATE TABLE push_devices(
+	id {{ .AutoincrementPrimaryKey }},
+	name text NOT NULL,
+	transport text NOT NULL,
+	token text NOT NULL,
+	last_notified_at {{ .Timestamp }},
+	last_error text,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE UNIQUE INDEX idx_push_devices_transport_token ON push_devices(transport, token);
+`
+
+var pushDevicesMigrationTmpl = template.Must(template.New("pushDevicesMigration").Parse(pushDevicesMigration))
+
+var _202610171034_push_devices = &gormigrate.Migration{
+	ID: "202610171034_push_devices",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, pushDevicesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// dead letters are events which a consumer failed to handle, kept until they are replayed
+const deadLettersMigration = `
+CREATE TABLE dead_letters(
+	id {{ .AutoincrementPrimaryKey }},
+	consumer text NOT NULL,
+	event text NOT NULL,
+	payload text NOT NULL,
+	error text,
+	state text NOT NULL,
+	replay_attempts integer NOT NULL DEFAULT 0,
+	replayed_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE INDEX idx_dead_letters_consumer_state ON dead_letters(consumer, state);
+`
+
+var deadLettersMigrationTmpl = template.Must(template.New("deadLettersMigration").Parse(deadLettersMigration))
+
+var _202610171035_dead_letters = &gormigrate.Migration{
+	ID: "202610171035_dead_letters",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, deadLettersMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
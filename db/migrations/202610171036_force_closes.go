@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// force-closed channels are tracked until their timelocked funds can be spent
+const forceClosesMigration = `
+CREATE TABLE force_closes(
+	id {{ .AutoincrementPrimaryKey }},
+	channel_id text,
+	counterparty_node_id text,
+	reason text,
+	initiator text,
+	funding_tx_id text,
+	funding_tx_vout integer,
+	pending_amount_sat bigint NOT NULL DEFAULT 0,
+	expected_spendable_at {{ .Timestamp }},
+	spendable_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE INDEX idx_force_closes_spendable_at ON force_closes(spendable_at);
+`
+
+var forceClosesMigrationTmpl = template.Must(template.New("forceClosesMigration").Parse(forceClosesMigration))
+
+var _202610171036_force_closes = &gormigrate.Migration{
+	ID: "202610171036_force_closes",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, forceClosesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const swapRulesMigration = `
+CREATE TABLE swap_rules(
+	id {{ .AutoincrementPrimaryKey }},
+	name text,
+	type text NOT NULL,
+	threshold_sat bigint NOT NULL DEFAULT 0,
+	destination text,
+	min_amount_sat bigint NOT NULL DEFAULT 0,
+	dry_run boolean,
+	enabled boolean,
+	last_run_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE TABLE swap_rule_runs(
+	id {{ .AutoincrementPrimaryKey }},
+	swap_rule_id integer NOT NULL,
+	amount_sat bigint NOT NULL DEFAULT 0,
+	dry_run boolean,
+	swap_id text,
+	error text,
+	created_at {{ .Timestamp }},
+	CONSTRAINT fk_swap_rule_runs_swap_rule FOREIGN KEY (swap_rule_id) REFERENCES swap_rules(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_swap_rule_runs_swap_rule_id ON swap_rule_runs(swap_rule_id);
+`
+
+var swapRulesMigrationTmpl = template.Must(template.New("swapRulesMigration").Parse(swapRulesMigration))
+
+var _202610171037_swap_rules = &gormigrate.Migration{
+	ID: "202610171037_swap_rules",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, swapRulesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
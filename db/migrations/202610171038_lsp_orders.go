@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const lspOrdersMigration = `
+CREATE TABLE lsp_orders(
+	id {{ .AutoincrementPrimaryKey }},
+	lsp_identifier text NOT NULL,
+	network text,
+	order_id text NOT NULL,
+	state text,
+	payment_state text,
+	lsp_balance_sat bigint NOT NULL DEFAULT 0,
+	fee_sat bigint NOT NULL DEFAULT 0,
+	invoice text,
+	funding_outpoint text,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE INDEX idx_lsp_orders_state ON lsp_orders(state);
+`
+
+var lspOrdersMigrationTmpl = template.Must(template.New("lspOrdersMigration").Parse(lspOrdersMigration))
+
+var _202610171038_lsp_orders = &gormigrate.Migration{
+	ID: "202610171038_lsp_orders",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, lspOrdersMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const feePoliciesMigration = `
+CREATE TABLE fee_policies(
+	id {{ .AutoincrementPrimaryKey }},
+	name text,
+	peer_pubkeys text,
+	strategy text NOT NULL,
+	min_local_ratio real NOT NULL DEFAULT 0,
+	max_local_ratio real NOT NULL DEFAULT 1,
+	base_fee_msat integer NOT NULL DEFAULT 0,
+	min_fee_ppm integer NOT NULL DEFAULT 0,
+	max_fee_ppm integer NOT NULL DEFAULT 0,
+	priority integer NOT NULL DEFAULT 0,
+	enabled boolean,
+	last_run_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE TABLE channel_fee_states(
+	id {{ .AutoincrementPrimaryKey }},
+	channel_id text NOT NULL,
+	fee_policy_id integer NOT NULL,
+	local_balance_msat bigint NOT NULL DEFAULT 0,
+	fee_ppm integer NOT NULL DEFAULT 0,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_channel_fee_states_fee_policy FOREIGN KEY (fee_policy_id) REFERENCES fee_policies(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_channel_fee_states_channel_id ON channel_fee_states(channel_id);
+`
+
+var feePoliciesMigrationTmpl = template.Must(template.New("feePoliciesMigration").Parse(feePoliciesMigration))
+
+var _202610171039_fee_policies = &gormigrate.Migration{
+	ID: "202610171039_fee_policies",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, feePoliciesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
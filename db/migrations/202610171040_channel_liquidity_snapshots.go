@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const channelLiquiditySnapshotsMigration = `
+CREATE TABLE channel_liquidity_snapshots(
+	id {{ .AutoincrementPrimaryKey }},
+	channel_id text NOT NULL,
+	peer_pubkey text,
+	local_balance_msat bigint NOT NULL DEFAULT 0,
+	remote_balance_msat bigint NOT NULL DEFAULT 0,
+	active boolean,
+	created_at {{ .Timestamp }}
+);
+
+CREATE INDEX idx_channel_liquidity_snapshots_created_at ON channel_liquidity_snapshots(created_at);
+`
+
+var channelLiquiditySnapshotsMigrationTmpl = template.Must(template.New("channelLiquiditySnapshotsMigration").Parse(channelLiquiditySnapshotsMigration))
+
+var _202610171040_channel_liquidity_snapshots = &gormigrate.Migration{
+	ID: "202610171040_channel_liquidity_snapshots",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, channelLiquiditySnapshotsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var _202610171042_archived_transaction_total_days = &gormigrate.Migration{
+	ID: "202610171042_archived_transaction_total_days",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+	ALTER TABLE archived_transaction_totals ADD COLUMN day text;
+	ALTER TABLE archived_transaction_totals ADD COLUMN fiat_amounts text;
+	CREATE INDEX idx_archived_transaction_totals_day ON archived_transaction_totals(day);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var _202610171043_admin_user_totp = &gormigrate.Migration{
+	ID: "202610171043_admin_user_totp",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+	ALTER TABLE admin_users ADD COLUMN totp_secret text;
+	ALTER TABLE admin_users ADD COLUMN totp_last_step integer NOT NULL DEFAULT 0;
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
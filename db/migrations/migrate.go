@@ -10,8 +10,58 @@ import (
 )
 
 func Migrate(gormDB *gorm.DB) error {
+	return gormigrate.New(gormDB, gormigrate.DefaultOptions, allMigrations()).Migrate()
+}
+
+// PendingMigrations returns the IDs of the migrations which were not applied yet, in the order they run
+func PendingMigrations(gormDB *gorm.DB) ([]string, error) {
+	applied, err := appliedMigrationIDs(gormDB)
+	if err != nil {
+		return nil, err
+	}
+	pending := []string{}
+	for _, migration := range allMigrations() {
+		if !applied[migration.ID] {
+			pending = append(pending, migration.ID)
+		}
+	}
+	return pending, nil
+}
 
-	m := gormigrate.New(gormDB, gormigrate.DefaultOptions, []*gormigrate.Migration{
+// LastAppliedMigration returns the ID of the newest applied migration known to this version,
+// or an empty string for a new database
+func LastAppliedMigration(gormDB *gorm.DB) (string, error) {
+	applied, err := appliedMigrationIDs(gormDB)
+	if err != nil {
+		return "", err
+	}
+	lastApplied := ""
+	for _, migration := range allMigrations() {
+		if applied[migration.ID] {
+			lastApplied = migration.ID
+		}
+	}
+	return lastApplied, nil
+}
+
+func appliedMigrationIDs(gormDB *gorm.DB) (map[string]bool, error) {
+	applied := map[string]bool{}
+	if !gormDB.Migrator().HasTable(gormigrate.DefaultOptions.TableName) {
+		return applied, nil
+	}
+	var ids []string
+	err := gormDB.Table(gormigrate.DefaultOptions.TableName).Pluck(gormigrate.DefaultOptions.IDColumnName, &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		applied[id] = true
+	}
+	return applied, nil
+}
+
+func allMigrations() []*gormigrate.Migration {
+	return []*gormigrate.Migration{
 		_202401191539_initial_migration,
 		_202403171120_delete_ldk_payments,
 		_202404021909_nullable_expires_at,
@@ -38,9 +88,51 @@ func Migrate(gormDB *gorm.DB) error {
 		_202508151405_swap_xpub,
 		_202508192137_forwards,
 		_202509031250_transactions_updated_at_index,
-	})
-
-	return m.Migrate()
+		_202610171000_webhooks,
+		_202610171001_transaction_refunds,
+		_202610171002_scheduled_payments,
+		_202610171003_hold_invoice_transitions,
+		_202610171004_app_max_payment_amount,
+		_202610171005_archived_transaction_totals,
+		_202610171006_transaction_fiat_rates,
+		_202610171007_transaction_idempotency_keys,
+		_202610171008_subwallets,
+		_202610171009_app_max_routing_fee,
+		_202610171010_fiat_budgets,
+		_202610171011_app_budgets,
+		_202610171012_app_expiry_renewal,
+		_202610171013_read_only_apps,
+		_202610171014_app_audit_logs,
+		_202610171015_app_groups,
+		_202610171016_payment_approvals,
+		_202610171017_app_activity_logs,
+		_202610171018_app_destinations,
+		_202610171019_app_payment_rate_limits,
+		_202610171020_app_webhooks,
+		_202610171021_single_use_apps,
+		_202610171022_nostr_profiles,
+		_202610171023_receive_only_apps,
+		_202610171024_admin_users,
+		_202610171025_app_lightning_addresses,
+		_202610171026_paused_apps,
+		_202610171027_api_keys,
+		_202610171028_admin_user_roles,
+		_202610171029_sessions,
+		_202610171030_passkeys,
+		_202610171031_backup_targets,
+		_202610171032_admin_audit_logs,
+		_202610171033_webhook_filters,
+		_202610171034_push_devices,
+		_202610171035_dead_letters,
+		_202610171036_force_closes,
+		_202610171037_swap_rules,
+		_202610171038_lsp_orders,
+		_202610171039_fee_policies,
+		_202610171040_channel_liquidity_snapshots,
+		_202610171041_scheduled_payment_hashes,
+		_202610171042_archived_transaction_total_days,
+		_202610171043_admin_user_totp,
+	}
 }
 
 type sqlDialectDef struct {
@@ -26,6 +26,172 @@ type App struct {
 	LastUsedAt   *time.Time
 	Isolated     bool
 	Metadata     datatypes.JSON
+	// overrides the hub-wide maximum single payment amount if set (0 = no limit)
+	MaxPaymentAmountSat *uint64
+	// allows the owner of an isolated app to log in to their sub-wallet
+	OwnerPasswordHash string
+	// limits the routing fee of each payment to the larger of a percentage of the amount and an absolute floor
+	MaxFeePercent  *float64
+	MaxFeeFloorSat *uint64
+	// start of the current validity period if the connection was renewed after it was created
+	RenewedAt *time.Time
+	// set once the user was notified that the connection expires soon, cleared on renewal
+	ExpiryNotifiedAt *time.Time
+	// read-only connections can never be granted scopes which spend or receive funds
+	ReadOnly bool
+	// receive-only connections are isolated and can never be granted scopes which spend funds.
+	// Their balance can only be moved by the hub owner.
+	ReceiveOnly bool
+	// username of the lightning address the hub serves for this isolated app
+	LightningAddressUsername *string
+	// all requests of a paused app are rejected until it is resumed
+	PausedAt *time.Time
+	// members of a group also draw from the group budget
+	AppGroupId *uint
+	// payments above this amount wait for manual approval
+	ApprovalThresholdSat *uint64
+	// limits how often the app can pay, in addition to its budget
+	MaxPaymentsPerMinute *uint
+	MaxPaymentsPerDay    *uint
+	// revoked after its first payment or paid invoice
+	SingleUse bool
+	// the nostr user the connection belongs to, whose profile is shown with the connection
+	NostrPubkey *string
+}
+
+// NostrProfile caches the kind-0 profile of a nostr user
+type NostrProfile struct {
+	ID        uint
+	Pubkey    string
+	Name      string
+	Picture   string
+	About     string
+	FetchedAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AdminUser is a secondary login. Its role decides whether it can administer the whole hub
+// or only manage the apps linked to it.
+type AdminUser struct {
+	ID           uint
+	Name         string
+	PasswordHash string
+	Role         string
+	// owners and operators need a two-factor code of their own to log in
+	TotpSecret   string `gorm:"serializer:encrypted"`
+	TotpLastStep int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type AdminUserApp struct {
+	ID          uint
+	AdminUserId uint
+	AppId       uint
+	CreatedAt   time.Time
+}
+
+// ApiKey authenticates requests to the automation API, limited to its scopes
+type ApiKey struct {
+	ID        uint
+	Name      string
+	KeyHash   string
+	KeyPrefix string
+	// space-separated list of scopes
+	Scopes     string
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Session is an issued login token, identified by the token id (jti claim) of the JWT
+type Session struct {
+	ID          uint
+	TokenId     string
+	Permission  string
+	AdminUserId *uint
+	AppId       *uint
+	IpAddress   string
+	UserAgent   string
+	ExpiresAt   time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Passkey is a WebAuthn credential which can be used instead of the unlock password to log in
+type Passkey struct {
+	ID           uint
+	Name         string
+	CredentialId string // base64url encoded
+	PublicKey    string // base64url encoded COSE key
+	SignCount    uint32
+	LastUsedAt   *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// PushDevice is a mobile device which receives payment and security alerts
+type PushDevice struct {
+	ID             uint
+	Name           string
+	Transport      string // unifiedpush, fcm or apns
+	Token          string // the UnifiedPush endpoint or the FCM/APNs device token
+	LastNotifiedAt *time.Time
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// BackupTarget is an S3-compatible bucket which receives encrypted snapshots of the hub
+type BackupTarget struct {
+	ID              uint
+	Name            string `validate:"required"`
+	Endpoint        string `validate:"required"`
+	Region          string
+	Bucket          string `validate:"required"`
+	Prefix          string
+	AccessKeyId     string
+	SecretAccessKey string `gorm:"serializer:always_encrypted"`
+	IntervalHours   uint
+	KeepLast        uint // 0 = keep all snapshots
+	KeepDays        uint // 0 = keep snapshots of any age
+	Enabled         bool
+	LastBackupAt    *time.Time
+	LastVerifiedAt  *time.Time
+	LastError       string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// PaymentApproval is a payment of an app waiting for the user to approve or reject it
+type PaymentApproval struct {
+	ID             uint
+	AppId          uint
+	App            *App
+	RequestEventId *uint
+	PaymentHash    string
+	Destination    string // keysend payments only
+	AmountMsat     uint64
+	Description    string
+	State          string
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// AppGroup is a set of apps sharing a budget
+type AppGroup struct {
+	ID            uint
+	Name          string
+	MaxAmountSat  uint64
+	BudgetRenewal string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 type AppPermission struct {
@@ -38,6 +204,9 @@ type AppPermission struct {
 	ExpiresAt     *time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+	// if set, the budget is MaxAmountFiat in this currency instead of MaxAmountSat
+	BudgetCurrency string
+	MaxAmountFiat  float64
 }
 
 type RequestEvent struct {
@@ -45,7 +214,7 @@ type RequestEvent struct {
 	AppId       *uint
 	App         App
 	NostrId     string `validate:"required"`
-	ContentData string
+	ContentData string `gorm:"serializer:encrypted"`
 	Method      string
 	State       string
 	CreatedAt   time.Time
@@ -75,9 +244,9 @@ type Transaction struct {
 	FeeReserveMsat  uint64
 	PaymentRequest  string
 	PaymentHash     string
-	Description     string
+	Description     string `gorm:"serializer:encrypted"`
 	DescriptionHash string
-	Preimage        *string
+	Preimage        *string `gorm:"serializer:encrypted"`
 	CreatedAt       time.Time
 	ExpiresAt       *time.Time
 	UpdatedAt       time.Time
@@ -87,7 +256,115 @@ type Transaction struct {
 	Boostagram      datatypes.JSON
 	FailureReason   string
 	Hold            bool
-	SettleDeadline  *uint32 // block number for accepted hold invoices
+	SettleDeadline  *uint32    // block number for accepted hold invoices
+	AcceptedAt      *time.Time // hold invoices only
+	CanceledAt      *time.Time // hold invoices only
+	// set on outgoing payments which refund an incoming payment
+	RefundOfTransactionId *uint
+	// client supplied key which makes retried payment requests return the original result
+	IdempotencyKey *string
+}
+
+// SubwalletAddress is an on-chain deposit address of an isolated app.
+// Confirmed deposits are credited to the app's balance.
+type SubwalletAddress struct {
+	ID          uint
+	AppId       uint
+	App         *App
+	Address     string
+	ReceivedSat uint64 // confirmed amount already credited to the app
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// AppBudget is an additional spending limit of an app, applied together with
+// the budget of its pay_invoice permission so that the most restrictive one wins.
+type AppBudget struct {
+	ID            uint
+	AppId         uint
+	App           *App
+	MaxAmountSat  uint64
+	BudgetRenewal string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// AppAuditLog records a change to the configuration of an app
+type AppAuditLog struct {
+	ID        uint
+	AppId     uint
+	App       *App
+	Action    string
+	Details   datatypes.JSON
+	CreatedAt time.Time
+}
+
+// AdminAuditLog records an administrative action. Entries are chained by hashing the
+// previous entry, and the table rejects updates and deletes.
+type AdminAuditLog struct {
+	ID       uint
+	Action   string
+	Actor    string
+	RemoteIp string
+	// JSON, stored as text so that the hashed bytes are kept unchanged
+	Details   string
+	PrevHash  string
+	Hash      string
+	CreatedAt time.Time
+}
+
+// AppActivityLog is a NIP-47 request handled for an app and its outcome
+type AppActivityLog struct {
+	ID             uint
+	AppId          uint
+	App            *App
+	RequestNostrId string
+	Method         string
+	Params         datatypes.JSON // summary without secrets and long values
+	Result         string
+	ErrorCode      string
+	ErrorMessage   string
+	RequestedAt    time.Time
+	CreatedAt      time.Time
+}
+
+// AppDestination is a destination an app is allowed to pay. Once an app has destinations,
+// payments to any other node are rejected.
+type AppDestination struct {
+	ID         uint
+	AppId      uint
+	App        *App
+	Type       string
+	Value      string
+	NodePubkey string // the node invoices of the destination are paid to
+	CreatedAt  time.Time
+}
+
+// TransactionFiatRate is the bitcoin price in a fiat currency at the time a transaction settled
+type TransactionFiatRate struct {
+	ID            uint
+	TransactionId uint
+	Transaction   *Transaction
+	Currency      string
+	Rate          float64 // fiat per bitcoin
+	CreatedAt     time.Time
+}
+
+// ArchivedTransactionTotal holds the aggregate of settled transactions
+// which were pruned from the transactions table, per app, type and day,
+// so that isolated app balances, lifetime budgets and summaries remain correct.
+type ArchivedTransactionTotal struct {
+	ID          uint
+	AppId       *uint
+	App         *App
+	Type        string
+	Day         string // YYYY-MM-DD in UTC of the settlement
+	Count       uint64
+	AmountMsat  uint64
+	FeeMsat     uint64
+	FiatAmounts map[string]float64 `gorm:"serializer:json"` // value of the amounts per currency, using the rates stored at settlement
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
 type Swap struct {
@@ -98,7 +375,7 @@ type Swap struct {
 	Invoice            string
 	SendAmount         uint64
 	ReceiveAmount      uint64
-	Preimage           string
+	Preimage           string `gorm:"serializer:encrypted"`
 	PaymentHash        string
 	DestinationAddress string
 	RefundAddress      string
@@ -122,6 +399,166 @@ type Forward struct {
 	UpdatedAt                   time.Time
 }
 
+type Webhook struct {
+	ID         uint
+	Url        string `validate:"required"`
+	Secret     string
+	EventTypes string // comma-separated list of webhook event types
+	Filter     string // JSON of webhooks.Filter, empty if every event is sent
+	Enabled    bool
+	AppId      *uint // only receives the events of this app if set
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type WebhookDelivery struct {
+	ID             uint
+	WebhookId      uint
+	Webhook        Webhook
+	EventType      string
+	Payload        string `gorm:"serializer:encrypted"`
+	State          string
+	Attempts       int
+	ResponseStatus int
+	Error          string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// DeadLetter is an event which a consumer permanently failed to handle. The payload is
+// what the consumer needs to replay it, e.g. the webhook delivery or the email.
+type DeadLetter struct {
+	ID             uint
+	Consumer       string
+	Event          string
+	Payload        string `gorm:"serializer:encrypted"`
+	Error          string
+	State          string
+	ReplayAttempts int
+	ReplayedAt     *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ForceClose is a force-closed channel, tracked until its timelocked funds are swept
+// on-chain and can be spent
+type ForceClose struct {
+	ID                  uint
+	ChannelId           string
+	CounterpartyNodeId  string
+	Reason              string
+	Initiator           string
+	FundingTxId         string
+	FundingTxVout       uint32
+	PendingAmountSat    uint64
+	ExpectedSpendableAt *time.Time
+	SpendableAt         *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type SwapRule struct {
+	ID           uint
+	Name         string
+	Type         string // swap_out_excess or keep_inbound
+	ThresholdSat uint64
+	Destination  string // on-chain address, empty for the node's on-chain wallet
+	MinAmountSat uint64
+	DryRun       bool
+	Enabled      bool
+	LastRunAt    *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// SwapRuleRun records each time a swap rule was triggered
+type SwapRuleRun struct {
+	ID         uint
+	SwapRuleId uint
+	SwapRule   *SwapRule
+	AmountSat  uint64
+	DryRun     bool
+	SwapId     string
+	Error      string
+	CreatedAt  time.Time
+}
+
+// LSPOrder is an LSPS1 channel order, tracked until the channel is opened or the order failed
+type LSPOrder struct {
+	ID              uint
+	LSPIdentifier   string
+	Network         string
+	OrderId         string
+	State           string // CREATED, COMPLETED or FAILED
+	PaymentState    string
+	LSPBalanceSat   uint64
+	FeeSat          uint64
+	Invoice         string
+	FundingOutpoint string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// FeePolicy sets the routing fees of a group of channels, similar to the rules of charge-lnd
+type FeePolicy struct {
+	ID            uint
+	Name          string
+	PeerPubkeys   string // comma-separated peers of the channel group, empty for all channels
+	Strategy      string // static, proportional or flow
+	MinLocalRatio float64
+	MaxLocalRatio float64
+	BaseFeeMsat   uint32
+	MinFeePpm     uint32
+	MaxFeePpm     uint32
+	Priority      int // the first matching policy in order of priority applies
+	Enabled       bool
+	LastRunAt     *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// ChannelFeeState is the state of a channel when its fee policy was last applied
+type ChannelFeeState struct {
+	ID               uint
+	ChannelId        string
+	FeePolicyId      uint
+	LocalBalanceMsat int64
+	FeePpm           uint32
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// ChannelLiquiditySnapshot is the balance of a channel when the balances of all channels were
+// recorded, which share the same CreatedAt
+type ChannelLiquiditySnapshot struct {
+	ID                uint
+	ChannelId         string
+	PeerPubkey        string
+	LocalBalanceMsat  int64
+	RemoteBalanceMsat int64
+	Active            bool
+	CreatedAt         time.Time
+}
+
+type ScheduledPayment struct {
+	ID          uint
+	AppId       *uint
+	App         *App
+	Destination string `validate:"required"` // lightning address
+	AmountMsat  uint64
+	Comment     string
+	Schedule    string // cron expression
+	NextRunAt   time.Time
+	EndsAt      *time.Time
+	Enabled     bool
+	Attempts    int    // failed attempts of the current run
+	PaymentHash string // invoice of the current run, stored before it is paid
+	LastRunAt   *time.Time
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
 const (
 	REQUEST_EVENT_STATE_HANDLER_EXECUTING = "executing"
 	REQUEST_EVENT_STATE_HANDLER_EXECUTED  = "executed"
@@ -132,3 +569,12 @@ const (
 	RESPONSE_EVENT_STATE_PUBLISH_FAILED      = "failed"
 	RESPONSE_EVENT_STATE_PUBLISH_UNCONFIRMED = "unconfirmed"
 )
+const (
+	WEBHOOK_DELIVERY_STATE_PENDING   = "pending"
+	WEBHOOK_DELIVERY_STATE_DELIVERED = "delivered"
+	WEBHOOK_DELIVERY_STATE_FAILED    = "failed"
+)
+const (
+	DEAD_LETTER_STATE_PENDING  = "pending"
+	DEAD_LETTER_STATE_REPLAYED = "replayed"
+)
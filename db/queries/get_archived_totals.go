@@ -0,0 +1,20 @@
+package queries
+
+import (
+	"gorm.io/gorm"
+)
+
+type archivedTotals struct {
+	AmountMsat int64
+	FeeMsat    int64
+}
+
+// getArchivedTotals returns the sum of an app's archived transactions of the given type
+func getArchivedTotals(tx *gorm.DB, appId uint, transactionType string) archivedTotals {
+	var totals archivedTotals
+	tx.
+		Table("archived_transaction_totals").
+		Select("SUM(amount_msat) as amount_msat, SUM(fee_msat) as fee_msat").
+		Where("app_id = ? AND type = ?", appId, transactionType).Scan(&totals)
+	return totals
+}
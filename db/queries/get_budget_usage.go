@@ -8,17 +8,72 @@ import (
 	"gorm.io/gorm"
 )
 
+const msatPerBtc = 100_000_000_000
+
 func GetBudgetUsageSat(tx *gorm.DB, appPermission *db.AppPermission) uint64 {
+	return GetBudgetUsageSatForPeriod(tx, appPermission.AppId, appPermission.BudgetRenewal)
+}
+
+// GetBudgetUsageSatForPeriod returns the spending of an app in the current period of the given budget renewal
+func GetBudgetUsageSatForPeriod(tx *gorm.DB, appId uint, budgetRenewal string) uint64 {
 	var result struct {
 		Sum uint64
 	}
 	tx.
 		Table("transactions").
 		Select("SUM(amount_msat + fee_msat + fee_reserve_msat) as sum").
-		Where("app_id = ? AND type = ? AND (state = ? OR state = ?) AND created_at > ?", appPermission.AppId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, getStartOfBudget(appPermission.BudgetRenewal)).Scan(&result)
+		Where("app_id = ? AND type = ? AND (state = ? OR state = ?) AND created_at > ?", appId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, getStartOfBudget(budgetRenewal)).Scan(&result)
+
+	// archived transactions are older than the start of any renewing budget period
+	if getStartOfBudget(budgetRenewal).IsZero() {
+		archivedSpent := getArchivedTotals(tx, appId, constants.TRANSACTION_TYPE_OUTGOING)
+		result.Sum += uint64(archivedSpent.AmountMsat + archivedSpent.FeeMsat)
+	}
 	return result.Sum / 1000
 }
 
+// GetAppGroupBudgetUsageSat returns the combined spending of all members of an app group in the current budget period
+func GetAppGroupBudgetUsageSat(tx *gorm.DB, appGroup *db.AppGroup) uint64 {
+	memberIds := tx.Table("apps").Select("id").Where("app_group_id = ?", appGroup.ID)
+
+	var result struct {
+		Sum uint64
+	}
+	tx.
+		Table("transactions").
+		Select("SUM(amount_msat + fee_msat + fee_reserve_msat) as sum").
+		Where("app_id IN (?) AND type = ? AND (state = ? OR state = ?) AND created_at > ?", memberIds, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, getStartOfBudget(appGroup.BudgetRenewal)).Scan(&result)
+
+	if getStartOfBudget(appGroup.BudgetRenewal).IsZero() {
+		var archivedSpent archivedTotals
+		tx.
+			Table("archived_transaction_totals").
+			Select("SUM(amount_msat) as amount_msat, SUM(fee_msat) as fee_msat").
+			Where("app_id IN (?) AND type = ?", memberIds, constants.TRANSACTION_TYPE_OUTGOING).Scan(&archivedSpent)
+		result.Sum += uint64(archivedSpent.AmountMsat + archivedSpent.FeeMsat)
+	}
+	return result.Sum / 1000
+}
+
+// GetBudgetUsageFiat returns the spending of an app in the currency of its fiat budget. Each payment
+// is converted at the rate recorded when it was made, falling back to the current rate.
+func GetBudgetUsageFiat(tx *gorm.DB, appPermission *db.AppPermission, currentRate float64) float64 {
+	var result struct {
+		Sum float64
+	}
+	tx.
+		Table("transactions").
+		Joins("LEFT JOIN transaction_fiat_rates ON transaction_fiat_rates.transaction_id = transactions.id AND transaction_fiat_rates.currency = ?", appPermission.BudgetCurrency).
+		Select("SUM((amount_msat + fee_msat + fee_reserve_msat) * COALESCE(transaction_fiat_rates.rate, ?)) as sum", currentRate).
+		Where("app_id = ? AND type = ? AND (state = ? OR state = ?) AND transactions.created_at > ?", appPermission.AppId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, getStartOfBudget(appPermission.BudgetRenewal)).Scan(&result)
+
+	if getStartOfBudget(appPermission.BudgetRenewal).IsZero() {
+		archivedSpent := getArchivedTotals(tx, appPermission.AppId, constants.TRANSACTION_TYPE_OUTGOING)
+		result.Sum += float64(archivedSpent.AmountMsat+archivedSpent.FeeMsat) * currentRate
+	}
+	return result.Sum / msatPerBtc
+}
+
 func getStartOfBudget(budget_type string) time.Time {
 	now := time.Now()
 	switch budget_type {
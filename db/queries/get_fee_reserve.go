@@ -0,0 +1,32 @@
+package queries
+
+import (
+	"time"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"gorm.io/gorm"
+)
+
+// GetFeeReserveMsat returns the routing fees currently reserved by in-flight payments of the app.
+// The reserve is already included in the isolated balance and budget usage, and is released
+// (replaced by the actual fee paid) once the payment settles or fails.
+func GetFeeReserveMsat(tx *gorm.DB, appId uint) uint64 {
+	return getFeeReserveMsatSince(tx, appId, time.Time{})
+}
+
+// GetBudgetFeeReserveMsat returns the part of the current budget usage that is reserved for routing fees
+func GetBudgetFeeReserveMsat(tx *gorm.DB, appPermission *db.AppPermission) uint64 {
+	return getFeeReserveMsatSince(tx, appPermission.AppId, getStartOfBudget(appPermission.BudgetRenewal))
+}
+
+func getFeeReserveMsatSince(tx *gorm.DB, appId uint, since time.Time) uint64 {
+	var result struct {
+		Sum uint64
+	}
+	tx.
+		Table("transactions").
+		Select("SUM(fee_reserve_msat) as sum").
+		Where("app_id = ? AND type = ? AND state = ? AND created_at > ?", appId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_PENDING, since).Scan(&result)
+	return result.Sum
+}
@@ -0,0 +1,57 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestGetFeeReserveMsat(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	svc.DB.Create(&db.Transaction{
+		AppId:          &app.ID,
+		Type:           constants.TRANSACTION_TYPE_OUTGOING,
+		State:          constants.TRANSACTION_STATE_PENDING,
+		AmountMsat:     100_000,
+		FeeReserveMsat: 10_000,
+	})
+	svc.DB.Create(&db.Transaction{
+		AppId:          &app.ID,
+		Type:           constants.TRANSACTION_TYPE_OUTGOING,
+		State:          constants.TRANSACTION_STATE_PENDING,
+		AmountMsat:     20_000,
+		FeeReserveMsat: 10_000,
+	})
+	// settled payments have their reserve released
+	svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat: 100_000,
+		FeeMsat:    1_000,
+	})
+	// reserves of other apps are not counted
+	svc.DB.Create(&db.Transaction{
+		Type:           constants.TRANSACTION_TYPE_OUTGOING,
+		State:          constants.TRANSACTION_STATE_PENDING,
+		AmountMsat:     100_000,
+		FeeReserveMsat: 10_000,
+	})
+
+	assert.Equal(t, uint64(20_000), GetFeeReserveMsat(svc.DB, app.ID))
+	assert.Equal(t, uint64(20_000), GetBudgetFeeReserveMsat(svc.DB, &db.AppPermission{
+		AppId:         app.ID,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	}))
+}
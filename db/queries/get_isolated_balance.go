@@ -23,5 +23,8 @@ func GetIsolatedBalance(tx *gorm.DB, appId uint) int64 {
 		Select("SUM(amount_msat + fee_msat + fee_reserve_msat) as sum").
 		Where("app_id = ? AND type = ? AND (state = ? OR state = ?)", appId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING).Scan(&spent)
 
-	return received.Sum - spent.Sum
+	archivedReceived := getArchivedTotals(tx, appId, constants.TRANSACTION_TYPE_INCOMING)
+	archivedSpent := getArchivedTotals(tx, appId, constants.TRANSACTION_TYPE_OUTGOING)
+
+	return received.Sum + archivedReceived.AmountMsat - spent.Sum - archivedSpent.AmountMsat - archivedSpent.FeeMsat
 }
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/logger"
+)
+
+const ReplicationLitestream = "litestream"
+
+type ReplicationStatus struct {
+	// false if litestream has not started to replicate the database
+	Replicating      bool       `json:"replicating"`
+	LastReplicatedAt *time.Time `json:"lastReplicatedAt,omitempty"`
+	// how long ago the oldest write which was not copied by litestream yet happened at most
+	Lag          time.Duration `json:"lag"`
+	WalSizeBytes int64         `json:"walSizeBytes"`
+}
+
+// isReplicated returns true if the database was opened with the driver which leaves checkpoints to litestream
+func isReplicated(gormDB *gorm.DB) bool {
+	var walAutocheckpoint int
+	if err := gormDB.Raw("PRAGMA wal_autocheckpoint").Scan(&walAutocheckpoint).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to read wal_autocheckpoint")
+		return false
+	}
+	return walAutocheckpoint == 0
+}
+
+// GetLitestreamStatus compares the write-ahead log of the database with the copy of it
+// litestream keeps in .<database>-litestream, next to the database. Litestream copies new
+// frames of the log every second, before it uploads them to the replica.
+func GetLitestreamStatus(dbPath string) (*ReplicationStatus, error) {
+	status := &ReplicationStatus{}
+
+	walInfo, err := os.Stat(dbPath + "-wal")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if walInfo != nil {
+		status.WalSizeBytes = walInfo.Size()
+	}
+
+	dir, name := filepath.Split(dbPath)
+	metaPath := filepath.Join(dir, "."+name+"-litestream")
+	var lastReplicatedAt time.Time
+	err = filepath.WalkDir(metaPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(lastReplicatedAt) {
+			lastReplicatedAt = info.ModTime()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastReplicatedAt.IsZero() {
+		return status, nil
+	}
+
+	status.Replicating = true
+	lastReplicatedAt = lastReplicatedAt.UTC()
+	status.LastReplicatedAt = &lastReplicatedAt
+	if walInfo != nil && walInfo.ModTime().After(lastReplicatedAt) {
+		status.Lag = time.Since(lastReplicatedAt)
+	}
+	return status, nil
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/logger"
+)
+
+func TestGetLitestreamStatus(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "nwc.db")
+	require.NoError(t, os.WriteFile(dbPath+"-wal", make([]byte, 100), 0600))
+
+	status, err := GetLitestreamStatus(dbPath)
+	require.NoError(t, err)
+	assert.False(t, status.Replicating)
+	assert.Equal(t, int64(100), status.WalSizeBytes)
+
+	shadowWalPath := filepath.Join(dir, ".nwc.db-litestream", "generations", "a1b2", "wal", "00000001.wal")
+	require.NoError(t, os.MkdirAll(filepath.Dir(shadowWalPath), 0700))
+	require.NoError(t, os.WriteFile(shadowWalPath, []byte{}, 0600))
+	status, err = GetLitestreamStatus(dbPath)
+	require.NoError(t, err)
+	assert.True(t, status.Replicating)
+	require.NotNil(t, status.LastReplicatedAt)
+	assert.Equal(t, time.Duration(0), status.Lag)
+
+	// the database was written to after litestream copied the log
+	replicatedAt := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, os.Chtimes(shadowWalPath, replicatedAt, replicatedAt))
+	status, err = GetLitestreamStatus(dbPath)
+	require.NoError(t, err)
+	assert.Greater(t, status.Lag, time.Minute)
+}
+
+func TestIsReplicated(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+
+	dir := t.TempDir()
+	gormDB, err := openDB(&Config{URI: filepath.Join(dir, "nwc.db")})
+	require.NoError(t, err)
+	assert.False(t, isReplicated(gormDB))
+	require.NoError(t, Stop(gormDB))
+
+	gormDB, err = openDB(&Config{URI: filepath.Join(dir, "replicated.db"), Replicated: true})
+	require.NoError(t, err)
+	assert.True(t, isReplicated(gormDB))
+	require.NoError(t, Stop(gormDB))
+}
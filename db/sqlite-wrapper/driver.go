@@ -6,7 +6,12 @@ import (
 	"github.com/mattn/go-sqlite3"
 )
 
-const Sqlite3WrapperDriverName = "sqlite3_wrapper"
+const (
+	Sqlite3WrapperDriverName = "sqlite3_wrapper"
+	// checkpoints of the write-ahead log are left to a replication tool like litestream,
+	// which has to copy the log before it is written back to the database
+	Sqlite3ReplicatedWrapperDriverName = "sqlite3_wrapper_replicated"
+)
 
 func init() {
 	// We need to set the temp_store setting on every connection, including
@@ -19,4 +24,13 @@ func init() {
 			return err
 		},
 	})
+	sql.Register(Sqlite3ReplicatedWrapperDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if _, err := conn.Exec("PRAGMA temp_store = MEMORY", nil); err != nil {
+				return err
+			}
+			_, err := conn.Exec("PRAGMA wal_autocheckpoint = 0", nil)
+			return err
+		},
+	})
 }
@@ -0,0 +1,272 @@
+// Package deadletters keeps the events which a consumer permanently failed to handle, e.g.
+// a webhook which failed all its attempts, so they can be inspected and replayed once the
+// consumer works again.
+package deadletters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	CONSUMER_WEBHOOKS            = "webhooks"
+	CONSUMER_EMAIL               = "email"
+	CONSUMER_PUSH                = "push"
+	CONSUMER_NOSTR_DM            = "nostr_dm"
+	CONSUMER_NIP47_NOTIFICATIONS = "nip47_notifications"
+	CONSUMER_EVENT_HOOKS         = "event_hooks"
+)
+
+// maxAutoReplayAttempts is how often a dead letter is replayed automatically, after that
+// it has to be replayed manually or discarded
+const maxAutoReplayAttempts = 10
+
+// autoReplayBatchSize limits the dead letters of a consumer replayed in one run
+const autoReplayBatchSize = 100
+
+// replayed dead letters are removed after this time
+const replayedRetention = 30 * 24 * time.Hour
+
+var autoReplayInterval = 5 * time.Minute
+
+// Replayer is implemented by consumers which can handle their dead letters again
+type Replayer interface {
+	ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error
+}
+
+type DeadLetterService interface {
+	RegisterReplayer(consumer string, replayer Replayer)
+	List(consumer string, state string) ([]db.DeadLetter, error)
+	Replay(ctx context.Context, id uint) (*db.DeadLetter, error)
+	ReplayAll(ctx context.Context, consumer string) (*ReplayResult, error)
+	Discard(id uint) error
+	Start(ctx context.Context)
+}
+
+type ReplayResult struct {
+	Replayed int `json:"replayed"`
+	Failed   int `json:"failed"`
+}
+
+type deadLetterService struct {
+	db          *gorm.DB
+	replayersMu sync.RWMutex
+	replayers   map[string]Replayer
+}
+
+func NewDeadLetterService(gormDB *gorm.DB) *deadLetterService {
+	return &deadLetterService{
+		db:        gormDB,
+		replayers: map[string]Replayer{},
+	}
+}
+
+// Add stores an event which the consumer could not handle. The payload is serialized to
+// JSON and passed back to the consumer's replayer.
+func Add(gormDB *gorm.DB, consumer string, event string, payload interface{}, cause error) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	deadLetter := db.DeadLetter{
+		Consumer: consumer,
+		Event:    event,
+		Payload:  string(payloadBytes),
+		State:    db.DEAD_LETTER_STATE_PENDING,
+	}
+	if cause != nil {
+		deadLetter.Error = cause.Error()
+	}
+	if err := gormDB.Create(&deadLetter).Error; err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"consumer": consumer,
+			"event":    event,
+		}).Error("Failed to store dead letter")
+		return err
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"dead_letter_id": deadLetter.ID,
+		"consumer":       consumer,
+		"event":          event,
+	}).WithError(cause).Warn("Stored dead letter")
+	return nil
+}
+
+func (svc *deadLetterService) RegisterReplayer(consumer string, replayer Replayer) {
+	svc.replayersMu.Lock()
+	defer svc.replayersMu.Unlock()
+	svc.replayers[consumer] = replayer
+}
+
+func (svc *deadLetterService) getReplayer(consumer string) Replayer {
+	svc.replayersMu.RLock()
+	defer svc.replayersMu.RUnlock()
+	return svc.replayers[consumer]
+}
+
+func (svc *deadLetterService) List(consumer string, state string) ([]db.DeadLetter, error) {
+	query := svc.db.Order("id desc")
+	if consumer != "" {
+		query = query.Where("consumer = ?", consumer)
+	}
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	deadLetters := []db.DeadLetter{}
+	if err := query.Find(&deadLetters).Error; err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+func (svc *deadLetterService) Replay(ctx context.Context, id uint) (*db.DeadLetter, error) {
+	var deadLetter db.DeadLetter
+	if svc.db.Limit(1).Find(&deadLetter, id).RowsAffected == 0 {
+		return nil, errors.New("dead letter not found")
+	}
+	if deadLetter.State != db.DEAD_LETTER_STATE_PENDING {
+		return nil, errors.New("dead letter was already replayed")
+	}
+	if err := svc.replay(ctx, &deadLetter); err != nil {
+		return nil, err
+	}
+	return &deadLetter, nil
+}
+
+// ReplayAll replays the pending dead letters of a consumer, or of all consumers
+func (svc *deadLetterService) ReplayAll(ctx context.Context, consumer string) (*ReplayResult, error) {
+	deadLetters, err := svc.List(consumer, db.DEAD_LETTER_STATE_PENDING)
+	if err != nil {
+		return nil, err
+	}
+	result := &ReplayResult{}
+	// oldest first, so the events are handled in the order they happened
+	for i := len(deadLetters) - 1; i >= 0; i-- {
+		if err := svc.replay(ctx, &deadLetters[i]); err != nil {
+			result.Failed++
+			continue
+		}
+		result.Replayed++
+	}
+	return result, nil
+}
+
+func (svc *deadLetterService) Discard(id uint) error {
+	result := svc.db.Delete(&db.DeadLetter{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("dead letter not found")
+	}
+	logger.Logger.WithField("dead_letter_id", id).Info("Discarded dead letter")
+	return nil
+}
+
+// replay passes the dead letter to its consumer and records the outcome
+func (svc *deadLetterService) replay(ctx context.Context, deadLetter *db.DeadLetter) error {
+	replayer := svc.getReplayer(deadLetter.Consumer)
+	if replayer == nil {
+		return errors.New("consumer is not running")
+	}
+
+	replayErr := replayer.ReplayDeadLetter(ctx, deadLetter)
+
+	deadLetter.ReplayAttempts++
+	updates := map[string]interface{}{
+		"replay_attempts": deadLetter.ReplayAttempts,
+	}
+	if replayErr != nil {
+		deadLetter.Error = replayErr.Error()
+		updates["error"] = deadLetter.Error
+	} else {
+		now := time.Now()
+		deadLetter.State = db.DEAD_LETTER_STATE_REPLAYED
+		deadLetter.ReplayedAt = &now
+		updates["state"] = deadLetter.State
+		updates["replayed_at"] = deadLetter.ReplayedAt
+	}
+	if err := svc.db.Model(deadLetter).Updates(updates).Error; err != nil {
+		logger.Logger.WithError(err).WithField("dead_letter_id", deadLetter.ID).Error("Failed to update dead letter")
+	}
+
+	if replayErr != nil {
+		logger.Logger.WithError(replayErr).WithFields(logrus.Fields{
+			"dead_letter_id": deadLetter.ID,
+			"consumer":       deadLetter.Consumer,
+		}).Warn("Failed to replay dead letter")
+		return replayErr
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"dead_letter_id": deadLetter.ID,
+		"consumer":       deadLetter.Consumer,
+	}).Info("Replayed dead letter")
+	return nil
+}
+
+// Start periodically replays the dead letters until ctx is done
+func (svc *deadLetterService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(autoReplayInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				svc.autoReplay(ctx)
+				svc.removeReplayed(time.Now())
+			}
+		}
+	}()
+}
+
+// autoReplay probes every consumer with its oldest dead letter. Once that succeeds the
+// consumer is considered healthy and the rest of its dead letters are replayed, until one fails.
+func (svc *deadLetterService) autoReplay(ctx context.Context) {
+	svc.replayersMu.RLock()
+	consumers := make([]string, 0, len(svc.replayers))
+	for consumer := range svc.replayers {
+		consumers = append(consumers, consumer)
+	}
+	svc.replayersMu.RUnlock()
+
+	for _, consumer := range consumers {
+		deadLetters := []db.DeadLetter{}
+		err := svc.db.
+			Where("consumer = ? AND state = ? AND replay_attempts < ?", consumer, db.DEAD_LETTER_STATE_PENDING, maxAutoReplayAttempts).
+			Order("id").
+			Limit(autoReplayBatchSize).
+			Find(&deadLetters).Error
+		if err != nil {
+			logger.Logger.WithError(err).WithField("consumer", consumer).Error("Failed to list dead letters")
+			continue
+		}
+		for i := range deadLetters {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := svc.replay(ctx, &deadLetters[i]); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func (svc *deadLetterService) removeReplayed(now time.Time) {
+	err := svc.db.
+		Where("state = ? AND replayed_at < ?", db.DEAD_LETTER_STATE_REPLAYED, now.Add(-replayedRetention)).
+		Delete(&db.DeadLetter{}).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to remove replayed dead letters")
+	}
+}
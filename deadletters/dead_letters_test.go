@@ -0,0 +1,134 @@
+package deadletters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+// mockReplayer fails while it is unhealthy and records the replayed payloads
+type mockReplayer struct {
+	healthy  bool
+	replayed []string
+}
+
+func (replayer *mockReplayer) ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error {
+	if !replayer.healthy {
+		return errors.New("relay offline")
+	}
+	var payload string
+	if err := json.Unmarshal([]byte(deadLetter.Payload), &payload); err != nil {
+		return err
+	}
+	replayer.replayed = append(replayer.replayed, payload)
+	return nil
+}
+
+func TestReplay(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	deadLetterSvc := NewDeadLetterService(svc.DB)
+	require.NoError(t, Add(svc.DB, CONSUMER_EMAIL, "large_payment", "first", errors.New("connection refused")))
+
+	deadLetters, err := deadLetterSvc.List(CONSUMER_EMAIL, db.DEAD_LETTER_STATE_PENDING)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "connection refused", deadLetters[0].Error)
+
+	// the consumer is not running
+	_, err = deadLetterSvc.Replay(context.TODO(), deadLetters[0].ID)
+	assert.EqualError(t, err, "consumer is not running")
+
+	replayer := &mockReplayer{}
+	deadLetterSvc.RegisterReplayer(CONSUMER_EMAIL, replayer)
+	_, err = deadLetterSvc.Replay(context.TODO(), deadLetters[0].ID)
+	assert.EqualError(t, err, "relay offline")
+
+	replayer.healthy = true
+	deadLetter, err := deadLetterSvc.Replay(context.TODO(), deadLetters[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.DEAD_LETTER_STATE_REPLAYED, deadLetter.State)
+	assert.Equal(t, 2, deadLetter.ReplayAttempts)
+	assert.NotNil(t, deadLetter.ReplayedAt)
+	assert.Equal(t, []string{"first"}, replayer.replayed)
+
+	_, err = deadLetterSvc.Replay(context.TODO(), deadLetters[0].ID)
+	assert.EqualError(t, err, "dead letter was already replayed")
+}
+
+func TestAutoReplay(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	deadLetterSvc := NewDeadLetterService(svc.DB)
+	emailReplayer := &mockReplayer{}
+	pushReplayer := &mockReplayer{healthy: true}
+	deadLetterSvc.RegisterReplayer(CONSUMER_EMAIL, emailReplayer)
+	deadLetterSvc.RegisterReplayer(CONSUMER_PUSH, pushReplayer)
+	for _, payload := range []string{"first", "second"} {
+		require.NoError(t, Add(svc.DB, CONSUMER_EMAIL, "large_payment", payload, nil))
+		require.NoError(t, Add(svc.DB, CONSUMER_PUSH, "nwc_unlocked", payload, nil))
+	}
+
+	deadLetterSvc.autoReplay(context.TODO())
+	assert.Equal(t, []string{"first", "second"}, pushReplayer.replayed)
+	// the email consumer is probed with its oldest dead letter only
+	deadLetters, err := deadLetterSvc.List(CONSUMER_EMAIL, db.DEAD_LETTER_STATE_PENDING)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 2)
+	assert.Equal(t, 0, deadLetters[0].ReplayAttempts)
+	assert.Equal(t, 1, deadLetters[1].ReplayAttempts)
+
+	emailReplayer.healthy = true
+	deadLetterSvc.autoReplay(context.TODO())
+	assert.Equal(t, []string{"first", "second"}, emailReplayer.replayed)
+	deadLetters, err = deadLetterSvc.List("", db.DEAD_LETTER_STATE_PENDING)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestAutoReplay_SkipsExhaustedDeadLetters(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	deadLetterSvc := NewDeadLetterService(svc.DB)
+	replayer := &mockReplayer{healthy: true}
+	deadLetterSvc.RegisterReplayer(CONSUMER_WEBHOOKS, replayer)
+	require.NoError(t, Add(svc.DB, CONSUMER_WEBHOOKS, "payment_received", "exhausted", nil))
+	require.NoError(t, Add(svc.DB, CONSUMER_WEBHOOKS, "payment_received", "new", nil))
+	require.NoError(t, svc.DB.Model(&db.DeadLetter{}).Where("payload = ?", `"exhausted"`).Update("replay_attempts", maxAutoReplayAttempts).Error)
+
+	deadLetterSvc.autoReplay(context.TODO())
+	assert.Equal(t, []string{"new"}, replayer.replayed)
+
+	result, err := deadLetterSvc.ReplayAll(context.TODO(), CONSUMER_WEBHOOKS)
+	require.NoError(t, err)
+	assert.Equal(t, &ReplayResult{Replayed: 1}, result)
+	assert.Equal(t, []string{"new", "exhausted"}, replayer.replayed)
+}
+
+func TestDiscard(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	deadLetterSvc := NewDeadLetterService(svc.DB)
+	require.NoError(t, Add(svc.DB, CONSUMER_NOSTR_DM, "nwc_backup_failed", "message", nil))
+	deadLetters, err := deadLetterSvc.List("", "")
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+
+	require.NoError(t, deadLetterSvc.Discard(deadLetters[0].ID))
+	assert.EqualError(t, deadLetterSvc.Discard(deadLetters[0].ID), "dead letter not found")
+}
@@ -0,0 +1,175 @@
+// Package eventhooks runs commands configured with EVENT_HOOKS on selected events, e.g. to
+// automate something on the host without writing a plugin. The command receives the event
+// as JSON on stdin, in the same format as webhooks.
+package eventhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/webhooks"
+)
+
+// commandTimeout is how long a command may run before it is killed
+var commandTimeout = 30 * time.Second
+
+// maxConcurrentCommands limits the commands running at the same time, further events wait
+const maxConcurrentCommands = 4
+
+// maxOutputLength limits the output of a command which is logged
+const maxOutputLength = 1000
+
+// the commands do not inherit the environment of the hub, which contains secrets
+var commandEnv = []string{"PATH=/usr/local/bin:/usr/bin:/bin"}
+
+type Hook struct {
+	EventType string
+	Command   string
+}
+
+type eventHooks struct {
+	db        *gorm.DB
+	hooks     []Hook
+	semaphore chan struct{}
+}
+
+// ParseHooks parses comma-separated event_type=command pairs. The event types are the webhook
+// event types, or * for all of them, and the commands absolute paths of executables.
+func ParseHooks(value string) ([]Hook, error) {
+	hooks := []Hook{}
+	eventTypes := webhooks.GetWebhookEventTypes()
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		eventType, command, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid event hook %q, expected event_type=command", entry)
+		}
+		eventType = strings.TrimSpace(eventType)
+		command = strings.TrimSpace(command)
+		if eventType != webhooks.WEBHOOK_EVENT_ALL && !slices.Contains(eventTypes, eventType) {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+		if !filepath.IsAbs(command) {
+			return nil, fmt.Errorf("event hook command %q must be an absolute path", command)
+		}
+		info, err := os.Stat(command)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event hook command %q: %w", command, err)
+		}
+		if info.IsDir() || info.Mode()&0111 == 0 {
+			return nil, fmt.Errorf("event hook command %q is not executable", command)
+		}
+		hooks = append(hooks, Hook{EventType: eventType, Command: command})
+	}
+	return hooks, nil
+}
+
+func NewEventHooks(gormDB *gorm.DB, hooks []Hook) *eventHooks {
+	return &eventHooks{
+		db:        gormDB,
+		hooks:     hooks,
+		semaphore: make(chan struct{}, maxConcurrentCommands),
+	}
+}
+
+func (eh *eventHooks) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	eventType, payload, err := webhooks.MarshalEvent(event)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("event", event.Event).Error("Failed to serialize event for event hooks")
+		return
+	}
+	if eventType == "" {
+		return
+	}
+	for _, hook := range eh.hooks {
+		if hook.EventType != eventType && hook.EventType != webhooks.WEBHOOK_EVENT_ALL {
+			continue
+		}
+		go func(hook Hook) {
+			if err := eh.run(ctx, hook.Command, eventType, payload); err != nil {
+				deadletters.Add(eh.db, deadletters.CONSUMER_EVENT_HOOKS, eventType, &hookDeadLetter{
+					Command: hook.Command,
+					Payload: string(payload),
+				}, err)
+			}
+		}(hook)
+	}
+}
+
+func (eh *eventHooks) run(ctx context.Context, command string, eventType string, payload []byte) error {
+	select {
+	case eh.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-eh.semaphore }()
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Dir = os.TempDir()
+	cmd.Env = append(slices.Clone(commandEnv), "HUB_EVENT="+eventType)
+	cmd.Stdin = bytes.NewReader(payload)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	outputString := output.String()
+	if len(outputString) > maxOutputLength {
+		outputString = outputString[:maxOutputLength]
+	}
+	fields := logrus.Fields{
+		"command": command,
+		"event":   eventType,
+		"output":  outputString,
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.New("event hook timed out")
+		}
+		logger.Logger.WithError(err).WithFields(fields).Error("Event hook failed")
+		return err
+	}
+	logger.Logger.WithFields(fields).Debug("Ran event hook")
+	return nil
+}
+
+type hookDeadLetter struct {
+	Command string `json:"command"`
+	Payload string `json:"payload"`
+}
+
+// ReplayDeadLetter runs a command which failed before with the same event
+func (eh *eventHooks) ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error {
+	var hook hookDeadLetter
+	if err := json.Unmarshal([]byte(deadLetter.Payload), &hook); err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(eh.hooks, func(configuredHook Hook) bool {
+		return configuredHook.Command == hook.Command
+	}) {
+		return errors.New("event hook is no longer configured")
+	}
+	return eh.run(ctx, hook.Command, deadLetter.Event, []byte(hook.Payload))
+}
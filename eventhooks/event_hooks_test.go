@@ -0,0 +1,97 @@
+package eventhooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/tests"
+)
+
+func writeScript(t *testing.T, name string, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("event hook scripts need a unix shell")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700))
+	return path
+}
+
+func TestParseHooks(t *testing.T) {
+	command := writeScript(t, "hook.sh", "exit 0\n")
+
+	hooks, err := ParseHooks("payment_received=" + command + ", *=" + command)
+	require.NoError(t, err)
+	assert.Equal(t, []Hook{{"payment_received", command}, {"*", command}}, hooks)
+
+	_, err = ParseHooks("payment_received")
+	assert.Error(t, err)
+	_, err = ParseHooks("unknown_event=" + command)
+	assert.EqualError(t, err, `unknown event type "unknown_event"`)
+	_, err = ParseHooks("payment_received=hook.sh")
+	assert.Error(t, err)
+	_, err = ParseHooks("payment_received=" + filepath.Dir(command))
+	assert.Error(t, err)
+}
+
+func TestEventHooks_Run(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	t.Setenv("HUB_TEST_SECRET", "secret")
+	outputPath := filepath.Join(t.TempDir(), "output")
+	command := writeScript(t, "hook.sh", `echo "$HUB_EVENT $HUB_TEST_SECRET" > `+outputPath+"\ncat >> "+outputPath+"\n")
+	eventHooks := NewEventHooks(svc.DB, []Hook{{"payment_received", command}})
+
+	// not selected
+	eventHooks.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_payment_sent",
+		Properties: &db.Transaction{AmountMsat: 1000},
+	}, map[string]interface{}{})
+	eventHooks.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_payment_received",
+		Properties: &db.Transaction{AmountMsat: 21000, PaymentHash: tests.MockPaymentHash},
+	}, map[string]interface{}{})
+
+	var output []byte
+	require.Eventually(t, func() bool {
+		output, err = os.ReadFile(outputPath)
+		return err == nil && len(output) > 0 && output[len(output)-1] == '}'
+	}, 5*time.Second, 10*time.Millisecond)
+	// the environment of the hub is not passed on
+	assert.Contains(t, string(output), "payment_received \n")
+	assert.Contains(t, string(output), `"event":"payment_received"`)
+	assert.Contains(t, string(output), tests.MockPaymentHash)
+}
+
+func TestEventHooks_DeadLetter(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	command := writeScript(t, "hook.sh", "exit 1\n")
+	eventHooks := NewEventHooks(svc.DB, []Hook{{"*", command}})
+	eventHooks.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_node_started",
+		Properties: map[string]interface{}{"node_type": "LDK"},
+	}, map[string]interface{}{})
+
+	var deadLetter db.DeadLetter
+	require.Eventually(t, func() bool {
+		return svc.DB.Where("consumer = ?", deadletters.CONSUMER_EVENT_HOOKS).Limit(1).Find(&deadLetter).RowsAffected == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "node_started", deadLetter.Event)
+	assert.Equal(t, "exit status 1", deadLetter.Error)
+
+	assert.Error(t, eventHooks.ReplayDeadLetter(context.TODO(), &deadLetter))
+}
@@ -14,12 +14,19 @@ type eventPublisher struct {
 	listeners        []EventSubscriber
 	subscriberMtx    sync.Mutex
 	globalProperties map[string]interface{}
+	pendingMtx       sync.Mutex
+	pending          int
+	// closed once all asynchronously published events were consumed
+	idle chan struct{}
 }
 
 func NewEventPublisher() *eventPublisher {
+	idle := make(chan struct{})
+	close(idle)
 	eventPublisher := &eventPublisher{
 		listeners:        []EventSubscriber{},
 		globalProperties: map[string]interface{}{},
+		idle:             idle,
 	}
 	eventPublisher.SetGlobalProperty("version", version.Tag)
 	return eventPublisher
@@ -61,11 +68,47 @@ func (ep *eventPublisher) publish(event *Event, sync bool) {
 			listener.ConsumeEvent(context.Background(), event, ep.globalProperties)
 		} else {
 			// consume event without blocking thread
-			go listener.ConsumeEvent(context.Background(), event, ep.globalProperties)
+			ep.addPending()
+			go func(listener EventSubscriber) {
+				defer ep.removePending()
+				listener.ConsumeEvent(context.Background(), event, ep.globalProperties)
+			}(listener)
 		}
 	}
 }
 
+func (ep *eventPublisher) addPending() {
+	ep.pendingMtx.Lock()
+	defer ep.pendingMtx.Unlock()
+	if ep.pending == 0 {
+		ep.idle = make(chan struct{})
+	}
+	ep.pending++
+}
+
+func (ep *eventPublisher) removePending() {
+	ep.pendingMtx.Lock()
+	defer ep.pendingMtx.Unlock()
+	ep.pending--
+	if ep.pending == 0 {
+		close(ep.idle)
+	}
+}
+
+// Flush waits until all subscribers consumed the events published so far
+func (ep *eventPublisher) Flush(ctx context.Context) error {
+	ep.pendingMtx.Lock()
+	idle := ep.idle
+	ep.pendingMtx.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (ep *eventPublisher) SetGlobalProperty(key string, value interface{}) {
 	ep.globalProperties[key] = value
 }
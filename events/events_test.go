@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/logger"
+)
+
+type slowSubscriber struct {
+	consumed atomic.Int32
+}
+
+func (subscriber *slowSubscriber) ConsumeEvent(ctx context.Context, event *Event, globalProperties map[string]interface{}) {
+	time.Sleep(20 * time.Millisecond)
+	subscriber.consumed.Add(1)
+}
+
+func TestFlush(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	eventPublisher := NewEventPublisher()
+	assert.NoError(t, eventPublisher.Flush(context.TODO()))
+
+	subscriber := &slowSubscriber{}
+	eventPublisher.RegisterSubscriber(subscriber)
+	eventPublisher.Publish(&Event{Event: "nwc_test"})
+	eventPublisher.Publish(&Event{Event: "nwc_test"})
+
+	assert.NoError(t, eventPublisher.Flush(context.TODO()))
+	assert.Equal(t, int32(2), subscriber.consumed.Load())
+}
+
+func TestFlush_Timeout(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	eventPublisher := NewEventPublisher()
+	eventPublisher.RegisterSubscriber(&slowSubscriber{})
+	eventPublisher.Publish(&Event{Event: "nwc_test"})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, eventPublisher.Flush(ctx), context.DeadlineExceeded)
+}
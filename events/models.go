@@ -16,6 +16,12 @@ type EventPublisher interface {
 	SetGlobalProperty(key string, value interface{})
 }
 
+// Flusher is implemented by event publishers which can wait for the events
+// which are still being consumed asynchronously
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
 type Event struct {
 	Event      string      `json:"event"`
 	Properties interface{} `json:"properties,omitempty"`
@@ -25,6 +31,8 @@ type StaticChannelsBackupEvent struct {
 	NodeID   string                        `json:"node_id"`
 	Channels []ChannelBackup               `json:"channels"`
 	Monitors []EncodedChannelMonitorBackup `json:"monitors"`
+	// encrypted multi-channel backup of LND, which can be restored with `lncli restorechanbackup --multi_file`
+	LNDMultiChanBackup []byte `json:"lnd_multi_chan_backup,omitempty"`
 }
 
 type EncodedChannelMonitorBackup struct {
@@ -40,3 +48,14 @@ type ChannelBackup struct {
 	FundingTxID       string `json:"funding_tx_id"`
 	FundingTxVout     uint32 `json:"funding_tx_vout"`
 }
+
+// BudgetThresholdReachedEvent is published when the spending of an app crosses one of the budget alert thresholds
+type BudgetThresholdReachedEvent struct {
+	AppId         uint    `json:"app_id"`
+	AppName       string  `json:"app_name"`
+	Threshold     uint    `json:"threshold"` // percent of the budget
+	BudgetRenewal string  `json:"budget_renewal"`
+	BudgetUsage   float64 `json:"budget_usage"`
+	MaxAmount     float64 `json:"max_amount"`
+	Currency      string  `json:"currency"` // SATS, or the currency of a fiat budget
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"slices"
+
+	"github.com/getAlby/hub/logger"
+)
+
+// StreamableEvents can be streamed to API clients. Other events are internal
+// and can contain secrets, e.g. the static channel backups of nwc_backup_channels.
+var StreamableEvents = []string{
+	"nwc_payment_received",
+	"nwc_payment_sent",
+	"nwc_payment_failed",
+	"nwc_invoice_expired",
+	"nwc_hold_invoice_accepted",
+	"nwc_hold_invoice_settled",
+	"nwc_hold_invoice_canceled",
+	"nwc_app_created",
+	"nwc_app_updated",
+	"nwc_app_deleted",
+	"nwc_app_revoked",
+	"nwc_app_paused",
+	"nwc_app_resumed",
+	"nwc_permission_denied",
+	"nwc_budget_warning",
+	"nwc_budget_threshold_reached",
+	"nwc_payment_approval_requested",
+	"nwc_channel_ready",
+	"nwc_channel_closed",
+	"nwc_channel_force_closed",
+	"nwc_force_close_funds_spendable",
+	"nwc_node_started",
+	"nwc_node_stopped",
+	"nwc_node_start_failed",
+	"nwc_node_sync_failed",
+}
+
+// StreamSubscriber forwards published events to a channel, e.g. to push them to a connected client.
+// Events are dropped rather than blocking the publisher if the client does not keep up.
+type StreamSubscriber struct {
+	events chan *Event
+	filter []string
+}
+
+// NewStreamSubscriber only forwards the given streamable events, or all of them if filter is empty
+func NewStreamSubscriber(bufferSize int, filter []string) *StreamSubscriber {
+	return &StreamSubscriber{
+		events: make(chan *Event, bufferSize),
+		filter: filter,
+	}
+}
+
+func (s *StreamSubscriber) Events() <-chan *Event {
+	return s.events
+}
+
+func (s *StreamSubscriber) ConsumeEvent(ctx context.Context, event *Event, globalProperties map[string]interface{}) {
+	if !slices.Contains(StreamableEvents, event.Event) {
+		return
+	}
+	if len(s.filter) > 0 && !slices.Contains(s.filter, event.Event) {
+		return
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		logger.Logger.WithField("event", event.Event).Warn("Dropped event for slow stream subscriber")
+	}
+}
@@ -0,0 +1,117 @@
+// Package feeadvisor watches the on-chain fee rate and warns when it is above the ceiling set by
+// the user. If enabled, channel opens, swaps and on-chain payments are delayed until it falls again.
+package feeadvisor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/feeestimator"
+	"github.com/getAlby/hub/logger"
+)
+
+var checkInterval = 10 * time.Minute
+
+// FeeRateAboveCeilingError is returned for on-chain operations which are delayed
+type FeeRateAboveCeilingError struct {
+	FeeRate uint64
+	Ceiling uint
+}
+
+func (err *FeeRateAboveCeilingError) Error() string {
+	return fmt.Sprintf("on-chain fees of %d sat/vB are above your ceiling of %d sat/vB, try again once they fall", err.FeeRate, err.Ceiling)
+}
+
+type feeAdvisorService struct {
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+
+	// the spike is published once when the ceiling is exceeded, and the end once the fee rate fell below it
+	mu        sync.Mutex
+	feeSpiked bool
+}
+
+func NewFeeAdvisorService(cfg config.Config, eventPublisher events.EventPublisher) *feeAdvisorService {
+	return &feeAdvisorService{
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+// CheckDelay returns a FeeRateAboveCeilingError if on-chain operations should be delayed because
+// of the current fee rate. Operations are not delayed if the fee rate cannot be fetched.
+func CheckDelay(ctx context.Context, cfg config.Config) error {
+	ceiling := config.GetUintSetting(cfg, config.OnchainFeeCeilingKey, 0)
+	delay, _ := cfg.Get(config.OnchainFeeCeilingDelayKey, "")
+	if ceiling == 0 || delay != "true" {
+		return nil
+	}
+	feeRate, err := feeestimator.GetFeeRate(ctx, cfg, feeestimator.TARGET_HALF_HOUR)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get fee rate, not delaying on-chain operation")
+		return nil
+	}
+	if feeRate > uint64(ceiling) {
+		return &FeeRateAboveCeilingError{FeeRate: feeRate, Ceiling: ceiling}
+	}
+	return nil
+}
+
+// Start checks the fee rate periodically until ctx is done
+func (svc *feeAdvisorService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				svc.check(ctx)
+			}
+		}
+	}()
+}
+
+func (svc *feeAdvisorService) check(ctx context.Context) {
+	ceiling := config.GetUintSetting(svc.cfg, config.OnchainFeeCeilingKey, 0)
+	if ceiling == 0 {
+		return
+	}
+	feeRate, err := feeestimator.GetFeeRate(ctx, svc.cfg, feeestimator.TARGET_HALF_HOUR)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get fee rate")
+		return
+	}
+
+	svc.mu.Lock()
+	aboveCeiling := feeRate > uint64(ceiling)
+	changed := aboveCeiling != svc.feeSpiked
+	svc.feeSpiked = aboveCeiling
+	svc.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	event := "nwc_onchain_fee_spike"
+	if !aboveCeiling {
+		event = "nwc_onchain_fee_normal"
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"fee_rate": feeRate,
+		"ceiling":  ceiling,
+	}).WithField("event", event).Info("On-chain fee rate crossed the ceiling")
+	svc.eventPublisher.Publish(&events.Event{
+		Event: event,
+		Properties: map[string]interface{}{
+			"fee_rate": feeRate,
+			"ceiling":  ceiling,
+		},
+	})
+}
@@ -0,0 +1,81 @@
+package feeadvisor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/tests"
+)
+
+func startMempoolServer(t *testing.T, feeRate *atomic.Uint64) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/fees/recommended", r.URL.Path)
+		fmt.Fprintf(w, `{"fastestFee":%d,"halfHourFee":%d,"hourFee":1,"economyFee":1,"minimumFee":1}`, feeRate.Load()+10, feeRate.Load())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckDelay(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	var feeRate atomic.Uint64
+	feeRate.Store(50)
+	svc.Cfg.GetEnv().MempoolApi = startMempoolServer(t, &feeRate).URL
+
+	// no ceiling
+	assert.NoError(t, CheckDelay(context.TODO(), svc.Cfg))
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.OnchainFeeCeilingKey, "20", ""))
+	// only warns
+	assert.NoError(t, CheckDelay(context.TODO(), svc.Cfg))
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.OnchainFeeCeilingDelayKey, "true", ""))
+	err = CheckDelay(context.TODO(), svc.Cfg)
+	var feeErr *FeeRateAboveCeilingError
+	require.ErrorAs(t, err, &feeErr)
+	assert.Equal(t, uint64(50), feeErr.FeeRate)
+	assert.Equal(t, uint(20), feeErr.Ceiling)
+
+	feeRate.Store(20)
+	assert.NoError(t, CheckDelay(context.TODO(), svc.Cfg))
+}
+
+func TestFeeAdvisor_Events(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	var feeRate atomic.Uint64
+	feeRate.Store(50)
+	svc.Cfg.GetEnv().MempoolApi = startMempoolServer(t, &feeRate).URL
+	require.NoError(t, svc.Cfg.SetUpdate(config.OnchainFeeCeilingKey, "20", ""))
+
+	consumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(consumer)
+	feeAdvisorSvc := NewFeeAdvisorService(svc.Cfg, svc.EventPublisher)
+
+	// the spike is only published once
+	feeAdvisorSvc.check(context.TODO())
+	feeAdvisorSvc.check(context.TODO())
+	consumedEvents := consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 1)
+	assert.Equal(t, "nwc_onchain_fee_spike", consumedEvents[0].Event)
+	assert.Equal(t, uint64(50), consumedEvents[0].Properties.(map[string]interface{})["fee_rate"])
+
+	feeRate.Store(10)
+	feeAdvisorSvc.check(context.TODO())
+	consumedEvents = consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 2)
+	assert.Equal(t, "nwc_onchain_fee_normal", consumedEvents[1].Event)
+}
@@ -0,0 +1,159 @@
+package feeestimator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type mempoolEstimator struct {
+	url string
+}
+
+func (estimator *mempoolEstimator) EstimateFeeRate(ctx context.Context, targetBlocks uint32) (float64, error) {
+	var feeRates struct {
+		FastestFee  float64 `json:"fastestFee"`
+		HalfHourFee float64 `json:"halfHourFee"`
+		HourFee     float64 `json:"hourFee"`
+		EconomyFee  float64 `json:"economyFee"`
+	}
+	if err := doRequest(ctx, http.MethodGet, estimator.url+"/v1/fees/recommended", nil, nil, &feeRates); err != nil {
+		return 0, err
+	}
+	switch {
+	case targetBlocks <= TARGET_NEXT_BLOCK:
+		return feeRates.FastestFee, nil
+	case targetBlocks <= TARGET_HALF_HOUR:
+		return feeRates.HalfHourFee, nil
+	case targetBlocks <= TARGET_HOUR:
+		return feeRates.HourFee, nil
+	default:
+		return feeRates.EconomyFee, nil
+	}
+}
+
+type esploraEstimator struct {
+	url string
+}
+
+func (estimator *esploraEstimator) EstimateFeeRate(ctx context.Context, targetBlocks uint32) (float64, error) {
+	// fee rates in sat/vB by confirmation target, e.g. {"1": 87.882, "2": 87.882, "3": 87.882, ...}
+	estimates := map[string]float64{}
+	if err := doRequest(ctx, http.MethodGet, estimator.url+"/fee-estimates", nil, nil, &estimates); err != nil {
+		return 0, err
+	}
+
+	targets := []uint64{}
+	for target := range estimates {
+		parsedTarget, err := strconv.ParseUint(target, 10, 32)
+		if err == nil {
+			targets = append(targets, parsedTarget)
+		}
+	}
+	if len(targets) == 0 {
+		return 0, errors.New("no fee estimates in response")
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+
+	// use the closest target which confirms at least as fast as requested
+	selectedTarget := targets[0]
+	for _, target := range targets {
+		if target > uint64(targetBlocks) {
+			break
+		}
+		selectedTarget = target
+	}
+	return estimates[strconv.FormatUint(selectedTarget, 10)], nil
+}
+
+type bitcoindEstimator struct {
+	url      string
+	user     string
+	password string
+}
+
+func (estimator *bitcoindEstimator) EstimateFeeRate(ctx context.Context, targetBlocks uint32) (float64, error) {
+	request := map[string]interface{}{
+		"jsonrpc": "1.0",
+		"id":      "albyhub",
+		"method":  "estimatesmartfee",
+		"params":  []interface{}{targetBlocks},
+	}
+	var response struct {
+		Result *struct {
+			// BTC/kvB
+			FeeRate float64  `json:"feerate"`
+			Errors  []string `json:"errors"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := doRequest(ctx, http.MethodPost, estimator.url, request, func(req *http.Request) {
+		req.SetBasicAuth(estimator.user, estimator.password)
+	}, &response); err != nil {
+		return 0, err
+	}
+	if response.Error != nil {
+		return 0, fmt.Errorf("estimatesmartfee failed: %s", response.Error.Message)
+	}
+	if response.Result == nil || response.Result.FeeRate == 0 {
+		if response.Result != nil && len(response.Result.Errors) > 0 {
+			return 0, fmt.Errorf("estimatesmartfee failed: %s", response.Result.Errors[0])
+		}
+		return 0, errors.New("estimatesmartfee returned no fee rate")
+	}
+	// 1 BTC/kvB = 100_000_000 sat / 1000 vB
+	return response.Result.FeeRate * 100_000, nil
+}
+
+func doRequest(ctx context.Context, method string, url string, payload interface{}, prepare func(req *http.Request), result interface{}) error {
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+
+	var body io.Reader
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payloadBytes)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return errors.New("failed to read response body")
+	}
+	// bitcoind returns RPC errors with a non-success status and a JSON body, which the caller checks
+	if err := json.Unmarshal(responseBody, result); err != nil {
+		if res.StatusCode >= 300 {
+			return fmt.Errorf("fee estimator returned non-success code %d: %s", res.StatusCode, string(responseBody))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Package feeestimator estimates on-chain fee rates from the configured backend, so that channel
+// opens, swaps and withdrawals use the same fee rates regardless of the lightning backend.
+package feeestimator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	ESTIMATOR_MEMPOOL  = "mempool"
+	ESTIMATOR_ESPLORA  = "esplora"
+	ESTIMATOR_BITCOIND = "bitcoind"
+)
+
+// confirmation targets in blocks
+const (
+	TARGET_NEXT_BLOCK = 1
+	TARGET_HALF_HOUR  = 3
+	TARGET_HOUR       = 6
+)
+
+// FeeEstimator returns the fee rate in sat/vB for a confirmation within the target number of blocks
+type FeeEstimator interface {
+	EstimateFeeRate(ctx context.Context, targetBlocks uint32) (float64, error)
+}
+
+func NewFeeEstimator(cfg config.Config) (FeeEstimator, error) {
+	env := cfg.GetEnv()
+	switch env.OnchainFeeEstimator {
+	case "", ESTIMATOR_MEMPOOL:
+		return &mempoolEstimator{url: env.MempoolApi}, nil
+	case ESTIMATOR_ESPLORA:
+		return &esploraEstimator{url: env.LDKEsploraServer}, nil
+	case ESTIMATOR_BITCOIND:
+		if env.LDKBitcoindRpcHost == "" {
+			return nil, errors.New("the bitcoind fee estimator requires LDK_BITCOIND_RPC_HOST")
+		}
+		return &bitcoindEstimator{
+			url:      fmt.Sprintf("http://%s:%s", env.LDKBitcoindRpcHost, env.LDKBitcoindRpcPort),
+			user:     env.LDKBitcoindRpcUser,
+			password: env.LDKBitcoindRpcPassword,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown on-chain fee estimator %q", env.OnchainFeeEstimator)
+	}
+}
+
+// GetFeeRate returns the fee rate in whole sat/vB for a confirmation within the target number of
+// blocks from the configured estimator, limited to ONCHAIN_FEE_MIN_RATE and ONCHAIN_FEE_MAX_RATE
+func GetFeeRate(ctx context.Context, cfg config.Config, targetBlocks uint32) (uint64, error) {
+	estimator, err := NewFeeEstimator(cfg)
+	if err != nil {
+		return 0, err
+	}
+	feeRate, err := estimator.EstimateFeeRate(ctx, targetBlocks)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(feeRate) || feeRate <= 0 {
+		return 0, fmt.Errorf("invalid fee rate estimate %v", feeRate)
+	}
+	return applyBounds(cfg, uint64(math.Ceil(feeRate))), nil
+}
+
+// applyBounds protects against estimates which are far off, e.g. from a misconfigured backend
+func applyBounds(cfg config.Config, feeRate uint64) uint64 {
+	minFeeRate := cfg.GetEnv().OnchainFeeMinRate
+	maxFeeRate := cfg.GetEnv().OnchainFeeMaxRate
+	if feeRate < minFeeRate {
+		return minFeeRate
+	}
+	if maxFeeRate > 0 && feeRate > maxFeeRate {
+		logger.Logger.WithFields(logrus.Fields{
+			"fee_rate":     feeRate,
+			"max_fee_rate": maxFeeRate,
+		}).Warn("Fee rate estimate is above the maximum, using the maximum")
+		return maxFeeRate
+	}
+	return feeRate
+}
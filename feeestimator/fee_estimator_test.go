@@ -0,0 +1,100 @@
+package feeestimator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/tests"
+)
+
+func TestEstimators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/fees/recommended":
+			w.Write([]byte(`{"fastestFee":40,"halfHourFee":30,"hourFee":20,"economyFee":10,"minimumFee":1}`))
+		case "/fee-estimates":
+			w.Write([]byte(`{"1":41.5,"2":35,"3":31.2,"6":21,"144":2}`))
+		case "/":
+			user, password, _ := r.BasicAuth()
+			assert.Equal(t, "user", user)
+			assert.Equal(t, "password", password)
+			var request struct {
+				Method string        `json:"method"`
+				Params []interface{} `json:"params"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			assert.Equal(t, "estimatesmartfee", request.Method)
+			w.Write([]byte(`{"result":{"feerate":0.00032,"blocks":3},"error":null,"id":"albyhub"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	mempool := &mempoolEstimator{url: server.URL}
+	feeRate, err := mempool.EstimateFeeRate(context.TODO(), TARGET_NEXT_BLOCK)
+	require.NoError(t, err)
+	assert.Equal(t, float64(40), feeRate)
+	feeRate, err = mempool.EstimateFeeRate(context.TODO(), TARGET_HALF_HOUR)
+	require.NoError(t, err)
+	assert.Equal(t, float64(30), feeRate)
+	feeRate, err = mempool.EstimateFeeRate(context.TODO(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, float64(10), feeRate)
+
+	esplora := &esploraEstimator{url: server.URL}
+	feeRate, err = esplora.EstimateFeeRate(context.TODO(), TARGET_HALF_HOUR)
+	require.NoError(t, err)
+	assert.Equal(t, 31.2, feeRate)
+	// the closest faster target is used
+	feeRate, err = esplora.EstimateFeeRate(context.TODO(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, float64(21), feeRate)
+
+	bitcoind := &bitcoindEstimator{url: server.URL, user: "user", password: "password"}
+	feeRate, err = bitcoind.EstimateFeeRate(context.TODO(), TARGET_HALF_HOUR)
+	require.NoError(t, err)
+	assert.InDelta(t, 32, feeRate, 0.0001)
+}
+
+func TestGetFeeRate(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	feeRate := "0.4"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"1":` + feeRate + `}`))
+	}))
+	defer server.Close()
+
+	svc.Cfg.GetEnv().OnchainFeeEstimator = ESTIMATOR_ESPLORA
+	svc.Cfg.GetEnv().LDKEsploraServer = server.URL
+	svc.Cfg.GetEnv().OnchainFeeMinRate = 1
+	svc.Cfg.GetEnv().OnchainFeeMaxRate = 100
+
+	// estimates are rounded up and limited to the bounds
+	result, err := GetFeeRate(context.TODO(), svc.Cfg, TARGET_NEXT_BLOCK)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), result)
+
+	feeRate = "12.1"
+	result, err = GetFeeRate(context.TODO(), svc.Cfg, TARGET_NEXT_BLOCK)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(13), result)
+
+	feeRate = "2500"
+	result, err = GetFeeRate(context.TODO(), svc.Cfg, TARGET_NEXT_BLOCK)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), result)
+
+	svc.Cfg.GetEnv().OnchainFeeEstimator = "unknown"
+	_, err = GetFeeRate(context.TODO(), svc.Cfg, TARGET_NEXT_BLOCK)
+	assert.EqualError(t, err, `unknown on-chain fee estimator "unknown"`)
+}
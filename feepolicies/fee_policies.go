@@ -0,0 +1,319 @@
+// Package feepolicies adjusts the routing fees of the node's channels on a schedule, based on
+// user-defined policies similar to the rules of charge-lnd. Each policy applies to a group of
+// channels, selected by their peers and the ratio of their local balance.
+package feepolicies
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	// STRATEGY_STATIC sets the max fee rate
+	STRATEGY_STATIC = "static"
+	// STRATEGY_PROPORTIONAL lowers the fee rate from the max to the min fee rate as the local balance grows
+	STRATEGY_PROPORTIONAL = "proportional"
+	// STRATEGY_FLOW raises the fee rate while the local balance flows out of the channel and lowers it
+	// while it does not, within the min and max fee rate
+	STRATEGY_FLOW = "flow"
+)
+
+var checkInterval = time.Hour
+
+// the number of steps from the min to the max fee rate of the flow strategy
+const flowSteps = 10
+
+type CreateFeePolicyParams struct {
+	Name          string
+	PeerPubkeys   []string
+	Strategy      string
+	MinLocalRatio float64
+	MaxLocalRatio float64
+	BaseFeeMsat   uint32
+	MinFeePpm     uint32
+	MaxFeePpm     uint32
+	Priority      int
+}
+
+type FeePoliciesService interface {
+	CreateFeePolicy(params *CreateFeePolicyParams) (*db.FeePolicy, error)
+	UpdateFeePolicy(id uint, enabled *bool, priority *int) (*db.FeePolicy, error)
+	ListFeePolicies() ([]db.FeePolicy, error)
+	DeleteFeePolicy(id uint) error
+	Start(ctx context.Context, lnClient lnclient.LNClient)
+}
+
+type feePoliciesService struct {
+	db             *gorm.DB
+	eventPublisher events.EventPublisher
+}
+
+func NewFeePoliciesService(db *gorm.DB, eventPublisher events.EventPublisher) *feePoliciesService {
+	return &feePoliciesService{
+		db:             db,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (svc *feePoliciesService) CreateFeePolicy(params *CreateFeePolicyParams) (*db.FeePolicy, error) {
+	if params.Strategy != STRATEGY_STATIC && params.Strategy != STRATEGY_PROPORTIONAL && params.Strategy != STRATEGY_FLOW {
+		return nil, errors.New("unknown fee policy strategy")
+	}
+	if params.MaxLocalRatio == 0 {
+		params.MaxLocalRatio = 1
+	}
+	if params.MinLocalRatio < 0 || params.MaxLocalRatio > 1 || params.MinLocalRatio > params.MaxLocalRatio {
+		return nil, errors.New("local ratio range must be within 0 and 1")
+	}
+	if params.MinFeePpm > params.MaxFeePpm {
+		return nil, errors.New("min fee rate must not be greater than max fee rate")
+	}
+
+	peerPubkeys := []string{}
+	for _, peerPubkey := range params.PeerPubkeys {
+		peerPubkey = strings.TrimSpace(peerPubkey)
+		if peerPubkey != "" {
+			peerPubkeys = append(peerPubkeys, peerPubkey)
+		}
+	}
+
+	feePolicy := db.FeePolicy{
+		Name:          strings.TrimSpace(params.Name),
+		PeerPubkeys:   strings.Join(peerPubkeys, ","),
+		Strategy:      params.Strategy,
+		MinLocalRatio: params.MinLocalRatio,
+		MaxLocalRatio: params.MaxLocalRatio,
+		BaseFeeMsat:   params.BaseFeeMsat,
+		MinFeePpm:     params.MinFeePpm,
+		MaxFeePpm:     params.MaxFeePpm,
+		Priority:      params.Priority,
+		Enabled:       true,
+	}
+	if err := svc.db.Create(&feePolicy).Error; err != nil {
+		return nil, err
+	}
+	return &feePolicy, nil
+}
+
+func (svc *feePoliciesService) UpdateFeePolicy(id uint, enabled *bool, priority *int) (*db.FeePolicy, error) {
+	var feePolicy db.FeePolicy
+	if svc.db.Limit(1).Find(&feePolicy, &db.FeePolicy{ID: id}).RowsAffected == 0 {
+		return nil, errors.New("fee policy not found")
+	}
+	if enabled != nil {
+		feePolicy.Enabled = *enabled
+	}
+	if priority != nil {
+		feePolicy.Priority = *priority
+	}
+	if err := svc.db.Model(&feePolicy).Select("enabled", "priority").Updates(&feePolicy).Error; err != nil {
+		return nil, err
+	}
+	return &feePolicy, nil
+}
+
+// ListFeePolicies returns the policies in the order they are matched
+func (svc *feePoliciesService) ListFeePolicies() ([]db.FeePolicy, error) {
+	feePolicies := []db.FeePolicy{}
+	if err := svc.db.Order("priority, id").Find(&feePolicies).Error; err != nil {
+		return nil, err
+	}
+	return feePolicies, nil
+}
+
+func (svc *feePoliciesService) DeleteFeePolicy(id uint) error {
+	return svc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&db.ChannelFeeState{FeePolicyId: id}).Delete(&db.ChannelFeeState{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&db.FeePolicy{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("fee policy not found")
+		}
+		return nil
+	})
+}
+
+// Start applies the enabled policies periodically until the context is cancelled
+func (svc *feePoliciesService) Start(ctx context.Context, lnClient lnclient.LNClient) {
+	logger.Logger.Info("Starting fee policies")
+	health.RegisterJob("fee_policies", checkInterval)
+	go func() {
+		defer health.RemoveJob("fee_policies")
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				health.ReportJobRun("fee_policies", svc.applyPolicies(ctx, lnClient))
+			case <-ctx.Done():
+				logger.Logger.Info("Stopping fee policies")
+				return
+			}
+		}
+	}()
+}
+
+// applyPolicies only returns an error if the policies could not be evaluated at all, channels
+// which fail to update are retried with the next run
+func (svc *feePoliciesService) applyPolicies(ctx context.Context, lnClient lnclient.LNClient) error {
+	feePolicies := []db.FeePolicy{}
+	if err := svc.db.Where("enabled = ?", true).Order("priority, id").Find(&feePolicies).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to list fee policies")
+		return err
+	}
+	if len(feePolicies) == 0 {
+		return nil
+	}
+
+	channels, err := lnClient.ListChannels(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list channels")
+		return err
+	}
+
+	appliedPolicyIds := map[uint]bool{}
+	for _, channel := range channels {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !channel.Active {
+			continue
+		}
+		localRatio := getLocalRatio(&channel)
+		feePolicy := matchPolicy(feePolicies, &channel, localRatio)
+		if feePolicy == nil {
+			continue
+		}
+		appliedPolicyIds[feePolicy.ID] = true
+		svc.applyPolicy(ctx, lnClient, feePolicy, &channel, localRatio)
+	}
+
+	now := time.Now()
+	for id := range appliedPolicyIds {
+		if err := svc.db.Model(&db.FeePolicy{ID: id}).Update("last_run_at", now).Error; err != nil {
+			logger.Logger.WithField("fee_policy_id", id).WithError(err).Error("Failed to update fee policy")
+		}
+	}
+	return nil
+}
+
+func (svc *feePoliciesService) applyPolicy(ctx context.Context, lnClient lnclient.LNClient, feePolicy *db.FeePolicy, channel *lnclient.Channel, localRatio float64) {
+	var lastState *db.ChannelFeeState
+	var channelFeeState db.ChannelFeeState
+	if svc.db.Where(&db.ChannelFeeState{ChannelId: channel.Id, FeePolicyId: feePolicy.ID}).Limit(1).Find(&channelFeeState).RowsAffected > 0 {
+		lastState = &channelFeeState
+	}
+
+	feePpm := getFeePpm(feePolicy, localRatio, channel.LocalBalance, lastState)
+
+	if feePpm != channel.ForwardingFeeProportionalMillionths || feePolicy.BaseFeeMsat != channel.ForwardingFeeBaseMsat {
+		logger.Logger.WithFields(logrus.Fields{
+			"fee_policy_id": feePolicy.ID,
+			"channel_id":    channel.Id,
+			"local_ratio":   localRatio,
+			"base_fee_msat": feePolicy.BaseFeeMsat,
+			"fee_ppm":       feePpm,
+		}).Info("Updating channel fees for fee policy")
+		err := lnClient.UpdateChannel(ctx, &lnclient.UpdateChannelRequest{
+			ChannelId:                           channel.Id,
+			NodeId:                              channel.RemotePubkey,
+			ForwardingFeeBaseMsat:               feePolicy.BaseFeeMsat,
+			ForwardingFeeProportionalMillionths: feePpm,
+		})
+		if err != nil {
+			logger.Logger.WithField("channel_id", channel.Id).WithError(err).Error("Failed to update channel fees")
+			return
+		}
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_channel_fees_updated",
+			Properties: map[string]interface{}{
+				"channel_id":    channel.Id,
+				"peer_pubkey":   channel.RemotePubkey,
+				"fee_policy_id": feePolicy.ID,
+				"base_fee_msat": feePolicy.BaseFeeMsat,
+				"fee_ppm":       feePpm,
+				"local_ratio":   localRatio,
+			},
+		})
+	}
+
+	err := svc.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fee_policy_id", "local_balance_msat", "fee_ppm", "updated_at"}),
+	}).Create(&db.ChannelFeeState{
+		ChannelId:        channel.Id,
+		FeePolicyId:      feePolicy.ID,
+		LocalBalanceMsat: channel.LocalBalance,
+		FeePpm:           feePpm,
+	}).Error
+	if err != nil {
+		logger.Logger.WithField("channel_id", channel.Id).WithError(err).Error("Failed to save channel fee state")
+	}
+}
+
+func getLocalRatio(channel *lnclient.Channel) float64 {
+	capacity := channel.LocalBalance + channel.RemoteBalance
+	if capacity <= 0 {
+		return 0
+	}
+	return float64(channel.LocalBalance) / float64(capacity)
+}
+
+// matchPolicy returns the first policy, in order of priority, which applies to the channel
+func matchPolicy(feePolicies []db.FeePolicy, channel *lnclient.Channel, localRatio float64) *db.FeePolicy {
+	for i := range feePolicies {
+		feePolicy := &feePolicies[i]
+		if feePolicy.PeerPubkeys != "" && !slices.Contains(strings.Split(feePolicy.PeerPubkeys, ","), channel.RemotePubkey) {
+			continue
+		}
+		if localRatio < feePolicy.MinLocalRatio || localRatio > feePolicy.MaxLocalRatio {
+			continue
+		}
+		return feePolicy
+	}
+	return nil
+}
+
+// getFeePpm returns the fee rate of the channel. lastState is the state of the channel when the
+// policy was last applied to it, if it was.
+func getFeePpm(feePolicy *db.FeePolicy, localRatio float64, localBalanceMsat int64, lastState *db.ChannelFeeState) uint32 {
+	proportionalFeePpm := feePolicy.MaxFeePpm - uint32(float64(feePolicy.MaxFeePpm-feePolicy.MinFeePpm)*localRatio)
+
+	switch feePolicy.Strategy {
+	case STRATEGY_PROPORTIONAL:
+		return proportionalFeePpm
+	case STRATEGY_FLOW:
+		if lastState == nil {
+			return proportionalFeePpm
+		}
+		step := max((feePolicy.MaxFeePpm-feePolicy.MinFeePpm)/flowSteps, 1)
+		feePpm := min(max(lastState.FeePpm, feePolicy.MinFeePpm), feePolicy.MaxFeePpm)
+		if localBalanceMsat < lastState.LocalBalanceMsat {
+			// forwards flowed out through the channel
+			return min(feePpm+step, feePolicy.MaxFeePpm)
+		}
+		if feePpm < feePolicy.MinFeePpm+step {
+			return feePolicy.MinFeePpm
+		}
+		return feePpm - step
+	default:
+		return feePolicy.MaxFeePpm
+	}
+}
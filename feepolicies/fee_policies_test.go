@@ -0,0 +1,103 @@
+package feepolicies
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+type mockChannelsLNClient struct {
+	lnclient.LNClient
+	channels []lnclient.Channel
+	updates  []lnclient.UpdateChannelRequest
+}
+
+func (mln *mockChannelsLNClient) ListChannels(ctx context.Context) ([]lnclient.Channel, error) {
+	return mln.channels, nil
+}
+
+func (mln *mockChannelsLNClient) UpdateChannel(ctx context.Context, updateChannelRequest *lnclient.UpdateChannelRequest) error {
+	mln.updates = append(mln.updates, *updateChannelRequest)
+	for i := range mln.channels {
+		if mln.channels[i].Id == updateChannelRequest.ChannelId {
+			mln.channels[i].ForwardingFeeBaseMsat = updateChannelRequest.ForwardingFeeBaseMsat
+			mln.channels[i].ForwardingFeeProportionalMillionths = updateChannelRequest.ForwardingFeeProportionalMillionths
+		}
+	}
+	return nil
+}
+
+func TestGetFeePpm(t *testing.T) {
+	feePolicy := &db.FeePolicy{Strategy: STRATEGY_PROPORTIONAL, MinFeePpm: 100, MaxFeePpm: 1100}
+	assert.Equal(t, uint32(1100), getFeePpm(feePolicy, 0, 0, nil))
+	assert.Equal(t, uint32(850), getFeePpm(feePolicy, 0.25, 0, nil))
+	assert.Equal(t, uint32(100), getFeePpm(feePolicy, 1, 0, nil))
+
+	feePolicy.Strategy = STRATEGY_STATIC
+	assert.Equal(t, uint32(1100), getFeePpm(feePolicy, 0.25, 0, nil))
+
+	feePolicy.Strategy = STRATEGY_FLOW
+	assert.Equal(t, uint32(850), getFeePpm(feePolicy, 0.25, 0, nil))
+	// outbound flow raises the fee rate, no flow lowers it
+	assert.Equal(t, uint32(600), getFeePpm(feePolicy, 0.25, 1_000, &db.ChannelFeeState{LocalBalanceMsat: 2_000, FeePpm: 500}))
+	assert.Equal(t, uint32(400), getFeePpm(feePolicy, 0.25, 2_000, &db.ChannelFeeState{LocalBalanceMsat: 2_000, FeePpm: 500}))
+	assert.Equal(t, uint32(1100), getFeePpm(feePolicy, 0.25, 1_000, &db.ChannelFeeState{LocalBalanceMsat: 2_000, FeePpm: 1050}))
+	assert.Equal(t, uint32(100), getFeePpm(feePolicy, 0.25, 2_000, &db.ChannelFeeState{LocalBalanceMsat: 2_000, FeePpm: 150}))
+}
+
+func TestApplyPolicies(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	lnClient := &mockChannelsLNClient{
+		LNClient: svc.LNClient,
+		channels: []lnclient.Channel{
+			{Id: "1", RemotePubkey: "02aaaa", Active: true, LocalBalance: 200_000, RemoteBalance: 800_000},
+			{Id: "2", RemotePubkey: "02bbbb", Active: true, LocalBalance: 900_000, RemoteBalance: 100_000},
+			{Id: "3", RemotePubkey: "02cccc", Active: false, LocalBalance: 900_000, RemoteBalance: 100_000},
+		},
+	}
+
+	consumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(consumer)
+	feePoliciesSvc := NewFeePoliciesService(svc.DB, svc.EventPublisher)
+	_, err = feePoliciesSvc.CreateFeePolicy(&CreateFeePolicyParams{
+		Name:        "sink",
+		PeerPubkeys: []string{"02aaaa"},
+		Strategy:    STRATEGY_STATIC,
+		BaseFeeMsat: 1000,
+		MaxFeePpm:   2000,
+	})
+	require.NoError(t, err)
+	_, err = feePoliciesSvc.CreateFeePolicy(&CreateFeePolicyParams{
+		Strategy:  STRATEGY_PROPORTIONAL,
+		MinFeePpm: 0,
+		MaxFeePpm: 1000,
+		Priority:  1,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, feePoliciesSvc.applyPolicies(context.TODO(), lnClient))
+	require.Len(t, lnClient.updates, 2)
+	assert.Equal(t, lnclient.UpdateChannelRequest{ChannelId: "1", NodeId: "02aaaa", ForwardingFeeBaseMsat: 1000, ForwardingFeeProportionalMillionths: 2000}, lnClient.updates[0])
+	assert.Equal(t, lnclient.UpdateChannelRequest{ChannelId: "2", NodeId: "02bbbb", ForwardingFeeProportionalMillionths: 100}, lnClient.updates[1])
+
+	// unchanged fees are not updated again
+	require.NoError(t, feePoliciesSvc.applyPolicies(context.TODO(), lnClient))
+	assert.Len(t, lnClient.updates, 2)
+
+	consumedEvents := consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 2)
+	assert.Equal(t, "nwc_channel_fees_updated", consumedEvents[0].Event)
+
+	var channelFeeStates []db.ChannelFeeState
+	require.NoError(t, svc.DB.Find(&channelFeeStates).Error)
+	assert.Len(t, channelFeeStates, 2)
+}
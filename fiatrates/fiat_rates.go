@@ -0,0 +1,171 @@
+// Package fiatrates fetches the bitcoin price in fiat currencies from a list of providers. A
+// provider which fails is skipped for a while and the next one is used instead, and rates are
+// cached so that bursts of requests do not each fetch new rates.
+package fiatrates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/logger"
+)
+
+// rates are reused for a short time so that bursts of payments do not each fetch new rates
+var rateCacheDuration = time.Minute
+
+// when all providers fail, a cached rate up to this age is returned instead of an error
+var maxStaleRateAge = time.Hour
+
+// a failing provider is skipped for this long, doubled with each further failure
+var minBackoff = time.Minute
+
+const maxBackoff = 30 * time.Minute
+
+var providerURLs = map[string]string{
+	PROVIDER_ALBY:      "https://getalby.com/api",
+	PROVIDER_COINGECKO: "https://api.coingecko.com",
+	PROVIDER_KRAKEN:    "https://api.kraken.com",
+	PROVIDER_MEMPOOL:   "https://mempool.space",
+}
+
+type cachedRate struct {
+	rate      float64
+	fetchedAt time.Time
+}
+
+type providerHealth struct {
+	failures     int
+	skippedUntil time.Time
+}
+
+type FiatRatesService struct {
+	providers []Provider
+	mutex     sync.Mutex
+	rates     map[string]cachedRate
+	health    map[string]*providerHealth
+}
+
+// NewFiatRatesService uses the providers listed in FIAT_RATE_PROVIDERS, in order. The custom
+// provider is tried first if FIAT_RATE_CUSTOM_URL is set and it is not listed.
+func NewFiatRatesService(cfg config.Config) (*FiatRatesService, error) {
+	names := []string{}
+	for _, name := range strings.Split(cfg.GetEnv().FiatRateProviders, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	customUrl := cfg.GetEnv().FiatRateCustomURL
+	if customUrl != "" && !slices.Contains(names, PROVIDER_CUSTOM) {
+		names = append([]string{PROVIDER_CUSTOM}, names...)
+	}
+
+	providers := []Provider{}
+	for _, name := range names {
+		switch name {
+		case PROVIDER_ALBY:
+			providers = append(providers, &albyProvider{url: providerURLs[name]})
+		case PROVIDER_COINGECKO:
+			providers = append(providers, &coingeckoProvider{url: providerURLs[name]})
+		case PROVIDER_KRAKEN:
+			providers = append(providers, &krakenProvider{url: providerURLs[name]})
+		case PROVIDER_MEMPOOL:
+			providers = append(providers, &mempoolProvider{url: providerURLs[name]})
+		case PROVIDER_CUSTOM:
+			if customUrl == "" {
+				return nil, errors.New("the custom fiat rate provider requires FIAT_RATE_CUSTOM_URL")
+			}
+			providers = append(providers, &customProvider{url: customUrl})
+		default:
+			return nil, fmt.Errorf("unknown fiat rate provider %q", name)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("no fiat rate providers configured")
+	}
+
+	return NewFiatRatesServiceWithProviders(providers), nil
+}
+
+func NewFiatRatesServiceWithProviders(providers []Provider) *FiatRatesService {
+	return &FiatRatesService{
+		providers: providers,
+		rates:     map[string]cachedRate{},
+		health:    map[string]*providerHealth{},
+	}
+}
+
+// GetFiatRate returns the bitcoin price in the currency from the first healthy provider which
+// supports it
+func (svc *FiatRatesService) GetFiatRate(ctx context.Context, currency string) (float64, error) {
+	currency = strings.ToUpper(currency)
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	cached, hasCached := svc.rates[currency]
+	if hasCached && time.Since(cached.fetchedAt) < rateCacheDuration {
+		return cached.rate, nil
+	}
+
+	var errs []error
+	for _, provider := range svc.getProviders() {
+		rate, err := provider.GetRate(ctx, currency)
+		if err == nil && rate <= 0 {
+			err = fmt.Errorf("invalid rate %v", rate)
+		}
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"provider": provider.Name(),
+				"currency": currency,
+			}).Warn("Failed to fetch fiat rate")
+			svc.reportFailure(provider)
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+		delete(svc.health, provider.Name())
+		svc.rates[currency] = cachedRate{rate: rate, fetchedAt: time.Now()}
+		return rate, nil
+	}
+
+	if hasCached && time.Since(cached.fetchedAt) < maxStaleRateAge {
+		logger.Logger.WithField("currency", currency).Warn("All fiat rate providers failed, using cached rate")
+		return cached.rate, nil
+	}
+	return 0, fmt.Errorf("failed to fetch %s rate: %w", currency, errors.Join(errs...))
+}
+
+// getProviders returns the providers which are not skipped after a failure, or all providers if
+// every one of them failed recently
+func (svc *FiatRatesService) getProviders() []Provider {
+	healthyProviders := []Provider{}
+	for _, provider := range svc.providers {
+		health, ok := svc.health[provider.Name()]
+		if !ok || time.Now().After(health.skippedUntil) {
+			healthyProviders = append(healthyProviders, provider)
+		}
+	}
+	if len(healthyProviders) == 0 {
+		return svc.providers
+	}
+	return healthyProviders
+}
+
+func (svc *FiatRatesService) reportFailure(provider Provider) {
+	health, ok := svc.health[provider.Name()]
+	if !ok {
+		health = &providerHealth{}
+		svc.health[provider.Name()] = health
+	}
+	health.failures++
+	backoff := min(minBackoff<<min(health.failures-1, 10), maxBackoff)
+	health.skippedUntil = time.Now().Add(backoff)
+}
@@ -0,0 +1,108 @@
+package fiatrates
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/logger"
+)
+
+type mockProvider struct {
+	name  string
+	rate  float64
+	err   error
+	calls int
+}
+
+func (provider *mockProvider) Name() string {
+	return provider.name
+}
+
+func (provider *mockProvider) GetRate(ctx context.Context, currency string) (float64, error) {
+	provider.calls++
+	return provider.rate, provider.err
+}
+
+func TestGetFiatRate_Fallback(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	first := &mockProvider{name: "first", err: errors.New("unavailable")}
+	second := &mockProvider{name: "second", rate: 65000}
+	svc := NewFiatRatesServiceWithProviders([]Provider{first, second})
+
+	rate, err := svc.GetFiatRate(context.TODO(), "usd")
+	require.NoError(t, err)
+	assert.Equal(t, float64(65000), rate)
+
+	// the failing provider is skipped until its backoff expires
+	delete(svc.rates, "USD")
+	_, err = svc.GetFiatRate(context.TODO(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.calls)
+	assert.Equal(t, 2, second.calls)
+}
+
+func TestGetFiatRate_Cache(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	provider := &mockProvider{name: "provider", rate: 65000}
+	svc := NewFiatRatesServiceWithProviders([]Provider{provider})
+
+	_, err := svc.GetFiatRate(context.TODO(), "USD")
+	require.NoError(t, err)
+	_, err = svc.GetFiatRate(context.TODO(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.calls)
+
+	// a stale rate is returned when all providers fail
+	svc.rates["USD"] = cachedRate{rate: 60000, fetchedAt: time.Now().Add(-10 * time.Minute)}
+	provider.err = errors.New("unavailable")
+	rate, err := svc.GetFiatRate(context.TODO(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, float64(60000), rate)
+
+	svc.rates["USD"] = cachedRate{rate: 60000, fetchedAt: time.Now().Add(-2 * time.Hour)}
+	_, err = svc.GetFiatRate(context.TODO(), "USD")
+	assert.Error(t, err)
+}
+
+func TestProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rates/USD", "/custom/USD":
+			w.Write([]byte(`{"rate_float": 65000.5}`))
+		case "/api/v3/simple/price":
+			w.Write([]byte(`{"bitcoin": {"usd": 65001}}`))
+		case "/0/public/Ticker":
+			w.Write([]byte(`{"error": [], "result": {"XXBTZUSD": {"c": ["65002.1", "0.01"]}}}`))
+		case "/api/v1/prices":
+			w.Write([]byte(`{"time": 1700000000, "USD": 65003}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	providers := map[Provider]float64{
+		&albyProvider{url: server.URL}:                          65000.5,
+		&coingeckoProvider{url: server.URL}:                     65001,
+		&krakenProvider{url: server.URL}:                        65002.1,
+		&mempoolProvider{url: server.URL}:                       65003,
+		&customProvider{url: server.URL + "/custom/{currency}"}: 65000.5,
+	}
+	for provider, expectedRate := range providers {
+		rate, err := provider.GetRate(context.TODO(), "USD")
+		require.NoError(t, err, provider.Name())
+		assert.Equal(t, expectedRate, rate, provider.Name())
+	}
+
+	_, err := (&mempoolProvider{url: server.URL}).GetRate(context.TODO(), "XYZ")
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package fiatrates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/version"
+)
+
+const (
+	PROVIDER_ALBY      = "alby"
+	PROVIDER_COINGECKO = "coingecko"
+	PROVIDER_KRAKEN    = "kraken"
+	PROVIDER_MEMPOOL   = "mempool"
+	PROVIDER_CUSTOM    = "custom"
+)
+
+// Provider returns the current bitcoin price in a fiat currency
+type Provider interface {
+	Name() string
+	GetRate(ctx context.Context, currency string) (float64, error)
+}
+
+type albyProvider struct {
+	url string
+}
+
+func (provider *albyProvider) Name() string {
+	return PROVIDER_ALBY
+}
+
+func (provider *albyProvider) GetRate(ctx context.Context, currency string) (float64, error) {
+	rate := struct {
+		RateFloat float64 `json:"rate_float"`
+	}{}
+	if err := getJSON(ctx, fmt.Sprintf("%s/rates/%s", provider.url, currency), &rate); err != nil {
+		return 0, err
+	}
+	return rate.RateFloat, nil
+}
+
+type coingeckoProvider struct {
+	url string
+}
+
+func (provider *coingeckoProvider) Name() string {
+	return PROVIDER_COINGECKO
+}
+
+func (provider *coingeckoProvider) GetRate(ctx context.Context, currency string) (float64, error) {
+	currency = strings.ToLower(currency)
+	prices := map[string]map[string]float64{}
+	if err := getJSON(ctx, fmt.Sprintf("%s/api/v3/simple/price?ids=bitcoin&vs_currencies=%s", provider.url, url.QueryEscape(currency)), &prices); err != nil {
+		return 0, err
+	}
+	rate, ok := prices["bitcoin"][currency]
+	if !ok {
+		return 0, fmt.Errorf("no %s rate in response", currency)
+	}
+	return rate, nil
+}
+
+type krakenProvider struct {
+	url string
+}
+
+func (provider *krakenProvider) Name() string {
+	return PROVIDER_KRAKEN
+}
+
+func (provider *krakenProvider) GetRate(ctx context.Context, currency string) (float64, error) {
+	ticker := struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			// last trade closed: price, lot volume
+			Close []string `json:"c"`
+		} `json:"result"`
+	}{}
+	if err := getJSON(ctx, fmt.Sprintf("%s/0/public/Ticker?pair=XBT%s", provider.url, url.QueryEscape(strings.ToUpper(currency))), &ticker); err != nil {
+		return 0, err
+	}
+	if len(ticker.Error) > 0 {
+		return 0, errors.New(strings.Join(ticker.Error, ", "))
+	}
+	// the pair is returned under Kraken's own name, e.g. XXBTZUSD
+	for _, pair := range ticker.Result {
+		if len(pair.Close) == 0 {
+			break
+		}
+		return strconv.ParseFloat(pair.Close[0], 64)
+	}
+	return 0, fmt.Errorf("no %s rate in response", currency)
+}
+
+// mempoolProvider only supports the major currencies of mempool.space
+type mempoolProvider struct {
+	url string
+}
+
+func (provider *mempoolProvider) Name() string {
+	return PROVIDER_MEMPOOL
+}
+
+func (provider *mempoolProvider) GetRate(ctx context.Context, currency string) (float64, error) {
+	prices := map[string]float64{}
+	if err := getJSON(ctx, provider.url+"/api/v1/prices", &prices); err != nil {
+		return 0, err
+	}
+	rate, ok := prices[strings.ToUpper(currency)]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no %s rate in response", currency)
+	}
+	return rate, nil
+}
+
+// customProvider requests a URL in which {currency} is replaced, which returns the rate in the
+// same format as the Alby API, e.g. {"rate_float": 65000.5}
+type customProvider struct {
+	url string
+}
+
+func (provider *customProvider) Name() string {
+	return PROVIDER_CUSTOM
+}
+
+func (provider *customProvider) GetRate(ctx context.Context, currency string) (float64, error) {
+	rate := struct {
+		RateFloat float64 `json:"rate_float"`
+	}{}
+	if err := getJSON(ctx, strings.ReplaceAll(provider.url, "{currency}", url.PathEscape(currency)), &rate); err != nil {
+		return 0, err
+	}
+	return rate.RateFloat, nil
+}
+
+func getJSON(ctx context.Context, requestUrl string, result interface{}) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "AlbyHub/"+version.Tag)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return errors.New("failed to read response body")
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("rate endpoint returned non-success code %d: %s", res.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, result)
+}
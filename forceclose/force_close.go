@@ -0,0 +1,239 @@
+// Package forceclose tracks force-closed channels. It alerts the user with the funds which are
+// timelocked on-chain and when they are expected to be swept, and again once they can be spent.
+package forceclose
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	INITIATOR_LOCAL   = "local"
+	INITIATOR_REMOTE  = "remote"
+	INITIATOR_UNKNOWN = "unknown"
+)
+
+// blockInterval is the average time between two blocks, used to estimate when timelocks expire
+const blockInterval = 10 * time.Minute
+
+var checkInterval = 10 * time.Minute
+
+type forceCloseService struct {
+	db             *gorm.DB
+	eventPublisher events.EventPublisher
+	lnClient       lnclient.LNClient
+}
+
+func NewForceCloseService(gormDB *gorm.DB, eventPublisher events.EventPublisher) *forceCloseService {
+	return &forceCloseService{
+		db:             gormDB,
+		eventPublisher: eventPublisher,
+	}
+}
+
+// IsForceClose returns whether the closure reason of a LDK or LND channel is a force-close
+func IsForceClose(reason string) bool {
+	reason = strings.ToLower(strings.ReplaceAll(reason, "_", ""))
+	for _, forceCloseReason := range []string{"forceclose", "commitmenttxconfirmed", "htlcstimedout", "breach"} {
+		if strings.Contains(reason, forceCloseReason) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetInitiator returns which side force-closed the channel
+func GetInitiator(reason string) string {
+	reason = strings.ToLower(strings.ReplaceAll(reason, "_", ""))
+	switch {
+	case strings.Contains(reason, "holder"), strings.Contains(reason, "localforceclose"), strings.Contains(reason, "htlcstimedout"):
+		return INITIATOR_LOCAL
+	case strings.Contains(reason, "counterparty"), strings.Contains(reason, "remoteforceclose"), strings.Contains(reason, "breach"):
+		return INITIATOR_REMOTE
+	}
+	return INITIATOR_UNKNOWN
+}
+
+// Start checks periodically whether the funds of force-closed channels can be spent until ctx is done
+func (svc *forceCloseService) Start(ctx context.Context, lnClient lnclient.LNClient) {
+	svc.lnClient = lnClient
+	svc.eventPublisher.RegisterSubscriber(svc)
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				svc.eventPublisher.RemoveSubscriber(svc)
+				return
+			case <-ticker.C:
+				svc.checkPending(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+func (svc *forceCloseService) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	if event.Event != "nwc_channel_closed" {
+		return
+	}
+	properties, ok := event.Properties.(map[string]interface{})
+	if !ok {
+		return
+	}
+	reason, _ := properties["reason"].(string)
+	if !IsForceClose(reason) {
+		return
+	}
+	forceClose := &db.ForceClose{
+		Reason:    reason,
+		Initiator: GetInitiator(reason),
+	}
+	forceClose.ChannelId, _ = properties["channel_id"].(string)
+	forceClose.CounterpartyNodeId, _ = properties["counterparty_node_id"].(string)
+	forceClose.FundingTxId, _ = properties["funding_tx_id"].(string)
+	forceClose.FundingTxVout, _ = properties["funding_tx_vout"].(uint32)
+	svc.handleForceClose(ctx, forceClose, time.Now())
+}
+
+func (svc *forceCloseService) handleForceClose(ctx context.Context, forceClose *db.ForceClose, now time.Time) {
+	var blocksUntilSpendable uint32
+	pendingBalances, err := svc.getPendingBalances(ctx)
+	if err != nil {
+		// the pending balance is updated by the next check
+		logger.Logger.WithError(err).Error("Failed to get pending balances of force-closed channel")
+	} else {
+		forceClose.PendingAmountSat, blocksUntilSpendable = findPendingBalance(forceClose, pendingBalances)
+		if forceClose.PendingAmountSat == 0 {
+			// none of the funds are ours, or they are already spendable
+			forceClose.SpendableAt = &now
+		}
+	}
+	if blocksUntilSpendable > 0 {
+		expectedSpendableAt := now.Add(time.Duration(blocksUntilSpendable) * blockInterval)
+		forceClose.ExpectedSpendableAt = &expectedSpendableAt
+	}
+
+	if err := svc.db.Create(forceClose).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to save force-closed channel")
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"channel_id":             forceClose.ChannelId,
+		"counterparty_node_id":   forceClose.CounterpartyNodeId,
+		"initiator":              forceClose.Initiator,
+		"pending_balance_sat":    forceClose.PendingAmountSat,
+		"blocks_until_spendable": blocksUntilSpendable,
+	}).Warn("Channel was force-closed")
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_channel_force_closed",
+		Properties: map[string]interface{}{
+			"channel_id":             forceClose.ChannelId,
+			"counterparty_node_id":   forceClose.CounterpartyNodeId,
+			"reason":                 forceClose.Reason,
+			"initiator":              forceClose.Initiator,
+			"funding_tx_id":          forceClose.FundingTxId,
+			"funding_tx_vout":        forceClose.FundingTxVout,
+			"pending_balance_sat":    forceClose.PendingAmountSat,
+			"blocks_until_spendable": blocksUntilSpendable,
+			"expected_spendable_at":  forceClose.ExpectedSpendableAt,
+		},
+	})
+}
+
+// checkPending publishes an event for every force-closed channel whose funds are no longer
+// pending, and updates the estimates of the others
+func (svc *forceCloseService) checkPending(ctx context.Context, now time.Time) {
+	forceCloses := []db.ForceClose{}
+	if err := svc.db.Where("spendable_at IS NULL").Find(&forceCloses).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to list force-closed channels")
+		return
+	}
+	if len(forceCloses) == 0 {
+		return
+	}
+	pendingBalances, err := svc.getPendingBalances(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get pending balances of force-closed channels")
+		return
+	}
+
+	for i := range forceCloses {
+		forceClose := &forceCloses[i]
+		pendingAmountSat, blocksUntilSpendable := findPendingBalance(forceClose, pendingBalances)
+		if pendingAmountSat > 0 {
+			var expectedSpendableAt *time.Time
+			if blocksUntilSpendable > 0 {
+				expected := now.Add(time.Duration(blocksUntilSpendable) * blockInterval)
+				expectedSpendableAt = &expected
+			}
+			err := svc.db.Model(forceClose).Updates(map[string]interface{}{
+				"pending_amount_sat":    pendingAmountSat,
+				"expected_spendable_at": expectedSpendableAt,
+			}).Error
+			if err != nil {
+				logger.Logger.WithError(err).WithField("force_close_id", forceClose.ID).Error("Failed to update force-closed channel")
+			}
+			continue
+		}
+
+		if err := svc.db.Model(forceClose).Update("spendable_at", now).Error; err != nil {
+			logger.Logger.WithError(err).WithField("force_close_id", forceClose.ID).Error("Failed to update force-closed channel")
+			continue
+		}
+		logger.Logger.WithFields(logrus.Fields{
+			"channel_id":           forceClose.ChannelId,
+			"counterparty_node_id": forceClose.CounterpartyNodeId,
+			"amount_sat":           forceClose.PendingAmountSat,
+		}).Info("Funds of force-closed channel are spendable")
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_force_close_funds_spendable",
+			Properties: map[string]interface{}{
+				"channel_id":           forceClose.ChannelId,
+				"counterparty_node_id": forceClose.CounterpartyNodeId,
+				"funding_tx_id":        forceClose.FundingTxId,
+				"amount_sat":           forceClose.PendingAmountSat,
+			},
+		})
+	}
+}
+
+func (svc *forceCloseService) getPendingBalances(ctx context.Context) ([]lnclient.PendingBalanceDetails, error) {
+	onchainBalance, err := svc.lnClient.GetOnchainBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(onchainBalance.PendingBalancesDetails, onchainBalance.PendingSweepBalancesDetails...), nil
+}
+
+// findPendingBalance returns the pending balance of the channel and the blocks until all of it can be swept.
+// LND does not return channel ids for closed channels, so they are matched by their funding transaction.
+func findPendingBalance(forceClose *db.ForceClose, pendingBalances []lnclient.PendingBalanceDetails) (uint64, uint32) {
+	var amountSat uint64
+	var blocksUntilSpendable uint32
+	for _, pendingBalance := range pendingBalances {
+		var matches bool
+		if pendingBalance.ChannelId != "" {
+			matches = pendingBalance.ChannelId == forceClose.ChannelId
+		} else {
+			matches = pendingBalance.NodeId == forceClose.CounterpartyNodeId &&
+				(forceClose.FundingTxId == "" || pendingBalance.FundingTxId == forceClose.FundingTxId)
+		}
+		if !matches {
+			continue
+		}
+		amountSat += pendingBalance.Amount
+		blocksUntilSpendable = max(blocksUntilSpendable, pendingBalance.BlocksUntilSpendable)
+	}
+	return amountSat, blocksUntilSpendable
+}
@@ -0,0 +1,92 @@
+package forceclose
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+type mockPendingBalancesLNClient struct {
+	lnclient.LNClient
+	pendingBalances []lnclient.PendingBalanceDetails
+}
+
+func (mln *mockPendingBalancesLNClient) GetOnchainBalance(ctx context.Context) (*lnclient.OnchainBalanceResponse, error) {
+	return &lnclient.OnchainBalanceResponse{PendingBalancesDetails: mln.pendingBalances}, nil
+}
+
+func TestIsForceClose(t *testing.T) {
+	assert.True(t, IsForceClose("CounterpartyForceClosed (Peer message: bye)"))
+	assert.True(t, IsForceClose("HolderForceClosed"))
+	assert.True(t, IsForceClose("REMOTE_FORCE_CLOSE"))
+	assert.True(t, IsForceClose("BREACH_CLOSE"))
+	assert.False(t, IsForceClose("LocallyInitiatedCooperativeClosure"))
+	assert.False(t, IsForceClose("COOPERATIVE_CLOSE"))
+
+	assert.Equal(t, INITIATOR_REMOTE, GetInitiator("CounterpartyForceClosed (Peer message: bye)"))
+	assert.Equal(t, INITIATOR_LOCAL, GetInitiator("HolderForceClosed"))
+	assert.Equal(t, INITIATOR_LOCAL, GetInitiator("LOCAL_FORCE_CLOSE"))
+	assert.Equal(t, INITIATOR_REMOTE, GetInitiator("REMOTE_FORCE_CLOSE"))
+	assert.Equal(t, INITIATOR_UNKNOWN, GetInitiator("CommitmentTxConfirmed"))
+}
+
+func TestForceClose_FundsSpendable(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	lnClient := &mockPendingBalancesLNClient{
+		LNClient: svc.LNClient,
+		pendingBalances: []lnclient.PendingBalanceDetails{
+			// matched by the funding transaction, like LND
+			{NodeId: "node1", FundingTxId: "tx1", Amount: 40_000, BlocksUntilSpendable: 144},
+			{NodeId: "node1", FundingTxId: "tx2", Amount: 1_000},
+		},
+	}
+	consumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(consumer)
+	forceCloseSvc := NewForceCloseService(svc.DB, svc.EventPublisher)
+	forceCloseSvc.lnClient = lnClient
+
+	now := time.Now()
+	forceCloseSvc.handleForceClose(context.TODO(), &db.ForceClose{
+		CounterpartyNodeId: "node1",
+		FundingTxId:        "tx1",
+		Reason:             "REMOTE_FORCE_CLOSE",
+		Initiator:          INITIATOR_REMOTE,
+	}, now)
+
+	consumedEvents := consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 1)
+	assert.Equal(t, "nwc_channel_force_closed", consumedEvents[0].Event)
+	properties := consumedEvents[0].Properties.(map[string]interface{})
+	assert.Equal(t, uint64(40_000), properties["pending_balance_sat"])
+	assert.Equal(t, uint32(144), properties["blocks_until_spendable"])
+	assert.Equal(t, now.Add(24*time.Hour), *properties["expected_spendable_at"].(*time.Time))
+
+	// still timelocked
+	forceCloseSvc.checkPending(context.TODO(), now.Add(time.Hour))
+	assert.Len(t, consumer.GetConsumedEvents(), 1)
+
+	lnClient.pendingBalances = lnClient.pendingBalances[1:]
+	forceCloseSvc.checkPending(context.TODO(), now.Add(25*time.Hour))
+	consumedEvents = consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 2)
+	assert.Equal(t, "nwc_force_close_funds_spendable", consumedEvents[1].Event)
+	assert.Equal(t, uint64(40_000), consumedEvents[1].Properties.(map[string]interface{})["amount_sat"])
+
+	var forceClose db.ForceClose
+	require.NoError(t, svc.DB.First(&forceClose).Error)
+	assert.NotNil(t, forceClose.SpendableAt)
+
+	// the follow-up is only sent once
+	forceCloseSvc.checkPending(context.TODO(), now.Add(26*time.Hour))
+	assert.Len(t, consumer.GetConsumedEvents(), 2)
+}
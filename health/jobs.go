@@ -0,0 +1,85 @@
+package health
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a job is reported as stale if it did not run for this many intervals
+const staleJobIntervals = 3
+
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Interval  string     `json:"interval"`
+	LastRunAt *time.Time `json:"lastRunAt,omitempty"`
+	Failing   bool       `json:"failing"`
+	Stale     bool       `json:"stale"`
+}
+
+type job struct {
+	interval  time.Duration
+	startedAt time.Time
+	lastRunAt *time.Time
+	lastErr   error
+}
+
+var (
+	jobs      = map[string]*job{}
+	jobsMutex sync.Mutex
+)
+
+// RegisterJob starts tracking a periodic background job which reports its runs with ReportJobRun
+func RegisterJob(name string, interval time.Duration) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	jobs[name] = &job{
+		interval:  interval,
+		startedAt: time.Now(),
+	}
+}
+
+// RemoveJob stops tracking a job, e.g. because the node was stopped
+func RemoveJob(name string) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	delete(jobs, name)
+}
+
+func ReportJobRun(name string, err error) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	job, ok := jobs[name]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	job.lastRunAt = &now
+	job.lastErr = err
+}
+
+// GetJobStatuses returns the status of all running jobs, ordered by name
+func GetJobStatuses() []JobStatus {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for name, job := range jobs {
+		lastActivity := job.startedAt
+		if job.lastRunAt != nil {
+			lastActivity = *job.lastRunAt
+		}
+		statuses = append(statuses, JobStatus{
+			Name:      name,
+			Interval:  job.interval.String(),
+			LastRunAt: job.lastRunAt,
+			Failing:   job.lastErr != nil,
+			Stale:     time.Since(lastActivity) > staleJobIntervals*job.interval,
+		})
+	}
+	slices.SortFunc(statuses, func(a, b JobStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return statuses
+}
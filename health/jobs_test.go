@@ -0,0 +1,37 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJobStatuses(t *testing.T) {
+	RegisterJob("sweep", time.Minute)
+	defer RemoveJob("sweep")
+	RegisterJob("prune", time.Hour)
+	defer RemoveJob("prune")
+
+	ReportJobRun("sweep", errors.New("database is locked"))
+	// not registered
+	ReportJobRun("sync", nil)
+
+	statuses := GetJobStatuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "prune", statuses[0].Name)
+	assert.Nil(t, statuses[0].LastRunAt)
+	assert.False(t, statuses[0].Failing)
+	assert.False(t, statuses[0].Stale)
+	assert.Equal(t, "sweep", statuses[1].Name)
+	assert.NotNil(t, statuses[1].LastRunAt)
+	assert.True(t, statuses[1].Failing)
+
+	ReportJobRun("sweep", nil)
+	assert.False(t, GetJobStatuses()[1].Failing)
+
+	jobs["prune"].startedAt = time.Now().Add(-4 * time.Hour)
+	assert.True(t, GetJobStatuses()[0].Stale)
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/auditlog"
+	"github.com/getAlby/hub/logger"
+)
+
+// payments are already recorded as transactions and would flood the audit log,
+// sub-wallets are used by their owners rather than by administrators
+var auditSkippedRoutes = []string{
+	"/api/payments/:invoice",
+	"/api/invoices",
+	"/api/offers",
+	"/api/automation/payments/:invoice",
+	"/api/automation/invoices",
+}
+
+const auditSkippedPrefix = "/api/subwallet/"
+
+// recordAdminAction appends every request which changes something through the admin API,
+// including failed ones like a wrong unlock password, to the admin audit log.
+// Request bodies are not recorded as they can contain passwords and secrets.
+func (httpSvc *HttpService) recordAdminAction(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		method := c.Request().Method
+		route := c.Path()
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions ||
+			!strings.HasPrefix(route, "/api/") || strings.HasPrefix(route, auditSkippedPrefix) || slices.Contains(auditSkippedRoutes, route) {
+			return next(c)
+		}
+
+		err := next(c)
+
+		status := c.Response().Status
+		var httpError *echo.HTTPError
+		if errors.As(err, &httpError) {
+			status = httpError.Code
+		} else if err != nil {
+			status = http.StatusInternalServerError
+		}
+		details := map[string]interface{}{
+			"status": status,
+		}
+		if len(c.ParamNames()) > 0 {
+			params := map[string]string{}
+			for _, name := range c.ParamNames() {
+				params[name] = c.Param(name)
+			}
+			details["params"] = params
+		}
+
+		recordErr := auditlog.Record(httpSvc.db, method+" "+route, auditActor(c), c.RealIP(), details)
+		if recordErr != nil {
+			logger.Logger.WithError(recordErr).WithField("route", route).Error("Failed to record admin action")
+		}
+		return err
+	}
+}
+
+func auditActor(c echo.Context) string {
+	if apiKey, ok := c.Get("apiKey").(*api.ApiKey); ok {
+		return fmt.Sprintf("api_key:%d", apiKey.ID)
+	}
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		// e.g. a login
+		return "anonymous"
+	}
+	claims, ok := token.Claims.(*jwtCustomClaims)
+	if !ok {
+		return "anonymous"
+	}
+	if claims.Permission == "subwallet" {
+		return fmt.Sprintf("subwallet:%d", claims.AppId)
+	}
+	if claims.AdminUserId != 0 {
+		return fmt.Sprintf("admin_user:%d", claims.AdminUserId)
+	}
+	return "owner"
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/getAlby/hub/events"
+)
+
+// decoyAppIdKey marks requests of a session which was opened with the duress password
+const decoyAppIdKey = "decoyAppId"
+
+// decoyLogin responds like a successful login with full access, but the session is backed by the
+// decoy wallet. Neither spending nor the node are unlocked.
+func (httpSvc *HttpService) decoyLogin(c echo.Context, tokenExpiryDays *uint64, totpCode string, appId uint) error {
+	// the code cannot be verified without the unlock password, but it is still asked for
+	if httpSvc.api.IsTotpEnabled() && totpCode == "" {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: "two-factor code required",
+		})
+	}
+
+	expiryDays := uint64(30)
+	if tokenExpiryDays != nil {
+		expiryDays = *tokenExpiryDays
+	}
+	claims := &jwtCustomClaims{
+		Permission: "full",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * time.Duration(expiryDays))),
+		},
+	}
+	token, err := httpSvc.signSessionJWT(c, claims, &appId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
+		})
+	}
+
+	httpSvc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_duress_unlock",
+		Properties: map[string]interface{}{
+			"app_id":    appId,
+			"remote_ip": c.RealIP(),
+		},
+	})
+
+	return c.JSON(http.StatusOK, &authTokenResponse{
+		Token: token,
+	})
+}
+
+// serveDecoySession answers the requests of duress sessions from the decoy wallet. The wallet routes
+// are served like for the sub-wallet, other data looks empty and nothing else can be changed.
+func (httpSvc *HttpService) serveDecoySession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		appId, ok := c.Get(decoyAppIdKey).(uint)
+		if !ok {
+			return next(c)
+		}
+		c.Set("subwalletAppId", appId)
+
+		switch c.Request().Method + " " + c.Path() {
+		case "GET /api/balances":
+			return httpSvc.decoyBalancesHandler(c)
+		case "GET /api/transactions":
+			return httpSvc.subwalletTransactionsListHandler(c)
+		case "POST /api/invoices":
+			return httpSvc.subwalletMakeInvoiceHandler(c)
+		case "POST /api/payments/:invoice":
+			return httpSvc.subwalletSendPaymentHandler(c)
+		case "POST /api/lock":
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		if c.Request().Method == http.MethodGet {
+			return c.JSON(http.StatusOK, []interface{}{})
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "Something went wrong, please try again later",
+		})
+	}
+}
+
+func (httpSvc *HttpService) decoyBalancesHandler(c echo.Context) error {
+	balances, err := httpSvc.api.GetDecoyBalances(c.Get(decoyAppIdKey).(uint))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, balances)
+}
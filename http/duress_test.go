@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	hubdb "github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/tests/db"
+	"github.com/getAlby/hub/tests/mocks"
+)
+
+func TestUnlock_DuressPassword(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	decoyApp := hubdb.App{Name: "Wallet", Isolated: true}
+	require.NoError(t, gormDb.Create(&decoyApp).Error)
+	require.NoError(t, gormDb.Create(&hubdb.Transaction{
+		AppId:       &decoyApp.ID,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		Type:        constants.TRANSACTION_TYPE_INCOMING,
+		AmountMsat:  21_000,
+		PaymentHash: "decoy",
+	}).Error)
+
+	key, salt, err := config.DeriveKey("duress-password", nil)
+	require.NoError(t, err)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+	mockConfig.On("CheckUnlockPassword", "duress-password").Return(false)
+	mockConfig.On("Get", "DuressAppId", "").Return(strconv.FormatUint(uint64(decoyApp.ID), 10), nil)
+	mockConfig.On("Get", "DuressPasswordHash", "").Return(hex.EncodeToString(salt)+"-"+hex.EncodeToString(key), nil)
+	mockConfig.On("Get", "TotpEnabled", "").Return("", nil)
+	mockConfig.On("Get", config.SpendableBalanceBucketsKey, "").Return("", nil)
+	mockConfig.On("GetJWTSecret").Return("dummy secret")
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	jsonBody, _ := json.Marshal(api.UnlockRequest{UnlockPassword: "duress-password", Permission: "full"})
+	req := httptest.NewRequest(http.MethodPost, "/api/unlock", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var unlockResponse authTokenResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &unlockResponse))
+
+	// the token looks like the token of the owner
+	claims := &jwtCustomClaims{}
+	_, err = jwt.ParseWithClaims(unlockResponse.Token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte("dummy secret"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "full", claims.Permission)
+	assert.Zero(t, claims.AppId)
+
+	serve := func(method string, route string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, route, nil)
+		req.Header.Set("Authorization", "Bearer "+unlockResponse.Token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// the balance is the one of the decoy wallet
+	rec = serve(http.MethodGet, "/api/balances")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var balances api.BalancesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &balances))
+	assert.Equal(t, int64(21_000), balances.Lightning.TotalSpendable)
+	assert.Equal(t, int64(21_000), balances.Spendable.Total)
+
+	// other data looks empty and nothing can be changed
+	rec = serve(http.MethodGet, "/api/sessions")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+	assert.Equal(t, http.StatusInternalServerError, serve(http.MethodPost, "/api/mnemonic").Code)
+}
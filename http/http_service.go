@@ -2,6 +2,10 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,10 +23,16 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/autolock"
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
+	"github.com/getAlby/hub/plugins"
 	"github.com/getAlby/hub/service"
+	"github.com/getAlby/hub/transactions"
+	"github.com/getAlby/hub/utils"
 
 	"github.com/getAlby/hub/api"
 	"github.com/getAlby/hub/frontend"
@@ -36,7 +46,9 @@ type jwtCustomClaims struct {
 	// we can add extra claims here
 	// Name  string `json:"name"`
 	// Admin bool   `json:"admin"`
-	Permission string `json:"permission,omitempty"` // "full" or "readonly"
+	Permission  string `json:"permission,omitempty"`  // "full", "readonly", "subwallet" or "admin_user"
+	AppId       uint   `json:"appId,omitempty"`       // the sub-wallet a "subwallet" token is scoped to
+	AdminUserId uint   `json:"adminUserId,omitempty"` // the admin user a token belongs to, 0 for the unlock password
 	jwt.RegisteredClaims
 }
 
@@ -47,10 +59,15 @@ type HttpService struct {
 	eventPublisher events.EventPublisher
 	db             *gorm.DB
 	appsSvc        apps.AppsService
+	// cancelled when the server shuts down, to end long-lived event streams
+	streamsCtx      context.Context
+	cancelStreams   context.CancelFunc
+	networkPolicy   *networkPolicy
+	abuseProtection *abuseProtection
 }
 
 func NewHttpService(svc service.Service, eventPublisher events.EventPublisher) *HttpService {
-	return &HttpService{
+	httpSvc := &HttpService{
 		api:            api.NewAPI(svc, svc.GetDB(), svc.GetConfig(), svc.GetKeys(), svc.GetAlbySvc(), svc.GetAlbyOAuthSvc(), eventPublisher),
 		albyHttpSvc:    NewAlbyHttpService(svc, svc.GetAlbySvc(), svc.GetAlbyOAuthSvc(), svc.GetConfig().GetEnv()),
 		cfg:            svc.GetConfig(),
@@ -58,10 +75,14 @@ func NewHttpService(svc service.Service, eventPublisher events.EventPublisher) *
 		db:             svc.GetDB(),
 		appsSvc:        apps.NewAppsService(svc.GetDB(), eventPublisher, svc.GetKeys(), svc.GetConfig()),
 	}
+	httpSvc.streamsCtx, httpSvc.cancelStreams = context.WithCancel(context.Background())
+	return httpSvc
 }
 
 func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	e.HideBanner = true
+	// otherwise open event streams would block the graceful shutdown
+	e.Server.RegisterOnShutdown(httpSvc.cancelStreams)
 
 	e.Use(middleware.SecureWithConfig(middleware.SecureConfig{
 		ContentTypeNosniff:    "nosniff",
@@ -92,6 +113,17 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	e.Use(middleware.Recover())
 	e.Use(middleware.RequestID())
 
+	e.IPExtractor = newIPExtractor(httpSvc.cfg.GetEnv())
+	httpSvc.abuseProtection = newAbuseProtection(httpSvc.cfg)
+	e.Use(httpSvc.abuseProtection.middleware)
+	httpSvc.networkPolicy = newNetworkPolicy(httpSvc.cfg)
+	e.Use(httpSvc.networkPolicy.middleware)
+	e.Use(httpSvc.recordAdminAction)
+
+	// probes for container orchestration and uptime monitoring
+	e.GET("/healthz", httpSvc.livenessHandler)
+	e.GET("/readyz", httpSvc.readinessHandler)
+
 	e.GET("/api/info", httpSvc.infoHandler)
 	e.POST("/api/setup", httpSvc.setupHandler)
 	e.POST("/api/restore", httpSvc.restoreBackupHandler)
@@ -101,7 +133,21 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	e.POST("/api/start", httpSvc.startHandler, unlockRateLimiter)
 	e.POST("/api/unlock", httpSvc.unlockHandler, unlockRateLimiter)
 	e.POST("/api/backup", httpSvc.createBackupHandler, unlockRateLimiter)
+	e.POST("/api/restore/validate", httpSvc.validateBackupHandler, unlockRateLimiter)
+	e.POST("/api/recovery", httpSvc.startRecoveryHandler, unlockRateLimiter)
 	e.GET("/logout", httpSvc.logoutHandler, unlockRateLimiter)
+	e.POST("/api/subwallet/login", httpSvc.subwalletLoginHandler, unlockRateLimiter)
+	e.POST("/api/admin-user/login", httpSvc.adminUserLoginHandler, unlockRateLimiter)
+	e.POST("/api/passkeys/login/begin", httpSvc.beginPasskeyLoginHandler, unlockRateLimiter)
+	e.POST("/api/passkeys/login/finish", httpSvc.finishPasskeyLoginHandler, unlockRateLimiter)
+
+	// lightning addresses of isolated apps are paid from other wallets, so they are public and allow cross-origin requests
+	e.GET("/.well-known/lnurlp/:username", httpSvc.lnurlPayHandler, middleware.CORS())
+	e.GET("/api/lnurlp/:username/callback", httpSvc.lnurlPayCallbackHandler, middleware.CORS())
+
+	if httpSvc.cfg.GetEnv().MetricsEnabled {
+		e.GET("/metrics", echo.WrapHandler(metrics.Handler()), httpSvc.metricsAuthMiddleware)
+	}
 
 	frontend.RegisterHandlers(e)
 
@@ -119,10 +165,19 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	// Read-only API group - accessible to both full and readonly tokens
 	readOnlyApiGroup := e.Group("/api")
 	readOnlyApiGroup.Use(echojwt.WithConfig(jwtConfig))
+	readOnlyApiGroup.Use(httpSvc.requireActiveSession)
+	readOnlyApiGroup.Use(httpSvc.serveDecoySession)
+	readOnlyApiGroup.Use(httpSvc.resolveAdminUserRole)
+	readOnlyApiGroup.Use(httpSvc.rejectScopedAccess)
 
 	readOnlyApiGroup.GET("/apps", httpSvc.appsListHandler)
 	readOnlyApiGroup.GET("/apps/:pubkey", httpSvc.appsShowByPubkeyHandler)
 	readOnlyApiGroup.GET("/v2/apps/:id", httpSvc.appsShowHandler)
+	readOnlyApiGroup.GET("/v2/apps/:id/addresses", httpSvc.subwalletAddressesListHandler)
+	readOnlyApiGroup.GET("/v2/apps/:id/audit-log", httpSvc.appAuditLogsListHandler)
+	readOnlyApiGroup.GET("/v2/apps/:id/activity", httpSvc.appActivityListHandler)
+	readOnlyApiGroup.GET("/v2/apps/:id/report", httpSvc.appReportHandler)
+	readOnlyApiGroup.GET("/v2/apps/:id/destinations", httpSvc.appDestinationsListHandler)
 	readOnlyApiGroup.GET("/channels", httpSvc.channelsListHandler)
 	readOnlyApiGroup.GET("/channels/suggestions", httpSvc.channelPeerSuggestionsHandler)
 	readOnlyApiGroup.GET("/channel-offer", httpSvc.channelOfferHandler)
@@ -134,8 +189,12 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	readOnlyApiGroup.GET("/wallet/address", httpSvc.onchainAddressHandler)
 	readOnlyApiGroup.GET("/wallet/capabilities", httpSvc.capabilitiesHandler)
 	readOnlyApiGroup.GET("/transactions", httpSvc.listTransactionsHandler)
+	readOnlyApiGroup.GET("/transactions/export", httpSvc.exportTransactionsHandler)
+	readOnlyApiGroup.GET("/transactions/summary", httpSvc.transactionsSummaryHandler)
 	readOnlyApiGroup.GET("/transactions/:paymentHash", httpSvc.lookupTransactionHandler)
+	readOnlyApiGroup.GET("/transactions/:paymentHash/receipt", httpSvc.transactionReceiptHandler)
 	readOnlyApiGroup.GET("/balances", httpSvc.balancesHandler)
+	readOnlyApiGroup.GET("/events/stream", httpSvc.eventStreamHandler)
 	readOnlyApiGroup.GET("/mempool", httpSvc.mempoolApiHandler)
 	readOnlyApiGroup.GET("/log/:type", httpSvc.getLogOutputHandler)
 	readOnlyApiGroup.GET("/health", httpSvc.healthHandler)
@@ -147,26 +206,86 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	readOnlyApiGroup.GET("/swaps/mnemonic", httpSvc.swapMnemonicHandler)
 	readOnlyApiGroup.GET("/autoswap", httpSvc.getAutoSwapConfigHandler)
 	readOnlyApiGroup.GET("/forwards", httpSvc.forwardsHandler)
+	readOnlyApiGroup.GET("/webhooks", httpSvc.listWebhooksHandler)
+	readOnlyApiGroup.GET("/webhooks/event-types", httpSvc.listWebhookEventTypesHandler)
+	readOnlyApiGroup.GET("/webhooks/:id/deliveries", httpSvc.listWebhookDeliveriesHandler)
+	readOnlyApiGroup.GET("/scheduled-payments", httpSvc.listScheduledPaymentsHandler)
+	readOnlyApiGroup.GET("/swap-rules", httpSvc.listSwapRulesHandler)
+	readOnlyApiGroup.GET("/lsp-orders", httpSvc.listLSPOrdersHandler)
+	readOnlyApiGroup.GET("/swap-rules/:id/runs", httpSvc.listSwapRuleRunsHandler)
+	readOnlyApiGroup.GET("/fee-policies", httpSvc.listFeePoliciesHandler)
+	readOnlyApiGroup.GET("/liquidity/report", httpSvc.liquidityReportHandler)
+	readOnlyApiGroup.GET("/backup-targets", httpSvc.listBackupTargetsHandler)
+	readOnlyApiGroup.GET("/database/maintenance", httpSvc.getDatabaseMaintenanceStatusHandler)
+	readOnlyApiGroup.GET("/database/migrations", httpSvc.getDatabaseMigrationsHandler)
+	readOnlyApiGroup.GET("/database/key-rotation", httpSvc.getDatabaseKeyRotationHandler)
+	readOnlyApiGroup.GET("/admin-audit-log/export", httpSvc.exportAdminAuditLogHandler, requireOwnerRole)
+	readOnlyApiGroup.GET("/admin-audit-log/verify", httpSvc.verifyAdminAuditLogHandler, requireOwnerRole)
+	readOnlyApiGroup.GET("/recovery", httpSvc.getRecoveryHandler)
+	readOnlyApiGroup.GET("/backup-targets/:id/snapshots", httpSvc.listBackupSnapshotsHandler)
+	readOnlyApiGroup.GET("/app-groups", httpSvc.listAppGroupsHandler)
+	readOnlyApiGroup.GET("/app-templates", httpSvc.listAppTemplatesHandler)
+	readOnlyApiGroup.GET("/payment-approvals", httpSvc.listPaymentApprovalsHandler)
+	readOnlyApiGroup.GET("/admin-users", httpSvc.listAdminUsersHandler)
+	readOnlyApiGroup.GET("/api-keys", httpSvc.listApiKeysHandler)
+	readOnlyApiGroup.GET("/sessions", httpSvc.listSessionsHandler, requireOwnerRole)
+	readOnlyApiGroup.GET("/passkeys", httpSvc.listPasskeysHandler, requireOwnerRole)
+	readOnlyApiGroup.GET("/push/devices", httpSvc.listPushDevicesHandler)
+	readOnlyApiGroup.GET("/dead-letters", httpSvc.listDeadLettersHandler)
 
 	// Full access API group - requires a token with full permissions
 	fullAccessApiGroup := e.Group("/api")
 	fullAccessApiGroup.Use(echojwt.WithConfig(jwtConfig))
+	fullAccessApiGroup.Use(httpSvc.requireActiveSession)
+	fullAccessApiGroup.Use(httpSvc.serveDecoySession)
+	fullAccessApiGroup.Use(httpSvc.resolveAdminUserRole)
 	fullAccessApiGroup.Use(httpSvc.requireFullAccess)
 
 	fullAccessApiGroup.POST("/api/event", httpSvc.eventHandler)
-	fullAccessApiGroup.PATCH("/unlock-password", httpSvc.changeUnlockPasswordHandler)
-	fullAccessApiGroup.PATCH("/auto-unlock", httpSvc.autoUnlockHandler)
-	fullAccessApiGroup.PATCH("/settings", httpSvc.updateSettingsHandler)
+	fullAccessApiGroup.PATCH("/unlock-password", httpSvc.changeUnlockPasswordHandler, requireOwnerRole)
+	fullAccessApiGroup.PATCH("/auto-unlock", httpSvc.autoUnlockHandler, requireOwnerRole)
+	fullAccessApiGroup.PATCH("/settings", httpSvc.updateSettingsHandler, requireOwnerRole)
 	fullAccessApiGroup.PATCH("/apps/:pubkey", httpSvc.appsUpdateHandler)
 	fullAccessApiGroup.DELETE("/apps/:pubkey", httpSvc.appsDeleteHandler)
 	fullAccessApiGroup.POST("/transfers", httpSvc.transfersHandler)
+	fullAccessApiGroup.POST("/v2/apps/bulk", httpSvc.appsBulkUpdateHandler)
+	fullAccessApiGroup.POST("/v2/apps/:id/renew", httpSvc.appsRenewHandler)
+	fullAccessApiGroup.POST("/v2/apps/:id/rotate-secret", httpSvc.appsRotateSecretHandler)
+	fullAccessApiGroup.POST("/v2/apps/:id/scopes", httpSvc.appScopesAddHandler)
+	fullAccessApiGroup.DELETE("/v2/apps/:id/scopes/:scope", httpSvc.appScopesRemoveHandler)
+	fullAccessApiGroup.POST("/v2/apps/:id/destinations", httpSvc.appDestinationsAddHandler)
+	fullAccessApiGroup.DELETE("/v2/apps/:id/destinations/:destinationId", httpSvc.appDestinationsRemoveHandler)
+	fullAccessApiGroup.POST("/v2/apps/:id/addresses", httpSvc.subwalletAddressesCreateHandler)
+	fullAccessApiGroup.PATCH("/v2/apps/:id/owner-password", httpSvc.subwalletOwnerPasswordHandler)
+	fullAccessApiGroup.PATCH("/v2/apps/:id/lightning-address", httpSvc.appLightningAddressHandler)
 	fullAccessApiGroup.POST("/apps", httpSvc.appsCreateHandler)
 	fullAccessApiGroup.POST("/lightning-addresses", httpSvc.lightningAddressesCreateHandler)
 	fullAccessApiGroup.DELETE("/lightning-addresses/:appId", httpSvc.lightningAddressesDeleteHandler)
-	fullAccessApiGroup.POST("/mnemonic", httpSvc.mnemonicHandler)
+	fullAccessApiGroup.POST("/mnemonic", httpSvc.mnemonicHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/seed-passphrase", httpSvc.setSeedPassphraseHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/lock", httpSvc.lockHandler)
+	fullAccessApiGroup.POST("/totp/setup", httpSvc.setupTotpHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/totp/enable", httpSvc.enableTotpHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/totp/disable", httpSvc.disableTotpHandler, requireOwnerRole)
+	fullAccessApiGroup.GET("/duress", httpSvc.duressSettingsHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/duress", httpSvc.setDuressPasswordHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/duress/disable", httpSvc.disableDuressPasswordHandler, requireOwnerRole)
+	fullAccessApiGroup.GET("/email-notifications", httpSvc.emailNotificationSettingsHandler)
+	fullAccessApiGroup.PUT("/email-notifications", httpSvc.updateEmailNotificationSettingsHandler)
+	fullAccessApiGroup.POST("/email-notifications/test", httpSvc.sendTestEmailHandler)
+	fullAccessApiGroup.POST("/passkeys/register/begin", httpSvc.beginPasskeyRegistrationHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/passkeys/register/finish", httpSvc.finishPasskeyRegistrationHandler, requireOwnerRole)
+	fullAccessApiGroup.DELETE("/passkeys/:id", httpSvc.deletePasskeyHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/push/devices", httpSvc.registerPushDeviceHandler)
+	fullAccessApiGroup.DELETE("/push/devices/:id", httpSvc.revokePushDeviceHandler)
+	fullAccessApiGroup.POST("/push/devices/:id/test", httpSvc.sendTestPushNotificationHandler)
+	fullAccessApiGroup.POST("/dead-letters/replay", httpSvc.replayDeadLettersHandler)
+	fullAccessApiGroup.POST("/dead-letters/:id/replay", httpSvc.replayDeadLetterHandler)
+	fullAccessApiGroup.DELETE("/dead-letters/:id", httpSvc.discardDeadLetterHandler)
 	fullAccessApiGroup.PATCH("/backup-reminder", httpSvc.backupReminderHandler)
 	fullAccessApiGroup.POST("/channels", httpSvc.openChannelHandler)
 	fullAccessApiGroup.POST("/channels/rebalance", httpSvc.rebalanceChannelHandler)
+	fullAccessApiGroup.POST("/channels/rebalance/circular", httpSvc.rebalanceCircularHandler)
 	fullAccessApiGroup.POST("/lsp-orders", httpSvc.newInstantChannelInvoiceHandler)
 	fullAccessApiGroup.POST("/node/migrate-storage", httpSvc.migrateNodeStorageHandler)
 	fullAccessApiGroup.POST("/peers", httpSvc.connectPeerHandler)
@@ -178,19 +297,111 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	fullAccessApiGroup.POST("/wallet/sign-message", httpSvc.signMessageHandler)
 	fullAccessApiGroup.POST("/wallet/sync", httpSvc.walletSyncHandler)
 	fullAccessApiGroup.POST("/payments/:invoice", httpSvc.sendPaymentHandler)
+	fullAccessApiGroup.POST("/transactions/:paymentHash/refund", httpSvc.refundTransactionHandler)
+	fullAccessApiGroup.POST("/transactions/archive", httpSvc.archiveTransactionsHandler, unlockRateLimiter)
 	fullAccessApiGroup.POST("/invoices", httpSvc.makeInvoiceHandler)
 	fullAccessApiGroup.POST("/offers", httpSvc.makeOfferHandler)
 	fullAccessApiGroup.POST("/reset-router", httpSvc.resetRouterHandler)
-	fullAccessApiGroup.POST("/stop", httpSvc.stopHandler)
+	fullAccessApiGroup.POST("/stop", httpSvc.stopHandler, requireOwnerRole)
 	fullAccessApiGroup.POST("/send-payment-probes", httpSvc.sendPaymentProbesHandler)
 	fullAccessApiGroup.POST("/send-spontaneous-payment-probes", httpSvc.sendSpontaneousPaymentProbesHandler)
-	fullAccessApiGroup.POST("/command", httpSvc.execCustomNodeCommandHandler)
+	fullAccessApiGroup.POST("/command", httpSvc.execCustomNodeCommandHandler, requireOwnerRole)
 	fullAccessApiGroup.POST("/swaps/out", httpSvc.initiateSwapOutHandler)
 	fullAccessApiGroup.POST("/swaps/in", httpSvc.initiateSwapInHandler)
 	fullAccessApiGroup.POST("/swaps/refund", httpSvc.refundSwapHandler)
 	fullAccessApiGroup.POST("/autoswap", httpSvc.enableAutoSwapOutHandler)
 	fullAccessApiGroup.DELETE("/autoswap", httpSvc.disableAutoSwapOutHandler)
 	fullAccessApiGroup.POST("/node/alias", httpSvc.setNodeAliasHandler)
+	fullAccessApiGroup.POST("/webhooks", httpSvc.createWebhookHandler)
+	fullAccessApiGroup.POST("/app-configs/export", httpSvc.exportAppsHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/app-configs/import", httpSvc.importAppsHandler)
+	fullAccessApiGroup.PATCH("/webhooks/:id", httpSvc.updateWebhookHandler)
+	fullAccessApiGroup.DELETE("/webhooks/:id", httpSvc.deleteWebhookHandler)
+	fullAccessApiGroup.POST("/webhooks/:id/deliveries/:deliveryId/retry", httpSvc.retryWebhookDeliveryHandler)
+	fullAccessApiGroup.POST("/scheduled-payments", httpSvc.createScheduledPaymentHandler)
+	fullAccessApiGroup.DELETE("/scheduled-payments/:id", httpSvc.deleteScheduledPaymentHandler)
+	fullAccessApiGroup.POST("/swap-rules", httpSvc.createSwapRuleHandler)
+	fullAccessApiGroup.PATCH("/swap-rules/:id", httpSvc.updateSwapRuleHandler)
+	fullAccessApiGroup.DELETE("/swap-rules/:id", httpSvc.deleteSwapRuleHandler)
+	fullAccessApiGroup.POST("/fee-policies", httpSvc.createFeePolicyHandler)
+	fullAccessApiGroup.PATCH("/fee-policies/:id", httpSvc.updateFeePolicyHandler)
+	fullAccessApiGroup.DELETE("/fee-policies/:id", httpSvc.deleteFeePolicyHandler)
+	fullAccessApiGroup.POST("/backup-targets", httpSvc.createBackupTargetHandler, requireOwnerRole)
+	fullAccessApiGroup.PATCH("/backup-targets/:id", httpSvc.updateBackupTargetHandler, requireOwnerRole)
+	fullAccessApiGroup.DELETE("/backup-targets/:id", httpSvc.deleteBackupTargetHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/backup-targets/:id/backup", httpSvc.runBackupHandler)
+	fullAccessApiGroup.GET("/nostr-backup", httpSvc.getNostrBackupHandler)
+	fullAccessApiGroup.POST("/nostr-backup", httpSvc.publishNostrBackupHandler)
+	fullAccessApiGroup.POST("/database/maintenance", httpSvc.runDatabaseMaintenanceHandler)
+	fullAccessApiGroup.POST("/database/key-rotation", httpSvc.rotateDatabaseEncryptionKeyHandler, requireOwnerRole)
+	fullAccessApiGroup.DELETE("/recovery", httpSvc.deleteRecoveryHandler)
+	fullAccessApiGroup.POST("/payment-approvals/:id/approve", httpSvc.approvePaymentHandler)
+	fullAccessApiGroup.POST("/payment-approvals/:id/reject", httpSvc.rejectPaymentHandler)
+	fullAccessApiGroup.POST("/app-groups", httpSvc.createAppGroupHandler)
+	fullAccessApiGroup.PATCH("/app-groups/:id", httpSvc.updateAppGroupHandler)
+	fullAccessApiGroup.DELETE("/app-groups/:id", httpSvc.deleteAppGroupHandler)
+	fullAccessApiGroup.POST("/admin-users", httpSvc.createAdminUserHandler, requireOwnerRole)
+	fullAccessApiGroup.PATCH("/admin-users/:id", httpSvc.updateAdminUserHandler, requireOwnerRole)
+	fullAccessApiGroup.DELETE("/admin-users/:id", httpSvc.deleteAdminUserHandler, requireOwnerRole)
+	fullAccessApiGroup.POST("/api-keys", httpSvc.createApiKeyHandler, requireOwnerRole)
+	fullAccessApiGroup.DELETE("/api-keys/:id", httpSvc.revokeApiKeyHandler, requireOwnerRole)
+	fullAccessApiGroup.DELETE("/sessions", httpSvc.revokeAllSessionsHandler, requireOwnerRole)
+	fullAccessApiGroup.DELETE("/sessions/:id", httpSvc.revokeSessionHandler, requireOwnerRole)
+
+	// any session can end itself, e.g. when logging out
+	e.DELETE("/api/session", httpSvc.revokeCurrentSessionHandler, echojwt.WithConfig(jwtConfig), httpSvc.requireActiveSession)
+
+	// Sub-wallet API group - only accessible with a sub-wallet owner token, scoped to that sub-wallet
+	subwalletApiGroup := e.Group("/api/subwallet")
+	subwalletApiGroup.Use(echojwt.WithConfig(jwtConfig))
+	subwalletApiGroup.Use(httpSvc.requireActiveSession)
+	subwalletApiGroup.Use(httpSvc.requireSubwalletAccess)
+
+	subwalletApiGroup.GET("", httpSvc.subwalletShowHandler)
+	subwalletApiGroup.GET("/transactions", httpSvc.subwalletTransactionsListHandler)
+	subwalletApiGroup.POST("/invoices", httpSvc.subwalletMakeInvoiceHandler)
+	subwalletApiGroup.POST("/payments/:invoice", httpSvc.subwalletSendPaymentHandler)
+	subwalletApiGroup.GET("/addresses", httpSvc.subwalletOwnAddressesListHandler)
+	subwalletApiGroup.POST("/addresses", httpSvc.subwalletOwnAddressesCreateHandler)
+	subwalletApiGroup.POST("/transfers", httpSvc.subwalletTransfersHandler)
+
+	// Admin user API group - only accessible with an admin user token, scoped to the apps the admin user manages
+	adminUserApiGroup := e.Group("/api/admin-user")
+	adminUserApiGroup.Use(echojwt.WithConfig(jwtConfig))
+	adminUserApiGroup.Use(httpSvc.requireActiveSession)
+	adminUserApiGroup.Use(httpSvc.requireAdminUserAccess)
+
+	adminUserApiGroup.GET("", httpSvc.adminUserShowHandler)
+	adminUserApiGroup.GET("/apps", httpSvc.adminUserAppsListHandler)
+	adminUserApiGroup.POST("/apps", httpSvc.adminUserAppsCreateHandler)
+	adminUserApiGroup.GET("/apps/:id", httpSvc.adminUserAppsShowHandler)
+	adminUserApiGroup.PATCH("/apps/:id", httpSvc.adminUserAppsUpdateHandler)
+	adminUserApiGroup.DELETE("/apps/:id", httpSvc.adminUserAppsDeleteHandler)
+
+	// Automation API group - authenticated with an api key instead of a session token.
+	// The routes mirror the ones of the admin API and are limited to the scopes of the key.
+	automationApiGroup := e.Group("/api/automation")
+	automationApiGroup.Use(httpSvc.requireApiKey)
+
+	automationApiGroup.POST("/invoices", httpSvc.makeInvoiceHandler, requireApiKeyScope(api.API_KEY_SCOPE_INVOICES))
+	automationApiGroup.POST("/payments/:invoice", httpSvc.sendPaymentHandler, requireApiKeyScope(api.API_KEY_SCOPE_PAYMENTS))
+	automationApiGroup.GET("/balances", httpSvc.balancesHandler, requireApiKeyScope(api.API_KEY_SCOPE_TRANSACTIONS))
+	automationApiGroup.GET("/events/stream", httpSvc.eventStreamHandler, requireApiKeyScope(api.API_KEY_SCOPE_TRANSACTIONS))
+	automationApiGroup.GET("/transactions", httpSvc.listTransactionsHandler, requireApiKeyScope(api.API_KEY_SCOPE_TRANSACTIONS))
+	automationApiGroup.GET("/transactions/:paymentHash", httpSvc.lookupTransactionHandler, requireApiKeyScope(api.API_KEY_SCOPE_TRANSACTIONS))
+	automationApiGroup.GET("/apps", httpSvc.appsListHandler, requireApiKeyScope(api.API_KEY_SCOPE_APPS))
+	automationApiGroup.POST("/apps", httpSvc.appsCreateHandler, requireApiKeyScope(api.API_KEY_SCOPE_APPS))
+	automationApiGroup.GET("/apps/:pubkey", httpSvc.appsShowByPubkeyHandler, requireApiKeyScope(api.API_KEY_SCOPE_APPS))
+	automationApiGroup.PATCH("/apps/:pubkey", httpSvc.appsUpdateHandler, requireApiKeyScope(api.API_KEY_SCOPE_APPS))
+	automationApiGroup.DELETE("/apps/:pubkey", httpSvc.appsDeleteHandler, requireApiKeyScope(api.API_KEY_SCOPE_APPS))
+	automationApiGroup.GET("/v2/apps/:id", httpSvc.appsShowHandler, requireApiKeyScope(api.API_KEY_SCOPE_APPS))
+	automationApiGroup.POST("/v2/apps/bulk", httpSvc.appsBulkUpdateHandler, requireApiKeyScope(api.API_KEY_SCOPE_APPS))
+
+	for _, plugin := range plugins.Plugins() {
+		if routeProvider, ok := plugin.(plugins.RouteProvider); ok {
+			routeProvider.RegisterRoutes(e.Group("/plugins/"+plugin.Name()), fullAccessApiGroup.Group("/plugins/"+plugin.Name()))
+		}
+	}
 
 	httpSvc.albyHttpSvc.RegisterSharedRoutes(readOnlyApiGroup, fullAccessApiGroup, e)
 }
@@ -208,13 +419,23 @@ func (httpSvc *HttpService) infoHandler(c echo.Context) error {
 		parts := strings.Split(authHeader, " ")
 		if parts[0] == "Bearer" {
 			tokenString := parts[1]
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			claims := &jwtCustomClaims{}
+			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 				return []byte(httpSvc.cfg.GetJWTSecret()), nil
 			})
 			if err != nil {
 				logger.Logger.WithError(err).Error("failed to parse token")
 			}
-			responseBody.Unlocked = err == nil && token != nil && token.Valid
+			// sub-wallet owners and admin users have not unlocked the hub itself
+			responseBody.Unlocked = err == nil && token != nil && token.Valid && claims.Permission != "subwallet" && claims.Permission != "admin_user"
+			if responseBody.Unlocked {
+				session, err := httpSvc.api.AuthenticateSession(claims.ID)
+				responseBody.Unlocked = err == nil
+				// a duress session looks like the hub was started, even if the node is not running
+				if err == nil && session.Permission == "full" && session.AppId != nil {
+					responseBody.Running = true
+				}
+			}
 		}
 	}
 
@@ -242,7 +463,13 @@ func (httpSvc *HttpService) mnemonicHandler(c echo.Context) error {
 		})
 	}
 
-	responseBody, err := httpSvc.api.GetMnemonic(mnemonicRequest.UnlockPassword)
+	if err := httpSvc.api.VerifyTotp(mnemonicRequest.UnlockPassword, mnemonicRequest.TotpCode); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	responseBody, err := httpSvc.api.GetMnemonic(mnemonicRequest.UnlockPassword, mnemonicRequest.SeedPassphrase)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -253,353 +480,2981 @@ func (httpSvc *HttpService) mnemonicHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, responseBody)
 }
 
-func (httpSvc *HttpService) backupReminderHandler(c echo.Context) error {
-	var backupReminderRequest api.BackupReminderRequest
-	if err := c.Bind(&backupReminderRequest); err != nil {
+func (httpSvc *HttpService) setSeedPassphraseHandler(c echo.Context) error {
+	var setSeedPassphraseRequest api.SetSeedPassphraseRequest
+	if err := c.Bind(&setSeedPassphraseRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.SetNextBackupReminder(&backupReminderRequest)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to store backup reminder: %s", err.Error()),
+	if err := httpSvc.api.SetSeedPassphrase(&setSeedPassphraseRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to set seed passphrase: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) startHandler(c echo.Context) error {
-	var startRequest api.StartRequest
-	if err := c.Bind(&startRequest); err != nil {
+func (httpSvc *HttpService) setupTotpHandler(c echo.Context) error {
+	var setupTotpRequest api.SetupTotpRequest
+	if err := c.Bind(&setupTotpRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	if !httpSvc.cfg.CheckUnlockPassword(startRequest.UnlockPassword) {
-		return c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Message: "Invalid password",
-		})
-	}
-
-	token, err := httpSvc.createJWT(nil, "full")
-
+	setupTotpResponse, err := httpSvc.api.SetupTotp(setupTotpRequest.UnlockPassword)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to set up two-factor authentication: %s", err.Error()),
 		})
 	}
 
-	go httpSvc.api.Start(&startRequest)
-
-	return c.JSON(http.StatusOK, &authTokenResponse{
-		Token: token,
-	})
+	return c.JSON(http.StatusOK, setupTotpResponse)
 }
 
-func (httpSvc *HttpService) unlockHandler(c echo.Context) error {
-	var unlockRequest api.UnlockRequest
-	if err := c.Bind(&unlockRequest); err != nil {
+func (httpSvc *HttpService) enableTotpHandler(c echo.Context) error {
+	var enableTotpRequest api.EnableTotpRequest
+	if err := c.Bind(&enableTotpRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	if !httpSvc.cfg.CheckUnlockPassword(unlockRequest.UnlockPassword) {
-		return c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Message: "Invalid password",
+	enableTotpResponse, err := httpSvc.api.EnableTotp(&enableTotpRequest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to enable two-factor authentication: %s", err.Error()),
 		})
 	}
 
-	if unlockRequest.Permission == "" {
+	return c.JSON(http.StatusOK, enableTotpResponse)
+}
+
+func (httpSvc *HttpService) disableTotpHandler(c echo.Context) error {
+	var disableTotpRequest api.DisableTotpRequest
+	if err := c.Bind(&disableTotpRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Permission field is required",
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	if !slices.Contains([]string{"full", "readonly"}, unlockRequest.Permission) {
+	if err := httpSvc.api.DisableTotp(&disableTotpRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Permission field is unknown",
+			Message: fmt.Sprintf("Failed to disable two-factor authentication: %s", err.Error()),
 		})
 	}
 
-	token, err := httpSvc.createJWT(unlockRequest.TokenExpiryDays, unlockRequest.Permission)
+	return c.NoContent(http.StatusNoContent)
+}
 
+func (httpSvc *HttpService) duressSettingsHandler(c echo.Context) error {
+	duressSettings, err := httpSvc.api.GetDuressSettings()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to get duress settings: %s", err.Error()),
 		})
 	}
 
-	httpSvc.eventPublisher.Publish(&events.Event{
-		Event: "nwc_unlocked",
-	})
-
-	return c.JSON(http.StatusOK, &authTokenResponse{
-		Token: token,
-	})
+	return c.JSON(http.StatusOK, duressSettings)
 }
 
-func (httpSvc *HttpService) requireFullAccess(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		token := c.Get("user").(*jwt.Token)
-		claims := token.Claims.(*jwtCustomClaims)
-
-		// Allow if no permission specified (backward compatibility) or if full access
-		if claims.Permission == "" || claims.Permission == "full" {
-			return next(c)
-		}
+func (httpSvc *HttpService) setDuressPasswordHandler(c echo.Context) error {
+	var setDuressPasswordRequest api.SetDuressPasswordRequest
+	if err := c.Bind(&setDuressPasswordRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
-		return c.JSON(http.StatusForbidden, ErrorResponse{
-			Message: "This operation requires full access permissions",
+	duressSettings, err := httpSvc.api.SetDuressPassword(&setDuressPasswordRequest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to set duress password: %s", err.Error()),
 		})
 	}
+
+	return c.JSON(http.StatusOK, duressSettings)
 }
 
-func (httpSvc *HttpService) changeUnlockPasswordHandler(c echo.Context) error {
-	var changeUnlockPasswordRequest api.ChangeUnlockPasswordRequest
-	if err := c.Bind(&changeUnlockPasswordRequest); err != nil {
+func (httpSvc *HttpService) disableDuressPasswordHandler(c echo.Context) error {
+	var disableDuressPasswordRequest api.DisableDuressPasswordRequest
+	if err := c.Bind(&disableDuressPasswordRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.ChangeUnlockPassword(&changeUnlockPasswordRequest)
+	if err := httpSvc.api.DisableDuressPassword(disableDuressPasswordRequest.UnlockPassword); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to disable duress password: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) emailNotificationSettingsHandler(c echo.Context) error {
+	settings, err := httpSvc.api.GetEmailNotificationSettings()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to change unlock password: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to get email notification settings: %s", err.Error()),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, settings)
 }
 
-func (httpSvc *HttpService) updateSettingsHandler(c echo.Context) error {
-	var updateSettingsRequest api.UpdateSettingsRequest
-	if err := c.Bind(&updateSettingsRequest); err != nil {
+func (httpSvc *HttpService) updateEmailNotificationSettingsHandler(c echo.Context) error {
+	var updateEmailNotificationsRequest api.UpdateEmailNotificationsRequest
+	if err := c.Bind(&updateEmailNotificationsRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.UpdateSettings(&updateSettingsRequest)
+	settings, err := httpSvc.api.UpdateEmailNotificationSettings(&updateEmailNotificationsRequest)
 	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update email notification settings: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+func (httpSvc *HttpService) sendTestEmailHandler(c echo.Context) error {
+	if err := httpSvc.api.SendTestEmail(); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to update settings: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to send test email: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) autoUnlockHandler(c echo.Context) error {
-	var autoUnlockRequest api.AutoUnlockRequest
-	if err := c.Bind(&autoUnlockRequest); err != nil {
+func (httpSvc *HttpService) backupReminderHandler(c echo.Context) error {
+	var backupReminderRequest api.BackupReminderRequest
+	if err := c.Bind(&backupReminderRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.SetAutoUnlockPassword(autoUnlockRequest.UnlockPassword)
+	err := httpSvc.api.SetNextBackupReminder(&backupReminderRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to set auto unlock password: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to store backup reminder: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) createJWT(tokenExpiryDays *uint64, permission string) (string, error) {
-	if !slices.Contains([]string{"full", "readonly"}, permission) {
-		return "", errors.New("invalid token permission")
+func (httpSvc *HttpService) startHandler(c echo.Context) error {
+	var startRequest api.StartRequest
+	if err := c.Bind(&startRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
 	}
 
-	expiryDays := uint64(30)
-	if tokenExpiryDays != nil {
-		expiryDays = *tokenExpiryDays
+	if !httpSvc.cfg.CheckUnlockPassword(startRequest.UnlockPassword) {
+		// the node cannot be started without the unlock password, so it looks like it failed to start
+		if appId, ok := httpSvc.api.CheckDuressPassword(startRequest.UnlockPassword); ok {
+			return httpSvc.decoyLogin(c, nil, startRequest.TotpCode, appId)
+		}
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: "Invalid password",
+		})
 	}
 
-	// Set custom claims
-	claims := &jwtCustomClaims{
-		Permission: permission,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * time.Duration(expiryDays))),
-		},
+	if err := httpSvc.api.VerifyTotp(startRequest.UnlockPassword, startRequest.TotpCode); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
+		})
 	}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	if token == nil {
-		return "", errors.New("failed to create token")
-	}
+	token, err := httpSvc.createJWT(c, nil, "full")
 
-	signed, err := token.SignedString([]byte(httpSvc.cfg.GetJWTSecret()))
 	if err != nil {
-		return "", err
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
+		})
 	}
-	return signed, nil
+
+	go httpSvc.api.Start(&startRequest)
+
+	return c.JSON(http.StatusOK, &authTokenResponse{
+		Token: token,
+	})
 }
 
-func (httpSvc *HttpService) channelsListHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) unlockHandler(c echo.Context) error {
+	var unlockRequest api.UnlockRequest
+	if err := c.Bind(&unlockRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
-	channels, err := httpSvc.api.ListChannels(ctx)
+	if !httpSvc.cfg.CheckUnlockPassword(unlockRequest.UnlockPassword) {
+		if appId, ok := httpSvc.api.CheckDuressPassword(unlockRequest.UnlockPassword); ok {
+			return httpSvc.decoyLogin(c, unlockRequest.TokenExpiryDays, unlockRequest.TotpCode, appId)
+		}
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: "Invalid password",
+		})
+	}
 
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+	if err := httpSvc.api.VerifyTotp(unlockRequest.UnlockPassword, unlockRequest.TotpCode); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Message: err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, channels)
-}
+	if unlockRequest.Permission == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Permission field is required",
+		})
+	}
 
-func (httpSvc *HttpService) channelPeerSuggestionsHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+	if !slices.Contains([]string{"full", "readonly"}, unlockRequest.Permission) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Permission field is unknown",
+		})
+	}
 
-	suggestions, err := httpSvc.api.GetChannelPeerSuggestions(ctx)
+	token, err := httpSvc.createJWT(c, unlockRequest.TokenExpiryDays, unlockRequest.Permission)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, suggestions)
-}
+	// unlocking with full access also resumes spending after the hub was locked for inactivity
+	if unlockRequest.Permission == "full" {
+		if err := httpSvc.api.UnlockSpending(unlockRequest.UnlockPassword); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Message: fmt.Sprintf("Failed to unlock spending: %s", err.Error()),
+			})
+		}
+	}
 
-func (httpSvc *HttpService) channelOfferHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+	httpSvc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_unlocked",
+	})
 
-	suggestions, err := httpSvc.api.GetLSPChannelOffer(ctx)
+	return c.JSON(http.StatusOK, &authTokenResponse{
+		Token: token,
+	})
+}
 
+func (httpSvc *HttpService) beginPasskeyLoginHandler(c echo.Context) error {
+	passkeyLoginOptions, err := httpSvc.api.BeginPasskeyLogin()
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to begin passkey login: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, suggestions)
+	return c.JSON(http.StatusOK, passkeyLoginOptions)
 }
 
-func (httpSvc *HttpService) resetRouterHandler(c echo.Context) error {
-	var resetRouterRequest api.ResetRouterRequest
-	if err := c.Bind(&resetRouterRequest); err != nil {
+// finishPasskeyLoginHandler issues a session like the unlock password does
+func (httpSvc *HttpService) finishPasskeyLoginHandler(c echo.Context) error {
+	var finishPasskeyLoginRequest api.FinishPasskeyLoginRequest
+	if err := c.Bind(&finishPasskeyLoginRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.ResetRouter(resetRouterRequest.Key)
-
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+	if !slices.Contains([]string{"full", "readonly"}, finishPasskeyLoginRequest.Permission) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Permission field is unknown",
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
-}
-
-func (httpSvc *HttpService) stopHandler(c echo.Context) error {
-
-	err := httpSvc.api.Stop()
-
+	passkey, err := httpSvc.api.FinishPasskeyLogin(&finishPasskeyLoginRequest.Credential)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Message: err.Error(),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
-}
-
-func (httpSvc *HttpService) nodeConnectionInfoHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	info, err := httpSvc.api.GetNodeConnectionInfo(ctx)
-
+	token, err := httpSvc.createJWT(c, finishPasskeyLoginRequest.TokenExpiryDays, finishPasskeyLoginRequest.Permission)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, info)
+	logger.Logger.WithField("passkey_id", passkey.ID).Info("Logged in with passkey")
+	httpSvc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_unlocked",
+	})
+
+	return c.JSON(http.StatusOK, &authTokenResponse{
+		Token: token,
+	})
 }
 
-func (httpSvc *HttpService) nodeStatusHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) lockHandler(c echo.Context) error {
+	httpSvc.api.LockSpending()
+	return c.NoContent(http.StatusNoContent)
+}
 
-	info, err := httpSvc.api.GetNodeStatus(ctx)
+func (httpSvc *HttpService) requireFullAccess(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// a watch-only hub can be monitored from less trusted devices, so nothing can be changed
+		if transactions.IsWatchOnly() {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: "This operation is not available in watch-only mode",
+			})
+		}
+
+		token := c.Get("user").(*jwt.Token)
+		claims := token.Claims.(*jwtCustomClaims)
+
+		// Allow if no permission specified (backward compatibility) or if full access
+		if claims.Permission == "" || claims.Permission == "full" {
+			return next(c)
+		}
+
+		return c.JSON(http.StatusForbidden, ErrorResponse{
+			Message: "This operation requires full access permissions",
+		})
+	}
+}
+
+func (httpSvc *HttpService) rejectScopedAccess(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := c.Get("user").(*jwt.Token)
+		claims := token.Claims.(*jwtCustomClaims)
+
+		if claims.Permission == "subwallet" {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: "This operation is not available to sub-wallet owners",
+			})
+		}
+		if claims.Permission == "admin_user" {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: "This operation is not available to admin users",
+			})
+		}
+
+		return next(c)
+	}
+}
+
+func (httpSvc *HttpService) requireSubwalletAccess(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := c.Get("user").(*jwt.Token)
+		claims := token.Claims.(*jwtCustomClaims)
+
+		if claims.Permission != "subwallet" || claims.AppId == 0 {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: "This operation requires a sub-wallet owner token",
+			})
+		}
+
+		c.Set("subwalletAppId", claims.AppId)
+		return next(c)
+	}
+}
+
+// requireApiKey authenticates requests to the automation API with an api key sent as bearer token
+func (httpSvc *HttpService) requireApiKey(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key, found := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		if !found {
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Message: "This operation requires an api key",
+			})
+		}
+
+		apiKey, err := httpSvc.api.AuthenticateApiKey(key)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+
+		c.Set("apiKey", apiKey)
+		return next(c)
+	}
+}
+
+func requireApiKeyScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			apiKey, ok := c.Get("apiKey").(*api.ApiKey)
+			if !ok || !slices.Contains(apiKey.Scopes, scope) {
+				return c.JSON(http.StatusForbidden, ErrorResponse{
+					Message: fmt.Sprintf("This operation requires an api key with the %s scope", scope),
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+func (httpSvc *HttpService) requireAdminUserAccess(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := c.Get("user").(*jwt.Token)
+		claims := token.Claims.(*jwtCustomClaims)
+
+		if claims.Permission != "admin_user" || claims.AdminUserId == 0 {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: "This operation requires an admin user token",
+			})
+		}
+		// tokens of deleted admin users stop working immediately, as do role changes
+		adminUser, err := httpSvc.api.GetAdminUser(claims.AdminUserId)
+		if err != nil || adminUser.Role != api.ADMIN_USER_ROLE_APP_MANAGER {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: "This operation requires an admin user token",
+			})
+		}
+
+		c.Set("adminUserId", claims.AdminUserId)
+		return next(c)
+	}
+}
+
+// requireActiveSession rejects tokens whose session was revoked. Tokens issued before
+// sessions were tracked have no id and need to log in again.
+func (httpSvc *HttpService) requireActiveSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := c.Get("user").(*jwt.Token)
+		claims := token.Claims.(*jwtCustomClaims)
+
+		if claims.ID == "" {
+			c.Set(sessionRejectedKey, true)
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Message: "Session expired, please log in again",
+			})
+		}
+		session, err := httpSvc.api.AuthenticateSession(claims.ID)
+		if err != nil {
+			c.Set(sessionRejectedKey, true)
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+
+		c.Set("sessionId", session.ID)
+		// full access sessions linked to an app were opened with the duress password
+		if session.Permission == "full" && session.AppId != nil {
+			c.Set(decoyAppIdKey, *session.AppId)
+		}
+		// the frontend polls in the background, only requests which change something postpone the lock
+		if c.Request().Method != http.MethodGet {
+			autolock.RecordActivity()
+		}
+		return next(c)
+	}
+}
+
+// resolveAdminUserRole applies the current role of the admin user a session belongs to,
+// so that role changes and deleted admin users take effect immediately
+func (httpSvc *HttpService) resolveAdminUserRole(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := c.Get("user").(*jwt.Token)
+		claims := token.Claims.(*jwtCustomClaims)
+
+		// sessions of the unlock password belong to the owner of the hub,
+		// read-only sessions only get the rights of a viewer
+		if claims.AdminUserId == 0 {
+			if claims.Permission == "" || claims.Permission == "full" {
+				c.Set("adminUserRole", api.ADMIN_USER_ROLE_OWNER)
+			} else {
+				c.Set("adminUserRole", api.ADMIN_USER_ROLE_VIEWER)
+			}
+			return next(c)
+		}
+
+		adminUser, err := httpSvc.api.GetAdminUser(claims.AdminUserId)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Message: "This admin user no longer exists",
+			})
+		}
+		claims.Permission = adminUserRolePermission(adminUser.Role)
+		c.Set("adminUserRole", adminUser.Role)
+		return next(c)
+	}
+}
+
+func requireOwnerRole(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Get("adminUserRole") != api.ADMIN_USER_ROLE_OWNER {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: "This operation requires the owner role",
+			})
+		}
+		return next(c)
+	}
+}
+
+// adminUserRolePermission returns the session permission of an admin user role
+func adminUserRolePermission(role string) string {
+	switch role {
+	case api.ADMIN_USER_ROLE_OWNER, api.ADMIN_USER_ROLE_OPERATOR:
+		return "full"
+	case api.ADMIN_USER_ROLE_VIEWER:
+		return "readonly"
+	default:
+		return "admin_user"
+	}
+}
+
+func (httpSvc *HttpService) changeUnlockPasswordHandler(c echo.Context) error {
+	var changeUnlockPasswordRequest api.ChangeUnlockPasswordRequest
+	if err := c.Bind(&changeUnlockPasswordRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.ChangeUnlockPassword(&changeUnlockPasswordRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to change unlock password: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) updateSettingsHandler(c echo.Context) error {
+	var updateSettingsRequest api.UpdateSettingsRequest
+	if err := c.Bind(&updateSettingsRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	if updateSettingsRequest.AdminAllowedNetworks != nil {
+		// do not let the owner lock themselves out
+		allowedNetworks, err := utils.ParseNetworks(*updateSettingsRequest.AdminAllowedNetworks)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+		if len(allowedNetworks) > 0 && !utils.NetworksContain(allowedNetworks, c.RealIP()) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: fmt.Sprintf("The allowed networks do not include your IP address %s", c.RealIP()),
+			})
+		}
+	}
+
+	if updateSettingsRequest.BannedIps != nil {
+		bannedNetworks, err := utils.ParseNetworks(*updateSettingsRequest.BannedIps)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+		if utils.NetworksContain(bannedNetworks, c.RealIP()) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: fmt.Sprintf("The banned IPs include your IP address %s", c.RealIP()),
+			})
+		}
+	}
+
+	err := httpSvc.api.UpdateSettings(&updateSettingsRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update settings: %s", err.Error()),
+		})
+	}
+	if updateSettingsRequest.AdminAllowedNetworks != nil {
+		httpSvc.networkPolicy.reload()
+	}
+	if updateSettingsRequest.RateLimitIpPerMinute != nil || updateSettingsRequest.RateLimitApiKeyPerMinute != nil ||
+		updateSettingsRequest.RateLimitSessionPerMinute != nil || updateSettingsRequest.BannedIps != nil {
+		httpSvc.abuseProtection.reload()
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) autoUnlockHandler(c echo.Context) error {
+	var autoUnlockRequest api.AutoUnlockRequest
+	if err := c.Bind(&autoUnlockRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.SetAutoUnlockPassword(autoUnlockRequest.UnlockPassword)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to set auto unlock password: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) createJWT(c echo.Context, tokenExpiryDays *uint64, permission string) (string, error) {
+	if !slices.Contains([]string{"full", "readonly"}, permission) {
+		return "", errors.New("invalid token permission")
+	}
+
+	expiryDays := uint64(30)
+	if tokenExpiryDays != nil {
+		expiryDays = *tokenExpiryDays
+	}
+
+	// Set custom claims
+	claims := &jwtCustomClaims{
+		Permission: permission,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * time.Duration(expiryDays))),
+		},
+	}
+
+	return httpSvc.signJWT(c, claims)
+}
+
+func (httpSvc *HttpService) createSubwalletJWT(c echo.Context, tokenExpiryDays *uint64, appId uint) (string, error) {
+	expiryDays := uint64(30)
+	if tokenExpiryDays != nil {
+		expiryDays = *tokenExpiryDays
+	}
+
+	claims := &jwtCustomClaims{
+		Permission: "subwallet",
+		AppId:      appId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * time.Duration(expiryDays))),
+		},
+	}
+
+	return httpSvc.signJWT(c, claims)
+}
+
+func (httpSvc *HttpService) createAdminUserJWT(c echo.Context, tokenExpiryDays *uint64, adminUser *api.AdminUser) (string, error) {
+	expiryDays := uint64(30)
+	if tokenExpiryDays != nil {
+		expiryDays = *tokenExpiryDays
+	}
+
+	claims := &jwtCustomClaims{
+		Permission:  adminUserRolePermission(adminUser.Role),
+		AdminUserId: adminUser.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * time.Duration(expiryDays))),
+		},
+	}
+
+	return httpSvc.signJWT(c, claims)
+}
+
+// signJWT signs the token and records it as session, so that it can be listed and revoked
+func (httpSvc *HttpService) signJWT(c echo.Context, claims *jwtCustomClaims) (string, error) {
+	return httpSvc.signSessionJWT(c, claims, nil)
+}
+
+// signSessionJWT is like signJWT, the decoy wallet of a duress session is only stored with the session
+// so that its token cannot be told apart from the token of the owner
+func (httpSvc *HttpService) signSessionJWT(c echo.Context, claims *jwtCustomClaims, decoyAppId *uint) (string, error) {
+	tokenIdBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenIdBytes); err != nil {
+		return "", err
+	}
+	claims.ID = hex.EncodeToString(tokenIdBytes)
+
+	// Create token with claims
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	if token == nil {
+		return "", errors.New("failed to create token")
+	}
+
+	signed, err := token.SignedString([]byte(httpSvc.cfg.GetJWTSecret()))
+	if err != nil {
+		return "", err
+	}
+
+	createSessionRequest := &api.CreateSessionRequest{
+		TokenId:    claims.ID,
+		Permission: claims.Permission,
+		IpAddress:  c.RealIP(),
+		UserAgent:  c.Request().UserAgent(),
+		ExpiresAt:  claims.ExpiresAt.Time,
+	}
+	if claims.AdminUserId != 0 {
+		createSessionRequest.AdminUserId = &claims.AdminUserId
+	}
+	if claims.AppId != 0 {
+		createSessionRequest.AppId = &claims.AppId
+	}
+	if decoyAppId != nil {
+		createSessionRequest.AppId = decoyAppId
+	}
+	if err := httpSvc.api.CreateSession(createSessionRequest); err != nil {
+		return "", err
+	}
+	return signed, nil
+}
+
+func (httpSvc *HttpService) channelsListHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	channels, err := httpSvc.api.ListChannels(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, channels)
+}
+
+func (httpSvc *HttpService) channelPeerSuggestionsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	suggestions, err := httpSvc.api.GetChannelPeerSuggestions(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, suggestions)
+}
+
+func (httpSvc *HttpService) channelOfferHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	suggestions, err := httpSvc.api.GetLSPChannelOffer(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, suggestions)
+}
+
+func (httpSvc *HttpService) resetRouterHandler(c echo.Context) error {
+	var resetRouterRequest api.ResetRouterRequest
+	if err := c.Bind(&resetRouterRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.ResetRouter(resetRouterRequest.Key)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) stopHandler(c echo.Context) error {
+
+	err := httpSvc.api.Stop()
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) nodeConnectionInfoHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	info, err := httpSvc.api.GetNodeConnectionInfo(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+func (httpSvc *HttpService) nodeStatusHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	info, err := httpSvc.api.GetNodeStatus(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+func (httpSvc *HttpService) nodeNetworkGraphHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	nodeIds := strings.Split(c.QueryParam("nodeIds"), ",")
+
+	info, err := httpSvc.api.GetNetworkGraph(ctx, nodeIds)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+func (httpSvc *HttpService) migrateNodeStorageHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	var migrateNodeStorageRequest api.MigrateNodeStorageRequest
+	if err := c.Bind(&migrateNodeStorageRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	if err := httpSvc.api.VerifyTotp(migrateNodeStorageRequest.UnlockPassword, migrateNodeStorageRequest.TotpCode); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	err := httpSvc.api.MigrateNodeStorage(ctx, migrateNodeStorageRequest.To)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) balancesHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	balances, err := httpSvc.api.GetBalances(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, balances)
+}
+
+const eventStreamKeepAliveInterval = 30 * time.Second
+
+// eventStreamHandler pushes hub events to the client as server-sent events.
+// The events can be filtered with a comma-separated "events" query parameter.
+func (httpSvc *HttpService) eventStreamHandler(c echo.Context) error {
+	var filter []string
+	if eventsParam := c.QueryParam("events"); eventsParam != "" {
+		filter = strings.Split(eventsParam, ",")
+	}
+
+	subscriber := events.NewStreamSubscriber(100, filter)
+	httpSvc.eventPublisher.RegisterSubscriber(subscriber)
+	defer httpSvc.eventPublisher.RemoveSubscriber(subscriber)
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	// disable response buffering of nginx
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(response, ": connected\n\n"); err != nil {
+		return nil
+	}
+	response.Flush()
+
+	keepAliveTicker := time.NewTicker(eventStreamKeepAliveInterval)
+	defer keepAliveTicker.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-httpSvc.streamsCtx.Done():
+			return nil
+		case <-keepAliveTicker.C:
+			if _, err := fmt.Fprint(response, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case event := <-subscriber.Events():
+			data, err := json.Marshal(api.ToStreamEvent(event))
+			if err != nil {
+				logger.Logger.WithError(err).WithField("event", event.Event).Error("Failed to encode event for stream")
+				continue
+			}
+			if _, err := fmt.Fprintf(response, "event: %s\ndata: %s\n\n", event.Event, data); err != nil {
+				return nil
+			}
+		}
+		response.Flush()
+	}
+}
+
+const componentHealthTimeout = 5 * time.Second
+
+// livenessHandler only fails if the database is not reachable, as restarting the hub
+// does not help if e.g. the lightning backend is down
+func (httpSvc *HttpService) livenessHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), componentHealthTimeout)
+	defer cancel()
+
+	componentHealth := httpSvc.api.GetComponentHealth(ctx)
+	if componentHealth.Components["database"].Status == api.ComponentStatusDown {
+		return c.JSON(http.StatusServiceUnavailable, componentHealth)
+	}
+	return c.JSON(http.StatusOK, componentHealth)
+}
+
+// readinessHandler fails if any component is down, e.g. because the hub is locked
+func (httpSvc *HttpService) readinessHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), componentHealthTimeout)
+	defer cancel()
+
+	componentHealth := httpSvc.api.GetComponentHealth(ctx)
+	if componentHealth.Status == api.ComponentStatusDown {
+		return c.JSON(http.StatusServiceUnavailable, componentHealth)
+	}
+	return c.JSON(http.StatusOK, componentHealth)
+}
+
+func (httpSvc *HttpService) sendPaymentHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var payInvoiceRequest api.PayInvoiceRequest
+	if err := c.Bind(&payInvoiceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	// the standard header takes precedence over the request body
+	idempotencyKey := payInvoiceRequest.IdempotencyKey
+	if idempotencyKeyHeader := c.Request().Header.Get("Idempotency-Key"); idempotencyKeyHeader != "" {
+		idempotencyKey = idempotencyKeyHeader
+	}
+
+	paymentResponse, err := httpSvc.api.SendPayment(ctx, c.Param("invoice"), payInvoiceRequest.Amount, payInvoiceRequest.Metadata, idempotencyKey)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, paymentResponse)
+}
+
+func (httpSvc *HttpService) makeOfferHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var makeOfferRequest api.MakeOfferRequest
+	if err := c.Bind(&makeOfferRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	offer, err := httpSvc.api.MakeOffer(ctx, makeOfferRequest.Description)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to generate BOLT-12 offer: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, offer)
+}
+
+func (httpSvc *HttpService) makeInvoiceHandler(c echo.Context) error {
+	var makeInvoiceRequest api.MakeInvoiceRequest
+	if err := c.Bind(&makeInvoiceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	invoice, err := httpSvc.api.CreateInvoice(c.Request().Context(), makeInvoiceRequest.Amount, makeInvoiceRequest.Description)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, invoice)
+}
+
+func (httpSvc *HttpService) lookupTransactionHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	transaction, err := httpSvc.api.LookupInvoice(ctx, c.Param("paymentHash"))
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, transaction)
+}
+
+func (httpSvc *HttpService) refundTransactionHandler(c echo.Context) error {
+	var refundRequest api.RefundTransactionRequest
+	if err := c.Bind(&refundRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	transaction, err := httpSvc.api.RefundTransaction(c.Request().Context(), c.Param("paymentHash"), &refundRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to refund payment: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, transaction)
+}
+
+func (httpSvc *HttpService) listTransactionsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit := uint64(20)
+	offset := uint64(0)
+	var appId *uint
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	if appIdParam := c.QueryParam("appId"); appIdParam != "" {
+		if parsedAppId, err := strconv.ParseUint(appIdParam, 10, 64); err == nil {
+			var unsignedAppId = uint(parsedAppId)
+			appId = &unsignedAppId
+		}
+	}
+
+	var currencies []string
+	if currencyParam := c.QueryParam("currency"); currencyParam != "" {
+		currencies = strings.Split(currencyParam, ",")
+	}
+
+	transactions, err := httpSvc.api.ListTransactions(ctx, appId, limit, offset, currencies)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, transactions)
+}
+
+func (httpSvc *HttpService) exportTransactionsHandler(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = transactions.EXPORT_FORMAT_CSV
+	}
+
+	if !slices.Contains(transactions.GetExportFormats(), format) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid export format. Must be one of %s", strings.Join(transactions.GetExportFormats(), ",")),
+		})
+	}
+
+	var buffer bytes.Buffer
+	err := httpSvc.api.ExportTransactions(c.Request().Context(), format, &buffer)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to export transactions: %s", err.Error()),
+		})
+	}
+
+	contentType := "text/csv"
+	extension := "csv"
+	if format == transactions.EXPORT_FORMAT_LEDGER {
+		contentType = "text/plain"
+		extension = "ledger"
+	}
+
+	c.Response().Header().Set("Content-Type", contentType)
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=albyhub-transactions-%s.%s", format, extension))
+	c.Response().WriteHeader(http.StatusOK)
+	c.Response().Write(buffer.Bytes())
+	return nil
+}
+
+func (httpSvc *HttpService) listOnchainTransactionsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	transactions, err := httpSvc.api.ListOnchainTransactions(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, transactions)
+}
+
+func (httpSvc *HttpService) walletSyncHandler(c echo.Context) error {
+	httpSvc.api.SyncWallet()
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) mempoolApiHandler(c echo.Context) error {
+	endpoint := c.QueryParam("endpoint")
+	if endpoint == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid pubkey parameter",
+		})
+	}
+
+	response, err := httpSvc.api.RequestMempoolApi(c.Request().Context(), endpoint)
+	if err != nil {
+		logger.Logger.WithField("endpoint", endpoint).WithError(err).Error("Failed to request mempool API")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to request mempool API: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (httpSvc *HttpService) capabilitiesHandler(c echo.Context) error {
+	response, err := httpSvc.api.GetWalletCapabilities(c.Request().Context())
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to request wallet capabilities")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to request wallet capabilities: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (httpSvc *HttpService) listPeers(c echo.Context) error {
+	peers, err := httpSvc.api.ListPeers(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list peers: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, peers)
+}
+
+func (httpSvc *HttpService) connectPeerHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var connectPeerRequest api.ConnectPeerRequest
+	if err := c.Bind(&connectPeerRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.ConnectPeer(ctx, &connectPeerRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to connect peer: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) openChannelHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var openChannelRequest api.OpenChannelRequest
+	if err := c.Bind(&openChannelRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	openChannelResponse, err := httpSvc.api.OpenChannel(ctx, &openChannelRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to open channel: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, openChannelResponse)
+}
+
+func (httpSvc *HttpService) rebalanceChannelHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var rebalanceChannelRequest api.RebalanceChannelRequest
+	if err := c.Bind(&rebalanceChannelRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	rebalanceChannelResponse, err := httpSvc.api.RebalanceChannel(ctx, &rebalanceChannelRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to rebalance channel: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, rebalanceChannelResponse)
+}
+
+func (httpSvc *HttpService) rebalanceCircularHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var circularRebalanceRequest api.CircularRebalanceRequest
+	if err := c.Bind(&circularRebalanceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	circularRebalanceResponse, err := httpSvc.api.RebalanceCircular(ctx, &circularRebalanceRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to rebalance channels: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, circularRebalanceResponse)
+}
+
+func (httpSvc *HttpService) disconnectPeerHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	err := httpSvc.api.DisconnectPeer(ctx, c.Param("peerId"))
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to disconnect peer: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) closeChannelHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	closeChannelResponse, err := httpSvc.api.CloseChannel(ctx, c.Param("peerId"), c.Param("channelId"), c.QueryParam("force") == "true")
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to close channel: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, closeChannelResponse)
+}
+
+func (httpSvc *HttpService) updateChannelHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var updateChannelRequest api.UpdateChannelRequest
+	if err := c.Bind(&updateChannelRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	updateChannelRequest.NodeId = c.Param("peerId")
+	updateChannelRequest.ChannelId = c.Param("channelId")
+
+	err := httpSvc.api.UpdateChannel(ctx, &updateChannelRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update channel: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) newInstantChannelInvoiceHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var newWrappedInvoiceRequest api.LSPOrderRequest
+	if err := c.Bind(&newWrappedInvoiceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	newLSPOrderResponse, err := httpSvc.api.RequestLSPOrder(ctx, &newWrappedInvoiceRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to request new channel order from LSP: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, newLSPOrderResponse)
+}
+
+func (httpSvc *HttpService) listLSPOrdersHandler(c echo.Context) error {
+	orders, err := httpSvc.api.ListLSPOrders()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list LSP orders: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, orders)
+}
+
+func (httpSvc *HttpService) onchainAddressHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	address, err := httpSvc.api.GetUnusedOnchainAddress(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to request new onchain address: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, address)
+}
+
+func (httpSvc *HttpService) newOnchainAddressHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	address, err := httpSvc.api.GetNewOnchainAddress(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to request new onchain address: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, address)
+}
+
+func (httpSvc *HttpService) redeemOnchainFundsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var redeemOnchainFundsRequest api.RedeemOnchainFundsRequest
+	if err := c.Bind(&redeemOnchainFundsRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	if redeemOnchainFundsRequest.SendAll || redeemOnchainFundsRequest.Amount >= uint64(httpSvc.cfg.GetEnv().TotpOnchainThresholdSat) {
+		if err := httpSvc.api.VerifyTotp(redeemOnchainFundsRequest.UnlockPassword, redeemOnchainFundsRequest.TotpCode); err != nil {
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+	}
+
+	redeemOnchainFundsResponse, err := httpSvc.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, redeemOnchainFundsRequest.Amount, redeemOnchainFundsRequest.FeeRate, redeemOnchainFundsRequest.SendAll)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to redeem onchain funds: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, redeemOnchainFundsResponse)
+}
+
+func (httpSvc *HttpService) signMessageHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var signMessageRequest api.SignMessageRequest
+	if err := c.Bind(&signMessageRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	signMessageResponse, err := httpSvc.api.SignMessage(ctx, signMessageRequest.Message)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to sign message: %s", err.Error()),
+		})
+	}
+	return c.JSON(http.StatusOK, signMessageResponse)
+}
+
+func (httpSvc *HttpService) appsListHandler(c echo.Context) error {
+	limit := uint64(0)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	filtersJSON := c.QueryParam("filters")
+	var filters api.ListAppsFilters
+	if filtersJSON != "" {
+		err := json.Unmarshal([]byte(filtersJSON), &filters)
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"filters": filtersJSON,
+			}).Error("Failed to deserialize app filters")
+			return err
+		}
+	}
+
+	orderBy := c.QueryParam("order_by")
+
+	apps, err := httpSvc.api.ListApps(limit, offset, filters, orderBy)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, apps)
+}
+
+func (httpSvc *HttpService) appsShowByPubkeyHandler(c echo.Context) error {
+	dbApp := httpSvc.appsSvc.GetAppByPubkey(c.Param("pubkey"))
+
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
+
+	response := httpSvc.api.GetApp(dbApp)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (httpSvc *HttpService) appsShowHandler(c echo.Context) error {
+	appIdStr := c.Param("id")
+	if appIdStr == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "App ID is required",
+		})
+	}
+
+	appId, err := strconv.ParseUint(appIdStr, 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
+
+	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
+
+	response := httpSvc.api.GetApp(dbApp)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (httpSvc *HttpService) appsUpdateHandler(c echo.Context) error {
+	var requestData api.UpdateAppRequest
+	if err := c.Bind(&requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	dbApp := httpSvc.appsSvc.GetAppByPubkey(c.Param("pubkey"))
+
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
+
+	err := httpSvc.api.UpdateApp(dbApp, &requestData)
+
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to update app")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update app: %v", err),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) transfersHandler(c echo.Context) error {
+	var requestData api.TransferRequest
+	if err := c.Bind(&requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	transferResponse, err := httpSvc.api.Transfer(c.Request().Context(), requestData.FromAppId, requestData.ToAppId, requestData.AmountSat*1000)
+
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to transfer funds")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to transfer funds: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, transferResponse)
+}
+
+func (httpSvc *HttpService) appsDeleteHandler(c echo.Context) error {
+	dbApp := httpSvc.appsSvc.GetAppByPubkey(c.Param("pubkey"))
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
+
+	if err := httpSvc.api.DeleteApp(dbApp); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "Failed to delete app",
+		})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) appsCreateHandler(c echo.Context) error {
+	var requestData api.CreateAppRequest
+	if err := c.Bind(&requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	responseBody, err := httpSvc.api.CreateApp(&requestData)
+
+	if err != nil {
+		logger.Logger.WithField("requestData", requestData).WithError(err).Error("Failed to save app")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to save app: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, responseBody)
+}
+
+func (httpSvc *HttpService) lightningAddressesCreateHandler(c echo.Context) error {
+	var requestData api.CreateLightningAddressRequest
+	if err := c.Bind(&requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.CreateLightningAddress(c.Request().Context(), &requestData)
+
+	if err != nil {
+		logger.Logger.WithField("request", requestData).WithError(err).Error("Failed to create lightning address")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) lightningAddressesDeleteHandler(c echo.Context) error {
+	appIdStr := c.Param("appId")
+	if appIdStr == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "App ID is required",
+		})
+	}
+
+	appId, err := strconv.ParseUint(appIdStr, 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
+
+	err = httpSvc.api.DeleteLightningAddress(c.Request().Context(), uint(appId))
+	if err != nil {
+		logger.Logger.WithField("appId", appId).WithError(err).Error("Failed to delete lightning address")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) setupHandler(c echo.Context) error {
+	var setupRequest api.SetupRequest
+	if err := c.Bind(&setupRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.Setup(c.Request().Context(), &setupRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to setup node: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) sendPaymentProbesHandler(c echo.Context) error {
+	var sendPaymentProbesRequest api.SendPaymentProbesRequest
+	if err := c.Bind(&sendPaymentProbesRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	sendPaymentProbesResponse, err := httpSvc.api.SendPaymentProbes(c.Request().Context(), &sendPaymentProbesRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to send payment probes: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, sendPaymentProbesResponse)
+}
+
+func (httpSvc *HttpService) sendSpontaneousPaymentProbesHandler(c echo.Context) error {
+	var sendSpontaneousPaymentProbesRequest api.SendSpontaneousPaymentProbesRequest
+	if err := c.Bind(&sendSpontaneousPaymentProbesRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	sendSpontaneousPaymentProbesResponse, err := httpSvc.api.SendSpontaneousPaymentProbes(c.Request().Context(), &sendSpontaneousPaymentProbesRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to send spontaneous payment probes: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, sendSpontaneousPaymentProbesResponse)
+}
+
+func (httpSvc *HttpService) getLogOutputHandler(c echo.Context) error {
+	var getLogRequest api.GetLogOutputRequest
+	if err := c.Bind(&getLogRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	logType := c.Param("type")
+	if logType != api.LogTypeNode && logType != api.LogTypeApp {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid log type parameter: '%s'", logType),
+		})
+	}
+
+	getLogResponse, err := httpSvc.api.GetLogOutput(c.Request().Context(), logType, &getLogRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get log output: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, getLogResponse)
+}
+
+func (httpSvc *HttpService) getCustomNodeCommandsHandler(c echo.Context) error {
+	nodeCommandsResponse, err := httpSvc.api.GetCustomNodeCommands()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get node commands: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, nodeCommandsResponse)
+}
+
+func (httpSvc *HttpService) execCustomNodeCommandHandler(c echo.Context) error {
+	var execCommandRequest api.ExecuteCustomNodeCommandRequest
+	if err := c.Bind(&execCommandRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	execCommandResponse, err := httpSvc.api.ExecuteCustomNodeCommand(c.Request().Context(), execCommandRequest.Command)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to execute command: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, execCommandResponse)
+}
+
+func (httpSvc *HttpService) logoutHandler(c echo.Context) error {
+	redirectUrl := httpSvc.cfg.GetEnv().GetBaseFrontendUrl()
+	if redirectUrl == "" {
+		redirectUrl = "/"
+	}
+
+	return c.Redirect(http.StatusFound, redirectUrl)
+}
+
+func (httpSvc *HttpService) createBackupHandler(c echo.Context) error {
+	var backupRequest api.BasicBackupRequest
+	if err := c.Bind(&backupRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	if !httpSvc.cfg.CheckUnlockPassword(backupRequest.UnlockPassword) {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: "Invalid password",
+		})
+	}
+
+	if err := httpSvc.api.VerifyTotp(backupRequest.UnlockPassword, backupRequest.TotpCode); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	var buffer bytes.Buffer
+	err := httpSvc.api.CreateBackup(backupRequest.UnlockPassword, backupRequest.SeedPassphrase, &buffer)
+	if err != nil {
+		return c.String(500, fmt.Sprintf("Failed to create backup: %v", err))
+	}
+
+	c.Response().Header().Set("Content-Type", "application/octet-stream")
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=albyhub.bkp")
+	c.Response().WriteHeader(http.StatusOK)
+	c.Response().Write(buffer.Bytes())
+	return nil
+}
+
+func (httpSvc *HttpService) restoreBackupHandler(c echo.Context) error {
+	info, err := httpSvc.api.GetInfo(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	if info.SetupCompleted {
+		return errors.New("setup already completed")
+	}
+
+	password := c.FormValue("unlockPassword")
+	seedPassphrase := c.FormValue("seedPassphrase")
+
+	fileHeader, err := c.FormFile("backup")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get backup file header: %v", err),
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to open backup file: %v", err),
+		})
+	}
+	defer file.Close()
+
+	err = httpSvc.api.RestoreBackup(password, seedPassphrase, file)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to restore backup: %v", err),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) validateBackupHandler(c echo.Context) error {
+	if httpSvc.cfg.SetupCompleted() {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Setup already completed",
+		})
+	}
+
+	fileHeader, err := c.FormFile("backup")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get backup file header: %v", err),
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to open backup file: %v", err),
+		})
+	}
+	defer file.Close()
+
+	backupInfo, err := httpSvc.api.ValidateBackup(c.FormValue("unlockPassword"), file)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid backup: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, backupInfo)
+}
+
+func (httpSvc *HttpService) healthHandler(c echo.Context) error {
+	healthResponse, err := httpSvc.api.Health(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to check node health: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, healthResponse)
+}
+
+func (httpSvc *HttpService) listSwapsHandler(c echo.Context) error {
+	swaps, err := httpSvc.api.ListSwaps()
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swaps)
+}
+
+func (httpSvc *HttpService) lookupSwapHandler(c echo.Context) error {
+	swap, err := httpSvc.api.LookupSwap(c.Param("swapId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, swap)
+}
+
+func (httpSvc *HttpService) getSwapOutInfoHandler(c echo.Context) error {
+	swapOutFeesResponse, err := httpSvc.api.GetSwapOutInfo()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get swap out info: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapOutFeesResponse)
+}
+
+func (httpSvc *HttpService) getSwapInInfoHandler(c echo.Context) error {
+	swapOutFeesResponse, err := httpSvc.api.GetSwapInInfo()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get swap in info: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapOutFeesResponse)
+}
+
+func (httpSvc *HttpService) initiateSwapOutHandler(c echo.Context) error {
+	var initiateSwapOutRequest api.InitiateSwapRequest
+	if err := c.Bind(&initiateSwapOutRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	swapOutResponse, err := httpSvc.api.InitiateSwapOut(c.Request().Context(), &initiateSwapOutRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to initiate swap out: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapOutResponse)
+}
+
+func (httpSvc *HttpService) initiateSwapInHandler(c echo.Context) error {
+	var initiateSwapInRequest api.InitiateSwapRequest
+	if err := c.Bind(&initiateSwapInRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	txId, err := httpSvc.api.InitiateSwapIn(c.Request().Context(), &initiateSwapInRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to initiate swap in: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, txId)
+}
+
+func (httpSvc *HttpService) refundSwapHandler(c echo.Context) error {
+	var refundSwapInRequest api.RefundSwapRequest
+	if err := c.Bind(&refundSwapInRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.RefundSwap(&refundSwapInRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) swapMnemonicHandler(c echo.Context) error {
+	mnemonic := httpSvc.api.GetSwapMnemonic()
+	return c.JSON(http.StatusOK, mnemonic)
+}
+
+func (httpSvc *HttpService) getAutoSwapConfigHandler(c echo.Context) error {
+	getAutoSwapConfigResponse, err := httpSvc.api.GetAutoSwapConfig()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get swap settings: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, getAutoSwapConfigResponse)
+}
+
+func (httpSvc *HttpService) enableAutoSwapOutHandler(c echo.Context) error {
+	var enableAutoSwapRequest api.EnableAutoSwapRequest
+	if err := c.Bind(&enableAutoSwapRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.EnableAutoSwapOut(c.Request().Context(), &enableAutoSwapRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to save swap settings: %v", err),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) disableAutoSwapOutHandler(c echo.Context) error {
+	err := httpSvc.api.DisableAutoSwap()
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) setNodeAliasHandler(c echo.Context) error {
+	var setNodeAliasRequest api.SetNodeAliasRequest
+	if err := c.Bind(&setNodeAliasRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.SetNodeAlias(setNodeAliasRequest.NodeAlias)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to set node alias: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) forwardsHandler(c echo.Context) error {
+	forwards, err := httpSvc.api.GetForwards()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get forwards: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, forwards)
+}
+
+func (httpSvc *HttpService) listWebhooksHandler(c echo.Context) error {
+	webhooks, err := httpSvc.api.ListWebhooks()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list webhooks: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, webhooks)
+}
+
+func (httpSvc *HttpService) listWebhookEventTypesHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, httpSvc.api.ListWebhookEventTypes())
+}
+
+func (httpSvc *HttpService) createWebhookHandler(c echo.Context) error {
+	var createWebhookRequest api.CreateWebhookRequest
+	if err := c.Bind(&createWebhookRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	webhook, err := httpSvc.api.CreateWebhook(&createWebhookRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create webhook: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (httpSvc *HttpService) updateWebhookHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid webhook ID",
+		})
+	}
+
+	var updateWebhookRequest api.UpdateWebhookRequest
+	if err := c.Bind(&updateWebhookRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	webhook, err := httpSvc.api.UpdateWebhook(uint(id), &updateWebhookRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update webhook: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (httpSvc *HttpService) deleteWebhookHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid webhook ID",
+		})
+	}
+
+	err = httpSvc.api.DeleteWebhook(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete webhook: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listWebhookDeliveriesHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid webhook ID",
+		})
+	}
+
+	limit := uint64(20)
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		limit, err = strconv.ParseUint(limitParam, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: "Invalid limit",
+			})
+		}
+	}
+
+	deliveries, err := httpSvc.api.ListWebhookDeliveries(uint(id), c.QueryParam("state"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list webhook deliveries: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+func (httpSvc *HttpService) retryWebhookDeliveryHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid webhook ID",
+		})
+	}
+	deliveryId, err := strconv.ParseUint(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid delivery ID",
+		})
+	}
+
+	delivery, err := httpSvc.api.RetryWebhookDelivery(c.Request().Context(), uint(id), uint(deliveryId))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to retry webhook delivery: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, delivery)
+}
+
+func (httpSvc *HttpService) listScheduledPaymentsHandler(c echo.Context) error {
+	scheduledPayments, err := httpSvc.api.ListScheduledPayments()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list scheduled payments: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, scheduledPayments)
+}
+
+func (httpSvc *HttpService) createScheduledPaymentHandler(c echo.Context) error {
+	var createScheduledPaymentRequest api.CreateScheduledPaymentRequest
+	if err := c.Bind(&createScheduledPaymentRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	scheduledPayment, err := httpSvc.api.CreateScheduledPayment(&createScheduledPaymentRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create scheduled payment: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, scheduledPayment)
+}
+
+func (httpSvc *HttpService) deleteScheduledPaymentHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid scheduled payment ID",
+		})
+	}
+
+	err = httpSvc.api.DeleteScheduledPayment(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete scheduled payment: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listSwapRulesHandler(c echo.Context) error {
+	swapRules, err := httpSvc.api.ListSwapRules()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list swap rules: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapRules)
+}
+
+func (httpSvc *HttpService) createSwapRuleHandler(c echo.Context) error {
+	var createSwapRuleRequest api.CreateSwapRuleRequest
+	if err := c.Bind(&createSwapRuleRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	swapRule, err := httpSvc.api.CreateSwapRule(&createSwapRuleRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create swap rule: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapRule)
+}
+
+func (httpSvc *HttpService) updateSwapRuleHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid swap rule ID",
+		})
+	}
+
+	var updateSwapRuleRequest api.UpdateSwapRuleRequest
+	if err := c.Bind(&updateSwapRuleRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	swapRule, err := httpSvc.api.UpdateSwapRule(uint(id), &updateSwapRuleRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update swap rule: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapRule)
+}
+
+func (httpSvc *HttpService) deleteSwapRuleHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid swap rule ID",
+		})
+	}
+
+	err = httpSvc.api.DeleteSwapRule(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete swap rule: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listSwapRuleRunsHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid swap rule ID",
+		})
+	}
+
+	limit := uint64(20)
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		limit, err = strconv.ParseUint(limitParam, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: "Invalid limit",
+			})
+		}
+	}
+
+	swapRuleRuns, err := httpSvc.api.ListSwapRuleRuns(uint(id), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list swap rule runs: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapRuleRuns)
+}
+
+func (httpSvc *HttpService) listFeePoliciesHandler(c echo.Context) error {
+	feePolicies, err := httpSvc.api.ListFeePolicies()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list fee policies: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feePolicies)
+}
+
+func (httpSvc *HttpService) createFeePolicyHandler(c echo.Context) error {
+	var createFeePolicyRequest api.CreateFeePolicyRequest
+	if err := c.Bind(&createFeePolicyRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	feePolicy, err := httpSvc.api.CreateFeePolicy(&createFeePolicyRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create fee policy: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feePolicy)
+}
+
+func (httpSvc *HttpService) updateFeePolicyHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid fee policy ID",
+		})
+	}
+
+	var updateFeePolicyRequest api.UpdateFeePolicyRequest
+	if err := c.Bind(&updateFeePolicyRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	feePolicy, err := httpSvc.api.UpdateFeePolicy(uint(id), &updateFeePolicyRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update fee policy: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feePolicy)
+}
+
+func (httpSvc *HttpService) deleteFeePolicyHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid fee policy ID",
+		})
+	}
+
+	err = httpSvc.api.DeleteFeePolicy(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete fee policy: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listBackupTargetsHandler(c echo.Context) error {
+	backupTargets, err := httpSvc.api.ListBackupTargets()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list backup targets: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, backupTargets)
+}
+
+func (httpSvc *HttpService) createBackupTargetHandler(c echo.Context) error {
+	var createBackupTargetRequest api.CreateBackupTargetRequest
+	if err := c.Bind(&createBackupTargetRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	backupTarget, err := httpSvc.api.CreateBackupTarget(&createBackupTargetRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create backup target: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, backupTarget)
+}
+
+func (httpSvc *HttpService) updateBackupTargetHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid backup target ID",
+		})
+	}
+
+	var updateBackupTargetRequest api.UpdateBackupTargetRequest
+	if err := c.Bind(&updateBackupTargetRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	backupTarget, err := httpSvc.api.UpdateBackupTarget(uint(id), &updateBackupTargetRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update backup target: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, backupTarget)
+}
+
+func (httpSvc *HttpService) deleteBackupTargetHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid backup target ID",
+		})
+	}
+
+	err = httpSvc.api.DeleteBackupTarget(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete backup target: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listBackupSnapshotsHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid backup target ID",
+		})
+	}
+
+	snapshots, err := httpSvc.api.ListBackupSnapshots(c.Request().Context(), uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list backup snapshots: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, snapshots)
+}
+
+func (httpSvc *HttpService) runBackupHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid backup target ID",
+		})
+	}
+
+	var runBackupRequest api.RunBackupRequest
+	if err := c.Bind(&runBackupRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	snapshot, err := httpSvc.api.RunBackup(c.Request().Context(), uint(id), &runBackupRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to run backup: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, snapshot)
+}
+
+func (httpSvc *HttpService) getNostrBackupHandler(c echo.Context) error {
+	nostrBackup, err := httpSvc.api.GetNostrBackup(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to fetch nostr backup: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, nostrBackup)
+}
+
+func (httpSvc *HttpService) publishNostrBackupHandler(c echo.Context) error {
+	nostrBackup, err := httpSvc.api.PublishNostrBackup(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to publish nostr backup: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, nostrBackup)
+}
+
+func (httpSvc *HttpService) getDatabaseMaintenanceStatusHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, httpSvc.api.GetDatabaseMaintenanceStatus())
+}
+
+func (httpSvc *HttpService) runDatabaseMaintenanceHandler(c echo.Context) error {
+	var runDatabaseMaintenanceRequest api.RunDatabaseMaintenanceRequest
+	if err := c.Bind(&runDatabaseMaintenanceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	report, err := httpSvc.api.RunDatabaseMaintenance(c.Request().Context(), &runDatabaseMaintenanceRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to run database maintenance: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func (httpSvc *HttpService) getDatabaseKeyRotationHandler(c echo.Context) error {
+	keyRotation, err := httpSvc.api.GetDatabaseKeyRotation()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get key rotation: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, keyRotation)
+}
+
+func (httpSvc *HttpService) rotateDatabaseEncryptionKeyHandler(c echo.Context) error {
+	var rotateDatabaseEncryptionKeyRequest api.RotateDatabaseEncryptionKeyRequest
+	if err := c.Bind(&rotateDatabaseEncryptionKeyRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.RotateDatabaseEncryptionKey(&rotateDatabaseEncryptionKeyRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to rotate encryption key: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) exportAdminAuditLogHandler(c echo.Context) error {
+	var buffer bytes.Buffer
+	err := httpSvc.api.ExportAdminAuditLog(&buffer)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to export admin audit log: %s", err.Error()),
+		})
+	}
+
+	c.Response().Header().Set("Content-Type", "application/x-ndjson")
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=albyhub-admin-audit-log.jsonl")
+	c.Response().WriteHeader(http.StatusOK)
+	c.Response().Write(buffer.Bytes())
+	return nil
+}
+
+func (httpSvc *HttpService) verifyAdminAuditLogHandler(c echo.Context) error {
+	verification, err := httpSvc.api.VerifyAdminAuditLog()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to verify admin audit log: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, verification)
+}
+
+func (httpSvc *HttpService) getDatabaseMigrationsHandler(c echo.Context) error {
+	databaseMigrations, err := httpSvc.api.GetDatabaseMigrations()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get database migrations: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, databaseMigrations)
+}
+
+func (httpSvc *HttpService) startRecoveryHandler(c echo.Context) error {
+	var startRecoveryRequest api.StartRecoveryRequest
+	if err := c.Bind(&startRecoveryRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	recovery, err := httpSvc.api.StartRecovery(c.Request().Context(), &startRecoveryRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to start recovery: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, recovery)
+}
+
+func (httpSvc *HttpService) getRecoveryHandler(c echo.Context) error {
+	recovery, err := httpSvc.api.GetRecovery()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get recovery: %s", err.Error()),
+		})
+	}
+	if recovery == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "No recovery found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, recovery)
+}
+
+func (httpSvc *HttpService) deleteRecoveryHandler(c echo.Context) error {
+	if err := httpSvc.api.DeleteRecovery(); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete recovery: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) transactionsSummaryHandler(c echo.Context) error {
+	var from, until uint64
+	var appId *uint
+
+	if fromParam := c.QueryParam("from"); fromParam != "" {
+		if parsedFrom, err := strconv.ParseUint(fromParam, 10, 64); err == nil {
+			from = parsedFrom
+		}
+	}
+
+	if untilParam := c.QueryParam("until"); untilParam != "" {
+		if parsedUntil, err := strconv.ParseUint(untilParam, 10, 64); err == nil {
+			until = parsedUntil
+		}
+	}
+
+	if appIdParam := c.QueryParam("appId"); appIdParam != "" {
+		if parsedAppId, err := strconv.ParseUint(appIdParam, 10, 64); err == nil {
+			var unsignedAppId = uint(parsedAppId)
+			appId = &unsignedAppId
+		}
+	}
+
+	summary, err := httpSvc.api.GetTransactionsSummary(c.Request().Context(), from, until, appId, c.QueryParam("currency"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get transactions summary: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
+
+func (httpSvc *HttpService) liquidityReportHandler(c echo.Context) error {
+	var from, until uint64
+
+	if fromParam := c.QueryParam("from"); fromParam != "" {
+		if parsedFrom, err := strconv.ParseUint(fromParam, 10, 64); err == nil {
+			from = parsedFrom
+		}
+	}
+
+	if untilParam := c.QueryParam("until"); untilParam != "" {
+		if parsedUntil, err := strconv.ParseUint(untilParam, 10, 64); err == nil {
+			until = parsedUntil
+		}
+	}
+
+	report, err := httpSvc.api.GetLiquidityReport(from, until)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get liquidity report: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func (httpSvc *HttpService) appReportHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
+
+	var from, until uint64
+
+	if fromParam := c.QueryParam("from"); fromParam != "" {
+		if parsedFrom, err := strconv.ParseUint(fromParam, 10, 64); err == nil {
+			from = parsedFrom
+		}
+	}
+
+	if untilParam := c.QueryParam("until"); untilParam != "" {
+		if parsedUntil, err := strconv.ParseUint(untilParam, 10, 64); err == nil {
+			until = parsedUntil
+		}
+	}
+
+	report, err := httpSvc.api.GetAppReport(c.Request().Context(), uint(appId), from, until, c.QueryParam("currency"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get app report: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func (httpSvc *HttpService) archiveTransactionsHandler(c echo.Context) error {
+	var archiveRequest api.ArchiveTransactionsRequest
+	if err := c.Bind(&archiveRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	if !httpSvc.cfg.CheckUnlockPassword(archiveRequest.UnlockPassword) {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: "Invalid password",
+		})
+	}
+
+	// the archive is sent before the transactions are pruned, which is rolled back if sending fails
+	archiveWriter := &archiveResponseWriter{response: c.Response()}
+	err := httpSvc.api.ArchiveTransactions(c.Request().Context(), &archiveRequest, archiveWriter)
+	if err != nil {
+		if c.Response().Committed {
+			logger.Logger.WithError(err).Error("Failed to archive transactions after sending the archive")
+			return nil
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to archive transactions: %s", err.Error()),
+		})
+	}
+	return nil
+}
+
+// archiveResponseWriter only sends the headers once the archive is written, so that errors
+// before can still be returned as JSON
+type archiveResponseWriter struct {
+	response *echo.Response
+}
+
+func (w *archiveResponseWriter) Write(p []byte) (int, error) {
+	if !w.response.Committed {
+		w.response.Header().Set("Content-Type", "application/octet-stream")
+		w.response.Header().Set("Content-Disposition", "attachment; filename=albyhub-transactions.archive")
+		w.response.WriteHeader(http.StatusOK)
+	}
+	return w.response.Write(p)
+}
+
+// Flush returns an error if the archive could not be sent to the client
+func (w *archiveResponseWriter) Flush() error {
+	return http.NewResponseController(w.response.Writer).Flush()
+}
+
+func (httpSvc *HttpService) transactionReceiptHandler(c echo.Context) error {
+	receipt, err := httpSvc.api.GetTransactionReceipt(c.Request().Context(), c.Param("paymentHash"), c.QueryParam("type"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create receipt: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, receipt)
+}
+
+func (httpSvc *HttpService) subwalletAddressesListHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
+
+	addresses, err := httpSvc.api.ListSubwalletAddresses(uint(appId))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list deposit addresses: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, addresses)
+}
+
+func (httpSvc *HttpService) subwalletAddressesCreateHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
+
+	address, err := httpSvc.api.CreateSubwalletAddress(c.Request().Context(), uint(appId))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create deposit address: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, address)
+}
+
+func (httpSvc *HttpService) subwalletOwnerPasswordHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
+
+	var setOwnerPasswordRequest api.SetSubwalletOwnerPasswordRequest
+	if err := c.Bind(&setOwnerPasswordRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err = httpSvc.api.SetSubwalletOwnerPassword(uint(appId), setOwnerPasswordRequest.Password)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to set owner password: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) subwalletLoginHandler(c echo.Context) error {
+	var loginRequest api.SubwalletLoginRequest
+	if err := c.Bind(&loginRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	if !httpSvc.api.CheckSubwalletOwnerPassword(loginRequest.AppId, loginRequest.Password) {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: "Invalid password",
+		})
+	}
+
+	token, err := httpSvc.createSubwalletJWT(c, loginRequest.TokenExpiryDays, loginRequest.AppId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, &authTokenResponse{
+		Token: token,
+	})
+}
 
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+func (httpSvc *HttpService) subwalletShowHandler(c echo.Context) error {
+	dbApp := httpSvc.appsSvc.GetAppById(c.Get("subwalletAppId").(uint))
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
 		})
 	}
 
-	return c.JSON(http.StatusOK, info)
+	return c.JSON(http.StatusOK, httpSvc.api.GetApp(dbApp))
 }
 
-func (httpSvc *HttpService) nodeNetworkGraphHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) subwalletTransactionsListHandler(c echo.Context) error {
+	appId := c.Get("subwalletAppId").(uint)
 
-	nodeIds := strings.Split(c.QueryParam("nodeIds"), ",")
+	limit := uint64(20)
+	offset := uint64(0)
 
-	info, err := httpSvc.api.GetNetworkGraph(ctx, nodeIds)
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
 
+	transactions, err := httpSvc.api.ListTransactions(c.Request().Context(), &appId, limit, offset, nil)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, info)
+	return c.JSON(http.StatusOK, transactions)
 }
 
-func (httpSvc *HttpService) migrateNodeStorageHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-	var migrateNodeStorageRequest api.MigrateNodeStorageRequest
-	if err := c.Bind(&migrateNodeStorageRequest); err != nil {
+func (httpSvc *HttpService) subwalletMakeInvoiceHandler(c echo.Context) error {
+	var makeInvoiceRequest api.MakeInvoiceRequest
+	if err := c.Bind(&makeInvoiceRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.MigrateNodeStorage(ctx, migrateNodeStorageRequest.To)
-
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
-		})
-	}
-
-	return c.NoContent(http.StatusNoContent)
-}
-
-func (httpSvc *HttpService) balancesHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	balances, err := httpSvc.api.GetBalances(ctx)
-
+	invoice, err := httpSvc.api.CreateSubwalletInvoice(c.Request().Context(), c.Get("subwalletAppId").(uint), makeInvoiceRequest.Amount, makeInvoiceRequest.Description)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, balances)
+	return c.JSON(http.StatusOK, invoice)
 }
 
-func (httpSvc *HttpService) sendPaymentHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
+func (httpSvc *HttpService) subwalletSendPaymentHandler(c echo.Context) error {
 	var payInvoiceRequest api.PayInvoiceRequest
 	if err := c.Bind(&payInvoiceRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
@@ -607,8 +3462,12 @@ func (httpSvc *HttpService) sendPaymentHandler(c echo.Context) error {
 		})
 	}
 
-	paymentResponse, err := httpSvc.api.SendPayment(ctx, c.Param("invoice"), payInvoiceRequest.Amount, payInvoiceRequest.Metadata)
+	idempotencyKey := payInvoiceRequest.IdempotencyKey
+	if idempotencyKeyHeader := c.Request().Header.Get("Idempotency-Key"); idempotencyKeyHeader != "" {
+		idempotencyKey = idempotencyKeyHeader
+	}
 
+	paymentResponse, err := httpSvc.api.SendSubwalletPayment(c.Request().Context(), c.Get("subwalletAppId").(uint), c.Param("invoice"), payInvoiceRequest.Amount, idempotencyKey)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: err.Error(),
@@ -618,938 +3477,1072 @@ func (httpSvc *HttpService) sendPaymentHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, paymentResponse)
 }
 
-func (httpSvc *HttpService) makeOfferHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	var makeOfferRequest api.MakeOfferRequest
-	if err := c.Bind(&makeOfferRequest); err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+func (httpSvc *HttpService) subwalletOwnAddressesListHandler(c echo.Context) error {
+	addresses, err := httpSvc.api.ListSubwalletAddresses(c.Get("subwalletAppId").(uint))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list deposit addresses: %s", err.Error()),
 		})
 	}
 
-	offer, err := httpSvc.api.MakeOffer(ctx, makeOfferRequest.Description)
+	return c.JSON(http.StatusOK, addresses)
+}
 
+func (httpSvc *HttpService) subwalletOwnAddressesCreateHandler(c echo.Context) error {
+	address, err := httpSvc.api.CreateSubwalletAddress(c.Request().Context(), c.Get("subwalletAppId").(uint))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to generate BOLT-12 offer: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to create deposit address: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, offer)
+	return c.JSON(http.StatusOK, address)
 }
 
-func (httpSvc *HttpService) makeInvoiceHandler(c echo.Context) error {
-	var makeInvoiceRequest api.MakeInvoiceRequest
-	if err := c.Bind(&makeInvoiceRequest); err != nil {
+func (httpSvc *HttpService) subwalletTransfersHandler(c echo.Context) error {
+	var transferRequest api.SubwalletTransferRequest
+	if err := c.Bind(&transferRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	invoice, err := httpSvc.api.CreateInvoice(c.Request().Context(), makeInvoiceRequest.Amount, makeInvoiceRequest.Description)
-
+	fromAppId := c.Get("subwalletAppId").(uint)
+	transferResponse, err := httpSvc.api.Transfer(c.Request().Context(), &fromAppId, &transferRequest.ToAppId, transferRequest.AmountSat*1000)
 	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to transfer funds")
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to transfer funds: %v", err),
 		})
 	}
 
-	return c.JSON(http.StatusOK, invoice)
+	// the sub-wallet only sees its own side of the transfer
+	return c.JSON(http.StatusOK, transferResponse.Sent)
 }
 
-func (httpSvc *HttpService) lookupTransactionHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) appsRenewHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
 
-	transaction, err := httpSvc.api.LookupInvoice(ctx, c.Param("paymentHash"))
+	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
+
+	var renewAppRequest api.RenewAppRequest
+	if err := c.Bind(&renewAppRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
+	app, err := httpSvc.api.RenewApp(dbApp, &renewAppRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to renew app: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, transaction)
+	return c.JSON(http.StatusOK, app)
 }
 
-func (httpSvc *HttpService) listTransactionsHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	limit := uint64(20)
-	offset := uint64(0)
-	var appId *uint
-
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
-			limit = parsedLimit
-		}
-	}
-
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
-			offset = parsedOffset
-		}
+func (httpSvc *HttpService) appsRotateSecretHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
 	}
 
-	if appIdParam := c.QueryParam("appId"); appIdParam != "" {
-		if parsedAppId, err := strconv.ParseUint(appIdParam, 10, 64); err == nil {
-			var unsignedAppId = uint(parsedAppId)
-			appId = &unsignedAppId
-		}
+	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
 	}
 
-	transactions, err := httpSvc.api.ListTransactions(ctx, appId, limit, offset)
-
+	rotateSecretResponse, err := httpSvc.api.RotateAppSecret(dbApp)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to rotate app secret: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, transactions)
+	return c.JSON(http.StatusOK, rotateSecretResponse)
 }
 
-func (httpSvc *HttpService) listOnchainTransactionsHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) appScopesAddHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
 
-	transactions, err := httpSvc.api.ListOnchainTransactions(ctx)
+	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
+
+	var appScopeRequest api.AppScopeRequest
+	if err := c.Bind(&appScopeRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
+	err = httpSvc.api.AddAppScope(dbApp, appScopeRequest.Scope)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to add scope: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, transactions)
-}
-
-func (httpSvc *HttpService) walletSyncHandler(c echo.Context) error {
-	httpSvc.api.SyncWallet()
-
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) mempoolApiHandler(c echo.Context) error {
-	endpoint := c.QueryParam("endpoint")
-	if endpoint == "" {
+func (httpSvc *HttpService) appScopesRemoveHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Invalid pubkey parameter",
+			Message: "Invalid App ID",
 		})
 	}
 
-	response, err := httpSvc.api.RequestMempoolApi(c.Request().Context(), endpoint)
+	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
+
+	err = httpSvc.api.RemoveAppScope(dbApp, c.Param("scope"))
 	if err != nil {
-		logger.Logger.WithField("endpoint", endpoint).WithError(err).Error("Failed to request mempool API")
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to request mempool API: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to remove scope: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) capabilitiesHandler(c echo.Context) error {
-	response, err := httpSvc.api.GetWalletCapabilities(c.Request().Context())
+func (httpSvc *HttpService) appAuditLogsListHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
+
+	auditLogs, err := httpSvc.api.ListAppAuditLogs(uint(appId))
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to request wallet capabilities")
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to request wallet capabilities: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to list audit log: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return c.JSON(http.StatusOK, auditLogs)
 }
 
-func (httpSvc *HttpService) listPeers(c echo.Context) error {
-	peers, err := httpSvc.api.ListPeers(c.Request().Context())
+func (httpSvc *HttpService) listAppGroupsHandler(c echo.Context) error {
+	appGroups, err := httpSvc.api.ListAppGroups()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to list peers: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to list app groups: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, peers)
+	return c.JSON(http.StatusOK, appGroups)
 }
 
-func (httpSvc *HttpService) connectPeerHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	var connectPeerRequest api.ConnectPeerRequest
-	if err := c.Bind(&connectPeerRequest); err != nil {
+func (httpSvc *HttpService) createAppGroupHandler(c echo.Context) error {
+	var createAppGroupRequest api.CreateAppGroupRequest
+	if err := c.Bind(&createAppGroupRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.ConnectPeer(ctx, &connectPeerRequest)
-
+	appGroup, err := httpSvc.api.CreateAppGroup(&createAppGroupRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to connect peer: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to create app group: %s", err.Error()),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, appGroup)
 }
 
-func (httpSvc *HttpService) openChannelHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) updateAppGroupHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid app group ID",
+		})
+	}
 
-	var openChannelRequest api.OpenChannelRequest
-	if err := c.Bind(&openChannelRequest); err != nil {
+	var updateAppGroupRequest api.UpdateAppGroupRequest
+	if err := c.Bind(&updateAppGroupRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	openChannelResponse, err := httpSvc.api.OpenChannel(ctx, &openChannelRequest)
-
+	appGroup, err := httpSvc.api.UpdateAppGroup(uint(id), &updateAppGroupRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to open channel: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to update app group: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, openChannelResponse)
+	return c.JSON(http.StatusOK, appGroup)
 }
 
-func (httpSvc *HttpService) rebalanceChannelHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	var rebalanceChannelRequest api.RebalanceChannelRequest
-	if err := c.Bind(&rebalanceChannelRequest); err != nil {
+func (httpSvc *HttpService) deleteAppGroupHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid app group ID",
 		})
 	}
 
-	rebalanceChannelResponse, err := httpSvc.api.RebalanceChannel(ctx, &rebalanceChannelRequest)
-
+	err = httpSvc.api.DeleteAppGroup(uint(id))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to rebalance channel: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to delete app group: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, rebalanceChannelResponse)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) disconnectPeerHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	err := httpSvc.api.DisconnectPeer(ctx, c.Param("peerId"))
-
+func (httpSvc *HttpService) listPaymentApprovalsHandler(c echo.Context) error {
+	paymentApprovals, err := httpSvc.api.ListPaymentApprovals(c.QueryParam("state"))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to disconnect peer: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to list payment approvals: %s", err.Error()),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, paymentApprovals)
 }
 
-func (httpSvc *HttpService) closeChannelHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) approvePaymentHandler(c echo.Context) error {
+	return httpSvc.decidePaymentApproval(c, true)
+}
 
-	closeChannelResponse, err := httpSvc.api.CloseChannel(ctx, c.Param("peerId"), c.Param("channelId"), c.QueryParam("force") == "true")
+func (httpSvc *HttpService) rejectPaymentHandler(c echo.Context) error {
+	return httpSvc.decidePaymentApproval(c, false)
+}
+
+func (httpSvc *HttpService) decidePaymentApproval(c echo.Context, approved bool) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid payment approval ID",
+		})
+	}
 
+	err = httpSvc.api.DecidePaymentApproval(uint(id), approved)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to close channel: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to decide payment approval: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, closeChannelResponse)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) updateChannelHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) listAppTemplatesHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, httpSvc.api.ListAppTemplates())
+}
 
-	var updateChannelRequest api.UpdateChannelRequest
-	if err := c.Bind(&updateChannelRequest); err != nil {
+func (httpSvc *HttpService) appActivityListHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid App ID",
 		})
 	}
 
-	updateChannelRequest.NodeId = c.Param("peerId")
-	updateChannelRequest.ChannelId = c.Param("channelId")
+	limit := uint64(20)
+	offset := uint64(0)
+	if parsedLimit, err := strconv.ParseUint(c.QueryParam("limit"), 10, 64); err == nil {
+		limit = parsedLimit
+	}
+	if parsedOffset, err := strconv.ParseUint(c.QueryParam("offset"), 10, 64); err == nil {
+		offset = parsedOffset
+	}
 
-	err := httpSvc.api.UpdateChannel(ctx, &updateChannelRequest)
+	filters := api.ListAppActivityFilters{
+		Method: c.QueryParam("method"),
+		Result: c.QueryParam("result"),
+	}
+	if parsedFrom, err := strconv.ParseUint(c.QueryParam("from"), 10, 64); err == nil {
+		filters.From = parsedFrom
+	}
+	if parsedUntil, err := strconv.ParseUint(c.QueryParam("until"), 10, 64); err == nil {
+		filters.Until = parsedUntil
+	}
 
+	activity, err := httpSvc.api.ListAppActivity(uint(appId), filters, limit, offset)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to update channel: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to list app activity: %s", err.Error()),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, activity)
 }
 
-func (httpSvc *HttpService) newInstantChannelInvoiceHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	var newWrappedInvoiceRequest api.LSPOrderRequest
-	if err := c.Bind(&newWrappedInvoiceRequest); err != nil {
+func (httpSvc *HttpService) appDestinationsListHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid App ID",
 		})
 	}
 
-	newLSPOrderResponse, err := httpSvc.api.RequestLSPOrder(ctx, &newWrappedInvoiceRequest)
-
+	appDestinations, err := httpSvc.api.ListAppDestinations(uint(appId))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to request new channel order from LSP: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to list app destinations: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, newLSPOrderResponse)
+	return c.JSON(http.StatusOK, appDestinations)
 }
 
-func (httpSvc *HttpService) onchainAddressHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	address, err := httpSvc.api.GetUnusedOnchainAddress(ctx)
-
+func (httpSvc *HttpService) appDestinationsAddHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to request new onchain address: %s", err.Error()),
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
 		})
 	}
 
-	return c.JSON(http.StatusOK, address)
-}
-
-func (httpSvc *HttpService) newOnchainAddressHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
 
-	address, err := httpSvc.api.GetNewOnchainAddress(ctx)
+	var addAppDestinationRequest api.AddAppDestinationRequest
+	if err := c.Bind(&addAppDestinationRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
+	appDestination, err := httpSvc.api.AddAppDestination(c.Request().Context(), dbApp, &addAppDestinationRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to request new onchain address: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to add app destination: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, address)
+	return c.JSON(http.StatusOK, appDestination)
 }
 
-func (httpSvc *HttpService) redeemOnchainFundsHandler(c echo.Context) error {
-	ctx := c.Request().Context()
+func (httpSvc *HttpService) appDestinationsRemoveHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
 
-	var redeemOnchainFundsRequest api.RedeemOnchainFundsRequest
-	if err := c.Bind(&redeemOnchainFundsRequest); err != nil {
+	destinationId, err := strconv.ParseUint(c.Param("destinationId"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid destination ID",
 		})
 	}
 
-	redeemOnchainFundsResponse, err := httpSvc.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, redeemOnchainFundsRequest.Amount, redeemOnchainFundsRequest.FeeRate, redeemOnchainFundsRequest.SendAll)
+	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+	if dbApp == nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
+		})
+	}
 
+	err = httpSvc.api.RemoveAppDestination(dbApp, uint(destinationId))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to redeem onchain funds: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to remove app destination: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, redeemOnchainFundsResponse)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) signMessageHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	var signMessageRequest api.SignMessageRequest
-	if err := c.Bind(&signMessageRequest); err != nil {
+func (httpSvc *HttpService) exportAppsHandler(c echo.Context) error {
+	var exportAppsRequest api.ExportAppsRequest
+	if err := c.Bind(&exportAppsRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	signMessageResponse, err := httpSvc.api.SignMessage(ctx, signMessageRequest.Message)
-
+	var buffer bytes.Buffer
+	err := httpSvc.api.ExportApps(&exportAppsRequest, &buffer)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to sign message: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to export apps: %s", err.Error()),
 		})
 	}
-	return c.JSON(http.StatusOK, signMessageResponse)
-}
 
-func (httpSvc *HttpService) appsListHandler(c echo.Context) error {
-	limit := uint64(0)
-	offset := uint64(0)
+	c.Response().Header().Set("Content-Type", "application/octet-stream")
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=albyhub-apps.export")
+	c.Response().WriteHeader(http.StatusOK)
+	c.Response().Write(buffer.Bytes())
+	return nil
+}
 
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
-			limit = parsedLimit
-		}
+func (httpSvc *HttpService) importAppsHandler(c echo.Context) error {
+	importAppsRequest := api.ImportAppsRequest{
+		UnlockPassword: c.FormValue("unlockPassword"),
+		ExportPassword: c.FormValue("exportPassword"),
 	}
 
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
-			offset = parsedOffset
-		}
+	fileHeader, err := c.FormFile("export")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get export file header: %v", err),
+		})
 	}
 
-	filtersJSON := c.QueryParam("filters")
-	var filters api.ListAppsFilters
-	if filtersJSON != "" {
-		err := json.Unmarshal([]byte(filtersJSON), &filters)
-		if err != nil {
-			logger.Logger.WithError(err).WithFields(logrus.Fields{
-				"filters": filtersJSON,
-			}).Error("Failed to deserialize app filters")
-			return err
-		}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to open export file: %v", err),
+		})
 	}
+	defer file.Close()
 
-	orderBy := c.QueryParam("order_by")
-
-	apps, err := httpSvc.api.ListApps(limit, offset, filters, orderBy)
-
+	importAppsResponse, err := httpSvc.api.ImportApps(&importAppsRequest, file)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to import apps: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, apps)
+	return c.JSON(http.StatusOK, importAppsResponse)
 }
 
-func (httpSvc *HttpService) appsShowByPubkeyHandler(c echo.Context) error {
-	dbApp := httpSvc.appsSvc.GetAppByPubkey(c.Param("pubkey"))
-
-	if dbApp == nil {
-		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Message: "App not found",
+func (httpSvc *HttpService) listApiKeysHandler(c echo.Context) error {
+	apiKeys, err := httpSvc.api.ListApiKeys()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list api keys: %s", err.Error()),
 		})
 	}
 
-	response := httpSvc.api.GetApp(dbApp)
-
-	return c.JSON(http.StatusOK, response)
+	return c.JSON(http.StatusOK, apiKeys)
 }
 
-func (httpSvc *HttpService) appsShowHandler(c echo.Context) error {
-	appIdStr := c.Param("id")
-	if appIdStr == "" {
+func (httpSvc *HttpService) createApiKeyHandler(c echo.Context) error {
+	var createApiKeyRequest api.CreateApiKeyRequest
+	if err := c.Bind(&createApiKeyRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "App ID is required",
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	appId, err := strconv.ParseUint(appIdStr, 10, 64)
+	createApiKeyResponse, err := httpSvc.api.CreateApiKey(&createApiKeyRequest)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Invalid App ID",
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create api key: %s", err.Error()),
 		})
 	}
 
-	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+	return c.JSON(http.StatusOK, createApiKeyResponse)
+}
 
-	if dbApp == nil {
-		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Message: "App not found",
+func (httpSvc *HttpService) revokeApiKeyHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid api key ID",
 		})
 	}
 
-	response := httpSvc.api.GetApp(dbApp)
+	if err := httpSvc.api.RevokeApiKey(uint(id)); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to revoke api key: %s", err.Error()),
+		})
+	}
 
-	return c.JSON(http.StatusOK, response)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) appsUpdateHandler(c echo.Context) error {
-	var requestData api.UpdateAppRequest
-	if err := c.Bind(&requestData); err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+func (httpSvc *HttpService) listSessionsHandler(c echo.Context) error {
+	sessions, err := httpSvc.api.ListSessions(currentTokenId(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list sessions: %s", err.Error()),
 		})
 	}
 
-	dbApp := httpSvc.appsSvc.GetAppByPubkey(c.Param("pubkey"))
+	return c.JSON(http.StatusOK, sessions)
+}
 
-	if dbApp == nil {
-		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Message: "App not found",
+func (httpSvc *HttpService) revokeSessionHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid session ID",
 		})
 	}
 
-	err := httpSvc.api.UpdateApp(dbApp, &requestData)
-
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to update app")
+	if err := httpSvc.api.RevokeSession(uint(id)); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to update app: %v", err),
+			Message: fmt.Sprintf("Failed to revoke session: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) transfersHandler(c echo.Context) error {
-	var requestData api.TransferRequest
-	if err := c.Bind(&requestData); err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+// revokeAllSessionsHandler logs out everywhere except for the session the request was made with
+func (httpSvc *HttpService) revokeAllSessionsHandler(c echo.Context) error {
+	revoked, err := httpSvc.api.RevokeAllSessions(currentTokenId(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to revoke sessions: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.Transfer(c.Request().Context(), requestData.FromAppId, requestData.ToAppId, requestData.AmountSat*1000)
+	return c.JSON(http.StatusOK, &api.RevokeAllSessionsResponse{
+		Revoked: revoked,
+	})
+}
 
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to transfer funds")
+func (httpSvc *HttpService) revokeCurrentSessionHandler(c echo.Context) error {
+	if err := httpSvc.api.RevokeSession(c.Get("sessionId").(uint)); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to transfer funds: %v", err),
+			Message: fmt.Sprintf("Failed to revoke session: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) appsDeleteHandler(c echo.Context) error {
-	dbApp := httpSvc.appsSvc.GetAppByPubkey(c.Param("pubkey"))
-	if dbApp == nil {
-		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Message: "App not found",
+func currentTokenId(c echo.Context) string {
+	token := c.Get("user").(*jwt.Token)
+	return token.Claims.(*jwtCustomClaims).ID
+}
+
+func (httpSvc *HttpService) listPasskeysHandler(c echo.Context) error {
+	passkeys, err := httpSvc.api.ListPasskeys()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list passkeys: %s", err.Error()),
 		})
 	}
 
-	if err := httpSvc.api.DeleteApp(dbApp); err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "Failed to delete app",
+	return c.JSON(http.StatusOK, passkeys)
+}
+
+func (httpSvc *HttpService) beginPasskeyRegistrationHandler(c echo.Context) error {
+	passkeyRegistrationOptions, err := httpSvc.api.BeginPasskeyRegistration()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to begin passkey registration: %s", err.Error()),
 		})
 	}
-	return c.NoContent(http.StatusNoContent)
+
+	return c.JSON(http.StatusOK, passkeyRegistrationOptions)
 }
 
-func (httpSvc *HttpService) appsCreateHandler(c echo.Context) error {
-	var requestData api.CreateAppRequest
-	if err := c.Bind(&requestData); err != nil {
+func (httpSvc *HttpService) finishPasskeyRegistrationHandler(c echo.Context) error {
+	var finishPasskeyRegistrationRequest api.FinishPasskeyRegistrationRequest
+	if err := c.Bind(&finishPasskeyRegistrationRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	responseBody, err := httpSvc.api.CreateApp(&requestData)
-
+	passkey, err := httpSvc.api.FinishPasskeyRegistration(&finishPasskeyRegistrationRequest)
 	if err != nil {
-		logger.Logger.WithField("requestData", requestData).WithError(err).Error("Failed to save app")
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to save app: %v", err),
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to register passkey: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, responseBody)
+	return c.JSON(http.StatusOK, passkey)
 }
 
-func (httpSvc *HttpService) lightningAddressesCreateHandler(c echo.Context) error {
-	var requestData api.CreateLightningAddressRequest
-	if err := c.Bind(&requestData); err != nil {
+func (httpSvc *HttpService) deletePasskeyHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid passkey ID",
 		})
 	}
 
-	err := httpSvc.api.CreateLightningAddress(c.Request().Context(), &requestData)
+	if err := httpSvc.api.DeletePasskey(uint(id)); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete passkey: %s", err.Error()),
+		})
+	}
 
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listPushDevicesHandler(c echo.Context) error {
+	pushDevices, err := httpSvc.api.ListPushDevices()
 	if err != nil {
-		logger.Logger.WithField("request", requestData).WithError(err).Error("Failed to create lightning address")
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to list push devices: %s", err.Error()),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, pushDevices)
 }
 
-func (httpSvc *HttpService) lightningAddressesDeleteHandler(c echo.Context) error {
-	appIdStr := c.Param("appId")
-	if appIdStr == "" {
+func (httpSvc *HttpService) registerPushDeviceHandler(c echo.Context) error {
+	var registerPushDeviceRequest api.RegisterPushDeviceRequest
+	if err := c.Bind(&registerPushDeviceRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "App ID is required",
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	appId, err := strconv.ParseUint(appIdStr, 10, 64)
+	pushDevice, err := httpSvc.api.RegisterPushDevice(&registerPushDeviceRequest)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Invalid App ID",
+			Message: fmt.Sprintf("Failed to register push device: %s", err.Error()),
 		})
 	}
 
-	err = httpSvc.api.DeleteLightningAddress(c.Request().Context(), uint(appId))
+	return c.JSON(http.StatusOK, pushDevice)
+}
+
+func (httpSvc *HttpService) revokePushDeviceHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		logger.Logger.WithField("appId", appId).WithError(err).Error("Failed to delete lightning address")
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid push device ID",
+		})
+	}
+
+	if err := httpSvc.api.RevokePushDevice(uint(id)); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to revoke push device: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) setupHandler(c echo.Context) error {
-	var setupRequest api.SetupRequest
-	if err := c.Bind(&setupRequest); err != nil {
+func (httpSvc *HttpService) sendTestPushNotificationHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid push device ID",
 		})
 	}
 
-	err := httpSvc.api.Setup(c.Request().Context(), &setupRequest)
-	if err != nil {
+	if err := httpSvc.api.SendTestPushNotification(c.Request().Context(), uint(id)); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to setup node: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to send test push notification: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
-
-func (httpSvc *HttpService) sendPaymentProbesHandler(c echo.Context) error {
-	var sendPaymentProbesRequest api.SendPaymentProbesRequest
-	if err := c.Bind(&sendPaymentProbesRequest); err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+
+func (httpSvc *HttpService) listDeadLettersHandler(c echo.Context) error {
+	deadLetters, err := httpSvc.api.ListDeadLetters(c.QueryParam("consumer"), c.QueryParam("state"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list dead letters: %s", err.Error()),
 		})
 	}
 
-	sendPaymentProbesResponse, err := httpSvc.api.SendPaymentProbes(c.Request().Context(), &sendPaymentProbesRequest)
+	return c.JSON(http.StatusOK, deadLetters)
+}
+
+func (httpSvc *HttpService) replayDeadLettersHandler(c echo.Context) error {
+	result, err := httpSvc.api.ReplayDeadLetters(c.Request().Context(), c.QueryParam("consumer"))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to send payment probes: %v", err),
+			Message: fmt.Sprintf("Failed to replay dead letters: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, sendPaymentProbesResponse)
+	return c.JSON(http.StatusOK, result)
 }
 
-func (httpSvc *HttpService) sendSpontaneousPaymentProbesHandler(c echo.Context) error {
-	var sendSpontaneousPaymentProbesRequest api.SendSpontaneousPaymentProbesRequest
-	if err := c.Bind(&sendSpontaneousPaymentProbesRequest); err != nil {
+func (httpSvc *HttpService) replayDeadLetterHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid dead letter ID",
 		})
 	}
 
-	sendSpontaneousPaymentProbesResponse, err := httpSvc.api.SendSpontaneousPaymentProbes(c.Request().Context(), &sendSpontaneousPaymentProbesRequest)
+	deadLetter, err := httpSvc.api.ReplayDeadLetter(c.Request().Context(), uint(id))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to send spontaneous payment probes: %v", err),
+			Message: fmt.Sprintf("Failed to replay dead letter: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, sendSpontaneousPaymentProbesResponse)
+	return c.JSON(http.StatusOK, deadLetter)
 }
 
-func (httpSvc *HttpService) getLogOutputHandler(c echo.Context) error {
-	var getLogRequest api.GetLogOutputRequest
-	if err := c.Bind(&getLogRequest); err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
-		})
-	}
-
-	logType := c.Param("type")
-	if logType != api.LogTypeNode && logType != api.LogTypeApp {
+func (httpSvc *HttpService) discardDeadLetterHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Invalid log type parameter: '%s'", logType),
+			Message: "Invalid dead letter ID",
 		})
 	}
 
-	getLogResponse, err := httpSvc.api.GetLogOutput(c.Request().Context(), logType, &getLogRequest)
-	if err != nil {
+	if err := httpSvc.api.DiscardDeadLetter(uint(id)); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to get log output: %v", err),
+			Message: fmt.Sprintf("Failed to discard dead letter: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, getLogResponse)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) getCustomNodeCommandsHandler(c echo.Context) error {
-	nodeCommandsResponse, err := httpSvc.api.GetCustomNodeCommands()
+func (httpSvc *HttpService) listAdminUsersHandler(c echo.Context) error {
+	adminUsers, err := httpSvc.api.ListAdminUsers()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to get node commands: %v", err),
+			Message: fmt.Sprintf("Failed to list admin users: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, nodeCommandsResponse)
+	return c.JSON(http.StatusOK, adminUsers)
 }
 
-func (httpSvc *HttpService) execCustomNodeCommandHandler(c echo.Context) error {
-	var execCommandRequest api.ExecuteCustomNodeCommandRequest
-	if err := c.Bind(&execCommandRequest); err != nil {
+func (httpSvc *HttpService) createAdminUserHandler(c echo.Context) error {
+	var createAdminUserRequest api.CreateAdminUserRequest
+	if err := c.Bind(&createAdminUserRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	execCommandResponse, err := httpSvc.api.ExecuteCustomNodeCommand(c.Request().Context(), execCommandRequest.Command)
+	adminUser, err := httpSvc.api.CreateAdminUser(&createAdminUserRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to execute command: %v", err),
+			Message: fmt.Sprintf("Failed to create admin user: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, execCommandResponse)
-}
-
-func (httpSvc *HttpService) logoutHandler(c echo.Context) error {
-	redirectUrl := httpSvc.cfg.GetEnv().GetBaseFrontendUrl()
-	if redirectUrl == "" {
-		redirectUrl = "/"
-	}
-
-	return c.Redirect(http.StatusFound, redirectUrl)
+	return c.JSON(http.StatusOK, adminUser)
 }
 
-func (httpSvc *HttpService) createBackupHandler(c echo.Context) error {
-	var backupRequest api.BasicBackupRequest
-	if err := c.Bind(&backupRequest); err != nil {
+func (httpSvc *HttpService) updateAdminUserHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid admin user ID",
 		})
 	}
 
-	if !httpSvc.cfg.CheckUnlockPassword(backupRequest.UnlockPassword) {
-		return c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Message: "Invalid password",
+	var updateAdminUserRequest api.UpdateAdminUserRequest
+	if err := c.Bind(&updateAdminUserRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	var buffer bytes.Buffer
-	err := httpSvc.api.CreateBackup(backupRequest.UnlockPassword, &buffer)
+	adminUser, err := httpSvc.api.UpdateAdminUser(uint(id), &updateAdminUserRequest)
 	if err != nil {
-		return c.String(500, fmt.Sprintf("Failed to create backup: %v", err))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update admin user: %s", err.Error()),
+		})
 	}
 
-	c.Response().Header().Set("Content-Type", "application/octet-stream")
-	c.Response().Header().Set("Content-Disposition", "attachment; filename=albyhub.bkp")
-	c.Response().WriteHeader(http.StatusOK)
-	c.Response().Write(buffer.Bytes())
-	return nil
+	return c.JSON(http.StatusOK, adminUser)
 }
 
-func (httpSvc *HttpService) restoreBackupHandler(c echo.Context) error {
-	info, err := httpSvc.api.GetInfo(c.Request().Context())
+func (httpSvc *HttpService) deleteAdminUserHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		return err
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid admin user ID",
+		})
 	}
-	if info.SetupCompleted {
-		return errors.New("setup already completed")
+
+	err = httpSvc.api.DeleteAdminUser(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete admin user: %s", err.Error()),
+		})
 	}
 
-	password := c.FormValue("unlockPassword")
+	return c.NoContent(http.StatusNoContent)
+}
 
-	fileHeader, err := c.FormFile("backup")
-	if err != nil {
+func (httpSvc *HttpService) adminUserLoginHandler(c echo.Context) error {
+	var loginRequest api.AdminUserLoginRequest
+	if err := c.Bind(&loginRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Failed to get backup file header: %v", err),
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	file, err := fileHeader.Open()
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to open backup file: %v", err),
+	adminUserId, ok := httpSvc.api.CheckAdminUserPassword(loginRequest.Name, loginRequest.Password)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: "Invalid name or password",
 		})
 	}
-	defer file.Close()
 
-	err = httpSvc.api.RestoreBackup(password, file)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to restore backup: %v", err),
+	if err := httpSvc.api.VerifyAdminUserTotp(adminUserId, loginRequest.TotpCode); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
-}
-
-func (httpSvc *HttpService) healthHandler(c echo.Context) error {
-	healthResponse, err := httpSvc.api.Health(c.Request().Context())
+	adminUser, err := httpSvc.api.GetAdminUser(adminUserId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to check node health: %v", err),
+			Message: fmt.Sprintf("Failed to get admin user: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, healthResponse)
-}
-
-func (httpSvc *HttpService) listSwapsHandler(c echo.Context) error {
-	swaps, err := httpSvc.api.ListSwaps()
-
+	token, err := httpSvc.createAdminUserJWT(c, loginRequest.TokenExpiryDays, adminUser)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, swaps)
+	return c.JSON(http.StatusOK, &authTokenResponse{
+		Token: token,
+	})
 }
 
-func (httpSvc *HttpService) lookupSwapHandler(c echo.Context) error {
-	swap, err := httpSvc.api.LookupSwap(c.Param("swapId"))
+func (httpSvc *HttpService) adminUserShowHandler(c echo.Context) error {
+	adminUser, err := httpSvc.api.GetAdminUser(c.Get("adminUserId").(uint))
 	if err != nil {
 		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Message: "App not found",
+			Message: err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, swap)
+	return c.JSON(http.StatusOK, adminUser)
 }
 
-func (httpSvc *HttpService) getSwapOutInfoHandler(c echo.Context) error {
-	swapOutFeesResponse, err := httpSvc.api.GetSwapOutInfo()
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to get swap out info: %v", err),
-		})
+func (httpSvc *HttpService) adminUserAppsListHandler(c echo.Context) error {
+	adminUserId := c.Get("adminUserId").(uint)
+
+	limit := uint64(0)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
 	}
 
-	return c.JSON(http.StatusOK, swapOutFeesResponse)
-}
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
 
-func (httpSvc *HttpService) getSwapInInfoHandler(c echo.Context) error {
-	swapOutFeesResponse, err := httpSvc.api.GetSwapInInfo()
+	apps, err := httpSvc.api.ListApps(limit, offset, api.ListAppsFilters{AdminUserId: &adminUserId}, c.QueryParam("order_by"))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to get swap in info: %v", err),
+			Message: err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, swapOutFeesResponse)
+	return c.JSON(http.StatusOK, apps)
 }
 
-func (httpSvc *HttpService) initiateSwapOutHandler(c echo.Context) error {
-	var initiateSwapOutRequest api.InitiateSwapRequest
-	if err := c.Bind(&initiateSwapOutRequest); err != nil {
+func (httpSvc *HttpService) adminUserAppsCreateHandler(c echo.Context) error {
+	var requestData api.CreateAppRequest
+	if err := c.Bind(&requestData); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	swapOutResponse, err := httpSvc.api.InitiateSwapOut(c.Request().Context(), &initiateSwapOutRequest)
+	responseBody, err := httpSvc.api.CreateAdminUserApp(c.Get("adminUserId").(uint), &requestData)
 	if err != nil {
+		logger.Logger.WithField("requestData", requestData).WithError(err).Error("Failed to save app")
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to initiate swap out: %v", err),
+			Message: fmt.Sprintf("Failed to save app: %v", err),
 		})
 	}
 
-	return c.JSON(http.StatusOK, swapOutResponse)
+	return c.JSON(http.StatusOK, responseBody)
 }
 
-func (httpSvc *HttpService) initiateSwapInHandler(c echo.Context) error {
-	var initiateSwapInRequest api.InitiateSwapRequest
-	if err := c.Bind(&initiateSwapInRequest); err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+// getAdminUserApp returns the app of the request, if the admin user manages it
+func (httpSvc *HttpService) getAdminUserApp(c echo.Context) (*db.App, error) {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
 		})
 	}
 
-	txId, err := httpSvc.api.InitiateSwapIn(c.Request().Context(), &initiateSwapInRequest)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to initiate swap in: %v", err),
+	dbApp := httpSvc.appsSvc.GetAppById(uint(appId))
+	if dbApp == nil || !httpSvc.api.CanAdminUserManageApp(c.Get("adminUserId").(uint), dbApp.ID) {
+		return nil, c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App not found",
 		})
 	}
+	return dbApp, nil
+}
 
-	return c.JSON(http.StatusOK, txId)
+func (httpSvc *HttpService) adminUserAppsShowHandler(c echo.Context) error {
+	dbApp, err := httpSvc.getAdminUserApp(c)
+	if dbApp == nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, httpSvc.api.GetApp(dbApp))
 }
 
-func (httpSvc *HttpService) refundSwapHandler(c echo.Context) error {
-	var refundSwapInRequest api.RefundSwapRequest
-	if err := c.Bind(&refundSwapInRequest); err != nil {
+func (httpSvc *HttpService) adminUserAppsUpdateHandler(c echo.Context) error {
+	dbApp, err := httpSvc.getAdminUserApp(c)
+	if dbApp == nil {
+		return err
+	}
+
+	var requestData api.UpdateAppRequest
+	if err := c.Bind(&requestData); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.RefundSwap(&refundSwapInRequest)
-	if err != nil {
+	if err := httpSvc.api.UpdateApp(dbApp, &requestData); err != nil {
+		logger.Logger.WithError(err).Error("Failed to update app")
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to update app: %v", err),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) swapMnemonicHandler(c echo.Context) error {
-	mnemonic := httpSvc.api.GetSwapMnemonic()
-	return c.JSON(http.StatusOK, mnemonic)
-}
+func (httpSvc *HttpService) adminUserAppsDeleteHandler(c echo.Context) error {
+	dbApp, err := httpSvc.getAdminUserApp(c)
+	if dbApp == nil {
+		return err
+	}
 
-func (httpSvc *HttpService) getAutoSwapConfigHandler(c echo.Context) error {
-	getAutoSwapConfigResponse, err := httpSvc.api.GetAutoSwapConfig()
-	if err != nil {
+	if err := httpSvc.api.DeleteApp(dbApp); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to get swap settings: %v", err),
+			Message: "Failed to delete app",
 		})
 	}
-
-	return c.JSON(http.StatusOK, getAutoSwapConfigResponse)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) enableAutoSwapOutHandler(c echo.Context) error {
-	var enableAutoSwapRequest api.EnableAutoSwapRequest
-	if err := c.Bind(&enableAutoSwapRequest); err != nil {
+func (httpSvc *HttpService) appLightningAddressHandler(c echo.Context) error {
+	appId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid App ID",
+		})
+	}
+
+	var setAppLightningAddressRequest api.SetAppLightningAddressRequest
+	if err := c.Bind(&setAppLightningAddressRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.EnableAutoSwapOut(c.Request().Context(), &enableAutoSwapRequest)
+	err = httpSvc.api.SetAppLightningAddress(uint(appId), setAppLightningAddressRequest.Username)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to save swap settings: %v", err),
+			Message: fmt.Sprintf("Failed to set lightning address: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) disableAutoSwapOutHandler(c echo.Context) error {
-	err := httpSvc.api.DisableAutoSwap()
+func (httpSvc *HttpService) appsBulkUpdateHandler(c echo.Context) error {
+	var bulkUpdateAppsRequest api.BulkUpdateAppsRequest
+	if err := c.Bind(&bulkUpdateAppsRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
+	bulkUpdateAppsResponse, err := httpSvc.api.BulkUpdateApps(&bulkUpdateAppsRequest)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update apps: %s", err.Error()),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, bulkUpdateAppsResponse)
 }
 
-func (httpSvc *HttpService) setNodeAliasHandler(c echo.Context) error {
-	var setNodeAliasRequest api.SetNodeAliasRequest
-	if err := c.Bind(&setNodeAliasRequest); err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
-		})
+// metricsAuthMiddleware requires the configured metrics token as bearer token, if there is one
+func (httpSvc *HttpService) metricsAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		metricsToken := httpSvc.cfg.GetEnv().MetricsToken
+		if metricsToken != "" && subtle.ConstantTimeCompare([]byte(c.Request().Header.Get("Authorization")), []byte("Bearer "+metricsToken)) != 1 {
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Message: "Invalid metrics token",
+			})
+		}
+		return next(c)
 	}
+}
 
-	err := httpSvc.api.SetNodeAlias(setNodeAliasRequest.NodeAlias)
+// lnurlErrorResponse is the error format LNURL wallets expect (LUD-06)
+type lnurlErrorResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+func (httpSvc *HttpService) lnurlPayHandler(c echo.Context) error {
+	lnurlPayResponse, err := httpSvc.api.GetLnurlPay(c.Param("username"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to set node alias: %s", err.Error()),
+		return c.JSON(http.StatusNotFound, lnurlErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, lnurlPayResponse)
 }
 
-func (httpSvc *HttpService) forwardsHandler(c echo.Context) error {
-	forwards, err := httpSvc.api.GetForwards()
+func (httpSvc *HttpService) lnurlPayCallbackHandler(c echo.Context) error {
+	amountMsat, err := strconv.ParseUint(c.QueryParam("amount"), 10, 64)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to get forwards: %s", err.Error()),
+		return c.JSON(http.StatusBadRequest, lnurlErrorResponse{
+			Status: "ERROR",
+			Reason: "Invalid amount",
 		})
 	}
 
-	return c.JSON(http.StatusOK, forwards)
+	lnurlPayInvoiceResponse, err := httpSvc.api.GetLnurlPayInvoice(c.Request().Context(), c.Param("username"), amountMsat, c.QueryParam("comment"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, lnurlErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, lnurlPayInvoiceResponse)
 }
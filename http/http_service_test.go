@@ -1,21 +1,30 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/getAlby/hub/api"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
+	hubdb "github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
+	"github.com/getAlby/hub/service"
 	"github.com/getAlby/hub/tests/db"
 	"github.com/getAlby/hub/tests/mocks"
+	"github.com/getAlby/hub/totp"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -34,7 +43,13 @@ func TestUnlock_IncorrectPassword(t *testing.T) {
 
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
 	mockConfig.On("CheckUnlockPassword", "123").Return(false)
+	mockConfig.On("Get", "DuressAppId", "").Return("", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
 	mockSvc.On("GetConfig").Return(mockConfig)
@@ -54,6 +69,89 @@ func TestUnlock_IncorrectPassword(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
 	mockConfig.AssertNotCalled(t, "GetJWTSecret")
+
+	// failed logins are part of the admin audit log
+	var auditLogs []hubdb.AdminAuditLog
+	require.NoError(t, gormDb.Find(&auditLogs).Error)
+	require.Len(t, auditLogs, 1)
+	assert.Equal(t, "POST /api/unlock", auditLogs[0].Action)
+	assert.Equal(t, "anonymous", auditLogs[0].Actor)
+	assert.JSONEq(t, `{"status":401}`, auditLogs[0].Details)
+}
+
+func TestUnlock_TotpRequired(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("true", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	jsonBody, _ := json.Marshal(api.UnlockRequest{UnlockPassword: "123", Permission: "full"})
+	req := httptest.NewRequest(http.MethodPost, "/api/unlock", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "two-factor code required")
+	mockConfig.AssertNotCalled(t, "GetJWTSecret")
+}
+
+func TestCreateBackup_TotpRequired(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("true", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	jsonBody, _ := json.Marshal(api.BasicBackupRequest{UnlockPassword: "123"})
+	req := httptest.NewRequest(http.MethodPost, "/api/backup", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "two-factor code required")
 }
 
 func TestUnlock_UnknownPermission(t *testing.T) {
@@ -68,7 +166,13 @@ func TestUnlock_UnknownPermission(t *testing.T) {
 
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
 	mockSvc.On("GetConfig").Return(mockConfig)
@@ -102,6 +206,11 @@ func TestGetApps_NoToken(t *testing.T) {
 
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
 	mockSvc.On("GetConfig").Return(mockConfig)
@@ -131,7 +240,13 @@ func TestGetApps_ReadonlyPermission(t *testing.T) {
 
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret")
 
 	mockSvc.On("GetDB").Return(gormDb)
@@ -172,6 +287,52 @@ func TestGetApps_ReadonlyPermission(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec2.Code)
 }
 
+func TestOwnerRoute_ReadonlyPermission(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("", nil)
+	mockConfig.On("GetJWTSecret").Return("dummy secret")
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	jsonBody, _ := json.Marshal(api.UnlockRequest{UnlockPassword: "123", Permission: "readonly"})
+	req := httptest.NewRequest(http.MethodPost, "/api/unlock", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var unlockResponse authTokenResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &unlockResponse))
+
+	// a read-only session of the unlock password is not the owner
+	req = httptest.NewRequest(http.MethodGet, "/api/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+unlockResponse.Token)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
 func TestGetApps_FullPermission(t *testing.T) {
 	e := echo.New()
 	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
@@ -184,7 +345,13 @@ func TestGetApps_FullPermission(t *testing.T) {
 
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret")
 
 	mockSvc.On("GetDB").Return(gormDb)
@@ -237,6 +404,11 @@ func TestCreateApp_NoToken(t *testing.T) {
 
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
 	mockSvc.On("GetConfig").Return(mockConfig)
@@ -269,7 +441,13 @@ func TestCreateApp_FullPermission(t *testing.T) {
 
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret")
 	mockConfig.On("GetRelayUrls").Return([]string{})
 
@@ -333,7 +511,13 @@ func TestCreateApp_ReadonlyPermission(t *testing.T) {
 
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret")
 
 	mockKeys := mocks.NewMockKeys(t)
@@ -381,3 +565,486 @@ func TestCreateApp_ReadonlyPermission(t *testing.T) {
 
 	assert.Equal(t, http.StatusForbidden, rec2.Code)
 }
+
+func TestAdminUser_ScopedAccess(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockEventPublisher := events.NewEventPublisher()
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+	mockConfig.On("GetJWTSecret").Return("dummy secret")
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, mockEventPublisher)
+	httpSvc.RegisterSharedRoutes(e)
+
+	_, err = httpSvc.api.CreateAdminUser(&api.CreateAdminUserRequest{Name: "alice", Password: "correct horse"})
+	require.NoError(t, err)
+
+	jsonBody, _ := json.Marshal(api.AdminUserLoginRequest{Name: "alice", Password: "correct horse"})
+	req := httptest.NewRequest(http.MethodPost, "/api/admin-user/login", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var loginResponse authTokenResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &loginResponse))
+	assert.NotEmpty(t, loginResponse.Token)
+
+	for route, expectedCode := range map[string]int{
+		"/api/admin-user/apps": http.StatusOK,
+		"/api/apps":            http.StatusForbidden,
+		"/api/admin-users":     http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, route, nil)
+		req.Header.Set("Authorization", "Bearer "+loginResponse.Token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, expectedCode, rec.Code, route)
+	}
+}
+
+func TestAdminUser_Roles(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+	mockConfig.On("GetJWTSecret").Return("dummy secret")
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	login := func(name string, role string) (*api.AdminUser, string) {
+		adminUser, err := httpSvc.api.CreateAdminUser(&api.CreateAdminUserRequest{Name: name, Password: "correct horse", Role: role})
+		require.NoError(t, err)
+
+		postLogin := func(loginRequest api.AdminUserLoginRequest, remoteAddr string) *httptest.ResponseRecorder {
+			jsonBody, _ := json.Marshal(loginRequest)
+			req := httptest.NewRequest(http.MethodPost, "/api/admin-user/login", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+			// logins are rate limited per IP address
+			req.RemoteAddr = remoteAddr + strconv.Itoa(int(adminUser.ID)) + ":1234"
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		loginRequest := api.AdminUserLoginRequest{Name: name, Password: "correct horse"}
+		if adminUser.TotpUri != "" {
+			// owners and operators need their two-factor code
+			rec := postLogin(loginRequest, "198.51.100.")
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Contains(t, rec.Body.String(), "two-factor code required")
+
+			totpUri, err := url.Parse(adminUser.TotpUri)
+			require.NoError(t, err)
+			loginRequest.TotpCode, err = totp.Code(totpUri.Query().Get("secret"), totp.Step(time.Now()))
+			require.NoError(t, err)
+		}
+		rec := postLogin(loginRequest, "192.0.2.")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var loginResponse authTokenResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &loginResponse))
+		return adminUser, loginResponse.Token
+	}
+	serve := func(method string, route string, token string) int {
+		req := httptest.NewRequest(method, route, bytes.NewBufferString(`{"name":"key","scopes":["apps"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	owner, ownerToken := login("owner", api.ADMIN_USER_ROLE_OWNER)
+	operator, operatorToken := login("operator", api.ADMIN_USER_ROLE_OPERATOR)
+	viewer, viewerToken := login("viewer", api.ADMIN_USER_ROLE_VIEWER)
+	assert.NotEmpty(t, owner.TotpUri)
+	assert.NotEmpty(t, operator.TotpUri)
+	assert.Empty(t, viewer.TotpUri)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/api-keys", viewerToken))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/api-keys", viewerToken))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/api-keys", operatorToken))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/api-keys", ownerToken))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/admin-user/apps", viewerToken))
+
+	for _, tc := range []struct {
+		method string
+		route  string
+	}{
+		{http.MethodPatch, "/api/settings"},
+		{http.MethodPost, "/api/stop"},
+		{http.MethodPost, "/api/command"},
+		{http.MethodPost, "/api/app-configs/export"},
+		{http.MethodPost, "/api/backup-targets"},
+		{http.MethodPatch, "/api/backup-targets/1"},
+		{http.MethodDelete, "/api/backup-targets/1"},
+	} {
+		assert.Equal(t, http.StatusForbidden, serve(tc.method, tc.route, operatorToken), "operator %s %s", tc.method, tc.route)
+		assert.Equal(t, http.StatusForbidden, serve(tc.method, tc.route, viewerToken), "viewer %s %s", tc.method, tc.route)
+	}
+
+	// role changes apply to existing sessions
+	appManagerRole := api.ADMIN_USER_ROLE_APP_MANAGER
+	_, err = httpSvc.api.UpdateAdminUser(viewer.ID, &api.UpdateAdminUserRequest{Role: &appManagerRole})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/api-keys", viewerToken))
+
+	require.NoError(t, httpSvc.api.DeleteAdminUser(operator.ID))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/api-keys", operatorToken))
+}
+
+func TestSessions(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TotpEnabled", "").Return("", nil)
+	mockConfig.On("GetJWTSecret").Return("dummy secret")
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	unlock := func(remoteAddr string) string {
+		jsonBody, _ := json.Marshal(api.UnlockRequest{UnlockPassword: "123", Permission: "full"})
+		req := httptest.NewRequest(http.MethodPost, "/api/unlock", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "test browser")
+		// logins are rate limited per IP address
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var unlockResponse authTokenResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &unlockResponse))
+		return unlockResponse.Token
+	}
+	serve := func(method string, route string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, route, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	laptopToken := unlock("192.0.2.1:1234")
+	phoneToken := unlock("192.0.2.2:1234")
+	tabletToken := unlock("192.0.2.3:1234")
+
+	rec := serve(http.MethodGet, "/api/sessions", laptopToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var sessions []api.Session
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sessions))
+	require.Len(t, sessions, 3)
+	assert.Equal(t, "192.0.2.1", sessions[2].IpAddress)
+	assert.Equal(t, "test browser", sessions[2].UserAgent)
+	assert.True(t, sessions[2].Current)
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/sessions/"+strconv.Itoa(int(sessions[1].ID)), laptopToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/apps", phoneToken).Code)
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/session", tabletToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/apps", tabletToken).Code)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/apps", laptopToken).Code)
+}
+
+func TestMetrics(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{
+		MetricsEnabled: true,
+		MetricsToken:   "metrics-token",
+	})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	metrics.NewEventConsumer().ConsumeEvent(context.Background(), &events.Event{
+		Event: "nwc_permission_denied",
+		Properties: map[string]interface{}{
+			"code": constants.ERROR_QUOTA_EXCEEDED,
+		},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer metrics-token")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `albyhub_permission_denials_total{code="QUOTA_EXCEEDED"}`)
+	assert.Contains(t, rec.Body.String(), `albyhub_db_query_duration_seconds_count{operation="query"}`)
+}
+
+func TestAutomationApi_ApiKeyScopes(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	createApiKeyResponse, err := httpSvc.api.CreateApiKey(&api.CreateApiKeyRequest{
+		Name:   "script",
+		Scopes: []string{api.API_KEY_SCOPE_APPS},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/automation/apps", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/automation/apps", nil)
+	req.Header.Set("Authorization", "Bearer "+createApiKeyResponse.Key)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/automation/invoices", nil)
+	req.Header.Set("Authorization", "Bearer "+createApiKeyResponse.Key)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// api keys cannot be used for the admin API
+	req = httptest.NewRequest(http.MethodGet, "/api/apps", nil)
+	req.Header.Set("Authorization", "Bearer "+createApiKeyResponse.Key)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	require.NoError(t, httpSvc.api.RevokeApiKey(createApiKeyResponse.ID))
+	req = httptest.NewRequest(http.MethodGet, "/api/automation/apps", nil)
+	req.Header.Set("Authorization", "Bearer "+createApiKeyResponse.Key)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestEventStream(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	eventPublisher := events.NewEventPublisher()
+	httpSvc := NewHttpService(mockSvc, eventPublisher)
+	httpSvc.RegisterSharedRoutes(e)
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	createApiKeyResponse, err := httpSvc.api.CreateApiKey(&api.CreateApiKeyRequest{
+		Name:   "dashboard",
+		Scopes: []string{api.API_KEY_SCOPE_TRANSACTIONS},
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/automation/events/stream?events=nwc_payment_received", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+createApiKeyResponse.Key)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(res.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, ": connected\n", line)
+	_, err = reader.ReadString('\n')
+	require.NoError(t, err)
+
+	// filtered out
+	eventPublisher.Publish(&events.Event{
+		Event: "nwc_payment_sent",
+	})
+	// never streamed as it contains the channel backups
+	eventPublisher.Publish(&events.Event{
+		Event: "nwc_backup_channels",
+	})
+	eventPublisher.Publish(&events.Event{
+		Event: "nwc_payment_received",
+		Properties: &hubdb.Transaction{
+			Type:        constants.TRANSACTION_TYPE_INCOMING,
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			PaymentHash: "payment-hash",
+			AmountMsat:  1000,
+		},
+	})
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: nwc_payment_received\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	data, found := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+	require.True(t, found)
+
+	var streamEvent struct {
+		Event      string          `json:"event"`
+		Properties api.Transaction `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &streamEvent))
+	assert.Equal(t, "nwc_payment_received", streamEvent.Event)
+	assert.Equal(t, "payment-hash", streamEvent.Properties.PaymentHash)
+	assert.Equal(t, uint64(1000), streamEvent.Properties.Amount)
+}
+
+func TestHealthProbes(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+	// the hub is locked
+	mockSvc.On("GetLNClient").Return(nil)
+	mockSvc.On("GetStartupState").Return("")
+	mockSvc.On("GetRelayStatuses").Return([]service.RelayStatus{})
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var componentHealth api.ComponentHealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &componentHealth))
+	assert.Equal(t, api.ComponentStatusDown, componentHealth.Status)
+	assert.Equal(t, api.ComponentStatusOk, componentHealth.Components["database"].Status)
+	assert.Equal(t, api.ComponentStatusDown, componentHealth.Components["lnclient"].Status)
+	assert.Equal(t, "Node is not running", componentHealth.Components["lnclient"].Message)
+	assert.Equal(t, api.ComponentStatusDown, componentHealth.Components["relays"].Status)
+
+	req = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/utils"
+)
+
+// publicRoutePrefixes are reachable from any network, as they are used by other
+// wallets, sub-wallet owners or monitoring rather than the hub owner
+var publicRoutePrefixes = []string{
+	"/healthz",
+	"/readyz",
+	"/metrics",
+	"/.well-known/lnurlp/",
+	"/api/lnurlp/",
+	"/api/subwallet/",
+}
+
+// networkPolicy restricts the admin API to the networks configured in the settings.
+// The frontend itself and public routes stay reachable from anywhere.
+type networkPolicy struct {
+	cfg             config.Config
+	allowedNetworks []*net.IPNet
+	mutex           sync.RWMutex
+}
+
+func newNetworkPolicy(cfg config.Config) *networkPolicy {
+	policy := &networkPolicy{cfg: cfg}
+	policy.reload()
+	return policy
+}
+
+// reload reads the allowed networks again after the settings were updated
+func (p *networkPolicy) reload() {
+	allowedNetworks, _ := p.cfg.Get(config.AdminAllowedNetworksKey, "")
+	parsedNetworks, err := utils.ParseNetworks(allowedNetworks)
+	if err != nil {
+		// invalid networks are rejected when saving the settings
+		logger.Logger.WithError(err).Error("Failed to parse admin allowed networks")
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.allowedNetworks = parsedNetworks
+}
+
+func (p *networkPolicy) isAllowed(ip string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return len(p.allowedNetworks) == 0 || utils.NetworksContain(p.allowedNetworks, ip)
+}
+
+// newIPExtractor only honours the X-Forwarded-For header of requests from trusted proxies,
+// otherwise clients could choose their IP address and bypass the network policy and IP bans.
+// A reverse proxy on the same host is always trusted.
+func newIPExtractor(appConfig *config.AppConfig) echo.IPExtractor {
+	if !appConfig.TrustProxyHeaders {
+		return echo.ExtractIPDirect()
+	}
+
+	trustOptions := []echo.TrustOption{
+		echo.TrustLoopback(true),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	trustedProxies, err := utils.ParseNetworks(appConfig.TrustedProxies)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Ignoring invalid trusted proxies")
+	}
+	for _, trustedProxy := range trustedProxies {
+		trustOptions = append(trustOptions, echo.TrustIPRange(trustedProxy))
+	}
+	return echo.ExtractIPFromXFFHeader(trustOptions...)
+}
+
+func isAdminRoute(path string) bool {
+	for _, prefix := range publicRoutePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return strings.HasPrefix(path, "/api/") || path == "/logout"
+}
+
+func (p *networkPolicy) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// use the matched route rather than the request path, which is not normalized
+		if !isAdminRoute(c.Path()) || p.isAllowed(c.RealIP()) {
+			return next(c)
+		}
+		return c.JSON(http.StatusForbidden, ErrorResponse{
+			Message: "The admin API is not available from your network",
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/tests/db"
+	"github.com/getAlby/hub/tests/mocks"
+)
+
+func newNetworkPolicyTestServer(t *testing.T, appConfig *config.AppConfig) *echo.Echo {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(appConfig)
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("10.0.0.0/8,tailscale", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+	return e
+}
+
+func serveForwardedFrom(e *echo.Echo, remoteAddr string, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/apps", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	req.Header.Set("X-Real-IP", forwardedFor)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestNetworkPolicy(t *testing.T) {
+	e := newNetworkPolicyTestServer(t, &config.AppConfig{})
+
+	assert.NotEqual(t, http.StatusForbidden, serveFrom(e, "10.1.2.3:1234", ""))
+	assert.NotEqual(t, http.StatusForbidden, serveFrom(e, "100.101.102.103:1234", ""))
+	assert.Equal(t, http.StatusForbidden, serveFrom(e, "192.0.2.1:1234", ""))
+
+	// lightning addresses stay reachable from anywhere
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/lnurlp/alice", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.NotEqual(t, http.StatusForbidden, rec.Code)
+}
+
+func TestNetworkPolicy_SpoofedHeaders(t *testing.T) {
+	e := newNetworkPolicyTestServer(t, &config.AppConfig{})
+	assert.Equal(t, http.StatusForbidden, serveForwardedFrom(e, "192.0.2.1:1234", "10.0.0.1"))
+
+	e = newNetworkPolicyTestServer(t, &config.AppConfig{TrustProxyHeaders: true})
+	// only proxies may set the client IP address
+	assert.Equal(t, http.StatusForbidden, serveForwardedFrom(e, "192.0.2.1:1234", "10.0.0.1"))
+	assert.NotEqual(t, http.StatusForbidden, serveForwardedFrom(e, "127.0.0.1:1234", "10.0.0.1"))
+	assert.Equal(t, http.StatusForbidden, serveForwardedFrom(e, "127.0.0.1:1234", "192.0.2.1"))
+
+	e = newNetworkPolicyTestServer(t, &config.AppConfig{TrustProxyHeaders: true, TrustedProxies: "172.16.0.0/12"})
+	assert.NotEqual(t, http.StatusForbidden, serveForwardedFrom(e, "172.17.0.2:1234", "10.0.0.1"))
+	assert.Equal(t, http.StatusForbidden, serveForwardedFrom(e, "192.0.2.2:1234", "10.0.0.1"))
+}
+
+func TestIsAdminRoute(t *testing.T) {
+	assert.True(t, isAdminRoute("/api/apps"))
+	assert.True(t, isAdminRoute("/api/unlock"))
+	assert.True(t, isAdminRoute("/logout"))
+	assert.False(t, isAdminRoute("/api/lnurlp/:username/callback"))
+	assert.False(t, isAdminRoute("/api/subwallet/login"))
+	assert.False(t, isAdminRoute("/healthz"))
+	assert.False(t, isAdminRoute("/*"))
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/utils"
+)
+
+// limiters are removed after being unused for this long
+const rateLimiterExpiry = 10 * time.Minute
+
+// sessionRejectedKey marks requests with a correctly signed token whose session expired or was
+// revoked. They are not attempts to guess credentials and do not count towards a ban.
+const sessionRejectedKey = "sessionRejected"
+
+type authFailures struct {
+	count       uint
+	firstAt     time.Time
+	bannedUntil time.Time
+}
+
+// abuseProtection rate limits requests per IP address, api key and session and rejects
+// requests from banned IP addresses. IP addresses are banned automatically after repeated
+// authentication failures.
+type abuseProtection struct {
+	cfg            config.Config
+	ipLimiter      middleware.RateLimiterStore
+	apiKeyLimiter  middleware.RateLimiterStore
+	sessionLimiter middleware.RateLimiterStore
+	bannedNetworks []*net.IPNet
+	settingsMutex  sync.RWMutex
+	banThreshold   uint
+	banDuration    time.Duration
+	authFailures   map[string]*authFailures
+	mutex          sync.Mutex
+}
+
+func newAbuseProtection(cfg config.Config) *abuseProtection {
+	p := &abuseProtection{
+		cfg:          cfg,
+		banThreshold: cfg.GetEnv().AuthFailureBanThreshold,
+		banDuration:  time.Duration(cfg.GetEnv().AuthFailureBanMinutes) * time.Minute,
+		authFailures: map[string]*authFailures{},
+	}
+	p.reload()
+	return p
+}
+
+// reload applies the rate limits and banned IPs again after the settings were updated.
+// Values from the settings take precedence over the environment.
+func (p *abuseProtection) reload() {
+	appConfig := p.cfg.GetEnv()
+	ipLimiter := newRateLimiterStore(config.GetUintSetting(p.cfg, config.RateLimitIpPerMinuteKey, appConfig.RateLimitIpPerMinute))
+	apiKeyLimiter := newRateLimiterStore(config.GetUintSetting(p.cfg, config.RateLimitApiKeyPerMinuteKey, appConfig.RateLimitApiKeyPerMinute))
+	sessionLimiter := newRateLimiterStore(config.GetUintSetting(p.cfg, config.RateLimitSessionPerMinuteKey, appConfig.RateLimitSessionPerMinute))
+	bannedNetworks := parseBannedIps(config.GetStringSetting(p.cfg, config.BannedIpsKey, appConfig.BannedIps))
+
+	p.settingsMutex.Lock()
+	defer p.settingsMutex.Unlock()
+	p.ipLimiter = ipLimiter
+	p.apiKeyLimiter = apiKeyLimiter
+	p.sessionLimiter = sessionLimiter
+	p.bannedNetworks = bannedNetworks
+}
+
+func (p *abuseProtection) limiters() (ipLimiter, apiKeyLimiter, sessionLimiter middleware.RateLimiterStore) {
+	p.settingsMutex.RLock()
+	defer p.settingsMutex.RUnlock()
+	return p.ipLimiter, p.apiKeyLimiter, p.sessionLimiter
+}
+
+func newRateLimiterStore(requestsPerMinute uint) middleware.RateLimiterStore {
+	if requestsPerMinute == 0 {
+		return nil
+	}
+	return middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(float64(requestsPerMinute) / 60),
+		Burst:     int(requestsPerMinute),
+		ExpiresIn: rateLimiterExpiry,
+	})
+}
+
+// parseBannedIps parses a comma-separated list of IP addresses and CIDR ranges
+func parseBannedIps(bannedIps string) []*net.IPNet {
+	bannedNetworks := []*net.IPNet{}
+	for _, bannedIp := range strings.Split(bannedIps, ",") {
+		bannedIp = strings.TrimSpace(bannedIp)
+		if bannedIp == "" {
+			continue
+		}
+		bannedNetwork, err := utils.ParseNetwork(bannedIp)
+		if err != nil {
+			logger.Logger.WithField("ip", bannedIp).WithError(err).Error("Ignoring invalid banned IP")
+			continue
+		}
+		bannedNetworks = append(bannedNetworks, bannedNetwork...)
+	}
+	return bannedNetworks
+}
+
+func (p *abuseProtection) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// probes are polled frequently by the orchestrator
+		if c.Path() == "/healthz" || c.Path() == "/readyz" {
+			return next(c)
+		}
+
+		ip := c.RealIP()
+		if p.isBanned(ip) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: "Your IP address is banned",
+			})
+		}
+
+		token, _ := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		if !p.allowRequest(ip, token) {
+			return tooManyRequests(c)
+		}
+
+		err := next(c)
+
+		// errors, e.g. of the JWT middleware, are only written to the response after the middleware chain
+		var httpError *echo.HTTPError
+		if c.Response().Status == http.StatusUnauthorized || (errors.As(err, &httpError) && httpError.Code == http.StatusUnauthorized) {
+			if !isSessionRejection(c, err) {
+				p.recordAuthFailure(ip)
+			}
+		}
+		return err
+	}
+}
+
+// allowRequest applies the rate limits of the IP address and of the api key or session token, if any
+func (p *abuseProtection) allowRequest(ip string, token string) bool {
+	ipLimiter, apiKeyLimiter, sessionLimiter := p.limiters()
+	// the IP limit also applies to authenticated requests, otherwise it could be
+	// bypassed by sending a different invalid token with every request
+	if !allow(ipLimiter, ip) {
+		return false
+	}
+	if token == "" {
+		return true
+	}
+	limiter := sessionLimiter
+	if strings.HasPrefix(token, "hub_") {
+		limiter = apiKeyLimiter
+	}
+	// do not keep the tokens themselves in memory
+	tokenHash := sha256.Sum256([]byte(token))
+	return allow(limiter, hex.EncodeToString(tokenHash[:]))
+}
+
+// isSessionRejection reports whether the request was rejected only because its session ended.
+// The signature of a token is verified before its expiry, so expired tokens were issued by the hub.
+func isSessionRejection(c echo.Context, err error) bool {
+	rejected, _ := c.Get(sessionRejectedKey).(bool)
+	return rejected || errors.Is(err, jwt.ErrTokenExpired)
+}
+
+func allow(limiter middleware.RateLimiterStore, identifier string) bool {
+	if limiter == nil {
+		return true
+	}
+	allowed, err := limiter.Allow(identifier)
+	return err == nil && allowed
+}
+
+func tooManyRequests(c echo.Context) error {
+	return c.JSON(http.StatusTooManyRequests, ErrorResponse{
+		Message: "Too many requests",
+	})
+}
+
+func (p *abuseProtection) isBanned(ip string) bool {
+	p.settingsMutex.RLock()
+	bannedNetworks := p.bannedNetworks
+	p.settingsMutex.RUnlock()
+	if utils.NetworksContain(bannedNetworks, ip) {
+		return true
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	failures, ok := p.authFailures[ip]
+	return ok && time.Now().Before(failures.bannedUntil)
+}
+
+// recordAuthFailure bans the IP address once it failed to authenticate too often within the ban duration
+func (p *abuseProtection) recordAuthFailure(ip string) {
+	if p.banThreshold == 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	// forget old failures, which also keeps the map from growing forever
+	for failedIp, failures := range p.authFailures {
+		if now.Sub(failures.firstAt) > p.banDuration && now.After(failures.bannedUntil) {
+			delete(p.authFailures, failedIp)
+		}
+	}
+
+	failures, ok := p.authFailures[ip]
+	if !ok {
+		failures = &authFailures{firstAt: now}
+		p.authFailures[ip] = failures
+	}
+	failures.count++
+	if failures.count >= p.banThreshold {
+		failures.bannedUntil = now.Add(p.banDuration)
+		logger.Logger.WithFields(logrus.Fields{
+			"ip":           ip,
+			"failures":     failures.count,
+			"banned_until": failures.bannedUntil,
+		}).Warn("Banned IP address after repeated authentication failures")
+		// start counting again once the ban is over
+		failures.count = 0
+		failures.firstAt = failures.bannedUntil
+	}
+}
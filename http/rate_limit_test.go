@@ -0,0 +1,162 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/tests/db"
+	"github.com/getAlby/hub/tests/mocks"
+)
+
+func newAbuseProtectionTestServer(t *testing.T, appConfig *config.AppConfig) *echo.Echo {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.CloseDB(gormDb) })
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(appConfig)
+	mockConfig.On("Get", "AdminAllowedNetworks", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "BannedIps", "").Return("", nil)
+	mockConfig.On("GetJWTSecret").Return("dummy secret").Maybe()
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, events.NewEventPublisher())
+	httpSvc.RegisterSharedRoutes(e)
+	return e
+}
+
+func serveFrom(e *echo.Echo, remoteAddr string, authorization string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/apps", nil)
+	req.RemoteAddr = remoteAddr
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAbuseProtection_IpRateLimit(t *testing.T) {
+	e := newAbuseProtectionTestServer(t, &config.AppConfig{
+		RateLimitIpPerMinute: 2,
+	})
+
+	assert.NotEqual(t, http.StatusTooManyRequests, serveFrom(e, "192.0.2.1:1234", ""))
+	assert.NotEqual(t, http.StatusTooManyRequests, serveFrom(e, "192.0.2.1:1234", ""))
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom(e, "192.0.2.1:1234", ""))
+	// other clients are not affected
+	assert.NotEqual(t, http.StatusTooManyRequests, serveFrom(e, "192.0.2.2:1234", ""))
+}
+
+func TestAbuseProtection_ApiKeyRateLimit(t *testing.T) {
+	e := newAbuseProtectionTestServer(t, &config.AppConfig{
+		RateLimitApiKeyPerMinute: 1,
+	})
+
+	assert.NotEqual(t, http.StatusTooManyRequests, serveFrom(e, "192.0.2.1:1234", "Bearer hub_key1"))
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom(e, "192.0.2.2:1234", "Bearer hub_key1"))
+	assert.NotEqual(t, http.StatusTooManyRequests, serveFrom(e, "192.0.2.1:1234", "Bearer hub_key2"))
+}
+
+func TestAbuseProtection_BannedIps(t *testing.T) {
+	e := newAbuseProtectionTestServer(t, &config.AppConfig{
+		BannedIps: "10.0.0.0/8, 192.0.2.5, invalid",
+	})
+
+	assert.Equal(t, http.StatusForbidden, serveFrom(e, "10.1.2.3:1234", ""))
+	assert.Equal(t, http.StatusForbidden, serveFrom(e, "192.0.2.5:1234", ""))
+	assert.NotEqual(t, http.StatusForbidden, serveFrom(e, "192.0.2.6:1234", ""))
+}
+
+func TestAbuseProtection_BanAfterAuthFailures(t *testing.T) {
+	e := newAbuseProtectionTestServer(t, &config.AppConfig{
+		AuthFailureBanThreshold: 2,
+		AuthFailureBanMinutes:   60,
+	})
+
+	assert.Equal(t, http.StatusUnauthorized, serveFrom(e, "192.0.2.1:1234", "Bearer invalid"))
+	assert.Equal(t, http.StatusUnauthorized, serveFrom(e, "192.0.2.1:1234", "Bearer invalid"))
+	assert.Equal(t, http.StatusForbidden, serveFrom(e, "192.0.2.1:1234", "Bearer invalid"))
+	assert.Equal(t, http.StatusUnauthorized, serveFrom(e, "192.0.2.2:1234", "Bearer invalid"))
+}
+
+func TestAbuseProtection_ExpiredSessionsAreNotBanned(t *testing.T) {
+	e := newAbuseProtectionTestServer(t, &config.AppConfig{
+		AuthFailureBanThreshold: 2,
+		AuthFailureBanMinutes:   60,
+	})
+
+	expiredToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwtCustomClaims{
+		Permission: "full",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		},
+	}).SignedString([]byte("dummy secret"))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, serveFrom(e, "192.0.2.1:1234", "Bearer "+expiredToken))
+	}
+	assert.Equal(t, http.StatusUnauthorized, serveFrom(e, "192.0.2.1:1234", "Bearer invalid"))
+}
+
+func TestAbuseProtection_ProxyHeaders(t *testing.T) {
+	e := newAbuseProtectionTestServer(t, &config.AppConfig{
+		BannedIps: "192.0.2.5",
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/apps", nil)
+	req.RemoteAddr = "192.0.2.5:1234"
+	req.Header.Set("X-Forwarded-For", "192.0.2.6")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAbuseProtection_Reload(t *testing.T) {
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{RateLimitIpPerMinute: 1})
+	mockConfig.On("Get", "RateLimitApiKeyPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitSessionPerMinute", "").Return("", nil)
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("", nil).Once()
+	mockConfig.On("Get", "BannedIps", "").Return("", nil).Once()
+
+	p := newAbuseProtection(mockConfig)
+	ipLimiter, _, _ := p.limiters()
+	assert.True(t, allow(ipLimiter, "192.0.2.1"))
+	assert.False(t, allow(ipLimiter, "192.0.2.1"))
+	assert.False(t, p.isBanned("192.0.2.3"))
+
+	// the settings take precedence over the environment
+	mockConfig.On("Get", "RateLimitIpPerMinute", "").Return("0", nil).Once()
+	mockConfig.On("Get", "BannedIps", "").Return("192.0.2.0/24", nil).Once()
+	p.reload()
+
+	ipLimiter, _, _ = p.limiters()
+	assert.Nil(t, ipLimiter)
+	assert.True(t, p.isBanned("192.0.2.3"))
+	assert.False(t, p.isBanned("198.51.100.1"))
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/apps"
+)
+
+// The admin API is also served over gRPC. It shares the API instance, the network policy,
+// the IP bans and the rate limits of the HTTP API, so that they cannot be bypassed through it.
+// RegisterSharedRoutes has to be called first.
+
+func (httpSvc *HttpService) GetAPI() api.API {
+	return httpSvc.api
+}
+
+func (httpSvc *HttpService) GetAppsService() apps.AppsService {
+	return httpSvc.appsSvc
+}
+
+// IsAllowed reports whether admin requests from the IP address pass the network policy and IP bans
+func (httpSvc *HttpService) IsAllowed(ip string) bool {
+	return httpSvc.networkPolicy.isAllowed(ip) && !httpSvc.abuseProtection.isBanned(ip)
+}
+
+// AllowRequest applies the rate limits of the IP address and the api key
+func (httpSvc *HttpService) AllowRequest(ip string, apiKey string) bool {
+	return httpSvc.abuseProtection.allowRequest(ip, apiKey)
+}
+
+// RecordAuthFailure counts a failed authentication towards banning the IP address
+func (httpSvc *HttpService) RecordAuthFailure(ip string) {
+	httpSvc.abuseProtection.recordAuthFailure(ip)
+}
@@ -0,0 +1,222 @@
+// Package liquidityhistory records the balances of the node's channels every hour and reports how
+// the inbound and outbound liquidity changed over time, which helps to decide when to open
+// channels or swap.
+package liquidityhistory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+var checkInterval = time.Hour
+
+// snapshots older than this are deleted
+const snapshotRetention = 180 * 24 * time.Hour
+
+// reports over longer periods than this have one point per day rather than per snapshot
+const hourlyReportPeriod = 7 * 24 * time.Hour
+
+// LiquidityPoint is the liquidity of all channels at one time
+type LiquidityPoint struct {
+	Time             time.Time `json:"time"`
+	ChannelCount     int       `json:"channelCount"`
+	CapacitySat      uint64    `json:"capacity"`
+	LocalBalanceSat  uint64    `json:"localBalance"`
+	RemoteBalanceSat uint64    `json:"remoteBalance"`
+}
+
+// ChannelLiquidity is the liquidity of a channel at the end of the report and how it changed
+type ChannelLiquidity struct {
+	ChannelId              string  `json:"channelId"`
+	PeerPubkey             string  `json:"peerPubkey"`
+	LocalBalanceSat        uint64  `json:"localBalance"`
+	RemoteBalanceSat       uint64  `json:"remoteBalance"`
+	LocalBalanceChangeSat  int64   `json:"localBalanceChange"`
+	RemoteBalanceChangeSat int64   `json:"remoteBalanceChange"`
+	AverageLocalRatio      float64 `json:"averageLocalRatio"`
+}
+
+type LiquidityReport struct {
+	From     time.Time          `json:"from"`
+	Until    time.Time          `json:"until"`
+	Points   []LiquidityPoint   `json:"points"`
+	Channels []ChannelLiquidity `json:"channels"`
+	// change of the total local and remote balance from the first to the last point
+	OutboundChangeSat int64 `json:"outboundChange"`
+	InboundChangeSat  int64 `json:"inboundChange"`
+}
+
+type LiquidityHistoryService interface {
+	GetLiquidityReport(from, until time.Time) (*LiquidityReport, error)
+	Start(ctx context.Context, lnClient lnclient.LNClient)
+}
+
+type liquidityHistoryService struct {
+	db *gorm.DB
+}
+
+func NewLiquidityHistoryService(db *gorm.DB) *liquidityHistoryService {
+	return &liquidityHistoryService{
+		db: db,
+	}
+}
+
+// Start records a snapshot every hour until ctx is done
+func (svc *liquidityHistoryService) Start(ctx context.Context, lnClient lnclient.LNClient) {
+	logger.Logger.Info("Starting liquidity history")
+	health.RegisterJob("liquidity_history", checkInterval)
+	go func() {
+		defer health.RemoveJob("liquidity_history")
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				health.ReportJobRun("liquidity_history", svc.recordSnapshot(ctx, lnClient))
+			case <-ctx.Done():
+				logger.Logger.Info("Stopping liquidity history")
+				return
+			}
+		}
+	}()
+}
+
+func (svc *liquidityHistoryService) recordSnapshot(ctx context.Context, lnClient lnclient.LNClient) error {
+	channels, err := lnClient.ListChannels(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list channels for liquidity snapshot")
+		return err
+	}
+
+	now := time.Now()
+	snapshots := []db.ChannelLiquiditySnapshot{}
+	for _, channel := range channels {
+		snapshots = append(snapshots, db.ChannelLiquiditySnapshot{
+			ChannelId:         channel.Id,
+			PeerPubkey:        channel.RemotePubkey,
+			LocalBalanceMsat:  channel.LocalBalance,
+			RemoteBalanceMsat: channel.RemoteBalance,
+			Active:            channel.Active,
+			CreatedAt:         now,
+		})
+	}
+
+	return svc.db.Transaction(func(tx *gorm.DB) error {
+		if len(snapshots) > 0 {
+			if err := tx.Create(&snapshots).Error; err != nil {
+				logger.Logger.WithError(err).Error("Failed to save liquidity snapshot")
+				return err
+			}
+		}
+		return tx.Where("created_at < ?", now.Add(-snapshotRetention)).Delete(&db.ChannelLiquiditySnapshot{}).Error
+	})
+}
+
+func (svc *liquidityHistoryService) GetLiquidityReport(from, until time.Time) (*LiquidityReport, error) {
+	if !from.Before(until) {
+		return nil, errors.New("the report must start before it ends")
+	}
+
+	var snapshots []db.ChannelLiquiditySnapshot
+	err := svc.db.
+		Where("created_at >= ? AND created_at <= ?", from, until).
+		Order("created_at, id").
+		Find(&snapshots).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LiquidityReport{
+		From:     from,
+		Until:    until,
+		Points:   []LiquidityPoint{},
+		Channels: []ChannelLiquidity{},
+	}
+
+	// snapshots are ordered by time, so the last point of a day replaces the earlier ones
+	resolution := time.Duration(0)
+	if until.Sub(from) > hourlyReportPeriod {
+		resolution = 24 * time.Hour
+	}
+	pointIndexes := map[time.Time]int{}
+	pointTimes := map[time.Time]time.Time{}
+
+	type channelHistory struct {
+		first      db.ChannelLiquiditySnapshot
+		last       db.ChannelLiquiditySnapshot
+		localRatio float64
+		count      int
+	}
+	channelHistories := map[string]*channelHistory{}
+
+	for _, snapshot := range snapshots {
+		bucket := snapshot.CreatedAt
+		if resolution > 0 {
+			bucket = snapshot.CreatedAt.Truncate(resolution)
+		}
+		index, ok := pointIndexes[bucket]
+		if !ok || !pointTimes[bucket].Equal(snapshot.CreatedAt) {
+			point := LiquidityPoint{Time: snapshot.CreatedAt}
+			if ok {
+				report.Points[index] = point
+			} else {
+				index = len(report.Points)
+				pointIndexes[bucket] = index
+				report.Points = append(report.Points, point)
+			}
+			pointTimes[bucket] = snapshot.CreatedAt
+		}
+		point := &report.Points[index]
+		point.ChannelCount++
+		point.LocalBalanceSat += uint64(snapshot.LocalBalanceMsat) / 1000
+		point.RemoteBalanceSat += uint64(snapshot.RemoteBalanceMsat) / 1000
+		point.CapacitySat = point.LocalBalanceSat + point.RemoteBalanceSat
+
+		history, ok := channelHistories[snapshot.ChannelId]
+		if !ok {
+			history = &channelHistory{first: snapshot}
+			channelHistories[snapshot.ChannelId] = history
+		}
+		history.last = snapshot
+		if capacity := snapshot.LocalBalanceMsat + snapshot.RemoteBalanceMsat; capacity > 0 {
+			history.localRatio += float64(snapshot.LocalBalanceMsat) / float64(capacity)
+			history.count++
+		}
+	}
+
+	if len(report.Points) > 0 {
+		first := report.Points[0]
+		last := report.Points[len(report.Points)-1]
+		report.OutboundChangeSat = int64(last.LocalBalanceSat) - int64(first.LocalBalanceSat)
+		report.InboundChangeSat = int64(last.RemoteBalanceSat) - int64(first.RemoteBalanceSat)
+	}
+
+	for channelId, history := range channelHistories {
+		channelLiquidity := ChannelLiquidity{
+			ChannelId:              channelId,
+			PeerPubkey:             history.last.PeerPubkey,
+			LocalBalanceSat:        uint64(history.last.LocalBalanceMsat) / 1000,
+			RemoteBalanceSat:       uint64(history.last.RemoteBalanceMsat) / 1000,
+			LocalBalanceChangeSat:  (history.last.LocalBalanceMsat - history.first.LocalBalanceMsat) / 1000,
+			RemoteBalanceChangeSat: (history.last.RemoteBalanceMsat - history.first.RemoteBalanceMsat) / 1000,
+		}
+		if history.count > 0 {
+			channelLiquidity.AverageLocalRatio = history.localRatio / float64(history.count)
+		}
+		report.Channels = append(report.Channels, channelLiquidity)
+	}
+	sort.Slice(report.Channels, func(i, j int) bool {
+		return report.Channels[i].ChannelId < report.Channels[j].ChannelId
+	})
+
+	return report, nil
+}
@@ -0,0 +1,90 @@
+package liquidityhistory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+type mockChannelsLNClient struct {
+	lnclient.LNClient
+	channels []lnclient.Channel
+}
+
+func (mln *mockChannelsLNClient) ListChannels(ctx context.Context) ([]lnclient.Channel, error) {
+	return mln.channels, nil
+}
+
+func TestRecordSnapshot(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	oldSnapshot := db.ChannelLiquiditySnapshot{ChannelId: "1", CreatedAt: time.Now().Add(-snapshotRetention - time.Hour)}
+	require.NoError(t, svc.DB.Create(&oldSnapshot).Error)
+
+	lnClient := &mockChannelsLNClient{
+		LNClient: svc.LNClient,
+		channels: []lnclient.Channel{
+			{Id: "1", RemotePubkey: "02aaaa", Active: true, LocalBalance: 200_000_000, RemoteBalance: 800_000_000},
+			{Id: "2", RemotePubkey: "02bbbb", Active: true, LocalBalance: 900_000_000, RemoteBalance: 100_000_000},
+		},
+	}
+	liquidityHistorySvc := NewLiquidityHistoryService(svc.DB)
+	require.NoError(t, liquidityHistorySvc.recordSnapshot(context.TODO(), lnClient))
+
+	var snapshots []db.ChannelLiquiditySnapshot
+	require.NoError(t, svc.DB.Order("channel_id").Find(&snapshots).Error)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "02aaaa", snapshots[0].PeerPubkey)
+	assert.Equal(t, int64(200_000_000), snapshots[0].LocalBalanceMsat)
+	assert.Equal(t, snapshots[0].CreatedAt, snapshots[1].CreatedAt)
+}
+
+func TestGetLiquidityReport(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	until := time.Now().Truncate(time.Hour)
+	from := until.Add(-3 * time.Hour)
+	snapshots := []db.ChannelLiquiditySnapshot{
+		{ChannelId: "1", PeerPubkey: "02aaaa", LocalBalanceMsat: 800_000_000, RemoteBalanceMsat: 200_000_000, CreatedAt: from.Add(time.Hour)},
+		{ChannelId: "2", PeerPubkey: "02bbbb", LocalBalanceMsat: 500_000_000, RemoteBalanceMsat: 500_000_000, CreatedAt: from.Add(time.Hour)},
+		{ChannelId: "1", PeerPubkey: "02aaaa", LocalBalanceMsat: 400_000_000, RemoteBalanceMsat: 600_000_000, CreatedAt: from.Add(2 * time.Hour)},
+		{ChannelId: "2", PeerPubkey: "02bbbb", LocalBalanceMsat: 600_000_000, RemoteBalanceMsat: 400_000_000, CreatedAt: from.Add(2 * time.Hour)},
+		// outside of the report
+		{ChannelId: "1", PeerPubkey: "02aaaa", LocalBalanceMsat: 1_000_000_000, CreatedAt: from.Add(-time.Hour)},
+	}
+	require.NoError(t, svc.DB.Create(&snapshots).Error)
+
+	report, err := NewLiquidityHistoryService(svc.DB).GetLiquidityReport(from, until)
+	require.NoError(t, err)
+
+	require.Len(t, report.Points, 2)
+	assert.Equal(t, LiquidityPoint{Time: report.Points[0].Time, ChannelCount: 2, CapacitySat: 2_000_000, LocalBalanceSat: 1_300_000, RemoteBalanceSat: 700_000}, report.Points[0])
+	assert.Equal(t, uint64(1_000_000), report.Points[1].LocalBalanceSat)
+	assert.Equal(t, int64(-300_000), report.OutboundChangeSat)
+	assert.Equal(t, int64(300_000), report.InboundChangeSat)
+
+	require.Len(t, report.Channels, 2)
+	assert.Equal(t, "1", report.Channels[0].ChannelId)
+	assert.Equal(t, uint64(400_000), report.Channels[0].LocalBalanceSat)
+	assert.Equal(t, uint64(600_000), report.Channels[0].RemoteBalanceSat)
+	assert.Equal(t, int64(-400_000), report.Channels[0].LocalBalanceChangeSat)
+	assert.Equal(t, int64(400_000), report.Channels[0].RemoteBalanceChangeSat)
+	assert.InDelta(t, 0.6, report.Channels[0].AverageLocalRatio, 0.0001)
+
+	// longer reports have one point per day
+	report, err = NewLiquidityHistoryService(svc.DB).GetLiquidityReport(until.Add(-30*24*time.Hour), until)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(report.Points), 2)
+	assert.Equal(t, uint64(1_000_000), report.Points[len(report.Points)-1].LocalBalanceSat)
+}
@@ -371,6 +371,7 @@ func (b *BarkService) GetBalances(ctx context.Context, includeInactiveChannels b
 			NextMaxSpendableMPP:  walletBal.SpendableSat * MSAT_PER_SAT,
 			NextMaxReceivableMPP: 0,
 		},
+		PendingArk: (walletBal.PendingInRoundSat + walletBal.PendingBoardSat) * MSAT_PER_SAT,
 	}, nil
 }
 
@@ -312,6 +312,7 @@ func (cs *CashuService) GetBalances(ctx context.Context, includeInactiveChannels
 			NextMaxSpendableMPP:  balance,
 			NextMaxReceivableMPP: 0,
 		},
+		Ecash: balance,
 	}, nil
 }
 
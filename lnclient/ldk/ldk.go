@@ -26,6 +26,7 @@ import (
 	decodepay "github.com/nbd-wtf/ln-decodepay"
 	"github.com/sirupsen/logrus"
 
+	"github.com/getAlby/hub/channelacceptance"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
@@ -53,6 +54,8 @@ type LDKService struct {
 	redeemedOnchainFundsWithinThisSync bool
 	pubkey                             string
 	shuttingDown                       bool
+	// invoices above the receivable capacity open a JIT channel with the LSPS2 LSP
+	lsps2Enabled bool
 }
 
 const resetRouterKey = "ResetRouter"
@@ -111,6 +114,19 @@ func NewLDKService(ctx context.Context, cfg config.Config, eventPublisher events
 		"035e8a9034a8c68f219aacadae748c7a3cd719109309db39b09886e5ff17696b1b", // lqwd*/
 	}
 
+	// LDK accepts all inbound channels, only the zero-conf peers of the acceptance policy apply
+	ldkConfig.TrustedPeers0conf = append(ldkConfig.TrustedPeers0conf, channelacceptance.GetPolicy(cfg).ZeroConfPeers...)
+
+	var lsps2Pubkey, lsps2Address string
+	if cfg.GetEnv().LSPS2LSP != "" {
+		lsps2Pubkey, lsps2Address, err = lsp.ParseNodeURI(cfg.GetEnv().LSPS2LSP)
+		if err != nil {
+			return nil, err
+		}
+		// JIT channels are usable before they are confirmed
+		ldkConfig.TrustedPeers0conf = append(ldkConfig.TrustedPeers0conf, lsps2Pubkey)
+	}
+
 	listeningAddresses := strings.Split(cfg.GetEnv().LDKListeningAddresses, ",")
 	ldkConfig.ListeningAddresses = &listeningAddresses
 	if cfg.GetEnv().LDKAnnouncementAddresses != "" {
@@ -170,6 +186,15 @@ func NewLDKService(ctx context.Context, cfg config.Config, eventPublisher events
 		logger.Logger.WithField("gossipSource", cfg.GetEnv().LDKGossipSource).Warn("LDK RGS instance set")
 		builder.SetGossipSourceRgs(cfg.GetEnv().LDKGossipSource)
 	}
+	if lsps2Pubkey != "" {
+		logger.Logger.WithField("lsp", cfg.GetEnv().LSPS2LSP).Info("Using LSPS2 LSP for JIT channels")
+		var lsps2Token *string
+		if cfg.GetEnv().LSPS2Token != "" {
+			token := cfg.GetEnv().LSPS2Token
+			lsps2Token = &token
+		}
+		builder.SetLiquiditySourceLsps2(lsps2Pubkey, lsps2Address, lsps2Token)
+	}
 	builder.SetStorageDirPath(filepath.Join(newpath, "./storage"))
 
 	migrateStorage, _ := cfg.Get("LdkMigrateStorage", "")
@@ -228,6 +253,7 @@ func NewLDKService(ctx context.Context, cfg config.Config, eventPublisher events
 		cfg:                 cfg,
 		pubkey:              nodeId,
 		ctx:                 ldkCtx,
+		lsps2Enabled:        lsps2Pubkey != "",
 	}
 
 	eventPublisher.RegisterSubscriber(&ls)
@@ -496,7 +522,13 @@ func (ls *LDKService) Shutdown() error {
 	return nil
 }
 
-func getMaxTotalRoutingFeeLimit(amountMsat uint64) ldk_node.MaxTotalRoutingFeeLimit {
+// falls back to the default fee reserve if no maximum fee is given
+func getMaxTotalRoutingFeeLimit(amountMsat uint64, maxFeeMsat *uint64) ldk_node.MaxTotalRoutingFeeLimit {
+	if maxFeeMsat != nil {
+		return ldk_node.MaxTotalRoutingFeeLimitSome{
+			AmountMsat: *maxFeeMsat,
+		}
+	}
 	return ldk_node.MaxTotalRoutingFeeLimitSome{
 		AmountMsat: transactions.CalculateFeeReserveMsat(amountMsat),
 	}
@@ -514,6 +546,14 @@ func (ls *LDKService) MakeOffer(ctx context.Context, description string) (string
 }
 
 func (ls *LDKService) SendPaymentSync(invoice string, amount *uint64) (*lnclient.PayInvoiceResponse, error) {
+	return ls.sendPaymentSync(invoice, amount, nil)
+}
+
+func (ls *LDKService) SendPaymentSyncWithMaxFee(invoice string, amount *uint64, maxFeeMsat uint64) (*lnclient.PayInvoiceResponse, error) {
+	return ls.sendPaymentSync(invoice, amount, &maxFeeMsat)
+}
+
+func (ls *LDKService) sendPaymentSync(invoice string, amount *uint64, maxFeeMsat *uint64) (*lnclient.PayInvoiceResponse, error) {
 	paymentRequest, err := decodepay.Decodepay(invoice)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
@@ -547,7 +587,7 @@ func (ls *LDKService) SendPaymentSync(invoice string, amount *uint64) (*lnclient
 
 	saturationPower := ls.cfg.GetEnv().LDKMaxChannelSaturationPowerOfHalf
 	maxPathCount := ls.cfg.GetEnv().LDKMaxPathCount
-	maxTotalRoutingFeeMsat := getMaxTotalRoutingFeeLimit(paymentAmountMsat)
+	maxTotalRoutingFeeMsat := getMaxTotalRoutingFeeLimit(paymentAmountMsat, maxFeeMsat)
 
 	sendingParams := &ldk_node.SendingParameters{
 		MaxTotalRoutingFeeMsat:          &maxTotalRoutingFeeMsat,
@@ -625,6 +665,14 @@ func (ls *LDKService) SendPaymentSync(invoice string, amount *uint64) (*lnclient
 }
 
 func (ls *LDKService) SendKeysend(amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
+	return ls.sendKeysend(amount, destination, custom_records, preimage, nil)
+}
+
+func (ls *LDKService) SendKeysendWithMaxFee(amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string, maxFeeMsat uint64) (*lnclient.PayKeysendResponse, error) {
+	return ls.sendKeysend(amount, destination, custom_records, preimage, &maxFeeMsat)
+}
+
+func (ls *LDKService) sendKeysend(amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string, maxFeeMsat *uint64) (*lnclient.PayKeysendResponse, error) {
 	paymentStart := time.Now()
 	customTlvs := []ldk_node.TlvEntry{}
 
@@ -644,7 +692,7 @@ func (ls *LDKService) SendKeysend(amount uint64, destination string, custom_reco
 
 	saturationPower := ls.cfg.GetEnv().LDKMaxChannelSaturationPowerOfHalf
 	maxPathCount := ls.cfg.GetEnv().LDKMaxPathCount
-	maxTotalRoutingFeeMsat := getMaxTotalRoutingFeeLimit(amount)
+	maxTotalRoutingFeeMsat := getMaxTotalRoutingFeeLimit(amount, maxFeeMsat)
 
 	sendingParams := &ldk_node.SendingParameters{
 		MaxTotalRoutingFeeMsat:          &maxTotalRoutingFeeMsat,
@@ -727,7 +775,10 @@ func (ls *LDKService) MakeInvoice(ctx context.Context, amount int64, description
 
 	maxReceivable := ls.getMaxReceivable()
 
-	if amount > maxReceivable {
+	// first-time receivers get a channel on the fly rather than an invoice which cannot be paid
+	useJitChannel := ls.lsps2Enabled && (amount > maxReceivable || (amount == 0 && maxReceivable == 0))
+
+	if amount > maxReceivable && !useJitChannel {
 		ls.eventPublisher.Publish(&events.Event{
 			Event: "nwc_incoming_liquidity_required",
 			Properties: map[string]interface{}{
@@ -753,9 +804,27 @@ func (ls *LDKService) MakeInvoice(ctx context.Context, amount int64, description
 		}
 	}
 
-	invoiceObj, err := ls.node.Bolt11Payment().Receive(uint64(amount),
-		descriptionType,
-		uint32(expiry))
+	var invoiceObj *ldk_node.Bolt11Invoice
+	if useJitChannel {
+		var maxLspFeeLimitMsat *uint64
+		if ls.cfg.GetEnv().LSPS2MaxFeeSat > 0 {
+			maxFee := ls.cfg.GetEnv().LSPS2MaxFeeSat * 1000
+			maxLspFeeLimitMsat = &maxFee
+		}
+		logger.Logger.WithFields(logrus.Fields{
+			"amount":         amount,
+			"max_receivable": maxReceivable,
+		}).Info("Requesting JIT channel for invoice")
+		if amount == 0 {
+			invoiceObj, err = ls.node.Bolt11Payment().ReceiveVariableAmountViaJitChannel(descriptionType, uint32(expiry), nil)
+		} else {
+			invoiceObj, err = ls.node.Bolt11Payment().ReceiveViaJitChannel(uint64(amount), descriptionType, uint32(expiry), maxLspFeeLimitMsat)
+		}
+	} else {
+		invoiceObj, err = ls.node.Bolt11Payment().Receive(uint64(amount),
+			descriptionType,
+			uint32(expiry))
+	}
 
 	if err != nil {
 		logger.Logger.WithError(err).Error("MakeInvoice failed")
@@ -763,7 +832,19 @@ func (ls *LDKService) MakeInvoice(ctx context.Context, amount int64, description
 	}
 
 	payment := ls.node.Payment(invoiceObj.PaymentHash())
-	invoice := *payment.Kind.(ldk_node.PaymentKindBolt11).Bolt11Invoice
+	// JIT channel payments do not store the invoice, which is wrapped with a route hint to the LSP
+	invoice := invoiceObj.String()
+	var preimage string
+	switch paymentKind := payment.Kind.(type) {
+	case ldk_node.PaymentKindBolt11:
+		if paymentKind.Preimage != nil {
+			preimage = *paymentKind.Preimage
+		}
+	case ldk_node.PaymentKindBolt11Jit:
+		if paymentKind.Preimage != nil {
+			preimage = *paymentKind.Preimage
+		}
+	}
 	paymentRequest, err := decodepay.Decodepay(invoice)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
@@ -778,7 +859,7 @@ func (ls *LDKService) MakeInvoice(ctx context.Context, amount int64, description
 		Type:            "incoming",
 		Invoice:         invoice,
 		PaymentHash:     paymentRequest.PaymentHash,
-		Preimage:        *payment.Kind.(ldk_node.PaymentKindBolt11).Preimage,
+		Preimage:        preimage,
 		Amount:          amount,
 		CreatedAt:       int64(paymentRequest.CreatedAt),
 		ExpiresAt:       &expiresAtUnix,
@@ -1225,17 +1306,23 @@ func (ls *LDKService) GetOnchainBalance(ctx context.Context) (*lnclient.OnchainB
 	// increase pending balance from any lightning balances for channels that are pending closure
 	// (they do not exist in our list of open channels)
 	for _, balance := range balances.LightningBalances {
-		increasePendingBalance := func(nodeId, channelId string, amount uint64, fundingTxId ldk_node.Txid, fundingTxIndex uint16) {
+		// spendableHeight is the block height at which the balance can be claimed, or 0 if it is not timelocked
+		increasePendingBalance := func(nodeId, channelId string, amount uint64, fundingTxId ldk_node.Txid, fundingTxIndex uint16, spendableHeight uint32) {
 			if !slices.ContainsFunc(channels, func(channel ldk_node.ChannelDetails) bool {
 				return channel.ChannelId == channelId
 			}) {
+				var blocksUntilSpendable uint32
+				if spendableHeight > nodeStatus.CurrentBestBlock.Height {
+					blocksUntilSpendable = spendableHeight - nodeStatus.CurrentBestBlock.Height
+				}
 				pendingBalancesFromChannelClosures += amount
 				pendingBalancesDetails = append(pendingBalancesDetails, lnclient.PendingBalanceDetails{
-					NodeId:        nodeId,
-					ChannelId:     channelId,
-					Amount:        amount,
-					FundingTxId:   fundingTxId,
-					FundingTxVout: uint32(fundingTxIndex),
+					NodeId:               nodeId,
+					ChannelId:            channelId,
+					Amount:               amount,
+					FundingTxId:          fundingTxId,
+					FundingTxVout:        uint32(fundingTxIndex),
+					BlocksUntilSpendable: blocksUntilSpendable,
 				})
 			}
 		}
@@ -1247,17 +1334,17 @@ func (ls *LDKService) GetOnchainBalance(ctx context.Context) (*lnclient.OnchainB
 		})
 		switch balanceType := (balance).(type) {
 		case ldk_node.LightningBalanceClaimableOnChannelClose:
-			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex)
+			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex, 0)
 		case ldk_node.LightningBalanceClaimableAwaitingConfirmations:
-			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex)
+			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex, balanceType.ConfirmationHeight)
 		case ldk_node.LightningBalanceContentiousClaimable:
-			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex)
+			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex, balanceType.TimeoutHeight)
 		case ldk_node.LightningBalanceMaybeTimeoutClaimableHtlc:
-			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex)
+			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex, balanceType.ClaimableHeight)
 		case ldk_node.LightningBalanceMaybePreimageClaimableHtlc:
-			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex)
+			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex, 0)
 		case ldk_node.LightningBalanceCounterpartyRevokedOutputClaimable:
-			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex)
+			increasePendingBalance(balanceType.CounterpartyNodeId, balanceType.ChannelId, balanceType.AmountSatoshis, balanceType.FundingTxId, balanceType.FundingTxIndex, 0)
 		}
 	}
 
@@ -1476,6 +1563,25 @@ func (ls *LDKService) ldkPaymentToTransaction(payment *ldk_node.PaymentDetails)
 		}
 	}
 
+	bolt11JitPaymentKind, isBolt11JitPaymentKind := payment.Kind.(ldk_node.PaymentKindBolt11Jit)
+
+	if isBolt11JitPaymentKind {
+		// the invoice is not stored by LDK, it is kept in the transaction created by MakeInvoice
+		createdAt = int64(payment.CreatedAt)
+		paymentHash = bolt11JitPaymentKind.Hash
+		if payment.Status == ldk_node.PaymentStatusSucceeded {
+			if bolt11JitPaymentKind.Preimage != nil {
+				preimage = *bolt11JitPaymentKind.Preimage
+			}
+			lastUpdate := int64(payment.LatestUpdateTimestamp)
+			settledAt = &lastUpdate
+		}
+		if bolt11JitPaymentKind.CounterpartySkimmedFeeMsat != nil {
+			// fee deducted by the LSP for opening the channel
+			metadata["lsp_fee_msat"] = *bolt11JitPaymentKind.CounterpartySkimmedFeeMsat
+		}
+	}
+
 	spontaneousPaymentKind, isSpontaneousPaymentKind := payment.Kind.(ldk_node.PaymentKindSpontaneous)
 	if isSpontaneousPaymentKind {
 		// keysend payment
@@ -1723,6 +1829,7 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 		ls.eventPublisher.Publish(&events.Event{
 			Event: "nwc_channel_closed",
 			Properties: map[string]interface{}{
+				"channel_id":            eventType.ChannelId,
 				"counterparty_node_id":  counterpartyNodeId,
 				"counterparty_node_url": counterpartyNodeUrl,
 				"reason":                closureReason,
@@ -1733,6 +1840,8 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 				"funding_tx_url":        fundingTxUrl,
 			},
 		})
+
+		ls.backupChannels()
 	case ldk_node.EventPaymentReceived:
 		if eventType.PaymentId == nil {
 			logger.Logger.WithField("payment_hash", eventType.PaymentHash).Error("payment received event has no payment ID")
@@ -1999,6 +2108,8 @@ func (ls *LDKService) deleteOldLDKPayments() {
 		switch (payment.Kind).(type) {
 		case ldk_node.PaymentKindBolt11:
 			deletablePaymentKind = true
+		case ldk_node.PaymentKindBolt11Jit:
+			deletablePaymentKind = true
 		case ldk_node.PaymentKindSpontaneous:
 			deletablePaymentKind = true
 		}
@@ -2097,6 +2208,7 @@ func (ls *LDKService) GetSupportedNIP47NotificationTypes() []string {
 		notifications.PAYMENT_RECEIVED_NOTIFICATION,
 		notifications.PAYMENT_SENT_NOTIFICATION,
 		notifications.HOLD_INVOICE_ACCEPTED_NOTIFICATION,
+		notifications.INVOICE_EXPIRED_NOTIFICATION,
 	}
 }
 
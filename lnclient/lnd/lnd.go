@@ -250,6 +250,68 @@ func (svc *LNDService) subscribeInvoices(ctx context.Context) {
 	}
 }
 
+func (svc *LNDService) AcceptChannels(acceptor lnclient.ChannelAcceptor) {
+	go svc.acceptChannels(svc.ctx, acceptor)
+}
+
+// acceptChannels registers a channel acceptor with LND, which accepts all requests again if the
+// stream is closed
+func (svc *LNDService) acceptChannels(ctx context.Context, acceptor lnclient.ChannelAcceptor) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			acceptorStream, err := svc.client.ChannelAcceptor(ctx)
+			if err != nil {
+				logger.Logger.WithError(err).Error("Error registering channel acceptor")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(10 * time.Second):
+					continue
+				}
+			}
+		acceptorLoop:
+			for {
+				request, err := acceptorStream.Recv()
+				if err != nil {
+					logger.Logger.WithError(err).Error("Failed to receive channel request")
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(2 * time.Second):
+						break acceptorLoop
+					}
+				}
+
+				response := &lnrpc.ChannelAcceptResponse{
+					PendingChanId: request.PendingChanId,
+					Accept:        true,
+				}
+				err = acceptor(ctx, &lnclient.ChannelRequest{
+					PeerPubkey:    hex.EncodeToString(request.NodePubkey),
+					CapacitySat:   request.FundingAmt,
+					PushAmountSat: request.PushAmt,
+					ZeroConf:      request.WantsZeroConf,
+				})
+				if err != nil {
+					response.Accept = false
+					response.Error = err.Error()
+				} else if request.WantsZeroConf {
+					response.ZeroConf = true
+					response.MinAcceptDepth = 0
+				}
+
+				if err := acceptorStream.Send(response); err != nil {
+					logger.Logger.WithError(err).Error("Failed to respond to channel request")
+					break acceptorLoop
+				}
+			}
+		}
+	}
+}
+
 func (svc *LNDService) subscribeChannelEvents(ctx context.Context) {
 	for {
 		select {
@@ -299,9 +361,12 @@ func (svc *LNDService) subscribeChannelEvents(ctx context.Context) {
 							"is_outbound":          channel.Initiator,
 						},
 					})
+					svc.backupChannels(ctx)
 				case *lnrpc.ChannelEventUpdate_ClosedChannel:
 					closureReason := update.ClosedChannel.CloseType.String()
 					counterpartyNodeId := update.ClosedChannel.RemotePubkey
+					// the getters return zero values if the channel point cannot be parsed
+					channelPoint, _ := svc.parseChannelPoint(update.ClosedChannel.ChannelPoint)
 
 					logger.Logger.WithFields(logrus.Fields{
 						"counterparty_node_id": counterpartyNodeId,
@@ -311,18 +376,78 @@ func (svc *LNDService) subscribeChannelEvents(ctx context.Context) {
 					svc.eventPublisher.Publish(&events.Event{
 						Event: "nwc_channel_closed",
 						Properties: map[string]interface{}{
+							"channel_id":            strconv.FormatUint(update.ClosedChannel.ChanId, 10),
 							"counterparty_node_id":  counterpartyNodeId,
 							"counterparty_node_url": "https://amboss.space/node/" + counterpartyNodeId,
 							"reason":                closureReason,
 							"node_type":             config.LNDBackendType,
+							"funding_tx_id":         channelPoint.GetFundingTxidStr(),
+							"funding_tx_vout":       channelPoint.GetOutputIndex(),
 						},
 					})
+					svc.backupChannels(ctx)
 				}
 			}
 		}
 	}
 }
 
+// backupChannels publishes the static channel backup of LND after a channel was opened or closed
+func (svc *LNDService) backupChannels(ctx context.Context) {
+	snapshot, err := svc.client.ExportAllChannelBackups(ctx, &lnrpc.ChanBackupExportRequest{})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to export channel backups")
+		return
+	}
+
+	channels := []events.ChannelBackup{}
+	if snapshot.SingleChanBackups != nil {
+		for _, channelBackup := range snapshot.SingleChanBackups.ChanBackups {
+			channelPoint := channelBackup.ChanPoint
+			if channelPoint == nil {
+				continue
+			}
+			var fundingTxId string
+			if txid := channelPoint.GetFundingTxidBytes(); txid != nil {
+				reversed := slices.Clone(txid)
+				slices.Reverse(reversed)
+				fundingTxId = hex.EncodeToString(reversed)
+			} else {
+				fundingTxId = channelPoint.GetFundingTxidStr()
+			}
+			channels = append(channels, events.ChannelBackup{
+				FundingTxID:   fundingTxId,
+				FundingTxVout: channelPoint.OutputIndex,
+			})
+		}
+	}
+
+	event := &events.StaticChannelsBackupEvent{
+		NodeID:   svc.client.GetMainPubkey(),
+		Channels: channels,
+		Monitors: []events.EncodedChannelMonitorBackup{},
+	}
+	if snapshot.MultiChanBackup != nil {
+		event.LNDMultiChanBackup = snapshot.MultiChanBackup.MultiChanBackup
+	}
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event:      "nwc_backup_channels",
+		Properties: event,
+	})
+}
+
+// RestoreChannelBackups hands a multi-channel backup to LND, which then asks the peers
+// to force close the channels so that the funds return to the on-chain wallet
+func (svc *LNDService) RestoreChannelBackups(ctx context.Context, multiChanBackup []byte) error {
+	_, err := svc.client.RestoreChannelBackups(ctx, &lnrpc.RestoreChanBackupRequest{
+		Backup: &lnrpc.RestoreChanBackupRequest_MultiChanBackup{
+			MultiChanBackup: multiChanBackup,
+		},
+	})
+	return err
+}
+
 func (svc *LNDService) subscribeOpenHoldInvoices(ctx context.Context) {
 	oneWeekAgo := time.Now().AddDate(0, 0, -7).Unix()
 
@@ -430,7 +555,23 @@ func (svc *LNDService) Shutdown() error {
 	return nil
 }
 
+// falls back to the default fee reserve if no maximum fee is given
+func getFeeLimitMsat(amountMsat uint64, maxFeeMsat *uint64) uint64 {
+	if maxFeeMsat != nil {
+		return *maxFeeMsat
+	}
+	return transactions.CalculateFeeReserveMsat(amountMsat)
+}
+
 func (svc *LNDService) SendPaymentSync(payReq string, amount *uint64) (*lnclient.PayInvoiceResponse, error) {
+	return svc.sendPaymentSync(payReq, amount, nil)
+}
+
+func (svc *LNDService) SendPaymentSyncWithMaxFee(payReq string, amount *uint64, maxFeeMsat uint64) (*lnclient.PayInvoiceResponse, error) {
+	return svc.sendPaymentSync(payReq, amount, &maxFeeMsat)
+}
+
+func (svc *LNDService) sendPaymentSync(payReq string, amount *uint64, maxFeeMsat *uint64) (*lnclient.PayInvoiceResponse, error) {
 	const MAX_PARTIAL_PAYMENTS = 16
 
 	paymentRequest, err := decodepay.Decodepay(payReq)
@@ -448,7 +589,7 @@ func (svc *LNDService) SendPaymentSync(payReq string, amount *uint64) (*lnclient
 	sendRequest := &routerrpc.SendPaymentRequest{
 		PaymentRequest: payReq,
 		MaxParts:       MAX_PARTIAL_PAYMENTS,
-		FeeLimitMsat:   int64(transactions.CalculateFeeReserveMsat(paymentAmountMsat)),
+		FeeLimitMsat:   int64(getFeeLimitMsat(paymentAmountMsat, maxFeeMsat)),
 	}
 
 	if amount != nil {
@@ -492,6 +633,14 @@ func (svc *LNDService) SendPaymentSync(payReq string, amount *uint64) (*lnclient
 }
 
 func (svc *LNDService) SendKeysend(amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
+	return svc.sendKeysend(amount, destination, custom_records, preimage, nil)
+}
+
+func (svc *LNDService) SendKeysendWithMaxFee(amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string, maxFeeMsat uint64) (*lnclient.PayKeysendResponse, error) {
+	return svc.sendKeysend(amount, destination, custom_records, preimage, &maxFeeMsat)
+}
+
+func (svc *LNDService) sendKeysend(amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string, maxFeeMsat *uint64) (*lnclient.PayKeysendResponse, error) {
 	destBytes, err := hex.DecodeString(destination)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
@@ -538,7 +687,7 @@ func (svc *LNDService) SendKeysend(amount uint64, destination string, custom_rec
 		DestCustomRecords: destCustomRecords,
 		MaxParts:          MAX_PARTIAL_PAYMENTS,
 		TimeoutSeconds:    SEND_PAYMENT_TIMEOUT,
-		FeeLimitMsat:      int64(transactions.CalculateFeeReserveMsat(amount)),
+		FeeLimitMsat:      int64(getFeeLimitMsat(amount, maxFeeMsat)),
 	}
 
 	payStream, err := svc.client.SendPayment(svc.ctx, sendPaymentRequest)
@@ -599,6 +748,72 @@ func (svc *LNDService) getPaymentResult(stream routerrpc.Router_SendPaymentV2Cli
 	}
 }
 
+// attempts of a circular payment. Failed routes are remembered by LND's mission control, so each
+// attempt tries different routes.
+const maxCircularPaymentAttempts = 3
+
+func (svc *LNDService) SendCircularPayment(ctx context.Context, payReq string, outgoingChannelId string, lastHopPubkey string, maxFeeMsat uint64) (*lnclient.PayInvoiceResponse, error) {
+	outgoingChanId, err := strconv.ParseUint(outgoingChannelId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outgoing channel id: %w", err)
+	}
+	lastHopPubkeyBytes, err := hex.DecodeString(lastHopPubkey)
+	if err != nil || len(lastHopPubkeyBytes) != 33 {
+		return nil, errors.New("invalid last hop pubkey")
+	}
+
+	sendRequest := &routerrpc.SendPaymentRequest{
+		PaymentRequest:   payReq,
+		FeeLimitMsat:     int64(maxFeeMsat),
+		OutgoingChanIds:  []uint64{outgoingChanId},
+		LastHopPubkey:    lastHopPubkeyBytes,
+		AllowSelfPayment: true,
+		TimeoutSeconds:   60,
+	}
+
+	var resp *lnrpc.Payment
+	for attempt := 1; attempt <= maxCircularPaymentAttempts; attempt++ {
+		payStream, err := svc.client.SendPayment(ctx, sendRequest)
+		if err != nil {
+			logger.Logger.WithField("bolt11", payReq).WithError(err).Error("SendPayment failed")
+			return nil, err
+		}
+
+		resp, err = svc.getPaymentResult(payStream)
+		if err != nil {
+			logger.Logger.WithField("bolt11", payReq).WithError(err).Error("Couldn't get response from paystream")
+			return nil, err
+		}
+
+		if resp.Status == lnrpc.Payment_SUCCEEDED {
+			break
+		}
+
+		logger.Logger.WithFields(logrus.Fields{
+			"bolt11":              payReq,
+			"outgoing_channel_id": outgoingChannelId,
+			"last_hop_pubkey":     lastHopPubkey,
+			"attempt":             attempt,
+			"reason":              resp.FailureReason.String(),
+		}).Warn("Circular payment attempt failed")
+
+		// other failures, e.g. an incorrect payment amount, do not change with another route
+		if resp.FailureReason != lnrpc.PaymentFailureReason_FAILURE_REASON_NO_ROUTE &&
+			resp.FailureReason != lnrpc.PaymentFailureReason_FAILURE_REASON_TIMEOUT {
+			break
+		}
+	}
+
+	if resp.Status != lnrpc.Payment_SUCCEEDED {
+		return nil, errors.New(resp.FailureReason.String())
+	}
+
+	return &lnclient.PayInvoiceResponse{
+		Preimage: resp.PaymentPreimage,
+		Fee:      uint64(resp.FeeMsat),
+	}, nil
+}
+
 func (svc *LNDService) MakeInvoice(ctx context.Context, amount int64, description string, descriptionHash string, expiry int64, throughNodePubkey *string) (transaction *lnclient.Transaction, err error) {
 	var descriptionHashBytes []byte
 
@@ -1147,13 +1362,18 @@ func (svc *LNDService) OpenChannel(ctx context.Context, openChannelRequest *lncl
 		return nil, errors.New("failed to decode pubkey")
 	}
 
-	channel, err := svc.client.OpenChannelSync(ctx, &lnrpc.OpenChannelRequest{
+	lndOpenChannelRequest := &lnrpc.OpenChannelRequest{
 		NodePubkey:         nodePub,
 		Private:            !openChannelRequest.Public,
 		LocalFundingAmount: openChannelRequest.AmountSats,
 		// set a super-high forwarding fee of 100K sats by default to disable unwanted routing
 		BaseFee: 100_000_000,
-	})
+	}
+	if openChannelRequest.FeeRate != nil {
+		lndOpenChannelRequest.SatPerVbyte = *openChannelRequest.FeeRate
+	}
+
+	channel, err := svc.client.OpenChannelSync(ctx, lndOpenChannelRequest)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to open channel")
 		return nil, fmt.Errorf("failed to open channel with %s: %s", foundPeer.NodeId, err)
@@ -1319,6 +1539,27 @@ func (svc *LNDService) GetOnchainBalance(ctx context.Context) (*lnclient.Onchain
 			})
 		}
 	}
+	// force-closed channels whose closing transaction confirmed, until the timelocked outputs are swept
+	for _, forceClosedChannel := range pendingChannels.PendingForceClosingChannels {
+		pendingBalancesFromChannelClosures += uint64(forceClosedChannel.LimboBalance)
+		if forceClosedChannel.Channel != nil {
+			channelPoint, err := svc.parseChannelPoint(forceClosedChannel.Channel.ChannelPoint)
+			if err != nil {
+				return nil, err
+			}
+			blocksUntilSpendable := forceClosedChannel.BlocksTilMaturity
+			for _, htlc := range forceClosedChannel.PendingHtlcs {
+				blocksUntilSpendable = max(blocksUntilSpendable, htlc.BlocksTilMaturity)
+			}
+			pendingBalancesDetails = append(pendingBalancesDetails, lnclient.PendingBalanceDetails{
+				NodeId:               forceClosedChannel.Channel.RemoteNodePub,
+				Amount:               uint64(forceClosedChannel.LimboBalance),
+				FundingTxId:          channelPoint.GetFundingTxidStr(),
+				FundingTxVout:        channelPoint.GetOutputIndex(),
+				BlocksUntilSpendable: uint32(max(blocksUntilSpendable, 0)),
+			})
+		}
+	}
 	logger.Logger.WithFields(logrus.Fields{
 		"balances": balances,
 	}).Debug("Listed Balances")
@@ -1572,7 +1813,7 @@ func (svc *LNDService) GetSupportedNIP47Methods() []string {
 }
 
 func (svc *LNDService) GetSupportedNIP47NotificationTypes() []string {
-	return []string{notifications.PAYMENT_RECEIVED_NOTIFICATION, notifications.PAYMENT_SENT_NOTIFICATION, notifications.HOLD_INVOICE_ACCEPTED_NOTIFICATION}
+	return []string{notifications.PAYMENT_RECEIVED_NOTIFICATION, notifications.PAYMENT_SENT_NOTIFICATION, notifications.HOLD_INVOICE_ACCEPTED_NOTIFICATION, notifications.INVOICE_EXPIRED_NOTIFICATION}
 }
 
 func (svc *LNDService) GetPubkey() string {
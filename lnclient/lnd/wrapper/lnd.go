@@ -244,6 +244,18 @@ func (wrapper *LNDWrapper) SubscribeChannelEvents(ctx context.Context, in *lnrpc
 	return wrapper.client.SubscribeChannelEvents(ctx, in, options...)
 }
 
+func (wrapper *LNDWrapper) ChannelAcceptor(ctx context.Context, options ...grpc.CallOption) (lnrpc.Lightning_ChannelAcceptorClient, error) {
+	return wrapper.client.ChannelAcceptor(ctx, options...)
+}
+
 func (wrapper *LNDWrapper) ForwardingHistory(ctx context.Context, in *lnrpc.ForwardingHistoryRequest, options ...grpc.CallOption) (*lnrpc.ForwardingHistoryResponse, error) {
 	return wrapper.client.ForwardingHistory(ctx, in, options...)
 }
+
+func (wrapper *LNDWrapper) ExportAllChannelBackups(ctx context.Context, in *lnrpc.ChanBackupExportRequest, options ...grpc.CallOption) (*lnrpc.ChanBackupSnapshot, error) {
+	return wrapper.client.ExportAllChannelBackups(ctx, in, options...)
+}
+
+func (wrapper *LNDWrapper) RestoreChannelBackups(ctx context.Context, in *lnrpc.RestoreChanBackupRequest, options ...grpc.CallOption) (*lnrpc.RestoreBackupResponse, error) {
+	return wrapper.client.RestoreChannelBackups(ctx, in, options...)
+}
@@ -3,6 +3,7 @@ package lnclient
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 // TODO: remove JSON tags from these models (LNClient models should not be exposed directly)
@@ -97,6 +98,36 @@ type LNClient interface {
 	ExecuteCustomNodeCommand(ctx context.Context, command *CustomNodeCommandRequest) (*CustomNodeCommandResponse, error)
 }
 
+// MaxRoutingFeeLNClient is implemented by backends which can limit the routing fee of a single payment
+type MaxRoutingFeeLNClient interface {
+	SendPaymentSyncWithMaxFee(payReq string, amount *uint64, maxFeeMsat uint64) (*PayInvoiceResponse, error)
+	SendKeysendWithMaxFee(amount uint64, destination string, customRecords []TLVRecord, preimage string, maxFeeMsat uint64) (*PayKeysendResponse, error)
+}
+
+// CircularPaymentLNClient is implemented by backends which can pay an invoice of the node itself
+// through a chosen outgoing channel, which moves liquidity between the node's channels
+type CircularPaymentLNClient interface {
+	SendCircularPayment(ctx context.Context, payReq string, outgoingChannelId string, lastHopPubkey string, maxFeeMsat uint64) (*PayInvoiceResponse, error)
+}
+
+// ChannelRequest is an inbound request of a peer to open a channel
+type ChannelRequest struct {
+	PeerPubkey    string
+	CapacitySat   uint64
+	PushAmountSat uint64
+	ZeroConf      bool
+}
+
+// ChannelAcceptor decides about an inbound channel request, which is rejected with the returned error
+type ChannelAcceptor func(ctx context.Context, request *ChannelRequest) error
+
+// ChannelAcceptorLNClient is implemented by backends which let the hub decide about each inbound
+// channel request
+type ChannelAcceptorLNClient interface {
+	// AcceptChannels passes inbound channel requests to the acceptor until the client is stopped
+	AcceptChannels(acceptor ChannelAcceptor)
+}
+
 type Channel struct {
 	LocalBalance                             int64
 	LocalSpendableBalance                    int64
@@ -121,6 +152,8 @@ type Channel struct {
 type NodeStatus struct {
 	IsReady            bool        `json:"isReady"`
 	InternalNodeStatus interface{} `json:"internalNodeStatus"`
+	// last time the static channel backup was stored on a backup target, nostr or the Alby account
+	LastChannelBackupAt *time.Time `json:"lastChannelBackupAt,omitempty"`
 }
 
 type ConnectPeerRequest struct {
@@ -133,6 +166,8 @@ type OpenChannelRequest struct {
 	Pubkey     string `json:"pubkey"`
 	AmountSats int64  `json:"amountSats"`
 	Public     bool   `json:"public"`
+	// sat/vB, not supported by LDK which uses its own fee estimate for channel opens
+	FeeRate *uint64 `json:"feeRate,omitempty"`
 }
 
 type OpenChannelResponse struct {
@@ -162,6 +197,8 @@ type PendingBalanceDetails struct {
 	Amount        uint64 `json:"amount"`
 	FundingTxId   string `json:"fundingTxId"`
 	FundingTxVout uint32 `json:"fundingTxVout"`
+	// the blocks until a timelock expires and the balance can be swept, e.g. after a force-close
+	BlocksUntilSpendable uint32 `json:"blocksUntilSpendable"`
 }
 
 type OnchainBalanceResponse struct {
@@ -207,6 +244,10 @@ type PayKeysendResponse struct {
 type BalancesResponse struct {
 	Onchain   OnchainBalanceResponse   `json:"onchain"`
 	Lightning LightningBalanceResponse `json:"lightning"`
+	// Ark balance waiting for a round or board to complete (msat)
+	PendingArk int64 `json:"pendingArk,omitempty"`
+	// part of the lightning balance held as ecash (msat)
+	Ecash int64 `json:"ecash,omitempty"`
 }
 
 type NetworkGraphResponse = interface{}
@@ -0,0 +1,67 @@
+// Package watchonly wraps the LNClient of a watch-only hub, which can show balances,
+// transactions and channels but must never spend or change the node.
+package watchonly
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/lnclient"
+)
+
+var ErrWatchOnly = errors.New("Alby Hub is running in watch-only mode. Payments, on-chain transactions and changes to the node are disabled.")
+
+// watchOnlyService only embeds the LNClient interface, so that optional interfaces of the
+// wrapped backend (e.g. payments with a maximum routing fee) are not exposed either
+type watchOnlyService struct {
+	lnclient.LNClient
+}
+
+func NewWatchOnlyService(lnClient lnclient.LNClient) lnclient.LNClient {
+	return &watchOnlyService{LNClient: lnClient}
+}
+
+func (svc *watchOnlyService) SendPaymentSync(payReq string, amount *uint64) (*lnclient.PayInvoiceResponse, error) {
+	return nil, ErrWatchOnly
+}
+
+func (svc *watchOnlyService) SendKeysend(amount uint64, destination string, customRecords []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
+	return nil, ErrWatchOnly
+}
+
+func (svc *watchOnlyService) ConnectPeer(ctx context.Context, connectPeerRequest *lnclient.ConnectPeerRequest) error {
+	return ErrWatchOnly
+}
+
+func (svc *watchOnlyService) DisconnectPeer(ctx context.Context, peerId string) error {
+	return ErrWatchOnly
+}
+
+func (svc *watchOnlyService) OpenChannel(ctx context.Context, openChannelRequest *lnclient.OpenChannelRequest) (*lnclient.OpenChannelResponse, error) {
+	return nil, ErrWatchOnly
+}
+
+func (svc *watchOnlyService) CloseChannel(ctx context.Context, closeChannelRequest *lnclient.CloseChannelRequest) (*lnclient.CloseChannelResponse, error) {
+	return nil, ErrWatchOnly
+}
+
+func (svc *watchOnlyService) UpdateChannel(ctx context.Context, updateChannelRequest *lnclient.UpdateChannelRequest) error {
+	return ErrWatchOnly
+}
+
+func (svc *watchOnlyService) RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, feeRate *uint64, sendAll bool) (string, error) {
+	return "", ErrWatchOnly
+}
+
+func (svc *watchOnlyService) ResetRouter(key string) error {
+	return ErrWatchOnly
+}
+
+// SignMessage is disabled as signatures of the node key can be used to log in to services
+func (svc *watchOnlyService) SignMessage(ctx context.Context, message string) (string, error) {
+	return "", ErrWatchOnly
+}
+
+func (svc *watchOnlyService) ExecuteCustomNodeCommand(ctx context.Context, command *lnclient.CustomNodeCommandRequest) (*lnclient.CustomNodeCommandResponse, error) {
+	return nil, ErrWatchOnly
+}
@@ -0,0 +1,140 @@
+package lnurl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+)
+
+// FetchLightningAddressInvoice requests an invoice for the given amount from a lightning address
+// using LNURL-pay (LUD-06, LUD-16)
+func FetchLightningAddressInvoice(ctx context.Context, lightningAddress string, amountMsat uint64) (string, error) {
+	lnurlpUrl, err := LightningAddressPayUrl(lightningAddress)
+	if err != nil {
+		return "", err
+	}
+	return FetchPayInvoice(ctx, lnurlpUrl, &amountMsat)
+}
+
+// LightningAddressPayUrl returns the LNURL-pay endpoint of a lightning address (LUD-16)
+func LightningAddressPayUrl(lightningAddress string) (string, error) {
+	parts := strings.Split(lightningAddress, "@")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", errors.New("invalid lightning address")
+	}
+	return fmt.Sprintf("https://%s/.well-known/lnurlp/%s", parts[1], url.PathEscape(parts[0])), nil
+}
+
+// FetchPayInvoice requests an invoice from an LNURL-pay endpoint (LUD-06).
+// Without an amount, the invoice is requested for the minimum amount the endpoint accepts.
+func FetchPayInvoice(ctx context.Context, lnurlpUrl string, amountMsat *uint64) (string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	var payResponse struct {
+		Callback    string `json:"callback"`
+		MinSendable uint64 `json:"minSendable"`
+		MaxSendable uint64 `json:"maxSendable"`
+		Metadata    string `json:"metadata"`
+		Tag         string `json:"tag"`
+	}
+	if err := getLnurlJson(ctx, client, lnurlpUrl, &payResponse); err != nil {
+		return "", err
+	}
+	if payResponse.Tag != "payRequest" || payResponse.Callback == "" {
+		return "", errors.New("lightning address does not support payments")
+	}
+	if amountMsat == nil {
+		amountMsat = &payResponse.MinSendable
+	}
+	if *amountMsat < payResponse.MinSendable || (payResponse.MaxSendable > 0 && *amountMsat > payResponse.MaxSendable) {
+		return "", fmt.Errorf("amount must be between %d and %d msat", payResponse.MinSendable, payResponse.MaxSendable)
+	}
+
+	callbackUrl, err := url.Parse(payResponse.Callback)
+	if err != nil {
+		return "", err
+	}
+	query := callbackUrl.Query()
+	query.Set("amount", strconv.FormatUint(*amountMsat, 10))
+	callbackUrl.RawQuery = query.Encode()
+
+	var invoiceResponse struct {
+		Pr     string `json:"pr"`
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := getLnurlJson(ctx, client, callbackUrl.String(), &invoiceResponse); err != nil {
+		return "", err
+	}
+	if invoiceResponse.Status == "ERROR" {
+		return "", errors.New(invoiceResponse.Reason)
+	}
+	if invoiceResponse.Pr == "" {
+		return "", errors.New("no invoice returned")
+	}
+
+	if err := validatePayInvoice(invoiceResponse.Pr, *amountMsat, payResponse.Metadata); err != nil {
+		return "", err
+	}
+
+	return invoiceResponse.Pr, nil
+}
+
+// validatePayInvoice ensures the invoice returned by the callback is for the requested amount and
+// commits to the metadata of the endpoint, so a compromised server cannot request a different payment
+func validatePayInvoice(invoice string, amountMsat uint64, metadata string) error {
+	paymentRequest, err := decodepay.Decodepay(strings.ToLower(invoice))
+	if err != nil {
+		return fmt.Errorf("invalid invoice returned: %w", err)
+	}
+	if paymentRequest.MSatoshi != int64(amountMsat) {
+		return fmt.Errorf("invoice amount %d msat does not match the requested %d msat", paymentRequest.MSatoshi, amountMsat)
+	}
+	metadataHash := sha256.Sum256([]byte(metadata))
+	if !strings.EqualFold(paymentRequest.DescriptionHash, hex.EncodeToString(metadataHash[:])) {
+		return errors.New("invoice description hash does not match the metadata")
+	}
+	return nil
+}
+
+// ResolvePayNode returns the pubkey of the node which issues the invoices of an LNURL-pay endpoint
+func ResolvePayNode(ctx context.Context, lnurlpUrl string) (string, error) {
+	invoice, err := FetchPayInvoice(ctx, lnurlpUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	paymentRequest, err := decodepay.Decodepay(strings.ToLower(invoice))
+	if err != nil {
+		return "", fmt.Errorf("invalid invoice returned: %w", err)
+	}
+	if paymentRequest.Payee == "" {
+		return "", errors.New("invoice has no payee")
+	}
+	return paymentRequest.Payee, nil
+}
+
+func getLnurlJson(ctx context.Context, client *http.Client, requestUrl string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,91 @@
+package lnurl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMetadata = `[["text/plain","pay bob"]]`
+
+func createTestInvoice(t *testing.T, amountMsat uint64, metadata string) string {
+	privateKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	var paymentHash [32]byte
+	descriptionHash := sha256.Sum256([]byte(metadata))
+	invoice, err := zpay32.NewInvoice(&chaincfg.MainNetParams, paymentHash, time.Now(),
+		zpay32.Amount(lnwire.MilliSatoshi(amountMsat)), zpay32.DescriptionHash(descriptionHash))
+	require.NoError(t, err)
+
+	encoded, err := invoice.Encode(zpay32.MessageSigner{
+		SignCompact: func(msg []byte) ([]byte, error) {
+			return ecdsa.SignCompact(privateKey, chainhash.HashB(msg), true), nil
+		},
+	})
+	require.NoError(t, err)
+	return encoded
+}
+
+func newTestLnurlServer(t *testing.T, invoice string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/lnurlp":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"tag":         "payRequest",
+				"callback":    server.URL + "/callback",
+				"minSendable": 1000,
+				"maxSendable": 1_000_000_000,
+				"metadata":    testMetadata,
+			})
+		case "/callback":
+			json.NewEncoder(w).Encode(map[string]interface{}{"pr": invoice})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestFetchPayInvoice(t *testing.T) {
+	invoice := createTestInvoice(t, 21_000, testMetadata)
+	server := newTestLnurlServer(t, invoice)
+	defer server.Close()
+
+	amountMsat := uint64(21_000)
+	pr, err := FetchPayInvoice(context.TODO(), server.URL+"/lnurlp", &amountMsat)
+	require.NoError(t, err)
+	assert.Equal(t, invoice, pr)
+}
+
+func TestFetchPayInvoice_WrongAmount(t *testing.T) {
+	server := newTestLnurlServer(t, createTestInvoice(t, 2_100_000, testMetadata))
+	defer server.Close()
+
+	amountMsat := uint64(21_000)
+	_, err := FetchPayInvoice(context.TODO(), server.URL+"/lnurlp", &amountMsat)
+	assert.EqualError(t, err, "invoice amount 2100000 msat does not match the requested 21000 msat")
+}
+
+func TestFetchPayInvoice_WrongDescriptionHash(t *testing.T) {
+	server := newTestLnurlServer(t, createTestInvoice(t, 21_000, `[["text/plain","pay mallory"]]`))
+	defer server.Close()
+
+	amountMsat := uint64(21_000)
+	_, err := FetchPayInvoice(context.TODO(), server.URL+"/lnurlp", &amountMsat)
+	assert.EqualError(t, err, "invoice description hash does not match the metadata")
+}
@@ -25,9 +25,14 @@ func Init(logLevel string) {
 	if err != nil {
 		logrusLogLevel = int(logrus.InfoLevel)
 	}
-	Logger.SetLevel(logrus.Level(logrusLogLevel))
-	if logrusLogLevel >= int(logrus.DebugLevel) {
-		Logger.ReportCaller = true
+	SetLevel(logrus.Level(logrusLogLevel))
+}
+
+// SetLevel changes the log level, e.g. when it is updated in the settings
+func SetLevel(level logrus.Level) {
+	Logger.SetLevel(level)
+	Logger.ReportCaller = level >= logrus.DebugLevel
+	if Logger.ReportCaller {
 		Logger.Debug("Logrus report caller enabled in debug mode")
 	}
 }
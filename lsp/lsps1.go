@@ -0,0 +1,220 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/version"
+)
+
+// LSPS1 order states
+const (
+	LSPS1_ORDER_STATE_CREATED   = "CREATED"
+	LSPS1_ORDER_STATE_COMPLETED = "COMPLETED"
+	LSPS1_ORDER_STATE_FAILED    = "FAILED"
+)
+
+// LSPs which expect a token to learn that the hub supports 0-conf channels
+// (e.g. LNServer before v1.17.2 and Flashsats before v1.21.0 do not)
+var zeroConfTokenLSPs = []string{"olympus", "lnserver", "flashsats"}
+
+// OrderToken returns the token to send with an order to the LSP
+func OrderToken(lspIdentifier string) string {
+	if slices.Contains(zeroConfTokenLSPs, lspIdentifier) {
+		return "AlbyHub/" + version.Tag
+	}
+	return ""
+}
+
+// ParseLSPS1LSPs parses comma-separated identifier=url pairs of LSPs which are requested
+// directly, rather than through the Alby API
+func ParseLSPS1LSPs(value string) (map[string]string, error) {
+	lsps := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		identifier, lspUrl, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid LSP %q, expected identifier=url", entry)
+		}
+		identifier = strings.TrimSpace(identifier)
+		lspUrl = strings.TrimSuffix(strings.TrimSpace(lspUrl), "/")
+		parsedUrl, err := url.Parse(lspUrl)
+		if err != nil || (parsedUrl.Scheme != "https" && parsedUrl.Scheme != "http") || parsedUrl.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q for LSP %q", lspUrl, identifier)
+		}
+		lsps[identifier] = lspUrl
+	}
+	return lsps, nil
+}
+
+type LSPS1Info struct {
+	MinRequiredChannelConfirmations uint64   `json:"min_required_channel_confirmations"`
+	MinFundingConfirmsWithinBlocks  uint64   `json:"min_funding_confirms_within_blocks"`
+	MaxChannelExpiryBlocks          uint64   `json:"max_channel_expiry_blocks"`
+	MinChannelBalanceSat            string   `json:"min_channel_balance_sat"`
+	MaxChannelBalanceSat            string   `json:"max_channel_balance_sat"`
+	URIs                            []string `json:"uris"`
+}
+
+type LSPS1OrderRequest struct {
+	PublicKey                    string `json:"public_key"`
+	LSPBalanceSat                string `json:"lsp_balance_sat"`
+	ClientBalanceSat             string `json:"client_balance_sat"`
+	RequiredChannelConfirmations uint64 `json:"required_channel_confirmations"`
+	FundingConfirmsWithinBlocks  uint64 `json:"funding_confirms_within_blocks"`
+	ChannelExpiryBlocks          uint64 `json:"channel_expiry_blocks"`
+	Token                        string `json:"token"`
+	RefundOnchainAddress         string `json:"refund_onchain_address"`
+	AnnounceChannel              bool   `json:"announce_channel"`
+}
+
+type LSPS1Bolt11Payment struct {
+	State         string `json:"state"` // EXPECT_PAYMENT, HOLD, PAID or REFUNDED
+	ExpiresAt     string `json:"expires_at"`
+	FeeTotalSat   string `json:"fee_total_sat"`
+	OrderTotalSat string `json:"order_total_sat"`
+	Invoice       string `json:"invoice"`
+}
+
+type LSPS1Payment struct {
+	Bolt11 LSPS1Bolt11Payment `json:"bolt11"`
+	// TODO: add onchain
+}
+
+type LSPS1Channel struct {
+	FundedAt        string `json:"funded_at"`
+	FundingOutpoint string `json:"funding_outpoint"`
+	ExpiresAt       string `json:"expires_at"`
+}
+
+type LSPS1Order struct {
+	OrderId       string        `json:"order_id"`
+	LSPBalanceSat string        `json:"lsp_balance_sat"`
+	OrderState    string        `json:"order_state"`
+	Payment       *LSPS1Payment `json:"payment"`
+	Channel       *LSPS1Channel `json:"channel"`
+}
+
+// LSPS1Client requests the LSPS1 HTTP API of an LSP, see
+// https://github.com/lightning/blips/blob/master/blip-0051.md
+type LSPS1Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewLSPS1Client creates a client for the API at url. The http client may add authentication,
+// e.g. for LSPs requested through the Alby API.
+func NewLSPS1Client(url string, httpClient *http.Client) *LSPS1Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	httpClient.Timeout = 30 * time.Second
+	return &LSPS1Client{
+		url:        strings.TrimSuffix(url, "/"),
+		httpClient: httpClient,
+	}
+}
+
+func (client *LSPS1Client) GetInfo(ctx context.Context) (*LSPS1Info, error) {
+	info := &LSPS1Info{}
+	if err := client.request(ctx, http.MethodGet, "/v1/get_info", nil, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (client *LSPS1Client) CreateOrder(ctx context.Context, orderRequest *LSPS1OrderRequest) (*LSPS1Order, error) {
+	order := &LSPS1Order{}
+	if err := client.request(ctx, http.MethodPost, "/v1/create_order", orderRequest, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (client *LSPS1Client) GetOrder(ctx context.Context, orderId string) (*LSPS1Order, error) {
+	order := &LSPS1Order{}
+	if err := client.request(ctx, http.MethodGet, "/v1/get_order?order_id="+url.QueryEscape(orderId), nil, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (client *LSPS1Client) request(ctx context.Context, method string, path string, payload interface{}, result interface{}) error {
+	var bodyReader io.Reader
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(payloadBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, client.url+path, bodyReader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AlbyHub/"+version.Tag)
+
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return errors.New("failed to read response body")
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("LSP endpoint %s returned non-success code %d: %s", path, res.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, result)
+}
+
+// ParseNodeURI splits a pubkey@host:port node URI into the pubkey and the address
+func ParseNodeURI(uri string) (pubkey string, address string, err error) {
+	pubkey, address, ok := strings.Cut(strings.TrimSpace(uri), "@")
+	if !ok || !regexp.MustCompile(`^0[23][0-9a-f]{64}$`).MatchString(pubkey) {
+		return "", "", fmt.Errorf("invalid node URI %q, expected pubkey@host:port", uri)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", "", fmt.Errorf("invalid node URI %q: %w", uri, err)
+	}
+	return pubkey, address, nil
+}
+
+// ParseURI returns the node of the first clearnet IPv4 URI of the LSP
+func ParseURI(uris []string) (pubkey string, address string, port uint16, err error) {
+	regex := regexp.MustCompile(`^([0-9a-f]+)@([0-9]+\.[0-9]+\.[0-9]+\.[0-9]+):([0-9]+)$`)
+	for _, uri := range uris {
+		if strings.Contains(uri, ".onion") {
+			continue
+		}
+		parts := regex.FindStringSubmatch(uri)
+		if parts == nil {
+			continue
+		}
+		parsedPort, err := strconv.ParseUint(parts[3], 10, 16)
+		if err != nil {
+			continue
+		}
+		return parts[1], parts[2], uint16(parsedPort), nil
+	}
+	return "", "", 0, errors.New("could not decode LSP URI")
+}
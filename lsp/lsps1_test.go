@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURI(t *testing.T) {
+	pubkey, address, port, err := ParseURI([]string{
+		"031b301307574bbe9b9ac7b79cbe1700e31e544513eae0b5d7497483083f99e581@abcdef.onion:9735",
+		"031b301307574bbe9b9ac7b79cbe1700e31e544513eae0b5d7497483083f99e581@45.79.192.236:9735",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "031b301307574bbe9b9ac7b79cbe1700e31e544513eae0b5d7497483083f99e581", pubkey)
+	assert.Equal(t, "45.79.192.236", address)
+	assert.Equal(t, uint16(9735), port)
+
+	_, _, _, err = ParseURI([]string{"031b30@abcdef.onion:9735"})
+	assert.Error(t, err)
+}
+
+func TestParseLSPS1LSPs(t *testing.T) {
+	lsps, err := ParseLSPS1LSPs("mylsp=https://lsp.example.com/api/, other = http://10.0.0.1:8080")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"mylsp": "https://lsp.example.com/api",
+		"other": "http://10.0.0.1:8080",
+	}, lsps)
+
+	_, err = ParseLSPS1LSPs("mylsp")
+	assert.Error(t, err)
+	_, err = ParseLSPS1LSPs("mylsp=lsp.example.com")
+	assert.Error(t, err)
+}
+
+func TestOrderToken(t *testing.T) {
+	assert.NotEmpty(t, OrderToken("olympus"))
+	assert.Empty(t, OrderToken("megalith"))
+}
+
+func TestParseNodeURI(t *testing.T) {
+	pubkey, address, err := ParseNodeURI("031b301307574bbe9b9ac7b79cbe1700e31e544513eae0b5d7497483083f99e581@lsp.example.com:9735")
+	require.NoError(t, err)
+	assert.Equal(t, "031b301307574bbe9b9ac7b79cbe1700e31e544513eae0b5d7497483083f99e581", pubkey)
+	assert.Equal(t, "lsp.example.com:9735", address)
+
+	_, _, err = ParseNodeURI("031b30@lsp.example.com:9735")
+	assert.Error(t, err)
+	_, _, err = ParseNodeURI("031b301307574bbe9b9ac7b79cbe1700e31e544513eae0b5d7497483083f99e581@lsp.example.com")
+	assert.Error(t, err)
+}
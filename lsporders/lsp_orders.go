@@ -0,0 +1,273 @@
+// Package lsporders purchases inbound channels from LSPs with the LSPS1 protocol and tracks the
+// orders until the channel is opened. LSPs configured with LSPS1_LSPS are requested directly,
+// others through the Alby API.
+package lsporders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/lsp"
+)
+
+var checkInterval = time.Minute
+
+// orders which are not completed within this time are no longer checked
+const maxOrderAge = 7 * 24 * time.Hour
+
+type LSPOrdersService interface {
+	CreateOrder(ctx context.Context, lnClient lnclient.LNClient, lspIdentifier string, amount uint64, public bool) (*db.LSPOrder, error)
+	ListOrders() ([]db.LSPOrder, error)
+	Start(ctx context.Context)
+}
+
+type lspOrdersService struct {
+	db             *gorm.DB
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+	albyOAuthSvc   alby.AlbyOAuthService
+}
+
+func NewLSPOrdersService(db *gorm.DB, cfg config.Config, eventPublisher events.EventPublisher, albyOAuthSvc alby.AlbyOAuthService) *lspOrdersService {
+	return &lspOrdersService{
+		db:             db,
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+		albyOAuthSvc:   albyOAuthSvc,
+	}
+}
+
+func (svc *lspOrdersService) getClient(ctx context.Context, lspIdentifier, network string) (*lsp.LSPS1Client, error) {
+	configuredLSPs, err := lsp.ParseLSPS1LSPs(svc.cfg.GetEnv().LSPS1LSPs)
+	if err != nil {
+		return nil, err
+	}
+	if lspUrl, ok := configuredLSPs[lspIdentifier]; ok {
+		return lsp.NewLSPS1Client(lspUrl, nil), nil
+	}
+	return svc.albyOAuthSvc.NewLSPS1Client(ctx, lspIdentifier, network)
+}
+
+// CreateOrder connects to the LSP and orders a channel with amount sats of inbound liquidity.
+// The invoice of the order has to be paid to open the channel.
+func (svc *lspOrdersService) CreateOrder(ctx context.Context, lnClient lnclient.LNClient, lspIdentifier string, amount uint64, public bool) (*db.LSPOrder, error) {
+	logger.Logger.Info("Requesting own node info")
+
+	nodeInfo, err := lnClient.GetInfo(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"lspIdentifier": lspIdentifier,
+		}).Error("Failed to request own node info", err)
+		return nil, err
+	}
+
+	lsps1Client, err := svc.getClient(ctx, lspIdentifier, nodeInfo.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Info("Requesting LSP info")
+	lspInfo, err := lsps1Client.GetInfo(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to request LSP info")
+		return nil, err
+	}
+
+	pubkey, address, port, err := lsp.ParseURI(lspInfo.URIs)
+	if err != nil {
+		logger.Logger.WithField("uris", lspInfo.URIs).WithError(err).Error("Unsupported URI")
+		return nil, err
+	}
+
+	logger.Logger.WithField("lspInfo", lspInfo).Info("Connecting to LSP node as a peer")
+
+	err = lnClient.ConnectPeer(ctx, &lnclient.ConnectPeerRequest{
+		Pubkey:  pubkey,
+		Address: address,
+		Port:    port,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to connect to peer")
+		return nil, err
+	}
+
+	refundAddress, err := lnClient.GetNewOnchainAddress(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to request onchain address")
+		return nil, err
+	}
+
+	var requiredChannelConfirmations uint64 = 0
+
+	backendType, err := svc.cfg.Get("LNBackendType", "")
+	if err != nil {
+		return nil, errors.New("failed to get LN backend type")
+	}
+
+	if backendType != config.LDKBackendType {
+		// LND does not support 0-conf by default
+		requiredChannelConfirmations = 1
+	}
+
+	// Some LSPs (e.g. Olympus) require more min confirmations, as per the spec ours must be at least as many blocks
+	requiredChannelConfirmations = max(requiredChannelConfirmations, lspInfo.MinRequiredChannelConfirmations)
+
+	if public {
+		// as per BOLT-7 6 confirmations are required for the channel to be gossiped
+		// https://github.com/lightning/bolts/blob/master/07-routing-gossip.md#requirements
+		requiredChannelConfirmations = 6
+	}
+
+	order, err := lsps1Client.CreateOrder(ctx, &lsp.LSPS1OrderRequest{
+		PublicKey:                    nodeInfo.Pubkey,
+		LSPBalanceSat:                strconv.FormatUint(amount, 10),
+		ClientBalanceSat:             "0",
+		RequiredChannelConfirmations: requiredChannelConfirmations,
+		FundingConfirmsWithinBlocks:  lspInfo.MinFundingConfirmsWithinBlocks,
+		ChannelExpiryBlocks:          lspInfo.MaxChannelExpiryBlocks,
+		Token:                        lsp.OrderToken(lspIdentifier),
+		RefundOnchainAddress:         refundAddress,
+		AnnounceChannel:              public,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).WithField("lspIdentifier", lspIdentifier).Error("Failed to create LSP order")
+		return nil, err
+	}
+
+	dbOrder := db.LSPOrder{
+		LSPIdentifier: lspIdentifier,
+		Network:       nodeInfo.Network,
+		OrderId:       order.OrderId,
+		State:         order.OrderState,
+		LSPBalanceSat: amount,
+	}
+	if order.Payment != nil {
+		dbOrder.Invoice = order.Payment.Bolt11.Invoice
+		dbOrder.PaymentState = order.Payment.Bolt11.State
+		dbOrder.FeeSat, err = strconv.ParseUint(order.Payment.Bolt11.FeeTotalSat, 10, 64)
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"lspIdentifier": lspIdentifier,
+			}).Error("Failed to parse fee")
+			return nil, fmt.Errorf("failed to parse fee %v", err)
+		}
+	}
+	if dbOrder.State == "" {
+		dbOrder.State = lsp.LSPS1_ORDER_STATE_CREATED
+	}
+
+	// orders without an id cannot be tracked, but are still returned so they can be paid
+	if dbOrder.OrderId != "" {
+		if err := svc.db.Create(&dbOrder).Error; err != nil {
+			logger.Logger.WithError(err).WithField("order_id", dbOrder.OrderId).Error("Failed to save LSP order")
+			return nil, err
+		}
+	}
+
+	return &dbOrder, nil
+}
+
+func (svc *lspOrdersService) ListOrders() ([]db.LSPOrder, error) {
+	orders := []db.LSPOrder{}
+	if err := svc.db.Order("id DESC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Start checks the state of open orders periodically until ctx is done
+func (svc *lspOrdersService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				svc.checkOrders(ctx)
+			}
+		}
+	}()
+}
+
+func (svc *lspOrdersService) checkOrders(ctx context.Context) {
+	orders := []db.LSPOrder{}
+	err := svc.db.Where("state = ? AND created_at > ?", lsp.LSPS1_ORDER_STATE_CREATED, time.Now().Add(-maxOrderAge)).Find(&orders).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list LSP orders")
+		return
+	}
+
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			return
+		}
+		svc.checkOrder(ctx, &order)
+	}
+}
+
+func (svc *lspOrdersService) checkOrder(ctx context.Context, dbOrder *db.LSPOrder) {
+	lsps1Client, err := svc.getClient(ctx, dbOrder.LSPIdentifier, dbOrder.Network)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("order_id", dbOrder.OrderId).Error("Failed to create LSP client")
+		return
+	}
+	order, err := lsps1Client.GetOrder(ctx, dbOrder.OrderId)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("order_id", dbOrder.OrderId).Error("Failed to get LSP order")
+		return
+	}
+
+	paymentState := dbOrder.PaymentState
+	if order.Payment != nil {
+		paymentState = order.Payment.Bolt11.State
+	}
+	fundingOutpoint := dbOrder.FundingOutpoint
+	if order.Channel != nil {
+		fundingOutpoint = order.Channel.FundingOutpoint
+	}
+	if order.OrderState == dbOrder.State && paymentState == dbOrder.PaymentState && fundingOutpoint == dbOrder.FundingOutpoint {
+		return
+	}
+
+	dbOrder.State = order.OrderState
+	dbOrder.PaymentState = paymentState
+	dbOrder.FundingOutpoint = fundingOutpoint
+	err = svc.db.Model(dbOrder).Select("state", "payment_state", "funding_outpoint").Updates(dbOrder).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("order_id", dbOrder.OrderId).Error("Failed to update LSP order")
+		return
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"order_id":         dbOrder.OrderId,
+		"lsp":              dbOrder.LSPIdentifier,
+		"state":            dbOrder.State,
+		"payment_state":    dbOrder.PaymentState,
+		"funding_outpoint": dbOrder.FundingOutpoint,
+	}).Info("LSP order updated")
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_lsp_order_updated",
+		Properties: map[string]interface{}{
+			"order_id":         dbOrder.OrderId,
+			"lsp":              dbOrder.LSPIdentifier,
+			"state":            dbOrder.State,
+			"payment_state":    dbOrder.PaymentState,
+			"funding_outpoint": dbOrder.FundingOutpoint,
+			"lsp_balance_sat":  dbOrder.LSPBalanceSat,
+		},
+	})
+}
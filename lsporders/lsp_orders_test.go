@@ -0,0 +1,82 @@
+package lsporders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lsp"
+	"github.com/getAlby/hub/tests"
+)
+
+func startLSPServer(t *testing.T, orderState *atomic.Value) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/get_info":
+			w.Write([]byte(`{"min_required_channel_confirmations":0,"min_funding_confirms_within_blocks":6,"max_channel_expiry_blocks":13000,"uris":["031b301307574bbe9b9ac7b79cbe1700e31e544513eae0b5d7497483083f99e581@45.79.192.236:9735"]}`))
+		case "/v1/create_order":
+			var orderRequest lsp.LSPS1OrderRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&orderRequest))
+			assert.Equal(t, "1000000", orderRequest.LSPBalanceSat)
+			assert.Equal(t, tests.MockOnchainAddress, orderRequest.RefundOnchainAddress)
+			w.Write([]byte(`{"order_id":"order1","lsp_balance_sat":"1000000","order_state":"CREATED","payment":{"bolt11":{"state":"EXPECT_PAYMENT","fee_total_sat":"5000","order_total_sat":"5000","invoice":"lnbc50u1"}}}`))
+		case "/v1/get_order":
+			assert.Equal(t, "order1", r.URL.Query().Get("order_id"))
+			w.Write([]byte(`{"order_id":"order1","order_state":"` + orderState.Load().(string) + `","payment":{"bolt11":{"state":"PAID"}},"channel":{"funding_outpoint":"abcd:0"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLSPOrders(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	var orderState atomic.Value
+	orderState.Store(lsp.LSPS1_ORDER_STATE_CREATED)
+	svc.Cfg.GetEnv().LSPS1LSPs = "mylsp=" + startLSPServer(t, &orderState).URL
+
+	consumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(consumer)
+	lspOrdersSvc := NewLSPOrdersService(svc.DB, svc.Cfg, svc.EventPublisher, nil)
+
+	order, err := lspOrdersSvc.CreateOrder(context.TODO(), svc.LNClient, "mylsp", 1_000_000, false)
+	require.NoError(t, err)
+	assert.Equal(t, "order1", order.OrderId)
+	assert.Equal(t, uint64(5000), order.FeeSat)
+	assert.Equal(t, "lnbc50u1", order.Invoice)
+
+	// paid, but the channel is not opened yet
+	lspOrdersSvc.checkOrders(context.TODO())
+	var dbOrder db.LSPOrder
+	require.NoError(t, svc.DB.First(&dbOrder).Error)
+	assert.Equal(t, lsp.LSPS1_ORDER_STATE_CREATED, dbOrder.State)
+	assert.Equal(t, "PAID", dbOrder.PaymentState)
+
+	orderState.Store(lsp.LSPS1_ORDER_STATE_COMPLETED)
+	lspOrdersSvc.checkOrders(context.TODO())
+	// completed orders are no longer checked
+	lspOrdersSvc.checkOrders(context.TODO())
+
+	orders, err := lspOrdersSvc.ListOrders()
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, lsp.LSPS1_ORDER_STATE_COMPLETED, orders[0].State)
+	assert.Equal(t, "abcd:0", orders[0].FundingOutpoint)
+
+	consumedEvents := consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 2)
+	assert.Equal(t, "nwc_lsp_order_updated", consumedEvents[1].Event)
+	assert.Equal(t, lsp.LSPS1_ORDER_STATE_COMPLETED, consumedEvents[1].Properties.(map[string]interface{})["state"])
+}
@@ -0,0 +1,215 @@
+package maintenance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// A key rotation replaces the data key of the encrypted columns. The new key is kept in the
+// user config, encrypted with the unlock password, until all values are re-encrypted with it.
+// If the hub is stopped during the rotation, it continues when the hub is unlocked again.
+
+// only one rotation at a time, also across service instances
+var keyRotationMutex sync.Mutex
+
+type KeyRotationStatus struct {
+	InProgress bool `json:"inProgress"`
+	// false if the rotation was interrupted, it continues when the hub is unlocked
+	Running  bool                    `json:"running"`
+	Progress *db.KeyRotationProgress `json:"progress,omitempty"`
+}
+
+func (svc *maintenanceService) getKeyRotationProgress() (*db.KeyRotationProgress, error) {
+	progressJson, err := svc.cfg.Get(config.DatabaseKeyRotationProgressKey, "")
+	if err != nil || progressJson == "" {
+		return nil, err
+	}
+	progress := &db.KeyRotationProgress{}
+	if err := json.Unmarshal([]byte(progressJson), progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (svc *maintenanceService) saveKeyRotationProgress(progress *db.KeyRotationProgress) error {
+	progressJson, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return svc.cfg.SetUpdate(config.DatabaseKeyRotationProgressKey, string(progressJson), "")
+}
+
+func (svc *maintenanceService) GetKeyRotationStatus() (*KeyRotationStatus, error) {
+	progress, err := svc.getKeyRotationProgress()
+	if err != nil {
+		return nil, err
+	}
+	running := !keyRotationMutex.TryLock()
+	if !running {
+		keyRotationMutex.Unlock()
+	}
+	return &KeyRotationStatus{
+		InProgress: progress != nil,
+		Running:    running && progress != nil,
+		Progress:   progress,
+	}, nil
+}
+
+// RotateEncryptionKey creates a new data key and re-encrypts the encrypted columns with it in
+// the background. If a new unlock password is given, e.g. because the secret in the KMS was
+// rotated, the encrypted user config is re-encrypted with it first. The hub has to be unlocked.
+func (svc *maintenanceService) RotateEncryptionKey(currentUnlockPassword string, newUnlockPassword string) error {
+	if !keyRotationMutex.TryLock() {
+		return errors.New("the encryption key is already being rotated")
+	}
+	unlockMutex := true
+	defer func() {
+		if unlockMutex {
+			keyRotationMutex.Unlock()
+		}
+	}()
+
+	if !svc.cfg.CheckUnlockPassword(currentUnlockPassword) {
+		return errors.New("incorrect password")
+	}
+	previousKey, err := svc.getDataKey(config.DatabaseEncryptionKeyKey, currentUnlockPassword)
+	if err != nil {
+		return err
+	}
+	if previousKey == nil {
+		return errors.New("database encryption is not enabled")
+	}
+
+	// an interrupted rotation is continued with its key
+	nextKey, err := svc.getDataKey(config.DatabaseNextEncryptionKeyKey, currentUnlockPassword)
+	if err != nil {
+		return err
+	}
+	if nextKey == nil {
+		nextKey = make([]byte, 32)
+		if _, err := rand.Read(nextKey); err != nil {
+			return err
+		}
+		err = svc.cfg.SetUpdate(config.DatabaseNextEncryptionKeyKey, hex.EncodeToString(nextKey), currentUnlockPassword)
+		if err != nil {
+			return err
+		}
+		totalValues, err := db.CountEncryptedValues(svc.db)
+		if err != nil {
+			return err
+		}
+		err = svc.saveKeyRotationProgress(&db.KeyRotationProgress{
+			StartedAt:   time.Now().UTC(),
+			TotalValues: totalValues,
+		})
+		if err != nil {
+			return err
+		}
+		logger.Logger.WithField("total_values", totalValues).Info("Started database encryption key rotation")
+	}
+
+	unlockPassword := currentUnlockPassword
+	if newUnlockPassword != "" && newUnlockPassword != currentUnlockPassword {
+		// also re-encrypts both data keys
+		if err := svc.cfg.ChangeUnlockPassword(currentUnlockPassword, newUnlockPassword); err != nil {
+			return err
+		}
+		unlockPassword = newUnlockPassword
+	}
+
+	err = db.SetRotatingEncryptionKey(nextKey, previousKey, svc.cfg.GetEnv().EncryptDatabase)
+	if err != nil {
+		return err
+	}
+
+	unlockMutex = false
+	go func() {
+		defer keyRotationMutex.Unlock()
+		svc.reencrypt(context.Background(), unlockPassword, nextKey)
+	}()
+	return nil
+}
+
+// ResumeKeyRotation continues an interrupted rotation after the hub was unlocked.
+// The data keys are already loaded by the service.
+func (svc *maintenanceService) ResumeKeyRotation(ctx context.Context, unlockPassword string) {
+	nextKey, err := svc.getDataKey(config.DatabaseNextEncryptionKeyKey, unlockPassword)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to load the next database encryption key")
+		return
+	}
+	if nextKey == nil || !keyRotationMutex.TryLock() {
+		return
+	}
+	logger.Logger.Info("Resuming database encryption key rotation")
+	go func() {
+		defer keyRotationMutex.Unlock()
+		svc.reencrypt(ctx, unlockPassword, nextKey)
+	}()
+}
+
+func (svc *maintenanceService) reencrypt(ctx context.Context, unlockPassword string, nextKey []byte) {
+	progress, err := svc.getKeyRotationProgress()
+	if err == nil && progress == nil {
+		progress = &db.KeyRotationProgress{StartedAt: time.Now().UTC()}
+	}
+	if err == nil {
+		progress.Error = ""
+		err = db.ReencryptValues(ctx, svc.db, progress, svc.saveKeyRotationProgress)
+	}
+	if err == nil {
+		err = svc.finishKeyRotation(unlockPassword, nextKey)
+	}
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to rotate database encryption key")
+		if progress != nil {
+			progress.Error = err.Error()
+			if err := svc.saveKeyRotationProgress(progress); err != nil {
+				logger.Logger.WithError(err).Error("Failed to save key rotation progress")
+			}
+		}
+		return
+	}
+	logger.Logger.WithField("reencrypted_values", progress.ReencryptedCount).Info("Finished database encryption key rotation")
+}
+
+// finishKeyRotation replaces the data key. If the hub stops before the next key is removed,
+// the rotation is repeated with the same key on the next unlock, which does no harm.
+func (svc *maintenanceService) finishKeyRotation(unlockPassword string, nextKey []byte) error {
+	err := svc.cfg.SetUpdate(config.DatabaseEncryptionKeyKey, hex.EncodeToString(nextKey), unlockPassword)
+	if err != nil {
+		return err
+	}
+	if err := db.SetEncryptionKey(nextKey, svc.cfg.GetEnv().EncryptDatabase); err != nil {
+		return err
+	}
+	if err := svc.cfg.SetUpdate(config.DatabaseNextEncryptionKeyKey, "", ""); err != nil {
+		return err
+	}
+	return svc.cfg.SetUpdate(config.DatabaseKeyRotationProgressKey, "", "")
+}
+
+func (svc *maintenanceService) getDataKey(key string, unlockPassword string) ([]byte, error) {
+	dataKeyHex, err := svc.cfg.Get(key, unlockPassword)
+	if err != nil {
+		return nil, err
+	}
+	if dataKeyHex == "" {
+		return nil, nil
+	}
+	dataKey, err := hex.DecodeString(dataKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return dataKey, nil
+}
@@ -0,0 +1,64 @@
+package maintenance
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestRotateEncryptionKey(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SaveUnlockPasswordCheck("password1"))
+	dataKey := make([]byte, 32)
+	dataKey[0] = 1
+	require.NoError(t, svc.Cfg.SetUpdate(config.DatabaseEncryptionKeyKey, hex.EncodeToString(dataKey), "password1"))
+	require.NoError(t, db.SetEncryptionKey(dataKey, true))
+	defer db.SetEncryptionKey(dataKey, false)
+
+	transaction := db.Transaction{Type: "incoming", Description: "coffee"}
+	require.NoError(t, svc.DB.Create(&transaction).Error)
+	var encryptedDescription string
+	require.NoError(t, svc.DB.Table("transactions").Select("description").Where("id = ?", transaction.ID).Scan(&encryptedDescription).Error)
+
+	maintenanceSvc := NewMaintenanceService(svc.DB, svc.Cfg, svc.EventPublisher)
+	err = maintenanceSvc.RotateEncryptionKey("wrong", "")
+	assert.EqualError(t, err, "incorrect password")
+
+	require.NoError(t, maintenanceSvc.RotateEncryptionKey("password1", "password2"))
+	require.Eventually(t, func() bool {
+		status, err := maintenanceSvc.GetKeyRotationStatus()
+		return err == nil && !status.InProgress && !status.Running
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.True(t, svc.Cfg.CheckUnlockPassword("password2"))
+	newDataKeyHex, err := svc.Cfg.Get(config.DatabaseEncryptionKeyKey, "password2")
+	require.NoError(t, err)
+	assert.NotEqual(t, hex.EncodeToString(dataKey), newDataKeyHex)
+	nextDataKeyHex, err := svc.Cfg.Get(config.DatabaseNextEncryptionKeyKey, "password2")
+	require.NoError(t, err)
+	assert.Empty(t, nextDataKeyHex)
+
+	var reencryptedDescription string
+	require.NoError(t, svc.DB.Table("transactions").Select("description").Where("id = ?", transaction.ID).Scan(&reencryptedDescription).Error)
+	assert.True(t, strings.HasPrefix(reencryptedDescription, "enc:v1:"))
+	assert.NotEqual(t, encryptedDescription, reencryptedDescription)
+
+	// the old key is no longer needed
+	newDataKey, err := hex.DecodeString(newDataKeyHex)
+	require.NoError(t, err)
+	require.NoError(t, db.SetEncryptionKey(newDataKey, true))
+	var reloaded db.Transaction
+	require.NoError(t, svc.DB.First(&reloaded, transaction.ID).Error)
+	assert.Equal(t, "coffee", reloaded.Description)
+}
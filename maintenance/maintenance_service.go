@@ -0,0 +1,302 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	maintenanceCheckInterval = time.Hour
+	// pruning and refreshing the query planner statistics
+	maintenanceInterval = 24 * time.Hour
+	// the integrity check and VACUUM read and rewrite the whole database
+	vacuumInterval = 7 * 24 * time.Hour
+)
+
+// used until a retention period is configured, 0 keeps the rows forever
+const (
+	DefaultRequestEventRetentionDays    = 90
+	DefaultAuditLogRetentionDays        = 0
+	DefaultWebhookDeliveryRetentionDays = 30
+)
+
+// payment requests older than 6 hours are ignored by the NIP-47 handler, request events must be
+// kept longer so that relays cannot make the hub process a pruned request again
+const MinRequestEventRetentionDays = 7
+
+// only one maintenance run at a time, also across service instances
+var runMutex sync.Mutex
+
+type MaintenanceService interface {
+	GetStatus() *MaintenanceStatus
+	RunMaintenance(ctx context.Context, vacuum bool) (*MaintenanceReport, error)
+	Start(ctx context.Context)
+	GetKeyRotationStatus() (*KeyRotationStatus, error)
+	RotateEncryptionKey(currentUnlockPassword string, newUnlockPassword string) error
+	ResumeKeyRotation(ctx context.Context, unlockPassword string)
+}
+
+type MaintenanceStatus struct {
+	LastMaintenanceAt *time.Time `json:"lastMaintenanceAt,omitempty"`
+	LastVacuumAt      *time.Time `json:"lastVacuumAt,omitempty"`
+	// result of the last integrity check, empty if the database was intact
+	IntegrityError string `json:"integrityError,omitempty"`
+}
+
+type MaintenanceReport struct {
+	PrunedRequestEvents     int64         `json:"prunedRequestEvents"`
+	PrunedAuditLogs         int64         `json:"prunedAuditLogs"`
+	PrunedWebhookDeliveries int64         `json:"prunedWebhookDeliveries"`
+	IntegrityChecked        bool          `json:"integrityChecked"`
+	Vacuumed                bool          `json:"vacuumed"`
+	Duration                time.Duration `json:"duration"`
+}
+
+type maintenanceService struct {
+	db             *gorm.DB
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+}
+
+func NewMaintenanceService(db *gorm.DB, cfg config.Config, eventPublisher events.EventPublisher) *maintenanceService {
+	return &maintenanceService{
+		db:             db,
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func getRetentionDays(cfg config.Config, key string, defaultDays uint) uint {
+	retentionDays, _ := cfg.Get(key, "")
+	if retentionDays == "" {
+		return defaultDays
+	}
+	parsedRetentionDays, _ := strconv.ParseUint(retentionDays, 10, 32)
+	return uint(parsedRetentionDays)
+}
+
+func GetRequestEventRetentionDays(cfg config.Config) uint {
+	return getRetentionDays(cfg, config.RequestEventRetentionDaysKey, DefaultRequestEventRetentionDays)
+}
+
+func GetAuditLogRetentionDays(cfg config.Config) uint {
+	return getRetentionDays(cfg, config.AuditLogRetentionDaysKey, DefaultAuditLogRetentionDays)
+}
+
+func GetWebhookDeliveryRetentionDays(cfg config.Config) uint {
+	return getRetentionDays(cfg, config.WebhookDeliveryRetentionDaysKey, DefaultWebhookDeliveryRetentionDays)
+}
+
+func getTime(cfg config.Config, key string) *time.Time {
+	value, _ := cfg.Get(key, "")
+	if value == "" {
+		return nil
+	}
+	parsedTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &parsedTime
+}
+
+func setTime(cfg config.Config, key string, value time.Time) {
+	if err := cfg.SetUpdate(key, value.UTC().Format(time.RFC3339), ""); err != nil {
+		logger.Logger.WithField("key", key).WithError(err).Error("Failed to save database maintenance time")
+	}
+}
+
+func (svc *maintenanceService) GetStatus() *MaintenanceStatus {
+	integrityError, _ := svc.cfg.Get(config.DatabaseIntegrityErrorKey, "")
+	return &MaintenanceStatus{
+		LastMaintenanceAt: getTime(svc.cfg, config.LastDatabaseMaintenanceAtKey),
+		LastVacuumAt:      getTime(svc.cfg, config.LastDatabaseVacuumAtKey),
+		IntegrityError:    integrityError,
+	}
+}
+
+// Start runs the maintenance once a day, and the integrity check and VACUUM once a week,
+// until the context is cancelled. The times of the last runs are kept across restarts.
+func (svc *maintenanceService) Start(ctx context.Context) {
+	health.RegisterJob("db_maintenance", maintenanceCheckInterval)
+	go func() {
+		defer health.RemoveJob("db_maintenance")
+		ticker := time.NewTicker(maintenanceCheckInterval)
+		defer ticker.Stop()
+		for {
+			health.ReportJobRun("db_maintenance", svc.runDueMaintenance(ctx, time.Now()))
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func isDue(lastRunAt *time.Time, interval time.Duration, now time.Time) bool {
+	return lastRunAt == nil || !now.Before(lastRunAt.Add(interval))
+}
+
+func (svc *maintenanceService) runDueMaintenance(ctx context.Context, now time.Time) error {
+	status := svc.GetStatus()
+	if !isDue(status.LastMaintenanceAt, maintenanceInterval, now) {
+		return nil
+	}
+	_, err := svc.RunMaintenance(ctx, isDue(status.LastVacuumAt, vacuumInterval, now))
+	return err
+}
+
+// RunMaintenance prunes old rows and refreshes the statistics of the query planner.
+// With vacuum the integrity of the database is checked and the database is rebuilt with
+// VACUUM to return the space of deleted rows, which blocks writes while it runs.
+func (svc *maintenanceService) RunMaintenance(ctx context.Context, vacuum bool) (*MaintenanceReport, error) {
+	if !runMutex.TryLock() {
+		return nil, errors.New("database maintenance is already running")
+	}
+	defer runMutex.Unlock()
+
+	startedAt := time.Now()
+	report := &MaintenanceReport{}
+	var err error
+
+	if report.PrunedRequestEvents, err = svc.pruneRequestEvents(startedAt); err != nil {
+		return nil, fmt.Errorf("failed to prune request events: %w", err)
+	}
+	if report.PrunedAuditLogs, err = svc.pruneAuditLogs(startedAt); err != nil {
+		return nil, fmt.Errorf("failed to prune audit logs: %w", err)
+	}
+	if report.PrunedWebhookDeliveries, err = svc.pruneWebhookDeliveries(startedAt); err != nil {
+		return nil, fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if vacuum {
+		report.IntegrityChecked = true
+		// never rebuild a corrupt database, it could lose the remaining data
+		if err := svc.checkIntegrity(); err != nil {
+			// the check is repeated with the next daily run
+			setTime(svc.cfg, config.LastDatabaseMaintenanceAtKey, startedAt)
+			return nil, err
+		}
+		if err := svc.vacuum(); err != nil {
+			return nil, fmt.Errorf("failed to vacuum database: %w", err)
+		}
+		report.Vacuumed = true
+		setTime(svc.cfg, config.LastDatabaseVacuumAtKey, startedAt)
+	}
+
+	if err := svc.analyze(); err != nil {
+		return nil, fmt.Errorf("failed to analyze database: %w", err)
+	}
+	setTime(svc.cfg, config.LastDatabaseMaintenanceAtKey, startedAt)
+
+	report.Duration = time.Since(startedAt)
+	logger.Logger.WithFields(logrus.Fields{
+		"pruned_request_events":     report.PrunedRequestEvents,
+		"pruned_audit_logs":         report.PrunedAuditLogs,
+		"pruned_webhook_deliveries": report.PrunedWebhookDeliveries,
+		"vacuumed":                  report.Vacuumed,
+		"duration":                  report.Duration.String(),
+	}).Info("Finished database maintenance")
+	return report, nil
+}
+
+func (svc *maintenanceService) pruneRequestEvents(now time.Time) (int64, error) {
+	retentionDays := GetRequestEventRetentionDays(svc.cfg)
+	if retentionDays == 0 {
+		return 0, nil
+	}
+	retentionDays = max(retentionDays, MinRequestEventRetentionDays)
+
+	// response events are removed by the foreign key
+	result := svc.db.
+		Where("created_at < ?", now.AddDate(0, 0, -int(retentionDays))).
+		Where("id NOT IN (?)", svc.db.Model(&db.PaymentApproval{}).Select("request_event_id").Where("request_event_id IS NOT NULL")).
+		Delete(&db.RequestEvent{})
+	return result.RowsAffected, result.Error
+}
+
+func (svc *maintenanceService) pruneAuditLogs(now time.Time) (int64, error) {
+	retentionDays := GetAuditLogRetentionDays(svc.cfg)
+	if retentionDays == 0 {
+		return 0, nil
+	}
+	result := svc.db.Where("created_at < ?", now.AddDate(0, 0, -int(retentionDays))).Delete(&db.AppAuditLog{})
+	return result.RowsAffected, result.Error
+}
+
+func (svc *maintenanceService) pruneWebhookDeliveries(now time.Time) (int64, error) {
+	retentionDays := GetWebhookDeliveryRetentionDays(svc.cfg)
+	if retentionDays == 0 {
+		return 0, nil
+	}
+	// pending deliveries are still retried
+	result := svc.db.
+		Where("created_at < ?", now.AddDate(0, 0, -int(retentionDays))).
+		Where("state != ?", db.WEBHOOK_DELIVERY_STATE_PENDING).
+		Delete(&db.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
+
+// checkIntegrity records the result of the check and publishes an event if the database is corrupt.
+// PostgreSQL does not offer a general integrity check, its server is expected to be monitored separately.
+func (svc *maintenanceService) checkIntegrity() error {
+	if svc.db.Dialector.Name() != "sqlite" {
+		return nil
+	}
+
+	var results []string
+	if err := svc.db.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+		return fmt.Errorf("failed to check database integrity: %w", err)
+	}
+	integrityError := ""
+	if len(results) != 1 || results[0] != "ok" {
+		integrityError = strings.Join(results, "; ")
+	}
+	if err := svc.cfg.SetUpdate(config.DatabaseIntegrityErrorKey, integrityError, ""); err != nil {
+		logger.Logger.WithError(err).Error("Failed to save database integrity check result")
+	}
+	if integrityError == "" {
+		return nil
+	}
+
+	logger.Logger.WithField("integrity_error", integrityError).Error("Database integrity check failed")
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_database_corrupt",
+		Properties: map[string]interface{}{
+			"error": integrityError,
+		},
+	})
+	return fmt.Errorf("database is corrupt: %s", integrityError)
+}
+
+func (svc *maintenanceService) vacuum() error {
+	// autovacuum of PostgreSQL already takes care of this
+	if svc.db.Dialector.Name() != "sqlite" {
+		return nil
+	}
+	return svc.db.Exec("VACUUM").Error
+}
+
+func (svc *maintenanceService) analyze() error {
+	if svc.db.Dialector.Name() != "sqlite" {
+		return svc.db.Exec("ANALYZE").Error
+	}
+	return svc.db.Exec("PRAGMA optimize").Error
+}
@@ -0,0 +1,115 @@
+package maintenance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestRunMaintenance_Prune(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	old := time.Now().AddDate(0, 0, -100)
+
+	oldRequest := db.RequestEvent{AppId: &app.ID, NostrId: "old", CreatedAt: old}
+	require.NoError(t, svc.DB.Create(&oldRequest).Error)
+	require.NoError(t, svc.DB.Create(&db.ResponseEvent{NostrId: "old-response", RequestId: oldRequest.ID, CreatedAt: old}).Error)
+	require.NoError(t, svc.DB.Create(&db.RequestEvent{AppId: &app.ID, NostrId: "new"}).Error)
+	// requests of payment approvals are kept
+	approvedRequest := db.RequestEvent{AppId: &app.ID, NostrId: "approval", CreatedAt: old}
+	require.NoError(t, svc.DB.Create(&approvedRequest).Error)
+	require.NoError(t, svc.DB.Create(&db.PaymentApproval{AppId: app.ID, RequestEventId: &approvedRequest.ID, State: "pending", ExpiresAt: time.Now()}).Error)
+
+	require.NoError(t, svc.DB.Create(&db.AppAuditLog{AppId: app.ID, Action: "updated", CreatedAt: old}).Error)
+
+	webhook := db.Webhook{Url: "https://example.com", Secret: "secret"}
+	require.NoError(t, svc.DB.Create(&webhook).Error)
+	require.NoError(t, svc.DB.Create(&db.WebhookDelivery{WebhookId: webhook.ID, State: db.WEBHOOK_DELIVERY_STATE_DELIVERED, CreatedAt: old}).Error)
+	require.NoError(t, svc.DB.Create(&db.WebhookDelivery{WebhookId: webhook.ID, State: db.WEBHOOK_DELIVERY_STATE_PENDING, CreatedAt: old}).Error)
+
+	maintenanceSvc := NewMaintenanceService(svc.DB, svc.Cfg, svc.EventPublisher)
+	report, err := maintenanceSvc.RunMaintenance(context.TODO(), false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.PrunedRequestEvents)
+	// audit logs are kept forever by default
+	assert.Equal(t, int64(0), report.PrunedAuditLogs)
+	assert.Equal(t, int64(1), report.PrunedWebhookDeliveries)
+	assert.False(t, report.Vacuumed)
+
+	var requestEvents []db.RequestEvent
+	require.NoError(t, svc.DB.Order("id").Find(&requestEvents).Error)
+	require.Len(t, requestEvents, 2)
+	assert.Equal(t, "new", requestEvents[0].NostrId)
+	assert.Equal(t, "approval", requestEvents[1].NostrId)
+	var responseEventCount int64
+	require.NoError(t, svc.DB.Model(&db.ResponseEvent{}).Count(&responseEventCount).Error)
+	assert.Equal(t, int64(0), responseEventCount)
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.AuditLogRetentionDaysKey, "30", ""))
+	report, err = maintenanceSvc.RunMaintenance(context.TODO(), false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.PrunedAuditLogs)
+
+	status := maintenanceSvc.GetStatus()
+	require.NotNil(t, status.LastMaintenanceAt)
+	assert.Nil(t, status.LastVacuumAt)
+}
+
+func TestRunMaintenance_Vacuum(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+	if svc.DB.Dialector.Name() != "sqlite" {
+		t.Skip("the integrity check is only supported with sqlite")
+	}
+
+	maintenanceSvc := NewMaintenanceService(svc.DB, svc.Cfg, svc.EventPublisher)
+	report, err := maintenanceSvc.RunMaintenance(context.TODO(), true)
+	require.NoError(t, err)
+	assert.True(t, report.IntegrityChecked)
+	assert.True(t, report.Vacuumed)
+
+	status := maintenanceSvc.GetStatus()
+	assert.NotNil(t, status.LastVacuumAt)
+	assert.Empty(t, status.IntegrityError)
+}
+
+func TestRunDueMaintenance(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	now := time.Now()
+	require.NoError(t, svc.Cfg.SetUpdate(config.LastDatabaseMaintenanceAtKey, now.Add(-time.Hour).UTC().Format(time.RFC3339), ""))
+	require.NoError(t, svc.Cfg.SetUpdate(config.LastDatabaseVacuumAtKey, now.Add(-time.Hour).UTC().Format(time.RFC3339), ""))
+
+	maintenanceSvc := NewMaintenanceService(svc.DB, svc.Cfg, svc.EventPublisher)
+	require.NoError(t, maintenanceSvc.runDueMaintenance(context.TODO(), now))
+	// not due yet
+	assert.True(t, maintenanceSvc.GetStatus().LastMaintenanceAt.Before(now.Add(-time.Minute)))
+
+	require.NoError(t, maintenanceSvc.runDueMaintenance(context.TODO(), now.Add(24*time.Hour)))
+	status := maintenanceSvc.GetStatus()
+	assert.True(t, status.LastMaintenanceAt.After(now.Add(-time.Minute)))
+	// the weekly vacuum is not due yet
+	assert.True(t, status.LastVacuumAt.Before(now.Add(-time.Minute)))
+}
+
+func TestIsDue(t *testing.T) {
+	now := time.Now()
+	assert.True(t, isDue(nil, time.Hour, now))
+	lastRunAt := now.Add(-30 * time.Minute)
+	assert.False(t, isDue(&lastRunAt, time.Hour, now))
+	assert.True(t, isDue(&lastRunAt, time.Hour, now.Add(30*time.Minute)))
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const dbStartedAtKey = "metrics:started_at"
+
+// RegisterDBCallbacks times all queries made through the gorm instance
+func RegisterDBCallbacks(gormDB *gorm.DB) error {
+	callback := gormDB.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("metrics:before_create", startDBTimer),
+		callback.Create().After("gorm:create").Register("metrics:after_create", observeDBQuery("create")),
+		callback.Query().Before("gorm:query").Register("metrics:before_query", startDBTimer),
+		callback.Query().After("gorm:query").Register("metrics:after_query", observeDBQuery("query")),
+		callback.Update().Before("gorm:update").Register("metrics:before_update", startDBTimer),
+		callback.Update().After("gorm:update").Register("metrics:after_update", observeDBQuery("update")),
+		callback.Delete().Before("gorm:delete").Register("metrics:before_delete", startDBTimer),
+		callback.Delete().After("gorm:delete").Register("metrics:after_delete", observeDBQuery("delete")),
+		callback.Row().Before("gorm:row").Register("metrics:before_row", startDBTimer),
+		callback.Row().After("gorm:row").Register("metrics:after_row", observeDBQuery("row")),
+		callback.Raw().Before("gorm:raw").Register("metrics:before_raw", startDBTimer),
+		callback.Raw().After("gorm:raw").Register("metrics:after_raw", observeDBQuery("raw")),
+	)
+}
+
+func startDBTimer(db *gorm.DB) {
+	db.InstanceSet(dbStartedAtKey, time.Now())
+}
+
+func observeDBQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		startedAt, ok := db.InstanceGet(dbStartedAtKey)
+		if !ok {
+			return
+		}
+		dbQueryDuration.WithLabelValues(operation).Observe(time.Since(startedAt.(time.Time)).Seconds())
+	}
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/events"
+)
+
+type eventConsumer struct {
+	events.EventSubscriber
+}
+
+// NewEventConsumer returns a subscriber which derives metrics from published events
+func NewEventConsumer() events.EventSubscriber {
+	return &eventConsumer{}
+}
+
+func (c *eventConsumer) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	switch event.Event {
+	case "nwc_payment_sent":
+		paymentsTotal.WithLabelValues(constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED).Inc()
+	case "nwc_payment_failed":
+		paymentsTotal.WithLabelValues(constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_FAILED).Inc()
+	case "nwc_payment_received":
+		paymentsTotal.WithLabelValues(constants.TRANSACTION_TYPE_INCOMING, constants.TRANSACTION_STATE_SETTLED).Inc()
+	case "nwc_permission_denied":
+		code := ""
+		if properties, ok := event.Properties.(map[string]interface{}); ok {
+			code, _ = properties["code"].(string)
+		}
+		permissionDenialsTotal.WithLabelValues(code).Inc()
+	case "nwc_node_started":
+		lnBackendUp.Set(1)
+	case "nwc_node_stopped", "nwc_node_start_failed":
+		lnBackendUp.Set(0)
+	case "nwc_node_sync_failed":
+		lnBackendSyncFailuresTotal.Inc()
+	}
+}
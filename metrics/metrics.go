@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "albyhub"
+
+// registry only contains the hub metrics (plus go runtime and process metrics),
+// so that dependencies registering to the default registry do not leak into /metrics
+var registry = prometheus.NewRegistry()
+
+var (
+	paymentsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_total",
+		Help:      "Number of settled and failed payments by type (incoming or outgoing).",
+	}, []string{"type", "state"})
+
+	paymentDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "payment_duration_seconds",
+		Help:      "Time taken to send outgoing payments, by final state.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"state"})
+
+	nip47RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "nip47_requests_total",
+		Help:      "Number of handled NIP-47 requests by method and error code (empty on success).",
+	}, []string{"method", "error_code"})
+
+	nip47RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "nip47_request_duration_seconds",
+		Help:      "Time taken to handle NIP-47 requests, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	relayPublishFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_publish_failures_total",
+		Help:      "Number of events which failed to be published to a relay, by relay and event kind.",
+	}, []string{"relay", "kind"})
+
+	permissionDenialsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "permission_denials_total",
+		Help:      "Number of requests rejected by app permissions, budgets and limits, by NIP-47 error code.",
+	}, []string{"code"})
+
+	lnBackendUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ln_backend_up",
+		Help:      "Whether the lightning backend is running (1) or not (0).",
+	})
+
+	lnBackendSyncFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ln_backend_sync_failures_total",
+		Help:      "Number of failed lightning backend wallet syncs.",
+	})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by database queries, by operation.",
+		Buckets:   []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+	}, []string{"operation"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		paymentsTotal,
+		paymentDuration,
+		nip47RequestsTotal,
+		nip47RequestDuration,
+		relayPublishFailuresTotal,
+		permissionDenialsTotal,
+		lnBackendUp,
+		lnBackendSyncFailuresTotal,
+		dbQueryDuration,
+	)
+}
+
+// Handler serves all metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObservePaymentDuration records how long an outgoing payment took to settle or fail
+func ObservePaymentDuration(state string, duration time.Duration) {
+	paymentDuration.WithLabelValues(state).Observe(duration.Seconds())
+}
+
+// ObserveNip47Request records a handled NIP-47 request. errorCode is empty on success.
+func ObserveNip47Request(method string, errorCode string, duration time.Duration) {
+	nip47RequestsTotal.WithLabelValues(method, errorCode).Inc()
+	nip47RequestDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// IncRelayPublishFailures records a failed publish of an event of the given kind (e.g. "response") to a relay
+func IncRelayPublishFailures(relay string, kind string) {
+	relayPublishFailuresTotal.WithLabelValues(relay, kind).Inc()
+}
@@ -0,0 +1,92 @@
+package nip47
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+)
+
+const (
+	activityParamMaxLength      = 64
+	activityParamMaxArrayLength = 10
+)
+
+// params which are never stored in the activity log
+var redactedActivityParams = []string{"preimage"}
+
+// recordAppActivity stores the outcome of a request in the activity log of the app
+func (svc *nip47Service) recordAppActivity(app *db.App, requestNostrId string, requestedAt time.Time, nip47Request *models.Request, nip47Response *models.Response) {
+	activityLog := db.AppActivityLog{
+		AppId:          app.ID,
+		RequestNostrId: requestNostrId,
+		Method:         nip47Request.Method,
+		Params:         summarizeRequestParams(nip47Request.Params),
+		Result:         constants.APP_ACTIVITY_RESULT_SUCCESS,
+		RequestedAt:    requestedAt,
+	}
+	if nip47Response.Error != nil {
+		activityLog.Result = constants.APP_ACTIVITY_RESULT_ERROR
+		activityLog.ErrorCode = nip47Response.Error.Code
+		activityLog.ErrorMessage = nip47Response.Error.Message
+	}
+
+	err := svc.db.Create(&activityLog).Error
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"requestEventNostrId": requestNostrId,
+			"appId":               app.ID,
+		}).WithError(err).Error("Failed to record app activity")
+	}
+}
+
+// summarizeRequestParams keeps the structure of the params, but shortens long values and removes secrets
+func summarizeRequestParams(params json.RawMessage) datatypes.JSON {
+	var decodedParams map[string]interface{}
+	if len(params) == 0 || json.Unmarshal(params, &decodedParams) != nil || len(decodedParams) == 0 {
+		return nil
+	}
+
+	summary, err := json.Marshal(summarizeRequestParam(decodedParams))
+	if err != nil {
+		return nil
+	}
+	return datatypes.JSON(summary)
+}
+
+func summarizeRequestParam(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		if len(value) > activityParamMaxLength {
+			return value[:activityParamMaxLength] + "..."
+		}
+		return value
+	case []interface{}:
+		summary := []interface{}{}
+		for i, item := range value {
+			if i == activityParamMaxArrayLength {
+				break
+			}
+			summary = append(summary, summarizeRequestParam(item))
+		}
+		return summary
+	case map[string]interface{}:
+		summary := map[string]interface{}{}
+		for key, item := range value {
+			if slices.Contains(redactedActivityParams, key) {
+				summary[key] = "[redacted]"
+				continue
+			}
+			summary[key] = summarizeRequestParam(item)
+		}
+		return summary
+	}
+	return value
+}
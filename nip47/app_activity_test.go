@@ -0,0 +1,34 @@
+package nip47
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeRequestParams(t *testing.T) {
+	invoice := "lnbc" + strings.Repeat("x", 200)
+	params := json.RawMessage(`{"invoice": "` + invoice + `", "amount": 1000, "metadata": {"comment": "hi"}, "preimage": "secret"}`)
+
+	var summary map[string]interface{}
+	assert.NoError(t, json.Unmarshal(summarizeRequestParams(params), &summary))
+	assert.Equal(t, invoice[:activityParamMaxLength]+"...", summary["invoice"])
+	assert.Equal(t, float64(1000), summary["amount"])
+	assert.Equal(t, map[string]interface{}{"comment": "hi"}, summary["metadata"])
+	assert.Equal(t, "[redacted]", summary["preimage"])
+
+	// multi_* requests only keep the first items
+	keysends := []string{}
+	for i := 0; i < 15; i++ {
+		keysends = append(keysends, `{"amount": 1, "pubkey": "abc"}`)
+	}
+	params = json.RawMessage(`{"keysends": [` + strings.Join(keysends, ",") + `]}`)
+	assert.NoError(t, json.Unmarshal(summarizeRequestParams(params), &summary))
+	assert.Len(t, summary["keysends"], activityParamMaxArrayLength)
+
+	assert.Nil(t, summarizeRequestParams(nil))
+	assert.Nil(t, summarizeRequestParams(json.RawMessage(`{}`)))
+	assert.Nil(t, summarizeRequestParams(json.RawMessage(`"invalid"`)))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/getAlby/hub/db/queries"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/spendablebalance"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sirupsen/logrus"
 )
@@ -17,6 +18,8 @@ const (
 
 type getBalanceResponse struct {
 	Balance int64 `json:"balance"`
+	// routing fees reserved by in-flight payments of isolated apps, already deducted from the balance
+	FeeReserve uint64 `json:"fee_reserve,omitempty"`
 	// MaxAmount     int    `json:"max_amount"`
 	// BudgetRenewal string `json:"budget_renewal"`
 }
@@ -29,11 +32,12 @@ func (controller *nip47Controller) HandleGetBalanceEvent(ctx context.Context, ni
 	}).Debug("Getting balance")
 
 	balance := int64(0)
+	feeReserve := uint64(0)
 	if app.Isolated {
 		balance = queries.GetIsolatedBalance(controller.db, app.ID)
+		feeReserve = queries.GetFeeReserveMsat(controller.db, app.ID)
 	} else {
 		balances, err := controller.lnClient.GetBalances(ctx, true)
-		balance = balances.Lightning.TotalSpendable
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"request_event_id": requestEventId,
@@ -44,10 +48,12 @@ func (controller *nip47Controller) HandleGetBalanceEvent(ctx context.Context, ni
 			}, nostr.Tags{})
 			return
 		}
+		balance = spendablebalance.GetSpendableBalance(controller.cfg, balances).Total
 	}
 
 	responsePayload := &getBalanceResponse{
-		Balance: balance,
+		Balance:    balance,
+		FeeReserve: feeReserve,
 	}
 
 	// this is not part of the spec and does not seem to be used
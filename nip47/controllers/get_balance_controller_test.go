@@ -127,3 +127,50 @@ func TestHandleGetBalanceEvent_IsolatedApp_Transactions(t *testing.T) {
 	assert.Equal(t, int64(1000), publishedResponse.Result.(*getBalanceResponse).Balance)
 	assert.Nil(t, publishedResponse.Error)
 }
+
+func TestHandleGetBalanceEvent_IsolatedApp_PendingPayment(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47GetBalanceJson), nip47Request)
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app.Isolated = true
+	svc.DB.Save(&app)
+
+	svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		Type:       constants.TRANSACTION_TYPE_INCOMING,
+		AmountMsat: 100_000,
+	})
+	svc.DB.Create(&db.Transaction{
+		AppId:          &app.ID,
+		State:          constants.TRANSACTION_STATE_PENDING,
+		Type:           constants.TRANSACTION_TYPE_OUTGOING,
+		AmountMsat:     50_000,
+		FeeReserveMsat: 10_000,
+	})
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandleGetBalanceEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+
+	assert.Equal(t, int64(40_000), publishedResponse.Result.(*getBalanceResponse).Balance)
+	assert.Equal(t, uint64(10_000), publishedResponse.Result.(*getBalanceResponse).FeeReserve)
+	assert.Nil(t, publishedResponse.Error)
+}
@@ -6,17 +6,27 @@ import (
 	"github.com/getAlby/hub/db/queries"
 	"github.com/nbd-wtf/go-nostr"
 
+	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/transactions"
 	"github.com/sirupsen/logrus"
 )
 
+const msatPerBtc = 100_000_000_000
+
 type getBudgetResponse struct {
 	UsedBudget    uint64  `json:"used_budget"`
 	TotalBudget   uint64  `json:"total_budget"`
 	RenewsAt      *uint64 `json:"renews_at,omitempty"`
 	RenewalPeriod string  `json:"renewal_period"`
+	// routing fees reserved by in-flight payments, already included in the used budget
+	FeeReserve uint64 `json:"fee_reserve,omitempty"`
+	// fiat budgets are converted to millisats at the current rate
+	Currency        string  `json:"currency,omitempty"`
+	TotalBudgetFiat float64 `json:"total_budget_fiat,omitempty"`
+	UsedBudgetFiat  float64 `json:"used_budget_fiat,omitempty"`
 }
 
 func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, publishResponse publishFunc) {
@@ -28,6 +38,11 @@ func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip
 	appPermission := db.AppPermission{}
 	controller.db.Where("app_id = ? AND scope = ?", app.ID, models.PAY_INVOICE_METHOD).First(&appPermission)
 
+	if appPermission.BudgetCurrency != "" && appPermission.MaxAmountFiat > 0 {
+		controller.handleGetFiatBudget(nip47Request, &appPermission, publishResponse)
+		return
+	}
+
 	maxAmount := appPermission.MaxAmountSat
 	if maxAmount == 0 {
 		publishResponse(&models.Response{
@@ -43,6 +58,39 @@ func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip
 		UsedBudget:    usedBudget * 1000,
 		RenewalPeriod: appPermission.BudgetRenewal,
 		RenewsAt:      queries.GetBudgetRenewsAt(appPermission.BudgetRenewal),
+		FeeReserve:    queries.GetBudgetFeeReserveMsat(controller.db, &appPermission),
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result:     responsePayload,
+	}, nostr.Tags{})
+}
+
+func (controller *nip47Controller) handleGetFiatBudget(nip47Request *models.Request, appPermission *db.AppPermission, publishResponse publishFunc) {
+	rate, err := transactions.GetCurrentFiatRate(context.Background(), appPermission.BudgetCurrency)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("currency", appPermission.BudgetCurrency).Error("Failed to get rate for fiat budget")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error: &models.Error{
+				Code:    constants.ERROR_INTERNAL,
+				Message: err.Error(),
+			},
+		}, nostr.Tags{})
+		return
+	}
+
+	usedBudgetFiat := queries.GetBudgetUsageFiat(controller.db, appPermission, rate)
+	responsePayload := &getBudgetResponse{
+		TotalBudget:     uint64(appPermission.MaxAmountFiat / rate * msatPerBtc),
+		UsedBudget:      uint64(usedBudgetFiat / rate * msatPerBtc),
+		RenewalPeriod:   appPermission.BudgetRenewal,
+		RenewsAt:        queries.GetBudgetRenewsAt(appPermission.BudgetRenewal),
+		FeeReserve:      queries.GetBudgetFeeReserveMsat(controller.db, appPermission),
+		Currency:        appPermission.BudgetCurrency,
+		TotalBudgetFiat: appPermission.MaxAmountFiat,
+		UsedBudgetFiat:  usedBudgetFiat,
 	}
 
 	publishResponse(&models.Response{
@@ -19,6 +19,30 @@ func mapNip47Error(err error) *models.Error {
 	if errors.Is(err, transactions.NewQuotaExceededError()) {
 		code = constants.ERROR_QUOTA_EXCEEDED
 	}
+	if errors.Is(err, transactions.NewPaymentAmountExceededError()) {
+		code = constants.ERROR_RESTRICTED
+	}
+	if errors.Is(err, transactions.NewPaymentNotApprovedError()) {
+		code = constants.ERROR_RESTRICTED
+	}
+	if errors.Is(err, transactions.NewDestinationNotAllowedError()) {
+		code = constants.ERROR_RESTRICTED
+	}
+	if errors.Is(err, transactions.NewSingleUseConsumedError()) {
+		code = constants.ERROR_RESTRICTED
+	}
+	if errors.Is(err, transactions.NewSpendingLockedError()) {
+		code = constants.ERROR_RESTRICTED
+	}
+	if errors.Is(err, transactions.NewReadOnlyModeError()) {
+		code = constants.ERROR_RESTRICTED
+	}
+	if errors.Is(err, transactions.NewPaymentRateLimitedError()) {
+		code = constants.ERROR_RATE_LIMITED
+	}
+	if errors.Is(err, transactions.NewIdempotencyKeyConflictError()) {
+		code = constants.ERROR_BAD_REQUEST
+	}
 
 	return &models.Error{
 		Code:    code,
@@ -10,6 +10,7 @@ import (
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/transactions"
 	"github.com/nbd-wtf/go-nostr"
 	decodepay "github.com/nbd-wtf/ln-decodepay"
 	"github.com/sirupsen/logrus"
@@ -33,45 +34,100 @@ func (controller *nip47Controller) HandleMultiPayInvoiceEvent(ctx context.Contex
 	}
 	logger.Logger.WithField("multiPayParams", multiPayParams).Debug("sending multi payment")
 
-	var wg sync.WaitGroup
-	wg.Add(len(multiPayParams.Invoices))
+	batchPayments := []transactions.BatchPayment{}
+	batchDTags := []nostr.Tags{}
 	for _, invoiceInfo := range multiPayParams.Invoices {
-		go func(invoiceInfo multiPayInvoiceElement) {
-			defer wg.Done()
-			bolt11 := invoiceInfo.Invoice
-			metadata := invoiceInfo.Metadata
-			// Convert invoice to lowercase string
-			bolt11 = strings.ToLower(bolt11)
-			paymentRequest, err := decodepay.Decodepay(bolt11)
-			if err != nil {
-				logger.Logger.WithFields(logrus.Fields{
-					"request_event_id": requestEventId,
-					"appId":            app.ID,
-					"bolt11":           bolt11,
-				}).Errorf("Failed to decode bolt11 invoice: %v", err)
+		// Convert invoice to lowercase string
+		bolt11 := strings.ToLower(invoiceInfo.Invoice)
+		paymentRequest, err := decodepay.Decodepay(bolt11)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"request_event_id": requestEventId,
+				"appId":            app.ID,
+				"bolt11":           bolt11,
+			}).Errorf("Failed to decode bolt11 invoice: %v", err)
 
-				// TODO: Decide what to do if id is empty
-				dTag := []string{"d", invoiceInfo.Id}
-				publishResponse(&models.Response{
-					ResultType: nip47Request.Method,
-					Error: &models.Error{
-						Code:    constants.ERROR_BAD_REQUEST,
-						Message: fmt.Sprintf("Failed to decode bolt11 invoice: %s", err.Error()),
-					},
-				}, nostr.Tags{dTag})
-				return
-			}
+			// TODO: Decide what to do if id is empty
+			dTag := []string{"d", invoiceInfo.Id}
+			publishResponse(&models.Response{
+				ResultType: nip47Request.Method,
+				Error: &models.Error{
+					Code:    constants.ERROR_BAD_REQUEST,
+					Message: fmt.Sprintf("Failed to decode bolt11 invoice: %s", err.Error()),
+				},
+			}, nostr.Tags{dTag})
+			continue
+		}
 
-			invoiceDTagValue := invoiceInfo.Id
-			if invoiceDTagValue == "" {
-				invoiceDTagValue = paymentRequest.PaymentHash
-			}
-			dTag := []string{"d", invoiceDTagValue}
+		invoiceDTagValue := invoiceInfo.Id
+		if invoiceDTagValue == "" {
+			invoiceDTagValue = paymentRequest.PaymentHash
+		}
+		batchPayments = append(batchPayments, transactions.BatchPayment{
+			Invoice:        bolt11,
+			AmountMsat:     invoiceInfo.Amount,
+			Metadata:       invoiceInfo.Metadata,
+			IdempotencyKey: invoiceInfo.IdempotencyKey,
+		})
+		batchDTags = append(batchDTags, nostr.Tags{[]string{"d", invoiceDTagValue}})
+	}
 
-			controller.
-				pay(bolt11, invoiceInfo.Amount, metadata, &paymentRequest, nip47Request, requestEventId, app, publishResponse, nostr.Tags{dTag})
-		}(invoiceInfo)
+	if len(batchPayments) == 0 {
+		return
 	}
 
-	wg.Wait()
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"app_id":           app.ID,
+		"count":            len(batchPayments),
+	}).Info("Sending batch payment")
+
+	// each payment is reserved against the budget separately, so one payment
+	// exceeding the budget does not prevent the others from being made.
+	// Responses are published as soon as each payment is done.
+	published := make([]bool, len(batchPayments))
+	var publishedMutex sync.Mutex
+	publishItemResponse := func(i int, item transactions.BatchPaymentItemResult) {
+		publishedMutex.Lock()
+		published[i] = true
+		publishedMutex.Unlock()
+
+		if item.Error != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"request_event_id": requestEventId,
+				"app_id":           app.ID,
+				"bolt11":           batchPayments[i].Invoice,
+			}).WithError(item.Error).Error("Failed to send payment")
+			publishResponse(&models.Response{
+				ResultType: nip47Request.Method,
+				Error:      mapNip47Error(item.Error),
+			}, batchDTags[i])
+			return
+		}
+
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Result: payResponse{
+				Preimage: *item.Transaction.Preimage,
+				FeesPaid: item.Transaction.FeeMsat,
+			},
+		}, batchDTags[i])
+	}
+
+	_, err := controller.transactionsService.SendPaymentBatch(ctx, batchPayments, controller.lnClient, &app.ID, &requestEventId, publishItemResponse)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+			"app_id":           app.ID,
+		}).WithError(err).Error("Failed to send batch payment")
+		for i, dTags := range batchDTags {
+			if published[i] {
+				continue
+			}
+			publishResponse(&models.Response{
+				ResultType: nip47Request.Method,
+				Error:      mapNip47Error(err),
+			}, dTags)
+		}
+	}
 }
@@ -36,6 +36,23 @@ const nip47MultiPayJson = `
 }
 `
 
+const nip47MultiPayIdempotencyKeyJson = `
+{
+	"method": "multi_pay_invoice",
+	"params": {
+		"invoices": [{
+				"invoice": "lntbs1230n1pnkqautdqyw3jsnp4q09a0z84kg4a2m38zjllw43h953fx5zvqe8qxfgw694ymkq26u8zcpp5yvnh6hsnlnj4xnuh2trzlnunx732dv8ta2wjr75pdfxf6p2vlyassp5hyeg97a3ft5u769kjwsn7p0e85h79pzz8kladmnqhpcypz2uawjs9qyysgqcqpcxq8zals8sq9yeg2pa9eywkgj50cyzxd5elatujuc0c0wh6j9nat5mn34pgk8u9ufpgs99tw9ldlfk42cqlkr48au3lmuh09269prg4qkggh4a8cyqpfl0y6j",
+				"idempotency_key": "key1"
+			},
+			{
+				"invoice": "lntbs1230n1pnkq7q2dqqnp4q09a0z84kg4a2m38zjllw43h953fx5zvqe8qxfgw694ymkq26u8zcpp54sde879ktfrwnt4re3t2ckkrt5tr6dgv6cfdjgkar7942ruccvuqsp52qlk3rxr926s630fmnc5mg6sexnng4cyyfas4msrms8j6q28j8ys9qyysgqcqpcxq8zals8sqgjd3a60n6dy92jn7ggtkywhw952sc302qj0cwfupp7gayadznaj5cahvuq7py8p7hnq8yxylru6279urzxta3783cxze2atj9zmwadcq36muep",
+				"idempotency_key": "key2"
+			}
+		]
+	}
+}
+`
+
 const nip47MultiPayOneMalformedInvoiceJson = `
 {
 	"method": "multi_pay_invoice",
@@ -513,3 +530,62 @@ EXECUTE PROCEDURE slow_down_query();`).Error
 		assert.Equal(t, constants.ERROR_INSUFFICIENT_BALANCE, response.Error.Code)
 	}
 }
+
+func TestHandleMultiPayInvoiceEvent_IdempotencyKey_Retry(t *testing.T) {
+	ctx := context.TODO()
+
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	sendMultiPay := func(nostrId string) []*models.Response {
+		nip47Request := &models.Request{}
+		err := json.Unmarshal([]byte(nip47MultiPayIdempotencyKeyJson), nip47Request)
+		assert.NoError(t, err)
+
+		responses := []*models.Response{}
+		var mu sync.Mutex
+		publishResponse := func(response *models.Response, tags nostr.Tags) {
+			mu.Lock()
+			defer mu.Unlock()
+			responses = append(responses, response)
+		}
+
+		dbRequestEvent := &db.RequestEvent{NostrId: nostrId}
+		err = svc.DB.Create(&dbRequestEvent).Error
+		assert.NoError(t, err)
+
+		NewTestNip47Controller(svc).
+			HandleMultiPayInvoiceEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+		return responses
+	}
+
+	responses := sendMultiPay("request1")
+	assert.Equal(t, 2, len(responses))
+	for _, response := range responses {
+		require.Nil(t, response.Error)
+	}
+
+	// the retry returns the previous results instead of failing as already paid
+	retriedResponses := sendMultiPay("request2")
+	assert.Equal(t, 2, len(retriedResponses))
+	for _, response := range retriedResponses {
+		require.Nil(t, response.Error)
+		assert.Equal(t, "123preimage", response.Result.(payResponse).Preimage)
+	}
+
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/plugins"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sirupsen/logrus"
+)
+
+func (controller *nip47Controller) HandlePluginMethodEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, method *plugins.NIP47Method, publishResponse publishFunc) {
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"method":           nip47Request.Method,
+	}).Debug("Handling plugin method")
+
+	result, err := method.Handle(ctx, &plugins.NIP47Request{
+		AppId:     app.ID,
+		AppName:   app.Name,
+		AppPubkey: app.AppPubkey,
+		Isolated:  app.Isolated,
+		Method:    nip47Request.Method,
+		Params:    nip47Request.Params,
+	})
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+			"method":           nip47Request.Method,
+		}).WithError(err).Error("Plugin failed to handle request")
+
+		nip47Error := mapNip47Error(err)
+		var pluginError *plugins.Error
+		if errors.As(err, &pluginError) && pluginError.Code != "" {
+			nip47Error.Code = pluginError.Code
+		}
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      nip47Error,
+		}, nostr.Tags{})
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result:     result,
+	}, nostr.Tags{})
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/plugins"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/transactions"
+)
+
+const nip47CreateOrderJson = `
+{
+	"method": "create_order",
+	"params": {
+		"item": "coffee"
+	}
+}
+`
+
+func TestHandlePluginMethodEvent(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47CreateOrderJson), nip47Request)
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	var receivedRequest *plugins.NIP47Request
+	method := &plugins.NIP47Method{
+		Method: "create_order",
+		Scope:  constants.MAKE_INVOICE_SCOPE,
+		Handle: func(ctx context.Context, request *plugins.NIP47Request) (interface{}, error) {
+			receivedRequest = request
+			return map[string]string{"order": "1"}, nil
+		},
+	}
+
+	NewTestNip47Controller(svc).
+		HandlePluginMethodEvent(ctx, nip47Request, dbRequestEvent.ID, app, method, publishResponse)
+
+	require.NotNil(t, receivedRequest)
+	assert.Equal(t, app.ID, receivedRequest.AppId)
+	assert.Equal(t, app.AppPubkey, receivedRequest.AppPubkey)
+	assert.JSONEq(t, `{"item":"coffee"}`, string(receivedRequest.Params))
+
+	assert.Nil(t, publishedResponse.Error)
+	assert.Equal(t, "create_order", publishedResponse.ResultType)
+	assert.Equal(t, map[string]string{"order": "1"}, publishedResponse.Result)
+}
+
+func TestHandlePluginMethodEvent_Errors(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47CreateOrderJson), nip47Request)
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	handleErr := error(&plugins.Error{Code: constants.ERROR_NOT_FOUND, Message: "unknown item"})
+	method := &plugins.NIP47Method{
+		Method: "create_order",
+		Scope:  constants.MAKE_INVOICE_SCOPE,
+		Handle: func(ctx context.Context, request *plugins.NIP47Request) (interface{}, error) {
+			return nil, handleErr
+		},
+	}
+
+	NewTestNip47Controller(svc).
+		HandlePluginMethodEvent(ctx, nip47Request, 0, app, method, publishResponse)
+
+	require.NotNil(t, publishedResponse.Error)
+	assert.Equal(t, constants.ERROR_NOT_FOUND, publishedResponse.Error.Code)
+	assert.Equal(t, "unknown item", publishedResponse.Error.Message)
+
+	// errors of the hub keep their usual codes
+	handleErr = transactions.NewInsufficientBalanceError()
+	NewTestNip47Controller(svc).
+		HandlePluginMethodEvent(ctx, nip47Request, 0, app, method, publishResponse)
+
+	require.NotNil(t, publishedResponse.Error)
+	assert.Equal(t, constants.ERROR_INSUFFICIENT_BALANCE, publishedResponse.Error.Code)
+}
@@ -13,17 +13,26 @@ import (
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
 	"github.com/getAlby/hub/nip47/cipher"
 	"github.com/getAlby/hub/nip47/controllers"
 	"github.com/getAlby/hub/nip47/models"
 	"github.com/getAlby/hub/nip47/permissions"
 	nostrmodels "github.com/getAlby/hub/nostr/models"
+	"github.com/getAlby/hub/plugins"
+	"github.com/getAlby/hub/tracing"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
 func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.SimplePool, event *nostr.Event, lnClient lnclient.LNClient) {
+	startedAt := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "nip47.HandleEvent", trace.WithAttributes(attribute.String("nostr.event_id", event.ID)))
+	defer span.End()
 	var nip47Response *models.Response
 	logger.Logger.WithFields(logrus.Fields{
 		"requestEventNostrId": event.ID,
@@ -272,11 +281,28 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 	// we ignore potential DB errors here as this only saves the method and content data
 	svc.db.Model(&requestEvent).Updates(map[string]interface{}{
 		"method":       nip47Request.Method,
-		"content_data": payload,
+		"content_data": db.Encrypt(payload),
 	})
+	span.SetAttributes(
+		attribute.String("nip47.method", nip47Request.Method),
+		attribute.Int("app.id", int(app.ID)),
+	)
 	// TODO: replace with a channel
 	// TODO: update all previous occurrences of svc.publishResponseEvent to also use the channel
 	publishResponse := func(nip47Response *models.Response, tags nostr.Tags) {
+		svc.recordAppActivity(&app, event.ID, event.CreatedAt.Time(), nip47Request, nip47Response)
+
+		errorCode := ""
+		if nip47Response.Error != nil {
+			errorCode = nip47Response.Error.Code
+		}
+		metrics.ObserveNip47Request(nip47Request.Method, errorCode, time.Since(startedAt))
+		// multi_pay_* requests publish a response for every payment
+		span.AddEvent("nip47.response", trace.WithAttributes(attribute.String("nip47.error_code", errorCode)))
+		if errorCode != "" {
+			span.SetStatus(codes.Error, errorCode)
+		}
+
 		var state string
 		resp, err := svc.CreateResponse(event, nip47Response, tags, nip47Cipher, appWalletPrivKey)
 		if err != nil {
@@ -381,9 +407,10 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 			}, nostr.Tags{})
 			return
 		}
+
 	}
 
-	controller := controllers.NewNip47Controller(lnClient, svc.db, svc.eventPublisher, svc.permissionsService, svc.transactionsService, svc.appsService, svc.albyOAuthSvc)
+	controller := controllers.NewNip47Controller(lnClient, svc.db, svc.eventPublisher, svc.permissionsService, svc.transactionsService, svc.appsService, svc.albyOAuthSvc, svc.cfg)
 
 	switch nip47Request.Method {
 	case models.MULTI_PAY_INVOICE_METHOD:
@@ -432,6 +459,11 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 		controller.
 			HandleSettleHoldInvoiceEvent(ctx, nip47Request, requestEvent.ID, app.ID, publishResponse)
 	default:
+		if pluginMethod := plugins.FindNIP47Method(nip47Request.Method); pluginMethod != nil {
+			controller.
+				HandlePluginMethodEvent(ctx, nip47Request, requestEvent.ID, &app, pluginMethod, publishResponse)
+			return
+		}
 		publishResponse(&models.Response{
 			ResultType: nip47Request.Method,
 			Error: &models.Error{
@@ -477,6 +509,9 @@ func (svc *nip47Service) CreateResponse(initialEvent *nostr.Event, content inter
 }
 
 func (svc *nip47Service) publishResponseEvent(ctx context.Context, pool nostrmodels.SimplePool, requestEvent *db.RequestEvent, resp *nostr.Event, app *db.App) error {
+	ctx, span := tracing.Tracer().Start(ctx, "nip47.PublishResponse")
+	defer span.End()
+
 	var appId *uint
 	if app != nil {
 		appId = &app.ID
@@ -508,11 +543,13 @@ func (svc *nip47Service) publishResponseEvent(ctx context.Context, pool nostrmod
 				"responseNostrEventId": resp.ID,
 				"relay":                result.RelayURL,
 			}).WithError(result.Error).Error("failed to publish response event to relay")
+			metrics.IncRelayPublishFailures(result.RelayURL, "response")
 		}
 	}
 
 	if !publishSuccessful {
 		updateColumns["state"] = db.RESPONSE_EVENT_STATE_PUBLISH_FAILED
+		span.SetStatus(codes.Error, "failed to publish response to any relay")
 		logger.Logger.WithFields(logrus.Fields{
 			"requestEventId":       requestEvent.ID,
 			"requestNostrEventId":  requestEvent.NostrId,
@@ -174,6 +174,13 @@ func doTestHandleResponse_WithPermission(t *testing.T, svc *tests.TestService, c
 	assert.Equal(t, models.GET_INFO_METHOD, unmarshalledResponse.ResultType)
 	expectedMethods := slices.Concat([]string{constants.GET_BALANCE_SCOPE}, permissions.GetAlwaysGrantedMethods())
 	assert.ElementsMatch(t, expectedMethods, unmarshalledResponse.Result.Methods)
+
+	var activityLog db.AppActivityLog
+	require.NoError(t, svc.DB.First(&activityLog, &db.AppActivityLog{AppId: app.ID}).Error)
+	assert.Equal(t, reqEvent.ID, activityLog.RequestNostrId)
+	assert.Equal(t, models.GET_INFO_METHOD, activityLog.Method)
+	assert.Equal(t, constants.APP_ACTIVITY_RESULT_SUCCESS, activityLog.Result)
+	assert.Equal(t, reqEvent.CreatedAt.Time().Unix(), activityLog.RequestedAt.Unix())
 }
 
 func TestHandleResponse_Nip04_DuplicateRequest(t *testing.T) {
@@ -321,6 +328,13 @@ func doTestHandleResponse_NoPermission(t *testing.T, svc *tests.TestService, cre
 	assert.Equal(t, models.GET_BALANCE_METHOD, unmarshalledResponse.ResultType)
 	assert.Equal(t, "RESTRICTED", unmarshalledResponse.Error.Code)
 	assert.Equal(t, "This app does not have the get_balance scope", unmarshalledResponse.Error.Message)
+
+	var activityLog db.AppActivityLog
+	require.NoError(t, svc.DB.First(&activityLog).Error)
+	assert.Equal(t, models.GET_BALANCE_METHOD, activityLog.Method)
+	assert.Equal(t, constants.APP_ACTIVITY_RESULT_ERROR, activityLog.Result)
+	assert.Equal(t, "RESTRICTED", activityLog.ErrorCode)
+	assert.Equal(t, "This app does not have the get_balance scope", activityLog.ErrorMessage)
 }
 
 func TestHandleResponse_Nip04_OldRequestForPayment(t *testing.T) {
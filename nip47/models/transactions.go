@@ -41,6 +41,23 @@ func ToNip47Transaction(transaction *transactions.Transaction) *Transaction {
 		}
 	}
 
+	if transaction.Boostagram != nil {
+		var boostagram map[string]interface{}
+		jsonErr := json.Unmarshal(transaction.Boostagram, &boostagram)
+		if jsonErr != nil {
+			logger.Logger.WithError(jsonErr).WithFields(logrus.Fields{
+				"payment_hash": transaction.PaymentHash,
+				"boostagram":   transaction.Boostagram,
+			}).Error("Failed to deserialize transaction boostagram")
+		} else {
+			if metadata == nil {
+				metadata = map[string]interface{}{}
+			}
+			// podcast apps read the parsed boostagram rather than decoding the raw TLV records
+			metadata["boostagram"] = boostagram
+		}
+	}
+
 	return &Transaction{
 		Type:            transaction.Type,
 		State:           state,
@@ -2,11 +2,15 @@ package nip47
 
 import (
 	"context"
+	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/apps"
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
@@ -32,10 +36,13 @@ type nip47Service struct {
 	keys                   keys.Keys
 	db                     *gorm.DB
 	eventPublisher         events.EventPublisher
+	// set once the notifier runs, used to replay dead letters
+	nip47Notifier atomic.Pointer[notifications.Nip47Notifier]
 }
 
 type Nip47Service interface {
 	events.EventSubscriber
+	deadletters.Replayer
 	StartNotifier(ctx context.Context, pool *nostr.SimplePool)
 	StartNip47InfoPublisher(ctx context.Context, pool *nostr.SimplePool, lnClient lnclient.LNClient)
 	HandleEvent(ctx context.Context, pool nostrmodels.SimplePool, event *nostr.Event, lnClient lnclient.LNClient)
@@ -70,6 +77,7 @@ func (svc *nip47Service) ConsumeEvent(ctx context.Context, event *events.Event,
 // to send notifications rather than dropping them
 func (svc *nip47Service) StartNotifier(ctx context.Context, pool *nostr.SimplePool) {
 	nip47Notifier := notifications.NewNip47Notifier(pool, svc.db, svc.cfg, svc.keys, svc.permissionsService)
+	svc.nip47Notifier.Store(nip47Notifier)
 	go func() {
 		for {
 			select {
@@ -90,6 +98,14 @@ func (svc *nip47Service) StartNotifier(ctx context.Context, pool *nostr.SimplePo
 	}()
 }
 
+func (svc *nip47Service) ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error {
+	nip47Notifier := svc.nip47Notifier.Load()
+	if nip47Notifier == nil {
+		return errors.New("not connected to relays")
+	}
+	return nip47Notifier.ReplayDeadLetter(ctx, deadLetter)
+}
+
 func (svc *nip47Service) EnqueueNip47InfoPublishRequest(appId uint, appWalletPubKey, appWalletPrivKey, relayUrl string) {
 	svc.enqueueNip47InfoPublishRequestWithAttempt(appId, appWalletPubKey, appWalletPrivKey, relayUrl, 0)
 }
@@ -11,6 +11,7 @@ const (
 	PAYMENT_RECEIVED_NOTIFICATION      = "payment_received"
 	PAYMENT_SENT_NOTIFICATION          = "payment_sent"
 	HOLD_INVOICE_ACCEPTED_NOTIFICATION = "hold_invoice_accepted"
+	INVOICE_EXPIRED_NOTIFICATION       = "invoice_expired"
 )
 
 type PaymentSentNotification struct {
@@ -24,3 +25,7 @@ type PaymentReceivedNotification struct {
 type HoldInvoiceAcceptedNotification struct {
 	models.Transaction
 }
+
+type InvoiceExpiredNotification struct {
+	models.Transaction
+}
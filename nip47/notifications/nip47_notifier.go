@@ -8,8 +8,10 @@ import (
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
 	"github.com/getAlby/hub/nip47/cipher"
 	"github.com/getAlby/hub/nip47/models"
 	"github.com/getAlby/hub/nip47/permissions"
@@ -89,6 +91,23 @@ func (notifier *Nip47Notifier) ConsumeEvent(ctx context.Context, event *events.E
 			Notification:     notification,
 			NotificationType: HOLD_INVOICE_ACCEPTED_NOTIFICATION,
 		}, nostr.Tags{}, dbTransaction.AppId)
+
+	case "nwc_invoice_expired":
+		dbTransaction, ok := event.Properties.(*db.Transaction)
+		if !ok {
+			logger.Logger.WithField("event", event).Error("Failed to cast event properties to db.Transaction for invoice expired")
+			return errors.New("failed to cast event")
+		}
+
+		notification := InvoiceExpiredNotification{
+			Transaction: *models.ToNip47Transaction(dbTransaction),
+		}
+
+		// only the app which created the invoice is waiting for it
+		notifier.notifyOwningApp(ctx, &Notification{
+			Notification:     notification,
+			NotificationType: INVOICE_EXPIRED_NOTIFICATION,
+		}, nostr.Tags{}, dbTransaction.AppId)
 	}
 	return nil
 }
@@ -107,43 +126,100 @@ func (notifier *Nip47Notifier) notifySubscribers(ctx context.Context, notificati
 		if app.Isolated && (appId == nil || app.ID != *appId) {
 			continue
 		}
+		// a failure only affects this app, the other apps are still notified
+		notifier.notifyAppWithPermission(ctx, &app, notification, tags)
+	}
+	return nil
+}
 
-		hasPermission, _, _ := notifier.permissionsSvc.HasPermission(&app, constants.NOTIFICATIONS_SCOPE)
-		if !hasPermission {
-			continue
-		}
+// notifyOwningApp only notifies the app with the given id, e.g. the app which created an invoice
+func (notifier *Nip47Notifier) notifyOwningApp(ctx context.Context, notification *Notification, tags nostr.Tags, appId *uint) {
+	if appId == nil {
+		return
+	}
+	var app db.App
+	if err := notifier.db.Limit(1).Find(&app, *appId).Error; err != nil {
+		logger.Logger.WithField("appId", *appId).WithError(err).Error("Failed to fetch app")
+		return
+	}
+	if app.ID == 0 {
+		return
+	}
+	notifier.notifyAppWithPermission(ctx, &app, notification, tags)
+}
 
-		appWalletPrivKey := notifier.keys.GetNostrSecretKey()
-		if app.WalletPubkey != nil {
-			appWalletPrivKey, err = notifier.keys.GetAppWalletKey(app.ID)
-			if err != nil {
-				logger.Logger.WithFields(logrus.Fields{
-					"notification": notification,
-					"appId":        app.ID,
-				}).WithError(err).Error("error deriving child key")
-				return errors.New("failed to derive child key")
-			}
-		}
+// notifyAppWithPermission notifies the app if it has the notifications permission,
+// failed notifications are kept as dead letters
+func (notifier *Nip47Notifier) notifyAppWithPermission(ctx context.Context, app *db.App, notification *Notification, tags nostr.Tags) {
+	hasPermission, _, _ := notifier.permissionsSvc.HasPermission(app, constants.NOTIFICATIONS_SCOPE)
+	if !hasPermission {
+		return
+	}
+
+	if err := notifier.notifyApp(ctx, app, notification, tags); err != nil {
+		deadletters.Add(notifier.db, deadletters.CONSUMER_NIP47_NOTIFICATIONS, notification.NotificationType, &notificationDeadLetter{
+			AppId:        app.ID,
+			Notification: notification,
+			Tags:         tags,
+		}, err)
+	}
+}
+
+type notificationDeadLetter struct {
+	AppId        uint          `json:"app_id"`
+	Notification *Notification `json:"notification"`
+	Tags         nostr.Tags    `json:"tags"`
+}
+
+// ReplayDeadLetter sends a notification again to the app it was meant for
+func (notifier *Nip47Notifier) ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error {
+	var payload notificationDeadLetter
+	if err := json.Unmarshal([]byte(deadLetter.Payload), &payload); err != nil {
+		return err
+	}
+	var app db.App
+	if notifier.db.Limit(1).Find(&app, payload.AppId).RowsAffected == 0 {
+		return errors.New("app not found")
+	}
+	hasPermission, _, _ := notifier.permissionsSvc.HasPermission(&app, constants.NOTIFICATIONS_SCOPE)
+	if !hasPermission {
+		return errors.New("app no longer has the notifications permission")
+	}
+	return notifier.notifyApp(ctx, &app, payload.Notification, payload.Tags)
+}
 
-		appWalletPubKey, err := nostr.GetPublicKey(appWalletPrivKey)
+func (notifier *Nip47Notifier) notifyApp(ctx context.Context, app *db.App, notification *Notification, tags nostr.Tags) error {
+	var err error
+	appWalletPrivKey := notifier.keys.GetNostrSecretKey()
+	if app.WalletPubkey != nil {
+		appWalletPrivKey, err = notifier.keys.GetAppWalletKey(app.ID)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"notification": notification,
 				"appId":        app.ID,
-			}).WithError(err).Error("Failed to calculate app wallet pub key")
-			return errors.New("failed to calculate app wallet pubkey")
+			}).WithError(err).Error("error deriving child key")
+			return errors.New("failed to derive child key")
 		}
+	}
 
-		err = notifier.notifySubscriber(ctx, &app, notification, tags, appWalletPubKey, appWalletPrivKey, constants.ENCRYPTION_TYPE_NIP04)
-		if err != nil {
-			logger.Logger.WithError(err).Error("failed to notify subscriber (NIP-04)")
-			return err
-		}
-		err = notifier.notifySubscriber(ctx, &app, notification, tags, appWalletPubKey, appWalletPrivKey, constants.ENCRYPTION_TYPE_NIP44_V2)
-		if err != nil {
-			logger.Logger.WithError(err).Error("failed to notify subscriber (NIP-44)")
-			return err
-		}
+	appWalletPubKey, err := nostr.GetPublicKey(appWalletPrivKey)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"notification": notification,
+			"appId":        app.ID,
+		}).WithError(err).Error("Failed to calculate app wallet pub key")
+		return errors.New("failed to calculate app wallet pubkey")
+	}
+
+	err = notifier.notifySubscriber(ctx, app, notification, tags, appWalletPubKey, appWalletPrivKey, constants.ENCRYPTION_TYPE_NIP04)
+	if err != nil {
+		logger.Logger.WithError(err).Error("failed to notify subscriber (NIP-04)")
+		return err
+	}
+	err = notifier.notifySubscriber(ctx, app, notification, tags, appWalletPubKey, appWalletPrivKey, constants.ENCRYPTION_TYPE_NIP44_V2)
+	if err != nil {
+		logger.Logger.WithError(err).Error("failed to notify subscriber (NIP-44)")
+		return err
 	}
 	return nil
 }
@@ -224,6 +300,7 @@ func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App
 				"appId":        app.ID,
 				"relay":        result.RelayURL,
 			}).WithError(result.Error).Error("failed to publish notification to relay")
+			metrics.IncRelayPublishFailures(result.RelayURL, "notification")
 		}
 	}
 
@@ -232,8 +309,8 @@ func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App
 			"notification": notification,
 			"appId":        app.ID,
 			"encryption":   encryption,
-		}).WithError(err).Error("Failed to publish notification")
-		return err
+		}).Error("Failed to publish notification")
+		return errors.New("failed to publish notification to all relays")
 	}
 	logger.Logger.WithFields(logrus.Fields{
 		"appId":      app.ID,
@@ -310,3 +310,51 @@ func TestSendNotification_SharedWalletPubkey_NoPermission(t *testing.T) {
 	assert.NoError(t, err)
 	doTestSendNotificationNoPermission(t, svc)
 }
+
+func TestSendNotification_InvoiceExpired(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, cipher, err := tests.CreateAppWithPrivateKey(svc, nostr.GeneratePrivateKey(), constants.ENCRYPTION_TYPE_NIP44_V2)
+	require.NoError(t, err)
+	otherApp, _, err := tests.CreateAppWithPrivateKey(svc, nostr.GeneratePrivateKey(), constants.ENCRYPTION_TYPE_NIP44_V2)
+	require.NoError(t, err)
+	for _, appId := range []uint{app.ID, otherApp.ID} {
+		require.NoError(t, svc.DB.Create(&db.AppPermission{AppId: appId, Scope: constants.NOTIFICATIONS_SCOPE}).Error)
+	}
+
+	expiresAt := time.Now().Add(-time.Minute)
+	expiredInvoice := db.Transaction{
+		Type:           constants.TRANSACTION_TYPE_INCOMING,
+		State:          constants.TRANSACTION_STATE_EXPIRED,
+		PaymentRequest: tests.MockLNClientTransaction.Invoice,
+		PaymentHash:    tests.MockLNClientTransaction.PaymentHash,
+		AmountMsat:     uint64(tests.MockLNClientTransaction.Amount),
+		ExpiresAt:      &expiresAt,
+		AppId:          &app.ID,
+	}
+	require.NoError(t, svc.DB.Create(&expiredInvoice).Error)
+
+	pool := tests.NewMockSimplePool()
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	notifier := NewNip47Notifier(pool, svc.DB, svc.Cfg, svc.Keys, permissionsSvc)
+	require.NoError(t, notifier.ConsumeEvent(ctx, &events.Event{
+		Event:      "nwc_invoice_expired",
+		Properties: &expiredInvoice,
+	}))
+
+	// only the app which created the invoice is notified (NIP-04 and NIP-44)
+	require.Len(t, pool.PublishedEvents, 2)
+	decrypted, err := cipher.Decrypt(pool.PublishedEvents[1].Content)
+	require.NoError(t, err)
+	unmarshalledResponse := Notification{
+		Notification: &InvoiceExpiredNotification{},
+	}
+	require.NoError(t, json.Unmarshal([]byte(decrypted), &unmarshalledResponse))
+	assert.Equal(t, INVOICE_EXPIRED_NOTIFICATION, unmarshalledResponse.NotificationType)
+	transaction := unmarshalledResponse.Notification.(*InvoiceExpiredNotification)
+	assert.Equal(t, "expired", transaction.State)
+	assert.Equal(t, tests.MockLNClientTransaction.PaymentHash, transaction.PaymentHash)
+}
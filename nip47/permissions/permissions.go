@@ -11,6 +11,7 @@ import (
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/plugins"
 	"github.com/getAlby/hub/utils"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -36,6 +37,16 @@ func NewPermissionsService(db *gorm.DB, eventPublisher events.EventPublisher) *p
 }
 
 func (svc *permissionsService) HasPermission(app *db.App, scope string) (result bool, code string, message string) {
+	if app.PausedAt != nil {
+		return false, constants.ERROR_RESTRICTED, "This app is paused"
+	}
+	if app.ReadOnly && !slices.Contains(ReadOnlyScopes(), scope) {
+		return false, constants.ERROR_RESTRICTED, fmt.Sprintf("This read-only app cannot have the %s scope", scope)
+	}
+	if app.ReceiveOnly && !slices.Contains(ReceiveOnlyScopes(), scope) {
+		return false, constants.ERROR_RESTRICTED, fmt.Sprintf("This receive-only app cannot have the %s scope", scope)
+	}
+
 	appPermission := db.AppPermission{}
 	findPermissionResult := svc.db.Limit(1).Find(&appPermission, &db.AppPermission{
 		AppId: app.ID,
@@ -65,6 +76,12 @@ func (svc *permissionsService) GetPermittedMethods(app *db.App, lnClient lnclien
 	svc.db.Where("app_id = ?", app.ID).Find(&appPermissions)
 	scopes := make([]string, 0, len(appPermissions))
 	for _, appPermission := range appPermissions {
+		if app.ReadOnly && !slices.Contains(ReadOnlyScopes(), appPermission.Scope) {
+			continue
+		}
+		if app.ReceiveOnly && !slices.Contains(ReceiveOnlyScopes(), appPermission.Scope) {
+			continue
+		}
 		scopes = append(scopes, appPermission.Scope)
 	}
 
@@ -83,6 +100,10 @@ func (svc *permissionsService) GetPermittedMethods(app *db.App, lnClient lnclien
 		if requestMethod == models.CREATE_CONNECTION_METHOD {
 			return true
 		}
+		// plugin methods do not depend on the lnclient
+		if plugins.FindNIP47Method(requestMethod) != nil {
+			return true
+		}
 
 		return slices.Contains(lnClientSupportedMethods, requestMethod)
 	})
@@ -106,6 +127,7 @@ func scopesToRequestMethods(scopes []string) []string {
 	for _, scope := range scopes {
 		scopeRequestMethods := scopeToRequestMethods(scope)
 		requestMethods = append(requestMethods, scopeRequestMethods...)
+		requestMethods = append(requestMethods, plugins.NIP47MethodsForScope(scope)...)
 	}
 	return requestMethods
 }
@@ -170,6 +192,9 @@ func RequestMethodToScope(requestMethod string) (string, error) {
 	case models.CREATE_CONNECTION_METHOD:
 		return constants.SUPERUSER_SCOPE, nil
 	}
+	if pluginMethod := plugins.FindNIP47Method(requestMethod); pluginMethod != nil {
+		return pluginMethod.Scope, nil
+	}
 	logger.Logger.WithField("request_method", requestMethod).Error("Unsupported request method")
 	return "", fmt.Errorf("unsupported request method: %s", requestMethod)
 }
@@ -188,6 +213,28 @@ func AllScopes() []string {
 	}
 }
 
+// ReadOnlyScopes are the only scopes a read-only app can have
+func ReadOnlyScopes() []string {
+	return []string{
+		constants.GET_INFO_SCOPE,
+		constants.GET_BALANCE_SCOPE,
+		constants.LOOKUP_INVOICE_SCOPE,
+		constants.LIST_TRANSACTIONS_SCOPE,
+	}
+}
+
+// ReceiveOnlyScopes are the only scopes a receive-only app can have
+func ReceiveOnlyScopes() []string {
+	return []string{
+		constants.GET_INFO_SCOPE,
+		constants.GET_BALANCE_SCOPE,
+		constants.MAKE_INVOICE_SCOPE,
+		constants.LOOKUP_INVOICE_SCOPE,
+		constants.LIST_TRANSACTIONS_SCOPE,
+		constants.NOTIFICATIONS_SCOPE,
+	}
+}
+
 func GetAlwaysGrantedMethods() []string {
 	return []string{models.GET_INFO_METHOD, models.GET_BUDGET_METHOD}
 }
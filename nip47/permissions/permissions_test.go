@@ -162,3 +162,98 @@ func TestGetPermittedMethods_PayInvoiceScopeGivesAllPaymentMethods(t *testing.T)
 	assert.Contains(t, result, models.MULTI_PAY_INVOICE_METHOD)
 	assert.Contains(t, result, models.MULTI_PAY_KEYSEND_METHOD)
 }
+
+func TestHasPermission_ReadOnly(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app.ReadOnly = true
+	require.NoError(t, svc.DB.Save(app).Error)
+
+	for _, scope := range []string{constants.PAY_INVOICE_SCOPE, constants.GET_BALANCE_SCOPE} {
+		err = svc.DB.Create(&db.AppPermission{
+			AppId: app.ID,
+			App:   *app,
+			Scope: scope,
+		}).Error
+		assert.NoError(t, err)
+	}
+
+	permissionsSvc := NewPermissionsService(svc.DB, svc.EventPublisher)
+	result, code, message := permissionsSvc.HasPermission(app, constants.PAY_INVOICE_SCOPE)
+	assert.False(t, result)
+	assert.Equal(t, constants.ERROR_RESTRICTED, code)
+	assert.Equal(t, "This read-only app cannot have the pay_invoice scope", message)
+
+	result, _, _ = permissionsSvc.HasPermission(app, constants.GET_BALANCE_SCOPE)
+	assert.True(t, result)
+
+	methods := permissionsSvc.GetPermittedMethods(app, svc.LNClient)
+	assert.Contains(t, methods, models.GET_BALANCE_METHOD)
+	assert.NotContains(t, methods, models.PAY_INVOICE_METHOD)
+}
+
+func TestHasPermission_ReceiveOnly(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app.Isolated = true
+	app.ReceiveOnly = true
+	require.NoError(t, svc.DB.Save(app).Error)
+
+	for _, scope := range []string{constants.PAY_INVOICE_SCOPE, constants.MAKE_INVOICE_SCOPE} {
+		err = svc.DB.Create(&db.AppPermission{
+			AppId: app.ID,
+			App:   *app,
+			Scope: scope,
+		}).Error
+		assert.NoError(t, err)
+	}
+
+	permissionsSvc := NewPermissionsService(svc.DB, svc.EventPublisher)
+	result, code, message := permissionsSvc.HasPermission(app, constants.PAY_INVOICE_SCOPE)
+	assert.False(t, result)
+	assert.Equal(t, constants.ERROR_RESTRICTED, code)
+	assert.Equal(t, "This receive-only app cannot have the pay_invoice scope", message)
+
+	result, _, _ = permissionsSvc.HasPermission(app, constants.MAKE_INVOICE_SCOPE)
+	assert.True(t, result)
+
+	methods := permissionsSvc.GetPermittedMethods(app, svc.LNClient)
+	assert.Contains(t, methods, models.MAKE_INVOICE_METHOD)
+	assert.NotContains(t, methods, models.PAY_INVOICE_METHOD)
+}
+
+func TestHasPermission_Paused(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	err = svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error
+	assert.NoError(t, err)
+
+	require.NoError(t, svc.AppsService.SetAppPaused(app, true))
+
+	permissionsSvc := NewPermissionsService(svc.DB, svc.EventPublisher)
+	result, code, message := permissionsSvc.HasPermission(app, constants.PAY_INVOICE_SCOPE)
+	assert.False(t, result)
+	assert.Equal(t, constants.ERROR_RESTRICTED, code)
+	assert.Equal(t, "This app is paused", message)
+
+	require.NoError(t, svc.AppsService.SetAppPaused(app, false))
+	result, _, _ = permissionsSvc.HasPermission(app, constants.PAY_INVOICE_SCOPE)
+	assert.True(t, result)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
 	"github.com/getAlby/hub/nip47/cipher"
 	"github.com/getAlby/hub/nip47/models"
 	nostrmodels "github.com/getAlby/hub/nostr/models"
@@ -114,6 +115,7 @@ func (svc *nip47Service) PublishNip47Info(ctx context.Context, pool nostrmodels.
 				"appId": appId,
 				"relay": result.RelayURL,
 			}).WithError(result.Error).Error("failed to publish nip47 info to relay")
+			metrics.IncRelayPublishFailures(result.RelayURL, "info")
 		}
 	}
 	if !publishSuccessful {
@@ -0,0 +1,77 @@
+package nip47
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestHandleResponse_PaymentRateLimited(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	albyOAuthSvc := alby.NewAlbyOAuthService(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher, albyOAuthSvc)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	reqPubkey, err := nostr.GetPublicKey(reqPrivateKey)
+	require.NoError(t, err)
+
+	app, cipher, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey, constants.ENCRYPTION_TYPE_NIP44_V2)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+	require.NoError(t, svc.DB.Model(app).Update("max_payments_per_minute", 1).Error)
+	require.NoError(t, svc.DB.Create(&db.Transaction{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_SETTLED, PaymentHash: "1"}).Error)
+
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"method": models.PAY_INVOICE_METHOD,
+		"params": map[string]interface{}{
+			"invoice": tests.MockLNClientTransaction.Invoice,
+		},
+	})
+	require.NoError(t, err)
+	msg, err := cipher.Encrypt(string(payloadBytes))
+	require.NoError(t, err)
+
+	reqEvent := &nostr.Event{
+		Kind:      models.REQUEST_KIND,
+		PubKey:    reqPubkey,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{[]string{"encryption", constants.ENCRYPTION_TYPE_NIP44_V2}},
+		Content:   msg,
+	}
+	require.NoError(t, reqEvent.Sign(reqPrivateKey))
+
+	pool := tests.NewMockSimplePool()
+	nip47svc.HandleEvent(context.TODO(), pool, reqEvent, svc.LNClient)
+
+	require.Len(t, pool.PublishedEvents, 1)
+	decrypted, err := cipher.Decrypt(pool.PublishedEvents[0].Content)
+	require.NoError(t, err)
+
+	unmarshalledResponse := models.Response{}
+	require.NoError(t, json.Unmarshal([]byte(decrypted), &unmarshalledResponse))
+	assert.Nil(t, unmarshalledResponse.Result)
+	assert.Equal(t, constants.ERROR_RATE_LIMITED, unmarshalledResponse.Error.Code)
+	assert.Equal(t, "This app can make at most 1 payments per minute", unmarshalledResponse.Error.Message)
+
+	// no payment was made
+	var count int64
+	require.NoError(t, svc.DB.Model(&db.Transaction{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
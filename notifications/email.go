@@ -0,0 +1,426 @@
+// Package notifications alerts the owner about hub events by email, nostr direct message
+// or push notifications to their mobile devices.
+package notifications
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/forceclose"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	EMAIL_EVENT_LARGE_PAYMENT = "large_payment"
+	EMAIL_EVENT_FORCE_CLOSE   = "force_close"
+	EMAIL_EVENT_LOW_BALANCE   = "low_balance"
+	EMAIL_EVENT_BACKUP_FAILED = "backup_failed"
+)
+
+const (
+	EMAIL_MODE_IMMEDIATE = "immediate"
+	EMAIL_MODE_DIGEST    = "digest"
+)
+
+const defaultDigestIntervalHours = 24
+
+// checkInterval is how often the digest is flushed and the balance is checked
+var checkInterval = time.Minute
+
+// EmailSettings are stored as JSON in the user config. Without recipients no emails are sent.
+type EmailSettings struct {
+	Recipients []string `json:"recipients"`
+	Events     []string `json:"events"`
+	// immediate sends an email per event, digest collects them into one email
+	Mode                string `json:"mode"`
+	DigestIntervalHours uint   `json:"digestIntervalHours,omitempty"`
+	// payments of at least this amount are reported as large payments
+	LargePaymentThresholdSat uint64 `json:"largePaymentThresholdSat,omitempty"`
+	// the lightning spending balance below which a low balance is reported
+	LowBalanceThresholdSat uint64 `json:"lowBalanceThresholdSat,omitempty"`
+}
+
+func GetEmailEventTypes() []string {
+	return []string{
+		EMAIL_EVENT_LARGE_PAYMENT,
+		EMAIL_EVENT_FORCE_CLOSE,
+		EMAIL_EVENT_LOW_BALANCE,
+		EMAIL_EVENT_BACKUP_FAILED,
+	}
+}
+
+// IsConfigured returns whether an SMTP server is set up to send emails
+func IsConfigured(appConfig *config.AppConfig) bool {
+	return appConfig.SmtpHost != "" && appConfig.SmtpFrom != ""
+}
+
+func ValidateEmailSettings(settings *EmailSettings) error {
+	for _, recipient := range settings.Recipients {
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+	}
+	for _, event := range settings.Events {
+		if !slices.Contains(GetEmailEventTypes(), event) {
+			return fmt.Errorf("unsupported event %s. Must be one of %s", event, strings.Join(GetEmailEventTypes(), ","))
+		}
+	}
+	if settings.Mode != EMAIL_MODE_IMMEDIATE && settings.Mode != EMAIL_MODE_DIGEST {
+		return fmt.Errorf("invalid mode. Must be one of %s,%s", EMAIL_MODE_IMMEDIATE, EMAIL_MODE_DIGEST)
+	}
+	if slices.Contains(settings.Events, EMAIL_EVENT_LARGE_PAYMENT) && settings.LargePaymentThresholdSat == 0 {
+		return errors.New("a large payment threshold is required")
+	}
+	if slices.Contains(settings.Events, EMAIL_EVENT_LOW_BALANCE) && settings.LowBalanceThresholdSat == 0 {
+		return errors.New("a low balance threshold is required")
+	}
+	return nil
+}
+
+func GetEmailSettings(cfg config.Config) (*EmailSettings, error) {
+	value, err := cfg.Get(config.EmailNotificationsKey, "")
+	if err != nil {
+		return nil, err
+	}
+	settings := &EmailSettings{
+		Recipients: []string{},
+		Events:     []string{},
+		Mode:       EMAIL_MODE_IMMEDIATE,
+	}
+	if value == "" {
+		return settings, nil
+	}
+	if err := json.Unmarshal([]byte(value), settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func SetEmailSettings(cfg config.Config, settings *EmailSettings) error {
+	if err := ValidateEmailSettings(settings); err != nil {
+		return err
+	}
+	value, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return cfg.SetUpdate(config.EmailNotificationsKey, string(value), "")
+}
+
+type notification struct {
+	event     string
+	subject   string
+	body      string
+	createdAt time.Time
+}
+
+type emailNotifier struct {
+	db          *gorm.DB
+	cfg         config.Config
+	getLNClient func() lnclient.LNClient
+	send        func(recipients []string, subject string, body string) error
+
+	mu                sync.Mutex
+	digest            []notification
+	lastDigestAt      time.Time
+	lowBalanceAlerted bool
+}
+
+func NewEmailNotifier(gormDB *gorm.DB, cfg config.Config, getLNClient func() lnclient.LNClient) *emailNotifier {
+	return &emailNotifier{
+		db:          gormDB,
+		cfg:         cfg,
+		getLNClient: getLNClient,
+		send: func(recipients []string, subject string, body string) error {
+			return SendEmail(cfg.GetEnv(), recipients, subject, body)
+		},
+		lastDigestAt: time.Now(),
+	}
+}
+
+// Start periodically checks the balance and sends the digest until ctx is done
+func (notifier *emailNotifier) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				notifier.checkBalance(ctx)
+				notifier.sendDigest(time.Now(), false)
+			}
+		}
+	}()
+}
+
+func (notifier *emailNotifier) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	settings, err := GetEmailSettings(notifier.cfg)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get email notification settings")
+		return
+	}
+	if len(settings.Recipients) == 0 || len(settings.Events) == 0 {
+		return
+	}
+
+	switch event.Event {
+	case "nwc_payment_received", "nwc_payment_sent":
+		transaction, ok := event.Properties.(*db.Transaction)
+		if !ok {
+			return
+		}
+		if slices.Contains(settings.Events, EMAIL_EVENT_LARGE_PAYMENT) && transaction.AmountMsat >= settings.LargePaymentThresholdSat*1000 {
+			direction := "Received"
+			if event.Event == "nwc_payment_sent" {
+				direction = "Sent"
+			}
+			body := fmt.Sprintf("%s a payment of %d sats.", direction, transaction.AmountMsat/1000)
+			if transaction.Description != "" {
+				body += fmt.Sprintf("\nDescription: %s", transaction.Description)
+			}
+			body += fmt.Sprintf("\nPayment hash: %s", transaction.PaymentHash)
+			notifier.notify(settings, EMAIL_EVENT_LARGE_PAYMENT, fmt.Sprintf("%s %d sats", direction, transaction.AmountMsat/1000), body)
+		}
+		if event.Event == "nwc_payment_sent" {
+			notifier.checkBalance(ctx)
+		}
+	case "nwc_channel_force_closed", "nwc_force_close_funds_spendable":
+		properties, ok := event.Properties.(map[string]interface{})
+		if !ok || !slices.Contains(settings.Events, EMAIL_EVENT_FORCE_CLOSE) {
+			return
+		}
+		if event.Event == "nwc_force_close_funds_spendable" {
+			body := fmt.Sprintf("The %v sats from the force-closed channel with %v were swept and can be spent on-chain.", properties["amount_sat"], properties["counterparty_node_id"])
+			notifier.notify(settings, EMAIL_EVENT_FORCE_CLOSE, "Force-closed channel funds spendable", body)
+			return
+		}
+		body := fmt.Sprintf("A channel with %v was force-closed by the %s side (%v).", properties["counterparty_node_id"], initiatorSide(properties["initiator"]), properties["reason"])
+		body += "\n" + describePendingBalance(properties)
+		notifier.notify(settings, EMAIL_EVENT_FORCE_CLOSE, "Channel force-closed", body)
+	case "nwc_backup_failed":
+		properties, ok := event.Properties.(map[string]interface{})
+		if !ok || !slices.Contains(settings.Events, EMAIL_EVENT_BACKUP_FAILED) {
+			return
+		}
+		body := fmt.Sprintf("The backup to %v failed: %v", properties["name"], properties["error"])
+		notifier.notify(settings, EMAIL_EVENT_BACKUP_FAILED, "Backup failed", body)
+	}
+}
+
+// initiatorSide describes who force-closed a channel
+func initiatorSide(initiator interface{}) string {
+	switch initiator {
+	case forceclose.INITIATOR_LOCAL:
+		return "local"
+	case forceclose.INITIATOR_REMOTE:
+		return "remote"
+	}
+	return "unknown"
+}
+
+// describePendingBalance describes the timelocked funds of a force-closed channel
+func describePendingBalance(properties map[string]interface{}) string {
+	if pendingBalanceSat, _ := properties["pending_balance_sat"].(uint64); pendingBalanceSat == 0 {
+		return "No funds are pending on-chain."
+	}
+	description := fmt.Sprintf("%v sats are pending on-chain", properties["pending_balance_sat"])
+	if expectedSpendableAt, ok := properties["expected_spendable_at"].(*time.Time); ok && expectedSpendableAt != nil {
+		description += fmt.Sprintf(" until the timelock expires in %v blocks, expected around %s", properties["blocks_until_spendable"], expectedSpendableAt.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	return description + ". You will be notified once they can be spent."
+}
+
+// checkBalance reports a low balance once, until the balance is above the threshold again
+func (notifier *emailNotifier) checkBalance(ctx context.Context) {
+	lnClient := notifier.getLNClient()
+	if lnClient == nil {
+		return
+	}
+	settings, err := GetEmailSettings(notifier.cfg)
+	if err != nil || len(settings.Recipients) == 0 || !slices.Contains(settings.Events, EMAIL_EVENT_LOW_BALANCE) {
+		return
+	}
+	balances, err := lnClient.GetBalances(ctx, false)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get balances for low balance notification")
+		return
+	}
+	spendableSat := balances.Lightning.TotalSpendable / 1000
+
+	notifier.mu.Lock()
+	isLow := spendableSat < int64(settings.LowBalanceThresholdSat)
+	shouldAlert := isLow && !notifier.lowBalanceAlerted
+	notifier.lowBalanceAlerted = isLow
+	notifier.mu.Unlock()
+
+	if shouldAlert {
+		body := fmt.Sprintf("The lightning spending balance of %d sats is below %d sats.", spendableSat, settings.LowBalanceThresholdSat)
+		notifier.notify(settings, EMAIL_EVENT_LOW_BALANCE, "Low balance", body)
+	}
+}
+
+func (notifier *emailNotifier) notify(settings *EmailSettings, event string, subject string, body string) {
+	if settings.Mode == EMAIL_MODE_DIGEST {
+		notifier.mu.Lock()
+		notifier.digest = append(notifier.digest, notification{
+			event:     event,
+			subject:   subject,
+			body:      body,
+			createdAt: time.Now(),
+		})
+		notifier.mu.Unlock()
+		return
+	}
+
+	if err := notifier.send(settings.Recipients, subject, body); err != nil {
+		logger.Logger.WithError(err).WithField("event", event).Error("Failed to send email notification")
+		deadletters.Add(notifier.db, deadletters.CONSUMER_EMAIL, event, &emailDeadLetter{settings.Recipients, subject, body}, err)
+	}
+}
+
+type emailDeadLetter struct {
+	Recipients []string `json:"recipients"`
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body"`
+}
+
+// ReplayDeadLetter sends an email which could not be sent before, to the original recipients
+func (notifier *emailNotifier) ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error {
+	var email emailDeadLetter
+	if err := json.Unmarshal([]byte(deadLetter.Payload), &email); err != nil {
+		return err
+	}
+	return notifier.send(email.Recipients, email.Subject, email.Body)
+}
+
+// sendDigest sends the collected notifications in one email once the digest interval passed.
+// Notifications are only kept in memory, a restart drops the pending digest.
+func (notifier *emailNotifier) sendDigest(now time.Time, force bool) {
+	settings, err := GetEmailSettings(notifier.cfg)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get email notification settings")
+		return
+	}
+
+	digestInterval := time.Duration(settings.DigestIntervalHours) * time.Hour
+	if digestInterval == 0 {
+		digestInterval = defaultDigestIntervalHours * time.Hour
+	}
+
+	notifier.mu.Lock()
+	if !force && now.Sub(notifier.lastDigestAt) < digestInterval {
+		notifier.mu.Unlock()
+		return
+	}
+	notifier.lastDigestAt = now
+	notifications := notifier.digest
+	notifier.digest = nil
+	notifier.mu.Unlock()
+
+	if len(notifications) == 0 || len(settings.Recipients) == 0 {
+		return
+	}
+
+	var body strings.Builder
+	for _, notification := range notifications {
+		fmt.Fprintf(&body, "%s - %s\n%s\n\n", notification.createdAt.UTC().Format(time.RFC3339), notification.subject, notification.body)
+	}
+	subject := fmt.Sprintf("%d notifications", len(notifications))
+	if err := notifier.send(settings.Recipients, subject, body.String()); err != nil {
+		logger.Logger.WithError(err).Error("Failed to send email digest")
+		deadletters.Add(notifier.db, deadletters.CONSUMER_EMAIL, EMAIL_MODE_DIGEST, &emailDeadLetter{settings.Recipients, subject, body.String()}, err)
+	}
+}
+
+// SendEmail sends a plain text email through the configured SMTP server. Port 465 uses
+// implicit TLS, other ports upgrade the connection with STARTTLS if the server offers it.
+func SendEmail(appConfig *config.AppConfig, recipients []string, subject string, body string) error {
+	if !IsConfigured(appConfig) {
+		return errors.New("no SMTP server configured")
+	}
+
+	from, err := mail.ParseAddress(appConfig.SmtpFrom)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+
+	addr := net.JoinHostPort(appConfig.SmtpHost, strconv.FormatUint(uint64(appConfig.SmtpPort), 10))
+	var auth smtp.Auth
+	if appConfig.SmtpUsername != "" {
+		auth = smtp.PlainAuth("", appConfig.SmtpUsername, appConfig.SmtpPassword, appConfig.SmtpHost)
+	}
+
+	message := buildMessage(from.String(), recipients, "[Alby Hub] "+subject, body, time.Now())
+
+	if appConfig.SmtpPort != 465 {
+		return smtp.SendMail(addr, auth, from.Address, recipients, message)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: appConfig.SmtpHost})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, appConfig.SmtpHost)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func buildMessage(from string, recipients []string, subject string, body string, date time.Time) []byte {
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(message.String())
+}
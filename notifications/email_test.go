@@ -0,0 +1,181 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/forceclose"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+type sentEmail struct {
+	recipients []string
+	subject    string
+	body       string
+}
+
+func newTestEmailNotifier(svc *tests.TestService) (*emailNotifier, func() []sentEmail) {
+	var mu sync.Mutex
+	sent := []sentEmail{}
+	notifier := NewEmailNotifier(svc.DB, svc.Cfg, func() lnclient.LNClient { return svc.LNClient })
+	notifier.send = func(recipients []string, subject string, body string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, sentEmail{recipients, subject, body})
+		return nil
+	}
+	return notifier, func() []sentEmail {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]sentEmail{}, sent...)
+	}
+}
+
+func TestSetEmailSettings_Invalid(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	err = SetEmailSettings(svc.Cfg, &EmailSettings{Recipients: []string{"not an email"}, Mode: EMAIL_MODE_IMMEDIATE})
+	assert.Error(t, err)
+	err = SetEmailSettings(svc.Cfg, &EmailSettings{Events: []string{"payment_received"}, Mode: EMAIL_MODE_IMMEDIATE})
+	assert.Error(t, err)
+	err = SetEmailSettings(svc.Cfg, &EmailSettings{Events: []string{EMAIL_EVENT_LARGE_PAYMENT}, Mode: EMAIL_MODE_IMMEDIATE})
+	assert.EqualError(t, err, "a large payment threshold is required")
+	err = SetEmailSettings(svc.Cfg, &EmailSettings{Mode: "weekly"})
+	assert.Error(t, err)
+}
+
+func TestEmailNotifier_Immediate(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, SetEmailSettings(svc.Cfg, &EmailSettings{
+		Recipients:               []string{"alice@example.com"},
+		Events:                   []string{EMAIL_EVENT_LARGE_PAYMENT, EMAIL_EVENT_FORCE_CLOSE},
+		Mode:                     EMAIL_MODE_IMMEDIATE,
+		LargePaymentThresholdSat: 100_000,
+	}))
+	notifier, sent := newTestEmailNotifier(svc)
+
+	for _, amountMsat := range []uint64{99_999_000, 100_000_000} {
+		notifier.ConsumeEvent(context.TODO(), &events.Event{
+			Event:      "nwc_payment_received",
+			Properties: &db.Transaction{AmountMsat: amountMsat, PaymentHash: tests.MockPaymentHash},
+		}, map[string]interface{}{})
+	}
+	expectedSpendableAt := time.Date(2026, 10, 31, 12, 0, 0, 0, time.UTC)
+	notifier.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_channel_force_closed",
+		Properties: map[string]interface{}{
+			"counterparty_node_id":   "abc",
+			"reason":                 "CounterpartyForceClosed (Peer message: bye)",
+			"initiator":              forceclose.INITIATOR_REMOTE,
+			"pending_balance_sat":    uint64(50_000),
+			"blocks_until_spendable": uint32(144),
+			"expected_spendable_at":  &expectedSpendableAt,
+		},
+	}, map[string]interface{}{})
+	notifier.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_force_close_funds_spendable",
+		Properties: map[string]interface{}{
+			"counterparty_node_id": "abc",
+			"amount_sat":           uint64(50_000),
+		},
+	}, map[string]interface{}{})
+	// not selected
+	notifier.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_backup_failed",
+		Properties: map[string]interface{}{"name": "s3", "error": "timeout"},
+	}, map[string]interface{}{})
+
+	emails := sent()
+	require.Len(t, emails, 3)
+	assert.Equal(t, []string{"alice@example.com"}, emails[0].recipients)
+	assert.Equal(t, "Received 100000 sats", emails[0].subject)
+	assert.Contains(t, emails[0].body, tests.MockPaymentHash)
+	assert.Equal(t, "Channel force-closed", emails[1].subject)
+	assert.Contains(t, emails[1].body, "force-closed by the remote side")
+	assert.Contains(t, emails[1].body, "50000 sats are pending on-chain until the timelock expires in 144 blocks, expected around 2026-10-31 12:00 UTC")
+	assert.Equal(t, "Force-closed channel funds spendable", emails[2].subject)
+}
+
+func TestEmailNotifier_Digest(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, SetEmailSettings(svc.Cfg, &EmailSettings{
+		Recipients:          []string{"alice@example.com"},
+		Events:              []string{EMAIL_EVENT_BACKUP_FAILED},
+		Mode:                EMAIL_MODE_DIGEST,
+		DigestIntervalHours: 2,
+	}))
+	notifier, sent := newTestEmailNotifier(svc)
+
+	for _, name := range []string{"s3", "nas"} {
+		notifier.ConsumeEvent(context.TODO(), &events.Event{
+			Event:      "nwc_backup_failed",
+			Properties: map[string]interface{}{"name": name, "error": "timeout"},
+		}, map[string]interface{}{})
+	}
+	assert.Empty(t, sent())
+
+	// the interval has not passed yet
+	notifier.sendDigest(time.Now().Add(time.Hour), false)
+	assert.Empty(t, sent())
+
+	notifier.sendDigest(time.Now().Add(2*time.Hour), false)
+	emails := sent()
+	require.Len(t, emails, 1)
+	assert.Equal(t, "2 notifications", emails[0].subject)
+	assert.Contains(t, emails[0].body, "The backup to s3 failed: timeout")
+	assert.Contains(t, emails[0].body, "The backup to nas failed: timeout")
+
+	// an empty digest is not sent
+	notifier.sendDigest(time.Now().Add(4*time.Hour), false)
+	assert.Len(t, sent(), 1)
+}
+
+func TestEmailNotifier_LowBalance(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// the mock balance is 21 sats
+	require.NoError(t, SetEmailSettings(svc.Cfg, &EmailSettings{
+		Recipients:             []string{"alice@example.com"},
+		Events:                 []string{EMAIL_EVENT_LOW_BALANCE},
+		Mode:                   EMAIL_MODE_IMMEDIATE,
+		LowBalanceThresholdSat: 1000,
+	}))
+	notifier, sent := newTestEmailNotifier(svc)
+
+	// the low balance is only reported once
+	for i := 0; i < 2; i++ {
+		notifier.ConsumeEvent(context.TODO(), &events.Event{
+			Event:      "nwc_payment_sent",
+			Properties: &db.Transaction{AmountMsat: 1000},
+		}, map[string]interface{}{})
+	}
+	emails := sent()
+	require.Len(t, emails, 1)
+	assert.Equal(t, "Low balance", emails[0].subject)
+	assert.Contains(t, emails[0].body, "21 sats")
+}
+
+func TestBuildMessage(t *testing.T) {
+	message := string(buildMessage("hub@example.com", []string{"alice@example.com", "bob@example.com"}, "[Alby Hub] Low balance", "line 1\nline 2", tests.MockTime))
+	assert.Contains(t, message, "To: alice@example.com, bob@example.com\r\n")
+	assert.Contains(t, message, "Subject: [Alby Hub] Low balance\r\n")
+	assert.Contains(t, message, "\r\n\r\nline 1\r\nline 2")
+}
@@ -0,0 +1,208 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip44"
+	"github.com/nbd-wtf/go-nostr/nip59"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+	nostrmodels "github.com/getAlby/hub/nostr/models"
+	"github.com/getAlby/hub/service/keys"
+)
+
+// maxDMRelays limits the relays of the owner's kind 10050 list which receive a message
+const maxDMRelays = 3
+
+const nostrDMTimeout = 30 * time.Second
+
+// nostrDMNotifier sends NIP-17 direct messages about critical events to the owner's npub,
+// signed with the nostr key of the hub
+type nostrDMNotifier struct {
+	db             *gorm.DB
+	cfg            config.Config
+	keys           keys.Keys
+	eventPublisher events.EventPublisher
+	pool           nostrmodels.SimplePool
+}
+
+func NewNostrDMNotifier(gormDB *gorm.DB, cfg config.Config, keys keys.Keys, eventPublisher events.EventPublisher) *nostrDMNotifier {
+	return &nostrDMNotifier{
+		db:             gormDB,
+		cfg:            cfg,
+		keys:           keys,
+		eventPublisher: eventPublisher,
+	}
+}
+
+// ParseNostrPubkey accepts an npub or a hex public key and returns the hex public key
+func ParseNostrPubkey(pubkey string) (string, error) {
+	if prefix, value, err := nip19.Decode(pubkey); err == nil {
+		if prefix != "npub" {
+			return "", fmt.Errorf("expected an npub, got %s", prefix)
+		}
+		return value.(string), nil
+	}
+	if !nostr.IsValidPublicKey(pubkey) {
+		return "", errors.New("invalid nostr public key")
+	}
+	return pubkey, nil
+}
+
+// Start sends messages while the nostr connection of the hub is running
+func (notifier *nostrDMNotifier) Start(ctx context.Context, pool nostrmodels.SimplePool) {
+	notifier.pool = pool
+	notifier.eventPublisher.RegisterSubscriber(notifier)
+	go func() {
+		<-ctx.Done()
+		notifier.eventPublisher.RemoveSubscriber(notifier)
+	}()
+}
+
+func (notifier *nostrDMNotifier) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	message := formatCriticalEvent(event)
+	if message == "" {
+		return
+	}
+	recipient, err := notifier.cfg.Get(config.NostrNotificationsPubkeyKey, "")
+	if err != nil || recipient == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nostrDMTimeout)
+	defer cancel()
+	if err := SendNostrDM(ctx, notifier.pool, notifier.cfg.GetRelayUrls(), notifier.keys.GetNostrSecretKey(), recipient, message); err != nil {
+		logger.Logger.WithError(err).WithField("event", event.Event).Error("Failed to send nostr notification")
+		deadletters.Add(notifier.db, deadletters.CONSUMER_NOSTR_DM, event.Event, &nostrDMDeadLetter{recipient, message}, err)
+	}
+}
+
+type nostrDMDeadLetter struct {
+	Recipient string `json:"recipient"`
+	Message   string `json:"message"`
+}
+
+// ReplayDeadLetter sends a message which could not be published before
+func (notifier *nostrDMNotifier) ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error {
+	var dm nostrDMDeadLetter
+	if err := json.Unmarshal([]byte(deadLetter.Payload), &dm); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, nostrDMTimeout)
+	defer cancel()
+	return SendNostrDM(ctx, notifier.pool, notifier.cfg.GetRelayUrls(), notifier.keys.GetNostrSecretKey(), dm.Recipient, dm.Message)
+}
+
+// formatCriticalEvent returns the message for the events which need the owner's attention,
+// and an empty string for the others
+func formatCriticalEvent(event *events.Event) string {
+	properties, _ := event.Properties.(map[string]interface{})
+	switch event.Event {
+	case "nwc_channel_force_closed":
+		return fmt.Sprintf("A channel with %v was force-closed by the %s side (%v). %s", properties["counterparty_node_id"], initiatorSide(properties["initiator"]), properties["reason"], describePendingBalance(properties))
+	case "nwc_force_close_funds_spendable":
+		return fmt.Sprintf("The %v sats from the force-closed channel with %v can be spent on-chain", properties["amount_sat"], properties["counterparty_node_id"])
+	case "nwc_node_sync_failed":
+		if properties["sync_type"] != "full" {
+			return ""
+		}
+		return fmt.Sprintf("The node failed to sync: %v", properties["error"])
+	case "nwc_backup_failed":
+		return fmt.Sprintf("The backup to %v failed: %v", properties["name"], properties["error"])
+	case "nwc_database_corrupt":
+		return fmt.Sprintf("The database integrity check failed: %v", properties["error"])
+	case "nwc_duress_unlock":
+		return fmt.Sprintf("The hub was unlocked with the duress password from %v", properties["remote_ip"])
+	}
+	return ""
+}
+
+// SendNostrDM gift wraps the message for the recipient and publishes it to their preferred
+// DM relays, or to the relays of the hub if they have not published a kind 10050 list
+func SendNostrDM(ctx context.Context, pool nostrmodels.SimplePool, hubRelayUrls []string, secretKey string, recipient string, message string) error {
+	if secretKey == "" {
+		return errors.New("hub is locked")
+	}
+	senderPubkey, err := nostr.GetPublicKey(secretKey)
+	if err != nil {
+		return err
+	}
+
+	rumor := nostr.Event{
+		Kind:      nostr.KindDirectMessage,
+		PubKey:    senderPubkey,
+		Content:   message,
+		Tags:      nostr.Tags{{"p", recipient}},
+		CreatedAt: nostr.Now(),
+	}
+	rumor.ID = rumor.GetID()
+
+	conversationKey, err := nip44.GenerateConversationKey(recipient, secretKey)
+	if err != nil {
+		return err
+	}
+	giftWrap, err := nip59.GiftWrap(
+		rumor,
+		recipient,
+		func(plaintext string) (string, error) { return nip44.Encrypt(plaintext, conversationKey) },
+		func(seal *nostr.Event) error { return seal.Sign(secretKey) },
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	relayUrls := getDMRelays(ctx, pool, hubRelayUrls, recipient)
+	publishSuccessful := false
+	for result := range pool.PublishMany(ctx, relayUrls, giftWrap) {
+		if result.Error == nil {
+			publishSuccessful = true
+		} else {
+			logger.Logger.WithField("relay", result.RelayURL).WithError(result.Error).Error("failed to publish nostr notification to relay")
+		}
+	}
+	if !publishSuccessful {
+		return errors.New("failed to publish nostr notification to all relays")
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"event_id": giftWrap.ID,
+		"relays":   relayUrls,
+	}).Info("Sent nostr notification")
+	return nil
+}
+
+func getDMRelays(ctx context.Context, pool nostrmodels.SimplePool, hubRelayUrls []string, recipient string) []string {
+	relayList := pool.QuerySingle(ctx, hubRelayUrls, nostr.Filter{
+		Authors: []string{recipient},
+		Kinds:   []int{nostr.KindDMRelayList},
+	})
+	if relayList == nil {
+		return hubRelayUrls
+	}
+	relayUrls := []string{}
+	for _, tag := range relayList.Tags {
+		if len(tag) >= 2 && tag[0] == "relay" && nostr.IsValidRelayURL(tag[1]) {
+			relayUrls = append(relayUrls, tag[1])
+			if len(relayUrls) == maxDMRelays {
+				break
+			}
+		}
+	}
+	if len(relayUrls) == 0 {
+		return hubRelayUrls
+	}
+	return relayUrls
+}
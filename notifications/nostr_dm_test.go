@@ -0,0 +1,100 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip44"
+	"github.com/nbd-wtf/go-nostr/nip59"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dmRelayPool returns the DM relay list of the recipient and records where events are published
+type dmRelayPool struct {
+	dmRelayList     *nostr.Event
+	publishedEvents []nostr.Event
+	publishedTo     [][]string
+}
+
+func (pool *dmRelayPool) PublishMany(ctx context.Context, relayUrls []string, event nostr.Event) chan nostr.PublishResult {
+	pool.publishedEvents = append(pool.publishedEvents, event)
+	pool.publishedTo = append(pool.publishedTo, relayUrls)
+	channel := make(chan nostr.PublishResult, 1)
+	channel <- nostr.PublishResult{RelayURL: relayUrls[0]}
+	close(channel)
+	return channel
+}
+
+func (pool *dmRelayPool) QuerySingle(ctx context.Context, urls []string, filter nostr.Filter, opts ...nostr.SubscriptionOption) *nostr.RelayEvent {
+	if pool.dmRelayList == nil {
+		return nil
+	}
+	return &nostr.RelayEvent{Event: pool.dmRelayList}
+}
+
+func TestParseNostrPubkey(t *testing.T) {
+	pubkey, err := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	require.NoError(t, err)
+	npub, err := nip19.EncodePublicKey(pubkey)
+	require.NoError(t, err)
+
+	parsed, err := ParseNostrPubkey(npub)
+	require.NoError(t, err)
+	assert.Equal(t, pubkey, parsed)
+	parsed, err = ParseNostrPubkey(pubkey)
+	require.NoError(t, err)
+	assert.Equal(t, pubkey, parsed)
+
+	nsec, err := nip19.EncodePrivateKey(nostr.GeneratePrivateKey())
+	require.NoError(t, err)
+	_, err = ParseNostrPubkey(nsec)
+	assert.Error(t, err)
+	_, err = ParseNostrPubkey("not a key")
+	assert.Error(t, err)
+}
+
+func TestSendNostrDM(t *testing.T) {
+	hubSecretKey := nostr.GeneratePrivateKey()
+	hubPubkey, err := nostr.GetPublicKey(hubSecretKey)
+	require.NoError(t, err)
+	ownerSecretKey := nostr.GeneratePrivateKey()
+	ownerPubkey, err := nostr.GetPublicKey(ownerSecretKey)
+	require.NoError(t, err)
+
+	hubRelays := []string{"wss://relay.getalby.com/v1"}
+
+	// without a DM relay list the relays of the hub are used
+	pool := &dmRelayPool{}
+	require.NoError(t, SendNostrDM(context.TODO(), pool, hubRelays, hubSecretKey, ownerPubkey, "Backup failed"))
+	require.Len(t, pool.publishedEvents, 1)
+	assert.Equal(t, hubRelays, pool.publishedTo[0])
+
+	giftWrap := pool.publishedEvents[0]
+	assert.Equal(t, nostr.KindGiftWrap, giftWrap.Kind)
+	assert.NotEqual(t, hubPubkey, giftWrap.PubKey)
+	rumor, err := nip59.GiftUnwrap(giftWrap, func(otherPubkey, ciphertext string) (string, error) {
+		conversationKey, err := nip44.GenerateConversationKey(otherPubkey, ownerSecretKey)
+		if err != nil {
+			return "", err
+		}
+		return nip44.Decrypt(ciphertext, conversationKey)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, nostr.KindDirectMessage, rumor.Kind)
+	assert.Equal(t, hubPubkey, rumor.PubKey)
+	assert.Equal(t, "Backup failed", rumor.Content)
+
+	pool = &dmRelayPool{
+		dmRelayList: &nostr.Event{
+			Kind: nostr.KindDMRelayList,
+			Tags: nostr.Tags{{"relay", "wss://inbox.example.com"}, {"relay", "not a relay"}},
+		},
+	}
+	require.NoError(t, SendNostrDM(context.TODO(), pool, hubRelays, hubSecretKey, ownerPubkey, "Backup failed"))
+	assert.Equal(t, []string{"wss://inbox.example.com"}, pool.publishedTo[0])
+
+	assert.EqualError(t, SendNostrDM(context.TODO(), pool, hubRelays, "", ownerPubkey, "Backup failed"), "hub is locked")
+}
@@ -0,0 +1,313 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	PUSH_TRANSPORT_UNIFIEDPUSH = "unifiedpush"
+	PUSH_TRANSPORT_FCM         = "fcm"
+	PUSH_TRANSPORT_APNS        = "apns"
+)
+
+const (
+	PUSH_CATEGORY_PAYMENT  = "payment"
+	PUSH_CATEGORY_SECURITY = "security"
+)
+
+const pushTimeout = 10 * time.Second
+
+// ErrPushDeviceGone is returned by a transport when the device unregistered, so it will not
+// receive any more notifications
+var ErrPushDeviceGone = errors.New("push device is no longer registered")
+
+type PushMessage struct {
+	Event    string `json:"event"`
+	Category string `json:"category"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+}
+
+// PushTransport delivers a message to a device identified by its token
+type PushTransport interface {
+	Send(ctx context.Context, token string, message *PushMessage) error
+}
+
+// unifiedPushTransport posts the message to the endpoint of the UnifiedPush distributor,
+// which can be self-hosted (e.g. ntfy), so no third party is involved
+type unifiedPushTransport struct {
+	httpClient *http.Client
+}
+
+func (transport *unifiedPushTransport) Send(ctx context.Context, endpoint string, message *PushMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("TTL", "86400")
+	if message.Category == PUSH_CATEGORY_SECURITY {
+		req.Header.Set("Urgency", "high")
+	}
+	return doPushRequest(transport.httpClient, req)
+}
+
+// proxyPushTransport forwards the message to a push proxy which holds the FCM or APNs
+// credentials of the mobile app
+type proxyPushTransport struct {
+	httpClient *http.Client
+	proxyUrl   string
+	platform   string
+}
+
+type proxyPushRequest struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+	*PushMessage
+}
+
+func (transport *proxyPushTransport) Send(ctx context.Context, token string, message *PushMessage) error {
+	body, err := json.Marshal(&proxyPushRequest{
+		Platform:    transport.platform,
+		Token:       token,
+		PushMessage: message,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(transport.proxyUrl, "/")+"/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doPushRequest(transport.httpClient, req)
+}
+
+func doPushRequest(httpClient *http.Client, req *http.Request) error {
+	req.Header.Set("User-Agent", "AlbyHub")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		return ErrPushDeviceGone
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// GetPushTransport returns the transport for devices of the given kind. FCM and APNs are only
+// available if a push proxy is configured with PUSH_PROXY_URL.
+func GetPushTransport(appConfig *config.AppConfig, transport string) (PushTransport, error) {
+	httpClient := &http.Client{Timeout: pushTimeout}
+	switch transport {
+	case PUSH_TRANSPORT_UNIFIEDPUSH:
+		return &unifiedPushTransport{httpClient: httpClient}, nil
+	case PUSH_TRANSPORT_FCM, PUSH_TRANSPORT_APNS:
+		if appConfig.PushProxyUrl == "" {
+			return nil, fmt.Errorf("%s requires a push proxy, set PUSH_PROXY_URL", transport)
+		}
+		return &proxyPushTransport{httpClient: httpClient, proxyUrl: appConfig.PushProxyUrl, platform: transport}, nil
+	}
+	return nil, fmt.Errorf("unsupported transport %s. Must be one of %s,%s,%s", transport, PUSH_TRANSPORT_UNIFIEDPUSH, PUSH_TRANSPORT_FCM, PUSH_TRANSPORT_APNS)
+}
+
+// ValidatePushDevice checks the transport is available and the token can be used with it
+func ValidatePushDevice(appConfig *config.AppConfig, transport string, token string) error {
+	if _, err := GetPushTransport(appConfig, transport); err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("a token is required")
+	}
+	if transport == PUSH_TRANSPORT_UNIFIEDPUSH {
+		endpoint, err := url.Parse(token)
+		if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+			return errors.New("the UnifiedPush endpoint must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// SendPushNotification sends the message to one device
+func SendPushNotification(ctx context.Context, appConfig *config.AppConfig, device *db.PushDevice, message *PushMessage) error {
+	transport, err := GetPushTransport(appConfig, device.Transport)
+	if err != nil {
+		return err
+	}
+	return transport.Send(ctx, device.Token, message)
+}
+
+// pushNotifier sends payment and security alerts to all registered devices
+type pushNotifier struct {
+	db   *gorm.DB
+	send func(ctx context.Context, device *db.PushDevice, message *PushMessage) error
+}
+
+func NewPushNotifier(gormDB *gorm.DB, appConfig *config.AppConfig) *pushNotifier {
+	return &pushNotifier{
+		db: gormDB,
+		send: func(ctx context.Context, device *db.PushDevice, message *PushMessage) error {
+			return SendPushNotification(ctx, appConfig, device, message)
+		},
+	}
+}
+
+func (notifier *pushNotifier) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	message := formatPushMessage(event)
+	if message == nil {
+		return
+	}
+
+	var devices []db.PushDevice
+	if err := notifier.db.Find(&devices).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to list push devices")
+		return
+	}
+
+	for _, device := range devices {
+		if err := notifier.deliver(ctx, &device, message); err != nil && !errors.Is(err, ErrPushDeviceGone) {
+			deadletters.Add(notifier.db, deadletters.CONSUMER_PUSH, message.Event, &pushDeadLetter{
+				PushDeviceId: device.ID,
+				Message:      message,
+			}, err)
+		}
+	}
+}
+
+type pushDeadLetter struct {
+	PushDeviceId uint         `json:"push_device_id"`
+	Message      *PushMessage `json:"message"`
+}
+
+// ReplayDeadLetter sends a notification again to the device it was meant for
+func (notifier *pushNotifier) ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error {
+	var payload pushDeadLetter
+	if err := json.Unmarshal([]byte(deadLetter.Payload), &payload); err != nil {
+		return err
+	}
+	var device db.PushDevice
+	if notifier.db.Limit(1).Find(&device, payload.PushDeviceId).RowsAffected == 0 {
+		return errors.New("push device not found")
+	}
+	return notifier.deliver(ctx, &device, payload.Message)
+}
+
+// deliver sends the message and records the outcome on the device
+func (notifier *pushNotifier) deliver(ctx context.Context, device *db.PushDevice, message *PushMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+	err := notifier.send(ctx, device, message)
+
+	if errors.Is(err, ErrPushDeviceGone) {
+		// the app was uninstalled or the distributor dropped the registration
+		logger.Logger.WithField("push_device_id", device.ID).Info("Removing unregistered push device")
+		if err := notifier.db.Delete(&db.PushDevice{}, device.ID).Error; err != nil {
+			logger.Logger.WithError(err).WithField("push_device_id", device.ID).Error("Failed to remove push device")
+		}
+		return err
+	}
+
+	updates := map[string]interface{}{}
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"push_device_id": device.ID,
+			"event":          message.Event,
+		}).Error("Failed to send push notification")
+		updates["last_error"] = err.Error()
+	} else {
+		updates["last_notified_at"] = time.Now()
+		updates["last_error"] = ""
+	}
+	if err := notifier.db.Model(&db.PushDevice{}).Where("id", device.ID).Updates(updates).Error; err != nil {
+		logger.Logger.WithError(err).WithField("push_device_id", device.ID).Error("Failed to update push device")
+	}
+	return err
+}
+
+// formatPushMessage returns the alert for payment, balance and security events, and nil for the others
+func formatPushMessage(event *events.Event) *PushMessage {
+	switch event.Event {
+	case "nwc_payment_received", "nwc_payment_sent", "nwc_payment_failed":
+		transaction, ok := event.Properties.(*db.Transaction)
+		if !ok {
+			return nil
+		}
+		title := map[string]string{
+			"nwc_payment_received": "Payment received",
+			"nwc_payment_sent":     "Payment sent",
+			"nwc_payment_failed":   "Payment failed",
+		}[event.Event]
+		body := fmt.Sprintf("%d sats", transaction.AmountMsat/1000)
+		if transaction.Description != "" {
+			body += fmt.Sprintf(" - %s", transaction.Description)
+		}
+		return &PushMessage{Event: event.Event, Category: PUSH_CATEGORY_PAYMENT, Title: title, Body: body}
+	}
+
+	properties, _ := event.Properties.(map[string]interface{})
+	switch event.Event {
+	case "nwc_low_balance":
+		body := fmt.Sprintf("%v sats can be spent, below your alert threshold of %v sats", properties["spendable_sat"], properties["threshold_sat"])
+		return &PushMessage{Event: event.Event, Category: PUSH_CATEGORY_PAYMENT, Title: "Low balance", Body: body}
+	case "nwc_low_inbound_liquidity":
+		body := fmt.Sprintf("Only %v sats can be received, below your alert threshold of %v sats", properties["receivable_sat"], properties["threshold_sat"])
+		return &PushMessage{Event: event.Event, Category: PUSH_CATEGORY_PAYMENT, Title: "Low inbound liquidity", Body: body}
+	case "nwc_channel_force_closed":
+		body := fmt.Sprintf("The channel with %v was force-closed. %s", properties["counterparty_node_id"], describePendingBalance(properties))
+		return &PushMessage{Event: event.Event, Category: PUSH_CATEGORY_PAYMENT, Title: "Channel force-closed", Body: body}
+	case "nwc_force_close_funds_spendable":
+		body := fmt.Sprintf("%v sats from the force-closed channel can be spent on-chain", properties["amount_sat"])
+		return &PushMessage{Event: event.Event, Category: PUSH_CATEGORY_PAYMENT, Title: "Funds spendable", Body: body}
+	case "nwc_onchain_fee_spike":
+		body := fmt.Sprintf("On-chain fees of %v sat/vB are above your ceiling of %v sat/vB", properties["fee_rate"], properties["ceiling"])
+		return &PushMessage{Event: event.Event, Category: PUSH_CATEGORY_PAYMENT, Title: "On-chain fee spike", Body: body}
+	case "nwc_onchain_fee_normal":
+		body := fmt.Sprintf("On-chain fees fell to %v sat/vB, below your ceiling of %v sat/vB", properties["fee_rate"], properties["ceiling"])
+		return &PushMessage{Event: event.Event, Category: PUSH_CATEGORY_PAYMENT, Title: "On-chain fees normal", Body: body}
+	}
+
+	var title, body string
+	switch event.Event {
+	case "nwc_unlocked":
+		title, body = "Hub unlocked", "Your hub was unlocked. If this was not you, change your unlock password."
+	case "nwc_duress_unlock":
+		title, body = "Duress unlock", fmt.Sprintf("Your hub was unlocked with the duress password from %v", properties["remote_ip"])
+	case "nwc_app_created":
+		title, body = "New app connection", fmt.Sprintf("%v was connected to your hub", properties["name"])
+	case "nwc_payment_approval_requested":
+		title, body = "Payment approval requested", fmt.Sprintf("%v requests a payment of %v sats", properties["app_name"], properties["amount"])
+	case "nwc_spending_locked":
+		title, body = "Spending locked", "Payments are blocked until the hub is unlocked."
+	case "nwc_database_corrupt":
+		title, body = "Database check failed", fmt.Sprintf("The database integrity check failed: %v", properties["error"])
+	default:
+		return nil
+	}
+	return &PushMessage{Event: event.Event, Category: PUSH_CATEGORY_SECURITY, Title: title, Body: body}
+}
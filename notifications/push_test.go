@@ -0,0 +1,103 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestValidatePushDevice(t *testing.T) {
+	appConfig := &config.AppConfig{}
+	assert.NoError(t, ValidatePushDevice(appConfig, PUSH_TRANSPORT_UNIFIEDPUSH, "https://ntfy.example.com/upAbc123"))
+	assert.Error(t, ValidatePushDevice(appConfig, PUSH_TRANSPORT_UNIFIEDPUSH, "ntfy.example.com/upAbc123"))
+	assert.Error(t, ValidatePushDevice(appConfig, PUSH_TRANSPORT_UNIFIEDPUSH, ""))
+	assert.EqualError(t, ValidatePushDevice(appConfig, PUSH_TRANSPORT_FCM, "token"), "fcm requires a push proxy, set PUSH_PROXY_URL")
+	assert.Error(t, ValidatePushDevice(appConfig, "sms", "token"))
+
+	appConfig.PushProxyUrl = "https://push.example.com"
+	assert.NoError(t, ValidatePushDevice(appConfig, PUSH_TRANSPORT_APNS, "token"))
+}
+
+func TestSendPushNotification(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	appConfig := &config.AppConfig{PushProxyUrl: server.URL + "/"}
+	message := &PushMessage{Event: "nwc_unlocked", Category: PUSH_CATEGORY_SECURITY, Title: "Hub unlocked", Body: "body"}
+
+	err := SendPushNotification(context.TODO(), appConfig, &db.PushDevice{Transport: PUSH_TRANSPORT_UNIFIEDPUSH, Token: server.URL + "/up"}, message)
+	require.NoError(t, err)
+	assert.Equal(t, "Hub unlocked", received["title"])
+
+	err = SendPushNotification(context.TODO(), appConfig, &db.PushDevice{Transport: PUSH_TRANSPORT_FCM, Token: "fcm-token"}, message)
+	require.NoError(t, err)
+	assert.Equal(t, "fcm", received["platform"])
+	assert.Equal(t, "fcm-token", received["token"])
+	assert.Equal(t, "security", received["category"])
+
+	err = SendPushNotification(context.TODO(), appConfig, &db.PushDevice{Transport: PUSH_TRANSPORT_UNIFIEDPUSH, Token: server.URL + "/gone"}, message)
+	assert.ErrorIs(t, err, ErrPushDeviceGone)
+}
+
+func TestPushNotifier(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	phone := db.PushDevice{Name: "phone", Transport: PUSH_TRANSPORT_FCM, Token: "phone"}
+	tablet := db.PushDevice{Name: "tablet", Transport: PUSH_TRANSPORT_UNIFIEDPUSH, Token: "https://ntfy.example.com/tablet"}
+	require.NoError(t, svc.DB.Create(&phone).Error)
+	require.NoError(t, svc.DB.Create(&tablet).Error)
+
+	messages := map[string][]*PushMessage{}
+	notifier := NewPushNotifier(svc.DB, svc.Cfg.GetEnv())
+	notifier.send = func(ctx context.Context, device *db.PushDevice, message *PushMessage) error {
+		messages[device.Name] = append(messages[device.Name], message)
+		if device.Name == "tablet" {
+			return ErrPushDeviceGone
+		}
+		return nil
+	}
+
+	notifier.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_payment_received",
+		Properties: &db.Transaction{AmountMsat: 21_000, Description: "coffee"},
+	}, map[string]interface{}{})
+	notifier.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_duress_unlock",
+		Properties: map[string]interface{}{"remote_ip": "1.2.3.4"},
+	}, map[string]interface{}{})
+	// not an alert
+	notifier.ConsumeEvent(context.TODO(), &events.Event{Event: "nwc_node_started"}, map[string]interface{}{})
+
+	require.Len(t, messages["phone"], 2)
+	assert.Equal(t, &PushMessage{Event: "nwc_payment_received", Category: PUSH_CATEGORY_PAYMENT, Title: "Payment received", Body: "21 sats - coffee"}, messages["phone"][0])
+	assert.Equal(t, PUSH_CATEGORY_SECURITY, messages["phone"][1].Category)
+	assert.Contains(t, messages["phone"][1].Body, "1.2.3.4")
+	// the unregistered tablet is removed after the first notification
+	assert.Len(t, messages["tablet"], 1)
+
+	var devices []db.PushDevice
+	require.NoError(t, svc.DB.Find(&devices).Error)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "phone", devices[0].Name)
+	assert.NotNil(t, devices[0].LastNotifiedAt)
+}
@@ -0,0 +1,269 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// External plugins are separate executables started by the hub. They serve
+// JSON-RPC 1.0 (as implemented by net/rpc/jsonrpc) on their stdin and stdout
+// with the methods Plugin.Manifest, Plugin.HandleNIP47, Plugin.HandleHTTP
+// and Plugin.ConsumeEvent. Anything written to stderr is logged.
+
+const externalCallTimeout = 30 * time.Second
+
+type Manifest struct {
+	Name    string           `json:"name"`
+	Methods []MethodManifest `json:"methods"`
+	// names of the events to receive, or "*" for all of them
+	Events []string `json:"events"`
+	// whether requests to /plugins/<name>/* and /api/plugins/<name>/* are forwarded
+	Routes bool `json:"routes"`
+}
+
+type MethodManifest struct {
+	Method string `json:"method"`
+	Scope  string `json:"scope"`
+}
+
+type ManifestRequest struct {
+	HubVersion string `json:"hubVersion"`
+}
+
+type NIP47Response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+type HTTPRequest struct {
+	// whether the request was made to the authenticated admin routes
+	Admin  bool                `json:"admin"`
+	Method string              `json:"method"`
+	Path   string              `json:"path"`
+	Query  string              `json:"query"`
+	Header map[string][]string `json:"header"`
+	Body   []byte              `json:"body"`
+}
+
+type HTTPResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header"`
+	Body   []byte              `json:"body"`
+}
+
+type EventRequest struct {
+	Event            *events.Event          `json:"event"`
+	GlobalProperties map[string]interface{} `json:"globalProperties"`
+}
+
+type externalPlugin struct {
+	manifest Manifest
+	client   *rpc.Client
+}
+
+// StartExternalPlugins starts and registers the plugin executables in a comma-separated list
+func StartExternalPlugins(ctx context.Context, paths string, hubVersion string) {
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		plugin, err := startExternalPlugin(ctx, path, hubVersion)
+		if err != nil {
+			logger.Logger.WithField("path", path).WithError(err).Error("Failed to start plugin")
+			continue
+		}
+		if err := register(plugin); err != nil {
+			logger.Logger.WithField("path", path).WithError(err).Error("Failed to register plugin")
+			plugin.client.Close()
+			continue
+		}
+		logger.Logger.WithFields(logrus.Fields{
+			"path":    path,
+			"name":    plugin.manifest.Name,
+			"methods": len(plugin.manifest.Methods),
+		}).Info("Started plugin")
+	}
+}
+
+type stdioConn struct {
+	io.ReadCloser
+	stdin io.WriteCloser
+}
+
+func (conn *stdioConn) Write(p []byte) (int, error) {
+	return conn.stdin.Write(p)
+}
+
+func (conn *stdioConn) Close() error {
+	return errors.Join(conn.stdin.Close(), conn.ReadCloser.Close())
+}
+
+func startExternalPlugin(ctx context.Context, path string, hubVersion string) (*externalPlugin, error) {
+	cmd := exec.CommandContext(ctx, path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = logger.Logger.WithField("plugin", path).Writer()
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		err := cmd.Wait()
+		logger.Logger.WithField("path", path).WithError(err).Warn("Plugin exited")
+	}()
+
+	plugin, err := newExternalPlugin(ctx, &stdioConn{ReadCloser: stdout, stdin: stdin}, hubVersion)
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, err
+	}
+	return plugin, nil
+}
+
+func newExternalPlugin(ctx context.Context, conn io.ReadWriteCloser, hubVersion string) (*externalPlugin, error) {
+	plugin := &externalPlugin{
+		client: jsonrpc.NewClient(conn),
+	}
+	err := plugin.call(ctx, "Plugin.Manifest", &ManifestRequest{HubVersion: hubVersion}, &plugin.manifest)
+	if err != nil {
+		plugin.client.Close()
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	return plugin, nil
+}
+
+// call invokes a method of the plugin, giving up after externalCallTimeout
+func (plugin *externalPlugin) call(ctx context.Context, method string, args interface{}, reply interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, externalCallTimeout)
+	defer cancel()
+
+	call := plugin.client.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (plugin *externalPlugin) Name() string {
+	return plugin.manifest.Name
+}
+
+func (plugin *externalPlugin) NIP47Methods() []NIP47Method {
+	nip47Methods := make([]NIP47Method, 0, len(plugin.manifest.Methods))
+	for _, method := range plugin.manifest.Methods {
+		nip47Methods = append(nip47Methods, NIP47Method{
+			Method: method.Method,
+			Scope:  method.Scope,
+			Handle: plugin.handleNIP47,
+		})
+	}
+	return nip47Methods
+}
+
+func (plugin *externalPlugin) handleNIP47(ctx context.Context, request *NIP47Request) (interface{}, error) {
+	var response NIP47Response
+	err := plugin.call(ctx, "Plugin.HandleNIP47", request, &response)
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	if response.Result == nil {
+		return nil, &Error{Code: constants.ERROR_INTERNAL, Message: "plugin returned no result"}
+	}
+	return response.Result, nil
+}
+
+func (plugin *externalPlugin) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	if !slices.Contains(plugin.manifest.Events, "*") && !slices.Contains(plugin.manifest.Events, event.Event) {
+		return
+	}
+	err := plugin.call(ctx, "Plugin.ConsumeEvent", &EventRequest{
+		Event:            event,
+		GlobalProperties: globalProperties,
+	}, &struct{}{})
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"plugin": plugin.manifest.Name,
+			"event":  event.Event,
+		}).WithError(err).Error("Plugin failed to consume event")
+	}
+}
+
+func (plugin *externalPlugin) RegisterRoutes(public *echo.Group, admin *echo.Group) {
+	if !plugin.manifest.Routes {
+		return
+	}
+	public.Any("/*", plugin.proxyHandler(false))
+	admin.Any("/*", plugin.proxyHandler(true))
+}
+
+// proxyHandler forwards HTTP requests to the plugin
+func (plugin *externalPlugin) proxyHandler(admin bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, 1<<20))
+		if err != nil {
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"message": err.Error()})
+		}
+
+		request := &HTTPRequest{
+			Admin:  admin,
+			Method: c.Request().Method,
+			Path:   "/" + c.Param("*"),
+			Query:  c.QueryString(),
+			Header: c.Request().Header,
+			Body:   body,
+		}
+		// the session token of the hub is not meant for plugins
+		if admin {
+			request.Header = c.Request().Header.Clone()
+			delete(request.Header, "Authorization")
+		}
+
+		var response HTTPResponse
+		err = plugin.call(c.Request().Context(), "Plugin.HandleHTTP", request, &response)
+		if err != nil {
+			logger.Logger.WithField("plugin", plugin.manifest.Name).WithError(err).Error("Plugin failed to handle HTTP request")
+			return c.JSON(http.StatusBadGateway, map[string]string{"message": "Plugin failed to handle the request"})
+		}
+
+		for key, values := range response.Header {
+			for _, value := range values {
+				c.Response().Header().Add(key, value)
+			}
+		}
+		if response.Status == 0 {
+			response.Status = http.StatusOK
+		}
+		c.Response().WriteHeader(response.Status)
+		_, err = c.Response().Write(response.Body)
+		return err
+	}
+}
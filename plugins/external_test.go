@@ -0,0 +1,166 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/events"
+)
+
+// fakeExternalPlugin is served with net/rpc the same way a plugin written in Go would be
+type fakeExternalPlugin struct {
+	events chan *EventRequest
+}
+
+func (plugin *fakeExternalPlugin) Manifest(request *ManifestRequest, manifest *Manifest) error {
+	*manifest = Manifest{
+		Name:    "store",
+		Methods: []MethodManifest{{Method: "create_order", Scope: constants.MAKE_INVOICE_SCOPE}},
+		Events:  []string{"nwc_payment_received"},
+		Routes:  true,
+	}
+	return nil
+}
+
+func (plugin *fakeExternalPlugin) HandleNIP47(request *NIP47Request, response *NIP47Response) error {
+	var params struct {
+		Item string `json:"item"`
+	}
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		return err
+	}
+	if params.Item == "" {
+		response.Error = &Error{Code: constants.ERROR_BAD_REQUEST, Message: "item is required"}
+		return nil
+	}
+	response.Result = json.RawMessage(`{"order":"` + params.Item + `","app":"` + request.AppName + `"}`)
+	return nil
+}
+
+func (plugin *fakeExternalPlugin) HandleHTTP(request *HTTPRequest, response *HTTPResponse) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"admin":         request.Admin,
+		"path":          request.Path,
+		"query":         request.Query,
+		"body":          string(request.Body),
+		"authorization": request.Header["Authorization"],
+	})
+	if err != nil {
+		return err
+	}
+	*response = HTTPResponse{
+		Status: http.StatusCreated,
+		Header: map[string][]string{"Content-Type": {"application/json"}},
+		Body:   body,
+	}
+	return nil
+}
+
+func (plugin *fakeExternalPlugin) ConsumeEvent(request *EventRequest, response *struct{}) error {
+	plugin.events <- request
+	return nil
+}
+
+func startFakeExternalPlugin(t *testing.T) (*externalPlugin, *fakeExternalPlugin) {
+	fake := &fakeExternalPlugin{events: make(chan *EventRequest, 10)}
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("Plugin", fake))
+
+	hubConn, pluginConn := net.Pipe()
+	go server.ServeCodec(jsonrpc.NewServerCodec(pluginConn))
+
+	plugin, err := newExternalPlugin(context.Background(), hubConn, "v1.0.0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		plugin.client.Close()
+	})
+	return plugin, fake
+}
+
+func TestExternalPlugin_Manifest(t *testing.T) {
+	plugin, _ := startFakeExternalPlugin(t)
+
+	assert.Equal(t, "store", plugin.Name())
+	methods := plugin.NIP47Methods()
+	require.Len(t, methods, 1)
+	assert.Equal(t, "create_order", methods[0].Method)
+	assert.Equal(t, constants.MAKE_INVOICE_SCOPE, methods[0].Scope)
+}
+
+func TestExternalPlugin_HandleNIP47(t *testing.T) {
+	plugin, _ := startFakeExternalPlugin(t)
+	handle := plugin.NIP47Methods()[0].Handle
+
+	result, err := handle(context.Background(), &NIP47Request{
+		AppName: "shop",
+		Method:  "create_order",
+		Params:  json.RawMessage(`{"item":"coffee"}`),
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"order":"coffee","app":"shop"}`, string(result.(json.RawMessage)))
+
+	_, err = handle(context.Background(), &NIP47Request{
+		Method: "create_order",
+		Params: json.RawMessage(`{}`),
+	})
+	var pluginError *Error
+	require.ErrorAs(t, err, &pluginError)
+	assert.Equal(t, constants.ERROR_BAD_REQUEST, pluginError.Code)
+	assert.Equal(t, "item is required", pluginError.Message)
+}
+
+func TestExternalPlugin_ConsumeEvent(t *testing.T) {
+	plugin, fake := startFakeExternalPlugin(t)
+
+	plugin.ConsumeEvent(context.Background(), &events.Event{Event: "nwc_payment_sent"}, nil)
+	plugin.ConsumeEvent(context.Background(), &events.Event{
+		Event:      "nwc_payment_received",
+		Properties: map[string]interface{}{"amount": 1000},
+	}, map[string]interface{}{"node_type": "LDK"})
+
+	select {
+	case request := <-fake.events:
+		assert.Equal(t, "nwc_payment_received", request.Event.Event)
+		assert.Equal(t, "LDK", request.GlobalProperties["node_type"])
+	case <-time.After(time.Second):
+		t.Fatal("event was not forwarded")
+	}
+	assert.Empty(t, fake.events)
+}
+
+func TestExternalPlugin_Routes(t *testing.T) {
+	plugin, _ := startFakeExternalPlugin(t)
+
+	e := echo.New()
+	plugin.RegisterRoutes(e.Group("/plugins/store"), e.Group("/api/plugins/store"))
+
+	req := httptest.NewRequest(http.MethodPost, "/plugins/store/webhook?order=1", strings.NewReader("paid"))
+	req.Header.Set("Authorization", "Bearer shop-secret")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"admin":false,"path":"/webhook","query":"order=1","body":"paid","authorization":["Bearer shop-secret"]}`, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/api/plugins/store/orders", nil)
+	req.Header.Set("Authorization", "Bearer hub-session")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"admin":true,"path":"/orders","query":"","body":"","authorization":null}`, rec.Body.String())
+}
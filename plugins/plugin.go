@@ -0,0 +1,171 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/nip47/models"
+)
+
+// Plugin extends the hub without forking it. Besides a name, a plugin implements
+// any of NIP47MethodProvider, RouteProvider, Initializer and events.EventSubscriber.
+type Plugin interface {
+	Name() string
+}
+
+// NIP47MethodProvider adds custom NIP-47 methods
+type NIP47MethodProvider interface {
+	NIP47Methods() []NIP47Method
+}
+
+// RouteProvider adds HTTP routes. Routes on the public group are served under
+// /plugins/<name> without authentication, routes on the admin group under
+// /api/plugins/<name> and require a full access session.
+type RouteProvider interface {
+	RegisterRoutes(public *echo.Group, admin *echo.Group)
+}
+
+// Initializer is called once on startup with access to the hub
+type Initializer interface {
+	Init(ctx context.Context, host *Host) error
+}
+
+// Host gives compiled-in plugins access to the hub
+type Host struct {
+	DB             *gorm.DB
+	Config         config.Config
+	EventPublisher events.EventPublisher
+	// returns nil until the node is started
+	LNClient func() lnclient.LNClient
+}
+
+type NIP47Method struct {
+	Method string
+	// the scope an app needs to call the method, e.g. make_invoice
+	Scope  string
+	Handle NIP47Handler
+}
+
+type NIP47Handler func(ctx context.Context, request *NIP47Request) (interface{}, error)
+
+type NIP47Request struct {
+	AppId     uint            `json:"appId"`
+	AppName   string          `json:"appName"`
+	AppPubkey string          `json:"appPubkey"`
+	Isolated  bool            `json:"isolated"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params"`
+}
+
+// Error is returned by NIP-47 handlers to respond with a specific error code
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+var builtinMethods = []string{
+	models.PAY_INVOICE_METHOD,
+	models.GET_BALANCE_METHOD,
+	models.GET_BUDGET_METHOD,
+	models.GET_INFO_METHOD,
+	models.MAKE_INVOICE_METHOD,
+	models.LOOKUP_INVOICE_METHOD,
+	models.LIST_TRANSACTIONS_METHOD,
+	models.PAY_KEYSEND_METHOD,
+	models.MULTI_PAY_INVOICE_METHOD,
+	models.MULTI_PAY_KEYSEND_METHOD,
+	models.SIGN_MESSAGE_METHOD,
+	models.CREATE_CONNECTION_METHOD,
+	models.MAKE_HOLD_INVOICE_METHOD,
+	models.CANCEL_HOLD_INVOICE_METHOD,
+	models.SETTLE_HOLD_INVOICE_METHOD,
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      []Plugin
+	methods       = map[string]*NIP47Method{}
+)
+
+// Register adds a compiled-in plugin. It is meant to be called from the init function
+// of the plugin package and panics if the plugin conflicts with another one.
+func Register(plugin Plugin) {
+	if err := register(plugin); err != nil {
+		panic(err)
+	}
+}
+
+func register(plugin Plugin) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	name := plugin.Name()
+	if name == "" {
+		return fmt.Errorf("plugin name must not be empty")
+	}
+	for _, existing := range registry {
+		if existing.Name() == name {
+			return fmt.Errorf("plugin %s is already registered", name)
+		}
+	}
+
+	var pluginMethods []NIP47Method
+	if methodProvider, ok := plugin.(NIP47MethodProvider); ok {
+		pluginMethods = methodProvider.NIP47Methods()
+	}
+	for _, method := range pluginMethods {
+		if slices.Contains(builtinMethods, method.Method) || methods[method.Method] != nil {
+			return fmt.Errorf("plugin %s: method %s is already handled", name, method.Method)
+		}
+		if method.Scope == "" || method.Handle == nil {
+			return fmt.Errorf("plugin %s: method %s needs a scope and a handler", name, method.Method)
+		}
+	}
+
+	for i := range pluginMethods {
+		methods[pluginMethods[i].Method] = &pluginMethods[i]
+	}
+	registry = append(registry, plugin)
+	return nil
+}
+
+// Plugins returns all registered plugins
+func Plugins() []Plugin {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return slices.Clone(registry)
+}
+
+// FindNIP47Method returns the plugin method with the given name, or nil
+func FindNIP47Method(method string) *NIP47Method {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return methods[method]
+}
+
+// NIP47MethodsForScope returns the names of the plugin methods granted by a scope
+func NIP47MethodsForScope(scope string) []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	result := []string{}
+	for name, method := range methods {
+		if method.Scope == scope {
+			result = append(result, name)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
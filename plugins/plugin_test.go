@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+)
+
+type testPlugin struct {
+	name    string
+	methods []NIP47Method
+}
+
+func (plugin *testPlugin) Name() string {
+	return plugin.name
+}
+
+func (plugin *testPlugin) NIP47Methods() []NIP47Method {
+	return plugin.methods
+}
+
+func echoHandler(ctx context.Context, request *NIP47Request) (interface{}, error) {
+	return request.Params, nil
+}
+
+func TestRegister(t *testing.T) {
+	err := register(&testPlugin{
+		name: "test-register",
+		methods: []NIP47Method{
+			{Method: "test_register_quote", Scope: constants.MAKE_INVOICE_SCOPE, Handle: echoHandler},
+			{Method: "test_register_order", Scope: constants.PAY_INVOICE_SCOPE, Handle: echoHandler},
+		},
+	})
+	require.NoError(t, err)
+
+	method := FindNIP47Method("test_register_quote")
+	require.NotNil(t, method)
+	assert.Equal(t, constants.MAKE_INVOICE_SCOPE, method.Scope)
+	assert.Nil(t, FindNIP47Method("test_register_unknown"))
+	assert.Contains(t, NIP47MethodsForScope(constants.PAY_INVOICE_SCOPE), "test_register_order")
+	assert.NotContains(t, NIP47MethodsForScope(constants.PAY_INVOICE_SCOPE), "test_register_quote")
+
+	names := []string{}
+	for _, plugin := range Plugins() {
+		names = append(names, plugin.Name())
+	}
+	assert.Contains(t, names, "test-register")
+}
+
+func TestRegister_Conflicts(t *testing.T) {
+	err := register(&testPlugin{name: "test-conflicts"})
+	require.NoError(t, err)
+
+	err = register(&testPlugin{name: "test-conflicts"})
+	assert.EqualError(t, err, "plugin test-conflicts is already registered")
+
+	err = register(&testPlugin{name: ""})
+	assert.Error(t, err)
+
+	err = register(&testPlugin{
+		name:    "test-conflicts-builtin",
+		methods: []NIP47Method{{Method: "pay_invoice", Scope: constants.PAY_INVOICE_SCOPE, Handle: echoHandler}},
+	})
+	assert.EqualError(t, err, "plugin test-conflicts-builtin: method pay_invoice is already handled")
+
+	err = register(&testPlugin{
+		name:    "test-conflicts-scope",
+		methods: []NIP47Method{{Method: "test_conflicts_no_scope", Handle: echoHandler}},
+	})
+	assert.Error(t, err)
+	assert.Nil(t, FindNIP47Method("test_conflicts_no_scope"))
+
+	assert.Panics(t, func() {
+		Register(&testPlugin{name: "test-conflicts"})
+	})
+}
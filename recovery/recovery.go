@@ -0,0 +1,134 @@
+package recovery
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/getAlby/hub/events"
+)
+
+// The recovery is kept in a file of the work dir instead of the database,
+// as restoring a migration archive replaces the database.
+const recoveryFileName = "recovery.json"
+
+const (
+	SOURCE_CHANNEL_BACKUP    = "channel_backup"
+	SOURCE_MIGRATION_ARCHIVE = "migration_archive"
+)
+
+// A recovery from a channel backup moves through these states in order,
+// a recovery from a migration archive goes from restoring_archive to completed.
+const (
+	STATE_RESTORING_ARCHIVE    = "restoring_archive"
+	STATE_WAITING_FOR_NODE     = "waiting_for_node"
+	STATE_RESTORING_CHANNELS   = "restoring_channels"
+	STATE_WAITING_FOR_CLOSURES = "waiting_for_closures"
+	STATE_COMPLETED            = "completed"
+)
+
+const (
+	CHANNEL_STATE_PENDING   = "pending"
+	CHANNEL_STATE_REQUESTED = "close_requested"
+	CHANNEL_STATE_CLOSED    = "closed"
+	CHANNEL_STATE_FAILED    = "failed"
+)
+
+type Recovery struct {
+	Source    string            `json:"source"`
+	State     string            `json:"state"`
+	NodeID    string            `json:"nodeId,omitempty"`
+	Channels  []RecoveryChannel `json:"channels"`
+	LastError string            `json:"lastError,omitempty"`
+	// funds which are back in the on-chain wallet, or still locked in closing channels
+	OnchainBalanceSat        int64      `json:"onchainBalanceSat"`
+	PendingClosureBalanceSat uint64     `json:"pendingClosureBalanceSat"`
+	CreatedAt                time.Time  `json:"createdAt"`
+	UpdatedAt                time.Time  `json:"updatedAt"`
+	CompletedAt              *time.Time `json:"completedAt,omitempty"`
+
+	// the multi-channel backup of LND, not returned by the API
+	LNDMultiChanBackup []byte `json:"lndMultiChanBackup,omitempty"`
+}
+
+type RecoveryChannel struct {
+	ChannelID         string `json:"channelId,omitempty"`
+	PeerID            string `json:"peerId,omitempty"`
+	PeerSocketAddress string `json:"peerSocketAddress,omitempty"`
+	ChannelSizeSat    uint64 `json:"channelSizeSat"`
+	FundingTxID       string `json:"fundingTxId"`
+	FundingTxVout     uint32 `json:"fundingTxVout"`
+	State             string `json:"state"`
+	Attempts          int    `json:"attempts"`
+	Error             string `json:"error,omitempty"`
+}
+
+func newChannelRecovery(channelBackup *events.StaticChannelsBackupEvent) (*Recovery, error) {
+	if channelBackup == nil || (len(channelBackup.Channels) == 0 && len(channelBackup.LNDMultiChanBackup) == 0) {
+		return nil, errors.New("channel backup contains no channels")
+	}
+	now := time.Now().UTC()
+	recovery := &Recovery{
+		Source:             SOURCE_CHANNEL_BACKUP,
+		State:              STATE_WAITING_FOR_NODE,
+		NodeID:             channelBackup.NodeID,
+		Channels:           []RecoveryChannel{},
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		LNDMultiChanBackup: channelBackup.LNDMultiChanBackup,
+	}
+	for _, channel := range channelBackup.Channels {
+		if channel.FundingTxID == "" {
+			return nil, errors.New("channel backup contains a channel without funding transaction")
+		}
+		recovery.Channels = append(recovery.Channels, RecoveryChannel{
+			ChannelID:         channel.ChannelID,
+			PeerID:            channel.PeerID,
+			PeerSocketAddress: channel.PeerSocketAddress,
+			ChannelSizeSat:    channel.ChannelSize,
+			FundingTxID:       channel.FundingTxID,
+			FundingTxVout:     channel.FundingTxVout,
+			State:             CHANNEL_STATE_PENDING,
+		})
+	}
+	return recovery, nil
+}
+
+func loadRecovery(workDir string) (*Recovery, error) {
+	content, err := os.ReadFile(filepath.Join(workDir, recoveryFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recovery Recovery
+	if err := json.Unmarshal(content, &recovery); err != nil {
+		return nil, err
+	}
+	return &recovery, nil
+}
+
+func saveRecovery(workDir string, recovery *Recovery) error {
+	recovery.UpdatedAt = time.Now().UTC()
+	content, err := json.Marshal(recovery)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so that a crash cannot leave a truncated state behind
+	tmpPath := filepath.Join(workDir, recoveryFileName+".tmp")
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(workDir, recoveryFileName))
+}
+
+func removeRecovery(workDir string) error {
+	err := os.Remove(filepath.Join(workDir, recoveryFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,373 @@
+package recovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	recoveryInterval = time.Minute
+	// peers are contacted again on every run until they could be reached this many times
+	maxConnectAttempts = 10
+)
+
+// fetchOutspend is a variable so it can be replaced in tests
+var fetchOutspend = fetchEsploraOutspend
+
+// channelBackupRestorer is implemented by backends which can restore a multi-channel backup themselves
+type channelBackupRestorer interface {
+	RestoreChannelBackups(ctx context.Context, multiChanBackup []byte) error
+}
+
+// RecoveryService guides the recovery of a hub from its seed and a static channel backup,
+// or from a migration archive. The recovery survives restarts and continues once the node runs.
+type RecoveryService interface {
+	GetRecovery() (*Recovery, error)
+	StartChannelRecovery(channelBackup *events.StaticChannelsBackupEvent) (*Recovery, error)
+	StartArchiveRecovery(restoreDir string) (*Recovery, error)
+	DeleteRecovery() error
+	Start(ctx context.Context, lnClient lnclient.LNClient)
+}
+
+type recoveryService struct {
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+}
+
+// only one step of the recovery at a time, also across service instances
+var recoveryMutex sync.Mutex
+
+func NewRecoveryService(cfg config.Config, eventPublisher events.EventPublisher) *recoveryService {
+	return &recoveryService{
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (svc *recoveryService) GetRecovery() (*Recovery, error) {
+	recoveryMutex.Lock()
+	defer recoveryMutex.Unlock()
+	return loadRecovery(svc.cfg.GetEnv().Workdir)
+}
+
+// StartChannelRecovery records the channels to recover, the channels are closed once the node was started
+func (svc *recoveryService) StartChannelRecovery(channelBackup *events.StaticChannelsBackupEvent) (*Recovery, error) {
+	recovery, err := newChannelRecovery(channelBackup)
+	if err != nil {
+		return nil, err
+	}
+	return svc.create(recovery)
+}
+
+// StartArchiveRecovery records that a migration archive is being restored, it is
+// completed as soon as the node of the archive was started. The state is written to the
+// directory the archive was extracted to, which replaces the work dir when the hub restarts.
+func (svc *recoveryService) StartArchiveRecovery(restoreDir string) (*Recovery, error) {
+	recoveryMutex.Lock()
+	defer recoveryMutex.Unlock()
+
+	now := time.Now().UTC()
+	recovery := &Recovery{
+		Source:    SOURCE_MIGRATION_ARCHIVE,
+		State:     STATE_RESTORING_ARCHIVE,
+		Channels:  []RecoveryChannel{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := saveRecovery(restoreDir, recovery); err != nil {
+		return nil, err
+	}
+	return recovery, nil
+}
+
+func (svc *recoveryService) create(recovery *Recovery) (*Recovery, error) {
+	recoveryMutex.Lock()
+	defer recoveryMutex.Unlock()
+
+	existingRecovery, err := loadRecovery(svc.cfg.GetEnv().Workdir)
+	if err != nil {
+		return nil, err
+	}
+	if existingRecovery != nil && existingRecovery.State != STATE_COMPLETED {
+		return nil, errors.New("a recovery is already in progress")
+	}
+	if err := saveRecovery(svc.cfg.GetEnv().Workdir, recovery); err != nil {
+		return nil, err
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"source":   recovery.Source,
+		"channels": len(recovery.Channels),
+	}).Info("Started recovery")
+	return recovery, nil
+}
+
+// DeleteRecovery stops following the recovery, channels which were already
+// requested to close are still closed by their peers
+func (svc *recoveryService) DeleteRecovery() error {
+	recoveryMutex.Lock()
+	defer recoveryMutex.Unlock()
+	return removeRecovery(svc.cfg.GetEnv().Workdir)
+}
+
+// Start continues the recovery where it stopped until it is completed or the context is cancelled
+func (svc *recoveryService) Start(ctx context.Context, lnClient lnclient.LNClient) {
+	recovery, err := svc.GetRecovery()
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to load recovery")
+		return
+	}
+	if recovery == nil || recovery.State == STATE_COMPLETED {
+		return
+	}
+
+	health.RegisterJob("recovery", recoveryInterval)
+	go func() {
+		defer health.RemoveJob("recovery")
+		ticker := time.NewTicker(recoveryInterval)
+		defer ticker.Stop()
+		for {
+			completed, err := svc.step(ctx, lnClient)
+			health.ReportJobRun("recovery", err)
+			if completed {
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// step advances the recovery as far as possible and saves its state. It returns true once
+// there is nothing left to do.
+func (svc *recoveryService) step(ctx context.Context, lnClient lnclient.LNClient) (bool, error) {
+	recoveryMutex.Lock()
+	defer recoveryMutex.Unlock()
+
+	workDir := svc.cfg.GetEnv().Workdir
+	recovery, err := loadRecovery(workDir)
+	if err != nil {
+		return false, err
+	}
+	if recovery == nil || recovery.State == STATE_COMPLETED {
+		return true, nil
+	}
+
+	previousState := recovery.State
+	stepErr := svc.advance(ctx, lnClient, recovery)
+	recovery.LastError = ""
+	if stepErr != nil {
+		recovery.LastError = stepErr.Error()
+		logger.Logger.WithField("state", recovery.State).WithError(stepErr).Error("Recovery step failed")
+	}
+	if err := saveRecovery(workDir, recovery); err != nil {
+		return false, err
+	}
+
+	if recovery.State != previousState {
+		logger.Logger.WithFields(logrus.Fields{
+			"from": previousState,
+			"to":   recovery.State,
+		}).Info("Recovery state changed")
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_recovery_state_changed",
+			Properties: map[string]interface{}{
+				"source": recovery.Source,
+				"state":  recovery.State,
+			},
+		})
+	}
+	return recovery.State == STATE_COMPLETED, stepErr
+}
+
+func (svc *recoveryService) advance(ctx context.Context, lnClient lnclient.LNClient, recovery *Recovery) error {
+	if recovery.State == STATE_RESTORING_ARCHIVE {
+		// the archive contained the full node state, nothing has to be closed
+		svc.complete(recovery)
+		return nil
+	}
+
+	if recovery.State == STATE_WAITING_FOR_NODE {
+		recovery.State = STATE_RESTORING_CHANNELS
+	}
+
+	if recovery.State == STATE_RESTORING_CHANNELS {
+		if err := svc.restoreChannels(ctx, lnClient, recovery); err != nil {
+			return err
+		}
+		for _, channel := range recovery.Channels {
+			if channel.State == CHANNEL_STATE_PENDING {
+				// try again with the next run
+				return nil
+			}
+		}
+		recovery.State = STATE_WAITING_FOR_CLOSURES
+	}
+
+	if recovery.State == STATE_WAITING_FOR_CLOSURES {
+		return svc.checkClosures(ctx, lnClient, recovery)
+	}
+	return nil
+}
+
+// restoreChannels asks the peers to force close the channels of the backup. Without the
+// channel state the node cannot close them itself, the peers close them when the node
+// reconnects and tells them that it lost its state.
+func (svc *recoveryService) restoreChannels(ctx context.Context, lnClient lnclient.LNClient, recovery *Recovery) error {
+	if len(recovery.LNDMultiChanBackup) > 0 {
+		restorer, ok := lnClient.(channelBackupRestorer)
+		if !ok {
+			return errors.New("the channel backup was created by LND and can only be restored with LND")
+		}
+		if err := restorer.RestoreChannelBackups(ctx, recovery.LNDMultiChanBackup); err != nil {
+			return fmt.Errorf("failed to restore channel backup: %w", err)
+		}
+		for i := range recovery.Channels {
+			recovery.Channels[i].State = CHANNEL_STATE_REQUESTED
+		}
+		return nil
+	}
+
+	for i := range recovery.Channels {
+		channel := &recovery.Channels[i]
+		if channel.State != CHANNEL_STATE_PENDING {
+			continue
+		}
+		channel.Attempts++
+		err := connectPeer(ctx, lnClient, channel)
+		if err == nil {
+			channel.State = CHANNEL_STATE_REQUESTED
+			channel.Error = ""
+			continue
+		}
+		logger.Logger.WithField("peer_id", channel.PeerID).WithError(err).Warn("Failed to connect to channel peer")
+		channel.Error = err.Error()
+		if channel.Attempts >= maxConnectAttempts {
+			// the funds can still return if the peer closes the channel on its own
+			channel.State = CHANNEL_STATE_FAILED
+		}
+	}
+	return nil
+}
+
+func connectPeer(ctx context.Context, lnClient lnclient.LNClient, channel *RecoveryChannel) error {
+	if channel.PeerID == "" || channel.PeerSocketAddress == "" {
+		return errors.New("the backup contains no address of the peer")
+	}
+	host, portString, err := net.SplitHostPort(channel.PeerSocketAddress)
+	if err != nil {
+		return fmt.Errorf("invalid peer address: %w", err)
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid peer port: %w", err)
+	}
+	return lnClient.ConnectPeer(ctx, &lnclient.ConnectPeerRequest{
+		Pubkey:  channel.PeerID,
+		Address: host,
+		Port:    uint16(port),
+	})
+}
+
+// checkClosures marks channels as closed once their funding output was spent and
+// completes the recovery when all funds arrived in the on-chain wallet
+func (svc *recoveryService) checkClosures(ctx context.Context, lnClient lnclient.LNClient, recovery *Recovery) error {
+	var checkErrors []error
+	for i := range recovery.Channels {
+		channel := &recovery.Channels[i]
+		if channel.State == CHANNEL_STATE_CLOSED {
+			continue
+		}
+		spent, err := fetchOutspend(ctx, svc.cfg.GetEnv().LDKEsploraServer, channel.FundingTxID, channel.FundingTxVout)
+		if err != nil {
+			checkErrors = append(checkErrors, err)
+			continue
+		}
+		if spent {
+			channel.State = CHANNEL_STATE_CLOSED
+			channel.Error = ""
+		}
+	}
+
+	balance, err := lnClient.GetOnchainBalance(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch on-chain balance: %w", err)
+	}
+	recovery.OnchainBalanceSat = balance.Total
+	recovery.PendingClosureBalanceSat = balance.PendingBalancesFromChannelClosures
+	if len(checkErrors) > 0 {
+		return errors.Join(checkErrors...)
+	}
+
+	for _, channel := range recovery.Channels {
+		if channel.State != CHANNEL_STATE_CLOSED {
+			return nil
+		}
+	}
+	if balance.PendingBalancesFromChannelClosures > 0 {
+		return nil
+	}
+	svc.complete(recovery)
+	return nil
+}
+
+func (svc *recoveryService) complete(recovery *Recovery) {
+	now := time.Now().UTC()
+	recovery.State = STATE_COMPLETED
+	recovery.CompletedAt = &now
+	// the backup is not needed anymore
+	recovery.LNDMultiChanBackup = nil
+	logger.Logger.WithField("onchain_balance_sat", recovery.OnchainBalanceSat).Info("Recovery completed")
+}
+
+type outspend struct {
+	Spent bool `json:"spent"`
+}
+
+func fetchEsploraOutspend(ctx context.Context, esploraServer string, txId string, vout uint32) (bool, error) {
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/tx/%s/outspend/%d", esploraServer, txId, vout), nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, err
+	}
+	if res.StatusCode >= 300 {
+		return false, fmt.Errorf("esplora API returned non-success code: %d %s", res.StatusCode, string(body))
+	}
+
+	var result outspend
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, err
+	}
+	return result.Spent, nil
+}
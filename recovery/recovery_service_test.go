@@ -0,0 +1,230 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+type recoveryLn struct {
+	*tests.MockLn
+	connectedPeers          []lnclient.ConnectPeerRequest
+	connectErr              error
+	restoredMultiChanBackup []byte
+	pendingClosureSat       uint64
+}
+
+func (ln *recoveryLn) ConnectPeer(ctx context.Context, connectPeerRequest *lnclient.ConnectPeerRequest) error {
+	if ln.connectErr != nil {
+		return ln.connectErr
+	}
+	ln.connectedPeers = append(ln.connectedPeers, *connectPeerRequest)
+	return nil
+}
+
+func (ln *recoveryLn) GetOnchainBalance(ctx context.Context) (*lnclient.OnchainBalanceResponse, error) {
+	return &lnclient.OnchainBalanceResponse{
+		Total:                              50_000,
+		PendingBalancesFromChannelClosures: ln.pendingClosureSat,
+	}, nil
+}
+
+type lndRecoveryLn struct {
+	recoveryLn
+}
+
+func (ln *lndRecoveryLn) RestoreChannelBackups(ctx context.Context, multiChanBackup []byte) error {
+	ln.restoredMultiChanBackup = multiChanBackup
+	return nil
+}
+
+func createTestRecoveryService(t *testing.T) (*tests.TestService, *recoveryService) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	t.Cleanup(svc.Remove)
+	svc.Cfg.GetEnv().Workdir = t.TempDir()
+	return svc, NewRecoveryService(svc.Cfg, svc.EventPublisher)
+}
+
+func mockOutspend(t *testing.T, spent map[string]bool) {
+	fetchOutspend = func(ctx context.Context, esploraServer string, txId string, vout uint32) (bool, error) {
+		return spent[txId], nil
+	}
+	t.Cleanup(func() {
+		fetchOutspend = fetchEsploraOutspend
+	})
+}
+
+var testChannelBackup = &events.StaticChannelsBackupEvent{
+	NodeID: "node",
+	Channels: []events.ChannelBackup{
+		{ChannelID: "1", PeerID: "peer1", PeerSocketAddress: "127.0.0.1:9735", ChannelSize: 100_000, FundingTxID: "tx1", FundingTxVout: 0},
+		{ChannelID: "2", PeerID: "peer2", PeerSocketAddress: "[::1]:9736", ChannelSize: 200_000, FundingTxID: "tx2", FundingTxVout: 1},
+	},
+}
+
+func TestChannelRecovery(t *testing.T) {
+	svc, recoverySvc := createTestRecoveryService(t)
+	spent := map[string]bool{}
+	mockOutspend(t, spent)
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	_, err := recoverySvc.StartChannelRecovery(&events.StaticChannelsBackupEvent{})
+	assert.EqualError(t, err, "channel backup contains no channels")
+
+	recovery, err := recoverySvc.StartChannelRecovery(testChannelBackup)
+	require.NoError(t, err)
+	assert.Equal(t, STATE_WAITING_FOR_NODE, recovery.State)
+	_, err = recoverySvc.StartChannelRecovery(testChannelBackup)
+	assert.EqualError(t, err, "a recovery is already in progress")
+
+	mockLn, err := tests.NewMockLn()
+	require.NoError(t, err)
+	ln := &recoveryLn{MockLn: mockLn, pendingClosureSat: 1000}
+
+	completed, err := recoverySvc.step(context.TODO(), ln)
+	require.NoError(t, err)
+	assert.False(t, completed)
+	require.Len(t, ln.connectedPeers, 2)
+	assert.Equal(t, lnclient.ConnectPeerRequest{Pubkey: "peer1", Address: "127.0.0.1", Port: 9735}, ln.connectedPeers[0])
+	assert.Equal(t, lnclient.ConnectPeerRequest{Pubkey: "peer2", Address: "::1", Port: 9736}, ln.connectedPeers[1])
+
+	recovery, err = recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Equal(t, STATE_WAITING_FOR_CLOSURES, recovery.State)
+	assert.Equal(t, CHANNEL_STATE_REQUESTED, recovery.Channels[0].State)
+	assert.Equal(t, int64(50_000), recovery.OnchainBalanceSat)
+
+	// one channel was closed by its peer
+	spent["tx1"] = true
+	completed, err = recoverySvc.step(context.TODO(), ln)
+	require.NoError(t, err)
+	assert.False(t, completed)
+	recovery, err = recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Equal(t, CHANNEL_STATE_CLOSED, recovery.Channels[0].State)
+	assert.Equal(t, CHANNEL_STATE_REQUESTED, recovery.Channels[1].State)
+
+	// the funds are only recovered once the closing transactions were swept
+	spent["tx2"] = true
+	completed, err = recoverySvc.step(context.TODO(), ln)
+	require.NoError(t, err)
+	assert.False(t, completed)
+
+	ln.pendingClosureSat = 0
+	completed, err = recoverySvc.step(context.TODO(), ln)
+	require.NoError(t, err)
+	assert.True(t, completed)
+	recovery, err = recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Equal(t, STATE_COMPLETED, recovery.State)
+	assert.NotNil(t, recovery.CompletedAt)
+
+	time.Sleep(10 * time.Millisecond)
+	stateChanges := []string{}
+	for _, event := range mockEventConsumer.GetConsumedEvents() {
+		if event.Event == "nwc_recovery_state_changed" {
+			stateChanges = append(stateChanges, event.Properties.(map[string]interface{})["state"].(string))
+		}
+	}
+	// events are consumed concurrently
+	assert.ElementsMatch(t, []string{STATE_WAITING_FOR_CLOSURES, STATE_COMPLETED}, stateChanges)
+
+	// a completed recovery can be replaced
+	_, err = recoverySvc.StartChannelRecovery(testChannelBackup)
+	assert.NoError(t, err)
+}
+
+func TestChannelRecovery_UnreachablePeer(t *testing.T) {
+	_, recoverySvc := createTestRecoveryService(t)
+	mockOutspend(t, map[string]bool{})
+
+	_, err := recoverySvc.StartChannelRecovery(testChannelBackup)
+	require.NoError(t, err)
+
+	mockLn, err := tests.NewMockLn()
+	require.NoError(t, err)
+	ln := &recoveryLn{MockLn: mockLn, connectErr: errors.New("connection refused")}
+
+	for i := 0; i < maxConnectAttempts-1; i++ {
+		_, err = recoverySvc.step(context.TODO(), ln)
+		require.NoError(t, err)
+	}
+	recovery, err := recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Equal(t, STATE_RESTORING_CHANNELS, recovery.State)
+	assert.Equal(t, CHANNEL_STATE_PENDING, recovery.Channels[0].State)
+	assert.Equal(t, "connection refused", recovery.Channels[0].Error)
+
+	// the recovery continues once the peer was tried often enough
+	_, err = recoverySvc.step(context.TODO(), ln)
+	require.NoError(t, err)
+	recovery, err = recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Equal(t, STATE_WAITING_FOR_CLOSURES, recovery.State)
+	assert.Equal(t, CHANNEL_STATE_FAILED, recovery.Channels[0].State)
+}
+
+func TestChannelRecovery_LND(t *testing.T) {
+	_, recoverySvc := createTestRecoveryService(t)
+	mockOutspend(t, map[string]bool{})
+
+	channelBackup := *testChannelBackup
+	channelBackup.LNDMultiChanBackup = []byte("multi")
+	_, err := recoverySvc.StartChannelRecovery(&channelBackup)
+	require.NoError(t, err)
+
+	mockLn, err := tests.NewMockLn()
+	require.NoError(t, err)
+
+	// LND backups cannot be restored by other backends
+	_, err = recoverySvc.step(context.TODO(), &recoveryLn{MockLn: mockLn})
+	assert.Error(t, err)
+	recovery, err := recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Equal(t, STATE_RESTORING_CHANNELS, recovery.State)
+	assert.NotEmpty(t, recovery.LastError)
+
+	ln := &lndRecoveryLn{recoveryLn{MockLn: mockLn}}
+	_, err = recoverySvc.step(context.TODO(), ln)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("multi"), ln.restoredMultiChanBackup)
+	assert.Empty(t, ln.connectedPeers)
+	recovery, err = recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Equal(t, STATE_WAITING_FOR_CLOSURES, recovery.State)
+	assert.Empty(t, recovery.LastError)
+}
+
+func TestArchiveRecovery(t *testing.T) {
+	svc, recoverySvc := createTestRecoveryService(t)
+
+	// the state is written next to the extracted archive and moved into the work dir on restart
+	_, err := recoverySvc.StartArchiveRecovery(svc.Cfg.GetEnv().Workdir)
+	require.NoError(t, err)
+
+	mockLn, err := tests.NewMockLn()
+	require.NoError(t, err)
+	completed, err := recoverySvc.step(context.TODO(), mockLn)
+	require.NoError(t, err)
+	assert.True(t, completed)
+
+	recovery, err := recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Equal(t, SOURCE_MIGRATION_ARCHIVE, recovery.Source)
+	assert.Equal(t, STATE_COMPLETED, recovery.State)
+
+	require.NoError(t, recoverySvc.DeleteRecovery())
+	recovery, err = recoverySvc.GetRecovery()
+	require.NoError(t, err)
+	assert.Nil(t, recovery)
+}
@@ -0,0 +1,163 @@
+package scheduledpayments
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed cron expression with the standard five fields:
+// minute, hour, day of month, month and day of week.
+type schedule struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// cron matches either day field if both are restricted
+	daysOfMonthRestricted bool
+	daysOfWeekRestricted  bool
+}
+
+var scheduleShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+type scheduleField struct {
+	name string
+	min  uint
+	max  uint
+}
+
+var scheduleFields = []scheduleField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func parseSchedule(expression string) (*schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if shortcut, ok := scheduleShortcuts[expression]; ok {
+		expression = shortcut
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(scheduleFields) {
+		return nil, errors.New("schedule must have 5 fields: minute hour day-of-month month day-of-week")
+	}
+
+	values := make([]uint64, len(parts))
+	for i, part := range parts {
+		value, err := parseScheduleField(part, scheduleFields[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	// both 0 and 7 mean sunday
+	if values[4]&(1<<7) != 0 {
+		values[4] |= 1
+	}
+
+	return &schedule{
+		minutes:               values[0],
+		hours:                 values[1],
+		daysOfMonth:           values[2],
+		months:                values[3],
+		daysOfWeek:            values[4],
+		daysOfMonthRestricted: parts[2] != "*",
+		daysOfWeekRestricted:  parts[4] != "*",
+	}, nil
+}
+
+// parses a comma separated list of values, ranges (1-5) and steps (*/15, 1-10/2) into a bitset
+func parseScheduleField(field string, definition scheduleField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		step := uint(1)
+		if rangePart, stepPart, found := strings.Cut(item, "/"); found {
+			parsedStep, err := strconv.ParseUint(stepPart, 10, 8)
+			if err != nil || parsedStep == 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %s", definition.name, item)
+			}
+			step = uint(parsedStep)
+			item = rangePart
+		}
+
+		start, end := definition.min, definition.max
+		if item != "*" {
+			startPart, endPart, isRange := strings.Cut(item, "-")
+			parsedStart, err := strconv.ParseUint(startPart, 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field: %s", definition.name, item)
+			}
+			start = uint(parsedStart)
+			end = start
+			if isRange {
+				parsedEnd, err := strconv.ParseUint(endPart, 10, 8)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %s field: %s", definition.name, item)
+				}
+				end = uint(parsedEnd)
+			} else if step > 1 {
+				// "5/15" means every 15 starting at 5
+				end = definition.max
+			}
+		}
+
+		if start < definition.min || end > definition.max || start > end {
+			return 0, fmt.Errorf("%s field out of range: %s", definition.name, item)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// next returns the first time matching the schedule strictly after the given time
+func (s *schedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// every valid schedule matches at least once within a few years (e.g. Feb 29th)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *schedule) matchesDay(t time.Time) bool {
+	dayOfMonthMatches := s.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeekMatches := s.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.daysOfMonthRestricted && s.daysOfWeekRestricted {
+		return dayOfMonthMatches || dayOfWeekMatches
+	}
+	return dayOfMonthMatches && dayOfWeekMatches
+}
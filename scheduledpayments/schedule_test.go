@@ -0,0 +1,48 @@
+package scheduledpayments
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@sometimes"} {
+		_, err := parseSchedule(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// a wednesday
+	from := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.January, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, time.January, 11, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC)},
+		{"30 10 10 1 *", time.Date(2025, time.January, 10, 10, 30, 0, 0, time.UTC)},
+		// day of month or day of week when both are restricted
+		{"0 0 1 * 5", time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, testCase := range testCases {
+		schedule, err := parseSchedule(testCase.expression)
+		require.NoError(t, err, testCase.expression)
+		assert.Equal(t, testCase.expected, schedule.next(from), testCase.expression)
+	}
+}
+
+func TestScheduleNext_NeverMatches(t *testing.T) {
+	schedule, err := parseSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.next(time.Now()).IsZero())
+}
@@ -0,0 +1,280 @@
+package scheduledpayments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/lnurl"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/transactions"
+)
+
+const maxAttempts = 5
+
+// retryBaseDelay is doubled after every failed attempt of a run
+var retryBaseDelay = time.Minute
+
+// fetchInvoice is a variable so it can be replaced in tests
+var fetchInvoice = lnurl.FetchLightningAddressInvoice
+
+type ScheduledPaymentsService interface {
+	CreateScheduledPayment(appId *uint, destination string, amountMsat uint64, comment string, scheduleExpression string, endsAt *time.Time) (*db.ScheduledPayment, error)
+	ListScheduledPayments() ([]db.ScheduledPayment, error)
+	DeleteScheduledPayment(id uint) error
+	Start(ctx context.Context, lnClient lnclient.LNClient, transactionsService transactions.TransactionsService)
+}
+
+type scheduledPaymentsService struct {
+	db             *gorm.DB
+	eventPublisher events.EventPublisher
+}
+
+func NewScheduledPaymentsService(db *gorm.DB, eventPublisher events.EventPublisher) *scheduledPaymentsService {
+	return &scheduledPaymentsService{
+		db:             db,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (svc *scheduledPaymentsService) CreateScheduledPayment(appId *uint, destination string, amountMsat uint64, comment string, scheduleExpression string, endsAt *time.Time) (*db.ScheduledPayment, error) {
+	destination = strings.TrimSpace(strings.ToLower(destination))
+	if strings.Count(destination, "@") != 1 {
+		return nil, errors.New("destination must be a lightning address")
+	}
+	if amountMsat == 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+
+	schedule, err := parseSchedule(scheduleExpression)
+	if err != nil {
+		return nil, err
+	}
+	nextRunAt := schedule.next(time.Now())
+	if nextRunAt.IsZero() {
+		return nil, errors.New("schedule never matches")
+	}
+	if endsAt != nil && endsAt.Before(nextRunAt) {
+		return nil, errors.New("end date is before the first payment")
+	}
+
+	// payments are charged to the budget of the app
+	if appId == nil {
+		return nil, errors.New("app is required")
+	}
+	var app db.App
+	if svc.db.Limit(1).Find(&app, &db.App{ID: *appId}).RowsAffected == 0 {
+		return nil, errors.New("app not found")
+	}
+
+	scheduledPayment := db.ScheduledPayment{
+		AppId:       appId,
+		Destination: destination,
+		AmountMsat:  amountMsat,
+		Comment:     comment,
+		Schedule:    scheduleExpression,
+		NextRunAt:   nextRunAt,
+		EndsAt:      endsAt,
+		Enabled:     true,
+	}
+	if err := svc.db.Create(&scheduledPayment).Error; err != nil {
+		return nil, err
+	}
+
+	return &scheduledPayment, nil
+}
+
+func (svc *scheduledPaymentsService) ListScheduledPayments() ([]db.ScheduledPayment, error) {
+	scheduledPayments := []db.ScheduledPayment{}
+	if err := svc.db.Order("id").Find(&scheduledPayments).Error; err != nil {
+		return nil, err
+	}
+	return scheduledPayments, nil
+}
+
+func (svc *scheduledPaymentsService) DeleteScheduledPayment(id uint) error {
+	result := svc.db.Delete(&db.ScheduledPayment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("scheduled payment not found")
+	}
+	return nil
+}
+
+// Start checks for due payments every minute until the context is cancelled
+func (svc *scheduledPaymentsService) Start(ctx context.Context, lnClient lnclient.LNClient, transactionsService transactions.TransactionsService) {
+	logger.Logger.Info("Starting scheduled payments")
+	health.RegisterJob("scheduled_payments", time.Minute)
+	go func() {
+		defer health.RemoveJob("scheduled_payments")
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				health.ReportJobRun("scheduled_payments", svc.processDuePayments(ctx, lnClient, transactionsService))
+			case <-ctx.Done():
+				logger.Logger.Info("Stopping scheduled payments")
+				return
+			}
+		}
+	}()
+}
+
+// processDuePayments only returns an error if the due payments could not be loaded,
+// failed payments are recorded on the scheduled payment itself
+func (svc *scheduledPaymentsService) processDuePayments(ctx context.Context, lnClient lnclient.LNClient, transactionsService transactions.TransactionsService) error {
+	duePayments := []db.ScheduledPayment{}
+	err := svc.db.Where("enabled = ? AND next_run_at <= ?", true, time.Now()).Order("next_run_at").Find(&duePayments).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list due scheduled payments")
+		return err
+	}
+
+	for _, scheduledPayment := range duePayments {
+		if ctx.Err() != nil {
+			return nil
+		}
+		svc.executePayment(ctx, &scheduledPayment, lnClient, transactionsService)
+	}
+	return nil
+}
+
+func (svc *scheduledPaymentsService) executePayment(ctx context.Context, scheduledPayment *db.ScheduledPayment, lnClient lnclient.LNClient, transactionsService transactions.TransactionsService) {
+	logger.Logger.WithFields(logrus.Fields{
+		"scheduled_payment_id": scheduledPayment.ID,
+		"destination":          scheduledPayment.Destination,
+		"amount_msat":          scheduledPayment.AmountMsat,
+		"attempt":              scheduledPayment.Attempts + 1,
+	}).Info("Executing scheduled payment")
+
+	now := time.Now()
+	scheduledPayment.LastRunAt = &now
+
+	transaction, err := svc.pay(ctx, scheduledPayment, lnClient, transactionsService)
+	if err != nil {
+		logger.Logger.WithField("scheduled_payment_id", scheduledPayment.ID).WithError(err).Error("Scheduled payment failed")
+		scheduledPayment.Attempts++
+		scheduledPayment.LastError = err.Error()
+
+		willRetry := scheduledPayment.Attempts < maxAttempts
+		if willRetry {
+			scheduledPayment.NextRunAt = now.Add(retryBaseDelay * time.Duration(1<<(scheduledPayment.Attempts-1)))
+		} else {
+			// give up on this run and wait for the next one
+			svc.scheduleNextRun(scheduledPayment, now)
+		}
+
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_scheduled_payment_failed",
+			Properties: map[string]interface{}{
+				"scheduled_payment_id": scheduledPayment.ID,
+				"destination":          scheduledPayment.Destination,
+				"amount":               scheduledPayment.AmountMsat / 1000,
+				"attempts":             scheduledPayment.Attempts,
+				"will_retry":           willRetry,
+				"error":                err.Error(),
+			},
+		})
+	} else {
+		scheduledPayment.LastError = ""
+		svc.scheduleNextRun(scheduledPayment, now)
+
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_scheduled_payment_succeeded",
+			Properties: map[string]interface{}{
+				"scheduled_payment_id": scheduledPayment.ID,
+				"destination":          scheduledPayment.Destination,
+				"amount":               scheduledPayment.AmountMsat / 1000,
+				"payment_hash":         transaction.PaymentHash,
+			},
+		})
+	}
+
+	err = svc.db.Model(scheduledPayment).Select("next_run_at", "enabled", "attempts", "payment_hash", "last_run_at", "last_error").Updates(scheduledPayment).Error
+	if err != nil {
+		logger.Logger.WithField("scheduled_payment_id", scheduledPayment.ID).WithError(err).Error("Failed to update scheduled payment")
+	}
+}
+
+func (svc *scheduledPaymentsService) pay(ctx context.Context, scheduledPayment *db.ScheduledPayment, lnClient lnclient.LNClient, transactionsService transactions.TransactionsService) (*transactions.Transaction, error) {
+	// payments without an app would not be limited by any budget
+	if scheduledPayment.AppId == nil {
+		return nil, errors.New("scheduled payment has no app")
+	}
+
+	// an earlier attempt of this run may have paid (or still be paying) its invoice
+	if scheduledPayment.PaymentHash != "" {
+		var previousTransaction db.Transaction
+		result := svc.db.Where("type = ? AND payment_hash = ?", constants.TRANSACTION_TYPE_OUTGOING, scheduledPayment.PaymentHash).
+			Order("id DESC").Limit(1).Find(&previousTransaction)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			switch previousTransaction.State {
+			case constants.TRANSACTION_STATE_SETTLED:
+				return &previousTransaction, nil
+			case constants.TRANSACTION_STATE_PENDING:
+				return nil, errors.New("payment of a previous attempt is still pending")
+			}
+		}
+		// the previous attempt failed or stopped before paying, so a new invoice is requested
+	}
+
+	invoice, err := fetchInvoice(ctx, scheduledPayment.Destination, scheduledPayment.AmountMsat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request invoice: %w", err)
+	}
+	paymentRequest, err := decodepay.Decodepay(strings.ToLower(invoice))
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice: %w", err)
+	}
+
+	scheduledPayment.PaymentHash = paymentRequest.PaymentHash
+	if err := svc.db.Model(scheduledPayment).Update("payment_hash", scheduledPayment.PaymentHash).Error; err != nil {
+		return nil, fmt.Errorf("failed to store payment hash: %w", err)
+	}
+
+	metadata := map[string]interface{}{
+		"scheduled_payment_id": scheduledPayment.ID,
+	}
+	if scheduledPayment.Comment != "" {
+		metadata["comment"] = scheduledPayment.Comment
+	}
+
+	// budgets of the app are enforced by the transactions service
+	return transactionsService.SendPaymentSync(ctx, invoice, nil, metadata, lnClient, scheduledPayment.AppId, nil)
+}
+
+// missed runs (e.g. while the hub was offline) are skipped rather than paid all at once
+func (svc *scheduledPaymentsService) scheduleNextRun(scheduledPayment *db.ScheduledPayment, after time.Time) {
+	scheduledPayment.Attempts = 0
+	scheduledPayment.PaymentHash = ""
+
+	schedule, err := parseSchedule(scheduledPayment.Schedule)
+	if err != nil {
+		logger.Logger.WithField("scheduled_payment_id", scheduledPayment.ID).WithError(err).Error("Invalid schedule, disabling scheduled payment")
+		scheduledPayment.Enabled = false
+		return
+	}
+
+	scheduledPayment.NextRunAt = schedule.next(after)
+	if scheduledPayment.NextRunAt.IsZero() || (scheduledPayment.EndsAt != nil && scheduledPayment.NextRunAt.After(*scheduledPayment.EndsAt)) {
+		logger.Logger.WithField("scheduled_payment_id", scheduledPayment.ID).Info("Scheduled payment has ended")
+		scheduledPayment.Enabled = false
+	}
+}
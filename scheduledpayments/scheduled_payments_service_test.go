@@ -0,0 +1,173 @@
+package scheduledpayments
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/transactions"
+)
+
+func TestCreateScheduledPayment_Invalid(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createPayingApp(t, svc)
+
+	scheduledPaymentsSvc := NewScheduledPaymentsService(svc.DB, svc.EventPublisher)
+	_, err = scheduledPaymentsSvc.CreateScheduledPayment(&app.ID, "not-an-address", 1000, "", "@daily", nil)
+	assert.EqualError(t, err, "destination must be a lightning address")
+	_, err = scheduledPaymentsSvc.CreateScheduledPayment(&app.ID, "bob@example.com", 1000, "", "every day", nil)
+	assert.Error(t, err)
+	endsAt := time.Now().Add(-time.Hour)
+	_, err = scheduledPaymentsSvc.CreateScheduledPayment(&app.ID, "bob@example.com", 1000, "", "@daily", &endsAt)
+	assert.EqualError(t, err, "end date is before the first payment")
+	_, err = scheduledPaymentsSvc.CreateScheduledPayment(nil, "bob@example.com", 1000, "", "@daily", nil)
+	assert.EqualError(t, err, "app is required")
+}
+
+func createPayingApp(t *testing.T, svc *tests.TestService) *db.App {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+	return app
+}
+
+func TestProcessDuePayments(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalFetchInvoice := fetchInvoice
+	defer func() { fetchInvoice = originalFetchInvoice }()
+	fetchInvoice = func(ctx context.Context, lightningAddress string, amountMsat uint64) (string, error) {
+		assert.Equal(t, "bob@example.com", lightningAddress)
+		return tests.MockInvoice, nil
+	}
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	app := createPayingApp(t, svc)
+	scheduledPaymentsSvc := NewScheduledPaymentsService(svc.DB, svc.EventPublisher)
+	scheduledPayment, err := scheduledPaymentsSvc.CreateScheduledPayment(&app.ID, "Bob@example.com", 123_000, "rent", "@monthly", nil)
+	require.NoError(t, err)
+
+	// make the payment due
+	require.NoError(t, svc.DB.Model(scheduledPayment).Update("next_run_at", time.Now().Add(-time.Minute)).Error)
+
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	scheduledPaymentsSvc.processDuePayments(context.TODO(), svc.LNClient, transactionsSvc)
+
+	var transaction db.Transaction
+	require.NoError(t, svc.DB.First(&transaction, &db.Transaction{PaymentHash: tests.MockPaymentHash}).Error)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.Equal(t, app.ID, *transaction.AppId)
+
+	var updatedScheduledPayment db.ScheduledPayment
+	require.NoError(t, svc.DB.First(&updatedScheduledPayment, scheduledPayment.ID).Error)
+	assert.True(t, updatedScheduledPayment.Enabled)
+	assert.True(t, updatedScheduledPayment.NextRunAt.After(time.Now()))
+	assert.NotNil(t, updatedScheduledPayment.LastRunAt)
+	assert.Zero(t, updatedScheduledPayment.Attempts)
+
+	require.Eventually(t, func() bool {
+		events := mockEventConsumer.GetConsumedEvents()
+		return len(events) > 0 && events[len(events)-1].Event == "nwc_scheduled_payment_succeeded"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestProcessDuePayments_Retry(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalFetchInvoice := fetchInvoice
+	defer func() { fetchInvoice = originalFetchInvoice }()
+	fetchInvoice = func(ctx context.Context, lightningAddress string, amountMsat uint64) (string, error) {
+		return "", errors.New("lightning address not found")
+	}
+
+	app := createPayingApp(t, svc)
+	scheduledPaymentsSvc := NewScheduledPaymentsService(svc.DB, svc.EventPublisher)
+	scheduledPayment, err := scheduledPaymentsSvc.CreateScheduledPayment(&app.ID, "bob@example.com", 123_000, "", "@monthly", nil)
+	require.NoError(t, err)
+
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		require.NoError(t, svc.DB.Model(scheduledPayment).Update("next_run_at", time.Now().Add(-time.Minute)).Error)
+		scheduledPaymentsSvc.processDuePayments(context.TODO(), svc.LNClient, transactionsSvc)
+
+		var updatedScheduledPayment db.ScheduledPayment
+		require.NoError(t, svc.DB.First(&updatedScheduledPayment, scheduledPayment.ID).Error)
+		assert.Equal(t, "failed to request invoice: lightning address not found", updatedScheduledPayment.LastError)
+		if attempt < maxAttempts {
+			assert.Equal(t, attempt, updatedScheduledPayment.Attempts)
+			assert.WithinDuration(t, time.Now().Add(retryBaseDelay*time.Duration(1<<(attempt-1))), updatedScheduledPayment.NextRunAt, 5*time.Second)
+		} else {
+			// the run is skipped after the last attempt
+			assert.Zero(t, updatedScheduledPayment.Attempts)
+			schedule, err := parseSchedule("@monthly")
+			require.NoError(t, err)
+			assert.Equal(t, schedule.next(time.Now()).Unix(), updatedScheduledPayment.NextRunAt.Unix())
+		}
+	}
+}
+
+func TestProcessDuePayments_PaidByEarlierAttempt(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalFetchInvoice := fetchInvoice
+	defer func() { fetchInvoice = originalFetchInvoice }()
+	fetchInvoice = func(ctx context.Context, lightningAddress string, amountMsat uint64) (string, error) {
+		t.Fatal("the invoice of the earlier attempt was already paid")
+		return "", nil
+	}
+
+	app := createPayingApp(t, svc)
+	scheduledPaymentsSvc := NewScheduledPaymentsService(svc.DB, svc.EventPublisher)
+	scheduledPayment, err := scheduledPaymentsSvc.CreateScheduledPayment(&app.ID, "bob@example.com", 123_000, "", "@monthly", nil)
+	require.NoError(t, err)
+
+	// an earlier attempt timed out after its payment was made
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		AppId:       &app.ID,
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		PaymentHash: tests.MockPaymentHash,
+		AmountMsat:  123_000,
+	}).Error)
+	require.NoError(t, svc.DB.Model(scheduledPayment).Updates(map[string]interface{}{
+		"next_run_at":  time.Now().Add(-time.Minute),
+		"attempts":     1,
+		"payment_hash": tests.MockPaymentHash,
+	}).Error)
+
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	scheduledPaymentsSvc.processDuePayments(context.TODO(), svc.LNClient, transactionsSvc)
+
+	var updatedScheduledPayment db.ScheduledPayment
+	require.NoError(t, svc.DB.First(&updatedScheduledPayment, scheduledPayment.ID).Error)
+	assert.Zero(t, updatedScheduledPayment.Attempts)
+	assert.Empty(t, updatedScheduledPayment.PaymentHash)
+	assert.Empty(t, updatedScheduledPayment.LastError)
+	assert.True(t, updatedScheduledPayment.NextRunAt.After(time.Now()))
+
+	var count int64
+	require.NoError(t, svc.DB.Model(&db.Transaction{}).Where("payment_hash = ?", tests.MockPaymentHash).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
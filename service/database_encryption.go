@@ -0,0 +1,67 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// initDatabaseEncryption loads the key of the encrypted database columns. The key is
+// created on the first unlock and is kept encrypted with the unlock password. Without
+// ENCRYPT_DATABASE it is only used for the columns which are always encrypted.
+func (svc *service) initDatabaseEncryption(encryptionKey string) error {
+	encrypt := svc.cfg.GetEnv().EncryptDatabase
+	dataKeyHex, err := svc.cfg.Get(config.DatabaseEncryptionKeyKey, encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	if dataKeyHex == "" {
+		dataKey := make([]byte, 32)
+		if _, err := rand.Read(dataKey); err != nil {
+			return err
+		}
+		dataKeyHex = hex.EncodeToString(dataKey)
+		err = svc.cfg.SetUpdate(config.DatabaseEncryptionKeyKey, dataKeyHex, encryptionKey)
+		if err != nil {
+			return err
+		}
+		logger.Logger.Info("Created database encryption key")
+	}
+
+	dataKey, err := hex.DecodeString(dataKeyHex)
+	if err != nil {
+		return err
+	}
+	// values encrypted before are still decrypted if encryption was switched off again
+	err = db.SetEncryptionKey(dataKey, encrypt)
+	if err != nil {
+		return err
+	}
+
+	// the key rotation continues when the maintenance service is started
+	nextDataKeyHex, err := svc.cfg.Get(config.DatabaseNextEncryptionKeyKey, encryptionKey)
+	if err != nil {
+		return err
+	}
+	if nextDataKeyHex != "" {
+		nextDataKey, err := hex.DecodeString(nextDataKeyHex)
+		if err != nil {
+			return err
+		}
+		err = db.SetRotatingEncryptionKey(nextDataKey, dataKey, encrypt)
+		if err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		if err := db.EncryptExistingValues(svc.db); err != nil {
+			logger.Logger.WithError(err).Error("Failed to encrypt existing database values")
+		}
+	}()
+	return nil
+}
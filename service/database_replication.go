@@ -0,0 +1,62 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// detectDatabaseRestore recognizes a database which was restored from a replica, e.g. by
+// `litestream restore` when the container was recreated. The ID of the database is kept
+// in the database and in a file next to it, which is not replicated. A restored database
+// knows an ID, but the file is missing or belongs to the lost database.
+func detectDatabaseRestore(cfg config.Config, eventPublisher events.EventPublisher, dbPath string) error {
+	markerPath := dbPath + ".replica-id"
+	replicaId, err := cfg.Get(config.DatabaseReplicaIdKey, "")
+	if err != nil {
+		return err
+	}
+	marker, err := os.ReadFile(markerPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if replicaId != "" && strings.TrimSpace(string(marker)) == replicaId {
+		return nil
+	}
+
+	if replicaId != "" {
+		restoredAt := time.Now().UTC()
+		logger.Logger.WithField("replica_id", replicaId).Warn("Database was restored from a replica, changes made after it was last replicated are lost")
+		err = cfg.SetUpdate(config.DatabaseRestoredAtKey, restoredAt.Format(time.RFC3339), "")
+		if err != nil {
+			return err
+		}
+		eventPublisher.Publish(&events.Event{
+			Event: "nwc_database_restored",
+			Properties: map[string]interface{}{
+				"restored_at": restoredAt,
+			},
+		})
+	}
+
+	// the restored database starts a new history
+	newReplicaId := make([]byte, 16)
+	if _, err := rand.Read(newReplicaId); err != nil {
+		return err
+	}
+	replicaId = hex.EncodeToString(newReplicaId)
+	err = cfg.SetUpdate(config.DatabaseReplicaIdKey, replicaId, "")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(markerPath, []byte(replicaId), 0600)
+}
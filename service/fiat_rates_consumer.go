@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/transactions"
+)
+
+// fiatRatesConsumer records the bitcoin price in each configured currency when a transaction settles
+type fiatRatesConsumer struct {
+	events.EventSubscriber
+	cfg                 config.Config
+	fiatRateProvider    transactions.FiatRateProvider
+	transactionsService transactions.TransactionsService
+}
+
+func newFiatRatesConsumer(cfg config.Config, fiatRateProvider transactions.FiatRateProvider, transactionsService transactions.TransactionsService) *fiatRatesConsumer {
+	return &fiatRatesConsumer{
+		cfg:                 cfg,
+		fiatRateProvider:    fiatRateProvider,
+		transactionsService: transactionsService,
+	}
+}
+
+func (c *fiatRatesConsumer) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	if event.Event != "nwc_payment_received" && event.Event != "nwc_payment_sent" {
+		return
+	}
+
+	transaction, ok := event.Properties.(*db.Transaction)
+	if !ok {
+		logger.Logger.WithField("event", event).Error("Failed to cast event.Properties to transaction")
+		return
+	}
+
+	rates := map[string]float64{}
+	for _, currency := range config.GetFiatCurrencies(c.cfg) {
+		rate, err := c.fiatRateProvider.GetFiatRate(ctx, currency)
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"currency":       currency,
+				"transaction_id": transaction.ID,
+			}).Warn("Failed to fetch bitcoin rate for settled transaction")
+			continue
+		}
+		rates[currency] = rate
+	}
+
+	err := c.transactionsService.SaveFiatRates(transaction.ID, rates)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("transaction_id", transaction.ID).Error("Failed to save fiat rates")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha512"
 	"encoding/hex"
 	"errors"
+	"sync"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
@@ -33,12 +34,27 @@ type Keys interface {
 	GetSwapKey(childIndex uint) (*btcec.PrivateKey, error)
 }
 
+// Lockable keys can drop the key material which is only needed to spend funds, while the keys
+// needed to receive payments and answer NWC requests stay available.
+// Locking does not remove all secrets from memory: the wallet keys of the apps and the database
+// encryption key are kept, so a memory dump of a locked hub can still reveal them.
+type Lockable interface {
+	// Lock zeroes the master keys. The wallet keys of the given apps are kept in plain text,
+	// so that their NWC requests can still be answered.
+	Lock(appIds []uint)
+	Unlock(cfg config.Config, encryptionKey string) error
+}
+
 type keys struct {
 	nostrSecretKey string
 	nostrPublicKey string
 	appKey         *bip32.Key
 	swapKey        *hdkeychain.ExtendedKey
 	swapMnemonic   string
+	// wallet keys of the apps which are kept while the keys are locked
+	appWalletKeys map[uint]string
+	// guards the keys which are dropped when locking
+	lockMutex sync.RWMutex
 }
 
 func NewKeys() *keys {
@@ -89,11 +105,14 @@ func (keys *keys) Init(cfg config.Config, encryptionKey string) error {
 		}
 	}
 
-	masterKey, err := bip32.NewMasterKey(bip39.NewSeed(mnemonic, ""))
+	seed := bip39.NewSeed(mnemonic, "")
+	defer zeroBytes(seed)
+	masterKey, err := bip32.NewMasterKey(seed)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to create seed from mnemonic")
 		return err
 	}
+	defer zeroBip32Key(masterKey)
 
 	albyHubIndex := uint32(bip32.FirstHardenedChild + 128029 /* 🐝 */)
 	appKey, err := masterKey.NewChildKey(albyHubIndex)
@@ -101,14 +120,12 @@ func (keys *keys) Init(cfg config.Config, encryptionKey string) error {
 		logger.Logger.WithError(err).Error("Failed to derive app key")
 		return err
 	}
-	keys.appKey = appKey
 
 	swapMnemonic, err := keys.GenerateSwapMnemonic(masterKey)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to generate swap mnemonic")
 		return err
 	}
-	keys.swapMnemonic = swapMnemonic
 
 	netParams := &chaincfg.MainNetParams
 	network := cfg.GetNetwork()
@@ -116,17 +133,58 @@ func (keys *keys) Init(cfg config.Config, encryptionKey string) error {
 		netParams = &chaincfg.TestNet3Params
 	}
 
-	swapKey, err := hdkeychain.NewMaster(bip39.NewSeed(swapMnemonic, ""), netParams)
+	swapSeed := bip39.NewSeed(swapMnemonic, "")
+	defer zeroBytes(swapSeed)
+	swapKey, err := hdkeychain.NewMaster(swapSeed, netParams)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to create seed from swap mnemonic")
 		return err
 	}
+	keys.lockMutex.Lock()
+	keys.appKey = appKey
 	keys.swapKey = swapKey
+	keys.swapMnemonic = swapMnemonic
+	keys.appWalletKeys = nil
+	keys.lockMutex.Unlock()
 
 	return nil
 }
 
+// Lock zeroes the app key, from which the keys of the node services are derived, and drops the swap keys,
+// which are needed to send swaps and to refund them. Swaps which are in progress derived their keys when
+// they were started. The seed and master key are never kept after Init. The wallet keys of the given apps
+// are derived before and stay cached until Unlock, they are not protected by locking.
+func (keys *keys) Lock(appIds []uint) {
+	appWalletKeys := map[uint]string{}
+	for _, appId := range appIds {
+		appWalletKey, err := keys.GetAppWalletKey(appId)
+		if err != nil {
+			logger.Logger.WithError(err).WithField("app_id", appId).Error("Failed to derive app wallet key before locking")
+			continue
+		}
+		appWalletKeys[appId] = appWalletKey
+	}
+
+	keys.lockMutex.Lock()
+	defer keys.lockMutex.Unlock()
+	zeroBip32Key(keys.appKey)
+	keys.appKey = nil
+	if keys.swapKey != nil {
+		keys.swapKey.Zero()
+	}
+	keys.swapKey = nil
+	keys.swapMnemonic = ""
+	keys.appWalletKeys = appWalletKeys
+}
+
+// Unlock derives the keys from the encrypted mnemonic again
+func (keys *keys) Unlock(cfg config.Config, encryptionKey string) error {
+	return keys.Init(cfg, encryptionKey)
+}
+
 func (keys *keys) GetSwapMnemonic() string {
+	keys.lockMutex.RLock()
+	defer keys.lockMutex.RUnlock()
 	return keys.swapMnemonic
 }
 
@@ -139,6 +197,13 @@ func (keys *keys) GetNostrSecretKey() string {
 }
 
 func (keys *keys) GetAppWalletKey(appID uint) (string, error) {
+	keys.lockMutex.RLock()
+	appWalletKey, ok := keys.appWalletKeys[appID]
+	keys.lockMutex.RUnlock()
+	if ok {
+		return appWalletKey, nil
+	}
+
 	path := []uint32{bip32.FirstHardenedChild + 1, bip32.FirstHardenedChild + uint32(appID)}
 	key, err := keys.DeriveKey(path)
 	if err != nil {
@@ -152,6 +217,12 @@ func (keys *keys) DeriveKey(path []uint32) (*bip32.Key, error) {
 	if len(path) == 0 {
 		return nil, errors.New("path must have at least one element")
 	}
+	// the key must not be zeroed while deriving from it
+	keys.lockMutex.RLock()
+	defer keys.lockMutex.RUnlock()
+	if keys.appWalletKeys != nil {
+		return nil, errors.New("keys are locked")
+	}
 	if keys.appKey == nil {
 		return nil, errors.New("app key not set")
 	}
@@ -170,7 +241,12 @@ func (keys *keys) DeriveKey(path []uint32) (*bip32.Key, error) {
 func (keys *keys) GetSwapKey(swapID uint) (*btcec.PrivateKey, error) {
 	path := []uint32{44, 0, 0, 0, uint32(swapID)}
 
+	keys.lockMutex.RLock()
 	key := keys.swapKey
+	keys.lockMutex.RUnlock()
+	if key == nil {
+		return nil, errors.New("swap keys are locked")
+	}
 	for _, index := range path {
 		var err error
 		key, err = key.Derive(index)
@@ -208,3 +284,17 @@ func (keys *keys) GenerateSwapMnemonic(key *bip32.Key) (string, error) {
 	}
 	return mnemonic, nil
 }
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func zeroBip32Key(key *bip32.Key) {
+	if key == nil {
+		return
+	}
+	zeroBytes(key.Key)
+	zeroBytes(key.ChainCode)
+}
@@ -142,3 +142,44 @@ func TestGenerateSwapMnemonic(t *testing.T) {
 	expectedSwapMnemonic := "truth cargo pluck prefer mosquito symptom review kitchen exile fit corn vault"
 	assert.Equal(t, expectedSwapMnemonic, swapMnemonic)
 }
+
+func TestLockAndUnlock(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	config, err := config.NewConfig(&config.AppConfig{}, gormDb)
+	require.NoError(t, err)
+
+	keys := NewKeys()
+	require.NoError(t, keys.Init(config, "123"))
+	swapMnemonic := keys.GetSwapMnemonic()
+	swapKey, err := keys.GetSwapKey(1)
+	require.NoError(t, err)
+
+	appWalletKey, err := keys.GetAppWalletKey(1)
+	require.NoError(t, err)
+
+	keys.Lock([]uint{1})
+	assert.Empty(t, keys.GetSwapMnemonic())
+	_, err = keys.GetSwapKey(1)
+	assert.EqualError(t, err, "swap keys are locked")
+	_, err = keys.DeriveKey([]uint32{1})
+	assert.EqualError(t, err, "keys are locked")
+	_, err = keys.GetAppWalletKey(2)
+	assert.EqualError(t, err, "keys are locked")
+	// receiving and NWC of existing apps keep working
+	lockedAppWalletKey, err := keys.GetAppWalletKey(1)
+	require.NoError(t, err)
+	assert.Equal(t, appWalletKey, lockedAppWalletKey)
+	assert.NotEmpty(t, keys.GetNostrSecretKey())
+
+	require.NoError(t, keys.Unlock(config, "123"))
+	assert.Equal(t, swapMnemonic, keys.GetSwapMnemonic())
+	unlockedSwapKey, err := keys.GetSwapKey(1)
+	require.NoError(t, err)
+	assert.Equal(t, swapKey.Serialize(), unlockedSwapKey.Serialize())
+	_, err = keys.GetAppWalletKey(2)
+	assert.NoError(t, err)
+}
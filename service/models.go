@@ -5,6 +5,7 @@ import (
 
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/deadletters"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/service/keys"
@@ -31,6 +32,7 @@ type Service interface {
 	GetSwapsService() swaps.SwapsService
 	GetDB() *gorm.DB
 	GetConfig() config.Config
+	GetDeadLetterService() deadletters.DeadLetterService
 	GetKeys() keys.Keys
 	GetRelayStatuses() []RelayStatus
 	GetStartupState() string
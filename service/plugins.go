@@ -0,0 +1,33 @@
+package service
+
+import (
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/plugins"
+)
+
+// initPlugins gives the registered plugins access to the hub and subscribes them to events
+func (svc *service) initPlugins() {
+	host := &plugins.Host{
+		DB:             svc.db,
+		Config:         svc.cfg,
+		EventPublisher: svc.eventPublisher,
+		LNClient: func() lnclient.LNClient {
+			return svc.GetLNClient()
+		},
+	}
+
+	for _, plugin := range plugins.Plugins() {
+		if initializer, ok := plugin.(plugins.Initializer); ok {
+			err := initializer.Init(svc.ctx, host)
+			if err != nil {
+				logger.Logger.WithField("plugin", plugin.Name()).WithError(err).Error("Failed to initialize plugin")
+				continue
+			}
+		}
+		if subscriber, ok := plugin.(events.EventSubscriber); ok {
+			svc.eventPublisher.RegisterSubscriber(subscriber)
+		}
+	}
+}
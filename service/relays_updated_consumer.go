@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+type relaysUpdatedConsumer struct {
+	events.EventSubscriber
+	svc *service
+}
+
+// When the relays are changed in the settings, publish the nip47 info events to the new relays
+func (s *relaysUpdatedConsumer) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	if event.Event != "nwc_relays_updated" {
+		return
+	}
+	logger.Logger.WithField("relay_urls", s.svc.cfg.GetRelayUrls()).Info("Relays updated")
+	s.svc.publishAllAppInfoEvents()
+}
+
+// resubscribeConsumer cancels a wallet subscription when the relays are changed,
+// so that it is created again on the new relays without a restart
+type resubscribeConsumer struct {
+	events.EventSubscriber
+	cancelSubscription func()
+	resubscribe        atomic.Bool
+}
+
+func (s *resubscribeConsumer) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	if event.Event != "nwc_relays_updated" {
+		return
+	}
+	s.resubscribe.Store(true)
+	s.cancelSubscription()
+}
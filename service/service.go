@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,17 +19,28 @@ import (
 	"github.com/kelseyhightower/envconfig"
 
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/eventhooks"
 	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/fiatrates"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
 	"github.com/getAlby/hub/service/keys"
 	"github.com/getAlby/hub/swaps"
+	"github.com/getAlby/hub/telegram"
+	"github.com/getAlby/hub/tracing"
 	"github.com/getAlby/hub/transactions"
+	"github.com/getAlby/hub/unlocksecret"
 	"github.com/getAlby/hub/version"
+	"github.com/getAlby/hub/webhooks"
 
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/nip47"
+	"github.com/getAlby/hub/notifications"
+	"github.com/getAlby/hub/plugins"
 )
 
 type service struct {
@@ -42,10 +56,15 @@ type service struct {
 	ctx                 context.Context
 	wg                  *sync.WaitGroup
 	nip47Service        nip47.Nip47Service
+	deadLetterSvc       deadletters.DeadLetterService
 	appCancelFn         context.CancelFunc
 	keys                keys.Keys
 	relayStatuses       []RelayStatus
 	startupState        string
+	shutdownTracing     func(context.Context) error
+	// NIP-47 requests being handled, which shutdown waits for
+	nip47RequestsMtx sync.Mutex
+	nip47Requests    sync.WaitGroup
 }
 
 func NewService(ctx context.Context) (*service, error) {
@@ -89,7 +108,29 @@ func NewService(ctx context.Context) (*service, error) {
 		}
 	}
 
-	gormDB, err := db.NewDB(appConfig.DatabaseUri, appConfig.LogDBQueries)
+	// litestream replicates sqlite files, postgres has its own replication
+	dbPath, isSqlite := db.SqlitePath(appConfig.DatabaseUri)
+	switch appConfig.DatabaseReplication {
+	case "":
+	case db.ReplicationLitestream:
+		if !isSqlite {
+			return nil, errors.New("DATABASE_REPLICATION=litestream requires a sqlite database file")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_REPLICATION: %s", appConfig.DatabaseReplication)
+	}
+
+	transactions.SetWatchOnly(appConfig.WatchOnly)
+
+	gormDB, err := db.NewDBWithConfig(&db.Config{
+		URI:                appConfig.DatabaseUri,
+		LogQueries:         appConfig.LogDBQueries,
+		MaxOpenConns:       int(appConfig.DatabaseMaxOpenConns),
+		MaxIdleConns:       int(appConfig.DatabaseMaxIdleConns),
+		ConnMaxLifetime:    time.Duration(appConfig.DatabaseConnMaxLifetimeMinutes) * time.Minute,
+		MigrationSnapshots: appConfig.DatabaseMigrationSnapshots,
+		Replicated:         appConfig.DatabaseReplication != "",
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +140,13 @@ func NewService(ctx context.Context) (*service, error) {
 		return nil, err
 	}
 
+	// a log level changed in the settings takes precedence over LOG_LEVEL
+	if logLevel, _ := cfg.Get(config.LogLevelKey, ""); logLevel != "" {
+		if level, err := strconv.Atoi(logLevel); err == nil {
+			logger.SetLevel(logrus.Level(level))
+		}
+	}
+
 	// write auto unlock password from env to user config
 	if appConfig.AutoUnlockPassword != "" {
 		err = cfg.SetUpdate("AutoUnlockPassword", appConfig.AutoUnlockPassword, "")
@@ -110,12 +158,35 @@ func NewService(ctx context.Context) (*service, error) {
 	if err != nil {
 		return nil, err
 	}
+	autoUnlockSource := "auto_unlock_password"
+	if appConfig.UnlockSecretSource != "" {
+		// the password is fetched on every start and never written to disk
+		autoUnlockSource = appConfig.UnlockSecretSource
+		autoUnlockPassword, err = unlocksecret.Fetch(ctx, appConfig)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to fetch unlock secret, the hub has to be unlocked manually")
+		}
+	}
+
+	var shutdownTracing func(context.Context) error
+	if appConfig.TracingEnabled {
+		shutdownTracing, err = tracing.Init(ctx)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to initialize tracing")
+			return nil, err
+		}
+	}
 
 	eventPublisher := events.NewEventPublisher()
 
 	keys := keys.NewKeys()
 
-	albySvc := alby.NewAlbyService(cfg)
+	fiatRatesSvc, err := fiatrates.NewFiatRatesService(cfg)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to configure fiat rate providers")
+		return nil, err
+	}
+	albySvc := alby.NewAlbyService(cfg, fiatRatesSvc)
 	albyOAuthSvc := alby.NewAlbyOAuthService(gormDB, cfg, keys, eventPublisher)
 
 	transactionsSvc := transactions.NewTransactionsService(gormDB, eventPublisher)
@@ -129,17 +200,63 @@ func NewService(ctx context.Context) (*service, error) {
 		albySvc:             albySvc,
 		albyOAuthSvc:        albyOAuthSvc,
 		nip47Service:        nip47.NewNip47Service(gormDB, cfg, keys, eventPublisher, albyOAuthSvc),
+		deadLetterSvc:       deadletters.NewDeadLetterService(gormDB),
 		transactionsService: transactionsSvc,
 		db:                  gormDB,
 		keys:                keys,
+		shutdownTracing:     shutdownTracing,
 	}
 
 	eventPublisher.RegisterSubscriber(svc.transactionsService)
-	eventPublisher.RegisterSubscriber(svc.nip47Service)
-	eventPublisher.RegisterSubscriber(svc.albyOAuthSvc)
+	// a watch-only hub runs next to the hub it watches, which already notifies apps, webhooks and the Alby account
+	if !appConfig.WatchOnly {
+		eventPublisher.RegisterSubscriber(svc.nip47Service)
+		svc.deadLetterSvc.RegisterReplayer(deadletters.CONSUMER_NIP47_NOTIFICATIONS, svc.nip47Service)
+		eventPublisher.RegisterSubscriber(svc.albyOAuthSvc)
+	}
 	eventPublisher.RegisterSubscriber(&paymentForwardedConsumer{
 		db: gormDB,
 	})
+	if !appConfig.WatchOnly {
+		webhooksSvc := webhooks.NewWebhooksService(gormDB)
+		eventPublisher.RegisterSubscriber(webhooksSvc)
+		svc.deadLetterSvc.RegisterReplayer(deadletters.CONSUMER_WEBHOOKS, webhooksSvc)
+		pushNotifier := notifications.NewPushNotifier(gormDB, appConfig)
+		eventPublisher.RegisterSubscriber(pushNotifier)
+		svc.deadLetterSvc.RegisterReplayer(deadletters.CONSUMER_PUSH, pushNotifier)
+		if notifications.IsConfigured(appConfig) {
+			emailNotifier := notifications.NewEmailNotifier(gormDB, cfg, svc.GetLNClient)
+			emailNotifier.Start(ctx)
+			eventPublisher.RegisterSubscriber(emailNotifier)
+			svc.deadLetterSvc.RegisterReplayer(deadletters.CONSUMER_EMAIL, emailNotifier)
+		}
+		if telegram.IsConfigured(appConfig) {
+			telegramBot, err := telegram.NewTelegramBot(gormDB, apps.NewAppsService(gormDB, eventPublisher, keys, cfg), transactionsSvc, appConfig, svc.GetLNClient)
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to create telegram bot")
+				return nil, err
+			}
+			telegramBot.Start(ctx)
+			eventPublisher.RegisterSubscriber(telegramBot)
+		}
+		if appConfig.EventHooks != "" {
+			hooks, err := eventhooks.ParseHooks(appConfig.EventHooks)
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to parse event hooks")
+				return nil, err
+			}
+			eventHooks := eventhooks.NewEventHooks(gormDB, hooks)
+			eventPublisher.RegisterSubscriber(eventHooks)
+			svc.deadLetterSvc.RegisterReplayer(deadletters.CONSUMER_EVENT_HOOKS, eventHooks)
+		}
+	}
+	svc.deadLetterSvc.Start(ctx)
+	eventPublisher.RegisterSubscriber(metrics.NewEventConsumer())
+	eventPublisher.RegisterSubscriber(newFiatRatesConsumer(cfg, fiatRatesSvc, transactionsSvc))
+	transactions.SetFiatRateProvider(fiatRatesSvc)
+
+	plugins.StartExternalPlugins(ctx, appConfig.Plugins, version.Tag)
+	svc.initPlugins()
 
 	eventPublisher.Publish(&events.Event{
 		Event: "nwc_started",
@@ -148,6 +265,14 @@ func NewService(ctx context.Context) (*service, error) {
 		},
 	})
 
+	if appConfig.DatabaseReplication != "" {
+		err = detectDatabaseRestore(cfg, eventPublisher, dbPath)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to check if the database was restored")
+			return nil, err
+		}
+	}
+
 	if appConfig.GoProfilerAddr != "" {
 		startProfiler(ctx, appConfig.GoProfilerAddr)
 	}
@@ -155,7 +280,18 @@ func NewService(ctx context.Context) (*service, error) {
 	if autoUnlockPassword != "" {
 		nodeLastStartTime, _ := cfg.Get("NodeLastStartTime", "")
 		if nodeLastStartTime != "" {
-			svc.StartApp(autoUnlockPassword)
+			err = svc.StartApp(autoUnlockPassword)
+			if err != nil {
+				logger.Logger.WithError(err).WithField("source", autoUnlockSource).Error("Failed to auto-unlock")
+			} else {
+				logger.Logger.WithField("source", autoUnlockSource).Info("Auto-unlocked hub")
+				eventPublisher.Publish(&events.Event{
+					Event: "nwc_auto_unlocked",
+					Properties: map[string]interface{}{
+						"source": autoUnlockSource,
+					},
+				})
+			}
 		}
 	}
 
@@ -224,11 +360,31 @@ func finishRestoreNode(workDir string) error {
 }
 
 func (svc *service) Shutdown() {
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(svc.cfg.GetEnv().ShutdownTimeoutSeconds)*time.Second)
+	defer cancelDrain()
+	svc.drain(drainCtx)
+
 	svc.StopApp()
 	svc.eventPublisher.PublishSync(&events.Event{
 		Event: "nwc_stopped",
 	})
+	if flusher, ok := svc.eventPublisher.(events.Flusher); ok {
+		// subscribers such as webhooks still write to the database
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFlush()
+		if err := flusher.Flush(flushCtx); err != nil {
+			logger.Logger.WithError(err).Warn("Timed out waiting for events to be consumed")
+		}
+	}
 	db.Stop(svc.db)
+	if svc.shutdownTracing != nil {
+		// the app context is already cancelled at this point
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := svc.shutdownTracing(ctx); err != nil {
+			logger.Logger.WithError(err).Error("Failed to flush traces")
+		}
+	}
 }
 
 func (svc *service) GetDB() *gorm.DB {
@@ -251,6 +407,10 @@ func (svc *service) GetNip47Service() nip47.Nip47Service {
 	return svc.nip47Service
 }
 
+func (svc *service) GetDeadLetterService() deadletters.DeadLetterService {
+	return svc.deadLetterSvc
+}
+
 func (svc *service) GetEventPublisher() events.EventPublisher {
 	return svc.eventPublisher
 }
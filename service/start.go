@@ -9,8 +9,21 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/autolock"
+	"github.com/getAlby/hub/backups"
+	"github.com/getAlby/hub/balancealerts"
 	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/feeadvisor"
+	"github.com/getAlby/hub/feepolicies"
+	"github.com/getAlby/hub/forceclose"
+	"github.com/getAlby/hub/liquidityhistory"
+	"github.com/getAlby/hub/lsporders"
 	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/scheduledpayments"
+	"github.com/getAlby/hub/subwallets"
+	"github.com/getAlby/hub/swaprules"
 	"github.com/getAlby/hub/swaps"
 	"github.com/getAlby/hub/version"
 
@@ -18,6 +31,7 @@ import (
 	"github.com/nbd-wtf/go-nostr/nip19"
 	"github.com/sirupsen/logrus"
 
+	"github.com/getAlby/hub/channelacceptance"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
@@ -26,7 +40,12 @@ import (
 	"github.com/getAlby/hub/lnclient/ldk"
 	"github.com/getAlby/hub/lnclient/lnd"
 	"github.com/getAlby/hub/lnclient/phoenixd"
+	"github.com/getAlby/hub/lnclient/watchonly"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/maintenance"
+	"github.com/getAlby/hub/notifications"
+	"github.com/getAlby/hub/recovery"
+	"github.com/getAlby/hub/service/keys"
 )
 
 func (svc *service) startNostr(ctx context.Context) error {
@@ -83,6 +102,11 @@ func (svc *service) startNostr(ctx context.Context) error {
 
 	svc.nip47Service.StartNotifier(ctx, pool)
 	svc.nip47Service.StartNip47InfoPublisher(ctx, pool, svc.lnClient)
+	apps.NewAppsService(svc.db, svc.eventPublisher, svc.keys, svc.cfg).StartNostrProfilesRefresh(ctx, pool)
+	backups.NewNostrBackupPublisher(svc.db, svc.cfg, svc.keys, svc.eventPublisher).Start(ctx, pool)
+	nostrDMNotifier := notifications.NewNostrDMNotifier(svc.db, svc.cfg, svc.keys, svc.eventPublisher)
+	nostrDMNotifier.Start(ctx, pool)
+	svc.deadLetterSvc.RegisterReplayer(deadletters.CONSUMER_NOSTR_DM, nostrDMNotifier)
 
 	// register a subscriber for events of "nwc_app_created" which handles creation of nostr subscription for new app
 	createAppEventListener := &createAppConsumer{svc: svc, pool: pool}
@@ -92,6 +116,10 @@ func (svc *service) startNostr(ctx context.Context) error {
 	updateAppEventListener := &updateAppConsumer{svc: svc}
 	svc.eventPublisher.RegisterSubscriber(updateAppEventListener)
 
+	// register a subscriber for events of "nwc_relays_updated" which publishes the nip47 info events to the new relays
+	relaysUpdatedEventListener := &relaysUpdatedConsumer{svc: svc}
+	svc.eventPublisher.RegisterSubscriber(relaysUpdatedEventListener)
+
 	// start each app wallet subscription which have a child derived wallet key
 	svc.startAllExistingAppsWalletSubscriptions(ctx, pool)
 
@@ -119,6 +147,7 @@ func (svc *service) startNostr(ctx context.Context) error {
 
 		svc.eventPublisher.RemoveSubscriber(createAppEventListener)
 		svc.eventPublisher.RemoveSubscriber(updateAppEventListener)
+		svc.eventPublisher.RemoveSubscriber(relaysUpdatedEventListener)
 	}()
 
 	return nil
@@ -206,9 +235,17 @@ func (svc *service) startAppWalletSubscription(ctx context.Context, pool *nostr.
 
 		svc.eventPublisher.RegisterSubscriber(&deleteAppSubscriber)
 
+		resubscribeSubscriber := &resubscribeConsumer{cancelSubscription: cancelSubscription}
+		svc.eventPublisher.RegisterSubscriber(resubscribeSubscriber)
+
 		err := svc.watchSubscription(subCtx, pool, eventsChannel)
 
 		svc.eventPublisher.RemoveSubscriber(&deleteAppSubscriber)
+		svc.eventPublisher.RemoveSubscriber(resubscribeSubscriber)
+		if resubscribeSubscriber.resubscribe.Load() && ctx.Err() == nil {
+			logger.Logger.WithField("wallet_pubkey", appWalletPubKey).Info("Relays updated, resubscribing")
+			continue
+		}
 		if err != nil {
 			logger.Logger.WithError(err).Error("got an error from the relay while listening to subscription, resubscribing")
 			time.Sleep(3 * time.Second)
@@ -224,7 +261,14 @@ func (svc *service) watchSubscription(ctx context.Context, pool *nostr.SimplePoo
 	go func() {
 		// loop through incoming events
 		for event := range eventsChannel {
-			go svc.nip47Service.HandleEvent(ctx, pool, event.Event, svc.lnClient)
+			if !svc.startNip47Request() {
+				logger.Logger.WithField("event_id", event.Event.ID).Info("Ignoring NIP-47 request while shutting down")
+				continue
+			}
+			go func(event *nostr.Event) {
+				defer svc.nip47Requests.Done()
+				svc.nip47Service.HandleEvent(ctx, pool, event, svc.lnClient)
+			}(event.Event)
 		}
 		logger.Logger.Debug("Relay subscription events channel ended")
 		eventsChannelClosed <- struct{}{}
@@ -242,6 +286,17 @@ func (svc *service) watchSubscription(ctx context.Context, pool *nostr.SimplePoo
 	}
 }
 
+// startNip47Request registers a NIP-47 request, unless the service is shutting down
+func (svc *service) startNip47Request() bool {
+	svc.nip47RequestsMtx.Lock()
+	defer svc.nip47RequestsMtx.Unlock()
+	if svc.ctx.Err() != nil {
+		return false
+	}
+	svc.nip47Requests.Add(1)
+	return true
+}
+
 func (svc *service) StartApp(encryptionKey string) error {
 	defer func() {
 		svc.startupState = ""
@@ -259,12 +314,18 @@ func (svc *service) StartApp(encryptionKey string) error {
 	if svc.lnClient != nil {
 		return errors.New("app already started")
 	}
+	if err := backups.CheckMigrationMarker(svc.cfg.GetEnv().Workdir); err != nil {
+		logger.Logger.WithError(err).Error("Refusing to start migrated hub")
+		return err
+	}
 	if !svc.cfg.CheckUnlockPassword(encryptionKey) {
 		logger.Logger.Errorf("Invalid password")
 		return errors.New("invalid password")
 	}
 
-	ctx, cancelFn := context.WithCancel(svc.ctx)
+	// the app keeps running when the service context is cancelled until Shutdown
+	// stopped it, so that payments in flight can complete
+	ctx, cancelFn := context.WithCancel(context.WithoutCancel(svc.ctx))
 
 	err = svc.keys.Init(svc.cfg, encryptionKey)
 	if err != nil {
@@ -273,6 +334,13 @@ func (svc *service) StartApp(encryptionKey string) error {
 		return err
 	}
 
+	err = svc.initDatabaseEncryption(encryptionKey)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to init database encryption")
+		cancelFn()
+		return err
+	}
+
 	svc.startupState = "Launching Node"
 	err = svc.launchLNBackend(ctx, encryptionKey)
 	if err != nil {
@@ -284,8 +352,55 @@ func (svc *service) StartApp(encryptionKey string) error {
 		return err
 	}
 
+	if svc.cfg.GetEnv().WatchOnly {
+		// the watched hub runs the background jobs and answers NIP-47 requests
+		logger.Logger.Info("Started in watch-only mode")
+		svc.appCancelFn = cancelFn
+		return nil
+	}
+
 	svc.swapsService = swaps.NewSwapsService(ctx, svc.db, svc.cfg, svc.keys, svc.eventPublisher, svc.lnClient, svc.transactionsService)
 
+	scheduledpayments.NewScheduledPaymentsService(svc.db, svc.eventPublisher).Start(ctx, svc.lnClient, svc.transactionsService)
+	swaprules.NewSwapRulesService(svc.db, svc.cfg, svc.eventPublisher).Start(ctx, svc.lnClient, svc.swapsService)
+	feepolicies.NewFeePoliciesService(svc.db, svc.eventPublisher).Start(ctx, svc.lnClient)
+	liquidityhistory.NewLiquidityHistoryService(svc.db).Start(ctx, svc.lnClient)
+	channelacceptance.NewChannelAcceptanceService(svc.cfg, svc.eventPublisher).Start(svc.lnClient)
+	lsporders.NewLSPOrdersService(svc.db, svc.cfg, svc.eventPublisher, svc.albyOAuthSvc).Start(ctx)
+	subwallets.NewSubwalletsService(svc.db, svc.cfg, svc.eventPublisher).Start(ctx)
+	// only the key derived for the backups is kept, not the unlock password
+	backupKey, err := backups.GetBackupKey(svc.cfg, encryptionKey)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to derive backup key, scheduled backups are disabled")
+	} else {
+		backups.NewBackupsService(svc.db, svc.cfg, svc.eventPublisher).Start(ctx, backupKey)
+	}
+	maintenanceSvc := maintenance.NewMaintenanceService(svc.db, svc.cfg, svc.eventPublisher)
+	maintenanceSvc.Start(ctx)
+	maintenanceSvc.ResumeKeyRotation(ctx, encryptionKey)
+	recovery.NewRecoveryService(svc.cfg, svc.eventPublisher).Start(ctx, svc.lnClient)
+	balancealerts.NewBalanceAlertsService(svc.cfg, svc.eventPublisher).Start(ctx, svc.lnClient)
+	forceclose.NewForceCloseService(svc.db, svc.eventPublisher).Start(ctx, svc.lnClient)
+	feeadvisor.NewFeeAdvisorService(svc.cfg, svc.eventPublisher).Start(ctx)
+	appsSvc := apps.NewAppsService(svc.db, svc.eventPublisher, svc.keys, svc.cfg)
+	appsSvc.StartExpiryNotifications(ctx)
+	appsSvc.StartAppTemplatesRefresh(ctx)
+	appsSvc.StartActivityLogPruning(ctx)
+	svc.transactionsService.StartInvoiceExpirySweep(ctx)
+	autolock.Start(ctx, time.Duration(svc.cfg.GetEnv().AutoLockMinutes)*time.Minute, func() {
+		if lockableKeys, ok := svc.keys.(keys.Lockable); ok {
+			// connected apps with their own wallet key keep receiving
+			var appIds []uint
+			if err := svc.db.Model(&db.App{}).Where("wallet_pubkey IS NOT NULL").Pluck("id", &appIds).Error; err != nil {
+				logger.Logger.WithError(err).Error("Failed to list apps to lock keys")
+			}
+			lockableKeys.Lock(appIds)
+		}
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_spending_locked",
+		})
+	})
+
 	svc.publishAllAppInfoEvents()
 
 	svc.startupState = "Connecting To Relay"
@@ -318,6 +433,12 @@ func (svc *service) launchLNBackend(ctx context.Context, encryptionKey string) e
 		return errors.New("no LNBackendType specified")
 	}
 
+	// the node state of embedded backends is not part of the database, and the same node
+	// must never run twice
+	if svc.cfg.GetEnv().WatchOnly && (lnBackend == config.LDKBackendType || lnBackend == config.CashuBackendType) {
+		return fmt.Errorf("watch-only mode is not supported with the %s backend", lnBackend)
+	}
+
 	logger.Logger.Infof("Launching LN Backend: %s", lnBackend)
 	var lnClient lnclient.LNClient
 	var err error
@@ -366,6 +487,9 @@ func (svc *service) launchLNBackend(ctx context.Context, encryptionKey string) e
 		logger.Logger.WithError(err).Error("Failed to launch LN backend")
 		return err
 	}
+	if svc.cfg.GetEnv().WatchOnly {
+		lnClient = watchonly.NewWatchOnlyService(lnClient)
+	}
 
 	// TODO: call a method on the LNClient here to check the LNClient is actually connectable,
 	// (e.g. lnClient.CheckConnection()) Rather than it being a side-effect
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/getAlby/hub/events"
@@ -16,6 +17,36 @@ func (svc *service) StopApp() {
 	}
 }
 
+// drain waits until the NIP-47 requests and payments in flight are done, so that the node
+// is not stopped in the middle of a payment. NIP-47 requests received after the service
+// context was cancelled are ignored.
+func (svc *service) drain(ctx context.Context) {
+	if svc.appCancelFn == nil {
+		return
+	}
+	logger.Logger.Info("Waiting for requests and payments in flight...")
+
+	// no request is added anymore once the service context is cancelled
+	svc.nip47RequestsMtx.Lock()
+	svc.nip47RequestsMtx.Unlock()
+	requestsDone := make(chan struct{})
+	go func() {
+		svc.nip47Requests.Wait()
+		close(requestsDone)
+	}()
+	select {
+	case <-requestsDone:
+	case <-ctx.Done():
+		logger.Logger.Warn("Timed out waiting for NIP-47 requests")
+	}
+
+	if pendingPayments := svc.transactionsService.WaitForPayments(ctx); pendingPayments > 0 {
+		logger.Logger.WithField("pending_payments", pendingPayments).Warn("Stopping with payments in flight")
+		return
+	}
+	logger.Logger.Info("Requests and payments in flight are done")
+}
+
 func (svc *service) stopLNClient() {
 	defer svc.wg.Done()
 	if svc.lnClient == nil {
@@ -0,0 +1,93 @@
+// Package spendablebalance computes the total spendable balance from the buckets the user chose
+// to count, so the balances API and NIP-47 get_balance report the same amount on every backend.
+package spendablebalance
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/lnclient"
+)
+
+const (
+	// trusted on-chain balance which can be spent right away
+	BUCKET_ONCHAIN = "onchain"
+	// balance spendable in channels, or in VTXOs for Ark backends
+	BUCKET_LIGHTNING = "lightning"
+	// Ark balance waiting for a round or board to complete
+	BUCKET_PENDING_ARK = "pending_ark"
+	// ecash tokens held with a mint
+	BUCKET_ECASH = "ecash"
+)
+
+var allBuckets = []string{BUCKET_ONCHAIN, BUCKET_LIGHTNING, BUCKET_PENDING_ARK, BUCKET_ECASH}
+
+// SpendableBalance is the total of the counted buckets, with the amount of every bucket (msat)
+type SpendableBalance struct {
+	Total   int64            `json:"total"`
+	Buckets map[string]int64 `json:"buckets"`
+	Counted []string         `json:"counted"`
+}
+
+// GetBuckets returns the buckets which count towards the total spendable balance
+func GetBuckets(cfg config.Config) []string {
+	value := config.GetStringSetting(cfg, config.SpendableBalanceBucketsKey, cfg.GetEnv().SpendableBalanceBuckets)
+	buckets, err := ParseBuckets(value)
+	if err != nil || len(buckets) == 0 {
+		// fall back to the lightning balance, which is what every backend reported before
+		return []string{BUCKET_LIGHTNING, BUCKET_ECASH}
+	}
+	return buckets
+}
+
+// ParseBuckets parses a comma-separated list of buckets
+func ParseBuckets(value string) ([]string, error) {
+	buckets := []string{}
+	for _, bucket := range strings.Split(value, ",") {
+		bucket = strings.TrimSpace(bucket)
+		if bucket == "" {
+			continue
+		}
+		if err := ValidateBucket(bucket); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(buckets, bucket) {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets, nil
+}
+
+func ValidateBucket(bucket string) error {
+	if !slices.Contains(allBuckets, bucket) {
+		return fmt.Errorf("unknown spendable balance bucket %q", bucket)
+	}
+	return nil
+}
+
+// Compute splits the balances reported by the backend into buckets and adds up the counted ones
+func Compute(balances *lnclient.BalancesResponse, buckets []string) *SpendableBalance {
+	// ecash is reported as part of the lightning balance, since it is spent over lightning
+	lightning := max(balances.Lightning.TotalSpendable-balances.Ecash, 0)
+
+	spendable := &SpendableBalance{
+		Buckets: map[string]int64{
+			BUCKET_ONCHAIN:     balances.Onchain.Spendable,
+			BUCKET_LIGHTNING:   lightning,
+			BUCKET_PENDING_ARK: balances.PendingArk,
+			BUCKET_ECASH:       balances.Ecash,
+		},
+		Counted: buckets,
+	}
+	for _, bucket := range buckets {
+		spendable.Total += spendable.Buckets[bucket]
+	}
+	return spendable
+}
+
+// GetSpendableBalance computes the spendable balance with the configured buckets
+func GetSpendableBalance(cfg config.Config, balances *lnclient.BalancesResponse) *SpendableBalance {
+	return Compute(balances, GetBuckets(cfg))
+}
@@ -0,0 +1,61 @@
+package spendablebalance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestCompute(t *testing.T) {
+	balances := &lnclient.BalancesResponse{
+		Onchain: lnclient.OnchainBalanceResponse{
+			Spendable: 50_000,
+		},
+		Lightning: lnclient.LightningBalanceResponse{
+			TotalSpendable: 30_000,
+		},
+		PendingArk: 7_000,
+		Ecash:      10_000,
+	}
+
+	spendable := Compute(balances, []string{BUCKET_LIGHTNING})
+	assert.Equal(t, int64(20_000), spendable.Total)
+	assert.Equal(t, map[string]int64{
+		BUCKET_ONCHAIN:     50_000,
+		BUCKET_LIGHTNING:   20_000,
+		BUCKET_PENDING_ARK: 7_000,
+		BUCKET_ECASH:       10_000,
+	}, spendable.Buckets)
+
+	spendable = Compute(balances, []string{BUCKET_LIGHTNING, BUCKET_ECASH})
+	assert.Equal(t, int64(30_000), spendable.Total)
+
+	spendable = Compute(balances, allBuckets)
+	assert.Equal(t, int64(87_000), spendable.Total)
+}
+
+func TestParseBuckets(t *testing.T) {
+	buckets, err := ParseBuckets(" onchain, lightning,,onchain ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{BUCKET_ONCHAIN, BUCKET_LIGHTNING}, buckets)
+
+	_, err = ParseBuckets("lightning,savings")
+	assert.EqualError(t, err, `unknown spendable balance bucket "savings"`)
+}
+
+func TestGetBuckets(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	assert.Equal(t, []string{BUCKET_LIGHTNING, BUCKET_ECASH}, GetBuckets(svc.Cfg))
+
+	err = svc.Cfg.SetUpdate(config.SpendableBalanceBucketsKey, "onchain,lightning", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{BUCKET_ONCHAIN, BUCKET_LIGHTNING}, GetBuckets(svc.Cfg))
+}
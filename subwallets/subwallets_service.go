@@ -0,0 +1,291 @@
+package subwallets
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const depositSyncInterval = 5 * time.Minute
+
+const minOwnerPasswordLength = 8
+
+// fetchAddressStats is a variable so it can be replaced in tests
+var fetchAddressStats = fetchEsploraAddressStats
+
+type addressStats struct {
+	ChainStats struct {
+		FundedTxoSum uint64 `json:"funded_txo_sum"`
+	} `json:"chain_stats"`
+}
+
+// SubwalletsService extends isolated apps into sub-wallets with their own
+// on-chain deposit addresses and an optional owner login.
+type SubwalletsService interface {
+	CreateDepositAddress(ctx context.Context, appId uint, lnClient lnclient.LNClient) (*db.SubwalletAddress, error)
+	ListDepositAddresses(appId uint) ([]db.SubwalletAddress, error)
+	SyncDeposits(ctx context.Context) error
+	SetOwnerPassword(appId uint, password string) error
+	CheckOwnerPassword(appId uint, password string) bool
+	Start(ctx context.Context)
+}
+
+type subwalletsService struct {
+	db             *gorm.DB
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+}
+
+func NewSubwalletsService(db *gorm.DB, cfg config.Config, eventPublisher events.EventPublisher) *subwalletsService {
+	return &subwalletsService{
+		db:             db,
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (svc *subwalletsService) getSubwallet(appId uint) (*db.App, error) {
+	var app db.App
+	if svc.db.Limit(1).Find(&app, &db.App{ID: appId}).RowsAffected == 0 {
+		return nil, errors.New("app not found")
+	}
+	if !app.Isolated {
+		return nil, errors.New("app is not isolated")
+	}
+	return &app, nil
+}
+
+func (svc *subwalletsService) CreateDepositAddress(ctx context.Context, appId uint, lnClient lnclient.LNClient) (*db.SubwalletAddress, error) {
+	if _, err := svc.getSubwallet(appId); err != nil {
+		return nil, err
+	}
+
+	address, err := lnClient.GetNewOnchainAddress(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create on-chain address: %w", err)
+	}
+
+	subwalletAddress := db.SubwalletAddress{
+		AppId:   appId,
+		Address: address,
+	}
+	if err := svc.db.Create(&subwalletAddress).Error; err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":  appId,
+		"address": address,
+	}).Info("Created sub-wallet deposit address")
+
+	return &subwalletAddress, nil
+}
+
+func (svc *subwalletsService) ListDepositAddresses(appId uint) ([]db.SubwalletAddress, error) {
+	addresses := []db.SubwalletAddress{}
+	err := svc.db.Where("app_id = ?", appId).Order("id DESC").Find(&addresses).Error
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// Start periodically credits confirmed on-chain deposits until the context is cancelled
+func (svc *subwalletsService) Start(ctx context.Context) {
+	health.RegisterJob("subwallet_deposit_sync", depositSyncInterval)
+	go func() {
+		defer health.RemoveJob("subwallet_deposit_sync")
+		ticker := time.NewTicker(depositSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := svc.SyncDeposits(ctx)
+				if err != nil {
+					logger.Logger.WithError(err).Error("Failed to sync sub-wallet deposits")
+				}
+				health.ReportJobRun("subwallet_deposit_sync", err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// SyncDeposits credits newly confirmed funds received on deposit addresses to the balance of their app.
+// The funds are held in the node's on-chain wallet, the sub-wallet balance only tracks ownership.
+func (svc *subwalletsService) SyncDeposits(ctx context.Context) error {
+	var addresses []db.SubwalletAddress
+	if err := svc.db.Find(&addresses).Error; err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		stats, err := fetchAddressStats(ctx, svc.cfg.GetEnv().LDKEsploraServer, address.Address)
+		if err != nil {
+			logger.Logger.WithError(err).WithField("address", address.Address).Error("Failed to fetch deposit address stats")
+			continue
+		}
+		if stats.ChainStats.FundedTxoSum <= address.ReceivedSat {
+			continue
+		}
+
+		if err := svc.creditDeposit(&address, stats.ChainStats.FundedTxoSum); err != nil {
+			logger.Logger.WithError(err).WithField("address", address.Address).Error("Failed to credit sub-wallet deposit")
+		}
+	}
+	return nil
+}
+
+func (svc *subwalletsService) creditDeposit(address *db.SubwalletAddress, fundedSat uint64) error {
+	var transaction db.Transaction
+	err := svc.db.Transaction(func(tx *gorm.DB) error {
+		// only credit the difference if no other sync credited it first
+		result := tx.Model(&db.SubwalletAddress{}).
+			Where("id = ? AND received_sat = ?", address.ID, address.ReceivedSat).
+			Update("received_sat", fundedSat)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		metadata, err := json.Marshal(map[string]interface{}{
+			"onchain_address": address.Address,
+		})
+		if err != nil {
+			return err
+		}
+
+		// deposits have no payment hash, derive a unique one from the address and total received
+		paymentHash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", address.Address, fundedSat)))
+		now := time.Now()
+		transaction = db.Transaction{
+			AppId:       &address.AppId,
+			Type:        constants.TRANSACTION_TYPE_INCOMING,
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			AmountMsat:  (fundedSat - address.ReceivedSat) * 1000,
+			PaymentHash: hex.EncodeToString(paymentHash[:]),
+			Description: "On-chain deposit",
+			SettledAt:   &now,
+			Metadata:    datatypes.JSON(metadata),
+		}
+		return tx.Create(&transaction).Error
+	})
+	if err != nil || transaction.ID == 0 {
+		return err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":      address.AppId,
+		"address":     address.Address,
+		"amount_msat": transaction.AmountMsat,
+	}).Info("Credited sub-wallet deposit")
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event:      "nwc_subwallet_deposit_received",
+		Properties: &transaction,
+	})
+	return nil
+}
+
+// SetOwnerPassword enables the owner login of a sub-wallet. An empty password disables it.
+func (svc *subwalletsService) SetOwnerPassword(appId uint, password string) error {
+	app, err := svc.getSubwallet(appId)
+	if err != nil {
+		return err
+	}
+	if app.ReceiveOnly && password != "" {
+		return errors.New("receive-only sub-wallets cannot have an owner login")
+	}
+
+	passwordHash := ""
+	if password != "" {
+		if len(password) < minOwnerPasswordLength {
+			return fmt.Errorf("password must be at least %d characters", minOwnerPasswordLength)
+		}
+		key, salt, err := config.DeriveKey(password, nil)
+		if err != nil {
+			return err
+		}
+		passwordHash = hex.EncodeToString(salt) + "-" + hex.EncodeToString(key)
+	}
+
+	return svc.db.Model(&db.App{}).Where("id = ?", appId).Update("owner_password_hash", passwordHash).Error
+}
+
+func (svc *subwalletsService) CheckOwnerPassword(appId uint, password string) bool {
+	app, err := svc.getSubwallet(appId)
+	if err != nil || app.OwnerPasswordHash == "" || password == "" {
+		return false
+	}
+
+	parts := strings.Split(app.OwnerPasswordHash, "-")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	expectedKey, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	key, _, err := config.DeriveKey(password, salt)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, expectedKey) == 1
+}
+
+func fetchEsploraAddressStats(ctx context.Context, esploraServer string, address string) (*addressStats, error) {
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, esploraServer+"/address/"+address, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("esplora API returned non-success code: %d %s", res.StatusCode, string(body))
+	}
+
+	var stats addressStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
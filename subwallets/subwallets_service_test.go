@@ -0,0 +1,119 @@
+package subwallets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/tests"
+)
+
+func createSubwallet(t *testing.T, svc *tests.TestService) *db.App {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	app.Isolated = true
+	require.NoError(t, svc.DB.Save(&app).Error)
+	return app
+}
+
+func TestCreateDepositAddress_NotIsolated(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	subwalletsSvc := NewSubwalletsService(svc.DB, svc.Cfg, svc.EventPublisher)
+	_, err = subwalletsSvc.CreateDepositAddress(context.TODO(), app.ID, svc.LNClient)
+	assert.EqualError(t, err, "app is not isolated")
+}
+
+func TestSyncDeposits(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	app := createSubwallet(t, svc)
+	subwalletsSvc := NewSubwalletsService(svc.DB, svc.Cfg, svc.EventPublisher)
+	address, err := subwalletsSvc.CreateDepositAddress(context.TODO(), app.ID, svc.LNClient)
+	require.NoError(t, err)
+	assert.Equal(t, tests.MockOnchainAddress, address.Address)
+
+	fundedSat := uint64(0)
+	originalFetchAddressStats := fetchAddressStats
+	defer func() { fetchAddressStats = originalFetchAddressStats }()
+	fetchAddressStats = func(ctx context.Context, esploraServer string, address string) (*addressStats, error) {
+		assert.Equal(t, tests.MockOnchainAddress, address)
+		stats := &addressStats{}
+		stats.ChainStats.FundedTxoSum = fundedSat
+		return stats, nil
+	}
+
+	// nothing received yet
+	require.NoError(t, subwalletsSvc.SyncDeposits(context.TODO()))
+	assert.Equal(t, int64(0), queries.GetIsolatedBalance(svc.DB, app.ID))
+
+	fundedSat = 10_000
+	require.NoError(t, subwalletsSvc.SyncDeposits(context.TODO()))
+	assert.Equal(t, int64(10_000_000), queries.GetIsolatedBalance(svc.DB, app.ID))
+
+	// already credited
+	require.NoError(t, subwalletsSvc.SyncDeposits(context.TODO()))
+	assert.Equal(t, int64(10_000_000), queries.GetIsolatedBalance(svc.DB, app.ID))
+
+	// a second deposit to the same address
+	fundedSat = 15_000
+	require.NoError(t, subwalletsSvc.SyncDeposits(context.TODO()))
+	assert.Equal(t, int64(15_000_000), queries.GetIsolatedBalance(svc.DB, app.ID))
+
+	var transactions []db.Transaction
+	require.NoError(t, svc.DB.Where("app_id = ?", app.ID).Order("id").Find(&transactions).Error)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, uint64(10_000_000), transactions[0].AmountMsat)
+	assert.Equal(t, uint64(5_000_000), transactions[1].AmountMsat)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transactions[1].State)
+	assert.NotEqual(t, transactions[0].PaymentHash, transactions[1].PaymentHash)
+
+	addresses, err := subwalletsSvc.ListDepositAddresses(app.ID)
+	require.NoError(t, err)
+	require.Len(t, addresses, 1)
+	assert.Equal(t, uint64(15_000), addresses[0].ReceivedSat)
+
+	depositEvents := 0
+	for _, event := range mockEventConsumer.GetConsumedEvents() {
+		if event.Event == "nwc_subwallet_deposit_received" {
+			depositEvents++
+		}
+	}
+	assert.Equal(t, 2, depositEvents)
+}
+
+func TestOwnerPassword(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createSubwallet(t, svc)
+	subwalletsSvc := NewSubwalletsService(svc.DB, svc.Cfg, svc.EventPublisher)
+
+	assert.False(t, subwalletsSvc.CheckOwnerPassword(app.ID, ""))
+
+	assert.Error(t, subwalletsSvc.SetOwnerPassword(app.ID, "short"))
+	require.NoError(t, subwalletsSvc.SetOwnerPassword(app.ID, "correct horse"))
+	assert.True(t, subwalletsSvc.CheckOwnerPassword(app.ID, "correct horse"))
+	assert.False(t, subwalletsSvc.CheckOwnerPassword(app.ID, "wrong horse"))
+	assert.False(t, subwalletsSvc.CheckOwnerPassword(app.ID+1, "correct horse"))
+
+	// disable the owner login
+	require.NoError(t, subwalletsSvc.SetOwnerPassword(app.ID, ""))
+	assert.False(t, subwalletsSvc.CheckOwnerPassword(app.ID, "correct horse"))
+}
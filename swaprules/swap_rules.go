@@ -0,0 +1,279 @@
+// Package swaprules runs user-defined rules which swap lightning funds out to an on-chain
+// address, e.g. to move the balance above a limit to cold storage or to keep some inbound
+// liquidity. Rules in dry-run mode only record the swaps they would have made.
+package swaprules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/feeadvisor"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/swaps"
+)
+
+const (
+	// RULE_TYPE_SWAP_OUT_EXCESS swaps out the lightning balance above the threshold
+	RULE_TYPE_SWAP_OUT_EXCESS = "swap_out_excess"
+	// RULE_TYPE_KEEP_INBOUND swaps out enough to keep at least the threshold as inbound liquidity
+	RULE_TYPE_KEEP_INBOUND = "keep_inbound"
+)
+
+// same as the interval of the auto swap configured in the settings
+var checkInterval = time.Hour
+
+type SwapRulesService interface {
+	CreateSwapRule(name string, ruleType string, thresholdSat uint64, destination string, minAmountSat uint64, dryRun bool) (*db.SwapRule, error)
+	UpdateSwapRule(id uint, enabled *bool, dryRun *bool) (*db.SwapRule, error)
+	ListSwapRules() ([]db.SwapRule, error)
+	ListSwapRuleRuns(id uint, limit uint64) ([]db.SwapRuleRun, error)
+	DeleteSwapRule(id uint) error
+	Start(ctx context.Context, lnClient lnclient.LNClient, swapsService swaps.SwapsService)
+}
+
+type swapRulesService struct {
+	db             *gorm.DB
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+}
+
+func NewSwapRulesService(db *gorm.DB, cfg config.Config, eventPublisher events.EventPublisher) *swapRulesService {
+	return &swapRulesService{
+		db:             db,
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (svc *swapRulesService) CreateSwapRule(name string, ruleType string, thresholdSat uint64, destination string, minAmountSat uint64, dryRun bool) (*db.SwapRule, error) {
+	if ruleType != RULE_TYPE_SWAP_OUT_EXCESS && ruleType != RULE_TYPE_KEEP_INBOUND {
+		return nil, errors.New("unknown swap rule type")
+	}
+	if ruleType == RULE_TYPE_KEEP_INBOUND && thresholdSat == 0 {
+		return nil, errors.New("threshold must be greater than zero")
+	}
+
+	swapRule := db.SwapRule{
+		Name:         strings.TrimSpace(name),
+		Type:         ruleType,
+		ThresholdSat: thresholdSat,
+		Destination:  strings.TrimSpace(destination),
+		MinAmountSat: minAmountSat,
+		DryRun:       dryRun,
+		Enabled:      true,
+	}
+	if err := svc.db.Create(&swapRule).Error; err != nil {
+		return nil, err
+	}
+	return &swapRule, nil
+}
+
+func (svc *swapRulesService) UpdateSwapRule(id uint, enabled *bool, dryRun *bool) (*db.SwapRule, error) {
+	var swapRule db.SwapRule
+	if svc.db.Limit(1).Find(&swapRule, &db.SwapRule{ID: id}).RowsAffected == 0 {
+		return nil, errors.New("swap rule not found")
+	}
+	if enabled != nil {
+		swapRule.Enabled = *enabled
+	}
+	if dryRun != nil {
+		swapRule.DryRun = *dryRun
+	}
+	if err := svc.db.Model(&swapRule).Select("enabled", "dry_run").Updates(&swapRule).Error; err != nil {
+		return nil, err
+	}
+	return &swapRule, nil
+}
+
+func (svc *swapRulesService) ListSwapRules() ([]db.SwapRule, error) {
+	swapRules := []db.SwapRule{}
+	if err := svc.db.Order("id").Find(&swapRules).Error; err != nil {
+		return nil, err
+	}
+	return swapRules, nil
+}
+
+// ListSwapRuleRuns returns the history of a rule, newest first
+func (svc *swapRulesService) ListSwapRuleRuns(id uint, limit uint64) ([]db.SwapRuleRun, error) {
+	swapRuleRuns := []db.SwapRuleRun{}
+	if err := svc.db.Where(&db.SwapRuleRun{SwapRuleId: id}).Order("id DESC").Limit(int(limit)).Find(&swapRuleRuns).Error; err != nil {
+		return nil, err
+	}
+	return swapRuleRuns, nil
+}
+
+func (svc *swapRulesService) DeleteSwapRule(id uint) error {
+	return svc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&db.SwapRuleRun{SwapRuleId: id}).Delete(&db.SwapRuleRun{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&db.SwapRule{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("swap rule not found")
+		}
+		return nil
+	})
+}
+
+// Start evaluates the enabled rules periodically until the context is cancelled
+func (svc *swapRulesService) Start(ctx context.Context, lnClient lnclient.LNClient, swapsService swaps.SwapsService) {
+	logger.Logger.Info("Starting swap rules")
+	health.RegisterJob("swap_rules", checkInterval)
+	go func() {
+		defer health.RemoveJob("swap_rules")
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				health.ReportJobRun("swap_rules", svc.runRules(ctx, lnClient, swapsService))
+			case <-ctx.Done():
+				logger.Logger.Info("Stopping swap rules")
+				return
+			}
+		}
+	}()
+}
+
+// runRules only returns an error if the rules could not be evaluated at all, failed swaps are
+// recorded in the history of the rule
+func (svc *swapRulesService) runRules(ctx context.Context, lnClient lnclient.LNClient, swapsService swaps.SwapsService) error {
+	swapRules := []db.SwapRule{}
+	if err := svc.db.Where("enabled = ?", true).Order("id").Find(&swapRules).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to list swap rules")
+		return err
+	}
+	if len(swapRules) == 0 {
+		return nil
+	}
+
+	swapOutInfo, err := swapsService.GetSwapOutInfo()
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get swap out info")
+		return err
+	}
+
+	for _, swapRule := range swapRules {
+		if ctx.Err() != nil {
+			return nil
+		}
+		// the balances change with every swap
+		balances, err := lnClient.GetBalances(ctx, false)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to get balances")
+			return err
+		}
+		svc.runRule(ctx, &swapRule, balances, swapOutInfo, swapsService)
+	}
+	return nil
+}
+
+func (svc *swapRulesService) runRule(ctx context.Context, swapRule *db.SwapRule, balances *lnclient.BalancesResponse, swapOutInfo *swaps.SwapInfo, swapsService swaps.SwapsService) {
+	if !swapRule.DryRun && svc.hasPendingSwap(swapRule) {
+		logger.Logger.WithField("swap_rule_id", swapRule.ID).Debug("Swap of rule is still pending, skipping")
+		return
+	}
+
+	amount := getSwapAmount(swapRule, balances, swapOutInfo)
+	if amount == 0 {
+		return
+	}
+
+	run := db.SwapRuleRun{
+		SwapRuleId: swapRule.ID,
+		AmountSat:  amount,
+		DryRun:     swapRule.DryRun,
+	}
+	if !swapRule.DryRun {
+		// retried with the next check, once the fees fell
+		if err := feeadvisor.CheckDelay(ctx, svc.cfg); err != nil {
+			logger.Logger.WithField("swap_rule_id", swapRule.ID).WithError(err).Info("Delaying swap of rule")
+			return
+		}
+		logger.Logger.WithFields(logrus.Fields{
+			"swap_rule_id": swapRule.ID,
+			"amount":       amount,
+			"destination":  swapRule.Destination,
+		}).Info("Initiating swap for rule")
+		swapResponse, err := swapsService.SwapOut(amount, swapRule.Destination, true, false)
+		if err != nil {
+			logger.Logger.WithField("swap_rule_id", swapRule.ID).WithError(err).Error("Failed to initiate swap for rule")
+			run.Error = err.Error()
+		} else {
+			run.SwapId = swapResponse.SwapId
+		}
+	}
+
+	if err := svc.db.Create(&run).Error; err != nil {
+		logger.Logger.WithField("swap_rule_id", swapRule.ID).WithError(err).Error("Failed to save swap rule run")
+	}
+	now := time.Now()
+	swapRule.LastRunAt = &now
+	if err := svc.db.Model(swapRule).Update("last_run_at", now).Error; err != nil {
+		logger.Logger.WithField("swap_rule_id", swapRule.ID).WithError(err).Error("Failed to update swap rule")
+	}
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_swap_rule_triggered",
+		Properties: map[string]interface{}{
+			"swap_rule_id": swapRule.ID,
+			"name":         swapRule.Name,
+			"type":         swapRule.Type,
+			"amount":       amount,
+			"dry_run":      swapRule.DryRun,
+			"swap_id":      run.SwapId,
+			"error":        run.Error,
+		},
+	})
+}
+
+// a rule does not swap again until its last swap completed, as the balances only change then
+func (svc *swapRulesService) hasPendingSwap(swapRule *db.SwapRule) bool {
+	var lastRun db.SwapRuleRun
+	if svc.db.Where("swap_rule_id = ? AND swap_id != ''", swapRule.ID).Order("id DESC").Limit(1).Find(&lastRun).RowsAffected == 0 {
+		return false
+	}
+	var swap db.Swap
+	return svc.db.Where(&db.Swap{SwapId: lastRun.SwapId, State: constants.SWAP_STATE_PENDING}).Limit(1).Find(&swap).RowsAffected > 0
+}
+
+// getSwapAmount returns the amount in sats to swap out, or 0 if the rule is not triggered
+func getSwapAmount(swapRule *db.SwapRule, balances *lnclient.BalancesResponse, swapOutInfo *swaps.SwapInfo) uint64 {
+	spendable := uint64(max(balances.Lightning.TotalSpendable, 0) / 1000)
+	receivable := uint64(max(balances.Lightning.TotalReceivable, 0) / 1000)
+
+	var amount uint64
+	switch swapRule.Type {
+	case RULE_TYPE_SWAP_OUT_EXCESS:
+		if spendable > swapRule.ThresholdSat {
+			amount = spendable - swapRule.ThresholdSat
+		}
+	case RULE_TYPE_KEEP_INBOUND:
+		// swapping out moves the amount from the local to the remote side of the channels
+		if receivable < swapRule.ThresholdSat {
+			amount = min(swapRule.ThresholdSat-receivable, spendable)
+		}
+	}
+
+	if swapOutInfo.MaxAmount > 0 {
+		amount = min(amount, swapOutInfo.MaxAmount)
+	}
+	if amount < max(swapRule.MinAmountSat, swapOutInfo.MinAmount, 1) {
+		return 0
+	}
+	return amount
+}
@@ -0,0 +1,117 @@
+package swaprules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/swaps"
+	"github.com/getAlby/hub/tests"
+)
+
+type mockBalancesLNClient struct {
+	lnclient.LNClient
+	balances lnclient.BalancesResponse
+}
+
+func (mln *mockBalancesLNClient) GetBalances(ctx context.Context, includeInactiveChannels bool) (*lnclient.BalancesResponse, error) {
+	return &mln.balances, nil
+}
+
+type mockSwapsService struct {
+	swaps.SwapsService
+	swapOuts []uint64
+}
+
+func (svc *mockSwapsService) GetSwapOutInfo() (*swaps.SwapInfo, error) {
+	return &swaps.SwapInfo{MinAmount: 25_000, MaxAmount: 1_000_000}, nil
+}
+
+func (svc *mockSwapsService) SwapOut(amount uint64, destination string, autoSwap, usedXpubDerivation bool) (*swaps.SwapResponse, error) {
+	svc.swapOuts = append(svc.swapOuts, amount)
+	return &swaps.SwapResponse{SwapId: "swap1"}, nil
+}
+
+func TestGetSwapAmount(t *testing.T) {
+	swapOutInfo := &swaps.SwapInfo{MinAmount: 25_000, MaxAmount: 1_000_000}
+	balances := &lnclient.BalancesResponse{}
+	balances.Lightning.TotalSpendable = 300_000_000
+	balances.Lightning.TotalReceivable = 50_000_000
+
+	assert.Equal(t, uint64(200_000), getSwapAmount(&db.SwapRule{Type: RULE_TYPE_SWAP_OUT_EXCESS, ThresholdSat: 100_000}, balances, swapOutInfo))
+	assert.Equal(t, uint64(0), getSwapAmount(&db.SwapRule{Type: RULE_TYPE_SWAP_OUT_EXCESS, ThresholdSat: 290_000}, balances, swapOutInfo))
+	assert.Equal(t, uint64(0), getSwapAmount(&db.SwapRule{Type: RULE_TYPE_SWAP_OUT_EXCESS, ThresholdSat: 100_000, MinAmountSat: 250_000}, balances, swapOutInfo))
+	assert.Equal(t, uint64(1_000_000), getSwapAmount(&db.SwapRule{Type: RULE_TYPE_SWAP_OUT_EXCESS}, &lnclient.BalancesResponse{Lightning: lnclient.LightningBalanceResponse{TotalSpendable: 5_000_000_000}}, swapOutInfo))
+
+	assert.Equal(t, uint64(150_000), getSwapAmount(&db.SwapRule{Type: RULE_TYPE_KEEP_INBOUND, ThresholdSat: 200_000}, balances, swapOutInfo))
+	assert.Equal(t, uint64(300_000), getSwapAmount(&db.SwapRule{Type: RULE_TYPE_KEEP_INBOUND, ThresholdSat: 500_000}, balances, swapOutInfo))
+	assert.Equal(t, uint64(0), getSwapAmount(&db.SwapRule{Type: RULE_TYPE_KEEP_INBOUND, ThresholdSat: 50_000}, balances, swapOutInfo))
+}
+
+func TestSwapRules_Run(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	lnClient := &mockBalancesLNClient{LNClient: svc.LNClient}
+	lnClient.balances.Lightning.TotalSpendable = 300_000_000
+	swapsService := &mockSwapsService{}
+
+	consumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(consumer)
+	swapRulesSvc := NewSwapRulesService(svc.DB, svc.Cfg, svc.EventPublisher)
+	swapRule, err := swapRulesSvc.CreateSwapRule("cold storage", RULE_TYPE_SWAP_OUT_EXCESS, 100_000, "bc1qcoldstorage", 0, false)
+	require.NoError(t, err)
+
+	require.NoError(t, swapRulesSvc.runRules(context.TODO(), lnClient, swapsService))
+	assert.Equal(t, []uint64{200_000}, swapsService.swapOuts)
+	require.NoError(t, svc.DB.Create(&db.Swap{SwapId: "swap1", Type: constants.SWAP_TYPE_OUT, State: constants.SWAP_STATE_PENDING}).Error)
+
+	// no further swap while the last one is pending
+	require.NoError(t, swapRulesSvc.runRules(context.TODO(), lnClient, swapsService))
+	assert.Len(t, swapsService.swapOuts, 1)
+
+	runs, err := swapRulesSvc.ListSwapRuleRuns(swapRule.ID, 20)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, uint64(200_000), runs[0].AmountSat)
+	assert.Equal(t, "swap1", runs[0].SwapId)
+
+	consumedEvents := consumer.GetConsumedEvents()
+	require.Len(t, consumedEvents, 1)
+	assert.Equal(t, "nwc_swap_rule_triggered", consumedEvents[0].Event)
+	assert.Equal(t, "swap1", consumedEvents[0].Properties.(map[string]interface{})["swap_id"])
+}
+
+func TestSwapRules_DryRun(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	lnClient := &mockBalancesLNClient{LNClient: svc.LNClient}
+	lnClient.balances.Lightning.TotalSpendable = 300_000_000
+	swapsService := &mockSwapsService{}
+
+	swapRulesSvc := NewSwapRulesService(svc.DB, svc.Cfg, svc.EventPublisher)
+	swapRule, err := swapRulesSvc.CreateSwapRule("", RULE_TYPE_KEEP_INBOUND, 100_000, "", 0, true)
+	require.NoError(t, err)
+
+	require.NoError(t, swapRulesSvc.runRules(context.TODO(), lnClient, swapsService))
+	assert.Empty(t, swapsService.swapOuts)
+
+	runs, err := swapRulesSvc.ListSwapRuleRuns(swapRule.ID, 20)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.True(t, runs[0].DryRun)
+	assert.Equal(t, uint64(100_000), runs[0].AmountSat)
+
+	require.NoError(t, swapRulesSvc.DeleteSwapRule(swapRule.ID))
+	runs, err = swapRulesSvc.ListSwapRuleRuns(swapRule.ID, 20)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
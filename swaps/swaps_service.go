@@ -24,6 +24,8 @@ import (
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/feeadvisor"
+	"github.com/getAlby/hub/feeestimator"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/service/keys"
@@ -67,13 +69,9 @@ const (
 	AlbySwapServiceFee = 1.0
 )
 
-type FeeRates struct {
-	FastestFee  uint64 `json:"fastestFee"`
-	HalfHourFee uint64 `json:"halfHourFee"`
-	HourFee     uint64 `json:"hourFee"`
-	EconomyFee  uint64 `json:"economyFee"`
-	MinimumFee  uint64 `json:"minimumFee"`
-}
+// SWAP_STATUS_CLAIM_BROADCASTED is published as progress of a swap out once the hub broadcast
+// its claim transaction, the other statuses are the ones of Boltz
+const SWAP_STATUS_CLAIM_BROADCASTED = "claim.broadcasted"
 
 type TxStatusInfo struct {
 	Confirmed   bool   `json:"confirmed"`
@@ -211,6 +209,11 @@ func (svc *swapsService) EnableAutoSwapOut() error {
 					logger.Logger.Info("Threshold requirements not met for swap, ignoring")
 					continue
 				}
+				// retried with the next check, once the fees fell
+				if err := feeadvisor.CheckDelay(ctx, svc.cfg); err != nil {
+					logger.Logger.WithError(err).Info("Delaying auto swap")
+					continue
+				}
 
 				actualDestination := swapDestination
 				var usedXpubDerivation bool
@@ -580,9 +583,34 @@ func (svc *swapsService) markSwapState(dbSwap *db.Swap, state string) {
 	}).Error
 	if dbErr != nil {
 		logger.Logger.WithError(dbErr).WithField("swapId", dbSwap.SwapId).Error("Failed to update swap state")
+		return
+	}
+
+	if state == constants.SWAP_STATE_FAILED {
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_swap_failed",
+			Properties: map[string]interface{}{
+				"swapId":   dbSwap.SwapId,
+				"swapType": dbSwap.Type,
+			},
+		})
 	}
 }
 
+// publishSwapProgress publishes the Boltz status updates of a swap, so its progress can be followed
+func (svc *swapsService) publishSwapProgress(dbSwap *db.Swap, status string) {
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_swap_progress",
+		Properties: map[string]interface{}{
+			"swapId":     dbSwap.SwapId,
+			"swapType":   dbSwap.Type,
+			"status":     status,
+			"lockupTxId": dbSwap.LockupTxId,
+			"claimTxId":  dbSwap.ClaimTxId,
+		},
+	})
+}
+
 func (svc *swapsService) RefundSwap(swapId, address string, enableRetries bool) error {
 	var swap db.Swap
 	err := svc.db.Limit(1).Find(&swap, &db.Swap{
@@ -713,7 +741,7 @@ func (svc *swapsService) RefundSwap(swapId, address string, enableRetries bool)
 			continue
 		}
 
-		feeRates, err := svc.getFeeRates()
+		feeRate, err := svc.getFeeRate()
 		if err != nil {
 			logger.Logger.WithError(err).WithFields(logrus.Fields{
 				"swapId":    swapId,
@@ -724,7 +752,6 @@ func (svc *swapsService) RefundSwap(swapId, address string, enableRetries bool)
 
 		cooperative := swapTransactionResp.TimeoutBlockHeight > nodeInfo.BlockHeight
 
-		fastestFee := float64(feeRates.FastestFee)
 		refundTransaction, _, err = boltz.ConstructTransaction(
 			network,
 			boltz.CurrencyBtc,
@@ -742,7 +769,7 @@ func (svc *swapsService) RefundSwap(swapId, address string, enableRetries bool)
 				},
 			},
 			boltz.Fee{
-				SatsPerVbyte: &fastestFee,
+				SatsPerVbyte: &feeRate,
 			},
 			svc.boltzApi,
 		)
@@ -959,6 +986,7 @@ func (svc *swapsService) startSwapInListener(swap *db.Swap) {
 			if update.Id != swap.SwapId {
 				continue
 			}
+			svc.publishSwapProgress(swap, update.Status)
 			switch boltz.ParseEvent(update.Status) {
 			case boltz.TransactionMempool:
 				logger.Logger.WithFields(logrus.Fields{
@@ -1153,6 +1181,7 @@ func (svc *swapsService) startSwapOutListener(swap *db.Swap) {
 			if update.Id != swap.SwapId {
 				continue
 			}
+			svc.publishSwapProgress(swap, update.Status)
 			switch boltz.ParseEvent(update.Status) {
 			case boltz.SwapCreated:
 				logger.Logger.WithField("swapId", swap.SwapId).Info("Paying the swap invoice")
@@ -1166,7 +1195,7 @@ func (svc *swapsService) startSwapOutListener(swap *db.Swap) {
 						"swap_id": swap.SwapId,
 					}
 					logger.Logger.WithField("swapId", swap.SwapId).Info("Initiating swap invoice payment")
-					_, err = svc.transactionsService.SendPaymentSync(swap.Invoice, nil, metadata, svc.lnClient, nil, nil)
+					_, err = svc.transactionsService.SendPaymentSync(svc.ctx, swap.Invoice, nil, metadata, svc.lnClient, nil, nil)
 					if err != nil {
 						logger.Logger.WithError(err).WithFields(logrus.Fields{
 							"swapId": swap.SwapId,
@@ -1240,16 +1269,15 @@ func (svc *swapsService) startSwapOutListener(swap *db.Swap) {
 					fee := lockupAmount - swap.ReceiveAmount
 					boltzFee.Sats = &fee
 				} else {
-					var feeRates *FeeRates
-					feeRates, err = svc.getFeeRates()
+					var feeRate float64
+					feeRate, err = svc.getFeeRate()
 					if err != nil {
 						logger.Logger.WithError(err).WithFields(logrus.Fields{
 							"swapId": swap.SwapId,
 						}).Error("Failed to fetch fee rate to create claim transaction")
 						return
 					}
-					fastestFee := float64(feeRates.FastestFee)
-					boltzFee.SatsPerVbyte = &fastestFee
+					boltzFee.SatsPerVbyte = &feeRate
 				}
 
 				var claimTransaction boltz.Transaction
@@ -1312,6 +1340,7 @@ func (svc *swapsService) startSwapOutListener(swap *db.Swap) {
 					}).WithError(err).Error("Failed to save claim info to swap")
 					return
 				}
+				svc.publishSwapProgress(swap, SWAP_STATUS_CLAIM_BROADCASTED)
 			case boltz.TransactionFailed, boltz.SwapExpired:
 				logger.Logger.WithFields(logrus.Fields{
 					"swapId": swap.SwapId,
@@ -1333,12 +1362,20 @@ func (svc *swapsService) getMempoolTx(txId string) (*MempoolTx, error) {
 	return &transaction, nil
 }
 
-func (svc *swapsService) getFeeRates() (*FeeRates, error) {
-	var rates FeeRates
-	if err := svc.requestMempoolApi("/v1/fees/recommended", &rates); err != nil {
-		return nil, err
+// getFeeRate returns the fee rate in sat/vB for claim and refund transactions, which should
+// confirm in the next block
+func (svc *swapsService) getFeeRate() (float64, error) {
+	var err error
+	for attempt := 1; attempt <= 10; attempt++ {
+		var feeRate uint64
+		feeRate, err = feeestimator.GetFeeRate(svc.ctx, svc.cfg, feeestimator.TARGET_NEXT_BLOCK)
+		if err == nil {
+			return float64(feeRate), nil
+		}
+		logger.Logger.WithError(err).WithField("attempt", attempt).Error("Failed to estimate fee rate, retrying")
+		time.Sleep(1 * time.Second)
 	}
-	return &rates, nil
+	return 0, err
 }
 
 func (svc *swapsService) requestMempoolApi(endpoint string, result interface{}) error {
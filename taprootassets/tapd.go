@@ -0,0 +1,326 @@
+// Package taprootassets reads the balances and transfers of Taproot Assets, such as stablecoins,
+// from a tapd node running next to LND, through the REST API of tapd.
+package taprootassets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+
+	"github.com/getAlby/hub/config"
+)
+
+const (
+	TRANSFER_TYPE_INCOMING = "incoming"
+	TRANSFER_TYPE_OUTGOING = "outgoing"
+)
+
+// AssetBalance is the balance of an asset in its base units, which are shown with DecimalDisplay
+// decimal places, e.g. 1230000 with 6 decimal places is 1.23
+type AssetBalance struct {
+	AssetId        string `json:"assetId"`
+	Name           string `json:"name"`
+	Balance        uint64 `json:"balance"`
+	DecimalDisplay uint32 `json:"decimalDisplay"`
+}
+
+type AssetTransfer struct {
+	Type           string    `json:"type"`
+	AssetId        string    `json:"assetId"`
+	Name           string    `json:"name"`
+	Amount         uint64    `json:"amount"`
+	DecimalDisplay uint32    `json:"decimalDisplay"`
+	AnchorTxId     string    `json:"anchorTxId"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type TapdClient interface {
+	GetBalances(ctx context.Context) ([]AssetBalance, error)
+	// ListTransfers returns the most recent transfers first
+	ListTransfers(ctx context.Context, limit int) ([]AssetTransfer, error)
+}
+
+type tapdClient struct {
+	url         string
+	macaroonHex string
+	httpClient  *http.Client
+}
+
+// NewTapdClient returns nil if no tapd node is configured
+func NewTapdClient(cfg config.Config) (*tapdClient, error) {
+	env := cfg.GetEnv()
+	if env.TapdAddress == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if env.TapdCertFile != "" {
+		certBytes, err := os.ReadFile(env.TapdCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tapd cert file: %w", err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(certBytes) {
+			return nil, errors.New("failed to parse tapd cert file")
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	var macaroonHex string
+	if env.TapdMacaroonFile != "" {
+		macaroonBytes, err := os.ReadFile(env.TapdMacaroonFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tapd macaroon file: %w", err)
+		}
+		macaroonHex = hex.EncodeToString(macaroonBytes)
+	}
+
+	url := env.TapdAddress
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "https://" + url
+	}
+
+	return &tapdClient{
+		url:         strings.TrimSuffix(url, "/"),
+		macaroonHex: macaroonHex,
+		httpClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+type tapdAssetGenesis struct {
+	Name    string `json:"name"`
+	AssetId string `json:"asset_id"`
+}
+
+type assetInfo struct {
+	name           string
+	decimalDisplay uint32
+}
+
+func (client *tapdClient) GetBalances(ctx context.Context) ([]AssetBalance, error) {
+	var balancesResponse struct {
+		AssetBalances map[string]struct {
+			AssetGenesis tapdAssetGenesis `json:"asset_genesis"`
+			Balance      string           `json:"balance"`
+		} `json:"asset_balances"`
+	}
+	if err := client.request(ctx, http.MethodGet, "/v1/taproot-assets/assets/balance?asset_id=true", nil, &balancesResponse); err != nil {
+		return nil, err
+	}
+	assetInfos, err := client.getAssetInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := []AssetBalance{}
+	for assetId, assetBalance := range balancesResponse.AssetBalances {
+		balance, err := strconv.ParseUint(assetBalance.Balance, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid balance of asset %s: %w", assetId, err)
+		}
+		balances = append(balances, AssetBalance{
+			AssetId:        assetId,
+			Name:           assetBalance.AssetGenesis.Name,
+			Balance:        balance,
+			DecimalDisplay: assetInfos[assetId].decimalDisplay,
+		})
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Name < balances[j].Name
+	})
+	return balances, nil
+}
+
+func (client *tapdClient) ListTransfers(ctx context.Context, limit int) ([]AssetTransfer, error) {
+	assetInfos, err := client.getAssetInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var transfersResponse struct {
+		Transfers []struct {
+			TransferTimestamp string `json:"transfer_timestamp"`
+			AnchorTxHash      string `json:"anchor_tx_hash"`
+			Outputs           []struct {
+				ScriptKeyIsLocal bool   `json:"script_key_is_local"`
+				Amount           string `json:"amount"`
+				AssetId          string `json:"asset_id"`
+			} `json:"outputs"`
+		} `json:"transfers"`
+	}
+	if err := client.request(ctx, http.MethodGet, "/v1/taproot-assets/assets/transfers", nil, &transfersResponse); err != nil {
+		return nil, err
+	}
+
+	transfers := []AssetTransfer{}
+	for _, tapdTransfer := range transfersResponse.Transfers {
+		// the outputs to other script keys are sent, the local ones are change
+		amounts := map[string]uint64{}
+		for _, output := range tapdTransfer.Outputs {
+			if output.ScriptKeyIsLocal {
+				continue
+			}
+			amount, err := strconv.ParseUint(output.Amount, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid transfer amount: %w", err)
+			}
+			amounts[base64ToHex(output.AssetId)] += amount
+		}
+		timestamp, _ := strconv.ParseInt(tapdTransfer.TransferTimestamp, 10, 64)
+		for assetId, amount := range amounts {
+			transfers = append(transfers, AssetTransfer{
+				Type:           TRANSFER_TYPE_OUTGOING,
+				AssetId:        assetId,
+				Name:           assetInfos[assetId].name,
+				Amount:         amount,
+				DecimalDisplay: assetInfos[assetId].decimalDisplay,
+				AnchorTxId:     txHashToTxId(tapdTransfer.AnchorTxHash),
+				CreatedAt:      time.Unix(timestamp, 0),
+			})
+		}
+	}
+
+	var receivesResponse struct {
+		Events []struct {
+			CreationTimeUnixMicros string `json:"creation_time_unix_micros"`
+			Addr                   struct {
+				AssetId string `json:"asset_id"`
+				Amount  string `json:"amount"`
+			} `json:"addr"`
+			Status   string `json:"status"`
+			Outpoint string `json:"outpoint"`
+		} `json:"events"`
+	}
+	if err := client.request(ctx, http.MethodPost, "/v1/taproot-assets/addrs/receives", map[string]interface{}{}, &receivesResponse); err != nil {
+		return nil, err
+	}
+	for _, event := range receivesResponse.Events {
+		if event.Status != "ADDR_EVENT_STATUS_COMPLETED" {
+			continue
+		}
+		amount, err := strconv.ParseUint(event.Addr.Amount, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid receive amount: %w", err)
+		}
+		micros, _ := strconv.ParseInt(event.CreationTimeUnixMicros, 10, 64)
+		assetId := base64ToHex(event.Addr.AssetId)
+		transfers = append(transfers, AssetTransfer{
+			Type:           TRANSFER_TYPE_INCOMING,
+			AssetId:        assetId,
+			Name:           assetInfos[assetId].name,
+			Amount:         amount,
+			DecimalDisplay: assetInfos[assetId].decimalDisplay,
+			AnchorTxId:     strings.Split(event.Outpoint, ":")[0],
+			CreatedAt:      time.UnixMicro(micros),
+		})
+	}
+
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].CreatedAt.After(transfers[j].CreatedAt)
+	})
+	if limit > 0 && len(transfers) > limit {
+		transfers = transfers[:limit]
+	}
+	return transfers, nil
+}
+
+// getAssetInfos returns the names and decimal places of the assets known to tapd by asset ID
+func (client *tapdClient) getAssetInfos(ctx context.Context) (map[string]assetInfo, error) {
+	var assetsResponse struct {
+		Assets []struct {
+			AssetGenesis   tapdAssetGenesis `json:"asset_genesis"`
+			DecimalDisplay *struct {
+				DecimalDisplay uint32 `json:"decimal_display"`
+			} `json:"decimal_display"`
+		} `json:"assets"`
+	}
+	if err := client.request(ctx, http.MethodGet, "/v1/taproot-assets/assets?include_spent=true", nil, &assetsResponse); err != nil {
+		return nil, err
+	}
+
+	assetInfos := map[string]assetInfo{}
+	for _, asset := range assetsResponse.Assets {
+		info := assetInfo{name: asset.AssetGenesis.Name}
+		if asset.DecimalDisplay != nil {
+			info.decimalDisplay = asset.DecimalDisplay.DecimalDisplay
+		}
+		assetInfos[base64ToHex(asset.AssetGenesis.AssetId)] = info
+	}
+	return assetInfos, nil
+}
+
+func (client *tapdClient) request(ctx context.Context, method string, path string, payload interface{}, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = strings.NewReader(string(payloadBytes))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, client.url+path, body)
+	if err != nil {
+		return err
+	}
+	if client.macaroonHex != "" {
+		req.Header.Set("Grpc-Metadata-macaroon", client.macaroonHex)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return errors.New("failed to read response body")
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("tapd returned non-success code %d: %s", res.StatusCode, string(responseBody))
+	}
+	return json.Unmarshal(responseBody, result)
+}
+
+// byte fields are base64-encoded in the REST API of tapd
+func base64ToHex(value string) string {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return value
+	}
+	return hex.EncodeToString(decoded)
+}
+
+// txHashToTxId returns the transaction ID of a hash, which is displayed in reverse byte order
+func txHashToTxId(value string) string {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return value
+	}
+	hash, err := chainhash.NewHash(decoded)
+	if err != nil {
+		return hex.EncodeToString(decoded)
+	}
+	return hash.String()
+}
@@ -0,0 +1,83 @@
+package taprootassets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/tests"
+)
+
+// asset ID 0x0102...20 in the base64 encoding of tapd's REST API
+const (
+	assetIdBase64 = "AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA="
+	assetIdHex    = "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"
+)
+
+func TestTapdClient(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/taproot-assets/assets/balance":
+			w.Write([]byte(`{"asset_balances":{"` + assetIdHex + `":{"asset_genesis":{"name":"USDT","asset_id":"` + assetIdBase64 + `"},"balance":"1230000"}}}`))
+		case "/v1/taproot-assets/assets":
+			w.Write([]byte(`{"assets":[{"asset_genesis":{"name":"USDT","asset_id":"` + assetIdBase64 + `"},"amount":"1230000","decimal_display":{"decimal_display":6}}]}`))
+		case "/v1/taproot-assets/assets/transfers":
+			w.Write([]byte(`{"transfers":[{"transfer_timestamp":"1700000000","anchor_tx_hash":"` + assetIdBase64 + `","outputs":[
+				{"script_key_is_local":true,"amount":"500","asset_id":"` + assetIdBase64 + `"},
+				{"script_key_is_local":false,"amount":"1000","asset_id":"` + assetIdBase64 + `"}]}]}`))
+		case "/v1/taproot-assets/addrs/receives":
+			assert.Equal(t, http.MethodPost, r.Method)
+			w.Write([]byte(`{"events":[
+				{"creation_time_unix_micros":"1700000100000000","addr":{"asset_id":"` + assetIdBase64 + `","amount":"2000"},"status":"ADDR_EVENT_STATUS_COMPLETED","outpoint":"abcd:1"},
+				{"creation_time_unix_micros":"1700000200000000","addr":{"asset_id":"` + assetIdBase64 + `","amount":"3000"},"status":"ADDR_EVENT_STATUS_TRANSACTION_DETECTED","outpoint":"ef01:0"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	svc.Cfg.GetEnv().TapdAddress = server.URL
+	client, err := NewTapdClient(svc.Cfg)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	balances, err := client.GetBalances(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []AssetBalance{{AssetId: assetIdHex, Name: "USDT", Balance: 1230000, DecimalDisplay: 6}}, balances)
+
+	transfers, err := client.ListTransfers(context.TODO(), 10)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	// the most recent transfer comes first, unconfirmed receives are not included
+	assert.Equal(t, AssetTransfer{
+		Type:           TRANSFER_TYPE_INCOMING,
+		AssetId:        assetIdHex,
+		Name:           "USDT",
+		Amount:         2000,
+		DecimalDisplay: 6,
+		AnchorTxId:     "abcd",
+		CreatedAt:      time.UnixMicro(1700000100000000),
+	}, transfers[0])
+	// the change output is not part of the sent amount
+	assert.Equal(t, TRANSFER_TYPE_OUTGOING, transfers[1].Type)
+	assert.Equal(t, uint64(1000), transfers[1].Amount)
+	assert.Equal(t, "201f1e1d1c1b1a191817161514131211100f0e0d0c0b0a090807060504030201", transfers[1].AnchorTxId)
+
+	transfers, err = client.ListTransfers(context.TODO(), 1)
+	require.NoError(t, err)
+	assert.Len(t, transfers, 1)
+
+	svc.Cfg.GetEnv().TapdAddress = ""
+	client, err = NewTapdClient(svc.Cfg)
+	require.NoError(t, err)
+	assert.Nil(t, client)
+}
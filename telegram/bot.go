@@ -0,0 +1,376 @@
+// Package telegram pushes payment and node alerts to Telegram chats and answers a few
+// commands which cannot move funds. Only the chats in TELEGRAM_CHAT_IDS can use the bot.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/forceclose"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/transactions"
+)
+
+const pollTimeoutSeconds = 30
+
+const recentTransactionsLimit = 5
+
+// retryDelay is the wait after a failed poll
+var retryDelay = 10 * time.Second
+
+const helpText = `/balance - show the lightning and on-chain balance
+/transactions - show the latest transactions
+/pause - pause all apps
+/resume - resume all apps`
+
+type telegramBot struct {
+	db              *gorm.DB
+	appsSvc         apps.AppsService
+	transactionsSvc transactions.TransactionsService
+	getLNClient     func() lnclient.LNClient
+	apiUrl          string
+	chatIds         []int64
+	httpClient      *http.Client
+}
+
+// IsConfigured returns whether a bot token and at least one chat is set
+func IsConfigured(appConfig *config.AppConfig) bool {
+	return appConfig.TelegramBotToken != "" && appConfig.TelegramChatIds != ""
+}
+
+func ParseChatIds(chatIds string) ([]int64, error) {
+	parsedChatIds := []int64{}
+	for _, chatId := range strings.Split(chatIds, ",") {
+		chatId = strings.TrimSpace(chatId)
+		if chatId == "" {
+			continue
+		}
+		parsedChatId, err := strconv.ParseInt(chatId, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid telegram chat id %q: %w", chatId, err)
+		}
+		parsedChatIds = append(parsedChatIds, parsedChatId)
+	}
+	if len(parsedChatIds) == 0 {
+		return nil, errors.New("no telegram chat ids configured")
+	}
+	return parsedChatIds, nil
+}
+
+func NewTelegramBot(gormDB *gorm.DB, appsSvc apps.AppsService, transactionsSvc transactions.TransactionsService, appConfig *config.AppConfig, getLNClient func() lnclient.LNClient) (*telegramBot, error) {
+	chatIds, err := ParseChatIds(appConfig.TelegramChatIds)
+	if err != nil {
+		return nil, err
+	}
+	return &telegramBot{
+		db:              gormDB,
+		appsSvc:         appsSvc,
+		transactionsSvc: transactionsSvc,
+		getLNClient:     getLNClient,
+		apiUrl:          strings.TrimSuffix(appConfig.TelegramApiUrl, "/") + "/bot" + appConfig.TelegramBotToken,
+		chatIds:         chatIds,
+		httpClient:      &http.Client{Timeout: (pollTimeoutSeconds + 10) * time.Second},
+	}, nil
+}
+
+type update struct {
+	UpdateId int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			Id int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// Start answers the commands of the allowed chats until ctx is done
+func (bot *telegramBot) Start(ctx context.Context) {
+	go func() {
+		var offset int64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			updates, err := bot.getUpdates(ctx, offset)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logger.Logger.WithError(err).Error("Failed to get telegram updates")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retryDelay):
+				}
+				continue
+			}
+
+			for _, update := range updates {
+				offset = update.UpdateId + 1
+				if update.Message == nil {
+					continue
+				}
+				if !slices.Contains(bot.chatIds, update.Message.Chat.Id) {
+					logger.Logger.WithField("chat_id", update.Message.Chat.Id).Warn("Ignoring telegram message from a chat which is not allowed")
+					continue
+				}
+				reply := bot.handleCommand(ctx, update.Message.Text)
+				if err := bot.sendMessage(ctx, update.Message.Chat.Id, reply); err != nil {
+					logger.Logger.WithError(err).Error("Failed to send telegram reply")
+				}
+			}
+		}
+	}()
+}
+
+func (bot *telegramBot) handleCommand(ctx context.Context, text string) string {
+	command := strings.Fields(text)
+	if len(command) == 0 {
+		return helpText
+	}
+	// commands in groups are suffixed with the bot name, e.g. /balance@my_hub_bot
+	name, _, _ := strings.Cut(command[0], "@")
+
+	var reply string
+	var err error
+	switch name {
+	case "/balance":
+		reply, err = bot.balance(ctx)
+	case "/transactions":
+		reply, err = bot.recentTransactions(ctx)
+	case "/pause":
+		reply, err = bot.setAppsPaused(true)
+	case "/resume":
+		reply, err = bot.setAppsPaused(false)
+	default:
+		return helpText
+	}
+	if err != nil {
+		logger.Logger.WithError(err).WithField("command", name).Error("Failed to handle telegram command")
+		return fmt.Sprintf("Failed: %s", err.Error())
+	}
+	return reply
+}
+
+func (bot *telegramBot) balance(ctx context.Context) (string, error) {
+	lnClient := bot.getLNClient()
+	if lnClient == nil {
+		return "", errors.New("the node is not running")
+	}
+	balances, err := lnClient.GetBalances(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Lightning: %d sats\nOn-chain: %d sats", balances.Lightning.TotalSpendable/1000, balances.Onchain.Total), nil
+}
+
+func (bot *telegramBot) recentTransactions(ctx context.Context) (string, error) {
+	lnClient := bot.getLNClient()
+	if lnClient == nil {
+		return "", errors.New("the node is not running")
+	}
+	transactions, _, err := bot.transactionsSvc.ListTransactions(ctx, 0, 0, recentTransactionsLimit, 0, false, false, nil, lnClient, nil, false)
+	if err != nil {
+		return "", err
+	}
+	if len(transactions) == 0 {
+		return "No transactions yet", nil
+	}
+	lines := []string{}
+	for _, transaction := range transactions {
+		sign := "+"
+		if transaction.Type == constants.TRANSACTION_TYPE_OUTGOING {
+			sign = "-"
+		}
+		line := fmt.Sprintf("%s%d sats %s", sign, transaction.AmountMsat/1000, transaction.State)
+		if transaction.Description != "" {
+			line += " " + transaction.Description
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (bot *telegramBot) setAppsPaused(paused bool) (string, error) {
+	query := bot.db.Where("paused_at IS NOT NULL")
+	if paused {
+		query = bot.db.Where("paused_at IS NULL")
+	}
+	var dbApps []db.App
+	if err := query.Find(&dbApps).Error; err != nil {
+		return "", err
+	}
+	for i := range dbApps {
+		if err := bot.appsSvc.SetAppPaused(&dbApps[i], paused); err != nil {
+			return "", err
+		}
+	}
+	if paused {
+		return fmt.Sprintf("Paused %d apps", len(dbApps)), nil
+	}
+	return fmt.Sprintf("Resumed %d apps", len(dbApps)), nil
+}
+
+func (bot *telegramBot) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	message := formatAlert(event)
+	if message == "" {
+		return
+	}
+	for _, chatId := range bot.chatIds {
+		if err := bot.sendMessage(ctx, chatId, message); err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"event":   event.Event,
+				"chat_id": chatId,
+			}).Error("Failed to send telegram alert")
+		}
+	}
+}
+
+// formatAlert returns the message for the payment, balance, fee and node events, and an empty string for the others
+func formatAlert(event *events.Event) string {
+	properties, _ := event.Properties.(map[string]interface{})
+	switch event.Event {
+	case "nwc_payment_received", "nwc_payment_sent", "nwc_payment_failed":
+		transaction, ok := event.Properties.(*db.Transaction)
+		if !ok {
+			return ""
+		}
+		var message string
+		switch event.Event {
+		case "nwc_payment_received":
+			message = fmt.Sprintf("Received %d sats", transaction.AmountMsat/1000)
+		case "nwc_payment_sent":
+			message = fmt.Sprintf("Sent %d sats", transaction.AmountMsat/1000)
+		default:
+			message = fmt.Sprintf("Payment of %d sats failed", transaction.AmountMsat/1000)
+		}
+		if transaction.Description != "" {
+			message += ": " + transaction.Description
+		}
+		return message
+	case "nwc_channel_ready":
+		return fmt.Sprintf("Channel opened with %v", properties["counterparty_node_id"])
+	case "nwc_channel_closed":
+		// force-closes are reported with their pending balance
+		reason, _ := properties["reason"].(string)
+		if forceclose.IsForceClose(reason) {
+			return ""
+		}
+		return fmt.Sprintf("Channel closed with %v (%v)", properties["counterparty_node_id"], properties["reason"])
+	case "nwc_channel_force_closed":
+		message := fmt.Sprintf("Channel force-closed with %v by the %v side (%v)", properties["counterparty_node_id"], properties["initiator"], properties["reason"])
+		if expectedSpendableAt, ok := properties["expected_spendable_at"].(*time.Time); ok && expectedSpendableAt != nil {
+			message += fmt.Sprintf("\n%v sats are timelocked for %v blocks, spendable around %s", properties["pending_balance_sat"], properties["blocks_until_spendable"], expectedSpendableAt.UTC().Format("2006-01-02 15:04 UTC"))
+		} else {
+			message += fmt.Sprintf("\n%v sats are pending on-chain", properties["pending_balance_sat"])
+		}
+		return message
+	case "nwc_force_close_funds_spendable":
+		return fmt.Sprintf("%v sats from the force-closed channel with %v can be spent on-chain", properties["amount_sat"], properties["counterparty_node_id"])
+	case "nwc_node_started":
+		return "Node started"
+	case "nwc_node_start_failed":
+		return "Node failed to start"
+	case "nwc_node_stopped":
+		return "Node stopped"
+	case "nwc_node_sync_failed":
+		// fee estimates are refreshed often and recover by themselves
+		if properties["sync_type"] != "full" {
+			return ""
+		}
+		return fmt.Sprintf("Node sync failed: %v", properties["error"])
+	case "nwc_backup_failed":
+		return fmt.Sprintf("Backup to %v failed: %v", properties["name"], properties["error"])
+	case "nwc_low_balance":
+		return fmt.Sprintf("Low balance: %v sats spendable, below %v sats", properties["spendable_sat"], properties["threshold_sat"])
+	case "nwc_low_inbound_liquidity":
+		return fmt.Sprintf("Low inbound liquidity: %v sats receivable, below %v sats", properties["receivable_sat"], properties["threshold_sat"])
+	case "nwc_onchain_fee_spike":
+		return fmt.Sprintf("On-chain fees of %v sat/vB are above your ceiling of %v sat/vB", properties["fee_rate"], properties["ceiling"])
+	case "nwc_onchain_fee_normal":
+		return fmt.Sprintf("On-chain fees fell to %v sat/vB, below your ceiling of %v sat/vB", properties["fee_rate"], properties["ceiling"])
+	}
+	return ""
+}
+
+func (bot *telegramBot) getUpdates(ctx context.Context, offset int64) ([]update, error) {
+	query := url.Values{}
+	query.Set("timeout", strconv.Itoa(pollTimeoutSeconds))
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	query.Set("allowed_updates", `["message"]`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bot.apiUrl+"/getUpdates?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var updates []update
+	if err := bot.do(req, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+func (bot *telegramBot) sendMessage(ctx context.Context, chatId int64, text string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id": chatId,
+		"text":    text,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, bot.apiUrl+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return bot.do(req, nil)
+}
+
+func (bot *telegramBot) do(req *http.Request, result interface{}) error {
+	res, err := bot.httpClient.Do(req)
+	if err != nil {
+		// the error contains the url, which contains the bot token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer res.Body.Close()
+
+	var response struct {
+		Ok          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return fmt.Errorf("unexpected telegram response status %d: %w", res.StatusCode, err)
+	}
+	if !response.Ok {
+		return fmt.Errorf("telegram request failed: %s", response.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/transactions"
+)
+
+type sentMessage struct {
+	ChatId int64  `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// mockTelegramApi returns the queued updates once and records the sent messages
+type mockTelegramApi struct {
+	mu       sync.Mutex
+	updates  []map[string]interface{}
+	messages []sentMessage
+}
+
+func (api *mockTelegramApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	switch r.URL.Path {
+	case "/bottoken/getUpdates":
+		updates := api.updates
+		api.updates = nil
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": updates})
+	case "/bottoken/sendMessage":
+		var message sentMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		api.messages = append(api.messages, message)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": map[string]interface{}{}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "description": "Not Found"})
+	}
+}
+
+func (api *mockTelegramApi) sentMessages() []sentMessage {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]sentMessage{}, api.messages...)
+}
+
+func newTestTelegramBot(t *testing.T, svc *tests.TestService, telegramApi *mockTelegramApi) *telegramBot {
+	server := httptest.NewServer(telegramApi)
+	t.Cleanup(server.Close)
+
+	bot, err := NewTelegramBot(svc.DB, svc.AppsService, transactions.NewTransactionsService(svc.DB, svc.EventPublisher), &config.AppConfig{
+		TelegramBotToken: "token",
+		TelegramChatIds:  "1, 2",
+		TelegramApiUrl:   server.URL,
+	}, func() lnclient.LNClient { return svc.LNClient })
+	require.NoError(t, err)
+	return bot
+}
+
+func TestParseChatIds(t *testing.T) {
+	chatIds, err := ParseChatIds("123, -100456,")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{123, -100456}, chatIds)
+
+	_, err = ParseChatIds("@channel")
+	assert.Error(t, err)
+	_, err = ParseChatIds(" ")
+	assert.Error(t, err)
+}
+
+func TestTelegramBot_Alerts(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	telegramApi := &mockTelegramApi{}
+	bot := newTestTelegramBot(t, svc, telegramApi)
+
+	bot.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_payment_received",
+		Properties: &db.Transaction{AmountMsat: 21_000, Description: "coffee"},
+	}, map[string]interface{}{})
+	bot.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_node_sync_failed",
+		Properties: map[string]interface{}{"sync_type": "fee_estimates", "error": "timeout"},
+	}, map[string]interface{}{})
+	bot.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_app_created",
+	}, map[string]interface{}{})
+
+	messages := telegramApi.sentMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, sentMessage{ChatId: 1, Text: "Received 21 sats: coffee"}, messages[0])
+	assert.Equal(t, sentMessage{ChatId: 2, Text: "Received 21 sats: coffee"}, messages[1])
+}
+
+func TestTelegramBot_Commands(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	telegramApi := &mockTelegramApi{
+		updates: []map[string]interface{}{
+			{"update_id": 10, "message": map[string]interface{}{"text": "/pause", "chat": map[string]interface{}{"id": 3}}},
+			{"update_id": 11, "message": map[string]interface{}{"text": "/balance@hub_bot", "chat": map[string]interface{}{"id": 1}}},
+			{"update_id": 12, "message": map[string]interface{}{"text": "/pause", "chat": map[string]interface{}{"id": 2}}},
+		},
+	}
+	bot := newTestTelegramBot(t, svc, telegramApi)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bot.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(telegramApi.sentMessages()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// chat 3 is not allowed
+	messages := telegramApi.sentMessages()
+	assert.Equal(t, sentMessage{ChatId: 1, Text: "Lightning: 21 sats\nOn-chain: 0 sats"}, messages[0])
+	assert.Equal(t, sentMessage{ChatId: 2, Text: "Paused 1 apps"}, messages[1])
+
+	var dbApp db.App
+	require.NoError(t, svc.DB.First(&dbApp, app.ID).Error)
+	assert.NotNil(t, dbApp.PausedAt)
+}
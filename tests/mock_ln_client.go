@@ -18,6 +18,8 @@ const MockPaymentHash = "23277d5e13fce5534f9752c62fcf9337a2a6b0ebea9d21fa816a4c9
 const MockZeroAmountInvoice = "lntbs1pnkjfgudqjd3hkueeqv4u8q6tj0ynp4qws83mqzuqptu5kfvxeles7qmyhsj6u2s6zyuft26mcr4tdmcupuupp533y9nwnsaktr9zlvyxmv97ta23faerygh3t9xvsfwytsr28lgggssp5mku3023z3kdxlpx6vrwtfxvvrxpffrquy6veex4ndk7rxhdtslhq9qyysgqcqpcxqxfvltyqva6y7k89jwtcljx399jl6wsq4lkq29vnm3rj4jxmapc6vcs358sx8mtpgh93rdc6ccqpxwwfga59zrla5m55zwzck2y2rsrxumu852sqkvpcm7"
 const MockZeroAmountPaymentHash = "8c4859ba70ed96328bec21b6c2f97d5453dc8c88bc56533209711701a8ff4211"
 
+const MockOnchainAddress = "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"
+
 var MockNodeInfo = lnclient.NodeInfo{
 	Alias:       "bob",
 	Color:       "#3399FF",
@@ -162,7 +164,7 @@ func (mln *MockLn) CloseChannel(ctx context.Context, closeChannelRequest *lnclie
 	return nil, nil
 }
 func (mln *MockLn) GetNewOnchainAddress(ctx context.Context) (string, error) {
-	return "", nil
+	return MockOnchainAddress, nil
 }
 func (mln *MockLn) GetBalances(ctx context.Context, includeInactiveChannels bool) (*lnclient.BalancesResponse, error) {
 	return &MockLNClientBalances, nil
@@ -94,6 +94,63 @@ func (_c *MockAlbyService_GetBitcoinRate_Call) RunAndReturn(run func(ctx context
 	return _c
 }
 
+// GetBitcoinRateForCurrency provides a mock function for the type MockAlbyService
+func (_mock *MockAlbyService) GetBitcoinRateForCurrency(ctx context.Context, currency string) (*alby.BitcoinRate, error) {
+	ret := _mock.Called(ctx, currency)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBitcoinRateForCurrency")
+	}
+
+	var r0 *alby.BitcoinRate
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*alby.BitcoinRate, error)); ok {
+		return returnFunc(ctx, currency)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *alby.BitcoinRate); ok {
+		r0 = returnFunc(ctx, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*alby.BitcoinRate)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAlbyService_GetBitcoinRateForCurrency_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetBitcoinRateForCurrency'
+type MockAlbyService_GetBitcoinRateForCurrency_Call struct {
+	*mock.Call
+}
+
+// GetBitcoinRateForCurrency is a helper method to define mock.On call
+//   - ctx
+//   - currency
+func (_e *MockAlbyService_Expecter) GetBitcoinRateForCurrency(ctx interface{}, currency interface{}) *MockAlbyService_GetBitcoinRateForCurrency_Call {
+	return &MockAlbyService_GetBitcoinRateForCurrency_Call{Call: _e.mock.On("GetBitcoinRateForCurrency", ctx, currency)}
+}
+
+func (_c *MockAlbyService_GetBitcoinRateForCurrency_Call) Run(run func(ctx context.Context, currency string)) *MockAlbyService_GetBitcoinRateForCurrency_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockAlbyService_GetBitcoinRateForCurrency_Call) Return(bitcoinRate *alby.BitcoinRate, err error) *MockAlbyService_GetBitcoinRateForCurrency_Call {
+	_c.Call.Return(bitcoinRate, err)
+	return _c
+}
+
+func (_c *MockAlbyService_GetBitcoinRateForCurrency_Call) RunAndReturn(run func(ctx context.Context, currency string) (*alby.BitcoinRate, error)) *MockAlbyService_GetBitcoinRateForCurrency_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetChannelPeerSuggestions provides a mock function for the type MockAlbyService
 func (_mock *MockAlbyService) GetChannelPeerSuggestions(ctx context.Context) ([]alby.ChannelPeerSuggestion, error) {
 	ret := _mock.Called(ctx)
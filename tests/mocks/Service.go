@@ -7,6 +7,7 @@ package mocks
 import (
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/deadletters"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/service"
@@ -228,6 +229,52 @@ func (_c *MockService_GetDB_Call) RunAndReturn(run func() *gorm.DB) *MockService
 	return _c
 }
 
+// GetDeadLetterService provides a mock function for the type MockService
+func (_mock *MockService) GetDeadLetterService() deadletters.DeadLetterService {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeadLetterService")
+	}
+
+	var r0 deadletters.DeadLetterService
+	if returnFunc, ok := ret.Get(0).(func() deadletters.DeadLetterService); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(deadletters.DeadLetterService)
+		}
+	}
+	return r0
+}
+
+// MockService_GetDeadLetterService_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDeadLetterService'
+type MockService_GetDeadLetterService_Call struct {
+	*mock.Call
+}
+
+// GetDeadLetterService is a helper method to define mock.On call
+func (_e *MockService_Expecter) GetDeadLetterService() *MockService_GetDeadLetterService_Call {
+	return &MockService_GetDeadLetterService_Call{Call: _e.mock.On("GetDeadLetterService")}
+}
+
+func (_c *MockService_GetDeadLetterService_Call) Run(run func()) *MockService_GetDeadLetterService_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockService_GetDeadLetterService_Call) Return(deadLetterService deadletters.DeadLetterService) *MockService_GetDeadLetterService_Call {
+	_c.Call.Return(deadLetterService)
+	return _c
+}
+
+func (_c *MockService_GetDeadLetterService_Call) RunAndReturn(run func() deadletters.DeadLetterService) *MockService_GetDeadLetterService_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetEventPublisher provides a mock function for the type MockService
 func (_mock *MockService) GetEventPublisher() events.EventPublisher {
 	ret := _mock.Called()
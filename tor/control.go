@@ -0,0 +1,133 @@
+// Package tor publishes the hub as an onion service through the control port of a
+// local Tor daemon, so it can be reached remotely without port forwarding.
+package tor
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const dialTimeout = 10 * time.Second
+
+// controller speaks the Tor control protocol
+// https://spec.torproject.org/control-spec/
+type controller struct {
+	conn *textproto.Conn
+}
+
+func dial(address string) (*controller, error) {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to tor control port: %w", err)
+	}
+	return &controller{conn: textproto.NewConn(conn)}, nil
+}
+
+func (c *controller) close() error {
+	return c.conn.Close()
+}
+
+// command sends a command and returns the lines of a successful reply
+func (c *controller) command(format string, args ...any) ([]string, error) {
+	id, err := c.conn.Cmd(format, args...)
+	if err != nil {
+		return nil, err
+	}
+	c.conn.StartResponse(id)
+	defer c.conn.EndResponse(id)
+	_, message, err := c.conn.ReadResponse(250)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(message, "\n"), nil
+}
+
+// authenticate uses the first supported method offered by the daemon
+func (c *controller) authenticate(password string) error {
+	lines, err := c.command("PROTOCOLINFO 1")
+	if err != nil {
+		return fmt.Errorf("failed to get tor protocol info: %w", err)
+	}
+
+	methods := []string{}
+	cookieFile := ""
+	for _, line := range lines {
+		fields, found := strings.CutPrefix(line, "AUTH ")
+		if !found {
+			continue
+		}
+		for _, field := range strings.Fields(fields) {
+			if value, found := strings.CutPrefix(field, "METHODS="); found {
+				methods = strings.Split(value, ",")
+			}
+			if value, found := strings.CutPrefix(field, "COOKIEFILE="); found {
+				cookieFile = strings.Trim(value, `"`)
+			}
+		}
+	}
+
+	credential := ""
+	switch {
+	case slices.Contains(methods, "NULL"):
+	case slices.Contains(methods, "HASHEDPASSWORD") && password != "":
+		credential = fmt.Sprintf("%q", password)
+	case slices.Contains(methods, "COOKIE") && cookieFile != "":
+		cookie, err := os.ReadFile(cookieFile)
+		if err != nil {
+			return fmt.Errorf("failed to read tor auth cookie: %w", err)
+		}
+		credential = hex.EncodeToString(cookie)
+	default:
+		return fmt.Errorf("no supported tor authentication method in %v, set TOR_CONTROL_PASSWORD or enable CookieAuthentication", methods)
+	}
+
+	if _, err := c.command("AUTHENTICATE %s", credential); err != nil {
+		return fmt.Errorf("failed to authenticate to tor: %w", err)
+	}
+	return nil
+}
+
+// addOnion publishes an onion service which forwards the virtual port to the target address.
+// A new key is generated if privateKey is empty. The service is removed again when the
+// control connection is closed.
+func (c *controller) addOnion(privateKey string, virtualPort int, target string) (serviceId string, newPrivateKey string, err error) {
+	key := privateKey
+	if key == "" {
+		key = "NEW:ED25519-V3"
+	}
+	lines, err := c.command("ADD_ONION %s Port=%d,%s", key, virtualPort, target)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to add onion service: %w", err)
+	}
+
+	newPrivateKey = privateKey
+	for _, line := range lines {
+		if value, found := strings.CutPrefix(line, "ServiceID="); found {
+			serviceId = value
+		}
+		if value, found := strings.CutPrefix(line, "PrivateKey="); found {
+			newPrivateKey = value
+		}
+	}
+	if serviceId == "" {
+		return "", "", errors.New("tor did not return a service id")
+	}
+	return serviceId, newPrivateKey, nil
+}
+
+// wait blocks until the control connection is closed
+func (c *controller) wait() error {
+	for {
+		// asynchronous events are not subscribed to, so this only returns once the connection is gone
+		if _, err := c.conn.ReadLine(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package tor
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTor answers control commands with the given replies and records the commands it received
+func fakeTor(t *testing.T, replies map[string]string) (string, *[]string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	commands := []string{}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimRight(line, "\r\n")
+			commands = append(commands, command)
+			reply, ok := replies[strings.Fields(command)[0]]
+			if !ok {
+				reply = "510 Unrecognized command"
+			}
+			conn.Write([]byte(strings.ReplaceAll(reply, "\n", "\r\n") + "\r\n"))
+		}
+	}()
+	return listener.Addr().String(), &commands
+}
+
+func TestAddOnion_CookieAuthentication(t *testing.T) {
+	cookieFile := filepath.Join(t.TempDir(), "control_auth_cookie")
+	require.NoError(t, os.WriteFile(cookieFile, []byte{0x01, 0x02, 0xab}, 0600))
+
+	address, commands := fakeTor(t, map[string]string{
+		"PROTOCOLINFO": "250-PROTOCOLINFO 1\n250-AUTH METHODS=COOKIE,SAFECOOKIE COOKIEFILE=\"" + cookieFile + "\"\n250-VERSION Tor=\"0.4.8.9\"\n250 OK",
+		"AUTHENTICATE": "250 OK",
+		"ADD_ONION":    "250-ServiceID=abcdefghijklmnop\n250-PrivateKey=ED25519-V3:secret\n250 OK",
+	})
+
+	controller, err := dial(address)
+	require.NoError(t, err)
+	defer controller.close()
+
+	require.NoError(t, controller.authenticate(""))
+	serviceId, privateKey, err := controller.addOnion("", 80, "127.0.0.1:8080")
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefghijklmnop", serviceId)
+	assert.Equal(t, "ED25519-V3:secret", privateKey)
+	assert.Equal(t, []string{
+		"PROTOCOLINFO 1",
+		"AUTHENTICATE 0102ab",
+		"ADD_ONION NEW:ED25519-V3 Port=80,127.0.0.1:8080",
+	}, *commands)
+}
+
+func TestAddOnion_ExistingKey(t *testing.T) {
+	address, commands := fakeTor(t, map[string]string{
+		"PROTOCOLINFO": "250-PROTOCOLINFO 1\n250-AUTH METHODS=HASHEDPASSWORD\n250 OK",
+		"AUTHENTICATE": "250 OK",
+		"ADD_ONION":    "250-ServiceID=abcdefghijklmnop\n250 OK",
+	})
+
+	controller, err := dial(address)
+	require.NoError(t, err)
+	defer controller.close()
+
+	require.NoError(t, controller.authenticate("hunter2"))
+	serviceId, privateKey, err := controller.addOnion("ED25519-V3:secret", 80, "127.0.0.1:8080")
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefghijklmnop", serviceId)
+	assert.Equal(t, "ED25519-V3:secret", privateKey)
+	assert.Equal(t, `AUTHENTICATE "hunter2"`, (*commands)[1])
+}
+
+func TestAuthenticate_Failure(t *testing.T) {
+	address, _ := fakeTor(t, map[string]string{
+		"PROTOCOLINFO": "250-PROTOCOLINFO 1\n250-AUTH METHODS=HASHEDPASSWORD\n250 OK",
+		"AUTHENTICATE": "515 Authentication failed: Password did not match HashedControlPassword value from configuration",
+	})
+
+	controller, err := dial(address)
+	require.NoError(t, err)
+	defer controller.close()
+
+	assert.ErrorContains(t, controller.authenticate("wrong"), "Authentication failed")
+	// without a password only cookie authentication could work
+	assert.ErrorContains(t, controller.authenticate(""), "no supported tor authentication method")
+}
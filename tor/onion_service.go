@@ -0,0 +1,101 @@
+package tor
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	// the onion service serves the hub on the default HTTP port
+	onionServicePort = 80
+	// tor may start after the hub or be restarted
+	retryInterval = 30 * time.Second
+)
+
+// PublishOnionService keeps the HTTP server of the hub published as an onion service until ctx is done.
+// The key is persisted, so the onion address stays the same across restarts. It is not encrypted
+// with the unlock password, as the hub has to be reachable to be unlocked remotely.
+func PublishOnionService(ctx context.Context, cfg config.Config, httpPort string) {
+	go func() {
+		for {
+			err := publishOnionService(ctx, cfg, httpPort)
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Logger.WithError(err).WithField("retry_in", retryInterval).Error("Onion service is not published")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+		}
+	}()
+}
+
+func publishOnionService(ctx context.Context, cfg config.Config, httpPort string) error {
+	controller, err := dial(cfg.GetEnv().TorControlAddress)
+	if err != nil {
+		return err
+	}
+	defer controller.close()
+
+	// closing the control connection removes the onion service
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			controller.close()
+		case <-done:
+		}
+	}()
+
+	err = controller.authenticate(cfg.GetEnv().TorControlPassword)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := cfg.Get(config.TorOnionPrivateKeyKey, "")
+	if err != nil {
+		return err
+	}
+	serviceId, newPrivateKey, err := controller.addOnion(privateKey, onionServicePort, net.JoinHostPort("127.0.0.1", httpPort))
+	if err != nil {
+		return err
+	}
+	if newPrivateKey != privateKey {
+		err = cfg.SetUpdate(config.TorOnionPrivateKeyKey, newPrivateKey, "")
+		if err != nil {
+			return err
+		}
+	}
+	onionAddress := serviceId + ".onion"
+	err = cfg.SetUpdate(config.TorOnionAddressKey, onionAddress, "")
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"onion_address": onionAddress,
+	}).Info("Published onion service")
+
+	return controller.wait()
+}
+
+// GetOnionUrl returns the URL of the hub's onion service, or an empty string if it is not published
+func GetOnionUrl(cfg config.Config) string {
+	if !cfg.GetEnv().TorEnabled {
+		return ""
+	}
+	onionAddress, _ := cfg.Get(config.TorOnionAddressKey, "")
+	if onionAddress == "" {
+		return ""
+	}
+	return "http://" + onionAddress
+}
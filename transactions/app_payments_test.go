@@ -1,6 +1,7 @@
 package transactions
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -25,7 +26,7 @@ func TestSendPaymentSync_App_NoPermission(t *testing.T) {
 	assert.NoError(t, err)
 
 	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
-	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
 
 	assert.Error(t, err)
 	assert.Equal(t, "app does not have pay_invoice scope", err.Error())
@@ -52,7 +53,7 @@ func TestSendPaymentSync_App_WithPermission(t *testing.T) {
 	assert.NoError(t, err)
 
 	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
-	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(123000), transaction.AmountMsat)
@@ -87,7 +88,7 @@ func TestSendPaymentSync_App_BudgetExceeded(t *testing.T) {
 	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
 
 	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
-	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
 
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, NewQuotaExceededError())
@@ -132,7 +133,7 @@ func TestSendPaymentSync_App_BudgetExceeded_SettledPayment(t *testing.T) {
 	assert.NoError(t, err)
 
 	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
-	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
 
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, NewQuotaExceededError())
@@ -169,7 +170,7 @@ func TestSendPaymentSync_App_BudgetExceeded_UnsettledPayment(t *testing.T) {
 	assert.NoError(t, err)
 
 	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
-	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
 
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, NewQuotaExceededError())
@@ -207,7 +208,7 @@ func TestSendPaymentSync_App_BudgetNotExceeded_FailedPayment(t *testing.T) {
 	assert.NoError(t, err)
 
 	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
-	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(123000), transaction.AmountMsat)
@@ -216,3 +217,94 @@ func TestSendPaymentSync_App_BudgetNotExceeded_FailedPayment(t *testing.T) {
 	assert.Equal(t, app.ID, *transaction.AppId)
 	assert.Equal(t, dbRequestEvent.ID, *transaction.RequestEventId)
 }
+
+func TestSendPaymentSync_App_AdditionalBudgetExceeded(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:         app.ID,
+		App:           *app,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  100_000,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	// the daily budget is more restrictive than the monthly one
+	err = svc.DB.Create(&db.AppBudget{
+		AppId:         app.ID,
+		MaxAmountSat:  133,
+		BudgetRenewal: constants.BUDGET_RENEWAL_DAILY,
+	}).Error
+	assert.NoError(t, err)
+
+	svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		AmountMsat: 1000,
+		CreatedAt:  time.Now(),
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+
+	assert.ErrorIs(t, err, NewQuotaExceededError())
+	assert.Nil(t, transaction)
+
+	// a payment made yesterday does not count towards the daily budget
+	err = svc.DB.Model(&db.Transaction{}).Where("app_id = ?", app.ID).Update("created_at", time.Now().AddDate(0, 0, -1)).Error
+	assert.NoError(t, err)
+
+	transaction, err = transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestSendPaymentSync_App_GroupBudgetExceeded(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	appGroup := &db.AppGroup{
+		Name:          "podcast apps",
+		MaxAmountSat:  133,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	}
+	require.NoError(t, svc.DB.Create(appGroup).Error)
+
+	var groupApps []*db.App
+	for i := 0; i < 2; i++ {
+		app, _, err := tests.CreateApp(svc)
+		require.NoError(t, err)
+		app.AppGroupId = &appGroup.ID
+		require.NoError(t, svc.DB.Save(app).Error)
+		require.NoError(t, svc.DB.Create(&db.AppPermission{
+			AppId: app.ID,
+			App:   *app,
+			Scope: constants.PAY_INVOICE_SCOPE,
+		}).Error)
+		groupApps = append(groupApps, app)
+	}
+
+	// a payment by one member counts towards the budget of the other
+	svc.DB.Create(&db.Transaction{
+		AppId:      &groupApps[0].ID,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		AmountMsat: 1000,
+		CreatedAt:  time.Now(),
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &groupApps[1].ID, nil)
+
+	assert.ErrorIs(t, err, NewQuotaExceededError())
+	assert.Nil(t, transaction)
+}
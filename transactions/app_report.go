@@ -0,0 +1,164 @@
+package transactions
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+)
+
+const defaultAppReportTopDestinations = 5
+
+type DestinationTotals struct {
+	// node pubkey of the recipient
+	Destination string
+	Count       uint64
+	AmountMsat  uint64
+	FeeMsat     uint64
+}
+
+// BudgetUtilization is the spending of an app in the current period of one of its budgets,
+// which is independent of the period of the report
+type BudgetUtilization struct {
+	BudgetRenewal string
+	MaxAmountSat  uint64
+	UsedSat       uint64
+	RenewsAt      *uint64
+}
+
+type AppReport struct {
+	AppId           uint
+	From            time.Time
+	Until           time.Time
+	Currency        string
+	Sent            SummaryTotals
+	Received        SummaryTotals
+	TopDestinations []DestinationTotals
+	Budgets         []BudgetUtilization
+}
+
+// GetAppReport summarizes where the money of an app went within [from, until)
+func (svc *transactionsService) GetAppReport(appId uint, from time.Time, until time.Time, currency string) (*AppReport, error) {
+	if !from.Before(until) {
+		return nil, errors.New("from must be before until")
+	}
+	var app db.App
+	if svc.db.Limit(1).Find(&app, &db.App{ID: appId}).RowsAffected == 0 {
+		return nil, NewNotFoundError()
+	}
+
+	report := &AppReport{
+		AppId:           appId,
+		From:            from,
+		Until:           until,
+		Currency:        strings.ToUpper(currency),
+		TopDestinations: []DestinationTotals{},
+		Budgets:         []BudgetUtilization{},
+	}
+
+	rows, err := svc.querySummary(from, until, &appId, report.Currency, "", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		addSummaryRow(&report.Sent, &report.Received, &row)
+	}
+
+	report.TopDestinations, err = svc.getTopDestinations(appId, from, until, defaultAppReportTopDestinations)
+	if err != nil {
+		return nil, err
+	}
+
+	var appPermission db.AppPermission
+	if svc.db.Limit(1).Find(&appPermission, &db.AppPermission{AppId: appId, Scope: constants.PAY_INVOICE_SCOPE}).RowsAffected > 0 && appPermission.MaxAmountSat > 0 {
+		report.Budgets = append(report.Budgets, BudgetUtilization{
+			BudgetRenewal: appPermission.BudgetRenewal,
+			MaxAmountSat:  uint64(appPermission.MaxAmountSat),
+			UsedSat:       queries.GetBudgetUsageSat(svc.db, &appPermission),
+			RenewsAt:      queries.GetBudgetRenewsAt(appPermission.BudgetRenewal),
+		})
+	}
+	var appBudgets []db.AppBudget
+	if err := svc.db.Where("app_id = ?", appId).Order("id").Find(&appBudgets).Error; err != nil {
+		return nil, err
+	}
+	for _, appBudget := range appBudgets {
+		report.Budgets = append(report.Budgets, BudgetUtilization{
+			BudgetRenewal: appBudget.BudgetRenewal,
+			MaxAmountSat:  appBudget.MaxAmountSat,
+			UsedSat:       queries.GetBudgetUsageSatForPeriod(svc.db, appId, appBudget.BudgetRenewal),
+			RenewsAt:      queries.GetBudgetRenewsAt(appBudget.BudgetRenewal),
+		})
+	}
+
+	return report, nil
+}
+
+// getTopDestinations returns the recipients the app sent the most to. Destinations are not stored
+// separately, so they are taken from the invoice or, for keysend payments, the metadata.
+func (svc *transactionsService) getTopDestinations(appId uint, from time.Time, until time.Time, limit int) ([]DestinationTotals, error) {
+	var dbTransactions []db.Transaction
+	err := svc.db.
+		Select("payment_request", "metadata", "amount_msat", "fee_msat").
+		Where("app_id = ? AND type = ? AND state = ? AND settled_at >= ? AND settled_at < ?", appId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, from, until).
+		Find(&dbTransactions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totalsByDestination := map[string]*DestinationTotals{}
+	for _, dbTransaction := range dbTransactions {
+		destination := getTransactionDestination(&dbTransaction)
+		if destination == "" {
+			continue
+		}
+		totals, ok := totalsByDestination[destination]
+		if !ok {
+			totals = &DestinationTotals{Destination: destination}
+			totalsByDestination[destination] = totals
+		}
+		totals.Count++
+		totals.AmountMsat += dbTransaction.AmountMsat
+		totals.FeeMsat += dbTransaction.FeeMsat
+	}
+
+	destinations := []DestinationTotals{}
+	for _, totals := range totalsByDestination {
+		destinations = append(destinations, *totals)
+	}
+	sort.Slice(destinations, func(i, j int) bool {
+		if destinations[i].AmountMsat != destinations[j].AmountMsat {
+			return destinations[i].AmountMsat > destinations[j].AmountMsat
+		}
+		return destinations[i].Destination < destinations[j].Destination
+	})
+	if len(destinations) > limit {
+		destinations = destinations[:limit]
+	}
+	return destinations, nil
+}
+
+func getTransactionDestination(dbTransaction *db.Transaction) string {
+	if dbTransaction.PaymentRequest != "" {
+		paymentRequest, err := decodepay.Decodepay(dbTransaction.PaymentRequest)
+		if err == nil {
+			return paymentRequest.Payee
+		}
+	}
+	if dbTransaction.Metadata != nil {
+		var metadata map[string]interface{}
+		if json.Unmarshal(dbTransaction.Metadata, &metadata) == nil {
+			if destination, ok := metadata["destination"].(string); ok {
+				return destination
+			}
+		}
+	}
+	return ""
+}
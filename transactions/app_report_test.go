@@ -0,0 +1,80 @@
+package transactions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestGetAppReport(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId:         app.ID,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  10_000,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	}).Error)
+	require.NoError(t, svc.DB.Create(&db.AppBudget{
+		AppId:         app.ID,
+		MaxAmountSat:  1_000,
+		BudgetRenewal: constants.BUDGET_RENEWAL_DAILY,
+	}).Error)
+
+	now := time.Now()
+	lastYear := now.AddDate(-1, 0, 0)
+	keysendDestination := "02aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	for _, transaction := range []db.Transaction{
+		{Type: constants.TRANSACTION_TYPE_OUTGOING, AmountMsat: 123_000, FeeMsat: 1_000, PaymentRequest: tests.MockInvoice, PaymentHash: "1"},
+		{Type: constants.TRANSACTION_TYPE_OUTGOING, AmountMsat: 5_000, PaymentHash: "2", Metadata: datatypes.JSON(`{"destination":"` + keysendDestination + `"}`)},
+		{Type: constants.TRANSACTION_TYPE_OUTGOING, AmountMsat: 7_000, PaymentHash: "3", Metadata: datatypes.JSON(`{"destination":"` + keysendDestination + `"}`)},
+		{Type: constants.TRANSACTION_TYPE_INCOMING, AmountMsat: 50_000, PaymentHash: "4"},
+		// outside of the period
+		{Type: constants.TRANSACTION_TYPE_OUTGOING, AmountMsat: 999_000, PaymentHash: "5", SettledAt: &lastYear, CreatedAt: lastYear},
+	} {
+		transaction.AppId = &app.ID
+		transaction.State = constants.TRANSACTION_STATE_SETTLED
+		if transaction.SettledAt == nil {
+			transaction.SettledAt = &now
+		}
+		require.NoError(t, svc.DB.Create(&transaction).Error)
+	}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	report, err := transactionsService.GetAppReport(app.ID, now.AddDate(0, 0, -30), now.Add(time.Minute), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(3), report.Sent.Count)
+	assert.Equal(t, uint64(135_000), report.Sent.AmountMsat)
+	assert.Equal(t, uint64(1_000), report.Sent.FeeMsat)
+	assert.Equal(t, uint64(1), report.Received.Count)
+	assert.Equal(t, uint64(50_000), report.Received.AmountMsat)
+
+	require.Len(t, report.TopDestinations, 2)
+	assert.Equal(t, "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c", report.TopDestinations[0].Destination)
+	assert.Equal(t, uint64(123_000), report.TopDestinations[0].AmountMsat)
+	assert.Equal(t, keysendDestination, report.TopDestinations[1].Destination)
+	assert.Equal(t, uint64(2), report.TopDestinations[1].Count)
+	assert.Equal(t, uint64(12_000), report.TopDestinations[1].AmountMsat)
+
+	require.Len(t, report.Budgets, 2)
+	assert.Equal(t, constants.BUDGET_RENEWAL_MONTHLY, report.Budgets[0].BudgetRenewal)
+	assert.Equal(t, uint64(10_000), report.Budgets[0].MaxAmountSat)
+	assert.Equal(t, uint64(136), report.Budgets[0].UsedSat)
+	assert.Equal(t, constants.BUDGET_RENEWAL_DAILY, report.Budgets[1].BudgetRenewal)
+	assert.Equal(t, uint64(136), report.Budgets[1].UsedSat)
+
+	_, err = transactionsService.GetAppReport(app.ID+1, now.AddDate(0, 0, -30), now, "")
+	assert.ErrorIs(t, err, NewNotFoundError())
+}
@@ -0,0 +1,267 @@
+package transactions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// transactions are only archived once they are older than any renewing budget period
+const MinArchiveRetentionMonths = 12
+
+const transactionsArchiveVersion = 1
+
+// TransactionsArchive is the content of an archive file, encrypted with the unlock password
+type TransactionsArchive struct {
+	Version      int              `json:"version"`
+	CreatedAt    time.Time        `json:"createdAt"`
+	Before       time.Time        `json:"before"`
+	Transactions []db.Transaction `json:"transactions"`
+}
+
+type ArchiveResult struct {
+	Before        time.Time
+	ArchivedCount uint64
+}
+
+type archivedTotalsKey struct {
+	appId           uint // 0 for transactions not linked to an app
+	transactionType string
+	day             string
+}
+
+// ArchiveTransactions writes all completed transactions created more than retentionMonths ago
+// to an encrypted archive and removes them from the transactions table. The daily totals of settled
+// transactions are kept so that isolated app balances, lifetime budgets and summaries are not affected.
+// Nothing is pruned if the archive cannot be written.
+func (svc *transactionsService) ArchiveTransactions(retentionMonths uint, password string, w io.Writer) (*ArchiveResult, error) {
+	if retentionMonths < MinArchiveRetentionMonths {
+		return nil, fmt.Errorf("retention period must be at least %d months", MinArchiveRetentionMonths)
+	}
+	if password == "" {
+		return nil, errors.New("no password provided to encrypt the archive")
+	}
+
+	now := time.Now()
+	result := &ArchiveResult{
+		Before: now.AddDate(0, -int(retentionMonths), 0),
+	}
+
+	err := svc.db.Transaction(func(tx *gorm.DB) error {
+		var archivedTransactions []db.Transaction
+		err := tx.
+			Where("created_at < ? AND state != ?", result.Before, constants.TRANSACTION_STATE_PENDING).
+			Order("id").
+			Find(&archivedTransactions).Error
+		if err != nil {
+			return err
+		}
+		if len(archivedTransactions) == 0 {
+			return nil
+		}
+
+		archive, err := json.Marshal(&TransactionsArchive{
+			Version:      transactionsArchiveVersion,
+			CreatedAt:    now,
+			Before:       result.Before,
+			Transactions: archivedTransactions,
+		})
+		if err != nil {
+			return err
+		}
+		encryptedArchive, err := config.AesGcmEncryptWithPassword(string(archive), password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt archive: %w", err)
+		}
+		if _, err := io.WriteString(w, encryptedArchive); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		if err := flushArchive(w); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+
+		if err := addArchivedTotals(tx, archivedTransactions); err != nil {
+			return err
+		}
+
+		lastId := archivedTransactions[len(archivedTransactions)-1].ID
+		err = tx.
+			Where("created_at < ? AND state != ? AND id <= ?", result.Before, constants.TRANSACTION_STATE_PENDING, lastId).
+			Delete(&db.Transaction{}).Error
+		if err != nil {
+			return err
+		}
+
+		result.ArchivedCount = uint64(len(archivedTransactions))
+		return nil
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to archive transactions")
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"before":         result.Before,
+		"archived_count": result.ArchivedCount,
+	}).Info("Archived transactions")
+
+	if result.ArchivedCount > 0 {
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_transactions_archived",
+			Properties: map[string]interface{}{
+				"before":         result.Before,
+				"archived_count": result.ArchivedCount,
+			},
+		})
+	}
+
+	return result, nil
+}
+
+// flushArchive makes sure the archive reached files and HTTP clients before anything is pruned
+func flushArchive(w io.Writer) error {
+	switch w := w.(type) {
+	case interface{ Sync() error }:
+		return w.Sync()
+	case interface{ Flush() error }:
+		return w.Flush()
+	}
+	return nil
+}
+
+func addArchivedTotals(tx *gorm.DB, archivedTransactions []db.Transaction) error {
+	fiatAmounts, err := getArchivedFiatAmounts(tx, archivedTransactions)
+	if err != nil {
+		return err
+	}
+
+	totals := map[archivedTotalsKey]*db.ArchivedTransactionTotal{}
+	keys := []archivedTotalsKey{}
+	for _, transaction := range archivedTransactions {
+		if transaction.State != constants.TRANSACTION_STATE_SETTLED {
+			continue
+		}
+		settledAt := transaction.CreatedAt
+		if transaction.SettledAt != nil {
+			settledAt = *transaction.SettledAt
+		}
+		key := archivedTotalsKey{transactionType: transaction.Type, day: settledAt.UTC().Format(time.DateOnly)}
+		if transaction.AppId != nil {
+			key.appId = *transaction.AppId
+		}
+		total, ok := totals[key]
+		if !ok {
+			total = &db.ArchivedTransactionTotal{
+				AppId:       transaction.AppId,
+				Type:        transaction.Type,
+				Day:         key.day,
+				FiatAmounts: map[string]float64{},
+			}
+			totals[key] = total
+			keys = append(keys, key)
+		}
+		total.Count++
+		total.AmountMsat += transaction.AmountMsat
+		total.FeeMsat += transaction.FeeMsat
+		for currency, fiatAmount := range fiatAmounts[transaction.ID] {
+			total.FiatAmounts[currency] += fiatAmount
+		}
+	}
+
+	for _, key := range keys {
+		total := totals[key]
+		var existing db.ArchivedTransactionTotal
+		query := tx.Where("type = ? AND day = ?", total.Type, total.Day)
+		if total.AppId != nil {
+			query = query.Where("app_id = ?", *total.AppId)
+		} else {
+			query = query.Where("app_id IS NULL")
+		}
+		if query.Limit(1).Find(&existing).RowsAffected == 0 {
+			if err := tx.Create(total).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		if existing.FiatAmounts == nil {
+			existing.FiatAmounts = map[string]float64{}
+		}
+		for currency, fiatAmount := range total.FiatAmounts {
+			existing.FiatAmounts[currency] += fiatAmount
+		}
+		existing.Count += total.Count
+		existing.AmountMsat += total.AmountMsat
+		existing.FeeMsat += total.FeeMsat
+		err := tx.Model(&existing).Select("count", "amount_msat", "fee_msat", "fiat_amounts").Updates(&existing).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getArchivedFiatAmounts returns the value of each transaction in the currencies it has a rate for,
+// as the rates are removed together with the transactions
+func getArchivedFiatAmounts(tx *gorm.DB, archivedTransactions []db.Transaction) (map[uint]map[string]float64, error) {
+	amounts := map[uint]uint64{}
+	transactionIds := []uint{}
+	for _, transaction := range archivedTransactions {
+		if transaction.State == constants.TRANSACTION_STATE_SETTLED {
+			amounts[transaction.ID] = transaction.AmountMsat
+			transactionIds = append(transactionIds, transaction.ID)
+		}
+	}
+
+	fiatAmounts := map[uint]map[string]float64{}
+	for chunk := range slices.Chunk(transactionIds, fiatRatesChunkSize) {
+		var fiatRates []db.TransactionFiatRate
+		if err := tx.Where("transaction_id IN ?", chunk).Find(&fiatRates).Error; err != nil {
+			return nil, err
+		}
+		for _, fiatRate := range fiatRates {
+			if fiatAmounts[fiatRate.TransactionId] == nil {
+				fiatAmounts[fiatRate.TransactionId] = map[string]float64{}
+			}
+			fiatAmounts[fiatRate.TransactionId][fiatRate.Currency] = float64(amounts[fiatRate.TransactionId]) * fiatRate.Rate / msatPerBtc
+		}
+	}
+	return fiatAmounts, nil
+}
+
+// ReadTransactionsArchive decrypts an archive created by ArchiveTransactions
+func ReadTransactionsArchive(r io.Reader, password string) (*TransactionsArchive, error) {
+	encryptedArchive, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ciphertext has the format salt-nonce-data
+	if len(strings.Split(string(encryptedArchive), "-")) != 3 {
+		return nil, errors.New("invalid archive file")
+	}
+
+	archive, err := config.AesGcmDecryptWithPassword(string(encryptedArchive), password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt archive: %w", err)
+	}
+
+	var transactionsArchive TransactionsArchive
+	if err := json.Unmarshal([]byte(archive), &transactionsArchive); err != nil {
+		return nil, fmt.Errorf("failed to parse archive: %w", err)
+	}
+	return &transactionsArchive, nil
+}
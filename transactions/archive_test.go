@@ -0,0 +1,179 @@
+package transactions
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestArchiveTransactions(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	app.Isolated = true
+	require.NoError(t, svc.DB.Save(&app).Error)
+
+	old := time.Now().AddDate(-2, 0, 0)
+	recent := time.Now().AddDate(0, -1, 0)
+	transactions := []db.Transaction{
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 10_000, PaymentHash: "1", CreatedAt: old, SettledAt: &old},
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 4_000, FeeMsat: 100, PaymentHash: "2", CreatedAt: old, SettledAt: &old},
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_FAILED, AmountMsat: 1_000, PaymentHash: "3", CreatedAt: old},
+		{Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 5_000, PaymentHash: "4", CreatedAt: old, SettledAt: &old},
+		// kept
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_PENDING, AmountMsat: 1_000, PaymentHash: "5", CreatedAt: old},
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 2_000, PaymentHash: "6", CreatedAt: recent, SettledAt: &recent},
+	}
+	require.NoError(t, svc.DB.Create(&transactions).Error)
+
+	balanceBefore := queries.GetIsolatedBalance(svc.DB, app.ID)
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	var buffer bytes.Buffer
+	result, err := transactionsService.ArchiveTransactions(12, "password", &buffer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), result.ArchivedCount)
+
+	var remainingTransactions []db.Transaction
+	require.NoError(t, svc.DB.Order("id").Find(&remainingTransactions).Error)
+	require.Equal(t, 2, len(remainingTransactions))
+	assert.Equal(t, "5", remainingTransactions[0].PaymentHash)
+	assert.Equal(t, "6", remainingTransactions[1].PaymentHash)
+
+	assert.Equal(t, balanceBefore, queries.GetIsolatedBalance(svc.DB, app.ID))
+
+	var archivedTotals []db.ArchivedTransactionTotal
+	require.NoError(t, svc.DB.Order("id").Find(&archivedTotals).Error)
+	require.Equal(t, 3, len(archivedTotals))
+
+	archive, err := ReadTransactionsArchive(bytes.NewReader(buffer.Bytes()), "password")
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(archive.Transactions))
+	assert.Equal(t, "1", archive.Transactions[0].PaymentHash)
+
+	_, err = ReadTransactionsArchive(bytes.NewReader(buffer.Bytes()), "wrong password")
+	assert.Error(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, []string{"nwc_transactions_archived"}, getConsumedEventNames(mockEventConsumer))
+
+	// archiving again adds to the existing totals
+	later := time.Now().AddDate(-1, -1, 0)
+	require.NoError(t, svc.DB.Create(&db.Transaction{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 1_000, PaymentHash: "7", CreatedAt: later, SettledAt: &later}).Error)
+	balanceBefore = queries.GetIsolatedBalance(svc.DB, app.ID)
+	buffer.Reset()
+	result, err = transactionsService.ArchiveTransactions(12, "password", &buffer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), result.ArchivedCount)
+	assert.Equal(t, balanceBefore, queries.GetIsolatedBalance(svc.DB, app.ID))
+
+	var appIncomingTotals []db.ArchivedTransactionTotal
+	require.NoError(t, svc.DB.Where("app_id = ? AND type = ?", app.ID, constants.TRANSACTION_TYPE_INCOMING).Order("day").Find(&appIncomingTotals).Error)
+	require.Equal(t, 2, len(appIncomingTotals))
+	assert.Equal(t, old.UTC().Format(time.DateOnly), appIncomingTotals[0].Day)
+	assert.Equal(t, uint64(10_000), appIncomingTotals[0].AmountMsat)
+	assert.Equal(t, later.UTC().Format(time.DateOnly), appIncomingTotals[1].Day)
+	assert.Equal(t, uint64(1_000), appIncomingTotals[1].AmountMsat)
+}
+
+func TestArchiveTransactions_KeepsSummary(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	day1 := time.Now().AddDate(-2, 0, 0).UTC().Truncate(24 * time.Hour).Add(10 * time.Hour)
+	day2 := day1.AddDate(0, 0, 1)
+	transactions := []db.Transaction{
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 10_000, PaymentHash: "1", CreatedAt: day1, SettledAt: &day1},
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 20_000, PaymentHash: "2", CreatedAt: day1, SettledAt: &day1},
+		{Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 4_000, FeeMsat: 100, PaymentHash: "3", CreatedAt: day2, SettledAt: &day2},
+	}
+	require.NoError(t, svc.DB.Create(&transactions).Error)
+	require.NoError(t, svc.DB.Create(&[]db.TransactionFiatRate{
+		{TransactionId: transactions[0].ID, Currency: "USD", Rate: 50_000},
+		{TransactionId: transactions[1].ID, Currency: "USD", Rate: 60_000},
+		{TransactionId: transactions[2].ID, Currency: "USD", Rate: 70_000},
+	}).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	from := day1.Truncate(24 * time.Hour)
+	until := from.AddDate(0, 1, 0)
+	summaryBefore, err := transactionsService.GetSummary(from, until, nil, "USD")
+	require.NoError(t, err)
+
+	result, err := transactionsService.ArchiveTransactions(12, "password", &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), result.ArchivedCount)
+
+	summaryAfter, err := transactionsService.GetSummary(from, until, nil, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, summaryBefore.Sent.Count, summaryAfter.Sent.Count)
+	assert.Equal(t, summaryBefore.Sent.AmountMsat, summaryAfter.Sent.AmountMsat)
+	assert.Equal(t, summaryBefore.Sent.FeeMsat, summaryAfter.Sent.FeeMsat)
+	assert.Equal(t, summaryBefore.Received.Count, summaryAfter.Received.Count)
+	assert.Equal(t, summaryBefore.Received.AmountMsat, summaryAfter.Received.AmountMsat)
+	assert.InDelta(t, summaryBefore.Received.FiatAmount, summaryAfter.Received.FiatAmount, 0.000001)
+	assert.InDelta(t, summaryBefore.Sent.FiatAmount, summaryAfter.Sent.FiatAmount, 0.000001)
+	assert.Equal(t, len(summaryBefore.Apps), len(summaryAfter.Apps))
+	require.Equal(t, 2, len(summaryAfter.Days))
+	assert.Equal(t, day1.Format(time.DateOnly), summaryAfter.Days[0].Date)
+	assert.Equal(t, uint64(30_000), summaryAfter.Days[0].Received.AmountMsat)
+
+	appSummary, err := transactionsService.GetSummary(from, until, &app.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), appSummary.Received.Count)
+	assert.Zero(t, appSummary.Sent.Count)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("connection closed")
+}
+
+func TestArchiveTransactions_WriteFailure(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	old := time.Now().AddDate(-2, 0, 0)
+	require.NoError(t, svc.DB.Create(&db.Transaction{Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 1_000, PaymentHash: "1", CreatedAt: old, SettledAt: &old}).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.ArchiveTransactions(12, "password", failingWriter{})
+	assert.ErrorContains(t, err, "failed to write archive")
+
+	var count int64
+	require.NoError(t, svc.DB.Model(&db.Transaction{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, svc.DB.Model(&db.ArchivedTransactionTotal{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestArchiveTransactions_MinimumRetention(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.ArchiveTransactions(6, "password", &bytes.Buffer{})
+	assert.EqualError(t, err, "retention period must be at least 12 months")
+}
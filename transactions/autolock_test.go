@@ -0,0 +1,34 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/autolock"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSendPaymentSync_SpendingLocked(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	autolock.Start(ctx, time.Hour, func() {})
+	autolock.Lock()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.ErrorIs(t, err, NewSpendingLockedError())
+	assert.Nil(t, transaction)
+
+	autolock.Unlock()
+	transaction, err = transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, transaction)
+}
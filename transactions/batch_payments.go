@@ -0,0 +1,259 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+// maxBatchPaymentConcurrency limits how many payments of a batch are in flight at once
+var maxBatchPaymentConcurrency = 5
+
+type BatchPayment struct {
+	Invoice    string
+	AmountMsat *uint64
+	Metadata   map[string]interface{}
+	// optional, a retried payment with the same key returns the result of the first one
+	IdempotencyKey string
+}
+
+type BatchPaymentItemResult struct {
+	Transaction *Transaction
+	Error       error
+}
+
+type BatchPaymentResult struct {
+	// results are in the same order as the requested payments
+	Items           []BatchPaymentItemResult
+	TotalAmountMsat uint64
+	TotalFeeMsat    uint64
+	SucceededCount  int
+	FailedCount     int
+}
+
+// BatchPaymentItemDoneFunc is called with the result of a payment of a batch as soon as it is known.
+// It is called from several goroutines at once.
+type BatchPaymentItemDoneFunc func(index int, item BatchPaymentItemResult)
+
+// SendPaymentBatch pays a list of invoices as one batch. The batch is not atomic: payments are reserved
+// one by one, in order, against the app balance and budget under one lock before any payment is made,
+// so the batch can never spend more than allowed. Payments which do not fit fail on their own and the
+// others are then made with bounded concurrency.
+// Approvals for payments above the approval threshold are requested together before the reservation.
+// onItemDone, if set, receives each result as soon as it is known. If an error is returned,
+// the payments which were not reported have not been made.
+func (svc *transactionsService) SendPaymentBatch(ctx context.Context, payments []BatchPayment, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, onItemDone BatchPaymentItemDoneFunc) (*BatchPaymentResult, error) {
+	if len(payments) == 0 {
+		return nil, errors.New("no payments provided")
+	}
+	if err := CheckSpendingAllowed(svc.db); err != nil {
+		return nil, err
+	}
+	if err := svc.payments.start(); err != nil {
+		return nil, err
+	}
+	defer svc.payments.done()
+	if len(payments) > 1 && svc.isSingleUseApp(appId) {
+		return nil, NewSingleUseConsumedError()
+	}
+
+	items := make([]BatchPaymentItemResult, len(payments))
+	reported := make([]bool, len(payments))
+	report := func(i int) {
+		reported[i] = true
+		if onItemDone != nil {
+			onItemDone(i, items[i])
+		}
+	}
+	reportFinished := func() {
+		for i, item := range items {
+			if (item.Error != nil || item.Transaction != nil) && !reported[i] {
+				report(i)
+			}
+		}
+	}
+
+	preparedPayments := make([]*preparedPayment, len(payments))
+	paymentHashes := map[string]bool{}
+	idempotencyKeys := map[string]bool{}
+	for i, payment := range payments {
+		if err := validateIdempotencyKey(payment.IdempotencyKey); err != nil {
+			items[i].Error = err
+			continue
+		}
+		preparedPayment, err := svc.preparePayment(payment.Invoice, payment.AmountMsat, payment.Metadata, lnClient)
+		if err != nil {
+			items[i].Error = err
+			continue
+		}
+		if payment.IdempotencyKey != "" {
+			if idempotencyKeys[payment.IdempotencyKey] {
+				items[i].Error = errors.New("idempotency key is included more than once in the batch")
+				continue
+			}
+			idempotencyKeys[payment.IdempotencyKey] = true
+			preparedPayment.idempotencyKey = &payment.IdempotencyKey
+		}
+		paymentHash := preparedPayment.paymentRequest.PaymentHash
+		if paymentHashes[paymentHash] {
+			items[i].Error = errors.New("invoice is included more than once in the batch")
+			continue
+		}
+		paymentHashes[paymentHash] = true
+
+		err = svc.validatePaymentDestination(appId, preparedPayment.paymentRequest.Payee)
+		if err != nil {
+			items[i].Error = err
+			continue
+		}
+		// a retried or already paid payment must not ask the user for approval again
+		if payment.IdempotencyKey != "" {
+			idempotentTransaction, err := findIdempotentPayment(svc.db, appId, payment.IdempotencyKey)
+			if err != nil {
+				items[i].Error = err
+				continue
+			}
+			if idempotentTransaction != nil {
+				items[i].Transaction, items[i].Error = getIdempotentResult(idempotentTransaction, paymentHash)
+				continue
+			}
+		}
+		if err := checkNotAlreadyPaid(svc.db, paymentHash); err != nil {
+			items[i].Error = err
+			continue
+		}
+		preparedPayments[i] = preparedPayment
+	}
+	reportFinished()
+
+	svc.waitForBatchPaymentApprovals(preparedPayments, items, appId, requestEventId)
+	reportFinished()
+
+	dbTransactions := make([]*db.Transaction, len(payments))
+	err := func() error {
+		balanceValidationLock.Lock()
+		defer balanceValidationLock.Unlock()
+		return svc.db.Transaction(func(tx *gorm.DB) error {
+			for i, payment := range preparedPayments {
+				if payment == nil {
+					continue
+				}
+				if payment.idempotencyKey != nil {
+					idempotentTransaction, err := findIdempotentPayment(tx, appId, *payment.idempotencyKey)
+					if err != nil {
+						return err
+					}
+					if idempotentTransaction != nil {
+						items[i].Transaction, items[i].Error = getIdempotentResult(idempotentTransaction, payment.paymentRequest.PaymentHash)
+						preparedPayments[i] = nil
+						continue
+					}
+				}
+				if err := checkNotAlreadyPaid(tx, payment.paymentRequest.PaymentHash); err != nil {
+					items[i].Error = err
+					continue
+				}
+				if err := svc.validateCanPay(tx, appId, payment.amountMsat, payment.paymentRequest.Description, payment.selfPayment); err != nil {
+					items[i].Error = err
+					continue
+				}
+				// the pending payment is included in the balance and budget of the next payments
+				dbTransaction, err := createPendingPayment(tx, payment, appId, requestEventId)
+				if err != nil {
+					return err
+				}
+				dbTransactions[i] = dbTransaction
+			}
+			return nil
+		})
+	}()
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"app_id":           appId,
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to reserve batch payments")
+		return nil, err
+	}
+	reportFinished()
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxBatchPaymentConcurrency)
+	for i, dbTransaction := range dbTransactions {
+		if dbTransaction == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, dbTransaction *db.Transaction) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			items[i].Transaction, items[i].Error = svc.executePayment(ctx, preparedPayments[i], dbTransaction, lnClient, appId, requestEventId)
+			report(i)
+		}(i, dbTransaction)
+	}
+	wg.Wait()
+
+	result := &BatchPaymentResult{
+		Items: items,
+	}
+	for _, item := range items {
+		if item.Error != nil {
+			result.FailedCount++
+			continue
+		}
+		result.SucceededCount++
+		result.TotalAmountMsat += item.Transaction.AmountMsat
+		result.TotalFeeMsat += item.Transaction.FeeMsat
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":            appId,
+		"request_event_id":  requestEventId,
+		"succeeded":         result.SucceededCount,
+		"failed":            result.FailedCount,
+		"total_amount_msat": result.TotalAmountMsat,
+	}).Info("Finished batch payment")
+
+	return result, nil
+}
+
+// waitForBatchPaymentApprovals requests the approvals of all payments of a batch at once, so the user
+// can review them together, and waits for all of them. Payments which are not approved are removed.
+func (svc *transactionsService) waitForBatchPaymentApprovals(preparedPayments []*preparedPayment, items []BatchPaymentItemResult, appId *uint, requestEventId *uint) {
+	pendingApprovals := make([]*pendingPaymentApproval, len(preparedPayments))
+	for i, payment := range preparedPayments {
+		if payment == nil {
+			continue
+		}
+		pendingApproval, err := svc.requestPaymentApproval(appId, requestEventId, payment.amountMsat, payment.paymentRequest.PaymentHash, "", payment.paymentRequest.Description)
+		if err != nil {
+			items[i].Error = err
+			preparedPayments[i] = nil
+			continue
+		}
+		pendingApprovals[i] = pendingApproval
+	}
+
+	var wg sync.WaitGroup
+	for i, pendingApproval := range pendingApprovals {
+		if pendingApproval == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, pendingApproval *pendingPaymentApproval) {
+			defer wg.Done()
+			if err := svc.awaitPaymentApproval(pendingApproval); err != nil {
+				items[i].Error = err
+				preparedPayments[i] = nil
+			}
+		}(i, pendingApproval)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,184 @@
+package transactions
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+// a second 123 sat invoice with a different payment hash than tests.MockInvoice
+const mockBatchInvoice2 = "lntbs1230n1pnkq7q2dqqnp4q09a0z84kg4a2m38zjllw43h953fx5zvqe8qxfgw694ymkq26u8zcpp54sde879ktfrwnt4re3t2ckkrt5tr6dgv6cfdjgkar7942ruccvuqsp52qlk3rxr926s630fmnc5mg6sexnng4cyyfas4msrms8j6q28j8ys9qyysgqcqpcxq8zals8sqgjd3a60n6dy92jn7ggtkywhw952sc302qj0cwfupp7gayadznaj5cahvuq7py8p7hnq8yxylru6279urzxta3783cxze2atj9zmwadcq36muep"
+
+func createBatchTestApp(t *testing.T, svc *tests.TestService, balanceMsat uint64) *db.App {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	app.Isolated = true
+	require.NoError(t, svc.DB.Save(&app).Error)
+
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		Type:       constants.TRANSACTION_TYPE_INCOMING,
+		AmountMsat: balanceMsat,
+	}).Error)
+	return app
+}
+
+func TestSendPaymentBatch(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// enough for both invoices including fee reserves
+	app := createBatchTestApp(t, svc, 266_000)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	result, err := transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockInvoice},
+		{Invoice: mockBatchInvoice2},
+	}, svc.LNClient, &app.ID, nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.SucceededCount)
+	assert.Equal(t, 0, result.FailedCount)
+	assert.Equal(t, uint64(246_000), result.TotalAmountMsat)
+	for _, item := range result.Items {
+		require.NoError(t, item.Error)
+		assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, item.Transaction.State)
+	}
+}
+
+func TestSendPaymentBatch_InsufficientBalance(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// not enough for any of the invoices
+	app := createBatchTestApp(t, svc, 100_000)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	result, err := transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockInvoice},
+		{Invoice: mockBatchInvoice2},
+	}, svc.LNClient, &app.ID, nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, result.SucceededCount)
+	assert.Equal(t, 2, result.FailedCount)
+	for _, item := range result.Items {
+		assert.ErrorIs(t, item.Error, NewInsufficientBalanceError())
+	}
+
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestSendPaymentBatch_PartialBudget(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// enough for one invoice only
+	app := createBatchTestApp(t, svc, 200_000)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	result, err := transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockInvoice},
+		{Invoice: mockBatchInvoice2},
+	}, svc.LNClient, &app.ID, nil, nil)
+	require.NoError(t, err)
+
+	// the first payment is reserved before the second one is validated
+	assert.Equal(t, 1, result.SucceededCount)
+	assert.Equal(t, 1, result.FailedCount)
+	assert.Equal(t, uint64(123_000), result.TotalAmountMsat)
+	assert.NoError(t, result.Items[0].Error)
+	assert.ErrorIs(t, result.Items[1].Error, NewInsufficientBalanceError())
+}
+
+func TestSendPaymentBatch_InvalidInvoice(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createBatchTestApp(t, svc, 266_000)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	result, err := transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockInvoice},
+		{Invoice: tests.MockInvoice},
+		{Invoice: "invalid"},
+	}, svc.LNClient, &app.ID, nil, nil)
+	require.NoError(t, err)
+
+	// invalid invoices do not prevent the others from being paid
+	assert.Equal(t, 1, result.SucceededCount)
+	assert.Equal(t, 2, result.FailedCount)
+	assert.NoError(t, result.Items[0].Error)
+	assert.EqualError(t, result.Items[1].Error, "invoice is included more than once in the batch")
+	assert.Error(t, result.Items[2].Error)
+}
+
+func TestSendPaymentBatch_ItemDone(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createBatchTestApp(t, svc, 266_000)
+
+	var mu sync.Mutex
+	doneIndexes := []int{}
+	doneItems := map[int]BatchPaymentItemResult{}
+	onItemDone := func(index int, item BatchPaymentItemResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		doneIndexes = append(doneIndexes, index)
+		doneItems[index] = item
+	}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	result, err := transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockInvoice},
+		{Invoice: "invalid"},
+	}, svc.LNClient, &app.ID, nil, onItemDone)
+	require.NoError(t, err)
+
+	// the invalid invoice is reported before any payment is made
+	assert.Equal(t, []int{1, 0}, doneIndexes)
+	for i, item := range result.Items {
+		assert.Equal(t, item, doneItems[i])
+	}
+}
+
+func TestSendPaymentBatch_ShuttingDown(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createBatchTestApp(t, svc, 266_000)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	assert.Zero(t, transactionsService.WaitForPayments(context.TODO()))
+	_, err = transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockInvoice},
+	}, svc.LNClient, &app.ID, nil, nil)
+	assert.Error(t, err)
+
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&count)
+	assert.Zero(t, count)
+}
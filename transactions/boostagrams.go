@@ -0,0 +1,101 @@
+package transactions
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+// getBoostagramBytesFromCustomRecords extracts the podcasting 2.0 boostagram from the TLV records
+// of a keysend payment. The bLIP-10 record takes priority over the legacy record. A tip note sent
+// in a separate record is merged into the boostagram message if the boostagram has none.
+// The original JSON is kept so fields unknown to the hub are not lost.
+func (svc *transactionsService) getBoostagramBytesFromCustomRecords(customRecords []lnclient.TLVRecord) []byte {
+	var boostagramRecord *lnclient.TLVRecord
+	var tipNote string
+	for _, record := range customRecords {
+		switch record.Type {
+		case BoostagramTlvType:
+			boostagramRecord = &record
+		case LegacyBoostagramTlvType:
+			if boostagramRecord == nil {
+				boostagramRecord = &record
+			}
+		case TipNoteTlvType:
+			bytes, err := hex.DecodeString(record.Value)
+			if err != nil {
+				logger.Logger.WithField("value", record.Value).WithError(err).Error("failed to decode tip note tlv hex value")
+				continue
+			}
+			tipNote = string(bytes)
+		}
+	}
+
+	if boostagramRecord == nil {
+		return nil
+	}
+
+	bytes, err := hex.DecodeString(boostagramRecord.Value)
+	if err != nil {
+		logger.Logger.WithField("value", boostagramRecord.Value).WithError(err).Error("failed to decode boostagram tlv hex value")
+		return nil
+	}
+
+	// ensure the boostagram is valid json
+	var boostagram Boostagram
+	if err := json.Unmarshal(bytes, &boostagram); err != nil {
+		logger.Logger.WithField("value", string(bytes)).WithError(err).Error("failed to unmarshal boostagram to json")
+		return nil
+	}
+
+	if tipNote != "" && boostagram.Message == "" {
+		var boostagramFields map[string]interface{}
+		if err := json.Unmarshal(bytes, &boostagramFields); err != nil {
+			logger.Logger.WithField("value", string(bytes)).WithError(err).Error("failed to unmarshal boostagram fields")
+			return bytes
+		}
+		boostagramFields["message"] = tipNote
+		mergedBytes, err := json.Marshal(boostagramFields)
+		if err != nil {
+			logger.Logger.WithError(err).Error("failed to serialize boostagram with tip note")
+			return bytes
+		}
+		return mergedBytes
+	}
+
+	return bytes
+}
+
+func (svc *transactionsService) getDescriptionFromCustomRecords(customRecords []lnclient.TLVRecord) string {
+	boostagramBytes := svc.getBoostagramBytesFromCustomRecords(customRecords)
+	if boostagramBytes != nil {
+		var boostagram Boostagram
+		if err := json.Unmarshal(boostagramBytes, &boostagram); err == nil && boostagram.Message != "" {
+			return boostagram.Message
+		}
+	}
+
+	var description string
+	for _, record := range customRecords {
+		switch record.Type {
+		// TODO: consider adding support for this in LDK
+		case WhatsatTlvType:
+			bytes, err := hex.DecodeString(record.Value)
+			if err == nil {
+				description = string(bytes)
+			}
+		case TipNoteTlvType:
+			if description != "" {
+				continue
+			}
+			bytes, err := hex.DecodeString(record.Value)
+			if err == nil {
+				description = string(bytes)
+			}
+		}
+	}
+
+	return description
+}
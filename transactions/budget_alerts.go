@@ -0,0 +1,168 @@
+package transactions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// percentages of a budget at which an alert is published, until other thresholds are configured
+var DefaultBudgetAlertThresholds = []uint{50, 80, 100}
+
+// GetBudgetAlertThresholds returns the configured thresholds in ascending order.
+// An empty configuration disables budget alerts.
+func GetBudgetAlertThresholds(tx *gorm.DB) []uint {
+	var userConfig db.UserConfig
+	if tx.Limit(1).Find(&userConfig, &db.UserConfig{Key: config.BudgetAlertThresholdsKey}).RowsAffected == 0 {
+		return DefaultBudgetAlertThresholds
+	}
+	thresholds, err := ParseBudgetAlertThresholds(userConfig.Value)
+	if err != nil {
+		logger.Logger.WithField("value", userConfig.Value).WithError(err).Error("Invalid budget alert thresholds config")
+		return DefaultBudgetAlertThresholds
+	}
+	return thresholds
+}
+
+func ParseBudgetAlertThresholds(value string) ([]uint, error) {
+	thresholds := []uint{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		threshold, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget alert threshold: %q", part)
+		}
+		thresholds = append(thresholds, uint(threshold))
+	}
+	return NormalizeBudgetAlertThresholds(thresholds)
+}
+
+// NormalizeBudgetAlertThresholds validates the thresholds and returns them sorted and without duplicates
+func NormalizeBudgetAlertThresholds(thresholds []uint) ([]uint, error) {
+	normalized := []uint{}
+	for _, threshold := range thresholds {
+		if threshold == 0 || threshold > 100 {
+			return nil, fmt.Errorf("invalid budget alert threshold: %d. Must be a percentage between 1 and 100", threshold)
+		}
+		if !slices.Contains(normalized, threshold) {
+			normalized = append(normalized, threshold)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+func FormatBudgetAlertThresholds(thresholds []uint) string {
+	parts := []string{}
+	for _, threshold := range thresholds {
+		parts = append(parts, strconv.FormatUint(uint64(threshold), 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+type budgetAlertUsage struct {
+	budgetRenewal string
+	currency      string
+	usage         float64
+	previousUsage float64
+	maxAmount     float64
+}
+
+// checkBudgetAlerts publishes an event for every budget of the app which crossed an alert threshold
+// with the settled payment. When several thresholds are crossed at once only the highest is reported.
+func (svc *transactionsService) checkBudgetAlerts(tx *gorm.DB, app *db.App, appPermission *db.AppPermission, dbTransaction *db.Transaction) {
+	thresholds := GetBudgetAlertThresholds(tx)
+	if len(thresholds) == 0 {
+		return
+	}
+
+	spentMsat := dbTransaction.AmountMsat + dbTransaction.FeeMsat
+	budgets := []budgetAlertUsage{}
+
+	if appPermission.BudgetCurrency != "" {
+		if appPermission.MaxAmountFiat > 0 {
+			rate, err := GetCurrentFiatRate(context.Background(), appPermission.BudgetCurrency)
+			if err != nil {
+				logger.Logger.WithError(err).WithField("currency", appPermission.BudgetCurrency).Error("Failed to get rate for budget alerts")
+			} else {
+				usage := queries.GetBudgetUsageFiat(tx, appPermission, rate)
+				budgets = append(budgets, budgetAlertUsage{
+					budgetRenewal: appPermission.BudgetRenewal,
+					currency:      appPermission.BudgetCurrency,
+					usage:         usage,
+					previousUsage: usage - GetFiatValue(spentMsat, rate),
+					maxAmount:     appPermission.MaxAmountFiat,
+				})
+			}
+		}
+	} else if appPermission.MaxAmountSat > 0 {
+		usage := queries.GetBudgetUsageSat(tx, appPermission)
+		budgets = append(budgets, newSatBudgetAlertUsage(appPermission.BudgetRenewal, usage, spentMsat, uint64(appPermission.MaxAmountSat)))
+	}
+
+	var appBudgets []db.AppBudget
+	if err := tx.Where("app_id = ?", app.ID).Find(&appBudgets).Error; err != nil {
+		logger.Logger.WithError(err).WithField("app_id", app.ID).Error("Failed to list app budgets for budget alerts")
+	}
+	for _, appBudget := range appBudgets {
+		usage := queries.GetBudgetUsageSatForPeriod(tx, app.ID, appBudget.BudgetRenewal)
+		budgets = append(budgets, newSatBudgetAlertUsage(appBudget.BudgetRenewal, usage, spentMsat, appBudget.MaxAmountSat))
+	}
+
+	for _, budget := range budgets {
+		var crossedThreshold uint
+		for _, threshold := range thresholds {
+			thresholdAmount := budget.maxAmount * float64(threshold) / 100
+			if budget.usage >= thresholdAmount && budget.previousUsage < thresholdAmount {
+				crossedThreshold = threshold
+			}
+		}
+		if crossedThreshold == 0 {
+			continue
+		}
+
+		logger.Logger.WithFields(logrus.Fields{
+			"app_id":         app.ID,
+			"threshold":      crossedThreshold,
+			"budget_renewal": budget.budgetRenewal,
+			"budget_usage":   budget.usage,
+			"max_amount":     budget.maxAmount,
+		}).Info("App crossed a budget alert threshold")
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_budget_threshold_reached",
+			Properties: &events.BudgetThresholdReachedEvent{
+				AppId:         app.ID,
+				AppName:       app.Name,
+				Threshold:     crossedThreshold,
+				BudgetRenewal: budget.budgetRenewal,
+				BudgetUsage:   budget.usage,
+				MaxAmount:     budget.maxAmount,
+				Currency:      budget.currency,
+			},
+		})
+	}
+}
+
+func newSatBudgetAlertUsage(budgetRenewal string, usageSat uint64, spentMsat uint64, maxAmountSat uint64) budgetAlertUsage {
+	return budgetAlertUsage{
+		budgetRenewal: budgetRenewal,
+		currency:      "SATS",
+		usage:         float64(usageSat),
+		previousUsage: float64(usageSat) - float64(spentMsat)/1000,
+		maxAmount:     float64(maxAmountSat),
+	}
+}
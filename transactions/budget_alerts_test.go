@@ -0,0 +1,133 @@
+package transactions
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/tests"
+)
+
+func settleBudgetAlertPayment(t *testing.T, svc *tests.TestService, transactionsService *transactionsService, appId uint, amountMsat uint64, paymentHash string) {
+	dbTransaction := &db.Transaction{
+		AppId:       &appId,
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat:  amountMsat,
+		PaymentHash: paymentHash,
+	}
+	require.NoError(t, svc.DB.Create(dbTransaction).Error)
+	transactionsService.checkBudgetUsage(dbTransaction, svc.DB)
+}
+
+func getBudgetThresholdEvents(consumedEvents []*events.Event) []*events.BudgetThresholdReachedEvent {
+	thresholdEvents := []*events.BudgetThresholdReachedEvent{}
+	for _, event := range consumedEvents {
+		if event.Event == "nwc_budget_threshold_reached" {
+			thresholdEvents = append(thresholdEvents, event.Properties.(*events.BudgetThresholdReachedEvent))
+		}
+	}
+	return thresholdEvents
+}
+
+func TestBudgetAlerts(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId:         app.ID,
+		App:           *app,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  100,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	}).Error)
+	require.NoError(t, svc.DB.Create(&db.AppBudget{
+		AppId:         app.ID,
+		MaxAmountSat:  50,
+		BudgetRenewal: constants.BUDGET_RENEWAL_DAILY,
+	}).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	settleBudgetAlertPayment(t, svc, transactionsService, app.ID, 20_000, "payment1")
+	assert.Empty(t, getBudgetThresholdEvents(mockEventConsumer.GetConsumedEvents()))
+
+	// 60% of the monthly budget and 120% of the daily budget, only the highest crossed threshold is reported
+	settleBudgetAlertPayment(t, svc, transactionsService, app.ID, 40_000, "payment2")
+	// events are published asynchronously
+	require.Eventually(t, func() bool {
+		return len(getBudgetThresholdEvents(mockEventConsumer.GetConsumedEvents())) == 2
+	}, time.Second, 10*time.Millisecond)
+	thresholdEvents := getBudgetThresholdEvents(mockEventConsumer.GetConsumedEvents())
+	slices.SortFunc(thresholdEvents, func(a, b *events.BudgetThresholdReachedEvent) int { return int(a.Threshold) - int(b.Threshold) })
+	assert.Equal(t, app.ID, thresholdEvents[0].AppId)
+	assert.Equal(t, uint(50), thresholdEvents[0].Threshold)
+	assert.Equal(t, constants.BUDGET_RENEWAL_MONTHLY, thresholdEvents[0].BudgetRenewal)
+	assert.Equal(t, float64(60), thresholdEvents[0].BudgetUsage)
+	assert.Equal(t, float64(100), thresholdEvents[0].MaxAmount)
+	assert.Equal(t, "SATS", thresholdEvents[0].Currency)
+	assert.Equal(t, uint(100), thresholdEvents[1].Threshold)
+	assert.Equal(t, constants.BUDGET_RENEWAL_DAILY, thresholdEvents[1].BudgetRenewal)
+
+	// thresholds which were already crossed are not reported again
+	settleBudgetAlertPayment(t, svc, transactionsService, app.ID, 5_000, "payment3")
+	assert.Len(t, getBudgetThresholdEvents(mockEventConsumer.GetConsumedEvents()), 2)
+}
+
+func TestBudgetAlerts_ConfiguredThresholds(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId:         app.ID,
+		App:           *app,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  100,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	}).Error)
+	require.NoError(t, svc.Cfg.SetUpdate(config.BudgetAlertThresholdsKey, "90", ""))
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	settleBudgetAlertPayment(t, svc, transactionsService, app.ID, 80_000, "payment1")
+	assert.Empty(t, getBudgetThresholdEvents(mockEventConsumer.GetConsumedEvents()))
+	settleBudgetAlertPayment(t, svc, transactionsService, app.ID, 10_000, "payment2")
+	require.Eventually(t, func() bool {
+		return len(getBudgetThresholdEvents(mockEventConsumer.GetConsumedEvents())) == 1
+	}, time.Second, 10*time.Millisecond)
+	thresholdEvents := getBudgetThresholdEvents(mockEventConsumer.GetConsumedEvents())
+	assert.Equal(t, uint(90), thresholdEvents[0].Threshold)
+
+	// an empty list disables the alerts
+	require.NoError(t, svc.Cfg.SetUpdate(config.BudgetAlertThresholdsKey, "", ""))
+	assert.Empty(t, GetBudgetAlertThresholds(svc.DB))
+}
+
+func TestParseBudgetAlertThresholds(t *testing.T) {
+	thresholds, err := ParseBudgetAlertThresholds("80, 50,80,100")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{50, 80, 100}, thresholds)
+
+	_, err = ParseBudgetAlertThresholds("0")
+	assert.EqualError(t, err, "invalid budget alert threshold: 0. Must be a percentage between 1 and 100")
+	_, err = ParseBudgetAlertThresholds("abc")
+	assert.EqualError(t, err, "invalid budget alert threshold: \"abc\"")
+}
@@ -0,0 +1,128 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/tracing"
+)
+
+type CircularRebalanceRequest struct {
+	// the channel the amount is sent out through, which loses outbound liquidity
+	OutgoingChannelId string
+	// the channel the amount comes back through, which gains outbound liquidity
+	IncomingChannelId string
+	AmountSat         uint64
+	// the maximum routing fee of the payment
+	MaxFeeSat uint64
+}
+
+type circularRoute struct {
+	outgoingChannelId string
+	lastHopPubkey     string
+	maxFeeMsat        uint64
+}
+
+// RebalanceCircular moves liquidity between two channels of the node by paying an invoice of the
+// node itself out through one channel and back in through the other. Both legs are recorded as
+// transactions, so only the routing fee changes the balance.
+func (svc *transactionsService) RebalanceCircular(ctx context.Context, request *CircularRebalanceRequest, lnClient lnclient.LNClient) (*Transaction, error) {
+	if err := CheckSpendingAllowed(svc.db); err != nil {
+		return nil, err
+	}
+	if err := svc.payments.start(); err != nil {
+		return nil, err
+	}
+	defer svc.payments.done()
+
+	if _, ok := lnClient.(lnclient.CircularPaymentLNClient); !ok {
+		return nil, errors.New("circular rebalancing is not supported by your node backend. Try LND.")
+	}
+	if request.AmountSat == 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	if request.MaxFeeSat == 0 {
+		return nil, errors.New("max fee must be greater than zero")
+	}
+	if request.OutgoingChannelId == request.IncomingChannelId {
+		return nil, errors.New("outgoing and incoming channel must be different")
+	}
+
+	amountMsat := request.AmountSat * 1000
+	channels, err := lnClient.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var outgoingChannel, incomingChannel *lnclient.Channel
+	for i := range channels {
+		switch channels[i].Id {
+		case request.OutgoingChannelId:
+			outgoingChannel = &channels[i]
+		case request.IncomingChannelId:
+			incomingChannel = &channels[i]
+		}
+	}
+	if outgoingChannel == nil || incomingChannel == nil {
+		return nil, errors.New("channel not found")
+	}
+	if outgoingChannel.LocalSpendableBalance < int64(amountMsat) {
+		return nil, errors.New("insufficient spendable balance in outgoing channel")
+	}
+	if incomingChannel.RemoteBalance < int64(amountMsat) {
+		return nil, errors.New("insufficient receivable balance in incoming channel")
+	}
+
+	metadata := map[string]interface{}{
+		"circular_rebalance": map[string]interface{}{
+			"outgoing_channel_id": request.OutgoingChannelId,
+			"incoming_channel_id": request.IncomingChannelId,
+		},
+	}
+
+	invoice, err := svc.MakeInvoice(ctx, amountMsat, "Circular rebalance", "", 0, metadata, lnClient, nil, nil, &incomingChannel.RemotePubkey)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to create circular rebalance invoice")
+		return nil, err
+	}
+
+	payment, err := svc.preparePayment(invoice.PaymentRequest, nil, metadata, lnClient)
+	if err != nil {
+		return nil, err
+	}
+	// the payment goes through the network, rather than being settled internally
+	payment.selfPayment = false
+	payment.circularRoute = &circularRoute{
+		outgoingChannelId: request.OutgoingChannelId,
+		lastHopPubkey:     incomingChannel.RemotePubkey,
+		maxFeeMsat:        request.MaxFeeSat * 1000,
+	}
+
+	dbTransaction, err := createPendingPayment(svc.db, payment, nil, nil)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"bolt11": payment.payReq,
+		}).WithError(err).Error("Failed to create DB transaction")
+		return nil, err
+	}
+	err = svc.db.Model(dbTransaction).Update("fee_reserve_msat", payment.circularRoute.maxFeeMsat).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.executePayment(ctx, payment, dbTransaction, lnClient, nil, nil)
+}
+
+func sendCircularPayment(ctx context.Context, lnClient lnclient.LNClient, payReq string, route *circularRoute) (response *lnclient.PayInvoiceResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "lnclient.SendCircularPayment")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	circularPaymentLNClient, ok := lnClient.(lnclient.CircularPaymentLNClient)
+	if !ok {
+		return nil, errors.New("circular payments are not supported by your node backend")
+	}
+	return circularPaymentLNClient.SendCircularPayment(ctx, payReq, route.outgoingChannelId, route.lastHopPubkey, route.maxFeeMsat)
+}
@@ -0,0 +1,91 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+type mockCircularPaymentLn struct {
+	*tests.MockLn
+	outgoingChannelId string
+	lastHopPubkey     string
+	maxFeeMsat        uint64
+}
+
+func (mln *mockCircularPaymentLn) ListChannels(ctx context.Context) ([]lnclient.Channel, error) {
+	return []lnclient.Channel{
+		{Id: "1", RemotePubkey: "02aaaa", LocalSpendableBalance: 500_000, RemoteBalance: 0},
+		{Id: "2", RemotePubkey: "02bbbb", LocalSpendableBalance: 0, RemoteBalance: 500_000},
+	}, nil
+}
+
+func (mln *mockCircularPaymentLn) SendCircularPayment(ctx context.Context, payReq string, outgoingChannelId string, lastHopPubkey string, maxFeeMsat uint64) (*lnclient.PayInvoiceResponse, error) {
+	mln.outgoingChannelId = outgoingChannelId
+	mln.lastHopPubkey = lastHopPubkey
+	mln.maxFeeMsat = maxFeeMsat
+	return &lnclient.PayInvoiceResponse{Preimage: tests.MockLNClientTransaction.Preimage, Fee: 20}, nil
+}
+
+func TestRebalanceCircular(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	lnClient := &mockCircularPaymentLn{MockLn: svc.LNClient.(*tests.MockLn)}
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.RebalanceCircular(context.TODO(), &CircularRebalanceRequest{
+		OutgoingChannelId: "1",
+		IncomingChannelId: "2",
+		AmountSat:         123,
+		MaxFeeSat:         5,
+	}, lnClient)
+	require.NoError(t, err)
+
+	assert.Equal(t, "1", lnClient.outgoingChannelId)
+	assert.Equal(t, "02bbbb", lnClient.lastHopPubkey)
+	assert.Equal(t, uint64(5_000), lnClient.maxFeeMsat)
+
+	// the payment to the own invoice is made through the network rather than intercepted
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.False(t, transaction.SelfPayment)
+	assert.Equal(t, uint64(20), transaction.FeeMsat)
+
+	var incomingTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&incomingTransaction, &db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_INCOMING,
+		PaymentHash: transaction.PaymentHash,
+	}).Error)
+}
+
+func TestRebalanceCircular_InsufficientBalance(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	lnClient := &mockCircularPaymentLn{MockLn: svc.LNClient.(*tests.MockLn)}
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.RebalanceCircular(context.TODO(), &CircularRebalanceRequest{
+		OutgoingChannelId: "2",
+		IncomingChannelId: "1",
+		AmountSat:         123,
+		MaxFeeSat:         5,
+	}, lnClient)
+	assert.EqualError(t, err, "insufficient spendable balance in outgoing channel")
+
+	// backends without circular payments are rejected
+	_, err = transactionsService.RebalanceCircular(context.TODO(), &CircularRebalanceRequest{
+		OutgoingChannelId: "1",
+		IncomingChannelId: "2",
+		AmountSat:         123,
+		MaxFeeSat:         5,
+	}, svc.LNClient)
+	assert.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package transactions
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+type destinationNotAllowedError struct {
+}
+
+func NewDestinationNotAllowedError() error {
+	return &destinationNotAllowedError{}
+}
+
+func (err *destinationNotAllowedError) Error() string {
+	return "This app is not allowed to pay this destination"
+}
+
+// validatePaymentDestination rejects payments to nodes which are not in the destinations of the app.
+// Apps without destinations can pay anyone.
+func (svc *transactionsService) validatePaymentDestination(appId *uint, destination string) error {
+	if appId == nil {
+		return nil
+	}
+	var appDestinations []db.AppDestination
+	if err := svc.db.Find(&appDestinations, &db.AppDestination{AppId: *appId}).Error; err != nil {
+		return err
+	}
+	if len(appDestinations) == 0 {
+		return nil
+	}
+	for _, appDestination := range appDestinations {
+		if appDestination.NodePubkey == destination {
+			return nil
+		}
+	}
+
+	var app db.App
+	if svc.db.Limit(1).Find(&app, &db.App{ID: *appId}).RowsAffected == 0 {
+		return NewNotFoundError()
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":      app.ID,
+		"destination": destination,
+	}).Warn("Rejected payment to a destination the app is not allowed to pay")
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_permission_denied",
+		Properties: map[string]interface{}{
+			"app_name": app.Name,
+			"code":     constants.ERROR_RESTRICTED,
+			"message":  NewDestinationNotAllowedError().Error() + " " + destination,
+		},
+	})
+	return NewDestinationNotAllowedError()
+}
@@ -0,0 +1,82 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func createDestinationsApp(t *testing.T, svc *tests.TestService, nodePubkeys ...string) *db.App {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+	for _, nodePubkey := range nodePubkeys {
+		require.NoError(t, svc.DB.Create(&db.AppDestination{
+			AppId:      app.ID,
+			Type:       constants.APP_DESTINATION_TYPE_NODE,
+			Value:      nodePubkey,
+			NodePubkey: nodePubkey,
+		}).Error)
+	}
+	return app
+}
+
+func TestSendPaymentSync_DestinationAllowed(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// the payee of the mock invoice
+	app := createDestinationsApp(t, svc, "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c")
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestSendPaymentSync_DestinationNotAllowed(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createDestinationsApp(t, svc, "02f1c4a0a2ba8f2aae6d8f43e8ee2a1b4cb5e9a1c7e9cfe4f3a0f7b2bbf2e5a1c0")
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.ErrorIs(t, err, NewDestinationNotAllowedError())
+	assert.Nil(t, transaction)
+
+	// no payment is attempted
+	var count int64
+	require.NoError(t, svc.DB.Model(&db.Transaction{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestSendKeysend_DestinationNotAllowed(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	allowedPubkey := "02f1c4a0a2ba8f2aae6d8f43e8ee2a1b4cb5e9a1c7e9cfe4f3a0f7b2bbf2e5a1c0"
+	app := createDestinationsApp(t, svc, allowedPubkey)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendKeysend(context.TODO(), 1000, "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c", nil, "", svc.LNClient, &app.ID, nil)
+	assert.ErrorIs(t, err, NewDestinationNotAllowedError())
+	assert.Nil(t, transaction)
+
+	transaction, err = transactionsService.SendKeysend(context.TODO(), 1000, allowedPubkey, nil, "", svc.LNClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
@@ -0,0 +1,36 @@
+package transactions
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSendPaymentSync_EncryptedDatabase(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	key := make([]byte, 32)
+	require.NoError(t, db.SetEncryptionKey(key, true))
+	defer db.SetEncryptionKey(key, false)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "123preimage", *transaction.Preimage)
+
+	var storedPreimage string
+	require.NoError(t, svc.DB.Table("transactions").Select("preimage").Where("id = ?", transaction.ID).Scan(&storedPreimage).Error)
+	assert.True(t, strings.HasPrefix(storedPreimage, "enc:v1:"))
+
+	var dbTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&dbTransaction, transaction.ID).Error)
+	assert.Equal(t, "123preimage", *dbTransaction.Preimage)
+}
@@ -0,0 +1,292 @@
+package transactions
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/constants"
+)
+
+const (
+	EXPORT_FORMAT_CSV         = "csv"
+	EXPORT_FORMAT_KOINLY      = "koinly"
+	EXPORT_FORMAT_COINTRACKER = "cointracker"
+	EXPORT_FORMAT_LEDGER      = "ledger"
+)
+
+func GetExportFormats() []string {
+	return []string{
+		EXPORT_FORMAT_CSV,
+		EXPORT_FORMAT_KOINLY,
+		EXPORT_FORMAT_COINTRACKER,
+		EXPORT_FORMAT_LEDGER,
+	}
+}
+
+// ExportOptions configures how transactions are written by ExportTransactions.
+// FiatRates are the prices of one bitcoin in FiatCurrency at the time each transaction settled,
+// by transaction id. They are optional; when FiatCurrency is empty no fiat values are included
+// in the export, and transactions without a recorded rate have no fiat value.
+type ExportOptions struct {
+	Format       string
+	FiatCurrency string
+	FiatRates    map[uint]float64
+}
+
+type exportWriter func(w io.Writer, transactions []Transaction, options *ExportOptions) error
+
+var exportWriters = map[string]exportWriter{
+	EXPORT_FORMAT_CSV:         writeCsvExport,
+	EXPORT_FORMAT_KOINLY:      writeKoinlyExport,
+	EXPORT_FORMAT_COINTRACKER: writeCointrackerExport,
+	EXPORT_FORMAT_LEDGER:      writeLedgerExport,
+}
+
+// ExportTransactions writes settled transactions in the requested accounting format.
+// Transactions that are not settled are skipped as they have no accounting impact.
+func ExportTransactions(w io.Writer, transactions []Transaction, options *ExportOptions) error {
+	if options == nil {
+		return errors.New("no export options provided")
+	}
+
+	writer, ok := exportWriters[options.Format]
+	if !ok {
+		return fmt.Errorf("unsupported export format. Must be one of %s", strings.Join(GetExportFormats(), ","))
+	}
+
+	settledTransactions := []Transaction{}
+	for _, transaction := range transactions {
+		if transaction.State == constants.TRANSACTION_STATE_SETTLED {
+			settledTransactions = append(settledTransactions, transaction)
+		}
+	}
+
+	// accounting tools expect records in chronological order
+	slices.SortStableFunc(settledTransactions, func(a, b Transaction) int {
+		return exportTime(&a).Compare(exportTime(&b))
+	})
+
+	return writer(w, settledTransactions, options)
+}
+
+func writeCsvExport(w io.Writer, transactions []Transaction, options *ExportOptions) error {
+	csvWriter := csv.NewWriter(w)
+	header := []string{"date", "type", "amount_sat", "fee_sat", "description", "payment_hash", "app_id"}
+	if options.FiatCurrency != "" {
+		header = append(header, "fiat_value", "fiat_currency")
+	}
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	for _, transaction := range transactions {
+		var appId string
+		if transaction.AppId != nil {
+			appId = strconv.FormatUint(uint64(*transaction.AppId), 10)
+		}
+		record := []string{
+			exportTime(&transaction).Format(time.RFC3339),
+			transaction.Type,
+			formatMsatAsSat(transaction.AmountMsat),
+			formatMsatAsSat(transaction.FeeMsat),
+			transaction.Description,
+			transaction.PaymentHash,
+			appId,
+		}
+		if options.FiatCurrency != "" {
+			var fiatValue, fiatCurrency string
+			if fiatRate, ok := options.FiatRates[transaction.ID]; ok {
+				fiatValue = formatFiat(transaction.AmountMsat, fiatRate)
+				fiatCurrency = options.FiatCurrency
+			}
+			record = append(record, fiatValue, fiatCurrency)
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// see https://support.koinly.io/en/articles/9489976-how-to-create-a-custom-csv-file-with-your-data
+func writeKoinlyExport(w io.Writer, transactions []Transaction, options *ExportOptions) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{
+		"Date",
+		"Sent Amount",
+		"Sent Currency",
+		"Received Amount",
+		"Received Currency",
+		"Fee Amount",
+		"Fee Currency",
+		"Net Worth Amount",
+		"Net Worth Currency",
+		"Label",
+		"Description",
+		"TxHash",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range transactions {
+		var sentAmount, sentCurrency, receivedAmount, receivedCurrency, feeAmount, feeCurrency, netWorthAmount, netWorthCurrency string
+		if transaction.Type == constants.TRANSACTION_TYPE_OUTGOING {
+			sentAmount = formatMsatAsBtc(transaction.AmountMsat)
+			sentCurrency = "BTC"
+			if transaction.FeeMsat > 0 {
+				feeAmount = formatMsatAsBtc(transaction.FeeMsat)
+				feeCurrency = "BTC"
+			}
+		} else {
+			receivedAmount = formatMsatAsBtc(transaction.AmountMsat)
+			receivedCurrency = "BTC"
+		}
+		if fiatRate, ok := options.FiatRates[transaction.ID]; ok && options.FiatCurrency != "" {
+			netWorthAmount = formatFiat(transaction.AmountMsat, fiatRate)
+			netWorthCurrency = options.FiatCurrency
+		}
+
+		err := csvWriter.Write([]string{
+			exportTime(&transaction).UTC().Format("2006-01-02 15:04:05 UTC"),
+			sentAmount,
+			sentCurrency,
+			receivedAmount,
+			receivedCurrency,
+			feeAmount,
+			feeCurrency,
+			netWorthAmount,
+			netWorthCurrency,
+			"",
+			transaction.Description,
+			transaction.PaymentHash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// see https://support.cointracker.io/hc/en-us/articles/4413071299729-Convert-your-transaction-history-to-CoinTracker-CSV
+func writeCointrackerExport(w io.Writer, transactions []Transaction, options *ExportOptions) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{
+		"Date",
+		"Received Quantity",
+		"Received Currency",
+		"Sent Quantity",
+		"Sent Currency",
+		"Fee Amount",
+		"Fee Currency",
+		"Tag",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range transactions {
+		var receivedQuantity, receivedCurrency, sentQuantity, sentCurrency, feeAmount, feeCurrency string
+		if transaction.Type == constants.TRANSACTION_TYPE_OUTGOING {
+			sentQuantity = formatMsatAsBtc(transaction.AmountMsat)
+			sentCurrency = "BTC"
+			if transaction.FeeMsat > 0 {
+				feeAmount = formatMsatAsBtc(transaction.FeeMsat)
+				feeCurrency = "BTC"
+			}
+		} else {
+			receivedQuantity = formatMsatAsBtc(transaction.AmountMsat)
+			receivedCurrency = "BTC"
+		}
+
+		err := csvWriter.Write([]string{
+			exportTime(&transaction).UTC().Format("01/02/2006 15:04:05"),
+			receivedQuantity,
+			receivedCurrency,
+			sentQuantity,
+			sentCurrency,
+			feeAmount,
+			feeCurrency,
+			"",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// writes a plain text journal which can be read by ledger-cli and hledger. Amounts stay in BTC
+// and are balanced in BTC; the fiat rate at settlement is added as a price directive, as costs
+// with @ would make ledger balance the transaction in fiat.
+func writeLedgerExport(w io.Writer, transactions []Transaction, options *ExportOptions) error {
+	for _, transaction := range transactions {
+		description := strings.ReplaceAll(transaction.Description, "\n", " ")
+		if description == "" {
+			description = "Lightning payment"
+		}
+		date := exportTime(&transaction).UTC().Format("2006/01/02")
+
+		var entry strings.Builder
+		if fiatRate, ok := options.FiatRates[transaction.ID]; ok && options.FiatCurrency != "" {
+			fmt.Fprintf(&entry, "P %s BTC %s %s\n", date, strconv.FormatFloat(fiatRate, 'f', 2, 64), options.FiatCurrency)
+		}
+		fmt.Fprintf(&entry, "%s * %s\n", date, description)
+		fmt.Fprintf(&entry, "    ; payment_hash: %s\n", transaction.PaymentHash)
+		if transaction.Type == constants.TRANSACTION_TYPE_OUTGOING {
+			fmt.Fprintf(&entry, "    Expenses:Lightning    %s BTC\n", formatMsatAsBtc(transaction.AmountMsat))
+			if transaction.FeeMsat > 0 {
+				fmt.Fprintf(&entry, "    Expenses:Lightning:Fees    %s BTC\n", formatMsatAsBtc(transaction.FeeMsat))
+			}
+			fmt.Fprintf(&entry, "    Assets:Lightning    -%s BTC\n\n", formatMsatAsBtc(transaction.AmountMsat+transaction.FeeMsat))
+		} else {
+			fmt.Fprintf(&entry, "    Assets:Lightning    %s BTC\n", formatMsatAsBtc(transaction.AmountMsat))
+			fmt.Fprintf(&entry, "    Income:Lightning    -%s BTC\n\n", formatMsatAsBtc(transaction.AmountMsat))
+		}
+
+		if _, err := io.WriteString(w, entry.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportTime(transaction *Transaction) time.Time {
+	if transaction.SettledAt != nil {
+		return *transaction.SettledAt
+	}
+	return transaction.CreatedAt
+}
+
+// formats a millisat amount as BTC without losing precision (1 msat = 0.00000000001 BTC)
+func formatMsatAsBtc(amountMsat uint64) string {
+	const msatPerBtc = 100_000_000_000
+	fraction := strings.TrimRight(fmt.Sprintf("%011d", amountMsat%msatPerBtc), "0")
+	if len(fraction) < 8 {
+		fraction += strings.Repeat("0", 8-len(fraction))
+	}
+	return fmt.Sprintf("%d.%s", amountMsat/msatPerBtc, fraction)
+}
+
+func formatMsatAsSat(amountMsat uint64) string {
+	if amountMsat%1000 == 0 {
+		return strconv.FormatUint(amountMsat/1000, 10)
+	}
+	return strconv.FormatFloat(float64(amountMsat)/1000, 'f', 3, 64)
+}
+
+func formatFiat(amountMsat uint64, fiatRate float64) string {
+	return strconv.FormatFloat(float64(amountMsat)/100_000_000_000*fiatRate, 'f', 2, 64)
+}
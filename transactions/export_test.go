@@ -0,0 +1,153 @@
+package transactions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+)
+
+func createExportTestTransactions() []Transaction {
+	settledAt := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+	earlierSettledAt := settledAt.Add(-time.Hour)
+	return []Transaction{
+		{
+			ID:          2,
+			Type:        constants.TRANSACTION_TYPE_OUTGOING,
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			AmountMsat:  123_456,
+			FeeMsat:     2_000,
+			Description: "coffee",
+			PaymentHash: "hash2",
+			SettledAt:   &settledAt,
+		},
+		{
+			ID:          1,
+			Type:        constants.TRANSACTION_TYPE_INCOMING,
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			AmountMsat:  1_000_000,
+			Description: "salary",
+			PaymentHash: "hash1",
+			SettledAt:   &earlierSettledAt,
+		},
+		{
+			ID:          3,
+			Type:        constants.TRANSACTION_TYPE_INCOMING,
+			State:       constants.TRANSACTION_STATE_PENDING,
+			AmountMsat:  5_000,
+			PaymentHash: "hash3",
+		},
+	}
+}
+
+func TestExportTransactions_Koinly(t *testing.T) {
+	var buffer bytes.Buffer
+	err := ExportTransactions(&buffer, createExportTestTransactions(), &ExportOptions{
+		Format:       EXPORT_FORMAT_KOINLY,
+		FiatCurrency: "USD",
+		// each transaction is valued at the rate when it settled
+		FiatRates: map[uint]float64{1: 50_000, 2: 60_000},
+	})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Equal(t, 3, len(lines))
+	assert.Equal(t, "Date,Sent Amount,Sent Currency,Received Amount,Received Currency,Fee Amount,Fee Currency,Net Worth Amount,Net Worth Currency,Label,Description,TxHash", lines[0])
+	// ordered by settle time, pending transactions are skipped
+	assert.Equal(t, "2024-03-01 11:30:00 UTC,,,0.00001000,BTC,,,0.50,USD,,salary,hash1", lines[1])
+	assert.Equal(t, "2024-03-01 12:30:00 UTC,0.00000123456,BTC,,,0.00000002,BTC,0.07,USD,,coffee,hash2", lines[2])
+}
+
+func TestExportTransactions_Cointracker(t *testing.T) {
+	var buffer bytes.Buffer
+	err := ExportTransactions(&buffer, createExportTestTransactions(), &ExportOptions{
+		Format: EXPORT_FORMAT_COINTRACKER,
+	})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Equal(t, 3, len(lines))
+	assert.Equal(t, "Date,Received Quantity,Received Currency,Sent Quantity,Sent Currency,Fee Amount,Fee Currency,Tag", lines[0])
+	assert.Equal(t, "03/01/2024 11:30:00,0.00001000,BTC,,,,,", lines[1])
+	assert.Equal(t, "03/01/2024 12:30:00,,,0.00000123456,BTC,0.00000002,BTC,", lines[2])
+}
+
+func TestExportTransactions_Ledger(t *testing.T) {
+	var buffer bytes.Buffer
+	err := ExportTransactions(&buffer, createExportTestTransactions(), &ExportOptions{
+		Format: EXPORT_FORMAT_LEDGER,
+	})
+	require.NoError(t, err)
+
+	expected := "2024/03/01 * salary\n" +
+		"    ; payment_hash: hash1\n" +
+		"    Assets:Lightning    0.00001000 BTC\n" +
+		"    Income:Lightning    -0.00001000 BTC\n\n" +
+		"2024/03/01 * coffee\n" +
+		"    ; payment_hash: hash2\n" +
+		"    Expenses:Lightning    0.00000123456 BTC\n" +
+		"    Expenses:Lightning:Fees    0.00000002 BTC\n" +
+		"    Assets:Lightning    -0.00000125456 BTC\n\n"
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestExportTransactions_LedgerWithFiatRates(t *testing.T) {
+	var buffer bytes.Buffer
+	err := ExportTransactions(&buffer, createExportTestTransactions(), &ExportOptions{
+		Format:       EXPORT_FORMAT_LEDGER,
+		FiatCurrency: "USD",
+		FiatRates:    map[uint]float64{1: 50_000, 2: 60_000},
+	})
+	require.NoError(t, err)
+
+	// the postings are balanced in BTC, the rates are price directives
+	expected := "P 2024/03/01 BTC 50000.00 USD\n" +
+		"2024/03/01 * salary\n" +
+		"    ; payment_hash: hash1\n" +
+		"    Assets:Lightning    0.00001000 BTC\n" +
+		"    Income:Lightning    -0.00001000 BTC\n\n" +
+		"P 2024/03/01 BTC 60000.00 USD\n" +
+		"2024/03/01 * coffee\n" +
+		"    ; payment_hash: hash2\n" +
+		"    Expenses:Lightning    0.00000123456 BTC\n" +
+		"    Expenses:Lightning:Fees    0.00000002 BTC\n" +
+		"    Assets:Lightning    -0.00000125456 BTC\n\n"
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestExportTransactions_CsvWithoutRecordedRate(t *testing.T) {
+	var buffer bytes.Buffer
+	err := ExportTransactions(&buffer, createExportTestTransactions(), &ExportOptions{
+		Format:       EXPORT_FORMAT_CSV,
+		FiatCurrency: "USD",
+		FiatRates:    map[uint]float64{1: 50_000},
+	})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Equal(t, 3, len(lines))
+	assert.Equal(t, "date,type,amount_sat,fee_sat,description,payment_hash,app_id,fiat_value,fiat_currency", lines[0])
+	assert.Equal(t, "2024-03-01T11:30:00Z,incoming,1000,0,salary,hash1,,0.50,USD", lines[1])
+	// no rate was recorded when the payment settled
+	assert.Equal(t, "2024-03-01T12:30:00Z,outgoing,123.456,2,coffee,hash2,,,", lines[2])
+}
+
+func TestExportTransactions_UnsupportedFormat(t *testing.T) {
+	var buffer bytes.Buffer
+	err := ExportTransactions(&buffer, createExportTestTransactions(), &ExportOptions{
+		Format: "xlsx",
+	})
+	assert.Error(t, err)
+}
+
+func TestFormatMsatAsBtc(t *testing.T) {
+	assert.Equal(t, "0.00000000", formatMsatAsBtc(0))
+	assert.Equal(t, "0.00000001", formatMsatAsBtc(1_000))
+	assert.Equal(t, "0.00000000001", formatMsatAsBtc(1))
+	assert.Equal(t, "21.00000000", formatMsatAsBtc(2_100_000_000_000))
+}
@@ -0,0 +1,98 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/logger"
+)
+
+// FiatRateProvider returns the current bitcoin price in a fiat currency
+type FiatRateProvider interface {
+	GetFiatRate(ctx context.Context, currency string) (float64, error)
+}
+
+// the provider is shared by all transactions service instances, like the balance validation lock
+var fiatRateProvider FiatRateProvider
+var fiatRateProviderMutex sync.RWMutex
+
+func SetFiatRateProvider(provider FiatRateProvider) {
+	fiatRateProviderMutex.Lock()
+	defer fiatRateProviderMutex.Unlock()
+	fiatRateProvider = provider
+}
+
+func GetCurrentFiatRate(ctx context.Context, currency string) (float64, error) {
+	fiatRateProviderMutex.RLock()
+	provider := fiatRateProvider
+	fiatRateProviderMutex.RUnlock()
+
+	if provider == nil {
+		return 0, errors.New("no fiat rate provider configured")
+	}
+	rate, err := provider.GetFiatRate(ctx, currency)
+	if err != nil {
+		return 0, err
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("invalid %s rate: %v", currency, rate)
+	}
+	return rate, nil
+}
+
+// validateFiatBudget converts the payment at the current rate and checks it against the remaining fiat budget
+func (svc *transactionsService) validateFiatBudget(tx *gorm.DB, app *db.App, appPermission *db.AppPermission, amountWithFeeReserve uint64, description string) error {
+	rate, err := GetCurrentFiatRate(context.Background(), appPermission.BudgetCurrency)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("currency", appPermission.BudgetCurrency).Error("Failed to get rate for fiat budget")
+		return fmt.Errorf("failed to convert payment to budget currency: %w", err)
+	}
+
+	budgetUsageFiat := queries.GetBudgetUsageFiat(tx, appPermission, rate)
+	amountFiat := GetFiatValue(amountWithFeeReserve, rate)
+	if amountFiat <= appPermission.MaxAmountFiat-budgetUsageFiat {
+		return nil
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":            app.ID,
+		"currency":          appPermission.BudgetCurrency,
+		"rate":              rate,
+		"amount_fiat":       amountFiat,
+		"budget_usage_fiat": budgetUsageFiat,
+		"max_amount_fiat":   appPermission.MaxAmountFiat,
+	}).Debug("Payment exceeds the remaining fiat budget")
+	svc.publishQuotaExceeded(app, description)
+	return NewQuotaExceededError()
+}
+
+// recordBudgetFiatRate stores the rate used to convert a payment against a fiat budget,
+// so that later budget checks keep counting the payment at the same value.
+func recordBudgetFiatRate(tx *gorm.DB, appId *uint, transactionId uint) error {
+	if appId == nil {
+		return nil
+	}
+	var appPermission db.AppPermission
+	if tx.Limit(1).Find(&appPermission, &db.AppPermission{AppId: *appId, Scope: constants.PAY_INVOICE_SCOPE}).RowsAffected == 0 || appPermission.BudgetCurrency == "" {
+		return nil
+	}
+
+	rate, err := GetCurrentFiatRate(context.Background(), appPermission.BudgetCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to convert payment to budget currency: %w", err)
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&db.TransactionFiatRate{
+		TransactionId: transactionId,
+		Currency:      appPermission.BudgetCurrency,
+		Rate:          rate,
+	}).Error
+}
@@ -0,0 +1,87 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+type mockFiatRateProvider struct {
+	rate float64
+}
+
+func (provider *mockFiatRateProvider) GetFiatRate(ctx context.Context, currency string) (float64, error) {
+	return provider.rate, nil
+}
+
+func createFiatBudgetApp(t *testing.T, svc *tests.TestService, maxAmountFiat float64) *db.App {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId:          app.ID,
+		App:            *app,
+		Scope:          constants.PAY_INVOICE_SCOPE,
+		BudgetRenewal:  constants.BUDGET_RENEWAL_MONTHLY,
+		BudgetCurrency: "EUR",
+		MaxAmountFiat:  maxAmountFiat,
+	}).Error)
+	return app
+}
+
+func TestSendPaymentSync_FiatBudget(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	SetFiatRateProvider(&mockFiatRateProvider{rate: 100_000})
+	defer SetFiatRateProvider(nil)
+
+	app := createFiatBudgetApp(t, svc, 1)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+
+	// the rate used for the budget is recorded with the payment
+	var transactionFiatRate db.TransactionFiatRate
+	require.NoError(t, svc.DB.First(&transactionFiatRate, &db.TransactionFiatRate{TransactionId: transaction.ID}).Error)
+	assert.Equal(t, "EUR", transactionFiatRate.Currency)
+	assert.Equal(t, float64(100_000), transactionFiatRate.Rate)
+}
+
+func TestSendPaymentSync_FiatBudgetExceeded(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	SetFiatRateProvider(&mockFiatRateProvider{rate: 100_000})
+	defer SetFiatRateProvider(nil)
+
+	// 123 sats are worth 0.123 EUR
+	app := createFiatBudgetApp(t, svc, 0.1)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.ErrorIs(t, err, NewQuotaExceededError())
+	assert.Nil(t, transaction)
+}
+
+func TestSendPaymentSync_FiatBudgetNoRateProvider(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createFiatBudgetApp(t, svc, 1)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.ErrorContains(t, err, "no fiat rate provider configured")
+	assert.Nil(t, transaction)
+}
@@ -0,0 +1,74 @@
+package transactions
+
+import (
+	"slices"
+	"strings"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/getAlby/hub/db"
+)
+
+const msatPerBtc = 100_000_000_000
+
+// fiatRatesChunkSize is the number of transactions which rates are queried for at once
+const fiatRatesChunkSize = 500
+
+// SaveFiatRates stores the bitcoin price in each currency at the time the transaction settled.
+// Rates which were already stored for a currency are kept.
+func (svc *transactionsService) SaveFiatRates(transactionId uint, rates map[string]float64) error {
+	fiatRates := []db.TransactionFiatRate{}
+	for currency, rate := range rates {
+		fiatRates = append(fiatRates, db.TransactionFiatRate{
+			TransactionId: transactionId,
+			Currency:      strings.ToUpper(currency),
+			Rate:          rate,
+		})
+	}
+	if len(fiatRates) == 0 {
+		return nil
+	}
+
+	return svc.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&fiatRates).Error
+}
+
+// GetFiatRates returns the stored rates of the given transactions, optionally limited to some currencies
+func (svc *transactionsService) GetFiatRates(ids []uint, currencies []string) (map[uint][]db.TransactionFiatRate, error) {
+	fiatRates := map[uint][]db.TransactionFiatRate{}
+	if len(ids) == 0 {
+		return fiatRates, nil
+	}
+
+	// the ids are queried in chunks to stay below the bound parameter limits of the databases
+	for chunk := range slices.Chunk(ids, fiatRatesChunkSize) {
+		query := svc.db.Where("transaction_id IN ?", chunk)
+		if len(currencies) > 0 {
+			query = query.Where("currency IN ?", normalizeCurrencies(currencies))
+		}
+		var results []db.TransactionFiatRate
+		if err := query.Order("currency").Find(&results).Error; err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			fiatRates[result.TransactionId] = append(fiatRates[result.TransactionId], result)
+		}
+	}
+	return fiatRates, nil
+}
+
+// GetFiatValue converts a millisat amount using a rate in fiat per bitcoin
+func GetFiatValue(amountMsat uint64, rate float64) float64 {
+	return float64(amountMsat) / msatPerBtc * rate
+}
+
+func normalizeCurrencies(currencies []string) []string {
+	normalized := []string{}
+	for _, currency := range currencies {
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if currency != "" {
+			normalized = append(normalized, currency)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,66 @@
+package transactions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestFiatRates(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	settledAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	transactions := []db.Transaction{
+		{Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 100_000_000, SettledAt: &settledAt},
+		{Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 200_000_000, SettledAt: &settledAt},
+		// no rates stored
+		{Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 300_000_000, SettledAt: &settledAt},
+	}
+	require.NoError(t, svc.DB.Create(&transactions).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	require.NoError(t, transactionsService.SaveFiatRates(transactions[0].ID, map[string]float64{"usd": 100_000, "EUR": 90_000}))
+	require.NoError(t, transactionsService.SaveFiatRates(transactions[1].ID, map[string]float64{"USD": 110_000}))
+	// rates at settlement are not overwritten
+	require.NoError(t, transactionsService.SaveFiatRates(transactions[0].ID, map[string]float64{"USD": 120_000}))
+
+	fiatRates, err := transactionsService.GetFiatRates([]uint{transactions[0].ID, transactions[1].ID, transactions[2].ID}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(fiatRates[transactions[0].ID]))
+	assert.Equal(t, "EUR", fiatRates[transactions[0].ID][0].Currency)
+	assert.Equal(t, float64(100_000), fiatRates[transactions[0].ID][1].Rate)
+	assert.Empty(t, fiatRates[transactions[2].ID])
+
+	fiatRates, err = transactionsService.GetFiatRates([]uint{transactions[0].ID}, []string{"eur"})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(fiatRates[transactions[0].ID]))
+	assert.Equal(t, 90.0, GetFiatValue(transactions[0].AmountMsat, fiatRates[transactions[0].ID][0].Rate))
+
+	// more ids than the databases allow as bound parameters of a single query
+	manyIds := []uint{}
+	for id := uint(1_000_000); len(manyIds) < 70_000; id++ {
+		manyIds = append(manyIds, id)
+	}
+	manyIds = append(manyIds, transactions[1].ID)
+	fiatRates, err = transactionsService.GetFiatRates(manyIds, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(fiatRates))
+	assert.Equal(t, float64(110_000), fiatRates[transactions[1].ID][0].Rate)
+
+	summary, err := transactionsService.GetSummary(settledAt.Add(-time.Hour), settledAt.Add(time.Hour), nil, "usd")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", summary.Currency)
+	assert.Equal(t, uint64(600_000_000), summary.Received.AmountMsat)
+	// 100 + 220 USD, the transaction without a rate has no fiat value
+	assert.InDelta(t, 320.0, summary.Received.FiatAmount, 0.0001)
+	require.Equal(t, 1, len(summary.Days))
+	assert.InDelta(t, 320.0, summary.Days[0].Received.FiatAmount, 0.0001)
+}
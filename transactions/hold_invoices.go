@@ -0,0 +1,25 @@
+package transactions
+
+import (
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+)
+
+// GetHoldInvoiceState returns where a hold invoice is in its lifecycle:
+// open -> accepted -> settled or canceled. An invoice which expired before it
+// was accepted is canceled. Returns an empty string for regular transactions.
+func GetHoldInvoiceState(transaction *db.Transaction) string {
+	if !transaction.Hold {
+		return ""
+	}
+	switch transaction.State {
+	case constants.TRANSACTION_STATE_PENDING:
+		return constants.HOLD_INVOICE_STATE_OPEN
+	case constants.TRANSACTION_STATE_ACCEPTED:
+		return constants.HOLD_INVOICE_STATE_ACCEPTED
+	case constants.TRANSACTION_STATE_SETTLED:
+		return constants.HOLD_INVOICE_STATE_SETTLED
+	default:
+		return constants.HOLD_INVOICE_STATE_CANCELED
+	}
+}
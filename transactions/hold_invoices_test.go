@@ -0,0 +1,116 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+func acceptHoldInvoice(ctx context.Context, transactionsService *transactionsService, paymentHash string) {
+	settleDeadline := uint32(800_000)
+	transactionsService.ConsumeEvent(ctx, &events.Event{
+		Event: "nwc_lnclient_hold_invoice_accepted",
+		Properties: &lnclient.Transaction{
+			Type:           "incoming",
+			PaymentHash:    paymentHash,
+			SettleDeadline: &settleDeadline,
+		},
+	}, map[string]interface{}{})
+}
+
+func getConsumedEventNames(mockEventConsumer interface{ GetConsumedEvents() []*events.Event }) []string {
+	names := []string{}
+	for _, event := range mockEventConsumer.GetConsumedEvents() {
+		names = append(names, event.Event)
+	}
+	return names
+}
+
+func TestHoldInvoiceLifecycle_Settled(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.MakeHoldInvoice(ctx, 2, "Hold invoice", "", 0, tests.MockLNClientHoldTransaction.PaymentHash, nil, svc.LNClient, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.HOLD_INVOICE_STATE_OPEN, GetHoldInvoiceState(transaction))
+
+	acceptHoldInvoice(ctx, transactionsService, transaction.PaymentHash)
+
+	var acceptedTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&acceptedTransaction, transaction.ID).Error)
+	assert.Equal(t, constants.HOLD_INVOICE_STATE_ACCEPTED, GetHoldInvoiceState(&acceptedTransaction))
+	assert.NotNil(t, acceptedTransaction.AcceptedAt)
+	assert.Nil(t, acceptedTransaction.SettledAt)
+
+	settledTransaction, err := transactionsService.SettleHoldInvoice(ctx, tests.MockLNClientHoldTransaction.Preimage, svc.LNClient)
+	require.NoError(t, err)
+	assert.Equal(t, constants.HOLD_INVOICE_STATE_SETTLED, GetHoldInvoiceState(settledTransaction))
+	assert.NotNil(t, settledTransaction.AcceptedAt)
+	assert.NotNil(t, settledTransaction.SettledAt)
+	assert.Nil(t, settledTransaction.CanceledAt)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.ElementsMatch(t, []string{
+		"nwc_hold_invoice_created",
+		"nwc_hold_invoice_accepted",
+		"nwc_payment_received",
+		"nwc_hold_invoice_settled",
+	}, getConsumedEventNames(mockEventConsumer))
+}
+
+func TestHoldInvoiceLifecycle_Canceled(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.MakeHoldInvoice(ctx, 2, "Hold invoice", "", 0, tests.MockLNClientHoldTransaction.PaymentHash, nil, svc.LNClient, nil, nil)
+	require.NoError(t, err)
+
+	// only accepted hold invoices can be canceled
+	err = transactionsService.CancelHoldInvoice(ctx, transaction.PaymentHash, svc.LNClient)
+	assert.IsType(t, NewNotFoundError(), err)
+
+	acceptHoldInvoice(ctx, transactionsService, transaction.PaymentHash)
+	err = transactionsService.CancelHoldInvoice(ctx, transaction.PaymentHash, svc.LNClient)
+	require.NoError(t, err)
+
+	var canceledTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&canceledTransaction, transaction.ID).Error)
+	assert.Equal(t, constants.HOLD_INVOICE_STATE_CANCELED, GetHoldInvoiceState(&canceledTransaction))
+	assert.NotNil(t, canceledTransaction.AcceptedAt)
+	assert.NotNil(t, canceledTransaction.CanceledAt)
+	assert.Nil(t, canceledTransaction.SettledAt)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.ElementsMatch(t, []string{
+		"nwc_hold_invoice_created",
+		"nwc_hold_invoice_accepted",
+		"nwc_payment_failed",
+		"nwc_hold_invoice_canceled",
+	}, getConsumedEventNames(mockEventConsumer))
+}
+
+func TestGetHoldInvoiceState_RegularInvoice(t *testing.T) {
+	assert.Empty(t, GetHoldInvoiceState(&db.Transaction{State: constants.TRANSACTION_STATE_PENDING}))
+	assert.Equal(t, constants.HOLD_INVOICE_STATE_CANCELED, GetHoldInvoiceState(&db.Transaction{Hold: true, State: constants.TRANSACTION_STATE_EXPIRED}))
+}
@@ -0,0 +1,76 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+const maxIdempotencyKeyLength = 255
+
+type idempotencyKeyConflictError struct {
+}
+
+func NewIdempotencyKeyConflictError() error {
+	return &idempotencyKeyConflictError{}
+}
+
+func (err *idempotencyKeyConflictError) Error() string {
+	return "The idempotency key has already been used for a different payment"
+}
+
+func validateIdempotencyKey(idempotencyKey string) error {
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key is too long. Limit: %d", maxIdempotencyKeyLength)
+	}
+	return nil
+}
+
+// findIdempotentPayment returns the outgoing payment previously made by the app with the same idempotency key
+func findIdempotentPayment(tx *gorm.DB, appId *uint, idempotencyKey string) (*db.Transaction, error) {
+	query := tx.Where("type = ? AND idempotency_key = ?", constants.TRANSACTION_TYPE_OUTGOING, idempotencyKey)
+	if appId != nil {
+		query = query.Where("app_id = ?", *appId)
+	} else {
+		query = query.Where("app_id IS NULL")
+	}
+
+	var transaction db.Transaction
+	result := query.Limit(1).Find(&transaction)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &transaction, nil
+}
+
+// getIdempotentResult replays the outcome of a payment which was already made with the same idempotency key
+func getIdempotentResult(transaction *db.Transaction, paymentHash string) (*Transaction, error) {
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":          transaction.AppId,
+		"idempotency_key": transaction.IdempotencyKey,
+		"payment_hash":    transaction.PaymentHash,
+		"state":           transaction.State,
+	}).Debug("Found existing payment for idempotency key")
+
+	if transaction.PaymentHash != paymentHash {
+		return nil, NewIdempotencyKeyConflictError()
+	}
+
+	switch transaction.State {
+	case constants.TRANSACTION_STATE_SETTLED:
+		return transaction, nil
+	case constants.TRANSACTION_STATE_FAILED:
+		return nil, errors.New(transaction.FailureReason)
+	default:
+		return nil, errors.New("there is already a payment pending for this invoice")
+	}
+}
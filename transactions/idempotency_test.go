@@ -0,0 +1,92 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSendPaymentSync_IdempotencyKey_Retry(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSyncWithIdempotencyKey(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "key1", *transaction.IdempotencyKey)
+
+	retriedTransaction, err := transactionsService.SendPaymentSyncWithIdempotencyKey(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, transaction.ID, retriedTransaction.ID)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, retriedTransaction.State)
+	assert.Equal(t, "123preimage", *retriedTransaction.Preimage)
+
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// without the key the retry is rejected
+	_, err = transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.EqualError(t, err, "this invoice has already been paid")
+}
+
+func TestSendPaymentSync_IdempotencyKey_Failed(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	idempotencyKey := "key1"
+	svc.DB.Create(&db.Transaction{
+		State:          constants.TRANSACTION_STATE_FAILED,
+		Type:           constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentHash:    tests.MockLNClientTransaction.PaymentHash,
+		AmountMsat:     123000,
+		FailureReason:  "no route",
+		IdempotencyKey: &idempotencyKey,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSyncWithIdempotencyKey(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil, idempotencyKey)
+	assert.EqualError(t, err, "no route")
+	assert.Nil(t, transaction)
+
+	// a new key makes a new attempt
+	transaction, err = transactionsService.SendPaymentSyncWithIdempotencyKey(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestSendPaymentSync_IdempotencyKey_Conflict(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	idempotencyKey := "key1"
+	svc.DB.Create(&db.Transaction{
+		AppId:          &app.ID,
+		State:          constants.TRANSACTION_STATE_SETTLED,
+		Type:           constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentHash:    "other",
+		AmountMsat:     1000,
+		IdempotencyKey: &idempotencyKey,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.SendPaymentSyncWithIdempotencyKey(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil, idempotencyKey)
+	assert.IsType(t, NewIdempotencyKeyConflictError(), err)
+
+	// keys are scoped to the app
+	transaction, err := transactionsService.SendPaymentSyncWithIdempotencyKey(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil, idempotencyKey)
+	require.NoError(t, err)
+	assert.Nil(t, transaction.AppId)
+}
@@ -0,0 +1,111 @@
+package transactions
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/health"
+	"github.com/getAlby/hub/logger"
+)
+
+const invoiceExpirySweepInterval = time.Minute
+
+// StartInvoiceExpirySweep periodically marks unpaid invoices as expired until the context is cancelled
+func (svc *transactionsService) StartInvoiceExpirySweep(ctx context.Context) {
+	health.RegisterJob("invoice_expiry_sweep", invoiceExpirySweepInterval)
+	go func() {
+		defer health.RemoveJob("invoice_expiry_sweep")
+		ticker := time.NewTicker(invoiceExpirySweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_, err := svc.ExpireInvoices()
+				if err != nil {
+					logger.Logger.WithError(err).Error("Failed to expire invoices")
+				}
+				health.ReportJobRun("invoice_expiry_sweep", err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// ExpireInvoices moves pending incoming invoices which are past their expiry to the expired state,
+// so they are no longer counted as amounts that can still be received. Accepted hold invoices are
+// not affected. An invoice that is paid after it expired is still marked as settled.
+func (svc *transactionsService) ExpireInvoices() (int, error) {
+	expiredInvoices := []db.Transaction{}
+	err := svc.db.
+		Where("type = ? AND state = ? AND expires_at < ?", constants.TRANSACTION_TYPE_INCOMING, constants.TRANSACTION_STATE_PENDING, time.Now()).
+		Find(&expiredInvoices).Error
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, expiredInvoice := range expiredInvoices {
+		if svc.markInvoiceExpired(&expiredInvoice) {
+			count++
+		}
+	}
+
+	if count > 0 {
+		logger.Logger.WithField("count", count).Debug("Marked invoices as expired")
+	}
+	return count, nil
+}
+
+func isInvoiceExpired(transaction *db.Transaction) bool {
+	return transaction.Type == constants.TRANSACTION_TYPE_INCOMING &&
+		transaction.ExpiresAt != nil &&
+		time.Now().After(*transaction.ExpiresAt)
+}
+
+func (svc *transactionsService) markInvoiceExpired(transaction *db.Transaction) bool {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"state": constants.TRANSACTION_STATE_EXPIRED,
+	}
+	// an open hold invoice can no longer be accepted once it expired
+	if transaction.Hold {
+		updates["canceled_at"] = now
+	}
+
+	// only update if the invoice is still pending to not race with it being paid
+	result := svc.db.Model(&db.Transaction{}).
+		Where("id = ? AND state = ?", transaction.ID, constants.TRANSACTION_STATE_PENDING).
+		Updates(updates)
+	if result.Error != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"payment_hash": transaction.PaymentHash,
+		}).WithError(result.Error).Error("Failed to mark invoice as expired")
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+
+	transaction.State = constants.TRANSACTION_STATE_EXPIRED
+	if transaction.Hold {
+		transaction.CanceledAt = &now
+	}
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event:      "nwc_invoice_expired",
+		Properties: transaction,
+	})
+	if transaction.Hold {
+		svc.eventPublisher.Publish(&events.Event{
+			Event:      "nwc_hold_invoice_canceled",
+			Properties: transaction,
+		})
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+func createInvoiceTransaction(t *testing.T, svc *tests.TestService, paymentHash string, state string, expiresAt time.Time) *db.Transaction {
+	transaction := &db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_INCOMING,
+		State:       state,
+		AmountMsat:  123_000,
+		PaymentHash: paymentHash,
+		ExpiresAt:   &expiresAt,
+	}
+	require.NoError(t, svc.DB.Create(transaction).Error)
+	return transaction
+}
+
+func TestExpireInvoices(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	expired := createInvoiceTransaction(t, svc, "expired", constants.TRANSACTION_STATE_PENDING, time.Now().Add(-time.Minute))
+	unexpired := createInvoiceTransaction(t, svc, "unexpired", constants.TRANSACTION_STATE_PENDING, time.Now().Add(time.Hour))
+	accepted := createInvoiceTransaction(t, svc, "accepted", constants.TRANSACTION_STATE_ACCEPTED, time.Now().Add(-time.Minute))
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	count, err := transactionsService.ExpireInvoices()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	getState := func(id uint) string {
+		var transaction db.Transaction
+		require.NoError(t, svc.DB.First(&transaction, id).Error)
+		return transaction.State
+	}
+	assert.Equal(t, constants.TRANSACTION_STATE_EXPIRED, getState(expired.ID))
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, getState(unexpired.ID))
+	assert.Equal(t, constants.TRANSACTION_STATE_ACCEPTED, getState(accepted.ID))
+
+	// already expired invoices are not expired again
+	count, err = transactionsService.ExpireInvoices()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	time.Sleep(10 * time.Millisecond)
+	consumedEvents := mockEventConsumer.GetConsumedEvents()
+	require.Equal(t, 1, len(consumedEvents))
+	assert.Equal(t, "nwc_invoice_expired", consumedEvents[0].Event)
+	assert.Equal(t, "expired", consumedEvents[0].Properties.(*db.Transaction).PaymentHash)
+}
+
+func TestExpireInvoices_PaidAfterExpiry(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	expired := createInvoiceTransaction(t, svc, tests.MockPaymentHash, constants.TRANSACTION_STATE_PENDING, time.Now().Add(-time.Minute))
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.ExpireInvoices()
+	require.NoError(t, err)
+
+	// the node can still accept a payment for an expired invoice
+	transactionsService.ConsumeEvent(ctx, &events.Event{
+		Event: "nwc_lnclient_payment_received",
+		Properties: &lnclient.Transaction{
+			Type:        "incoming",
+			Amount:      123_000,
+			PaymentHash: tests.MockPaymentHash,
+			Preimage:    "preimage",
+			SettledAt:   &tests.MockTimeUnix,
+		},
+	}, map[string]interface{}{})
+
+	var transaction db.Transaction
+	require.NoError(t, svc.DB.First(&transaction, expired.ID).Error)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
@@ -0,0 +1,106 @@
+package transactions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// encodeMetadata serializes transaction metadata and enforces the configured size limit.
+// Depending on the configured policy oversized metadata is either rejected, or its largest
+// top-level fields are dropped until it fits. Dropped fields are listed under "truncated_keys".
+func (svc *transactionsService) encodeMetadata(metadata map[string]interface{}, kind string) ([]byte, error) {
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to serialize metadata")
+		return nil, err
+	}
+
+	maxLength, policy := svc.getMetadataLimits()
+	if len(metadataBytes) <= maxLength {
+		return metadataBytes, nil
+	}
+
+	if policy != constants.METADATA_POLICY_TRUNCATE {
+		return nil, fmt.Errorf("encoded %s metadata provided is too large. Limit: %d Received: %d", kind, maxLength, len(metadataBytes))
+	}
+
+	truncatedBytes, err := truncateMetadata(metadata, maxLength)
+	if err != nil {
+		return nil, fmt.Errorf("encoded %s metadata provided is too large and could not be truncated. Limit: %d Received: %d", kind, maxLength, len(metadataBytes))
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"limit":    maxLength,
+		"received": len(metadataBytes),
+	}).Warn("Truncated oversized metadata")
+	return truncatedBytes, nil
+}
+
+func truncateMetadata(metadata map[string]interface{}, maxLength int) ([]byte, error) {
+	type field struct {
+		key  string
+		size int
+	}
+	fields := []field{}
+	for key, value := range metadata {
+		valueBytes, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{key: key, size: len(key) + len(valueBytes)})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].size == fields[j].size {
+			return fields[i].key < fields[j].key
+		}
+		return fields[i].size > fields[j].size
+	})
+
+	truncated := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		truncated[key] = value
+	}
+	truncatedKeys := []string{}
+	for _, field := range fields {
+		delete(truncated, field.key)
+		truncatedKeys = append(truncatedKeys, field.key)
+		truncated["truncated_keys"] = truncatedKeys
+
+		truncatedBytes, err := json.Marshal(truncated)
+		if err != nil {
+			return nil, err
+		}
+		if len(truncatedBytes) <= maxLength {
+			return truncatedBytes, nil
+		}
+	}
+	return nil, fmt.Errorf("metadata does not fit within %d bytes", maxLength)
+}
+
+func (svc *transactionsService) getMetadataLimits() (int, string) {
+	maxLength := constants.INVOICE_METADATA_MAX_LENGTH
+	policy := constants.METADATA_POLICY_REJECT
+
+	var userConfigs []db.UserConfig
+	svc.db.Where("key IN ?", []string{config.MetadataMaxLengthKey, config.MetadataPolicyKey}).Find(&userConfigs)
+	for _, userConfig := range userConfigs {
+		switch userConfig.Key {
+		case config.MetadataMaxLengthKey:
+			if parsedMaxLength, err := strconv.Atoi(userConfig.Value); err == nil && parsedMaxLength > 0 {
+				maxLength = parsedMaxLength
+			}
+		case config.MetadataPolicyKey:
+			if userConfig.Value == constants.METADATA_POLICY_TRUNCATE {
+				policy = constants.METADATA_POLICY_TRUNCATE
+			}
+		}
+	}
+	return maxLength, policy
+}
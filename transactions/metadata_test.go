@@ -0,0 +1,62 @@
+package transactions
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestMakeInvoice_MetadataConfiguredLimit(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.MetadataMaxLengthKey, "50", ""))
+
+	metadata := map[string]interface{}{
+		"order": strings.Repeat("a", 100),
+	}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.MakeInvoice(ctx, 1234, "Hello world", "", 0, metadata, svc.LNClient, nil, nil, nil)
+	assert.EqualError(t, err, "encoded invoice metadata provided is too large. Limit: 50 Received: 112")
+}
+
+func TestMakeInvoice_MetadataTruncated(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.MetadataMaxLengthKey, "100", ""))
+	require.NoError(t, svc.Cfg.SetUpdate(config.MetadataPolicyKey, constants.METADATA_POLICY_TRUNCATE, ""))
+
+	metadata := map[string]interface{}{
+		"comment":     "thanks",
+		"zap_request": strings.Repeat("a", 200),
+	}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.MakeInvoice(ctx, 1234, "Hello world", "", 0, metadata, svc.LNClient, nil, nil, nil)
+	require.NoError(t, err)
+
+	var storedMetadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(transaction.Metadata, &storedMetadata))
+	assert.Equal(t, "thanks", storedMetadata["comment"])
+	assert.Nil(t, storedMetadata["zap_request"])
+	assert.Equal(t, []interface{}{"zap_request"}, storedMetadata["truncated_keys"])
+}
+
+func TestTruncateMetadata_DoesNotFit(t *testing.T) {
+	_, err := truncateMetadata(map[string]interface{}{"a": "b"}, 5)
+	assert.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package transactions
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// how long a payment waits for the user to approve it
+var paymentApprovalTimeout = 5 * time.Minute
+
+// waiting payments are shared by all transactions service instances, like the balance validation lock
+var paymentApprovalWaiters = map[uint]chan bool{}
+var paymentApprovalWaitersMutex sync.Mutex
+
+type paymentNotApprovedError struct {
+}
+
+func NewPaymentNotApprovedError() error {
+	return &paymentNotApprovedError{}
+}
+
+func (err *paymentNotApprovedError) Error() string {
+	return "This payment requires approval and was not approved. Please review pending approvals in your Alby Hub."
+}
+
+// waitForPaymentApproval blocks payments above the approval threshold of the app until the user
+// approves or rejects them, or the approval times out. Smaller payments continue immediately.
+func (svc *transactionsService) waitForPaymentApproval(appId *uint, requestEventId *uint, amountMsat uint64, paymentHash string, destination string, description string) error {
+	pendingApproval, err := svc.requestPaymentApproval(appId, requestEventId, amountMsat, paymentHash, destination, description)
+	if err != nil {
+		return err
+	}
+	return svc.awaitPaymentApproval(pendingApproval)
+}
+
+type pendingPaymentApproval struct {
+	paymentApproval db.PaymentApproval
+	decision        chan bool
+}
+
+// requestPaymentApproval stores an approval for a payment above the approval threshold of the app
+// and notifies the user. It returns nil if the payment does not need to be approved.
+func (svc *transactionsService) requestPaymentApproval(appId *uint, requestEventId *uint, amountMsat uint64, paymentHash string, destination string, description string) (*pendingPaymentApproval, error) {
+	if appId == nil {
+		return nil, nil
+	}
+	var app db.App
+	if svc.db.Limit(1).Find(&app, &db.App{ID: *appId}).RowsAffected == 0 {
+		return nil, NewNotFoundError()
+	}
+	if app.ApprovalThresholdSat == nil || amountMsat <= *app.ApprovalThresholdSat*1000 {
+		return nil, nil
+	}
+
+	pendingApproval := &pendingPaymentApproval{
+		paymentApproval: db.PaymentApproval{
+			AppId:          app.ID,
+			RequestEventId: requestEventId,
+			PaymentHash:    paymentHash,
+			Destination:    destination,
+			AmountMsat:     amountMsat,
+			Description:    description,
+			State:          constants.PAYMENT_APPROVAL_STATE_PENDING,
+			ExpiresAt:      time.Now().Add(paymentApprovalTimeout),
+		},
+		decision: make(chan bool, 1),
+	}
+	paymentApproval := &pendingApproval.paymentApproval
+	paymentApprovalWaitersMutex.Lock()
+	err := svc.db.Create(paymentApproval).Error
+	if err == nil {
+		paymentApprovalWaiters[paymentApproval.ID] = pendingApproval.decision
+	}
+	paymentApprovalWaitersMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":              app.ID,
+		"payment_approval_id": paymentApproval.ID,
+		"amount_msat":         amountMsat,
+		"approval_threshold":  *app.ApprovalThresholdSat,
+		"approval_expires_at": paymentApproval.ExpiresAt,
+	}).Info("Payment is waiting for approval")
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_payment_approval_requested",
+		Properties: map[string]interface{}{
+			"id":          paymentApproval.ID,
+			"app_name":    app.Name,
+			"amount":      amountMsat / 1000,
+			"description": description,
+			"expires_at":  paymentApproval.ExpiresAt.Unix(),
+		},
+	})
+
+	return pendingApproval, nil
+}
+
+// awaitPaymentApproval blocks until the user decides a requested approval or it times out.
+// A nil approval was not needed and continues immediately.
+func (svc *transactionsService) awaitPaymentApproval(pendingApproval *pendingPaymentApproval) error {
+	if pendingApproval == nil {
+		return nil
+	}
+	paymentApproval := &pendingApproval.paymentApproval
+	defer func() {
+		paymentApprovalWaitersMutex.Lock()
+		delete(paymentApprovalWaiters, paymentApproval.ID)
+		paymentApprovalWaitersMutex.Unlock()
+	}()
+
+	select {
+	case approved := <-pendingApproval.decision:
+		if approved {
+			return nil
+		}
+		return NewPaymentNotApprovedError()
+	case <-time.After(time.Until(paymentApproval.ExpiresAt)):
+		result := svc.db.Model(&db.PaymentApproval{}).
+			Where("id = ? AND state = ?", paymentApproval.ID, constants.PAYMENT_APPROVAL_STATE_PENDING).
+			Update("state", constants.PAYMENT_APPROVAL_STATE_EXPIRED)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// decided at the same time as the timeout
+			var decidedApproval db.PaymentApproval
+			if err := svc.db.First(&decidedApproval, paymentApproval.ID).Error; err != nil {
+				return err
+			}
+			if decidedApproval.State == constants.PAYMENT_APPROVAL_STATE_APPROVED {
+				return nil
+			}
+		}
+		return NewPaymentNotApprovedError()
+	}
+}
+
+func (svc *transactionsService) ListPaymentApprovals(state string) ([]db.PaymentApproval, error) {
+	paymentApprovalWaitersMutex.Lock()
+	err := expirePaymentApprovals(svc.db)
+	paymentApprovalWaitersMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	query := svc.db.Preload("App").Order("id DESC")
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	var paymentApprovals []db.PaymentApproval
+	err = query.Find(&paymentApprovals).Error
+	return paymentApprovals, err
+}
+
+// DecidePaymentApproval approves or rejects a payment which is waiting for approval
+func (svc *transactionsService) DecidePaymentApproval(id uint, approved bool) error {
+	state := constants.PAYMENT_APPROVAL_STATE_REJECTED
+	if approved {
+		state = constants.PAYMENT_APPROVAL_STATE_APPROVED
+	}
+
+	paymentApprovalWaitersMutex.Lock()
+	defer paymentApprovalWaitersMutex.Unlock()
+
+	decision, ok := paymentApprovalWaiters[id]
+	if !ok {
+		// e.g. the hub restarted while the payment was waiting
+		if err := expirePaymentApprovals(svc.db); err != nil {
+			return err
+		}
+		return errors.New("no pending payment approval found")
+	}
+
+	result := svc.db.Model(&db.PaymentApproval{}).
+		Where("id = ? AND state = ?", id, constants.PAYMENT_APPROVAL_STATE_PENDING).
+		Update("state", state)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("no pending payment approval found")
+	}
+
+	decision <- approved
+	return nil
+}
+
+// expirePaymentApprovals marks approvals which no payment is waiting for anymore as expired.
+// Must be called with the waiters mutex held.
+func expirePaymentApprovals(tx *gorm.DB) error {
+	waitingIds := []uint{0}
+	for id := range paymentApprovalWaiters {
+		waitingIds = append(waitingIds, id)
+	}
+	return tx.Model(&db.PaymentApproval{}).
+		Where("state = ? AND id NOT IN ?", constants.PAYMENT_APPROVAL_STATE_PENDING, waitingIds).
+		Update("state", constants.PAYMENT_APPROVAL_STATE_EXPIRED).Error
+}
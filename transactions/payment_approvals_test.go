@@ -0,0 +1,178 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func createApprovalApp(t *testing.T, svc *tests.TestService, approvalThresholdSat uint64) *db.App {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	app.ApprovalThresholdSat = &approvalThresholdSat
+	require.NoError(t, svc.DB.Save(app).Error)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+	return app
+}
+
+// decides the next payment approval once it is requested
+func decidePaymentApproval(t *testing.T, transactionsService *transactionsService, approved bool) {
+	go func() {
+		assert.Eventually(t, func() bool {
+			paymentApprovals, err := transactionsService.ListPaymentApprovals(constants.PAYMENT_APPROVAL_STATE_PENDING)
+			if err != nil || len(paymentApprovals) == 0 {
+				return false
+			}
+			assert.Equal(t, uint64(123_000), paymentApprovals[0].AmountMsat)
+			assert.Equal(t, tests.MockPaymentHash, paymentApprovals[0].PaymentHash)
+			assert.NoError(t, transactionsService.DecidePaymentApproval(paymentApprovals[0].ID, approved))
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+	}()
+}
+
+func TestSendPaymentSync_BelowApprovalThreshold(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createApprovalApp(t, svc, 123)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+
+	var count int64
+	svc.DB.Model(&db.PaymentApproval{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestSendPaymentSync_Approved(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createApprovalApp(t, svc, 100)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	decidePaymentApproval(t, transactionsService, true)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+
+	var paymentApproval db.PaymentApproval
+	require.NoError(t, svc.DB.First(&paymentApproval).Error)
+	assert.Equal(t, constants.PAYMENT_APPROVAL_STATE_APPROVED, paymentApproval.State)
+}
+
+func TestSendPaymentSync_Rejected(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createApprovalApp(t, svc, 100)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	decidePaymentApproval(t, transactionsService, false)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.ErrorIs(t, err, NewPaymentNotApprovedError())
+	assert.Nil(t, transaction)
+
+	// no payment was attempted
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestSendPaymentSync_ApprovalTimeout(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalTimeout := paymentApprovalTimeout
+	paymentApprovalTimeout = 50 * time.Millisecond
+	defer func() { paymentApprovalTimeout = originalTimeout }()
+
+	app := createApprovalApp(t, svc, 100)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.ErrorIs(t, err, NewPaymentNotApprovedError())
+	assert.Nil(t, transaction)
+
+	var paymentApproval db.PaymentApproval
+	require.NoError(t, svc.DB.First(&paymentApproval).Error)
+	assert.Equal(t, constants.PAYMENT_APPROVAL_STATE_EXPIRED, paymentApproval.State)
+	assert.EqualError(t, transactionsService.DecidePaymentApproval(paymentApproval.ID, true), "no pending payment approval found")
+}
+
+func TestSendPaymentSync_IdempotencyKey_RetryAfterApproval(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createApprovalApp(t, svc, 100)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	decidePaymentApproval(t, transactionsService, true)
+	transaction, err := transactionsService.SendPaymentSyncWithIdempotencyKey(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil, "key1")
+	require.NoError(t, err)
+
+	// the retry returns the approved payment without asking for approval again
+	retriedTransaction, err := transactionsService.SendPaymentSyncWithIdempotencyKey(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, transaction.ID, retriedTransaction.ID)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, retriedTransaction.State)
+
+	var count int64
+	svc.DB.Model(&db.PaymentApproval{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestSendPaymentBatch_Approved(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createApprovalApp(t, svc, 100)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	// the approvals of both payments are requested before any is decided
+	go func() {
+		assert.Eventually(t, func() bool {
+			paymentApprovals, err := transactionsService.ListPaymentApprovals(constants.PAYMENT_APPROVAL_STATE_PENDING)
+			if err != nil || len(paymentApprovals) < 2 {
+				return false
+			}
+			for _, paymentApproval := range paymentApprovals {
+				assert.NoError(t, transactionsService.DecidePaymentApproval(paymentApproval.ID, true))
+			}
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+	}()
+	result, err := transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockInvoice},
+		{Invoice: mockBatchInvoice2},
+	}, svc.LNClient, &app.ID, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.SucceededCount)
+
+	var paymentApprovals []db.PaymentApproval
+	require.NoError(t, svc.DB.Find(&paymentApprovals).Error)
+	assert.Len(t, paymentApprovals, 2)
+	for _, paymentApproval := range paymentApprovals {
+		assert.Equal(t, constants.PAYMENT_APPROVAL_STATE_APPROVED, paymentApproval.State)
+	}
+}
@@ -0,0 +1,67 @@
+package transactions
+
+import (
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+// validatePaymentAmount limits the size of any single outgoing payment, so that a compromised
+// app connection cannot drain the node in one go. The hub-wide limit can be overridden per app.
+func (svc *transactionsService) validatePaymentAmount(tx *gorm.DB, appId *uint, amountMsat uint64) error {
+	var app *db.App
+	if appId != nil {
+		app = &db.App{}
+		if tx.Limit(1).Find(app, &db.App{ID: *appId}).RowsAffected == 0 {
+			return NewNotFoundError()
+		}
+	}
+
+	maxAmountSat := getMaxPaymentAmountSat(tx, app)
+	if maxAmountSat == 0 || amountMsat <= maxAmountSat*1000 {
+		return nil
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":         appId,
+		"amount_msat":    amountMsat,
+		"max_amount_sat": maxAmountSat,
+	}).Debug("Payment exceeds the maximum single payment amount")
+
+	if app != nil {
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_permission_denied",
+			Properties: map[string]interface{}{
+				"app_name": app.Name,
+				"code":     constants.ERROR_RESTRICTED,
+				"message":  NewPaymentAmountExceededError().Error(),
+			},
+		})
+	}
+	return NewPaymentAmountExceededError()
+}
+
+// returns 0 if there is no limit
+func getMaxPaymentAmountSat(tx *gorm.DB, app *db.App) uint64 {
+	if app != nil && app.MaxPaymentAmountSat != nil {
+		return *app.MaxPaymentAmountSat
+	}
+
+	var userConfig db.UserConfig
+	if tx.Limit(1).Find(&userConfig, &db.UserConfig{Key: config.MaxPaymentAmountSatKey}).RowsAffected == 0 || userConfig.Value == "" {
+		return 0
+	}
+	maxAmountSat, err := strconv.ParseUint(userConfig.Value, 10, 64)
+	if err != nil {
+		logger.Logger.WithField("value", userConfig.Value).WithError(err).Error("Invalid maximum payment amount config")
+		return 0
+	}
+	return maxAmountSat
+}
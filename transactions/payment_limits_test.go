@@ -0,0 +1,70 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSendPaymentSync_MaxPaymentAmount_Hub(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// the mock invoice is for 123 sats
+	require.NoError(t, svc.Cfg.SetUpdate(config.MaxPaymentAmountSatKey, "100", ""))
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.ErrorIs(t, err, NewPaymentAmountExceededError())
+	assert.Nil(t, transaction)
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.MaxPaymentAmountSatKey, "123", ""))
+	transaction, err = transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestSendPaymentSync_MaxPaymentAmount_AppOverride(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.MaxPaymentAmountSatKey, "1000", ""))
+
+	// the app has a lower limit than the hub
+	maxPaymentAmountSat := uint64(100)
+	require.NoError(t, svc.DB.Model(app).Update("max_payment_amount_sat", maxPaymentAmountSat).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.ErrorIs(t, err, NewPaymentAmountExceededError())
+	assert.Nil(t, transaction)
+
+	// no pending payment was created
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Count(&count)
+	assert.Zero(t, count)
+
+	// 0 removes the limit for the app even though the hub has one
+	require.NoError(t, svc.Cfg.SetUpdate(config.MaxPaymentAmountSatKey, "1", ""))
+	require.NoError(t, svc.DB.Model(app).Update("max_payment_amount_sat", 0).Error)
+	transaction, err = transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
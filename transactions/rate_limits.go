@@ -0,0 +1,80 @@
+package transactions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+type paymentRateLimitedError struct {
+	maxPayments uint
+	period      string
+}
+
+func NewPaymentRateLimitedError() error {
+	return &paymentRateLimitedError{}
+}
+
+func (err *paymentRateLimitedError) Error() string {
+	return fmt.Sprintf("This app can make at most %d payments per %s", err.maxPayments, err.period)
+}
+
+func (err *paymentRateLimitedError) Is(target error) bool {
+	_, ok := target.(*paymentRateLimitedError)
+	return ok
+}
+
+// validatePaymentRateLimits fails if the app already made as many payments as it is allowed to in the last
+// minute or day. Payments are counted from the transactions of the app, so failed payments count too and
+// the limits are kept across restarts. It must be called under balanceValidationLock before the pending
+// payment is created, so that concurrent payments cannot exceed the limits.
+func (svc *transactionsService) validatePaymentRateLimits(tx *gorm.DB, app *db.App) error {
+	limits := []struct {
+		maxPayments *uint
+		period      time.Duration
+		name        string
+	}{
+		{app.MaxPaymentsPerMinute, time.Minute, "minute"},
+		{app.MaxPaymentsPerDay, 24 * time.Hour, "day"},
+	}
+
+	for _, limit := range limits {
+		if limit.maxPayments == nil {
+			continue
+		}
+		var paymentCount int64
+		err := tx.Model(&db.Transaction{}).
+			Where("app_id = ? AND type = ? AND created_at > ?", app.ID, constants.TRANSACTION_TYPE_OUTGOING, time.Now().Add(-limit.period)).
+			Count(&paymentCount).Error
+		if err != nil {
+			return err
+		}
+		if paymentCount < int64(*limit.maxPayments) {
+			continue
+		}
+
+		rateLimitedErr := &paymentRateLimitedError{maxPayments: *limit.maxPayments, period: limit.name}
+		logger.Logger.WithFields(logrus.Fields{
+			"app_id":  app.ID,
+			"message": rateLimitedErr.Error(),
+		}).Warn("App exceeded its payment rate limit")
+
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_permission_denied",
+			Properties: map[string]interface{}{
+				"app_name": app.Name,
+				"code":     constants.ERROR_RATE_LIMITED,
+				"message":  rateLimitedErr.Error(),
+			},
+		})
+		return rateLimitedErr
+	}
+	return nil
+}
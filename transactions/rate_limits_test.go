@@ -0,0 +1,69 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestValidatePaymentRateLimits(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	assert.NoError(t, transactionsService.validatePaymentRateLimits(svc.DB, app))
+
+	maxPaymentsPerMinute := uint(2)
+	maxPaymentsPerDay := uint(3)
+	app.MaxPaymentsPerMinute = &maxPaymentsPerMinute
+	app.MaxPaymentsPerDay = &maxPaymentsPerDay
+
+	// an earlier payment today, an incoming payment and a payment of another app are not counted per minute
+	require.NoError(t, svc.DB.Create(&db.Transaction{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_SETTLED, PaymentHash: "1", CreatedAt: time.Now().Add(-time.Hour)}).Error)
+	require.NoError(t, svc.DB.Create(&db.Transaction{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, PaymentHash: "2"}).Error)
+	require.NoError(t, svc.DB.Create(&db.Transaction{Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_SETTLED, PaymentHash: "3"}).Error)
+	require.NoError(t, svc.DB.Create(&db.Transaction{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_FAILED, PaymentHash: "4"}).Error)
+
+	assert.NoError(t, transactionsService.validatePaymentRateLimits(svc.DB, app))
+
+	require.NoError(t, svc.DB.Create(&db.Transaction{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_SETTLED, PaymentHash: "5"}).Error)
+	err = transactionsService.validatePaymentRateLimits(svc.DB, app)
+	assert.ErrorIs(t, err, NewPaymentRateLimitedError())
+	assert.EqualError(t, err, "This app can make at most 2 payments per minute")
+
+	maxPaymentsPerMinute = 10
+	assert.EqualError(t, transactionsService.validatePaymentRateLimits(svc.DB, app), "This app can make at most 3 payments per day")
+}
+
+func TestSendPaymentBatch_RateLimited(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createBatchTestApp(t, svc, 266_000)
+	require.NoError(t, svc.DB.Model(app).Update("max_payments_per_minute", 1).Error)
+
+	// the limit is checked for every payment, not once per request
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	result, err := transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockInvoice},
+		{Invoice: mockBatchInvoice2},
+	}, svc.LNClient, &app.ID, nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.SucceededCount)
+	assert.NoError(t, result.Items[0].Error)
+	assert.ErrorIs(t, result.Items[1].Error, NewPaymentRateLimitedError())
+}
@@ -0,0 +1,174 @@
+package transactions
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/autolock"
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient/watchonly"
+	"github.com/getAlby/hub/logger"
+)
+
+// a watch-only hub never spends, regardless of the config in its (possibly synced) database
+var watchOnly atomic.Bool
+
+func SetWatchOnly(enabled bool) {
+	watchOnly.Store(enabled)
+}
+
+func IsWatchOnly() bool {
+	return watchOnly.Load()
+}
+
+// ReadOnlyWindow is a recurring period in which spending is disabled, e.g. during travel.
+// Start and end are "HH:MM" in UTC. A window which ends before it starts runs past midnight.
+type ReadOnlyWindow struct {
+	// days of the week on which the window starts (0 is Sunday), empty means every day
+	Weekdays []time.Weekday `json:"weekdays"`
+	Start    string         `json:"start"`
+	End      string         `json:"end"`
+}
+
+type readOnlyModeError struct {
+}
+
+func NewReadOnlyModeError() error {
+	return &readOnlyModeError{}
+}
+
+func (err *readOnlyModeError) Error() string {
+	return "Your Alby Hub is in read-only mode. Payments, on-chain transactions and channel closes are disabled."
+}
+
+// CheckSpendingAllowed returns an error if the hub is watch-only, locked or in read-only mode.
+// Receiving payments is always allowed.
+func CheckSpendingAllowed(tx *gorm.DB) error {
+	if IsWatchOnly() {
+		return watchonly.ErrWatchOnly
+	}
+	if autolock.IsLocked() {
+		return NewSpendingLockedError()
+	}
+	if IsReadOnly(tx, time.Now()) {
+		return NewReadOnlyModeError()
+	}
+	return nil
+}
+
+// IsReadOnly returns true if read-only mode is switched on or one of its windows is active
+func IsReadOnly(tx *gorm.DB, now time.Time) bool {
+	var userConfigs []db.UserConfig
+	err := tx.Where("key IN ?", []string{config.ReadOnlyModeKey, config.ReadOnlyWindowsKey}).Find(&userConfigs).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to read read-only mode config")
+		return false
+	}
+
+	for _, userConfig := range userConfigs {
+		switch userConfig.Key {
+		case config.ReadOnlyModeKey:
+			if userConfig.Value == "true" {
+				return true
+			}
+		case config.ReadOnlyWindowsKey:
+			windows, err := ParseReadOnlyWindows(userConfig.Value)
+			if err != nil {
+				logger.Logger.WithField("value", userConfig.Value).WithError(err).Error("Invalid read-only windows config")
+				continue
+			}
+			for _, window := range windows {
+				if window.contains(now) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// GetReadOnlyWindows returns the configured read-only windows
+func GetReadOnlyWindows(tx *gorm.DB) []ReadOnlyWindow {
+	var userConfig db.UserConfig
+	if tx.Limit(1).Find(&userConfig, &db.UserConfig{Key: config.ReadOnlyWindowsKey}).RowsAffected == 0 {
+		return []ReadOnlyWindow{}
+	}
+	windows, err := ParseReadOnlyWindows(userConfig.Value)
+	if err != nil {
+		logger.Logger.WithField("value", userConfig.Value).WithError(err).Error("Invalid read-only windows config")
+		return []ReadOnlyWindow{}
+	}
+	return windows
+}
+
+func ParseReadOnlyWindows(value string) ([]ReadOnlyWindow, error) {
+	windows := []ReadOnlyWindow{}
+	if value == "" {
+		return windows, nil
+	}
+	if err := json.Unmarshal([]byte(value), &windows); err != nil {
+		return nil, err
+	}
+	return windows, ValidateReadOnlyWindows(windows)
+}
+
+func ValidateReadOnlyWindows(windows []ReadOnlyWindow) error {
+	for _, window := range windows {
+		for _, weekday := range window.Weekdays {
+			if weekday < time.Sunday || weekday > time.Saturday {
+				return fmt.Errorf("invalid weekday: %d. Must be between 0 (Sunday) and 6 (Saturday)", weekday)
+			}
+		}
+		start, err := parseTimeOfDay(window.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseTimeOfDay(window.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return fmt.Errorf("read-only window must not start and end at %s", window.Start)
+		}
+	}
+	return nil
+}
+
+// parseTimeOfDay returns the minutes since midnight of a "HH:MM" time
+func parseTimeOfDay(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q. Must be HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func (window *ReadOnlyWindow) startsOn(weekday time.Weekday) bool {
+	return len(window.Weekdays) == 0 || slices.Contains(window.Weekdays, weekday)
+}
+
+func (window *ReadOnlyWindow) contains(now time.Time) bool {
+	start, err := parseTimeOfDay(window.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseTimeOfDay(window.End)
+	if err != nil {
+		return false
+	}
+
+	now = now.UTC()
+	minutes := now.Hour()*60 + now.Minute()
+	if start < end {
+		return window.startsOn(now.Weekday()) && minutes >= start && minutes < end
+	}
+	// the window runs past midnight
+	return (window.startsOn(now.Weekday()) && minutes >= start) ||
+		(window.startsOn(now.AddDate(0, 0, -1).Weekday()) && minutes < end)
+}
@@ -0,0 +1,80 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/lnclient/watchonly"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSendPaymentSync_ReadOnlyMode(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.ReadOnlyModeKey, "true", ""))
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.ErrorIs(t, err, NewReadOnlyModeError())
+	assert.Nil(t, transaction)
+
+	require.NoError(t, svc.Cfg.SetUpdate(config.ReadOnlyModeKey, "false", ""))
+	transaction, err = transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, transaction)
+}
+
+func TestSendPaymentSync_WatchOnly(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	SetWatchOnly(true)
+	defer SetWatchOnly(false)
+
+	// the wrapped LNClient refuses to pay even if the check is bypassed
+	lnClient := watchonly.NewWatchOnlyService(svc.LNClient)
+	_, err = lnClient.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil)
+	assert.ErrorIs(t, err, watchonly.ErrWatchOnly)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.ErrorIs(t, err, watchonly.ErrWatchOnly)
+	assert.Nil(t, transaction)
+
+	// receiving is still possible
+	transaction, err = transactionsService.MakeInvoice(context.TODO(), 1000, "", "", 0, nil, lnClient, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, transaction)
+}
+
+func TestIsReadOnly_Windows(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// Fridays 22:00 until Saturday 06:00 UTC
+	require.NoError(t, svc.Cfg.SetUpdate(config.ReadOnlyWindowsKey, `[{"weekdays":[5],"start":"22:00","end":"06:00"}]`, ""))
+
+	friday := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	assert.False(t, IsReadOnly(svc.DB, friday.Add(21*time.Hour+59*time.Minute)))
+	assert.True(t, IsReadOnly(svc.DB, friday.Add(22*time.Hour)))
+	assert.True(t, IsReadOnly(svc.DB, friday.Add(29*time.Hour)))
+	assert.False(t, IsReadOnly(svc.DB, friday.Add(30*time.Hour)))
+	// the window does not start on Saturdays
+	assert.False(t, IsReadOnly(svc.DB, friday.Add(47*time.Hour)))
+}
+
+func TestValidateReadOnlyWindows(t *testing.T) {
+	assert.NoError(t, ValidateReadOnlyWindows([]ReadOnlyWindow{{Start: "09:00", End: "17:30"}}))
+	assert.EqualError(t, ValidateReadOnlyWindows([]ReadOnlyWindow{{Start: "9am", End: "17:30"}}), `invalid time "9am". Must be HH:MM`)
+	assert.EqualError(t, ValidateReadOnlyWindows([]ReadOnlyWindow{{Weekdays: []time.Weekday{7}, Start: "09:00", End: "17:30"}}), "invalid weekday: 7. Must be between 0 (Sunday) and 6 (Saturday)")
+	assert.Error(t, ValidateReadOnlyWindows([]ReadOnlyWindow{{Start: "09:00", End: "09:00"}}))
+}
@@ -0,0 +1,74 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/lnclient"
+)
+
+const receiptVersion = 1
+
+// Receipt proves that a single payment was settled. The preimage proves the payment
+// itself, and the signature over Message can be checked against NodePubkey with
+// any lightning node's verifymessage command.
+type Receipt struct {
+	Version     int
+	Type        string
+	PaymentHash string
+	Preimage    string
+	AmountMsat  uint64
+	Description string
+	SettledAt   time.Time
+	NodePubkey  string
+	Message     string
+	Signature   string
+}
+
+func (svc *transactionsService) CreateReceipt(ctx context.Context, paymentHash string, transactionType *string, lnClient lnclient.LNClient, appId *uint) (*Receipt, error) {
+	transaction, err := svc.LookupTransaction(ctx, paymentHash, transactionType, lnClient, appId)
+	if err != nil {
+		return nil, err
+	}
+
+	if transaction.State != constants.TRANSACTION_STATE_SETTLED || transaction.Preimage == nil || transaction.SettledAt == nil {
+		return nil, errors.New("receipts can only be created for settled payments")
+	}
+
+	receipt := &Receipt{
+		Version:     receiptVersion,
+		Type:        transaction.Type,
+		PaymentHash: transaction.PaymentHash,
+		Preimage:    *transaction.Preimage,
+		AmountMsat:  transaction.AmountMsat,
+		Description: transaction.Description,
+		SettledAt:   transaction.SettledAt.UTC().Truncate(time.Second),
+		NodePubkey:  lnClient.GetPubkey(),
+	}
+	receipt.Message = receipt.signedMessage()
+
+	receipt.Signature, err = lnClient.SignMessage(ctx, receipt.Message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign receipt: %w", err)
+	}
+
+	return receipt, nil
+}
+
+// signedMessage is the canonical text of the receipt which is signed by the node
+func (receipt *Receipt) signedMessage() string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "Alby Hub payment receipt v%d\n", receipt.Version)
+	fmt.Fprintf(&message, "type: %s\n", receipt.Type)
+	fmt.Fprintf(&message, "payment_hash: %s\n", receipt.PaymentHash)
+	fmt.Fprintf(&message, "preimage: %s\n", receipt.Preimage)
+	fmt.Fprintf(&message, "amount_msat: %d\n", receipt.AmountMsat)
+	fmt.Fprintf(&message, "description: %s\n", strings.ReplaceAll(receipt.Description, "\n", " "))
+	fmt.Fprintf(&message, "settled_at: %s\n", receipt.SettledAt.Format(time.RFC3339))
+	fmt.Fprintf(&message, "node_pubkey: %s", receipt.NodePubkey)
+	return message.String()
+}
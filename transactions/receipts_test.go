@@ -0,0 +1,82 @@
+package transactions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestCreateReceipt(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	preimageBytes := sha256.Sum256([]byte("receipt"))
+	preimage := hex.EncodeToString(preimageBytes[:])
+	paymentHashBytes := sha256.Sum256(preimageBytes[:])
+	paymentHash := hex.EncodeToString(paymentHashBytes[:])
+	settledAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat:  123_000,
+		FeeMsat:     1_000,
+		PaymentHash: paymentHash,
+		Preimage:    &preimage,
+		Description: "coffee",
+		SettledAt:   &settledAt,
+	}).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transactionType := constants.TRANSACTION_TYPE_OUTGOING
+	receipt, err := transactionsService.CreateReceipt(ctx, paymentHash, &transactionType, svc.LNClient, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, preimage, receipt.Preimage)
+	assert.Equal(t, uint64(123_000), receipt.AmountMsat)
+	assert.Equal(t, settledAt, receipt.SettledAt)
+	assert.Equal(t, svc.LNClient.GetPubkey(), receipt.NodePubkey)
+	assert.Equal(t, strings.Join([]string{
+		"Alby Hub payment receipt v1",
+		"type: outgoing",
+		"payment_hash: " + paymentHash,
+		"preimage: " + preimage,
+		"amount_msat: 123000",
+		"description: coffee",
+		"settled_at: 2026-03-01T10:00:00Z",
+		"node_pubkey: " + svc.LNClient.GetPubkey(),
+	}, "\n"), receipt.Message)
+}
+
+func TestCreateReceipt_NotSettled(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		State:       constants.TRANSACTION_STATE_FAILED,
+		AmountMsat:  123_000,
+		PaymentHash: tests.MockPaymentHash,
+	}).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.CreateReceipt(ctx, tests.MockPaymentHash, nil, svc.LNClient, nil)
+	assert.EqualError(t, err, "receipts can only be created for settled payments")
+
+	_, err = transactionsService.CreateReceipt(ctx, "unknown", nil, svc.LNClient, nil)
+	assert.IsType(t, NewNotFoundError(), err)
+}
@@ -95,6 +95,56 @@ func TestReceiveKeysend_InvalidBoostagram(t *testing.T) {
 	assert.Nil(t, transaction.Boostagram)
 }
 
+func TestReceiveKeysend_LegacyBoostagramWithTipNote(t *testing.T) {
+	ctx := context.TODO()
+
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	tlv := []lnclient.TLVRecord{
+		{
+			Type:  133773310,
+			Value: hex.EncodeToString([]byte("{\"podcast\": \"Podcasting 2.0\", \"episode\": \"Episode 104\", \"sender_name\": \"Satoshi\", \"guid\": \"917393e3-1b1e-5cef-ace4-edaa54e1f810\"}")),
+		},
+		{
+			Type:  7629171,
+			Value: hex.EncodeToString([]byte("Great show!")),
+		},
+	}
+	tx := lnclient.Transaction{
+		Type:        "incoming",
+		Description: "mock invoice 1",
+		Preimage:    "9f59b18f80a77c2930deb8be5ff1143eacdd1891c63c23d61bc9f99c64e57325",
+		PaymentHash: "ae4277b7be3ca1420cafd24c143866190f52b996856b0e4164763f936e61ea1b",
+		Amount:      1000,
+		SettledAt:   &tests.MockTimeUnix,
+		Metadata: map[string]interface{}{
+			"tlv_records": tlv,
+		},
+	}
+
+	event := events.Event{
+		Event:      "nwc_lnclient_payment_received",
+		Properties: &tx,
+	}
+	transactionsService.ConsumeEvent(ctx, &event, map[string]interface{}{})
+
+	transaction, err := transactionsService.LookupTransaction(ctx, tx.PaymentHash, nil, svc.LNClient, nil)
+	require.NoError(t, err)
+	var txBoostagram Boostagram
+	err = json.Unmarshal(transaction.Boostagram, &txBoostagram)
+	require.NoError(t, err)
+	assert.Equal(t, "Podcasting 2.0", txBoostagram.Podcast)
+	assert.Equal(t, "Episode 104", txBoostagram.Episode.String())
+	assert.Equal(t, "Satoshi", txBoostagram.SenderName)
+	assert.Equal(t, "917393e3-1b1e-5cef-ace4-edaa54e1f810", txBoostagram.Guid)
+	assert.Equal(t, "Great show!", txBoostagram.Message)
+	assert.Equal(t, "Great show!", transaction.Description)
+}
+
 func TestReceiveKeysendWithCustomKey(t *testing.T) {
 	ctx := context.TODO()
 
@@ -0,0 +1,79 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSendPaymentSync_ReceiveOnlyApp(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app.Isolated = true
+	app.ReceiveOnly = true
+	require.NoError(t, svc.DB.Save(app).Error)
+
+	svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		Type:       constants.TRANSACTION_TYPE_INCOMING,
+		AmountMsat: 200000,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.EqualError(t, err, "app is receive-only")
+	assert.Nil(t, transaction)
+	assert.Equal(t, int64(200000), queries.GetIsolatedBalance(svc.DB, app.ID))
+}
+
+func TestSendPaymentSync_ReceiveOnlyApp_SweepToMainBalance(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// pubkey matches mock invoice = self payment
+	svc.LNClient.(*tests.MockLn).Pubkey = "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c"
+
+	// receive-only apps never have the pay_invoice scope
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app.Isolated = true
+	app.ReceiveOnly = true
+	require.NoError(t, svc.DB.Save(app).Error)
+
+	svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		Type:       constants.TRANSACTION_TYPE_INCOMING,
+		AmountMsat: 123000, // invoice is 123000 msat
+	})
+
+	mockPreimage := "123preimage"
+	svc.DB.Create(&db.Transaction{
+		State:          constants.TRANSACTION_STATE_PENDING,
+		Type:           constants.TRANSACTION_TYPE_INCOMING,
+		PaymentRequest: tests.MockInvoice,
+		PaymentHash:    tests.MockPaymentHash,
+		Preimage:       &mockPreimage,
+		AmountMsat:     123000,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockInvoice, nil, nil, svc.LNClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.True(t, transaction.SelfPayment)
+	assert.Equal(t, int64(0), queries.GetIsolatedBalance(svc.DB, app.ID))
+}
@@ -0,0 +1,182 @@
+package transactions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/lnurl"
+	"github.com/getAlby/hub/logger"
+)
+
+// fetchRefundInvoice requests an invoice from the payer's lightning address.
+// It is a variable so it can be replaced in tests.
+var fetchRefundInvoice = lnurl.FetchLightningAddressInvoice
+
+// RefundTransaction refunds (part of) a settled incoming payment. If no invoice is provided, an
+// invoice is requested from the lightning address the payer shared with the payment (LUD-18).
+// Refunds are capped at the original amount minus any earlier refunds, and are linked to the
+// original transaction so the net amount received can be shown. The refundable amount is checked
+// again when the pending refund is stored, so concurrent refunds cannot exceed the original amount.
+func (svc *transactionsService) RefundTransaction(ctx context.Context, id uint, payReq string, amountMsat *uint64, lnClient lnclient.LNClient) (*Transaction, error) {
+	var originalTransaction db.Transaction
+	if err := svc.db.Limit(1).Find(&originalTransaction, &db.Transaction{
+		ID:   id,
+		Type: constants.TRANSACTION_TYPE_INCOMING,
+	}).Error; err != nil {
+		return nil, err
+	}
+	if originalTransaction.ID == 0 {
+		return nil, NewNotFoundError()
+	}
+	if originalTransaction.State != constants.TRANSACTION_STATE_SETTLED {
+		return nil, errors.New("only settled payments can be refunded")
+	}
+	if originalTransaction.SelfPayment {
+		return nil, errors.New("self payments cannot be refunded")
+	}
+
+	refundedAmounts, err := svc.GetRefundedAmounts([]uint{originalTransaction.ID})
+	if err != nil {
+		return nil, err
+	}
+	refundableAmountMsat := originalTransaction.AmountMsat - refundedAmounts[originalTransaction.ID]
+	if refundableAmountMsat == 0 {
+		return nil, errors.New("payment has already been fully refunded")
+	}
+
+	refundAmountMsat := refundableAmountMsat
+	if amountMsat != nil {
+		refundAmountMsat = *amountMsat
+	}
+
+	if payReq == "" {
+		lightningAddress := getPayerLightningAddress(&originalTransaction)
+		if lightningAddress == "" {
+			return nil, errors.New("no invoice provided and the payer did not share a lightning address")
+		}
+		if refundAmountMsat > refundableAmountMsat {
+			return nil, fmt.Errorf("refund amount exceeds the refundable amount of %d msat", refundableAmountMsat)
+		}
+		payReq, err = fetchRefundInvoice(ctx, lightningAddress, refundAmountMsat)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"lightning_address": lightningAddress,
+				"amount_msat":       refundAmountMsat,
+			}).WithError(err).Error("Failed to request refund invoice")
+			return nil, fmt.Errorf("failed to request refund invoice: %w", err)
+		}
+	}
+
+	paymentRequest, err := decodepay.Decodepay(strings.ToLower(payReq))
+	if err != nil {
+		return nil, err
+	}
+	if paymentRequest.MSatoshi > 0 {
+		refundAmountMsat = uint64(paymentRequest.MSatoshi)
+	}
+	if refundAmountMsat > refundableAmountMsat {
+		return nil, fmt.Errorf("refund amount exceeds the refundable amount of %d msat", refundableAmountMsat)
+	}
+
+	var sendAmountMsat *uint64
+	if paymentRequest.MSatoshi == 0 {
+		sendAmountMsat = &refundAmountMsat
+	}
+
+	metadata := map[string]interface{}{
+		"refund_of": originalTransaction.PaymentHash,
+	}
+	// the refund is paid from the balance of the app that received the original payment
+	return svc.sendPaymentSync(ctx, payReq, sendAmountMsat, metadata, lnClient, originalTransaction.AppId, nil, "", &originalTransaction.ID)
+}
+
+// validateRefundAmount checks that a refund does not exceed the amount which is left to refund.
+// It must be called under balanceValidationLock, in the transaction which stores the pending refund.
+func validateRefundAmount(tx *gorm.DB, refundOfTransactionId uint, amountMsat uint64) error {
+	var originalTransaction db.Transaction
+	if err := tx.Limit(1).Find(&originalTransaction, &db.Transaction{
+		ID:   refundOfTransactionId,
+		Type: constants.TRANSACTION_TYPE_INCOMING,
+	}).Error; err != nil {
+		return err
+	}
+	if originalTransaction.ID == 0 {
+		return NewNotFoundError()
+	}
+
+	refundedAmounts, err := getRefundedAmounts(tx, []uint{refundOfTransactionId})
+	if err != nil {
+		return err
+	}
+	refundableAmountMsat := uint64(0)
+	if refundedAmounts[refundOfTransactionId] < originalTransaction.AmountMsat {
+		refundableAmountMsat = originalTransaction.AmountMsat - refundedAmounts[refundOfTransactionId]
+	}
+	if refundableAmountMsat == 0 {
+		return errors.New("payment has already been fully refunded")
+	}
+	if amountMsat > refundableAmountMsat {
+		return fmt.Errorf("refund amount exceeds the refundable amount of %d msat", refundableAmountMsat)
+	}
+	return nil
+}
+
+// GetRefundedAmounts returns the total settled or pending refunds for each of the given transactions
+func (svc *transactionsService) GetRefundedAmounts(ids []uint) (map[uint]uint64, error) {
+	return getRefundedAmounts(svc.db, ids)
+}
+
+func getRefundedAmounts(tx *gorm.DB, ids []uint) (map[uint]uint64, error) {
+	refundedAmounts := map[uint]uint64{}
+	if len(ids) == 0 {
+		return refundedAmounts, nil
+	}
+
+	var results []struct {
+		RefundOfTransactionId uint
+		AmountMsat            uint64
+	}
+	err := tx.Model(&db.Transaction{}).
+		Select("refund_of_transaction_id, SUM(amount_msat) AS amount_msat").
+		Where("refund_of_transaction_id IN ? AND state IN ?", ids, []string{constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING}).
+		Group("refund_of_transaction_id").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		refundedAmounts[result.RefundOfTransactionId] = result.AmountMsat
+	}
+	return refundedAmounts, nil
+}
+
+// see https://github.com/lnurl/luds/blob/luds/18.md
+func getPayerLightningAddress(transaction *db.Transaction) string {
+	if transaction.Metadata == nil {
+		return ""
+	}
+	var metadata struct {
+		PayerData struct {
+			Identifier string `json:"identifier"`
+		} `json:"payer_data"`
+	}
+	if err := json.Unmarshal(transaction.Metadata, &metadata); err != nil {
+		return ""
+	}
+	identifier := strings.TrimPrefix(strings.ToLower(metadata.PayerData.Identifier), "lightning:")
+	if strings.Count(identifier, "@") != 1 {
+		return ""
+	}
+	return identifier
+}
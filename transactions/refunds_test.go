@@ -0,0 +1,158 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func createRefundableTransaction(t *testing.T, svc *tests.TestService, amountMsat uint64, metadata string) *db.Transaction {
+	transaction := &db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_INCOMING,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat:  amountMsat,
+		PaymentHash: "refundable",
+		SettledAt:   &tests.MockTime,
+	}
+	if metadata != "" {
+		transaction.Metadata = datatypes.JSON(metadata)
+	}
+	require.NoError(t, svc.DB.Create(transaction).Error)
+	return transaction
+}
+
+func TestRefundTransaction_ProvidedInvoice(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalTransaction := createRefundableTransaction(t, svc, 200_000, "")
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	refundTransaction, err := transactionsService.RefundTransaction(ctx, originalTransaction.ID, tests.MockInvoice, nil, svc.LNClient)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, refundTransaction.State)
+	assert.Equal(t, uint64(123_000), refundTransaction.AmountMsat)
+	assert.Equal(t, originalTransaction.ID, *refundTransaction.RefundOfTransactionId)
+
+	refundedAmounts, err := transactionsService.GetRefundedAmounts([]uint{originalTransaction.ID})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123_000), refundedAmounts[originalTransaction.ID])
+
+	// only 77 sats are left to refund
+	_, err = transactionsService.RefundTransaction(ctx, originalTransaction.ID, tests.MockZeroAmountInvoice, nil, svc.LNClient)
+	assert.NoError(t, err)
+	_, err = transactionsService.RefundTransaction(ctx, originalTransaction.ID, tests.MockZeroAmountInvoice, nil, svc.LNClient)
+	assert.EqualError(t, err, "payment has already been fully refunded")
+}
+
+func TestRefundTransaction_ExceedsOriginalAmount(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalTransaction := createRefundableTransaction(t, svc, 100_000, "")
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.RefundTransaction(ctx, originalTransaction.ID, tests.MockInvoice, nil, svc.LNClient)
+	assert.EqualError(t, err, "refund amount exceeds the refundable amount of 100000 msat")
+
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestRefundTransaction_PayerLightningAddress(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalFetchRefundInvoice := fetchRefundInvoice
+	defer func() { fetchRefundInvoice = originalFetchRefundInvoice }()
+	var requestedAddress string
+	var requestedAmountMsat uint64
+	fetchRefundInvoice = func(ctx context.Context, lightningAddress string, amountMsat uint64) (string, error) {
+		requestedAddress = lightningAddress
+		requestedAmountMsat = amountMsat
+		return tests.MockInvoice, nil
+	}
+
+	originalTransaction := createRefundableTransaction(t, svc, 123_000, `{"payer_data":{"identifier":"Satoshi@example.com"}}`)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	refundTransaction, err := transactionsService.RefundTransaction(ctx, originalTransaction.ID, "", nil, svc.LNClient)
+	require.NoError(t, err)
+	assert.Equal(t, "satoshi@example.com", requestedAddress)
+	assert.Equal(t, uint64(123_000), requestedAmountMsat)
+	assert.Equal(t, uint64(123_000), refundTransaction.AmountMsat)
+}
+
+func TestRefundTransaction_NoRefundAddress(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalTransaction := createRefundableTransaction(t, svc, 123_000, "")
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.RefundTransaction(ctx, originalTransaction.ID, "", nil, svc.LNClient)
+	assert.EqualError(t, err, "no invoice provided and the payer did not share a lightning address")
+}
+
+func TestRefundTransaction_ConcurrentRefund(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalTransaction := createRefundableTransaction(t, svc, 200_000, "")
+	// another refund was stored after the refundable amount was checked outside of the lock
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		Type:                  constants.TRANSACTION_TYPE_OUTGOING,
+		State:                 constants.TRANSACTION_STATE_PENDING,
+		AmountMsat:            100_000,
+		PaymentHash:           "pending-refund",
+		RefundOfTransactionId: &originalTransaction.ID,
+	}).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.sendPaymentSync(ctx, tests.MockInvoice, nil, nil, svc.LNClient, nil, nil, "", &originalTransaction.ID)
+	assert.EqualError(t, err, "refund amount exceeds the refundable amount of 100000 msat")
+
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRefundTransaction_LinkedWhilePending(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalTransaction := createRefundableTransaction(t, svc, 200_000, "")
+	svc.LNClient.(*tests.MockLn).PayInvoiceErrors = append(svc.LNClient.(*tests.MockLn).PayInvoiceErrors, errors.New("some routing error"))
+	svc.LNClient.(*tests.MockLn).PayInvoiceResponses = append(svc.LNClient.(*tests.MockLn).PayInvoiceResponses, nil)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.RefundTransaction(ctx, originalTransaction.ID, tests.MockInvoice, nil, svc.LNClient)
+	assert.Error(t, err)
+
+	// the refund is linked to the original payment even though it failed
+	var refundTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&refundTransaction, &db.Transaction{Type: constants.TRANSACTION_TYPE_OUTGOING}).Error)
+	assert.Equal(t, constants.TRANSACTION_STATE_FAILED, refundTransaction.State)
+	assert.Equal(t, originalTransaction.ID, *refundTransaction.RefundOfTransactionId)
+}
@@ -0,0 +1,89 @@
+package transactions
+
+import (
+	"context"
+	"math"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tracing"
+)
+
+type routingFeeLimitUnsupportedError struct {
+}
+
+func NewRoutingFeeLimitUnsupportedError() error {
+	return &routingFeeLimitUnsupportedError{}
+}
+
+func (err *routingFeeLimitUnsupportedError) Error() string {
+	return "This app has a maximum routing fee, which is not supported by your node backend. Try LDK or LND."
+}
+
+// CalculateMaxRoutingFeeMsat returns the larger of a percentage of the amount and an absolute floor
+func CalculateMaxRoutingFeeMsat(amountMsat uint64, maxFeePercent *float64, maxFeeFloorSat *uint64) uint64 {
+	maxFeeMsat := uint64(0)
+	if maxFeePercent != nil {
+		maxFeeMsat = uint64(math.Floor(float64(amountMsat) * *maxFeePercent / 100))
+	}
+	if maxFeeFloorSat != nil && *maxFeeFloorSat*1000 > maxFeeMsat {
+		maxFeeMsat = *maxFeeFloorSat * 1000
+	}
+	return maxFeeMsat
+}
+
+// getMaxRoutingFeeMsat returns the routing fee limit of the app for a payment,
+// or nil if the app has no routing fee policy and the default fee reserve applies.
+func getMaxRoutingFeeMsat(tx *gorm.DB, appId *uint, amountMsat uint64) *uint64 {
+	if appId == nil {
+		return nil
+	}
+	var app db.App
+	if tx.Limit(1).Find(&app, &db.App{ID: *appId}).RowsAffected == 0 {
+		return nil
+	}
+	if app.MaxFeePercent == nil && app.MaxFeeFloorSat == nil {
+		return nil
+	}
+	maxFeeMsat := CalculateMaxRoutingFeeMsat(amountMsat, app.MaxFeePercent, app.MaxFeeFloorSat)
+	return &maxFeeMsat
+}
+
+// calculateAppFeeReserveMsat reserves exactly the routing fee limit of the app, so that
+// a payment can never use more of the budget or balance than was checked up front.
+func calculateAppFeeReserveMsat(tx *gorm.DB, appId *uint, amountMsat uint64) uint64 {
+	if maxFeeMsat := getMaxRoutingFeeMsat(tx, appId, amountMsat); maxFeeMsat != nil {
+		return *maxFeeMsat
+	}
+	return CalculateFeeReserveMsat(amountMsat)
+}
+
+func sendPaymentWithMaxFee(ctx context.Context, lnClient lnclient.LNClient, payReq string, amountMsat *uint64, maxFeeMsat *uint64) (response *lnclient.PayInvoiceResponse, err error) {
+	_, span := tracing.Tracer().Start(ctx, "lnclient.SendPaymentSync")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if maxFeeMsat == nil {
+		return lnClient.SendPaymentSync(payReq, amountMsat)
+	}
+	maxRoutingFeeLNClient, ok := lnClient.(lnclient.MaxRoutingFeeLNClient)
+	if !ok {
+		return nil, NewRoutingFeeLimitUnsupportedError()
+	}
+	return maxRoutingFeeLNClient.SendPaymentSyncWithMaxFee(payReq, amountMsat, *maxFeeMsat)
+}
+
+func sendKeysendWithMaxFee(ctx context.Context, lnClient lnclient.LNClient, amountMsat uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, maxFeeMsat *uint64) (response *lnclient.PayKeysendResponse, err error) {
+	_, span := tracing.Tracer().Start(ctx, "lnclient.SendKeysend")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if maxFeeMsat == nil {
+		return lnClient.SendKeysend(amountMsat, destination, customRecords, preimage)
+	}
+	maxRoutingFeeLNClient, ok := lnClient.(lnclient.MaxRoutingFeeLNClient)
+	if !ok {
+		return nil, NewRoutingFeeLimitUnsupportedError()
+	}
+	return maxRoutingFeeLNClient.SendKeysendWithMaxFee(amountMsat, destination, customRecords, preimage, *maxFeeMsat)
+}
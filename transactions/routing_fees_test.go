@@ -0,0 +1,120 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+type mockMaxRoutingFeeLn struct {
+	*tests.MockLn
+	maxFeeMsat *uint64
+}
+
+func (mln *mockMaxRoutingFeeLn) SendPaymentSyncWithMaxFee(payReq string, amount *uint64, maxFeeMsat uint64) (*lnclient.PayInvoiceResponse, error) {
+	mln.maxFeeMsat = &maxFeeMsat
+	return mln.SendPaymentSync(payReq, amount)
+}
+
+func (mln *mockMaxRoutingFeeLn) SendKeysendWithMaxFee(amount uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, maxFeeMsat uint64) (*lnclient.PayKeysendResponse, error) {
+	mln.maxFeeMsat = &maxFeeMsat
+	return mln.SendKeysend(amount, destination, customRecords, preimage)
+}
+
+func TestCalculateMaxRoutingFeeMsat(t *testing.T) {
+	percent := 0.5
+	floorSat := uint64(5)
+
+	assert.Equal(t, uint64(0), CalculateMaxRoutingFeeMsat(123_000, nil, nil))
+	assert.Equal(t, uint64(615), CalculateMaxRoutingFeeMsat(123_000, &percent, nil))
+	assert.Equal(t, uint64(5_000), CalculateMaxRoutingFeeMsat(123_000, &percent, &floorSat))
+	assert.Equal(t, uint64(10_000), CalculateMaxRoutingFeeMsat(2_000_000, &percent, &floorSat))
+}
+
+func TestSendPaymentSync_MaxRoutingFee(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+
+	maxFeeFloorSat := uint64(2)
+	require.NoError(t, svc.DB.Model(app).Update("max_fee_floor_sat", maxFeeFloorSat).Error)
+
+	lnClient := &mockMaxRoutingFeeLn{MockLn: svc.LNClient.(*tests.MockLn)}
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, lnClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+
+	// the app limit is passed to the node instead of the default fee reserve
+	require.NotNil(t, lnClient.maxFeeMsat)
+	assert.Equal(t, uint64(2_000), *lnClient.maxFeeMsat)
+	assert.Equal(t, uint64(2_000), calculateAppFeeReserveMsat(svc.DB, &app.ID, transaction.AmountMsat))
+}
+
+func TestSendPaymentSync_MaxRoutingFee_Unsupported(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+
+	maxFeePercent := 1.0
+	require.NoError(t, svc.DB.Model(app).Update("max_fee_percent", maxFeePercent).Error)
+
+	// the mock LNClient cannot limit routing fees
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.ErrorIs(t, err, NewRoutingFeeLimitUnsupportedError())
+	assert.Nil(t, transaction)
+
+	var dbTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&dbTransaction, &db.Transaction{PaymentHash: tests.MockPaymentHash}).Error)
+	assert.Equal(t, constants.TRANSACTION_STATE_FAILED, dbTransaction.State)
+}
+
+func TestSendKeysend_MaxRoutingFee(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+
+	maxFeePercent := 1.0
+	require.NoError(t, svc.DB.Model(app).Update("max_fee_percent", maxFeePercent).Error)
+
+	lnClient := &mockMaxRoutingFeeLn{MockLn: svc.LNClient.(*tests.MockLn)}
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendKeysend(context.TODO(), 500_000, "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c", nil, "", lnClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+
+	require.NotNil(t, lnClient.maxFeeMsat)
+	assert.Equal(t, uint64(5_000), *lnClient.maxFeeMsat)
+}
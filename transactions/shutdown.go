@@ -0,0 +1,79 @@
+package transactions
+
+import (
+	"context"
+	"sync"
+)
+
+type shuttingDownError struct {
+}
+
+func NewShuttingDownError() error {
+	return &shuttingDownError{}
+}
+
+func (err *shuttingDownError) Error() string {
+	return "Your Alby Hub is shutting down. Please try again once it is back online."
+}
+
+// paymentTracker counts the payments which are being sent so that shutdown can wait for them
+type paymentTracker struct {
+	mtx      sync.Mutex
+	draining bool
+	inFlight int
+	// closed once no payment is in flight anymore
+	idle chan struct{}
+}
+
+func newPaymentTracker() *paymentTracker {
+	idle := make(chan struct{})
+	close(idle)
+	return &paymentTracker{idle: idle}
+}
+
+// start registers a new payment, unless the hub is shutting down
+func (tracker *paymentTracker) start() error {
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+	if tracker.draining {
+		return NewShuttingDownError()
+	}
+	if tracker.inFlight == 0 {
+		tracker.idle = make(chan struct{})
+	}
+	tracker.inFlight++
+	return nil
+}
+
+func (tracker *paymentTracker) done() {
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+	tracker.inFlight--
+	if tracker.inFlight == 0 {
+		close(tracker.idle)
+	}
+}
+
+// drain rejects new payments and waits until the ones in flight are done or ctx is done.
+// It returns the number of payments still in flight.
+func (tracker *paymentTracker) drain(ctx context.Context) int {
+	tracker.mtx.Lock()
+	tracker.draining = true
+	idle := tracker.idle
+	tracker.mtx.Unlock()
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+	}
+
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+	return tracker.inFlight
+}
+
+// WaitForPayments stops accepting new outgoing payments and waits until the payments
+// being sent were either settled or failed, so that none is left pending when the node stops.
+func (svc *transactionsService) WaitForPayments(ctx context.Context) int {
+	return svc.payments.drain(ctx)
+}
@@ -0,0 +1,48 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/tests"
+)
+
+func TestWaitForPayments_RejectsNewPayments(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	assert.Equal(t, 0, transactionsService.WaitForPayments(context.TODO()))
+
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, nil, nil)
+	assert.ErrorIs(t, err, NewShuttingDownError())
+	assert.Nil(t, transaction)
+}
+
+func TestPaymentTracker_Drain(t *testing.T) {
+	tracker := newPaymentTracker()
+	require.NoError(t, tracker.start())
+	require.NoError(t, tracker.start())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		tracker.done()
+		tracker.done()
+	}()
+	assert.Equal(t, 0, tracker.drain(context.TODO()))
+	assert.ErrorIs(t, tracker.start(), NewShuttingDownError())
+}
+
+func TestPaymentTracker_DrainTimeout(t *testing.T) {
+	tracker := newPaymentTracker()
+	require.NoError(t, tracker.start())
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, 1, tracker.drain(ctx))
+}
@@ -0,0 +1,94 @@
+package transactions
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+type singleUseConsumedError struct {
+}
+
+func NewSingleUseConsumedError() error {
+	return &singleUseConsumedError{}
+}
+
+func (err *singleUseConsumedError) Error() string {
+	return "This connection can only be used once and was already used"
+}
+
+// validateSingleUse rejects a transaction of a single-use app if it already has a transaction of the
+// same type which is in flight or settled. Failed and expired transactions do not use up the connection.
+func validateSingleUse(tx *gorm.DB, app *db.App, transactionType string) error {
+	if !app.SingleUse {
+		return nil
+	}
+	var count int64
+	err := tx.Model(&db.Transaction{}).
+		Where("app_id = ? AND type = ? AND state IN ?", app.ID, transactionType, []string{constants.TRANSACTION_STATE_PENDING, constants.TRANSACTION_STATE_ACCEPTED, constants.TRANSACTION_STATE_SETTLED}).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return NewSingleUseConsumedError()
+	}
+	return nil
+}
+
+func (svc *transactionsService) isSingleUseApp(appId *uint) bool {
+	if appId == nil {
+		return false
+	}
+	var app db.App
+	return svc.db.Limit(1).Find(&app, &db.App{ID: *appId}).RowsAffected > 0 && app.SingleUse
+}
+
+func (svc *transactionsService) validateSingleUseInvoice(appId *uint) error {
+	if appId == nil {
+		return nil
+	}
+	var app db.App
+	if svc.db.Limit(1).Find(&app, &db.App{ID: *appId}).RowsAffected == 0 {
+		return NewNotFoundError()
+	}
+	return validateSingleUse(svc.db, &app, constants.TRANSACTION_TYPE_INCOMING)
+}
+
+// revokeSingleUseApp expires all permissions of a single-use app once one of its transactions settled
+func (svc *transactionsService) revokeSingleUseApp(tx *gorm.DB, appId uint) error {
+	var app db.App
+	if tx.Limit(1).Find(&app, &db.App{ID: appId}).RowsAffected == 0 || !app.SingleUse {
+		return nil
+	}
+
+	now := time.Now()
+	result := tx.Model(&db.AppPermission{}).
+		Where("app_id = ? AND (expires_at IS NULL OR expires_at > ?)", appId, now).
+		Update("expires_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	if err := apps.RecordAuditLog(tx, appId, apps.AUDIT_ACTION_REVOKED, nil); err != nil {
+		return err
+	}
+
+	logger.Logger.WithField("app_id", appId).Info("Revoked single-use app")
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_app_revoked",
+		Properties: map[string]interface{}{
+			"name": app.Name,
+			"id":   app.ID,
+		},
+	})
+	return nil
+}
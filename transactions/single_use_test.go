@@ -0,0 +1,119 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func createSingleUseApp(t *testing.T, svc *tests.TestService) *db.App {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Model(app).Update("single_use", true).Error)
+	require.NoError(t, svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error)
+	return app
+}
+
+func TestSendPaymentSync_SingleUse(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	app := createSingleUseApp(t, svc)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+
+	// the app is revoked after the payment settled
+	var appPermissions []db.AppPermission
+	require.NoError(t, svc.DB.Where("app_id = ?", app.ID).Find(&appPermissions).Error)
+	require.Len(t, appPermissions, 2)
+	for _, appPermission := range appPermissions {
+		require.NotNil(t, appPermission.ExpiresAt)
+	}
+
+	var auditLog db.AppAuditLog
+	require.NoError(t, svc.DB.Where("app_id = ? AND action = ?", app.ID, apps.AUDIT_ACTION_REVOKED).First(&auditLog).Error)
+
+	var revokedEvents int
+	for _, event := range mockEventConsumer.GetConsumedEvents() {
+		if event.Event == "nwc_app_revoked" {
+			revokedEvents++
+		}
+	}
+	assert.Equal(t, 1, revokedEvents)
+
+	transaction, err = transactionsService.SendKeysend(context.TODO(), 1000, "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c", nil, "", svc.LNClient, &app.ID, nil)
+	assert.ErrorIs(t, err, NewSingleUseConsumedError())
+	assert.Nil(t, transaction)
+}
+
+func TestSendPaymentSync_SingleUse_FailedPaymentDoesNotConsume(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createSingleUseApp(t, svc)
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		AppId:       &app.ID,
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		State:       constants.TRANSACTION_STATE_FAILED,
+		AmountMsat:  1000,
+		PaymentHash: "failed",
+	}).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(context.TODO(), tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestSendPaymentBatch_SingleUse(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createSingleUseApp(t, svc)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	result, err := transactionsService.SendPaymentBatch(context.TODO(), []BatchPayment{
+		{Invoice: tests.MockLNClientTransaction.Invoice},
+		{Invoice: tests.MockInvoice},
+	}, svc.LNClient, &app.ID, nil, nil)
+	assert.ErrorIs(t, err, NewSingleUseConsumedError())
+	assert.Nil(t, result)
+}
+
+func TestMakeInvoice_SingleUse(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app := createSingleUseApp(t, svc)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.MakeInvoice(context.TODO(), 1234, "", "", 0, nil, svc.LNClient, &app.ID, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, transaction.State)
+
+	// only one invoice can be open at a time
+	transaction, err = transactionsService.MakeInvoice(context.TODO(), 1234, "", "", 0, nil, svc.LNClient, &app.ID, nil, nil)
+	assert.ErrorIs(t, err, NewSingleUseConsumedError())
+	assert.Nil(t, transaction)
+}
@@ -0,0 +1,217 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+)
+
+type SummaryTotals struct {
+	Count      uint64
+	AmountMsat uint64
+	FeeMsat    uint64
+	// value of the amounts in the requested currency, using the rates stored at settlement
+	FiatAmount float64
+}
+
+type AppSummary struct {
+	AppId    *uint
+	Sent     SummaryTotals
+	Received SummaryTotals
+}
+
+type DailySummary struct {
+	Date     string // YYYY-MM-DD in UTC
+	Sent     SummaryTotals
+	Received SummaryTotals
+}
+
+type Summary struct {
+	From     time.Time
+	Until    time.Time
+	Currency string
+	Sent     SummaryTotals
+	Received SummaryTotals
+	Apps     []AppSummary
+	Days     []DailySummary
+}
+
+type summaryRow struct {
+	AppId      *uint
+	Day        string
+	Type       string
+	Count      uint64
+	AmountMsat uint64
+	FeeMsat    uint64
+	FiatAmount float64
+}
+
+// GetSummary aggregates settled transactions within [from, until) in the database
+// so that dashboards do not need to download the full transaction history.
+// If a currency is provided, fiat amounts are calculated from the rates stored at settlement.
+func (svc *transactionsService) GetSummary(from time.Time, until time.Time, appId *uint, currency string) (*Summary, error) {
+	if !from.Before(until) {
+		return nil, errors.New("from must be before until")
+	}
+
+	summary := &Summary{
+		From:     from,
+		Until:    until,
+		Currency: strings.ToUpper(currency),
+		Apps:     []AppSummary{},
+		Days:     []DailySummary{},
+	}
+
+	archivedRows, err := svc.queryArchivedSummary(from, until, appId, summary.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	totalRows, err := svc.querySummary(from, until, appId, summary.Currency, "", "")
+	if err != nil {
+		return nil, err
+	}
+	totalRows = append(totalRows, archivedRows...)
+	for _, row := range totalRows {
+		addSummaryRow(&summary.Sent, &summary.Received, &row)
+	}
+
+	appRows, err := svc.querySummary(from, until, appId, summary.Currency, "app_id", "app_id")
+	if err != nil {
+		return nil, err
+	}
+	appRows = append(appRows, archivedRows...)
+	appIndexes := map[uint]int{}
+	for _, row := range appRows {
+		// transactions not linked to an app are grouped under id 0
+		key := uint(0)
+		if row.AppId != nil {
+			key = *row.AppId
+		}
+		index, ok := appIndexes[key]
+		if !ok {
+			index = len(summary.Apps)
+			appIndexes[key] = index
+			summary.Apps = append(summary.Apps, AppSummary{AppId: row.AppId})
+		}
+		addSummaryRow(&summary.Apps[index].Sent, &summary.Apps[index].Received, &row)
+	}
+	sort.SliceStable(summary.Apps, func(i, j int) bool {
+		if summary.Apps[i].AppId == nil || summary.Apps[j].AppId == nil {
+			return summary.Apps[i].AppId == nil && summary.Apps[j].AppId != nil
+		}
+		return *summary.Apps[i].AppId < *summary.Apps[j].AppId
+	})
+
+	dayExpression := "strftime('%Y-%m-%d', settled_at)"
+	if svc.db.Dialector.Name() == "postgres" {
+		dayExpression = "to_char(settled_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')"
+	}
+	dayRows, err := svc.querySummary(from, until, appId, summary.Currency, dayExpression+" AS day", "day")
+	if err != nil {
+		return nil, err
+	}
+	dayRows = append(dayRows, archivedRows...)
+	dayIndexes := map[string]int{}
+	for _, row := range dayRows {
+		index, ok := dayIndexes[row.Day]
+		if !ok {
+			index = len(summary.Days)
+			dayIndexes[row.Day] = index
+			summary.Days = append(summary.Days, DailySummary{Date: row.Day})
+		}
+		addSummaryRow(&summary.Days[index].Sent, &summary.Days[index].Received, &row)
+	}
+	sort.SliceStable(summary.Days, func(i, j int) bool {
+		return summary.Days[i].Date < summary.Days[j].Date
+	})
+
+	return summary, nil
+}
+
+func (svc *transactionsService) querySummary(from time.Time, until time.Time, appId *uint, currency string, selectExpression string, groupBy string) ([]summaryRow, error) {
+	columns := "type, COUNT(*) AS count, SUM(amount_msat) AS amount_msat, SUM(fee_msat) AS fee_msat"
+	if currency != "" {
+		columns += fmt.Sprintf(", SUM(amount_msat * transaction_fiat_rates.rate) / %d AS fiat_amount", msatPerBtc)
+	}
+	if selectExpression != "" {
+		columns = selectExpression + ", " + columns
+	}
+
+	query := svc.db.
+		Table("transactions").
+		Select(columns)
+	if currency != "" {
+		query = query.Joins("LEFT JOIN transaction_fiat_rates ON transaction_fiat_rates.transaction_id = transactions.id AND transaction_fiat_rates.currency = ?", currency)
+	}
+	query = query.
+		Where("state = ? AND settled_at >= ? AND settled_at < ?", constants.TRANSACTION_STATE_SETTLED, from, until)
+	if appId != nil {
+		query = query.Where("app_id = ?", *appId)
+	}
+	if groupBy != "" {
+		query = query.Group(groupBy)
+	}
+	query = query.Group("type")
+
+	var rows []summaryRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// queryArchivedSummary returns the totals of archived transactions. Only daily totals are kept
+// of them, which are included if the day starts within [from, until).
+func (svc *transactionsService) queryArchivedSummary(from time.Time, until time.Time, appId *uint, currency string) ([]summaryRow, error) {
+	fromDay := from.UTC().Truncate(24 * time.Hour)
+	if fromDay.Before(from) {
+		fromDay = fromDay.AddDate(0, 0, 1)
+	}
+	untilDay := until.UTC().Truncate(24 * time.Hour)
+	if untilDay.Before(until) {
+		untilDay = untilDay.AddDate(0, 0, 1)
+	}
+
+	query := svc.db.Where("day >= ? AND day < ?", fromDay.Format(time.DateOnly), untilDay.Format(time.DateOnly))
+	if appId != nil {
+		query = query.Where("app_id = ?", *appId)
+	}
+	var archivedTotals []db.ArchivedTransactionTotal
+	if err := query.Find(&archivedTotals).Error; err != nil {
+		return nil, err
+	}
+
+	rows := []summaryRow{}
+	for _, archivedTotal := range archivedTotals {
+		row := summaryRow{
+			AppId:      archivedTotal.AppId,
+			Day:        archivedTotal.Day,
+			Type:       archivedTotal.Type,
+			Count:      archivedTotal.Count,
+			AmountMsat: archivedTotal.AmountMsat,
+			FeeMsat:    archivedTotal.FeeMsat,
+		}
+		if currency != "" {
+			row.FiatAmount = archivedTotal.FiatAmounts[currency]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func addSummaryRow(sent *SummaryTotals, received *SummaryTotals, row *summaryRow) {
+	totals := received
+	if row.Type == constants.TRANSACTION_TYPE_OUTGOING {
+		totals = sent
+	}
+	totals.Count += row.Count
+	totals.AmountMsat += row.AmountMsat
+	totals.FeeMsat += row.FeeMsat
+	totals.FiatAmount += row.FiatAmount
+}
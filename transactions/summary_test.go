@@ -0,0 +1,68 @@
+package transactions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestGetSummary(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC)
+	outsidePeriod := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+
+	transactions := []db.Transaction{
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 10_000, SettledAt: &day1},
+		{AppId: &app.ID, Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 4_000, FeeMsat: 100, SettledAt: &day1},
+		{Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 5_000, SettledAt: &day2},
+		{Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 2_000, FeeMsat: 50, SettledAt: &day2},
+		// not counted
+		{Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_SETTLED, AmountMsat: 1_000, SettledAt: &outsidePeriod},
+		{Type: constants.TRANSACTION_TYPE_OUTGOING, State: constants.TRANSACTION_STATE_FAILED, AmountMsat: 1_000},
+		{Type: constants.TRANSACTION_TYPE_INCOMING, State: constants.TRANSACTION_STATE_PENDING, AmountMsat: 1_000},
+	}
+	require.NoError(t, svc.DB.Create(&transactions).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	summary, err := transactionsService.GetSummary(from, until, nil, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, SummaryTotals{Count: 2, AmountMsat: 15_000}, summary.Received)
+	assert.Equal(t, SummaryTotals{Count: 2, AmountMsat: 6_000, FeeMsat: 150}, summary.Sent)
+
+	require.Equal(t, 2, len(summary.Apps))
+	assert.Nil(t, summary.Apps[0].AppId)
+	assert.Equal(t, SummaryTotals{Count: 1, AmountMsat: 5_000}, summary.Apps[0].Received)
+	assert.Equal(t, app.ID, *summary.Apps[1].AppId)
+	assert.Equal(t, SummaryTotals{Count: 1, AmountMsat: 4_000, FeeMsat: 100}, summary.Apps[1].Sent)
+
+	require.Equal(t, 2, len(summary.Days))
+	assert.Equal(t, "2026-03-01", summary.Days[0].Date)
+	assert.Equal(t, uint64(10_000), summary.Days[0].Received.AmountMsat)
+	assert.Equal(t, "2026-03-02", summary.Days[1].Date)
+	assert.Equal(t, uint64(2_000), summary.Days[1].Sent.AmountMsat)
+
+	appSummary, err := transactionsService.GetSummary(from, until, &app.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, SummaryTotals{Count: 1, AmountMsat: 10_000}, appSummary.Received)
+	assert.Equal(t, 1, len(appSummary.Apps))
+	assert.Equal(t, 1, len(appSummary.Days))
+
+	_, err = transactionsService.GetSummary(until, from, nil, "")
+	assert.EqualError(t, err, "from must be before until")
+}
@@ -0,0 +1,13 @@
+package transactions
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// appIdAttribute adds the app to payment spans, payments made from the hub itself have app id 0
+func appIdAttribute(appId *uint) attribute.KeyValue {
+	if appId == nil {
+		return attribute.Int("app.id", 0)
+	}
+	return attribute.Int("app.id", int(*appId))
+}
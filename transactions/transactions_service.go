@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"slices"
 	"strconv"
@@ -27,11 +28,15 @@ import (
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
+	"github.com/getAlby/hub/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type transactionsService struct {
 	db             *gorm.DB
 	eventPublisher events.EventPublisher
+	payments       *paymentTracker
 }
 
 type TransactionsService interface {
@@ -39,18 +44,36 @@ type TransactionsService interface {
 	MakeInvoice(ctx context.Context, amount uint64, description string, descriptionHash string, expiry uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, throughNodePubkey *string) (*Transaction, error)
 	LookupTransaction(ctx context.Context, paymentHash string, transactionType *string, lnClient lnclient.LNClient, appId *uint) (*Transaction, error)
 	ListTransactions(ctx context.Context, from, until, limit, offset uint64, unpaidOutgoing bool, unpaidIncoming bool, transactionType *string, lnClient lnclient.LNClient, appId *uint, forceFilterByAppId bool) (transactions []Transaction, totalCount uint64, err error)
-	SendPaymentSync(payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
-	SendKeysend(amount uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	SendPaymentSync(ctx context.Context, payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	SendPaymentSyncWithIdempotencyKey(ctx context.Context, payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, idempotencyKey string) (*Transaction, error)
+	SendPaymentBatch(ctx context.Context, payments []BatchPayment, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, onItemDone BatchPaymentItemDoneFunc) (*BatchPaymentResult, error)
+	SendKeysend(ctx context.Context, amount uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	RebalanceCircular(ctx context.Context, request *CircularRebalanceRequest, lnClient lnclient.LNClient) (*Transaction, error)
 	MakeHoldInvoice(ctx context.Context, amount uint64, description string, descriptionHash string, expiry uint64, paymentHash string, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
 	SettleHoldInvoice(ctx context.Context, preimage string, lnClient lnclient.LNClient) (*Transaction, error)
 	CancelHoldInvoice(ctx context.Context, paymentHash string, lnClient lnclient.LNClient) error
 	SetTransactionMetadata(ctx context.Context, id uint, metadata map[string]interface{}) error
+	RefundTransaction(ctx context.Context, id uint, payReq string, amountMsat *uint64, lnClient lnclient.LNClient) (*Transaction, error)
+	GetRefundedAmounts(ids []uint) (map[uint]uint64, error)
+	CreateReceipt(ctx context.Context, paymentHash string, transactionType *string, lnClient lnclient.LNClient, appId *uint) (*Receipt, error)
+	GetSummary(from time.Time, until time.Time, appId *uint, currency string) (*Summary, error)
+	GetAppReport(appId uint, from time.Time, until time.Time, currency string) (*AppReport, error)
+	SaveFiatRates(transactionId uint, rates map[string]float64) error
+	GetFiatRates(ids []uint, currencies []string) (map[uint][]db.TransactionFiatRate, error)
+	ArchiveTransactions(retentionMonths uint, password string, w io.Writer) (*ArchiveResult, error)
+	ExpireInvoices() (int, error)
+	ListPaymentApprovals(state string) ([]db.PaymentApproval, error)
+	DecidePaymentApproval(id uint, approved bool) error
+	StartInvoiceExpirySweep(ctx context.Context)
+	WaitForPayments(ctx context.Context) int
 }
 
 const (
-	BoostagramTlvType = 7629169
-	WhatsatTlvType    = 34349334
-	CustomKeyTlvType  = 696969
+	BoostagramTlvType       = 7629169
+	TipNoteTlvType          = 7629171   // podcasting 2.0 plain text message sent alongside a boost
+	LegacyBoostagramTlvType = 133773310 // JSON payload used by older podcast apps (e.g. Sphinx)
+	WhatsatTlvType          = 34349334
+	CustomKeyTlvType        = 696969
 )
 
 // Prevent races when checking the current balance and creating payment
@@ -74,6 +97,12 @@ type Boostagram struct {
 	Time           string         `json:"time"`
 	Action         string         `json:"action"`
 	ValueMsatTotal int64          `json:"value_msat_total"`
+	Guid           string         `json:"guid,omitempty"`
+	EpisodeGuid    string         `json:"episode_guid,omitempty"`
+	RemoteFeedGuid string         `json:"remote_feed_guid,omitempty"`
+	RemoteItemGuid string         `json:"remote_item_guid,omitempty"`
+	ReplyAddress   string         `json:"reply_address,omitempty"`
+	BoostLink      string         `json:"boost_link,omitempty"`
 }
 
 type StringOrNumber struct {
@@ -133,14 +162,40 @@ func (err *quotaExceededError) Error() string {
 	return "Your app does not have enough budget remaining to make this payment. Please review this app in the connections page of your Alby Hub."
 }
 
+type paymentAmountExceededError struct {
+}
+
+func NewPaymentAmountExceededError() error {
+	return &paymentAmountExceededError{}
+}
+
+func (err *paymentAmountExceededError) Error() string {
+	return "The payment amount exceeds the maximum allowed for a single payment. Please review the payment limits in your Alby Hub."
+}
+
+type spendingLockedError struct {
+}
+
+func NewSpendingLockedError() error {
+	return &spendingLockedError{}
+}
+
+func (err *spendingLockedError) Error() string {
+	return "Your Alby Hub was locked after a period of inactivity. Please unlock it to make payments."
+}
+
 func NewTransactionsService(db *gorm.DB, eventPublisher events.EventPublisher) *transactionsService {
 	return &transactionsService{
 		db:             db,
 		eventPublisher: eventPublisher,
+		payments:       newPaymentTracker(),
 	}
 }
 
-func (svc *transactionsService) MakeInvoice(ctx context.Context, amount uint64, description string, descriptionHash string, expiry uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, throughNodePubkey *string) (*Transaction, error) {
+func (svc *transactionsService) MakeInvoice(ctx context.Context, amount uint64, description string, descriptionHash string, expiry uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, throughNodePubkey *string) (transaction *Transaction, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "transactions.MakeInvoice", trace.WithAttributes(appIdAttribute(appId)))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	logger.Logger.WithFields(logrus.Fields{
 		"app_id":           appId,
 		"request_event_id": requestEventId,
@@ -154,14 +209,10 @@ func (svc *transactionsService) MakeInvoice(ctx context.Context, amount uint64,
 	var metadataBytes []byte
 	if metadata != nil {
 		var err error
-		metadataBytes, err = json.Marshal(metadata)
+		metadataBytes, err = svc.encodeMetadata(metadata, "invoice")
 		if err != nil {
-			logger.Logger.WithError(err).Error("Failed to serialize metadata")
 			return nil, err
 		}
-		if len(metadataBytes) > constants.INVOICE_METADATA_MAX_LENGTH {
-			return nil, fmt.Errorf("encoded invoice metadata provided is too large. Limit: %d Received: %d", constants.INVOICE_METADATA_MAX_LENGTH, len(metadataBytes))
-		}
 	}
 
 	if metadata["app_id"] != nil {
@@ -174,7 +225,13 @@ func (svc *transactionsService) MakeInvoice(ctx context.Context, amount uint64,
 		appId = &overwriteAppId
 	}
 
-	lnClientTransaction, err := lnClient.MakeInvoice(ctx, int64(amount), description, descriptionHash, int64(expiry), throughNodePubkey)
+	if err := svc.validateSingleUseInvoice(appId); err != nil {
+		return nil, err
+	}
+
+	lnClientCtx, lnClientSpan := tracing.Tracer().Start(ctx, "lnclient.MakeInvoice")
+	lnClientTransaction, err := lnClient.MakeInvoice(lnClientCtx, int64(amount), description, descriptionHash, int64(expiry), throughNodePubkey)
+	tracing.EndSpan(lnClientSpan, err)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to create transaction")
 		return nil, err
@@ -217,14 +274,14 @@ func (svc *transactionsService) MakeHoldInvoice(ctx context.Context, amount uint
 	var err error
 	var metadataBytes []byte
 	if metadata != nil {
-		metadataBytes, err = json.Marshal(metadata)
+		metadataBytes, err = svc.encodeMetadata(metadata, "invoice")
 		if err != nil {
-			logger.Logger.WithError(err).Error("Failed to serialize metadata")
 			return nil, err
 		}
-		if len(metadataBytes) > constants.INVOICE_METADATA_MAX_LENGTH {
-			return nil, fmt.Errorf("encoded invoice metadata provided is too large. Limit: %d Received: %d", constants.INVOICE_METADATA_MAX_LENGTH, len(metadataBytes))
-		}
+	}
+
+	if err := svc.validateSingleUseInvoice(appId); err != nil {
+		return nil, err
 	}
 
 	lnClientTransaction, err := lnClient.MakeHoldInvoice(ctx, int64(amount), description, descriptionHash, int64(expiry), paymentHash)
@@ -264,21 +321,150 @@ func (svc *transactionsService) MakeHoldInvoice(ctx context.Context, amount uint
 		logger.Logger.WithError(err).Error("Failed to create hold invoice DB transaction")
 		return nil, err
 	}
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event:      "nwc_hold_invoice_created",
+		Properties: &dbTransaction,
+	})
+
 	return &dbTransaction, nil
 }
 
-func (svc *transactionsService) SendPaymentSync(payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
+func (svc *transactionsService) SendPaymentSync(ctx context.Context, payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
+	return svc.SendPaymentSyncWithIdempotencyKey(ctx, payReq, amountMsat, metadata, lnClient, appId, requestEventId, "")
+}
+
+// SendPaymentSyncWithIdempotencyKey pays an invoice at most once per idempotency key. Retries with
+// the same key return the result of the original payment instead of paying again.
+func (svc *transactionsService) SendPaymentSyncWithIdempotencyKey(ctx context.Context, payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, idempotencyKey string) (*Transaction, error) {
+	return svc.sendPaymentSync(ctx, payReq, amountMsat, metadata, lnClient, appId, requestEventId, idempotencyKey, nil)
+}
+
+// sendPaymentSync pays an invoice. If refundOfTransactionId is set, the payment is stored as a refund
+// of that transaction and the refundable amount is checked under the same lock as the balance.
+func (svc *transactionsService) sendPaymentSync(ctx context.Context, payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, idempotencyKey string, refundOfTransactionId *uint) (transaction *Transaction, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "transactions.SendPayment", trace.WithAttributes(appIdAttribute(appId)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if err := CheckSpendingAllowed(svc.db); err != nil {
+		return nil, err
+	}
+	if err := svc.payments.start(); err != nil {
+		return nil, err
+	}
+	defer svc.payments.done()
+	if err := validateIdempotencyKey(idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	payment, err := svc.preparePayment(payReq, amountMsat, metadata, lnClient)
+	if err != nil {
+		return nil, err
+	}
+	if idempotencyKey != "" {
+		payment.idempotencyKey = &idempotencyKey
+	}
+	payment.refundOfTransactionId = refundOfTransactionId
+
+	err = svc.validatePaymentDestination(appId, payment.paymentRequest.Payee)
+	if err != nil {
+		return nil, err
+	}
+
+	// a retried or already paid payment must not ask the user for approval again.
+	// Both are checked again under the lock below.
+	if idempotencyKey != "" {
+		idempotentTransaction, err := findIdempotentPayment(svc.db, appId, idempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if idempotentTransaction != nil {
+			return getIdempotentResult(idempotentTransaction, payment.paymentRequest.PaymentHash)
+		}
+	}
+	if err := checkNotAlreadyPaid(svc.db, payment.paymentRequest.PaymentHash); err != nil {
+		return nil, err
+	}
+
+	err = svc.waitForPaymentApproval(appId, requestEventId, payment.amountMsat, payment.paymentRequest.PaymentHash, "", payment.paymentRequest.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbTransaction *db.Transaction
+	var idempotentTransaction *db.Transaction
+
+	err = func() error {
+		balanceValidationLock.Lock()
+		defer balanceValidationLock.Unlock()
+		return svc.db.Transaction(func(tx *gorm.DB) error {
+			if idempotencyKey != "" {
+				var err error
+				idempotentTransaction, err = findIdempotentPayment(tx, appId, idempotencyKey)
+				if err != nil || idempotentTransaction != nil {
+					return err
+				}
+			}
+
+			if err := checkNotAlreadyPaid(tx, payment.paymentRequest.PaymentHash); err != nil {
+				return err
+			}
+
+			if payment.refundOfTransactionId != nil {
+				if err := validateRefundAmount(tx, *payment.refundOfTransactionId, payment.amountMsat); err != nil {
+					return err
+				}
+			}
+
+			err := svc.validateCanPay(tx, appId, payment.amountMsat, payment.paymentRequest.Description, payment.selfPayment)
+			if err != nil {
+				return err
+			}
+
+			dbTransaction, err = createPendingPayment(tx, payment, appId, requestEventId)
+			return err
+		})
+	}()
+
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"bolt11": payment.payReq,
+		}).WithError(err).Error("Failed to create DB transaction")
+		return nil, err
+	}
+
+	if idempotentTransaction != nil {
+		return getIdempotentResult(idempotentTransaction, payment.paymentRequest.PaymentHash)
+	}
+
+	return svc.executePayment(ctx, payment, dbTransaction, lnClient, appId, requestEventId)
+}
+
+type preparedPayment struct {
+	payReq         string
+	paymentRequest decodepay.Bolt11
+	amountMsat     uint64
+	// the amount requested by the caller, passed on to the LNClient
+	sendAmountMsat *uint64
+	metadataBytes  []byte
+	metadata       map[string]interface{}
+	selfPayment    bool
+	idempotencyKey *string
+	// set for refunds, the pending payment is linked to the refunded transaction
+	refundOfTransactionId *uint
+	// set for payments to the node itself which go out through a channel and come back through another
+	circularRoute *circularRoute
+}
+
+// preparePayment decodes and validates an invoice before any budget is reserved for it
+func (svc *transactionsService) preparePayment(payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient) (*preparedPayment, error) {
 	var metadataBytes []byte
 	if metadata != nil {
 		var err error
-		metadataBytes, err = json.Marshal(metadata)
+		metadataBytes, err = svc.encodeMetadata(metadata, "payment")
 		if err != nil {
-			logger.Logger.WithError(err).Error("Failed to serialize metadata")
 			return nil, err
 		}
-		if len(metadataBytes) > constants.INVOICE_METADATA_MAX_LENGTH {
-			return nil, fmt.Errorf("encoded payment metadata provided is too large. Limit: %d Received: %d", constants.INVOICE_METADATA_MAX_LENGTH, len(metadataBytes))
-		}
 	}
 
 	payReq = strings.ToLower(payReq)
@@ -312,106 +498,118 @@ func (svc *transactionsService) SendPaymentSync(payReq string, amountMsat *uint6
 		}
 	}
 
-	var dbTransaction db.Transaction
-
 	paymentAmount := uint64(paymentRequest.MSatoshi)
 	if amountMsat != nil && paymentRequest.MSatoshi == 0 {
 		paymentAmount = *amountMsat
 	}
 
-	err = func() error {
-		balanceValidationLock.Lock()
-		defer balanceValidationLock.Unlock()
-		return svc.db.Transaction(func(tx *gorm.DB) error {
-			var existingSettledTransaction db.Transaction
-			if tx.Limit(1).Find(&existingSettledTransaction, &db.Transaction{
-				Type:        constants.TRANSACTION_TYPE_OUTGOING,
-				PaymentHash: paymentRequest.PaymentHash,
-				State:       constants.TRANSACTION_STATE_SETTLED,
-			}).RowsAffected > 0 {
-				logger.Logger.WithField("payment_hash", dbTransaction.PaymentHash).Debug("this invoice has already been paid")
-				return errors.New("this invoice has already been paid")
-			}
-			if tx.Limit(1).Find(&existingSettledTransaction, &db.Transaction{
-				Type:        constants.TRANSACTION_TYPE_OUTGOING,
-				PaymentHash: paymentRequest.PaymentHash,
-				State:       constants.TRANSACTION_STATE_PENDING,
-			}).RowsAffected > 0 {
-				logger.Logger.WithField("payment_hash", dbTransaction.PaymentHash).Debug("this invoice is already being paid")
-				return errors.New("there is already a payment pending for this invoice")
-			}
-
-			err := svc.validateCanPay(tx, appId, paymentAmount, paymentRequest.Description, selfPayment)
-			if err != nil {
-				return err
-			}
+	return &preparedPayment{
+		payReq:         payReq,
+		paymentRequest: paymentRequest,
+		amountMsat:     paymentAmount,
+		sendAmountMsat: amountMsat,
+		metadataBytes:  metadataBytes,
+		metadata:       metadata,
+		selfPayment:    selfPayment,
+	}, nil
+}
 
-			var expiresAt *time.Time
-			if paymentRequest.Expiry > 0 {
-				expiresAtValue := time.Now().Add(time.Duration(paymentRequest.Expiry) * time.Second)
-				expiresAt = &expiresAtValue
-			}
-			dbTransaction = db.Transaction{
-				AppId:           appId,
-				RequestEventId:  requestEventId,
-				Type:            constants.TRANSACTION_TYPE_OUTGOING,
-				State:           constants.TRANSACTION_STATE_PENDING,
-				FeeReserveMsat:  CalculateFeeReserveMsat(paymentAmount),
-				AmountMsat:      paymentAmount,
-				PaymentRequest:  payReq,
-				PaymentHash:     paymentRequest.PaymentHash,
-				Description:     paymentRequest.Description,
-				DescriptionHash: paymentRequest.DescriptionHash,
-				ExpiresAt:       expiresAt,
-				SelfPayment:     selfPayment,
-				Metadata:        datatypes.JSON(metadataBytes),
-			}
-			err = tx.Create(&dbTransaction).Error
-			return err
-		})
-	}()
+func checkNotAlreadyPaid(tx *gorm.DB, paymentHash string) error {
+	var existingTransaction db.Transaction
+	if tx.Limit(1).Find(&existingTransaction, &db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentHash: paymentHash,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+	}).RowsAffected > 0 {
+		logger.Logger.WithField("payment_hash", paymentHash).Debug("this invoice has already been paid")
+		return errors.New("this invoice has already been paid")
+	}
+	if tx.Limit(1).Find(&existingTransaction, &db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentHash: paymentHash,
+		State:       constants.TRANSACTION_STATE_PENDING,
+	}).RowsAffected > 0 {
+		logger.Logger.WithField("payment_hash", paymentHash).Debug("this invoice is already being paid")
+		return errors.New("there is already a payment pending for this invoice")
+	}
+	return nil
+}
 
-	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"bolt11": payReq,
-		}).WithError(err).Error("Failed to create DB transaction")
+// createPendingPayment stores the outgoing payment. Pending payments count towards
+// the app budget and isolated balance, so this reserves the amount and fee reserve.
+func createPendingPayment(tx *gorm.DB, payment *preparedPayment, appId *uint, requestEventId *uint) (*db.Transaction, error) {
+	var expiresAt *time.Time
+	if payment.paymentRequest.Expiry > 0 {
+		expiresAtValue := time.Now().Add(time.Duration(payment.paymentRequest.Expiry) * time.Second)
+		expiresAt = &expiresAtValue
+	}
+	dbTransaction := db.Transaction{
+		AppId:                 appId,
+		RequestEventId:        requestEventId,
+		Type:                  constants.TRANSACTION_TYPE_OUTGOING,
+		State:                 constants.TRANSACTION_STATE_PENDING,
+		FeeReserveMsat:        calculateAppFeeReserveMsat(tx, appId, payment.amountMsat),
+		AmountMsat:            payment.amountMsat,
+		PaymentRequest:        payment.payReq,
+		PaymentHash:           payment.paymentRequest.PaymentHash,
+		Description:           payment.paymentRequest.Description,
+		DescriptionHash:       payment.paymentRequest.DescriptionHash,
+		ExpiresAt:             expiresAt,
+		SelfPayment:           payment.selfPayment,
+		Metadata:              datatypes.JSON(payment.metadataBytes),
+		IdempotencyKey:        payment.idempotencyKey,
+		RefundOfTransactionId: payment.refundOfTransactionId,
+	}
+	if err := tx.Create(&dbTransaction).Error; err != nil {
 		return nil, err
 	}
+	if err := recordBudgetFiatRate(tx, appId, dbTransaction.ID); err != nil {
+		return nil, err
+	}
+	return &dbTransaction, nil
+}
 
+func (svc *transactionsService) executePayment(ctx context.Context, payment *preparedPayment, dbTransaction *db.Transaction, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
 	logger.Logger.WithFields(logrus.Fields{
 		"app_id":           appId,
 		"request_event_id": requestEventId,
-		"amount":           paymentAmount,
-		"description":      paymentRequest.Description,
-		"description_hash": paymentRequest.DescriptionHash,
-		"expiry":           paymentRequest.Expiry,
-		"self_payment":     selfPayment,
-		"metadata":         metadata,
+		"amount":           payment.amountMsat,
+		"description":      payment.paymentRequest.Description,
+		"description_hash": payment.paymentRequest.DescriptionHash,
+		"expiry":           payment.paymentRequest.Expiry,
+		"self_payment":     payment.selfPayment,
+		"metadata":         payment.metadata,
 	}).Debug("Initiating payment")
 
+	startedAt := time.Now()
 	var response *lnclient.PayInvoiceResponse
-	if selfPayment {
-		response, err = svc.interceptSelfPayment(paymentRequest.PaymentHash, lnClient)
+	var err error
+	if payment.circularRoute != nil {
+		response, err = sendCircularPayment(ctx, lnClient, payment.payReq, payment.circularRoute)
+	} else if payment.selfPayment {
+		response, err = svc.interceptSelfPayment(payment.paymentRequest.PaymentHash, lnClient)
 	} else {
-		response, err = lnClient.SendPaymentSync(payReq, amountMsat)
+		response, err = sendPaymentWithMaxFee(ctx, lnClient, payment.payReq, payment.sendAmountMsat, getMaxRoutingFeeMsat(svc.db, appId, payment.amountMsat))
 	}
 
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
-			"bolt11": payReq,
+			"bolt11": payment.payReq,
 		}).WithError(err).Error("Failed to send payment")
 
 		svc.db.Transaction(func(tx *gorm.DB) error {
-			return svc.markPaymentFailed(tx, &dbTransaction, err.Error())
+			return svc.markPaymentFailed(tx, dbTransaction, err.Error())
 		})
+		metrics.ObservePaymentDuration(constants.TRANSACTION_STATE_FAILED, time.Since(startedAt))
 
 		return nil, err
 	}
+	metrics.ObservePaymentDuration(constants.TRANSACTION_STATE_SETTLED, time.Since(startedAt))
 
 	// the payment definitely succeeded
 	var settledTransaction *db.Transaction
 	err = svc.db.Transaction(func(tx *gorm.DB) error {
-		settledTransaction, err = svc.markTransactionSettled(tx, &dbTransaction, response.Preimage, response.Fee, selfPayment)
+		settledTransaction, err = svc.markTransactionSettled(tx, dbTransaction, response.Preimage, response.Fee, payment.selfPayment)
 		return err
 	})
 	if err != nil {
@@ -421,7 +619,17 @@ func (svc *transactionsService) SendPaymentSync(payReq string, amountMsat *uint6
 	return settledTransaction, nil
 }
 
-func (svc *transactionsService) SendKeysend(amount uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
+func (svc *transactionsService) SendKeysend(ctx context.Context, amount uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (transaction *Transaction, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "transactions.SendKeysend", trace.WithAttributes(appIdAttribute(appId)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if err := CheckSpendingAllowed(svc.db); err != nil {
+		return nil, err
+	}
+	if err := svc.payments.start(); err != nil {
+		return nil, err
+	}
+	defer svc.payments.done()
 	if preimage == "" {
 		preImageBytes, err := makePreimageHex()
 		if err != nil {
@@ -459,10 +667,29 @@ func (svc *transactionsService) SendKeysend(amount uint64, destination string, c
 
 	selfPayment := destination == lnClient.GetPubkey()
 
+	err = svc.validatePaymentDestination(appId, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	// a keysend with a reused preimage must not ask the user for approval again
+	if err := checkNotAlreadyPaid(svc.db, paymentHash); err != nil {
+		return nil, err
+	}
+
+	err = svc.waitForPaymentApproval(appId, requestEventId, amount, paymentHash, destination, svc.getDescriptionFromCustomRecords(customRecords))
+	if err != nil {
+		return nil, err
+	}
+
 	err = func() error {
 		balanceValidationLock.Lock()
 		defer balanceValidationLock.Unlock()
 		return svc.db.Transaction(func(tx *gorm.DB) error {
+			if err := checkNotAlreadyPaid(tx, paymentHash); err != nil {
+				return err
+			}
+
 			err := svc.validateCanPay(tx, appId, amount, "", selfPayment)
 			if err != nil {
 				return err
@@ -474,7 +701,7 @@ func (svc *transactionsService) SendKeysend(amount uint64, destination string, c
 				RequestEventId: requestEventId,
 				Type:           constants.TRANSACTION_TYPE_OUTGOING,
 				State:          constants.TRANSACTION_STATE_PENDING,
-				FeeReserveMsat: CalculateFeeReserveMsat(uint64(amount)),
+				FeeReserveMsat: calculateAppFeeReserveMsat(tx, appId, amount),
 				AmountMsat:     amount,
 				Metadata:       datatypes.JSON(metadataBytes),
 				Boostagram:     datatypes.JSON(boostagramBytes),
@@ -483,8 +710,11 @@ func (svc *transactionsService) SendKeysend(amount uint64, destination string, c
 				SelfPayment:    selfPayment,
 			}
 			err = tx.Create(&dbTransaction).Error
+			if err != nil {
+				return err
+			}
 
-			return err
+			return recordBudgetFiatRate(tx, appId, dbTransaction.ID)
 		})
 	}()
 
@@ -496,6 +726,7 @@ func (svc *transactionsService) SendKeysend(amount uint64, destination string, c
 		return nil, err
 	}
 
+	startedAt := time.Now()
 	var payKeysendResponse *lnclient.PayKeysendResponse
 
 	if selfPayment {
@@ -527,7 +758,7 @@ func (svc *transactionsService) SendKeysend(amount uint64, destination string, c
 			}
 		}
 	} else {
-		payKeysendResponse, err = lnClient.SendKeysend(amount, destination, customRecords, preimage)
+		payKeysendResponse, err = sendKeysendWithMaxFee(ctx, lnClient, amount, destination, customRecords, preimage, getMaxRoutingFeeMsat(svc.db, appId, amount))
 	}
 
 	if err != nil {
@@ -546,9 +777,11 @@ func (svc *transactionsService) SendKeysend(amount uint64, destination string, c
 				"amount":      amount,
 			}).WithError(dbErr).Error("Failed to update DB transaction")
 		}
+		metrics.ObservePaymentDuration(constants.TRANSACTION_STATE_FAILED, time.Since(startedAt))
 
 		return nil, err
 	}
+	metrics.ObservePaymentDuration(constants.TRANSACTION_STATE_SETTLED, time.Since(startedAt))
 
 	// the payment definitely succeeded
 	var settledTransaction *db.Transaction
@@ -614,6 +847,9 @@ func (svc *transactionsService) LookupTransaction(ctx context.Context, paymentHa
 
 	if transaction.State == constants.TRANSACTION_STATE_PENDING {
 		svc.checkUnsettledTransaction(ctx, &transaction, lnClient)
+		if transaction.State == constants.TRANSACTION_STATE_PENDING && isInvoiceExpired(&transaction) {
+			svc.markInvoiceExpired(&transaction)
+		}
 	}
 
 	return &transaction, nil
@@ -923,6 +1159,7 @@ func (svc *transactionsService) markHoldInvoiceAccepted(paymentHash string, sett
 			"state":           constants.TRANSACTION_STATE_ACCEPTED,
 			"self_payment":    selfPayment,
 			"settle_deadline": settleDeadline,
+			"accepted_at":     time.Now(),
 		}).Error
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
@@ -1029,11 +1266,19 @@ func (svc *transactionsService) interceptSelfHoldPayment(paymentHash string, lnC
 }
 
 func (svc *transactionsService) validateCanPay(tx *gorm.DB, appId *uint, amount uint64, description string, selfPayment bool) error {
+	if err := svc.validatePaymentAmount(tx, appId, amount); err != nil {
+		return err
+	}
+
 	amountWithFeeReserve := amount
 	if !selfPayment {
-		amountWithFeeReserve += CalculateFeeReserveMsat(amount)
+		amountWithFeeReserve += calculateAppFeeReserveMsat(tx, appId, amount)
 	}
 
+	return svc.validateCanPayWithFeeReserve(tx, appId, amount, amountWithFeeReserve, description, selfPayment)
+}
+
+func (svc *transactionsService) validateCanPayWithFeeReserve(tx *gorm.DB, appId *uint, amount uint64, amountWithFeeReserve uint64, description string, selfPayment bool) error {
 	// ensure balance for isolated apps
 	if appId != nil {
 		var app db.App
@@ -1043,18 +1288,31 @@ func (svc *transactionsService) validateCanPay(tx *gorm.DB, appId *uint, amount
 		if result.RowsAffected == 0 {
 			return NewNotFoundError()
 		}
+		if app.ReadOnly {
+			return errors.New("app is read-only")
+		}
+		// the balance of a receive-only app can only be moved within the hub, by the hub owner
+		if app.ReceiveOnly && !selfPayment {
+			return errors.New("app is receive-only")
+		}
+		if err := validateSingleUse(tx, &app, constants.TRANSACTION_TYPE_OUTGOING); err != nil {
+			return err
+		}
+		if err := svc.validatePaymentRateLimits(tx, &app); err != nil {
+			return err
+		}
 
 		var appPermission db.AppPermission
 		result = tx.Limit(1).Find(&appPermission, &db.AppPermission{
 			AppId: *appId,
 			Scope: constants.PAY_INVOICE_SCOPE,
 		})
-		if result.RowsAffected == 0 {
+		if result.RowsAffected == 0 && !app.ReceiveOnly {
 			return errors.New("app does not have pay_invoice scope")
 		}
 
 		if app.Isolated {
-			balance := queries.GetIsolatedBalance(tx, appPermission.AppId)
+			balance := queries.GetIsolatedBalance(tx, app.ID)
 
 			if int64(amountWithFeeReserve) > balance {
 				logger.Logger.WithFields(logrus.Fields{
@@ -1080,29 +1338,75 @@ func (svc *transactionsService) validateCanPay(tx *gorm.DB, appId *uint, amount
 			}
 		}
 
-		if appPermission.MaxAmountSat > 0 {
+		if appPermission.BudgetCurrency != "" {
+			if appPermission.MaxAmountFiat > 0 {
+				if err := svc.validateFiatBudget(tx, &app, &appPermission, amountWithFeeReserve, description); err != nil {
+					return err
+				}
+			}
+		} else if appPermission.MaxAmountSat > 0 {
 			budgetUsageSat := queries.GetBudgetUsageSat(tx, &appPermission)
 			if int(amountWithFeeReserve/1000) > appPermission.MaxAmountSat-int(budgetUsageSat) {
-				message := NewQuotaExceededError().Error()
-				if description != "" {
-					message += " " + description
-				}
-				svc.eventPublisher.Publish(&events.Event{
-					Event: "nwc_permission_denied",
-					Properties: map[string]interface{}{
-						"app_name": app.Name,
-						"code":     constants.ERROR_QUOTA_EXCEEDED,
-						"message":  message,
-					},
-				})
+				svc.publishQuotaExceeded(&app, description)
 				return NewQuotaExceededError()
 			}
 		}
+
+		// additional budgets with other renewal periods all have to allow the payment
+		var appBudgets []db.AppBudget
+		if err := tx.Where("app_id = ?", app.ID).Find(&appBudgets).Error; err != nil {
+			return err
+		}
+		for _, appBudget := range appBudgets {
+			budgetUsageSat := queries.GetBudgetUsageSatForPeriod(tx, app.ID, appBudget.BudgetRenewal)
+			if amountWithFeeReserve/1000+budgetUsageSat > appBudget.MaxAmountSat {
+				logger.Logger.WithFields(logrus.Fields{
+					"app_id":         app.ID,
+					"budget_renewal": appBudget.BudgetRenewal,
+					"budget_usage":   budgetUsageSat,
+					"max_amount":     appBudget.MaxAmountSat,
+				}).Debug("Payment exceeds an additional app budget")
+				svc.publishQuotaExceeded(&app, description)
+				return NewQuotaExceededError()
+			}
+		}
+
+		if app.AppGroupId != nil {
+			var appGroup db.AppGroup
+			if tx.Limit(1).Find(&appGroup, *app.AppGroupId).RowsAffected > 0 && appGroup.MaxAmountSat > 0 {
+				budgetUsageSat := queries.GetAppGroupBudgetUsageSat(tx, &appGroup)
+				if amountWithFeeReserve/1000+budgetUsageSat > appGroup.MaxAmountSat {
+					logger.Logger.WithFields(logrus.Fields{
+						"app_id":       app.ID,
+						"app_group_id": appGroup.ID,
+						"budget_usage": budgetUsageSat,
+						"max_amount":   appGroup.MaxAmountSat,
+					}).Debug("Payment exceeds the app group budget")
+					svc.publishQuotaExceeded(&app, description)
+					return NewQuotaExceededError()
+				}
+			}
+		}
 	}
 
 	return nil
 }
 
+func (svc *transactionsService) publishQuotaExceeded(app *db.App, description string) {
+	message := NewQuotaExceededError().Error()
+	if description != "" {
+		message += " " + description
+	}
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_permission_denied",
+		Properties: map[string]interface{}{
+			"app_name": app.Name,
+			"code":     constants.ERROR_QUOTA_EXCEEDED,
+			"message":  message,
+		},
+	})
+}
+
 // max of 1% or 10000 millisats (10 sats)
 func CalculateFeeReserveMsat(amountMsat uint64) uint64 {
 	return uint64(math.Max(math.Ceil(float64(amountMsat)*0.01), 10000))
@@ -1117,57 +1421,6 @@ func makePreimageHex() ([]byte, error) {
 	return bytes, nil
 }
 
-func (svc *transactionsService) getBoostagramBytesFromCustomRecords(customRecords []lnclient.TLVRecord) []byte {
-	for _, record := range customRecords {
-		if record.Type == BoostagramTlvType {
-			bytes, err := hex.DecodeString(record.Value)
-			if err != nil {
-				logger.Logger.WithField("value", record.Value).WithError(err).Error("failed to decode boostagram tlv hex value")
-				return nil
-			}
-
-			// ensure the boostagram is valid json
-			var boostagram Boostagram
-			if err := json.Unmarshal(bytes, &boostagram); err != nil {
-				logger.Logger.WithField("value", string(bytes)).WithError(err).Error("failed to unmarshal boostagram to json")
-				return nil
-			}
-
-			return bytes
-		}
-	}
-
-	return nil
-}
-
-func (svc *transactionsService) getDescriptionFromCustomRecords(customRecords []lnclient.TLVRecord) string {
-	var description string
-
-	for _, record := range customRecords {
-		switch record.Type {
-		case BoostagramTlvType:
-			bytes, err := hex.DecodeString(record.Value)
-			if err != nil {
-				continue
-			}
-			var boostagram Boostagram
-			if err := json.Unmarshal(bytes, &boostagram); err != nil {
-				continue
-			}
-			return boostagram.Message
-
-		// TODO: consider adding support for this in LDK
-		case WhatsatTlvType:
-			bytes, err := hex.DecodeString(record.Value)
-			if err == nil {
-				description = string(bytes)
-			}
-		}
-	}
-
-	return description
-}
-
 func (svc *transactionsService) getAppIdFromCustomRecords(customRecords []lnclient.TLVRecord, tx *gorm.DB) *uint {
 	app := db.App{}
 	for _, record := range customRecords {
@@ -1246,6 +1499,11 @@ func (svc *transactionsService) SettleHoldInvoice(ctx context.Context, preimage
 		return nil, err
 	}
 
+	svc.eventPublisher.Publish(&events.Event{
+		Event:      "nwc_hold_invoice_settled",
+		Properties: settledTransaction,
+	})
+
 	return settledTransaction, nil
 }
 
@@ -1275,7 +1533,6 @@ func (svc *transactionsService) CancelHoldInvoice(ctx context.Context, paymentHa
 	}
 
 	err := svc.db.Transaction(func(tx *gorm.DB) error {
-		var dbTransaction db.Transaction
 		result := tx.Limit(1).Find(&dbTransaction, &db.Transaction{
 			Type:        constants.TRANSACTION_TYPE_INCOMING,
 			State:       constants.TRANSACTION_STATE_ACCEPTED,
@@ -1295,6 +1552,10 @@ func (svc *transactionsService) CancelHoldInvoice(ctx context.Context, paymentHa
 			return NewNotFoundError()
 		}
 
+		if err := tx.Model(&dbTransaction).Update("canceled_at", time.Now()).Error; err != nil {
+			return err
+		}
+
 		return svc.markPaymentFailed(tx, &dbTransaction, "Hold invoice was cancelled")
 	})
 
@@ -1318,15 +1579,10 @@ func (svc *transactionsService) CancelHoldInvoice(ctx context.Context, paymentHa
 }
 
 func (svc *transactionsService) SetTransactionMetadata(ctx context.Context, id uint, metadata map[string]interface{}) error {
-	var metadataBytes []byte
-	metadataBytes, err := json.Marshal(metadata)
+	metadataBytes, err := svc.encodeMetadata(metadata, "invoice")
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to serialize metadata")
 		return err
 	}
-	if len(metadataBytes) > constants.INVOICE_METADATA_MAX_LENGTH {
-		return fmt.Errorf("encoded invoice metadata provided is too large. Limit: %d Received: %d", constants.INVOICE_METADATA_MAX_LENGTH, len(metadataBytes))
-	}
 
 	err = svc.db.Model(&db.Transaction{}).Where("id", id).Update("metadata", datatypes.JSON(metadataBytes)).Error
 	if err != nil {
@@ -1362,9 +1618,11 @@ func (svc *transactionsService) markTransactionSettled(tx *gorm.DB, dbTransactio
 	}
 
 	now := time.Now()
+	// map updates do not apply the serializer of the encrypted column
+	encryptedPreimage := db.Encrypt(preimage)
 	err := tx.Model(dbTransaction).Updates(map[string]interface{}{
 		"State":          constants.TRANSACTION_STATE_SETTLED,
-		"Preimage":       &preimage,
+		"Preimage":       &encryptedPreimage,
 		"FeeMsat":        fee,
 		"FeeReserveMsat": 0,
 		"SettledAt":      &now,
@@ -1376,6 +1634,7 @@ func (svc *transactionsService) markTransactionSettled(tx *gorm.DB, dbTransactio
 		}).WithError(err).Error("Failed to update DB transaction")
 		return nil, err
 	}
+	dbTransaction.Preimage = &preimage
 
 	logger.Logger.WithFields(logrus.Fields{
 		"payment_hash": dbTransaction.PaymentHash,
@@ -1396,6 +1655,13 @@ func (svc *transactionsService) markTransactionSettled(tx *gorm.DB, dbTransactio
 		svc.checkBudgetUsage(dbTransaction, tx)
 	}
 
+	if dbTransaction.AppId != nil {
+		if err := svc.revokeSingleUseApp(tx, *dbTransaction.AppId); err != nil {
+			logger.Logger.WithError(err).WithField("app_id", *dbTransaction.AppId).Error("Failed to revoke single-use app")
+			return nil, err
+		}
+	}
+
 	return dbTransaction, nil
 }
 
@@ -1408,9 +1674,6 @@ func (svc *transactionsService) checkBudgetUsage(dbTransaction *db.Transaction,
 		logger.Logger.WithField("app_id", dbTransaction.AppId).Error("failed to find app by id")
 		return
 	}
-	if app.Isolated {
-		return
-	}
 
 	var appPermission db.AppPermission
 	result = gormTransaction.Limit(1).Find(&appPermission, &db.AppPermission{
@@ -1422,6 +1685,12 @@ func (svc *transactionsService) checkBudgetUsage(dbTransaction *db.Transaction,
 		return
 	}
 
+	svc.checkBudgetAlerts(gormTransaction, &app, &appPermission, dbTransaction)
+
+	if app.Isolated {
+		return
+	}
+
 	budgetUsage := queries.GetBudgetUsageSat(gormTransaction, &appPermission)
 	warningUsage := uint64(math.Floor(float64(appPermission.MaxAmountSat) * 0.8))
 	if budgetUsage >= warningUsage && budgetUsage-dbTransaction.AmountMsat/1000 < warningUsage {
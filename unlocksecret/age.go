@@ -0,0 +1,241 @@
+package unlocksecret
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// the subset of the age file format (https://age-encryption.org/v1) which is needed to
+// decrypt files encrypted to an X25519 recipient
+const (
+	ageVersionLine     = "age-encryption.org/v1"
+	ageX25519Label     = "age-encryption.org/v1/X25519"
+	ageIdentityHrp     = "age-secret-key-"
+	ageChunkSize       = 64 * 1024
+	ageFileKeySize     = 16
+	agePayloadNonceLen = 16
+)
+
+var ageBase64 = base64.RawStdEncoding
+
+type ageStanza struct {
+	arguments []string
+	body      []byte
+}
+
+// decryptAgeFile decrypts a file created with `age -r <recipient>` using the identity file of
+// the recipient, e.g. the output of `age-keygen`
+func decryptAgeFile(path string, identityPath string) (string, error) {
+	if path == "" || identityPath == "" {
+		return "", errors.New("UNLOCK_SECRET_FILE and UNLOCK_SECRET_AGE_IDENTITY_FILE are required")
+	}
+	identity, err := readAgeIdentity(identityPath)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := ageDecrypt(file, identity)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func readAgeIdentity(path string) (*ecdh.PrivateKey, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(file), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "AGE-SECRET-KEY-1") {
+			continue
+		}
+		hrp, data, err := bech32.Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age identity: %w", err)
+		}
+		if hrp != ageIdentityHrp {
+			return nil, fmt.Errorf("invalid age identity type %q", hrp)
+		}
+		key, err := bech32.ConvertBits(data, 5, 8, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age identity: %w", err)
+		}
+		return ecdh.X25519().NewPrivateKey(key)
+	}
+	return nil, errors.New("no age identity found")
+}
+
+func ageDecrypt(file []byte, identity *ecdh.PrivateKey) ([]byte, error) {
+	reader := bufio.NewReader(bytes.NewReader(file))
+	header := &bytes.Buffer{}
+
+	readLine := func() (string, error) {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", errors.New("invalid age header")
+		}
+		header.WriteString(line)
+		return strings.TrimSuffix(line, "\n"), nil
+	}
+
+	line, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line != ageVersionLine {
+		return nil, errors.New("unsupported age file version")
+	}
+
+	stanzas := []ageStanza{}
+	var mac []byte
+	for {
+		line, err = readLine()
+		if err != nil {
+			return nil, err
+		}
+		if macLine, found := strings.CutPrefix(line, "--- "); found {
+			// the MAC covers the header up to and including "---"
+			header.Truncate(header.Len() - len(macLine) - 2)
+			mac, err = ageBase64.DecodeString(macLine)
+			if err != nil {
+				return nil, errors.New("invalid age header MAC")
+			}
+			break
+		}
+		arguments, found := strings.CutPrefix(line, "-> ")
+		if !found {
+			return nil, errors.New("invalid age stanza")
+		}
+		stanza := ageStanza{arguments: strings.Split(arguments, " ")}
+		// the body is wrapped at 64 columns and ends with a shorter line
+		for {
+			line, err = readLine()
+			if err != nil {
+				return nil, err
+			}
+			chunk, err := ageBase64.DecodeString(line)
+			if err != nil {
+				return nil, errors.New("invalid age stanza body")
+			}
+			stanza.body = append(stanza.body, chunk...)
+			if len(line) < 64 {
+				break
+			}
+		}
+		stanzas = append(stanzas, stanza)
+	}
+
+	var fileKey []byte
+	for _, stanza := range stanzas {
+		fileKey, err = unwrapAgeX25519FileKey(stanza, identity)
+		if err == nil {
+			break
+		}
+	}
+	if fileKey == nil {
+		return nil, errors.New("the age file is not encrypted to this identity")
+	}
+
+	headerMac := hmac.New(sha256.New, ageHkdf(fileKey, nil, "header"))
+	headerMac.Write(header.Bytes())
+	if !hmac.Equal(headerMac.Sum(nil), mac) {
+		return nil, errors.New("invalid age header MAC")
+	}
+
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < agePayloadNonceLen {
+		return nil, errors.New("age payload is too short")
+	}
+	return ageDecryptPayload(ageHkdf(fileKey, payload[:agePayloadNonceLen], "payload"), payload[agePayloadNonceLen:])
+}
+
+func unwrapAgeX25519FileKey(stanza ageStanza, identity *ecdh.PrivateKey) ([]byte, error) {
+	if len(stanza.arguments) != 2 || stanza.arguments[0] != "X25519" {
+		return nil, errors.New("not an X25519 stanza")
+	}
+	share, err := ageBase64.DecodeString(stanza.arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	sharePublicKey, err := ecdh.X25519().NewPublicKey(share)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := identity.ECDH(sharePublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := append(append([]byte{}, share...), identity.PublicKey().Bytes()...)
+	aead, err := chacha20poly1305.New(ageHkdf(sharedSecret, salt, ageX25519Label))
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), stanza.body, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileKey) != ageFileKeySize {
+		return nil, errors.New("invalid file key")
+	}
+	return fileKey, nil
+}
+
+// ageDecryptPayload decrypts the STREAM of 64 KiB chunks, each with its counter as nonce
+func ageDecryptPayload(key []byte, ciphertext []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := []byte{}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	for counter := uint64(0); ; counter++ {
+		chunkLen := min(len(ciphertext), ageChunkSize+aead.Overhead())
+		last := chunkLen == len(ciphertext)
+		for i := 0; i < 8; i++ {
+			nonce[10-i] = byte(counter >> (8 * i))
+		}
+		nonce[11] = 0
+		if last {
+			nonce[11] = 1
+		}
+		chunk, err := aead.Open(nil, nonce, ciphertext[:chunkLen], nil)
+		if err != nil {
+			return nil, errors.New("failed to decrypt age payload")
+		}
+		plaintext = append(plaintext, chunk...)
+		ciphertext = ciphertext[chunkLen:]
+		if last {
+			return plaintext, nil
+		}
+	}
+}
+
+func ageHkdf(secret []byte, salt []byte, info string) []byte {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		panic(err)
+	}
+	return key
+}
@@ -0,0 +1,126 @@
+package unlocksecret
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/getAlby/hub/config"
+)
+
+// ageEncrypt encrypts to a single X25519 recipient like `age -r`
+func ageEncrypt(t *testing.T, plaintext []byte, recipient *ecdh.PublicKey) []byte {
+	fileKey := make([]byte, ageFileKeySize)
+	_, err := rand.Read(fileKey)
+	require.NoError(t, err)
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sharedSecret, err := ephemeral.ECDH(recipient)
+	require.NoError(t, err)
+	share := ephemeral.PublicKey().Bytes()
+	salt := append(append([]byte{}, share...), recipient.Bytes()...)
+	aead, err := chacha20poly1305.New(ageHkdf(sharedSecret, salt, ageX25519Label))
+	require.NoError(t, err)
+	body := ageBase64.EncodeToString(aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil))
+
+	header := &bytes.Buffer{}
+	header.WriteString(ageVersionLine + "\n")
+	header.WriteString("-> X25519 " + ageBase64.EncodeToString(share) + "\n")
+	for len(body) >= 64 {
+		header.WriteString(body[:64] + "\n")
+		body = body[64:]
+	}
+	header.WriteString(body + "\n")
+	header.WriteString("---")
+	mac := hmac.New(sha256.New, ageHkdf(fileKey, nil, "header"))
+	mac.Write(header.Bytes())
+	header.WriteString(" " + ageBase64.EncodeToString(mac.Sum(nil)) + "\n")
+
+	nonce := make([]byte, agePayloadNonceLen)
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+	payloadAead, err := chacha20poly1305.New(ageHkdf(fileKey, nonce, "payload"))
+	require.NoError(t, err)
+	header.Write(nonce)
+	chunkNonce := make([]byte, chacha20poly1305.NonceSize)
+	for counter := 0; ; counter++ {
+		chunkLen := min(len(plaintext), ageChunkSize)
+		last := chunkLen == len(plaintext)
+		chunkNonce[10] = byte(counter)
+		chunkNonce[11] = 0
+		if last {
+			chunkNonce[11] = 1
+		}
+		header.Write(payloadAead.Seal(nil, chunkNonce, plaintext[:chunkLen], nil))
+		plaintext = plaintext[chunkLen:]
+		if last {
+			return header.Bytes()
+		}
+	}
+}
+
+func writeAgeIdentity(t *testing.T, identity *ecdh.PrivateKey) string {
+	data, err := bech32.ConvertBits(identity.Bytes(), 8, 5, true)
+	require.NoError(t, err)
+	encoded, err := bech32.Encode(ageIdentityHrp, data)
+	require.NoError(t, err)
+	identityFile := filepath.Join(t.TempDir(), "identity.txt")
+	content := "# created: 2026-10-17T10:00:00Z\n# public key: age1...\n" + strings.ToUpper(encoded) + "\n"
+	require.NoError(t, os.WriteFile(identityFile, []byte(content), 0600))
+	return identityFile
+}
+
+func TestFetch_Age(t *testing.T) {
+	identity, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	identityFile := writeAgeIdentity(t, identity)
+
+	secretFile := filepath.Join(t.TempDir(), "unlock-password.age")
+	require.NoError(t, os.WriteFile(secretFile, ageEncrypt(t, []byte("age-password\n"), identity.PublicKey()), 0600))
+
+	password, err := Fetch(context.Background(), &config.AppConfig{
+		UnlockSecretSource:          SourceAge,
+		UnlockSecretFile:            secretFile,
+		UnlockSecretAgeIdentityFile: identityFile,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "age-password", password)
+
+	otherIdentity, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = Fetch(context.Background(), &config.AppConfig{
+		UnlockSecretSource:          SourceAge,
+		UnlockSecretFile:            secretFile,
+		UnlockSecretAgeIdentityFile: writeAgeIdentity(t, otherIdentity),
+	})
+	assert.EqualError(t, err, "failed to fetch unlock secret from age: the age file is not encrypted to this identity")
+}
+
+func TestAgeDecrypt_MultipleChunks(t *testing.T) {
+	identity, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	plaintext := bytes.Repeat([]byte("x"), 2*ageChunkSize+10)
+	decrypted, err := ageDecrypt(ageEncrypt(t, plaintext, identity.PublicKey()), identity)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	// a modified payload is detected
+	file := ageEncrypt(t, []byte("secret"), identity.PublicKey())
+	file[len(file)-1] ^= 1
+	_, err = ageDecrypt(file, identity)
+	assert.EqualError(t, err, "failed to decrypt age payload")
+}
@@ -0,0 +1,143 @@
+package unlocksecret
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/config"
+)
+
+// decryptWithAwsKms decrypts a ciphertext blob created with `aws kms encrypt`.
+// The blob is read from UNLOCK_SECRET_FILE, either binary or base64 encoded.
+func decryptWithAwsKms(ctx context.Context, appConfig *config.AppConfig) (string, error) {
+	if appConfig.AwsRegion == "" || appConfig.AwsAccessKeyId == "" || appConfig.AwsSecretAccessKey == "" {
+		return "", errors.New("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+	}
+	ciphertext, err := os.ReadFile(appConfig.UnlockSecretFile)
+	if err != nil {
+		return "", err
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(ciphertext))); err == nil {
+		ciphertext = decoded
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"CiphertextBlob": base64.StdEncoding.EncodeToString(ciphertext),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := appConfig.UnlockSecretAwsEndpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com/", appConfig.AwsRegion)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	signAwsRequest(req, body, appConfig, "kms", time.Now())
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("kms returned status %d: %s", res.StatusCode, string(responseBody))
+	}
+
+	var decryptResponse struct {
+		Plaintext string `json:"Plaintext"`
+	}
+	err = json.Unmarshal(responseBody, &decryptResponse)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(decryptResponse.Plaintext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// signAwsRequest adds an AWS Signature Version 4 to the request
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_sigv-create-signed-request.html
+func signAwsRequest(req *http.Request, body []byte, appConfig *config.AppConfig, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256.Sum256(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if appConfig.AwsSessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", appConfig.AwsSessionToken)
+	}
+
+	signedHeaders := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if appConfig.AwsSessionToken != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+	}
+	// headers have to be sorted by name
+	slices.Sort(signedHeaders)
+	canonicalHeaders := ""
+	for _, header := range signedHeaders {
+		canonicalHeaders += header + ":" + strings.TrimSpace(req.Header.Get(header)) + "\n"
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		strings.Join(signedHeaders, ";"),
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := strings.Join([]string{date, appConfig.AwsRegion, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+appConfig.AwsSecretAccessKey), date)
+	signingKey = hmacSha256(signingKey, appConfig.AwsRegion)
+	signingKey = hmacSha256(signingKey, service)
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		appConfig.AwsAccessKeyId, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
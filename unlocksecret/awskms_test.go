@@ -0,0 +1,70 @@
+package unlocksecret
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+)
+
+func TestFetch_AwsKms(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "TrentService.Decrypt", r.Header.Get("X-Amz-Target"))
+		assert.Regexp(t, regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/\d{8}/eu-central-1/kms/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target, Signature=[0-9a-f]{64}$`), r.Header.Get("Authorization"))
+
+		var decryptRequest map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&decryptRequest))
+		ciphertext, err := base64.StdEncoding.DecodeString(decryptRequest["CiphertextBlob"])
+		require.NoError(t, err)
+		if string(ciphertext) != "ciphertext" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"Plaintext": base64.StdEncoding.EncodeToString([]byte("kms-password")),
+		})
+	}))
+	defer server.Close()
+
+	ciphertextFile := filepath.Join(t.TempDir(), "unlock-password.enc")
+	require.NoError(t, os.WriteFile(ciphertextFile, []byte(base64.StdEncoding.EncodeToString([]byte("ciphertext"))+"\n"), 0600))
+
+	password, err := Fetch(context.Background(), &config.AppConfig{
+		UnlockSecretSource:      SourceAwsKms,
+		UnlockSecretFile:        ciphertextFile,
+		UnlockSecretAwsEndpoint: server.URL,
+		AwsRegion:               "eu-central-1",
+		AwsAccessKeyId:          "AKIDEXAMPLE",
+		AwsSecretAccessKey:      "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "kms-password", password)
+}
+
+func TestSignAwsRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://kms.us-east-1.amazonaws.com/", nil)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	appConfig := &config.AppConfig{
+		AwsRegion:          "us-east-1",
+		AwsAccessKeyId:     "AKIDEXAMPLE",
+		AwsSecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		AwsSessionToken:    "session-token",
+	}
+	signAwsRequest(req, []byte(`{}`), appConfig, "kms", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "session-token", req.Header.Get("X-Amz-Security-Token"))
+	assert.Regexp(t, regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/kms/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature=[0-9a-f]{64}$`), req.Header.Get("Authorization"))
+}
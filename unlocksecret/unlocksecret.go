@@ -0,0 +1,48 @@
+// Package unlocksecret fetches the unlock password from an external secret store, so headless
+// hubs can start unattended after a reboot without the password being stored in plaintext.
+package unlocksecret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/config"
+)
+
+const (
+	SourceVault  = "vault"
+	SourceAwsKms = "aws-kms"
+	SourceAge    = "age"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Fetch returns the unlock password from the source configured in UNLOCK_SECRET_SOURCE
+func Fetch(ctx context.Context, appConfig *config.AppConfig) (string, error) {
+	var password string
+	var err error
+	switch appConfig.UnlockSecretSource {
+	case SourceVault:
+		password, err = fetchFromVault(ctx, appConfig)
+	case SourceAwsKms:
+		password, err = decryptWithAwsKms(ctx, appConfig)
+	case SourceAge:
+		password, err = decryptAgeFile(appConfig.UnlockSecretFile, appConfig.UnlockSecretAgeIdentityFile)
+	default:
+		return "", fmt.Errorf("unsupported unlock secret source %q. Must be one of %s,%s,%s", appConfig.UnlockSecretSource, SourceVault, SourceAwsKms, SourceAge)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch unlock secret from %s: %w", appConfig.UnlockSecretSource, err)
+	}
+
+	// secrets written with echo usually end with a newline
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return "", errors.New("unlock secret is empty")
+	}
+	return password, nil
+}
@@ -0,0 +1,57 @@
+package unlocksecret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getAlby/hub/config"
+)
+
+// fetchFromVault reads the password from a KV secret of HashiCorp Vault or OpenBao.
+// Both versions of the KV secrets engine are supported.
+func fetchFromVault(ctx context.Context, appConfig *config.AppConfig) (string, error) {
+	if appConfig.VaultAddress == "" || appConfig.VaultToken == "" || appConfig.UnlockSecretVaultPath == "" {
+		return "", errors.New("VAULT_ADDR, VAULT_TOKEN and UNLOCK_SECRET_VAULT_PATH are required")
+	}
+
+	url := strings.TrimSuffix(appConfig.VaultAddress, "/") + "/v1/" + strings.TrimPrefix(appConfig.UnlockSecretVaultPath, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", appConfig.VaultToken)
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d", res.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&secret)
+	if err != nil {
+		return "", err
+	}
+
+	data := secret.Data
+	// KV version 2 nests the values together with their metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nested
+		}
+	}
+	password, ok := data[appConfig.UnlockSecretVaultField].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", appConfig.UnlockSecretVaultField)
+	}
+	return password, nil
+}
@@ -0,0 +1,57 @@
+package unlocksecret
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+)
+
+func TestFetch_Vault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/albyhub":
+			w.Write([]byte(`{"data":{"data":{"unlock_password":"kv2-password\n"},"metadata":{"version":1}}}`))
+		case "/v1/kv/albyhub":
+			w.Write([]byte(`{"data":{"password":"kv1-password"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	appConfig := &config.AppConfig{
+		UnlockSecretSource:     SourceVault,
+		VaultAddress:           server.URL,
+		VaultToken:             "vault-token",
+		UnlockSecretVaultPath:  "secret/data/albyhub",
+		UnlockSecretVaultField: "unlock_password",
+	}
+	password, err := Fetch(context.Background(), appConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "kv2-password", password)
+
+	appConfig.UnlockSecretVaultPath = "/kv/albyhub"
+	appConfig.UnlockSecretVaultField = "password"
+	password, err = Fetch(context.Background(), appConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "kv1-password", password)
+
+	appConfig.VaultToken = "wrong"
+	_, err = Fetch(context.Background(), appConfig)
+	assert.EqualError(t, err, "failed to fetch unlock secret from vault: vault returned status 403")
+}
+
+func TestFetch_UnsupportedSource(t *testing.T) {
+	_, err := Fetch(context.Background(), &config.AppConfig{UnlockSecretSource: "file"})
+	assert.EqualError(t, err, `unsupported unlock secret source "file". Must be one of vault,aws-kms,age`)
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// networkAliases name address ranges which are easier to remember than their CIDRs
+var networkAliases = map[string][]string{
+	// Tailscale assigns addresses from the CGNAT range and its own ULA prefix
+	"tailscale": {"100.64.0.0/10", "fd7a:115c:a1e0::/48"},
+	// onion services are forwarded to the hub by the local Tor daemon
+	"tor": {"127.0.0.0/8", "::1/128"},
+}
+
+// ParseNetworks parses a comma-separated list of IP addresses, CIDR ranges and
+// the aliases "tailscale" and "tor"
+func ParseNetworks(networks string) ([]*net.IPNet, error) {
+	parsedNetworks := []*net.IPNet{}
+	for _, network := range strings.Split(networks, ",") {
+		network = strings.ToLower(strings.TrimSpace(network))
+		if network == "" {
+			continue
+		}
+		parsedNetwork, err := ParseNetwork(network)
+		if err != nil {
+			return nil, err
+		}
+		parsedNetworks = append(parsedNetworks, parsedNetwork...)
+	}
+	return parsedNetworks, nil
+}
+
+// ParseNetwork parses a single IP address, CIDR range or network alias
+func ParseNetwork(network string) ([]*net.IPNet, error) {
+	if cidrs, ok := networkAliases[network]; ok {
+		parsedNetworks := []*net.IPNet{}
+		for _, cidr := range cidrs {
+			_, parsedNetwork, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, err
+			}
+			parsedNetworks = append(parsedNetworks, parsedNetwork)
+		}
+		return parsedNetworks, nil
+	}
+
+	if !strings.Contains(network, "/") {
+		if strings.Contains(network, ":") {
+			network += "/128"
+		} else {
+			network += "/32"
+		}
+	}
+	_, parsedNetwork, err := net.ParseCIDR(network)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network %q: %w", network, err)
+	}
+	return []*net.IPNet{parsedNetwork}, nil
+}
+
+// NetworksContain returns true if the IP address is part of any of the networks
+func NetworksContain(networks []*net.IPNet, ip string) bool {
+	parsedIp := net.ParseIP(ip)
+	if parsedIp == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(parsedIp) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks(" 192.0.2.1, 10.0.0.0/8,,Tailscale,2001:db8::1")
+	require.NoError(t, err)
+	assert.Len(t, networks, 5)
+
+	assert.True(t, NetworksContain(networks, "192.0.2.1"))
+	assert.False(t, NetworksContain(networks, "192.0.2.2"))
+	assert.True(t, NetworksContain(networks, "10.20.30.40"))
+	assert.True(t, NetworksContain(networks, "100.64.0.1"))
+	assert.True(t, NetworksContain(networks, "fd7a:115c:a1e0::1"))
+	assert.True(t, NetworksContain(networks, "2001:db8::1"))
+	assert.False(t, NetworksContain(networks, "invalid"))
+}
+
+func TestParseNetworks_Invalid(t *testing.T) {
+	_, err := ParseNetworks("10.0.0.0/8,example.com")
+	assert.EqualError(t, err, `invalid network "example.com/32": invalid CIDR address: example.com/32`)
+}
@@ -0,0 +1,112 @@
+package wails
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+
+	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/events"
+)
+
+var (
+	decoyPaymentRegex          = regexp.MustCompile(`^/api/payments/([0-9a-zA-Z]+)`)
+	decoyListTransactionsRegex = regexp.MustCompile(`^/api/transactions(\?|$)`)
+	decoyListParamRegex        = regexp.MustCompile(`[?&](limit|offset)=([^&]+)`)
+)
+
+// checkDuressPassword switches the app to the decoy wallet if the password is the duress password.
+// It returns false if the password is not the duress password.
+func (app *WailsApp) checkDuressPassword(password string, totpCode string) (WailsRequestRouterResponse, bool) {
+	if app.svc.GetConfig().CheckUnlockPassword(password) {
+		return WailsRequestRouterResponse{}, false
+	}
+	appId, ok := app.api.CheckDuressPassword(password)
+	if !ok {
+		return WailsRequestRouterResponse{}, false
+	}
+	// the code cannot be verified without the unlock password, but it is still asked for
+	if app.api.IsTotpEnabled() && totpCode == "" {
+		return WailsRequestRouterResponse{Body: nil, Error: "two-factor code required"}, true
+	}
+
+	app.decoyAppId.Store(uint64(appId))
+	app.svc.GetEventPublisher().Publish(&events.Event{
+		Event: "nwc_duress_unlock",
+		Properties: map[string]interface{}{
+			"app_id": appId,
+		},
+	})
+	return WailsRequestRouterResponse{Body: nil, Error: ""}, true
+}
+
+// decoyRequestRouter answers the requests made after unlocking with the duress password from the decoy
+// wallet, like the HTTP API does for duress sessions. Neither spending nor the node are unlocked.
+func (app *WailsApp) decoyRequestRouter(ctx context.Context, route string, method string, body string, appId uint) WailsRequestRouterResponse {
+	switch {
+	case route == "/api/info":
+		infoResponse, err := app.api.GetInfo(ctx)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		infoResponse.Running = true
+		infoResponse.Unlocked = true
+		return WailsRequestRouterResponse{Body: *infoResponse, Error: ""}
+	case route == "/api/balances":
+		balancesResponse, err := app.api.GetDecoyBalances(appId)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *balancesResponse, Error: ""}
+	case route == "/api/lock":
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	case route == "/api/invoices" && method == "POST":
+		makeInvoiceRequest := &api.MakeInvoiceRequest{}
+		if err := json.Unmarshal([]byte(body), makeInvoiceRequest); err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		invoice, err := app.api.CreateSubwalletInvoice(ctx, appId, makeInvoiceRequest.Amount, makeInvoiceRequest.Description)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: invoice, Error: ""}
+	case decoyPaymentRegex.MatchString(route) && method == "POST":
+		payRequest := &api.PayInvoiceRequest{}
+		if body != "" {
+			if err := json.Unmarshal([]byte(body), payRequest); err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+		}
+		invoice := decoyPaymentRegex.FindStringSubmatch(route)[1]
+		paymentResponse, err := app.api.SendSubwalletPayment(ctx, appId, invoice, payRequest.Amount, payRequest.IdempotencyKey)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: paymentResponse, Error: ""}
+	case decoyListTransactionsRegex.MatchString(route):
+		limit := uint64(20)
+		offset := uint64(0)
+		for _, match := range decoyListParamRegex.FindAllStringSubmatch(route, -1) {
+			value, err := strconv.ParseUint(match[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			if match[1] == "limit" {
+				limit = value
+			} else {
+				offset = value
+			}
+		}
+		transactions, err := app.api.ListTransactions(ctx, &appId, limit, offset, nil)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: transactions, Error: ""}
+	}
+
+	if method == "GET" {
+		return WailsRequestRouterResponse{Body: []interface{}{}, Error: ""}
+	}
+	return WailsRequestRouterResponse{Body: nil, Error: "Something went wrong, please try again later"}
+}
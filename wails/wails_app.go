@@ -3,6 +3,7 @@ package wails
 import (
 	"context"
 	"embed"
+	"sync/atomic"
 
 	"github.com/getAlby/hub/api"
 	"github.com/getAlby/hub/apps"
@@ -23,6 +24,8 @@ type WailsApp struct {
 	api     api.API
 	db      *gorm.DB
 	appsSvc apps.AppsService
+	// the decoy wallet after unlocking with the duress password, 0 otherwise
+	decoyAppId atomic.Uint64
 }
 
 func NewApp(svc service.Service) *WailsApp {
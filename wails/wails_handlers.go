@@ -1,11 +1,13 @@
 package wails
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -15,7 +17,10 @@ import (
 
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/auditlog"
+	"github.com/getAlby/hub/autolock"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/transactions"
 )
 
 type WailsRequestRouterResponse struct {
@@ -24,8 +29,40 @@ type WailsRequestRouterResponse struct {
 }
 
 // TODO: make this match echo
-func (app *WailsApp) WailsRequestRouter(route string, method string, body string) WailsRequestRouterResponse {
+// payments are already recorded as transactions and would flood the admin audit log
+var auditSkippedRoutePrefixes = []string{
+	"/api/payments/",
+	"/api/invoices",
+	"/api/offers",
+	"/api/subwallet/",
+}
+
+func (app *WailsApp) WailsRequestRouter(route string, method string, body string) (response WailsRequestRouterResponse) {
 	ctx := app.ctx
+	// the frontend polls in the background, only requests which change something postpone the lock
+	if method != "GET" {
+		autolock.RecordActivity()
+	}
+
+	if decoyAppId := app.decoyAppId.Load(); decoyAppId != 0 {
+		return app.decoyRequestRouter(ctx, route, method, body, uint(decoyAppId))
+	}
+
+	// the desktop app has a single user, who owns the hub
+	if method != "GET" && !slices.ContainsFunc(auditSkippedRoutePrefixes, func(prefix string) bool {
+		return strings.HasPrefix(route, prefix)
+	}) {
+		defer func() {
+			details := map[string]interface{}{}
+			if response.Error != "" {
+				details["error"] = response.Error
+			}
+			action := method + " " + strings.SplitN(route, "?", 2)[0]
+			if err := auditlog.Record(app.db, action, "owner", "", details); err != nil {
+				logger.Logger.WithError(err).WithField("route", route).Error("Failed to record admin action")
+			}
+		}()
+	}
 
 	// the grouping is done to avoid other parameters like &unused=true
 	albyCallbackRegex := regexp.MustCompile(
@@ -50,6 +87,295 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		return WailsRequestRouterResponse{Body: nil, Error: ""}
 	}
 
+	subwalletRegex := regexp.MustCompile(
+		`/api/v2/apps/([0-9]+)/(addresses|owner-password|lightning-address)`,
+	)
+	subwalletMatch := subwalletRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(subwalletMatch) == 3:
+		appId, err := strconv.ParseUint(subwalletMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid app ID"}
+		}
+
+		switch {
+		case subwalletMatch[2] == "addresses" && method == "GET":
+			addresses, err := app.api.ListSubwalletAddresses(uint(appId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: addresses, Error: ""}
+		case subwalletMatch[2] == "addresses" && method == "POST":
+			address, err := app.api.CreateSubwalletAddress(ctx, uint(appId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: address, Error: ""}
+		case subwalletMatch[2] == "owner-password" && method == "PATCH":
+			setOwnerPasswordRequest := &api.SetSubwalletOwnerPasswordRequest{}
+			err := json.Unmarshal([]byte(body), setOwnerPasswordRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			err = app.api.SetSubwalletOwnerPassword(uint(appId), setOwnerPasswordRequest.Password)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		case subwalletMatch[2] == "lightning-address" && method == "PATCH":
+			setAppLightningAddressRequest := &api.SetAppLightningAddressRequest{}
+			err := json.Unmarshal([]byte(body), setAppLightningAddressRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			err = app.api.SetAppLightningAddress(uint(appId), setAppLightningAddressRequest.Username)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	appRenewRegex := regexp.MustCompile(
+		`/api/v2/apps/([0-9]+)/renew`,
+	)
+	appRenewMatch := appRenewRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appRenewMatch) == 2 && method == "POST":
+		appId, err := strconv.ParseUint(appRenewMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid app ID"}
+		}
+		dbApp := app.appsSvc.GetAppById(uint(appId))
+		if dbApp == nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "App does not exist"}
+		}
+		renewAppRequest := &api.RenewAppRequest{}
+		err = json.Unmarshal([]byte(body), renewAppRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		renewedApp, err := app.api.RenewApp(dbApp, renewAppRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: renewedApp, Error: ""}
+	}
+
+	appRotateSecretRegex := regexp.MustCompile(
+		`/api/v2/apps/([0-9]+)/rotate-secret`,
+	)
+	appRotateSecretMatch := appRotateSecretRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appRotateSecretMatch) == 2 && method == "POST":
+		appId, err := strconv.ParseUint(appRotateSecretMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid app ID"}
+		}
+		dbApp := app.appsSvc.GetAppById(uint(appId))
+		if dbApp == nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "App does not exist"}
+		}
+		rotateSecretResponse, err := app.api.RotateAppSecret(dbApp)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: rotateSecretResponse, Error: ""}
+	}
+
+	appReportRegex := regexp.MustCompile(
+		`/api/v2/apps/([0-9]+)/report`,
+	)
+	appReportMatch := appReportRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appReportMatch) == 2 && method == "GET":
+		appId, err := strconv.ParseUint(appReportMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid app ID"}
+		}
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Failed to parse route URL"}
+		}
+		var from, until uint64
+		if parsedFrom, err := strconv.ParseUint(parsedUrl.Query().Get("from"), 10, 64); err == nil {
+			from = parsedFrom
+		}
+		if parsedUntil, err := strconv.ParseUint(parsedUrl.Query().Get("until"), 10, 64); err == nil {
+			until = parsedUntil
+		}
+		report, err := app.api.GetAppReport(ctx, uint(appId), from, until, parsedUrl.Query().Get("currency"))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: report, Error: ""}
+	}
+
+	appActivityRegex := regexp.MustCompile(
+		`/api/v2/apps/([0-9]+)/activity`,
+	)
+	appActivityMatch := appActivityRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appActivityMatch) == 2 && method == "GET":
+		appId, err := strconv.ParseUint(appActivityMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid app ID"}
+		}
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Failed to parse route URL"}
+		}
+		limit := uint64(20)
+		offset := uint64(0)
+		if parsedLimit, err := strconv.ParseUint(parsedUrl.Query().Get("limit"), 10, 64); err == nil {
+			limit = parsedLimit
+		}
+		if parsedOffset, err := strconv.ParseUint(parsedUrl.Query().Get("offset"), 10, 64); err == nil {
+			offset = parsedOffset
+		}
+		filters := api.ListAppActivityFilters{
+			Method: parsedUrl.Query().Get("method"),
+			Result: parsedUrl.Query().Get("result"),
+		}
+		if parsedFrom, err := strconv.ParseUint(parsedUrl.Query().Get("from"), 10, 64); err == nil {
+			filters.From = parsedFrom
+		}
+		if parsedUntil, err := strconv.ParseUint(parsedUrl.Query().Get("until"), 10, 64); err == nil {
+			filters.Until = parsedUntil
+		}
+		activity, err := app.api.ListAppActivity(uint(appId), filters, limit, offset)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: activity, Error: ""}
+	}
+
+	appDestinationsRegex := regexp.MustCompile(
+		`/api/v2/apps/([0-9]+)/destinations(?:/([0-9]+))?`,
+	)
+	appDestinationsMatch := appDestinationsRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appDestinationsMatch) == 3:
+		appId, err := strconv.ParseUint(appDestinationsMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid app ID"}
+		}
+
+		if method == "GET" {
+			appDestinations, err := app.api.ListAppDestinations(uint(appId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: appDestinations, Error: ""}
+		}
+
+		dbApp := app.appsSvc.GetAppById(uint(appId))
+		if dbApp == nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "App does not exist"}
+		}
+
+		switch {
+		case method == "POST":
+			addAppDestinationRequest := &api.AddAppDestinationRequest{}
+			err := json.Unmarshal([]byte(body), addAppDestinationRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			appDestination, err := app.api.AddAppDestination(ctx, dbApp, addAppDestinationRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: appDestination, Error: ""}
+		case method == "DELETE" && appDestinationsMatch[2] != "":
+			destinationId, err := strconv.ParseUint(appDestinationsMatch[2], 10, 64)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: "Invalid destination ID"}
+			}
+			err = app.api.RemoveAppDestination(dbApp, uint(destinationId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	appScopesRegex := regexp.MustCompile(
+		`/api/v2/apps/([0-9]+)/(scopes|audit-log)(?:/([a-z_]+))?`,
+	)
+	appScopesMatch := appScopesRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appScopesMatch) == 4:
+		appId, err := strconv.ParseUint(appScopesMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid app ID"}
+		}
+
+		if appScopesMatch[2] == "audit-log" && method == "GET" {
+			auditLogs, err := app.api.ListAppAuditLogs(uint(appId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: auditLogs, Error: ""}
+		}
+
+		dbApp := app.appsSvc.GetAppById(uint(appId))
+		if dbApp == nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "App does not exist"}
+		}
+
+		switch {
+		case appScopesMatch[2] == "scopes" && method == "POST":
+			appScopeRequest := &api.AppScopeRequest{}
+			err := json.Unmarshal([]byte(body), appScopeRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			err = app.api.AddAppScope(dbApp, appScopeRequest.Scope)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		case appScopesMatch[2] == "scopes" && method == "DELETE":
+			err := app.api.RemoveAppScope(dbApp, appScopesMatch[3])
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
 	appv2Regex := regexp.MustCompile(
 		`/api/v2/apps/([0-9a-f]+)`,
 	)
@@ -264,66 +590,196 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		return WailsRequestRouterResponse{Body: node, Error: ""}
 	}
 
-	transactionRegex := regexp.MustCompile(
-		`/api/transactions/([0-9a-fA-F]+)`,
-	)
-	paymentHashMatch := transactionRegex.FindStringSubmatch(route)
-
-	switch {
-	case len(paymentHashMatch) > 1:
-		paymentHash := paymentHashMatch[1]
-		paymentInfo, err := app.api.LookupInvoice(ctx, paymentHash)
+	// needs to be handled before the transaction lookup route as "export" would match a payment hash prefix
+	if strings.HasPrefix(route, "/api/transactions/export") {
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Failed to parse route URL"}
+		}
+		format := parsedUrl.Query().Get("format")
+		if format == "" {
+			format = transactions.EXPORT_FORMAT_CSV
+		}
+		var buffer bytes.Buffer
+		err = app.api.ExportTransactions(ctx, format, &buffer)
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-
-		return WailsRequestRouterResponse{Body: paymentInfo, Error: ""}
+		return WailsRequestRouterResponse{Body: buffer.String(), Error: ""}
 	}
 
-	listTransactionsRegex := regexp.MustCompile(
-		`/api/transactions`,
-	)
-
-	switch {
-	case listTransactionsRegex.MatchString(route):
-		limit := uint64(20)
-		offset := uint64(0)
+	if strings.HasPrefix(route, "/api/transactions/summary") {
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Failed to parse route URL"}
+		}
+		var from, until uint64
 		var appId *uint
-
-		// Extract limit and offset parameters
-		paramRegex := regexp.MustCompile(`[?&](limit|offset|appId)=([^&]+)`)
-		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
-		for _, match := range paramMatches {
-			switch match[1] {
-			case "limit":
-				if parsedLimit, err := strconv.ParseUint(match[2], 10, 64); err == nil {
-					limit = parsedLimit
-				}
-			case "offset":
-				if parsedOffset, err := strconv.ParseUint(match[2], 10, 64); err == nil {
-					offset = parsedOffset
-				}
-			case "appId":
-				if parsedAppId, err := strconv.ParseUint(match[2], 10, 64); err == nil {
-					var unsignedAppId = uint(parsedAppId)
-					appId = &unsignedAppId
-				}
-			}
+		if parsedFrom, err := strconv.ParseUint(parsedUrl.Query().Get("from"), 10, 64); err == nil {
+			from = parsedFrom
 		}
-
-		transactions, err := app.api.ListTransactions(ctx, appId, limit, offset)
+		if parsedUntil, err := strconv.ParseUint(parsedUrl.Query().Get("until"), 10, 64); err == nil {
+			until = parsedUntil
+		}
+		if parsedAppId, err := strconv.ParseUint(parsedUrl.Query().Get("appId"), 10, 64); err == nil {
+			unsignedAppId := uint(parsedAppId)
+			appId = &unsignedAppId
+		}
+		summary, err := app.api.GetTransactionsSummary(ctx, from, until, appId, parsedUrl.Query().Get("currency"))
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		return WailsRequestRouterResponse{Body: transactions, Error: ""}
+		return WailsRequestRouterResponse{Body: summary, Error: ""}
 	}
 
-	paymentRegex := regexp.MustCompile(
-		`/api/payments/([0-9a-zA-Z]+)`,
-	)
-	invoiceMatch := paymentRegex.FindStringSubmatch(route)
-
-	switch {
+	if strings.HasPrefix(route, "/api/transactions/archive") && method == "POST" {
+		archiveRequest := &api.ArchiveTransactionsRequest{}
+		err := json.Unmarshal([]byte(body), archiveRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		saveFilePath, err := runtime.SaveFileDialog(ctx, runtime.SaveDialogOptions{
+			Title:           "Save Transactions Archive",
+			DefaultFilename: "albyhub-transactions.archive",
+		})
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to open save file dialog")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		archiveFile, err := os.Create(saveFilePath)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to create transactions archive file")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		// transactions are only pruned once the archive has been written to the file
+		err = app.api.ArchiveTransactions(ctx, archiveRequest, archiveFile)
+		archiveFile.Close()
+		if err != nil {
+			os.Remove(saveFilePath)
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	}
+
+	refundTransactionRegex := regexp.MustCompile(
+		`/api/transactions/([0-9a-fA-F]+)/refund`,
+	)
+	refundPaymentHashMatch := refundTransactionRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(refundPaymentHashMatch) > 1 && method == "POST":
+		refundRequest := &api.RefundTransactionRequest{}
+		err := json.Unmarshal([]byte(body), refundRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		transaction, err := app.api.RefundTransaction(ctx, refundPaymentHashMatch[1], refundRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: transaction, Error: ""}
+	}
+
+	receiptRegex := regexp.MustCompile(
+		`/api/transactions/([0-9a-fA-F]+)/receipt`,
+	)
+	receiptPaymentHashMatch := receiptRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(receiptPaymentHashMatch) > 1 && method == "GET":
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Failed to parse route URL"}
+		}
+		receipt, err := app.api.GetTransactionReceipt(ctx, receiptPaymentHashMatch[1], parsedUrl.Query().Get("type"))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: receipt, Error: ""}
+	}
+
+	transactionRegex := regexp.MustCompile(
+		`/api/transactions/([0-9a-fA-F]+)`,
+	)
+	paymentHashMatch := transactionRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(paymentHashMatch) > 1:
+		paymentHash := paymentHashMatch[1]
+		paymentInfo, err := app.api.LookupInvoice(ctx, paymentHash)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		return WailsRequestRouterResponse{Body: paymentInfo, Error: ""}
+	}
+
+	listTransactionsRegex := regexp.MustCompile(
+		`/api/transactions`,
+	)
+
+	switch {
+	case listTransactionsRegex.MatchString(route):
+		limit := uint64(20)
+		offset := uint64(0)
+		var appId *uint
+		var currencies []string
+
+		// Extract limit and offset parameters
+		paramRegex := regexp.MustCompile(`[?&](limit|offset|appId|currency)=([^&]+)`)
+		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
+		for _, match := range paramMatches {
+			switch match[1] {
+			case "limit":
+				if parsedLimit, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					limit = parsedLimit
+				}
+			case "offset":
+				if parsedOffset, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					offset = parsedOffset
+				}
+			case "appId":
+				if parsedAppId, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					var unsignedAppId = uint(parsedAppId)
+					appId = &unsignedAppId
+				}
+			case "currency":
+				currencies = strings.Split(match[2], ",")
+			}
+		}
+
+		transactions, err := app.api.ListTransactions(ctx, appId, limit, offset, currencies)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: transactions, Error: ""}
+	}
+
+	paymentRegex := regexp.MustCompile(
+		`/api/payments/([0-9a-zA-Z]+)`,
+	)
+	invoiceMatch := paymentRegex.FindStringSubmatch(route)
+
+	switch {
 	case len(invoiceMatch) > 1:
 		invoice := invoiceMatch[1]
 		payRequest := &api.PayInvoiceRequest{}
@@ -338,7 +794,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 			}
 		}
-		paymentResponse, err := app.api.SendPayment(ctx, invoice, payRequest.Amount, payRequest.Metadata)
+		paymentResponse, err := app.api.SendPayment(ctx, invoice, payRequest.Amount, payRequest.Metadata, payRequest.IdempotencyKey)
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
@@ -358,11 +814,11 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			}).WithError(err).Error("Failed to decode request to wails router")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		err = app.api.Transfer(ctx, transferRequest.FromAppId, transferRequest.ToAppId, transferRequest.AmountSat*1000)
+		transferResponse, err := app.api.Transfer(ctx, transferRequest.FromAppId, transferRequest.ToAppId, transferRequest.AmountSat*1000)
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		return WailsRequestRouterResponse{Body: nil, Error: ""}
+		return WailsRequestRouterResponse{Body: transferResponse, Error: ""}
 	case "/api/alby/info":
 		info, err := app.svc.GetAlbySvc().GetInfo(ctx)
 		if err != nil {
@@ -503,6 +959,22 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: rebalanceChannelResponse, Error: ""}
+	case "/api/channels/rebalance/circular":
+		circularRebalanceRequest := &api.CircularRebalanceRequest{}
+		err := json.Unmarshal([]byte(body), circularRebalanceRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		circularRebalanceResponse, err := app.api.RebalanceCircular(ctx, circularRebalanceRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: circularRebalanceResponse, Error: ""}
 	case "/api/balances":
 		balancesResponse, err := app.api.GetBalances(ctx)
 		if err != nil {
@@ -549,11 +1021,19 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
+				// Skip logging the body for this request as we don't want the
+				// unlock password to end up in any logs
+				// "body": body,
 			}).WithError(err).Error("Failed to decode request to wails router")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
+		if redeemOnchainFundsRequest.SendAll || redeemOnchainFundsRequest.Amount >= uint64(app.svc.GetConfig().GetEnv().TotpOnchainThresholdSat) {
+			if err := app.api.VerifyTotp(redeemOnchainFundsRequest.UnlockPassword, redeemOnchainFundsRequest.TotpCode); err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+		}
+
 		redeemOnchainFundsResponse, err := app.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, redeemOnchainFundsRequest.Amount, redeemOnchainFundsRequest.FeeRate, redeemOnchainFundsRequest.SendAll)
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
@@ -577,6 +1057,13 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		}
 		return WailsRequestRouterResponse{Body: *capabilitiesResponse, Error: ""}
 	case "/api/lsp-orders":
+		if method == "GET" {
+			orders, err := app.api.ListLSPOrders()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: orders, Error: ""}
+		}
 		newInstantChannelRequest := &api.LSPOrderRequest{}
 		err := json.Unmarshal([]byte(body), newInstantChannelRequest)
 		if err != nil {
@@ -648,10 +1135,15 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
+				// Skip logging the body for this request as we don't want the
+				// unlock password to end up in any logs
+				// "body": body,
 			}).WithError(err).Error("Failed to decode request to wails router")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
+		if err := app.api.VerifyTotp(migrateNodeStorageRequest.UnlockPassword, migrateNodeStorageRequest.TotpCode); err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
 		err = app.api.MigrateNodeStorage(ctx, migrateNodeStorageRequest.To)
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
@@ -704,7 +1196,10 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			}).WithError(err).Error("Failed to parse mnemonic request")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		mnemonicResponse, err := app.api.GetMnemonic(mnemonicRequest.UnlockPassword)
+		if err := app.api.VerifyTotp(mnemonicRequest.UnlockPassword, mnemonicRequest.TotpCode); err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		mnemonicResponse, err := app.api.GetMnemonic(mnemonicRequest.UnlockPassword, mnemonicRequest.SeedPassphrase)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
@@ -717,6 +1212,93 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		}
 		res := WailsRequestRouterResponse{Body: *mnemonicResponse, Error: ""}
 		return res
+	case "/api/seed-passphrase":
+		setSeedPassphraseRequest := &api.SetSeedPassphraseRequest{}
+		err := json.Unmarshal([]byte(body), setSeedPassphraseRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.SetSeedPassphrase(setSeedPassphraseRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	case "/api/lock":
+		app.api.LockSpending()
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	case "/api/unlock":
+		unlockRequest := &api.UnlockRequest{}
+		err := json.Unmarshal([]byte(body), unlockRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				// Skip logging the body for this request as we don't want the
+				// unlock password to end up in any logs
+				// "body": body,
+			}).WithError(err).Error("Failed to parse unlock request")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		if duressResponse, ok := app.checkDuressPassword(unlockRequest.UnlockPassword, unlockRequest.TotpCode); ok {
+			return duressResponse
+		}
+		if err := app.api.VerifyTotp(unlockRequest.UnlockPassword, unlockRequest.TotpCode); err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.UnlockSpending(unlockRequest.UnlockPassword)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	case "/api/totp/setup":
+		setupTotpRequest := &api.SetupTotpRequest{}
+		err := json.Unmarshal([]byte(body), setupTotpRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		setupTotpResponse, err := app.api.SetupTotp(setupTotpRequest.UnlockPassword)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *setupTotpResponse, Error: ""}
+	case "/api/totp/enable":
+		enableTotpRequest := &api.EnableTotpRequest{}
+		err := json.Unmarshal([]byte(body), enableTotpRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		enableTotpResponse, err := app.api.EnableTotp(enableTotpRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *enableTotpResponse, Error: ""}
+	case "/api/totp/disable":
+		disableTotpRequest := &api.DisableTotpRequest{}
+		err := json.Unmarshal([]byte(body), disableTotpRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.DisableTotp(disableTotpRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
 	case "/api/backup-reminder":
 		backupReminderRequest := &api.BackupReminderRequest{}
 		err := json.Unmarshal([]byte(body), backupReminderRequest)
@@ -817,6 +1399,14 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
+		// the node is not started with the duress password, the decoy wallet reports it as running
+		if duressResponse, ok := app.checkDuressPassword(startRequest.UnlockPassword, startRequest.TotpCode); ok {
+			return duressResponse
+		}
+		if err := app.api.VerifyTotp(startRequest.UnlockPassword, startRequest.TotpCode); err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
 		go app.api.Start(startRequest)
 
 		return WailsRequestRouterResponse{Body: nil, Error: ""}
@@ -894,6 +1484,9 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			}).WithError(err).Error("Failed to decode request to wails router")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
+		if err := app.api.VerifyTotp(backupRequest.UnlockPassword, backupRequest.TotpCode); err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
 
 		saveFilePath, err := runtime.SaveFileDialog(ctx, runtime.SaveDialogOptions{
 			Title:           "Save Backup File",
@@ -920,7 +1513,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 
 		defer backupFile.Close()
 
-		err = app.api.CreateBackup(backupRequest.UnlockPassword, backupFile)
+		err = app.api.CreateBackup(backupRequest.UnlockPassword, backupRequest.SeedPassphrase, backupFile)
 
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
@@ -931,97 +1524,183 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: nil, Error: ""}
-	case "/api/restore":
-		restoreRequest := &api.BasicRestoreWailsRequest{}
-		err := json.Unmarshal([]byte(body), restoreRequest)
+	case "/api/app-configs/export":
+		exportAppsRequest := &api.ExportAppsRequest{}
+		err := json.Unmarshal([]byte(body), exportAppsRequest)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
 			}).WithError(err).Error("Failed to decode request to wails router")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
-		backupFilePath, err := runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
-			Title:           "Select Backup File",
-			DefaultFilename: "albyhub.bkp",
+		saveFilePath, err := runtime.SaveFileDialog(ctx, runtime.SaveDialogOptions{
+			Title:           "Save App Connections Export",
+			DefaultFilename: "albyhub-apps.export",
 		})
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
 			}).WithError(err).Error("Failed to open save file dialog")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
-		backupFile, err := os.Open(backupFilePath)
+		exportFile, err := os.Create(saveFilePath)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
-			}).WithError(err).Error("Failed to open backup file")
+			}).WithError(err).Error("Failed to create export file")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
-		defer backupFile.Close()
+		defer exportFile.Close()
 
-		err = app.api.RestoreBackup(restoreRequest.UnlockPassword, backupFile)
+		err = app.api.ExportApps(exportAppsRequest, exportFile)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
-			}).WithError(err).Error("Failed to restore backup")
+			}).WithError(err).Error("Failed to export apps")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: nil, Error: ""}
-	case "/api/health":
-		nodeHealth, err := app.api.Health(ctx)
+	case "/api/app-configs/import":
+		importAppsRequest := &api.ImportAppsRequest{}
+		err := json.Unmarshal([]byte(body), importAppsRequest)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
-			}).WithError(err).Error("Failed to check node health")
+			}).WithError(err).Error("Failed to decode request to wails router")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		return WailsRequestRouterResponse{Body: *nodeHealth, Error: ""}
-	case "/api/offers":
-		makeOfferRequest := &api.MakeOfferRequest{}
-		err := json.Unmarshal([]byte(body), makeOfferRequest)
+
+		exportFilePath, err := runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
+			Title:           "Select App Connections Export",
+			DefaultFilename: "albyhub-apps.export",
+		})
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
-			}).WithError(err).Error("Failed to decode request to wails router")
+			}).WithError(err).Error("Failed to open file dialog")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		offer, err := app.api.MakeOffer(ctx, makeOfferRequest.Description)
+
+		exportFile, err := os.Open(exportFilePath)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
-			}).WithError(err).Error("Failed to generate BOLT-12 offer")
+			}).WithError(err).Error("Failed to open export file")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		return WailsRequestRouterResponse{Body: offer, Error: ""}
-	case "/api/commands":
-		nodeCommandsResponse, err := app.api.GetCustomNodeCommands()
+
+		defer exportFile.Close()
+
+		importAppsResponse, err := app.api.ImportApps(importAppsRequest, exportFile)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
 				"method": method,
-				"body":   body,
-			}).WithError(err).Error("Failed to get node commands")
+			}).WithError(err).Error("Failed to import apps")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		return WailsRequestRouterResponse{Body: nodeCommandsResponse, Error: ""}
-	case "/api/command":
+		return WailsRequestRouterResponse{Body: importAppsResponse, Error: ""}
+	case "/api/restore":
+		restoreRequest := &api.BasicRestoreWailsRequest{}
+		err := json.Unmarshal([]byte(body), restoreRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		backupFilePath, err := runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
+			Title:           "Select Backup File",
+			DefaultFilename: "albyhub.bkp",
+		})
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to open save file dialog")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		backupFile, err := os.Open(backupFilePath)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to open backup file")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		defer backupFile.Close()
+
+		err = app.api.RestoreBackup(restoreRequest.UnlockPassword, restoreRequest.SeedPassphrase, backupFile)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to restore backup")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	case "/api/health":
+		nodeHealth, err := app.api.Health(ctx)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to check node health")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *nodeHealth, Error: ""}
+	case "/api/offers":
+		makeOfferRequest := &api.MakeOfferRequest{}
+		err := json.Unmarshal([]byte(body), makeOfferRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		offer, err := app.api.MakeOffer(ctx, makeOfferRequest.Description)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to generate BOLT-12 offer")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: offer, Error: ""}
+	case "/api/commands":
+		nodeCommandsResponse, err := app.api.GetCustomNodeCommands()
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to get node commands")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nodeCommandsResponse, Error: ""}
+	case "/api/command":
 		commandRequest := &api.ExecuteCustomNodeCommandRequest{}
 		err := json.Unmarshal([]byte(body), commandRequest)
 		if err != nil {
@@ -1216,6 +1895,26 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 
 			return WailsRequestRouterResponse{Body: nil, Error: ""}
 		}
+	case "/api/v2/apps/bulk":
+		switch method {
+		case "POST":
+			bulkUpdateAppsRequest := &api.BulkUpdateAppsRequest{}
+			err := json.Unmarshal([]byte(body), bulkUpdateAppsRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+
+			bulkUpdateAppsResponse, err := app.api.BulkUpdateApps(bulkUpdateAppsRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: bulkUpdateAppsResponse, Error: ""}
+		}
 	case "/api/lightning-addresses":
 		switch method {
 		case "POST":
@@ -1242,6 +1941,850 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: forwards, Error: ""}
+	case "/api/webhooks":
+		switch method {
+		case "GET":
+			webhooks, err := app.api.ListWebhooks()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: webhooks, Error: ""}
+		case "POST":
+			createWebhookRequest := &api.CreateWebhookRequest{}
+			err := json.Unmarshal([]byte(body), createWebhookRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			webhook, err := app.api.CreateWebhook(createWebhookRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: webhook, Error: ""}
+		}
+	case "/api/webhooks/event-types":
+		return WailsRequestRouterResponse{Body: app.api.ListWebhookEventTypes(), Error: ""}
+	case "/api/app-templates":
+		return WailsRequestRouterResponse{Body: app.api.ListAppTemplates(), Error: ""}
+	case "/api/app-groups":
+		switch method {
+		case "GET":
+			appGroups, err := app.api.ListAppGroups()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: appGroups, Error: ""}
+		case "POST":
+			createAppGroupRequest := &api.CreateAppGroupRequest{}
+			err := json.Unmarshal([]byte(body), createAppGroupRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			appGroup, err := app.api.CreateAppGroup(createAppGroupRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: appGroup, Error: ""}
+		}
+	case "/api/admin-users":
+		switch method {
+		case "GET":
+			adminUsers, err := app.api.ListAdminUsers()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: adminUsers, Error: ""}
+		case "POST":
+			createAdminUserRequest := &api.CreateAdminUserRequest{}
+			err := json.Unmarshal([]byte(body), createAdminUserRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			adminUser, err := app.api.CreateAdminUser(createAdminUserRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: adminUser, Error: ""}
+		}
+	case "/api/api-keys":
+		switch method {
+		case "GET":
+			apiKeys, err := app.api.ListApiKeys()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: apiKeys, Error: ""}
+		case "POST":
+			createApiKeyRequest := &api.CreateApiKeyRequest{}
+			err := json.Unmarshal([]byte(body), createApiKeyRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			createApiKeyResponse, err := app.api.CreateApiKey(createApiKeyRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: createApiKeyResponse, Error: ""}
+		}
+	case "/api/scheduled-payments":
+		switch method {
+		case "GET":
+			scheduledPayments, err := app.api.ListScheduledPayments()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: scheduledPayments, Error: ""}
+		case "POST":
+			createScheduledPaymentRequest := &api.CreateScheduledPaymentRequest{}
+			err := json.Unmarshal([]byte(body), createScheduledPaymentRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			scheduledPayment, err := app.api.CreateScheduledPayment(createScheduledPaymentRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: scheduledPayment, Error: ""}
+		}
+	case "/api/swap-rules":
+		switch method {
+		case "GET":
+			swapRules, err := app.api.ListSwapRules()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: swapRules, Error: ""}
+		case "POST":
+			createSwapRuleRequest := &api.CreateSwapRuleRequest{}
+			err := json.Unmarshal([]byte(body), createSwapRuleRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			swapRule, err := app.api.CreateSwapRule(createSwapRuleRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: swapRule, Error: ""}
+		}
+	case "/api/fee-policies":
+		switch method {
+		case "GET":
+			feePolicies, err := app.api.ListFeePolicies()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: feePolicies, Error: ""}
+		case "POST":
+			createFeePolicyRequest := &api.CreateFeePolicyRequest{}
+			err := json.Unmarshal([]byte(body), createFeePolicyRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			feePolicy, err := app.api.CreateFeePolicy(createFeePolicyRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: feePolicy, Error: ""}
+		}
+	}
+
+	switch route {
+	case "/api/backup-targets":
+		switch method {
+		case "GET":
+			backupTargets, err := app.api.ListBackupTargets()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: backupTargets, Error: ""}
+		case "POST":
+			createBackupTargetRequest := &api.CreateBackupTargetRequest{}
+			err := json.Unmarshal([]byte(body), createBackupTargetRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			backupTarget, err := app.api.CreateBackupTarget(createBackupTargetRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: backupTarget, Error: ""}
+		}
+	}
+
+	if route == "/api/nostr-backup" {
+		switch method {
+		case "GET":
+			nostrBackup, err := app.api.GetNostrBackup(ctx)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nostrBackup, Error: ""}
+		case "POST":
+			nostrBackup, err := app.api.PublishNostrBackup(ctx)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nostrBackup, Error: ""}
+		}
+	}
+
+	if route == "/api/recovery" {
+		switch method {
+		case "GET":
+			recovery, err := app.api.GetRecovery()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: recovery, Error: ""}
+		case "POST":
+			startRecoveryRequest := &api.StartRecoveryRequest{}
+			err := json.Unmarshal([]byte(body), startRecoveryRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			recovery, err := app.api.StartRecovery(ctx, startRecoveryRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: recovery, Error: ""}
+		case "DELETE":
+			if err := app.api.DeleteRecovery(); err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	if route == "/api/database/maintenance" {
+		switch method {
+		case "GET":
+			return WailsRequestRouterResponse{Body: app.api.GetDatabaseMaintenanceStatus(), Error: ""}
+		case "POST":
+			runDatabaseMaintenanceRequest := &api.RunDatabaseMaintenanceRequest{}
+			err := json.Unmarshal([]byte(body), runDatabaseMaintenanceRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			report, err := app.api.RunDatabaseMaintenance(ctx, runDatabaseMaintenanceRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: report, Error: ""}
+		}
+	}
+
+	if route == "/api/admin-audit-log/export" {
+		var buffer bytes.Buffer
+		err := app.api.ExportAdminAuditLog(&buffer)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: buffer.String(), Error: ""}
+	}
+
+	if route == "/api/admin-audit-log/verify" {
+		verification, err := app.api.VerifyAdminAuditLog()
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: verification, Error: ""}
+	}
+
+	if route == "/api/duress" {
+		switch method {
+		case "GET":
+			duressSettings, err := app.api.GetDuressSettings()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: duressSettings, Error: ""}
+		case "POST":
+			setDuressPasswordRequest := &api.SetDuressPasswordRequest{}
+			err := json.Unmarshal([]byte(body), setDuressPasswordRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			duressSettings, err := app.api.SetDuressPassword(setDuressPasswordRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: duressSettings, Error: ""}
+		}
+	}
+
+	if route == "/api/duress/disable" && method == "POST" {
+		disableDuressPasswordRequest := &api.DisableDuressPasswordRequest{}
+		err := json.Unmarshal([]byte(body), disableDuressPasswordRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.DisableDuressPassword(disableDuressPasswordRequest.UnlockPassword)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	}
+
+	if route == "/api/email-notifications" {
+		switch method {
+		case "GET":
+			settings, err := app.api.GetEmailNotificationSettings()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: settings, Error: ""}
+		case "PUT":
+			updateEmailNotificationsRequest := &api.UpdateEmailNotificationsRequest{}
+			err := json.Unmarshal([]byte(body), updateEmailNotificationsRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			settings, err := app.api.UpdateEmailNotificationSettings(updateEmailNotificationsRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: settings, Error: ""}
+		}
+	}
+
+	if route == "/api/email-notifications/test" && method == "POST" {
+		err := app.api.SendTestEmail()
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	}
+
+	if route == "/api/database/key-rotation" {
+		switch method {
+		case "GET":
+			keyRotation, err := app.api.GetDatabaseKeyRotation()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: keyRotation, Error: ""}
+		case "POST":
+			rotateDatabaseEncryptionKeyRequest := &api.RotateDatabaseEncryptionKeyRequest{}
+			err := json.Unmarshal([]byte(body), rotateDatabaseEncryptionKeyRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			err = app.api.RotateDatabaseEncryptionKey(rotateDatabaseEncryptionKeyRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	if route == "/api/database/migrations" {
+		switch method {
+		case "GET":
+			databaseMigrations, err := app.api.GetDatabaseMigrations()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: databaseMigrations, Error: ""}
+		}
+	}
+
+	if route == "/api/push/devices" {
+		switch method {
+		case "GET":
+			pushDevices, err := app.api.ListPushDevices()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: pushDevices, Error: ""}
+		case "POST":
+			registerPushDeviceRequest := &api.RegisterPushDeviceRequest{}
+			err := json.Unmarshal([]byte(body), registerPushDeviceRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			pushDevice, err := app.api.RegisterPushDevice(registerPushDeviceRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: pushDevice, Error: ""}
+		}
+	}
+
+	if strings.HasPrefix(route, "/api/dead-letters") {
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Failed to parse route URL"}
+		}
+		consumer := parsedUrl.Query().Get("consumer")
+
+		switch {
+		case parsedUrl.Path == "/api/dead-letters" && method == "GET":
+			deadLetters, err := app.api.ListDeadLetters(consumer, parsedUrl.Query().Get("state"))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: deadLetters, Error: ""}
+		case parsedUrl.Path == "/api/dead-letters/replay" && method == "POST":
+			result, err := app.api.ReplayDeadLetters(ctx, consumer)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: result, Error: ""}
+		}
+
+		deadLetterMatch := regexp.MustCompile(`^/api/dead-letters/([0-9]+)(/replay)?$`).FindStringSubmatch(parsedUrl.Path)
+		if len(deadLetterMatch) == 3 {
+			deadLetterId, err := strconv.ParseUint(deadLetterMatch[1], 10, 64)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: "Invalid dead letter ID"}
+			}
+			switch {
+			case deadLetterMatch[2] == "/replay" && method == "POST":
+				deadLetter, err := app.api.ReplayDeadLetter(ctx, uint(deadLetterId))
+				if err != nil {
+					return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+				}
+				return WailsRequestRouterResponse{Body: deadLetter, Error: ""}
+			case deadLetterMatch[2] == "" && method == "DELETE":
+				err := app.api.DiscardDeadLetter(uint(deadLetterId))
+				if err != nil {
+					return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+				}
+				return WailsRequestRouterResponse{Body: nil, Error: ""}
+			}
+		}
+	}
+
+	pushDeviceRegex := regexp.MustCompile(
+		`^/api/push/devices/([0-9]+)(/test)?$`,
+	)
+	pushDeviceMatch := pushDeviceRegex.FindStringSubmatch(route)
+
+	if len(pushDeviceMatch) == 3 {
+		pushDeviceId, err := strconv.ParseUint(pushDeviceMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid push device ID"}
+		}
+		switch {
+		case pushDeviceMatch[2] == "/test" && method == "POST":
+			err := app.api.SendTestPushNotification(ctx, uint(pushDeviceId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		case pushDeviceMatch[2] == "" && method == "DELETE":
+			err := app.api.RevokePushDevice(uint(pushDeviceId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	backupTargetRegex := regexp.MustCompile(
+		`^/api/backup-targets/([0-9]+)(/snapshots|/backup)?$`,
+	)
+	backupTargetMatch := backupTargetRegex.FindStringSubmatch(route)
+
+	if len(backupTargetMatch) == 3 {
+		backupTargetId, err := strconv.ParseUint(backupTargetMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid backup target ID"}
+		}
+		switch {
+		case backupTargetMatch[2] == "/snapshots" && method == "GET":
+			snapshots, err := app.api.ListBackupSnapshots(ctx, uint(backupTargetId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: snapshots, Error: ""}
+		case backupTargetMatch[2] == "/backup" && method == "POST":
+			runBackupRequest := &api.RunBackupRequest{}
+			err := json.Unmarshal([]byte(body), runBackupRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			snapshot, err := app.api.RunBackup(ctx, uint(backupTargetId), runBackupRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: snapshot, Error: ""}
+		case backupTargetMatch[2] == "" && method == "PATCH":
+			updateBackupTargetRequest := &api.UpdateBackupTargetRequest{}
+			err := json.Unmarshal([]byte(body), updateBackupTargetRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			backupTarget, err := app.api.UpdateBackupTarget(uint(backupTargetId), updateBackupTargetRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: backupTarget, Error: ""}
+		case backupTargetMatch[2] == "" && method == "DELETE":
+			err := app.api.DeleteBackupTarget(uint(backupTargetId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	if strings.HasPrefix(route, "/api/payment-approvals") && method == "GET" {
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Failed to parse route URL"}
+		}
+		paymentApprovals, err := app.api.ListPaymentApprovals(parsedUrl.Query().Get("state"))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: paymentApprovals, Error: ""}
+	}
+
+	paymentApprovalRegex := regexp.MustCompile(
+		`/api/payment-approvals/([0-9]+)/(approve|reject)`,
+	)
+	paymentApprovalMatch := paymentApprovalRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(paymentApprovalMatch) == 3 && method == "POST":
+		paymentApprovalId, err := strconv.ParseUint(paymentApprovalMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid payment approval ID"}
+		}
+		err = app.api.DecidePaymentApproval(uint(paymentApprovalId), paymentApprovalMatch[2] == "approve")
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	}
+
+	appGroupRegex := regexp.MustCompile(
+		`/api/app-groups/([0-9]+)`,
+	)
+	appGroupMatch := appGroupRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appGroupMatch) == 2:
+		appGroupId, err := strconv.ParseUint(appGroupMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid app group ID"}
+		}
+
+		switch method {
+		case "PATCH":
+			updateAppGroupRequest := &api.UpdateAppGroupRequest{}
+			err := json.Unmarshal([]byte(body), updateAppGroupRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			appGroup, err := app.api.UpdateAppGroup(uint(appGroupId), updateAppGroupRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: appGroup, Error: ""}
+		case "DELETE":
+			err := app.api.DeleteAppGroup(uint(appGroupId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	adminUserRegex := regexp.MustCompile(
+		`/api/admin-users/([0-9]+)`,
+	)
+	adminUserMatch := adminUserRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(adminUserMatch) == 2:
+		adminUserId, err := strconv.ParseUint(adminUserMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid admin user ID"}
+		}
+
+		switch method {
+		case "PATCH":
+			updateAdminUserRequest := &api.UpdateAdminUserRequest{}
+			err := json.Unmarshal([]byte(body), updateAdminUserRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			adminUser, err := app.api.UpdateAdminUser(uint(adminUserId), updateAdminUserRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: adminUser, Error: ""}
+		case "DELETE":
+			err := app.api.DeleteAdminUser(uint(adminUserId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	apiKeyRegex := regexp.MustCompile(
+		`/api/api-keys/([0-9]+)`,
+	)
+	apiKeyMatch := apiKeyRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(apiKeyMatch) == 2 && method == "DELETE":
+		apiKeyId, err := strconv.ParseUint(apiKeyMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid api key ID"}
+		}
+		err = app.api.RevokeApiKey(uint(apiKeyId))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	}
+
+	scheduledPaymentRegex := regexp.MustCompile(
+		`/api/scheduled-payments/([0-9]+)`,
+	)
+	scheduledPaymentMatch := scheduledPaymentRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(scheduledPaymentMatch) == 2 && method == "DELETE":
+		scheduledPaymentId, err := strconv.ParseUint(scheduledPaymentMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid scheduled payment ID"}
+		}
+		err = app.api.DeleteScheduledPayment(uint(scheduledPaymentId))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	}
+
+	swapRuleRegex := regexp.MustCompile(
+		`/api/swap-rules/([0-9]+)(/runs)?`,
+	)
+	swapRuleMatch := swapRuleRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(swapRuleMatch) == 3:
+		swapRuleId, err := strconv.ParseUint(swapRuleMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid swap rule ID"}
+		}
+
+		if swapRuleMatch[2] != "" {
+			swapRuleRuns, err := app.api.ListSwapRuleRuns(uint(swapRuleId), 20)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: swapRuleRuns, Error: ""}
+		}
+
+		switch method {
+		case "PATCH":
+			updateSwapRuleRequest := &api.UpdateSwapRuleRequest{}
+			err := json.Unmarshal([]byte(body), updateSwapRuleRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			swapRule, err := app.api.UpdateSwapRule(uint(swapRuleId), updateSwapRuleRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: swapRule, Error: ""}
+		case "DELETE":
+			err := app.api.DeleteSwapRule(uint(swapRuleId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	feePolicyRegex := regexp.MustCompile(
+		`/api/fee-policies/([0-9]+)`,
+	)
+	feePolicyMatch := feePolicyRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(feePolicyMatch) == 2:
+		feePolicyId, err := strconv.ParseUint(feePolicyMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid fee policy ID"}
+		}
+
+		switch method {
+		case "PATCH":
+			updateFeePolicyRequest := &api.UpdateFeePolicyRequest{}
+			err := json.Unmarshal([]byte(body), updateFeePolicyRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			feePolicy, err := app.api.UpdateFeePolicy(uint(feePolicyId), updateFeePolicyRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: feePolicy, Error: ""}
+		case "DELETE":
+			err := app.api.DeleteFeePolicy(uint(feePolicyId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	liquidityReportRegex := regexp.MustCompile(
+		`/api/liquidity/report`,
+	)
+	liquidityReportMatch := liquidityReportRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(liquidityReportMatch) == 1 && method == "GET":
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Failed to parse route URL"}
+		}
+		var from, until uint64
+		if parsedFrom, err := strconv.ParseUint(parsedUrl.Query().Get("from"), 10, 64); err == nil {
+			from = parsedFrom
+		}
+		if parsedUntil, err := strconv.ParseUint(parsedUrl.Query().Get("until"), 10, 64); err == nil {
+			until = parsedUntil
+		}
+		report, err := app.api.GetLiquidityReport(from, until)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: report, Error: ""}
+	}
+
+	webhookRegex := regexp.MustCompile(
+		`/api/webhooks/([0-9]+)(/deliveries)?(?:/([0-9]+)/retry)?`,
+	)
+	webhookMatch := webhookRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(webhookMatch) == 4:
+		webhookId, err := strconv.ParseUint(webhookMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid webhook ID"}
+		}
+
+		if webhookMatch[3] != "" {
+			deliveryId, err := strconv.ParseUint(webhookMatch[3], 10, 64)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: "Invalid delivery ID"}
+			}
+			delivery, err := app.api.RetryWebhookDelivery(ctx, uint(webhookId), uint(deliveryId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: delivery, Error: ""}
+		}
+
+		if webhookMatch[2] != "" {
+			deliveries, err := app.api.ListWebhookDeliveries(uint(webhookId), "", 20)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: deliveries, Error: ""}
+		}
+
+		switch method {
+		case "PATCH":
+			updateWebhookRequest := &api.UpdateWebhookRequest{}
+			err := json.Unmarshal([]byte(body), updateWebhookRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			webhook, err := app.api.UpdateWebhook(uint(webhookId), updateWebhookRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: webhook, Error: ""}
+		case "DELETE":
+			err := app.api.DeleteWebhook(uint(webhookId))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
 	}
 
 	lightningAddressRegex := regexp.MustCompile(
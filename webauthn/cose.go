@@ -0,0 +1,131 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE algorithms supported for credentials, in order of preference
+const (
+	AlgorithmES256 = -7
+	AlgorithmEdDSA = -8
+	AlgorithmRS256 = -257
+)
+
+var SupportedAlgorithms = []int{AlgorithmES256, AlgorithmEdDSA, AlgorithmRS256}
+
+// COSE key parameters (RFC 9053)
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1
+	coseX         = -2
+	coseY         = -3
+	coseRsaN      = -1
+	coseRsaE      = -2
+
+	coseKeyTypeOkp = 1
+	coseKeyTypeEc2 = 2
+	coseKeyTypeRsa = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+type publicKey struct {
+	algorithm int
+	key       crypto.PublicKey
+}
+
+func parsePublicKey(coseKey []byte) (*publicKey, error) {
+	var params map[int]cbor.RawMessage
+	if err := cbor.Unmarshal(coseKey, &params); err != nil {
+		return nil, fmt.Errorf("invalid COSE key: %w", err)
+	}
+	var keyType, algorithm int
+	if err := unmarshalParam(params, coseKeyType, &keyType); err != nil {
+		return nil, err
+	}
+	if err := unmarshalParam(params, coseAlgorithm, &algorithm); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case keyType == coseKeyTypeEc2 && algorithm == AlgorithmES256:
+		var curve int
+		var x, y []byte
+		if err := unmarshalParams(params, map[int]any{coseCurve: &curve, coseX: &x, coseY: &y}); err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if curve != coseCurveP256 || !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid P-256 public key")
+		}
+		return &publicKey{algorithm: algorithm, key: key}, nil
+	case keyType == coseKeyTypeOkp && algorithm == AlgorithmEdDSA:
+		var curve int
+		var x []byte
+		if err := unmarshalParams(params, map[int]any{coseCurve: &curve, coseX: &x}); err != nil {
+			return nil, err
+		}
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return &publicKey{algorithm: algorithm, key: ed25519.PublicKey(x)}, nil
+	case keyType == coseKeyTypeRsa && algorithm == AlgorithmRS256:
+		var n, e []byte
+		if err := unmarshalParams(params, map[int]any{coseRsaN: &n, coseRsaE: &e}); err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA public key")
+		}
+		return &publicKey{algorithm: algorithm, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key algorithm: %d", algorithm)
+	}
+}
+
+func (key *publicKey) verify(message []byte, signature []byte) bool {
+	switch key.algorithm {
+	case AlgorithmES256:
+		hash := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(key.key.(*ecdsa.PublicKey), hash[:], signature)
+	case AlgorithmEdDSA:
+		return ed25519.Verify(key.key.(ed25519.PublicKey), message, signature)
+	case AlgorithmRS256:
+		hash := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(key.key.(*rsa.PublicKey), crypto.SHA256, hash[:], signature) == nil
+	}
+	return false
+}
+
+func unmarshalParams(params map[int]cbor.RawMessage, values map[int]any) error {
+	for label, value := range values {
+		if err := unmarshalParam(params, label, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalParam(params map[int]cbor.RawMessage, label int, value any) error {
+	param, ok := params[label]
+	if !ok {
+		return fmt.Errorf("COSE key parameter %d missing", label)
+	}
+	if err := cbor.Unmarshal(param, value); err != nil {
+		return fmt.Errorf("invalid COSE key parameter %d: %w", label, err)
+	}
+	return nil
+}
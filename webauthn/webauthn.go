@@ -0,0 +1,221 @@
+// Package webauthn verifies passkey and security key registrations and assertions of a
+// WebAuthn relying party. Attestation statements are not verified, since the hub does not
+// restrict which authenticators can be used.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+type RelyingParty struct {
+	Id     string
+	Name   string
+	Origin string
+}
+
+// NewRelyingParty returns the relying party of the frontend served at the given URL.
+// Its hostname is used as relying party id, so passkeys keep working if the port changes.
+func NewRelyingParty(frontendUrl string, name string) (*RelyingParty, error) {
+	parsedUrl, err := url.Parse(frontendUrl)
+	if err != nil || parsedUrl.Scheme == "" || parsedUrl.Hostname() == "" {
+		return nil, fmt.Errorf("invalid frontend url: %s", frontendUrl)
+	}
+	return &RelyingParty{
+		Id:     parsedUrl.Hostname(),
+		Name:   name,
+		Origin: parsedUrl.Scheme + "://" + parsedUrl.Host,
+	}, nil
+}
+
+// Credential is a registered public key credential
+type Credential struct {
+	Id []byte
+	// COSE encoded
+	PublicKey []byte
+	SignCount uint32
+}
+
+// NewChallenge returns a random challenge, which must only be accepted once
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// EncodeBase64 encodes binary values as the WebAuthn JSON serialization does
+func EncodeBase64(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+func DecodeBase64(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type attestationObject struct {
+	Fmt      string          `cbor:"fmt"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+type authenticatorData struct {
+	rpIdHash  []byte
+	flags     byte
+	signCount uint32
+	// only set for registrations
+	credentialId        []byte
+	credentialPublicKey []byte
+}
+
+// VerifyRegistration checks the response of navigator.credentials.create and returns the new credential
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, clientDataJSON []byte, attestationObjectBytes []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	var attestation attestationObject
+	if err := cbor.Unmarshal(attestationObjectBytes, &attestation); err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	authData, err := parseAuthenticatorData(attestation.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.credentialId == nil {
+		return nil, errors.New("no attested credential data")
+	}
+	if _, err := parsePublicKey(authData.credentialPublicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		Id:        authData.credentialId,
+		PublicKey: authData.credentialPublicKey,
+		SignCount: authData.signCount,
+	}, nil
+}
+
+// VerifyAssertion checks the response of navigator.credentials.get and returns the new signature counter
+func (rp *RelyingParty) VerifyAssertion(credential *Credential, challenge []byte, clientDataJSON []byte, authenticatorDataBytes []byte, signature []byte) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(authenticatorDataBytes)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+
+	publicKey, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if !publicKey.verify(append(bytes.Clone(authenticatorDataBytes), clientDataHash[:]...), signature) {
+		return 0, errors.New("invalid signature")
+	}
+
+	// authenticators which do not support counters always return 0
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, errors.New("signature counter did not increase, the authenticator might be cloned")
+	}
+	return authData.signCount, nil
+}
+
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, expectedType string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return fmt.Errorf("invalid client data: %w", err)
+	}
+	if data.Type != expectedType {
+		return fmt.Errorf("unexpected client data type: %s", data.Type)
+	}
+	receivedChallenge, err := DecodeBase64(data.Challenge)
+	if err != nil || !bytes.Equal(receivedChallenge, challenge) {
+		return errors.New("challenge does not match")
+	}
+	if data.Origin != rp.Origin {
+		return fmt.Errorf("unexpected origin: %s", data.Origin)
+	}
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData) error {
+	rpIdHash := sha256.Sum256([]byte(rp.Id))
+	if !bytes.Equal(authData.rpIdHash, rpIdHash[:]) {
+		return errors.New("relying party id does not match")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return errors.New("user was not present")
+	}
+	// passkeys replace the unlock password, so the authenticator has to verify the user as well
+	if authData.flags&flagUserVerified == 0 {
+		return errors.New("user was not verified")
+	}
+	return nil
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data too short")
+	}
+	authData := &authenticatorData{
+		rpIdHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authData.flags&flagAttestedCredentialData == 0 {
+		return authData, nil
+	}
+
+	// 16 bytes AAGUID, followed by the length of the credential id
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("attested credential data too short")
+	}
+	credentialIdLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < credentialIdLength {
+		return nil, errors.New("credential id too short")
+	}
+	authData.credentialId = rest[:credentialIdLength]
+
+	// the public key can be followed by extensions
+	var publicKey cbor.RawMessage
+	if _, err := cbor.UnmarshalFirst(rest[credentialIdLength:], &publicKey); err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	authData.credentialPublicKey = publicKey
+	return authData, nil
+}
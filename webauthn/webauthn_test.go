@@ -0,0 +1,129 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAuthenticator is a software authenticator with a P-256 key
+type testAuthenticator struct {
+	rpId         string
+	origin       string
+	credentialId []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+}
+
+func newTestAuthenticator(t *testing.T, rpId string, origin string) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return &testAuthenticator{rpId: rpId, origin: origin, credentialId: []byte("credential"), key: key}
+}
+
+func (a *testAuthenticator) clientDataJSON(t *testing.T, clientDataType string, challenge []byte) []byte {
+	clientDataJSON, err := json.Marshal(clientData{Type: clientDataType, Challenge: EncodeBase64(challenge), Origin: a.origin})
+	require.NoError(t, err)
+	return clientDataJSON
+}
+
+func (a *testAuthenticator) authenticatorData(flags byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(a.rpId))
+	authData := append(rpIdHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, a.signCount)
+}
+
+func (a *testAuthenticator) create(t *testing.T, challenge []byte) ([]byte, []byte) {
+	publicKey, err := cbor.Marshal(map[int]any{
+		coseKeyType:   coseKeyTypeEc2,
+		coseAlgorithm: AlgorithmES256,
+		coseCurve:     coseCurveP256,
+		coseX:         a.key.X.FillBytes(make([]byte, 32)),
+		coseY:         a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	authData := a.authenticatorData(flagUserPresent | flagUserVerified | flagAttestedCredentialData)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialId)))
+	authData = append(authData, a.credentialId...)
+	authData = append(authData, publicKey...)
+
+	attestation, err := cbor.Marshal(map[string]any{"fmt": "none", "attStmt": map[string]any{}, "authData": authData})
+	require.NoError(t, err)
+	return a.clientDataJSON(t, "webauthn.create", challenge), attestation
+}
+
+func (a *testAuthenticator) get(t *testing.T, challenge []byte, flags byte) ([]byte, []byte, []byte) {
+	a.signCount++
+	clientDataJSON := a.clientDataJSON(t, "webauthn.get", challenge)
+	authData := a.authenticatorData(flags)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	hash := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, hash[:])
+	require.NoError(t, err)
+	return clientDataJSON, authData, signature
+}
+
+func TestNewRelyingParty(t *testing.T) {
+	rp, err := NewRelyingParty("https://hub.example.com:8443/path", "Alby Hub")
+	require.NoError(t, err)
+	assert.Equal(t, "hub.example.com", rp.Id)
+	assert.Equal(t, "https://hub.example.com:8443", rp.Origin)
+
+	_, err = NewRelyingParty("", "Alby Hub")
+	assert.Error(t, err)
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp, err := NewRelyingParty("https://hub.example.com", "Alby Hub")
+	require.NoError(t, err)
+	authenticator := newTestAuthenticator(t, rp.Id, rp.Origin)
+
+	challenge, err := NewChallenge()
+	require.NoError(t, err)
+	clientDataJSON, attestation := authenticator.create(t, challenge)
+
+	_, err = rp.VerifyRegistration([]byte("other challenge"), clientDataJSON, attestation)
+	assert.EqualError(t, err, "challenge does not match")
+
+	credential, err := rp.VerifyRegistration(challenge, clientDataJSON, attestation)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("credential"), credential.Id)
+
+	clientDataJSON, authData, signature := authenticator.get(t, challenge, flagUserPresent|flagUserVerified)
+	signCount, err := rp.VerifyAssertion(credential, challenge, clientDataJSON, authData, signature)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), signCount)
+
+	// replaying the assertion is detected by the counter
+	credential.SignCount = signCount
+	_, err = rp.VerifyAssertion(credential, challenge, clientDataJSON, authData, signature)
+	assert.EqualError(t, err, "signature counter did not increase, the authenticator might be cloned")
+
+	signature[len(signature)-1] ^= 0xff
+	_, err = rp.VerifyAssertion(&Credential{PublicKey: credential.PublicKey}, challenge, clientDataJSON, authData, signature)
+	assert.EqualError(t, err, "invalid signature")
+
+	clientDataJSON, authData, signature = authenticator.get(t, challenge, flagUserPresent)
+	_, err = rp.VerifyAssertion(credential, challenge, clientDataJSON, authData, signature)
+	assert.EqualError(t, err, "user was not verified")
+
+	phishingRp := &RelyingParty{Id: "hub.example.com.evil", Origin: rp.Origin}
+	clientDataJSON, authData, signature = authenticator.get(t, challenge, flagUserPresent|flagUserVerified)
+	_, err = phishingRp.VerifyAssertion(credential, challenge, clientDataJSON, authData, signature)
+	assert.EqualError(t, err, "relying party id does not match")
+
+	authenticator.origin = "https://evil.example.com"
+	clientDataJSON, authData, signature = authenticator.get(t, challenge, flagUserPresent|flagUserVerified)
+	_, err = rp.VerifyAssertion(credential, challenge, clientDataJSON, authData, signature)
+	assert.EqualError(t, err, "unexpected origin: https://evil.example.com")
+}
@@ -0,0 +1,761 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	WEBHOOK_EVENT_PAYMENT_RECEIVED = "payment_received"
+	WEBHOOK_EVENT_PAYMENT_SENT     = "payment_sent"
+	WEBHOOK_EVENT_PAYMENT_FAILED   = "payment_failed"
+	WEBHOOK_EVENT_INVOICE_EXPIRED  = "invoice_expired"
+
+	WEBHOOK_EVENT_HOLD_INVOICE_ACCEPTED = "hold_invoice_accepted"
+	WEBHOOK_EVENT_HOLD_INVOICE_SETTLED  = "hold_invoice_settled"
+	WEBHOOK_EVENT_HOLD_INVOICE_CANCELED = "hold_invoice_canceled"
+
+	WEBHOOK_EVENT_BUDGET_THRESHOLD_REACHED   = "budget_threshold_reached"
+	WEBHOOK_EVENT_SUBWALLET_DEPOSIT_RECEIVED = "subwallet_deposit_received"
+)
+
+// events of the hub, which are not related to a single app
+const (
+	WEBHOOK_EVENT_CHANNEL_OPENED              = "channel_opened"
+	WEBHOOK_EVENT_CHANNEL_CLOSED              = "channel_closed"
+	WEBHOOK_EVENT_CHANNEL_FORCE_CLOSED        = "channel_force_closed"
+	WEBHOOK_EVENT_FORCE_CLOSE_FUNDS_SPENDABLE = "force_close_funds_spendable"
+	WEBHOOK_EVENT_PAYMENT_FORWARDED           = "payment_forwarded"
+	WEBHOOK_EVENT_OUTGOING_LIQUIDITY_REQUIRED = "outgoing_liquidity_required"
+	WEBHOOK_EVENT_INCOMING_LIQUIDITY_REQUIRED = "incoming_liquidity_required"
+	WEBHOOK_EVENT_LOW_BALANCE                 = "low_balance"
+	WEBHOOK_EVENT_LOW_INBOUND_LIQUIDITY       = "low_inbound_liquidity"
+	WEBHOOK_EVENT_ONCHAIN_FEE_SPIKE           = "onchain_fee_spike"
+	WEBHOOK_EVENT_ONCHAIN_FEE_NORMAL          = "onchain_fee_normal"
+	WEBHOOK_EVENT_SWAP_SUCCEEDED              = "swap_succeeded"
+	WEBHOOK_EVENT_SWAP_PROGRESS               = "swap_progress"
+	WEBHOOK_EVENT_SWAP_FAILED                 = "swap_failed"
+	WEBHOOK_EVENT_SWAP_RULE_TRIGGERED         = "swap_rule_triggered"
+	WEBHOOK_EVENT_LSP_ORDER_UPDATED           = "lsp_order_updated"
+	WEBHOOK_EVENT_REBALANCE_SUCCEEDED         = "rebalance_succeeded"
+	WEBHOOK_EVENT_CHANNEL_FEES_UPDATED        = "channel_fees_updated"
+	WEBHOOK_EVENT_CHANNEL_REQUEST_ACCEPTED    = "channel_request_accepted"
+	WEBHOOK_EVENT_CHANNEL_REQUEST_REJECTED    = "channel_request_rejected"
+
+	WEBHOOK_EVENT_NODE_STARTED      = "node_started"
+	WEBHOOK_EVENT_NODE_START_FAILED = "node_start_failed"
+	WEBHOOK_EVENT_NODE_SYNC_FAILED  = "node_sync_failed"
+	WEBHOOK_EVENT_NODE_STOPPED      = "node_stopped"
+
+	WEBHOOK_EVENT_BACKUP_SUCCEEDED  = "backup_succeeded"
+	WEBHOOK_EVENT_BACKUP_FAILED     = "backup_failed"
+	WEBHOOK_EVENT_DATABASE_CORRUPT  = "database_corrupt"
+	WEBHOOK_EVENT_DATABASE_RESTORED = "database_restored"
+
+	WEBHOOK_EVENT_APP_CREATED       = "app_created"
+	WEBHOOK_EVENT_APP_DELETED       = "app_deleted"
+	WEBHOOK_EVENT_APP_REVOKED       = "app_revoked"
+	WEBHOOK_EVENT_APP_EXPIRING      = "app_expiring"
+	WEBHOOK_EVENT_BUDGET_WARNING    = "budget_warning"
+	WEBHOOK_EVENT_PERMISSION_DENIED = "permission_denied"
+
+	WEBHOOK_EVENT_SCHEDULED_PAYMENT_SUCCEEDED = "scheduled_payment_succeeded"
+	WEBHOOK_EVENT_SCHEDULED_PAYMENT_FAILED    = "scheduled_payment_failed"
+
+	WEBHOOK_EVENT_SPENDING_LOCKED = "spending_locked"
+	WEBHOOK_EVENT_DURESS_UNLOCK   = "duress_unlock"
+)
+
+// WEBHOOK_EVENT_ALL subscribes a webhook to every event type, including ones added later
+const WEBHOOK_EVENT_ALL = "*"
+
+// GetAppWebhookEventTypes returns the event types which belong to an app, only these can be
+// sent to webhooks of an app
+func GetAppWebhookEventTypes() []string {
+	return []string{
+		WEBHOOK_EVENT_PAYMENT_RECEIVED,
+		WEBHOOK_EVENT_PAYMENT_SENT,
+		WEBHOOK_EVENT_PAYMENT_FAILED,
+		WEBHOOK_EVENT_INVOICE_EXPIRED,
+		WEBHOOK_EVENT_HOLD_INVOICE_ACCEPTED,
+		WEBHOOK_EVENT_HOLD_INVOICE_SETTLED,
+		WEBHOOK_EVENT_HOLD_INVOICE_CANCELED,
+		WEBHOOK_EVENT_BUDGET_THRESHOLD_REACHED,
+		WEBHOOK_EVENT_SUBWALLET_DEPOSIT_RECEIVED,
+	}
+}
+
+func GetWebhookEventTypes() []string {
+	return append(GetAppWebhookEventTypes(),
+		WEBHOOK_EVENT_CHANNEL_OPENED,
+		WEBHOOK_EVENT_CHANNEL_CLOSED,
+		WEBHOOK_EVENT_CHANNEL_FORCE_CLOSED,
+		WEBHOOK_EVENT_FORCE_CLOSE_FUNDS_SPENDABLE,
+		WEBHOOK_EVENT_PAYMENT_FORWARDED,
+		WEBHOOK_EVENT_OUTGOING_LIQUIDITY_REQUIRED,
+		WEBHOOK_EVENT_INCOMING_LIQUIDITY_REQUIRED,
+		WEBHOOK_EVENT_LOW_BALANCE,
+		WEBHOOK_EVENT_LOW_INBOUND_LIQUIDITY,
+		WEBHOOK_EVENT_ONCHAIN_FEE_SPIKE,
+		WEBHOOK_EVENT_ONCHAIN_FEE_NORMAL,
+		WEBHOOK_EVENT_SWAP_SUCCEEDED,
+		WEBHOOK_EVENT_SWAP_PROGRESS,
+		WEBHOOK_EVENT_SWAP_FAILED,
+		WEBHOOK_EVENT_SWAP_RULE_TRIGGERED,
+		WEBHOOK_EVENT_LSP_ORDER_UPDATED,
+		WEBHOOK_EVENT_REBALANCE_SUCCEEDED,
+		WEBHOOK_EVENT_CHANNEL_FEES_UPDATED,
+		WEBHOOK_EVENT_CHANNEL_REQUEST_ACCEPTED,
+		WEBHOOK_EVENT_CHANNEL_REQUEST_REJECTED,
+		WEBHOOK_EVENT_NODE_STARTED,
+		WEBHOOK_EVENT_NODE_START_FAILED,
+		WEBHOOK_EVENT_NODE_SYNC_FAILED,
+		WEBHOOK_EVENT_NODE_STOPPED,
+		WEBHOOK_EVENT_BACKUP_SUCCEEDED,
+		WEBHOOK_EVENT_BACKUP_FAILED,
+		WEBHOOK_EVENT_DATABASE_CORRUPT,
+		WEBHOOK_EVENT_DATABASE_RESTORED,
+		WEBHOOK_EVENT_APP_CREATED,
+		WEBHOOK_EVENT_APP_DELETED,
+		WEBHOOK_EVENT_APP_REVOKED,
+		WEBHOOK_EVENT_APP_EXPIRING,
+		WEBHOOK_EVENT_BUDGET_WARNING,
+		WEBHOOK_EVENT_PERMISSION_DENIED,
+		WEBHOOK_EVENT_SCHEDULED_PAYMENT_SUCCEEDED,
+		WEBHOOK_EVENT_SCHEDULED_PAYMENT_FAILED,
+		WEBHOOK_EVENT_SPENDING_LOCKED,
+		WEBHOOK_EVENT_DURESS_UNLOCK,
+	)
+}
+
+// maps internal event names to the event types exposed to webhook consumers.
+// Events with secrets, such as the static channel backup, must never be added here.
+var webhookEventTypes = map[string]string{
+	"nwc_payment_received": WEBHOOK_EVENT_PAYMENT_RECEIVED,
+	"nwc_payment_sent":     WEBHOOK_EVENT_PAYMENT_SENT,
+	"nwc_payment_failed":   WEBHOOK_EVENT_PAYMENT_FAILED,
+	"nwc_invoice_expired":  WEBHOOK_EVENT_INVOICE_EXPIRED,
+
+	"nwc_hold_invoice_accepted": WEBHOOK_EVENT_HOLD_INVOICE_ACCEPTED,
+	"nwc_hold_invoice_settled":  WEBHOOK_EVENT_HOLD_INVOICE_SETTLED,
+	"nwc_hold_invoice_canceled": WEBHOOK_EVENT_HOLD_INVOICE_CANCELED,
+
+	"nwc_budget_threshold_reached":   WEBHOOK_EVENT_BUDGET_THRESHOLD_REACHED,
+	"nwc_subwallet_deposit_received": WEBHOOK_EVENT_SUBWALLET_DEPOSIT_RECEIVED,
+
+	"nwc_channel_ready":               WEBHOOK_EVENT_CHANNEL_OPENED,
+	"nwc_channel_closed":              WEBHOOK_EVENT_CHANNEL_CLOSED,
+	"nwc_channel_force_closed":        WEBHOOK_EVENT_CHANNEL_FORCE_CLOSED,
+	"nwc_force_close_funds_spendable": WEBHOOK_EVENT_FORCE_CLOSE_FUNDS_SPENDABLE,
+	"nwc_payment_forwarded":           WEBHOOK_EVENT_PAYMENT_FORWARDED,
+	"nwc_outgoing_liquidity_required": WEBHOOK_EVENT_OUTGOING_LIQUIDITY_REQUIRED,
+	"nwc_incoming_liquidity_required": WEBHOOK_EVENT_INCOMING_LIQUIDITY_REQUIRED,
+	"nwc_low_balance":                 WEBHOOK_EVENT_LOW_BALANCE,
+	"nwc_low_inbound_liquidity":       WEBHOOK_EVENT_LOW_INBOUND_LIQUIDITY,
+	"nwc_onchain_fee_spike":           WEBHOOK_EVENT_ONCHAIN_FEE_SPIKE,
+	"nwc_onchain_fee_normal":          WEBHOOK_EVENT_ONCHAIN_FEE_NORMAL,
+	"nwc_swap_succeeded":              WEBHOOK_EVENT_SWAP_SUCCEEDED,
+	"nwc_swap_progress":               WEBHOOK_EVENT_SWAP_PROGRESS,
+	"nwc_swap_failed":                 WEBHOOK_EVENT_SWAP_FAILED,
+	"nwc_swap_rule_triggered":         WEBHOOK_EVENT_SWAP_RULE_TRIGGERED,
+	"nwc_lsp_order_updated":           WEBHOOK_EVENT_LSP_ORDER_UPDATED,
+	"nwc_rebalance_succeeded":         WEBHOOK_EVENT_REBALANCE_SUCCEEDED,
+	"nwc_channel_fees_updated":        WEBHOOK_EVENT_CHANNEL_FEES_UPDATED,
+	"nwc_channel_request_accepted":    WEBHOOK_EVENT_CHANNEL_REQUEST_ACCEPTED,
+	"nwc_channel_request_rejected":    WEBHOOK_EVENT_CHANNEL_REQUEST_REJECTED,
+
+	"nwc_node_started":      WEBHOOK_EVENT_NODE_STARTED,
+	"nwc_node_start_failed": WEBHOOK_EVENT_NODE_START_FAILED,
+	"nwc_node_sync_failed":  WEBHOOK_EVENT_NODE_SYNC_FAILED,
+	"nwc_node_stopped":      WEBHOOK_EVENT_NODE_STOPPED,
+
+	"nwc_backup_succeeded":  WEBHOOK_EVENT_BACKUP_SUCCEEDED,
+	"nwc_backup_failed":     WEBHOOK_EVENT_BACKUP_FAILED,
+	"nwc_database_corrupt":  WEBHOOK_EVENT_DATABASE_CORRUPT,
+	"nwc_database_restored": WEBHOOK_EVENT_DATABASE_RESTORED,
+
+	"nwc_app_created":       WEBHOOK_EVENT_APP_CREATED,
+	"nwc_app_deleted":       WEBHOOK_EVENT_APP_DELETED,
+	"nwc_app_revoked":       WEBHOOK_EVENT_APP_REVOKED,
+	"nwc_app_expiring":      WEBHOOK_EVENT_APP_EXPIRING,
+	"nwc_budget_warning":    WEBHOOK_EVENT_BUDGET_WARNING,
+	"nwc_permission_denied": WEBHOOK_EVENT_PERMISSION_DENIED,
+
+	"nwc_scheduled_payment_succeeded": WEBHOOK_EVENT_SCHEDULED_PAYMENT_SUCCEEDED,
+	"nwc_scheduled_payment_failed":    WEBHOOK_EVENT_SCHEDULED_PAYMENT_FAILED,
+
+	"nwc_spending_locked": WEBHOOK_EVENT_SPENDING_LOCKED,
+	"nwc_duress_unlock":   WEBHOOK_EVENT_DURESS_UNLOCK,
+}
+
+// Filter limits the events which are sent to a webhook
+type Filter struct {
+	// payments and invoices below this amount are not sent
+	MinAmountSat uint64 `json:"minAmountSat,omitempty"`
+	// only events whose data contains all of these values are sent, e.g. {"public": true}
+	Match map[string]interface{} `json:"match,omitempty"`
+}
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	WebhookIdHeader = "X-Webhook-Id"
+)
+
+const maxDeliveryAttempts = 5
+
+// retryBaseDelay is doubled after every failed delivery attempt
+var retryBaseDelay = 10 * time.Second
+
+type WebhooksService interface {
+	events.EventSubscriber
+	CreateWebhook(url string, eventTypes []string, filter *Filter) (*db.Webhook, error)
+	CreateAppWebhook(appId uint, url string, eventTypes []string, filter *Filter) (*db.Webhook, error)
+	UpdateWebhook(id uint, enabled *bool, eventTypes []string, filter *Filter) (*db.Webhook, error)
+	ListWebhooks() ([]db.Webhook, error)
+	DeleteWebhook(id uint) error
+	ListDeliveries(webhookId uint, state string, limit uint64) ([]db.WebhookDelivery, error)
+	GetDeliveryStats(webhookId uint) (*DeliveryStats, error)
+	RetryDelivery(ctx context.Context, webhookId uint, deliveryId uint) (*db.WebhookDelivery, error)
+}
+
+type DeliveryStats struct {
+	Pending         int64      `json:"pending"`
+	Delivered       int64      `json:"delivered"`
+	Failed          int64      `json:"failed"`
+	LastDeliveredAt *time.Time `json:"lastDeliveredAt"`
+	LastFailedAt    *time.Time `json:"lastFailedAt"`
+}
+
+type webhooksService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+}
+
+type webhookPayload struct {
+	Event     string      `json:"event"`
+	CreatedAt int64       `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type transactionPayload struct {
+	*models.Transaction
+	AppId *uint `json:"app_id"`
+}
+
+func NewWebhooksService(db *gorm.DB) *webhooksService {
+	return &webhooksService{
+		db: db,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (svc *webhooksService) CreateWebhook(webhookUrl string, eventTypes []string, filter *Filter) (*db.Webhook, error) {
+	if err := validateEventTypes(eventTypes, GetWebhookEventTypes()); err != nil {
+		return nil, err
+	}
+	return svc.createWebhook(webhookUrl, eventTypes, filter, nil)
+}
+
+// CreateAppWebhook creates a webhook which only receives the events of the given app,
+// so that services using the connection can learn about their payments without a nostr client.
+func (svc *webhooksService) CreateAppWebhook(appId uint, webhookUrl string, eventTypes []string, filter *Filter) (*db.Webhook, error) {
+	if svc.db.Limit(1).Find(&db.App{}, appId).RowsAffected == 0 {
+		return nil, errors.New("app not found")
+	}
+	if err := validateEventTypes(eventTypes, GetAppWebhookEventTypes()); err != nil {
+		return nil, err
+	}
+	return svc.createWebhook(webhookUrl, eventTypes, filter, &appId)
+}
+
+func (svc *webhooksService) createWebhook(webhookUrl string, eventTypes []string, filter *Filter, appId *uint) (*db.Webhook, error) {
+	if err := ValidateWebhookUrl(webhookUrl); err != nil {
+		return nil, err
+	}
+	serializedFilter, err := serializeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, err
+	}
+
+	webhook := db.Webhook{
+		Url:        webhookUrl,
+		Secret:     hex.EncodeToString(secretBytes),
+		EventTypes: strings.Join(eventTypes, ","),
+		Filter:     serializedFilter,
+		Enabled:    true,
+		AppId:      appId,
+	}
+	if err := svc.db.Create(&webhook).Error; err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// UpdateWebhook enables or disables a webhook or changes the events it receives.
+// Fields which are nil are left unchanged.
+func (svc *webhooksService) UpdateWebhook(id uint, enabled *bool, eventTypes []string, filter *Filter) (*db.Webhook, error) {
+	var webhook db.Webhook
+	if svc.db.Limit(1).Find(&webhook, id).RowsAffected == 0 {
+		return nil, errors.New("webhook not found")
+	}
+
+	updates := map[string]interface{}{}
+	if enabled != nil {
+		updates["enabled"] = *enabled
+	}
+	if eventTypes != nil {
+		allowedEventTypes := GetWebhookEventTypes()
+		if webhook.AppId != nil {
+			allowedEventTypes = GetAppWebhookEventTypes()
+		}
+		if err := validateEventTypes(eventTypes, allowedEventTypes); err != nil {
+			return nil, err
+		}
+		updates["event_types"] = strings.Join(eventTypes, ",")
+	}
+	if filter != nil {
+		serializedFilter, err := serializeFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		updates["filter"] = serializedFilter
+	}
+	if len(updates) == 0 {
+		return &webhook, nil
+	}
+
+	if err := svc.db.Model(&webhook).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func validateEventTypes(eventTypes []string, allowedEventTypes []string) error {
+	if len(eventTypes) == 0 {
+		return errors.New("no event types provided")
+	}
+	for _, eventType := range eventTypes {
+		if eventType != WEBHOOK_EVENT_ALL && !slices.Contains(allowedEventTypes, eventType) {
+			return fmt.Errorf("unsupported event type %s. Must be %s or one of %s", eventType, WEBHOOK_EVENT_ALL, strings.Join(allowedEventTypes, ","))
+		}
+	}
+	return nil
+}
+
+// serializeFilter returns an empty string for an empty filter, which matches every event
+func serializeFilter(filter *Filter) (string, error) {
+	if filter == nil || (filter.MinAmountSat == 0 && len(filter.Match) == 0) {
+		return "", nil
+	}
+	serializedFilter, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+	return string(serializedFilter), nil
+}
+
+func ParseFilter(serializedFilter string) (*Filter, error) {
+	filter := &Filter{}
+	if serializedFilter == "" {
+		return filter, nil
+	}
+	if err := json.Unmarshal([]byte(serializedFilter), filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// matches checks the filter against the data of an event, serialized as JSON object
+func (filter *Filter) matches(data map[string]interface{}) bool {
+	if filter.MinAmountSat > 0 {
+		// transactions are sent with their amount in millisats
+		if amount, ok := data["amount"].(float64); ok && amount < float64(filter.MinAmountSat*1000) {
+			return false
+		}
+	}
+	for key, value := range filter.Match {
+		if !reflect.DeepEqual(data[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+func ValidateWebhookUrl(webhookUrl string) error {
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return errors.New("webhook url must be a valid http or https url")
+	}
+	return nil
+}
+
+func (svc *webhooksService) ListWebhooks() ([]db.Webhook, error) {
+	webhooks := []db.Webhook{}
+	if err := svc.db.Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (svc *webhooksService) DeleteWebhook(id uint) error {
+	result := svc.db.Delete(&db.Webhook{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("webhook not found")
+	}
+	return nil
+}
+
+func (svc *webhooksService) ListDeliveries(webhookId uint, state string, limit uint64) ([]db.WebhookDelivery, error) {
+	deliveries := []db.WebhookDelivery{}
+	query := svc.db.Where("webhook_id = ?", webhookId).Order("id desc")
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (svc *webhooksService) GetDeliveryStats(webhookId uint) (*DeliveryStats, error) {
+	var counts []struct {
+		State string
+		Count int64
+	}
+	err := svc.db.Model(&db.WebhookDelivery{}).
+		Select("state, COUNT(*) AS count").
+		Where("webhook_id = ?", webhookId).
+		Group("state").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &DeliveryStats{}
+	for _, count := range counts {
+		switch count.State {
+		case db.WEBHOOK_DELIVERY_STATE_PENDING:
+			stats.Pending = count.Count
+		case db.WEBHOOK_DELIVERY_STATE_DELIVERED:
+			stats.Delivered = count.Count
+		case db.WEBHOOK_DELIVERY_STATE_FAILED:
+			stats.Failed = count.Count
+		}
+	}
+
+	for state, lastAt := range map[string]**time.Time{
+		db.WEBHOOK_DELIVERY_STATE_DELIVERED: &stats.LastDeliveredAt,
+		db.WEBHOOK_DELIVERY_STATE_FAILED:    &stats.LastFailedAt,
+	} {
+		var delivery db.WebhookDelivery
+		if svc.db.Where("webhook_id = ? AND state = ?", webhookId, state).Order("id desc").Limit(1).Find(&delivery).RowsAffected > 0 {
+			*lastAt = &delivery.UpdatedAt
+		}
+	}
+	return stats, nil
+}
+
+// RetryDelivery sends a failed delivery again, with a new series of attempts
+func (svc *webhooksService) RetryDelivery(ctx context.Context, webhookId uint, deliveryId uint) (*db.WebhookDelivery, error) {
+	var webhook db.Webhook
+	if svc.db.Limit(1).Find(&webhook, webhookId).RowsAffected == 0 {
+		return nil, errors.New("webhook not found")
+	}
+	var delivery db.WebhookDelivery
+	if svc.db.Limit(1).Find(&delivery, &db.WebhookDelivery{ID: deliveryId, WebhookId: webhookId}).RowsAffected == 0 {
+		return nil, errors.New("webhook delivery not found")
+	}
+	if delivery.State != db.WEBHOOK_DELIVERY_STATE_FAILED {
+		return nil, errors.New("only failed deliveries can be retried")
+	}
+
+	delivery.State = db.WEBHOOK_DELIVERY_STATE_PENDING
+	delivery.Attempts = 0
+	if err := svc.db.Model(&delivery).Updates(map[string]interface{}{
+		"state":    delivery.State,
+		"attempts": delivery.Attempts,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	// the delivery continues after the request which retried it is done
+	go svc.deliver(context.WithoutCancel(ctx), &webhook, &delivery)
+	return &delivery, nil
+}
+
+// getEventData returns the data of the event as webhook consumers see it, and the app it belongs to
+func getEventData(event *events.Event) (interface{}, *uint) {
+	switch properties := event.Properties.(type) {
+	case *db.Transaction:
+		return &transactionPayload{
+			Transaction: models.ToNip47Transaction(properties),
+			AppId:       properties.AppId,
+		}, properties.AppId
+	case *events.BudgetThresholdReachedEvent:
+		return properties, &properties.AppId
+	}
+	return event.Properties, nil
+}
+
+// MarshalEvent returns the event type and the payload which webhooks receive for the event,
+// or an empty event type if the event is not exposed to webhooks
+func MarshalEvent(event *events.Event) (string, []byte, error) {
+	eventType, ok := webhookEventTypes[event.Event]
+	if !ok {
+		return "", nil, nil
+	}
+	data, _ := getEventData(event)
+	payloadBytes, err := json.Marshal(&webhookPayload{
+		Event:     eventType,
+		CreatedAt: time.Now().Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return eventType, payloadBytes, nil
+}
+
+func (svc *webhooksService) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	eventType, ok := webhookEventTypes[event.Event]
+	if !ok {
+		return
+	}
+
+	// app webhooks only receive the events of their app
+	data, appId := getEventData(event)
+
+	webhooks := []db.Webhook{}
+	if err := svc.db.Where("enabled = ?", true).Find(&webhooks).Error; err != nil {
+		logger.Logger.WithError(err).Error("failed to list webhooks")
+		return
+	}
+
+	var payloadBytes []byte
+	var dataFields map[string]interface{}
+	for _, webhook := range webhooks {
+		webhookEventTypes := strings.Split(webhook.EventTypes, ",")
+		if !slices.Contains(webhookEventTypes, eventType) && !slices.Contains(webhookEventTypes, WEBHOOK_EVENT_ALL) {
+			continue
+		}
+		if webhook.AppId != nil && (appId == nil || *appId != *webhook.AppId) {
+			continue
+		}
+
+		if payloadBytes == nil {
+			var err error
+			payloadBytes, err = json.Marshal(&webhookPayload{
+				Event:     eventType,
+				CreatedAt: time.Now().Unix(),
+				Data:      data,
+			})
+			if err != nil {
+				logger.Logger.WithError(err).Error("failed to serialize webhook payload")
+				return
+			}
+			// filters are applied to the data as the receiver sees it
+			var payload struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(payloadBytes, &payload); err == nil {
+				dataFields = payload.Data
+			}
+		}
+
+		if webhook.Filter != "" {
+			filter, err := ParseFilter(webhook.Filter)
+			if err != nil {
+				logger.Logger.WithField("webhook_id", webhook.ID).WithError(err).Error("invalid webhook filter")
+				continue
+			}
+			if !filter.matches(dataFields) {
+				continue
+			}
+		}
+
+		delivery := db.WebhookDelivery{
+			WebhookId: webhook.ID,
+			EventType: eventType,
+			Payload:   string(payloadBytes),
+			State:     db.WEBHOOK_DELIVERY_STATE_PENDING,
+		}
+		if err := svc.db.Create(&delivery).Error; err != nil {
+			logger.Logger.WithError(err).Error("failed to create webhook delivery")
+			continue
+		}
+
+		go svc.deliver(ctx, &webhook, &delivery)
+	}
+}
+
+// deliver posts the payload to the webhook url, retrying with exponential backoff.
+// Every attempt is recorded on the delivery so failures can be inspected later.
+func (svc *webhooksService) deliver(ctx context.Context, webhook *db.Webhook, delivery *db.WebhookDelivery) {
+	delay := retryBaseDelay
+	for delivery.Attempts < maxDeliveryAttempts {
+		delivery.Attempts++
+		status, err := svc.post(ctx, webhook, delivery)
+		delivery.ResponseStatus = status
+		delivery.Error = ""
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+
+		if err == nil {
+			delivery.State = db.WEBHOOK_DELIVERY_STATE_DELIVERED
+		} else if delivery.Attempts >= maxDeliveryAttempts {
+			delivery.State = db.WEBHOOK_DELIVERY_STATE_FAILED
+		}
+
+		if dbErr := svc.db.Model(delivery).Updates(map[string]interface{}{
+			"state":           delivery.State,
+			"attempts":        delivery.Attempts,
+			"response_status": delivery.ResponseStatus,
+			"error":           delivery.Error,
+		}).Error; dbErr != nil {
+			logger.Logger.WithError(dbErr).Error("failed to update webhook delivery")
+		}
+
+		if err == nil {
+			return
+		}
+
+		logger.Logger.WithFields(logrus.Fields{
+			"webhook_id":  webhook.ID,
+			"delivery_id": delivery.ID,
+			"attempts":    delivery.Attempts,
+		}).WithError(err).Warn("failed to deliver webhook")
+
+		if delivery.State == db.WEBHOOK_DELIVERY_STATE_FAILED {
+			deadletters.Add(svc.db, deadletters.CONSUMER_WEBHOOKS, delivery.EventType, &webhookDeadLetter{
+				WebhookId:  webhook.ID,
+				DeliveryId: delivery.ID,
+			}, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// webhookDeadLetter refers to the failed delivery, which holds the payload
+type webhookDeadLetter struct {
+	WebhookId  uint `json:"webhook_id"`
+	DeliveryId uint `json:"delivery_id"`
+}
+
+// ReplayDeadLetter sends a failed delivery once more
+func (svc *webhooksService) ReplayDeadLetter(ctx context.Context, deadLetter *db.DeadLetter) error {
+	var payload webhookDeadLetter
+	if err := json.Unmarshal([]byte(deadLetter.Payload), &payload); err != nil {
+		return err
+	}
+	var webhook db.Webhook
+	if svc.db.Limit(1).Find(&webhook, payload.WebhookId).RowsAffected == 0 {
+		return errors.New("webhook not found")
+	}
+	if !webhook.Enabled {
+		return errors.New("webhook is disabled")
+	}
+	var delivery db.WebhookDelivery
+	if svc.db.Limit(1).Find(&delivery, &db.WebhookDelivery{ID: payload.DeliveryId, WebhookId: webhook.ID}).RowsAffected == 0 {
+		return errors.New("webhook delivery not found")
+	}
+	if delivery.State == db.WEBHOOK_DELIVERY_STATE_DELIVERED {
+		return nil
+	}
+
+	status, err := svc.post(ctx, &webhook, &delivery)
+	updates := map[string]interface{}{
+		"attempts":        delivery.Attempts + 1,
+		"response_status": status,
+		"error":           "",
+	}
+	if err != nil {
+		updates["error"] = err.Error()
+	} else {
+		updates["state"] = db.WEBHOOK_DELIVERY_STATE_DELIVERED
+	}
+	if dbErr := svc.db.Model(&delivery).Updates(updates).Error; dbErr != nil {
+		logger.Logger.WithError(dbErr).Error("failed to update webhook delivery")
+	}
+	return err
+}
+
+func (svc *webhooksService) post(ctx context.Context, webhook *db.Webhook, delivery *db.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AlbyHub")
+	req.Header.Set(WebhookIdHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>" using the webhook secret.
+// Receivers should compute the same value and compare it to the signature header, and
+// reject requests with an old timestamp to prevent replays.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,401 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/deadletters"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestCreateWebhook_InvalidUrl(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	_, err = webhooksSvc.CreateWebhook("ftp://example.com", []string{WEBHOOK_EVENT_PAYMENT_RECEIVED}, nil)
+	assert.Error(t, err)
+	_, err = webhooksSvc.CreateWebhook("https://example.com", []string{"unknown_event"}, nil)
+	assert.Error(t, err)
+}
+
+func TestWebhookDelivery_Signed(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	var mu sync.Mutex
+	var receivedBody []byte
+	var receivedHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		receivedBody, _ = io.ReadAll(r.Body)
+		receivedHeaders = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	webhook, err := webhooksSvc.CreateWebhook(server.URL, []string{WEBHOOK_EVENT_PAYMENT_RECEIVED}, nil)
+	require.NoError(t, err)
+	assert.Len(t, webhook.Secret, 64)
+
+	webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_payment_received",
+		Properties: &db.Transaction{
+			Type:        constants.TRANSACTION_TYPE_INCOMING,
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			AmountMsat:  1000,
+			PaymentHash: tests.MockPaymentHash,
+		},
+	}, map[string]interface{}{})
+
+	// events not subscribed to are ignored
+	webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_payment_sent",
+		Properties: &db.Transaction{},
+	}, map[string]interface{}{})
+
+	require.Eventually(t, func() bool {
+		deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, "", 0)
+		return err == nil && len(deliveries) == 1 && deliveries[0].State == db.WEBHOOK_DELIVERY_STATE_DELIVERED
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, string(receivedBody), "\"event\":\"payment_received\"")
+	assert.Contains(t, string(receivedBody), tests.MockPaymentHash)
+	expectedSignature := "sha256=" + Sign(webhook.Secret, receivedHeaders.Get(TimestampHeader), receivedBody)
+	assert.Equal(t, expectedSignature, receivedHeaders.Get(SignatureHeader))
+}
+
+func TestWebhookDelivery_Retries(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalRetryBaseDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = originalRetryBaseDelay }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	webhook, err := webhooksSvc.CreateWebhook(server.URL, []string{WEBHOOK_EVENT_PAYMENT_FAILED}, nil)
+	require.NoError(t, err)
+
+	webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_payment_failed",
+		Properties: &db.Transaction{PaymentHash: tests.MockPaymentHash},
+	}, map[string]interface{}{})
+
+	require.Eventually(t, func() bool {
+		deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, "", 0)
+		return err == nil && len(deliveries) == 1 && deliveries[0].State == db.WEBHOOK_DELIVERY_STATE_FAILED
+	}, 5*time.Second, 10*time.Millisecond)
+
+	deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, maxDeliveryAttempts, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseStatus)
+
+	stats, err := webhooksSvc.GetDeliveryStats(webhook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.NotNil(t, stats.LastFailedAt)
+}
+
+func TestWebhookDelivery_RetryFailed(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalRetryBaseDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = originalRetryBaseDelay }()
+
+	var available atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	webhook, err := webhooksSvc.CreateWebhook(server.URL, []string{WEBHOOK_EVENT_PAYMENT_FAILED}, nil)
+	require.NoError(t, err)
+
+	webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_payment_failed",
+		Properties: &db.Transaction{PaymentHash: tests.MockPaymentHash},
+	}, map[string]interface{}{})
+
+	require.Eventually(t, func() bool {
+		deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, db.WEBHOOK_DELIVERY_STATE_FAILED, 0)
+		return err == nil && len(deliveries) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, db.WEBHOOK_DELIVERY_STATE_FAILED, 0)
+	require.NoError(t, err)
+	_, err = webhooksSvc.RetryDelivery(context.TODO(), webhook.ID+1, deliveries[0].ID)
+	assert.Error(t, err)
+
+	available.Store(true)
+	_, err = webhooksSvc.RetryDelivery(context.TODO(), webhook.ID, deliveries[0].ID)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, db.WEBHOOK_DELIVERY_STATE_DELIVERED, 0)
+		return err == nil && len(deliveries) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// only failed deliveries can be retried
+	_, err = webhooksSvc.RetryDelivery(context.TODO(), webhook.ID, deliveries[0].ID)
+	assert.Error(t, err)
+}
+
+func TestWebhookDelivery_DeadLetter(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	originalRetryBaseDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = originalRetryBaseDelay }()
+
+	var available atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	webhook, err := webhooksSvc.CreateWebhook(server.URL, []string{WEBHOOK_EVENT_PAYMENT_FAILED}, nil)
+	require.NoError(t, err)
+
+	webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_payment_failed",
+		Properties: &db.Transaction{PaymentHash: tests.MockPaymentHash},
+	}, map[string]interface{}{})
+
+	var deadLetter db.DeadLetter
+	require.Eventually(t, func() bool {
+		return svc.DB.Limit(1).Find(&deadLetter, &db.DeadLetter{Consumer: deadletters.CONSUMER_WEBHOOKS}).RowsAffected == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, WEBHOOK_EVENT_PAYMENT_FAILED, deadLetter.Event)
+	assert.Equal(t, "unexpected response status 503", deadLetter.Error)
+
+	assert.Error(t, webhooksSvc.ReplayDeadLetter(context.TODO(), &deadLetter))
+
+	available.Store(true)
+	require.NoError(t, webhooksSvc.ReplayDeadLetter(context.TODO(), &deadLetter))
+	deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, db.WEBHOOK_DELIVERY_STATE_DELIVERED, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, maxDeliveryAttempts+2, deliveries[0].Attempts)
+}
+
+func TestWebhookDelivery_HubEvents(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	_, err = webhooksSvc.CreateAppWebhook(app.ID, server.URL, []string{WEBHOOK_EVENT_CHANNEL_OPENED}, nil)
+	assert.Error(t, err)
+
+	webhook, err := webhooksSvc.CreateWebhook(server.URL, []string{WEBHOOK_EVENT_CHANNEL_OPENED}, nil)
+	require.NoError(t, err)
+	allWebhook, err := webhooksSvc.CreateWebhook(server.URL, []string{WEBHOOK_EVENT_ALL}, nil)
+	require.NoError(t, err)
+	// app webhooks never receive hub events, even when subscribed to all events
+	appWebhook, err := webhooksSvc.CreateAppWebhook(app.ID, server.URL, []string{WEBHOOK_EVENT_ALL}, nil)
+	require.NoError(t, err)
+
+	webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_channel_ready",
+		Properties: map[string]interface{}{
+			"counterparty_node_id": "abc",
+		},
+	}, map[string]interface{}{})
+	webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_node_started",
+	}, map[string]interface{}{})
+
+	require.Eventually(t, func() bool {
+		deliveries, err := webhooksSvc.ListDeliveries(allWebhook.ID, db.WEBHOOK_DELIVERY_STATE_DELIVERED, 0)
+		return err == nil && len(deliveries) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, "", 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Contains(t, deliveries[0].Payload, "\"event\":\"channel_opened\"")
+	assert.Contains(t, deliveries[0].Payload, "\"counterparty_node_id\":\"abc\"")
+
+	deliveries, err = webhooksSvc.ListDeliveries(appWebhook.ID, "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
+
+func TestWebhookDelivery_Filter(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	webhook, err := webhooksSvc.CreateWebhook(server.URL, []string{WEBHOOK_EVENT_PAYMENT_RECEIVED}, &Filter{
+		MinAmountSat: 100,
+		Match: map[string]interface{}{
+			"description": "coffee",
+		},
+	})
+	require.NoError(t, err)
+
+	for _, transaction := range []db.Transaction{
+		{AmountMsat: 1_000_000, Description: "coffee"},
+		{AmountMsat: 1_000_000, Description: "tea"},
+		{AmountMsat: 99_000, Description: "coffee"},
+	} {
+		transaction.Type = constants.TRANSACTION_TYPE_INCOMING
+		transaction.State = constants.TRANSACTION_STATE_SETTLED
+		transaction.PaymentHash = tests.MockPaymentHash
+		webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+			Event:      "nwc_payment_received",
+			Properties: &transaction,
+		}, map[string]interface{}{})
+	}
+
+	deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, "", 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Contains(t, deliveries[0].Payload, "\"amount\":1000000")
+
+	// the filter is removed by updating with an empty filter
+	webhook, err = webhooksSvc.UpdateWebhook(webhook.ID, nil, nil, &Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, webhook.Filter)
+}
+
+func TestWebhookDelivery_AppWebhook(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	otherApp, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	_, err = webhooksSvc.CreateAppWebhook(otherApp.ID+1, server.URL, []string{WEBHOOK_EVENT_PAYMENT_RECEIVED}, nil)
+	assert.EqualError(t, err, "app not found")
+
+	appWebhook, err := webhooksSvc.CreateAppWebhook(app.ID, server.URL, []string{WEBHOOK_EVENT_PAYMENT_RECEIVED}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &app.ID, appWebhook.AppId)
+	webhook, err := webhooksSvc.CreateWebhook(server.URL, []string{WEBHOOK_EVENT_PAYMENT_RECEIVED}, nil)
+	require.NoError(t, err)
+
+	// payments of other apps and of the hub itself only go to webhooks without an app
+	for _, appId := range []*uint{&app.ID, &otherApp.ID, nil} {
+		webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+			Event: "nwc_payment_received",
+			Properties: &db.Transaction{
+				AppId:       appId,
+				Type:        constants.TRANSACTION_TYPE_INCOMING,
+				State:       constants.TRANSACTION_STATE_SETTLED,
+				PaymentHash: tests.MockPaymentHash,
+			},
+		}, map[string]interface{}{})
+	}
+
+	require.Eventually(t, func() bool {
+		deliveries, err := webhooksSvc.ListDeliveries(webhook.ID, "", 0)
+		return err == nil && len(deliveries) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	deliveries, err := webhooksSvc.ListDeliveries(appWebhook.ID, "", 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Contains(t, deliveries[0].Payload, fmt.Sprintf("\"app_id\":%d", app.ID))
+
+	// the webhook is removed together with the app
+	require.NoError(t, svc.AppsService.DeleteApp(app))
+	webhooks, err := webhooksSvc.ListWebhooks()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, webhook.ID, webhooks[0].ID)
+}
+
+func TestWebhookDelivery_BudgetThresholdReached(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	webhooksSvc := NewWebhooksService(svc.DB)
+	appWebhook, err := webhooksSvc.CreateAppWebhook(app.ID, server.URL, []string{WEBHOOK_EVENT_BUDGET_THRESHOLD_REACHED}, nil)
+	require.NoError(t, err)
+
+	for _, appId := range []uint{app.ID, app.ID + 1} {
+		webhooksSvc.ConsumeEvent(context.TODO(), &events.Event{
+			Event: "nwc_budget_threshold_reached",
+			Properties: &events.BudgetThresholdReachedEvent{
+				AppId:         appId,
+				Threshold:     80,
+				BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+				BudgetUsage:   800,
+				MaxAmount:     1000,
+				Currency:      "SATS",
+			},
+		}, map[string]interface{}{})
+	}
+
+	require.Eventually(t, func() bool {
+		deliveries, err := webhooksSvc.ListDeliveries(appWebhook.ID, "", 0)
+		return err == nil && len(deliveries) == 1 && deliveries[0].State == db.WEBHOOK_DELIVERY_STATE_DELIVERED
+	}, 5*time.Second, 10*time.Millisecond)
+
+	deliveries, err := webhooksSvc.ListDeliveries(appWebhook.ID, "", 0)
+	require.NoError(t, err)
+	assert.Contains(t, deliveries[0].Payload, "\"event\":\"budget_threshold_reached\"")
+	assert.Contains(t, deliveries[0].Payload, "\"threshold\":80")
+}